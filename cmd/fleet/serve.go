@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
 	"crypto/tls"
 	"database/sql/driver"
 	"errors"
@@ -14,6 +15,7 @@ import (
 	"os"
 	"os/signal"
 	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -23,6 +25,7 @@ import (
 	"github.com/fleetdm/fleet/v4/ee/server/licensing"
 	eeservice "github.com/fleetdm/fleet/v4/ee/server/service"
 	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/agentca"
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/datastore/cached_mysql"
@@ -32,6 +35,8 @@ import (
 	"github.com/fleetdm/fleet/v4/server/errorstore"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/health"
+	"github.com/fleetdm/fleet/v4/server/ingestsidecar"
+	"github.com/fleetdm/fleet/v4/server/jira"
 	"github.com/fleetdm/fleet/v4/server/launcher"
 	"github.com/fleetdm/fleet/v4/server/live_query"
 	"github.com/fleetdm/fleet/v4/server/logging"
@@ -39,8 +44,10 @@ import (
 	"github.com/fleetdm/fleet/v4/server/pubsub"
 	"github.com/fleetdm/fleet/v4/server/service"
 	"github.com/fleetdm/fleet/v4/server/service/async"
+	"github.com/fleetdm/fleet/v4/server/service/osquery_utils"
 	"github.com/fleetdm/fleet/v4/server/service/redis_policy_set"
 	"github.com/fleetdm/fleet/v4/server/sso"
+	"github.com/fleetdm/fleet/v4/server/threatintel"
 	"github.com/fleetdm/fleet/v4/server/vulnerabilities"
 	"github.com/fleetdm/fleet/v4/server/webhooks"
 	"github.com/getsentry/sentry-go"
@@ -291,7 +298,11 @@ the way that the Fleet server works.
 			level.Info(logger).Log("component", "redis", "mode", redisPool.Mode())
 
 			ds = cached_mysql.New(ds)
-			resultStore := pubsub.NewRedisQueryResults(redisPool, config.Redis.DuplicateResults)
+			resultStore, err := pubsub.NewQueryResultStore(config.Osquery.LiveQueryResultStore, config, redisPool)
+			if err != nil {
+				initFatal(err, "initialize live query result store")
+			}
+			level.Info(logger).Log("component", "live_query_result_store", "store", config.Osquery.LiveQueryResultStore)
 			liveQueryStore := live_query.NewRedisLiveQuery(redisPool)
 			ssoSessionStore := sso.NewSessionStore(redisPool)
 
@@ -345,6 +356,13 @@ the way that the Fleet server works.
 			// TODO: gather all the different contexts and use just one
 			ctx, cancelFunc := context.WithCancel(context.Background())
 			defer cancelFunc()
+
+			if config.IngestSidecar.URL != "" {
+				sidecar := ingestsidecar.New(config.IngestSidecar)
+				osquery_utils.RegisterExtension("sidecar", sidecar)
+				go refreshIngestSidecar(ctx, sidecar, kitlog.With(logger, "component", "ingest_sidecar"), config.IngestSidecar.RefreshInterval)
+			}
+
 			svc, err := service.NewService(ctx, ds, task, resultStore, logger, osqueryLogger, config, mailService, clock.C, ssoSessionStore, liveQueryStore, carveStore, *license, failingPolicySet, geoIP)
 			if err != nil {
 				initFatal(err, "initializing service")
@@ -357,7 +375,7 @@ the way that the Fleet server works.
 				}
 			}
 
-			cancelBackground := runCrons(ds, task, kitlog.With(logger, "component", "crons"), config, license, failingPolicySet)
+			cancelBackground := runCrons(ds, task, kitlog.With(logger, "component", "crons"), config, license, failingPolicySet, liveQueryStore)
 
 			// Flush seen hosts every second
 			go func() {
@@ -368,6 +386,24 @@ the way that the Fleet server works.
 							"msg", "failed to update host seen times",
 						)
 					}
+					if err := svc.FlushEnrollmentStats(context.Background()); err != nil {
+						level.Info(logger).Log(
+							"err", err,
+							"msg", "failed to record enrollment stats",
+						)
+					}
+					if err := svc.FlushQueryUsage(context.Background()); err != nil {
+						level.Info(logger).Log(
+							"err", err,
+							"msg", "failed to record query usage",
+						)
+					}
+					if err := svc.FlushLiveQueryResults(context.Background()); err != nil {
+						level.Info(logger).Log(
+							"err", err,
+							"msg", "failed to record live query results",
+						)
+					}
 				}
 			}()
 
@@ -446,6 +482,28 @@ the way that the Fleet server works.
 			rootMux.Handle("/", frontendHandler)
 			rootMux.Handle("/debug/", service.MakeDebugHandler(svc, config, logger, eh, ds))
 
+			if config.AgentCA.Cert != "" || config.AgentCA.Key != "" {
+				agentCA, err := agentca.LoadCA(config.AgentCA.Cert, config.AgentCA.Key, config.AgentCA.Validity)
+				if err != nil {
+					initFatal(err, "loading agent CA")
+				}
+				verifyChallenge := func(ctx context.Context, challenge string) error {
+					if config.AgentCA.Challenge != "" {
+						if subtle.ConstantTimeCompare([]byte(challenge), []byte(config.AgentCA.Challenge)) != 1 {
+							return agentca.ErrInvalidChallenge
+						}
+						return nil
+					}
+					_, err := ds.VerifyEnrollSecret(ctx, challenge)
+					return err
+				}
+				// like every rootMux handler, this is served under
+				// server.url_prefix (see prefixMux below)
+				rootMux.Handle("/agent_ca/", http.StripPrefix("/agent_ca",
+					service.InstrumentHandler("agent_ca", agentca.Handler(agentCA, verifyChallenge, httpLogger)),
+				))
+			}
+
 			if path, ok := os.LookupEnv("FLEET_TEST_PAGE_PATH"); ok {
 				// test that we can load this
 				_, err := ioutil.ReadFile(path)
@@ -550,7 +608,11 @@ the way that the Fleet server works.
 					cancelBackground()
 					cancelFunc()
 					launcher.GracefulStop()
-					return srv.Shutdown(ctx)
+					err := srv.Shutdown(ctx)
+					if err := osqueryLogger.Flush(ctx); err != nil {
+						level.Error(logger).Log("msg", "flushing osquery logs", "err", err)
+					}
+					return err
 				}()
 			}()
 
@@ -571,6 +633,9 @@ const (
 	lockKeyVulnerabilities         = "vulnerabilities"
 	lockKeyWebhooksHostStatus      = "webhooks" // keeping this name for backwards compatibility.
 	lockKeyWebhooksFailingPolicies = "webhooks:global_failing_policies"
+	lockKeyWebhooksHostLifecycle   = "webhooks:host_lifecycle"
+	lockKeyThreatIntel             = "threat_intel"
+	lockKeyLiveQueryCampaigns      = "live_query_campaigns"
 )
 
 func trySendStatistics(ctx context.Context, ds fleet.Datastore, frequency time.Duration, url string, license *fleet.LicenseInfo) error {
@@ -597,7 +662,7 @@ func trySendStatistics(ctx context.Context, ds fleet.Datastore, frequency time.D
 	return ds.RecordStatisticsSent(ctx)
 }
 
-func runCrons(ds fleet.Datastore, task *async.Task, logger kitlog.Logger, config config.FleetConfig, license *fleet.LicenseInfo, failingPoliciesSet fleet.FailingPolicySet) context.CancelFunc {
+func runCrons(ds fleet.Datastore, task *async.Task, logger kitlog.Logger, config config.FleetConfig, license *fleet.LicenseInfo, failingPoliciesSet fleet.FailingPolicySet, liveQueryStore fleet.LiveQueryStore) context.CancelFunc {
 	ctx, cancelBackground := context.WithCancel(context.Background())
 
 	ourIdentifier, err := server.GenerateRandomText(64)
@@ -612,6 +677,9 @@ func runCrons(ds fleet.Datastore, task *async.Task, logger kitlog.Logger, config
 	go cronVulnerabilities(
 		ctx, ds, kitlog.With(logger, "cron", "vulnerabilities"), ourIdentifier, config)
 	go cronWebhooks(ctx, ds, kitlog.With(logger, "cron", "webhooks"), ourIdentifier, failingPoliciesSet, 1*time.Hour)
+	go cronHostLifecycleWebhook(ctx, ds, kitlog.With(logger, "cron", "host_lifecycle_webhook"), ourIdentifier, 1*time.Minute)
+	go cronThreatIntel(ctx, ds, kitlog.With(logger, "cron", "threat_intel"), ourIdentifier, config)
+	go cronLiveQueryCampaigns(ctx, ds, liveQueryStore, kitlog.With(logger, "cron", "live_query_campaigns"), ourIdentifier, config)
 
 	return cancelBackground
 }
@@ -634,9 +702,14 @@ func cronDB(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, ident
 			continue
 		}
 
-		_, err := ds.CleanupDistributedQueryCampaigns(ctx, time.Now())
+		err := ds.CleanupDistributedQueryExecutions(ctx, time.Now())
+		if err != nil {
+			level.Error(logger).Log("err", "cleaning distributed query executions", "details", err)
+			sentry.CaptureException(err)
+		}
+		err = ds.CleanupBatchQueries(ctx, time.Now())
 		if err != nil {
-			level.Error(logger).Log("err", "cleaning distributed query campaigns", "details", err)
+			level.Error(logger).Log("err", "cleaning batch queries", "details", err)
 			sentry.CaptureException(err)
 		}
 		err = ds.CleanupIncomingHosts(ctx, time.Now())
@@ -644,6 +717,26 @@ func cronDB(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, ident
 			level.Error(logger).Log("err", "cleaning incoming hosts", "details", err)
 			sentry.CaptureException(err)
 		}
+		err = ds.CleanupHostTombstones(ctx, time.Now())
+		if err != nil {
+			level.Error(logger).Log("err", "cleaning host tombstones", "details", err)
+			sentry.CaptureException(err)
+		}
+		err = ds.CleanupExpiredHostAgentOptionsOverrides(ctx, time.Now())
+		if err != nil {
+			level.Error(logger).Log("err", "cleaning expired host agent options overrides", "details", err)
+			sentry.CaptureException(err)
+		}
+		err = ds.CleanupStartupItemChanges(ctx, time.Now())
+		if err != nil {
+			level.Error(logger).Log("err", "cleaning startup item changes", "details", err)
+			sentry.CaptureException(err)
+		}
+		err = ds.CleanupHostActivities(ctx, time.Now())
+		if err != nil {
+			level.Error(logger).Log("err", "cleaning host activities", "details", err)
+			sentry.CaptureException(err)
+		}
 		_, err = ds.CleanupCarves(ctx, time.Now())
 		if err != nil {
 			level.Error(logger).Log("err", "cleaning carves", "details", err)
@@ -659,6 +752,11 @@ func cronDB(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, ident
 			level.Error(logger).Log("err", "aggregating scheduled query stats", "details", err)
 			sentry.CaptureException(err)
 		}
+		err = ds.UpdatePolicyStats(ctx)
+		if err != nil {
+			level.Error(logger).Log("err", "aggregating policy stats", "details", err)
+			sentry.CaptureException(err)
+		}
 		err = ds.CleanupExpiredHosts(ctx)
 		if err != nil {
 			level.Error(logger).Log("err", "cleaning expired hosts", "details", err)
@@ -674,6 +772,11 @@ func cronDB(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, ident
 			level.Error(logger).Log("err", "cleanup policy membership", "details", err)
 			sentry.CaptureException(err)
 		}
+		err = ds.UpdateHostIssues(ctx)
+		if err != nil {
+			level.Error(logger).Log("err", "update host issues", "details", err)
+			sentry.CaptureException(err)
+		}
 		err = ds.UpdateOSVersions(ctx)
 		if err != nil {
 			level.Error(logger).Log("err", "update os versions", "details", err)
@@ -813,6 +916,130 @@ func checkVulnerabilities(ctx context.Context, ds fleet.Datastore, logger kitlog
 	return recentVulns
 }
 
+func cronThreatIntel(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	identifier string,
+	config config.FleetConfig,
+) {
+	if config.ThreatIntel.URL == "" {
+		level.Info(logger).Log("threat intel", "not configured")
+		return
+	}
+	level.Info(logger).Log("periodicity", config.ThreatIntel.Periodicity)
+
+	client := threatintel.NewClient(config.ThreatIntel.URL, config.ThreatIntel.APIKey)
+	ticker := time.NewTicker(10 * time.Second)
+	for {
+		level.Debug(logger).Log("waiting", "on ticker")
+		select {
+		case <-ticker.C:
+			level.Debug(logger).Log("waiting", "done")
+			ticker.Reset(config.ThreatIntel.Periodicity)
+		case <-ctx.Done():
+			level.Debug(logger).Log("exit", "done with cron.")
+			return
+		}
+
+		if locked, err := ds.Lock(ctx, lockKeyThreatIntel, identifier, config.ThreatIntel.Periodicity); err != nil || !locked {
+			level.Debug(logger).Log("leader", "Not the leader. Skipping...")
+			continue
+		}
+
+		if err := threatintel.Enrich(ctx, ds, client, logger, config.ThreatIntel, time.Now()); err != nil {
+			level.Error(logger).Log("msg", "enriching hosts with threat intel", "err", err)
+			sentry.CaptureException(err)
+		}
+
+		level.Debug(logger).Log("loop", "done")
+	}
+}
+
+func cronLiveQueryCampaigns(
+	ctx context.Context,
+	ds fleet.Datastore,
+	liveQueryStore fleet.LiveQueryStore,
+	logger kitlog.Logger,
+	identifier string,
+	config config.FleetConfig,
+) {
+	if config.Osquery.LiveQueryReapInterval <= 0 {
+		level.Info(logger).Log("live query campaigns reaping", "disabled")
+		return
+	}
+	level.Info(logger).Log("ttl", config.Osquery.LiveQueryCampaignTTL, "interval", config.Osquery.LiveQueryReapInterval)
+
+	ticker := time.NewTicker(10 * time.Second)
+	for {
+		level.Debug(logger).Log("waiting", "on ticker")
+		select {
+		case <-ticker.C:
+			level.Debug(logger).Log("waiting", "done")
+			ticker.Reset(config.Osquery.LiveQueryReapInterval)
+		case <-ctx.Done():
+			level.Debug(logger).Log("exit", "done with cron.")
+			return
+		}
+
+		if locked, err := ds.Lock(ctx, lockKeyLiveQueryCampaigns, identifier, config.Osquery.LiveQueryReapInterval); err != nil || !locked {
+			level.Debug(logger).Log("leader", "Not the leader. Skipping...")
+			continue
+		}
+
+		if err := reapLiveQueryCampaigns(ctx, ds, liveQueryStore, logger, config.Osquery.LiveQueryCampaignTTL, time.Now()); err != nil {
+			level.Error(logger).Log("err", "reaping live query campaigns", "details", err)
+			sentry.CaptureException(err)
+		}
+
+		level.Debug(logger).Log("loop", "done")
+	}
+}
+
+// reapLiveQueryCampaigns completes the stale live query campaigns, which are
+// otherwise only completed once a host sends a result nobody is subscribed to,
+// and the campaigns of the expired batch queries, and stops their queries so
+// that the hosts that did not run them yet do not receive them anymore.
+func reapLiveQueryCampaigns(ctx context.Context, ds fleet.Datastore, liveQueryStore fleet.LiveQueryStore, logger kitlog.Logger, ttl time.Duration, now time.Time) error {
+	expired, err := ds.CleanupDistributedQueryCampaigns(ctx, now, ttl)
+	if err != nil {
+		return err
+	}
+	for _, id := range expired {
+		if err := liveQueryStore.StopQuery(strconv.Itoa(int(id))); err != nil {
+			// Keep going, the query expires from Redis eventually.
+			level.Error(logger).Log("err", "stopping live query", "campaign_id", id, "details", err)
+			sentry.CaptureException(err)
+		}
+	}
+	if len(expired) > 0 {
+		level.Info(logger).Log("msg", "completed stale live query campaigns", "count", len(expired))
+	}
+	return nil
+}
+
+// refreshIngestSidecar refreshes the detail queries of the ingest sidecar
+// every interval, until ctx is done.
+func refreshIngestSidecar(ctx context.Context, sidecar *ingestsidecar.Sidecar, logger kitlog.Logger, interval time.Duration) {
+	level.Info(logger).Log("refresh_interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := sidecar.Refresh(ctx); err != nil {
+			level.Error(logger).Log("msg", "refreshing ingest sidecar detail queries", "err", err)
+			sentry.CaptureException(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			level.Debug(logger).Log("exit", "done with ingest sidecar refresh.")
+			return
+		}
+	}
+}
+
 func cronWebhooks(
 	ctx context.Context,
 	ds fleet.Datastore,
@@ -870,6 +1097,48 @@ func cronWebhooks(
 	}
 }
 
+// cronHostLifecycleWebhook sends the host lifecycle events every interval,
+// the events are queued as they happen so that the webhook is triggered in
+// near real time, unlike the other webhooks.
+func cronHostLifecycleWebhook(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	identifier string,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		level.Debug(logger).Log("waiting", "on ticker")
+		select {
+		case <-ticker.C:
+			level.Debug(logger).Log("waiting", "done")
+		case <-ctx.Done():
+			level.Debug(logger).Log("exit", "done with cron.")
+			return
+		}
+
+		if locked, err := ds.Lock(ctx, lockKeyWebhooksHostLifecycle, identifier, interval); err != nil || !locked {
+			level.Debug(logger).Log("leader", "Not the leader. Skipping...")
+			continue
+		}
+
+		appConfig, err := ds.AppConfig(ctx)
+		if err != nil {
+			level.Error(logger).Log("config", "couldn't read app config", "err", err)
+			sentry.CaptureException(err)
+			continue
+		}
+		if err := webhooks.TriggerHostLifecycleWebhook(ctx, ds, logger, appConfig, time.Now()); err != nil {
+			level.Error(logger).Log("err", "triggering host lifecycle webhook", "details", err)
+			sentry.CaptureException(err)
+		}
+
+		level.Debug(logger).Log("loop", "done")
+	}
+}
+
 func maybeTriggerHostStatus(
 	ctx context.Context,
 	ds fleet.Datastore,
@@ -905,6 +1174,19 @@ func maybeTriggerFailingPoliciesWebhook(
 		return
 	}
 
+	if integration := appConfig.Integrations.FailingPoliciesJira(); integration != nil {
+		client, err := jira.NewClient(integration)
+		if err != nil {
+			level.Error(logger).Log("err", "creating jira client", "details", err)
+			sentry.CaptureException(err)
+		} else if err := webhooks.TriggerFailingPoliciesJira(
+			ctx, ds, kitlog.With(logger, "integration", "jira_failing_policies"), appConfig, failingPoliciesSet, client,
+		); err != nil {
+			level.Error(logger).Log("err", "triggering failing policies jira integration", "details", err)
+			sentry.CaptureException(err)
+		}
+	}
+
 	if err := webhooks.TriggerFailingPoliciesWebhook(
 		ctx, ds, kitlog.With(logger, "webhook", "failing_policies"), appConfig, failingPoliciesSet, time.Now(),
 	); err != nil {
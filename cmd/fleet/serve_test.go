@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/live_query"
 	"github.com/fleetdm/fleet/v4/server/mock"
 	"github.com/fleetdm/fleet/v4/server/service"
 	kitlog "github.com/go-kit/kit/log"
@@ -115,6 +117,31 @@ func TestMaybeSendStatisticsSkipsIfNotConfigured(t *testing.T) {
 	assert.False(t, called)
 }
 
+func TestReapLiveQueryCampaigns(t *testing.T) {
+	ds := new(mock.Store)
+	lq := new(live_query.MockLiveQuery)
+
+	now := time.Now()
+	ds.CleanupDistributedQueryCampaignsFunc = func(ctx context.Context, now time.Time, runningTTL time.Duration) ([]uint, error) {
+		assert.Equal(t, 2*time.Hour, runningTTL)
+		return []uint{1, 42}, nil
+	}
+	lq.On("StopQuery", "1").Return(errors.New("redis is down"))
+	lq.On("StopQuery", "42").Return(nil)
+
+	// the campaigns are all stopped, even if one fails
+	err := reapLiveQueryCampaigns(context.Background(), ds, lq, kitlog.NewNopLogger(), 2*time.Hour, now)
+	require.NoError(t, err)
+	assert.True(t, ds.CleanupDistributedQueryCampaignsFuncInvoked)
+	lq.AssertExpectations(t)
+
+	ds.CleanupDistributedQueryCampaignsFunc = func(ctx context.Context, now time.Time, runningTTL time.Duration) ([]uint, error) {
+		return nil, errors.New("mysql is down")
+	}
+	err = reapLiveQueryCampaigns(context.Background(), ds, lq, kitlog.NewNopLogger(), 2*time.Hour, now)
+	require.Error(t, err)
+}
+
 func TestCronWebhooks(t *testing.T) {
 	ds := new(mock.Store)
 
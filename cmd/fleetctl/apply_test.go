@@ -387,6 +387,10 @@ spec:
 func TestApplyPacks(t *testing.T) {
 	_, ds := runServerWithMockedDS(t)
 
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
 	ds.ListPacksFunc = func(ctx context.Context, opt fleet.PackListOptions) ([]*fleet.Pack, error) {
 		return nil, nil
 	}
@@ -399,6 +403,9 @@ func TestApplyPacks(t *testing.T) {
 		appliedPacks = specs
 		return nil
 	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
 
 	name := writeTmpYml(t, `---
 apiVersion: v1
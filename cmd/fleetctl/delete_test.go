@@ -40,6 +40,9 @@ func TestDeletePack(t *testing.T) {
 		deletedPack = name
 		return nil
 	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
 	ds.PackByNameFunc = func(ctx context.Context, name string, opts ...fleet.OptionalArg) (*fleet.Pack, bool, error) {
 		if name != "pack1" {
 			return nil, false, nil
@@ -166,6 +166,7 @@ spec:
         destination_url: ""
         enable_failing_policies_webhook: false
         host_batch_size: 0
+        max_hosts: 0
         policy_ids: null
 ---
 apiVersion: v1
@@ -190,10 +191,11 @@ spec:
         destination_url: ""
         enable_failing_policies_webhook: false
         host_batch_size: 0
+        max_hosts: 0
         policy_ids: null
 `
-			expectedJson := `{"kind":"team","apiVersion":"v1","spec":{"team":{"id":42,"created_at":"1999-03-10T02:45:06.371Z","name":"team1","description":"team1 description","webhook_settings":{"failing_policies_webhook":{"enable_failing_policies_webhook":false,"destination_url":"","policy_ids":null,"host_batch_size":0}},"user_count":99,"host_count":0}}}
-{"kind":"team","apiVersion":"v1","spec":{"team":{"id":43,"created_at":"1999-03-10T02:45:06.371Z","name":"team2","description":"team2 description","agent_options":{"config":{"foo":"bar"},"overrides":{"platforms":{"darwin":{"foo":"override"}}}},"webhook_settings":{"failing_policies_webhook":{"enable_failing_policies_webhook":false,"destination_url":"","policy_ids":null,"host_batch_size":0}},"user_count":87,"host_count":0}}}
+			expectedJson := `{"kind":"team","apiVersion":"v1","spec":{"team":{"id":42,"created_at":"1999-03-10T02:45:06.371Z","name":"team1","description":"team1 description","webhook_settings":{"failing_policies_webhook":{"enable_failing_policies_webhook":false,"destination_url":"","policy_ids":null,"host_batch_size":0,"max_hosts":0}},"user_count":99,"host_count":0}}}
+{"kind":"team","apiVersion":"v1","spec":{"team":{"id":43,"created_at":"1999-03-10T02:45:06.371Z","name":"team2","description":"team2 description","agent_options":{"config":{"foo":"bar"},"overrides":{"platforms":{"darwin":{"foo":"override"}}}},"webhook_settings":{"failing_policies_webhook":{"enable_failing_policies_webhook":false,"destination_url":"","policy_ids":null,"host_batch_size":0,"max_hosts":0}},"user_count":87,"host_count":0}}}
 `
 			if tt.shouldHaveExpiredBanner {
 				expectedJson = expiredBanner.String() + expectedJson
@@ -301,6 +303,12 @@ func TestGetHosts(t *testing.T) {
 	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) (packs []*fleet.Pack, err error) {
 		return make([]*fleet.Pack, 0), nil
 	}
+	ds.ListHostAgentVersionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostAgentVersion, error) {
+		return []*fleet.HostAgentVersion{{Component: fleet.AgentComponentOrbit, Version: "0.0.11"}}, nil
+	}
+	ds.HostMetadataFunc = func(ctx context.Context, hostID uint) (*fleet.HostMetadata, error) {
+		return &fleet.HostMetadata{HostID: hostID, Tags: fleet.HostTags{}}, nil
+	}
 	defaultPolicyQuery := "select 1 from osquery_info where start_time > 1;"
 	ds.ListPoliciesForHostFunc = func(ctx context.Context, host *fleet.Host) ([]*fleet.HostPolicy, error) {
 		return []*fleet.HostPolicy{
@@ -440,11 +448,16 @@ spec:
   host_settings:
     enable_host_users: true
     enable_software_inventory: false
+    missing_threshold_days: 0
+    offline_threshold: 0s
   integrations:
     jira: null
   org_info:
     org_logo_url: ""
     org_name: ""
+  schedule_settings:
+    min_interval: 0
+    min_snapshot_interval: 0
   server_settings:
     deferred_save_host: false
     enable_analytics: false
@@ -476,11 +489,23 @@ spec:
   vulnerability_settings:
     databases_path: /some/path
   webhook_settings:
+    batch_query_webhook:
+      destination_url: ""
+      enable_batch_query_webhook: false
     failing_policies_webhook:
       destination_url: ""
       enable_failing_policies_webhook: false
       host_batch_size: 0
+      max_hosts: 0
       policy_ids: null
+    host_lifecycle_webhook:
+      destination_url: ""
+      enable_host_lifecycle_webhook: false
+      offline_threshold: 0s
+      secret: ""
+    host_online_webhook:
+      destination_url: ""
+      enable_host_online_webhook: false
     host_status_webhook:
       days_count: 0
       destination_url: ""
@@ -492,7 +517,7 @@ spec:
       enable_vulnerabilities_webhook: false
       host_batch_size: 0
 `
-		expectedJson := `{"kind":"config","apiVersion":"v1","spec":{"org_info":{"org_name":"","org_logo_url":""},"server_settings":{"server_url":"","live_query_disabled":false,"enable_analytics":false,"deferred_save_host":false},"smtp_settings":{"enable_smtp":false,"configured":false,"sender_address":"","server":"","port":0,"authentication_type":"","user_name":"","password":"","enable_ssl_tls":false,"authentication_method":"","domain":"","verify_ssl_certs":false,"enable_start_tls":false},"host_expiry_settings":{"host_expiry_enabled":false,"host_expiry_window":0},"host_settings":{"enable_host_users":true,"enable_software_inventory":false},"sso_settings":{"entity_id":"","issuer_uri":"","idp_image_url":"","metadata":"","metadata_url":"","idp_name":"","enable_sso":false,"enable_sso_idp_login":false},"vulnerability_settings":{"databases_path":"/some/path"},"webhook_settings":{"host_status_webhook":{"enable_host_status_webhook":false,"destination_url":"","host_percentage":0,"days_count":0},"failing_policies_webhook":{"enable_failing_policies_webhook":false,"destination_url":"","policy_ids":null,"host_batch_size":0},"vulnerabilities_webhook":{"enable_vulnerabilities_webhook":false,"destination_url":"","host_batch_size":0},"interval":"0s"},"integrations":{"jira":null}}}
+		expectedJson := `{"kind":"config","apiVersion":"v1","spec":{"org_info":{"org_name":"","org_logo_url":""},"server_settings":{"server_url":"","live_query_disabled":false,"enable_analytics":false,"deferred_save_host":false},"smtp_settings":{"enable_smtp":false,"configured":false,"sender_address":"","server":"","port":0,"authentication_type":"","user_name":"","password":"","enable_ssl_tls":false,"authentication_method":"","domain":"","verify_ssl_certs":false,"enable_start_tls":false},"host_expiry_settings":{"host_expiry_enabled":false,"host_expiry_window":0},"host_settings":{"enable_host_users":true,"enable_software_inventory":false,"offline_threshold":"0s","missing_threshold_days":0},"sso_settings":{"entity_id":"","issuer_uri":"","idp_image_url":"","metadata":"","metadata_url":"","idp_name":"","enable_sso":false,"enable_sso_idp_login":false},"vulnerability_settings":{"databases_path":"/some/path"},"webhook_settings":{"host_status_webhook":{"enable_host_status_webhook":false,"destination_url":"","host_percentage":0,"days_count":0},"failing_policies_webhook":{"enable_failing_policies_webhook":false,"destination_url":"","policy_ids":null,"host_batch_size":0,"max_hosts":0},"vulnerabilities_webhook":{"enable_vulnerabilities_webhook":false,"destination_url":"","host_batch_size":0},"host_online_webhook":{"enable_host_online_webhook":false,"destination_url":""},"batch_query_webhook":{"enable_batch_query_webhook":false,"destination_url":""},"host_lifecycle_webhook":{"enable_host_lifecycle_webhook":false,"destination_url":"","secret":"","offline_threshold":"0s"},"interval":"0s"},"integrations":{"jira":null},"schedule_settings":{"min_interval":0,"min_snapshot_interval":0}}}
 `
 
 		assert.Equal(t, expectedYaml, runAppForTest(t, []string{"get", "config"}))
@@ -511,6 +536,8 @@ spec:
   host_settings:
     enable_host_users: true
     enable_software_inventory: false
+    missing_threshold_days: 0
+    offline_threshold: 0s
   integrations:
     jira: null
   license:
@@ -536,6 +563,9 @@ spec:
   org_info:
     org_logo_url: ""
     org_name: ""
+  schedule_settings:
+    min_interval: 0
+    min_snapshot_interval: 0
   server_settings:
     deferred_save_host: false
     enable_analytics: false
@@ -577,11 +607,23 @@ spec:
   vulnerability_settings:
     databases_path: /some/path
   webhook_settings:
+    batch_query_webhook:
+      destination_url: ""
+      enable_batch_query_webhook: false
     failing_policies_webhook:
       destination_url: ""
       enable_failing_policies_webhook: false
       host_batch_size: 0
+      max_hosts: 0
       policy_ids: null
+    host_lifecycle_webhook:
+      destination_url: ""
+      enable_host_lifecycle_webhook: false
+      offline_threshold: 0s
+      secret: ""
+    host_online_webhook:
+      destination_url: ""
+      enable_host_online_webhook: false
     host_status_webhook:
       days_count: 0
       destination_url: ""
@@ -593,7 +635,7 @@ spec:
       enable_vulnerabilities_webhook: false
       host_batch_size: 0
 `
-		expectedJson := `{"kind":"config","apiVersion":"v1","spec":{"org_info":{"org_name":"","org_logo_url":""},"server_settings":{"server_url":"","live_query_disabled":false,"enable_analytics":false,"deferred_save_host":false},"smtp_settings":{"enable_smtp":false,"configured":false,"sender_address":"","server":"","port":0,"authentication_type":"","user_name":"","password":"","enable_ssl_tls":false,"authentication_method":"","domain":"","verify_ssl_certs":false,"enable_start_tls":false},"host_expiry_settings":{"host_expiry_enabled":false,"host_expiry_window":0},"host_settings":{"enable_host_users":true,"enable_software_inventory":false},"sso_settings":{"entity_id":"","issuer_uri":"","idp_image_url":"","metadata":"","metadata_url":"","idp_name":"","enable_sso":false,"enable_sso_idp_login":false},"vulnerability_settings":{"databases_path":"/some/path"},"webhook_settings":{"host_status_webhook":{"enable_host_status_webhook":false,"destination_url":"","host_percentage":0,"days_count":0},"failing_policies_webhook":{"enable_failing_policies_webhook":false,"destination_url":"","policy_ids":null,"host_batch_size":0},"vulnerabilities_webhook":{"enable_vulnerabilities_webhook":false,"destination_url":"","host_batch_size":0},"interval":"0s"},"integrations":{"jira":null},"update_interval":{"osquery_detail":3600000000000,"osquery_policy":3600000000000},"vulnerabilities":{"databases_path":"","periodicity":0,"cpe_database_url":"","cve_feed_prefix_url":"","current_instance_checks":"","disable_data_sync":false},"license":{"tier":"free","expiration":"0001-01-01T00:00:00Z"},"logging":{"debug":true,"json":false,"result":{"plugin":"filesystem","config":{"enable_log_compression":false,"enable_log_rotation":false,"result_log_file":"/dev/null","status_log_file":"/dev/null"}},"status":{"plugin":"filesystem","config":{"enable_log_compression":false,"enable_log_rotation":false,"result_log_file":"/dev/null","status_log_file":"/dev/null"}}}}}
+		expectedJson := `{"kind":"config","apiVersion":"v1","spec":{"org_info":{"org_name":"","org_logo_url":""},"server_settings":{"server_url":"","live_query_disabled":false,"enable_analytics":false,"deferred_save_host":false},"smtp_settings":{"enable_smtp":false,"configured":false,"sender_address":"","server":"","port":0,"authentication_type":"","user_name":"","password":"","enable_ssl_tls":false,"authentication_method":"","domain":"","verify_ssl_certs":false,"enable_start_tls":false},"host_expiry_settings":{"host_expiry_enabled":false,"host_expiry_window":0},"host_settings":{"enable_host_users":true,"enable_software_inventory":false,"offline_threshold":"0s","missing_threshold_days":0},"sso_settings":{"entity_id":"","issuer_uri":"","idp_image_url":"","metadata":"","metadata_url":"","idp_name":"","enable_sso":false,"enable_sso_idp_login":false},"vulnerability_settings":{"databases_path":"/some/path"},"webhook_settings":{"host_status_webhook":{"enable_host_status_webhook":false,"destination_url":"","host_percentage":0,"days_count":0},"failing_policies_webhook":{"enable_failing_policies_webhook":false,"destination_url":"","policy_ids":null,"host_batch_size":0,"max_hosts":0},"vulnerabilities_webhook":{"enable_vulnerabilities_webhook":false,"destination_url":"","host_batch_size":0},"host_online_webhook":{"enable_host_online_webhook":false,"destination_url":""},"batch_query_webhook":{"enable_batch_query_webhook":false,"destination_url":""},"host_lifecycle_webhook":{"enable_host_lifecycle_webhook":false,"destination_url":"","secret":"","offline_threshold":"0s"},"interval":"0s"},"integrations":{"jira":null},"schedule_settings":{"min_interval":0,"min_snapshot_interval":0},"update_interval":{"osquery_detail":3600000000000,"osquery_policy":3600000000000},"vulnerabilities":{"databases_path":"","periodicity":0,"cpe_database_url":"","cve_feed_prefix_url":"","current_instance_checks":"","disable_data_sync":false},"license":{"tier":"free","expiration":"0001-01-01T00:00:00Z"},"logging":{"debug":true,"json":false,"result":{"plugin":"filesystem","config":{"enable_log_compression":false,"enable_log_rotation":false,"result_log_file":"/dev/null","status_log_file":"/dev/null"}},"status":{"plugin":"filesystem","config":{"enable_log_compression":false,"enable_log_rotation":false,"result_log_file":"/dev/null","status_log_file":"/dev/null"}}}}}
 `
 
 		assert.Equal(t, expectedYaml, runAppForTest(t, []string{"get", "config", "--include-server-config"}))
@@ -35,6 +35,18 @@ func TestHostsTransferByHosts(t *testing.T) {
 		return &fleet.Team{ID: 99, Name: "team1"}, nil
 	}
 
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		require.Equal(t, fleet.ActivityTypeTransferredHosts, activityType)
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
 	ds.AddHostsToTeamFunc = func(ctx context.Context, teamID *uint, hostIDs []uint) error {
 		require.NotNil(t, teamID)
 		require.Equal(t, uint(99), *teamID)
@@ -58,6 +70,18 @@ func TestHostsTransferByLabel(t *testing.T) {
 		return &fleet.Team{ID: 99, Name: "team1"}, nil
 	}
 
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		require.Equal(t, fleet.ActivityTypeTransferredHosts, activityType)
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
 	ds.LabelIDsByNameFunc = func(ctx context.Context, labels []string) ([]uint, error) {
 		require.Equal(t, []string{"label1"}, labels)
 		return []uint{uint(11)}, nil
@@ -92,6 +116,18 @@ func TestHostsTransferByStatus(t *testing.T) {
 		return &fleet.Team{ID: 99, Name: "team1"}, nil
 	}
 
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		require.Equal(t, fleet.ActivityTypeTransferredHosts, activityType)
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
 	ds.LabelIDsByNameFunc = func(ctx context.Context, labels []string) ([]uint, error) {
 		require.Equal(t, []string{"label1"}, labels)
 		return []uint{uint(11)}, nil
@@ -126,6 +162,18 @@ func TestHostsTransferByStatusAndSearchQuery(t *testing.T) {
 		return &fleet.Team{ID: 99, Name: "team1"}, nil
 	}
 
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		require.Equal(t, fleet.ActivityTypeTransferredHosts, activityType)
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
 	ds.LabelIDsByNameFunc = func(ctx context.Context, labels []string) ([]uint, error) {
 		require.Equal(t, []string{"label1"}, labels)
 		return []uint{uint(11)}, nil
@@ -35,13 +35,22 @@ func TestLiveQuery(t *testing.T) {
 		camp.ID = 321
 		return camp, nil
 	}
+	ds.RecordLiveQueryCampaignUsageFunc = func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+		return nil
+	}
+	ds.NewDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+		return nil
+	}
+	ds.NewLiveQueryAuditLogFunc = func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+		return nil
+	}
 	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
 		return target, nil
 	}
-	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
 		return []uint{1}, nil
 	}
-	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
 		return fleet.TargetMetrics{TotalHosts: 1, OnlineHosts: 1}, nil
 	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
@@ -60,6 +69,9 @@ func TestLiveQuery(t *testing.T) {
 	ds.DistributedQueryCampaignTargetIDsFunc = func(ctx context.Context, id uint) (targets *fleet.HostTargets, err error) {
 		return &fleet.HostTargets{HostIDs: []uint{99}}, nil
 	}
+	ds.DistributedQueryCampaignProgressFunc = func(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaignProgress, error) {
+		return &fleet.DistributedQueryCampaignProgress{}, nil
+	}
 	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
 		return &fleet.DistributedQueryCampaign{ID: 321}, nil
 	}
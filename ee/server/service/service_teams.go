@@ -12,6 +12,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/contexts/logging"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	osquerylogging "github.com/fleetdm/fleet/v4/server/logging"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 )
 
@@ -92,6 +93,15 @@ func (svc *Service) ModifyTeam(ctx context.Context, teamID uint, payload fleet.T
 	if payload.WebhookSettings != nil {
 		team.Config.WebhookSettings = *payload.WebhookSettings
 	}
+	if payload.ScheduleSettings != nil {
+		team.Config.ScheduleSettings = payload.ScheduleSettings
+	}
+	if payload.LogSettings != nil {
+		if plugin := payload.LogSettings.ResultLogPlugin; plugin != "" && !osquerylogging.IsLogPlugin(plugin) {
+			return nil, fleet.NewInvalidArgumentError("log_settings.result_log_plugin", fmt.Sprintf("unknown log plugin: %s", plugin))
+		}
+		team.Config.LogSettings = payload.LogSettings
+	}
 
 	return svc.ds.SaveTeam(ctx, team)
 }
@@ -107,6 +117,9 @@ func (svc *Service) ModifyTeamAgentOptions(ctx context.Context, teamID uint, opt
 	}
 
 	if options != nil {
+		if err := fleet.ValidateJSONAgentOptions(options); err != nil {
+			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("agent_options", err.Error()))
+		}
 		team.Config.AgentOptions = &options
 	} else {
 		team.Config.AgentOptions = nil
@@ -292,9 +305,15 @@ func (svc *Service) ModifyTeamEnrollSecrets(ctx context.Context, teamID uint, se
 
 	var newSecrets []*fleet.EnrollSecret
 	for _, secret := range secrets {
-		newSecrets = append(newSecrets, &fleet.EnrollSecret{
-			Secret: secret.Secret,
-		})
+		newSecret := &fleet.EnrollSecret{
+			Secret:    secret.Secret,
+			ExpiresAt: secret.ExpiresAt,
+			MaxUses:   secret.MaxUses,
+		}
+		if err := newSecret.Validate(); err != nil {
+			return nil, err
+		}
+		newSecrets = append(newSecrets, newSecret)
 	}
 	if err := svc.ds.ApplyEnrollSecrets(ctx, ptr.Uint(teamID), newSecrets); err != nil {
 		return nil, err
@@ -334,6 +353,14 @@ func (svc Service) ApplyTeamSpecs(ctx context.Context, specs []*fleet.TeamSpec)
 		return err
 	}
 
+	for _, spec := range specs {
+		if spec.AgentOptions != nil {
+			if err := fleet.ValidateJSONAgentOptions(*spec.AgentOptions); err != nil {
+				return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("agent_options", err.Error()))
+			}
+		}
+	}
+
 	for _, spec := range specs {
 		var secrets []*fleet.EnrollSecret
 		for _, secret := range spec.Secrets {
@@ -78,6 +78,7 @@ export default (client: any) => {
           selectedLabel === "new" ||
           selectedLabel === "online" ||
           selectedLabel === "offline" ||
+          selectedLabel === "missing" ||
           selectedLabel === "mia"
         ) {
           selectedFilter = `&status=${selectedLabel}`;
@@ -82,6 +82,17 @@ const labelStubs = [
     statusLabelKey: "offline_count",
     type: "status",
   },
+  {
+    id: "missing",
+    count: 0,
+    description:
+      "Hosts that have not been seen by Fleet for longer than the missing threshold of the host settings.",
+    display_text: "Missing",
+    slug: "missing",
+    statusLabelKey: "missing_count",
+    title_description: "(offline > missing threshold)",
+    type: "status",
+  },
   {
     id: "mia",
     count: 0,
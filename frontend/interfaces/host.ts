@@ -52,6 +52,7 @@ export default PropTypes.shape({
   additional: PropTypes.object, // eslint-disable-line react/forbid-prop-types
   percent_disk_space_available: PropTypes.number,
   gigs_disk_space_available: PropTypes.number,
+  disk_encryption_enabled: PropTypes.bool,
   labels: PropTypes.arrayOf(labelInterface),
   packs: PropTypes.arrayOf(packInterface),
   software: PropTypes.arrayOf(softwareInterface),
@@ -155,6 +156,7 @@ export interface IHost {
   additional: object; // eslint-disable-line @typescript-eslint/ban-types
   percent_disk_space_available: number;
   gigs_disk_space_available: number;
+  disk_encryption_enabled: boolean | null;
   labels: ILabel[];
   packs: IPack[];
   software: ISoftware[];
@@ -3,6 +3,7 @@ import PropTypes from "prop-types";
 export default PropTypes.shape({
   online_count: PropTypes.number,
   offline_count: PropTypes.number,
+  missing_count: PropTypes.number,
   mia_count: PropTypes.number,
   new_count: PropTypes.number,
 });
@@ -17,6 +18,7 @@ export interface IHostSummary {
   platforms: IHostSummaryPlatforms[] | null;
   online_count: number;
   offline_count: number;
+  missing_count: number;
   mia_count: number;
   new_count: number;
 }
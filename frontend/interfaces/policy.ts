@@ -27,6 +27,10 @@ export interface IPolicy {
   author_email: string;
   resolution: string;
   platform: IPlatformString;
+  critical?: boolean;
+  evaluation_window?: string;
+  remediation_script?: string;
+  auto_remediate?: boolean;
   team_id?: number;
   created_at: string;
   updated_at: string;
@@ -36,6 +40,7 @@ export interface IPolicy {
 export interface IPolicyStats extends IPolicy {
   passing_host_count: number;
   failing_host_count: number;
+  host_count_updated_at: string | null;
   webhook: string;
 }
 
@@ -5,6 +5,7 @@ export interface IStatusLabels {
   new_count: number;
   online_count: number;
   offline_count: number;
+  missing_count: number;
   mia_count: number;
 }
 
@@ -13,5 +14,6 @@ export default PropTypes.shape({
   new_count: PropTypes.number,
   online_count: PropTypes.number,
   offline_count: PropTypes.number,
+  missing_count: PropTypes.number,
   mia_count: PropTypes.number,
 });
@@ -19,6 +19,7 @@ export interface ITargets {
 export interface ITargetsAPIResponse {
   targets: ITargets;
   targets_count: number;
+  targets_missing: number;
   targets_missing_in_action: number;
   targets_offline: number;
   targets_online: number;
@@ -5,6 +5,7 @@ export default PropTypes.shape({
   policy_ids: PropTypes.arrayOf(PropTypes.number),
   enable_failing_policies_webhook: PropTypes.bool,
   host_batch_size: PropTypes.number,
+  max_hosts: PropTypes.number,
 });
 
 export interface IWebhookHostStatus {
@@ -18,6 +19,7 @@ export interface IWebhookFailingPolicies {
   policy_ids?: number[];
   enable_failing_policies_webhook?: boolean;
   host_batch_size?: number;
+  max_hosts?: number;
 }
 
 export interface IWebhookSoftwareVulnerabilities {
@@ -40,6 +40,13 @@ export const HOST_SELECT_STATUSES = [
     value: "new",
     helpText: "Hosts that have been enrolled to Fleet in the last 24 hours.",
   },
+  {
+    disabled: false,
+    label: "Missing hosts",
+    value: "missing",
+    helpText:
+      "Hosts that have not been seen by Fleet for longer than the missing threshold of the host settings.",
+  },
   {
     disabled: false,
     label: "MIA hosts",
@@ -53,6 +60,7 @@ export const isAcceptableStatus = (filter: string): boolean => {
     filter === "new" ||
     filter === "online" ||
     filter === "offline" ||
+    filter === "missing" ||
     filter === "mia"
   );
 };
@@ -10,6 +10,7 @@ export const initialState = {
     loading_counts: false,
     online_count: 0,
     offline_count: 0,
+    missing_count: 0,
     mia_count: 0,
     total_count: 0,
   },
@@ -39,6 +39,7 @@ export default {
       status === "new" ||
       status === "online" ||
       status === "offline" ||
+      status === "missing" ||
       status === "mia";
 
     let queryString = "";
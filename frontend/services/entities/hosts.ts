@@ -88,6 +88,7 @@ export default {
       status === "new" ||
       status === "online" ||
       status === "offline" ||
+      status === "missing" ||
       status === "mia";
 
     if (label) {
@@ -7,7 +7,12 @@ export default {
         bearerToken,
         endpoint: "/api/v1/fleet/host_summary",
         method: "get",
-        response: { online_count: 1, offline_count: 23, mia_count: 2 },
+        response: {
+          online_count: 1,
+          offline_count: 23,
+          missing_count: 0,
+          mia_count: 2,
+        },
       });
     },
   },
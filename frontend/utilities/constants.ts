@@ -225,8 +225,10 @@ export const DEFAULT_POLICY = {
   author_email: "john@example.com",
   resolution: "Resolution steps",
   platform: DEFAULT_POLICY_PLATFORM,
+  critical: false,
   passing_host_count: 2000,
   failing_host_count: 300,
+  host_count_updated_at: null,
   created_at: "",
   updated_at: "",
 };
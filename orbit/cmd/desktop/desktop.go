@@ -17,12 +17,20 @@ import (
 	"github.com/getlantern/systray"
 )
 
+// version is set during build, see the desktop targets of the Makefile.
+var version = "unknown"
+
 func main() {
 	// Our TUF provided targets must support launching with "--help".
 	if len(os.Args) > 1 && os.Args[1] == "--help" {
 		fmt.Println("Fleet Desktop application executable")
 		return
 	}
+	// Orbit reports the version of Fleet Desktop to Fleet.
+	if len(os.Args) > 1 && os.Args[1] == "--version" {
+		fmt.Println("fleet-desktop " + version)
+		return
+	}
 
 	devURL := os.Getenv("FLEET_DESKTOP_DEVICE_URL")
 	if devURL == "" {
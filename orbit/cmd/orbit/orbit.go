@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
@@ -19,11 +20,13 @@ import (
 	"github.com/fleetdm/fleet/v4/orbit/pkg/execuser"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/insecure"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/osquery"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/remediation"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/table"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/update"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/update/filestore"
 	"github.com/fleetdm/fleet/v4/pkg/certificate"
 	"github.com/fleetdm/fleet/v4/pkg/file"
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
 	"github.com/fleetdm/fleet/v4/pkg/secure"
 	"github.com/google/uuid"
 	"github.com/oklog/run"
@@ -137,6 +140,11 @@ func main() {
 			Usage:   "Launch Fleet Desktop application (flag currently only used on darwin)",
 			EnvVars: []string{"ORBIT_FLEET_DESKTOP"},
 		},
+		&cli.BoolFlag{
+			Name:    "enable-scripts",
+			Usage:   "Run the policy remediation scripts sent by the Fleet server",
+			EnvVars: []string{"ORBIT_ENABLE_SCRIPTS"},
+		},
 	}
 	app.Action = func(c *cli.Context) error {
 		if c.Bool("version") {
@@ -239,9 +247,10 @@ func main() {
 		opt.InsecureTransport = c.Bool("insecure")
 
 		var (
-			updater      *update.Updater
-			osquerydPath string
-			desktopPath  string
+			updater         *update.Updater
+			osquerydPath    string
+			desktopPath     string
+			desktopExecPath string
 		)
 
 		// NOTE: When running in dev-mode, even if `disable-updates` is set,
@@ -269,6 +278,7 @@ func main() {
 				} else {
 					desktopPath = fleetDesktopLocalTarget.ExecPath
 				}
+				desktopExecPath = fleetDesktopLocalTarget.ExecPath
 			}
 		} else {
 			log.Info().Msg("running with auto updates disabled")
@@ -289,6 +299,10 @@ func main() {
 						return fmt.Errorf("get desktop target: %w", err)
 					}
 				}
+				desktopExecPath, err = updater.ExecutableLocalPath("desktop")
+				if err != nil {
+					return fmt.Errorf("get desktop target: %w", err)
+				}
 			}
 		}
 
@@ -471,8 +485,14 @@ func main() {
 		}
 		g.Add(r.Execute, r.Interrupt)
 
+		var desktopVersion string
+		if c.Bool("fleet-desktop") && (runtime.GOOS == "darwin" || runtime.GOOS == "windows") {
+			desktopVersion = getDesktopVersion(desktopExecPath)
+		}
+
 		ext := table.NewRunner(r.ExtensionSocketPath(), table.WithExtension(orbitInfoExtension{
 			deviceAuthToken: deviceAuthToken,
+			desktopVersion:  desktopVersion,
 		}))
 		g.Add(ext.Execute, ext.Interrupt)
 
@@ -481,6 +501,36 @@ func main() {
 			g.Add(desktopRunner.actor())
 		}
 
+		if c.Bool("enable-scripts") && fleetURL != "https://" {
+			tlsConfig := &tls.Config{InsecureSkipVerify: c.Bool("insecure")}
+			certPath := c.String("fleet-certificate")
+			if certPath == "" {
+				certPath = filepath.Join(c.String("root-dir"), "certs.pem")
+				if exists, err := file.Exists(certPath); err != nil || !exists {
+					certPath = ""
+				}
+			}
+			if certPath != "" && !c.Bool("insecure") {
+				pool, err := certificate.LoadPEM(certPath)
+				if err != nil {
+					return fmt.Errorf("load certificate: %w", err)
+				}
+				tlsConfig.RootCAs = pool
+			}
+
+			remediationRunner, err := remediation.NewRunner(
+				fleethttp.NewClient(fleethttp.WithTLSClientConfig(tlsConfig), fleethttp.WithTimeout(1*time.Minute)),
+				fleetURL,
+				c.String("enroll-secret"),
+				deviceAuthToken,
+				filepath.Join(c.String("root-dir"), constant.OrbitNodeKeyFileName),
+			)
+			if err != nil {
+				return fmt.Errorf("create remediation runner: %w", err)
+			}
+			g.Add(remediationRunner.Execute, remediationRunner.Interrupt)
+		}
+
 		// Install a signal handler
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
@@ -2,14 +2,21 @@ package main
 
 import (
 	"context"
+	"os/exec"
+	"strings"
 
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
 	orbit_table "github.com/fleetdm/fleet/v4/orbit/pkg/table"
 	"github.com/osquery/osquery-go/plugin/table"
+	"github.com/rs/zerolog/log"
 )
 
 // orbitInfoExtension implements an extension table that provides info about Orbit.
 type orbitInfoExtension struct {
 	deviceAuthToken string
+	// desktopVersion is the version of Fleet Desktop, empty if Fleet Desktop
+	// is not enabled.
+	desktopVersion string
 }
 
 var _ orbit_table.Extension = orbitInfoExtension{}
@@ -24,6 +31,7 @@ func (o orbitInfoExtension) Columns() []table.ColumnDefinition {
 	return []table.ColumnDefinition{
 		table.TextColumn("version"),
 		table.TextColumn("device_auth_token"),
+		table.TextColumn("desktop_version"),
 	}
 }
 
@@ -37,6 +45,24 @@ func (o orbitInfoExtension) GenerateFunc(_ context.Context, _ table.QueryContext
 		{
 			"version":           v,
 			"device_auth_token": o.deviceAuthToken,
+			"desktop_version":   o.desktopVersion,
 		},
 	}, nil
 }
+
+// getDesktopVersion returns the version of the Fleet Desktop executable, or
+// "unknown" if the executable does not report it (as with the versions of
+// Fleet Desktop that don't support the --version flag).
+func getDesktopVersion(execPath string) string {
+	/* #nosec G204 -- the path is the one of the desktop target */
+	out, err := exec.Command(execPath, "--version").Output()
+	if err != nil {
+		log.Debug().Err(err).Msg("get fleet-desktop version")
+		return "unknown"
+	}
+	v := strings.TrimPrefix(strings.TrimSpace(string(out)), constant.DesktopAppExecName+" ")
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
@@ -10,4 +10,7 @@ const (
 	// We use fleet-desktop as name to properly identify the process when listing
 	// running processes/tasks.
 	DesktopAppExecName = "fleet-desktop"
+	// OrbitNodeKeyFileName is the name of the file, in the root directory, that
+	// stores the orbit node key used to authenticate to Fleet.
+	OrbitNodeKeyFileName = "secret-orbit-node-key.txt"
 )
@@ -0,0 +1,315 @@
+// Package remediation implements a runner that executes the policy
+// remediation scripts that Fleet queued for the host and reports their
+// results.
+//
+// The runner authenticates with an orbit node key, obtained by enrolling with
+// the enroll secret and the device auth token of the host, and kept in a file
+// readable only by root. The device auth token alone cannot be used to report
+// results, as it is also known to the unprivileged users of the host.
+package remediation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultInterval = 1 * time.Minute
+	defaultTimeout  = 5 * time.Minute
+	// maxOutputSize is the maximum size of the output reported to Fleet, the
+	// server truncates longer outputs anyway.
+	maxOutputSize = 10000
+)
+
+// Runner periodically fetches the pending remediations of the host and runs
+// them. It is designed with Execute and Interrupt functions to be compatible
+// with oklog/run.
+type Runner struct {
+	client          *http.Client
+	baseURL         *url.URL
+	enrollSecret    string
+	deviceAuthToken string
+	nodeKeyPath     string
+	nodeKey         string
+	interval        time.Duration
+	timeout         time.Duration
+
+	interruptCh   chan struct{} // closed when interrupt is triggered
+	executeDoneCh chan struct{} // closed when execute returns
+}
+
+// Option allows configuring a Runner.
+type Option func(*Runner)
+
+// WithInterval sets the interval at which the pending remediations are
+// fetched.
+func WithInterval(d time.Duration) Option {
+	return func(r *Runner) {
+		r.interval = d
+	}
+}
+
+// WithTimeout sets the maximum duration of a remediation script.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		r.timeout = d
+	}
+}
+
+// NewRunner creates a remediation runner for the device identified by the
+// given device authentication token. The orbit node key is stored in the file
+// at nodeKeyPath, the runner enrolls with the enroll secret to get a new one
+// if the file does not exist or the node key is rejected.
+func NewRunner(client *http.Client, fleetURL, enrollSecret, deviceAuthToken, nodeKeyPath string, opts ...Option) (*Runner, error) {
+	baseURL, err := url.Parse(fleetURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid fleet-url: %w", err)
+	}
+	baseURL.Path = path.Join(baseURL.Path, "api", "latest", "fleet", "orbit")
+
+	nodeKey, err := ioutil.ReadFile(nodeKeyPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("read orbit node key file %q: %w", nodeKeyPath, err)
+	}
+
+	r := &Runner{
+		client:          client,
+		baseURL:         baseURL,
+		enrollSecret:    enrollSecret,
+		deviceAuthToken: deviceAuthToken,
+		nodeKeyPath:     nodeKeyPath,
+		nodeKey:         strings.TrimSpace(string(nodeKey)),
+		interval:        defaultInterval,
+		timeout:         defaultTimeout,
+		interruptCh:     make(chan struct{}),
+		executeDoneCh:   make(chan struct{}),
+	}
+	for _, fn := range opts {
+		fn(r)
+	}
+	return r, nil
+}
+
+// Execute runs the pending remediations until the runner is interrupted.
+func (r *Runner) Execute() error {
+	defer close(r.executeDoneCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-r.interruptCh
+		cancel()
+	}()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if err := r.runPending(ctx); err != nil {
+			log.Info().Err(err).Msg("run policy remediations")
+		}
+		select {
+		case <-r.interruptCh:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Interrupt stops the runner, killing the script being executed if any.
+func (r *Runner) Interrupt(err error) {
+	log.Debug().Err(err).Msg("interrupt remediation runner")
+
+	close(r.interruptCh) // Signal execute to return.
+	<-r.executeDoneCh    // Wait for execute to return.
+}
+
+type remediation struct {
+	ID       uint   `json:"id"`
+	PolicyID uint   `json:"policy_id"`
+	Script   string `json:"script"`
+}
+
+type result struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	ExitCode     int    `json:"exit_code"`
+	Output       string `json:"output"`
+}
+
+// errUnauthorized is returned when Fleet rejects the orbit node key.
+var errUnauthorized = errors.New("unauthorized")
+
+// url returns the URL of the orbit API at the provided path.
+func (r *Runner) url(elem ...string) string {
+	u := *r.baseURL
+	u.Path = path.Join(append([]string{u.Path}, elem...)...)
+	return u.String()
+}
+
+// enroll gets a new orbit node key from Fleet and stores it. It fails until
+// osquery reported the device auth token of the host to Fleet.
+func (r *Runner) enroll(ctx context.Context) error {
+	var resp struct {
+		OrbitNodeKey string `json:"orbit_node_key"`
+	}
+	req := struct {
+		EnrollSecret    string `json:"enroll_secret"`
+		DeviceAuthToken string `json:"device_auth_token"`
+	}{r.enrollSecret, r.deviceAuthToken}
+	if err := r.do(ctx, http.MethodPost, r.url("enroll"), req, &resp); err != nil {
+		return err
+	}
+	if resp.OrbitNodeKey == "" {
+		return errors.New("empty orbit node key")
+	}
+	if err := ioutil.WriteFile(r.nodeKeyPath, []byte(resp.OrbitNodeKey), constant.DefaultFileMode); err != nil {
+		return fmt.Errorf("write orbit node key file %q: %w", r.nodeKeyPath, err)
+	}
+	r.nodeKey = resp.OrbitNodeKey
+	return nil
+}
+
+func (r *Runner) runPending(ctx context.Context) error {
+	if r.nodeKey == "" {
+		if err := r.enroll(ctx); err != nil {
+			return fmt.Errorf("enroll: %w", err)
+		}
+	}
+
+	err := r.runRemediations(ctx)
+	if errors.Is(err, errUnauthorized) {
+		// enroll again on the next run, e.g. if the host was deleted
+		r.nodeKey = ""
+	}
+	return err
+}
+
+func (r *Runner) runRemediations(ctx context.Context) error {
+	var resp struct {
+		Remediations []remediation `json:"remediations"`
+	}
+	req := struct {
+		OrbitNodeKey string `json:"orbit_node_key"`
+	}{r.nodeKey}
+	if err := r.do(ctx, http.MethodPost, r.url("policy_remediations"), req, &resp); err != nil {
+		return fmt.Errorf("list pending remediations: %w", err)
+	}
+
+	for _, rem := range resp.Remediations {
+		log.Info().Uint("id", rem.ID).Uint("policy_id", rem.PolicyID).Msg("running policy remediation")
+		exitCode, output := r.runScript(ctx, rem.Script)
+		if ctx.Err() != nil {
+			// interrupted, the remediation is left pending to be run again
+			return nil
+		}
+
+		resultURL := r.url("policy_remediations", strconv.FormatUint(uint64(rem.ID), 10), "result")
+		res := result{OrbitNodeKey: r.nodeKey, ExitCode: exitCode, Output: output}
+		if err := r.do(ctx, http.MethodPost, resultURL, res, nil); err != nil {
+			return fmt.Errorf("set remediation %d result: %w", rem.ID, err)
+		}
+	}
+	return nil
+}
+
+// runScript runs the script with the system shell and returns its exit code
+// and combined output. The exit code is -1 if the script could not be run to
+// completion.
+func (r *Runner) runScript(ctx context.Context, script string) (int, string) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	cmd := scriptCommand(script)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Start(); err != nil {
+		return -1, err.Error()
+	}
+	waitCh := make(chan error, 1)
+	go func() {
+		waitCh <- cmd.Wait()
+	}()
+
+	var err error
+	select {
+	case err = <-waitCh:
+	case <-ctx.Done():
+		if err := killScript(cmd); err != nil {
+			log.Info().Err(err).Msg("kill remediation script")
+		}
+		err = <-waitCh
+	}
+
+	out := output.String()
+	if len(out) > maxOutputSize {
+		out = out[:maxOutputSize]
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return 0, out
+	case ctx.Err() != nil:
+		return -1, out + "\n" + "remediation script timed out"
+	case errors.As(err, &exitErr):
+		return exitErr.ExitCode(), out
+	default:
+		return -1, out + "\n" + err.Error()
+	}
+}
+
+func (r *Runner) do(ctx context.Context, method, url string, body, dst interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("do request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		return errUnauthorized
+	default:
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	if dst != nil {
+		if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
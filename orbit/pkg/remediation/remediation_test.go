@@ -0,0 +1,96 @@
+package remediation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPending(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test scripts use sh")
+	}
+
+	var mu sync.Mutex
+	results := make(map[string]result)
+	var enrolls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/latest/fleet/orbit/enroll":
+			var req struct {
+				EnrollSecret    string `json:"enroll_secret"`
+				DeviceAuthToken string `json:"device_auth_token"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "secret", req.EnrollSecret)
+			assert.Equal(t, "token", req.DeviceAuthToken)
+			enrolls++
+			_, _ = w.Write([]byte(`{"orbit_node_key": "node_key"}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/latest/fleet/orbit/policy_remediations":
+			var req struct {
+				OrbitNodeKey string `json:"orbit_node_key"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			if req.OrbitNodeKey != "node_key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"remediations": [
+				{"id": 1, "policy_id": 1, "script": "echo fixed"},
+				{"id": 2, "policy_id": 2, "script": "echo oops >&2; exit 3"},
+				{"id": 3, "policy_id": 3, "script": "sleep 10"}
+			]}`))
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/result"):
+			var res result
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+			mu.Lock()
+			results[r.URL.Path] = res
+			mu.Unlock()
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	// a stale node key is replaced by enrolling again
+	nodeKeyPath := filepath.Join(t.TempDir(), "secret-orbit-node-key.txt")
+	require.NoError(t, os.WriteFile(nodeKeyPath, []byte("stale"), 0o600))
+
+	r, err := NewRunner(srv.Client(), srv.URL, "secret", "token", nodeKeyPath, WithTimeout(500*time.Millisecond))
+	require.NoError(t, err)
+	require.ErrorIs(t, r.runPending(context.Background()), errUnauthorized)
+	assert.Zero(t, enrolls)
+	require.NoError(t, r.runPending(context.Background()))
+	assert.Equal(t, 1, enrolls)
+
+	// the node key is stored for root only
+	b, err := os.ReadFile(nodeKeyPath)
+	require.NoError(t, err)
+	assert.Equal(t, "node_key", string(b))
+	info, err := os.Stat(nodeKeyPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	prefix := "/api/latest/fleet/orbit/policy_remediations/"
+	assert.Equal(t, result{OrbitNodeKey: "node_key", ExitCode: 0, Output: "fixed\n"}, results[prefix+"1/result"])
+	assert.Equal(t, result{OrbitNodeKey: "node_key", ExitCode: 3, Output: "oops\n"}, results[prefix+"2/result"])
+	assert.Equal(t, -1, results[prefix+"3/result"].ExitCode)
+	assert.Contains(t, results[prefix+"3/result"].Output, "timed out")
+
+	// the stored node key is used by new runners
+	r, err = NewRunner(srv.Client(), srv.URL, "secret", "token", nodeKeyPath, WithTimeout(500*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, "node_key", r.nodeKey)
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package remediation
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+func scriptCommand(script string) *exec.Cmd {
+	cmd := exec.Command("/bin/sh", "-c", script)
+	// run the script in its own process group so that the processes it
+	// starts are killed with it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+func killScript(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package remediation
+
+import "os/exec"
+
+func scriptCommand(script string) *exec.Cmd {
+	return exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", script)
+}
+
+func killScript(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
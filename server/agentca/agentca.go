@@ -0,0 +1,248 @@
+// Package agentca implements a minimal certificate authority so that osquery
+// agents can be issued client certificates at enrollment time without an
+// external PKI.
+//
+// This is not a SCEP server: requests and responses are not wrapped in PKCS#7
+// envelopes. The CA certificate is served as DER, and certificates are issued
+// for DER or PEM encoded PKCS#10 certificate signing requests, responding with
+// the DER encoded signed certificate. As neither osquery nor orbit request
+// certificates yet, the client certificates are not required on the osquery
+// endpoints.
+package agentca
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	// DefaultValidity is the validity period of issued certificates used when
+	// none is configured.
+	DefaultValidity = 365 * 24 * time.Hour
+
+	// maxCSRSize is the maximum size of a certificate signing request.
+	maxCSRSize = 64 * 1024
+
+	// serialBits is the size of the random serial numbers of issued
+	// certificates.
+	serialBits = 128
+)
+
+var oidChallengePassword = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 7}
+
+// ErrInvalidChallenge is returned when the challenge password of a
+// certificate signing request is missing or rejected.
+var ErrInvalidChallenge = errors.New("invalid challenge password")
+
+// ChallengeVerifier verifies the challenge password sent in a certificate
+// signing request.
+type ChallengeVerifier func(ctx context.Context, challenge string) error
+
+// CA is a certificate authority that signs agent client certificates.
+type CA struct {
+	cert     *x509.Certificate
+	key      crypto.Signer
+	validity time.Duration
+}
+
+// LoadCA loads the CA certificate and private key from the provided PEM
+// files. If validity is zero, DefaultValidity is used.
+func LoadCA(certPath, keyPath string, validity time.Duration) (*CA, error) {
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("load CA key pair: %w", err)
+	}
+	return newCA(pair, validity)
+}
+
+func newCA(pair tls.Certificate, validity time.Duration) (*CA, error) {
+	if len(pair.Certificate) == 0 {
+		return nil, errors.New("no CA certificate found")
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse CA certificate: %w", err)
+	}
+	if !cert.IsCA {
+		return nil, errors.New("certificate is not a CA")
+	}
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("CA private key is not a signer")
+	}
+	if validity <= 0 {
+		validity = DefaultValidity
+	}
+	return &CA{cert: cert, key: key, validity: validity}, nil
+}
+
+// Certificate returns the CA certificate.
+func (ca *CA) Certificate() *x509.Certificate {
+	return ca.cert
+}
+
+// CertPool returns a certificate pool containing only the CA certificate,
+// suitable to verify client certificates issued by the CA.
+func (ca *CA) CertPool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// newSerial returns a random serial number, so that serials are unique
+// across restarts and across Fleet instances sharing the same CA.
+func newSerial() (*big.Int, error) {
+	for {
+		serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), serialBits))
+		if err != nil {
+			return nil, err
+		}
+		// serial numbers must be positive
+		if serial.Sign() > 0 {
+			return serial, nil
+		}
+	}
+}
+
+// Sign issues a client certificate for the provided certificate signing
+// request. The subject of the request is kept as is.
+func (ca *CA) Sign(csr *x509.CertificateRequest) (*x509.Certificate, error) {
+	if err := csr.CheckSignature(); err != nil {
+		return nil, fmt.Errorf("check CSR signature: %w", err)
+	}
+	if csr.Subject.CommonName == "" {
+		return nil, errors.New("CSR common name is required")
+	}
+
+	serial, err := newSerial()
+	if err != nil {
+		return nil, fmt.Errorf("generate serial: %w", err)
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      csr.Subject,
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(ca.validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		DNSNames:     csr.DNSNames,
+	}
+	if tmpl.NotAfter.After(ca.cert.NotAfter) {
+		tmpl.NotAfter = ca.cert.NotAfter
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, csr.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("create certificate: %w", err)
+	}
+	return x509.ParseCertificate(der)
+}
+
+// challengePassword extracts the PKCS#9 challengePassword attribute from the
+// certificate signing request, as the standard library does not expose it.
+func challengePassword(csr *x509.CertificateRequest) (string, error) {
+	var info struct {
+		Version    int
+		Subject    asn1.RawValue
+		PublicKey  asn1.RawValue
+		Attributes []struct {
+			Type   asn1.ObjectIdentifier
+			Values []asn1.RawValue `asn1:"set"`
+		} `asn1:"optional,tag:0"`
+	}
+	if _, err := asn1.Unmarshal(csr.RawTBSCertificateRequest, &info); err != nil {
+		return "", fmt.Errorf("parse CSR attributes: %w", err)
+	}
+	for _, attr := range info.Attributes {
+		if !attr.Type.Equal(oidChallengePassword) || len(attr.Values) == 0 {
+			continue
+		}
+		var challenge string
+		if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &challenge); err != nil {
+			return "", fmt.Errorf("parse challenge password: %w", err)
+		}
+		return challenge, nil
+	}
+	return "", nil
+}
+
+func parseCSR(b []byte) (*x509.CertificateRequest, error) {
+	if block, _ := pem.Decode(b); block != nil {
+		b = block.Bytes
+	}
+	return x509.ParseCertificateRequest(b)
+}
+
+// Handler returns the http.Handler serving the CA certificate on GET
+// /ca_certificate and issuing certificates on POST /certificate. Every
+// certificate signing request must carry a challenge password accepted by
+// verify.
+func Handler(ca *CA, verify ChallengeVerifier, logger log.Logger) http.Handler {
+	logger = log.With(logger, "component", "agentca")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ca_certificate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pkix-cert")
+		w.Write(ca.cert.Raw)
+	})
+	mux.HandleFunc("/certificate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxCSRSize))
+		if err != nil {
+			http.Error(w, "read request body", http.StatusBadRequest)
+			return
+		}
+		csr, err := parseCSR(body)
+		if err != nil {
+			http.Error(w, "invalid certificate signing request", http.StatusBadRequest)
+			return
+		}
+		challenge, err := challengePassword(csr)
+		if err != nil {
+			http.Error(w, "invalid certificate signing request", http.StatusBadRequest)
+			return
+		}
+		if challenge == "" {
+			http.Error(w, ErrInvalidChallenge.Error(), http.StatusForbidden)
+			return
+		}
+		if err := verify(r.Context(), challenge); err != nil {
+			level.Info(logger).Log("msg", "rejected certificate request", "cn", csr.Subject.CommonName, "err", err)
+			http.Error(w, ErrInvalidChallenge.Error(), http.StatusForbidden)
+			return
+		}
+		cert, err := ca.Sign(csr)
+		if err != nil {
+			level.Info(logger).Log("msg", "failed to sign certificate request", "cn", csr.Subject.CommonName, "err", err)
+			http.Error(w, "unable to sign certificate request", http.StatusBadRequest)
+			return
+		}
+		level.Debug(logger).Log("msg", "issued certificate", "cn", cert.Subject.CommonName, "serial", cert.SerialNumber.String())
+		w.Header().Set("Content-Type", "application/pkix-cert")
+		w.Write(cert.Raw)
+	})
+	return mux
+}
@@ -0,0 +1,197 @@
+package agentca
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCA(t *testing.T) *CA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Fleet Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	require.NoError(t, err)
+
+	ca, err := newCA(tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, time.Hour)
+	require.NoError(t, err)
+	return ca
+}
+
+// newTestCSR builds a DER encoded CSR with the provided challenge password
+// attribute, which the standard library cannot encode.
+func newTestCSR(t *testing.T, cn, challenge string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	base, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: cn},
+	}, key)
+	require.NoError(t, err)
+	parsed, err := x509.ParseCertificateRequest(base)
+	require.NoError(t, err)
+
+	type attribute struct {
+		Type   asn1.ObjectIdentifier
+		Values []asn1.RawValue `asn1:"set"`
+	}
+	info := struct {
+		Version    int
+		Subject    asn1.RawValue
+		PublicKey  asn1.RawValue
+		Attributes []attribute `asn1:"tag:0"`
+	}{
+		Subject:    asn1.RawValue{FullBytes: parsed.RawSubject},
+		PublicKey:  asn1.RawValue{FullBytes: parsed.RawSubjectPublicKeyInfo},
+		Attributes: []attribute{},
+	}
+	if challenge != "" {
+		info.Attributes = append(info.Attributes, attribute{
+			Type:   oidChallengePassword,
+			Values: []asn1.RawValue{{Tag: asn1.TagUTF8String, Bytes: []byte(challenge)}},
+		})
+	}
+	tbs, err := asn1.Marshal(info)
+	require.NoError(t, err)
+
+	digest := sha256.Sum256(tbs)
+	sig, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+
+	der, err := asn1.Marshal(struct {
+		Info      asn1.RawValue
+		Algorithm pkix.AlgorithmIdentifier
+		Signature asn1.BitString
+	}{
+		Info:      asn1.RawValue{FullBytes: tbs},
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}},
+		Signature: asn1.BitString{Bytes: sig, BitLength: len(sig) * 8},
+	})
+	require.NoError(t, err)
+	return der
+}
+
+func TestChallengePassword(t *testing.T) {
+	csr, err := x509.ParseCertificateRequest(newTestCSR(t, "host1", "s3cret"))
+	require.NoError(t, err)
+	challenge, err := challengePassword(csr)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", challenge)
+
+	csr, err = x509.ParseCertificateRequest(newTestCSR(t, "host1", ""))
+	require.NoError(t, err)
+	challenge, err = challengePassword(csr)
+	require.NoError(t, err)
+	assert.Empty(t, challenge)
+}
+
+func TestSign(t *testing.T) {
+	ca := newTestCA(t)
+	csr, err := x509.ParseCertificateRequest(newTestCSR(t, "host1", "s3cret"))
+	require.NoError(t, err)
+
+	cert, err := ca.Sign(csr)
+	require.NoError(t, err)
+	assert.Equal(t, "host1", cert.Subject.CommonName)
+	assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, cert.ExtKeyUsage)
+
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:     ca.CertPool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	require.NoError(t, err)
+
+	// serials are random and unique
+	cert2, err := ca.Sign(csr)
+	require.NoError(t, err)
+	assert.NotEqual(t, cert.SerialNumber, cert2.SerialNumber)
+	for _, c := range []*x509.Certificate{cert, cert2} {
+		assert.Equal(t, 1, c.SerialNumber.Sign())
+		assert.LessOrEqual(t, c.SerialNumber.BitLen(), serialBits)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ca := newTestCA(t)
+	verify := func(ctx context.Context, challenge string) error {
+		if challenge != "s3cret" {
+			return errors.New("nope")
+		}
+		return nil
+	}
+	srv := httptest.NewServer(Handler(ca, verify, log.NewNopLogger()))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/ca_certificate")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, ca.Certificate().Raw, buf.Bytes())
+
+	cases := []struct {
+		name      string
+		challenge string
+		status    int
+	}{
+		{"valid challenge", "s3cret", http.StatusOK},
+		{"invalid challenge", "wrong", http.StatusForbidden},
+		{"missing challenge", "", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/certificate", "application/pkcs10", bytes.NewReader(newTestCSR(t, "host1", c.challenge)))
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			require.Equal(t, c.status, resp.StatusCode)
+			if c.status != http.StatusOK {
+				return
+			}
+			var buf bytes.Buffer
+			_, err = buf.ReadFrom(resp.Body)
+			require.NoError(t, err)
+			cert, err := x509.ParseCertificate(buf.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, "host1", cert.Subject.CommonName)
+		})
+	}
+
+	resp, err = http.Post(srv.URL+"/certificate", "application/pkcs10", bytes.NewReader([]byte("garbage")))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "/certificate")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -257,10 +257,27 @@ allow {
 # Queries
 ##
 
-# All users can read queries
+# All users can read global queries
 allow {
   not is_null(subject)
   object.type == "query"
+  is_null(object.team_id)
+  action == read
+}
+
+# Global users can read all team queries
+allow {
+  not is_null(object.team_id)
+  object.type == "query"
+  not is_null(subject.global_role)
+  action == read
+}
+
+# Team members can read the queries of their teams
+allow {
+  not is_null(object.team_id)
+  object.type == "query"
+  team_role(subject, object.team_id) == [admin,maintainer,observer][_]
   action == read
 }
 
@@ -276,22 +293,33 @@ allow {
   action == write
 }
 
-# Team admins and maintainers can create new queries
+# Team admins and maintainers can create new global queries
 allow {
   object.id == 0 # new queries have ID zero
+  is_null(object.team_id)
   object.type == "query"
   team_role(subject, subject.teams[_].id) == [admin, maintainer][_]
   action == write
 }
 
-# Team admins and maintainers can edit and delete only their own queries
+# Team admins and maintainers can edit and delete only their own global queries
 allow {
   object.author_id == subject.id
+  is_null(object.team_id)
   object.type == "query"
   team_role(subject, subject.teams[_].id) == [admin,maintainer][_]
   action == write
 }
 
+# Team admins and maintainers can create, edit and delete the queries of their
+# teams
+allow {
+  not is_null(object.team_id)
+  object.type == "query"
+  team_role(subject, object.team_id) == [admin,maintainer][_]
+  action == write
+}
+
 # Global admins and maintainers can run any
 allow {
   object.type == "targeted_query"
@@ -487,3 +515,185 @@ allow {
   object.type == "software"
   action == read
 }
+
+##
+# Dashboards
+##
+
+# Any logged in user can list dashboards (must be filtered appropriately by the
+# service).
+allow {
+  not is_null(subject)
+  object.type == "dashboard"
+  action == list
+}
+
+# Users can read/write their own global dashboards
+allow {
+  object.type == "dashboard"
+  object.user_id == subject.id
+  is_null(object.team_id)
+  action == [read, write][_]
+}
+
+# Users can read/write their own team dashboards if they have access to the
+# team.
+allow {
+  object.type == "dashboard"
+  object.user_id == subject.id
+  not is_null(object.team_id)
+  not is_null(subject.global_role)
+  action == [read, write][_]
+}
+allow {
+  object.type == "dashboard"
+  object.user_id == subject.id
+  not is_null(object.team_id)
+  team_role(subject, object.team_id) == [admin,maintainer,observer][_]
+  action == [read, write][_]
+}
+
+# Any logged in user can read shared global dashboards
+allow {
+  not is_null(subject)
+  object.type == "dashboard"
+  object.shared == true
+  is_null(object.team_id)
+  action == read
+}
+
+# Global users can read all shared team dashboards
+allow {
+  object.type == "dashboard"
+  object.shared == true
+  not is_null(subject.global_role)
+  action == read
+}
+
+# Team members can read shared dashboards of their teams
+allow {
+  object.type == "dashboard"
+  object.shared == true
+  not is_null(object.team_id)
+  team_role(subject, object.team_id) == [admin,maintainer,observer][_]
+  action == read
+}
+
+# Global admins can write all shared dashboards
+allow {
+  object.type == "dashboard"
+  object.shared == true
+  subject.global_role == admin
+  action == write
+}
+
+# Team admins can write shared dashboards of their teams
+allow {
+  object.type == "dashboard"
+  object.shared == true
+  not is_null(object.team_id)
+  team_role(subject, object.team_id) == admin
+  action == write
+}
+
+##
+# ATC tables
+##
+
+# Global admins and maintainers can read/write ATC tables
+allow {
+  object.type == "atc_table"
+  subject.global_role == [admin,maintainer][_]
+  action == [read, write][_]
+}
+
+# Global observers can read ATC tables
+allow {
+  object.type == "atc_table"
+  subject.global_role == observer
+  action == read
+}
+
+##
+# FIM categories
+##
+
+# Global admins and maintainers can read/write FIM categories
+allow {
+  object.type == "fim_category"
+  subject.global_role == [admin,maintainer][_]
+  action == [read, write][_]
+}
+
+# Global observers can read FIM categories
+allow {
+  object.type == "fim_category"
+  subject.global_role == observer
+  action == read
+}
+
+# Team admins and maintainers can read/write the FIM categories of their teams
+allow {
+  not is_null(object.team_id)
+  object.type == "fim_category"
+  team_role(subject, object.team_id) == [admin,maintainer][_]
+  action == [read, write][_]
+}
+
+# Team observers can read the FIM categories of their teams
+allow {
+  not is_null(object.team_id)
+  object.type == "fim_category"
+  team_role(subject, object.team_id) == observer
+  action == read
+}
+
+# Team members can read global FIM categories
+allow {
+  is_null(object.team_id)
+  object.type == "fim_category"
+  team_role(subject, subject.teams[_].id) == [admin,maintainer,observer][_]
+  action == read
+}
+
+##
+# YARA signature groups
+##
+
+# Global admins and maintainers can read/write YARA signature groups
+allow {
+  object.type == "yara_signature_group"
+  subject.global_role == [admin,maintainer][_]
+  action == [read, write][_]
+}
+
+# Global observers can read YARA signature groups
+allow {
+  object.type == "yara_signature_group"
+  subject.global_role == observer
+  action == read
+}
+
+# Team admins and maintainers can read/write the YARA signature groups of their teams
+allow {
+  not is_null(object.team_id)
+  object.type == "yara_signature_group"
+  team_role(subject, object.team_id) == [admin,maintainer][_]
+  action == [read, write][_]
+}
+
+# Team observers can read the YARA signature groups of their teams
+allow {
+  not is_null(object.team_id)
+  object.type == "yara_signature_group"
+  team_role(subject, object.team_id) == observer
+  action == read
+}
+
+# Team members can read global YARA signature groups
+allow {
+  is_null(object.team_id)
+  object.type == "yara_signature_group"
+  team_role(subject, subject.teams[_].id) == [admin,maintainer,observer][_]
+  action == read
+}
@@ -576,6 +576,40 @@ func TestAuthorizeQuery(t *testing.T) {
 	})
 }
 
+func TestAuthorizeTeamQueries(t *testing.T) {
+	t.Parallel()
+
+	newTeam1Query := &fleet.Query{TeamID: ptr.Uint(1)}
+	team1Query := &fleet.Query{ID: 1, AuthorID: ptr.Uint(test.UserAdmin.ID), TeamID: ptr.Uint(1)}
+	// authored by the team 1 admin, but belongs to team 2
+	team2Query := &fleet.Query{ID: 2, AuthorID: ptr.Uint(test.UserTeamAdminTeam1.ID), TeamID: ptr.Uint(2)}
+
+	runTestCases(t, []authTestCase{
+		{user: nil, object: team1Query, action: read, allow: false},
+		{user: test.UserNoRoles, object: team1Query, action: read, allow: false},
+		{user: test.UserNoRoles, object: newTeam1Query, action: write, allow: false},
+
+		{user: test.UserAdmin, object: team1Query, action: read, allow: true},
+		{user: test.UserAdmin, object: team1Query, action: write, allow: true},
+		{user: test.UserMaintainer, object: newTeam1Query, action: write, allow: true},
+		{user: test.UserObserver, object: team1Query, action: read, allow: true},
+		{user: test.UserObserver, object: team1Query, action: write, allow: false},
+
+		{user: test.UserTeamAdminTeam1, object: newTeam1Query, action: write, allow: true},
+		{user: test.UserTeamAdminTeam1, object: team1Query, action: read, allow: true},
+		{user: test.UserTeamAdminTeam1, object: team1Query, action: write, allow: true},
+		{user: test.UserTeamAdminTeam1, object: team2Query, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: team2Query, action: write, allow: false},
+		{user: test.UserTeamMaintainerTeam1, object: team1Query, action: write, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1Query, action: read, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1Query, action: write, allow: false},
+		{user: test.UserTeamObserverTeam1, object: newTeam1Query, action: write, allow: false},
+		{user: test.UserTeamAdminTeam2, object: newTeam1Query, action: write, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1Query, action: read, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team2Query, action: write, allow: true},
+	})
+}
+
 func TestAuthorizeTargets(t *testing.T) {
 	t.Parallel()
 
@@ -769,6 +803,132 @@ func assertUnauthorized(t *testing.T, user *fleet.User, object, action interface
 	assert.Error(t, auth.Authorize(test.UserContext(user), object, action), "should be unauthorized\n%s", string(b))
 }
 
+func TestAuthorizeDashboards(t *testing.T) {
+	t.Parallel()
+
+	// owned by the team 1 maintainer
+	privateGlobal := &fleet.Dashboard{UserID: test.UserTeamMaintainerTeam1.ID}
+	sharedGlobal := &fleet.Dashboard{UserID: test.UserTeamMaintainerTeam1.ID, Shared: true}
+	privateTeam1 := &fleet.Dashboard{UserID: test.UserTeamMaintainerTeam1.ID, TeamID: ptr.Uint(1)}
+	sharedTeam1 := &fleet.Dashboard{UserID: test.UserTeamMaintainerTeam1.ID, TeamID: ptr.Uint(1), Shared: true}
+	// owned by the team 2 admin
+	ownTeam1 := &fleet.Dashboard{UserID: test.UserTeamAdminTeam2.ID, TeamID: ptr.Uint(1)}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: &fleet.Dashboard{}, action: list, allow: false},
+		{user: nil, object: sharedGlobal, action: read, allow: false},
+		{user: test.UserNoRoles, object: &fleet.Dashboard{}, action: list, allow: true},
+
+		{user: test.UserTeamMaintainerTeam1, object: privateGlobal, action: read, allow: true},
+		{user: test.UserTeamMaintainerTeam1, object: privateGlobal, action: write, allow: true},
+		{user: test.UserTeamMaintainerTeam1, object: privateTeam1, action: write, allow: true},
+		{user: test.UserTeamMaintainerTeam1, object: sharedTeam1, action: write, allow: true},
+
+		{user: test.UserAdmin, object: privateGlobal, action: read, allow: false},
+		{user: test.UserAdmin, object: privateTeam1, action: read, allow: false},
+		{user: test.UserAdmin, object: sharedGlobal, action: write, allow: true},
+		{user: test.UserAdmin, object: sharedTeam1, action: write, allow: true},
+		{user: test.UserObserver, object: sharedTeam1, action: read, allow: true},
+		{user: test.UserObserver, object: sharedTeam1, action: write, allow: false},
+		{user: test.UserMaintainer, object: sharedGlobal, action: write, allow: false},
+
+		{user: test.UserTeamObserverTeam1, object: sharedGlobal, action: read, allow: true},
+		{user: test.UserTeamObserverTeam1, object: sharedTeam1, action: read, allow: true},
+		{user: test.UserTeamObserverTeam1, object: sharedTeam1, action: write, allow: false},
+		{user: test.UserTeamObserverTeam1, object: privateTeam1, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: sharedTeam1, action: write, allow: true},
+		{user: test.UserTeamAdminTeam1, object: privateTeam1, action: write, allow: false},
+		{user: test.UserTeamAdminTeam2, object: sharedTeam1, action: read, allow: false},
+
+		// owners must have access to the team of their dashboards
+		{user: test.UserTeamAdminTeam2, object: ownTeam1, action: read, allow: false},
+		{user: test.UserTeamAdminTeam2, object: ownTeam1, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeATCTables(t *testing.T) {
+	t.Parallel()
+
+	table := &fleet.ATCTable{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: table, action: read, allow: false},
+		{user: nil, object: table, action: write, allow: false},
+		{user: test.UserNoRoles, object: table, action: read, allow: false},
+		{user: test.UserNoRoles, object: table, action: write, allow: false},
+
+		{user: test.UserAdmin, object: table, action: read, allow: true},
+		{user: test.UserAdmin, object: table, action: write, allow: true},
+		{user: test.UserMaintainer, object: table, action: read, allow: true},
+		{user: test.UserMaintainer, object: table, action: write, allow: true},
+		{user: test.UserObserver, object: table, action: read, allow: true},
+		{user: test.UserObserver, object: table, action: write, allow: false},
+
+		{user: test.UserTeamAdminTeam1, object: table, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: table, action: write, allow: false},
+		{user: test.UserTeamMaintainerTeam1, object: table, action: read, allow: false},
+		{user: test.UserTeamObserverTeam1, object: table, action: read, allow: false},
+	})
+}
+
+func TestAuthorizeFIMCategories(t *testing.T) {
+	t.Parallel()
+
+	global := &fleet.FIMCategory{}
+	team1 := &fleet.FIMCategory{TeamID: ptr.Uint(1)}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: global, action: read, allow: false},
+		{user: nil, object: team1, action: write, allow: false},
+		{user: test.UserNoRoles, object: global, action: read, allow: false},
+		{user: test.UserNoRoles, object: team1, action: read, allow: false},
+
+		{user: test.UserAdmin, object: global, action: write, allow: true},
+		{user: test.UserAdmin, object: team1, action: write, allow: true},
+		{user: test.UserMaintainer, object: global, action: write, allow: true},
+		{user: test.UserMaintainer, object: team1, action: write, allow: true},
+		{user: test.UserObserver, object: global, action: read, allow: true},
+		{user: test.UserObserver, object: team1, action: read, allow: true},
+		{user: test.UserObserver, object: team1, action: write, allow: false},
+
+		{user: test.UserTeamAdminTeam1, object: global, action: read, allow: true},
+		{user: test.UserTeamAdminTeam1, object: global, action: write, allow: false},
+		{user: test.UserTeamAdminTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1, action: read, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1, action: write, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1, action: read, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeYARASignatureGroups(t *testing.T) {
+	t.Parallel()
+
+	global := &fleet.YARASignatureGroup{}
+	team1 := &fleet.YARASignatureGroup{TeamID: ptr.Uint(1)}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: global, action: read, allow: false},
+		{user: nil, object: team1, action: write, allow: false},
+		{user: test.UserNoRoles, object: global, action: read, allow: false},
+		{user: test.UserNoRoles, object: team1, action: read, allow: false},
+
+		{user: test.UserAdmin, object: global, action: write, allow: true},
+		{user: test.UserAdmin, object: team1, action: write, allow: true},
+		{user: test.UserMaintainer, object: global, action: write, allow: true},
+		{user: test.UserMaintainer, object: team1, action: write, allow: true},
+		{user: test.UserObserver, object: global, action: read, allow: true},
+		{user: test.UserObserver, object: team1, action: read, allow: true},
+		{user: test.UserObserver, object: global, action: write, allow: false},
+
+		{user: test.UserTeamAdminTeam1, object: global, action: read, allow: true},
+		{user: test.UserTeamAdminTeam1, object: global, action: write, allow: false},
+		{user: test.UserTeamAdminTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamMaintainerTeam1, object: team1, action: write, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1, action: read, allow: true},
+		{user: test.UserTeamObserverTeam1, object: team1, action: write, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1, action: read, allow: false},
+		{user: test.UserTeamAdminTeam2, object: team1, action: write, allow: false},
+	})
+}
+
 func runTestCases(t *testing.T, testCases []authTestCase) {
 	t.Helper()
 
@@ -109,8 +109,13 @@ type OsqueryConfig struct {
 	EnrollCooldown                   time.Duration `yaml:"enroll_cooldown"`
 	StatusLogPlugin                  string        `yaml:"status_log_plugin"`
 	ResultLogPlugin                  string        `yaml:"result_log_plugin"`
+	ResultLogRoutes                  string        `yaml:"result_log_routes"`
+	EnrichResultLogs                 bool          `yaml:"enrich_result_logs"`
+	AuditLogPlugin                   string        `yaml:"audit_log_plugin"`
+	AuditLogQueries                  string        `yaml:"audit_log_queries"`
 	LabelUpdateInterval              time.Duration `yaml:"label_update_interval"`
 	PolicyUpdateInterval             time.Duration `yaml:"policy_update_interval"`
+	CriticalPolicyUpdateInterval     time.Duration `yaml:"critical_policy_update_interval"`
 	DetailUpdateInterval             time.Duration `yaml:"detail_update_interval"`
 	StatusLogFile                    string        `yaml:"status_log_file"`
 	ResultLogFile                    string        `yaml:"result_log_file"`
@@ -126,6 +131,18 @@ type OsqueryConfig struct {
 	AsyncHostUpdateBatch             int           `yaml:"async_host_update_batch"`
 	AsyncHostRedisPopCount           int           `yaml:"async_host_redis_pop_count"`
 	AsyncHostRedisScanKeysCount      int           `yaml:"async_host_redis_scan_keys_count"`
+	EnableAsyncDetailIngestion       bool          `yaml:"enable_async_detail_ingestion"`
+	AsyncDetailIngestionQueueSize    int           `yaml:"async_detail_ingestion_queue_size"`
+	AsyncDetailIngestionWorkers      int           `yaml:"async_detail_ingestion_workers"`
+	AsyncDetailIngestionUpdateBatch  int           `yaml:"async_detail_ingestion_update_batch"`
+	AsyncDetailIngestionInterval     time.Duration `yaml:"async_detail_ingestion_interval"`
+	ClientConfigCacheTTL             time.Duration `yaml:"client_config_cache_ttl"`
+	IntervalJitterPercent            int           `yaml:"interval_jitter_percent"`
+	LiveQueryCampaignTTL             time.Duration `yaml:"live_query_campaign_ttl"`
+	LiveQueryReapInterval            time.Duration `yaml:"live_query_reap_interval"`
+	MaxLiveQueryCampaigns            int           `yaml:"max_live_query_campaigns"`
+	MaxLiveQueryCampaignsPerUser     int           `yaml:"max_live_query_campaigns_per_user"`
+	LiveQueryResultStore             string        `yaml:"live_query_result_store"`
 }
 
 // LoggingConfig defines configs related to logging
@@ -148,6 +165,7 @@ type FirehoseConfig struct {
 	StsAssumeRoleArn string `yaml:"sts_assume_role_arn"`
 	StatusStream     string `yaml:"status_stream"`
 	ResultStream     string `yaml:"result_stream"`
+	AuditStream      string `yaml:"audit_stream"`
 }
 
 // KinesisConfig defines configs for the AWS Kinesis logging plugin
@@ -159,6 +177,7 @@ type KinesisConfig struct {
 	StsAssumeRoleArn string `yaml:"sts_assume_role_arn"`
 	StatusStream     string `yaml:"status_stream"`
 	ResultStream     string `yaml:"result_stream"`
+	AuditStream      string `yaml:"audit_stream"`
 }
 
 // LambdaConfig defines configs for the AWS Lambda logging plugin
@@ -169,6 +188,7 @@ type LambdaConfig struct {
 	StsAssumeRoleArn string `yaml:"sts_assume_role_arn"`
 	StatusFunction   string `yaml:"status_function"`
 	ResultFunction   string `yaml:"result_function"`
+	AuditFunction    string `yaml:"audit_function"`
 }
 
 // S3Config defines config to enable file carving storage to an S3 bucket
@@ -184,11 +204,92 @@ type S3Config struct {
 	ForceS3PathStyle bool   `yaml:"force_s3_path_style"`
 }
 
+// S3LoggingConfig defines configs for the AWS S3 logging plugin
+type S3LoggingConfig struct {
+	Bucket           string        `yaml:"bucket"`
+	Prefix           string        `yaml:"prefix"`
+	Region           string        `yaml:"region"`
+	EndpointURL      string        `yaml:"endpoint_url"`
+	AccessKeyID      string        `yaml:"access_key_id"`
+	SecretAccessKey  string        `yaml:"secret_access_key"`
+	StsAssumeRoleArn string        `yaml:"sts_assume_role_arn"`
+	DisableSSL       bool          `yaml:"disable_ssl"`
+	ForceS3PathStyle bool          `yaml:"force_s3_path_style"`
+	MaxObjectSize    int           `yaml:"max_object_size"`
+	MaxObjectAge     time.Duration `yaml:"max_object_age"`
+}
+
+// SplunkConfig defines configs for the Splunk HTTP Event Collector logging
+// plugin
+type SplunkConfig struct {
+	URL              string        `yaml:"url"`
+	Token            string        `yaml:"token"`
+	Index            string        `yaml:"index"`
+	Source           string        `yaml:"source"`
+	StatusSourcetype string        `yaml:"status_sourcetype"`
+	ResultSourcetype string        `yaml:"result_sourcetype"`
+	AuditSourcetype  string        `yaml:"audit_sourcetype"`
+	PackSourcetypes  string        `yaml:"pack_sourcetypes"`
+	MaxBatchSize     int           `yaml:"max_batch_size"`
+	MaxRetries       int           `yaml:"max_retries"`
+	Timeout          time.Duration `yaml:"timeout"`
+}
+
+// ElasticsearchConfig defines configs for the Elasticsearch logging plugin
+type ElasticsearchConfig struct {
+	URL          string        `yaml:"url"`
+	Username     string        `yaml:"username"`
+	Password     string        `yaml:"password"`
+	APIKey       string        `yaml:"api_key"`
+	Index        string        `yaml:"index"`
+	MaxBatchSize int           `yaml:"max_batch_size"`
+	MaxRetries   int           `yaml:"max_retries"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+// SyslogConfig defines configs for the syslog logging plugin
+type SyslogConfig struct {
+	Network   string `yaml:"network"`
+	Address   string `yaml:"address"`
+	TLSCAFile string `yaml:"tls_ca_file"`
+	Facility  string `yaml:"facility"`
+	Severity  string `yaml:"severity"`
+}
+
+// WebhookLoggingConfig defines configs for the HTTP webhook logging plugin
+type WebhookLoggingConfig struct {
+	URL          string        `yaml:"url"`
+	Headers      string        `yaml:"headers"`
+	Secret       string        `yaml:"secret"`
+	MaxBatchSize int           `yaml:"max_batch_size"`
+	MaxRetries   int           `yaml:"max_retries"`
+	Timeout      time.Duration `yaml:"timeout"`
+}
+
+// LogSpoolConfig defines configs for the spool of the osquery logs that could
+// not be written by the logging plugins
+type LogSpoolConfig struct {
+	Dir            string        `yaml:"dir"`
+	MaxSize        int           `yaml:"max_size"`
+	ReplayInterval time.Duration `yaml:"replay_interval"`
+}
+
+// LogQueueConfig defines configs for the in-memory queue of the osquery logs
+// written in the background
+type LogQueueConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	MaxSize       int           `yaml:"max_size"`
+	MaxBatchSize  int           `yaml:"max_batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	Workers       int           `yaml:"workers"`
+}
+
 // PubSubConfig defines configs the for Google PubSub logging plugin
 type PubSubConfig struct {
 	Project       string `json:"project"`
 	StatusTopic   string `json:"status_topic" yaml:"status_topic"`
 	ResultTopic   string `json:"result_topic" yaml:"result_topic"`
+	AuditTopic    string `json:"audit_topic" yaml:"audit_topic"`
 	AddAttributes bool   `json:"add_attributes" yaml:"add_attributes"`
 }
 
@@ -196,14 +297,19 @@ type PubSubConfig struct {
 type FilesystemConfig struct {
 	StatusLogFile        string `json:"status_log_file" yaml:"status_log_file"`
 	ResultLogFile        string `json:"result_log_file" yaml:"result_log_file"`
+	AuditLogFile         string `json:"audit_log_file" yaml:"audit_log_file"`
 	EnableLogRotation    bool   `json:"enable_log_rotation" yaml:"enable_log_rotation"`
 	EnableLogCompression bool   `json:"enable_log_compression" yaml:"enable_log_compression"`
+	MaxSize              int    `json:"max_size" yaml:"max_size"`
+	MaxAge               int    `json:"max_age" yaml:"max_age"`
+	MaxBackups           int    `json:"max_backups" yaml:"max_backups"`
 }
 
 // KafkaRESTConfig defines configs for the Kafka REST Proxy logging plugin.
 type KafkaRESTConfig struct {
 	StatusTopic      string `json:"status_topic" yaml:"status_topic"`
 	ResultTopic      string `json:"result_topic" yaml:"result_topic"`
+	AuditTopic       string `json:"audit_topic" yaml:"audit_topic"`
 	ProxyHost        string `json:"proxyhost" yaml:"proxyhost"`
 	ContentTypeValue string `json:"content_type_value" yaml:"content_type_value"`
 	Timeout          int    `json:"timeout" yaml:"timeout"`
@@ -237,6 +343,50 @@ type GeoIPConfig struct {
 	DatabasePath string `json:"database_path" yaml:"database_path"`
 }
 
+// AgentCAConfig defines configs related to the issuance of client
+// certificates to osquery agents.
+type AgentCAConfig struct {
+	Cert      string        `json:"cert" yaml:"cert"`
+	Key       string        `json:"key" yaml:"key"`
+	Challenge string        `json:"challenge" yaml:"challenge"`
+	Validity  time.Duration `json:"validity" yaml:"validity"`
+}
+
+// ThreatIntelConfig defines configs related to the lookup of the indicators
+// collected from hosts with an external threat intelligence API.
+type ThreatIntelConfig struct {
+	URL         string        `json:"url" yaml:"url"`
+	APIKey      string        `json:"api_key" yaml:"api_key"`
+	Periodicity time.Duration `json:"periodicity" yaml:"periodicity"`
+	RateLimit   int           `json:"rate_limit" yaml:"rate_limit"`
+	CacheTTL    time.Duration `json:"cache_ttl" yaml:"cache_ttl"`
+}
+
+// IngestSidecarConfig defines configs related to the sidecar service that
+// contributes additional detail queries and ingests their results.
+type IngestSidecarConfig struct {
+	URL             string        `json:"url" yaml:"url"`
+	APIKey          string        `json:"api_key" yaml:"api_key"`
+	RefreshInterval time.Duration `json:"refresh_interval" yaml:"refresh_interval"`
+	Timeout         time.Duration `json:"timeout" yaml:"timeout"`
+}
+
+// NATSConfig defines configs related to the NATS JetStream live query result
+// store.
+type NATSConfig struct {
+	URL              string        `json:"url" yaml:"url"`
+	CredentialsFile  string        `json:"credentials_file" yaml:"credentials_file"`
+	TLSCert          string        `json:"tls_cert" yaml:"tls_cert"`
+	TLSKey           string        `json:"tls_key" yaml:"tls_key"`
+	TLSCA            string        `json:"tls_ca" yaml:"tls_ca"`
+	ConnectTimeout   time.Duration `json:"connect_timeout" yaml:"connect_timeout"`
+	Stream           string        `json:"stream" yaml:"stream"`
+	SubjectPrefix    string        `json:"subject_prefix" yaml:"subject_prefix"`
+	Replicas         int           `json:"replicas" yaml:"replicas"`
+	MaxAge           time.Duration `json:"max_age" yaml:"max_age"`
+	DuplicateResults bool          `json:"duplicate_results" yaml:"duplicate_results"`
+}
+
 // FleetConfig stores the application configuration. Each subcategory is
 // broken up into it's own struct, defined above. When editing any of these
 // structs, Manager.addConfigs and Manager.LoadConfig should be
@@ -255,6 +405,13 @@ type FleetConfig struct {
 	Kinesis          KinesisConfig
 	Lambda           LambdaConfig
 	S3               S3Config
+	S3Logging        S3LoggingConfig
+	Splunk           SplunkConfig
+	Elasticsearch    ElasticsearchConfig
+	Syslog           SyslogConfig
+	WebhookLogging   WebhookLoggingConfig
+	LogSpool         LogSpoolConfig
+	LogQueue         LogQueueConfig
 	PubSub           PubSubConfig
 	Filesystem       FilesystemConfig
 	KafkaREST        KafkaRESTConfig
@@ -263,6 +420,10 @@ type FleetConfig struct {
 	Upgrades         UpgradesConfig
 	Sentry           SentryConfig
 	GeoIP            GeoIPConfig
+	AgentCA          AgentCAConfig
+	ThreatIntel      ThreatIntelConfig
+	IngestSidecar    IngestSidecarConfig
+	NATS             NATSConfig
 }
 
 type TLS struct {
@@ -415,10 +576,20 @@ func (man Manager) addConfigs() {
 		"Log plugin to use for status logs")
 	man.addConfigString("osquery.result_log_plugin", "filesystem",
 		"Log plugin to use for result logs")
+	man.addConfigString("osquery.result_log_routes", "",
+		"Log plugins to use for the result logs of the matching query names (pattern1=plugin1,pattern2=plugin2)")
+	man.addConfigBool("osquery.enrich_result_logs", false,
+		"Add the Fleet host ID, hostname, team and labels to the result logs")
+	man.addConfigString("osquery.audit_log_plugin", "",
+		"Log plugin to use for audit logs (if blank the audit logs are result logs)")
+	man.addConfigString("osquery.audit_log_queries", "",
+		"Query names of the result logs that are audit logs (pattern1,pattern2)")
 	man.addConfigDuration("osquery.label_update_interval", 1*time.Hour,
 		"Interval to update host label membership (i.e. 1h)")
 	man.addConfigDuration("osquery.policy_update_interval", 1*time.Hour,
 		"Interval to update host policy membership (i.e. 1h)")
+	man.addConfigDuration("osquery.critical_policy_update_interval", 0,
+		"Interval to update host critical policy membership, 0 to use the policy interval (i.e. 5m)")
 	man.addConfigDuration("osquery.detail_update_interval", 1*time.Hour,
 		"Interval to update host details (i.e. 1h)")
 	man.addConfigString("osquery.status_log_file", "",
@@ -449,6 +620,30 @@ func (man Manager) addConfigs() {
 		"Batch size to pop items from redis in async collection")
 	man.addConfigInt("osquery.async_host_redis_scan_keys_count", 1000,
 		"Batch size to scan redis keys in async collection")
+	man.addConfigBool("osquery.enable_async_detail_ingestion", false,
+		"Enable asynchronous ingestion of the host details reported by osquery")
+	man.addConfigInt("osquery.async_detail_ingestion_queue_size", 10000,
+		"Maximum number of host detail results waiting for asynchronous ingestion")
+	man.addConfigInt("osquery.async_detail_ingestion_workers", 4,
+		"Number of workers ingesting the host details asynchronously")
+	man.addConfigInt("osquery.async_detail_ingestion_update_batch", 100,
+		"Batch size for the host updates of the asynchronous detail ingestion")
+	man.addConfigDuration("osquery.async_detail_ingestion_interval", 1*time.Second,
+		"Maximum interval between the host updates of the asynchronous detail ingestion")
+	man.addConfigDuration("osquery.client_config_cache_ttl", 1*time.Minute,
+		"Duration for which the rendered osquery client config is cached (0 disables caching)")
+	man.addConfigInt("osquery.interval_jitter_percent", 0,
+		"Maximum percentage added to or removed from the distributed_interval and logger_tls_period of each host (0 disables jitter)")
+	man.addConfigDuration("osquery.live_query_campaign_ttl", 24*time.Hour,
+		"Duration after which a running live query campaign is considered stale and completed")
+	man.addConfigDuration("osquery.live_query_reap_interval", 1*time.Minute,
+		"How much time to wait between the completions of the stale live query campaigns (0 disables them)")
+	man.addConfigInt("osquery.max_live_query_campaigns", 0,
+		"Maximum number of live query campaigns running at the same time (0 for no limit)")
+	man.addConfigInt("osquery.max_live_query_campaigns_per_user", 0,
+		"Maximum number of live query campaigns a user can have running at the same time (0 for no limit)")
+	man.addConfigString("osquery.live_query_result_store", "redis",
+		"Store of the results of the live queries (redis, nats)")
 
 	// Logging
 	man.addConfigBool("logging.debug", false,
@@ -476,6 +671,8 @@ func (man Manager) addConfigs() {
 		"Firehose stream name for status logs")
 	man.addConfigString("firehose.result_stream", "",
 		"Firehose stream name for result logs")
+	man.addConfigString("firehose.audit_stream", "",
+		"Firehose stream name for audit logs")
 
 	// Kinesis
 	man.addConfigString("kinesis.region", "", "AWS Region to use")
@@ -489,6 +686,8 @@ func (man Manager) addConfigs() {
 		"Kinesis stream name for status logs")
 	man.addConfigString("kinesis.result_stream", "",
 		"Kinesis stream name for result logs")
+	man.addConfigString("kinesis.audit_stream", "",
+		"Kinesis stream name for audit logs")
 
 	// Lambda
 	man.addConfigString("lambda.region", "", "AWS Region to use")
@@ -500,6 +699,8 @@ func (man Manager) addConfigs() {
 		"Lambda function name for status logs")
 	man.addConfigString("lambda.result_function", "",
 		"Lambda function name for result logs")
+	man.addConfigString("lambda.audit_function", "",
+		"Lambda function name for audit logs")
 
 	// S3 for file carving
 	man.addConfigString("s3.bucket", "", "Bucket where to store file carves")
@@ -512,10 +713,81 @@ func (man Manager) addConfigs() {
 	man.addConfigBool("s3.disable_ssl", false, "Disable SSL (typically for local testing)")
 	man.addConfigBool("s3.force_s3_path_style", false, "Set this to true to force path-style addressing, i.e., `http://s3.amazonaws.com/BUCKET/KEY`")
 
+	// S3 for logging
+	man.addConfigString("s3_logging.bucket", "", "Bucket where to upload osquery logs")
+	man.addConfigString("s3_logging.prefix", "{log_type}/{year}/{month}/{day}/",
+		"Prefix of the osquery log objects, the {log_type}, {year}, {month}, {day} and {hour} placeholders are replaced")
+	man.addConfigString("s3_logging.region", "", "AWS Region (if blank region is derived)")
+	man.addConfigString("s3_logging.endpoint_url", "", "AWS Service Endpoint to use (leave blank for default service endpoints)")
+	man.addConfigString("s3_logging.access_key_id", "", "Access Key ID for AWS authentication")
+	man.addConfigString("s3_logging.secret_access_key", "", "Secret Access Key for AWS authentication")
+	man.addConfigString("s3_logging.sts_assume_role_arn", "", "ARN of role to assume for AWS")
+	man.addConfigBool("s3_logging.disable_ssl", false, "Disable SSL (typically for local testing)")
+	man.addConfigBool("s3_logging.force_s3_path_style", false, "Set this to true to force path-style addressing, i.e., `http://s3.amazonaws.com/BUCKET/KEY`")
+	man.addConfigInt("s3_logging.max_object_size", 64*1024*1024,
+		"Uncompressed size in bytes of the osquery logs after which an object is uploaded")
+	man.addConfigDuration("s3_logging.max_object_age", 5*time.Minute,
+		"Time after which an object of osquery logs is uploaded, whatever its size")
+
+	// Splunk
+	man.addConfigString("splunk.url", "", "URL of the Splunk HTTP Event Collector")
+	man.addConfigString("splunk.token", "", "Token of the Splunk HTTP Event Collector")
+	man.addConfigString("splunk.index", "", "Splunk index of the osquery logs (if blank the default index of the token is used)")
+	man.addConfigString("splunk.source", "fleet", "Splunk source of the osquery logs")
+	man.addConfigString("splunk.status_sourcetype", "osquery:status", "Splunk sourcetype of the osquery status logs")
+	man.addConfigString("splunk.result_sourcetype", "osquery:results", "Splunk sourcetype of the osquery result logs")
+	man.addConfigString("splunk.audit_sourcetype", "osquery:audit", "Splunk sourcetype of the osquery audit logs")
+	man.addConfigString("splunk.pack_sourcetypes", "",
+		"Splunk sourcetypes of the results of the queries of packs, in the format <pack>=<sourcetype>,<pack>=<sourcetype>")
+	man.addConfigInt("splunk.max_batch_size", 1024*1024, "Maximum size in bytes of the events sent in a request to Splunk")
+	man.addConfigInt("splunk.max_retries", 5, "Maximum number of retries when Splunk is overloaded")
+	man.addConfigDuration("splunk.timeout", 10*time.Second, "Timeout of the requests to Splunk")
+
+	// Elasticsearch
+	man.addConfigString("elasticsearch.url", "", "URL of the Elasticsearch or OpenSearch cluster")
+	man.addConfigString("elasticsearch.username", "", "Username for Elasticsearch basic authentication")
+	man.addConfigString("elasticsearch.password", "", "Password for Elasticsearch basic authentication")
+	man.addConfigString("elasticsearch.api_key", "", "API key for Elasticsearch authentication (used instead of the username and password if set)")
+	man.addConfigString("elasticsearch.index", "osquery-{log_type}-{year}.{month}.{day}",
+		"Index of the osquery logs, the {log_type}, {year}, {month}, {day} and {hour} placeholders are replaced")
+	man.addConfigInt("elasticsearch.max_batch_size", 5*1024*1024, "Maximum size in bytes of the documents sent in a bulk request")
+	man.addConfigInt("elasticsearch.max_retries", 5, "Maximum number of retries when Elasticsearch is overloaded")
+	man.addConfigDuration("elasticsearch.timeout", 30*time.Second, "Timeout of the requests to Elasticsearch")
+
+	// Syslog
+	man.addConfigString("syslog.network", "udp", "Network of the syslog collector (udp, tcp or tls)")
+	man.addConfigString("syslog.address", "", "Address (host:port) of the syslog collector")
+	man.addConfigString("syslog.tls_ca_file", "", "CA certificates to verify the certificate of the syslog collector (if blank the system roots are used)")
+	man.addConfigString("syslog.facility", "local0", "Facility of the syslog messages")
+	man.addConfigString("syslog.severity", "info", "Severity of the syslog messages")
+
+	// Webhook logging
+	man.addConfigString("webhook_logging.url", "", "URL of the endpoint the osquery logs are posted to")
+	man.addConfigString("webhook_logging.headers", "",
+		"Additional headers of the requests to the endpoint, in the format <name>: <value>, <name>: <value>")
+	man.addConfigString("webhook_logging.secret", "", "Secret used to sign the requests to the endpoint (if blank the requests are not signed)")
+	man.addConfigInt("webhook_logging.max_batch_size", 1024*1024, "Maximum size in bytes of the logs posted in a request")
+	man.addConfigInt("webhook_logging.max_retries", 5, "Maximum number of retries of a failed request")
+	man.addConfigDuration("webhook_logging.timeout", 10*time.Second, "Timeout of the requests to the endpoint")
+
+	// Log spool
+	man.addConfigString("log_spool.dir", "",
+		"Directory where the osquery logs that could not be written are spooled (if blank the logs are not spooled)")
+	man.addConfigInt("log_spool.max_size", 1024*1024*1024, "Maximum size in bytes of the spooled logs of each log type")
+	man.addConfigDuration("log_spool.replay_interval", 1*time.Minute, "Interval to replay the spooled logs")
+
+	// Log queue
+	man.addConfigBool("log_queue.enabled", false, "Queue the osquery logs in memory and write them in the background")
+	man.addConfigInt("log_queue.max_size", 64*1024*1024, "Maximum size in bytes of the queued logs of each log type")
+	man.addConfigInt("log_queue.max_batch_size", 1024*1024, "Maximum size in bytes of the batches of logs written by the workers")
+	man.addConfigDuration("log_queue.flush_interval", 1*time.Second, "Interval to write the queued logs")
+	man.addConfigInt("log_queue.workers", 1, "Number of workers writing the queued logs of each log type")
+
 	// PubSub
 	man.addConfigString("pubsub.project", "", "Google Cloud Project to use")
 	man.addConfigString("pubsub.status_topic", "", "PubSub topic for status logs")
 	man.addConfigString("pubsub.result_topic", "", "PubSub topic for result logs")
+	man.addConfigString("pubsub.audit_topic", "", "PubSub topic for audit logs")
 	man.addConfigBool("pubsub.add_attributes", false, "Add PubSub attributes in addition to the message body")
 
 	// Filesystem
@@ -523,14 +795,23 @@ func (man Manager) addConfigs() {
 		"Log file path to use for status logs")
 	man.addConfigString("filesystem.result_log_file", filepath.Join(os.TempDir(), "osquery_result"),
 		"Log file path to use for result logs")
+	man.addConfigString("filesystem.audit_log_file", filepath.Join(os.TempDir(), "osquery_audit"),
+		"Log file path to use for audit logs")
 	man.addConfigBool("filesystem.enable_log_rotation", false,
 		"Enable automatic rotation for osquery log files")
 	man.addConfigBool("filesystem.enable_log_compression", false,
 		"Enable compression for the rotated osquery log files")
+	man.addConfigInt("filesystem.max_size", 500,
+		"Maximum size in megabytes of the osquery log files before they are rotated")
+	man.addConfigInt("filesystem.max_age", 28,
+		"Maximum number of days to retain the rotated osquery log files (0 for no limit)")
+	man.addConfigInt("filesystem.max_backups", 3,
+		"Maximum number of rotated osquery log files to retain (0 for no limit)")
 
 	// KafkaREST
 	man.addConfigString("kafkarest.status_topic", "", "Kafka REST topic for status logs")
 	man.addConfigString("kafkarest.result_topic", "", "Kafka REST topic for result logs")
+	man.addConfigString("kafkarest.audit_topic", "", "Kafka REST topic for audit logs")
 	man.addConfigString("kafkarest.proxyhost", "", "Kafka REST proxy host url")
 	man.addConfigString("kafkarest.content_type_value", "application/vnd.kafka.json.v1+json",
 		"Kafka REST proxy content type header (defaults to \"application/vnd.kafka.json.v1+json\"")
@@ -562,6 +843,59 @@ func (man Manager) addConfigs() {
 
 	// GeoIP
 	man.addConfigString("geoip.database_path", "", "path to mmdb file")
+
+	// Agent CA
+	man.addConfigString("agent_ca.cert", "",
+		"Path to the PEM encoded CA certificate used to issue agent client certificates")
+	man.addConfigString("agent_ca.key", "",
+		"Path to the PEM encoded CA private key used to issue agent client certificates")
+	man.addConfigString("agent_ca.challenge", "",
+		"Challenge password required to issue a certificate (if empty, a valid enroll secret is required)")
+	man.addConfigDuration("agent_ca.validity", 365*24*time.Hour,
+		"Validity period of issued agent client certificates")
+
+	// Threat intel
+	man.addConfigString("threat_intel.url", "",
+		"URL of the threat intel API used to look up host indicators (if empty, lookups are disabled)")
+	man.addConfigString("threat_intel.api_key", "",
+		"API key sent to the threat intel API")
+	man.addConfigDuration("threat_intel.periodicity", 1*time.Hour,
+		"How much time to wait between threat intel lookups of host indicators")
+	man.addConfigInt("threat_intel.rate_limit", 60,
+		"Maximum number of threat intel lookups per minute")
+	man.addConfigDuration("threat_intel.cache_ttl", 24*time.Hour,
+		"How long the result of a threat intel lookup is reused before the indicator is looked up again")
+
+	// Ingest sidecar
+	man.addConfigString("ingest_sidecar.url", "",
+		"URL of the sidecar service contributing additional detail queries (if empty, the sidecar is disabled)")
+	man.addConfigString("ingest_sidecar.api_key", "",
+		"API key sent to the ingest sidecar")
+	man.addConfigDuration("ingest_sidecar.refresh_interval", 5*time.Minute,
+		"How much time to wait between refreshes of the detail queries of the ingest sidecar")
+	man.addConfigDuration("ingest_sidecar.timeout", 5*time.Second,
+		"Timeout of the requests to the ingest sidecar")
+
+	// NATS
+	man.addConfigString("nats.url", "nats://localhost:4222",
+		"NATS server URLs, comma-separated")
+	man.addConfigString("nats.credentials_file", "",
+		"NATS user credentials file")
+	man.addConfigString("nats.tls_cert", "", "NATS TLS client certificate path")
+	man.addConfigString("nats.tls_key", "", "NATS TLS client key path")
+	man.addConfigString("nats.tls_ca", "", "NATS TLS server CA")
+	man.addConfigDuration("nats.connect_timeout", 5*time.Second,
+		"Timeout at connection time")
+	man.addConfigString("nats.stream", "FLEET_LIVE_QUERY_RESULTS",
+		"JetStream stream of the live query results")
+	man.addConfigString("nats.subject_prefix", "fleet.live_query",
+		"Prefix of the NATS subjects of the live query results")
+	man.addConfigInt("nats.replicas", 1,
+		"Number of replicas of the JetStream stream of the live query results")
+	man.addConfigDuration("nats.max_age", 1*time.Hour,
+		"Maximum age of the live query results kept in the JetStream stream")
+	man.addConfigBool("nats.duplicate_results", false,
+		"Duplicate Live Query results to another NATS subject")
 }
 
 // LoadConfig will load the config variables into a fully initialized
@@ -643,10 +977,15 @@ func (man Manager) LoadConfig() FleetConfig {
 			EnrollCooldown:                   man.getConfigDuration("osquery.enroll_cooldown"),
 			StatusLogPlugin:                  man.getConfigString("osquery.status_log_plugin"),
 			ResultLogPlugin:                  man.getConfigString("osquery.result_log_plugin"),
+			ResultLogRoutes:                  man.getConfigString("osquery.result_log_routes"),
+			EnrichResultLogs:                 man.getConfigBool("osquery.enrich_result_logs"),
+			AuditLogPlugin:                   man.getConfigString("osquery.audit_log_plugin"),
+			AuditLogQueries:                  man.getConfigString("osquery.audit_log_queries"),
 			StatusLogFile:                    man.getConfigString("osquery.status_log_file"),
 			ResultLogFile:                    man.getConfigString("osquery.result_log_file"),
 			LabelUpdateInterval:              man.getConfigDuration("osquery.label_update_interval"),
 			PolicyUpdateInterval:             man.getConfigDuration("osquery.policy_update_interval"),
+			CriticalPolicyUpdateInterval:     man.getConfigDuration("osquery.critical_policy_update_interval"),
 			DetailUpdateInterval:             man.getConfigDuration("osquery.detail_update_interval"),
 			EnableLogRotation:                man.getConfigBool("osquery.enable_log_rotation"),
 			MaxJitterPercent:                 man.getConfigInt("osquery.max_jitter_percent"),
@@ -660,6 +999,18 @@ func (man Manager) LoadConfig() FleetConfig {
 			AsyncHostUpdateBatch:             man.getConfigInt("osquery.async_host_update_batch"),
 			AsyncHostRedisPopCount:           man.getConfigInt("osquery.async_host_redis_pop_count"),
 			AsyncHostRedisScanKeysCount:      man.getConfigInt("osquery.async_host_redis_scan_keys_count"),
+			EnableAsyncDetailIngestion:       man.getConfigBool("osquery.enable_async_detail_ingestion"),
+			AsyncDetailIngestionQueueSize:    man.getConfigInt("osquery.async_detail_ingestion_queue_size"),
+			AsyncDetailIngestionWorkers:      man.getConfigInt("osquery.async_detail_ingestion_workers"),
+			AsyncDetailIngestionUpdateBatch:  man.getConfigInt("osquery.async_detail_ingestion_update_batch"),
+			AsyncDetailIngestionInterval:     man.getConfigDuration("osquery.async_detail_ingestion_interval"),
+			ClientConfigCacheTTL:             man.getConfigDuration("osquery.client_config_cache_ttl"),
+			IntervalJitterPercent:            man.getConfigInt("osquery.interval_jitter_percent"),
+			LiveQueryCampaignTTL:             man.getConfigDuration("osquery.live_query_campaign_ttl"),
+			LiveQueryReapInterval:            man.getConfigDuration("osquery.live_query_reap_interval"),
+			MaxLiveQueryCampaigns:            man.getConfigInt("osquery.max_live_query_campaigns"),
+			MaxLiveQueryCampaignsPerUser:     man.getConfigInt("osquery.max_live_query_campaigns_per_user"),
+			LiveQueryResultStore:             man.getConfigString("osquery.live_query_result_store"),
 		},
 		Logging: LoggingConfig{
 			Debug:                man.getConfigBool("logging.debug"),
@@ -677,6 +1028,7 @@ func (man Manager) LoadConfig() FleetConfig {
 			StsAssumeRoleArn: man.getConfigString("firehose.sts_assume_role_arn"),
 			StatusStream:     man.getConfigString("firehose.status_stream"),
 			ResultStream:     man.getConfigString("firehose.result_stream"),
+			AuditStream:      man.getConfigString("firehose.audit_stream"),
 		},
 		Kinesis: KinesisConfig{
 			Region:           man.getConfigString("kinesis.region"),
@@ -685,6 +1037,7 @@ func (man Manager) LoadConfig() FleetConfig {
 			SecretAccessKey:  man.getConfigString("kinesis.secret_access_key"),
 			StatusStream:     man.getConfigString("kinesis.status_stream"),
 			ResultStream:     man.getConfigString("kinesis.result_stream"),
+			AuditStream:      man.getConfigString("kinesis.audit_stream"),
 			StsAssumeRoleArn: man.getConfigString("kinesis.sts_assume_role_arn"),
 		},
 		Lambda: LambdaConfig{
@@ -693,6 +1046,7 @@ func (man Manager) LoadConfig() FleetConfig {
 			SecretAccessKey:  man.getConfigString("lambda.secret_access_key"),
 			StatusFunction:   man.getConfigString("lambda.status_function"),
 			ResultFunction:   man.getConfigString("lambda.result_function"),
+			AuditFunction:    man.getConfigString("lambda.audit_function"),
 			StsAssumeRoleArn: man.getConfigString("lambda.sts_assume_role_arn"),
 		},
 		S3: S3Config{
@@ -706,21 +1060,90 @@ func (man Manager) LoadConfig() FleetConfig {
 			DisableSSL:       man.getConfigBool("s3.disable_ssl"),
 			ForceS3PathStyle: man.getConfigBool("s3.force_s3_path_style"),
 		},
+		S3Logging: S3LoggingConfig{
+			Bucket:           man.getConfigString("s3_logging.bucket"),
+			Prefix:           man.getConfigString("s3_logging.prefix"),
+			Region:           man.getConfigString("s3_logging.region"),
+			EndpointURL:      man.getConfigString("s3_logging.endpoint_url"),
+			AccessKeyID:      man.getConfigString("s3_logging.access_key_id"),
+			SecretAccessKey:  man.getConfigString("s3_logging.secret_access_key"),
+			StsAssumeRoleArn: man.getConfigString("s3_logging.sts_assume_role_arn"),
+			DisableSSL:       man.getConfigBool("s3_logging.disable_ssl"),
+			ForceS3PathStyle: man.getConfigBool("s3_logging.force_s3_path_style"),
+			MaxObjectSize:    man.getConfigInt("s3_logging.max_object_size"),
+			MaxObjectAge:     man.getConfigDuration("s3_logging.max_object_age"),
+		},
+		Splunk: SplunkConfig{
+			URL:              man.getConfigString("splunk.url"),
+			Token:            man.getConfigString("splunk.token"),
+			Index:            man.getConfigString("splunk.index"),
+			Source:           man.getConfigString("splunk.source"),
+			StatusSourcetype: man.getConfigString("splunk.status_sourcetype"),
+			ResultSourcetype: man.getConfigString("splunk.result_sourcetype"),
+			AuditSourcetype:  man.getConfigString("splunk.audit_sourcetype"),
+			PackSourcetypes:  man.getConfigString("splunk.pack_sourcetypes"),
+			MaxBatchSize:     man.getConfigInt("splunk.max_batch_size"),
+			MaxRetries:       man.getConfigInt("splunk.max_retries"),
+			Timeout:          man.getConfigDuration("splunk.timeout"),
+		},
+		Elasticsearch: ElasticsearchConfig{
+			URL:          man.getConfigString("elasticsearch.url"),
+			Username:     man.getConfigString("elasticsearch.username"),
+			Password:     man.getConfigString("elasticsearch.password"),
+			APIKey:       man.getConfigString("elasticsearch.api_key"),
+			Index:        man.getConfigString("elasticsearch.index"),
+			MaxBatchSize: man.getConfigInt("elasticsearch.max_batch_size"),
+			MaxRetries:   man.getConfigInt("elasticsearch.max_retries"),
+			Timeout:      man.getConfigDuration("elasticsearch.timeout"),
+		},
+		Syslog: SyslogConfig{
+			Network:   man.getConfigString("syslog.network"),
+			Address:   man.getConfigString("syslog.address"),
+			TLSCAFile: man.getConfigString("syslog.tls_ca_file"),
+			Facility:  man.getConfigString("syslog.facility"),
+			Severity:  man.getConfigString("syslog.severity"),
+		},
+		WebhookLogging: WebhookLoggingConfig{
+			URL:          man.getConfigString("webhook_logging.url"),
+			Headers:      man.getConfigString("webhook_logging.headers"),
+			Secret:       man.getConfigString("webhook_logging.secret"),
+			MaxBatchSize: man.getConfigInt("webhook_logging.max_batch_size"),
+			MaxRetries:   man.getConfigInt("webhook_logging.max_retries"),
+			Timeout:      man.getConfigDuration("webhook_logging.timeout"),
+		},
+		LogSpool: LogSpoolConfig{
+			Dir:            man.getConfigString("log_spool.dir"),
+			MaxSize:        man.getConfigInt("log_spool.max_size"),
+			ReplayInterval: man.getConfigDuration("log_spool.replay_interval"),
+		},
+		LogQueue: LogQueueConfig{
+			Enabled:       man.getConfigBool("log_queue.enabled"),
+			MaxSize:       man.getConfigInt("log_queue.max_size"),
+			MaxBatchSize:  man.getConfigInt("log_queue.max_batch_size"),
+			FlushInterval: man.getConfigDuration("log_queue.flush_interval"),
+			Workers:       man.getConfigInt("log_queue.workers"),
+		},
 		PubSub: PubSubConfig{
 			Project:       man.getConfigString("pubsub.project"),
 			StatusTopic:   man.getConfigString("pubsub.status_topic"),
 			ResultTopic:   man.getConfigString("pubsub.result_topic"),
+			AuditTopic:    man.getConfigString("pubsub.audit_topic"),
 			AddAttributes: man.getConfigBool("pubsub.add_attributes"),
 		},
 		Filesystem: FilesystemConfig{
 			StatusLogFile:        man.getConfigString("filesystem.status_log_file"),
 			ResultLogFile:        man.getConfigString("filesystem.result_log_file"),
+			AuditLogFile:         man.getConfigString("filesystem.audit_log_file"),
 			EnableLogRotation:    man.getConfigBool("filesystem.enable_log_rotation"),
 			EnableLogCompression: man.getConfigBool("filesystem.enable_log_compression"),
+			MaxSize:              man.getConfigInt("filesystem.max_size"),
+			MaxAge:               man.getConfigInt("filesystem.max_age"),
+			MaxBackups:           man.getConfigInt("filesystem.max_backups"),
 		},
 		KafkaREST: KafkaRESTConfig{
 			StatusTopic:      man.getConfigString("kafkarest.status_topic"),
 			ResultTopic:      man.getConfigString("kafkarest.result_topic"),
+			AuditTopic:       man.getConfigString("kafkarest.audit_topic"),
 			ProxyHost:        man.getConfigString("kafkarest.proxyhost"),
 			ContentTypeValue: man.getConfigString("kafkarest.content_type_value"),
 			Timeout:          man.getConfigInt("kafkarest.timeout"),
@@ -745,6 +1168,38 @@ func (man Manager) LoadConfig() FleetConfig {
 		GeoIP: GeoIPConfig{
 			DatabasePath: man.getConfigString("geoip.database_path"),
 		},
+		AgentCA: AgentCAConfig{
+			Cert:      man.getConfigString("agent_ca.cert"),
+			Key:       man.getConfigString("agent_ca.key"),
+			Challenge: man.getConfigString("agent_ca.challenge"),
+			Validity:  man.getConfigDuration("agent_ca.validity"),
+		},
+		ThreatIntel: ThreatIntelConfig{
+			URL:         man.getConfigString("threat_intel.url"),
+			APIKey:      man.getConfigString("threat_intel.api_key"),
+			Periodicity: man.getConfigDuration("threat_intel.periodicity"),
+			RateLimit:   man.getConfigInt("threat_intel.rate_limit"),
+			CacheTTL:    man.getConfigDuration("threat_intel.cache_ttl"),
+		},
+		IngestSidecar: IngestSidecarConfig{
+			URL:             man.getConfigString("ingest_sidecar.url"),
+			APIKey:          man.getConfigString("ingest_sidecar.api_key"),
+			RefreshInterval: man.getConfigDuration("ingest_sidecar.refresh_interval"),
+			Timeout:         man.getConfigDuration("ingest_sidecar.timeout"),
+		},
+		NATS: NATSConfig{
+			URL:              man.getConfigString("nats.url"),
+			CredentialsFile:  man.getConfigString("nats.credentials_file"),
+			TLSCert:          man.getConfigString("nats.tls_cert"),
+			TLSKey:           man.getConfigString("nats.tls_key"),
+			TLSCA:            man.getConfigString("nats.tls_ca"),
+			ConnectTimeout:   man.getConfigDuration("nats.connect_timeout"),
+			Stream:           man.getConfigString("nats.stream"),
+			SubjectPrefix:    man.getConfigString("nats.subject_prefix"),
+			Replicas:         man.getConfigInt("nats.replicas"),
+			MaxAge:           man.getConfigDuration("nats.max_age"),
+			DuplicateResults: man.getConfigBool("nats.duplicate_results"),
+		},
 	}
 }
 
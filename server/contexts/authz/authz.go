@@ -39,6 +39,11 @@ const (
 	// which only allows limited access to the device's own host information.
 	// This authentication mode does not support granular authorization.
 	AuthnDeviceToken
+	// AuthnOrbitNodeKey is when authentication is done via the orbit node key,
+	// which is only readable by root on the host and allows orbit to act on
+	// behalf of the device. This authentication mode does not support
+	// granular authorization.
+	AuthnOrbitNodeKey
 )
 
 // AuthorizationContext contains the context information used for the
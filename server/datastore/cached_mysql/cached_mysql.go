@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -15,12 +16,17 @@ import (
 const (
 	appConfigKey                      = "AppConfig:%s"
 	defaultAppConfigExpiration        = 1 * time.Second
-	packsHostKey                      = "Packs:host:%d"
+	packsHostKeyPrefix                = "Packs:host:"
+	packsHostKey                      = packsHostKeyPrefix + "%d"
 	defaultPacksExpiration            = 1 * time.Minute
-	scheduledQueriesKey               = "ScheduledQueries:pack:%d"
+	scheduledQueriesKeyPrefix         = "ScheduledQueries:pack:"
+	scheduledQueriesKey               = scheduledQueriesKeyPrefix + "%d"
 	defaultScheduledQueriesExpiration = 1 * time.Minute
+	discardDataScheduledQueriesKey    = "ScheduledQueries:discard_data"
 	teamAgentOptionsKey               = "TeamAgentOptions:team:%d"
 	defaultTeamAgentOptionsExpiration = 1 * time.Minute
+	teamLogSettingsKey                = "TeamLogSettings:team:%d"
+	defaultTeamLogSettingsExpiration  = 1 * time.Minute
 )
 
 // cloner represents any type that can clone itself. Used by types to provide a more efficient clone method.
@@ -88,6 +94,15 @@ func (c *cloneCache) Set(k string, x interface{}, d time.Duration) {
 	c.Cache.Set(k, clone, d)
 }
 
+// DeleteWithPrefix removes all the items whose key starts with prefix.
+func (c *cloneCache) DeleteWithPrefix(prefix string) {
+	for k := range c.Cache.Items() {
+		if strings.HasPrefix(k, prefix) {
+			c.Cache.Delete(k)
+		}
+	}
+}
+
 type cachedMysql struct {
 	fleet.Datastore
 
@@ -96,6 +111,7 @@ type cachedMysql struct {
 	packsExp            time.Duration
 	scheduledQueriesExp time.Duration
 	teamAgentOptionsExp time.Duration
+	teamLogSettingsExp  time.Duration
 }
 
 type Option func(*cachedMysql)
@@ -118,6 +134,12 @@ func WithTeamAgentOptionsExpiration(d time.Duration) Option {
 	}
 }
 
+func WithTeamLogSettingsExpiration(d time.Duration) Option {
+	return func(o *cachedMysql) {
+		o.teamLogSettingsExp = d
+	}
+}
+
 func New(ds fleet.Datastore, opts ...Option) fleet.Datastore {
 	c := &cachedMysql{
 		Datastore:           ds,
@@ -125,6 +147,7 @@ func New(ds fleet.Datastore, opts ...Option) fleet.Datastore {
 		packsExp:            defaultPacksExpiration,
 		scheduledQueriesExp: defaultScheduledQueriesExpiration,
 		teamAgentOptionsExp: defaultTeamAgentOptionsExpiration,
+		teamLogSettingsExp:  defaultTeamLogSettingsExpiration,
 	}
 	for _, fn := range opts {
 		fn(c)
@@ -210,6 +233,24 @@ func (ds *cachedMysql) ListScheduledQueriesInPack(ctx context.Context, packID ui
 	return scheduledQueries, nil
 }
 
+func (ds *cachedMysql) ListDiscardDataScheduledQueryNames(ctx context.Context) ([]fleet.PackScheduledQueryName, error) {
+	if x, found := ds.c.Get(discardDataScheduledQueriesKey); found {
+		names, ok := x.([]fleet.PackScheduledQueryName)
+		if ok {
+			return names, nil
+		}
+	}
+
+	names, err := ds.Datastore.ListDiscardDataScheduledQueryNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.c.Set(discardDataScheduledQueriesKey, names, ds.scheduledQueriesExp)
+
+	return names, nil
+}
+
 func (ds *cachedMysql) TeamAgentOptions(ctx context.Context, teamID uint) (*json.RawMessage, error) {
 	key := fmt.Sprintf(teamAgentOptionsKey, teamID)
 	if x, found := ds.c.Get(key); found {
@@ -228,6 +269,24 @@ func (ds *cachedMysql) TeamAgentOptions(ctx context.Context, teamID uint) (*json
 	return agentOptions, nil
 }
 
+func (ds *cachedMysql) TeamLogSettings(ctx context.Context, teamID uint) (*fleet.TeamLogSettings, error) {
+	key := fmt.Sprintf(teamLogSettingsKey, teamID)
+	if x, found := ds.c.Get(key); found {
+		if settings, ok := x.(*fleet.TeamLogSettings); ok {
+			return settings, nil
+		}
+	}
+
+	settings, err := ds.Datastore.TeamLogSettings(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.c.Set(key, settings, ds.teamLogSettingsExp)
+
+	return settings, nil
+}
+
 func (ds *cachedMysql) SaveTeam(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 	team, err := ds.Datastore.SaveTeam(ctx, team)
 	if err != nil {
@@ -238,6 +297,12 @@ func (ds *cachedMysql) SaveTeam(ctx context.Context, team *fleet.Team) (*fleet.T
 
 	ds.c.Set(key, team.Config.AgentOptions, ds.teamAgentOptionsExp)
 
+	logSettings := &fleet.TeamLogSettings{}
+	if team.Config.LogSettings != nil {
+		*logSettings = *team.Config.LogSettings
+	}
+	ds.c.Set(fmt.Sprintf(teamLogSettingsKey, team.ID), logSettings, ds.teamLogSettingsExp)
+
 	return team, nil
 }
 
@@ -250,6 +315,108 @@ func (ds *cachedMysql) DeleteTeam(ctx context.Context, teamID uint) error {
 	key := fmt.Sprintf(teamAgentOptionsKey, teamID)
 
 	ds.c.Delete(key)
+	ds.c.Delete(fmt.Sprintf(teamLogSettingsKey, teamID))
+
+	return nil
+}
+
+// invalidatePacks removes the cached packs of all hosts and the cached
+// scheduled queries of all packs, so that changes to packs, scheduled queries
+// and queries are reflected in the next osquery config served to the hosts.
+func (ds *cachedMysql) invalidatePacks() {
+	ds.c.DeleteWithPrefix(packsHostKeyPrefix)
+	ds.c.DeleteWithPrefix(scheduledQueriesKeyPrefix)
+	ds.c.Delete(discardDataScheduledQueriesKey)
+}
+
+func (ds *cachedMysql) NewPack(ctx context.Context, pack *fleet.Pack, opts ...fleet.OptionalArg) (*fleet.Pack, error) {
+	pack, err := ds.Datastore.NewPack(ctx, pack, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ds.invalidatePacks()
+	return pack, nil
+}
+
+func (ds *cachedMysql) SavePack(ctx context.Context, pack *fleet.Pack) error {
+	if err := ds.Datastore.SavePack(ctx, pack); err != nil {
+		return err
+	}
+	ds.invalidatePacks()
+	return nil
+}
+
+func (ds *cachedMysql) DeletePack(ctx context.Context, name string) error {
+	if err := ds.Datastore.DeletePack(ctx, name); err != nil {
+		return err
+	}
+	ds.invalidatePacks()
+	return nil
+}
+
+func (ds *cachedMysql) ApplyPackSpecs(ctx context.Context, specs []*fleet.PackSpec) error {
+	if err := ds.Datastore.ApplyPackSpecs(ctx, specs); err != nil {
+		return err
+	}
+	ds.invalidatePacks()
+	return nil
+}
+
+func (ds *cachedMysql) NewScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery, opts ...fleet.OptionalArg) (*fleet.ScheduledQuery, error) {
+	sq, err := ds.Datastore.NewScheduledQuery(ctx, sq, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ds.invalidatePacks()
+	return sq, nil
+}
+
+func (ds *cachedMysql) SaveScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+	sq, err := ds.Datastore.SaveScheduledQuery(ctx, sq)
+	if err != nil {
+		return nil, err
+	}
+	ds.invalidatePacks()
+	return sq, nil
+}
+
+func (ds *cachedMysql) DeleteScheduledQuery(ctx context.Context, id uint) error {
+	if err := ds.Datastore.DeleteScheduledQuery(ctx, id); err != nil {
+		return err
+	}
+	ds.invalidatePacks()
+	return nil
+}
+
+func (ds *cachedMysql) SaveQuery(ctx context.Context, query *fleet.Query) error {
+	if err := ds.Datastore.SaveQuery(ctx, query); err != nil {
+		return err
+	}
+	ds.invalidatePacks()
+	return nil
+}
 
+func (ds *cachedMysql) DeleteQuery(ctx context.Context, name string) error {
+	if err := ds.Datastore.DeleteQuery(ctx, name); err != nil {
+		return err
+	}
+	ds.invalidatePacks()
+	return nil
+}
+
+func (ds *cachedMysql) DeleteQueries(ctx context.Context, ids []uint) (uint, error) {
+	n, err := ds.Datastore.DeleteQueries(ctx, ids)
+	if err != nil {
+		return n, err
+	}
+	ds.invalidatePacks()
+	return n, nil
+}
+
+func (ds *cachedMysql) ApplyQueries(ctx context.Context, authorID uint, queries []*fleet.Query) error {
+	if err := ds.Datastore.ApplyQueries(ctx, authorID, queries); err != nil {
+		return err
+	}
+	ds.invalidatePacks()
 	return nil
 }
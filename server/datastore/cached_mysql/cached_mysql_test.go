@@ -330,3 +330,134 @@ func TestCachedTeamAgentOptions(t *testing.T) {
 	_, err = ds.TeamAgentOptions(context.Background(), testTeam.ID)
 	require.Error(t, err)
 }
+
+func TestCachedTeamLogSettings(t *testing.T) {
+	t.Parallel()
+
+	mockedDS := new(mock.Store)
+	ds := New(mockedDS, WithTeamLogSettingsExpiration(100*time.Millisecond))
+
+	called := 0
+	mockedDS.TeamLogSettingsFunc = func(ctx context.Context, teamID uint) (*fleet.TeamLogSettings, error) {
+		called++
+		return &fleet.TeamLogSettings{ResultLogPlugin: "splunk"}, nil
+	}
+	mockedDS.SaveTeamFunc = func(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
+		return team, nil
+	}
+	mockedDS.DeleteTeamFunc = func(ctx context.Context, teamID uint) error {
+		return nil
+	}
+
+	settings, err := ds.TeamLogSettings(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, "splunk", settings.ResultLogPlugin)
+	settings, err = ds.TeamLogSettings(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, "splunk", settings.ResultLogPlugin)
+	require.Equal(t, 1, called)
+
+	// saving a team updates the log settings in cache
+	_, err = ds.SaveTeam(context.Background(), &fleet.Team{ID: 1, Name: "test"})
+	require.NoError(t, err)
+	settings, err = ds.TeamLogSettings(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, &fleet.TeamLogSettings{}, settings)
+	require.Equal(t, 1, called)
+
+	// deleting a team removes the log settings from the cache
+	require.NoError(t, ds.DeleteTeam(context.Background(), 1))
+	settings, err = ds.TeamLogSettings(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, "splunk", settings.ResultLogPlugin)
+	require.Equal(t, 2, called)
+
+	// the cached log settings expire
+	time.Sleep(200 * time.Millisecond)
+	_, err = ds.TeamLogSettings(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, called)
+}
+
+func TestCachedPacksInvalidation(t *testing.T) {
+	t.Parallel()
+
+	mockedDS := new(mock.Store)
+	ds := New(mockedDS)
+
+	packsCalled, scheduledQueriesCalled := 0, 0
+	mockedDS.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		packsCalled++
+		return []*fleet.Pack{{ID: 1, Name: "test-pack-1"}}, nil
+	}
+	mockedDS.ListScheduledQueriesInPackFunc = func(ctx context.Context, packID uint) ([]*fleet.ScheduledQuery, error) {
+		scheduledQueriesCalled++
+		return []*fleet.ScheduledQuery{{ID: 1, Name: "test-schedule-1"}}, nil
+	}
+	mockedDS.SavePackFunc = func(ctx context.Context, pack *fleet.Pack) error {
+		return nil
+	}
+	mockedDS.SaveScheduledQueryFunc = func(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+		return sq, nil
+	}
+	mockedDS.SaveQueryFunc = func(ctx context.Context, query *fleet.Query) error {
+		return nil
+	}
+
+	load := func() {
+		_, err := ds.ListPacksForHost(context.Background(), 1)
+		require.NoError(t, err)
+		_, err = ds.ListScheduledQueriesInPack(context.Background(), 1)
+		require.NoError(t, err)
+	}
+
+	load()
+	load()
+	assert.Equal(t, 1, packsCalled)
+	assert.Equal(t, 1, scheduledQueriesCalled)
+
+	require.NoError(t, ds.SavePack(context.Background(), &fleet.Pack{ID: 1}))
+	load()
+	assert.Equal(t, 2, packsCalled)
+	assert.Equal(t, 2, scheduledQueriesCalled)
+
+	_, err := ds.SaveScheduledQuery(context.Background(), &fleet.ScheduledQuery{ID: 1})
+	require.NoError(t, err)
+	load()
+	assert.Equal(t, 3, packsCalled)
+	assert.Equal(t, 3, scheduledQueriesCalled)
+
+	require.NoError(t, ds.SaveQuery(context.Background(), &fleet.Query{ID: 1}))
+	load()
+	assert.Equal(t, 4, packsCalled)
+	assert.Equal(t, 4, scheduledQueriesCalled)
+}
+
+func TestCachedListDiscardDataScheduledQueryNames(t *testing.T) {
+	t.Parallel()
+
+	mockedDS := new(mock.Store)
+	ds := New(mockedDS)
+
+	called := 0
+	mockedDS.ListDiscardDataScheduledQueryNamesFunc = func(ctx context.Context) ([]fleet.PackScheduledQueryName, error) {
+		called++
+		return []fleet.PackScheduledQueryName{{PackName: "pack", ScheduledQueryName: "query"}}, nil
+	}
+	mockedDS.SaveScheduledQueryFunc = func(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+		return sq, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		names, err := ds.ListDiscardDataScheduledQueryNames(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []fleet.PackScheduledQueryName{{PackName: "pack", ScheduledQueryName: "query"}}, names)
+	}
+	assert.Equal(t, 1, called)
+
+	_, err := ds.SaveScheduledQuery(context.Background(), &fleet.ScheduledQuery{ID: 1, DiscardData: true})
+	require.NoError(t, err)
+	_, err = ds.ListDiscardDataScheduledQueryNames(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, called)
+}
@@ -0,0 +1,161 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// agentConfigVersionRow is an agent_config_versions row, with the JSON
+// columns that are decoded into the fleet.AgentConfigVersion fields.
+type agentConfigVersionRow struct {
+	fleet.AgentConfigVersion
+	ChangesJSON []byte `db:"changes"`
+	ConfigJSON  []byte `db:"config"`
+}
+
+func (r *agentConfigVersionRow) decode(withConfig bool) (*fleet.AgentConfigVersion, error) {
+	v := r.AgentConfigVersion
+	if err := json.Unmarshal(r.ChangesJSON, &v.Changes); err != nil {
+		return nil, err
+	}
+	if withConfig {
+		v.Config = &fleet.AgentConfig{}
+		if err := json.Unmarshal(r.ConfigJSON, v.Config); err != nil {
+			return nil, err
+		}
+	}
+	return &v, nil
+}
+
+// RecordAgentConfigVersion records the current agent configuration as a new
+// version, unless it did not change since the latest version.
+func (ds *Datastore) RecordAgentConfigVersion(ctx context.Context, user *fleet.User) error {
+	appConfig, err := appConfigDB(ctx, ds.writer)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "load agent options")
+	}
+	packs, err := ds.GetPackSpecs(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "load pack specs")
+	}
+	for _, pack := range packs {
+		// the IDs are not part of the configuration, and change when a
+		// pack is deleted and re-created.
+		pack.ID = 0
+	}
+	config := &fleet.AgentConfig{AgentOptions: appConfig.AgentOptions, Packs: packs}
+
+	var authorID *uint
+	if user != nil {
+		authorID = &user.ID
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var latest *fleet.AgentConfig
+		var latestJSON []byte
+		err := sqlx.GetContext(ctx, tx, &latestJSON, `SELECT config FROM agent_config_versions ORDER BY id DESC LIMIT 1 FOR UPDATE`)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return ctxerr.Wrap(ctx, err, "select latest agent config version")
+		default:
+			latest = &fleet.AgentConfig{}
+			if err := json.Unmarshal(latestJSON, latest); err != nil {
+				return ctxerr.Wrap(ctx, err, "unmarshal latest agent config version")
+			}
+		}
+
+		changes, err := fleet.DiffAgentConfigs(latest, config)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "diff agent config versions")
+		}
+		if latest != nil && len(changes) == 0 {
+			return nil
+		}
+		if changes == nil {
+			changes = []fleet.AgentConfigChange{}
+		}
+
+		configJSON, err := json.Marshal(config)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "marshal agent config")
+		}
+		changesJSON, err := json.Marshal(changes)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "marshal agent config changes")
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO agent_config_versions (author_id, config, changes) VALUES (?, ?, ?)`,
+			authorID, configJSON, changesJSON,
+		)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "insert agent config version")
+		}
+		return nil
+	})
+}
+
+// ListAgentConfigVersions lists the versions of the agent configuration, with
+// their changes but without their configuration.
+func (ds *Datastore) ListAgentConfigVersions(ctx context.Context, opt fleet.ListOptions) ([]*fleet.AgentConfigVersion, error) {
+	query := `
+		SELECT
+			v.id,
+			v.created_at,
+			v.author_id,
+			(SELECT u.name FROM users u WHERE u.id = v.author_id) AS author_name,
+			v.changes
+		FROM agent_config_versions v
+		WHERE true
+	`
+	query = appendListOptionsToSQL(query, opt)
+
+	var rows []*agentConfigVersionRow
+	if err := sqlx.SelectContext(ctx, ds.reader, &rows, query); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select agent config versions")
+	}
+
+	versions := make([]*fleet.AgentConfigVersion, 0, len(rows))
+	for _, row := range rows {
+		v, err := row.decode(false)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "decode agent config version")
+		}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// AgentConfigVersion returns the version of the agent configuration, with its
+// changes and its configuration.
+func (ds *Datastore) AgentConfigVersion(ctx context.Context, id uint) (*fleet.AgentConfigVersion, error) {
+	query := `
+		SELECT
+			v.id,
+			v.created_at,
+			v.author_id,
+			(SELECT u.name FROM users u WHERE u.id = v.author_id) AS author_name,
+			v.changes,
+			v.config
+		FROM agent_config_versions v
+		WHERE v.id = ?
+	`
+	var row agentConfigVersionRow
+	if err := sqlx.GetContext(ctx, ds.reader, &row, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("AgentConfigVersion").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "select agent config version")
+	}
+
+	v, err := row.decode(true)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "decode agent config version")
+	}
+	return v, nil
+}
@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentConfigVersions(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Record", testAgentConfigVersionsRecord},
+		{"AuthorDeleted", testAgentConfigVersionsAuthorDeleted},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testAgentConfigVersionsRecord(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+
+	origConfig, err := ds.AppConfig(ctx)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, ds.SaveAppConfig(ctx, origConfig))
+	}()
+
+	// the first version is always recorded
+	require.NoError(t, ds.RecordAgentConfigVersion(ctx, user))
+	versions, err := ds.ListAgentConfigVersions(ctx, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, user.ID, *versions[0].AuthorID)
+	assert.Equal(t, "Zach", *versions[0].AuthorName)
+	assert.Nil(t, versions[0].Config)
+
+	// nothing changed, no new version
+	require.NoError(t, ds.RecordAgentConfigVersion(ctx, user))
+	versions, err = ds.ListAgentConfigVersions(ctx, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+
+	q := test.NewQuery(t, ds, "time", "select * from time", user.ID, true)
+	require.NoError(t, ds.ApplyPackSpecs(ctx, []*fleet.PackSpec{{
+		Name:    "pack1",
+		Queries: []fleet.PackSpecQuery{{QueryName: q.Name, Name: "q1", Interval: 60}},
+	}}))
+	require.NoError(t, ds.RecordAgentConfigVersion(ctx, user))
+
+	appConfig, err := ds.AppConfig(ctx)
+	require.NoError(t, err)
+	appConfig.AgentOptions = ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":42}}}`))
+	require.NoError(t, ds.SaveAppConfig(ctx, appConfig))
+	require.NoError(t, ds.RecordAgentConfigVersion(ctx, nil))
+
+	versions, err = ds.ListAgentConfigVersions(ctx, fleet.ListOptions{OrderKey: "id", OrderDirection: fleet.OrderDescending})
+	require.NoError(t, err)
+	require.Len(t, versions, 3)
+	assert.Nil(t, versions[0].AuthorID)
+	assert.Nil(t, versions[0].AuthorName)
+	require.Len(t, versions[0].Changes, 1)
+	assert.Equal(t, "agent_options.config.options.distributed_interval", versions[0].Changes[0].Path)
+	assert.Equal(t, float64(42), versions[0].Changes[0].New)
+	require.Len(t, versions[1].Changes, 1)
+	assert.Equal(t, "packs.pack1", versions[1].Changes[0].Path)
+	assert.Nil(t, versions[1].Changes[0].Old)
+
+	version, err := ds.AgentConfigVersion(ctx, versions[1].ID)
+	require.NoError(t, err)
+	require.NotNil(t, version.Config)
+	require.Len(t, version.Config.Packs, 1)
+	assert.Equal(t, "pack1", version.Config.Packs[0].Name)
+	assert.Zero(t, version.Config.Packs[0].ID)
+	require.Len(t, version.Config.Packs[0].Queries, 1)
+	assert.Equal(t, uint(60), version.Config.Packs[0].Queries[0].Interval)
+
+	_, err = ds.AgentConfigVersion(ctx, versions[0].ID+1)
+	require.Error(t, err)
+	var nfe fleet.NotFoundError
+	require.ErrorAs(t, err, &nfe)
+}
+
+func testAgentConfigVersionsAuthorDeleted(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+
+	require.NoError(t, ds.RecordAgentConfigVersion(ctx, user))
+	require.NoError(t, ds.DeleteUser(ctx, user.ID))
+
+	versions, err := ds.ListAgentConfigVersions(ctx, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Nil(t, versions[0].AuthorID)
+	assert.Nil(t, versions[0].AuthorName)
+}
@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+const agentOptionsRolloutColumns = `id, author_id, agent_options, percentage, label_id, status, created_at, updated_at`
+
+func (ds *Datastore) NewAgentOptionsRollout(ctx context.Context, rollout *fleet.AgentOptionsRollout) (*fleet.AgentOptionsRollout, error) {
+	res, err := ds.writer.ExecContext(ctx, `
+		INSERT INTO agent_options_rollouts (author_id, agent_options, percentage, label_id, status)
+		VALUES (?, ?, ?, ?, ?)`,
+		rollout.AuthorID, rollout.AgentOptions, rollout.Percentage, rollout.LabelID, rollout.Status,
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "insert agent options rollout")
+	}
+	id, _ := res.LastInsertId()
+	return ds.AgentOptionsRollout(ctx, uint(id))
+}
+
+func (ds *Datastore) AgentOptionsRollout(ctx context.Context, id uint) (*fleet.AgentOptionsRollout, error) {
+	var rollout fleet.AgentOptionsRollout
+	err := sqlx.GetContext(ctx, ds.writer, &rollout,
+		`SELECT `+agentOptionsRolloutColumns+` FROM agent_options_rollouts WHERE id = ?`, id,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("AgentOptionsRollout").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get agent options rollout")
+	}
+	return &rollout, nil
+}
+
+func (ds *Datastore) ActiveAgentOptionsRollout(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+	var rollout fleet.AgentOptionsRollout
+	err := sqlx.GetContext(ctx, ds.reader, &rollout,
+		`SELECT `+agentOptionsRolloutColumns+` FROM agent_options_rollouts WHERE status = ? ORDER BY id DESC LIMIT 1`,
+		fleet.AgentOptionsRolloutStatusActive,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("AgentOptionsRollout"))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get active agent options rollout")
+	}
+	return &rollout, nil
+}
+
+func (ds *Datastore) ListAgentOptionsRollouts(ctx context.Context, opt fleet.ListOptions) ([]*fleet.AgentOptionsRollout, error) {
+	query := `SELECT ` + agentOptionsRolloutColumns + ` FROM agent_options_rollouts WHERE true`
+	query = appendListOptionsToSQL(query, opt)
+
+	rollouts := []*fleet.AgentOptionsRollout{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &rollouts, query); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list agent options rollouts")
+	}
+	return rollouts, nil
+}
+
+// EndAgentOptionsRollout sets the final status of the rollout, which must be
+// active.
+func (ds *Datastore) EndAgentOptionsRollout(ctx context.Context, id uint, status string) error {
+	res, err := ds.writer.ExecContext(ctx,
+		`UPDATE agent_options_rollouts SET status = ? WHERE id = ? AND status = ?`,
+		status, id, fleet.AgentOptionsRolloutStatusActive,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "update agent options rollout status")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("active AgentOptionsRollout").WithID(id))
+	}
+	return nil
+}
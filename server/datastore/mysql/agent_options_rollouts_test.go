@@ -0,0 +1,108 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentOptionsRollouts(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Lifecycle", testAgentOptionsRolloutsLifecycle},
+		{"LabelDeleted", testAgentOptionsRolloutsLabelDeleted},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testAgentOptionsRolloutsLifecycle(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+
+	_, err := ds.ActiveAgentOptionsRollout(ctx)
+	require.True(t, fleet.IsNotFound(err))
+
+	rollout, err := ds.NewAgentOptionsRollout(ctx, &fleet.AgentOptionsRollout{
+		AuthorID:     &user.ID,
+		AgentOptions: json.RawMessage(`{"config":{"options":{"distributed_interval":42}}}`),
+		Percentage:   10,
+		Status:       fleet.AgentOptionsRolloutStatusActive,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, rollout.ID)
+	assert.Equal(t, user.ID, *rollout.AuthorID)
+	assert.Equal(t, uint(10), rollout.Percentage)
+	assert.Nil(t, rollout.LabelID)
+	assert.JSONEq(t, `{"config":{"options":{"distributed_interval":42}}}`, string(rollout.AgentOptions))
+	assert.False(t, rollout.CreatedAt.IsZero())
+
+	active, err := ds.ActiveAgentOptionsRollout(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, rollout.ID, active.ID)
+
+	require.NoError(t, ds.EndAgentOptionsRollout(ctx, rollout.ID, fleet.AgentOptionsRolloutStatusAborted))
+	_, err = ds.ActiveAgentOptionsRollout(ctx)
+	require.True(t, fleet.IsNotFound(err))
+
+	// a rollout can only be ended once
+	err = ds.EndAgentOptionsRollout(ctx, rollout.ID, fleet.AgentOptionsRolloutStatusPromoted)
+	require.True(t, fleet.IsNotFound(err))
+
+	got, err := ds.AgentOptionsRollout(ctx, rollout.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.AgentOptionsRolloutStatusAborted, got.Status)
+
+	second, err := ds.NewAgentOptionsRollout(ctx, &fleet.AgentOptionsRollout{
+		AgentOptions: json.RawMessage(`{"config":{}}`),
+		Percentage:   50,
+		Status:       fleet.AgentOptionsRolloutStatusActive,
+	})
+	require.NoError(t, err)
+	assert.Nil(t, second.AuthorID)
+
+	rollouts, err := ds.ListAgentOptionsRollouts(ctx, fleet.ListOptions{OrderKey: "id", OrderDirection: fleet.OrderDescending})
+	require.NoError(t, err)
+	require.Len(t, rollouts, 2)
+	assert.Equal(t, second.ID, rollouts[0].ID)
+	assert.Equal(t, fleet.AgentOptionsRolloutStatusActive, rollouts[0].Status)
+	assert.Equal(t, rollout.ID, rollouts[1].ID)
+
+	_, err = ds.AgentOptionsRollout(ctx, second.ID+1)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testAgentOptionsRolloutsLabelDeleted(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	label, err := ds.NewLabel(ctx, &fleet.Label{Name: "canary", Query: "select 1"})
+	require.NoError(t, err)
+
+	rollout, err := ds.NewAgentOptionsRollout(ctx, &fleet.AgentOptionsRollout{
+		AgentOptions: json.RawMessage(`{"config":{}}`),
+		LabelID:      ptr.Uint(label.ID),
+		Status:       fleet.AgentOptionsRolloutStatusActive,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, label.ID, *rollout.LabelID)
+
+	require.NoError(t, ds.DeleteLabel(ctx, label.Name))
+
+	rollout, err = ds.AgentOptionsRollout(ctx, rollout.ID)
+	require.NoError(t, err)
+	assert.Nil(t, rollout.LabelID)
+}
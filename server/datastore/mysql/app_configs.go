@@ -73,42 +73,78 @@ func (ds *Datastore) SaveAppConfig(ctx context.Context, info *fleet.AppConfig) e
 
 func (ds *Datastore) VerifyEnrollSecret(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
 	var s fleet.EnrollSecret
-	err := sqlx.GetContext(ctx, ds.reader, &s, "SELECT team_id FROM enroll_secrets WHERE secret = ?", secret)
+	err := sqlx.GetContext(ctx, ds.reader, &s, "SELECT team_id, expires_at, max_uses, uses FROM enroll_secrets WHERE secret = ?", secret)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ctxerr.New(ctx, "no matching secret found")
+			return nil, ctxerr.Wrap(ctx, fleet.ErrEnrollSecretNotFound)
 		}
 		return nil, ctxerr.Wrap(ctx, err, "verify enroll secret")
 	}
+	if s.ExpiresAt != nil && !s.ExpiresAt.After(ds.clock.Now()) {
+		return nil, ctxerr.Wrap(ctx, fleet.ErrEnrollSecretExpired, "verify enroll secret")
+	}
+	s.Secret = secret
 
 	return &s, nil
 }
 
+// consumeEnrollSecretDB increments the number of uses of the enroll secret.
+// It returns fleet.ErrEnrollSecretExhausted if the secret already reached its
+// maximum number of uses.
+func consumeEnrollSecretDB(ctx context.Context, tx sqlx.ExecerContext, secret string) error {
+	// the condition on max_uses makes the increment atomic, so that concurrent
+	// enrollments cannot go over the limit.
+	res, err := tx.ExecContext(ctx, `
+		UPDATE enroll_secrets
+		SET uses = uses + 1
+		WHERE secret = ? AND (max_uses IS NULL OR uses < max_uses)`,
+		secret,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "consume enroll secret")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, fleet.ErrEnrollSecretExhausted, "consume enroll secret")
+	}
+	return nil
+}
+
 func (ds *Datastore) ApplyEnrollSecrets(ctx context.Context, teamID *uint, secrets []*fleet.EnrollSecret) error {
 	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
 		return applyEnrollSecretsDB(ctx, tx, teamID, secrets)
 	})
 }
 
-func applyEnrollSecretsDB(ctx context.Context, exec sqlx.ExecerContext, teamID *uint, secrets []*fleet.EnrollSecret) error {
+func applyEnrollSecretsDB(ctx context.Context, q sqlx.ExtContext, teamID *uint, secrets []*fleet.EnrollSecret) error {
+	// keep track of the uses of existing secrets, so that re-applying a
+	// secret does not reset its usage count.
+	existing, err := getEnrollSecretsDB(ctx, q, teamID)
+	if err != nil {
+		return err
+	}
+	uses := make(map[string]uint, len(existing))
+	for _, s := range existing {
+		uses[s.Secret] = s.Uses
+	}
+
 	if teamID != nil {
 		sql := `DELETE FROM enroll_secrets WHERE team_id = ?`
-		if _, err := exec.ExecContext(ctx, sql, teamID); err != nil {
+		if _, err := q.ExecContext(ctx, sql, teamID); err != nil {
 			return ctxerr.Wrap(ctx, err, "clear before insert")
 		}
 	} else {
 		sql := `DELETE FROM enroll_secrets WHERE team_id IS NULL`
-		if _, err := exec.ExecContext(ctx, sql); err != nil {
+		if _, err := q.ExecContext(ctx, sql); err != nil {
 			return ctxerr.Wrap(ctx, err, "clear before insert")
 		}
 	}
 
 	for _, secret := range secrets {
 		sql := `
-				INSERT INTO enroll_secrets (secret, team_id)
-				VALUES ( ?, ? )
+				INSERT INTO enroll_secrets (secret, team_id, expires_at, max_uses, uses)
+				VALUES ( ?, ?, ?, ?, ? )
 			`
-		if _, err := exec.ExecContext(ctx, sql, secret.Secret, teamID); err != nil {
+		if _, err := q.ExecContext(ctx, sql, secret.Secret, teamID, secret.ExpiresAt, secret.MaxUses, uses[secret.Secret]); err != nil {
 			return ctxerr.Wrap(ctx, err, "upsert secret")
 		}
 	}
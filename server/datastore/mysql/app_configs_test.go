@@ -27,6 +27,7 @@ func TestAppConfig(t *testing.T) {
 		{"EnrollSecretsCaseSensitive", testAppConfigEnrollSecretsCaseSensitive},
 		{"EnrollSecretRoundtrip", testAppConfigEnrollSecretRoundtrip},
 		{"EnrollSecretUniqueness", testAppConfigEnrollSecretUniqueness},
+		{"EnrollSecretExpiryAndUses", testAppConfigEnrollSecretExpiryAndUses},
 		{"Defaults", testAppConfigDefaults},
 	}
 	for _, c := range cases {
@@ -270,6 +271,80 @@ func testAppConfigEnrollSecretUniqueness(t *testing.T, ds *Datastore) {
 	require.Error(t, err)
 }
 
+func testAppConfigEnrollSecretExpiryAndUses(t *testing.T, ds *Datastore) {
+	defer TruncateTables(t, ds)
+
+	ctx := context.Background()
+	err := ds.ApplyEnrollSecrets(ctx, nil, []*fleet.EnrollSecret{
+		{Secret: "expired", ExpiresAt: ptr.Time(time.Now().Add(-time.Hour))},
+		{Secret: "not_expired", ExpiresAt: ptr.Time(time.Now().Add(time.Hour))},
+		{Secret: "two_uses", MaxUses: ptr.Uint(2)},
+	})
+	require.NoError(t, err)
+
+	_, err = ds.VerifyEnrollSecret(ctx, "expired")
+	require.ErrorIs(t, err, fleet.ErrEnrollSecretExpired)
+	_, err = ds.VerifyEnrollSecret(ctx, "not_expired")
+	require.NoError(t, err)
+
+	checkUses := func(uses uint) {
+		secret, err := ds.VerifyEnrollSecret(ctx, "two_uses")
+		require.NoError(t, err)
+		require.Equal(t, uses, secret.Uses)
+	}
+
+	// enrolling a new host uses the secret
+	checkUses(0)
+	_, err = ds.EnrollHost(ctx, "host1", "host1key", nil, 0, "two_uses")
+	require.NoError(t, err)
+	checkUses(1)
+
+	// re-enrolling the same host does not
+	_, err = ds.EnrollHost(ctx, "host1", "host1key2", nil, 0, "two_uses")
+	require.NoError(t, err)
+	checkUses(1)
+
+	// nor does an enrollment rejected because of the cooldown
+	_, err = ds.EnrollHost(ctx, "host1", "host1key3", nil, time.Hour, "two_uses")
+	require.ErrorIs(t, err, fleet.ErrHostEnrollCooldown)
+	checkUses(1)
+
+	_, err = ds.EnrollHost(ctx, "host2", "host2key", nil, 0, "two_uses")
+	require.NoError(t, err)
+	checkUses(2)
+
+	// an exhausted secret does not enroll new hosts
+	_, err = ds.EnrollHost(ctx, "host3", "host3key", nil, 0, "two_uses")
+	require.ErrorIs(t, err, fleet.ErrEnrollSecretExhausted)
+	_, err = ds.LoadHostByNodeKey(ctx, "host3key")
+	require.True(t, fleet.IsNotFound(err))
+
+	// but the hosts enrolled with it can still re-enroll
+	_, err = ds.EnrollHost(ctx, "host1", "host1key4", nil, 0, "two_uses")
+	require.NoError(t, err)
+	_, err = ds.LoadHostByNodeKey(ctx, "host1key4")
+	require.NoError(t, err)
+	checkUses(2)
+
+	// re-applying the secrets does not reset the uses
+	err = ds.ApplyEnrollSecrets(ctx, nil, []*fleet.EnrollSecret{
+		{Secret: "two_uses", MaxUses: ptr.Uint(2)},
+	})
+	require.NoError(t, err)
+	checkUses(2)
+	_, err = ds.EnrollHost(ctx, "host3", "host3key", nil, 0, "two_uses")
+	require.ErrorIs(t, err, fleet.ErrEnrollSecretExhausted)
+
+	// raising the maximum allows more enrollments
+	err = ds.ApplyEnrollSecrets(ctx, nil, []*fleet.EnrollSecret{
+		{Secret: "two_uses", MaxUses: ptr.Uint(3)},
+	})
+	require.NoError(t, err)
+	secret, err := ds.VerifyEnrollSecret(ctx, "two_uses")
+	require.NoError(t, err)
+	require.Equal(t, uint(2), secret.Uses)
+}
+
 func testAppConfigDefaults(t *testing.T, ds *Datastore) {
 	insertAppConfigQuery := `INSERT INTO app_config_json(json_value) VALUES(?) ON DUPLICATE KEY UPDATE json_value = VALUES(json_value)`
 	_, err := ds.writer.Exec(insertAppConfigQuery, `{}`)
@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewATCTable(ctx context.Context, table *fleet.ATCTable) (*fleet.ATCTable, error) {
+	sqlStatement := `
+		INSERT INTO atc_tables (
+			name,
+			query,
+			path,
+			columns,
+			platform
+		) VALUES ( ?, ?, ?, ?, ? )
+	`
+	result, err := ds.writer.ExecContext(ctx, sqlStatement, table.Name, table.Query, table.Path, table.Columns, table.Platform)
+	if err != nil && isDuplicate(err) {
+		return nil, ctxerr.Wrap(ctx, alreadyExists("ATCTable", table.Name))
+	} else if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "creating new atc table")
+	}
+
+	id, _ := result.LastInsertId()
+	return atcTableDB(ctx, ds.writer, uint(id))
+}
+
+func (ds *Datastore) ATCTable(ctx context.Context, id uint) (*fleet.ATCTable, error) {
+	return atcTableDB(ctx, ds.reader, id)
+}
+
+func atcTableDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.ATCTable, error) {
+	var table fleet.ATCTable
+	err := sqlx.GetContext(ctx, q, &table, `SELECT * FROM atc_tables WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("ATCTable").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting atc table")
+	}
+	return &table, nil
+}
+
+func (ds *Datastore) ListATCTables(ctx context.Context) ([]*fleet.ATCTable, error) {
+	var tables []*fleet.ATCTable
+	if err := sqlx.SelectContext(ctx, ds.reader, &tables, `SELECT * FROM atc_tables ORDER BY name`); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing atc tables")
+	}
+	return tables, nil
+}
+
+func (ds *Datastore) SaveATCTable(ctx context.Context, table *fleet.ATCTable) error {
+	sqlStatement := `
+		UPDATE atc_tables
+			SET name = ?, query = ?, path = ?, columns = ?, platform = ?
+			WHERE id = ?
+	`
+	result, err := ds.writer.ExecContext(ctx, sqlStatement, table.Name, table.Query, table.Path, table.Columns, table.Platform, table.ID)
+	if err != nil && isDuplicate(err) {
+		return ctxerr.Wrap(ctx, alreadyExists("ATCTable", table.Name))
+	} else if err != nil {
+		return ctxerr.Wrap(ctx, err, "updating atc table")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "rows affected updating atc table")
+	}
+	if rows == 0 {
+		return ctxerr.Wrap(ctx, notFound("ATCTable").WithID(table.ID))
+	}
+	return nil
+}
+
+func (ds *Datastore) DeleteATCTable(ctx context.Context, id uint) error {
+	return ds.deleteEntity(ctx, atcTablesTable, id)
+}
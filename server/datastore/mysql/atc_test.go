@@ -0,0 +1,52 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestATCTables(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	table, err := ds.NewATCTable(ctx, &fleet.ATCTable{
+		Name:     "tcc_entries",
+		Query:    "SELECT service, client FROM access",
+		Path:     "/Library/Application Support/com.apple.TCC/TCC.db",
+		Columns:  fleet.ATCColumns{"service", "client"},
+		Platform: "darwin",
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, table.ID)
+	assert.Equal(t, fleet.ATCColumns{"service", "client"}, table.Columns)
+
+	_, err = ds.NewATCTable(ctx, &fleet.ATCTable{Name: "tcc_entries", Query: "SELECT 1", Path: "/tmp/x.db", Columns: fleet.ATCColumns{"a"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+
+	other, err := ds.NewATCTable(ctx, &fleet.ATCTable{Name: "browser_history", Query: "SELECT url FROM urls", Path: "/tmp/History", Columns: fleet.ATCColumns{"url"}})
+	require.NoError(t, err)
+
+	tables, err := ds.ListATCTables(ctx)
+	require.NoError(t, err)
+	require.Len(t, tables, 2)
+	assert.Equal(t, "browser_history", tables[0].Name)
+	assert.Equal(t, "tcc_entries", tables[1].Name)
+
+	table.Columns = fleet.ATCColumns{"service"}
+	table.Platform = ""
+	require.NoError(t, ds.SaveATCTable(ctx, table))
+	got, err := ds.ATCTable(ctx, table.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.ATCColumns{"service"}, got.Columns)
+	assert.Empty(t, got.Platform)
+
+	require.NoError(t, ds.DeleteATCTable(ctx, other.ID))
+	_, err = ds.ATCTable(ctx, other.ID)
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+}
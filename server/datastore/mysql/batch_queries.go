@@ -0,0 +1,173 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewBatchQuery(ctx context.Context, bq *fleet.BatchQuery) error {
+	stmt := `
+		INSERT INTO batch_queries (
+			distributed_query_campaign_id,
+			completion_threshold,
+			targeted_hosts,
+			expires_at
+		)
+		VALUES (?, ?, ?, ?)
+	`
+	if _, err := ds.writer.ExecContext(ctx, stmt, bq.CampaignID, bq.CompletionThreshold, bq.TargetedHosts, bq.ExpiresAt); err != nil {
+		return ctxerr.Wrap(ctx, err, "insert batch query")
+	}
+	return nil
+}
+
+const selectBatchQueryStmt = `
+	SELECT
+		bq.distributed_query_campaign_id,
+		bq.created_at,
+		c.query_id,
+		c.status,
+		bq.completion_threshold,
+		bq.targeted_hosts,
+		bq.responded_hosts,
+		bq.expires_at,
+		bq.notified_at
+	FROM batch_queries bq
+	JOIN distributed_query_campaigns c ON c.id = bq.distributed_query_campaign_id
+	WHERE bq.distributed_query_campaign_id = ?
+`
+
+func (ds *Datastore) BatchQuery(ctx context.Context, campaignID uint) (*fleet.BatchQuery, error) {
+	return batchQueryDB(ctx, ds.reader, campaignID)
+}
+
+func batchQueryDB(ctx context.Context, q sqlx.QueryerContext, campaignID uint) (*fleet.BatchQuery, error) {
+	var bq fleet.BatchQuery
+	if err := sqlx.GetContext(ctx, q, &bq, selectBatchQueryStmt, campaignID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("BatchQuery").WithID(campaignID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "select batch query")
+	}
+	return &bq, nil
+}
+
+// batchQueryResultsBatchSize is the maximum number of results of a batch
+// query inserted at once.
+const batchQueryResultsBatchSize = 500
+
+func (ds *Datastore) NewBatchQueryResults(ctx context.Context, campaignID uint, results []*fleet.BatchQueryResult) (*fleet.BatchQuery, error) {
+	var bq *fleet.BatchQuery
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		// the hosts may send their result again, only the first one is
+		// stored and counted
+		var inserted int64
+		for rest := results; len(rest) > 0; {
+			batch := rest
+			if len(batch) > batchQueryResultsBatchSize {
+				batch = batch[:batchQueryResultsBatchSize]
+			}
+			rest = rest[len(batch):]
+
+			args := make([]interface{}, 0, len(batch)*6)
+			for _, res := range batch {
+				args = append(args, campaignID, res.HostID, res.Hostname, res.Rows, res.Error, res.Truncated)
+			}
+			stmt := `
+				INSERT IGNORE INTO batch_query_results (
+					distributed_query_campaign_id,
+					host_id,
+					hostname,
+					result_rows,
+					error,
+					truncated
+				)
+				VALUES ` + strings.TrimSuffix(strings.Repeat(`(?, ?, ?, ?, ?, ?),`, len(batch)), ",")
+			r, err := tx.ExecContext(ctx, stmt, args...)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "insert batch query results")
+			}
+			n, _ := r.RowsAffected()
+			inserted += n
+		}
+		if inserted > 0 {
+			stmt := `UPDATE batch_queries SET responded_hosts = responded_hosts + ? WHERE distributed_query_campaign_id = ?`
+			if _, err := tx.ExecContext(ctx, stmt, inserted, campaignID); err != nil {
+				return ctxerr.Wrap(ctx, err, "update batch query responded hosts")
+			}
+		}
+
+		bq, err = batchQueryDB(ctx, tx, campaignID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bq, nil
+}
+
+func (ds *Datastore) MarkBatchQueryNotified(ctx context.Context, campaignID uint, now time.Time) (bool, error) {
+	stmt := `UPDATE batch_queries SET notified_at = ? WHERE distributed_query_campaign_id = ? AND notified_at IS NULL`
+	res, err := ds.writer.ExecContext(ctx, stmt, now, campaignID)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "mark batch query notified")
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// batchQueryResultsOrderKeys are the supported order keys of the results of a
+// batch query.
+var batchQueryResultsOrderKeys = map[string]bool{
+	"id":         true,
+	"host_id":    true,
+	"hostname":   true,
+	"created_at": true,
+}
+
+func (ds *Datastore) ListBatchQueryResults(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.BatchQueryResult, error) {
+	stmt := `
+		SELECT
+			host_id,
+			hostname,
+			result_rows,
+			error,
+			truncated,
+			created_at
+		FROM batch_query_results
+		WHERE distributed_query_campaign_id = ?`
+	args := []interface{}{campaignID}
+
+	if opt.OrderKey == "" {
+		opt.OrderKey = "id"
+	}
+	if !batchQueryResultsOrderKeys[opt.OrderKey] {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("order_key", "unsupported order key: "+opt.OrderKey))
+	}
+	stmt, args = appendListOptionsWithCursorToSQL(stmt, args, opt)
+
+	results := []*fleet.BatchQueryResult{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &results, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select batch query results")
+	}
+	return results, nil
+}
+
+// batchQueryRetention is how long the batch queries and their results are
+// kept once they expired.
+const batchQueryRetention = 30 * 24 * time.Hour
+
+func (ds *Datastore) CleanupBatchQueries(ctx context.Context, now time.Time) error {
+	// the results are deleted in cascade
+	stmt := `DELETE FROM batch_queries WHERE expires_at < ?`
+	if _, err := ds.writer.ExecContext(ctx, stmt, now.Add(-batchQueryRetention)); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete expired batch queries")
+	}
+	return nil
+}
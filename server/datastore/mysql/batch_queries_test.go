@@ -0,0 +1,172 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchQueries(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Results", testBatchQueriesResults},
+		{"Expiration", testBatchQueriesExpiration},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func newTestBatchQuery(t *testing.T, ds *Datastore, userID uint, targetedHosts uint, expiresAt time.Time) *fleet.DistributedQueryCampaign {
+	ctx := context.Background()
+	query := test.NewQuery(t, ds, "batch", "select * from time", userID, false)
+	campaign, err := ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
+		QueryID: query.ID,
+		Status:  fleet.QueryRunning,
+		UserID:  userID,
+		Batch:   true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, ds.NewBatchQuery(ctx, &fleet.BatchQuery{
+		CampaignID:          campaign.ID,
+		CompletionThreshold: 50,
+		TargetedHosts:       targetedHosts,
+		ExpiresAt:           expiresAt,
+	}))
+	return campaign
+}
+
+func testBatchQueriesResults(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	campaign := newTestBatchQuery(t, ds, user.ID, 3, time.Now().Add(time.Hour))
+
+	bq, err := ds.BatchQuery(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, campaign.ID, bq.CampaignID)
+	assert.Equal(t, campaign.QueryID, bq.QueryID)
+	assert.Equal(t, fleet.QueryRunning, bq.Status)
+	assert.Equal(t, uint(50), bq.CompletionThreshold)
+	assert.Equal(t, uint(3), bq.TargetedHosts)
+	assert.Zero(t, bq.RespondedHosts)
+	assert.Nil(t, bq.NotifiedAt)
+	assert.False(t, bq.ThresholdReached())
+
+	_, err = ds.BatchQuery(ctx, campaign.ID+1)
+	require.True(t, fleet.IsNotFound(err))
+
+	bq, err = ds.NewBatchQueryResults(ctx, campaign.ID, []*fleet.BatchQueryResult{{
+		HostID:   1,
+		Hostname: "foo",
+		Rows:     json.RawMessage(`[{"a":"1"}]`),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), bq.RespondedHosts)
+	assert.False(t, bq.ThresholdReached())
+
+	// only the first result of a host is stored and counted, in the same
+	// batch or not
+	bq, err = ds.NewBatchQueryResults(ctx, campaign.ID, []*fleet.BatchQueryResult{{
+		HostID:   1,
+		Hostname: "foo",
+		Rows:     json.RawMessage(`[{"a":"2"}]`),
+	}, {
+		HostID:    2,
+		Hostname:  "bar",
+		Rows:      json.RawMessage(`[]`),
+		Error:     ptr.String("failed"),
+		Truncated: true,
+	}, {
+		HostID:   2,
+		Hostname: "bar",
+		Rows:     json.RawMessage(`[{"a":"3"}]`),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), bq.RespondedHosts)
+	assert.True(t, bq.ThresholdReached())
+
+	// the threshold is notified only once
+	notified, err := ds.MarkBatchQueryNotified(ctx, campaign.ID, time.Now())
+	require.NoError(t, err)
+	assert.True(t, notified)
+	notified, err = ds.MarkBatchQueryNotified(ctx, campaign.ID, time.Now())
+	require.NoError(t, err)
+	assert.False(t, notified)
+	bq, err = ds.BatchQuery(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, bq.NotifiedAt)
+
+	results, err := ds.ListBatchQueryResults(ctx, campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, uint(1), results[0].HostID)
+	assert.Equal(t, "foo", results[0].Hostname)
+	assert.JSONEq(t, `[{"a":"1"}]`, string(results[0].Rows))
+	assert.Nil(t, results[0].Error)
+	assert.False(t, results[0].Truncated)
+	assert.Equal(t, uint(2), results[1].HostID)
+	assert.Equal(t, ptr.String("failed"), results[1].Error)
+	assert.True(t, results[1].Truncated)
+
+	results, err = ds.ListBatchQueryResults(ctx, campaign.ID, fleet.ListOptions{OrderKey: "hostname", PerPage: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "bar", results[0].Hostname)
+
+	_, err = ds.ListBatchQueryResults(ctx, campaign.ID, fleet.ListOptions{OrderKey: "result_rows"})
+	require.Error(t, err)
+}
+
+func testBatchQueriesExpiration(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	now := time.Now()
+	campaign := newTestBatchQuery(t, ds, user.ID, 1, now.Add(48*time.Hour))
+	_, err := ds.NewBatchQueryResults(ctx, campaign.ID, []*fleet.BatchQueryResult{{HostID: 1, Rows: json.RawMessage(`[]`)}})
+	require.NoError(t, err)
+
+	// the batch queries are not limited like the live queries
+	count, err := ds.CountActiveDistributedQueryCampaigns(ctx, nil)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	// the campaign is not expired by the TTL of the live queries
+	expired, err := ds.CleanupDistributedQueryCampaigns(ctx, now.Add(25*time.Hour), 24*time.Hour)
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	// but once the batch query expired
+	expired, err = ds.CleanupDistributedQueryCampaigns(ctx, now.Add(49*time.Hour), 24*time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{campaign.ID}, expired)
+	bq, err := ds.BatchQuery(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.QueryComplete, bq.Status)
+
+	// the batch query and its results are kept for a month
+	require.NoError(t, ds.CleanupBatchQueries(ctx, now.Add(49*time.Hour)))
+	results, err := ds.ListBatchQueryResults(ctx, campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	require.NoError(t, ds.CleanupBatchQueries(ctx, now.Add(48*time.Hour+batchQueryRetention+time.Minute)))
+	_, err = ds.BatchQuery(ctx, campaign.ID)
+	require.True(t, fleet.IsNotFound(err))
+	results, err = ds.ListBatchQueryResults(ctx, campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
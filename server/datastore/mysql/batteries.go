@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) ReplaceHostBatteries(ctx context.Context, hostID uint, batteries []fleet.HostBattery) error {
+	const (
+		selStmt = `SELECT serial_number FROM host_batteries WHERE host_id = ?`
+		delStmt = `DELETE FROM host_batteries WHERE host_id = ? AND serial_number = ?`
+		insStmt = "INSERT INTO host_batteries (host_id, serial_number, cycle_count, health, `condition`) VALUES"
+		insPart = ` (?, ?, ?, ?, ?),`
+		// the health of the batteries changes over time, update the existing ones
+		updPart = " ON DUPLICATE KEY UPDATE cycle_count = VALUES(cycle_count), health = VALUES(health), `condition` = VALUES(`condition`)"
+	)
+
+	bySerial := make(map[string]fleet.HostBattery, len(batteries))
+	for _, b := range batteries {
+		bySerial[b.SerialNumber] = b
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var prevSerials []string
+		if err := sqlx.SelectContext(ctx, tx, &prevSerials, selStmt, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "select previous host batteries")
+		}
+
+		for _, serial := range prevSerials {
+			if _, ok := bySerial[serial]; ok {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, delStmt, hostID, serial); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete host battery")
+			}
+		}
+
+		if len(bySerial) > 0 {
+			args := make([]interface{}, 0, len(bySerial)*5)
+			for _, b := range bySerial {
+				args = append(args, hostID, b.SerialNumber, b.CycleCount, b.Health, b.Condition)
+			}
+			stmt := insStmt + strings.TrimSuffix(strings.Repeat(insPart, len(bySerial)), ",") + updPart
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert host batteries")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ListHostBatteries(ctx context.Context, hostID uint) ([]fleet.HostBattery, error) {
+	batteries := []fleet.HostBattery{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &batteries,
+		"SELECT serial_number, cycle_count, health, `condition` FROM host_batteries WHERE host_id = ? ORDER BY serial_number", hostID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host batteries")
+	}
+	return batteries, nil
+}
@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatteries(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"ReplaceHost", testBatteriesReplaceHost},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testBatteriesReplaceHost(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	batteries, err := ds.ListHostBatteries(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, batteries)
+
+	b1 := fleet.HostBattery{SerialNumber: "a", CycleCount: 10, Health: "Good", Condition: "Normal"}
+	b2 := fleet.HostBattery{SerialNumber: "b", CycleCount: 900, Health: "Poor", Condition: "Service recommended"}
+	require.NoError(t, ds.ReplaceHostBatteries(ctx, host.ID, []fleet.HostBattery{b2, b1}))
+	batteries, err = ds.ListHostBatteries(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostBattery{b1, b2}, batteries)
+
+	// replace updates the existing batteries and removes the missing ones
+	b1.CycleCount = 11
+	b1.Health = "Fair"
+	require.NoError(t, ds.ReplaceHostBatteries(ctx, host.ID, []fleet.HostBattery{b1}))
+	batteries, err = ds.ListHostBatteries(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostBattery{b1}, batteries)
+
+	// the batteries are removed with the host
+	require.NoError(t, ds.DeleteHost(ctx, host.ID))
+	batteries, err = ds.ListHostBatteries(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, batteries)
+}
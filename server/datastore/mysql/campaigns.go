@@ -2,6 +2,8 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
@@ -15,11 +17,24 @@ func (ds *Datastore) NewDistributedQueryCampaign(ctx context.Context, camp *flee
 		INSERT INTO distributed_query_campaigns (
 			query_id,
 			status,
-			user_id
+			user_id,
+			anonymize,
+			pseudonym_key,
+			label_expression,
+			max_rows,
+			max_result_bytes,
+			online_only,
+			batch,
+			duplicate_results
 		)
-		VALUES(?,?,?)
+		VALUES(?,?,?,?,?,?,?,?,?,?,?)
 	`
-	result, err := ds.writer.ExecContext(ctx, sqlStatement, camp.QueryID, camp.Status, camp.UserID)
+	result, err := ds.writer.ExecContext(
+		ctx, sqlStatement,
+		camp.QueryID, camp.Status, camp.UserID, camp.Anonymize, camp.PseudonymKey, camp.LabelExpression,
+		camp.MaxRows, camp.MaxResultBytes, camp.OnlineOnly, camp.Batch,
+		camp.DuplicateResults,
+	)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "inserting distributed query campaign")
 	}
@@ -30,11 +45,14 @@ func (ds *Datastore) NewDistributedQueryCampaign(ctx context.Context, camp *flee
 }
 
 func (ds *Datastore) DistributedQueryCampaign(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
-	sql := `
+	sqlStatement := `
 		SELECT * FROM distributed_query_campaigns WHERE id = ?
 	`
 	campaign := &fleet.DistributedQueryCampaign{}
-	if err := sqlx.GetContext(ctx, ds.reader, campaign, sql, id); err != nil {
+	if err := sqlx.GetContext(ctx, ds.reader, campaign, sqlStatement, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("DistributedQueryCampaign").WithID(id))
+		}
 		return nil, ctxerr.Wrap(ctx, err, "selecting distributed query campaign")
 	}
 
@@ -73,6 +91,138 @@ func (ds *Datastore) DistributedQueryCampaignsForQuery(ctx context.Context, quer
 	return campaigns, nil
 }
 
+func (ds *Datastore) CountActiveDistributedQueryCampaigns(ctx context.Context, userID *uint) (int, error) {
+	// the batch queries run for hours, they are not limited like the live
+	// queries
+	stmt := `SELECT COUNT(*) FROM distributed_query_campaigns WHERE status IN (?, ?) AND batch = 0`
+	args := []interface{}{fleet.QueryWaiting, fleet.QueryRunning}
+	if userID != nil {
+		stmt += ` AND user_id = ?`
+		args = append(args, *userID)
+	}
+	var count int
+	// use the primary so that the campaigns that were just created are counted
+	if err := sqlx.GetContext(ctx, ds.writer, &count, stmt, args...); err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "count active distributed query campaigns")
+	}
+	return count, nil
+}
+
+// distributedQueryExecutionsBatchSize is the number of executions inserted by
+// statement when a campaign is launched.
+const distributedQueryExecutionsBatchSize = 1000
+
+func (ds *Datastore) NewDistributedQueryExecutions(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+	// The online status must remain synchronized with CountHostsInTargets.
+	onlineSQL, onlineArgs := hostStatusSQL(fleet.StatusOnline, "h", now, settings)
+	stmt := fmt.Sprintf(`
+		INSERT IGNORE INTO distributed_query_executions (distributed_query_campaign_id, host_id, online_at_launch)
+		SELECT ?, h.id, %s
+		FROM hosts h
+		LEFT JOIN host_seen_times hst ON (h.id = hst.host_id)
+		WHERE h.id IN (?)
+	`, onlineSQL)
+
+	for len(hostIDs) > 0 {
+		batch := hostIDs
+		if len(batch) > distributedQueryExecutionsBatchSize {
+			batch = batch[:distributedQueryExecutionsBatchSize]
+		}
+		hostIDs = hostIDs[len(batch):]
+
+		args := append([]interface{}{campaignID}, onlineArgs...)
+		query, args, err := sqlx.In(stmt, append(args, batch)...)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "sqlx.In NewDistributedQueryExecutions")
+		}
+		if _, err := ds.writer.ExecContext(ctx, query, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert distributed query executions")
+		}
+	}
+	return nil
+}
+
+func (ds *Datastore) UpdateDistributedQueryExecutions(ctx context.Context, campaignID uint, hostIDs []uint, status fleet.DistributedQueryExecutionStatus) error {
+	// Only the first result of a host is accounted for.
+	stmt := `
+		UPDATE distributed_query_executions
+		SET status = ?
+		WHERE distributed_query_campaign_id = ? AND status = ? AND host_id IN (?)
+	`
+
+	for len(hostIDs) > 0 {
+		batch := hostIDs
+		if len(batch) > distributedQueryExecutionsBatchSize {
+			batch = batch[:distributedQueryExecutionsBatchSize]
+		}
+		hostIDs = hostIDs[len(batch):]
+
+		query, args, err := sqlx.In(stmt, status, campaignID, fleet.ExecutionPending, batch)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "sqlx.In UpdateDistributedQueryExecutions")
+		}
+		if _, err := ds.writer.ExecContext(ctx, query, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "update distributed query executions")
+		}
+	}
+	return nil
+}
+
+func (ds *Datastore) DeletePendingDistributedQueryExecutions(ctx context.Context, campaignID uint, hostIDs []uint) (uint, error) {
+	stmt := `
+		DELETE FROM distributed_query_executions
+		WHERE distributed_query_campaign_id = ? AND status = ? AND host_id IN (?)
+	`
+
+	var deleted uint
+	for len(hostIDs) > 0 {
+		batch := hostIDs
+		if len(batch) > distributedQueryExecutionsBatchSize {
+			batch = batch[:distributedQueryExecutionsBatchSize]
+		}
+		hostIDs = hostIDs[len(batch):]
+
+		query, args, err := sqlx.In(stmt, campaignID, fleet.ExecutionPending, batch)
+		if err != nil {
+			return 0, ctxerr.Wrap(ctx, err, "sqlx.In DeletePendingDistributedQueryExecutions")
+		}
+		res, err := ds.writer.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, ctxerr.Wrap(ctx, err, "delete pending distributed query executions")
+		}
+		n, _ := res.RowsAffected()
+		deleted += uint(n)
+	}
+	return deleted, nil
+}
+
+func (ds *Datastore) DistributedQueryCampaignProgress(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaignProgress, error) {
+	stmt := `
+		SELECT
+			COUNT(*) targeted_hosts,
+			COALESCE(SUM(online_at_launch), 0) online_hosts_at_launch,
+			COALESCE(SUM(status <> ?), 0) responded_hosts,
+			COALESCE(SUM(status = ?), 0) failed_hosts,
+			COALESCE(SUM(status = ?), 0) pending_hosts
+		FROM distributed_query_executions
+		WHERE distributed_query_campaign_id = ?
+	`
+	var progress fleet.DistributedQueryCampaignProgress
+	if err := sqlx.GetContext(ctx, ds.reader, &progress, stmt,
+		fleet.ExecutionPending, fleet.ExecutionFailed, fleet.ExecutionPending, campaignID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get distributed query campaign progress")
+	}
+	return &progress, nil
+}
+
+func (ds *Datastore) CleanupDistributedQueryExecutions(ctx context.Context, now time.Time) error {
+	stmt := `DELETE FROM distributed_query_executions WHERE created_at < ?`
+	if _, err := ds.writer.ExecContext(ctx, stmt, now.Add(-7*24*time.Hour)); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete distributed query executions")
+	}
+	return nil
+}
+
 func (ds *Datastore) DistributedQueryCampaignTargetIDs(ctx context.Context, id uint) (*fleet.HostTargets, error) {
 	sqlStatement := `
 		SELECT * FROM distributed_query_campaign_targets WHERE distributed_query_campaign_id = ?
@@ -86,7 +236,19 @@ func (ds *Datastore) DistributedQueryCampaignTargetIDs(ctx context.Context, id u
 	hostIDs := []uint{}
 	labelIDs := []uint{}
 	teamIDs := []uint{}
+	var excludedHostIDs, excludedLabelIDs []uint
 	for _, target := range targets {
+		switch {
+		case target.Excluded && target.Type == fleet.TargetHost:
+			excludedHostIDs = append(excludedHostIDs, target.TargetID)
+			continue
+		case target.Excluded && target.Type == fleet.TargetLabel:
+			excludedLabelIDs = append(excludedLabelIDs, target.TargetID)
+			continue
+		case target.Excluded:
+			return nil, ctxerr.Errorf(ctx, "invalid excluded target type: %d", target.Type)
+		}
+
 		switch target.Type {
 		case fleet.TargetHost:
 			hostIDs = append(hostIDs, target.TargetID)
@@ -99,7 +261,25 @@ func (ds *Datastore) DistributedQueryCampaignTargetIDs(ctx context.Context, id u
 		}
 	}
 
-	return &fleet.HostTargets{HostIDs: hostIDs, LabelIDs: labelIDs, TeamIDs: teamIDs}, nil
+	var campaign struct {
+		LabelExpression string `db:"label_expression"`
+		OnlineOnly      bool   `db:"online_only"`
+	}
+	err := sqlx.GetContext(ctx, ds.reader, &campaign, `SELECT label_expression, online_only FROM distributed_query_campaigns WHERE id = ?`, id)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, ctxerr.Wrap(ctx, err, "select distributed campaign label expression")
+	}
+
+	return &fleet.HostTargets{
+		HostIDs:         hostIDs,
+		LabelIDs:        labelIDs,
+		TeamIDs:         teamIDs,
+		LabelExpression: campaign.LabelExpression,
+		OnlineOnly:      campaign.OnlineOnly,
+
+		ExcludedHostIDs:  excludedHostIDs,
+		ExcludedLabelIDs: excludedLabelIDs,
+	}, nil
 }
 
 func (ds *Datastore) NewDistributedQueryCampaignTarget(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
@@ -107,11 +287,12 @@ func (ds *Datastore) NewDistributedQueryCampaignTarget(ctx context.Context, targ
 		INSERT into distributed_query_campaign_targets (
 			type,
 			distributed_query_campaign_id,
-			target_id
+			target_id,
+			excluded
 		)
-		VALUES (?,?,?)
+		VALUES (?,?,?,?)
 	`
-	result, err := ds.writer.ExecContext(ctx, sqlStatement, target.Type, target.DistributedQueryCampaignID, target.TargetID)
+	result, err := ds.writer.ExecContext(ctx, sqlStatement, target.Type, target.DistributedQueryCampaignID, target.TargetID, target.Excluded)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "insert distributed campaign target")
 	}
@@ -121,25 +302,54 @@ func (ds *Datastore) NewDistributedQueryCampaignTarget(ctx context.Context, targ
 	return target, nil
 }
 
-func (ds *Datastore) CleanupDistributedQueryCampaigns(ctx context.Context, now time.Time) (expired uint, err error) {
-	// Expire old waiting/running campaigns
-	sqlStatement := `
-		UPDATE distributed_query_campaigns
-		SET status = ?
-		WHERE (status = ? AND created_at < ?)
-		OR (status = ? AND created_at < ?)
-	`
-	result, err := ds.writer.ExecContext(ctx, sqlStatement, fleet.QueryComplete,
-		fleet.QueryWaiting, now.Add(-1*time.Minute),
-		fleet.QueryRunning, now.Add(-24*time.Hour))
-	if err != nil {
-		return 0, ctxerr.Wrap(ctx, err, "updating distributed query campaign")
-	}
+func (ds *Datastore) CleanupDistributedQueryCampaigns(ctx context.Context, now time.Time, runningTTL time.Duration) (expired []uint, err error) {
+	err = ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		// Get IDs of old waiting/running campaigns, and of the campaigns of
+		// expired batch queries
+		stmt := `
+			SELECT id
+			FROM distributed_query_campaigns
+			WHERE (batch = 0 AND status = ? AND created_at < ?)
+			OR (batch = 0 AND status = ? AND created_at < ?)
+			UNION
+			SELECT c.id
+			FROM distributed_query_campaigns c
+			JOIN batch_queries bq ON bq.distributed_query_campaign_id = c.id
+			WHERE c.status != ? AND bq.expires_at <= ?
+		`
+		expired = nil
+		if err := sqlx.SelectContext(ctx, tx, &expired, stmt,
+			fleet.QueryWaiting, now.Add(-1*time.Minute),
+			fleet.QueryRunning, now.Add(-runningTTL),
+			fleet.QueryComplete, now); err != nil {
+			return ctxerr.Wrap(ctx, err, "get expired distributed query campaigns")
+		}
+
+		if len(expired) == 0 {
+			// Nothing to do
+			return nil
+		}
+
+		// Expire them
+		stmt = `
+			UPDATE distributed_query_campaigns
+			SET status = ?
+			WHERE id IN (?)
+		`
+		stmt, args, err := sqlx.In(stmt, fleet.QueryComplete, expired)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "IN for UPDATE distributed_query_campaigns")
+		}
+		stmt = tx.Rebind(stmt)
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "updating distributed query campaign")
+		}
 
-	exp, err := result.RowsAffected()
+		return nil
+	})
 	if err != nil {
-		return 0, ctxerr.Wrap(ctx, err, "rows affected updating distributed query campaign")
+		return nil, err
 	}
 
-	return uint(exp), nil
+	return expired, nil
 }
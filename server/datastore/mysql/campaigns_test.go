@@ -7,6 +7,7 @@ import (
 
 	"github.com/WatchBeam/clock"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -22,6 +23,13 @@ func TestCampaigns(t *testing.T) {
 		{"DistributedQuery", testCampaignsDistributedQuery},
 		{"CleanupDistributedQuery", testCampaignsCleanupDistributedQuery},
 		{"SaveDistributedQuery", testCampaignsSaveDistributedQuery},
+		{"AnonymizeDistributedQuery", testCampaignsAnonymizeDistributedQuery},
+		{"LabelExpressionDistributedQuery", testCampaignsLabelExpressionDistributedQuery},
+		{"OnlineOnlyDistributedQuery", testCampaignsOnlineOnlyDistributedQuery},
+		{"ExcludedTargetsDistributedQuery", testCampaignsExcludedTargetsDistributedQuery},
+		{"ResultLimitsDistributedQuery", testCampaignsResultLimitsDistributedQuery},
+		{"CountActiveDistributedQuery", testCampaignsCountActiveDistributedQuery},
+		{"ExecutionsDistributedQuery", testCampaignsExecutionsDistributedQuery},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -91,9 +99,9 @@ func testCampaignsCleanupDistributedQuery(t *testing.T, ds *Datastore) {
 
 	// Cleanup and verify that nothing changed (because time has not
 	// advanced)
-	expired, err := ds.CleanupDistributedQueryCampaigns(context.Background(), mockClock.Now())
+	expired, err := ds.CleanupDistributedQueryCampaigns(context.Background(), mockClock.Now(), 24*time.Hour)
 	require.Nil(t, err)
-	assert.Equal(t, uint(0), expired)
+	assert.Empty(t, expired)
 
 	{
 		retrieved, err := ds.DistributedQueryCampaign(context.Background(), c1.ID)
@@ -114,9 +122,9 @@ func testCampaignsCleanupDistributedQuery(t *testing.T, ds *Datastore) {
 
 	// Cleanup and verify that the campaign was expired and executions
 	// deleted appropriately
-	expired, err = ds.CleanupDistributedQueryCampaigns(context.Background(), mockClock.Now())
+	expired, err = ds.CleanupDistributedQueryCampaigns(context.Background(), mockClock.Now(), 24*time.Hour)
 	require.Nil(t, err)
-	assert.Equal(t, uint(1), expired)
+	assert.Equal(t, []uint{c1.ID}, expired)
 	{
 		// c1 should now be complete
 		retrieved, err := ds.DistributedQueryCampaign(context.Background(), c1.ID)
@@ -131,13 +139,14 @@ func testCampaignsCleanupDistributedQuery(t *testing.T, ds *Datastore) {
 		assert.Equal(t, c2.Status, retrieved.Status)
 	}
 
-	mockClock.AddTime(24*time.Hour + 1*time.Second)
-
-	// Cleanup and verify that the campaign was expired and executions
-	// deleted appropriately
-	expired, err = ds.CleanupDistributedQueryCampaigns(context.Background(), mockClock.Now())
+	// A shorter TTL expires the running campaign sooner
+	mockClock.AddTime(1 * time.Hour)
+	expired, err = ds.CleanupDistributedQueryCampaigns(context.Background(), mockClock.Now(), 24*time.Hour)
 	require.Nil(t, err)
-	assert.Equal(t, uint(1), expired)
+	assert.Empty(t, expired)
+	expired, err = ds.CleanupDistributedQueryCampaigns(context.Background(), mockClock.Now(), 1*time.Hour)
+	require.Nil(t, err)
+	assert.Equal(t, []uint{c2.ID}, expired)
 	{
 		retrieved, err := ds.DistributedQueryCampaign(context.Background(), c1.ID)
 		require.Nil(t, err)
@@ -179,4 +188,254 @@ func checkTargets(t *testing.T, ds fleet.Datastore, campaignID uint, expectedTar
 	assert.ElementsMatch(t, expectedTargets.HostIDs, targets.HostIDs)
 	assert.ElementsMatch(t, expectedTargets.LabelIDs, targets.LabelIDs)
 	assert.ElementsMatch(t, expectedTargets.TeamIDs, targets.TeamIDs)
+	assert.Equal(t, expectedTargets.LabelExpression, targets.LabelExpression)
+	assert.Equal(t, expectedTargets.OnlineOnly, targets.OnlineOnly)
+	assert.ElementsMatch(t, expectedTargets.ExcludedHostIDs, targets.ExcludedHostIDs)
+	assert.ElementsMatch(t, expectedTargets.ExcludedLabelIDs, targets.ExcludedLabelIDs)
+}
+
+func testCampaignsAnonymizeDistributedQuery(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from time", user.ID, false)
+
+	campaign, err := ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
+		QueryID:      query.ID,
+		Status:       fleet.QueryWaiting,
+		UserID:       user.ID,
+		Anonymize:    true,
+		PseudonymKey: "abc",
+	})
+	require.NoError(t, err)
+
+	retrieved, err := ds.DistributedQueryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.True(t, retrieved.Anonymize)
+	assert.Equal(t, "abc", retrieved.PseudonymKey)
+
+	// saving the campaign does not change the anonymization settings
+	retrieved.Status = fleet.QueryComplete
+	require.NoError(t, ds.SaveDistributedQueryCampaign(ctx, retrieved))
+	retrieved, err = ds.DistributedQueryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.True(t, retrieved.Anonymize)
+	assert.Equal(t, "abc", retrieved.PseudonymKey)
+
+	plain := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, time.Now())
+	assert.False(t, plain.Anonymize)
+	assert.Empty(t, plain.PseudonymKey)
+}
+
+func testCampaignsLabelExpressionDistributedQuery(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from time", user.ID, false)
+
+	expr := "label:servers AND NOT label:staging"
+	campaign, err := ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
+		QueryID:         query.ID,
+		Status:          fleet.QueryWaiting,
+		UserID:          user.ID,
+		LabelExpression: expr,
+	})
+	require.NoError(t, err)
+	test.AddHostToCampaign(t, ds, campaign.ID, 1)
+
+	retrieved, err := ds.DistributedQueryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, expr, retrieved.LabelExpression)
+	checkTargets(t, ds, campaign.ID, fleet.HostTargets{HostIDs: []uint{1}, LabelExpression: expr})
+
+	_, err = ds.DistributedQueryCampaign(ctx, campaign.ID+1)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testCampaignsOnlineOnlyDistributedQuery(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from time", user.ID, false)
+
+	campaign, err := ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
+		QueryID:    query.ID,
+		Status:     fleet.QueryWaiting,
+		UserID:     user.ID,
+		OnlineOnly: true,
+	})
+	require.NoError(t, err)
+	test.AddLabelToCampaign(t, ds, campaign.ID, 1)
+
+	retrieved, err := ds.DistributedQueryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.True(t, retrieved.OnlineOnly)
+	checkTargets(t, ds, campaign.ID, fleet.HostTargets{LabelIDs: []uint{1}, OnlineOnly: true})
+
+	plain := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, time.Now())
+	assert.False(t, plain.OnlineOnly)
+	checkTargets(t, ds, plain.ID, fleet.HostTargets{})
+}
+
+func testCampaignsExcludedTargetsDistributedQuery(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from time", user.ID, false)
+	campaign := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, time.Now())
+
+	test.AddLabelToCampaign(t, ds, campaign.ID, 1)
+	test.AddHostToCampaign(t, ds, campaign.ID, 2)
+	for _, target := range []fleet.DistributedQueryCampaignTarget{
+		{Type: fleet.TargetHost, TargetID: 3},
+		{Type: fleet.TargetHost, TargetID: 4},
+		{Type: fleet.TargetLabel, TargetID: 5},
+	} {
+		target.DistributedQueryCampaignID = campaign.ID
+		target.Excluded = true
+		_, err := ds.NewDistributedQueryCampaignTarget(ctx, &target)
+		require.NoError(t, err)
+	}
+	checkTargets(t, ds, campaign.ID, fleet.HostTargets{
+		HostIDs:          []uint{2},
+		LabelIDs:         []uint{1},
+		ExcludedHostIDs:  []uint{3, 4},
+		ExcludedLabelIDs: []uint{5},
+	})
+
+	// teams cannot be excluded
+	_, err := ds.NewDistributedQueryCampaignTarget(ctx, &fleet.DistributedQueryCampaignTarget{
+		Type:                       fleet.TargetTeam,
+		DistributedQueryCampaignID: campaign.ID,
+		TargetID:                   1,
+		Excluded:                   true,
+	})
+	require.NoError(t, err)
+	_, err = ds.DistributedQueryCampaignTargetIDs(ctx, campaign.ID)
+	require.Error(t, err)
+}
+
+func testCampaignsResultLimitsDistributedQuery(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from file", user.ID, false)
+
+	limits := fleet.DistributedQueryResultLimits{MaxRows: 100, MaxResultBytes: 1 << 20}
+	campaign, err := ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
+		QueryID:                      query.ID,
+		Status:                       fleet.QueryWaiting,
+		UserID:                       user.ID,
+		DistributedQueryResultLimits: limits,
+	})
+	require.NoError(t, err)
+
+	retrieved, err := ds.DistributedQueryCampaign(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, limits, retrieved.DistributedQueryResultLimits)
+
+	plain := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, time.Now())
+	assert.Zero(t, plain.DistributedQueryResultLimits)
+}
+
+func testCampaignsCountActiveDistributedQuery(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user1 := test.NewUser(t, ds, "user1", "user1@fleet.co", true)
+	user2 := test.NewUser(t, ds, "user2", "user2@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from time", user1.ID, false)
+
+	newCampaign := func(userID uint, status fleet.DistributedQueryStatus) {
+		_, err := ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
+			QueryID: query.ID,
+			Status:  status,
+			UserID:  userID,
+		})
+		require.NoError(t, err)
+	}
+	newCampaign(user1.ID, fleet.QueryWaiting)
+	newCampaign(user1.ID, fleet.QueryRunning)
+	newCampaign(user1.ID, fleet.QueryComplete)
+	newCampaign(user2.ID, fleet.QueryRunning)
+	newCampaign(user2.ID, fleet.QueryComplete)
+
+	count, err := ds.CountActiveDistributedQueryCampaigns(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	count, err = ds.CountActiveDistributedQueryCampaigns(ctx, &user1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = ds.CountActiveDistributedQueryCampaigns(ctx, &user2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, err = ds.CountActiveDistributedQueryCampaigns(ctx, ptr.Uint(user2.ID+1))
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func testCampaignsExecutionsDistributedQuery(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from time", user.ID, false)
+	campaign := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, time.Now())
+
+	now := time.Now()
+	h1 := test.NewHost(t, ds, "h1", "192.168.1.10", "1", "1", now)
+	h2 := test.NewHost(t, ds, "h2", "192.168.1.11", "2", "2", now)
+	h3 := test.NewHost(t, ds, "h3", "192.168.1.12", "3", "3", now.Add(-48*time.Hour))
+	h4 := test.NewHost(t, ds, "h4", "192.168.1.13", "4", "4", now)
+
+	progress, err := ds.DistributedQueryCampaignProgress(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.DistributedQueryCampaignProgress{}, *progress)
+
+	// the unknown hosts are ignored, and the executions are only recorded once
+	require.NoError(t, ds.NewDistributedQueryExecutions(ctx, campaign.ID, []uint{h1.ID, h2.ID, h3.ID, h4.ID + 1000}, now, fleet.HostSettings{}))
+	require.NoError(t, ds.NewDistributedQueryExecutions(ctx, campaign.ID, []uint{h1.ID}, now, fleet.HostSettings{}))
+	progress, err = ds.DistributedQueryCampaignProgress(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.DistributedQueryCampaignProgress{
+		TargetedHosts:       3,
+		OnlineHostsAtLaunch: 2,
+		PendingHosts:        3,
+	}, *progress)
+
+	// only the first result of a host is accounted for
+	require.NoError(t, ds.UpdateDistributedQueryExecutions(ctx, campaign.ID, []uint{h1.ID}, fleet.ExecutionSucceeded))
+	// a host that was not targeted is ignored
+	require.NoError(t, ds.UpdateDistributedQueryExecutions(ctx, campaign.ID, []uint{h1.ID, h2.ID, h4.ID}, fleet.ExecutionFailed))
+	require.NoError(t, ds.UpdateDistributedQueryExecutions(ctx, campaign.ID, nil, fleet.ExecutionFailed))
+	progress, err = ds.DistributedQueryCampaignProgress(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.DistributedQueryCampaignProgress{
+		TargetedHosts:       3,
+		OnlineHostsAtLaunch: 2,
+		RespondedHosts:      2,
+		FailedHosts:         1,
+		PendingHosts:        1,
+	}, *progress)
+
+	// only the pending executions are deleted
+	deleted, err := ds.DeletePendingDistributedQueryExecutions(ctx, campaign.ID, []uint{h1.ID, h3.ID, h4.ID})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), deleted)
+	deleted, err = ds.DeletePendingDistributedQueryExecutions(ctx, campaign.ID, nil)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	progress, err = ds.DistributedQueryCampaignProgress(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.DistributedQueryCampaignProgress{
+		TargetedHosts:       2,
+		OnlineHostsAtLaunch: 2,
+		RespondedHosts:      2,
+		FailedHosts:         1,
+	}, *progress)
+
+	// the executions are deleted after a week
+	require.NoError(t, ds.CleanupDistributedQueryExecutions(ctx, now))
+	progress, err = ds.DistributedQueryCampaignProgress(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), progress.TargetedHosts)
+
+	require.NoError(t, ds.CleanupDistributedQueryExecutions(ctx, now.Add(8*24*time.Hour)))
+	progress, err = ds.DistributedQueryCampaignProgress(ctx, campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.DistributedQueryCampaignProgress{}, *progress)
 }
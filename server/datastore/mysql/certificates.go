@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) ReplaceHostCertificates(ctx context.Context, hostID uint, certs []fleet.HostCertificate) error {
+	const (
+		selStmt = `SELECT sha1 FROM host_certificates WHERE host_id = ?`
+		delStmt = `DELETE FROM host_certificates WHERE host_id = ? AND sha1 = ?`
+		insStmt = `INSERT INTO host_certificates (host_id, sha1, common_name, subject, issuer, ca, self_signed, not_valid_before, not_valid_after) VALUES`
+		insPart = ` (?, ?, ?, ?, ?, ?, ?, ?, ?),`
+	)
+
+	// the same certificate may be installed in multiple stores of the host
+	bySHA1 := make(map[string]fleet.HostCertificate, len(certs))
+	for _, c := range certs {
+		bySHA1[c.SHA1] = c
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var prevSHA1s []string
+		if err := sqlx.SelectContext(ctx, tx, &prevSHA1s, selStmt, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "select previous host certificates")
+		}
+
+		for _, sha1 := range prevSHA1s {
+			if _, ok := bySHA1[sha1]; ok {
+				// the certificates are identified by their fingerprint, the
+				// existing ones do not change
+				delete(bySHA1, sha1)
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, delStmt, hostID, sha1); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete host certificate")
+			}
+		}
+
+		if len(bySHA1) > 0 {
+			args := make([]interface{}, 0, len(bySHA1)*9)
+			for _, c := range bySHA1 {
+				args = append(args, hostID, c.SHA1, c.CommonName, c.Subject, c.Issuer, c.CA, c.SelfSigned, c.NotValidBefore, c.NotValidAfter)
+			}
+			stmt := insStmt + strings.TrimSuffix(strings.Repeat(insPart, len(bySHA1)), ",")
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert host certificates")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ListHostCertificates(ctx context.Context, hostID uint) ([]fleet.HostCertificate, error) {
+	certs := []fleet.HostCertificate{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &certs, `
+		SELECT sha1, common_name, subject, issuer, ca, self_signed, not_valid_before, not_valid_after
+		FROM host_certificates WHERE host_id = ? ORDER BY common_name, sha1`, hostID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host certificates")
+	}
+	return certs, nil
+}
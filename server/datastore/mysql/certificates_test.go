@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertificates(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"ReplaceHost", testCertificatesReplaceHost},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testCertificatesReplaceHost(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	certs, err := ds.ListHostCertificates(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, certs)
+
+	notBefore := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	root := fleet.HostCertificate{
+		SHA1: "d1eb23a46d17d68fd92564c2f1f1601764d8e349", CommonName: "Example Root CA", Subject: "/CN=Example Root CA", Issuer: "/CN=Example Root CA",
+		CA: true, SelfSigned: true, NotValidBefore: notBefore, NotValidAfter: notAfter,
+	}
+	leaf := fleet.HostCertificate{
+		SHA1: "0123456789abcdef0123456789abcdef01234567", CommonName: "host1.example.com", Subject: "/CN=host1.example.com", Issuer: "/CN=Example Root CA",
+		NotValidBefore: notBefore, NotValidAfter: notAfter,
+	}
+	// the same certificate reported from multiple stores is stored once
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, host.ID, []fleet.HostCertificate{root, leaf, root}))
+	certs, err = ds.ListHostCertificates(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostCertificate{root, leaf}, certs)
+
+	// replace removes the missing certificates
+	other := fleet.HostCertificate{
+		SHA1: "ffffffffffffffffffffffffffffffffffffffff", CommonName: "Other CA", Subject: "/CN=Other CA", Issuer: "/CN=Other CA",
+		CA: true, SelfSigned: true, NotValidBefore: notBefore, NotValidAfter: notAfter,
+	}
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, host.ID, []fleet.HostCertificate{root, other}))
+	certs, err = ds.ListHostCertificates(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostCertificate{root, other}, certs)
+
+	// the certificates are removed with the host
+	require.NoError(t, ds.DeleteHost(ctx, host.ID))
+	certs, err = ds.ListHostCertificates(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, certs)
+}
@@ -0,0 +1,112 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewDashboard(ctx context.Context, dashboard *fleet.Dashboard) (*fleet.Dashboard, error) {
+	sqlStatement := `
+		INSERT INTO dashboards (
+			name,
+			description,
+			user_id,
+			team_id,
+			shared,
+			widgets
+		) VALUES ( ?, ?, ?, ?, ?, ? )
+	`
+	result, err := ds.writer.ExecContext(ctx, sqlStatement,
+		dashboard.Name, dashboard.Description, dashboard.UserID, dashboard.TeamID, dashboard.Shared, dashboard.Widgets)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "creating new dashboard")
+	}
+
+	id, _ := result.LastInsertId()
+	return dashboardDB(ctx, ds.writer, uint(id))
+}
+
+func (ds *Datastore) Dashboard(ctx context.Context, id uint) (*fleet.Dashboard, error) {
+	return dashboardDB(ctx, ds.reader, id)
+}
+
+func dashboardDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.Dashboard, error) {
+	var dashboard fleet.Dashboard
+	err := sqlx.GetContext(ctx, q, &dashboard, `SELECT * FROM dashboards WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("Dashboard").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting dashboard")
+	}
+	if dashboard.Widgets == nil {
+		dashboard.Widgets = fleet.DashboardWidgets{}
+	}
+	return &dashboard, nil
+}
+
+func (ds *Datastore) ListDashboards(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.Dashboard, error) {
+	if filter.User == nil {
+		return nil, ctxerr.New(ctx, "team filter missing user")
+	}
+
+	// Users see their own dashboards, and the shared dashboards that are global
+	// or belong to a team they have access to.
+	teamFilter := fleet.TeamFilter{User: filter.User, IncludeObserver: true}
+	sqlStatement := fmt.Sprintf(`
+		SELECT d.*
+			FROM dashboards d
+			LEFT JOIN teams t ON t.id = d.team_id
+			WHERE (d.user_id = ? OR (d.shared AND (d.team_id IS NULL OR %s)))
+	`, ds.whereFilterTeams(teamFilter, "t"))
+	params := []interface{}{filter.User.ID}
+	if filter.TeamID != nil {
+		sqlStatement += ` AND d.team_id = ?`
+		params = append(params, *filter.TeamID)
+	}
+	if opt.OrderKey == "" {
+		opt.OrderKey = "name"
+	}
+	sqlStatement = appendListOptionsToSQL(sqlStatement, opt)
+
+	var dashboards []*fleet.Dashboard
+	if err := sqlx.SelectContext(ctx, ds.reader, &dashboards, sqlStatement, params...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing dashboards")
+	}
+	for _, dashboard := range dashboards {
+		if dashboard.Widgets == nil {
+			dashboard.Widgets = fleet.DashboardWidgets{}
+		}
+	}
+	return dashboards, nil
+}
+
+func (ds *Datastore) SaveDashboard(ctx context.Context, dashboard *fleet.Dashboard) error {
+	sqlStatement := `
+		UPDATE dashboards
+			SET name = ?, description = ?, team_id = ?, shared = ?, widgets = ?
+			WHERE id = ?
+	`
+	result, err := ds.writer.ExecContext(ctx, sqlStatement,
+		dashboard.Name, dashboard.Description, dashboard.TeamID, dashboard.Shared, dashboard.Widgets, dashboard.ID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "updating dashboard")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "rows affected updating dashboard")
+	}
+	if rows == 0 {
+		return ctxerr.Wrap(ctx, notFound("Dashboard").WithID(dashboard.ID))
+	}
+	return nil
+}
+
+func (ds *Datastore) DeleteDashboard(ctx context.Context, id uint) error {
+	return ds.deleteEntity(ctx, dashboardsTable, id)
+}
@@ -0,0 +1,126 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboards(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testDashboardsCRUD},
+		{"List", testDashboardsList},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testDashboardsCRUD(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	dashboard, err := ds.NewDashboard(ctx, &fleet.Dashboard{
+		Name:   "Compliance",
+		UserID: user.ID,
+		Widgets: fleet.DashboardWidgets{
+			{Type: fleet.DashboardWidgetPolicy, ReferenceID: ptr.Uint(1)},
+			{Type: fleet.DashboardWidgetSoftware, Query: "chrome"},
+		},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, dashboard.ID)
+	assert.Equal(t, "Compliance", dashboard.Name)
+	assert.Nil(t, dashboard.TeamID)
+	assert.False(t, dashboard.Shared)
+	require.Len(t, dashboard.Widgets, 2)
+	assert.Equal(t, "chrome", dashboard.Widgets[1].Query)
+	assert.False(t, dashboard.CreatedAt.IsZero())
+
+	dashboard.Name = "Compliance v2"
+	dashboard.Shared = true
+	dashboard.Widgets = nil
+	require.NoError(t, ds.SaveDashboard(ctx, dashboard))
+
+	got, err := ds.Dashboard(ctx, dashboard.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Compliance v2", got.Name)
+	assert.True(t, got.Shared)
+	assert.Equal(t, fleet.DashboardWidgets{}, got.Widgets)
+
+	require.NoError(t, ds.DeleteDashboard(ctx, dashboard.ID))
+	_, err = ds.Dashboard(ctx, dashboard.ID)
+	var nfe fleet.NotFoundError
+	require.ErrorAs(t, err, &nfe)
+	require.ErrorAs(t, ds.DeleteDashboard(ctx, dashboard.ID), &nfe)
+	require.ErrorAs(t, ds.SaveDashboard(ctx, dashboard), &nfe)
+}
+
+func testDashboardsList(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	team2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	admin := test.NewUser(t, ds, "Admin", "admin@example.com", true)
+	teamUser := test.NewUser(t, ds, "Team", "team@example.com", false)
+	teamUser.GlobalRole = nil
+	teamUser.Teams = []fleet.UserTeam{{Team: *team1, Role: fleet.RoleObserver}}
+
+	newDashboard := func(name string, userID uint, teamID *uint, shared bool) *fleet.Dashboard {
+		d, err := ds.NewDashboard(ctx, &fleet.Dashboard{Name: name, UserID: userID, TeamID: teamID, Shared: shared})
+		require.NoError(t, err)
+		return d
+	}
+	newDashboard("a global private", admin.ID, nil, false)
+	newDashboard("b global shared", admin.ID, nil, true)
+	newDashboard("c team1 private", admin.ID, &team1.ID, false)
+	newDashboard("d team1 shared", admin.ID, &team1.ID, true)
+	newDashboard("e team2 shared", admin.ID, &team2.ID, true)
+	newDashboard("f team user own", teamUser.ID, &team1.ID, false)
+
+	names := func(dashboards []*fleet.Dashboard) []string {
+		var res []string
+		for _, d := range dashboards {
+			res = append(res, d.Name)
+		}
+		return res
+	}
+
+	list, err := ds.ListDashboards(ctx, fleet.TeamFilter{User: admin}, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a global private", "b global shared", "c team1 private", "d team1 shared", "e team2 shared"}, names(list))
+
+	list, err = ds.ListDashboards(ctx, fleet.TeamFilter{User: teamUser}, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b global shared", "d team1 shared", "f team user own"}, names(list))
+
+	list, err = ds.ListDashboards(ctx, fleet.TeamFilter{User: teamUser, TeamID: &team1.ID}, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d team1 shared", "f team user own"}, names(list))
+
+	list, err = ds.ListDashboards(ctx, fleet.TeamFilter{User: teamUser, TeamID: &team2.ID}, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	// deleting a team deletes its dashboards
+	require.NoError(t, ds.DeleteTeam(ctx, team1.ID))
+	list, err = ds.ListDashboards(ctx, fleet.TeamFilter{User: admin}, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a global private", "b global shared", "e team2 shared"}, names(list))
+}
@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) ReplaceHostDisks(ctx context.Context, hostID uint, disks []fleet.HostDisk) error {
+	const (
+		selStmt = `SELECT path FROM host_disks WHERE host_id = ?`
+		delStmt = `DELETE FROM host_disks WHERE host_id = ? AND path = ?`
+		insStmt = `INSERT INTO host_disks (host_id, path, device, type, total_bytes, available_bytes, encrypted) VALUES`
+		insPart = ` (?, ?, ?, ?, ?, ?, ?),`
+		// the space of the disks changes all the time, update the existing ones
+		updPart = ` ON DUPLICATE KEY UPDATE
+			device = VALUES(device),
+			type = VALUES(type),
+			total_bytes = VALUES(total_bytes),
+			available_bytes = VALUES(available_bytes),
+			encrypted = VALUES(encrypted)`
+	)
+
+	// a path is mounted only once, keep the last one reported
+	byPath := make(map[string]fleet.HostDisk, len(disks))
+	for _, d := range disks {
+		byPath[d.Path] = d
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var prevPaths []string
+		if err := sqlx.SelectContext(ctx, tx, &prevPaths, selStmt, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "select previous host disks")
+		}
+
+		for _, path := range prevPaths {
+			if _, ok := byPath[path]; ok {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, delStmt, hostID, path); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete host disk")
+			}
+		}
+
+		if len(byPath) > 0 {
+			args := make([]interface{}, 0, len(byPath)*7)
+			for _, d := range byPath {
+				args = append(args, hostID, d.Path, d.Device, d.Type, d.TotalBytes, d.AvailableBytes, d.Encrypted)
+			}
+			stmt := insStmt + strings.TrimSuffix(strings.Repeat(insPart, len(byPath)), ",") + updPart
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert host disks")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ListHostDisks(ctx context.Context, hostID uint) ([]fleet.HostDisk, error) {
+	disks := []fleet.HostDisk{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &disks,
+		`SELECT path, device, type, total_bytes, available_bytes, encrypted FROM host_disks WHERE host_id = ? ORDER BY path`, hostID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host disks")
+	}
+	return disks, nil
+}
@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisks(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"ReplaceHost", testDisksReplaceHost},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testDisksReplaceHost(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	disks, err := ds.ListHostDisks(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, disks)
+
+	root := fleet.HostDisk{Path: "/", Device: "/dev/sda1", Type: "ext4", TotalBytes: 100, AvailableBytes: 40, Encrypted: true}
+	boot := fleet.HostDisk{Path: "/boot", Device: "/dev/sda2", Type: "vfat", TotalBytes: 10, AvailableBytes: 5}
+	require.NoError(t, ds.ReplaceHostDisks(ctx, host.ID, []fleet.HostDisk{boot, root}))
+	disks, err = ds.ListHostDisks(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostDisk{root, boot}, disks)
+
+	// replace updates the existing disks and removes the missing ones
+	root.AvailableBytes = 20
+	data := fleet.HostDisk{Path: "/data", Device: "/dev/sdb1", Type: "xfs", TotalBytes: 1000, AvailableBytes: 900}
+	require.NoError(t, ds.ReplaceHostDisks(ctx, host.ID, []fleet.HostDisk{root, data}))
+	disks, err = ds.ListHostDisks(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostDisk{root, data}, disks)
+
+	// the disks are removed with the host
+	require.NoError(t, ds.DeleteHost(ctx, host.ID))
+	disks, err = ds.ListHostDisks(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, disks)
+}
@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) RecordEnrollmentOutcomes(ctx context.Context, counts []fleet.EnrollmentOutcomeCount) error {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	values := strings.TrimSuffix(strings.Repeat("(?, ?, ?),", len(counts)), ",")
+	args := make([]interface{}, 0, len(counts)*3)
+	for _, c := range counts {
+		args = append(args, c.Date.UTC().Format("2006-01-02"), c.Outcome, c.Count)
+	}
+	_, err := ds.writer.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO enrollment_stats (date, outcome, count) VALUES %s
+		ON DUPLICATE KEY UPDATE count = count + VALUES(count)`, values),
+		args...,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "record enrollment outcomes")
+	}
+	return nil
+}
+
+func (ds *Datastore) EnrollmentStats(ctx context.Context, since time.Time) (*fleet.EnrollmentStats, error) {
+	var rows []struct {
+		Date    time.Time               `db:"date"`
+		Outcome fleet.EnrollmentOutcome `db:"outcome"`
+		Count   uint                    `db:"count"`
+	}
+	err := sqlx.SelectContext(ctx, ds.reader, &rows, `
+		SELECT date, outcome, count FROM enrollment_stats
+		WHERE date >= ?
+		ORDER BY date`,
+		since.UTC().Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select enrollment stats")
+	}
+
+	stats := &fleet.EnrollmentStats{
+		Since: since,
+		Daily: []fleet.DailyEnrollmentCounts{},
+	}
+	for _, row := range rows {
+		stats.Add(row.Outcome, row.Count)
+		if n := len(stats.Daily); n == 0 || !stats.Daily[n-1].Date.Equal(row.Date) {
+			stats.Daily = append(stats.Daily, fleet.DailyEnrollmentCounts{Date: row.Date})
+		}
+		stats.Daily[len(stats.Daily)-1].Add(row.Outcome, row.Count)
+	}
+	return stats, nil
+}
@@ -0,0 +1,56 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrollmentStats(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	stats, err := ds.EnrollmentStats(ctx, today)
+	require.NoError(t, err)
+	require.Equal(t, uint(0), stats.Attempts)
+	require.Empty(t, stats.Daily)
+
+	require.NoError(t, ds.RecordEnrollmentOutcomes(ctx, nil))
+	require.NoError(t, ds.RecordEnrollmentOutcomes(ctx, []fleet.EnrollmentOutcomeCount{
+		{Date: now, Outcome: fleet.EnrollmentOutcomeSuccess, Count: 1},
+		{Date: now, Outcome: fleet.EnrollmentOutcomeInvalidSecret, Count: 1},
+	}))
+	// counts of the same day and outcome are added up
+	require.NoError(t, ds.RecordEnrollmentOutcomes(ctx, []fleet.EnrollmentOutcomeCount{
+		{Date: now, Outcome: fleet.EnrollmentOutcomeSuccess, Count: 1},
+		{Date: now, Outcome: fleet.EnrollmentOutcomeCooldown, Count: 1},
+	}))
+	// insert older stats
+	_, err = ds.writer.Exec(`INSERT INTO enrollment_stats (date, outcome, count) VALUES (?, ?, ?), (?, ?, ?)`,
+		today.AddDate(0, 0, -2), fleet.EnrollmentOutcomeDatastoreError, 3,
+		today.AddDate(0, 0, -10), fleet.EnrollmentOutcomeSuccess, 5,
+	)
+	require.NoError(t, err)
+
+	stats, err = ds.EnrollmentStats(ctx, today.AddDate(0, 0, -6))
+	require.NoError(t, err)
+	require.Equal(t, fleet.EnrollmentCounts{
+		Attempts:       7,
+		Success:        2,
+		InvalidSecret:  1,
+		Cooldown:       1,
+		DatastoreError: 3,
+	}, stats.EnrollmentCounts)
+	require.Len(t, stats.Daily, 2)
+	require.Equal(t, today.AddDate(0, 0, -2), stats.Daily[0].Date)
+	require.Equal(t, uint(3), stats.Daily[0].DatastoreError)
+	require.Equal(t, today, stats.Daily[1].Date)
+	require.Equal(t, uint(4), stats.Daily[1].Attempts)
+}
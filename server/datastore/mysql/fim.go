@@ -0,0 +1,98 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewFIMCategory(ctx context.Context, category *fleet.FIMCategory) (*fleet.FIMCategory, error) {
+	sqlStatement := `
+		INSERT INTO fim_categories (
+			team_id,
+			name,
+			paths,
+			exclude_paths,
+			accesses
+		) VALUES ( ?, ?, ?, ?, ? )
+	`
+	result, err := ds.writer.ExecContext(ctx, sqlStatement, category.TeamID, category.Name, category.Paths, category.ExcludePaths, category.Accesses)
+	if err != nil && isDuplicate(err) {
+		return nil, ctxerr.Wrap(ctx, alreadyExists("FIMCategory", category.Name))
+	} else if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "creating new fim category")
+	}
+
+	id, _ := result.LastInsertId()
+	return fimCategoryDB(ctx, ds.writer, uint(id))
+}
+
+func (ds *Datastore) FIMCategory(ctx context.Context, id uint) (*fleet.FIMCategory, error) {
+	return fimCategoryDB(ctx, ds.reader, id)
+}
+
+func fimCategoryDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.FIMCategory, error) {
+	var category fleet.FIMCategory
+	err := sqlx.GetContext(ctx, q, &category, `SELECT * FROM fim_categories WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("FIMCategory").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting fim category")
+	}
+	return &category, nil
+}
+
+func (ds *Datastore) ListFIMCategories(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+	sqlStatement := `SELECT * FROM fim_categories WHERE team_id IS NULL ORDER BY name`
+	var args []interface{}
+	if teamID != nil {
+		sqlStatement = `SELECT * FROM fim_categories WHERE team_id = ? ORDER BY name`
+		args = append(args, *teamID)
+	}
+
+	var categories []*fleet.FIMCategory
+	if err := sqlx.SelectContext(ctx, ds.reader, &categories, sqlStatement, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing fim categories")
+	}
+	return categories, nil
+}
+
+func (ds *Datastore) ListFIMCategoriesForHost(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+	sqlStatement := `SELECT * FROM fim_categories WHERE team_id IS NULL OR team_id = ? ORDER BY name`
+
+	var categories []*fleet.FIMCategory
+	if err := sqlx.SelectContext(ctx, ds.reader, &categories, sqlStatement, teamID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing fim categories for host")
+	}
+	return categories, nil
+}
+
+func (ds *Datastore) SaveFIMCategory(ctx context.Context, category *fleet.FIMCategory) error {
+	sqlStatement := `
+		UPDATE fim_categories
+			SET name = ?, paths = ?, exclude_paths = ?, accesses = ?
+			WHERE id = ?
+	`
+	result, err := ds.writer.ExecContext(ctx, sqlStatement, category.Name, category.Paths, category.ExcludePaths, category.Accesses, category.ID)
+	if err != nil && isDuplicate(err) {
+		return ctxerr.Wrap(ctx, alreadyExists("FIMCategory", category.Name))
+	} else if err != nil {
+		return ctxerr.Wrap(ctx, err, "updating fim category")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "rows affected updating fim category")
+	}
+	if rows == 0 {
+		return ctxerr.Wrap(ctx, notFound("FIMCategory").WithID(category.ID))
+	}
+	return nil
+}
+
+func (ds *Datastore) DeleteFIMCategory(ctx context.Context, id uint) error {
+	return ds.deleteEntity(ctx, fimCategoriesTable, id)
+}
@@ -0,0 +1,87 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIMCategories(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	team2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	homes, err := ds.NewFIMCategory(ctx, &fleet.FIMCategory{
+		Name:         "homes",
+		Paths:        fleet.FIMPaths{"/home/%/%%"},
+		ExcludePaths: fleet.FIMPaths{"/home/%/.cache/%%"},
+		Accesses:     true,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, homes.ID)
+	assert.Nil(t, homes.TeamID)
+	assert.Equal(t, fleet.FIMPaths{"/home/%/%%"}, homes.Paths)
+	assert.Equal(t, fleet.FIMPaths{"/home/%/.cache/%%"}, homes.ExcludePaths)
+	assert.True(t, homes.Accesses)
+
+	_, err = ds.NewFIMCategory(ctx, &fleet.FIMCategory{Name: "homes", TeamID: &team1.ID, Paths: fleet.FIMPaths{"/tmp/%%"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+
+	etc, err := ds.NewFIMCategory(ctx, &fleet.FIMCategory{Name: "etc", TeamID: &team1.ID, Paths: fleet.FIMPaths{"/etc/%%"}})
+	require.NoError(t, err)
+	require.NotNil(t, etc.TeamID)
+	assert.Equal(t, team1.ID, *etc.TeamID)
+	assert.Equal(t, fleet.FIMPaths{}, etc.ExcludePaths)
+
+	categories, err := ds.ListFIMCategories(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+	assert.Equal(t, "homes", categories[0].Name)
+
+	categories, err = ds.ListFIMCategories(ctx, &team1.ID)
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+	assert.Equal(t, "etc", categories[0].Name)
+
+	categories, err = ds.ListFIMCategoriesForHost(ctx, &team1.ID)
+	require.NoError(t, err)
+	require.Len(t, categories, 2)
+	assert.Equal(t, "etc", categories[0].Name)
+	assert.Equal(t, "homes", categories[1].Name)
+
+	categories, err = ds.ListFIMCategoriesForHost(ctx, &team2.ID)
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+	assert.Equal(t, "homes", categories[0].Name)
+
+	categories, err = ds.ListFIMCategoriesForHost(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, categories, 1)
+
+	homes.Paths = fleet.FIMPaths{"/home/%%"}
+	homes.Accesses = false
+	require.NoError(t, ds.SaveFIMCategory(ctx, homes))
+	got, err := ds.FIMCategory(ctx, homes.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.FIMPaths{"/home/%%"}, got.Paths)
+	assert.False(t, got.Accesses)
+
+	require.NoError(t, ds.DeleteFIMCategory(ctx, homes.ID))
+	_, err = ds.FIMCategory(ctx, homes.ID)
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+
+	// deleting the team deletes its categories
+	require.NoError(t, ds.DeleteTeam(ctx, team1.ID))
+	_, err = ds.FIMCategory(ctx, etc.ID)
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+}
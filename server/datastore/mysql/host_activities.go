@@ -0,0 +1,94 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// NewHostActivities stores the same activity for each of the hosts,
+// performed by the user if not nil.
+func (ds *Datastore) NewHostActivities(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	detailsBytes, err := json.Marshal(details)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshaling host activity details")
+	}
+	// the name of the user is kept in case the user is deleted, if it is known
+	var userID *uint
+	var userName *string
+	if user != nil {
+		userID = &user.ID
+		if user.Name != "" {
+			userName = &user.Name
+		}
+	}
+
+	const insPart = `(?, ?, ?, ?, ?),`
+	args := make([]interface{}, 0, len(hostIDs)*5)
+	for _, hostID := range hostIDs {
+		args = append(args, hostID, userID, userName, activityType, detailsBytes)
+	}
+	stmt := `INSERT INTO host_activities (host_id, user_id, user_name, activity_type, details) VALUES ` +
+		strings.TrimSuffix(strings.Repeat(insPart, len(hostIDs)), ",")
+	if _, err := ds.writer.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "new host activities")
+	}
+	return nil
+}
+
+// hostActivitiesOrderKeys maps the supported order keys to their unambiguous
+// column.
+var hostActivitiesOrderKeys = map[string]string{
+	"id":         "a.id",
+	"created_at": "a.created_at",
+	"type":       "a.activity_type",
+}
+
+func (ds *Datastore) ListHostActivities(ctx context.Context, hostID uint, opt fleet.ListOptions) ([]*fleet.HostActivity, error) {
+	stmt := `
+		SELECT
+			a.id, a.host_id, a.created_at, a.user_id, a.activity_type, a.details,
+			coalesce(u.name, a.user_name) as name
+		FROM host_activities a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE a.host_id = ?`
+	args := []interface{}{hostID}
+
+	// most recent activities first unless otherwise requested
+	if opt.OrderKey == "" {
+		opt.OrderKey = "id"
+		opt.OrderDirection = fleet.OrderDescending
+	}
+	orderKey, ok := hostActivitiesOrderKeys[opt.OrderKey]
+	if !ok {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("order_key", "unsupported order key: "+opt.OrderKey))
+	}
+	opt.OrderKey = orderKey
+	stmt, args = appendListOptionsWithCursorToSQL(stmt, args, opt)
+
+	activities := []*fleet.HostActivity{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &activities, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host activities")
+	}
+	return activities, nil
+}
+
+func (ds *Datastore) CleanupHostActivities(ctx context.Context, now time.Time) error {
+	_, err := ds.writer.ExecContext(ctx,
+		`DELETE FROM host_activities WHERE created_at < ?`,
+		now.Add(-fleet.HostActivitiesRetention),
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup host activities")
+	}
+	return nil
+}
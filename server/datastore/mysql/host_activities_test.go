@@ -0,0 +1,118 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostActivities(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"NewAndList", testHostActivitiesNewAndList},
+		{"Enrollment", testHostActivitiesEnrollment},
+		{"Cleanup", testHostActivitiesCleanup},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostActivitiesNewAndList(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+
+	activities, err := ds.ListHostActivities(ctx, host1.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, activities)
+
+	require.NoError(t, ds.NewHostActivities(ctx, nil, user, fleet.HostActivityTypeTransferred, nil))
+	require.NoError(t, ds.NewHostActivities(ctx, []uint{host1.ID, host2.ID}, user, fleet.HostActivityTypeTransferred, &map[string]interface{}{"team_id": nil}))
+	require.NoError(t, ds.NewHostActivities(ctx, []uint{host1.ID}, nil, fleet.HostActivityTypePolicyFailing, &map[string]interface{}{"policy_id": 1}))
+
+	// most recent first
+	activities, err = ds.ListHostActivities(ctx, host1.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, activities, 2)
+	assert.Equal(t, fleet.HostActivityTypePolicyFailing, activities[0].Type)
+	assert.Equal(t, host1.ID, activities[0].HostID)
+	assert.Nil(t, activities[0].ActorID)
+	assert.Nil(t, activities[0].ActorFullName)
+	require.NotNil(t, activities[0].Details)
+	var details map[string]interface{}
+	require.NoError(t, json.Unmarshal(*activities[0].Details, &details))
+	assert.Equal(t, float64(1), details["policy_id"])
+	assert.Equal(t, fleet.HostActivityTypeTransferred, activities[1].Type)
+	require.NotNil(t, activities[1].ActorID)
+	assert.Equal(t, user.ID, *activities[1].ActorID)
+	require.NotNil(t, activities[1].ActorFullName)
+	assert.Equal(t, "Alice", *activities[1].ActorFullName)
+
+	activities, err = ds.ListHostActivities(ctx, host1.ID, fleet.ListOptions{OrderKey: "id", PerPage: 1})
+	require.NoError(t, err)
+	require.Len(t, activities, 1)
+	assert.Equal(t, fleet.HostActivityTypeTransferred, activities[0].Type)
+	_, err = ds.ListHostActivities(ctx, host1.ID, fleet.ListOptions{OrderKey: "details"})
+	var argErr *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &argErr)
+
+	// the name of the actor is kept when the user is deleted
+	require.NoError(t, ds.DeleteUser(ctx, user.ID))
+	activities, err = ds.ListHostActivities(ctx, host2.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, activities, 1)
+	require.NotNil(t, activities[0].ActorFullName)
+	assert.Equal(t, "Alice", *activities[0].ActorFullName)
+
+	// the activities are deleted with the host
+	require.NoError(t, ds.DeleteHost(ctx, host1.ID))
+	activities, err = ds.ListHostActivities(ctx, host1.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, activities)
+}
+
+func testHostActivitiesEnrollment(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	host, err := ds.EnrollHost(ctx, "host1uuid", "key1", nil, 0, "")
+	require.NoError(t, err)
+	_, err = ds.EnrollHost(ctx, "host1uuid", "key2", nil, 0, "")
+	require.NoError(t, err)
+
+	activities, err := ds.ListHostActivities(ctx, host.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, activities, 2)
+	assert.Equal(t, fleet.HostActivityTypeReenrolled, activities[0].Type)
+	assert.Equal(t, fleet.HostActivityTypeEnrolled, activities[1].Type)
+}
+
+func testHostActivitiesCleanup(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	require.NoError(t, ds.NewHostActivities(ctx, []uint{host.ID}, nil, fleet.HostActivityTypePolicyFailing, nil))
+	_, err := ds.writer.ExecContext(ctx, `UPDATE host_activities SET created_at = ?`, time.Now().Add(-fleet.HostActivitiesRetention-time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, ds.NewHostActivities(ctx, []uint{host.ID}, nil, fleet.HostActivityTypePolicyPassing, nil))
+
+	require.NoError(t, ds.CleanupHostActivities(ctx, time.Now()))
+	activities, err := ds.ListHostActivities(ctx, host.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, activities, 1)
+	assert.Equal(t, fleet.HostActivityTypePolicyPassing, activities[0].Type)
+}
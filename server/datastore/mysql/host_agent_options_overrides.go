@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) SetHostAgentOptionsOverride(ctx context.Context, override *fleet.HostAgentOptionsOverride) error {
+	_, err := ds.writer.ExecContext(ctx, `
+		INSERT INTO host_agent_options_overrides (host_id, options, expires_at)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			options = VALUES(options),
+			expires_at = VALUES(expires_at)`,
+		override.HostID, override.Options, override.ExpiresAt,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "set host agent options override")
+	}
+	return nil
+}
+
+func (ds *Datastore) HostAgentOptionsOverride(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+	var override fleet.HostAgentOptionsOverride
+	err := sqlx.GetContext(ctx, ds.reader, &override, `
+		SELECT host_id, options, expires_at, created_at, updated_at
+		FROM host_agent_options_overrides
+		WHERE host_id = ? AND expires_at > ?`,
+		hostID, now,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("HostAgentOptionsOverride").WithID(hostID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get host agent options override")
+	}
+	return &override, nil
+}
+
+func (ds *Datastore) DeleteHostAgentOptionsOverride(ctx context.Context, hostID uint) error {
+	_, err := ds.writer.ExecContext(ctx, `DELETE FROM host_agent_options_overrides WHERE host_id = ?`, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete host agent options override")
+	}
+	return nil
+}
+
+func (ds *Datastore) CleanupExpiredHostAgentOptionsOverrides(ctx context.Context, now time.Time) error {
+	_, err := ds.writer.ExecContext(ctx, `DELETE FROM host_agent_options_overrides WHERE expires_at <= ?`, now)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup expired host agent options overrides")
+	}
+	return nil
+}
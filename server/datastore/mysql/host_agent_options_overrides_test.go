@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostAgentOptionsOverrides(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"SetGetDelete", testHostAgentOptionsOverridesSetGetDelete},
+		{"Cleanup", testHostAgentOptionsOverridesCleanup},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostAgentOptionsOverridesSetGetDelete(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", now)
+
+	_, err := ds.HostAgentOptionsOverride(ctx, host.ID, now)
+	var nfe fleet.NotFoundError
+	require.ErrorAs(t, err, &nfe)
+
+	require.NoError(t, ds.SetHostAgentOptionsOverride(ctx, &fleet.HostAgentOptionsOverride{
+		HostID:    host.ID,
+		Options:   json.RawMessage(`{"options":{"verbose":true}}`),
+		ExpiresAt: now.Add(time.Hour),
+	}))
+	override, err := ds.HostAgentOptionsOverride(ctx, host.ID, now)
+	require.NoError(t, err)
+	assert.Equal(t, host.ID, override.HostID)
+	assert.JSONEq(t, `{"options":{"verbose":true}}`, string(override.Options))
+	assert.Equal(t, now.Add(time.Hour), override.ExpiresAt)
+
+	// expired overrides are not returned
+	_, err = ds.HostAgentOptionsOverride(ctx, host.ID, now.Add(2*time.Hour))
+	require.ErrorAs(t, err, &nfe)
+
+	// setting the override again replaces it
+	require.NoError(t, ds.SetHostAgentOptionsOverride(ctx, &fleet.HostAgentOptionsOverride{
+		HostID:    host.ID,
+		Options:   json.RawMessage(`{"options":{"verbose":false}}`),
+		ExpiresAt: now.Add(3 * time.Hour),
+	}))
+	override, err = ds.HostAgentOptionsOverride(ctx, host.ID, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"options":{"verbose":false}}`, string(override.Options))
+
+	require.NoError(t, ds.DeleteHostAgentOptionsOverride(ctx, host.ID))
+	_, err = ds.HostAgentOptionsOverride(ctx, host.ID, now)
+	require.ErrorAs(t, err, &nfe)
+
+	// deleting a missing override is not an error
+	require.NoError(t, ds.DeleteHostAgentOptionsOverride(ctx, host.ID))
+}
+
+func testHostAgentOptionsOverridesCleanup(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", now)
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", now)
+
+	for i, h := range []*fleet.Host{host1, host2} {
+		require.NoError(t, ds.SetHostAgentOptionsOverride(ctx, &fleet.HostAgentOptionsOverride{
+			HostID:    h.ID,
+			Options:   json.RawMessage(`{}`),
+			ExpiresAt: now.Add(time.Duration(i+1) * time.Hour),
+		}))
+	}
+
+	require.NoError(t, ds.CleanupExpiredHostAgentOptionsOverrides(ctx, now.Add(90*time.Minute)))
+
+	var hostIDs []uint
+	require.NoError(t, ds.writer.SelectContext(ctx, &hostIDs, `SELECT host_id FROM host_agent_options_overrides`))
+	assert.Equal(t, []uint{host2.ID}, hostIDs)
+
+	// the override is deleted with the host
+	require.NoError(t, ds.DeleteHost(ctx, host2.ID))
+	_, err := ds.HostAgentOptionsOverride(ctx, host2.ID, now)
+	var nfe fleet.NotFoundError
+	require.ErrorAs(t, err, &nfe)
+}
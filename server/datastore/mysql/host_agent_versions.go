@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) SetOrUpdateHostAgentVersion(ctx context.Context, hostID uint, component, version string) error {
+	if version == "" {
+		_, err := ds.writer.ExecContext(ctx, `DELETE FROM host_agent_versions WHERE host_id = ? AND component = ?`, hostID, component)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "delete host agent version")
+		}
+		return nil
+	}
+
+	// updated_at is only changed by MySQL when the version changes.
+	const stmt = `
+		INSERT INTO host_agent_versions (host_id, component, version)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE version = VALUES(version)
+	`
+	if _, err := ds.writer.ExecContext(ctx, stmt, hostID, component, version); err != nil {
+		return ctxerr.Wrap(ctx, err, "insert host agent version")
+	}
+	return nil
+}
+
+func (ds *Datastore) ListHostAgentVersions(ctx context.Context, hostID uint) ([]*fleet.HostAgentVersion, error) {
+	const stmt = `
+		SELECT component, version, updated_at
+		FROM host_agent_versions
+		WHERE host_id = ?
+		ORDER BY component
+	`
+	var versions []*fleet.HostAgentVersion
+	if err := sqlx.SelectContext(ctx, ds.reader, &versions, stmt, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host agent versions")
+	}
+	return versions, nil
+}
@@ -0,0 +1,127 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostAgentVersions(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"SetOrUpdate", testHostAgentVersionsSetOrUpdate},
+		{"ListHostsFilter", testHostAgentVersionsListHostsFilter},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostAgentVersionsSetOrUpdate(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+
+	versions, err := ds.ListHostAgentVersions(ctx, host1.ID)
+	require.NoError(t, err)
+	assert.Empty(t, versions)
+
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, host1.ID, fleet.AgentComponentOrbit, "0.0.10"))
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, host1.ID, fleet.AgentComponentFleetDesktop, "0.0.2"))
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, host2.ID, fleet.AgentComponentLauncher, "0.11.25"))
+
+	versions, err = ds.ListHostAgentVersions(ctx, host1.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, fleet.AgentComponentFleetDesktop, versions[0].Component)
+	assert.Equal(t, "0.0.2", versions[0].Version)
+	assert.Equal(t, fleet.AgentComponentOrbit, versions[1].Component)
+	assert.Equal(t, "0.0.10", versions[1].Version)
+
+	// the time of the version is only changed when the version changes
+	past := time.Now().Add(-24 * time.Hour).UTC().Truncate(time.Second)
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE host_agent_versions SET updated_at = ? WHERE host_id = ?`, past, host1.ID)
+		return err
+	})
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, host1.ID, fleet.AgentComponentOrbit, "0.0.10"))
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, host1.ID, fleet.AgentComponentFleetDesktop, "0.0.3"))
+
+	versions, err = ds.ListHostAgentVersions(ctx, host1.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, "0.0.3", versions[0].Version)
+	assert.True(t, versions[0].UpdatedAt.After(past))
+	assert.Equal(t, "0.0.10", versions[1].Version)
+	assert.Equal(t, past, versions[1].UpdatedAt.UTC())
+
+	// an empty version removes the component
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, host1.ID, fleet.AgentComponentFleetDesktop, ""))
+	versions, err = ds.ListHostAgentVersions(ctx, host1.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, fleet.AgentComponentOrbit, versions[0].Component)
+
+	versions, err = ds.ListHostAgentVersions(ctx, host2.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	assert.Equal(t, fleet.AgentComponentLauncher, versions[0].Component)
+}
+
+func testHostAgentVersionsListHostsFilter(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	var hosts []*fleet.Host
+	for _, name := range []string{"host0", "host1", "host2"} {
+		hosts = append(hosts, test.NewHost(t, ds, name, "", name+"key", name+"uuid", time.Now()))
+	}
+
+	// host 0 runs the new version of orbit, host 1 the old one, and host 2
+	// does not run orbit.
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, hosts[0].ID, fleet.AgentComponentOrbit, "0.0.11"))
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, hosts[1].ID, fleet.AgentComponentOrbit, "0.0.10"))
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, hosts[0].ID, fleet.AgentComponentFleetDesktop, "0.0.3"))
+	require.NoError(t, ds.SetOrUpdateHostAgentVersion(ctx, hosts[2].ID, fleet.AgentComponentLauncher, "0.11.25"))
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	got := listHostsCheckCount(t, ds, filter, fleet.HostListOptions{AgentComponentFilter: fleet.AgentComponentOrbit}, 2)
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, hostIDs(got))
+
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{AgentComponentFilter: fleet.AgentComponentOrbit, AgentVersionFilter: "0.0.11"}, 1)
+	assert.Equal(t, []uint{hosts[0].ID}, hostIDs(got))
+
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{AgentComponentFilter: fleet.AgentComponentFleetDesktop, AgentVersionFilter: "0.0.11"}, 0)
+
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{AgentComponentFilter: fleet.AgentComponentLauncher}, 1)
+	assert.Equal(t, []uint{hosts[2].ID}, hostIDs(got))
+
+	// combined with the hosts in label endpoint
+	l1 := &fleet.LabelSpec{ID: 1, Name: "label1", Query: "query1"}
+	require.NoError(t, ds.ApplyLabelSpecs(ctx, []*fleet.LabelSpec{l1}))
+	for _, h := range hosts {
+		require.NoError(t, ds.RecordLabelQueryExecutions(ctx, h, map[uint]*bool{l1.ID: ptr.Bool(true)}, time.Now(), false))
+	}
+	got = listHostsInLabelCheckCount(t, ds, filter, l1.ID, fleet.HostListOptions{AgentComponentFilter: fleet.AgentComponentOrbit, AgentVersionFilter: "0.0.10"}, 1)
+	assert.Equal(t, []uint{hosts[1].ID}, hostIDs(got))
+}
@@ -0,0 +1,138 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// hostHistoryRefs are the tables storing the history of a host, which is
+// transferred to the host kept when merging duplicate hosts.
+var hostHistoryRefs = []string{
+	"host_activities",
+	"host_startup_item_changes",
+}
+
+func (ds *Datastore) ListDuplicateHosts(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.DuplicateHosts, error) {
+	// the hosts are identified by their hardware serial number if they
+	// reported one, by their UUID otherwise.
+	stmt := fmt.Sprintf(`
+		SELECT
+			h.id, h.hostname, h.display_name, h.team_id, h.hardware_serial, h.uuid,
+			h.created_at, h.last_enrolled_at,
+			COALESCE(hst.seen_time, h.created_at) AS seen_time
+		FROM hosts h
+		LEFT JOIN host_seen_times hst ON (h.id = hst.host_id)
+		JOIN (
+			SELECT IF(hd.hardware_serial != '', hd.hardware_serial, hd.uuid) AS identifier
+			FROM hosts hd
+			WHERE (hd.hardware_serial != '' OR hd.uuid != '') AND %s
+			GROUP BY identifier
+			HAVING COUNT(*) > 1
+		) d ON (d.identifier = IF(h.hardware_serial != '', h.hardware_serial, h.uuid))
+		WHERE %s
+		ORDER BY d.identifier, seen_time DESC, h.id DESC`,
+		ds.whereFilterHostsByTeams(filter, "hd"), ds.whereFilterHostsByTeams(filter, "h"),
+	)
+
+	var hosts []*fleet.DuplicateHost
+	if err := sqlx.SelectContext(ctx, ds.reader, &hosts, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select duplicate hosts")
+	}
+
+	groups := []*fleet.DuplicateHosts{}
+	var group *fleet.DuplicateHosts
+	for _, host := range hosts {
+		identifier := host.HardwareSerial
+		if identifier == "" {
+			identifier = host.UUID
+		}
+		if group == nil || (identifier != group.HardwareSerial && identifier != group.UUID) {
+			group = &fleet.DuplicateHosts{}
+			if host.HardwareSerial != "" {
+				group.HardwareSerial = identifier
+			} else {
+				group.UUID = identifier
+			}
+			groups = append(groups, group)
+		}
+		group.Hosts = append(group.Hosts, host)
+	}
+	return groups, nil
+}
+
+func (ds *Datastore) MergeHosts(ctx context.Context, hostID uint, duplicateIDs []uint) error {
+	if len(duplicateIDs) == 0 {
+		return nil
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		exec := func(stmt string, args ...interface{}) error {
+			stmt, args, err := sqlx.In(stmt, args...)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, stmt, args...)
+			return err
+		}
+
+		var createdAt time.Time
+		if err := sqlx.GetContext(ctx, tx, &createdAt, `SELECT created_at FROM hosts WHERE id = ?`, hostID); err != nil {
+			if err == sql.ErrNoRows {
+				return ctxerr.Wrap(ctx, notFound("Host").WithID(hostID))
+			}
+			return ctxerr.Wrap(ctx, err, "select host enrollment")
+		}
+
+		// the host keeps the oldest enrollment date
+		stmt, args, err := sqlx.In(`SELECT MIN(created_at) FROM hosts WHERE id IN (?)`, duplicateIDs)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build oldest enrollment query")
+		}
+		var oldest sql.NullTime
+		if err := sqlx.GetContext(ctx, tx, &oldest, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "select oldest enrollment")
+		}
+		if oldest.Valid && oldest.Time.Before(createdAt) {
+			if _, err := tx.ExecContext(ctx, `UPDATE hosts SET created_at = ? WHERE id = ?`, oldest.Time, hostID); err != nil {
+				return ctxerr.Wrap(ctx, err, "update host enrollment")
+			}
+		}
+
+		for _, table := range hostHistoryRefs {
+			if err := exec(fmt.Sprintf(`UPDATE %s SET host_id = ? WHERE host_id IN (?)`, table), hostID, duplicateIDs); err != nil {
+				return ctxerr.Wrapf(ctx, err, "merge %s", table)
+			}
+		}
+		// the pending remediations are not transferred as they were queued
+		// for the previous installation.
+		if err := exec(
+			`UPDATE host_policy_remediations SET host_id = ? WHERE host_id IN (?) AND status != ?`,
+			hostID, duplicateIDs, fleet.PolicyRemediationPending,
+		); err != nil {
+			return ctxerr.Wrap(ctx, err, "merge host_policy_remediations")
+		}
+		// the notes and tags are only transferred if the host has none
+		if err := exec(`UPDATE IGNORE host_metadata SET host_id = ? WHERE host_id IN (?)`, hostID, duplicateIDs); err != nil {
+			return ctxerr.Wrap(ctx, err, "merge host_metadata")
+		}
+		// the memberships of the dynamic labels are computed by the host, only
+		// the manual ones are transferred.
+		if err := exec(`
+			INSERT IGNORE INTO label_membership (label_id, host_id)
+			SELECT lm.label_id, ? FROM label_membership lm
+			JOIN labels l ON (l.id = lm.label_id)
+			WHERE l.label_membership_type = ? AND lm.host_id IN (?)`,
+			hostID, fleet.LabelMembershipTypeManual, duplicateIDs,
+		); err != nil {
+			return ctxerr.Wrap(ctx, err, "merge manual label_membership")
+		}
+
+		return deleteHostsDB(ctx, tx, duplicateIDs)
+	})
+}
@@ -0,0 +1,185 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostDuplicates(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"List", testHostDuplicatesList},
+		{"Merge", testHostDuplicatesMerge},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+// newDuplicateTestHost creates a host with the hardware serial number and
+// enrollment date.
+func newDuplicateTestHost(t *testing.T, ds *Datastore, name, uuid, serial string, createdAt, seenTime time.Time) *fleet.Host {
+	host := test.NewHost(t, ds, name, "", name+"key", uuid, seenTime)
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(context.Background(),
+			`UPDATE hosts SET hardware_serial = ?, created_at = ? WHERE id = ?`, serial, createdAt, host.ID)
+		return err
+	})
+	return host
+}
+
+func testHostDuplicatesList(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+
+	groups, err := ds.ListDuplicateHosts(ctx, filter)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	serial1 := newDuplicateTestHost(t, ds, "serial1", "uuid1", "S1", now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	serial2 := newDuplicateTestHost(t, ds, "serial2", "uuid2", "S1", now.Add(-time.Hour), now)
+	serial3 := newDuplicateTestHost(t, ds, "serial3", "uuid3", "S1", now.Add(-2*time.Hour), now.Add(-time.Hour))
+	uuid1 := newDuplicateTestHost(t, ds, "uuid1", "U1", "", now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	uuid2 := newDuplicateTestHost(t, ds, "uuid2", "U1", "", now.Add(-time.Hour), now)
+	// unique or unidentified hosts
+	newDuplicateTestHost(t, ds, "unique", "uuid4", "S2", now, now)
+	newDuplicateTestHost(t, ds, "empty1", "", "", now, now)
+	newDuplicateTestHost(t, ds, "empty2", "", "", now, now)
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{uuid2.ID}))
+
+	hostIDs := func(group *fleet.DuplicateHosts) []uint {
+		var ids []uint
+		for _, h := range group.Hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	// the hosts are ordered by most recently seen
+	groups, err = ds.ListDuplicateHosts(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "S1", groups[0].HardwareSerial)
+	assert.Empty(t, groups[0].UUID)
+	assert.Equal(t, []uint{serial2.ID, serial3.ID, serial1.ID}, hostIDs(groups[0]))
+	assert.Equal(t, now.Add(-48*time.Hour), groups[0].Hosts[2].CreatedAt)
+	assert.Equal(t, "U1", groups[1].UUID)
+	assert.Empty(t, groups[1].HardwareSerial)
+	assert.Equal(t, []uint{uuid2.ID, uuid1.ID}, hostIDs(groups[1]))
+	require.NotNil(t, groups[1].Hosts[0].TeamID)
+	assert.Equal(t, team.ID, *groups[1].Hosts[0].TeamID)
+
+	// the hosts of other teams are not visible to the team users
+	teamFilter := fleet.TeamFilter{User: &fleet.User{Teams: []fleet.UserTeam{{Team: *team, Role: fleet.RoleMaintainer}}}}
+	groups, err = ds.ListDuplicateHosts(ctx, teamFilter)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{uuid1.ID}))
+	groups, err = ds.ListDuplicateHosts(ctx, teamFilter)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, []uint{uuid2.ID, uuid1.ID}, hostIDs(groups[0]))
+}
+
+func testHostDuplicatesMerge(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	older := newDuplicateTestHost(t, ds, "older", "uuid1", "S1", now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	old := newDuplicateTestHost(t, ds, "old", "uuid2", "S1", now.Add(-24*time.Hour), now.Add(-time.Hour))
+	current := newDuplicateTestHost(t, ds, "current", "uuid3", "S1", now.Add(-time.Hour), now)
+
+	// the history of the old hosts
+	require.NoError(t, ds.NewHostActivities(ctx, []uint{older.ID, old.ID, current.ID}, user, fleet.HostActivityTypeTransferred, nil))
+	require.NoError(t, ds.SetHostMetadata(ctx, &fleet.HostMetadata{HostID: old.ID, Notes: "asset 42", Tags: fleet.HostTags{"owner": "bob"}}))
+	p, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "p1", Query: "select 1;", RemediationScript: "echo fix"})
+	require.NoError(t, err)
+	done, err := ds.NewHostPolicyRemediation(ctx, old.ID, p.ID, &user.ID)
+	require.NoError(t, err)
+	require.NoError(t, ds.SetHostPolicyRemediationResult(ctx, old.ID, done.ID, 0, "fixed"))
+	_, err = ds.NewHostPolicyRemediation(ctx, old.ID, p.ID, &user.ID)
+	require.NoError(t, err)
+	manual, err := ds.NewLabel(ctx, &fleet.Label{Name: "manual", LabelMembershipType: fleet.LabelMembershipTypeManual})
+	require.NoError(t, err)
+	dynamic, err := ds.NewLabel(ctx, &fleet.Label{Name: "dynamic", Query: "select 1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.AddHostsToLabel(ctx, manual.ID, []uint{older.ID}))
+	require.NoError(t, ds.RecordLabelQueryExecutions(ctx, older, map[uint]*bool{dynamic.ID: ptr.Bool(true)}, now, false))
+
+	err = ds.MergeHosts(ctx, current.ID+1000, []uint{old.ID})
+	require.True(t, fleet.IsNotFound(err))
+	require.NoError(t, ds.MergeHosts(ctx, current.ID, nil))
+
+	require.NoError(t, ds.MergeHosts(ctx, current.ID, []uint{older.ID, old.ID}))
+
+	// the duplicates are deleted
+	_, err = ds.Host(ctx, older.ID, true)
+	require.True(t, fleet.IsNotFound(err))
+	_, err = ds.Host(ctx, old.ID, true)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the host keeps the oldest enrollment date and its own last enrollment
+	host, err := ds.Host(ctx, current.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-48*time.Hour), host.CreatedAt)
+	assert.Equal(t, "current", host.Hostname)
+
+	activities, err := ds.ListHostActivities(ctx, current.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, activities, 3)
+
+	metadata, err := ds.HostMetadata(ctx, current.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "asset 42", metadata.Notes)
+	assert.Equal(t, fleet.HostTags{"owner": "bob"}, metadata.Tags)
+
+	// only the completed remediations are transferred
+	remediations, err := ds.ListHostPolicyRemediations(ctx, current.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, remediations, 1)
+	assert.Equal(t, done.ID, remediations[0].ID)
+	pending, err := ds.PendingHostPolicyRemediations(ctx, current.ID)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// only the manual labels are transferred
+	labels, err := ds.ListLabelsForHost(ctx, current.ID)
+	require.NoError(t, err)
+	require.Len(t, labels, 1)
+	assert.Equal(t, manual.ID, labels[0].ID)
+
+	groups, err := ds.ListDuplicateHosts(ctx, fleet.TeamFilter{User: test.UserAdmin})
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	// the notes and tags of the host are kept
+	dup := newDuplicateTestHost(t, ds, "dup", "uuid4", "S1", now.Add(-72*time.Hour), now.Add(-72*time.Hour))
+	require.NoError(t, ds.SetHostMetadata(ctx, &fleet.HostMetadata{HostID: dup.ID, Notes: "dup", Tags: fleet.HostTags{}}))
+	require.NoError(t, ds.MergeHosts(ctx, current.ID, []uint{dup.ID}))
+	metadata, err = ds.HostMetadata(ctx, current.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "asset 42", metadata.Notes)
+	host, err = ds.Host(ctx, current.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-72*time.Hour), host.CreatedAt)
+}
@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/jmoiron/sqlx"
+)
+
+// The issues of the hosts (for now, the number of failing policies) are
+// precomputed in the host_issues table so that the hosts can be listed with
+// their issues (and ordered by them) without counting the policy_membership
+// rows of each host.
+//
+// They are updated along with the policy_membership rows of the hosts when
+// the policy results are recorded, the hosts change team or the policies are
+// deleted. The other removals of policy_membership rows (e.g. when the
+// platforms of a policy change or when a team is deleted) are caught up by
+// UpdateHostIssues, run hourly.
+
+// updateHostIssuesFailingPoliciesDB recomputes the issues of the provided
+// hosts from their policy_membership rows.
+func updateHostIssuesFailingPoliciesDB(ctx context.Context, tx sqlx.ExtContext, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	stmt, args, err := sqlx.In(`
+		INSERT INTO host_issues (host_id, failing_policies_count, total_issues_count)
+		SELECT
+			h.id,
+			COALESCE(SUM(pm.passes = 0), 0),
+			COALESCE(SUM(pm.passes = 0), 0)
+		FROM hosts h
+		LEFT JOIN policy_membership pm ON pm.host_id = h.id
+		WHERE h.id IN (?)
+		GROUP BY h.id
+		ON DUPLICATE KEY UPDATE
+			failing_policies_count = VALUES(failing_policies_count),
+			total_issues_count = VALUES(total_issues_count)`, hostIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build host issues update")
+	}
+	if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "update host issues")
+	}
+	return nil
+}
+
+// hostIssuesBatchSize is the number of hosts whose issues are upserted by each
+// statement of UpdateHostIssues.
+const hostIssuesBatchSize = 1000
+
+// UpdateHostIssues recomputes the issues of all the hosts.
+func (ds *Datastore) UpdateHostIssues(ctx context.Context) error {
+	// the counts are aggregated on the reader, so that the policy_membership
+	// rows are not locked while they are scanned
+	selectStmt := `
+		SELECT
+			h.id AS host_id,
+			COALESCE(SUM(pm.passes = 0), 0) AS failing_policies_count
+		FROM hosts h
+		LEFT JOIN policy_membership pm ON pm.host_id = h.id
+		GROUP BY h.id`
+	var issues []struct {
+		HostID               uint `db:"host_id"`
+		FailingPoliciesCount uint `db:"failing_policies_count"`
+	}
+	if err := sqlx.SelectContext(ctx, ds.reader, &issues, selectStmt); err != nil {
+		return ctxerr.Wrap(ctx, err, "aggregate host issues")
+	}
+
+	for i := 0; i < len(issues); i += hostIssuesBatchSize {
+		end := i + hostIssuesBatchSize
+		if end > len(issues) {
+			end = len(issues)
+		}
+		batch := issues[i:end]
+
+		sql := `INSERT INTO host_issues (host_id, failing_policies_count, total_issues_count) VALUES `
+		sql += strings.Repeat(`(?, ?, ?),`, len(batch))
+		sql = strings.TrimSuffix(sql, ",")
+		sql += ` ON DUPLICATE KEY UPDATE
+			failing_policies_count = VALUES(failing_policies_count),
+			total_issues_count = VALUES(total_issues_count)`
+
+		vals := make([]interface{}, 0, len(batch)*3)
+		for _, issue := range batch {
+			vals = append(vals, issue.HostID, issue.FailingPoliciesCount, issue.FailingPoliciesCount)
+		}
+		if _, err := ds.writer.ExecContext(ctx, sql, vals...); err != nil {
+			return ctxerr.Wrap(ctx, err, "upsert host issues")
+		}
+	}
+	return nil
+}
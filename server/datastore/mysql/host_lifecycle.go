@@ -0,0 +1,90 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewHostOfflineEvents(ctx context.Context, seenBefore time.Time) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		// the hosts are reported once until they are seen again
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO host_lifecycle_events (host_id, event_type)
+			SELECT hst.host_id, ?
+			FROM host_seen_times hst
+			LEFT JOIN host_offline_reports r ON (r.host_id = hst.host_id)
+			WHERE hst.seen_time < ? AND (r.seen_time IS NULL OR r.seen_time < hst.seen_time)`,
+			fleet.HostLifecycleEventOffline, seenBefore,
+		)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "insert host offline events")
+		}
+		return markHostsReportedOfflineDB(ctx, tx, seenBefore)
+	})
+}
+
+func (ds *Datastore) MarkHostsReportedOffline(ctx context.Context, seenBefore time.Time) error {
+	return markHostsReportedOfflineDB(ctx, ds.writer, seenBefore)
+}
+
+func markHostsReportedOfflineDB(ctx context.Context, q sqlx.ExecerContext, seenBefore time.Time) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO host_offline_reports (host_id, seen_time)
+		SELECT host_id, seen_time FROM host_seen_times WHERE seen_time < ?
+		ON DUPLICATE KEY UPDATE seen_time = VALUES(seen_time)`,
+		seenBefore,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "update host offline reports")
+	}
+	return nil
+}
+
+func (ds *Datastore) ListHostLifecycleEvents(ctx context.Context, limit int) ([]*fleet.HostLifecycleEvent, error) {
+	// the events of the deleted hosts have the identifiers of the host at the
+	// time of the deletion, the others the current ones.
+	stmt := `
+		SELECT
+			e.id, e.event_type, e.created_at, e.host_id,
+			COALESCE(h.hostname, e.hostname) AS hostname,
+			COALESCE(h.uuid, e.uuid) AS uuid,
+			COALESCE(h.hardware_serial, e.hardware_serial) AS hardware_serial,
+			IF(h.id IS NULL, e.team_id, h.team_id) AS team_id
+		FROM host_lifecycle_events e
+		LEFT JOIN hosts h ON (h.id = e.host_id)
+		ORDER BY e.id
+		LIMIT ?`
+
+	// read from the primary, the events that were just queued must be sent and
+	// the ones that were just delivered and deleted must not be sent again.
+	events := []*fleet.HostLifecycleEvent{}
+	if err := sqlx.SelectContext(ctx, ds.writer, &events, stmt, limit); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host lifecycle events")
+	}
+	return events, nil
+}
+
+func (ds *Datastore) DeleteHostLifecycleEvents(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	stmt, args, err := sqlx.In(`DELETE FROM host_lifecycle_events WHERE id IN (?)`, ids)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build delete host lifecycle events query")
+	}
+	if _, err := ds.writer.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete host lifecycle events")
+	}
+	return nil
+}
+
+func (ds *Datastore) CleanupHostLifecycleEvents(ctx context.Context, before time.Time) error {
+	if _, err := ds.writer.ExecContext(ctx, `DELETE FROM host_lifecycle_events WHERE created_at < ?`, before); err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup host lifecycle events")
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostLifecycle(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"EnrollAndDelete", testHostLifecycleEnrollAndDelete},
+		{"Offline", testHostLifecycleOffline},
+		{"MarkReportedOffline", testHostLifecycleMarkReportedOffline},
+		{"Cleanup", testHostLifecycleCleanup},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func listHostLifecycleEventTypes(t *testing.T, ds *Datastore) []string {
+	events, err := ds.ListHostLifecycleEvents(context.Background(), 100)
+	require.NoError(t, err)
+	var types []string
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func testHostLifecycleEnrollAndDelete(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	events, err := ds.ListHostLifecycleEvents(ctx, 100)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	host, err := ds.EnrollHost(ctx, "host1", "host1key", &team.ID, 0, "")
+	require.NoError(t, err)
+	_, err = ds.EnrollHost(ctx, "host1", "host1key2", &team.ID, 0, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{fleet.HostLifecycleEventEnrolled, fleet.HostLifecycleEventReenrolled}, listHostLifecycleEventTypes(t, ds))
+
+	// the events have the current identifiers of the host
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE hosts SET hostname = ?, uuid = ?, hardware_serial = ? WHERE id = ?`, "host1.local", "uuid1", "serial1", host.ID)
+		return err
+	})
+	events, err = ds.ListHostLifecycleEvents(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, host.ID, events[0].HostID)
+	assert.Equal(t, "host1.local", events[0].Hostname)
+	assert.Equal(t, "uuid1", events[0].UUID)
+	assert.Equal(t, "serial1", events[0].HardwareSerial)
+	require.NotNil(t, events[0].TeamID)
+	assert.Equal(t, team.ID, *events[0].TeamID)
+
+	// the deletion keeps the identifiers of the host
+	require.NoError(t, ds.DeleteHost(ctx, host.ID))
+	events, err = ds.ListHostLifecycleEvents(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, fleet.HostLifecycleEventDeleted, events[2].Type)
+	assert.Equal(t, host.ID, events[2].HostID)
+	assert.Equal(t, "host1.local", events[2].Hostname)
+	assert.Equal(t, "uuid1", events[2].UUID)
+	assert.Equal(t, "serial1", events[2].HardwareSerial)
+	require.NotNil(t, events[2].TeamID)
+	assert.Equal(t, team.ID, *events[2].TeamID)
+
+	require.NoError(t, ds.DeleteHostLifecycleEvents(ctx, []uint{events[0].ID, events[1].ID}))
+	assert.Equal(t, []string{fleet.HostLifecycleEventDeleted}, listHostLifecycleEventTypes(t, ds))
+	require.NoError(t, ds.DeleteHostLifecycleEvents(ctx, nil))
+}
+
+func testHostLifecycleOffline(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	offline := test.NewHost(t, ds, "offline", "", "offlinekey", "offlineuuid", now.Add(-2*time.Hour))
+	test.NewHost(t, ds, "online", "", "onlinekey", "onlineuuid", now)
+
+	require.NoError(t, ds.NewHostOfflineEvents(ctx, now.Add(-time.Hour)))
+	events, err := ds.ListHostLifecycleEvents(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, fleet.HostLifecycleEventOffline, events[0].Type)
+	assert.Equal(t, offline.ID, events[0].HostID)
+	assert.Equal(t, "offline", events[0].Hostname)
+
+	// the host is only reported once while offline
+	require.NoError(t, ds.NewHostOfflineEvents(ctx, now.Add(-time.Hour)))
+	require.NoError(t, ds.NewHostOfflineEvents(ctx, now.Add(-30*time.Minute)))
+	assert.Len(t, listHostLifecycleEventTypes(t, ds), 1)
+
+	// and again once it goes offline after being seen
+	require.NoError(t, ds.MarkHostsSeen(ctx, []uint{offline.ID}, now.Add(-time.Minute)))
+	require.NoError(t, ds.NewHostOfflineEvents(ctx, now.Add(-30*time.Minute)))
+	assert.Len(t, listHostLifecycleEventTypes(t, ds), 1)
+	require.NoError(t, ds.NewHostOfflineEvents(ctx, now.Add(time.Second)))
+	assert.Equal(t, []string{fleet.HostLifecycleEventOffline, fleet.HostLifecycleEventOffline, fleet.HostLifecycleEventOffline}, listHostLifecycleEventTypes(t, ds))
+}
+
+func testHostLifecycleMarkReportedOffline(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	offline := test.NewHost(t, ds, "offline", "", "offlinekey", "offlineuuid", now.Add(-2*time.Hour))
+	online := test.NewHost(t, ds, "online", "", "onlinekey", "onlineuuid", now.Add(-time.Minute))
+
+	// the hosts already offline are not reported, only the ones going offline after
+	require.NoError(t, ds.MarkHostsReportedOffline(ctx, now.Add(-time.Hour)))
+	require.NoError(t, ds.NewHostOfflineEvents(ctx, now.Add(-time.Hour)))
+	assert.Empty(t, listHostLifecycleEventTypes(t, ds))
+
+	require.NoError(t, ds.NewHostOfflineEvents(ctx, now))
+	events, err := ds.ListHostLifecycleEvents(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, online.ID, events[0].HostID)
+
+	// the host marked as reported is reported again once it goes offline after being seen
+	require.NoError(t, ds.MarkHostsSeen(ctx, []uint{offline.ID}, now))
+	require.NoError(t, ds.NewHostOfflineEvents(ctx, now.Add(time.Second)))
+	events, err = ds.ListHostLifecycleEvents(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, offline.ID, events[1].HostID)
+}
+
+func testHostLifecycleCleanup(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	old, err := ds.EnrollHost(ctx, "host1", "host1key", nil, 0, "")
+	require.NoError(t, err)
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE host_lifecycle_events SET created_at = ? WHERE host_id = ?`, time.Now().Add(-fleet.HostLifecycleEventsRetention-time.Hour), old.ID)
+		return err
+	})
+	recent, err := ds.EnrollHost(ctx, "host2", "host2key", nil, 0, "")
+	require.NoError(t, err)
+
+	require.NoError(t, ds.CleanupHostLifecycleEvents(ctx, time.Now().Add(-fleet.HostLifecycleEventsRetention)))
+	events, err := ds.ListHostLifecycleEvents(ctx, 100)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, recent.ID, events[0].HostID)
+
+	require.NoError(t, ds.CleanupHostLifecycleEvents(ctx, time.Now().Add(time.Minute)))
+	events, err = ds.ListHostLifecycleEvents(ctx, 100)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
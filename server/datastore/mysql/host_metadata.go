@@ -0,0 +1,37 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) HostMetadata(ctx context.Context, hostID uint) (*fleet.HostMetadata, error) {
+	metadata := fleet.HostMetadata{HostID: hostID}
+	err := sqlx.GetContext(ctx, ds.reader, &metadata, `SELECT host_id, notes, tags FROM host_metadata WHERE host_id = ?`, hostID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, ctxerr.Wrap(ctx, err, "get host metadata")
+	}
+	if metadata.Tags == nil {
+		metadata.Tags = fleet.HostTags{}
+	}
+	return &metadata, nil
+}
+
+func (ds *Datastore) SetHostMetadata(ctx context.Context, metadata *fleet.HostMetadata) error {
+	_, err := ds.writer.ExecContext(ctx, `
+		INSERT INTO host_metadata (host_id, notes, tags)
+		VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			notes = VALUES(notes),
+			tags = VALUES(tags)`,
+		metadata.HostID, metadata.Notes, metadata.Tags,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "set host metadata")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostMetadata(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"SetAndGet", testHostMetadataSetAndGet},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostMetadataSetAndGet(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+
+	// the metadata is empty if it was never set
+	metadata, err := ds.HostMetadata(ctx, host1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, &fleet.HostMetadata{HostID: host1.ID, Tags: fleet.HostTags{}}, metadata)
+
+	require.NoError(t, ds.SetHostMetadata(ctx, &fleet.HostMetadata{
+		HostID: host1.ID,
+		Notes:  "in the server room",
+		Tags:   fleet.HostTags{"owner": "alice", "cost_center": "42"},
+	}))
+	require.NoError(t, ds.SetHostMetadata(ctx, &fleet.HostMetadata{HostID: host2.ID, Notes: "spare"}))
+
+	metadata, err = ds.HostMetadata(ctx, host1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "in the server room", metadata.Notes)
+	assert.Equal(t, fleet.HostTags{"owner": "alice", "cost_center": "42"}, metadata.Tags)
+	metadata, err = ds.HostMetadata(ctx, host2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "spare", metadata.Notes)
+	assert.Equal(t, fleet.HostTags{}, metadata.Tags)
+
+	// setting it again replaces it
+	require.NoError(t, ds.SetHostMetadata(ctx, &fleet.HostMetadata{HostID: host1.ID, Tags: fleet.HostTags{"owner": "bob"}}))
+	metadata, err = ds.HostMetadata(ctx, host1.ID)
+	require.NoError(t, err)
+	assert.Empty(t, metadata.Notes)
+	assert.Equal(t, fleet.HostTags{"owner": "bob"}, metadata.Tags)
+}
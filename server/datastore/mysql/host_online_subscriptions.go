@@ -0,0 +1,131 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewHostOnlineSubscription(ctx context.Context, hostID, userID uint) (*fleet.HostOnlineSubscription, error) {
+	_, err := ds.writer.ExecContext(ctx,
+		`INSERT IGNORE INTO host_online_subscriptions (host_id, user_id) VALUES (?, ?)`,
+		hostID, userID,
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "insert host online subscription")
+	}
+
+	var sub fleet.HostOnlineSubscription
+	err = sqlx.GetContext(ctx, ds.writer, &sub, `
+		SELECT s.id, s.host_id, s.user_id, s.created_at, h.hostname
+		FROM host_online_subscriptions s
+		JOIN hosts h ON h.id = s.host_id
+		WHERE s.host_id = ? AND s.user_id = ?`,
+		hostID, userID,
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host online subscription")
+	}
+	return &sub, nil
+}
+
+func (ds *Datastore) ListHostOnlineSubscriptions(ctx context.Context, userID uint) ([]*fleet.HostOnlineSubscription, error) {
+	var subs []*fleet.HostOnlineSubscription
+	err := sqlx.SelectContext(ctx, ds.reader, &subs, `
+		SELECT s.id, s.host_id, s.user_id, s.created_at, h.hostname
+		FROM host_online_subscriptions s
+		JOIN hosts h ON h.id = s.host_id
+		WHERE s.user_id = ?
+		ORDER BY s.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host online subscriptions")
+	}
+	return subs, nil
+}
+
+func (ds *Datastore) DeleteHostOnlineSubscription(ctx context.Context, hostID, userID uint) error {
+	res, err := ds.writer.ExecContext(ctx,
+		`DELETE FROM host_online_subscriptions WHERE host_id = ? AND user_id = ?`,
+		hostID, userID,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete host online subscription")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("HostOnlineSubscription").WithID(hostID))
+	}
+	return nil
+}
+
+func (ds *Datastore) ConsumeHostOnlineSubscriptions(ctx context.Context, hostIDs []uint) ([]*fleet.HostOnlineNotification, error) {
+	if len(hostIDs) == 0 {
+		return nil, nil
+	}
+
+	// This runs every time the seen hosts are flushed, with potentially a lot
+	// of hosts, while there are few subscriptions: look up the subscribed hosts
+	// first and only lock the subscriptions of the hosts that were seen.
+	var subscribedIDs []uint
+	if err := sqlx.SelectContext(ctx, ds.writer, &subscribedIDs,
+		`SELECT DISTINCT host_id FROM host_online_subscriptions`,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select subscribed hosts")
+	}
+	if len(subscribedIDs) == 0 {
+		return nil, nil
+	}
+	seen := make(map[uint]bool, len(hostIDs))
+	for _, id := range hostIDs {
+		seen[id] = true
+	}
+	var seenIDs []uint
+	for _, id := range subscribedIDs {
+		if seen[id] {
+			seenIDs = append(seenIDs, id)
+		}
+	}
+	if len(seenIDs) == 0 {
+		return nil, nil
+	}
+
+	var notifications []*fleet.HostOnlineNotification
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		notifications = nil
+
+		// the rows are locked so that when several Fleet instances see the host,
+		// only one of them notifies the subscribers.
+		stmt, args, err := sqlx.In(`
+			SELECT s.host_id, h.hostname, s.user_id, u.name AS user_name, u.email AS user_email
+			FROM host_online_subscriptions s
+			JOIN hosts h ON h.id = s.host_id
+			JOIN users u ON u.id = s.user_id
+			WHERE s.host_id IN (?)
+			ORDER BY s.id
+			FOR UPDATE`,
+			seenIDs,
+		)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build select host online subscriptions")
+		}
+		if err := sqlx.SelectContext(ctx, tx, &notifications, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "select host online subscriptions")
+		}
+
+		stmt, args, err = sqlx.In(`DELETE FROM host_online_subscriptions WHERE host_id IN (?)`, seenIDs)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build delete host online subscriptions")
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete host online subscriptions")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "consume host online subscriptions")
+	}
+	return notifications, nil
+}
@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostOnlineSubscriptions(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Lifecycle", testHostOnlineSubscriptionsLifecycle},
+		{"Consume", testHostOnlineSubscriptionsConsume},
+		{"UserDeleted", testHostOnlineSubscriptionsUserDeleted},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testHostOnlineSubscriptionsLifecycle(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	alice := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	bob := test.NewUser(t, ds, "Bob", "bob@example.com", true)
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+
+	sub, err := ds.NewHostOnlineSubscription(ctx, host1.ID, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, host1.ID, sub.HostID)
+	assert.Equal(t, alice.ID, sub.UserID)
+	assert.Equal(t, "host1", sub.Hostname)
+	assert.False(t, sub.CreatedAt.IsZero())
+
+	// subscribing again returns the existing subscription
+	again, err := ds.NewHostOnlineSubscription(ctx, host1.ID, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sub.ID, again.ID)
+
+	_, err = ds.NewHostOnlineSubscription(ctx, host2.ID, alice.ID)
+	require.NoError(t, err)
+	_, err = ds.NewHostOnlineSubscription(ctx, host1.ID, bob.ID)
+	require.NoError(t, err)
+
+	subs, err := ds.ListHostOnlineSubscriptions(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, subs, 2)
+	assert.Equal(t, host1.ID, subs[0].HostID)
+	assert.Equal(t, host2.ID, subs[1].HostID)
+	assert.Equal(t, "host2", subs[1].Hostname)
+
+	require.NoError(t, ds.DeleteHostOnlineSubscription(ctx, host1.ID, alice.ID))
+	err = ds.DeleteHostOnlineSubscription(ctx, host1.ID, alice.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	subs, err = ds.ListHostOnlineSubscriptions(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, host2.ID, subs[0].HostID)
+
+	// bob's subscription is left untouched
+	subs, err = ds.ListHostOnlineSubscriptions(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, host1.ID, subs[0].HostID)
+}
+
+func testHostOnlineSubscriptionsConsume(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	alice := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	bob := test.NewUser(t, ds, "Bob", "bob@example.com", true)
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	host3 := test.NewHost(t, ds, "host3", "", "host3key", "host3uuid", time.Now())
+
+	// nothing to consume without subscriptions
+	notifications, err := ds.ConsumeHostOnlineSubscriptions(ctx, []uint{host1.ID, host2.ID})
+	require.NoError(t, err)
+	require.Empty(t, notifications)
+
+	_, err = ds.NewHostOnlineSubscription(ctx, host1.ID, alice.ID)
+	require.NoError(t, err)
+	_, err = ds.NewHostOnlineSubscription(ctx, host1.ID, bob.ID)
+	require.NoError(t, err)
+	_, err = ds.NewHostOnlineSubscription(ctx, host2.ID, bob.ID)
+	require.NoError(t, err)
+
+	notifications, err = ds.ConsumeHostOnlineSubscriptions(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, notifications)
+
+	notifications, err = ds.ConsumeHostOnlineSubscriptions(ctx, []uint{host1.ID, host3.ID})
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, fleet.HostOnlineNotification{
+		HostID: host1.ID, Hostname: "host1", UserID: alice.ID, UserName: "Alice", UserEmail: "alice@example.com",
+	}, *notifications[0])
+	assert.Equal(t, fleet.HostOnlineNotification{
+		HostID: host1.ID, Hostname: "host1", UserID: bob.ID, UserName: "Bob", UserEmail: "bob@example.com",
+	}, *notifications[1])
+
+	// the subscriptions are only notified once
+	notifications, err = ds.ConsumeHostOnlineSubscriptions(ctx, []uint{host1.ID, host3.ID})
+	require.NoError(t, err)
+	require.Empty(t, notifications)
+
+	subs, err := ds.ListHostOnlineSubscriptions(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, host2.ID, subs[0].HostID)
+}
+
+func testHostOnlineSubscriptionsUserDeleted(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	_, err := ds.NewHostOnlineSubscription(ctx, host.ID, user.ID)
+	require.NoError(t, err)
+	require.NoError(t, ds.DeleteUser(ctx, user.ID))
+
+	notifications, err := ds.ConsumeHostOnlineSubscriptions(ctx, []uint{host.ID})
+	require.NoError(t, err)
+	require.Empty(t, notifications)
+}
@@ -18,7 +18,7 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-var hostSearchColumns = []string{"hostname", "uuid", "hardware_serial", "primary_ip"}
+var hostSearchColumns = []string{"hostname", "display_name", "uuid", "hardware_serial", "primary_ip", "primary_mac"}
 
 // NewHost creates a new host on the datastore.
 //
@@ -42,9 +42,10 @@ func (ds *Datastore) NewHost(ctx context.Context, host *fleet.Host) (*fleet.Host
 		distributed_interval,
 		logger_tls_period,
 		config_tls_refresh,
-		refetch_requested
+		refetch_requested,
+		display_name
 	)
-	VALUES( ?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,? )
+	VALUES( ?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,? )
 	`
 	result, err := ds.writer.ExecContext(
 		ctx,
@@ -66,6 +67,7 @@ func (ds *Datastore) NewHost(ctx context.Context, host *fleet.Host) (*fleet.Host
 		host.LoggerTLSPeriod,
 		host.ConfigTLSRefresh,
 		host.RefetchRequested,
+		host.DisplayName,
 	)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "new host")
@@ -324,52 +326,86 @@ var hostRefs = []string{
 	"host_mdm",
 	"host_munki_info",
 	"host_device_auth",
+	"host_orbit_auth",
+	"host_threat_findings",
+	"host_dns_servers",
+	"host_proxies",
+	"host_agent_options_overrides",
+	"host_startup_items",
+	"host_startup_item_changes",
+	"host_listening_ports",
+	"host_online_subscriptions",
+	"host_agent_versions",
+	"host_issues",
+	"host_policy_remediations",
+	"host_disks",
+	"host_batteries",
+	"host_certificates",
+	"host_network_addresses",
+	"host_metadata",
+	"host_activities",
+	"host_offline_reports",
 }
 
+// deleteHostsBatchSize is the maximum number of hosts deleted in a single
+// transaction by DeleteHosts.
+var deleteHostsBatchSize = 500
+
 func (ds *Datastore) DeleteHost(ctx context.Context, hid uint) error {
-	delHostRef := func(tx sqlx.ExtContext, table string) error {
-		_, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE host_id=?`, table), hid)
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		return deleteHostsDB(ctx, tx, []uint{hid})
+	})
+}
+
+func deleteHostsDB(ctx context.Context, tx sqlx.ExtContext, ids []uint) error {
+	exec := func(stmt string, args ...interface{}) error {
+		stmt, args, err := sqlx.In(stmt, args...)
 		if err != nil {
-			return ctxerr.Wrapf(ctx, err, "deleting %s for host %d", table, hid)
+			return err
 		}
-		return nil
+		_, err = tx.ExecContext(ctx, stmt, args...)
+		return err
 	}
 
-	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
-		_, err := tx.ExecContext(ctx, `DELETE FROM hosts WHERE id = ?`, hid)
-		if err != nil {
-			return ctxerr.Wrapf(ctx, err, "delete host")
-		}
+	err := exec(`
+		INSERT INTO host_tombstones (host_id, team_id, hostname, uuid)
+		SELECT id, team_id, hostname, uuid FROM hosts WHERE id IN (?)`, ids)
+	if err != nil {
+		return ctxerr.Wrapf(ctx, err, "insert host tombstone")
+	}
 
-		for _, table := range hostRefs {
-			err := delHostRef(tx, table)
-			if err != nil {
-				return err
-			}
-		}
+	err = exec(`
+		INSERT INTO host_lifecycle_events (host_id, event_type, hostname, uuid, hardware_serial, team_id)
+		SELECT id, ?, hostname, uuid, hardware_serial, team_id FROM hosts WHERE id IN (?)`, fleet.HostLifecycleEventDeleted, ids)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "insert host deleted lifecycle events")
+	}
 
-		_, err = tx.ExecContext(ctx, `DELETE FROM pack_targets WHERE type=? AND target_id=?`, fleet.TargetHost, hid)
-		if err != nil {
-			return ctxerr.Wrapf(ctx, err, "deleting pack_targets for host %d", hid)
+	if err := exec(`DELETE FROM hosts WHERE id IN (?)`, ids); err != nil {
+		return ctxerr.Wrapf(ctx, err, "delete host")
+	}
+
+	for _, table := range hostRefs {
+		if err := exec(fmt.Sprintf(`DELETE FROM %s WHERE host_id IN (?)`, table), ids); err != nil {
+			return ctxerr.Wrapf(ctx, err, "deleting %s for hosts", table)
 		}
+	}
 
-		return nil
-	})
+	if err := exec(`DELETE FROM pack_targets WHERE type = ? AND target_id IN (?)`, fleet.TargetHost, ids); err != nil {
+		return ctxerr.Wrapf(ctx, err, "deleting pack_targets for hosts")
+	}
+	return nil
 }
 
 func (ds *Datastore) Host(ctx context.Context, id uint, skipLoadingExtras bool) (*fleet.Host, error) {
 	policiesColumns := `,
-		       coalesce(failing_policies.count, 0) as failing_policies_count,
-		       coalesce(failing_policies.count, 0) as total_issues_count`
+		       coalesce(hi.failing_policies_count, 0) as failing_policies_count,
+		       coalesce(hi.total_issues_count, 0) as total_issues_count`
 	policiesJoin := `
-			JOIN (
-		    	SELECT count(*) as count FROM policy_membership WHERE passes=0 AND host_id=?
-			) failing_policies`
-	args := []interface{}{id, id}
+			LEFT JOIN host_issues hi ON (h.id = hi.host_id)`
 	if skipLoadingExtras {
 		policiesColumns = ""
 		policiesJoin = ""
-		args = []interface{}{id}
 	}
 	sqlStatement := fmt.Sprintf(`
 		SELECT
@@ -385,7 +421,7 @@ func (ds *Datastore) Host(ctx context.Context, id uint, skipLoadingExtras bool)
 		WHERE h.id = ?
 		LIMIT 1`, policiesColumns, policiesJoin)
 	host := &fleet.Host{}
-	err := sqlx.GetContext(ctx, ds.reader, host, sqlStatement, args...)
+	err := sqlx.GetContext(ctx, ds.reader, host, sqlStatement, id)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ctxerr.Wrap(ctx, notFound("Host").WithID(id))
@@ -425,8 +461,8 @@ func (ds *Datastore) ListHosts(ctx context.Context, filter fleet.TeamFilter, opt
 		`
 
 	failingPoliciesSelect := `,
-		coalesce(failing_policies.count, 0) as failing_policies_count,
-		coalesce(failing_policies.count, 0) as total_issues_count
+		coalesce(hi.failing_policies_count, 0) as failing_policies_count,
+		coalesce(hi.total_issues_count, 0) as total_issues_count
 `
 	if opt.DisableFailingPolicies {
 		failingPoliciesSelect = ""
@@ -455,7 +491,11 @@ func (ds *Datastore) ListHosts(ctx context.Context, filter fleet.TeamFilter, opt
 		    `
 	}
 
-	sql, params = ds.applyHostFilters(opt, sql, filter, params)
+	settings, err := ds.hostStatusSettings(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	sql, params = ds.applyHostFilters(opt, sql, filter, params, settings)
 
 	hosts := []*fleet.Host{}
 	if err := sqlx.SelectContext(ctx, ds.reader, &hosts, sql, params...); err != nil {
@@ -465,7 +505,20 @@ func (ds *Datastore) ListHosts(ctx context.Context, filter fleet.TeamFilter, opt
 	return hosts, nil
 }
 
-func (ds *Datastore) applyHostFilters(opt fleet.HostListOptions, sql string, filter fleet.TeamFilter, params []interface{}) (string, []interface{}) {
+// hostStatusSettings returns the host settings used to filter the hosts by
+// status. The app config is only loaded if the hosts are filtered by status.
+func (ds *Datastore) hostStatusSettings(ctx context.Context, opt fleet.HostListOptions) (fleet.HostSettings, error) {
+	if opt.StatusFilter == "" {
+		return fleet.HostSettings{}, nil
+	}
+	ac, err := ds.AppConfig(ctx)
+	if err != nil {
+		return fleet.HostSettings{}, ctxerr.Wrap(ctx, err, "get app config for host status")
+	}
+	return ac.HostSettings, nil
+}
+
+func (ds *Datastore) applyHostFilters(opt fleet.HostListOptions, sql string, filter fleet.TeamFilter, params []interface{}, settings fleet.HostSettings) (string, []interface{}) {
 	policyMembershipJoin := "JOIN policy_membership pm ON (h.id=pm.host_id)"
 	if opt.PolicyIDFilter == nil {
 		policyMembershipJoin = ""
@@ -479,10 +532,7 @@ func (ds *Datastore) applyHostFilters(opt fleet.HostListOptions, sql string, fil
 		params = append(params, opt.SoftwareIDFilter)
 	}
 
-	failingPoliciesJoin := `LEFT JOIN (
-		    SELECT host_id, count(*) as count FROM policy_membership WHERE passes=0
-		    GROUP BY host_id
-		) as failing_policies ON (h.id=failing_policies.host_id)`
+	failingPoliciesJoin := `LEFT JOIN host_issues hi ON (h.id = hi.host_id)`
 	if opt.DisableFailingPolicies {
 		failingPoliciesJoin = ""
 	}
@@ -496,9 +546,15 @@ func (ds *Datastore) applyHostFilters(opt fleet.HostListOptions, sql string, fil
     `, policyMembershipJoin, failingPoliciesJoin, ds.whereFilterHostsByTeams(filter, "h"), softwareFilter,
 	)
 
-	sql, params = filterHostsByStatus(sql, opt, params)
+	sql, params = filterHostsByStatus(sql, opt, params, settings)
 	sql, params = filterHostsByTeam(sql, opt, params)
 	sql, params = filterHostsByPolicy(sql, opt, params)
+	sql, params = filterHostsByLabels(sql, opt, params)
+	sql, params = filterHostsByAgentVersion(sql, opt, params)
+	sql, params = filterHostsByDiskSpace(sql, opt, params)
+	sql, params = filterHostsByDiskEncryption(sql, opt, params)
+	sql, params = filterHostsByMacadmins(sql, opt, params)
+	sql, params = filterHostsByCertificate(sql, opt, params)
 	sql, params = hostSearchLike(sql, params, opt.MatchQuery, hostSearchColumns...)
 	sql, params = appendListOptionsWithCursorToSQL(sql, params, opt.ListOptions)
 
@@ -524,20 +580,170 @@ func filterHostsByPolicy(sql string, opt fleet.HostListOptions, params []interfa
 	return sql, params
 }
 
-func filterHostsByStatus(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+// filterHostsByLabels selects the hosts that are members of all the labels
+// of the filter. The intersection is computed from the label_membership
+// table alone (using the label_id index, which also covers host_id) before
+// being joined with the hosts.
+func filterHostsByLabels(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if len(opt.LabelIDsFilter) == 0 {
+		return sql, params
+	}
+
+	seen := make(map[uint]bool, len(opt.LabelIDsFilter))
+	var labelIDs []uint
+	for _, lid := range opt.LabelIDsFilter {
+		if !seen[lid] {
+			seen[lid] = true
+			labelIDs = append(labelIDs, lid)
+		}
+	}
+
+	sql += fmt.Sprintf(` AND h.id IN (
+		SELECT host_id FROM label_membership
+		WHERE label_id IN (%s)
+		GROUP BY host_id
+		HAVING COUNT(*) = ?
+	)`, strings.TrimSuffix(strings.Repeat("?,", len(labelIDs)), ","))
+	for _, lid := range labelIDs {
+		params = append(params, lid)
+	}
+	params = append(params, len(labelIDs))
+	return sql, params
+}
+
+// filterHostsByAgentVersion selects the hosts that report a version of the
+// agent component of the filter, or the version of the filter.
+func filterHostsByAgentVersion(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.AgentComponentFilter == "" {
+		return sql, params
+	}
+
+	versionFilter := ""
+	params = append(params, opt.AgentComponentFilter)
+	if opt.AgentVersionFilter != "" {
+		versionFilter = " AND hav.version = ?"
+		params = append(params, opt.AgentVersionFilter)
+	}
+	sql += fmt.Sprintf(` AND EXISTS (
+		SELECT 1 FROM host_agent_versions hav
+		WHERE hav.host_id = h.id AND hav.component = ?%s
+	)`, versionFilter)
+	return sql, params
+}
+
+// filterHostsByDiskSpace selects the hosts with less disk space available
+// than the gigabytes or the percentage of the filter.
+func filterHostsByDiskSpace(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.LowDiskSpaceFilter != nil {
+		sql += ` AND h.gigs_disk_space_available < ?`
+		params = append(params, *opt.LowDiskSpaceFilter)
+	}
+	if opt.LowDiskSpacePercentFilter != nil {
+		sql += ` AND h.percent_disk_space_available < ?`
+		params = append(params, *opt.LowDiskSpacePercentFilter)
+	}
+	return sql, params
+}
+
+// filterHostsByDiskEncryption selects the hosts that reported the encryption
+// status of the filter, the hosts that did not report it are never selected.
+func filterHostsByDiskEncryption(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.DiskEncryptionEnabledFilter != nil {
+		sql += ` AND h.disk_encryption_enabled = ?`
+		params = append(params, *opt.DiskEncryptionEnabledFilter)
+	}
+	return sql, params
+}
+
+// filterHostsByMacadmins selects the hosts with the MDM enrollment status or
+// the Munki version of the filter, as reported by the macadmins extension.
+func filterHostsByMacadmins(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.MDMEnrollmentStatusFilter != "" {
+		var cond string
+		switch opt.MDMEnrollmentStatusFilter {
+		case fleet.MDMEnrollmentStatusManual:
+			cond = "hmdm.enrolled AND NOT hmdm.installed_from_dep"
+		case fleet.MDMEnrollmentStatusAutomatic:
+			cond = "hmdm.enrolled AND hmdm.installed_from_dep"
+		default:
+			cond = "NOT hmdm.enrolled"
+		}
+		sql += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM host_mdm hmdm WHERE hmdm.host_id = h.id AND %s
+		)`, cond)
+	}
+	if opt.MunkiVersionFilter != "" {
+		sql += ` AND EXISTS (
+			SELECT 1 FROM host_munki_info hmi
+			WHERE hmi.host_id = h.id AND hmi.deleted_at IS NULL AND hmi.version = ?
+		)`
+		params = append(params, opt.MunkiVersionFilter)
+	}
+	return sql, params
+}
+
+// filterHostsByCertificate selects the hosts that have the certificate of the
+// filter installed.
+func filterHostsByCertificate(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.CertificateSHA1Filter != "" {
+		sql += ` AND EXISTS (
+			SELECT 1 FROM host_certificates hc WHERE hc.host_id = h.id AND hc.sha1 = ?
+		)`
+		params = append(params, opt.CertificateSHA1Filter)
+	}
+	return sql, params
+}
+
+// onlineIntervalSQL returns the number of seconds without checking in after
+// which a host (of the hostsTable table or alias) is offline, given the
+// offline threshold of the host settings.
+//
+// It must remain synchronized with Host.Status.
+func onlineIntervalSQL(hostsTable string, offlineThreshold time.Duration) string {
+	return fmt.Sprintf(
+		"GREATEST(LEAST(%[1]s.distributed_interval, %[1]s.config_tls_refresh) + %[2]d, %[3]d)",
+		hostsTable, fleet.OnlineIntervalBuffer, int64(offlineThreshold/time.Second),
+	)
+}
+
+// hostStatusSQL returns the SQL condition and its arguments that selects the
+// hosts (of the hostsTable table or alias, joined with host_seen_times as hst)
+// with the online, offline, missing or MIA status at the time now. The
+// statuses are mutually exclusive.
+//
+// It must remain synchronized with Host.Status.
+func hostStatusSQL(status fleet.HostStatus, hostsTable string, now time.Time, settings fleet.HostSettings) (string, []interface{}) {
+	seenTime := fmt.Sprintf("COALESCE(hst.seen_time, %s.created_at)", hostsTable)
+	onlineInterval := onlineIntervalSQL(hostsTable, settings.OfflineThreshold.Duration)
+	// without missing threshold the offline hosts are MIA after MIADuration,
+	// and no host is missing
+	missingThreshold := settings.MissingThreshold()
+	if missingThreshold == 0 {
+		missingThreshold = fleet.MIADuration
+	}
+	missingBefore := now.Add(-missingThreshold)
+	switch status {
+	case fleet.StatusOnline:
+		return fmt.Sprintf("DATE_ADD(%s, INTERVAL %s SECOND) > ?", seenTime, onlineInterval), []interface{}{now}
+	case fleet.StatusOffline:
+		return fmt.Sprintf("DATE_ADD(%[1]s, INTERVAL %[2]s SECOND) <= ? AND %[1]s > ?", seenTime, onlineInterval), []interface{}{now, missingBefore}
+	case fleet.StatusMissing:
+		return fmt.Sprintf("%[1]s <= ? AND DATE_ADD(%[1]s, INTERVAL 30 DAY) > ?", seenTime), []interface{}{missingBefore, now}
+	case fleet.StatusMIA:
+		return fmt.Sprintf("DATE_ADD(%s, INTERVAL 30 DAY) <= ?", seenTime), []interface{}{now}
+	}
+	return "TRUE", nil
+}
+
+func filterHostsByStatus(sql string, opt fleet.HostListOptions, params []interface{}, settings fleet.HostSettings) (string, []interface{}) {
 	switch opt.StatusFilter {
 	case "new":
 		sql += "AND DATE_ADD(h.created_at, INTERVAL 1 DAY) >= ?"
 		params = append(params, time.Now())
-	case "online":
-		sql += fmt.Sprintf("AND DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(h.distributed_interval, h.config_tls_refresh) + %d SECOND) > ?", fleet.OnlineIntervalBuffer)
-		params = append(params, time.Now())
-	case "offline":
-		sql += fmt.Sprintf("AND DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(h.distributed_interval, h.config_tls_refresh) + %d SECOND) <= ? AND DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) >= ?", fleet.OnlineIntervalBuffer)
-		params = append(params, time.Now(), time.Now())
-	case "mia":
-		sql += "AND DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) <= ?"
-		params = append(params, time.Now())
+	case fleet.StatusOnline, fleet.StatusOffline, fleet.StatusMissing, fleet.StatusMIA:
+		statusSQL, statusArgs := hostStatusSQL(opt.StatusFilter, "h", time.Now(), settings)
+		sql += "AND " + statusSQL
+		params = append(params, statusArgs...)
 	}
 	return sql, params
 }
@@ -549,8 +755,12 @@ func (ds *Datastore) CountHosts(ctx context.Context, filter fleet.TeamFilter, op
 	opt.Page = 0
 	opt.PerPage = 0
 
+	settings, err := ds.hostStatusSettings(ctx, opt)
+	if err != nil {
+		return 0, err
+	}
 	var params []interface{}
-	sql, params = ds.applyHostFilters(opt, sql, filter, params)
+	sql, params = ds.applyHostFilters(opt, sql, filter, params, settings)
 
 	var count int
 	if err := sqlx.GetContext(ctx, ds.reader, &count, sql, params...); err != nil {
@@ -561,45 +771,140 @@ func (ds *Datastore) CountHosts(ctx context.Context, filter fleet.TeamFilter, op
 }
 
 func (ds *Datastore) CleanupIncomingHosts(ctx context.Context, now time.Time) error {
-	sqlStatement := `
-		DELETE FROM hosts
+	const whereIncoming = `
 		WHERE hostname = '' AND osquery_version = ''
 		AND created_at < (? - INTERVAL 5 MINUTE)
 	`
-	if _, err := ds.writer.ExecContext(ctx, sqlStatement, now); err != nil {
-		return ctxerr.Wrap(ctx, err, "cleanup incoming hosts")
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		tombstoneStmt := `
+			INSERT INTO host_tombstones (host_id, team_id, hostname, uuid)
+			SELECT id, team_id, hostname, uuid FROM hosts` + whereIncoming
+		if _, err := tx.ExecContext(ctx, tombstoneStmt, now); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert incoming hosts tombstones")
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM hosts`+whereIncoming, now); err != nil {
+			return ctxerr.Wrap(ctx, err, "cleanup incoming hosts")
+		}
+		return nil
+	})
+}
+
+// hostChangesSettleDelay is how long the changes are given to be committed
+// before they are listed. The updated_at and deleted_at columns only have a
+// second precision and are set when the rows are written, not when the
+// transactions commit, so a change committed after a later one could have an
+// earlier position than the cursor. Only the changes older than that delay
+// are listed, and the tombstones position of the cursor is moved up to it
+// when all the tombstones are listed, so that it does not fall behind the
+// retention of the tombstones when no host is deleted.
+const hostChangesSettleDelay = time.Minute
+
+func (ds *Datastore) ListHostChanges(ctx context.Context, filter fleet.TeamFilter, cursor fleet.HostChangesCursor, limit int) (*fleet.HostChanges, error) {
+	// The changes are read from the primary, a replica could be behind by more
+	// than the settle delay. The cursor never moves past the settle time, so
+	// the changes are not lost as long as the transactions commit within the
+	// settle delay, but changes that take longer to commit can be missed.
+	var now time.Time
+	if err := sqlx.GetContext(ctx, ds.writer, &now, `SELECT NOW()`); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host changes time")
+	}
+	settled := now.Add(-hostChangesSettleDelay)
+
+	changes := fleet.HostChanges{Cursor: cursor}
+	hostsStmt := fmt.Sprintf(`
+		SELECT
+			h.*,
+			COALESCE(hst.seen_time, h.created_at) AS seen_time,
+			t.name AS team_name
+		FROM hosts h
+		LEFT JOIN host_seen_times hst ON (h.id = hst.host_id)
+		LEFT JOIN teams t ON (h.team_id = t.id)
+		WHERE (h.updated_at > ? OR (h.updated_at = ? AND h.id > ?)) AND h.updated_at < ? AND %s
+		ORDER BY h.updated_at, h.id
+		LIMIT ?
+	`, ds.whereFilterHostsByTeams(filter, "h"))
+	if err := sqlx.SelectContext(ctx, ds.writer, &changes.Hosts, hostsStmt, cursor.UpdatedAt, cursor.UpdatedAt, cursor.HostID, settled, limit); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list changed hosts")
+	}
+	if n := len(changes.Hosts); n > 0 {
+		changes.Cursor.UpdatedAt = changes.Hosts[n-1].UpdatedAt
+		changes.Cursor.HostID = changes.Hosts[n-1].ID
+	}
+
+	tombstonesStmt := fmt.Sprintf(`
+		SELECT host_id, team_id, hostname, uuid, deleted_at
+		FROM host_tombstones ht
+		WHERE (ht.deleted_at > ? OR (ht.deleted_at = ? AND ht.host_id > ?)) AND ht.deleted_at < ? AND %s
+		ORDER BY ht.deleted_at, ht.host_id
+		LIMIT ?
+	`, ds.whereFilterHostsByTeams(filter, "ht"))
+	if err := sqlx.SelectContext(ctx, ds.writer, &changes.Deleted, tombstonesStmt, cursor.DeletedAt, cursor.DeletedAt, cursor.DeletedHostID, settled, limit); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host tombstones")
+	}
+	if n := len(changes.Deleted); n > 0 {
+		changes.Cursor.DeletedAt = changes.Deleted[n-1].DeletedAt
+		changes.Cursor.DeletedHostID = changes.Deleted[n-1].HostID
+	}
+	if len(changes.Deleted) < limit {
+		if changes.Cursor.DeletedAt.Before(settled) {
+			changes.Cursor.DeletedAt = settled
+			changes.Cursor.DeletedHostID = 0
+		}
 	}
 
+	changes.More = len(changes.Hosts) == limit || len(changes.Deleted) == limit
+	return &changes, nil
+}
+
+func (ds *Datastore) CleanupHostTombstones(ctx context.Context, now time.Time) error {
+	_, err := ds.writer.ExecContext(ctx, `DELETE FROM host_tombstones WHERE deleted_at < ?`, now.Add(-fleet.HostTombstoneRetention))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup host tombstones")
+	}
 	return nil
 }
 
-func (ds *Datastore) GenerateHostStatusStatistics(ctx context.Context, filter fleet.TeamFilter, now time.Time, platform *string) (*fleet.HostSummary, error) {
+func (ds *Datastore) GenerateHostStatusStatistics(ctx context.Context, filter fleet.TeamFilter, now time.Time, settings fleet.HostSettings, platform *string, labelID *uint) (*fleet.HostSummary, error) {
 	// The logic in this function should remain synchronized with
 	// host.Status and CountHostsInTargets - that is, the intervals associated
 	// with each status must be the same.
 
-	args := []interface{}{now, now, now, now, now}
+	var args []interface{}
+	statusSQL := make(map[fleet.HostStatus]string)
+	for _, status := range []fleet.HostStatus{fleet.StatusMIA, fleet.StatusMissing, fleet.StatusOffline, fleet.StatusOnline} {
+		var statusArgs []interface{}
+		statusSQL[status], statusArgs = hostStatusSQL(status, "h", now, settings)
+		args = append(args, statusArgs...)
+	}
+	args = append(args, now)
 	whereClause := ds.whereFilterHostsByTeams(filter, "h")
+	var whereArgs []interface{}
 	if platform != nil {
 		whereClause += " AND h.platform IN (?) "
-		args = append(args, fleet.ExpandPlatform(*platform))
+		whereArgs = append(whereArgs, fleet.ExpandPlatform(*platform))
+	}
+	if labelID != nil {
+		whereClause += " AND EXISTS (SELECT 1 FROM label_membership lm WHERE lm.host_id = h.id AND lm.label_id = ?) "
+		whereArgs = append(whereArgs, *labelID)
 	}
+	args = append(args, whereArgs...)
 	sqlStatement := fmt.Sprintf(`
 			SELECT
 				COUNT(*) total,
-				COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) <= ? THEN 1 ELSE 0 END), 0) mia,
-				COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(distributed_interval, config_tls_refresh) + %d SECOND) <= ? AND DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) >= ? THEN 1 ELSE 0 END), 0) offline,
-				COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(distributed_interval, config_tls_refresh) + %d SECOND) > ? THEN 1 ELSE 0 END), 0) online,
-				COALESCE(SUM(CASE WHEN DATE_ADD(created_at, INTERVAL 1 DAY) >= ? THEN 1 ELSE 0 END), 0) new
+				COALESCE(SUM(CASE WHEN %s THEN 1 ELSE 0 END), 0) mia,
+				COALESCE(SUM(CASE WHEN %s THEN 1 ELSE 0 END), 0) missing,
+				COALESCE(SUM(CASE WHEN %s THEN 1 ELSE 0 END), 0) offline,
+				COALESCE(SUM(CASE WHEN %s THEN 1 ELSE 0 END), 0) online,
+				COALESCE(SUM(CASE WHEN DATE_ADD(h.created_at, INTERVAL 1 DAY) >= ? THEN 1 ELSE 0 END), 0) new
 			FROM hosts h LEFT JOIN host_seen_times hst ON (h.id=hst.host_id) WHERE %s
 			LIMIT 1;
-		`, fleet.OnlineIntervalBuffer, fleet.OnlineIntervalBuffer, whereClause)
+		`, statusSQL[fleet.StatusMIA], statusSQL[fleet.StatusMissing], statusSQL[fleet.StatusOffline], statusSQL[fleet.StatusOnline], whereClause)
 
 	stmt, args, err := sqlx.In(sqlStatement, args...)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "generating host statistics statement")
 	}
-	summary := fleet.HostSummary{TeamID: filter.TeamID}
+	summary := fleet.HostSummary{TeamID: filter.TeamID, LabelID: labelID}
 	err = sqlx.GetContext(ctx, ds.reader, &summary, stmt, args...)
 	if err != nil && err != sql.ErrNoRows {
 		return nil, ctxerr.Wrap(ctx, err, "generating host statistics")
@@ -607,10 +912,7 @@ func (ds *Datastore) GenerateHostStatusStatistics(ctx context.Context, filter fl
 
 	// get the counts per platform, the `h` alias for hosts is required so that
 	// reusing the whereClause is ok.
-	args = []interface{}{}
-	if platform != nil {
-		args = append(args, fleet.ExpandPlatform(*platform))
-	}
+	args = append([]interface{}{}, whereArgs...)
 	sqlStatement = fmt.Sprintf(`
 			SELECT
 			  COUNT(*) total,
@@ -635,7 +937,7 @@ func (ds *Datastore) GenerateHostStatusStatistics(ctx context.Context, filter fl
 }
 
 // EnrollHost enrolls a host
-func (ds *Datastore) EnrollHost(ctx context.Context, osqueryHostID, nodeKey string, teamID *uint, cooldown time.Duration) (*fleet.Host, error) {
+func (ds *Datastore) EnrollHost(ctx context.Context, osqueryHostID, nodeKey string, teamID *uint, cooldown time.Duration, consumeSecret string) (*fleet.Host, error) {
 	if osqueryHostID == "" {
 		return nil, ctxerr.New(ctx, "missing osquery host identifier")
 	}
@@ -645,11 +947,19 @@ func (ds *Datastore) EnrollHost(ctx context.Context, osqueryHostID, nodeKey stri
 		zeroTime := time.Unix(0, 0).Add(24 * time.Hour)
 
 		var hostID int64
+		activityType := fleet.HostActivityTypeReenrolled
 		err := sqlx.GetContext(ctx, tx, &host, `SELECT id, last_enrolled_at FROM hosts WHERE osquery_host_id = ?`, osqueryHostID)
 		switch {
 		case err != nil && !errors.Is(err, sql.ErrNoRows):
 			return ctxerr.Wrap(ctx, err, "check existing")
 		case errors.Is(err, sql.ErrNoRows):
+			// Only the enrollment of a new host uses the enroll secret, so that
+			// re-enrollments and rejected enrollments do not exhaust it.
+			if consumeSecret != "" {
+				if err := consumeEnrollSecretDB(ctx, tx, consumeSecret); err != nil {
+					return err
+				}
+			}
 			// Create new host record
 			sqlInsert := `
 				INSERT INTO hosts (
@@ -666,12 +976,13 @@ func (ds *Datastore) EnrollHost(ctx context.Context, osqueryHostID, nodeKey stri
 				return ctxerr.Wrap(ctx, err, "insert host")
 			}
 			hostID, _ = result.LastInsertId()
+			activityType = fleet.HostActivityTypeEnrolled
 		default:
 			// Prevent hosts from enrolling too often with the same identifier.
 			// Prior to adding this we saw many hosts (probably VMs) with the
 			// same identifier competing for enrollment and causing perf issues.
 			if cooldown > 0 && time.Since(host.LastEnrolledAt) < cooldown {
-				return backoff.Permanent(ctxerr.Errorf(ctx, "host identified by %s enrolling too often", osqueryHostID))
+				return backoff.Permanent(ctxerr.Wrapf(ctx, fleet.ErrHostEnrollCooldown, "host identified by %s", osqueryHostID))
 			}
 			hostID = int64(host.ID)
 			// Update existing host record
@@ -694,6 +1005,15 @@ func (ds *Datastore) EnrollHost(ctx context.Context, osqueryHostID, nodeKey stri
 		if err != nil {
 			return ctxerr.Wrap(ctx, err, "new host seen time")
 		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO host_activities (host_id, activity_type) VALUES (?, ?)`, hostID, activityType)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "new host enrollment activity")
+		}
+		// the lifecycle event types of the enrollments match the activity types
+		_, err = tx.ExecContext(ctx, `INSERT INTO host_lifecycle_events (host_id, event_type) VALUES (?, ?)`, hostID, activityType)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "new host enrollment lifecycle event")
+		}
 		sqlSelect := `
 			SELECT * FROM hosts WHERE id = ? LIMIT 1
 		`
@@ -776,6 +1096,40 @@ func (ds *Datastore) SetOrUpdateDeviceAuthToken(ctx context.Context, hostID uint
 	)
 }
 
+// LoadHostByOrbitNodeKey loads the host identified by the orbit node key.
+func (ds *Datastore) LoadHostByOrbitNodeKey(ctx context.Context, nodeKey string) (*fleet.Host, error) {
+	const query = `
+    SELECT
+      h.*
+    FROM
+      host_orbit_auth hoa
+    INNER JOIN
+      hosts h
+    ON
+      hoa.host_id = h.id
+    WHERE hoa.node_key = ?`
+
+	var host fleet.Host
+	switch err := sqlx.GetContext(ctx, ds.reader, &host, query, nodeKey); {
+	case err == nil:
+		return &host, nil
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, ctxerr.Wrap(ctx, notFound("Host"))
+	default:
+		return nil, ctxerr.Wrap(ctx, err, "find host")
+	}
+}
+
+// SetOrUpdateOrbitNodeKey inserts or updates the orbit node key for a host.
+func (ds *Datastore) SetOrUpdateOrbitNodeKey(ctx context.Context, hostID uint, nodeKey string) error {
+	return ds.updateOrInsert(
+		ctx,
+		`UPDATE host_orbit_auth SET node_key=? WHERE host_id=?`,
+		`INSERT INTO host_orbit_auth(node_key, host_id) VALUES (?,?)`,
+		nodeKey, hostID,
+	)
+}
+
 func (ds *Datastore) MarkHostsSeen(ctx context.Context, hostIDs []uint, t time.Time) error {
 	if len(hostIDs) == 0 {
 		return nil
@@ -810,7 +1164,8 @@ func (ds *Datastore) MarkHostsSeen(ctx context.Context, hostIDs []uint, t time.T
 
 // SearchHosts performs a search on the hosts table using the following criteria:
 //	- Use the provided team filter.
-//	- Search hostname, uuid, hardware_serial, and primary_ip using LIKE (mimics ListHosts behavior)
+//	- Search hostname, display_name, uuid, hardware_serial, primary_ip and primary_mac using LIKE, as well as
+//	  the addresses of all the network interfaces if the query is an IP or MAC address (mimics ListHosts behavior)
 //	- An optional list of IDs to omit from the search.
 func (ds *Datastore) SearchHosts(ctx context.Context, filter fleet.TeamFilter, matchQuery string, omit ...uint) ([]*fleet.Host, error) {
 	query := `SELECT
@@ -912,6 +1267,11 @@ func (ds *Datastore) AddHostsToTeam(ctx context.Context, teamID *uint, hostIDs [
 		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
 			return ctxerr.Wrap(ctx, err, "exec AddHostsToTeam delete policy membership")
 		}
+		if teamID != nil {
+			if err := deleteExemptedPolicyMembershipDB(ctx, tx, *teamID, hostIDs); err != nil {
+				return err
+			}
+		}
 
 		query, args, err = sqlx.In(`UPDATE hosts SET team_id = ? WHERE id IN (?)`, teamID, hostIDs)
 		if err != nil {
@@ -922,7 +1282,7 @@ func (ds *Datastore) AddHostsToTeam(ctx context.Context, teamID *uint, hostIDs [
 			return ctxerr.Wrap(ctx, err, "exec AddHostsToTeam")
 		}
 
-		return nil
+		return updateHostIssuesFailingPoliciesDB(ctx, tx, hostIDs)
 	})
 }
 
@@ -1021,9 +1381,18 @@ func (ds *Datastore) TotalAndUnseenHostsSince(ctx context.Context, daysCount int
 }
 
 func (ds *Datastore) DeleteHosts(ctx context.Context, ids []uint) error {
-	for _, id := range ids {
-		if err := ds.DeleteHost(ctx, id); err != nil {
-			return ctxerr.Wrapf(ctx, err, "delete host %d", id)
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > deleteHostsBatchSize {
+			batch = batch[:deleteHostsBatchSize]
+		}
+		ids = ids[len(batch):]
+
+		err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+			return deleteHostsDB(ctx, tx, batch)
+		})
+		if err != nil {
+			return ctxerr.Wrapf(ctx, err, "delete %d hosts", len(batch))
 		}
 	}
 	return nil
@@ -1042,15 +1411,21 @@ func (ds *Datastore) ListPoliciesForHost(ctx context.Context, host *fleet.Host)
 			WHEN pm.passes = 0 THEN 'fail'
 			ELSE ''
 		END AS response,
+		pm.updated_at AS last_evaluated_at,
 		coalesce(p.resolution, '') as resolution
 	FROM policies p
 	LEFT JOIN policy_membership pm ON (p.id=pm.policy_id AND host_id=?)
 	LEFT JOIN users u ON p.author_id = u.id
 	WHERE (p.team_id IS NULL OR p.team_id = (select team_id from hosts WHERE id = ?))
-	AND (p.platforms IS NULL OR p.platforms = "" OR FIND_IN_SET(?, p.platforms) != 0)`
+	AND (p.platforms IS NULL OR p.platforms = "" OR FIND_IN_SET(?, p.platforms) != 0)
+	AND NOT EXISTS (
+		SELECT 1 FROM team_policy_exemptions e
+		WHERE e.policy_id = p.id AND e.team_id = (select team_id from hosts WHERE id = ?)
+	)
+	ORDER BY p.critical DESC, p.id`
 
 	var policies []*fleet.HostPolicy
-	if err := sqlx.SelectContext(ctx, ds.reader, &policies, query, host.ID, host.ID, host.FleetPlatform()); err != nil {
+	if err := sqlx.SelectContext(ctx, ds.reader, &policies, query, host.ID, host.ID, host.FleetPlatform(), host.ID); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "get host policies")
 	}
 	return policies, nil
@@ -1061,7 +1436,9 @@ func (ds *Datastore) CleanupExpiredHosts(ctx context.Context) error {
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "getting app config")
 	}
-	if !ac.HostExpirySettings.HostExpiryEnabled {
+	if !ac.HostExpirySettings.HostExpiryEnabled || ac.HostExpirySettings.HostExpiryWindow <= 0 {
+		// a window of 0 days would expire all the hosts, it is rejected when
+		// the settings are modified.
 		return nil
 	}
 
@@ -1128,6 +1505,28 @@ func (ds *Datastore) ListHostDeviceMapping(ctx context.Context, id uint) ([]*fle
 	return mappings, nil
 }
 
+func (ds *Datastore) ListHostUsers(ctx context.Context, hostID uint) ([]fleet.HostUser, error) {
+	stmt := `
+    SELECT
+      username,
+      groupname,
+      uid,
+      user_type,
+      shell
+    FROM
+      host_users
+    WHERE
+      host_id = ? AND removed_at IS NULL
+    ORDER BY
+      username`
+
+	var users []fleet.HostUser
+	if err := sqlx.SelectContext(ctx, ds.reader, &users, stmt, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host users by host id")
+	}
+	return users, nil
+}
+
 func (ds *Datastore) ReplaceHostDeviceMapping(ctx context.Context, hid uint, mappings []*fleet.HostDeviceMapping) error {
 	for _, m := range mappings {
 		if hid != m.HostID {
@@ -1146,7 +1545,8 @@ func (ds *Datastore) ReplaceHostDeviceMapping(ctx context.Context, hid uint, map
       FROM
         host_emails
       WHERE
-        host_id = ?`
+        host_id = ? AND
+        source <> ?`
 
 		delStmt = `
       DELETE FROM
@@ -1166,12 +1566,16 @@ func (ds *Datastore) ReplaceHostDeviceMapping(ctx context.Context, hid uint, map
 	// need to be deleted and inserted
 	toIns := make(map[string]*fleet.HostDeviceMapping)
 	for _, m := range mappings {
+		if m.Source == fleet.DeviceMappingCustom {
+			// the custom mapping is only set via SetOrUpdateCustomHostDeviceMapping
+			continue
+		}
 		toIns[m.Email+"\n"+m.Source] = m
 	}
 
 	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
 		var prevMappings []*fleet.HostDeviceMapping
-		if err := sqlx.SelectContext(ctx, tx, &prevMappings, selStmt, hid); err != nil {
+		if err := sqlx.SelectContext(ctx, tx, &prevMappings, selStmt, hid, fleet.DeviceMappingCustom); err != nil {
 			return ctxerr.Wrap(ctx, err, "select previous host emails")
 		}
 
@@ -1211,6 +1615,30 @@ func (ds *Datastore) ReplaceHostDeviceMapping(ctx context.Context, hid uint, map
 	})
 }
 
+func (ds *Datastore) SetOrUpdateCustomHostDeviceMapping(ctx context.Context, hostID uint, email string) ([]*fleet.HostDeviceMapping, error) {
+	const (
+		delStmt = `DELETE FROM host_emails WHERE host_id = ? AND source = ?`
+		insStmt = `INSERT INTO host_emails (host_id, email, source) VALUES (?, ?, ?)`
+	)
+
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if _, err := tx.ExecContext(ctx, delStmt, hostID, fleet.DeviceMappingCustom); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete custom host email")
+		}
+		if email == "" {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, insStmt, hostID, email, fleet.DeviceMappingCustom); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert custom host email")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ds.ListHostDeviceMapping(ctx, hostID)
+}
+
 func (ds *Datastore) updateOrInsert(ctx context.Context, updateQuery string, insertQuery string, args ...interface{}) error {
 	res, err := ds.writer.ExecContext(ctx, updateQuery, args...)
 	if err != nil {
@@ -1509,6 +1937,98 @@ func (ds *Datastore) UpdateHostRefetchRequested(ctx context.Context, id uint, va
 // UpdateHost updates all columns of the `hosts` table.
 // It only updates `hosts` table, other additional host information is ignored.
 func (ds *Datastore) UpdateHost(ctx context.Context, host *fleet.Host) error {
+	return updateHostDB(ctx, ds.writer, host)
+}
+
+// UpdateHostsDetails updates the detail columns of the hosts in a single
+// transaction, so that the hosts ingested asynchronously are saved in batches.
+// Only the columns set by the detail queries (and the refetch request they
+// fulfill) are updated, so that the columns updated concurrently by the other
+// results of the hosts (e.g. the label and policy timestamps) or by the API
+// (e.g. the team) are not overwritten.
+func (ds *Datastore) UpdateHostsDetails(ctx context.Context, hosts []*fleet.Host) error {
+	sqlStatement := `
+		UPDATE hosts SET
+			detail_updated_at = ?,
+			hostname = ?,
+			uuid = ?,
+			platform = ?,
+			osquery_version = ?,
+			os_version = ?,
+			uptime = ?,
+			memory = ?,
+			cpu_type = ?,
+			cpu_subtype = ?,
+			cpu_brand = ?,
+			cpu_physical_cores = ?,
+			hardware_vendor = ?,
+			hardware_model = ?,
+			hardware_version = ?,
+			hardware_serial = ?,
+			computer_name = ?,
+			build = ?,
+			platform_like = ?,
+			code_name = ?,
+			cpu_logical_cores = ?,
+			distributed_interval = ?,
+			config_tls_refresh = ?,
+			logger_tls_period = ?,
+			primary_ip = ?,
+			primary_mac = ?,
+			public_ip = ?,
+			refetch_requested = ?,
+			gigs_disk_space_available = ?,
+			percent_disk_space_available = ?,
+			display_name = ?,
+			disk_encryption_enabled = ?
+		WHERE id = ?
+	`
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		for _, host := range hosts {
+			_, err := tx.ExecContext(ctx, sqlStatement,
+				host.DetailUpdatedAt,
+				host.Hostname,
+				host.UUID,
+				host.Platform,
+				host.OsqueryVersion,
+				host.OSVersion,
+				host.Uptime,
+				host.Memory,
+				host.CPUType,
+				host.CPUSubtype,
+				host.CPUBrand,
+				host.CPUPhysicalCores,
+				host.HardwareVendor,
+				host.HardwareModel,
+				host.HardwareVersion,
+				host.HardwareSerial,
+				host.ComputerName,
+				host.Build,
+				host.PlatformLike,
+				host.CodeName,
+				host.CPULogicalCores,
+				host.DistributedInterval,
+				host.ConfigTLSRefresh,
+				host.LoggerTLSPeriod,
+				host.PrimaryIP,
+				host.PrimaryMac,
+				host.PublicIP,
+				host.RefetchRequested,
+				host.GigsDiskSpaceAvailable,
+				host.PercentDiskSpaceAvailable,
+				host.DisplayName,
+				host.DiskEncryptionEnabled,
+				host.ID,
+			)
+			if err != nil {
+				return ctxerr.Wrapf(ctx, err, "update details of host with id %d", host.ID)
+			}
+		}
+		return nil
+	})
+}
+
+func updateHostDB(ctx context.Context, exec sqlx.ExecerContext, host *fleet.Host) error {
 	sqlStatement := `
 		UPDATE hosts SET
 			detail_updated_at = ?,
@@ -1544,10 +2064,12 @@ func (ds *Datastore) UpdateHost(ctx context.Context, host *fleet.Host) error {
 			public_ip = ?,
 			refetch_requested = ?,
 			gigs_disk_space_available = ?,
-			percent_disk_space_available = ?
+			percent_disk_space_available = ?,
+			display_name = ?,
+			disk_encryption_enabled = ?
 		WHERE id = ?
 	`
-	_, err := ds.writer.ExecContext(ctx, sqlStatement,
+	_, err := exec.ExecContext(ctx, sqlStatement,
 		host.DetailUpdatedAt,
 		host.LabelUpdatedAt,
 		host.PolicyUpdatedAt,
@@ -1582,6 +2104,8 @@ func (ds *Datastore) UpdateHost(ctx context.Context, host *fleet.Host) error {
 		host.RefetchRequested,
 		host.GigsDiskSpaceAvailable,
 		host.PercentDiskSpaceAvailable,
+		host.DisplayName,
+		host.DiskEncryptionEnabled,
 		host.ID,
 	)
 	if err != nil {
@@ -1590,6 +2114,27 @@ func (ds *Datastore) UpdateHost(ctx context.Context, host *fleet.Host) error {
 	return nil
 }
 
+// UpdateHostDisplayNames recomputes the display name of all hosts with the
+// provided sources, see fleet.Host.ComputeDisplayName.
+func (ds *Datastore) UpdateHostDisplayNames(ctx context.Context, sources []string) error {
+	// the sources are host columns, only accept the known ones as they are
+	// used in the SQL statement.
+	var names []string
+	for _, source := range sources {
+		if !fleet.IsValidHostDisplayNameSource(source) {
+			return ctxerr.Errorf(ctx, "invalid host display name source: %s", source)
+		}
+		names = append(names, fmt.Sprintf("NULLIF(%s, '')", source))
+	}
+	names = append(names, "hostname")
+
+	stmt := fmt.Sprintf(`UPDATE hosts SET display_name = COALESCE(%s)`, strings.Join(names, ", "))
+	if _, err := ds.writer.ExecContext(ctx, stmt); err != nil {
+		return ctxerr.Wrap(ctx, err, "update hosts display name")
+	}
+	return nil
+}
+
 // OSVersions gets the aggregated os version host counts. If a non-nil teamID is passed, it will filter hosts by team.
 func (ds *Datastore) OSVersions(ctx context.Context, teamID *uint, platform *string) (*fleet.OSVersions, error) {
 	query := `
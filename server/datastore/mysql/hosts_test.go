@@ -75,6 +75,12 @@ func TestHosts(t *testing.T) {
 		{"ListFilterAdditional", testHostsListFilterAdditional},
 		{"ListStatus", testHostsListStatus},
 		{"ListQuery", testHostsListQuery},
+		{"ListByLabels", testHostsListByLabels},
+		{"ListByDiskSpace", testHostsListByDiskSpace},
+		{"ListByDiskEncryption", testHostsListByDiskEncryption},
+		{"ListByMacadmins", testHostsListByMacadmins},
+		{"ListByCertificate", testHostsListByCertificate},
+		{"SearchByNetworkAddress", testHostsSearchByNetworkAddress},
 		{"Enroll", testHostsEnroll},
 		{"LoadHostByNodeKey", testHostsLoadHostByNodeKey},
 		{"LoadHostByNodeKeyCaseSensitive", testHostsLoadHostByNodeKeyCaseSensitive},
@@ -107,6 +113,8 @@ func TestHosts(t *testing.T) {
 		{"HostsNoSeenTime", testHostsNoSeenTime},
 		{"ListHostDeviceMapping", testHostsListHostDeviceMapping},
 		{"ReplaceHostDeviceMapping", testHostsReplaceHostDeviceMapping},
+		{"CustomHostDeviceMapping", testHostsCustomHostDeviceMapping},
+		{"UpdateHostsDetails", testHostsUpdateHostsDetails},
 		{"HostMDMAndMunki", testHostMDMAndMunki},
 		{"AggregatedHostMDMAndMunki", testAggregatedHostMDMAndMunki},
 		{"HostLite", testHostsLite},
@@ -114,8 +122,12 @@ func TestHosts(t *testing.T) {
 		{"UpdateRefetchRequested", testUpdateRefetchRequested},
 		{"LoadHostByDeviceAuthToken", testHostsLoadHostByDeviceAuthToken},
 		{"SetOrUpdateDeviceAuthToken", testHostsSetOrUpdateDeviceAuthToken},
+		{"SetOrUpdateOrbitNodeKey", testHostsSetOrUpdateOrbitNodeKey},
 		{"OSVersions", testOSVersions},
 		{"DeleteHosts", testHostsDeleteHosts},
+		{"DeleteHostsBatches", testHostsDeleteHostsBatches},
+		{"ListHostChanges", testHostsListHostChanges},
+		{"UpdateHostDisplayNames", testHostsUpdateDisplayNames},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -709,6 +721,324 @@ func testHostsListStatus(t *testing.T, ds *Datastore) {
 
 	hosts = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "new", ListOptions: fleet.ListOptions{OrderKey: "h.id", After: fmt.Sprint(hosts[2].ID)}}, 7)
 	assert.Equal(t, 7, len(hosts))
+
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "missing"}, 0)
+
+	// a host not seen for 10 days is offline, no host is missing without
+	// missing threshold
+	_, err := ds.NewHost(context.Background(), &fleet.Host{
+		DetailUpdatedAt: time.Now(),
+		LabelUpdatedAt:  time.Now(),
+		PolicyUpdatedAt: time.Now(),
+		SeenTime:        time.Now().Add(-10 * 24 * time.Hour),
+		OsqueryHostID:   "10",
+		NodeKey:         "10",
+		UUID:            "10",
+		Hostname:        "foo.local10",
+	})
+	require.NoError(t, err)
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "offline"}, 10)
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "missing"}, 0)
+
+	// the thresholds are configurable
+	ac, err := ds.AppConfig(context.Background())
+	require.NoError(t, err)
+	defer func(settings fleet.HostSettings) {
+		ac.HostSettings = settings
+		require.NoError(t, ds.SaveAppConfig(context.Background(), ac))
+	}(ac.HostSettings)
+	ac.HostSettings.OfflineThreshold = fleet.Duration{Duration: 5 * time.Minute}
+	ac.HostSettings.MissingThresholdDays = 7
+	require.NoError(t, ds.SaveAppConfig(context.Background(), ac))
+
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "online"}, 3)
+	hosts = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "missing"}, 1)
+	assert.Equal(t, "foo.local10", hosts[0].Hostname)
+	// the statuses are mutually exclusive
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "offline"}, 7)
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "mia"}, 0)
+
+	ac.HostSettings.MissingThresholdDays = 14
+	require.NoError(t, ds.SaveAppConfig(context.Background(), ac))
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "offline"}, 8)
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{StatusFilter: "missing"}, 0)
+}
+
+func testHostsListByLabels(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	var hosts []*fleet.Host
+	for i := 0; i < 4; i++ {
+		host, err := ds.NewHost(ctx, &fleet.Host{
+			DetailUpdatedAt: time.Now(),
+			LabelUpdatedAt:  time.Now(),
+			PolicyUpdatedAt: time.Now(),
+			SeenTime:        time.Now(),
+			OsqueryHostID:   strconv.Itoa(i),
+			NodeKey:         strconv.Itoa(i),
+			UUID:            strconv.Itoa(i),
+			Hostname:        fmt.Sprintf("foo.local%d", i),
+		})
+		require.NoError(t, err)
+		hosts = append(hosts, host)
+	}
+
+	l1 := &fleet.LabelSpec{ID: 1, Name: "label1", Query: "query1"}
+	l2 := &fleet.LabelSpec{ID: 2, Name: "label2", Query: "query2"}
+	l3 := &fleet.LabelSpec{ID: 3, Name: "label3", Query: "query3"}
+	require.NoError(t, ds.ApplyLabelSpecs(ctx, []*fleet.LabelSpec{l1, l2, l3}))
+
+	// host 0 is in labels 1, 2 and 3, host 1 in labels 1 and 2, host 2 in
+	// label 1 only, and host 3 in no label.
+	require.NoError(t, ds.RecordLabelQueryExecutions(ctx, hosts[0], map[uint]*bool{l1.ID: ptr.Bool(true), l2.ID: ptr.Bool(true), l3.ID: ptr.Bool(true)}, time.Now(), false))
+	require.NoError(t, ds.RecordLabelQueryExecutions(ctx, hosts[1], map[uint]*bool{l1.ID: ptr.Bool(true), l2.ID: ptr.Bool(true), l3.ID: ptr.Bool(false)}, time.Now(), false))
+	require.NoError(t, ds.RecordLabelQueryExecutions(ctx, hosts[2], map[uint]*bool{l1.ID: ptr.Bool(true)}, time.Now(), false))
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	got := listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LabelIDsFilter: []uint{l1.ID}}, 3)
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID, hosts[2].ID}, hostIDs(got))
+
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LabelIDsFilter: []uint{l1.ID, l2.ID}}, 2)
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, hostIDs(got))
+
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LabelIDsFilter: []uint{l1.ID, l2.ID, l3.ID}}, 1)
+	assert.Equal(t, []uint{hosts[0].ID}, hostIDs(got))
+
+	// duplicate label IDs are ignored
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LabelIDsFilter: []uint{l2.ID, l2.ID}}, 2)
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, hostIDs(got))
+
+	// unknown label
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LabelIDsFilter: []uint{l1.ID, 999}}, 0)
+
+	// combined with other filters and pagination
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{
+		ListOptions:    fleet.ListOptions{MatchQuery: "foo.local1"},
+		LabelIDsFilter: []uint{l1.ID},
+	}, 1)
+	assert.Equal(t, []uint{hosts[1].ID}, hostIDs(got))
+
+	got, err := ds.ListHosts(ctx, filter, fleet.HostListOptions{
+		ListOptions:    fleet.ListOptions{PerPage: 1, OrderKey: "id"},
+		LabelIDsFilter: []uint{l1.ID, l2.ID},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{hosts[0].ID}, hostIDs(got))
+
+	// combined with a label of the hosts in label endpoint
+	got = listHostsInLabelCheckCount(t, ds, filter, l1.ID, fleet.HostListOptions{LabelIDsFilter: []uint{l3.ID}}, 1)
+	assert.Equal(t, []uint{hosts[0].ID}, hostIDs(got))
+}
+
+func testHostsListByDiskSpace(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	// host 0 has 5GB (10%) available, host 1 50GB (40%) and host 2 200GB (80%)
+	space := []struct {
+		gigs    float64
+		percent float64
+	}{{5, 10}, {50, 40}, {200, 80}}
+	var hosts []*fleet.Host
+	for i, sp := range space {
+		host := test.NewHost(t, ds, fmt.Sprintf("foo.local%d", i), "", strconv.Itoa(i), strconv.Itoa(i), time.Now())
+		host.GigsDiskSpaceAvailable = sp.gigs
+		host.PercentDiskSpaceAvailable = sp.percent
+		require.NoError(t, ds.UpdateHost(ctx, host))
+		hosts = append(hosts, host)
+	}
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	got := listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LowDiskSpaceFilter: ptr.Int(100)}, 2)
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, hostIDs(got))
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LowDiskSpaceFilter: ptr.Int(5)}, 0)
+	assert.Empty(t, got)
+
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LowDiskSpacePercentFilter: ptr.Int(20)}, 1)
+	assert.Equal(t, []uint{hosts[0].ID}, hostIDs(got))
+
+	// both filters must match
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LowDiskSpaceFilter: ptr.Int(100), LowDiskSpacePercentFilter: ptr.Int(50)}, 2)
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, hostIDs(got))
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{LowDiskSpaceFilter: ptr.Int(10), LowDiskSpacePercentFilter: ptr.Int(5)}, 0)
+}
+
+func testHostsListByDiskEncryption(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	// host 0 is encrypted, host 1 is not, host 2 did not report it
+	enabled := []*bool{ptr.Bool(true), ptr.Bool(false), nil}
+	var hosts []*fleet.Host
+	for i, e := range enabled {
+		host := test.NewHost(t, ds, fmt.Sprintf("foo.local%d", i), "", strconv.Itoa(i), strconv.Itoa(i), time.Now())
+		host.DiskEncryptionEnabled = e
+		require.NoError(t, ds.UpdateHost(ctx, host))
+		hosts = append(hosts, host)
+	}
+
+	host, err := ds.Host(ctx, hosts[0].ID, false)
+	require.NoError(t, err)
+	require.NotNil(t, host.DiskEncryptionEnabled)
+	assert.True(t, *host.DiskEncryptionEnabled)
+	host, err = ds.Host(ctx, hosts[2].ID, false)
+	require.NoError(t, err)
+	assert.Nil(t, host.DiskEncryptionEnabled)
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	got := listHostsCheckCount(t, ds, filter, fleet.HostListOptions{DiskEncryptionEnabledFilter: ptr.Bool(true)}, 1)
+	assert.Equal(t, hosts[0].ID, got[0].ID)
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{DiskEncryptionEnabledFilter: ptr.Bool(false)}, 1)
+	assert.Equal(t, hosts[1].ID, got[0].ID)
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{}, 3)
+}
+
+func testHostsListByMacadmins(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	var hosts []*fleet.Host
+	for i := 0; i < 4; i++ {
+		hosts = append(hosts, test.NewHost(t, ds, fmt.Sprintf("foo.local%d", i), "", strconv.Itoa(i), strconv.Itoa(i), time.Now()))
+	}
+
+	// host 0 is enrolled manually, host 1 automatically, host 2 is not
+	// enrolled and host 3 did not report its MDM status
+	require.NoError(t, ds.SetOrUpdateMDMData(ctx, hosts[0].ID, true, "https://mdm.example.com", false))
+	require.NoError(t, ds.SetOrUpdateMDMData(ctx, hosts[1].ID, true, "https://mdm.example.com", true))
+	require.NoError(t, ds.SetOrUpdateMDMData(ctx, hosts[2].ID, false, "", false))
+	require.NoError(t, ds.SetOrUpdateMunkiVersion(ctx, hosts[0].ID, "5.6.3"))
+	require.NoError(t, ds.SetOrUpdateMunkiVersion(ctx, hosts[1].ID, "5.6.3"))
+	require.NoError(t, ds.SetOrUpdateMunkiVersion(ctx, hosts[2].ID, "5.5.0"))
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	got := listHostsCheckCount(t, ds, filter, fleet.HostListOptions{MDMEnrollmentStatusFilter: fleet.MDMEnrollmentStatusManual}, 1)
+	assert.Equal(t, []uint{hosts[0].ID}, hostIDs(got))
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{MDMEnrollmentStatusFilter: fleet.MDMEnrollmentStatusAutomatic}, 1)
+	assert.Equal(t, []uint{hosts[1].ID}, hostIDs(got))
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{MDMEnrollmentStatusFilter: fleet.MDMEnrollmentStatusUnenrolled}, 1)
+	assert.Equal(t, []uint{hosts[2].ID}, hostIDs(got))
+
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{MunkiVersionFilter: "5.6.3"}, 2)
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, hostIDs(got))
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{MunkiVersionFilter: "5.6.3", MDMEnrollmentStatusFilter: fleet.MDMEnrollmentStatusAutomatic}, 1)
+	assert.Equal(t, []uint{hosts[1].ID}, hostIDs(got))
+
+	// uninstalled Munki is not reported
+	require.NoError(t, ds.SetOrUpdateMunkiVersion(ctx, hosts[2].ID, ""))
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{MunkiVersionFilter: "5.5.0"}, 0)
+}
+
+func testHostsListByCertificate(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	var hosts []*fleet.Host
+	for i := 0; i < 3; i++ {
+		hosts = append(hosts, test.NewHost(t, ds, fmt.Sprintf("foo.local%d", i), "", strconv.Itoa(i), strconv.Itoa(i), time.Now()))
+	}
+
+	root := fleet.HostCertificate{SHA1: "d1eb23a46d17d68fd92564c2f1f1601764d8e349", CommonName: "root", CA: true, SelfSigned: true}
+	leaf := fleet.HostCertificate{SHA1: "0123456789abcdef0123456789abcdef01234567", CommonName: "leaf"}
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, hosts[0].ID, []fleet.HostCertificate{root, leaf}))
+	require.NoError(t, ds.ReplaceHostCertificates(ctx, hosts[1].ID, []fleet.HostCertificate{root}))
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	got := listHostsCheckCount(t, ds, filter, fleet.HostListOptions{CertificateSHA1Filter: root.SHA1}, 2)
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, hostIDs(got))
+	got = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{CertificateSHA1Filter: leaf.SHA1}, 1)
+	assert.Equal(t, []uint{hosts[0].ID}, hostIDs(got))
+	listHostsCheckCount(t, ds, filter, fleet.HostListOptions{CertificateSHA1Filter: "ffffffffffffffffffffffffffffffffffffffff"}, 0)
+}
+
+func testHostsSearchByNetworkAddress(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	var hosts []*fleet.Host
+	for i := 0; i < 3; i++ {
+		host := test.NewHost(t, ds, fmt.Sprintf("foo.local%d", i), "", strconv.Itoa(i), strconv.Itoa(i), time.Now())
+		host.PrimaryIP = fmt.Sprintf("192.168.1.%d", i)
+		host.PrimaryMac = fmt.Sprintf("aa:bb:cc:dd:ee:0%d", i)
+		require.NoError(t, ds.SaveHost(ctx, host))
+		hosts = append(hosts, host)
+	}
+
+	require.NoError(t, ds.ReplaceHostNetworkAddresses(ctx, hosts[0].ID, []fleet.HostNetworkAddress{
+		{Address: "192.168.1.0", MAC: "aa:bb:cc:dd:ee:00"},
+		{Address: "10.8.0.2", MAC: ""},
+		{Address: "fe80::1", MAC: "11:22:33:44:55:66"},
+	}))
+	require.NoError(t, ds.ReplaceHostNetworkAddresses(ctx, hosts[1].ID, []fleet.HostNetworkAddress{
+		{Address: "192.168.1.1", MAC: "aa:bb:cc:dd:ee:01"},
+		{Address: "fe80::1", MAC: "11:22:33:44:55:77"},
+	}))
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+	listByQuery := func(query string, expectedCount int) []uint {
+		return hostIDs(listHostsCheckCount(t, ds, filter, fleet.HostListOptions{ListOptions: fleet.ListOptions{MatchQuery: query}}, expectedCount))
+	}
+
+	// the addresses of the other interfaces are searched
+	assert.Equal(t, []uint{hosts[0].ID}, listByQuery("10.8.0.2", 1))
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, listByQuery("fe80::1", 2))
+	assert.Equal(t, []uint{hosts[1].ID}, listByQuery("11-22-33-44-55-77", 1))
+	// as well as the primary addresses
+	assert.Equal(t, []uint{hosts[2].ID}, listByQuery("192.168.1.2", 1))
+	assert.Equal(t, []uint{hosts[2].ID}, listByQuery("aa:bb:cc:dd:ee:02", 1))
+	assert.Len(t, listByQuery("aa:bb:cc", 3), 3)
+	listByQuery("10.8.0.3", 0)
+
+	found, err := ds.SearchHosts(ctx, filter, "10.8.0.2")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{hosts[0].ID}, hostIDs(found))
+
+	// replace keeps the existing addresses and removes the missing ones
+	require.NoError(t, ds.ReplaceHostNetworkAddresses(ctx, hosts[0].ID, []fleet.HostNetworkAddress{
+		{Address: "192.168.1.0", MAC: "aa:bb:cc:dd:ee:00"},
+		{Address: "10.8.0.3", MAC: ""},
+	}))
+	listByQuery("10.8.0.2", 0)
+	assert.Equal(t, []uint{hosts[0].ID}, listByQuery("10.8.0.3", 1))
+	assert.Equal(t, []uint{hosts[1].ID}, listByQuery("fe80::1", 1))
+
+	// the addresses are deleted with the host
+	require.NoError(t, ds.DeleteHost(ctx, hosts[1].ID))
+	listByQuery("fe80::1", 0)
 }
 
 func testHostsListQuery(t *testing.T, ds *Datastore) {
@@ -821,18 +1151,18 @@ func testHostsEnroll(t *testing.T, ds *Datastore) {
 	}
 
 	for _, tt := range enrollTests {
-		h, err := ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey, &team.ID, 0)
+		h, err := ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey, &team.ID, 0, "")
 		require.NoError(t, err)
 
 		assert.Equal(t, tt.uuid, h.OsqueryHostID)
 		assert.Equal(t, tt.nodeKey, h.NodeKey)
 
 		// This host should be allowed to re-enroll immediately if cooldown is disabled
-		_, err = ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey+"new", nil, 0)
+		_, err = ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey+"new", nil, 0, "")
 		require.NoError(t, err)
 
 		// This host should not be allowed to re-enroll immediately if cooldown is enabled
-		_, err = ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey+"new", nil, 10*time.Second)
+		_, err = ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey+"new", nil, 10*time.Second, "")
 		require.Error(t, err)
 	}
 
@@ -847,7 +1177,7 @@ func testHostsEnroll(t *testing.T, ds *Datastore) {
 func testHostsLoadHostByNodeKey(t *testing.T, ds *Datastore) {
 	test.AddAllHostsLabel(t, ds)
 	for _, tt := range enrollTests {
-		h, err := ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey, nil, 0)
+		h, err := ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey, nil, 0, "")
 		require.NoError(t, err)
 
 		returned, err := ds.LoadHostByNodeKey(context.Background(), h.NodeKey)
@@ -865,7 +1195,7 @@ func testHostsLoadHostByNodeKey(t *testing.T, ds *Datastore) {
 func testHostsLoadHostByNodeKeyCaseSensitive(t *testing.T, ds *Datastore) {
 	test.AddAllHostsLabel(t, ds)
 	for _, tt := range enrollTests {
-		h, err := ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey, nil, 0)
+		h, err := ds.EnrollHost(context.Background(), tt.uuid, tt.nodeKey, nil, 0, "")
 		require.NoError(t, err)
 
 		_, err = ds.LoadHostByNodeKey(context.Background(), strings.ToUpper(h.NodeKey))
@@ -1076,7 +1406,7 @@ func testHostsGenerateStatusStatistics(t *testing.T, ds *Datastore) {
 	filter := fleet.TeamFilter{User: test.UserAdmin}
 	mockClock := clock.NewMockClock()
 
-	summary, err := ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now(), nil)
+	summary, err := ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now(), fleet.HostSettings{}, nil, nil)
 	require.NoError(t, err)
 	assert.Nil(t, summary.TeamID)
 	assert.Equal(t, uint(0), summary.TotalsHostsCount)
@@ -1157,16 +1487,43 @@ func testHostsGenerateStatusStatistics(t *testing.T, ds *Datastore) {
 		{Platform: "darwin", HostsCount: 1},
 	}
 
-	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now(), nil)
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now(), fleet.HostSettings{}, nil, nil)
 	require.NoError(t, err)
 	assert.Equal(t, uint(4), summary.TotalsHostsCount)
 	assert.Equal(t, uint(2), summary.OnlineCount)
 	assert.Equal(t, uint(1), summary.OfflineCount)
 	assert.Equal(t, uint(1), summary.MIACount)
+	assert.Equal(t, uint(0), summary.MissingCount)
 	assert.Equal(t, uint(4), summary.NewCount)
 	assert.ElementsMatch(t, summary.Platforms, wantPlatforms)
 
-	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(1*time.Hour), nil)
+	// with the configured thresholds
+	settings := fleet.HostSettings{OfflineThreshold: fleet.Duration{Duration: 2 * time.Hour}, MissingThresholdDays: 14}
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now(), settings, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), summary.OnlineCount)
+	assert.Equal(t, uint(0), summary.OfflineCount)
+	assert.Equal(t, uint(1), summary.MIACount)
+	assert.Equal(t, uint(0), summary.MissingCount)
+
+	// the hosts not seen for longer than the missing threshold are missing
+	// until they are MIA, no host is missing without missing threshold
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(8*24*time.Hour), fleet.HostSettings{}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), summary.OfflineCount)
+	assert.Equal(t, uint(0), summary.MissingCount)
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(8*24*time.Hour), fleet.HostSettings{MissingThresholdDays: 7}, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), summary.OnlineCount)
+	assert.Equal(t, uint(0), summary.OfflineCount)
+	assert.Equal(t, uint(3), summary.MissingCount)
+	assert.Equal(t, uint(1), summary.MIACount)
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(8*24*time.Hour), settings, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), summary.OfflineCount)
+	assert.Equal(t, uint(0), summary.MissingCount)
+
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(1*time.Hour), fleet.HostSettings{}, nil, nil)
 	require.NoError(t, err)
 	assert.Equal(t, uint(4), summary.TotalsHostsCount)
 	assert.Equal(t, uint(0), summary.OnlineCount)
@@ -1178,37 +1535,75 @@ func testHostsGenerateStatusStatistics(t *testing.T, ds *Datastore) {
 	userObs := &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}
 	filter = fleet.TeamFilter{User: userObs}
 
-	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(1*time.Hour), nil)
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(1*time.Hour), fleet.HostSettings{}, nil, nil)
 	require.NoError(t, err)
 	assert.Equal(t, uint(0), summary.TotalsHostsCount)
 
 	filter.IncludeObserver = true
-	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(1*time.Hour), nil)
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(1*time.Hour), fleet.HostSettings{}, nil, nil)
 	require.NoError(t, err)
 	assert.Equal(t, uint(4), summary.TotalsHostsCount)
 
 	userTeam1 := &fleet.User{Teams: []fleet.UserTeam{{Team: *team1, Role: fleet.RoleAdmin}}}
 	filter = fleet.TeamFilter{User: userTeam1}
-	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(1*time.Hour), nil)
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now().Add(1*time.Hour), fleet.HostSettings{}, nil, nil)
 	require.NoError(t, err)
 	assert.Equal(t, uint(1), summary.TotalsHostsCount)
 	assert.Equal(t, uint(1), summary.MIACount)
 
-	summary, err = ds.GenerateHostStatusStatistics(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, mockClock.Now(), ptr.String("linux"))
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, mockClock.Now(), fleet.HostSettings{}, ptr.String("linux"), nil)
 	require.NoError(t, err)
 	assert.Equal(t, uint(2), summary.TotalsHostsCount)
 
-	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now(), ptr.String("linux"))
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now(), fleet.HostSettings{}, ptr.String("linux"), nil)
 	require.NoError(t, err)
 	assert.Equal(t, uint(1), summary.TotalsHostsCount)
 
-	summary, err = ds.GenerateHostStatusStatistics(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, mockClock.Now(), ptr.String("darwin"))
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, mockClock.Now(), fleet.HostSettings{}, ptr.String("darwin"), nil)
 	require.NoError(t, err)
 	assert.Equal(t, uint(1), summary.TotalsHostsCount)
 
-	summary, err = ds.GenerateHostStatusStatistics(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, mockClock.Now(), ptr.String("windows"))
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, mockClock.Now(), fleet.HostSettings{}, ptr.String("windows"), nil)
 	require.NoError(t, err)
 	assert.Equal(t, uint(1), summary.TotalsHostsCount)
+
+	// the hosts 1, 3 and 4 are members of the label
+	label, err := ds.NewLabel(context.Background(), &fleet.Label{Name: "label1", Query: "select 1"})
+	require.NoError(t, err)
+	for _, id := range []uint{1, 3, 4} {
+		require.NoError(t, ds.RecordLabelQueryExecutions(context.Background(), &fleet.Host{ID: id, LabelUpdatedAt: mockClock.Now()}, map[uint]*bool{label.ID: ptr.Bool(true)}, mockClock.Now(), false))
+	}
+
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, mockClock.Now(), fleet.HostSettings{}, nil, &label.ID)
+	require.NoError(t, err)
+	require.NotNil(t, summary.LabelID)
+	assert.Equal(t, label.ID, *summary.LabelID)
+	assert.Equal(t, uint(3), summary.TotalsHostsCount)
+	assert.Equal(t, uint(1), summary.OnlineCount)
+	assert.Equal(t, uint(1), summary.OfflineCount)
+	assert.Equal(t, uint(1), summary.MIACount)
+	assert.ElementsMatch(t, summary.Platforms, []*fleet.HostSummaryPlatform{
+		{Platform: "debian", HostsCount: 1},
+		{Platform: "rhel", HostsCount: 1},
+		{Platform: "darwin", HostsCount: 1},
+	})
+
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, mockClock.Now(), fleet.HostSettings{}, ptr.String("linux"), &label.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), summary.TotalsHostsCount)
+	assert.ElementsMatch(t, summary.Platforms, []*fleet.HostSummaryPlatform{
+		{Platform: "debian", HostsCount: 1},
+		{Platform: "rhel", HostsCount: 1},
+	})
+
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), filter, mockClock.Now(), fleet.HostSettings{}, nil, &label.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), summary.TotalsHostsCount)
+	assert.Equal(t, uint(1), summary.MIACount)
+
+	summary, err = ds.GenerateHostStatusStatistics(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, mockClock.Now(), fleet.HostSettings{}, nil, ptr.Uint(label.ID+1))
+	require.NoError(t, err)
+	assert.Equal(t, uint(0), summary.TotalsHostsCount)
 }
 
 func testHostsMarkSeen(t *testing.T, ds *Datastore) {
@@ -1355,6 +1750,15 @@ func testHostsCleanupIncoming(t *testing.T, ds *Datastore) {
 	assert.NotNil(t, err)
 	_, err = ds.Host(context.Background(), h2.ID, false)
 	require.NoError(t, err)
+
+	// A tombstone is recorded for the deleted host
+	// the tombstones are only listed once settled
+	_, err = ds.writer.Exec(`UPDATE host_tombstones SET deleted_at = DATE_SUB(deleted_at, INTERVAL 2 MINUTE)`)
+	require.NoError(t, err)
+	changes, err := ds.ListHostChanges(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, fleet.NewHostChangesCursor(mockClock.Now().Add(-time.Hour)), 100)
+	require.NoError(t, err)
+	require.Len(t, changes.Deleted, 1)
+	assert.Equal(t, h1.ID, changes.Deleted[0].HostID)
 }
 
 func testHostsIDsByName(t *testing.T, ds *Datastore) {
@@ -1950,6 +2354,36 @@ func testHostsListFailingPolicies(t *testing.T, ds *Datastore) {
 	checkHostIssues(t, ds, hosts, filter, h1.ID, 1)
 
 	checkHostIssuesWithOpts(t, ds, hosts, filter, h1.ID, fleet.HostListOptions{DisableFailingPolicies: true}, 0)
+
+	// the issues are updated when a failing team policy no longer applies to
+	// the host that changed team
+	team1, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.AddHostsToTeam(context.Background(), &team1.ID, []uint{h2.ID}))
+	tp, err := ds.NewTeamPolicy(context.Background(), team1.ID, &user1.ID, fleet.PolicyPayload{Name: "team policy", Query: "select 1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), h2, map[uint]*bool{p.ID: ptr.Bool(false), tp.ID: ptr.Bool(false)}, time.Now(), false))
+	checkHostIssues(t, ds, hosts, filter, h2.ID, 2)
+	require.NoError(t, ds.AddHostsToTeam(context.Background(), nil, []uint{h2.ID}))
+	checkHostIssues(t, ds, hosts, filter, h2.ID, 1)
+
+	// and when a failing policy is deleted
+	_, err = ds.DeleteGlobalPolicies(context.Background(), []uint{p.ID})
+	require.NoError(t, err)
+	checkHostIssues(t, ds, hosts, filter, h1.ID, 0)
+	checkHostIssues(t, ds, hosts, filter, h2.ID, 0)
+
+	// the other changes of the policy_membership rows are caught up by
+	// UpdateHostIssues
+	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), h1, map[uint]*bool{p2.ID: ptr.Bool(false)}, time.Now(), false))
+	checkHostIssues(t, ds, hosts, filter, h1.ID, 1)
+	_, err = ds.writer.Exec(`DELETE FROM policy_membership WHERE host_id = ?`, h1.ID)
+	require.NoError(t, err)
+	_, err = ds.writer.Exec(`INSERT INTO policy_membership (policy_id, host_id, passes) VALUES (?, ?, false)`, p2.ID, h2.ID)
+	require.NoError(t, err)
+	require.NoError(t, ds.UpdateHostIssues(context.Background()))
+	checkHostIssues(t, ds, hosts, filter, h1.ID, 0)
+	checkHostIssues(t, ds, hosts, filter, h2.ID, 1)
 }
 
 // This doesn't work when running the whole test suite, but helps inspect individual tests
@@ -2475,6 +2909,17 @@ func testHostsExpiration(t *testing.T, ds *Datastore) {
 
 	hosts = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{}, 5)
 	require.Len(t, hosts, 5)
+
+	// an invalid window does not remove all the hosts
+	ac.HostExpirySettings.HostExpiryWindow = 0
+	err = ds.SaveAppConfig(context.Background(), ac)
+	require.NoError(t, err)
+
+	err = ds.CleanupExpiredHosts(context.Background())
+	require.NoError(t, err)
+
+	hosts = listHostsCheckCount(t, ds, filter, fleet.HostListOptions{}, 5)
+	require.Len(t, hosts, 5)
 }
 
 func testHostsAllPackStats(t *testing.T, ds *Datastore) {
@@ -3127,7 +3572,7 @@ func testHostsNoSeenTime(t *testing.T, ds *Datastore) {
 	}, labelID, fleet.HostListOptions{}, 1)
 
 	mockClock := clock.NewMockClock()
-	summary, err := ds.GenerateHostStatusStatistics(context.Background(), teamFilter, mockClock.Now(), nil)
+	summary, err := ds.GenerateHostStatusStatistics(context.Background(), teamFilter, mockClock.Now(), fleet.HostSettings{}, nil, nil)
 	assert.NoError(t, err)
 	assert.Nil(t, summary.TeamID)
 	assert.Equal(t, uint(1), summary.TotalsHostsCount)
@@ -3143,7 +3588,7 @@ func testHostsNoSeenTime(t *testing.T, ds *Datastore) {
 	require.Zero(t, count[0])
 
 	// Enroll existing host.
-	_, err = ds.EnrollHost(context.Background(), "1", "1", nil, 0)
+	_, err = ds.EnrollHost(context.Background(), "1", "1", nil, 0, "")
 	require.NoError(t, err)
 
 	var seenTime1 []time.Time
@@ -3155,7 +3600,7 @@ func testHostsNoSeenTime(t *testing.T, ds *Datastore) {
 	time.Sleep(1 * time.Second)
 
 	// Enroll again to trigger an update of host_seen_times.
-	_, err = ds.EnrollHost(context.Background(), "1", "1", nil, 0)
+	_, err = ds.EnrollHost(context.Background(), "1", "1", nil, 0, "")
 	require.NoError(t, err)
 
 	var seenTime2 []time.Time
@@ -3242,7 +3687,7 @@ func testHostsNoSeenTime(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 	metrics, err := ds.CountHostsInTargets(context.Background(), teamFilter, fleet.HostTargets{
 		LabelIDs: []uint{l1.ID},
-	}, mockClock.Now())
+	}, mockClock.Now(), fleet.HostSettings{})
 	require.NoError(t, err)
 	assert.Equal(t, uint(3), metrics.TotalHosts)
 	assert.Equal(t, uint(0), metrics.OfflineHosts)
@@ -3351,6 +3796,77 @@ func testHostsReplaceHostDeviceMapping(t *testing.T, ds *Datastore) {
 	assertHostDeviceMapping(t, dms, nil)
 }
 
+func testHostsUpdateHostsDetails(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	require.NoError(t, ds.UpdateHostsDetails(ctx, nil))
+
+	// the host is updated concurrently while its details are ingested
+	hostCopy := host1.Copy()
+	host1.TeamID = &team.ID
+	host1.LabelUpdatedAt = time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	require.NoError(t, ds.UpdateHost(ctx, host1))
+
+	hostCopy.OSVersion = "macOS 12.3.0"
+	host2.OSVersion = "Ubuntu 20.04.4"
+	host2.RefetchRequested = true
+	require.NoError(t, ds.UpdateHostsDetails(ctx, []*fleet.Host{hostCopy, host2}))
+
+	// only the details are updated
+	got1, err := ds.Host(ctx, host1.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, "macOS 12.3.0", got1.OSVersion)
+	require.NotNil(t, got1.TeamID)
+	assert.Equal(t, team.ID, *got1.TeamID)
+	assert.Equal(t, host1.LabelUpdatedAt, got1.LabelUpdatedAt.UTC())
+	got2, err := ds.Host(ctx, host2.ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, "Ubuntu 20.04.4", got2.OSVersion)
+	assert.True(t, got2.RefetchRequested)
+}
+
+func testHostsCustomHostDeviceMapping(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	h := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	dms, err := ds.SetOrUpdateCustomHostDeviceMapping(ctx, h.ID, "a@b.c")
+	require.NoError(t, err)
+	assertHostDeviceMapping(t, dms, []*fleet.HostDeviceMapping{
+		{Email: "a@b.c", Source: fleet.DeviceMappingCustom},
+	})
+
+	// the custom mapping is kept when the host reports its mappings
+	err = ds.ReplaceHostDeviceMapping(ctx, h.ID, []*fleet.HostDeviceMapping{
+		{HostID: h.ID, Email: "b@b.c", Source: fleet.DeviceMappingGoogleChromeProfiles},
+	})
+	require.NoError(t, err)
+	dms, err = ds.ListHostDeviceMapping(ctx, h.ID)
+	require.NoError(t, err)
+	assertHostDeviceMapping(t, dms, []*fleet.HostDeviceMapping{
+		{Email: "a@b.c", Source: fleet.DeviceMappingCustom},
+		{Email: "b@b.c", Source: fleet.DeviceMappingGoogleChromeProfiles},
+	})
+
+	// the custom mapping is replaced
+	dms, err = ds.SetOrUpdateCustomHostDeviceMapping(ctx, h.ID, "c@b.c")
+	require.NoError(t, err)
+	assertHostDeviceMapping(t, dms, []*fleet.HostDeviceMapping{
+		{Email: "b@b.c", Source: fleet.DeviceMappingGoogleChromeProfiles},
+		{Email: "c@b.c", Source: fleet.DeviceMappingCustom},
+	})
+
+	// and removed with an empty email
+	dms, err = ds.SetOrUpdateCustomHostDeviceMapping(ctx, h.ID, "")
+	require.NoError(t, err)
+	assertHostDeviceMapping(t, dms, []*fleet.HostDeviceMapping{
+		{Email: "b@b.c", Source: fleet.DeviceMappingGoogleChromeProfiles},
+	})
+}
+
 func assertHostDeviceMapping(t *testing.T, got, want []*fleet.HostDeviceMapping) {
 	t.Helper()
 
@@ -3653,6 +4169,54 @@ func testUpdateRefetchRequested(t *testing.T, ds *Datastore) {
 	require.False(t, h.RefetchRequested)
 }
 
+func testHostsUpdateDisplayNames(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	newHost := func(name, computerName, serial string) *fleet.Host {
+		h, err := ds.NewHost(ctx, &fleet.Host{
+			OsqueryHostID:   name,
+			NodeKey:         name,
+			Hostname:        name,
+			DetailUpdatedAt: time.Now(),
+			LabelUpdatedAt:  time.Now(),
+			PolicyUpdatedAt: time.Now(),
+			SeenTime:        time.Now(),
+		})
+		require.NoError(t, err)
+		h.ComputerName = computerName
+		h.HardwareSerial = serial
+		h.DisplayName = h.ComputeDisplayName([]string{fleet.HostDisplayNameSourceComputerName, fleet.HostDisplayNameSourceHostname})
+		require.NoError(t, ds.UpdateHost(ctx, h))
+		return h
+	}
+	h1 := newHost("h1.local", "h1-computer", "h1-serial")
+	h2 := newHost("h2.local", "", "h2-serial")
+	h3 := newHost("h3.local", "", "")
+
+	displayNames := func() []string {
+		var names []string
+		for _, h := range []*fleet.Host{h1, h2, h3} {
+			host, err := ds.Host(ctx, h.ID, true)
+			require.NoError(t, err)
+			names = append(names, host.DisplayName)
+		}
+		return names
+	}
+	assert.Equal(t, []string{"h1-computer", "h2.local", "h3.local"}, displayNames())
+
+	require.NoError(t, ds.UpdateHostDisplayNames(ctx, []string{fleet.HostDisplayNameSourceHardwareSerial}))
+	assert.Equal(t, []string{"h1-serial", "h2-serial", "h3.local"}, displayNames())
+
+	// the hosts can be searched by display name
+	hosts, err := ds.ListHosts(ctx, fleet.TeamFilter{User: test.UserAdmin}, fleet.HostListOptions{ListOptions: fleet.ListOptions{MatchQuery: "h2-ser"}})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, h2.ID, hosts[0].ID)
+
+	require.Error(t, ds.UpdateHostDisplayNames(ctx, []string{"hostname; DROP TABLE hosts"}))
+	assert.Equal(t, []string{"h1-serial", "h2-serial", "h3.local"}, displayNames())
+}
+
 func testHostsSaveHostUsers(t *testing.T, ds *Datastore) {
 	host, err := ds.NewHost(context.Background(), &fleet.Host{
 		DetailUpdatedAt: time.Now(),
@@ -3692,6 +4256,17 @@ func testHostsSaveHostUsers(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 	require.Len(t, host.Users, 2)
 	test.ElementsMatchSkipID(t, users, host.Users)
+
+	// the removed users are not listed
+	err = ds.SaveHostUsers(context.Background(), host.ID, users[1:])
+	require.NoError(t, err)
+	listed, err := ds.ListHostUsers(context.Background(), host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, users[1:], listed)
+
+	listed, err = ds.ListHostUsers(context.Background(), host.ID+1)
+	require.NoError(t, err)
+	assert.Empty(t, listed)
 }
 
 func testHostsLoadHostByDeviceAuthToken(t *testing.T, ds *Datastore) {
@@ -3782,6 +4357,46 @@ func testHostsSetOrUpdateDeviceAuthToken(t *testing.T, ds *Datastore) {
 	assert.ErrorIs(t, err, sql.ErrNoRows)
 }
 
+func testHostsSetOrUpdateOrbitNodeKey(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host, err := ds.NewHost(ctx, &fleet.Host{
+		DetailUpdatedAt: time.Now(),
+		LabelUpdatedAt:  time.Now(),
+		PolicyUpdatedAt: time.Now(),
+		SeenTime:        time.Now(),
+		NodeKey:         "1",
+		UUID:            "1",
+		OsqueryHostID:   "1",
+		Hostname:        "foo.local",
+	})
+	require.NoError(t, err)
+
+	_, err = ds.LoadHostByOrbitNodeKey(ctx, "nosuchkey")
+	require.True(t, fleet.IsNotFound(err))
+
+	require.NoError(t, ds.SetOrUpdateOrbitNodeKey(ctx, host.ID, "key1"))
+	h, err := ds.LoadHostByOrbitNodeKey(ctx, "key1")
+	require.NoError(t, err)
+	require.Equal(t, host.ID, h.ID)
+
+	// the node key is not a device auth token
+	_, err = ds.LoadHostByDeviceAuthToken(ctx, "key1")
+	require.True(t, fleet.IsNotFound(err))
+
+	// a new enrollment replaces the node key
+	require.NoError(t, ds.SetOrUpdateOrbitNodeKey(ctx, host.ID, "key2"))
+	_, err = ds.LoadHostByOrbitNodeKey(ctx, "key1")
+	require.True(t, fleet.IsNotFound(err))
+	h, err = ds.LoadHostByOrbitNodeKey(ctx, "key2")
+	require.NoError(t, err)
+	require.Equal(t, host.ID, h.ID)
+
+	// and the node key is removed with the host
+	require.NoError(t, ds.DeleteHost(ctx, host.ID))
+	_, err = ds.LoadHostByOrbitNodeKey(ctx, "key2")
+	require.True(t, fleet.IsNotFound(err))
+}
+
 func testOSVersions(t *testing.T, ds *Datastore) {
 	team1, err := ds.NewTeam(context.Background(), &fleet.Team{
 		Name: "team1",
@@ -3911,6 +4526,36 @@ func testOSVersions(t *testing.T, ds *Datastore) {
 	require.Equal(t, expected, osVersions.OSVersions)
 }
 
+func testHostsDeleteHostsBatches(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	defer func(size int) { deleteHostsBatchSize = size }(deleteHostsBatchSize)
+	deleteHostsBatchSize = 2
+
+	var ids []uint
+	for i := 0; i < 5; i++ {
+		host := test.NewHost(t, ds, fmt.Sprintf("foo.local%d", i), "", strconv.Itoa(i), strconv.Itoa(i), time.Now())
+		ids = append(ids, host.ID)
+	}
+	keep := test.NewHost(t, ds, "keep.local", "", "keep", "keep", time.Now())
+
+	require.NoError(t, ds.DeleteHosts(ctx, ids))
+	for _, id := range ids {
+		_, err := ds.Host(ctx, id, true)
+		require.True(t, fleet.IsNotFound(err))
+	}
+	_, err := ds.Host(ctx, keep.ID, true)
+	require.NoError(t, err)
+
+	// a tombstone is recorded for each deleted host
+	// the tombstones are only listed once settled
+	_, err = ds.writer.Exec(`UPDATE host_tombstones SET deleted_at = DATE_SUB(deleted_at, INTERVAL 2 MINUTE)`)
+	require.NoError(t, err)
+	changes, err := ds.ListHostChanges(ctx, fleet.TeamFilter{User: test.UserAdmin}, fleet.NewHostChangesCursor(time.Now().Add(-time.Hour)), 100)
+	require.NoError(t, err)
+	assert.Len(t, changes.Deleted, len(ids))
+}
+
 func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 	// Updates hosts and host_seen_times.
 	host, err := ds.NewHost(context.Background(), &fleet.Host{
@@ -4022,6 +4667,51 @@ func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 	// Update device_auth_token.
 	err = ds.SetOrUpdateDeviceAuthToken(context.Background(), host.ID, "foo")
 	require.NoError(t, err)
+	// Update host_dns_servers and host_proxies.
+	err = ds.ReplaceHostDNSServers(context.Background(), host.ID, []string{"10.0.0.1"})
+	require.NoError(t, err)
+	err = ds.ReplaceHostProxies(context.Background(), host.ID, []fleet.HostProxy{{Protocol: "http", Address: "proxy.local:3128"}})
+	require.NoError(t, err)
+	// Update host_agent_options_overrides.
+	err = ds.SetHostAgentOptionsOverride(context.Background(), &fleet.HostAgentOptionsOverride{
+		HostID:    host.ID,
+		Options:   json.RawMessage(`{"options": {"verbose": true}}`),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	// Update host_startup_items and host_startup_item_changes (the first
+	// collection is the baseline, the second records a change).
+	err = ds.ReplaceHostStartupItems(context.Background(), host.ID, []fleet.HostStartupItem{{Source: "launchd", Name: "foo", Path: "/foo"}})
+	require.NoError(t, err)
+	err = ds.ReplaceHostStartupItems(context.Background(), host.ID, []fleet.HostStartupItem{{Source: "launchd", Name: "foo", Path: "/bar"}})
+	require.NoError(t, err)
+	// Update host_listening_ports.
+	err = ds.ReplaceHostListeningPorts(context.Background(), host.ID, []fleet.HostListeningPort{{Port: 22, Protocol: "tcp", ProcessName: "sshd"}})
+	require.NoError(t, err)
+	// Update host_threat_findings.
+	err = ds.SaveThreatIntelVerdicts(context.Background(), []fleet.ThreatIntelVerdict{{
+		Indicator: fleet.Indicator{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/22"},
+		Malicious: true,
+		CheckedAt: time.Now(),
+	}})
+	require.NoError(t, err)
+	err = ds.UpdateHostThreatFindings(context.Background())
+	require.NoError(t, err)
+	// Update host_online_subscriptions.
+	_, err = ds.NewHostOnlineSubscription(context.Background(), host.ID, user1.ID)
+	require.NoError(t, err)
+	// Update host_agent_versions.
+	err = ds.SetOrUpdateHostAgentVersion(context.Background(), host.ID, fleet.AgentComponentOrbit, "0.0.11")
+	require.NoError(t, err)
+	// Update host_metadata.
+	err = ds.SetHostMetadata(context.Background(), &fleet.HostMetadata{HostID: host.ID, Notes: "notes"})
+	require.NoError(t, err)
+	// Update host_activities.
+	err = ds.NewHostActivities(context.Background(), []uint{host.ID}, user1, fleet.HostActivityTypeTransferred, nil)
+	require.NoError(t, err)
+	// Update host_offline_reports.
+	err = ds.NewHostOfflineEvents(context.Background(), time.Now().Add(time.Hour))
+	require.NoError(t, err)
 
 	// Check there's an entry for the host in all the associated tables.
 	for _, hostRef := range hostRefs {
@@ -4042,3 +4732,153 @@ func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 		require.False(t, ok, "table: %s", hostRef)
 	}
 }
+
+func testHostsListHostChanges(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	h1 := test.NewHost(t, ds, "h1", "", "key1", "uuid1", now)
+	h2 := test.NewHost(t, ds, "h2", "", "key2", "uuid2", now)
+	h3 := test.NewHost(t, ds, "h3", "", "key3", "uuid3", now)
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team1.ID, []uint{h3.ID}))
+
+	setUpdatedAt := func(h *fleet.Host, ts time.Time) {
+		_, err := ds.writer.Exec(`UPDATE hosts SET updated_at = ? WHERE id = ?`, ts, h.ID)
+		require.NoError(t, err)
+	}
+	setUpdatedAt(h1, now.Add(-2*time.Hour))
+	setUpdatedAt(h2, now.Add(-5*time.Minute))
+	setUpdatedAt(h3, now.Add(-5*time.Minute))
+	// the changes are only listed once older than the settle delay, the time
+	// passing is simulated by moving the changes and the cursor back.
+	settle := func(cursor *fleet.HostChangesCursor) {
+		_, err := ds.writer.Exec(`UPDATE hosts SET updated_at = DATE_SUB(updated_at, INTERVAL 2 MINUTE)`)
+		require.NoError(t, err)
+		_, err = ds.writer.Exec(`UPDATE host_tombstones SET deleted_at = DATE_SUB(deleted_at, INTERVAL 2 MINUTE)`)
+		require.NoError(t, err)
+		cursor.UpdatedAt = cursor.UpdatedAt.Add(-2 * time.Minute)
+		cursor.DeletedAt = cursor.DeletedAt.Add(-2 * time.Minute)
+	}
+
+	adminFilter := fleet.TeamFilter{User: test.UserAdmin}
+	team1Filter := fleet.TeamFilter{
+		User:            &fleet.User{Teams: []fleet.UserTeam{{Team: *team1, Role: fleet.RoleObserver}}},
+		IncludeObserver: true,
+	}
+	hostIDs := func(hosts []*fleet.Host) []uint {
+		var ids []uint
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	since := fleet.NewHostChangesCursor(now.Add(-time.Hour))
+	changes, err := ds.ListHostChanges(ctx, adminFilter, since, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{h2.ID, h3.ID}, hostIDs(changes.Hosts))
+	assert.Empty(t, changes.Deleted)
+	assert.False(t, changes.More)
+	assert.Equal(t, now.Add(-5*time.Minute), changes.Cursor.UpdatedAt.UTC())
+	assert.Equal(t, h3.ID, changes.Cursor.HostID)
+	// without tombstones, the tombstones position moves forward
+	assert.True(t, changes.Cursor.DeletedAt.After(since.DeletedAt))
+	cursor := changes.Cursor
+
+	changes, err = ds.ListHostChanges(ctx, team1Filter, since, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{h3.ID}, hostIDs(changes.Hosts))
+
+	// the changes are paginated with the cursor
+	changes, err = ds.ListHostChanges(ctx, adminFilter, since, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{h2.ID}, hostIDs(changes.Hosts))
+	assert.True(t, changes.More)
+	changes, err = ds.ListHostChanges(ctx, adminFilter, changes.Cursor, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{h3.ID}, hostIDs(changes.Hosts))
+	assert.True(t, changes.More)
+	changes, err = ds.ListHostChanges(ctx, adminFilter, changes.Cursor, 1)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Hosts)
+	assert.False(t, changes.More)
+
+	// no changes after the cursor
+	changes, err = ds.ListHostChanges(ctx, adminFilter, cursor, 10)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Hosts)
+	assert.Empty(t, changes.Deleted)
+	assert.Equal(t, cursor.UpdatedAt, changes.Cursor.UpdatedAt)
+	assert.Equal(t, cursor.HostID, changes.Cursor.HostID)
+
+	// updating and deleting hosts are reported as changes
+	h1.Hostname = "h1-renamed"
+	require.NoError(t, ds.SaveHost(ctx, h1))
+	require.NoError(t, ds.DeleteHost(ctx, h3.ID))
+
+	// the recent changes are not listed until they settle
+	changes, err = ds.ListHostChanges(ctx, adminFilter, cursor, 10)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Hosts)
+	assert.Empty(t, changes.Deleted)
+	assert.Equal(t, cursor.UpdatedAt, changes.Cursor.UpdatedAt)
+	assert.Equal(t, cursor.HostID, changes.Cursor.HostID)
+
+	settle(&cursor)
+	changes, err = ds.ListHostChanges(ctx, adminFilter, cursor, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{h1.ID}, hostIDs(changes.Hosts))
+	assert.Equal(t, "h1-renamed", changes.Hosts[0].Hostname)
+	require.Len(t, changes.Deleted, 1)
+	assert.Equal(t, h3.ID, changes.Deleted[0].HostID)
+	assert.Equal(t, "h3", changes.Deleted[0].Hostname)
+	assert.Equal(t, "uuid3", changes.Deleted[0].UUID)
+	require.NotNil(t, changes.Deleted[0].TeamID)
+	assert.Equal(t, team1.ID, *changes.Deleted[0].TeamID)
+	assert.Equal(t, h3.ID, changes.Cursor.DeletedHostID)
+
+	changes, err = ds.ListHostChanges(ctx, team1Filter, cursor, 10)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Hosts)
+	require.Len(t, changes.Deleted, 1)
+
+	// tombstones are kept for the retention period
+	require.NoError(t, ds.CleanupHostTombstones(ctx, time.Now()))
+	changes, err = ds.ListHostChanges(ctx, adminFilter, cursor, 10)
+	require.NoError(t, err)
+	require.Len(t, changes.Deleted, 1)
+
+	require.NoError(t, ds.CleanupHostTombstones(ctx, time.Now().Add(fleet.HostTombstoneRetention+time.Hour)))
+	changes, err = ds.ListHostChanges(ctx, adminFilter, cursor, 10)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Deleted)
+
+	// bulk deletes and expired hosts leave tombstones too
+	h4 := test.NewHost(t, ds, "h4", "", "key4", "uuid4", now.Add(-48*time.Hour))
+	require.NoError(t, ds.DeleteHosts(ctx, []uint{h1.ID, h2.ID}))
+
+	ac, err := ds.AppConfig(ctx)
+	require.NoError(t, err)
+	expirySettings := ac.HostExpirySettings
+	defer func() {
+		ac.HostExpirySettings = expirySettings
+		require.NoError(t, ds.SaveAppConfig(ctx, ac))
+	}()
+	ac.HostExpirySettings.HostExpiryEnabled = true
+	ac.HostExpirySettings.HostExpiryWindow = 1
+	require.NoError(t, ds.SaveAppConfig(ctx, ac))
+	require.NoError(t, ds.CleanupExpiredHosts(ctx))
+
+	settle(&cursor)
+	changes, err = ds.ListHostChanges(ctx, adminFilter, cursor, 10)
+	require.NoError(t, err)
+	assert.Empty(t, changes.Hosts)
+	var deletedIDs []uint
+	for _, d := range changes.Deleted {
+		deletedIDs = append(deletedIDs, d.HostID)
+	}
+	assert.ElementsMatch(t, []uint{h1.ID, h2.ID, h4.ID}, deletedIDs)
+}
@@ -317,7 +317,7 @@ func (ds *Datastore) LabelQueriesForHost(ctx context.Context, host *fleet.Host)
 	var rows *sql.Rows
 	var err error
 	platform := platformForHost(host)
-	query := `SELECT id, query FROM labels WHERE platform = ? OR platform = '' AND label_membership_type = ?`
+	query := `SELECT id, query FROM labels WHERE (platform = ? OR platform = '') AND label_membership_type = ?`
 	rows, err = ds.reader.QueryContext(ctx, query, platform, fleet.LabelMembershipTypeDynamic)
 
 	if err != nil && err != sql.ErrNoRows {
@@ -471,23 +471,65 @@ func (ds *Datastore) ListHostsInLabel(ctx context.Context, filter fleet.TeamFilt
 			WHERE lm.label_id = ?
 	`
 
-	query, params := ds.applyHostLabelFilters(filter, lid, query, opt)
+	settings, err := ds.hostStatusSettings(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	query, params := ds.applyHostLabelFilters(filter, lid, query, opt, settings)
 
 	hosts := []*fleet.Host{}
-	err := sqlx.SelectContext(ctx, ds.reader, &hosts, query, params...)
+	err = sqlx.SelectContext(ctx, ds.reader, &hosts, query, params...)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "selecting label query executions")
 	}
 	return hosts, nil
 }
 
+func (ds *Datastore) AddHostsToLabel(ctx context.Context, lid uint, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	// the unknown host IDs are ignored, as well as the hosts that are already
+	// members of the label.
+	stmt, args, err := sqlx.In(`INSERT IGNORE INTO label_membership (label_id, host_id) SELECT ?, id FROM hosts WHERE id IN (?)`, lid, hostIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build add hosts to label statement")
+	}
+	if _, err := ds.writer.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "add hosts to label")
+	}
+	return nil
+}
+
+func (ds *Datastore) RemoveHostsFromLabel(ctx context.Context, lid uint, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	stmt, args, err := sqlx.In(`DELETE FROM label_membership WHERE label_id = ? AND host_id IN (?)`, lid, hostIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build remove hosts from label statement")
+	}
+	if _, err := ds.writer.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "remove hosts from label")
+	}
+	return nil
+}
+
 // NOTE: the hosts table must be aliased to `h` in the query passed to this function.
-func (ds *Datastore) applyHostLabelFilters(filter fleet.TeamFilter, lid uint, query string, opt fleet.HostListOptions) (string, []interface{}) {
+func (ds *Datastore) applyHostLabelFilters(filter fleet.TeamFilter, lid uint, query string, opt fleet.HostListOptions, settings fleet.HostSettings) (string, []interface{}) {
 	params := []interface{}{lid}
 
 	query = fmt.Sprintf(`%s AND %s `, query, ds.whereFilterHostsByTeams(filter, "h"))
-	query, params = filterHostsByStatus(query, opt, params)
+	query, params = filterHostsByStatus(query, opt, params, settings)
 	query, params = filterHostsByTeam(query, opt, params)
+	query, params = filterHostsByLabels(query, opt, params)
+	query, params = filterHostsByAgentVersion(query, opt, params)
+	query, params = filterHostsByDiskSpace(query, opt, params)
+	query, params = filterHostsByDiskEncryption(query, opt, params)
+	query, params = filterHostsByMacadmins(query, opt, params)
+	query, params = filterHostsByCertificate(query, opt, params)
 	query, params = searchLike(query, params, opt.MatchQuery, hostSearchColumns...)
 
 	query = appendListOptionsToSQL(query, opt.ListOptions)
@@ -500,7 +542,11 @@ func (ds *Datastore) CountHostsInLabel(ctx context.Context, filter fleet.TeamFil
 	LEFT JOIN host_seen_times hst ON (h.id=hst.host_id)
 	WHERE lm.label_id = ?`
 
-	query, params := ds.applyHostLabelFilters(filter, lid, query, opt)
+	settings, err := ds.hostStatusSettings(ctx, opt)
+	if err != nil {
+		return 0, err
+	}
+	query, params := ds.applyHostLabelFilters(filter, lid, query, opt, settings)
 
 	var count int
 	if err := sqlx.GetContext(ctx, ds.reader, &count, query, params...); err != nil {
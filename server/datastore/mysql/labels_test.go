@@ -60,6 +60,7 @@ func TestLabels(t *testing.T) {
 		{"QueriesForCentOSHost", testLabelsQueriesForCentOSHost},
 		{"RecordNonExistentQueryLabelExecution", testLabelsRecordNonexistentQueryLabelExecution},
 		{"DeleteLabel", testDeleteLabel},
+		{"ManualHosts", testLabelsManualHosts},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -75,7 +76,7 @@ func testLabelsAddAllHosts(deferred bool, t *testing.T, db *Datastore) {
 	var host *fleet.Host
 	var err error
 	for i := 0; i < 10; i++ {
-		host, err = db.EnrollHost(context.Background(), fmt.Sprint(i), fmt.Sprint(i), nil, 0)
+		host, err = db.EnrollHost(context.Background(), fmt.Sprint(i), fmt.Sprint(i), nil, 0, "")
 		require.Nil(t, err, "enrollment should succeed")
 		hosts = append(hosts, *host)
 	}
@@ -705,7 +706,7 @@ func testLabelsSave(t *testing.T, db *Datastore) {
 }
 
 func testLabelsQueriesForCentOSHost(t *testing.T, db *Datastore) {
-	host, err := db.EnrollHost(context.Background(), "0", "0", nil, 0)
+	host, err := db.EnrollHost(context.Background(), "0", "0", nil, 0, "")
 	require.Nil(t, err, "enrollment should succeed")
 	host.Platform = "rhel"
 	host.OSVersion = "CentOS 6"
@@ -776,3 +777,55 @@ func testDeleteLabel(t *testing.T, db *Datastore) {
 
 	require.NoError(t, db.DeletePack(context.Background(), newP.Name))
 }
+
+func testLabelsManualHosts(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	var hosts []*fleet.Host
+	for i := 0; i < 3; i++ {
+		h := test.NewHost(t, ds, fmt.Sprintf("foo.local%d", i), "", strconv.Itoa(i), strconv.Itoa(i), time.Now())
+		h.Platform = "darwin"
+		require.NoError(t, ds.SaveHost(ctx, h))
+		hosts = append(hosts, h)
+	}
+
+	manual, err := ds.NewLabel(ctx, &fleet.Label{Name: "manual", Platform: "darwin", LabelMembershipType: fleet.LabelMembershipTypeManual})
+	require.NoError(t, err)
+	dynamic, err := ds.NewLabel(ctx, &fleet.Label{Name: "dynamic", Query: "select 1", Platform: "darwin"})
+	require.NoError(t, err)
+
+	// the manual labels are not queried, whatever their platform
+	queries, err := ds.LabelQueriesForHost(ctx, hosts[0])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{fmt.Sprint(dynamic.ID): "select 1"}, queries)
+
+	listIDs := func() []uint {
+		got, err := ds.ListHostsInLabel(ctx, fleet.TeamFilter{User: test.UserAdmin}, manual.ID, fleet.HostListOptions{})
+		require.NoError(t, err)
+		var ids []uint
+		for _, h := range got {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	// the unknown hosts are ignored
+	require.NoError(t, ds.AddHostsToLabel(ctx, manual.ID, []uint{hosts[0].ID, hosts[1].ID, hosts[2].ID + 1000}))
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, listIDs())
+	require.NoError(t, ds.AddHostsToLabel(ctx, manual.ID, []uint{hosts[1].ID, hosts[2].ID}))
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID, hosts[2].ID}, listIDs())
+
+	require.NoError(t, ds.RemoveHostsFromLabel(ctx, manual.ID, []uint{hosts[0].ID, hosts[2].ID}))
+	assert.Equal(t, []uint{hosts[1].ID}, listIDs())
+
+	// the membership is not changed by the results of the label queries
+	require.NoError(t, ds.RecordLabelQueryExecutions(ctx, hosts[1], map[uint]*bool{dynamic.ID: ptr.Bool(true)}, time.Now(), false))
+	assert.Equal(t, []uint{hosts[1].ID}, listIDs())
+	labels, err := ds.ListLabelsForHost(ctx, hosts[1].ID)
+	require.NoError(t, err)
+	assert.Len(t, labels, 2)
+
+	spec, err := ds.GetLabelSpec(ctx, "manual")
+	require.NoError(t, err)
+	assert.Equal(t, []string{hosts[1].Hostname}, spec.Hosts)
+}
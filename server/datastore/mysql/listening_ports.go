@@ -0,0 +1,113 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) ReplaceHostListeningPorts(ctx context.Context, hostID uint, ports []fleet.HostListeningPort) error {
+	const (
+		selStmt = `SELECT port, protocol, process_name FROM host_listening_ports WHERE host_id = ?`
+		delStmt = `DELETE FROM host_listening_ports WHERE host_id = ? AND port = ? AND protocol = ? AND process_name = ?`
+		insStmt = `INSERT INTO host_listening_ports (host_id, port, protocol, process_name) VALUES`
+		insPart = ` (?, ?, ?, ?),`
+	)
+
+	toIns := make(map[fleet.HostListeningPort]struct{}, len(ports))
+	for _, p := range ports {
+		toIns[p] = struct{}{}
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var prevPorts []fleet.HostListeningPort
+		if err := sqlx.SelectContext(ctx, tx, &prevPorts, selStmt, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "select previous host listening ports")
+		}
+
+		for _, p := range prevPorts {
+			if _, ok := toIns[p]; ok {
+				delete(toIns, p)
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, delStmt, hostID, p.Port, p.Protocol, p.ProcessName); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete host listening port")
+			}
+		}
+
+		if len(toIns) > 0 {
+			args := make([]interface{}, 0, len(toIns)*4)
+			for p := range toIns {
+				args = append(args, hostID, p.Port, p.Protocol, p.ProcessName)
+			}
+			stmt := insStmt + strings.TrimSuffix(strings.Repeat(insPart, len(toIns)), ",")
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert host listening ports")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ListHostListeningPorts(ctx context.Context, hostID uint) ([]fleet.HostListeningPort, error) {
+	ports := []fleet.HostListeningPort{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &ports,
+		`SELECT port, protocol, process_name FROM host_listening_ports WHERE host_id = ? ORDER BY port, protocol, process_name`, hostID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host listening ports")
+	}
+	return ports, nil
+}
+
+// listeningPortsReportOrderKeys are the supported order keys of the listening
+// ports report.
+var listeningPortsReportOrderKeys = map[string]bool{
+	"port":         true,
+	"protocol":     true,
+	"process_name": true,
+	"hosts_count":  true,
+}
+
+func (ds *Datastore) ListeningPortsReport(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListeningPortsReportOptions) ([]fleet.ListeningPortHostsCount, error) {
+	stmt := `
+		SELECT lp.port, lp.protocol, lp.process_name, COUNT(*) AS hosts_count
+		FROM host_listening_ports lp
+		JOIN hosts h ON h.id = lp.host_id
+		WHERE ` + ds.whereFilterHostsByTeams(filter, "h")
+
+	var args []interface{}
+	if opt.Port != nil {
+		stmt += ` AND lp.port = ?`
+		args = append(args, *opt.Port)
+	}
+	if opt.Protocol != "" {
+		stmt += ` AND lp.protocol = ?`
+		args = append(args, opt.Protocol)
+	}
+	if opt.ProcessName != "" {
+		stmt += ` AND lp.process_name = ?`
+		args = append(args, opt.ProcessName)
+	}
+	stmt += ` GROUP BY lp.port, lp.protocol, lp.process_name`
+
+	// most common first unless otherwise requested
+	if opt.OrderKey == "" {
+		opt.OrderKey = "hosts_count"
+		opt.OrderDirection = fleet.OrderDescending
+	}
+	if !listeningPortsReportOrderKeys[opt.OrderKey] {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("order_key", "unsupported order key: "+opt.OrderKey))
+	}
+	// the cursor cannot be applied to the aggregated rows
+	opt.After = ""
+	stmt = appendListOptionsToSQL(stmt, opt.ListOptions)
+
+	report := []fleet.ListeningPortHostsCount{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &report, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select listening ports report")
+	}
+	return report, nil
+}
@@ -0,0 +1,136 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListeningPorts(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"ReplaceHost", testListeningPortsReplaceHost},
+		{"Report", testListeningPortsReport},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testListeningPortsReplaceHost(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	ports, err := ds.ListHostListeningPorts(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, ports)
+
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host.ID, []fleet.HostListeningPort{
+		{Port: 443, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "nginx"},
+		{Port: 22, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "sshd"},
+		{Port: 22, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "sshd"},
+	}))
+	ports, err = ds.ListHostListeningPorts(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostListeningPort{
+		{Port: 22, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "sshd"},
+		{Port: 443, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "nginx"},
+	}, ports)
+
+	// replace keeps the existing entries and removes the missing ones
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host.ID, []fleet.HostListeningPort{
+		{Port: 443, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "nginx"},
+		{Port: 53, Protocol: fleet.ListeningPortProtocolUDP, ProcessName: "dnsmasq"},
+	}))
+	ports, err = ds.ListHostListeningPorts(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostListeningPort{
+		{Port: 53, Protocol: fleet.ListeningPortProtocolUDP, ProcessName: "dnsmasq"},
+		{Port: 443, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "nginx"},
+	}, ports)
+
+	// the listening ports are removed with the host
+	require.NoError(t, ds.DeleteHost(ctx, host.ID))
+	ports, err = ds.ListHostListeningPorts(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, ports)
+}
+
+func testListeningPortsReport(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{host2.ID}))
+
+	rdp := fleet.HostListeningPort{Port: 3389, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "svchost.exe"}
+	rogue := fleet.HostListeningPort{Port: 3389, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "rogue.exe"}
+	dns := fleet.HostListeningPort{Port: 53, Protocol: fleet.ListeningPortProtocolUDP, ProcessName: "dns.exe"}
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host1.ID, []fleet.HostListeningPort{rdp, dns}))
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host2.ID, []fleet.HostListeningPort{rdp, rogue}))
+
+	admin := fleet.TeamFilter{User: test.UserAdmin}
+	report, err := ds.ListeningPortsReport(ctx, admin, fleet.ListeningPortsReportOptions{})
+	require.NoError(t, err)
+	require.Len(t, report, 3)
+	assert.Equal(t, fleet.ListeningPortHostsCount{HostListeningPort: rdp, HostsCount: 2}, report[0])
+	assert.ElementsMatch(t, []fleet.ListeningPortHostsCount{
+		{HostListeningPort: rogue, HostsCount: 1},
+		{HostListeningPort: dns, HostsCount: 1},
+	}, report[1:])
+
+	report, err = ds.ListeningPortsReport(ctx, admin, fleet.ListeningPortsReportOptions{Port: ptr.Uint(3389)})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.ListeningPortHostsCount{
+		{HostListeningPort: rdp, HostsCount: 2},
+		{HostListeningPort: rogue, HostsCount: 1},
+	}, report)
+
+	report, err = ds.ListeningPortsReport(ctx, admin, fleet.ListeningPortsReportOptions{Protocol: fleet.ListeningPortProtocolUDP})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.ListeningPortHostsCount{{HostListeningPort: dns, HostsCount: 1}}, report)
+
+	report, err = ds.ListeningPortsReport(ctx, admin, fleet.ListeningPortsReportOptions{ProcessName: "rogue.exe"})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.ListeningPortHostsCount{{HostListeningPort: rogue, HostsCount: 1}}, report)
+
+	report, err = ds.ListeningPortsReport(ctx, admin, fleet.ListeningPortsReportOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "port", PerPage: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.ListeningPortHostsCount{{HostListeningPort: dns, HostsCount: 1}}, report)
+
+	_, err = ds.ListeningPortsReport(ctx, admin, fleet.ListeningPortsReportOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "host_id"},
+	})
+	require.Error(t, err)
+
+	report, err = ds.ListeningPortsReport(ctx, fleet.TeamFilter{User: test.UserAdmin, TeamID: &team.ID}, fleet.ListeningPortsReportOptions{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []fleet.ListeningPortHostsCount{
+		{HostListeningPort: rdp, HostsCount: 1},
+		{HostListeningPort: rogue, HostsCount: 1},
+	}, report)
+
+	// the hosts of other teams are not counted for team users
+	report, err = ds.ListeningPortsReport(ctx, fleet.TeamFilter{User: &fleet.User{
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: team.ID + 1}, Role: fleet.RoleAdmin}},
+	}}, fleet.ListeningPortsReportOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, report)
+}
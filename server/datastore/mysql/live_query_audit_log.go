@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewLiveQueryAuditLog(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+	stmt := `
+		INSERT INTO live_query_audit_log (
+			user_id,
+			user_name,
+			user_email,
+			distributed_query_campaign_id,
+			query_id,
+			query_name,
+			query_sql,
+			targets,
+			targeted_hosts
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := ds.writer.ExecContext(ctx, stmt,
+		entry.UserID, entry.UserName, entry.UserEmail, entry.CampaignID,
+		entry.QueryID, entry.QueryName, entry.QuerySQL, entry.Targets, entry.TargetedHosts,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "insert live query audit log")
+	}
+	id, _ := res.LastInsertId()
+	entry.ID = uint(id)
+	return nil
+}
+
+// liveQueryAuditLogOrderKeys are the supported order keys of the live query
+// audit log.
+var liveQueryAuditLogOrderKeys = map[string]bool{
+	"id":             true,
+	"created_at":     true,
+	"user_id":        true,
+	"user_email":     true,
+	"query_id":       true,
+	"targeted_hosts": true,
+}
+
+func (ds *Datastore) ListLiveQueryAuditLog(ctx context.Context, opt fleet.LiveQueryAuditLogOptions) ([]*fleet.LiveQueryAuditLog, error) {
+	stmt := `
+		SELECT
+			id,
+			created_at,
+			user_id,
+			user_name,
+			user_email,
+			distributed_query_campaign_id,
+			query_id,
+			query_name,
+			query_sql,
+			targets,
+			targeted_hosts
+		FROM live_query_audit_log
+		WHERE created_at >= ? AND created_at < ?`
+	// the end date is inclusive
+	args := []interface{}{liveQueryAuditLogDay(opt.StartDate), liveQueryAuditLogDay(opt.EndDate).AddDate(0, 0, 1)}
+	if opt.UserID != nil {
+		stmt += ` AND user_id = ?`
+		args = append(args, *opt.UserID)
+	}
+	stmt, args = searchLike(stmt, args, opt.MatchQuery, "query_sql", "user_email")
+
+	// most recent first unless otherwise requested
+	if opt.OrderKey == "" {
+		opt.OrderKey = "id"
+		opt.OrderDirection = fleet.OrderDescending
+	}
+	if !liveQueryAuditLogOrderKeys[opt.OrderKey] {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("order_key", "unsupported order key: "+opt.OrderKey))
+	}
+	stmt, args = appendListOptionsWithCursorToSQL(stmt, args, opt.ListOptions)
+
+	entries := []*fleet.LiveQueryAuditLog{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &entries, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select live query audit log")
+	}
+	return entries, nil
+}
+
+// liveQueryAuditLogDay returns the start of the day of t, in UTC.
+func liveQueryAuditLogDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
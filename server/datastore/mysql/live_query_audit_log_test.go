@@ -0,0 +1,118 @@
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveQueryAuditLog(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"NewAndList", testLiveQueryAuditLogNewAndList},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testLiveQueryAuditLogNewAndList(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	newEntry := func(userID uint, email, sql string, targetedHosts uint) *fleet.LiveQueryAuditLog {
+		entry := &fleet.LiveQueryAuditLog{
+			UserID:        userID,
+			UserName:      "User " + email,
+			UserEmail:     email,
+			CampaignID:    userID * 10,
+			QueryID:       userID * 100,
+			QueryName:     "distributed_" + email,
+			QuerySQL:      sql,
+			Targets:       json.RawMessage(`{"hosts":[1,2],"labels":null,"teams":null}`),
+			TargetedHosts: targetedHosts,
+		}
+		require.NoError(t, ds.NewLiveQueryAuditLog(ctx, entry))
+		require.NotZero(t, entry.ID)
+		return entry
+	}
+	e1 := newEntry(1, "alice@example.com", "SELECT * FROM users", 2)
+	e2 := newEntry(2, "bob@example.com", "SELECT * FROM processes", 5)
+	e3 := newEntry(1, "alice@example.com", "SELECT * FROM file", 1)
+
+	// the first entry was recorded 3 days ago
+	now := time.Now().UTC()
+	_, err := ds.writer.ExecContext(ctx, `UPDATE live_query_audit_log SET created_at = ? WHERE id = ?`, now.AddDate(0, 0, -3), e1.ID)
+	require.NoError(t, err)
+
+	ids := func(entries []*fleet.LiveQueryAuditLog) []uint {
+		var ids []uint
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+
+	// most recent first
+	entries, err := ds.ListLiveQueryAuditLog(ctx, fleet.LiveQueryAuditLogOptions{StartDate: now.AddDate(0, 0, -7), EndDate: now})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{e3.ID, e2.ID, e1.ID}, ids(entries))
+	got := entries[1]
+	assert.Equal(t, uint(2), got.UserID)
+	assert.Equal(t, "User bob@example.com", got.UserName)
+	assert.Equal(t, "bob@example.com", got.UserEmail)
+	assert.Equal(t, uint(20), got.CampaignID)
+	assert.Equal(t, uint(200), got.QueryID)
+	assert.Equal(t, "distributed_bob@example.com", got.QueryName)
+	assert.Equal(t, "SELECT * FROM processes", got.QuerySQL)
+	assert.JSONEq(t, `{"hosts":[1,2],"labels":null,"teams":null}`, string(got.Targets))
+	assert.Equal(t, uint(5), got.TargetedHosts)
+	assert.False(t, got.CreatedAt.IsZero())
+
+	// the dates are inclusive
+	entries, err = ds.ListLiveQueryAuditLog(ctx, fleet.LiveQueryAuditLogOptions{StartDate: now.AddDate(0, 0, -3), EndDate: now.AddDate(0, 0, -1)})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{e1.ID}, ids(entries))
+	entries, err = ds.ListLiveQueryAuditLog(ctx, fleet.LiveQueryAuditLogOptions{StartDate: now, EndDate: now})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{e3.ID, e2.ID}, ids(entries))
+
+	// filtered by user, and by query or email
+	entries, err = ds.ListLiveQueryAuditLog(ctx, fleet.LiveQueryAuditLogOptions{StartDate: now.AddDate(0, 0, -7), EndDate: now, UserID: &e1.UserID})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{e3.ID, e1.ID}, ids(entries))
+	entries, err = ds.ListLiveQueryAuditLog(ctx, fleet.LiveQueryAuditLogOptions{
+		StartDate: now.AddDate(0, 0, -7), EndDate: now, ListOptions: fleet.ListOptions{MatchQuery: "processes"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{e2.ID}, ids(entries))
+	entries, err = ds.ListLiveQueryAuditLog(ctx, fleet.LiveQueryAuditLogOptions{
+		StartDate: now.AddDate(0, 0, -7), EndDate: now, ListOptions: fleet.ListOptions{MatchQuery: "alice"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{e3.ID, e1.ID}, ids(entries))
+
+	// ordered and paginated
+	entries, err = ds.ListLiveQueryAuditLog(ctx, fleet.LiveQueryAuditLogOptions{
+		StartDate: now.AddDate(0, 0, -7), EndDate: now,
+		ListOptions: fleet.ListOptions{OrderKey: "targeted_hosts", OrderDirection: fleet.OrderDescending, PerPage: 2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{e2.ID, e1.ID}, ids(entries))
+
+	_, err = ds.ListLiveQueryAuditLog(ctx, fleet.LiveQueryAuditLogOptions{
+		StartDate: now.AddDate(0, 0, -7), EndDate: now, ListOptions: fleet.ListOptions{OrderKey: "query_sql"},
+	})
+	require.Error(t, err)
+}
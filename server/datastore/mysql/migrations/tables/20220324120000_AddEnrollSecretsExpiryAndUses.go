@@ -0,0 +1,29 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324120000, Down_20220324120000)
+}
+
+func Up_20220324120000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE enroll_secrets
+		ADD COLUMN expires_at TIMESTAMP NULL DEFAULT NULL,
+		ADD COLUMN max_uses INT UNSIGNED NULL DEFAULT NULL,
+		ADD COLUMN uses INT UNSIGNED NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add enroll_secrets expiry and uses columns")
+	}
+
+	return nil
+}
+
+func Down_20220324120000(tx *sql.Tx) error {
+	return nil
+}
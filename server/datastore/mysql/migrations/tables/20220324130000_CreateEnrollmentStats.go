@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324130000, Down_20220324130000)
+}
+
+func Up_20220324130000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS enrollment_stats (
+			date DATE NOT NULL,
+			outcome VARCHAR(32) NOT NULL,
+			count INT UNSIGNED NOT NULL DEFAULT 0,
+			PRIMARY KEY (date, outcome)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create enrollment_stats table")
+	}
+
+	return nil
+}
+
+func Down_20220324130000(tx *sql.Tx) error {
+	return nil
+}
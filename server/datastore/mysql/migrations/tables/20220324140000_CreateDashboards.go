@@ -0,0 +1,41 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324140000, Down_20220324140000)
+}
+
+func Up_20220324140000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS dashboards (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			name VARCHAR(255) NOT NULL,
+			description TEXT NOT NULL,
+			user_id INT UNSIGNED NOT NULL,
+			team_id INT UNSIGNED DEFAULT NULL,
+			shared TINYINT(1) NOT NULL DEFAULT FALSE,
+			widgets JSON NOT NULL,
+			PRIMARY KEY (id),
+			KEY idx_dashboards_user_id (user_id),
+			KEY idx_dashboards_team_id (team_id),
+			FOREIGN KEY fk_dashboards_user_id (user_id) REFERENCES users (id) ON DELETE CASCADE,
+			FOREIGN KEY fk_dashboards_team_id (team_id) REFERENCES teams (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create dashboards table")
+	}
+
+	return nil
+}
+
+func Down_20220324140000(tx *sql.Tx) error {
+	return nil
+}
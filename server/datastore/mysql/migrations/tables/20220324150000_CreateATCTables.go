@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324150000, Down_20220324150000)
+}
+
+func Up_20220324150000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS atc_tables (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			name VARCHAR(255) NOT NULL,
+			query TEXT NOT NULL,
+			path TEXT NOT NULL,
+			columns JSON NOT NULL,
+			platform VARCHAR(255) NOT NULL DEFAULT '',
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_atc_tables_name (name)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create atc_tables table")
+	}
+
+	return nil
+}
+
+func Down_20220324150000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,60 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324160000, Down_20220324160000)
+}
+
+func Up_20220324160000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_indicators (
+			host_id INT UNSIGNED NOT NULL,
+			type VARCHAR(32) NOT NULL,
+			value VARCHAR(255) NOT NULL,
+			PRIMARY KEY (host_id, type, value),
+			KEY idx_host_indicators_type_value (type, value)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_indicators table")
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS threat_intel_verdicts (
+			type VARCHAR(32) NOT NULL,
+			value VARCHAR(255) NOT NULL,
+			malicious TINYINT(1) NOT NULL DEFAULT 0,
+			description VARCHAR(255) NOT NULL DEFAULT '',
+			checked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (type, value)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create threat_intel_verdicts table")
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_threat_findings (
+			host_id INT UNSIGNED NOT NULL,
+			type VARCHAR(32) NOT NULL,
+			value VARCHAR(255) NOT NULL,
+			description VARCHAR(255) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (host_id, type, value)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_threat_findings table")
+	}
+
+	return nil
+}
+
+func Down_20220324160000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,40 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324170000, Down_20220324170000)
+}
+
+func Up_20220324170000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_tombstones (
+			host_id INT UNSIGNED NOT NULL,
+			team_id INT UNSIGNED NULL,
+			hostname VARCHAR(255) NOT NULL DEFAULT '',
+			uuid VARCHAR(255) NOT NULL DEFAULT '',
+			deleted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (host_id),
+			KEY idx_host_tombstones_deleted_at (deleted_at)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_tombstones table")
+	}
+
+	// updated_at is used to list the hosts changed since a point in time
+	_, err = tx.Exec(`ALTER TABLE hosts ADD INDEX idx_hosts_updated_at (updated_at)`)
+	if err != nil {
+		return errors.Wrap(err, "add hosts updated_at index")
+	}
+
+	return nil
+}
+
+func Down_20220324170000(tx *sql.Tx) error {
+	return nil
+}
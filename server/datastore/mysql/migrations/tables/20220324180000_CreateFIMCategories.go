@@ -0,0 +1,39 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324180000, Down_20220324180000)
+}
+
+func Up_20220324180000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS fim_categories (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			team_id INT UNSIGNED DEFAULT NULL,
+			name VARCHAR(255) NOT NULL,
+			paths JSON NOT NULL,
+			exclude_paths JSON NOT NULL,
+			accesses TINYINT(1) NOT NULL DEFAULT FALSE,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_fim_categories_name (name),
+			KEY idx_fim_categories_team_id (team_id),
+			CONSTRAINT fim_categories_team_id_fk FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create fim_categories table")
+	}
+
+	return nil
+}
+
+func Down_20220324180000(tx *sql.Tx) error {
+	return nil
+}
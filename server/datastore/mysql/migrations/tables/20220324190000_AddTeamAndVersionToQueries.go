@@ -0,0 +1,29 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324190000, Down_20220324190000)
+}
+
+func Up_20220324190000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE queries
+			ADD COLUMN team_id INT UNSIGNED DEFAULT NULL,
+			ADD COLUMN version INT UNSIGNED NOT NULL DEFAULT 1,
+			ADD CONSTRAINT queries_team_id_fk FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add team_id and version to queries")
+	}
+
+	return nil
+}
+
+func Down_20220324190000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324200000, Down_20220324200000)
+}
+
+func Up_20220324200000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE distributed_query_campaigns
+			ADD COLUMN anonymize TINYINT(1) NOT NULL DEFAULT 0,
+			ADD COLUMN pseudonym_key VARCHAR(64) NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add anonymize to distributed_query_campaigns")
+	}
+
+	return nil
+}
+
+func Down_20220324200000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324210000, Down_20220324210000)
+}
+
+func Up_20220324210000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS yara_signature_groups (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			team_id INT UNSIGNED DEFAULT NULL,
+			name VARCHAR(255) NOT NULL,
+			signatures JSON NOT NULL,
+			file_paths JSON NOT NULL,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_yara_signature_groups_name (name),
+			KEY idx_yara_signature_groups_team_id (team_id),
+			CONSTRAINT yara_signature_groups_team_id_fk FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create yara_signature_groups table")
+	}
+
+	return nil
+}
+
+func Down_20220324210000(tx *sql.Tx) error {
+	return nil
+}
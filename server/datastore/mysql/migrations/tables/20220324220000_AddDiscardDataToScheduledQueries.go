@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324220000, Down_20220324220000)
+}
+
+func Up_20220324220000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE scheduled_queries
+			ADD COLUMN discard_data TINYINT(1) NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add discard_data to scheduled_queries")
+	}
+
+	return nil
+}
+
+func Down_20220324220000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220324230000, Down_20220324230000)
+}
+
+func Up_20220324230000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS agent_config_versions (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			author_id INT UNSIGNED DEFAULT NULL,
+			config JSON NOT NULL,
+			changes JSON NOT NULL,
+			PRIMARY KEY (id),
+			KEY idx_agent_config_versions_author_id (author_id),
+			CONSTRAINT agent_config_versions_author_id_fk FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create agent_config_versions table")
+	}
+
+	return nil
+}
+
+func Down_20220324230000(tx *sql.Tx) error {
+	return nil
+}
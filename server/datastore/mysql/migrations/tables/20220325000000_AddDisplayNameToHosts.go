@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325000000, Down_20220325000000)
+}
+
+func Up_20220325000000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE hosts
+			ADD COLUMN display_name VARCHAR(255) NOT NULL DEFAULT '',
+			ADD INDEX idx_hosts_display_name (display_name)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add display_name to hosts")
+	}
+
+	// the default display name sources are the computer name, then the
+	// hostname.
+	_, err = tx.Exec(`UPDATE hosts SET display_name = COALESCE(NULLIF(computer_name, ''), hostname)`)
+	if err != nil {
+		return errors.Wrap(err, "set hosts display_name")
+	}
+
+	return nil
+}
+
+func Down_20220325000000(tx *sql.Tx) error {
+	return nil
+}
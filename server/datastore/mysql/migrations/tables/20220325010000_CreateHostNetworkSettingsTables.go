@@ -0,0 +1,44 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325010000, Down_20220325010000)
+}
+
+func Up_20220325010000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_dns_servers (
+			host_id INT UNSIGNED NOT NULL,
+			address VARCHAR(255) NOT NULL,
+			PRIMARY KEY (host_id, address),
+			KEY idx_host_dns_servers_address (address)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_dns_servers table")
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_proxies (
+			host_id INT UNSIGNED NOT NULL,
+			protocol VARCHAR(32) NOT NULL,
+			address VARCHAR(255) NOT NULL,
+			PRIMARY KEY (host_id, protocol, address),
+			KEY idx_host_proxies_protocol_address (protocol, address)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_proxies table")
+	}
+
+	return nil
+}
+
+func Down_20220325010000(tx *sql.Tx) error {
+	return nil
+}
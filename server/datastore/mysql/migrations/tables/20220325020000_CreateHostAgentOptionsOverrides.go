@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325020000, Down_20220325020000)
+}
+
+func Up_20220325020000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_agent_options_overrides (
+			host_id INT UNSIGNED NOT NULL PRIMARY KEY,
+			options JSON NOT NULL,
+			expires_at TIMESTAMP NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			KEY idx_host_agent_options_overrides_expires_at (expires_at)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_agent_options_overrides table")
+	}
+	return nil
+}
+
+func Down_20220325020000(tx *sql.Tx) error {
+	return nil
+}
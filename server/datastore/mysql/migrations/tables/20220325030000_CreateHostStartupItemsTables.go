@@ -0,0 +1,50 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325030000, Down_20220325030000)
+}
+
+func Up_20220325030000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_startup_items (
+			host_id INT UNSIGNED NOT NULL,
+			source VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			path VARCHAR(1024) NOT NULL DEFAULT '',
+			PRIMARY KEY (host_id, source, name)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_startup_items table")
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_startup_item_changes (
+			id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+			host_id INT UNSIGNED NOT NULL,
+			change_type VARCHAR(16) NOT NULL,
+			source VARCHAR(255) NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			path VARCHAR(1024) NOT NULL DEFAULT '',
+			previous_path VARCHAR(1024) NOT NULL DEFAULT '',
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			KEY idx_host_startup_item_changes_host_id (host_id),
+			KEY idx_host_startup_item_changes_created_at (created_at)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_startup_item_changes table")
+	}
+
+	return nil
+}
+
+func Down_20220325030000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325040000, Down_20220325040000)
+}
+
+func Up_20220325040000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_listening_ports (
+			host_id INT UNSIGNED NOT NULL,
+			port SMALLINT UNSIGNED NOT NULL,
+			protocol VARCHAR(8) NOT NULL,
+			process_name VARCHAR(255) NOT NULL DEFAULT '',
+			PRIMARY KEY (host_id, port, protocol, process_name),
+			KEY idx_host_listening_ports_port_protocol (port, protocol)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_listening_ports table")
+	}
+	return nil
+}
+
+func Down_20220325040000(tx *sql.Tx) error {
+	return nil
+}
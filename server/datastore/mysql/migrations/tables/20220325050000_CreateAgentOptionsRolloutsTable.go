@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325050000, Down_20220325050000)
+}
+
+func Up_20220325050000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS agent_options_rollouts (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			author_id INT UNSIGNED DEFAULT NULL,
+			agent_options JSON NOT NULL,
+			percentage TINYINT UNSIGNED NOT NULL DEFAULT 0,
+			label_id INT UNSIGNED DEFAULT NULL,
+			status VARCHAR(16) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY idx_agent_options_rollouts_status (status),
+			CONSTRAINT agent_options_rollouts_author_id_fk FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL,
+			CONSTRAINT agent_options_rollouts_label_id_fk FOREIGN KEY (label_id) REFERENCES labels (id) ON DELETE SET NULL
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create agent_options_rollouts table")
+	}
+	return nil
+}
+
+func Down_20220325050000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325060000, Down_20220325060000)
+}
+
+func Up_20220325060000(tx *sql.Tx) error {
+	// The usage is aggregated per user, team of the hosts (0 for the hosts
+	// without a team) and day. There are no foreign keys so that the usage of
+	// deleted users and teams is kept.
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS query_usage (
+			user_id INT UNSIGNED NOT NULL,
+			team_id INT UNSIGNED NOT NULL DEFAULT 0,
+			date DATE NOT NULL,
+			campaigns_launched INT UNSIGNED NOT NULL DEFAULT 0,
+			hosts_targeted INT UNSIGNED NOT NULL DEFAULT 0,
+			rows_returned BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			PRIMARY KEY (user_id, team_id, date),
+			KEY idx_query_usage_team_id_date (team_id, date),
+			KEY idx_query_usage_date (date)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create query_usage table")
+	}
+	return nil
+}
+
+func Down_20220325060000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,33 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325070000, Down_20220325070000)
+}
+
+func Up_20220325070000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_online_subscriptions (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			host_id INT UNSIGNED NOT NULL,
+			user_id INT UNSIGNED NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_host_online_subscriptions_host_id_user_id (host_id, user_id),
+			CONSTRAINT host_online_subscriptions_user_id_fk FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_online_subscriptions table")
+	}
+	return nil
+}
+
+func Down_20220325070000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325080000, Down_20220325080000)
+}
+
+func Up_20220325080000(tx *sql.Tx) error {
+	// updated_at is only updated when the version changes, so it is the time
+	// the host was first seen running that version.
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_agent_versions (
+			host_id INT UNSIGNED NOT NULL,
+			component VARCHAR(32) NOT NULL,
+			version VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (host_id, component),
+			KEY idx_host_agent_versions_component_version (component, version)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_agent_versions table")
+	}
+	return nil
+}
+
+func Down_20220325080000(tx *sql.Tx) error {
+	return nil
+}
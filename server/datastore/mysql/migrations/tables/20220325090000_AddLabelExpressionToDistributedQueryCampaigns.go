@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325090000, Down_20220325090000)
+}
+
+func Up_20220325090000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE distributed_query_campaigns
+			ADD COLUMN label_expression VARCHAR(1024) NOT NULL DEFAULT ''
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add label_expression to distributed_query_campaigns")
+	}
+
+	return nil
+}
+
+func Down_20220325090000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325100000, Down_20220325100000)
+}
+
+func Up_20220325100000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE distributed_query_campaigns
+			ADD COLUMN max_rows INT UNSIGNED NOT NULL DEFAULT 0,
+			ADD COLUMN max_result_bytes INT UNSIGNED NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add result limits to distributed_query_campaigns")
+	}
+
+	return nil
+}
+
+func Down_20220325100000(tx *sql.Tx) error {
+	return nil
+}
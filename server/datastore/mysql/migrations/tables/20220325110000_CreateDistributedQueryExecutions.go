@@ -0,0 +1,36 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325110000, Down_20220325110000)
+}
+
+func Up_20220325110000(tx *sql.Tx) error {
+	// The previous distributed_query_executions table was dropped by
+	// 20200420120000, this one records the execution of the query of a
+	// campaign on each of its targeted hosts.
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS distributed_query_executions (
+			distributed_query_campaign_id INT UNSIGNED NOT NULL,
+			host_id INT UNSIGNED NOT NULL,
+			status TINYINT NOT NULL DEFAULT 0,
+			online_at_launch TINYINT(1) NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (distributed_query_campaign_id, host_id)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create distributed_query_executions table")
+	}
+	return nil
+}
+
+func Down_20220325110000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325120000, Down_20220325120000)
+}
+
+func Up_20220325120000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE distributed_query_campaigns
+			ADD COLUMN online_only TINYINT(1) NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add online_only to distributed_query_campaigns")
+	}
+
+	return nil
+}
+
+func Down_20220325120000(tx *sql.Tx) error {
+	return nil
+}
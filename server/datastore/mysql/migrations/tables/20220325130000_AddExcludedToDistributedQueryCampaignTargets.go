@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325130000, Down_20220325130000)
+}
+
+func Up_20220325130000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE distributed_query_campaign_targets
+			ADD COLUMN excluded TINYINT(1) NOT NULL DEFAULT 0
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add excluded to distributed_query_campaign_targets")
+	}
+
+	return nil
+}
+
+func Down_20220325130000(tx *sql.Tx) error {
+	return nil
+}
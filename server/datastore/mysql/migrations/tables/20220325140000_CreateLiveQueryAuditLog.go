@@ -0,0 +1,43 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325140000, Down_20220325140000)
+}
+
+func Up_20220325140000(tx *sql.Tx) error {
+	// The name and email of the user and the name and SQL of the query are
+	// copied, and there are no foreign keys, so that the audit log is kept
+	// as is when the users, queries or campaigns are deleted.
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS live_query_audit_log (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			user_id INT UNSIGNED NOT NULL,
+			user_name VARCHAR(255) NOT NULL DEFAULT '',
+			user_email VARCHAR(255) NOT NULL DEFAULT '',
+			distributed_query_campaign_id INT UNSIGNED NOT NULL,
+			query_id INT UNSIGNED NOT NULL,
+			query_name VARCHAR(255) NOT NULL DEFAULT '',
+			query_sql MEDIUMTEXT NOT NULL,
+			targets JSON NOT NULL,
+			targeted_hosts INT UNSIGNED NOT NULL DEFAULT 0,
+			PRIMARY KEY (id),
+			KEY idx_live_query_audit_log_created_at (created_at),
+			KEY idx_live_query_audit_log_user_id_created_at (user_id, created_at)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create live_query_audit_log table")
+	}
+	return nil
+}
+
+func Down_20220325140000(tx *sql.Tx) error {
+	return nil
+}
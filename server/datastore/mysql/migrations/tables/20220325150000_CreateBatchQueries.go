@@ -0,0 +1,63 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325150000, Down_20220325150000)
+}
+
+func Up_20220325150000(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		ALTER TABLE distributed_query_campaigns
+		ADD COLUMN batch TINYINT(1) NOT NULL DEFAULT 0
+	`); err != nil {
+		return errors.Wrap(err, "add batch to distributed_query_campaigns")
+	}
+
+	// The batch queries are identified by their campaign.
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS batch_queries (
+			distributed_query_campaign_id INT UNSIGNED NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			completion_threshold TINYINT UNSIGNED NOT NULL DEFAULT 100,
+			targeted_hosts INT UNSIGNED NOT NULL DEFAULT 0,
+			responded_hosts INT UNSIGNED NOT NULL DEFAULT 0,
+			expires_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			notified_at TIMESTAMP NULL DEFAULT NULL,
+			PRIMARY KEY (distributed_query_campaign_id),
+			KEY idx_batch_queries_expires_at (expires_at)
+		)
+	`); err != nil {
+		return errors.Wrap(err, "create batch_queries table")
+	}
+
+	// The host_id is kept without foreign key so that the results of the
+	// deleted hosts are kept.
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS batch_query_results (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			distributed_query_campaign_id INT UNSIGNED NOT NULL,
+			host_id INT UNSIGNED NOT NULL,
+			hostname VARCHAR(255) NOT NULL DEFAULT '',
+			result_rows JSON NOT NULL,
+			error TEXT NULL,
+			truncated TINYINT(1) NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_batch_query_results_campaign_host (distributed_query_campaign_id, host_id),
+			CONSTRAINT fk_batch_query_results_campaign_id FOREIGN KEY (distributed_query_campaign_id) REFERENCES batch_queries (distributed_query_campaign_id) ON DELETE CASCADE
+		)
+	`); err != nil {
+		return errors.Wrap(err, "create batch_query_results table")
+	}
+	return nil
+}
+
+func Down_20220325150000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325160000, Down_20220325160000)
+}
+
+func Up_20220325160000(tx *sql.Tx) error {
+	// NULL uses the redis.duplicate_results configuration.
+	_, err := tx.Exec(`
+		ALTER TABLE distributed_query_campaigns
+		ADD COLUMN duplicate_results TINYINT(1) NULL DEFAULT NULL
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add duplicate_results to distributed_query_campaigns")
+	}
+	return nil
+}
+
+func Down_20220325160000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,33 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325170000, Down_20220325170000)
+}
+
+func Up_20220325170000(tx *sql.Tx) error {
+	// The Jira issue opened for the hosts failing a policy, deleted once it is
+	// closed.
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS policy_jira_issues (
+			policy_id INT UNSIGNED NOT NULL,
+			issue_key VARCHAR(255) NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (policy_id),
+			CONSTRAINT fk_policy_jira_issues_policy_id FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE
+		)
+	`); err != nil {
+		return errors.Wrap(err, "create policy_jira_issues table")
+	}
+	return nil
+}
+
+func Down_20220325170000(tx *sql.Tx) error {
+	return nil
+}
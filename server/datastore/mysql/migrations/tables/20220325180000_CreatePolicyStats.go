@@ -0,0 +1,45 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325180000, Down_20220325180000)
+}
+
+func Up_20220325180000(tx *sql.Tx) error {
+	// The number of hosts passing and failing each policy, aggregated
+	// periodically from policy_membership.
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS policy_stats (
+			policy_id INT UNSIGNED NOT NULL,
+			passing_host_count INT UNSIGNED NOT NULL DEFAULT 0,
+			failing_host_count INT UNSIGNED NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (policy_id),
+			CONSTRAINT fk_policy_stats_policy_id FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE
+		)
+	`); err != nil {
+		return errors.Wrap(err, "create policy_stats table")
+	}
+
+	// populate the counts, so that they are not reset until the first
+	// aggregation
+	if _, err := tx.Exec(`
+		INSERT INTO policy_stats (policy_id, passing_host_count, failing_host_count)
+		SELECT policy_id, COALESCE(SUM(passes = 1), 0), COALESCE(SUM(passes = 0), 0)
+		FROM policy_membership
+		GROUP BY policy_id
+	`); err != nil {
+		return errors.Wrap(err, "populate policy_stats table")
+	}
+	return nil
+}
+
+func Down_20220325180000(tx *sql.Tx) error {
+	return nil
+}
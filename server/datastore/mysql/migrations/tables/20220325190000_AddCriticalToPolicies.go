@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220325190000, Down_20220325190000)
+}
+
+func Up_20220325190000(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE policies ADD COLUMN critical TINYINT(1) NOT NULL DEFAULT FALSE`); err != nil {
+		return errors.Wrap(err, "add critical to policies")
+	}
+	// The critical policies are run at their own interval, the timestamp of
+	// their last run is tracked apart from policy_updated_at.
+	if _, err := tx.Exec(`ALTER TABLE hosts ADD COLUMN critical_policy_updated_at TIMESTAMP NOT NULL DEFAULT '2000-01-01 00:00:00'`); err != nil {
+		return errors.Wrap(err, "add critical_policy_updated_at to hosts")
+	}
+	return nil
+}
+
+func Down_20220325190000(tx *sql.Tx) error {
+	return nil
+}
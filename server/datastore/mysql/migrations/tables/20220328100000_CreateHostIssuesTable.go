@@ -0,0 +1,40 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220328100000, Down_20220328100000)
+}
+
+func Up_20220328100000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_issues (
+			host_id INT UNSIGNED NOT NULL PRIMARY KEY,
+			failing_policies_count INT UNSIGNED NOT NULL DEFAULT 0,
+			total_issues_count INT UNSIGNED NOT NULL DEFAULT 0,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	if err != nil {
+		return errors.Wrap(err, "create host_issues table")
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO host_issues (host_id, failing_policies_count, total_issues_count)
+		SELECT host_id, COUNT(*), COUNT(*)
+		FROM policy_membership
+		WHERE passes = 0
+		GROUP BY host_id`)
+	if err != nil {
+		return errors.Wrap(err, "populate host_issues table")
+	}
+	return nil
+}
+
+func Down_20220328100000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220328110000, Down_20220328110000)
+}
+
+func Up_20220328110000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS team_policy_exemptions (
+			team_id INT UNSIGNED NOT NULL,
+			policy_id INT UNSIGNED NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (team_id, policy_id),
+			KEY idx_team_policy_exemptions_policy_id (policy_id),
+			CONSTRAINT fk_team_policy_exemptions_team_id FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE,
+			CONSTRAINT fk_team_policy_exemptions_policy_id FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	if err != nil {
+		return errors.Wrap(err, "create team_policy_exemptions table")
+	}
+	return nil
+}
+
+func Down_20220328110000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220328120000, Down_20220328120000)
+}
+
+func Up_20220328120000(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE policies ADD COLUMN evaluation_window VARCHAR(255) NOT NULL DEFAULT ''`); err != nil {
+		return errors.Wrap(err, "add evaluation_window to policies")
+	}
+	return nil
+}
+
+func Down_20220328120000(tx *sql.Tx) error {
+	return nil
+}
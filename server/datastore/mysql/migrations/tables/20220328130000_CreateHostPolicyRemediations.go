@@ -0,0 +1,49 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220328130000, Down_20220328130000)
+}
+
+func Up_20220328130000(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		ALTER TABLE policies
+			ADD COLUMN remediation_script TEXT,
+			ADD COLUMN auto_remediate TINYINT(1) NOT NULL DEFAULT 0`); err != nil {
+		return errors.Wrap(err, "add remediation columns to policies")
+	}
+
+	// the script is copied when the remediation is queued, so that the
+	// remediation records what was run on the host
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_policy_remediations (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			host_id INT UNSIGNED NOT NULL,
+			policy_id INT UNSIGNED NOT NULL,
+			author_id INT UNSIGNED DEFAULT NULL,
+			script TEXT NOT NULL,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			exit_code INT DEFAULT NULL,
+			output TEXT,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY idx_host_policy_remediations_host_id_status (host_id, status),
+			KEY idx_host_policy_remediations_policy_id (policy_id),
+			CONSTRAINT fk_host_policy_remediations_policy_id FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE,
+			CONSTRAINT fk_host_policy_remediations_author_id FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`)
+	if err != nil {
+		return errors.Wrap(err, "create host_policy_remediations table")
+	}
+	return nil
+}
+
+func Down_20220328130000(tx *sql.Tx) error {
+	return nil
+}
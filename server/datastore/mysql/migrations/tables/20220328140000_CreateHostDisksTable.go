@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220328140000, Down_20220328140000)
+}
+
+func Up_20220328140000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_disks (
+			host_id INT UNSIGNED NOT NULL,
+			path VARCHAR(255) NOT NULL,
+			device VARCHAR(255) NOT NULL DEFAULT '',
+			type VARCHAR(50) NOT NULL DEFAULT '',
+			total_bytes BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			available_bytes BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			encrypted TINYINT(1) NOT NULL DEFAULT 0,
+			PRIMARY KEY (host_id, path)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_disks table")
+	}
+	return nil
+}
+
+func Down_20220328140000(tx *sql.Tx) error {
+	return nil
+}
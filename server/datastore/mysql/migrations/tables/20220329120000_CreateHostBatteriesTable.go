@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220329120000, Down_20220329120000)
+}
+
+func Up_20220329120000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_batteries (
+			host_id INT UNSIGNED NOT NULL,
+			serial_number VARCHAR(255) NOT NULL,
+			cycle_count INT NOT NULL DEFAULT 0,
+			health VARCHAR(50) NOT NULL DEFAULT '',
+			` + "`condition`" + ` VARCHAR(50) NOT NULL DEFAULT '',
+			PRIMARY KEY (host_id, serial_number)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_batteries table")
+	}
+	return nil
+}
+
+func Down_20220329120000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220329130000, Down_20220329130000)
+}
+
+func Up_20220329130000(tx *sql.Tx) error {
+	// NULL until the host reports the encryption status of its disk
+	_, err := tx.Exec(`
+		ALTER TABLE hosts
+			ADD COLUMN disk_encryption_enabled TINYINT(1) NULL DEFAULT NULL
+	`)
+	if err != nil {
+		return errors.Wrap(err, "add disk_encryption_enabled to hosts")
+	}
+	return nil
+}
+
+func Down_20220329130000(tx *sql.Tx) error {
+	return nil
+}
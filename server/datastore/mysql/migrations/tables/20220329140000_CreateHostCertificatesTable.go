@@ -0,0 +1,40 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220329140000, Down_20220329140000)
+}
+
+func Up_20220329140000(tx *sql.Tx) error {
+	// the validity dates are DATETIME as certificates commonly expire after
+	// the TIMESTAMP range, the sha1 index is used to find the hosts that
+	// have a certificate.
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_certificates (
+			host_id INT UNSIGNED NOT NULL,
+			sha1 CHAR(40) NOT NULL,
+			common_name VARCHAR(255) NOT NULL DEFAULT '',
+			subject VARCHAR(1024) NOT NULL DEFAULT '',
+			issuer VARCHAR(1024) NOT NULL DEFAULT '',
+			ca TINYINT(1) NOT NULL DEFAULT 0,
+			self_signed TINYINT(1) NOT NULL DEFAULT 0,
+			not_valid_before DATETIME NOT NULL,
+			not_valid_after DATETIME NOT NULL,
+			PRIMARY KEY (host_id, sha1),
+			KEY idx_host_certificates_sha1 (sha1)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_certificates table")
+	}
+	return nil
+}
+
+func Down_20220329140000(tx *sql.Tx) error {
+	return nil
+}
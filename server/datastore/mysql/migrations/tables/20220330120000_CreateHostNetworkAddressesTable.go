@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220330120000, Down_20220330120000)
+}
+
+func Up_20220330120000(tx *sql.Tx) error {
+	// the addresses and macs are indexed to find the hosts by any of their
+	// network addresses, not only the primary one.
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_network_addresses (
+			host_id INT UNSIGNED NOT NULL,
+			address VARCHAR(45) NOT NULL,
+			mac VARCHAR(17) NOT NULL DEFAULT '',
+			PRIMARY KEY (host_id, address, mac),
+			KEY idx_host_network_addresses_address (address),
+			KEY idx_host_network_addresses_mac (mac)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_network_addresses table")
+	}
+	return nil
+}
+
+func Down_20220330120000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220331120000, Down_20220331120000)
+}
+
+func Up_20220331120000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_metadata (
+			host_id INT UNSIGNED NOT NULL,
+			notes TEXT NOT NULL,
+			tags JSON NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (host_id)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_metadata table")
+	}
+	return nil
+}
+
+func Down_20220331120000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,39 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220401120000, Down_20220401120000)
+}
+
+func Up_20220401120000(tx *sql.Tx) error {
+	// unlike the activities table, the user is not a foreign key so that the
+	// activities of the hosts are kept (with the name of the user) when the
+	// user is deleted.
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_activities (
+			id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+			host_id INT UNSIGNED NOT NULL,
+			user_id INT UNSIGNED NULL,
+			user_name VARCHAR(255) NULL,
+			activity_type VARCHAR(64) NOT NULL,
+			details JSON NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY idx_host_activities_host_id_created_at (host_id, created_at),
+			KEY idx_host_activities_created_at (created_at)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_activities table")
+	}
+	return nil
+}
+
+func Down_20220401120000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220402120000, Down_20220402120000)
+}
+
+func Up_20220402120000(tx *sql.Tx) error {
+	// the events are kept until they are delivered by the host lifecycle
+	// webhook, including the deletion events, so they are not deleted with the
+	// hosts and keep a copy of the identifiers of the host.
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_lifecycle_events (
+			id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+			host_id INT UNSIGNED NOT NULL,
+			event_type VARCHAR(32) NOT NULL,
+			hostname VARCHAR(255) NOT NULL DEFAULT '',
+			uuid VARCHAR(255) NOT NULL DEFAULT '',
+			hardware_serial VARCHAR(255) NOT NULL DEFAULT '',
+			team_id INT UNSIGNED NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY idx_host_lifecycle_events_created_at (created_at)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_lifecycle_events table")
+	}
+
+	// seen_time is the last time the host was seen when it was reported
+	// offline, it is reported again once it is seen and goes offline again.
+	_, err = tx.Exec(`
+		CREATE TABLE IF NOT EXISTS host_offline_reports (
+			host_id INT UNSIGNED NOT NULL,
+			seen_time TIMESTAMP NOT NULL,
+			PRIMARY KEY (host_id)
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create host_offline_reports table")
+	}
+	return nil
+}
+
+func Down_20220402120000(tx *sql.Tx) error {
+	return nil
+}
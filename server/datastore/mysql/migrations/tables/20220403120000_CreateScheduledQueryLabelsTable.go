@@ -0,0 +1,33 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220403120000, Down_20220403120000)
+}
+
+func Up_20220403120000(tx *sql.Tx) error {
+	// a scheduled query with labels only runs on the hosts that are members of
+	// any of them, the labels are removed with the scheduled query or label.
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS scheduled_query_labels (
+			scheduled_query_id INT UNSIGNED NOT NULL,
+			label_id INT UNSIGNED NOT NULL,
+			PRIMARY KEY (scheduled_query_id, label_id),
+			CONSTRAINT scheduled_query_labels_scheduled_query_id_fk FOREIGN KEY (scheduled_query_id) REFERENCES scheduled_queries (id) ON DELETE CASCADE,
+			CONSTRAINT scheduled_query_labels_label_id_fk FOREIGN KEY (label_id) REFERENCES labels (id) ON DELETE CASCADE
+		)
+	`)
+	if err != nil {
+		return errors.Wrap(err, "create scheduled_query_labels table")
+	}
+	return nil
+}
+
+func Down_20220403120000(tx *sql.Tx) error {
+	return nil
+}
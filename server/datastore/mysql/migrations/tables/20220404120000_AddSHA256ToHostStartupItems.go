@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220404120000, Down_20220404120000)
+}
+
+func Up_20220404120000(tx *sql.Tx) error {
+	// the threat intel indicators are now selected from the listening ports
+	// and startup items of the hosts, the hashes of the startup items are
+	// collected with them.
+	_, err := tx.Exec(`DROP TABLE IF EXISTS host_indicators`)
+	if err != nil {
+		return errors.Wrap(err, "drop host_indicators table")
+	}
+
+	_, err = tx.Exec(`ALTER TABLE host_startup_items ADD COLUMN sha256 VARCHAR(64) NOT NULL DEFAULT ''`)
+	if err != nil {
+		return errors.Wrap(err, "add sha256 to host_startup_items")
+	}
+	return nil
+}
+
+func Down_20220404120000(tx *sql.Tx) error {
+	return nil
+}
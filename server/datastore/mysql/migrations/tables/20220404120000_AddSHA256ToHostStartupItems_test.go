@@ -0,0 +1,29 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20220404120000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	_, err := db.Exec(`INSERT INTO host_indicators (host_id, type, value) VALUES (1, 'listening_port', 'tcp/22')`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO host_startup_items (host_id, source, name, path) VALUES (1, 'launchd', 'com.example.agent', '/usr/local/bin/agent')`)
+	require.NoError(t, err)
+
+	applyNext(t, db)
+
+	var count int
+	err = db.Get(&count, `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = 'host_indicators'`)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	// the existing startup items are kept without a hash until collected again
+	var sha256 string
+	err = db.Get(&sha256, `SELECT sha256 FROM host_startup_items WHERE host_id = 1 AND source = 'launchd' AND name = 'com.example.agent'`)
+	require.NoError(t, err)
+	require.Empty(t, sha256)
+}
@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20220405120000, Down_20220405120000)
+}
+
+func Up_20220405120000(tx *sql.Tx) error {
+	// the orbit node keys are kept apart from the device auth tokens, as the
+	// latter are also known to the unprivileged users of the host.
+	hostOrbitAuthTable := `
+    CREATE TABLE IF NOT EXISTS host_orbit_auth (
+        host_id int(10) UNSIGNED NOT NULL,
+        node_key VARCHAR(255) NOT NULL,
+        PRIMARY KEY (host_id),
+        UNIQUE INDEX idx_host_orbit_auth_node_key (node_key)
+    );
+	`
+	if _, err := tx.Exec(hostOrbitAuthTable); err != nil {
+		return errors.Wrap(err, "create host_orbit_auth table")
+	}
+	return nil
+}
+
+func Down_20220405120000(tx *sql.Tx) error {
+	return nil
+}
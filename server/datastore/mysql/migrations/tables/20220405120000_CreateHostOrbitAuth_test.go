@@ -0,0 +1,20 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20220405120000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	applyNext(t, db)
+
+	_, err := db.Exec(`INSERT INTO host_orbit_auth (host_id, node_key) VALUES (1, 'abc')`)
+	require.NoError(t, err)
+
+	// the node keys are unique
+	_, err = db.Exec(`INSERT INTO host_orbit_auth (host_id, node_key) VALUES (2, 'abc')`)
+	require.Error(t, err)
+}
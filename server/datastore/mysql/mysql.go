@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/url"
 	"regexp"
 	"strconv"
@@ -105,12 +106,16 @@ type entity struct {
 }
 
 var (
-	hostsTable    = entity{"hosts"}
-	invitesTable  = entity{"invites"}
-	packsTable    = entity{"packs"}
-	queriesTable  = entity{"queries"}
-	sessionsTable = entity{"sessions"}
-	usersTable    = entity{"users"}
+	atcTablesTable           = entity{"atc_tables"}
+	dashboardsTable          = entity{"dashboards"}
+	fimCategoriesTable       = entity{"fim_categories"}
+	hostsTable               = entity{"hosts"}
+	invitesTable             = entity{"invites"}
+	packsTable               = entity{"packs"}
+	queriesTable             = entity{"queries"}
+	sessionsTable            = entity{"sessions"}
+	usersTable               = entity{"users"}
+	yaraSignatureGroupsTable = entity{"yara_signature_groups"}
 )
 
 // retryableError determines whether a MySQL error can be retried. By default
@@ -890,6 +895,17 @@ func hostSearchLike(sql string, params []interface{}, match string, columns ...s
 		base = strings.TrimSuffix(base, ")") + " OR (" + ` EXISTS (SELECT 1 FROM host_emails he WHERE he.host_id = h.id AND he.email LIKE ?)))`
 		args = append(args, likePattern(match))
 	}
+
+	// if match is an IP or MAC address, add searching in the addresses of all
+	// the network interfaces of the hosts, not only the primary one. The match
+	// is exact so that the indexes of host_network_addresses are used.
+	if ip := net.ParseIP(match); ip != nil {
+		base = strings.TrimSuffix(base, ")") + ` OR EXISTS (SELECT 1 FROM host_network_addresses hna WHERE hna.host_id = h.id AND hna.address = ?))`
+		args = append(args, match)
+	} else if mac, err := net.ParseMAC(match); err == nil {
+		base = strings.TrimSuffix(base, ")") + ` OR EXISTS (SELECT 1 FROM host_network_addresses hna WHERE hna.host_id = h.id AND hna.mac = ?))`
+		args = append(args, mac.String())
+	}
 	return base, args
 }
 
@@ -211,6 +211,22 @@ func TestHostSearchLike(t *testing.T) {
 			outSQL:    "SELECT * FROM HOSTS h WHERE 1=1 AND (ipv4 LIKE ? OR ( EXISTS (SELECT 1 FROM host_emails he WHERE he.host_id = h.id AND he.email LIKE ?)))",
 			outParams: []interface{}{1, "%a@b.c%", "%a@b.c%"},
 		},
+		{
+			inSQL:     "SELECT * FROM HOSTS h WHERE TRUE",
+			inParams:  []interface{}{},
+			match:     "10.0.0.1",
+			columns:   []string{"primary_ip"},
+			outSQL:    "SELECT * FROM HOSTS h WHERE TRUE AND (primary_ip LIKE ? OR EXISTS (SELECT 1 FROM host_network_addresses hna WHERE hna.host_id = h.id AND hna.address = ?))",
+			outParams: []interface{}{"%10.0.0.1%", "10.0.0.1"},
+		},
+		{
+			inSQL:     "SELECT * FROM HOSTS h WHERE TRUE",
+			inParams:  []interface{}{},
+			match:     "AA-BB-CC-DD-EE-FF",
+			columns:   []string{"primary_mac"},
+			outSQL:    "SELECT * FROM HOSTS h WHERE TRUE AND (primary_mac LIKE ? OR EXISTS (SELECT 1 FROM host_network_addresses hna WHERE hna.host_id = h.id AND hna.mac = ?))",
+			outParams: []interface{}{"%AA-BB-CC-DD-EE-FF%", "aa:bb:cc:dd:ee:ff"},
+		},
 	}
 
 	for _, tt := range testCases {
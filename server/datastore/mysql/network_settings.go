@@ -0,0 +1,201 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) ReplaceHostDNSServers(ctx context.Context, hostID uint, addresses []string) error {
+	const (
+		selStmt = `SELECT address FROM host_dns_servers WHERE host_id = ?`
+		delStmt = `DELETE FROM host_dns_servers WHERE host_id = ? AND address IN (?)`
+		insStmt = `INSERT INTO host_dns_servers (host_id, address) VALUES`
+		insPart = ` (?, ?),`
+	)
+
+	toIns := make(map[string]struct{}, len(addresses))
+	for _, a := range addresses {
+		toIns[a] = struct{}{}
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var prevAddresses []string
+		if err := sqlx.SelectContext(ctx, tx, &prevAddresses, selStmt, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "select previous host dns servers")
+		}
+
+		var toDel []string
+		for _, a := range prevAddresses {
+			if _, ok := toIns[a]; ok {
+				delete(toIns, a)
+			} else {
+				toDel = append(toDel, a)
+			}
+		}
+
+		if len(toDel) > 0 {
+			stmt, args, err := sqlx.In(delStmt, hostID, toDel)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "prepare delete statement")
+			}
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete host dns servers")
+			}
+		}
+
+		if len(toIns) > 0 {
+			args := make([]interface{}, 0, len(toIns)*2)
+			for a := range toIns {
+				args = append(args, hostID, a)
+			}
+			stmt := insStmt + strings.TrimSuffix(strings.Repeat(insPart, len(toIns)), ",")
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert host dns servers")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ReplaceHostProxies(ctx context.Context, hostID uint, proxies []fleet.HostProxy) error {
+	const (
+		selStmt = `SELECT protocol, address FROM host_proxies WHERE host_id = ?`
+		delStmt = `DELETE FROM host_proxies WHERE host_id = ? AND protocol = ? AND address = ?`
+		insStmt = `INSERT INTO host_proxies (host_id, protocol, address) VALUES`
+		insPart = ` (?, ?, ?),`
+	)
+
+	toIns := make(map[fleet.HostProxy]struct{}, len(proxies))
+	for _, p := range proxies {
+		toIns[p] = struct{}{}
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var prevProxies []fleet.HostProxy
+		if err := sqlx.SelectContext(ctx, tx, &prevProxies, selStmt, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "select previous host proxies")
+		}
+
+		for _, p := range prevProxies {
+			if _, ok := toIns[p]; ok {
+				delete(toIns, p)
+				continue
+			}
+			// a host has very few proxies, delete them one by one
+			if _, err := tx.ExecContext(ctx, delStmt, hostID, p.Protocol, p.Address); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete host proxy")
+			}
+		}
+
+		if len(toIns) > 0 {
+			args := make([]interface{}, 0, len(toIns)*3)
+			for p := range toIns {
+				args = append(args, hostID, p.Protocol, p.Address)
+			}
+			stmt := insStmt + strings.TrimSuffix(strings.Repeat(insPart, len(toIns)), ",")
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert host proxies")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ReplaceHostNetworkAddresses(ctx context.Context, hostID uint, addresses []fleet.HostNetworkAddress) error {
+	const (
+		selStmt = `SELECT address, mac FROM host_network_addresses WHERE host_id = ?`
+		delStmt = `DELETE FROM host_network_addresses WHERE host_id = ? AND address = ? AND mac = ?`
+		insStmt = `INSERT INTO host_network_addresses (host_id, address, mac) VALUES`
+		insPart = ` (?, ?, ?),`
+	)
+
+	toIns := make(map[fleet.HostNetworkAddress]struct{}, len(addresses))
+	for _, a := range addresses {
+		toIns[a] = struct{}{}
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var prevAddresses []fleet.HostNetworkAddress
+		if err := sqlx.SelectContext(ctx, tx, &prevAddresses, selStmt, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "select previous host network addresses")
+		}
+
+		for _, a := range prevAddresses {
+			if _, ok := toIns[a]; ok {
+				delete(toIns, a)
+				continue
+			}
+			// the addresses of a host rarely change, delete them one by one
+			if _, err := tx.ExecContext(ctx, delStmt, hostID, a.Address, a.MAC); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete host network address")
+			}
+		}
+
+		if len(toIns) > 0 {
+			args := make([]interface{}, 0, len(toIns)*3)
+			for a := range toIns {
+				args = append(args, hostID, a.Address, a.MAC)
+			}
+			stmt := insStmt + strings.TrimSuffix(strings.Repeat(insPart, len(toIns)), ",")
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert host network addresses")
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) HostNetworkSettings(ctx context.Context, hostID uint) (*fleet.HostNetworkSettings, error) {
+	settings := &fleet.HostNetworkSettings{
+		DNSServers: []string{},
+		Proxies:    []fleet.HostProxy{},
+	}
+	if err := sqlx.SelectContext(ctx, ds.reader, &settings.DNSServers,
+		`SELECT address FROM host_dns_servers WHERE host_id = ? ORDER BY address`, hostID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host dns servers")
+	}
+	if err := sqlx.SelectContext(ctx, ds.reader, &settings.Proxies,
+		`SELECT protocol, address FROM host_proxies WHERE host_id = ? ORDER BY protocol, address`, hostID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host proxies")
+	}
+	return settings, nil
+}
+
+func (ds *Datastore) NetworkSettingsReport(ctx context.Context, filter fleet.TeamFilter) (*fleet.NetworkSettingsReport, error) {
+	hostsWhere := ds.whereFilterHostsByTeams(filter, "h")
+
+	report := &fleet.NetworkSettingsReport{
+		DNSServers: []fleet.DNSServerHostsCount{},
+		Proxies:    []fleet.ProxyHostsCount{},
+	}
+
+	dnsStmt := `
+		SELECT d.address, COUNT(*) AS hosts_count
+		FROM host_dns_servers d
+		JOIN hosts h ON h.id = d.host_id
+		WHERE ` + hostsWhere + `
+		GROUP BY d.address
+		ORDER BY hosts_count DESC, d.address`
+	if err := sqlx.SelectContext(ctx, ds.reader, &report.DNSServers, dnsStmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select dns servers report")
+	}
+
+	proxiesStmt := `
+		SELECT p.protocol, p.address, COUNT(*) AS hosts_count
+		FROM host_proxies p
+		JOIN hosts h ON h.id = p.host_id
+		WHERE ` + hostsWhere + `
+		GROUP BY p.protocol, p.address
+		ORDER BY hosts_count DESC, p.protocol, p.address`
+	if err := sqlx.SelectContext(ctx, ds.reader, &report.Proxies, proxiesStmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select proxies report")
+	}
+
+	return report, nil
+}
@@ -0,0 +1,120 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkSettings(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"ReplaceHost", testNetworkSettingsReplaceHost},
+		{"Report", testNetworkSettingsReport},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testNetworkSettingsReplaceHost(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	settings, err := ds.HostNetworkSettings(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, settings.DNSServers)
+	assert.Empty(t, settings.Proxies)
+
+	require.NoError(t, ds.ReplaceHostDNSServers(ctx, host.ID, []string{"8.8.8.8", "10.0.0.1"}))
+	require.NoError(t, ds.ReplaceHostProxies(ctx, host.ID, []fleet.HostProxy{
+		{Protocol: fleet.HostProxyProtocolHTTP, Address: "proxy:3128"},
+		{Protocol: fleet.HostProxyProtocolHTTPS, Address: "proxy:3128"},
+	}))
+
+	settings, err = ds.HostNetworkSettings(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "8.8.8.8"}, settings.DNSServers)
+	assert.Equal(t, []fleet.HostProxy{
+		{Protocol: fleet.HostProxyProtocolHTTP, Address: "proxy:3128"},
+		{Protocol: fleet.HostProxyProtocolHTTPS, Address: "proxy:3128"},
+	}, settings.Proxies)
+
+	// replace keeps the existing entries and removes the missing ones
+	require.NoError(t, ds.ReplaceHostDNSServers(ctx, host.ID, []string{"10.0.0.1", "10.0.0.2"}))
+	require.NoError(t, ds.ReplaceHostProxies(ctx, host.ID, []fleet.HostProxy{
+		{Protocol: fleet.HostProxyProtocolHTTPS, Address: "proxy:3128"},
+	}))
+
+	settings, err = ds.HostNetworkSettings(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "10.0.0.2"}, settings.DNSServers)
+	assert.Equal(t, []fleet.HostProxy{
+		{Protocol: fleet.HostProxyProtocolHTTPS, Address: "proxy:3128"},
+	}, settings.Proxies)
+
+	require.NoError(t, ds.ReplaceHostDNSServers(ctx, host.ID, nil))
+	require.NoError(t, ds.ReplaceHostProxies(ctx, host.ID, nil))
+
+	settings, err = ds.HostNetworkSettings(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, settings.DNSServers)
+	assert.Empty(t, settings.Proxies)
+}
+
+func testNetworkSettingsReport(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{host2.ID}))
+
+	require.NoError(t, ds.ReplaceHostDNSServers(ctx, host1.ID, []string{"8.8.8.8", "10.0.0.1"}))
+	require.NoError(t, ds.ReplaceHostDNSServers(ctx, host2.ID, []string{"8.8.8.8"}))
+	require.NoError(t, ds.ReplaceHostProxies(ctx, host2.ID, []fleet.HostProxy{
+		{Protocol: fleet.HostProxyProtocolSOCKS, Address: "proxy:1080"},
+	}))
+
+	report, err := ds.NetworkSettingsReport(ctx, fleet.TeamFilter{User: test.UserAdmin})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.DNSServerHostsCount{
+		{Address: "8.8.8.8", HostsCount: 2},
+		{Address: "10.0.0.1", HostsCount: 1},
+	}, report.DNSServers)
+	assert.Equal(t, []fleet.ProxyHostsCount{
+		{HostProxy: fleet.HostProxy{Protocol: fleet.HostProxyProtocolSOCKS, Address: "proxy:1080"}, HostsCount: 1},
+	}, report.Proxies)
+
+	report, err = ds.NetworkSettingsReport(ctx, fleet.TeamFilter{User: test.UserAdmin, TeamID: &team.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.DNSServerHostsCount{{Address: "8.8.8.8", HostsCount: 1}}, report.DNSServers)
+	assert.Len(t, report.Proxies, 1)
+
+	// the hosts of other teams are not counted for team users
+	report, err = ds.NetworkSettingsReport(ctx, fleet.TeamFilter{User: &fleet.User{
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: team.ID + 1}, Role: fleet.RoleAdmin}},
+	}})
+	require.NoError(t, err)
+	assert.Empty(t, report.DNSServers)
+	assert.Empty(t, report.Proxies)
+
+	// the network settings are removed with the host
+	require.NoError(t, ds.DeleteHost(ctx, host1.ID))
+	report, err = ds.NetworkSettingsReport(ctx, fleet.TeamFilter{User: test.UserAdmin})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.DNSServerHostsCount{{Address: "8.8.8.8", HostsCount: 1}}, report.DNSServers)
+}
@@ -63,11 +63,11 @@ func applyPackSpecDB(ctx context.Context, tx sqlx.ExtContext, spec *fleet.PackSp
 	query = `
 		INSERT INTO scheduled_queries (
 			pack_id, query_name, name, description, ` + "`interval`" + `,
-			snapshot, removed, shard, platform, version, denylist
+			snapshot, removed, shard, platform, version, denylist, discard_data
 		)
 		VALUES (
 			?, ?, ?, ?, ?,
-			?, ?, ?, ?, ?, ?
+			?, ?, ?, ?, ?, ?, ?
 		)
 	`
 	for _, q := range spec.Queries {
@@ -75,9 +75,9 @@ func applyPackSpecDB(ctx context.Context, tx sqlx.ExtContext, spec *fleet.PackSp
 		if q.Name == "" {
 			q.Name = q.QueryName
 		}
-		_, err := tx.ExecContext(ctx, query,
+		res, err := tx.ExecContext(ctx, query,
 			packID, q.QueryName, q.Name, q.Description, q.Interval,
-			q.Snapshot, q.Removed, q.Shard, q.Platform, q.Version, q.Denylist,
+			q.Snapshot, q.Removed, q.Shard, q.Platform, q.Version, q.Denylist, q.DiscardData,
 		)
 		switch {
 		case isChildForeignKeyError(err):
@@ -85,6 +85,12 @@ func applyPackSpecDB(ctx context.Context, tx sqlx.ExtContext, spec *fleet.PackSp
 		case err != nil:
 			return ctxerr.Wrapf(ctx, err, "adding query %s referencing %s", q.Name, q.QueryName)
 		}
+		if len(q.Labels) > 0 {
+			scheduledQueryID, _ := res.LastInsertId()
+			if err := applyPackSpecQueryLabelsDB(ctx, tx, uint(scheduledQueryID), q); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Delete existing targets
@@ -116,6 +122,58 @@ func applyPackSpecDB(ctx context.Context, tx sqlx.ExtContext, spec *fleet.PackSp
 	return nil
 }
 
+// applyPackSpecQueryLabelsDB restricts the scheduled query of the pack spec
+// to its labels, which are referenced by name.
+func applyPackSpecQueryLabelsDB(ctx context.Context, tx sqlx.ExtContext, scheduledQueryID uint, q fleet.PackSpecQuery) error {
+	query := `
+		INSERT INTO scheduled_query_labels (scheduled_query_id, label_id)
+		SELECT ?, id FROM labels WHERE name = ?
+		ON DUPLICATE KEY UPDATE label_id = label_id
+	`
+	for _, l := range q.Labels {
+		res, err := tx.ExecContext(ctx, query, scheduledQueryID, l)
+		if err != nil {
+			return ctxerr.Wrapf(ctx, err, "adding label %s to query %s", l, q.Name)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			var exists bool
+			if err := sqlx.GetContext(ctx, tx, &exists, `SELECT EXISTS(SELECT 1 FROM labels WHERE name = ?)`, l); err != nil {
+				return ctxerr.Wrapf(ctx, err, "check label %s of query %s", l, q.Name)
+			}
+			if !exists {
+				return ctxerr.Errorf(ctx, "cannot target query '%s' at unknown label '%s'", q.Name, l)
+			}
+		}
+	}
+	return nil
+}
+
+// loadPackSpecQueryLabelsDB loads the names of the labels the scheduled
+// queries of the pack spec are restricted to.
+func loadPackSpecQueryLabelsDB(ctx context.Context, tx sqlx.QueryerContext, spec *fleet.PackSpec) error {
+	query := `
+SELECT sq.name AS scheduled_query_name, l.name AS label_name
+FROM scheduled_query_labels sqlb JOIN scheduled_queries sq JOIN labels l
+WHERE sq.pack_id = ? AND sqlb.scheduled_query_id = sq.id AND sqlb.label_id = l.id
+ORDER BY l.name
+`
+	var rows []struct {
+		ScheduledQueryName string `db:"scheduled_query_name"`
+		LabelName          string `db:"label_name"`
+	}
+	if err := sqlx.SelectContext(ctx, tx, &rows, query, spec.ID); err != nil {
+		return ctxerr.Wrap(ctx, err, "get pack query labels")
+	}
+	for _, row := range rows {
+		for i := range spec.Queries {
+			if spec.Queries[i].Name == row.ScheduledQueryName {
+				spec.Queries[i].Labels = append(spec.Queries[i].Labels, row.LabelName)
+			}
+		}
+	}
+	return nil
+}
+
 func (ds *Datastore) GetPackSpecs(ctx context.Context) ([]*fleet.PackSpec, error) {
 	var specs []*fleet.PackSpec
 	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
@@ -154,13 +212,16 @@ WHERE pack_id = ? AND pt.type = ? AND pt.target_id = t.id
 			query = `
 SELECT
 query_name, name, description, ` + "`interval`" + `,
-snapshot, removed, shard, platform, version, denylist
+snapshot, removed, shard, platform, version, denylist, discard_data
 FROM scheduled_queries
 WHERE pack_id = ?
 `
 			if err := sqlx.SelectContext(ctx, tx, &spec.Queries, query, spec.ID); err != nil {
 				return ctxerr.Wrap(ctx, err, "get pack queries")
 			}
+			if err := loadPackSpecQueryLabelsDB(ctx, tx, spec); err != nil {
+				return err
+			}
 		}
 
 		return nil
@@ -208,13 +269,16 @@ WHERE pack_id = ? AND pt.type = ? AND pt.target_id = t.id
 		query = `
 SELECT
 query_name, name, description, ` + "`interval`" + `,
-snapshot, removed, shard, platform, version, denylist
+snapshot, removed, shard, platform, version, denylist, discard_data
 FROM scheduled_queries
 WHERE pack_id = ?
 `
 		if err := sqlx.SelectContext(ctx, tx, &spec.Queries, query, spec.ID); err != nil {
 			return ctxerr.Wrap(ctx, err, "get pack queries")
 		}
+		if err := loadPackSpecQueryLabelsDB(ctx, tx, spec); err != nil {
+			return err
+		}
 
 		return nil
 	})
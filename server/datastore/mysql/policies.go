@@ -26,8 +26,8 @@ func (ds *Datastore) NewGlobalPolicy(ctx context.Context, authorID *uint, args f
 		args.Description = q.Description
 	}
 	res, err := ds.writer.ExecContext(ctx,
-		`INSERT INTO policies (name, query, description, resolution, author_id, platforms) VALUES (?, ?, ?, ?, ?, ?)`,
-		args.Name, args.Query, args.Description, args.Resolution, authorID, args.Platform,
+		`INSERT INTO policies (name, query, description, resolution, author_id, platforms, critical, evaluation_window, remediation_script, auto_remediate) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		args.Name, args.Query, args.Description, args.Resolution, authorID, args.Platform, args.Critical, args.EvaluationWindow, args.RemediationScript, args.AutoRemediate,
 	)
 	switch {
 	case err == nil:
@@ -61,10 +61,12 @@ func policyDB(ctx context.Context, q sqlx.QueryerContext, id uint, teamID *uint)
 		fmt.Sprintf(`SELECT p.*,
 		    COALESCE(u.name, '<deleted>') AS author_name,
 			COALESCE(u.email, '') AS author_email,
-       		(select count(*) from policy_membership where policy_id=p.id and passes=true) as passing_host_count,
-       		(select count(*) from policy_membership where policy_id=p.id and passes=false) as failing_host_count
+			COALESCE(ps.passing_host_count, 0) AS passing_host_count,
+			COALESCE(ps.failing_host_count, 0) AS failing_host_count,
+			ps.updated_at AS host_count_updated_at
 		FROM policies p
 		LEFT JOIN users u ON p.author_id = u.id
+		LEFT JOIN policy_stats ps ON p.id = ps.policy_id
 		WHERE p.id=? AND %s`, teamWhere),
 		args...)
 	if err != nil {
@@ -83,10 +85,10 @@ func policyDB(ctx context.Context, q sqlx.QueryerContext, id uint, teamID *uint)
 func (ds *Datastore) SavePolicy(ctx context.Context, p *fleet.Policy) error {
 	sql := `
 		UPDATE policies
-			SET name = ?, query = ?, description = ?, resolution = ?, platforms = ?
+			SET name = ?, query = ?, description = ?, resolution = ?, platforms = ?, critical = ?, evaluation_window = ?, remediation_script = ?, auto_remediate = ?
 			WHERE id = ?
 	`
-	result, err := ds.writer.ExecContext(ctx, sql, p.Name, p.Query, p.Description, p.Resolution, p.Platform, p.ID)
+	result, err := ds.writer.ExecContext(ctx, sql, p.Name, p.Query, p.Description, p.Resolution, p.Platform, p.Critical, p.EvaluationWindow, p.RemediationScript, p.AutoRemediate, p.ID)
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "updating policy")
 	}
@@ -221,6 +223,10 @@ func (ds *Datastore) RecordPolicyQueryExecutions(ctx context.Context, host *flee
 			return ctxerr.Wrapf(ctx, err, "insert policy_membership (%v)", vals)
 		}
 
+		if err := updateHostIssuesFailingPoliciesDB(ctx, tx, []uint{host.ID}); err != nil {
+			return err
+		}
+
 		// if we are deferring host updates, we return at this point and do the change outside of the tx
 		if deferredSaveHost {
 			return nil
@@ -257,31 +263,103 @@ func (ds *Datastore) RecordPolicyQueryExecutions(ctx context.Context, host *flee
 	return nil
 }
 
-func (ds *Datastore) ListGlobalPolicies(ctx context.Context) ([]*fleet.Policy, error) {
-	return listPoliciesDB(ctx, ds.reader, nil)
+// RecordCriticalPolicyQueryExecutions records the results of the critical policies
+// executed on the host, and updates its critical_policy_updated_at timestamp. The
+// policy_updated_at timestamp is left untouched, as the other policies of the host
+// were not executed.
+func (ds *Datastore) RecordCriticalPolicyQueryExecutions(ctx context.Context, host *fleet.Host, results map[uint]*bool, updated time.Time) error {
+	// Sort the results to have generated SQL queries ordered to minimize
+	// deadlocks. See https://github.com/fleetdm/fleet/issues/1146.
+	orderedIDs := make([]uint, 0, len(results))
+	for policyID := range results {
+		orderedIDs = append(orderedIDs, policyID)
+	}
+	sort.Slice(orderedIDs, func(i, j int) bool { return orderedIDs[i] < orderedIDs[j] })
+
+	vals := []interface{}{}
+	bindvars := []string{}
+	for _, policyID := range orderedIDs {
+		bindvars = append(bindvars, "(?,?,?,?)")
+		vals = append(vals, updated, policyID, host.ID, results[policyID])
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if len(bindvars) > 0 {
+			query := fmt.Sprintf(
+				`INSERT INTO policy_membership (updated_at, policy_id, host_id, passes)
+				VALUES %s ON DUPLICATE KEY UPDATE updated_at=VALUES(updated_at), passes=VALUES(passes)`,
+				strings.Join(bindvars, ","),
+			)
+			if _, err := tx.ExecContext(ctx, query, vals...); err != nil {
+				return ctxerr.Wrapf(ctx, err, "insert policy_membership (%v)", vals)
+			}
+			if err := updateHostIssuesFailingPoliciesDB(ctx, tx, []uint{host.ID}); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE hosts SET critical_policy_updated_at = ? WHERE id=?`, updated, host.ID); err != nil {
+			return ctxerr.Wrap(ctx, err, "updating hosts critical policy updated at")
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ListGlobalPolicies(ctx context.Context, opts fleet.ListOptions) ([]*fleet.Policy, error) {
+	return listPoliciesDB(ctx, ds.reader, nil, opts)
 }
 
-func listPoliciesDB(ctx context.Context, q sqlx.QueryerContext, teamID *uint) ([]*fleet.Policy, error) {
+// listPoliciesOrderKeys maps the supported order keys of the policies lists to
+// their column.
+var listPoliciesOrderKeys = map[string]string{
+	"id":                 "p.id",
+	"name":               "p.name",
+	"created_at":         "p.created_at",
+	"updated_at":         "p.updated_at",
+	"passing_host_count": "passing_host_count",
+	"failing_host_count": "failing_host_count",
+}
+
+func listPoliciesDB(ctx context.Context, q sqlx.QueryerContext, teamID *uint, opts fleet.ListOptions) ([]*fleet.Policy, error) {
 	teamWhere := "p.team_id is NULL"
 	var args []interface{}
 	if teamID != nil {
 		teamWhere = "p.team_id = ?"
 		args = append(args, *teamID)
 	}
-	var policies []*fleet.Policy
-	err := sqlx.SelectContext(
-		ctx,
-		q,
-		&policies,
-		fmt.Sprintf(`SELECT p.*,
+	stmt := fmt.Sprintf(`SELECT p.*,
 		    COALESCE(u.name, '<deleted>') AS author_name,
 			COALESCE(u.email, '') AS author_email,
-       		(select count(*) from policy_membership where policy_id=p.id and passes=true) as passing_host_count,
-       		(select count(*) from policy_membership where policy_id=p.id and passes=false) as failing_host_count
+			COALESCE(ps.passing_host_count, 0) AS passing_host_count,
+			COALESCE(ps.failing_host_count, 0) AS failing_host_count,
+			ps.updated_at AS host_count_updated_at
 		FROM policies p
 		LEFT JOIN users u ON p.author_id = u.id
-		WHERE %s`, teamWhere), args...,
-	)
+		LEFT JOIN policy_stats ps ON p.id = ps.policy_id
+		WHERE %s`, teamWhere)
+	stmt, args = searchLike(stmt, args, opts.MatchQuery, "p.name")
+
+	// the critical policies are always listed first, then the policies are
+	// ordered by the requested key.
+	stmt += " ORDER BY p.critical DESC"
+	if opts.OrderKey != "" {
+		column, ok := listPoliciesOrderKeys[opts.OrderKey]
+		if !ok {
+			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("order_key", "unsupported order key: "+opts.OrderKey))
+		}
+		direction := "ASC"
+		if opts.OrderDirection == fleet.OrderDescending {
+			direction = "DESC"
+		}
+		stmt += fmt.Sprintf(", %s %s", column, direction)
+	}
+	stmt += ", p.id"
+	opts.OrderKey = ""
+	opts.After = ""
+	stmt = appendListOptionsToSQL(stmt, opts)
+
+	var policies []*fleet.Policy
+	err := sqlx.SelectContext(ctx, q, &policies, stmt, args...)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "listing policies")
 	}
@@ -292,10 +370,12 @@ func (ds *Datastore) PoliciesByID(ctx context.Context, ids []uint) (map[uint]*fl
 	sql := `SELECT p.*,
 		    COALESCE(u.name, '<deleted>') AS author_name,
 			COALESCE(u.email, '') AS author_email,
-       		(select count(*) from policy_membership where policy_id=p.id and passes=true) as passing_host_count,
-       		(select count(*) from policy_membership where policy_id=p.id and passes=false) as failing_host_count
+			COALESCE(ps.passing_host_count, 0) AS passing_host_count,
+			COALESCE(ps.failing_host_count, 0) AS failing_host_count,
+			ps.updated_at AS host_count_updated_at
 		FROM policies p
 		LEFT JOIN users u ON p.author_id = u.id
+		LEFT JOIN policy_stats ps ON p.id = ps.policy_id
 		WHERE p.id IN (?)`
 	query, args, err := sqlx.In(sql, ids)
 	if err != nil {
@@ -327,14 +407,35 @@ func (ds *Datastore) PoliciesByID(ctx context.Context, ids []uint) (map[uint]*fl
 }
 
 func (ds *Datastore) DeleteGlobalPolicies(ctx context.Context, ids []uint) ([]uint, error) {
-	return deletePolicyDB(ctx, ds.writer, ids, nil)
+	return ds.deletePolicies(ctx, ids, nil)
+}
+
+func (ds *Datastore) deletePolicies(ctx context.Context, ids []uint, teamID *uint) ([]uint, error) {
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		return deletePolicyDB(ctx, tx, ids, teamID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
 }
 
-func deletePolicyDB(ctx context.Context, q sqlx.ExtContext, ids []uint, teamID *uint) ([]uint, error) {
-	stmt := `DELETE FROM policies WHERE id IN (?) AND %s`
-	stmt, args, err := sqlx.In(stmt, ids)
+func deletePolicyDB(ctx context.Context, q sqlx.ExtContext, ids []uint, teamID *uint) error {
+	// the policy_membership rows are deleted via cascade, the issues of the
+	// hosts failing the policies must be updated after the delete.
+	stmt, args, err := sqlx.In(`SELECT DISTINCT host_id FROM policy_membership WHERE policy_id IN (?) AND passes = 0 ORDER BY host_id`, ids)
 	if err != nil {
-		return nil, ctxerr.Wrap(ctx, err, "IN for DELETE FROM policies")
+		return ctxerr.Wrap(ctx, err, "IN for SELECT failing hosts of policies")
+	}
+	var failingHostIDs []uint
+	if err := sqlx.SelectContext(ctx, q, &failingHostIDs, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "select failing hosts of policies")
+	}
+
+	stmt = `DELETE FROM policies WHERE id IN (?) AND %s`
+	stmt, args, err = sqlx.In(stmt, ids)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "IN for DELETE FROM policies")
 	}
 	stmt = q.Rebind(stmt)
 
@@ -345,16 +446,27 @@ func deletePolicyDB(ctx context.Context, q sqlx.ExtContext, ids []uint, teamID *
 	}
 
 	if _, err := q.ExecContext(ctx, fmt.Sprintf(stmt, teamWhere), args...); err != nil {
-		return nil, ctxerr.Wrap(ctx, err, "delete policies")
+		return ctxerr.Wrap(ctx, err, "delete policies")
 	}
-	return ids, nil
+	return updateHostIssuesFailingPoliciesDB(ctx, q, failingHostIDs)
 }
 
 // PolicyQueriesForHost returns the policy queries that are to be executed on the given host.
 func (ds *Datastore) PolicyQueriesForHost(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+	return ds.policyQueriesForHost(ctx, host, false)
+}
+
+// CriticalPolicyQueriesForHost returns the critical policy queries that are to be executed
+// on the given host.
+func (ds *Datastore) CriticalPolicyQueriesForHost(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+	return ds.policyQueriesForHost(ctx, host, true)
+}
+
+func (ds *Datastore) policyQueriesForHost(ctx context.Context, host *fleet.Host, onlyCritical bool) (map[string]string, error) {
 	var rows []struct {
-		ID    string `db:"id"`
-		Query string `db:"query"`
+		ID               string `db:"id"`
+		Query            string `db:"query"`
+		EvaluationWindow string `db:"evaluation_window"`
 	}
 	if host.FleetPlatform() == "" {
 		// We log to help troubleshooting in case this happens, as the host
@@ -364,6 +476,7 @@ func (ds *Datastore) PolicyQueriesForHost(ctx context.Context, host *fleet.Host)
 	q := dialect.From("policies").Select(
 		goqu.I("id"),
 		goqu.I("query"),
+		goqu.I("evaluation_window"),
 	).Where(
 		goqu.And(
 			goqu.Or(
@@ -379,6 +492,16 @@ func (ds *Datastore) PolicyQueriesForHost(ctx context.Context, host *fleet.Host)
 			),
 		),
 	)
+	if host.TeamID != nil {
+		// the global policies the team of the host is exempted from
+		q = q.Where(goqu.L(
+			"NOT EXISTS (SELECT 1 FROM team_policy_exemptions e WHERE e.team_id = ? AND e.policy_id = policies.id)",
+			*host.TeamID,
+		))
+	}
+	if onlyCritical {
+		q = q.Where(goqu.I("critical").Eq(true))
+	}
 	sql, args, err := q.ToSQL()
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "selecting policies sql build")
@@ -386,8 +509,18 @@ func (ds *Datastore) PolicyQueriesForHost(ctx context.Context, host *fleet.Host)
 	if err := sqlx.SelectContext(ctx, ds.reader, &rows, sql, args...); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "selecting policies for host")
 	}
+	now := ds.clock.Now()
 	results := make(map[string]string)
 	for _, row := range rows {
+		if row.EvaluationWindow != "" {
+			// the windows are verified when the policies are saved
+			window, err := fleet.ParsePolicyEvaluationWindow(row.EvaluationWindow)
+			if err != nil {
+				level.Error(ds.logger).Log("err", fmt.Sprintf("policy %s with invalid evaluation window", row.ID), "details", err)
+			} else if !window.Contains(now) {
+				continue
+			}
+		}
 		results[row.ID] = row.Query
 	}
 	return results, nil
@@ -404,8 +537,8 @@ func (ds *Datastore) NewTeamPolicy(ctx context.Context, teamID uint, authorID *u
 		args.Description = q.Description
 	}
 	res, err := ds.writer.ExecContext(ctx,
-		`INSERT INTO policies (name, query, description, team_id, resolution, author_id, platforms) VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		args.Name, args.Query, args.Description, teamID, args.Resolution, authorID, args.Platform)
+		`INSERT INTO policies (name, query, description, team_id, resolution, author_id, platforms, critical, evaluation_window, remediation_script, auto_remediate) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		args.Name, args.Query, args.Description, teamID, args.Resolution, authorID, args.Platform, args.Critical, args.EvaluationWindow, args.RemediationScript, args.AutoRemediate)
 	switch {
 	case err == nil:
 		// OK
@@ -421,18 +554,96 @@ func (ds *Datastore) NewTeamPolicy(ctx context.Context, teamID uint, authorID *u
 	return policyDB(ctx, ds.writer, uint(lastIdInt64), &teamID)
 }
 
-func (ds *Datastore) ListTeamPolicies(ctx context.Context, teamID uint) ([]*fleet.Policy, error) {
-	return listPoliciesDB(ctx, ds.reader, &teamID)
+func (ds *Datastore) ListTeamPolicies(ctx context.Context, teamID uint, opts fleet.ListOptions) ([]*fleet.Policy, error) {
+	return listPoliciesDB(ctx, ds.reader, &teamID, opts)
 }
 
 func (ds *Datastore) DeleteTeamPolicies(ctx context.Context, teamID uint, ids []uint) ([]uint, error) {
-	return deletePolicyDB(ctx, ds.writer, ids, &teamID)
+	return ds.deletePolicies(ctx, ids, &teamID)
 }
 
 func (ds *Datastore) TeamPolicy(ctx context.Context, teamID uint, policyID uint) (*fleet.Policy, error) {
 	return policyDB(ctx, ds.reader, policyID, &teamID)
 }
 
+func (ds *Datastore) TeamPolicyExemptions(ctx context.Context, teamID uint) ([]uint, error) {
+	var policyIDs []uint
+	if err := sqlx.SelectContext(ctx, ds.reader, &policyIDs,
+		`SELECT policy_id FROM team_policy_exemptions WHERE team_id = ? ORDER BY policy_id`, teamID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select team policy exemptions")
+	}
+	return policyIDs, nil
+}
+
+// SetTeamPolicyExemptions replaces the global policies the team is exempted
+// from. The results of the exempted policies are deleted for the hosts of the
+// team, as the policies no longer apply to them.
+func (ds *Datastore) SetTeamPolicyExemptions(ctx context.Context, teamID uint, policyIDs []uint) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM team_policy_exemptions WHERE team_id = ?`, teamID); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete team policy exemptions")
+		}
+		if len(policyIDs) == 0 {
+			return nil
+		}
+
+		stmt := `INSERT INTO team_policy_exemptions (team_id, policy_id) VALUES `
+		stmt += strings.Repeat(`(?, ?),`, len(policyIDs))
+		stmt = strings.TrimSuffix(stmt, ",")
+		args := make([]interface{}, 0, len(policyIDs)*2)
+		for _, policyID := range policyIDs {
+			args = append(args, teamID, policyID)
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert team policy exemptions")
+		}
+
+		var failingHostIDs []uint
+		if err := sqlx.SelectContext(ctx, tx, &failingHostIDs, `
+			SELECT DISTINCT pm.host_id
+			FROM policy_membership pm
+			JOIN hosts h ON h.id = pm.host_id
+			JOIN team_policy_exemptions e ON e.policy_id = pm.policy_id AND e.team_id = h.team_id
+			WHERE h.team_id = ? AND pm.passes = 0
+			ORDER BY pm.host_id`, teamID,
+		); err != nil {
+			return ctxerr.Wrap(ctx, err, "select failing hosts of exempted policies")
+		}
+		if err := deleteExemptedPolicyMembershipDB(ctx, tx, teamID, nil); err != nil {
+			return err
+		}
+		return updateHostIssuesFailingPoliciesDB(ctx, tx, failingHostIDs)
+	})
+}
+
+// deleteExemptedPolicyMembershipDB deletes the results of the global policies
+// the team is exempted from, for the provided hosts or all the hosts of the
+// team if hostIDs is empty.
+func deleteExemptedPolicyMembershipDB(ctx context.Context, tx sqlx.ExtContext, teamID uint, hostIDs []uint) error {
+	stmt := `
+		DELETE pm
+		FROM policy_membership pm
+		JOIN team_policy_exemptions e ON e.policy_id = pm.policy_id
+		WHERE e.team_id = ?`
+	args := []interface{}{teamID}
+	if len(hostIDs) > 0 {
+		stmt += ` AND pm.host_id IN (?)`
+		args = append(args, hostIDs)
+	} else {
+		stmt += ` AND pm.host_id IN (SELECT id FROM hosts WHERE team_id = ?)`
+		args = append(args, teamID)
+	}
+	stmt, args, err := sqlx.In(stmt, args...)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build delete exempted policy membership")
+	}
+	if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete exempted policy membership")
+	}
+	return nil
+}
+
 // ApplyPolicySpecs applies the given policy specs, creating new policies and updating the ones that
 // already exist (a policy is identified by its name).
 //
@@ -450,19 +661,27 @@ func (ds *Datastore) ApplyPolicySpecs(ctx context.Context, authorID uint, specs
 			author_id,
 			resolution,
 			team_id,
-			platforms
-		) VALUES ( ?, ?, ?, ?, ?, (SELECT IFNULL(MIN(id), NULL) FROM teams WHERE name = ?), ? )
+			platforms,
+			critical,
+			evaluation_window,
+			remediation_script,
+			auto_remediate
+		) VALUES ( ?, ?, ?, ?, ?, (SELECT IFNULL(MIN(id), NULL) FROM teams WHERE name = ?), ?, ?, ?, ?, ? )
 		ON DUPLICATE KEY UPDATE
 			name = VALUES(name),
 			query = VALUES(query),
 			description = VALUES(description),
 			author_id = VALUES(author_id),
 			resolution = VALUES(resolution),
-			platforms = VALUES(platforms)
+			platforms = VALUES(platforms),
+			critical = VALUES(critical),
+			evaluation_window = VALUES(evaluation_window),
+			remediation_script = VALUES(remediation_script),
+			auto_remediate = VALUES(auto_remediate)
 		`
 		for _, spec := range specs {
 			res, err := tx.ExecContext(ctx,
-				sql, spec.Name, spec.Query, spec.Description, authorID, spec.Resolution, spec.Team, spec.Platform,
+				sql, spec.Name, spec.Query, spec.Description, authorID, spec.Resolution, spec.Team, spec.Platform, spec.Critical, spec.EvaluationWindow, spec.RemediationScript, spec.AutoRemediate,
 			)
 			if err != nil {
 				return ctxerr.Wrap(ctx, err, "exec ApplyPolicySpecs insert")
@@ -505,12 +724,22 @@ func (ds *Datastore) AsyncBatchInsertPolicyMembership(ctx context.Context, batch
 	sql += ` ON DUPLICATE KEY UPDATE updated_at = VALUES(updated_at), passes = VALUES(passes)`
 
 	vals := make([]interface{}, 0, len(batch)*3)
+	seenHostIDs := make(map[uint]bool)
+	var hostIDs []uint
 	for _, tup := range batch {
 		vals = append(vals, tup.PolicyID, tup.HostID, tup.Passes)
+		if !seenHostIDs[tup.HostID] {
+			seenHostIDs[tup.HostID] = true
+			hostIDs = append(hostIDs, tup.HostID)
+		}
 	}
+	sort.Slice(hostIDs, func(i, j int) bool { return hostIDs[i] < hostIDs[j] })
+
 	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
-		_, err := tx.ExecContext(ctx, sql, vals...)
-		return ctxerr.Wrap(ctx, err, "insert into policy_membership")
+		if _, err := tx.ExecContext(ctx, sql, vals...); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert into policy_membership")
+		}
+		return updateHostIssuesFailingPoliciesDB(ctx, tx, hostIDs)
 	})
 }
 
@@ -621,3 +850,59 @@ func (ds *Datastore) CleanupPolicyMembership(ctx context.Context, now time.Time)
 
 	return nil
 }
+
+// policyStatsBatchSize is the number of policies whose stats are upserted by
+// each statement of UpdatePolicyStats.
+const policyStatsBatchSize = 500
+
+// UpdatePolicyStats aggregates the number of hosts passing and failing each
+// policy into the policy_stats table, read by the policies endpoints instead
+// of counting the policy_membership rows of each policy.
+func (ds *Datastore) UpdatePolicyStats(ctx context.Context) error {
+	// the counts are aggregated on the reader, so that the policy_membership
+	// rows are not locked while they are scanned
+	selectStmt := `
+		SELECT
+			p.id AS policy_id,
+			COALESCE(SUM(pm.passes = 1), 0) AS passing_host_count,
+			COALESCE(SUM(pm.passes = 0), 0) AS failing_host_count
+		FROM policies p
+		LEFT JOIN policy_membership pm ON p.id = pm.policy_id
+		GROUP BY p.id`
+	var stats []struct {
+		PolicyID         uint `db:"policy_id"`
+		PassingHostCount uint `db:"passing_host_count"`
+		FailingHostCount uint `db:"failing_host_count"`
+	}
+	if err := sqlx.SelectContext(ctx, ds.reader, &stats, selectStmt); err != nil {
+		return ctxerr.Wrap(ctx, err, "aggregate policy stats")
+	}
+
+	for i := 0; i < len(stats); i += policyStatsBatchSize {
+		end := i + policyStatsBatchSize
+		if end > len(stats) {
+			end = len(stats)
+		}
+		batch := stats[i:end]
+
+		// INSERT IGNORE, the policies could be deleted since the counts were
+		// aggregated. The timestamp is updated even if the counts did not
+		// change, it is the time of the last aggregation.
+		sql := `INSERT IGNORE INTO policy_stats (policy_id, passing_host_count, failing_host_count) VALUES `
+		sql += strings.Repeat(`(?, ?, ?),`, len(batch))
+		sql = strings.TrimSuffix(sql, ",")
+		sql += ` ON DUPLICATE KEY UPDATE
+			passing_host_count = VALUES(passing_host_count),
+			failing_host_count = VALUES(failing_host_count),
+			updated_at = CURRENT_TIMESTAMP`
+
+		vals := make([]interface{}, 0, len(batch)*3)
+		for _, s := range batch {
+			vals = append(vals, s.PolicyID, s.PassingHostCount, s.FailingHostCount)
+		}
+		if _, err := ds.writer.ExecContext(ctx, sql, vals...); err != nil {
+			return ctxerr.Wrap(ctx, err, "upsert policy stats")
+		}
+	}
+	return nil
+}
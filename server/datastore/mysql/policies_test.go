@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/WatchBeam/clock"
 	"github.com/fleetdm/fleet/v4/server"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
@@ -43,6 +44,10 @@ func TestPolicies(t *testing.T) {
 		{"FlippingPoliciesForHost", testFlippingPoliciesForHost},
 		{"PlatformUpdate", testPolicyPlatformUpdate},
 		{"CleanupPolicyMembership", testPolicyCleanupPolicyMembership},
+		{"CriticalPolicies", testCriticalPolicies},
+		{"ListOptions", testPoliciesListOptions},
+		{"TeamPolicyExemptions", testTeamPolicyExemptions},
+		{"EvaluationWindows", testPolicyEvaluationWindows},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -84,7 +89,7 @@ func testPoliciesNewGlobalPolicyLegacy(t *testing.T, ds *Datastore) {
 	})
 	require.NoError(t, err)
 
-	policies, err := ds.ListGlobalPolicies(context.Background())
+	policies, err := ds.ListGlobalPolicies(context.Background(), fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, policies, 2)
 	assert.Equal(t, q.Name, policies[0].Name)
@@ -102,7 +107,7 @@ func testPoliciesNewGlobalPolicyLegacy(t *testing.T, ds *Datastore) {
 	_, err = ds.DeleteGlobalPolicies(context.Background(), []uint{policies[0].ID, policies[1].ID})
 	require.NoError(t, err)
 
-	policies, err = ds.ListGlobalPolicies(context.Background())
+	policies, err = ds.ListGlobalPolicies(context.Background(), fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, policies, 0)
 }
@@ -134,7 +139,7 @@ func testPoliciesNewGlobalPolicyProprietary(t *testing.T, ds *Datastore) {
 	})
 	require.NoError(t, err)
 
-	policies, err := ds.ListGlobalPolicies(ctx)
+	policies, err := ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, policies, 2)
 	assert.Equal(t, "query1", policies[0].Name)
@@ -169,7 +174,7 @@ func testPoliciesNewGlobalPolicyProprietary(t *testing.T, ds *Datastore) {
 	_, err = ds.DeleteGlobalPolicies(ctx, []uint{policies[0].ID, policies[1].ID})
 	require.NoError(t, err)
 
-	policies, err = ds.ListGlobalPolicies(ctx)
+	policies, err = ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, policies, 0)
 
@@ -261,10 +266,19 @@ func testPoliciesMembershipView(deferred bool, t *testing.T, ds *Datastore) {
 
 	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host2, map[uint]*bool{p2.ID: nil}, time.Now(), deferred))
 
-	policies, err := ds.ListGlobalPolicies(context.Background())
+	// the counts are only updated by the aggregation
+	policies, err := ds.ListGlobalPolicies(context.Background(), fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, policies, 2)
+	assert.Equal(t, uint(0), policies[0].PassingHostCount)
+	assert.Nil(t, policies[0].HostCountUpdatedAt)
 
+	require.NoError(t, ds.UpdatePolicyStats(context.Background()))
+	policies, err = ds.ListGlobalPolicies(context.Background(), fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+
+	assert.NotNil(t, policies[0].HostCountUpdatedAt)
 	assert.Equal(t, uint(2), policies[0].PassingHostCount)
 	assert.Equal(t, uint(0), policies[0].FailingHostCount)
 
@@ -273,8 +287,9 @@ func testPoliciesMembershipView(deferred bool, t *testing.T, ds *Datastore) {
 
 	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host1, map[uint]*bool{p.ID: ptr.Bool(false)}, time.Now(), deferred))
 	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host2, map[uint]*bool{p2.ID: ptr.Bool(false)}, time.Now(), deferred))
+	require.NoError(t, ds.UpdatePolicyStats(context.Background()))
 
-	policies, err = ds.ListGlobalPolicies(context.Background())
+	policies, err = ds.ListGlobalPolicies(context.Background(), fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, policies, 2)
 
@@ -319,7 +334,7 @@ func testTeamPolicyLegacy(t *testing.T, ds *Datastore) {
 	})
 	require.NoError(t, err)
 
-	prevPolicies, err := ds.ListGlobalPolicies(context.Background())
+	prevPolicies, err := ds.ListGlobalPolicies(context.Background(), fleet.ListOptions{})
 	require.NoError(t, err)
 
 	_, err = ds.NewTeamPolicy(context.Background(), 99999999, &user1.ID, fleet.PolicyPayload{
@@ -342,7 +357,7 @@ func testTeamPolicyLegacy(t *testing.T, ds *Datastore) {
 	require.NotNil(t, p.Resolution)
 	assert.Equal(t, "some resolution", *p.Resolution)
 
-	globalPolicies, err := ds.ListGlobalPolicies(context.Background())
+	globalPolicies, err := ds.ListGlobalPolicies(context.Background(), fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, globalPolicies, len(prevPolicies))
 
@@ -357,7 +372,7 @@ func testTeamPolicyLegacy(t *testing.T, ds *Datastore) {
 	require.NotNil(t, p2.AuthorID)
 	assert.Equal(t, user1.ID, *p2.AuthorID)
 
-	teamPolicies, err := ds.ListTeamPolicies(context.Background(), team1.ID)
+	teamPolicies, err := ds.ListTeamPolicies(context.Background(), team1.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, teamPolicies, 1)
 	assert.Equal(t, q.Name, teamPolicies[0].Name)
@@ -366,7 +381,7 @@ func testTeamPolicyLegacy(t *testing.T, ds *Datastore) {
 	require.NotNil(t, teamPolicies[0].AuthorID)
 	require.Equal(t, user1.ID, *teamPolicies[0].AuthorID)
 
-	team2Policies, err := ds.ListTeamPolicies(context.Background(), team2.ID)
+	team2Policies, err := ds.ListTeamPolicies(context.Background(), team2.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, team2Policies, 1)
 	assert.Equal(t, q2.Name, team2Policies[0].Name)
@@ -378,7 +393,7 @@ func testTeamPolicyLegacy(t *testing.T, ds *Datastore) {
 	_, err = ds.DeleteTeamPolicies(context.Background(), team1.ID, []uint{teamPolicies[0].ID})
 	require.NoError(t, err)
 
-	teamPolicies, err = ds.ListTeamPolicies(context.Background(), team1.ID)
+	teamPolicies, err = ds.ListTeamPolicies(context.Background(), team1.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, teamPolicies, 0)
 }
@@ -399,7 +414,7 @@ func testTeamPolicyProprietary(t *testing.T, ds *Datastore) {
 	})
 	require.NoError(t, err)
 
-	prevPolicies, err := ds.ListGlobalPolicies(ctx)
+	prevPolicies, err := ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
 	require.NoError(t, err)
 
 	_, err = ds.NewTeamPolicy(ctx, 99999999, &user1.ID, fleet.PolicyPayload{
@@ -451,7 +466,7 @@ func testTeamPolicyProprietary(t *testing.T, ds *Datastore) {
 	require.NotNil(t, p.AuthorID)
 	assert.Equal(t, user1.ID, *p.AuthorID)
 
-	globalPolicies, err := ds.ListGlobalPolicies(ctx)
+	globalPolicies, err := ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, globalPolicies, len(prevPolicies))
 
@@ -471,7 +486,7 @@ func testTeamPolicyProprietary(t *testing.T, ds *Datastore) {
 	require.NotNil(t, p2.AuthorID)
 	assert.Equal(t, user1.ID, *p2.AuthorID)
 
-	teamPolicies, err := ds.ListTeamPolicies(ctx, team1.ID)
+	teamPolicies, err := ds.ListTeamPolicies(ctx, team1.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, teamPolicies, 1)
 	assert.Equal(t, "query1", teamPolicies[0].Name)
@@ -482,7 +497,7 @@ func testTeamPolicyProprietary(t *testing.T, ds *Datastore) {
 	require.NotNil(t, teamPolicies[0].AuthorID)
 	require.Equal(t, user1.ID, *teamPolicies[0].AuthorID)
 
-	team2Policies, err := ds.ListTeamPolicies(context.Background(), team2.ID)
+	team2Policies, err := ds.ListTeamPolicies(context.Background(), team2.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, team2Policies, 1)
 	assert.Equal(t, "query2", team2Policies[0].Name)
@@ -505,7 +520,7 @@ func testTeamPolicyProprietary(t *testing.T, ds *Datastore) {
 
 	_, err = ds.DeleteTeamPolicies(context.Background(), team1.ID, []uint{teamPolicies[0].ID})
 	require.NoError(t, err)
-	teamPolicies, err = ds.ListTeamPolicies(ctx, team1.ID)
+	teamPolicies, err = ds.ListTeamPolicies(ctx, team1.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, teamPolicies, 0)
 
@@ -517,7 +532,7 @@ func testTeamPolicyProprietary(t *testing.T, ds *Datastore) {
 		Resolution:  "query2 other resolution",
 	})
 	require.NoError(t, err)
-	teamPolicies, err = ds.ListTeamPolicies(ctx, team1.ID)
+	teamPolicies, err = ds.ListTeamPolicies(ctx, team1.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, teamPolicies, 1)
 	assert.Equal(t, "query1", teamPolicies[0].Name)
@@ -873,8 +888,10 @@ func testPolicyQueriesForHost(t *testing.T, ds *Datastore) {
 	checkGlobaPolicy(policies)
 
 	assert.Equal(t, "", policies[0].Response)
+	assert.Nil(t, policies[0].LastEvaluatedAt)
 
-	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host2, map[uint]*bool{gp.ID: ptr.Bool(true)}, time.Now(), false))
+	evaluatedAt := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host2, map[uint]*bool{gp.ID: ptr.Bool(true)}, evaluatedAt, false))
 
 	policies, err = ds.ListPoliciesForHost(context.Background(), host2)
 	require.NoError(t, err)
@@ -883,6 +900,8 @@ func testPolicyQueriesForHost(t *testing.T, ds *Datastore) {
 	checkGlobaPolicy(policies)
 
 	assert.Equal(t, "pass", policies[0].Response)
+	require.NotNil(t, policies[0].LastEvaluatedAt)
+	assert.Equal(t, evaluatedAt, policies[0].LastEvaluatedAt.UTC())
 
 	// Manually insert a global policy with null resolution.
 	res, err := ds.writer.ExecContext(context.Background(), `INSERT INTO policies (name, query, description) VALUES (?, ?, ?)`, q.Name+"2", q.Query, q.Description)
@@ -973,18 +992,19 @@ func testTeamPolicyTransfer(t *testing.T, ds *Datastore) {
 	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host1, map[uint]*bool{teamPolicy.ID: ptr.Bool(true), globalPolicy.ID: ptr.Bool(true)}, time.Now(), false))
 
 	checkPassingCount := func(expectedCount uint) {
-		policies, err := ds.ListTeamPolicies(context.Background(), team1.ID)
+		require.NoError(t, ds.UpdatePolicyStats(context.Background()))
+		policies, err := ds.ListTeamPolicies(context.Background(), team1.ID, fleet.ListOptions{})
 		require.NoError(t, err)
 		require.Len(t, policies, 1)
 
 		assert.Equal(t, expectedCount, policies[0].PassingHostCount)
 
-		policies, err = ds.ListGlobalPolicies(context.Background())
+		policies, err = ds.ListGlobalPolicies(context.Background(), fleet.ListOptions{})
 		require.NoError(t, err)
 		require.Len(t, policies, 1)
 		assert.Equal(t, uint(1), policies[0].PassingHostCount)
 
-		policies, err = ds.ListTeamPolicies(context.Background(), team2.ID)
+		policies, err = ds.ListTeamPolicies(context.Background(), team2.ID, fleet.ListOptions{})
 		require.NoError(t, err)
 		require.Len(t, policies, 0)
 	}
@@ -1029,7 +1049,7 @@ func testApplyPolicySpec(t *testing.T, ds *Datastore) {
 		},
 	}))
 
-	policies, err := ds.ListGlobalPolicies(ctx)
+	policies, err := ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, policies, 1)
 	assert.Equal(t, "query1", policies[0].Name)
@@ -1041,7 +1061,7 @@ func testApplyPolicySpec(t *testing.T, ds *Datastore) {
 	assert.Equal(t, "some resolution", *policies[0].Resolution)
 	assert.Equal(t, "", policies[0].Platform)
 
-	teamPolicies, err := ds.ListTeamPolicies(ctx, team1.ID)
+	teamPolicies, err := ds.ListTeamPolicies(ctx, team1.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, teamPolicies, 2)
 	assert.Equal(t, "query2", teamPolicies[0].Name)
@@ -1090,10 +1110,10 @@ func testApplyPolicySpec(t *testing.T, ds *Datastore) {
 		},
 	}))
 
-	policies, err = ds.ListGlobalPolicies(ctx)
+	policies, err = ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, policies, 1)
-	teamPolicies, err = ds.ListTeamPolicies(ctx, team1.ID)
+	teamPolicies, err = ds.ListTeamPolicies(ctx, team1.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, teamPolicies, 2)
 
@@ -1116,7 +1136,7 @@ func testApplyPolicySpec(t *testing.T, ds *Datastore) {
 			Platform:    "windows",
 		},
 	}))
-	policies, err = ds.ListGlobalPolicies(ctx)
+	policies, err = ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, policies, 1)
 
@@ -1129,7 +1149,7 @@ func testApplyPolicySpec(t *testing.T, ds *Datastore) {
 	assert.Equal(t, "some resolution updated", *policies[0].Resolution)
 	assert.Equal(t, "", policies[0].Platform)
 
-	teamPolicies, err = ds.ListTeamPolicies(ctx, team1.ID)
+	teamPolicies, err = ds.ListTeamPolicies(ctx, team1.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, teamPolicies, 2)
 
@@ -1501,11 +1521,11 @@ func testPolicyPlatformUpdate(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 
 	// load the global policies
-	gpols, err := ds.ListGlobalPolicies(ctx)
+	gpols, err := ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, gpols, 2)
 	// load the team policies
-	tpols, err := ds.ListTeamPolicies(ctx, tm.ID)
+	tpols, err := ds.ListTeamPolicies(ctx, tm.ID, fleet.ListOptions{})
 	require.NoError(t, err)
 	require.Len(t, tpols, 2)
 
@@ -1787,3 +1807,316 @@ func updatePolicyWithTimestamp(t *testing.T, ds *Datastore, p *fleet.Policy, ts
 	_, err := ds.writer.ExecContext(context.Background(), sql, p.Name, p.Query, p.Description, p.Resolution, p.Platform, ts, p.ID)
 	require.NoError(t, err)
 }
+
+func testCriticalPolicies(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	host, err := ds.NewHost(ctx, &fleet.Host{
+		OsqueryHostID:   "1234",
+		DetailUpdatedAt: time.Now(),
+		LabelUpdatedAt:  time.Now(),
+		PolicyUpdatedAt: time.Now(),
+		SeenTime:        time.Now(),
+		NodeKey:         "1",
+		UUID:            "1",
+		Hostname:        "foo.local",
+		Platform:        "darwin",
+	})
+	require.NoError(t, err)
+
+	p1, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{
+		Name:  "p1",
+		Query: "select 1;",
+	})
+	require.NoError(t, err)
+	assert.False(t, p1.Critical)
+	p2, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{
+		Name:     "p2",
+		Query:    "select 2;",
+		Critical: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, p2.Critical)
+	p3, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{
+		Name:     "p3",
+		Query:    "select 3;",
+		Platform: "windows",
+		Critical: true,
+	})
+	require.NoError(t, err)
+
+	// the critical policies are listed first
+	policies, err := ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, policies, 3)
+	assert.Equal(t, []uint{p2.ID, p3.ID, p1.ID}, []uint{policies[0].ID, policies[1].ID, policies[2].ID})
+
+	// only the critical policies of the host's platform are returned
+	queries, err := ds.CriticalPolicyQueriesForHost(ctx, host)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{fmt.Sprint(p2.ID): "select 2;"}, queries)
+
+	p1.Critical = true
+	require.NoError(t, ds.SavePolicy(ctx, p1))
+	queries, err = ds.CriticalPolicyQueriesForHost(ctx, host)
+	require.NoError(t, err)
+	assert.Len(t, queries, 2)
+
+	hostPolicies, err := ds.ListPoliciesForHost(ctx, host)
+	require.NoError(t, err)
+	require.Len(t, hostPolicies, 2)
+	assert.True(t, hostPolicies[0].Critical)
+	assert.True(t, hostPolicies[1].Critical)
+
+	// recording the critical policies updates only the critical timestamp
+	updated := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, ds.RecordCriticalPolicyQueryExecutions(ctx, host, map[uint]*bool{p1.ID: ptr.Bool(true), p2.ID: ptr.Bool(false)}, updated))
+	loaded, err := ds.Host(ctx, host.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, updated, loaded.CriticalPolicyUpdatedAt.UTC())
+	assert.Equal(t, host.PolicyUpdatedAt.UTC().Truncate(time.Second), loaded.PolicyUpdatedAt.UTC().Truncate(time.Second))
+
+	hostPolicies, err = ds.ListPoliciesForHost(ctx, host)
+	require.NoError(t, err)
+	require.Len(t, hostPolicies, 2)
+	assert.Equal(t, "pass", hostPolicies[0].Response)
+	assert.Equal(t, "fail", hostPolicies[1].Response)
+
+	// recording no results still updates the critical timestamp
+	updated = updated.Add(time.Hour)
+	require.NoError(t, ds.RecordCriticalPolicyQueryExecutions(ctx, host, nil, updated))
+	loaded, err = ds.Host(ctx, host.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, updated, loaded.CriticalPolicyUpdatedAt.UTC())
+
+	// the critical flag is applied with the specs
+	require.NoError(t, ds.ApplyPolicySpecs(ctx, user.ID, []*fleet.PolicySpec{
+		{Name: "p4", Query: "select 4;", Critical: true},
+		{Name: "p3", Query: "select 3;", Platform: "windows"},
+	}))
+	policies, err = ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, policies, 4)
+	var names []string
+	for _, p := range policies {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"p1", "p2", "p4", "p3"}, names)
+}
+
+func testPoliciesListOptions(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	var hosts []*fleet.Host
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("%s-%d", strings.ReplaceAll(t.Name(), "/", "_"), i)
+		h, err := ds.NewHost(ctx, &fleet.Host{
+			OsqueryHostID:   id,
+			DetailUpdatedAt: time.Now(),
+			LabelUpdatedAt:  time.Now(),
+			PolicyUpdatedAt: time.Now(),
+			SeenTime:        time.Now(),
+			NodeKey:         id,
+			UUID:            id,
+			Hostname:        id,
+		})
+		require.NoError(t, err)
+		hosts = append(hosts, h)
+	}
+
+	// the policies fail on as many hosts as their index
+	names := []string{"b firewall", "a disk encryption", "c firewall", "d screen lock"}
+	var policies []*fleet.Policy
+	for i, name := range names {
+		p, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{
+			Name:     name,
+			Query:    "select 1;",
+			Critical: name == "d screen lock",
+		})
+		require.NoError(t, err)
+		results := make(map[uint]*bool)
+		for j, h := range hosts {
+			results[p.ID] = ptr.Bool(j >= i)
+			require.NoError(t, ds.RecordPolicyQueryExecutions(ctx, h, results, time.Now(), false))
+		}
+		policies = append(policies, p)
+	}
+	_, err = ds.NewTeamPolicy(ctx, team.ID, &user.ID, fleet.PolicyPayload{Name: "team firewall", Query: "select 1;"})
+	require.NoError(t, err)
+	require.NoError(t, ds.UpdatePolicyStats(ctx))
+
+	listNames := func(opts fleet.ListOptions) []string {
+		list, err := ds.ListGlobalPolicies(ctx, opts)
+		require.NoError(t, err)
+		var got []string
+		for _, p := range list {
+			got = append(got, p.Name)
+		}
+		return got
+	}
+
+	// the critical policies are listed first
+	assert.Equal(t, []string{"d screen lock", "b firewall", "a disk encryption", "c firewall"}, listNames(fleet.ListOptions{}))
+	assert.Equal(t, []string{"d screen lock", "a disk encryption", "b firewall", "c firewall"}, listNames(fleet.ListOptions{OrderKey: "name"}))
+	assert.Equal(t, []string{"d screen lock", "c firewall", "a disk encryption", "b firewall"},
+		listNames(fleet.ListOptions{OrderKey: "failing_host_count", OrderDirection: fleet.OrderDescending}))
+	assert.Equal(t, []string{"d screen lock", "a disk encryption"}, listNames(fleet.ListOptions{OrderKey: "name", PerPage: 2}))
+	assert.Equal(t, []string{"b firewall", "c firewall"}, listNames(fleet.ListOptions{OrderKey: "name", PerPage: 2, Page: 1}))
+	assert.Equal(t, []string{"b firewall", "c firewall"}, listNames(fleet.ListOptions{MatchQuery: "firewall"}))
+	assert.Empty(t, listNames(fleet.ListOptions{MatchQuery: "nothing"}))
+
+	_, err = ds.ListGlobalPolicies(ctx, fleet.ListOptions{OrderKey: "query"})
+	require.Error(t, err)
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+
+	teamPolicies, err := ds.ListTeamPolicies(ctx, team.ID, fleet.ListOptions{MatchQuery: "firewall", OrderKey: "failing_host_count"})
+	require.NoError(t, err)
+	require.Len(t, teamPolicies, 1)
+	assert.Equal(t, "team firewall", teamPolicies[0].Name)
+}
+
+func testTeamPolicyExemptions(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	team2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team1.ID, []uint{host1.ID}))
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team2.ID, []uint{host2.ID}))
+	host1.TeamID = &team1.ID
+	host2.TeamID = &team2.ID
+
+	gp1, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "screen lock", Query: "select 1;"})
+	require.NoError(t, err)
+	gp2, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "disk encryption", Query: "select 2;"})
+	require.NoError(t, err)
+
+	for _, h := range []*fleet.Host{host1, host2} {
+		require.NoError(t, ds.RecordPolicyQueryExecutions(ctx, h, map[uint]*bool{gp1.ID: ptr.Bool(false), gp2.ID: ptr.Bool(true)}, time.Now(), false))
+	}
+
+	exemptions, err := ds.TeamPolicyExemptions(ctx, team1.ID)
+	require.NoError(t, err)
+	assert.Empty(t, exemptions)
+
+	// exempt team1 from the screen lock policy
+	require.NoError(t, ds.SetTeamPolicyExemptions(ctx, team1.ID, []uint{gp1.ID}))
+	exemptions, err = ds.TeamPolicyExemptions(ctx, team1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{gp1.ID}, exemptions)
+
+	queries, err := ds.PolicyQueriesForHost(ctx, host1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{fmt.Sprint(gp2.ID): "select 2;"}, queries)
+	queries, err = ds.PolicyQueriesForHost(ctx, host2)
+	require.NoError(t, err)
+	assert.Len(t, queries, 2)
+
+	hostPolicies, err := ds.ListPoliciesForHost(ctx, host1)
+	require.NoError(t, err)
+	require.Len(t, hostPolicies, 1)
+	assert.Equal(t, gp2.ID, hostPolicies[0].ID)
+
+	// the results of the exempted policy were deleted for the hosts of team1
+	h1, err := ds.Host(ctx, host1.ID, false)
+	require.NoError(t, err)
+	assert.Zero(t, h1.HostIssues.FailingPoliciesCount)
+	h2, err := ds.Host(ctx, host2.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, h2.HostIssues.FailingPoliciesCount)
+
+	// and for the hosts transferred to team1
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team1.ID, []uint{host2.ID}))
+	host2.TeamID = &team1.ID
+	h2, err = ds.Host(ctx, host2.ID, false)
+	require.NoError(t, err)
+	assert.Zero(t, h2.HostIssues.FailingPoliciesCount)
+	hostPolicies, err = ds.ListPoliciesForHost(ctx, host2)
+	require.NoError(t, err)
+	require.Len(t, hostPolicies, 1)
+
+	// the exemptions are deleted with the policy
+	_, err = ds.DeleteGlobalPolicies(ctx, []uint{gp1.ID})
+	require.NoError(t, err)
+	exemptions, err = ds.TeamPolicyExemptions(ctx, team1.ID)
+	require.NoError(t, err)
+	assert.Empty(t, exemptions)
+
+	require.NoError(t, ds.SetTeamPolicyExemptions(ctx, team1.ID, []uint{gp2.ID}))
+	queries, err = ds.PolicyQueriesForHost(ctx, host1)
+	require.NoError(t, err)
+	assert.Empty(t, queries)
+	require.NoError(t, ds.SetTeamPolicyExemptions(ctx, team1.ID, nil))
+	queries, err = ds.PolicyQueriesForHost(ctx, host1)
+	require.NoError(t, err)
+	assert.Len(t, queries, 1)
+}
+
+func testPolicyEvaluationWindows(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	p1, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "p1", Query: "select 1;"})
+	require.NoError(t, err)
+	// on weekend nights
+	p2, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{
+		Name:             "p2",
+		Query:            "select 2;",
+		EvaluationWindow: "* 1-4 * * 6,0",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "* 1-4 * * 6,0", p2.EvaluationWindow)
+
+	oldClock := ds.clock
+	defer func() { ds.clock = oldClock }()
+
+	// Saturday 2022-03-26 at 02:30 UTC
+	ds.clock = clock.NewMockClock(time.Date(2022, 3, 26, 2, 30, 0, 0, time.UTC))
+	queries, err := ds.PolicyQueriesForHost(ctx, host)
+	require.NoError(t, err)
+	assert.Len(t, queries, 2)
+
+	// Saturday 2022-03-26 at 12:00 UTC
+	ds.clock = clock.NewMockClock(time.Date(2022, 3, 26, 12, 0, 0, 0, time.UTC))
+	queries, err = ds.PolicyQueriesForHost(ctx, host)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{fmt.Sprint(p1.ID): "select 1;"}, queries)
+
+	// Monday 2022-03-28 at 02:30 UTC
+	ds.clock = clock.NewMockClock(time.Date(2022, 3, 28, 2, 30, 0, 0, time.UTC))
+	queries, err = ds.PolicyQueriesForHost(ctx, host)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{fmt.Sprint(p1.ID): "select 1;"}, queries)
+
+	// the window is removed
+	p2.EvaluationWindow = ""
+	require.NoError(t, ds.SavePolicy(ctx, p2))
+	queries, err = ds.PolicyQueriesForHost(ctx, host)
+	require.NoError(t, err)
+	assert.Len(t, queries, 2)
+
+	// and set with a spec
+	require.NoError(t, ds.ApplyPolicySpecs(ctx, user.ID, []*fleet.PolicySpec{
+		{Name: "p2", Query: "select 2;", EvaluationWindow: "0-29 * * * *"},
+	}))
+	p2, err = ds.Policy(ctx, p2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "0-29 * * * *", p2.EvaluationWindow)
+	queries, err = ds.PolicyQueriesForHost(ctx, host)
+	require.NoError(t, err)
+	assert.Len(t, queries, 2)
+	ds.clock = clock.NewMockClock(time.Date(2022, 3, 28, 2, 45, 0, 0, time.UTC))
+	queries, err = ds.PolicyQueriesForHost(ctx, host)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{fmt.Sprint(p1.ID): "select 1;"}, queries)
+}
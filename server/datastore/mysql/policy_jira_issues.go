@@ -0,0 +1,57 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewPolicyJiraIssue(ctx context.Context, policyID uint, issueKey string) error {
+	stmt := `
+		INSERT INTO policy_jira_issues (policy_id, issue_key)
+		VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE issue_key = VALUES(issue_key)
+	`
+	if _, err := ds.writer.ExecContext(ctx, stmt, policyID, issueKey); err != nil {
+		return ctxerr.Wrap(ctx, err, "insert policy jira issue")
+	}
+	return nil
+}
+
+const selectPolicyJiraIssuesStmt = `
+	SELECT
+		pji.policy_id,
+		pji.issue_key,
+		pji.created_at,
+		(SELECT COUNT(*) FROM policy_membership WHERE policy_id = pji.policy_id AND passes = false) AS failing_host_count
+	FROM policy_jira_issues pji
+`
+
+func (ds *Datastore) PolicyJiraIssue(ctx context.Context, policyID uint) (*fleet.PolicyJiraIssue, error) {
+	var issue fleet.PolicyJiraIssue
+	if err := sqlx.GetContext(ctx, ds.reader, &issue, selectPolicyJiraIssuesStmt+` WHERE pji.policy_id = ?`, policyID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("PolicyJiraIssue").WithID(policyID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "select policy jira issue")
+	}
+	return &issue, nil
+}
+
+func (ds *Datastore) ListPolicyJiraIssues(ctx context.Context) ([]*fleet.PolicyJiraIssue, error) {
+	var issues []*fleet.PolicyJiraIssue
+	if err := sqlx.SelectContext(ctx, ds.reader, &issues, selectPolicyJiraIssuesStmt+` ORDER BY pji.policy_id`); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list policy jira issues")
+	}
+	return issues, nil
+}
+
+func (ds *Datastore) DeletePolicyJiraIssue(ctx context.Context, policyID uint) error {
+	if _, err := ds.writer.ExecContext(ctx, `DELETE FROM policy_jira_issues WHERE policy_id = ?`, policyID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete policy jira issue")
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyJiraIssues(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Issues", testPolicyJiraIssues},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testPolicyJiraIssues(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	p1, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "p1", Query: "select 1;"})
+	require.NoError(t, err)
+	p2, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "p2", Query: "select 2;"})
+	require.NoError(t, err)
+
+	_, err = ds.PolicyJiraIssue(ctx, p1.ID)
+	require.True(t, fleet.IsNotFound(err))
+	issues, err := ds.ListPolicyJiraIssues(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+
+	host1 := test.NewHost(t, ds, "host1", "1.1.1.1", "1", "1", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "2.2.2.2", "2", "2", time.Now())
+	require.NoError(t, ds.RecordPolicyQueryExecutions(ctx, host1, map[uint]*bool{p1.ID: ptr.Bool(false), p2.ID: ptr.Bool(true)}, time.Now(), false))
+	require.NoError(t, ds.RecordPolicyQueryExecutions(ctx, host2, map[uint]*bool{p1.ID: ptr.Bool(false), p2.ID: ptr.Bool(true)}, time.Now(), false))
+
+	require.NoError(t, ds.NewPolicyJiraIssue(ctx, p1.ID, "FLEET-1"))
+	require.NoError(t, ds.NewPolicyJiraIssue(ctx, p2.ID, "FLEET-2"))
+
+	issue, err := ds.PolicyJiraIssue(ctx, p1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, p1.ID, issue.PolicyID)
+	assert.Equal(t, "FLEET-1", issue.IssueKey)
+	assert.Equal(t, uint(2), issue.FailingHostCount)
+	assert.False(t, issue.CreatedAt.IsZero())
+
+	// the policy is fixed on one of the hosts
+	require.NoError(t, ds.RecordPolicyQueryExecutions(ctx, host1, map[uint]*bool{p1.ID: ptr.Bool(true)}, time.Now(), false))
+	issues, err = ds.ListPolicyJiraIssues(ctx)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	assert.Equal(t, "FLEET-1", issues[0].IssueKey)
+	assert.Equal(t, uint(1), issues[0].FailingHostCount)
+	assert.Equal(t, "FLEET-2", issues[1].IssueKey)
+	assert.Zero(t, issues[1].FailingHostCount)
+
+	require.NoError(t, ds.DeletePolicyJiraIssue(ctx, p2.ID))
+	_, err = ds.PolicyJiraIssue(ctx, p2.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	// the issue is deleted with its policy
+	_, err = ds.DeleteGlobalPolicies(ctx, []uint{p1.ID})
+	require.NoError(t, err)
+	issues, err = ds.ListPolicyJiraIssues(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, issues)
+}
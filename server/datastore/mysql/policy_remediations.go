@@ -0,0 +1,152 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+const hostPolicyRemediationsSelect = `
+	SELECT
+		r.id, r.host_id, r.policy_id, r.author_id, r.script, r.status, r.exit_code, r.output, r.created_at, r.updated_at,
+		COALESCE((SELECT p.name FROM policies p WHERE p.id = r.policy_id), '') AS policy_name
+	FROM host_policy_remediations r`
+
+func (ds *Datastore) NewHostPolicyRemediation(ctx context.Context, hostID, policyID uint, authorID *uint) (*fleet.HostPolicyRemediation, error) {
+	var id uint
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var script sql.NullString
+		err := sqlx.GetContext(ctx, tx, &script, `SELECT remediation_script FROM policies WHERE id = ? FOR UPDATE`, policyID)
+		switch {
+		case err == sql.ErrNoRows:
+			return ctxerr.Wrap(ctx, notFound("Policy").WithID(policyID))
+		case err != nil:
+			return ctxerr.Wrap(ctx, err, "select policy remediation script")
+		}
+		if script.String == "" {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("policy_id", "the policy has no remediation script"))
+		}
+
+		err = sqlx.GetContext(ctx, tx, &id,
+			`SELECT id FROM host_policy_remediations WHERE host_id = ? AND policy_id = ? AND status = ?`,
+			hostID, policyID, fleet.PolicyRemediationPending,
+		)
+		switch {
+		case err == nil:
+			// a remediation is already pending
+			return nil
+		case err != sql.ErrNoRows:
+			return ctxerr.Wrap(ctx, err, "select pending host policy remediation")
+		}
+
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO host_policy_remediations (host_id, policy_id, author_id, script, status) VALUES (?, ?, ?, ?, ?)`,
+			hostID, policyID, authorID, script.String, fleet.PolicyRemediationPending,
+		)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "insert host policy remediation")
+		}
+		lastID, _ := res.LastInsertId()
+		id = uint(lastID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var remediation fleet.HostPolicyRemediation
+	if err := sqlx.GetContext(ctx, ds.writer, &remediation, hostPolicyRemediationsSelect+` WHERE r.id = ?`, id); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host policy remediation")
+	}
+	return &remediation, nil
+}
+
+func (ds *Datastore) QueueHostPolicyAutoRemediations(ctx context.Context, hostID uint, failingPolicyIDs []uint) error {
+	if len(failingPolicyIDs) == 0 {
+		return nil
+	}
+
+	// the policies start failing if the host has no result for them yet or if
+	// they were passing, as the new results are not recorded yet
+	stmt, args, err := sqlx.In(`
+		INSERT INTO host_policy_remediations (host_id, policy_id, script, status)
+		SELECT ?, p.id, p.remediation_script, ?
+		FROM policies p
+		LEFT JOIN policy_membership pm ON pm.policy_id = p.id AND pm.host_id = ?
+		WHERE p.id IN (?) AND p.auto_remediate = 1 AND p.remediation_script != ''
+		AND (pm.passes IS NULL OR pm.passes = 1)
+		AND NOT EXISTS (
+			SELECT 1 FROM host_policy_remediations r
+			WHERE r.host_id = ? AND r.policy_id = p.id AND r.status = ?
+		)`,
+		hostID, fleet.PolicyRemediationPending, hostID, failingPolicyIDs, hostID, fleet.PolicyRemediationPending,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build queue host policy auto remediations")
+	}
+	if _, err := ds.writer.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "queue host policy auto remediations")
+	}
+	return nil
+}
+
+var hostPolicyRemediationsOrderKeys = map[string]bool{
+	"id":         true,
+	"policy_id":  true,
+	"status":     true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+func (ds *Datastore) ListHostPolicyRemediations(ctx context.Context, hostID uint, opts fleet.ListOptions) ([]*fleet.HostPolicyRemediation, error) {
+	// most recent first unless otherwise requested
+	if opts.OrderKey == "" {
+		opts.OrderKey = "id"
+		opts.OrderDirection = fleet.OrderDescending
+	}
+	if !hostPolicyRemediationsOrderKeys[opts.OrderKey] {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("order_key", "unsupported order key: "+opts.OrderKey))
+	}
+	stmt := appendListOptionsToSQL(hostPolicyRemediationsSelect+` WHERE r.host_id = ?`, opts)
+
+	var remediations []*fleet.HostPolicyRemediation
+	if err := sqlx.SelectContext(ctx, ds.reader, &remediations, stmt, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host policy remediations")
+	}
+	return remediations, nil
+}
+
+func (ds *Datastore) PendingHostPolicyRemediations(ctx context.Context, hostID uint) ([]*fleet.HostPolicyRemediation, error) {
+	var remediations []*fleet.HostPolicyRemediation
+	err := sqlx.SelectContext(ctx, ds.writer, &remediations,
+		hostPolicyRemediationsSelect+` WHERE r.host_id = ? AND r.status = ? ORDER BY r.id`,
+		hostID, fleet.PolicyRemediationPending,
+	)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select pending host policy remediations")
+	}
+	return remediations, nil
+}
+
+func (ds *Datastore) SetHostPolicyRemediationResult(ctx context.Context, hostID, id uint, exitCode int, output string) error {
+	status := fleet.PolicyRemediationSucceeded
+	if exitCode != 0 {
+		status = fleet.PolicyRemediationFailed
+	}
+	res, err := ds.writer.ExecContext(ctx, `
+		UPDATE host_policy_remediations
+		SET status = ?, exit_code = ?, output = ?
+		WHERE id = ? AND host_id = ? AND status = ?`,
+		status, exitCode, output, id, hostID, fleet.PolicyRemediationPending,
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "update host policy remediation")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("HostPolicyRemediation").WithID(id))
+	}
+	return nil
+}
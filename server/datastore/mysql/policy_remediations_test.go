@@ -0,0 +1,187 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyRemediations(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Lifecycle", testPolicyRemediationsLifecycle},
+		{"AutoRemediations", testPolicyRemediationsAuto},
+		{"PolicyDeleted", testPolicyRemediationsPolicyDeleted},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testPolicyRemediationsLifecycle(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+
+	p1, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "p1", Query: "select 1;", RemediationScript: "echo fix"})
+	require.NoError(t, err)
+	require.NotNil(t, p1.RemediationScript)
+	assert.Equal(t, "echo fix", *p1.RemediationScript)
+	p2, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "p2", Query: "select 2;"})
+	require.NoError(t, err)
+
+	// the policy has no script
+	_, err = ds.NewHostPolicyRemediation(ctx, host1.ID, p2.ID, &user.ID)
+	var argErr *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &argErr)
+	_, err = ds.NewHostPolicyRemediation(ctx, host1.ID, 999, &user.ID)
+	require.True(t, fleet.IsNotFound(err))
+
+	r1, err := ds.NewHostPolicyRemediation(ctx, host1.ID, p1.ID, &user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, host1.ID, r1.HostID)
+	assert.Equal(t, p1.ID, r1.PolicyID)
+	assert.Equal(t, "p1", r1.PolicyName)
+	assert.Equal(t, "echo fix", r1.Script)
+	assert.Equal(t, fleet.PolicyRemediationPending, r1.Status)
+	require.NotNil(t, r1.AuthorID)
+	assert.Equal(t, user.ID, *r1.AuthorID)
+	assert.Nil(t, r1.ExitCode)
+	assert.Nil(t, r1.Output)
+
+	// the pending remediation is returned again
+	r, err := ds.NewHostPolicyRemediation(ctx, host1.ID, p1.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, r1.ID, r.ID)
+
+	// the script of the remediation does not change with the policy
+	p1.RemediationScript = ptr.String("echo fixed")
+	require.NoError(t, ds.SavePolicy(ctx, p1))
+
+	pending, err := ds.PendingHostPolicyRemediations(ctx, host1.ID)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "echo fix", pending[0].Script)
+	pending, err = ds.PendingHostPolicyRemediations(ctx, host2.ID)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// the results can only be set by the host of the remediation
+	err = ds.SetHostPolicyRemediationResult(ctx, host2.ID, r1.ID, 0, "done")
+	require.True(t, fleet.IsNotFound(err))
+	require.NoError(t, ds.SetHostPolicyRemediationResult(ctx, host1.ID, r1.ID, 1, "oops"))
+	// and only once
+	err = ds.SetHostPolicyRemediationResult(ctx, host1.ID, r1.ID, 0, "done")
+	require.True(t, fleet.IsNotFound(err))
+
+	pending, err = ds.PendingHostPolicyRemediations(ctx, host1.ID)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	r2, err := ds.NewHostPolicyRemediation(ctx, host1.ID, p1.ID, nil)
+	require.NoError(t, err)
+	assert.NotEqual(t, r1.ID, r2.ID)
+	assert.Equal(t, "echo fixed", r2.Script)
+	assert.Nil(t, r2.AuthorID)
+	require.NoError(t, ds.SetHostPolicyRemediationResult(ctx, host1.ID, r2.ID, 0, "fixed"))
+
+	remediations, err := ds.ListHostPolicyRemediations(ctx, host1.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, remediations, 2)
+	assert.Equal(t, r2.ID, remediations[0].ID)
+	assert.Equal(t, fleet.PolicyRemediationSucceeded, remediations[0].Status)
+	require.NotNil(t, remediations[0].ExitCode)
+	assert.Equal(t, 0, *remediations[0].ExitCode)
+	require.NotNil(t, remediations[0].Output)
+	assert.Equal(t, "fixed", *remediations[0].Output)
+	assert.Equal(t, r1.ID, remediations[1].ID)
+	assert.Equal(t, fleet.PolicyRemediationFailed, remediations[1].Status)
+	require.NotNil(t, remediations[1].ExitCode)
+	assert.Equal(t, 1, *remediations[1].ExitCode)
+
+	remediations, err = ds.ListHostPolicyRemediations(ctx, host1.ID, fleet.ListOptions{OrderKey: "id", PerPage: 1})
+	require.NoError(t, err)
+	require.Len(t, remediations, 1)
+	assert.Equal(t, r1.ID, remediations[0].ID)
+	_, err = ds.ListHostPolicyRemediations(ctx, host1.ID, fleet.ListOptions{OrderKey: "script"})
+	require.ErrorAs(t, err, &argErr)
+
+	// the remediations are deleted with the host
+	require.NoError(t, ds.DeleteHost(ctx, host1.ID))
+	remediations, err = ds.ListHostPolicyRemediations(ctx, host1.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, remediations)
+}
+
+func testPolicyRemediationsAuto(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	auto, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "auto", Query: "select 1;", RemediationScript: "echo auto", AutoRemediate: true})
+	require.NoError(t, err)
+	assert.True(t, auto.AutoRemediate)
+	manual, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "manual", Query: "select 2;", RemediationScript: "echo manual"})
+	require.NoError(t, err)
+
+	listPending := func() []uint {
+		pending, err := ds.PendingHostPolicyRemediations(ctx, host.ID)
+		require.NoError(t, err)
+		var ids []uint
+		for _, r := range pending {
+			assert.Nil(t, r.AuthorID)
+			ids = append(ids, r.PolicyID)
+		}
+		return ids
+	}
+
+	// the policies start failing
+	require.NoError(t, ds.QueueHostPolicyAutoRemediations(ctx, host.ID, []uint{auto.ID, manual.ID}))
+	require.NoError(t, ds.RecordPolicyQueryExecutions(ctx, host, map[uint]*bool{auto.ID: ptr.Bool(false), manual.ID: ptr.Bool(false)}, time.Now(), false))
+	assert.Equal(t, []uint{auto.ID}, listPending())
+
+	// still failing, the remediation is not queued again
+	require.NoError(t, ds.QueueHostPolicyAutoRemediations(ctx, host.ID, []uint{auto.ID, manual.ID}))
+	assert.Equal(t, []uint{auto.ID}, listPending())
+	pending, err := ds.PendingHostPolicyRemediations(ctx, host.ID)
+	require.NoError(t, err)
+	require.NoError(t, ds.SetHostPolicyRemediationResult(ctx, host.ID, pending[0].ID, 0, ""))
+	require.NoError(t, ds.QueueHostPolicyAutoRemediations(ctx, host.ID, []uint{auto.ID, manual.ID}))
+	assert.Empty(t, listPending())
+
+	// the policy passes and fails again
+	require.NoError(t, ds.RecordPolicyQueryExecutions(ctx, host, map[uint]*bool{auto.ID: ptr.Bool(true), manual.ID: ptr.Bool(true)}, time.Now(), false))
+	require.NoError(t, ds.QueueHostPolicyAutoRemediations(ctx, host.ID, []uint{auto.ID, manual.ID}))
+	assert.Equal(t, []uint{auto.ID}, listPending())
+}
+
+func testPolicyRemediationsPolicyDeleted(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	p, err := ds.NewGlobalPolicy(ctx, &user.ID, fleet.PolicyPayload{Name: "p1", Query: "select 1;", RemediationScript: "echo fix"})
+	require.NoError(t, err)
+	_, err = ds.NewHostPolicyRemediation(ctx, host.ID, p.ID, &user.ID)
+	require.NoError(t, err)
+
+	_, err = ds.DeleteGlobalPolicies(ctx, []uint{p.ID})
+	require.NoError(t, err)
+	pending, err := ds.PendingHostPolicyRemediations(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
@@ -44,7 +44,8 @@ func (ds *Datastore) ApplyQueries(ctx context.Context, authorID uint, queries []
 			query = VALUES(query),
 			author_id = VALUES(author_id),
 			saved = VALUES(saved),
-			observer_can_run = VALUES(observer_can_run)
+			observer_can_run = VALUES(observer_can_run),
+			version = version + 1
 	`
 	stmt, err := tx.PrepareContext(ctx, sql)
 	if err != nil {
@@ -97,10 +98,11 @@ func (ds *Datastore) NewQuery(ctx context.Context, query *fleet.Query, opts ...f
 			query,
 			saved,
 			author_id,
-			observer_can_run
-		) VALUES ( ?, ?, ?, ?, ?, ? )
+			observer_can_run,
+			team_id
+		) VALUES ( ?, ?, ?, ?, ?, ?, ? )
 	`
-	result, err := ds.writer.ExecContext(ctx, sqlStatement, query.Name, query.Description, query.Query, query.Saved, query.AuthorID, query.ObserverCanRun)
+	result, err := ds.writer.ExecContext(ctx, sqlStatement, query.Name, query.Description, query.Query, query.Saved, query.AuthorID, query.ObserverCanRun, query.TeamID)
 
 	if err != nil && isDuplicate(err) {
 		return nil, ctxerr.Wrap(ctx, alreadyExists("Query", query.Name))
@@ -110,18 +112,21 @@ func (ds *Datastore) NewQuery(ctx context.Context, query *fleet.Query, opts ...f
 
 	id, _ := result.LastInsertId()
 	query.ID = uint(id)
+	query.Version = 1
 	query.Packs = []fleet.Pack{}
 	return query, nil
 }
 
-// SaveQuery saves changes to a Query.
+// SaveQuery saves changes to a Query. The query is only saved if its version
+// in the database is still q.Version, otherwise a fleet.ConflictError is
+// returned. On success, q.Version is incremented.
 func (ds *Datastore) SaveQuery(ctx context.Context, q *fleet.Query) error {
 	sql := `
 		UPDATE queries
-			SET name = ?, description = ?, query = ?, author_id = ?, saved = ?, observer_can_run = ?
-			WHERE id = ?
+			SET name = ?, description = ?, query = ?, author_id = ?, saved = ?, observer_can_run = ?, version = version + 1
+			WHERE id = ? AND version = ?
 	`
-	result, err := ds.writer.ExecContext(ctx, sql, q.Name, q.Description, q.Query, q.AuthorID, q.Saved, q.ObserverCanRun, q.ID)
+	result, err := ds.writer.ExecContext(ctx, sql, q.Name, q.Description, q.Query, q.AuthorID, q.Saved, q.ObserverCanRun, q.ID, q.Version)
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "updating query")
 	}
@@ -130,8 +135,18 @@ func (ds *Datastore) SaveQuery(ctx context.Context, q *fleet.Query) error {
 		return ctxerr.Wrap(ctx, err, "rows affected updating query")
 	}
 	if rows == 0 {
-		return ctxerr.Wrap(ctx, notFound("Query").WithID(q.ID))
+		var exists bool
+		if err := sqlx.GetContext(ctx, ds.writer, &exists, `SELECT EXISTS(SELECT 1 FROM queries WHERE id = ?)`, q.ID); err != nil {
+			return ctxerr.Wrap(ctx, err, "check query existence")
+		}
+		if !exists {
+			return ctxerr.Wrap(ctx, notFound("Query").WithID(q.ID))
+		}
+		return ctxerr.Wrap(ctx, fleet.NewConflictError(
+			fmt.Sprintf("query %d was modified since version %d, reload it and retry", q.ID, q.Version),
+		))
 	}
+	q.Version++
 
 	return nil
 }
@@ -187,16 +202,28 @@ func (ds *Datastore) ListQueries(ctx context.Context, opt fleet.ListQueryOptions
 		FROM queries q
 		LEFT JOIN users u ON (q.author_id = u.id)
 		LEFT JOIN aggregated_stats ag ON (ag.id=q.id AND ag.type="query")
+		LEFT JOIN teams t ON (t.id = q.team_id)
 		WHERE saved = true
 	`
+	var params []interface{}
 	if opt.OnlyObserverCanRun {
 		sql += " AND q.observer_can_run=true"
 	}
+	if opt.TeamFilter != nil {
+		// users see the global queries and the queries of the teams they
+		// are a member of, whatever their role in the team.
+		teamFilter := fleet.TeamFilter{User: opt.TeamFilter.User, IncludeObserver: true}
+		sql += fmt.Sprintf(" AND (q.team_id IS NULL OR %s)", ds.whereFilterTeams(teamFilter, "t"))
+		if opt.TeamFilter.TeamID != nil {
+			sql += " AND q.team_id = ?"
+			params = append(params, *opt.TeamFilter.TeamID)
+		}
+	}
 	sql = appendListOptionsToSQL(sql, opt.ListOptions)
 
 	results := []*fleet.Query{}
 
-	if err := sqlx.SelectContext(ctx, ds.reader, &results, sql); err != nil {
+	if err := sqlx.SelectContext(ctx, ds.reader, &results, sql, params...); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "listing queries")
 	}
 
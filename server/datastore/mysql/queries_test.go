@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,6 +29,8 @@ func TestQueries(t *testing.T) {
 		{"LoadPacksForQueries", testQueriesLoadPacksForQueries},
 		{"DuplicateNew", testQueriesDuplicateNew},
 		{"ListFiltersObservers", testQueriesListFiltersObservers},
+		{"SaveVersionConflict", testQueriesSaveVersionConflict},
+		{"ListTeamFilter", testQueriesListTeamFilter},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -431,3 +434,89 @@ func testQueriesListFiltersObservers(t *testing.T, ds *Datastore) {
 	require.Len(t, queries, 1)
 	assert.Equal(t, query3.ID, queries[0].ID)
 }
+
+func testQueriesSaveVersionConflict(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	query, err := ds.NewQuery(ctx, &fleet.Query{Name: "foo", Query: "select 1;", Saved: true})
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), query.Version)
+
+	// two users load the same version of the query
+	first, err := ds.Query(ctx, query.ID)
+	require.NoError(t, err)
+	second, err := ds.Query(ctx, query.ID)
+	require.NoError(t, err)
+
+	first.Query = "select 2;"
+	require.NoError(t, ds.SaveQuery(ctx, first))
+	assert.Equal(t, uint(2), first.Version)
+
+	// the second modification is based on an outdated version
+	second.Query = "select 3;"
+	err = ds.SaveQuery(ctx, second)
+	require.Error(t, err)
+	var conflictErr *fleet.ConflictError
+	require.ErrorAs(t, err, &conflictErr)
+
+	got, err := ds.Query(ctx, query.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "select 2;", got.Query)
+	assert.Equal(t, uint(2), got.Version)
+
+	// applying queries also increments the version
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	require.NoError(t, ds.ApplyQueries(ctx, user.ID, []*fleet.Query{{Name: "foo", Query: "select 4;"}}))
+	got, err = ds.Query(ctx, query.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), got.Version)
+
+	err = ds.SaveQuery(ctx, &fleet.Query{ID: query.ID + 1000, Name: "bar", Query: "select 1;"})
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+}
+
+func testQueriesListTeamFilter(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	team2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	_, err = ds.NewQuery(ctx, &fleet.Query{Name: "global", Query: "select 1;", Saved: true})
+	require.NoError(t, err)
+	_, err = ds.NewQuery(ctx, &fleet.Query{Name: "team1", Query: "select 1;", Saved: true, TeamID: &team1.ID})
+	require.NoError(t, err)
+	_, err = ds.NewQuery(ctx, &fleet.Query{Name: "team2", Query: "select 1;", Saved: true, TeamID: &team2.ID})
+	require.NoError(t, err)
+
+	queryNames := func(filter *fleet.TeamFilter) []string {
+		queries, err := ds.ListQueries(ctx, fleet.ListQueryOptions{
+			ListOptions: fleet.ListOptions{OrderKey: "name"},
+			TeamFilter:  filter,
+		})
+		require.NoError(t, err)
+		var names []string
+		for _, q := range queries {
+			names = append(names, q.Name)
+		}
+		return names
+	}
+
+	globalObserver := &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}
+	team1Observer := &fleet.User{Teams: []fleet.UserTeam{{Team: *team1, Role: fleet.RoleObserver}}}
+	noRoles := &fleet.User{}
+
+	assert.Equal(t, []string{"global", "team1", "team2"}, queryNames(nil))
+	assert.Equal(t, []string{"global", "team1", "team2"}, queryNames(&fleet.TeamFilter{User: globalObserver}))
+	assert.Equal(t, []string{"global", "team1"}, queryNames(&fleet.TeamFilter{User: team1Observer}))
+	assert.Equal(t, []string{"global"}, queryNames(&fleet.TeamFilter{User: noRoles}))
+	assert.Equal(t, []string{"team2"}, queryNames(&fleet.TeamFilter{User: globalObserver, TeamID: &team2.ID}))
+	assert.Equal(t, []string{"team1"}, queryNames(&fleet.TeamFilter{User: team1Observer, TeamID: &team1.ID}))
+	assert.Empty(t, queryNames(&fleet.TeamFilter{User: team1Observer, TeamID: &team2.ID}))
+
+	// deleting a team deletes its queries
+	require.NoError(t, ds.DeleteTeam(ctx, team1.ID))
+	assert.Equal(t, []string{"global", "team2"}, queryNames(nil))
+}
@@ -0,0 +1,149 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// queryUsageDate returns the day of the usage recorded at now, in UTC.
+func queryUsageDate(now time.Time) string {
+	return now.UTC().Format("2006-01-02")
+}
+
+func (ds *Datastore) RecordLiveQueryCampaignUsage(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	// count the targeted hosts per team, in batches to stay under the max
+	// number of parameters
+	const batchSize = 50000
+	hostsPerTeam := make(map[uint]uint)
+	for len(hostIDs) > 0 {
+		batch := hostIDs
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		hostIDs = hostIDs[len(batch):]
+
+		stmt, args, err := sqlx.In(`
+			SELECT COALESCE(team_id, 0) AS team_id, COUNT(*) AS hosts_count
+			FROM hosts
+			WHERE id IN (?)
+			GROUP BY COALESCE(team_id, 0)`, batch,
+		)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build count targeted hosts query")
+		}
+		var counts []struct {
+			TeamID     uint `db:"team_id"`
+			HostsCount uint `db:"hosts_count"`
+		}
+		if err := sqlx.SelectContext(ctx, ds.reader, &counts, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "count targeted hosts per team")
+		}
+		for _, c := range counts {
+			hostsPerTeam[c.TeamID] += c.HostsCount
+		}
+	}
+	if len(hostsPerTeam) == 0 {
+		return nil
+	}
+
+	date := queryUsageDate(now)
+	args := make([]interface{}, 0, len(hostsPerTeam)*4)
+	for teamID, count := range hostsPerTeam {
+		args = append(args, userID, teamID, date, count)
+	}
+	stmt := `
+		INSERT INTO query_usage (user_id, team_id, date, campaigns_launched, hosts_targeted)
+		VALUES ` + strings.TrimSuffix(strings.Repeat(`(?, ?, ?, 1, ?),`, len(hostsPerTeam)), ",") + `
+		ON DUPLICATE KEY UPDATE
+			campaigns_launched = campaigns_launched + 1,
+			hosts_targeted = hosts_targeted + VALUES(hosts_targeted)`
+	if _, err := ds.writer.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "record live query campaign usage")
+	}
+	return nil
+}
+
+func (ds *Datastore) RecordLiveQueryRowsUsage(ctx context.Context, usage []fleet.QueryRowsUsage) error {
+	args := make([]interface{}, 0, len(usage)*4)
+	for _, u := range usage {
+		if u.Rows == 0 {
+			continue
+		}
+		args = append(args, u.UserID, u.TeamID, queryUsageDate(u.Date), u.Rows)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	stmt := `
+		INSERT INTO query_usage (user_id, team_id, date, rows_returned)
+		VALUES ` + strings.TrimSuffix(strings.Repeat(`(?, ?, ?, ?),`, len(args)/4), ",") + `
+		ON DUPLICATE KEY UPDATE rows_returned = rows_returned + VALUES(rows_returned)`
+	if _, err := ds.writer.ExecContext(ctx, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "record live query rows usage")
+	}
+	return nil
+}
+
+// queryUsageOrderKeys are the supported order keys of the query usage.
+var queryUsageOrderKeys = map[string]bool{
+	"user_id":            true,
+	"user_name":          true,
+	"team_id":            true,
+	"team_name":          true,
+	"campaigns_launched": true,
+	"hosts_targeted":     true,
+	"rows_returned":      true,
+}
+
+func (ds *Datastore) ListQueryUsage(ctx context.Context, opt fleet.QueryUsageOptions) ([]fleet.QueryUsage, error) {
+	stmt := `
+		SELECT
+			qu.user_id,
+			(SELECT u.name FROM users u WHERE u.id = qu.user_id) AS user_name,
+			(SELECT u.email FROM users u WHERE u.id = qu.user_id) AS user_email,
+			NULLIF(qu.team_id, 0) AS team_id,
+			(SELECT t.name FROM teams t WHERE t.id = qu.team_id) AS team_name,
+			SUM(qu.campaigns_launched) AS campaigns_launched,
+			SUM(qu.hosts_targeted) AS hosts_targeted,
+			SUM(qu.rows_returned) AS rows_returned
+		FROM query_usage qu
+		WHERE qu.date BETWEEN ? AND ?`
+	args := []interface{}{queryUsageDate(opt.StartDate), queryUsageDate(opt.EndDate)}
+	if opt.TeamID != nil {
+		stmt += ` AND qu.team_id = ?`
+		args = append(args, *opt.TeamID)
+	}
+	if opt.UserID != nil {
+		stmt += ` AND qu.user_id = ?`
+		args = append(args, *opt.UserID)
+	}
+	stmt += ` GROUP BY qu.user_id, qu.team_id`
+
+	// heaviest users first unless otherwise requested
+	if opt.OrderKey == "" {
+		opt.OrderKey = "rows_returned"
+		opt.OrderDirection = fleet.OrderDescending
+	}
+	if !queryUsageOrderKeys[opt.OrderKey] {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("order_key", "unsupported order key: "+opt.OrderKey))
+	}
+	// the cursor cannot be applied to the aggregated rows
+	opt.After = ""
+	stmt = appendListOptionsToSQL(stmt, opt.ListOptions)
+
+	usage := []fleet.QueryUsage{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &usage, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select query usage")
+	}
+	return usage, nil
+}
@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryUsage(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Record", testQueryUsageRecord},
+		{"UserDeleted", testQueryUsageUserDeleted},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testQueryUsageRecord(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	alice := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	bob := test.NewUser(t, ds, "Bob", "bob@example.com", true)
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	host3 := test.NewHost(t, ds, "host3", "", "host3key", "host3uuid", time.Now())
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{host2.ID, host3.ID}))
+
+	day1 := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+
+	// alice targets all the hosts twice, over two days
+	require.NoError(t, ds.RecordLiveQueryCampaignUsage(ctx, alice.ID, []uint{host1.ID, host2.ID, host3.ID}, day1))
+	require.NoError(t, ds.RecordLiveQueryCampaignUsage(ctx, alice.ID, []uint{host1.ID, host2.ID, host3.ID}, day2))
+	require.NoError(t, ds.RecordLiveQueryRowsUsage(ctx, []fleet.QueryRowsUsage{
+		{UserID: alice.ID, Date: day1, Rows: 10},
+		{UserID: alice.ID, TeamID: team.ID, Date: day1, Rows: 5},
+	}))
+	require.NoError(t, ds.RecordLiveQueryRowsUsage(ctx, []fleet.QueryRowsUsage{
+		{UserID: alice.ID, TeamID: team.ID, Date: day2, Rows: 7},
+	}))
+	// bob only targets a host of the team
+	require.NoError(t, ds.RecordLiveQueryCampaignUsage(ctx, bob.ID, []uint{host2.ID}, day1))
+	require.NoError(t, ds.RecordLiveQueryRowsUsage(ctx, []fleet.QueryRowsUsage{{UserID: bob.ID, TeamID: team.ID, Date: day1, Rows: 1}}))
+	// nothing is recorded without hosts or rows
+	require.NoError(t, ds.RecordLiveQueryCampaignUsage(ctx, bob.ID, nil, day1))
+	require.NoError(t, ds.RecordLiveQueryRowsUsage(ctx, nil))
+	require.NoError(t, ds.RecordLiveQueryRowsUsage(ctx, []fleet.QueryRowsUsage{{UserID: bob.ID, Date: day1}}))
+
+	usage, err := ds.ListQueryUsage(ctx, fleet.QueryUsageOptions{StartDate: day1, EndDate: day2})
+	require.NoError(t, err)
+	require.Len(t, usage, 3)
+	assert.Equal(t, fleet.QueryUsage{
+		UserID: alice.ID, UserName: ptr.String("Alice"), UserEmail: ptr.String("alice@example.com"),
+		TeamID: ptr.Uint(team.ID), TeamName: ptr.String("team1"),
+		CampaignsLaunched: 2, HostsTargeted: 4, RowsReturned: 12,
+	}, usage[0])
+	assert.Equal(t, fleet.QueryUsage{
+		UserID: alice.ID, UserName: ptr.String("Alice"), UserEmail: ptr.String("alice@example.com"),
+		CampaignsLaunched: 2, HostsTargeted: 2, RowsReturned: 10,
+	}, usage[1])
+	assert.Equal(t, fleet.QueryUsage{
+		UserID: bob.ID, UserName: ptr.String("Bob"), UserEmail: ptr.String("bob@example.com"),
+		TeamID: ptr.Uint(team.ID), TeamName: ptr.String("team1"),
+		CampaignsLaunched: 1, HostsTargeted: 1, RowsReturned: 1,
+	}, usage[2])
+
+	// only the usage of the days in range is summed
+	usage, err = ds.ListQueryUsage(ctx, fleet.QueryUsageOptions{StartDate: day2, EndDate: day2})
+	require.NoError(t, err)
+	require.Len(t, usage, 2)
+	assert.Equal(t, uint64(7), usage[0].RowsReturned)
+	assert.Equal(t, uint(1), usage[0].CampaignsLaunched)
+	assert.Equal(t, uint64(0), usage[1].RowsReturned)
+
+	// filter by team, 0 for the hosts without a team
+	usage, err = ds.ListQueryUsage(ctx, fleet.QueryUsageOptions{StartDate: day1, EndDate: day2, TeamID: ptr.Uint(0)})
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Nil(t, usage[0].TeamID)
+	assert.Equal(t, alice.ID, usage[0].UserID)
+
+	// filter by user
+	usage, err = ds.ListQueryUsage(ctx, fleet.QueryUsageOptions{StartDate: day1, EndDate: day2, UserID: &bob.ID})
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, bob.ID, usage[0].UserID)
+
+	usage, err = ds.ListQueryUsage(ctx, fleet.QueryUsageOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "hosts_targeted"},
+		StartDate:   day1,
+		EndDate:     day2,
+	})
+	require.NoError(t, err)
+	require.Len(t, usage, 3)
+	assert.Equal(t, bob.ID, usage[0].UserID)
+
+	_, err = ds.ListQueryUsage(ctx, fleet.QueryUsageOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "date"},
+		StartDate:   day1,
+		EndDate:     day2,
+	})
+	require.Error(t, err)
+}
+
+func testQueryUsageUserDeleted(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	now := time.Now()
+
+	require.NoError(t, ds.RecordLiveQueryRowsUsage(ctx, []fleet.QueryRowsUsage{{UserID: user.ID, Date: now, Rows: 3}}))
+	require.NoError(t, ds.DeleteUser(ctx, user.ID))
+
+	usage, err := ds.ListQueryUsage(ctx, fleet.QueryUsageOptions{StartDate: now, EndDate: now})
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, user.ID, usage[0].UserID)
+	assert.Nil(t, usage[0].UserName)
+	assert.Nil(t, usage[0].UserEmail)
+	assert.Equal(t, uint64(3), usage[0].RowsReturned)
+}
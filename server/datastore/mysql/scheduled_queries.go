@@ -26,6 +26,7 @@ func (ds *Datastore) ListScheduledQueriesInPackWithStats(ctx context.Context, id
 			sq.version,
 			sq.shard,
 			sq.denylist,
+			sq.discard_data,
 			q.query,
 			q.id AS query_id,
 			JSON_EXTRACT(ag.json_value, "$.user_time_p50") as user_time_p50,
@@ -44,6 +45,9 @@ func (ds *Datastore) ListScheduledQueriesInPackWithStats(ctx context.Context, id
 	if err := sqlx.SelectContext(ctx, ds.reader, &results, query, id); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "listing scheduled queries")
 	}
+	if err := loadScheduledQueriesLabelIDsDB(ctx, ds.reader, results); err != nil {
+		return nil, err
+	}
 
 	return results, nil
 }
@@ -64,6 +68,7 @@ func (ds *Datastore) ListScheduledQueriesInPack(ctx context.Context, id uint) ([
 			sq.version,
 			sq.shard,
 			sq.denylist,
+			sq.discard_data,
 			q.query,
 			q.id AS query_id
 		FROM scheduled_queries sq
@@ -74,12 +79,45 @@ func (ds *Datastore) ListScheduledQueriesInPack(ctx context.Context, id uint) ([
 	if err := sqlx.SelectContext(ctx, ds.reader, &results, query, id); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "listing scheduled queries")
 	}
+	if err := loadScheduledQueriesLabelIDsDB(ctx, ds.reader, results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
 
+// ListDiscardDataScheduledQueryNames lists the pack and scheduled query names
+// of the scheduled queries whose result logs must be discarded.
+func (ds *Datastore) ListDiscardDataScheduledQueryNames(ctx context.Context) ([]fleet.PackScheduledQueryName, error) {
+	query := `
+		SELECT
+			p.name AS pack_name,
+			sq.name AS scheduled_query_name
+		FROM scheduled_queries sq
+		JOIN packs p ON (sq.pack_id = p.id)
+		WHERE sq.discard_data = 1
+	`
+	var results []fleet.PackScheduledQueryName
+	if err := sqlx.SelectContext(ctx, ds.reader, &results, query); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing discard data scheduled queries")
+	}
 	return results, nil
 }
 
 func (ds *Datastore) NewScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery, opts ...fleet.OptionalArg) (*fleet.ScheduledQuery, error) {
-	return insertScheduledQueryDB(ctx, ds.writer, sq)
+	var res *fleet.ScheduledQuery
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var err error
+		res, err = insertScheduledQueryDB(ctx, tx, sq)
+		if err != nil {
+			return err
+		}
+		return setScheduledQueryLabelsDB(ctx, tx, res.ID, res.LabelIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
 }
 
 func insertScheduledQueryDB(ctx context.Context, q sqlx.ExtContext, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
@@ -97,13 +135,14 @@ func insertScheduledQueryDB(ctx context.Context, q sqlx.ExtContext, sq *fleet.Sc
 			platform,
 			version,
 			shard,
-			denylist
+			denylist,
+			discard_data
 		)
-		SELECT name, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+		SELECT name, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
 		FROM queries
 		WHERE id = ?
 		`
-	result, err := q.ExecContext(ctx, query, sq.QueryID, sq.Name, sq.PackID, sq.Snapshot, sq.Removed, sq.Interval, sq.Platform, sq.Version, sq.Shard, sq.Denylist, sq.QueryID)
+	result, err := q.ExecContext(ctx, query, sq.QueryID, sq.Name, sq.PackID, sq.Snapshot, sq.Removed, sq.Interval, sq.Platform, sq.Version, sq.Shard, sq.Denylist, sq.DiscardData, sq.QueryID)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "insert scheduled query")
 	}
@@ -135,16 +174,28 @@ func insertScheduledQueryDB(ctx context.Context, q sqlx.ExtContext, sq *fleet.Sc
 }
 
 func (ds *Datastore) SaveScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
-	return saveScheduledQueryDB(ctx, ds.writer, sq)
+	var res *fleet.ScheduledQuery
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var err error
+		res, err = saveScheduledQueryDB(ctx, tx, sq)
+		if err != nil {
+			return err
+		}
+		return setScheduledQueryLabelsDB(ctx, tx, res.ID, res.LabelIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
 }
 
 func saveScheduledQueryDB(ctx context.Context, exec sqlx.ExecerContext, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
 	query := `
 		UPDATE scheduled_queries
-			SET pack_id = ?, query_id = ?, ` + "`interval`" + ` = ?, snapshot = ?, removed = ?, platform = ?, version = ?, shard = ?, denylist = ?
+			SET pack_id = ?, query_id = ?, ` + "`interval`" + ` = ?, snapshot = ?, removed = ?, platform = ?, version = ?, shard = ?, denylist = ?, discard_data = ?
 			WHERE id = ?
 	`
-	result, err := exec.ExecContext(ctx, query, sq.PackID, sq.QueryID, sq.Interval, sq.Snapshot, sq.Removed, sq.Platform, sq.Version, sq.Shard, sq.Denylist, sq.ID)
+	result, err := exec.ExecContext(ctx, query, sq.PackID, sq.QueryID, sq.Interval, sq.Snapshot, sq.Removed, sq.Platform, sq.Version, sq.Shard, sq.Denylist, sq.DiscardData, sq.ID)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "saving a scheduled query")
 	}
@@ -195,6 +246,7 @@ func (ds *Datastore) ScheduledQuery(ctx context.Context, id uint) (*fleet.Schedu
 			sq.query_name,
 			sq.description,
 			sq.denylist,
+			sq.discard_data,
 			q.query,
 			q.name,
 			q.id AS query_id
@@ -210,6 +262,65 @@ func (ds *Datastore) ScheduledQuery(ctx context.Context, id uint) (*fleet.Schedu
 		}
 		return nil, ctxerr.Wrap(ctx, err, "select scheduled query")
 	}
+	if err := loadScheduledQueriesLabelIDsDB(ctx, ds.reader, []*fleet.ScheduledQuery{sq}); err != nil {
+		return nil, err
+	}
 
 	return sq, nil
 }
+
+// setScheduledQueryLabelsDB replaces the labels the scheduled query is
+// restricted to.
+func setScheduledQueryLabelsDB(ctx context.Context, tx sqlx.ExtContext, scheduledQueryID uint, labelIDs []uint) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scheduled_query_labels WHERE scheduled_query_id = ?`, scheduledQueryID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete scheduled query labels")
+	}
+	for _, labelID := range labelIDs {
+		_, err := tx.ExecContext(ctx, `INSERT INTO scheduled_query_labels (scheduled_query_id, label_id) VALUES (?, ?) ON DUPLICATE KEY UPDATE label_id = label_id`, scheduledQueryID, labelID)
+		switch {
+		case isChildForeignKeyError(err):
+			return ctxerr.Wrap(ctx, notFound("Label").WithID(labelID))
+		case err != nil:
+			return ctxerr.Wrap(ctx, err, "insert scheduled query label")
+		}
+	}
+	return nil
+}
+
+// loadScheduledQueriesLabelIDsDB loads the labels the scheduled queries are
+// restricted to.
+func loadScheduledQueriesLabelIDsDB(ctx context.Context, q sqlx.QueryerContext, scheduledQueries []*fleet.ScheduledQuery) error {
+	if len(scheduledQueries) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint]*fleet.ScheduledQuery, len(scheduledQueries))
+	ids := make([]uint, 0, len(scheduledQueries))
+	for _, sq := range scheduledQueries {
+		byID[sq.ID] = sq
+		ids = append(ids, sq.ID)
+	}
+
+	query, args, err := sqlx.In(`
+		SELECT scheduled_query_id, label_id
+		FROM scheduled_query_labels
+		WHERE scheduled_query_id IN (?)
+		ORDER BY scheduled_query_id, label_id
+	`, ids)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build scheduled query labels query")
+	}
+	var rows []struct {
+		ScheduledQueryID uint `db:"scheduled_query_id"`
+		LabelID          uint `db:"label_id"`
+	}
+	if err := sqlx.SelectContext(ctx, q, &rows, query, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "select scheduled query labels")
+	}
+	for _, row := range rows {
+		if sq := byID[row.ScheduledQueryID]; sq != nil {
+			sq.LabelIDs = append(sq.LabelIDs, row.LabelID)
+		}
+	}
+	return nil
+}
@@ -25,6 +25,8 @@ func TestScheduledQueries(t *testing.T) {
 		{"Get", testScheduledQueriesGet},
 		{"Delete", testScheduledQueriesDelete},
 		{"CascadingDelete", testScheduledQueriesCascadingDelete},
+		{"DiscardData", testScheduledQueriesDiscardData},
+		{"Labels", testScheduledQueriesLabels},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -365,3 +367,129 @@ func testScheduledQueriesCascadingDelete(t *testing.T, ds *Datastore) {
 	require.Nil(t, err)
 	require.Len(t, gotQueries, 1)
 }
+
+func testScheduledQueriesDiscardData(t *testing.T, ds *Datastore) {
+	u1 := test.NewUser(t, ds, "Admin", "admin@fleet.co", true)
+	q1 := test.NewQuery(t, ds, "foo", "select * from time;", u1.ID, true)
+	p1 := test.NewPack(t, ds, "baz")
+	sq1 := test.NewScheduledQuery(t, ds, p1.ID, q1.ID, 60, false, false, "sq1")
+
+	names, err := ds.ListDiscardDataScheduledQueryNames(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	sq2, err := ds.NewScheduledQuery(context.Background(), &fleet.ScheduledQuery{
+		PackID:      p1.ID,
+		QueryID:     q1.ID,
+		Name:        "sq2",
+		Interval:    60,
+		DiscardData: true,
+	})
+	require.NoError(t, err)
+
+	query, err := ds.ScheduledQuery(context.Background(), sq2.ID)
+	require.NoError(t, err)
+	assert.True(t, query.DiscardData)
+
+	names, err = ds.ListDiscardDataScheduledQueryNames(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.PackScheduledQueryName{{PackName: "baz", ScheduledQueryName: "sq2"}}, names)
+
+	query, err = ds.ScheduledQuery(context.Background(), sq1.ID)
+	require.NoError(t, err)
+	assert.False(t, query.DiscardData)
+	query.DiscardData = true
+	_, err = ds.SaveScheduledQuery(context.Background(), query)
+	require.NoError(t, err)
+
+	names, err = ds.ListDiscardDataScheduledQueryNames(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []fleet.PackScheduledQueryName{
+		{PackName: "baz", ScheduledQueryName: "sq1"},
+		{PackName: "baz", ScheduledQueryName: "sq2"},
+	}, names)
+}
+
+func testScheduledQueriesLabels(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	u1 := test.NewUser(t, ds, "Admin", "admin@fleet.co", true)
+	q1 := test.NewQuery(t, ds, "foo", "select * from time;", u1.ID, true)
+	p1 := test.NewPack(t, ds, "baz")
+	l1, err := ds.NewLabel(ctx, &fleet.Label{Name: "label1", Query: "select 1"})
+	require.NoError(t, err)
+	l2, err := ds.NewLabel(ctx, &fleet.Label{Name: "label2", Query: "select 1"})
+	require.NoError(t, err)
+
+	sq1, err := ds.NewScheduledQuery(ctx, &fleet.ScheduledQuery{
+		PackID:   p1.ID,
+		QueryID:  q1.ID,
+		Name:     "sq1",
+		Interval: 60,
+		LabelIDs: []uint{l1.ID, l2.ID},
+	})
+	require.NoError(t, err)
+	sq2 := test.NewScheduledQuery(t, ds, p1.ID, q1.ID, 60, false, false, "sq2")
+
+	query, err := ds.ScheduledQuery(ctx, sq1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{l1.ID, l2.ID}, query.LabelIDs)
+
+	queries, err := ds.ListScheduledQueriesInPack(ctx, p1.ID)
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	sort.Slice(queries, func(i, j int) bool { return queries[i].ID < queries[j].ID })
+	assert.Equal(t, []uint{l1.ID, l2.ID}, queries[0].LabelIDs)
+	assert.Empty(t, queries[1].LabelIDs)
+
+	// the labels are replaced when saved
+	query.LabelIDs = []uint{l2.ID}
+	_, err = ds.SaveScheduledQuery(ctx, query)
+	require.NoError(t, err)
+	queries, err = ds.ListScheduledQueriesInPackWithStats(ctx, p1.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	sort.Slice(queries, func(i, j int) bool { return queries[i].ID < queries[j].ID })
+	assert.Equal(t, []uint{l2.ID}, queries[0].LabelIDs)
+
+	// unknown labels are rejected
+	query, err = ds.ScheduledQuery(ctx, sq2.ID)
+	require.NoError(t, err)
+	query.LabelIDs = []uint{l1.ID, l2.ID + 1000}
+	_, err = ds.SaveScheduledQuery(ctx, query)
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+	query, err = ds.ScheduledQuery(ctx, sq2.ID)
+	require.NoError(t, err)
+	assert.Empty(t, query.LabelIDs)
+
+	// the labels are removed with the label
+	require.NoError(t, ds.DeleteLabel(ctx, l2.Name))
+	query, err = ds.ScheduledQuery(ctx, sq1.ID)
+	require.NoError(t, err)
+	assert.Empty(t, query.LabelIDs)
+
+	// pack specs reference the labels by name
+	err = ds.ApplyPackSpecs(ctx, []*fleet.PackSpec{{
+		Name: "spec",
+		Queries: []fleet.PackSpecQuery{
+			{QueryName: q1.Name, Name: "labeled", Interval: 60, Labels: []string{l1.Name}},
+			{QueryName: q1.Name, Name: "unlabeled", Interval: 60},
+		},
+	}})
+	require.NoError(t, err)
+	spec, err := ds.GetPackSpec(ctx, "spec")
+	require.NoError(t, err)
+	require.Len(t, spec.Queries, 2)
+	sort.Slice(spec.Queries, func(i, j int) bool { return spec.Queries[i].Name < spec.Queries[j].Name })
+	assert.Equal(t, []string{l1.Name}, spec.Queries[0].Labels)
+	assert.Empty(t, spec.Queries[1].Labels)
+
+	err = ds.ApplyPackSpecs(ctx, []*fleet.PackSpec{{
+		Name: "spec",
+		Queries: []fleet.PackSpecQuery{
+			{QueryName: q1.Name, Name: "labeled", Interval: 60, Labels: []string{"unknown"}},
+		},
+	}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown label 'unknown'")
+}
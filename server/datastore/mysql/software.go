@@ -284,6 +284,10 @@ func selectSoftwareSQL(hostID *uint, opts fleet.SoftwareListOptions) (string, []
 		ds = ds.Where(goqu.I("hs.host_id").Eq(hostID))
 	}
 
+	if opts.Source != "" {
+		ds = ds.Where(goqu.I("s.source").Eq(opts.Source))
+	}
+
 	if opts.TeamID != nil {
 		ds = ds.Join(
 			goqu.I("hosts").As("h"),
@@ -547,6 +551,10 @@ func (ds *Datastore) CountSoftware(ctx context.Context, opt fleet.SoftwareListOp
 	return countSoftwareDB(ctx, ds.reader, nil, opt)
 }
 
+func (ds *Datastore) ListHostSoftware(ctx context.Context, hostID uint, opt fleet.SoftwareListOptions) ([]fleet.Software, error) {
+	return listSoftwareDB(ctx, ds.reader, &hostID, opt)
+}
+
 // ListVulnerableSoftwareBySource lists all the vulnerable software that matches the given source.
 func (ds *Datastore) ListVulnerableSoftwareBySource(ctx context.Context, source string) ([]fleet.SoftwareWithCPE, error) {
 	var softwareCVEs []struct {
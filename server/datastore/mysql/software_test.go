@@ -33,6 +33,7 @@ func TestSoftware(t *testing.T) {
 		{"NothingChanged", testSoftwareNothingChanged},
 		{"LoadSupportsTonsOfCVEs", testSoftwareLoadSupportsTonsOfCVEs},
 		{"List", testSoftwareList},
+		{"ListHost", testSoftwareListHost},
 		{"CalculateHostsPerSoftware", testSoftwareCalculateHostsPerSoftware},
 		{"ListVulnerableSoftwareBySource", testListVulnerableSoftwareBySource},
 		{"DeleteVulnerabilitiesByCPECVE", testDeleteVulnerabilitiesByCPECVE},
@@ -555,6 +556,50 @@ func listSoftwareCheckCount(t *testing.T, ds *Datastore, expectedListCount int,
 	return software
 }
 
+func testSoftwareListHost(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+
+	require.NoError(t, ds.UpdateHostSoftware(ctx, host1.ID, []fleet.Software{
+		{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"},
+		{Name: "bar", Version: "0.0.3", Source: "deb_packages"},
+		{Name: "baz", Version: "1.0", Source: "python_packages"},
+	}))
+	require.NoError(t, ds.UpdateHostSoftware(ctx, host2.ID, []fleet.Software{
+		{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"},
+		{Name: "qux", Version: "2.0", Source: "deb_packages"},
+	}))
+
+	names := func(software []fleet.Software) []string {
+		var names []string
+		for _, s := range software {
+			names = append(names, s.Name)
+		}
+		return names
+	}
+
+	software, err := ds.ListHostSoftware(ctx, host1.ID, fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{OrderKey: "name"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "baz", "foo"}, names(software))
+
+	software, err = ds.ListHostSoftware(ctx, host2.ID, fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{OrderKey: "name", PerPage: 1, Page: 1}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"qux"}, names(software))
+
+	software, err = ds.ListHostSoftware(ctx, host1.ID, fleet.SoftwareListOptions{Source: "deb_packages"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar"}, names(software))
+
+	software, err = ds.ListHostSoftware(ctx, host1.ID, fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{MatchQuery: "ba", OrderKey: "name"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "baz"}, names(software))
+
+	software, err = ds.ListHostSoftware(ctx, 999, fleet.SoftwareListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, software)
+}
+
 func testSoftwareCalculateHostsPerSoftware(t *testing.T, ds *Datastore) {
 	ctx := context.Background()
 
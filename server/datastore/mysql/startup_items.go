@@ -0,0 +1,184 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+type startupItemKey struct {
+	source string
+	name   string
+}
+
+// ReplaceHostStartupItems replaces the startup items of the host with the
+// provided items and records the differences with the previous collection as
+// changes. Nothing is recorded on the first collection of a host, which
+// serves as the baseline.
+func (ds *Datastore) ReplaceHostStartupItems(ctx context.Context, hostID uint, items []fleet.HostStartupItem) error {
+	const (
+		selStmt    = `SELECT source, name, path, sha256 FROM host_startup_items WHERE host_id = ?`
+		delStmt    = `DELETE FROM host_startup_items WHERE host_id = ? AND source = ? AND name = ?`
+		updStmt    = `UPDATE host_startup_items SET path = ?, sha256 = ? WHERE host_id = ? AND source = ? AND name = ?`
+		insStmt    = `INSERT INTO host_startup_items (host_id, source, name, path, sha256) VALUES`
+		insPart    = ` (?, ?, ?, ?, ?),`
+		chgStmt    = `INSERT INTO host_startup_item_changes (host_id, change_type, source, name, path, previous_path) VALUES`
+		chgInsPart = ` (?, ?, ?, ?, ?, ?),`
+	)
+
+	current := make(map[startupItemKey]fleet.HostStartupItem, len(items))
+	for _, item := range items {
+		current[startupItemKey{item.Source, item.Name}] = item
+	}
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		var prevItems []fleet.HostStartupItem
+		if err := sqlx.SelectContext(ctx, tx, &prevItems, selStmt, hostID); err != nil {
+			return ctxerr.Wrap(ctx, err, "select previous host startup items")
+		}
+
+		toIns := make(map[startupItemKey]fleet.HostStartupItem, len(current))
+		for k, item := range current {
+			toIns[k] = item
+		}
+
+		var changes []fleet.StartupItemChange
+		for _, prev := range prevItems {
+			k := startupItemKey{prev.Source, prev.Name}
+			item, ok := toIns[k]
+			if !ok {
+				if _, err := tx.ExecContext(ctx, delStmt, hostID, prev.Source, prev.Name); err != nil {
+					return ctxerr.Wrap(ctx, err, "delete host startup item")
+				}
+				changes = append(changes, fleet.StartupItemChange{
+					ChangeType:      fleet.StartupItemChangeRemoved,
+					HostStartupItem: prev,
+				})
+				continue
+			}
+
+			delete(toIns, k)
+			if item.Path == prev.Path && item.SHA256 == prev.SHA256 {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, updStmt, item.Path, item.SHA256, hostID, item.Source, item.Name); err != nil {
+				return ctxerr.Wrap(ctx, err, "update host startup item")
+			}
+			// only the changes of path are recorded, the hash changes with
+			// the updates of the executable
+			if item.Path != prev.Path {
+				changes = append(changes, fleet.StartupItemChange{
+					ChangeType:      fleet.StartupItemChangeModified,
+					HostStartupItem: item,
+					PreviousPath:    prev.Path,
+				})
+			}
+		}
+
+		if len(toIns) > 0 {
+			args := make([]interface{}, 0, len(toIns)*5)
+			for _, item := range toIns {
+				args = append(args, hostID, item.Source, item.Name, item.Path, item.SHA256)
+				changes = append(changes, fleet.StartupItemChange{
+					ChangeType:      fleet.StartupItemChangeAdded,
+					HostStartupItem: item,
+				})
+			}
+			stmt := insStmt + strings.TrimSuffix(strings.Repeat(insPart, len(toIns)), ",")
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert host startup items")
+			}
+		}
+
+		// the first collection of a host is the baseline, there is nothing to
+		// compare it to
+		if len(prevItems) == 0 || len(changes) == 0 {
+			return nil
+		}
+
+		args := make([]interface{}, 0, len(changes)*6)
+		for _, c := range changes {
+			args = append(args, hostID, c.ChangeType, c.Source, c.Name, c.Path, c.PreviousPath)
+		}
+		stmt := chgStmt + strings.TrimSuffix(strings.Repeat(chgInsPart, len(changes)), ",")
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert host startup item changes")
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ListHostStartupItems(ctx context.Context, hostID uint) ([]fleet.HostStartupItem, error) {
+	items := []fleet.HostStartupItem{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &items,
+		`SELECT source, name, path, sha256 FROM host_startup_items WHERE host_id = ? ORDER BY source, name`, hostID,
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select host startup items")
+	}
+	return items, nil
+}
+
+// startupItemChangesOrderKeys maps the supported order keys to their
+// unambiguous column.
+var startupItemChangesOrderKeys = map[string]string{
+	"id":          "c.id",
+	"host_id":     "c.host_id",
+	"hostname":    "h.hostname",
+	"change_type": "c.change_type",
+	"source":      "c.source",
+	"name":        "c.name",
+	"created_at":  "c.created_at",
+}
+
+func (ds *Datastore) ListStartupItemChanges(ctx context.Context, filter fleet.TeamFilter, opt fleet.StartupItemChangesListOptions) ([]fleet.StartupItemChange, error) {
+	stmt := `
+		SELECT
+			c.id, c.host_id, h.hostname, c.change_type, c.source, c.name,
+			c.path, c.previous_path, c.created_at
+		FROM host_startup_item_changes c
+		JOIN hosts h ON h.id = c.host_id
+		WHERE ` + ds.whereFilterHostsByTeams(filter, "h")
+
+	var args []interface{}
+	if opt.HostID != nil {
+		stmt += ` AND c.host_id = ?`
+		args = append(args, *opt.HostID)
+	}
+	if opt.ChangeType != "" {
+		stmt += ` AND c.change_type = ?`
+		args = append(args, opt.ChangeType)
+	}
+
+	// most recent changes first unless otherwise requested
+	if opt.OrderKey == "" {
+		opt.OrderKey = "id"
+		opt.OrderDirection = fleet.OrderDescending
+	}
+	orderKey, ok := startupItemChangesOrderKeys[opt.OrderKey]
+	if !ok {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("order_key", "unsupported order key: "+opt.OrderKey))
+	}
+	opt.OrderKey = orderKey
+	stmt, args = appendListOptionsWithCursorToSQL(stmt, args, opt.ListOptions)
+
+	changes := []fleet.StartupItemChange{}
+	if err := sqlx.SelectContext(ctx, ds.reader, &changes, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select startup item changes")
+	}
+	return changes, nil
+}
+
+func (ds *Datastore) CleanupStartupItemChanges(ctx context.Context, now time.Time) error {
+	_, err := ds.writer.ExecContext(ctx,
+		`DELETE FROM host_startup_item_changes WHERE created_at < ?`,
+		now.Add(-fleet.StartupItemChangesRetention),
+	)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup startup item changes")
+	}
+	return nil
+}
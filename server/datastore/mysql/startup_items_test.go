@@ -0,0 +1,198 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupItems(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"ReplaceHost", testStartupItemsReplaceHost},
+		{"ListChanges", testStartupItemsListChanges},
+		{"Cleanup", testStartupItemsCleanup},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testStartupItemsReplaceHost(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+
+	items, err := ds.ListHostStartupItems(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	// the first collection is the baseline, no change is recorded
+	require.NoError(t, ds.ReplaceHostStartupItems(ctx, host.ID, []fleet.HostStartupItem{
+		{Source: "launchd", Name: "com.example.b", Path: "/bin/b"},
+		{Source: "launchd", Name: "com.example.a", Path: "/bin/a"},
+	}))
+	items, err = ds.ListHostStartupItems(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostStartupItem{
+		{Source: "launchd", Name: "com.example.a", Path: "/bin/a"},
+		{Source: "launchd", Name: "com.example.b", Path: "/bin/b"},
+	}, items)
+	changes, err := ds.ListStartupItemChanges(ctx, filter, fleet.StartupItemChangesListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// an identical collection records nothing
+	require.NoError(t, ds.ReplaceHostStartupItems(ctx, host.ID, items))
+	changes, err = ds.ListStartupItemChanges(ctx, filter, fleet.StartupItemChangesListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	require.NoError(t, ds.ReplaceHostStartupItems(ctx, host.ID, []fleet.HostStartupItem{
+		{Source: "launchd", Name: "com.example.a", Path: "/tmp/a"},
+		{Source: "login_items", Name: "c", Path: "/bin/c"},
+	}))
+	items, err = ds.ListHostStartupItems(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostStartupItem{
+		{Source: "launchd", Name: "com.example.a", Path: "/tmp/a"},
+		{Source: "login_items", Name: "c", Path: "/bin/c"},
+	}, items)
+
+	changes, err = ds.ListStartupItemChanges(ctx, filter, fleet.StartupItemChangesListOptions{})
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	byType := make(map[string]fleet.StartupItemChange)
+	for _, c := range changes {
+		assert.Equal(t, host.ID, c.HostID)
+		assert.Equal(t, "host1", c.Hostname)
+		byType[c.ChangeType] = c
+	}
+	assert.Equal(t, fleet.HostStartupItem{Source: "launchd", Name: "com.example.b", Path: "/bin/b"}, byType[fleet.StartupItemChangeRemoved].HostStartupItem)
+	assert.Equal(t, fleet.HostStartupItem{Source: "login_items", Name: "c", Path: "/bin/c"}, byType[fleet.StartupItemChangeAdded].HostStartupItem)
+	assert.Equal(t, fleet.HostStartupItem{Source: "launchd", Name: "com.example.a", Path: "/tmp/a"}, byType[fleet.StartupItemChangeModified].HostStartupItem)
+	assert.Equal(t, "/bin/a", byType[fleet.StartupItemChangeModified].PreviousPath)
+
+	// the hash of an item is updated without recording a change
+	require.NoError(t, ds.ReplaceHostStartupItems(ctx, host.ID, []fleet.HostStartupItem{
+		{Source: "launchd", Name: "com.example.a", Path: "/tmp/a", SHA256: "abc"},
+		{Source: "login_items", Name: "c", Path: "/bin/c"},
+	}))
+	items, err = ds.ListHostStartupItems(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostStartupItem{
+		{Source: "launchd", Name: "com.example.a", Path: "/tmp/a", SHA256: "abc"},
+		{Source: "login_items", Name: "c", Path: "/bin/c"},
+	}, items)
+	changes, err = ds.ListStartupItemChanges(ctx, filter, fleet.StartupItemChangesListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, changes, 3)
+
+	// the startup items and their changes are removed with the host
+	require.NoError(t, ds.DeleteHost(ctx, host.ID))
+	items, err = ds.ListHostStartupItems(ctx, host.ID)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	var count int
+	require.NoError(t, ds.writer.GetContext(ctx, &count, `SELECT COUNT(*) FROM host_startup_item_changes`))
+	assert.Zero(t, count)
+}
+
+func testStartupItemsListChanges(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, &team.ID, []uint{host2.ID}))
+
+	for _, h := range []*fleet.Host{host1, host2} {
+		require.NoError(t, ds.ReplaceHostStartupItems(ctx, h.ID, []fleet.HostStartupItem{
+			{Source: "services", Name: "a", Path: `C:\a.exe`},
+		}))
+	}
+	require.NoError(t, ds.ReplaceHostStartupItems(ctx, host1.ID, []fleet.HostStartupItem{
+		{Source: "services", Name: "b", Path: `C:\b.exe`},
+	}))
+	require.NoError(t, ds.ReplaceHostStartupItems(ctx, host2.ID, []fleet.HostStartupItem{
+		{Source: "services", Name: "a", Path: `C:\a.exe`},
+		{Source: "services", Name: "c", Path: `C:\c.exe`},
+	}))
+
+	admin := fleet.TeamFilter{User: test.UserAdmin}
+	changes, err := ds.ListStartupItemChanges(ctx, admin, fleet.StartupItemChangesListOptions{})
+	require.NoError(t, err)
+	require.Len(t, changes, 3)
+	// most recent first
+	assert.Equal(t, host2.ID, changes[0].HostID)
+	assert.Equal(t, "c", changes[0].Name)
+
+	changes, err = ds.ListStartupItemChanges(ctx, admin, fleet.StartupItemChangesListOptions{HostID: &host1.ID})
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+
+	changes, err = ds.ListStartupItemChanges(ctx, admin, fleet.StartupItemChangesListOptions{ChangeType: fleet.StartupItemChangeAdded})
+	require.NoError(t, err)
+	assert.Len(t, changes, 2)
+
+	changes, err = ds.ListStartupItemChanges(ctx, fleet.TeamFilter{User: test.UserAdmin, TeamID: &team.ID}, fleet.StartupItemChangesListOptions{})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "host2", changes[0].Hostname)
+
+	changes, err = ds.ListStartupItemChanges(ctx, admin, fleet.StartupItemChangesListOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "hostname", PerPage: 1},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "host1", changes[0].Hostname)
+
+	_, err = ds.ListStartupItemChanges(ctx, admin, fleet.StartupItemChangesListOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "path"},
+	})
+	require.Error(t, err)
+
+	// the changes of the hosts of other teams are not visible to team users
+	changes, err = ds.ListStartupItemChanges(ctx, fleet.TeamFilter{User: &fleet.User{
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: team.ID + 1}, Role: fleet.RoleAdmin}},
+	}}, fleet.StartupItemChangesListOptions{HostID: ptr.Uint(host2.ID)})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func testStartupItemsCleanup(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+
+	require.NoError(t, ds.ReplaceHostStartupItems(ctx, host.ID, []fleet.HostStartupItem{{Source: "systemd", Name: "a"}}))
+	require.NoError(t, ds.ReplaceHostStartupItems(ctx, host.ID, []fleet.HostStartupItem{{Source: "systemd", Name: "b"}}))
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	changes, err := ds.ListStartupItemChanges(ctx, filter, fleet.StartupItemChangesListOptions{})
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+
+	_, err = ds.writer.ExecContext(ctx, `UPDATE host_startup_item_changes SET created_at = ? WHERE id = ?`,
+		time.Now().Add(-fleet.StartupItemChangesRetention-time.Hour), changes[0].ID)
+	require.NoError(t, err)
+
+	require.NoError(t, ds.CleanupStartupItemChanges(ctx, time.Now()))
+	remaining, err := ds.ListStartupItemChanges(ctx, filter, fleet.StartupItemChangesListOptions{})
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, changes[1].ID, remaining[0].ID)
+}
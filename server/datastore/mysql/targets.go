@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
@@ -10,27 +11,44 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-func (ds *Datastore) CountHostsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+func (ds *Datastore) CountHostsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
 	// The logic in this function should remain synchronized with
 	// host.Status and GenerateHostStatusStatistics - that is, the intervals associated
 	// with each status must be the same.
 
-	if len(targets.HostIDs) == 0 && len(targets.LabelIDs) == 0 && len(targets.TeamIDs) == 0 {
+	if len(targets.HostIDs) == 0 && len(targets.LabelIDs) == 0 && len(targets.TeamIDs) == 0 && targets.LabelExpression == "" {
 		// No need to query if no targets selected
 		return fleet.TargetMetrics{}, nil
 	}
 
+	exprSQL, exprArgs, err := labelExpressionSQL(targets.LabelExpression, "h")
+	if err != nil {
+		return fleet.TargetMetrics{}, ctxerr.Wrap(ctx, err, "CountHostsInTargets")
+	}
+	onlineSQL, onlineArgs := onlineHostsSQL(targets.OnlineOnly, "h", now, settings)
+	excludedSQL, excludedArgs := excludedHostsSQL(targets, "h")
+
+	var args []interface{}
+	statusSQL := make(map[fleet.HostStatus]string)
+	for _, status := range []fleet.HostStatus{fleet.StatusMIA, fleet.StatusMissing, fleet.StatusOffline, fleet.StatusOnline} {
+		var statusArgs []interface{}
+		statusSQL[status], statusArgs = hostStatusSQL(status, "h", now, settings)
+		args = append(args, statusArgs...)
+	}
+
 	sql := fmt.Sprintf(`
 		SELECT
 			COUNT(*) total,
-			COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) <= ? THEN 1 ELSE 0 END), 0) mia,
-			COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(distributed_interval, config_tls_refresh) + %d SECOND) <= ? AND DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL 30 DAY) >= ? THEN 1 ELSE 0 END), 0) offline,
-			COALESCE(SUM(CASE WHEN DATE_ADD(COALESCE(hst.seen_time, h.created_at), INTERVAL LEAST(distributed_interval, config_tls_refresh) + %d SECOND) > ? THEN 1 ELSE 0 END), 0) online,
+			COALESCE(SUM(CASE WHEN %s THEN 1 ELSE 0 END), 0) mia,
+			COALESCE(SUM(CASE WHEN %s THEN 1 ELSE 0 END), 0) missing,
+			COALESCE(SUM(CASE WHEN %s THEN 1 ELSE 0 END), 0) offline,
+			COALESCE(SUM(CASE WHEN %s THEN 1 ELSE 0 END), 0) online,
 			COALESCE(SUM(CASE WHEN DATE_ADD(created_at, INTERVAL 1 DAY) >= ? THEN 1 ELSE 0 END), 0) new
 		FROM hosts h
 		LEFT JOIN host_seen_times hst ON (h.id=hst.host_id)
-		WHERE (id IN (?) OR (id IN (SELECT DISTINCT host_id FROM label_membership WHERE label_id IN (?))) OR team_id IN (?)) AND %s
-`, fleet.OnlineIntervalBuffer, fleet.OnlineIntervalBuffer, ds.whereFilterHostsByTeams(filter, "h"))
+		WHERE (id IN (?) OR (id IN (SELECT DISTINCT host_id FROM label_membership WHERE label_id IN (?))) OR team_id IN (?) OR %s) AND %s AND %s AND %s
+`, statusSQL[fleet.StatusMIA], statusSQL[fleet.StatusMissing], statusSQL[fleet.StatusOffline], statusSQL[fleet.StatusOnline],
+		exprSQL, onlineSQL, excludedSQL, ds.whereFilterHostsByTeams(filter, "h"))
 
 	// Using -1 in the ID slices for the IN clause allows us to include the
 	// IN clause even if we have no IDs to use. -1 will not match the
@@ -49,7 +67,11 @@ func (ds *Datastore) CountHostsInTargets(ctx context.Context, filter fleet.TeamF
 		queryTeamIDs = append(queryTeamIDs, int(id))
 	}
 
-	query, args, err := sqlx.In(sql, now, now, now, now, now, queryHostIDs, queryLabelIDs, queryTeamIDs)
+	args = append(args, now, queryHostIDs, queryLabelIDs, queryTeamIDs)
+	args = append(args, exprArgs...)
+	args = append(args, onlineArgs...)
+	args = append(args, excludedArgs...)
+	query, args, err := sqlx.In(sql, args...)
 	if err != nil {
 		return fleet.TargetMetrics{}, ctxerr.Wrap(ctx, err, "sqlx.In CountHostsInTargets")
 	}
@@ -63,18 +85,29 @@ func (ds *Datastore) CountHostsInTargets(ctx context.Context, filter fleet.TeamF
 	return res, nil
 }
 
-func (ds *Datastore) HostIDsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
-	if len(targets.HostIDs) == 0 && len(targets.LabelIDs) == 0 && len(targets.TeamIDs) == 0 {
+func (ds *Datastore) HostIDsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
+	if len(targets.HostIDs) == 0 && len(targets.LabelIDs) == 0 && len(targets.TeamIDs) == 0 && targets.LabelExpression == "" {
 		// No need to query if no targets selected
 		return []uint{}, nil
 	}
 
+	exprSQL, exprArgs, err := labelExpressionSQL(targets.LabelExpression, "hosts")
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "HostIDsInTargets")
+	}
+	onlineSQL, onlineArgs := onlineHostsSQL(targets.OnlineOnly, "hosts", ds.clock.Now(), settings)
+	excludedSQL, excludedArgs := excludedHostsSQL(targets, "hosts")
+
 	sql := fmt.Sprintf(`
 			SELECT DISTINCT id
 			FROM hosts
-			WHERE (id IN (?) OR (id IN (SELECT host_id FROM label_membership WHERE label_id IN (?))) OR team_id IN (?)) AND %s
+			LEFT JOIN host_seen_times hst ON (hosts.id = hst.host_id)
+			WHERE (id IN (?) OR (id IN (SELECT host_id FROM label_membership WHERE label_id IN (?))) OR team_id IN (?) OR %s) AND %s AND %s AND %s
 			ORDER BY id ASC
 		`,
+		exprSQL,
+		onlineSQL,
+		excludedSQL,
 		ds.whereFilterHostsByTeams(filter, "hosts"),
 	)
 
@@ -95,7 +128,10 @@ func (ds *Datastore) HostIDsInTargets(ctx context.Context, filter fleet.TeamFilt
 		queryTeamIDs = append(queryTeamIDs, int(id))
 	}
 
-	query, args, err := sqlx.In(sql, queryHostIDs, queryLabelIDs, queryTeamIDs)
+	args := append([]interface{}{queryHostIDs, queryLabelIDs, queryTeamIDs}, exprArgs...)
+	args = append(args, onlineArgs...)
+	args = append(args, excludedArgs...)
+	query, args, err := sqlx.In(sql, args...)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "sqlx.In HostIDsInTargets")
 	}
@@ -107,3 +143,69 @@ func (ds *Datastore) HostIDsInTargets(ctx context.Context, filter fleet.TeamFilt
 	}
 	return res, nil
 }
+
+// onlineHostsSQL returns the condition matching the hosts (of the hostsTable
+// table or alias, joined with host_seen_times as hst) online at now, and its
+// arguments. The condition is always true if onlineOnly is false.
+//
+// The online status must remain synchronized with CountHostsInTargets.
+func onlineHostsSQL(onlineOnly bool, hostsTable string, now time.Time, settings fleet.HostSettings) (string, []interface{}) {
+	if !onlineOnly {
+		return "TRUE", nil
+	}
+	return hostStatusSQL(fleet.StatusOnline, hostsTable, now, settings)
+}
+
+// excludedHostsSQL returns the condition excluding the hosts (of the
+// hostsTable table or alias) excluded from the targets, and its arguments.
+func excludedHostsSQL(targets fleet.HostTargets, hostsTable string) (string, []interface{}) {
+	// As with the targets, -1 allows to include the IN clauses even if there
+	// are no excluded hosts or labels.
+	excludedHostIDs := []int{-1}
+	for _, id := range targets.ExcludedHostIDs {
+		excludedHostIDs = append(excludedHostIDs, int(id))
+	}
+	excludedLabelIDs := []int{-1}
+	for _, id := range targets.ExcludedLabelIDs {
+		excludedLabelIDs = append(excludedLabelIDs, int(id))
+	}
+	return fmt.Sprintf(
+		"%[1]s.id NOT IN (?) AND %[1]s.id NOT IN (SELECT host_id FROM label_membership WHERE label_id IN (?))",
+		hostsTable,
+	), []interface{}{excludedHostIDs, excludedLabelIDs}
+}
+
+// labelExpressionSQL returns the condition matching the hosts (of the
+// hostsTable table or alias) of the label expression, and its arguments. The
+// condition is always false if the expression is empty.
+func labelExpressionSQL(expr string, hostsTable string) (string, []interface{}, error) {
+	if expr == "" {
+		return "FALSE", nil, nil
+	}
+	parsed, err := fleet.ParseLabelExpression(expr)
+	if err != nil {
+		return "", nil, fmt.Errorf("parse label expression: %w", err)
+	}
+
+	var args []interface{}
+	var build func(e *fleet.LabelExpression) string
+	build = func(e *fleet.LabelExpression) string {
+		switch e.Op {
+		case fleet.LabelExpressionLabel:
+			args = append(args, e.Label)
+			return fmt.Sprintf(`EXISTS (
+				SELECT 1 FROM label_membership lm JOIN labels l ON l.id = lm.label_id
+				WHERE lm.host_id = %s.id AND l.name = ?
+			)`, hostsTable)
+		case fleet.LabelExpressionNot:
+			return "NOT " + build(e.Operands[0])
+		default:
+			conds := make([]string, 0, len(e.Operands))
+			for _, operand := range e.Operands {
+				conds = append(conds, build(operand))
+			}
+			return "(" + strings.Join(conds, " "+string(e.Op)+" ") + ")"
+		}
+	}
+	return "(" + build(parsed) + ")", args, nil
+}
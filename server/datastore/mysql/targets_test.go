@@ -96,70 +96,78 @@ func testTargetsCountHosts(t *testing.T, ds *Datastore) {
 		assert.Nil(t, err)
 	}
 
-	metrics, err := ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now())
+	metrics, err := ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(6), metrics.TotalHosts)
 	assert.Equal(t, uint(2), metrics.OfflineHosts)
 	assert.Equal(t, uint(3), metrics.OnlineHosts)
 	assert.Equal(t, uint(1), metrics.MissingInActionHosts)
 
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}, LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now())
+	// only the online hosts are targeted
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}, OnlineOnly: true}, mockClock.Now(), fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Equal(t, uint(3), metrics.TotalHosts)
+	assert.Equal(t, uint(0), metrics.OfflineHosts)
+	assert.Equal(t, uint(3), metrics.OnlineHosts)
+	assert.Equal(t, uint(0), metrics.MissingInActionHosts)
+
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}, LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(6), metrics.TotalHosts)
 	assert.Equal(t, uint(2), metrics.OfflineHosts)
 	assert.Equal(t, uint(3), metrics.OnlineHosts)
 	assert.Equal(t, uint(1), metrics.MissingInActionHosts)
 
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}, LabelIDs: []uint{l1.ID, l2.ID}, TeamIDs: []uint{team1.ID, team2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}, LabelIDs: []uint{l1.ID, l2.ID}, TeamIDs: []uint{team1.ID, team2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(6), metrics.TotalHosts)
 	assert.Equal(t, uint(2), metrics.OfflineHosts)
 	assert.Equal(t, uint(3), metrics.OnlineHosts)
 	assert.Equal(t, uint(1), metrics.MissingInActionHosts)
 
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(2), metrics.TotalHosts)
 	assert.Equal(t, uint(1), metrics.OnlineHosts)
 	assert.Equal(t, uint(1), metrics.OfflineHosts)
 	assert.Equal(t, uint(0), metrics.MissingInActionHosts)
 
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}, TeamIDs: []uint{team2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}, TeamIDs: []uint{team2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(4), metrics.TotalHosts)
 	assert.Equal(t, uint(2), metrics.OnlineHosts)
 	assert.Equal(t, uint(2), metrics.OfflineHosts)
 	assert.Equal(t, uint(0), metrics.MissingInActionHosts)
 
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID}, LabelIDs: []uint{l2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID}, LabelIDs: []uint{l2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(4), metrics.TotalHosts)
 	assert.Equal(t, uint(3), metrics.OnlineHosts)
 	assert.Equal(t, uint(1), metrics.OfflineHosts)
 	assert.Equal(t, uint(0), metrics.MissingInActionHosts)
 
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(0), metrics.TotalHosts)
 	assert.Equal(t, uint(0), metrics.OnlineHosts)
 	assert.Equal(t, uint(0), metrics.OfflineHosts)
 	assert.Equal(t, uint(0), metrics.MissingInActionHosts)
 
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{}, LabelIDs: []uint{}, TeamIDs: []uint{}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{}, LabelIDs: []uint{}, TeamIDs: []uint{}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(0), metrics.TotalHosts)
 	assert.Equal(t, uint(0), metrics.OnlineHosts)
 	assert.Equal(t, uint(0), metrics.OfflineHosts)
 	assert.Equal(t, uint(0), metrics.MissingInActionHosts)
 
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{TeamIDs: []uint{team1.ID, team3.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{TeamIDs: []uint{team1.ID, team3.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(1), metrics.TotalHosts)
 	assert.Equal(t, uint(1), metrics.OnlineHosts)
 	assert.Equal(t, uint(0), metrics.OfflineHosts)
 	assert.Equal(t, uint(0), metrics.MissingInActionHosts)
 
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{TeamIDs: []uint{team2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{TeamIDs: []uint{team2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(3), metrics.TotalHosts)
 	assert.Equal(t, uint(1), metrics.OnlineHosts)
@@ -168,7 +176,7 @@ func testTargetsCountHosts(t *testing.T, ds *Datastore) {
 
 	// Advance clock so all hosts are offline
 	mockClock.AddTime(2 * time.Minute)
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(6), metrics.TotalHosts)
 	assert.Equal(t, uint(0), metrics.OnlineHosts)
@@ -179,13 +187,13 @@ func testTargetsCountHosts(t *testing.T, ds *Datastore) {
 	filter = fleet.TeamFilter{User: userObs}
 
 	// observer not included
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(0), metrics.TotalHosts)
 
 	// observer included
 	filter.IncludeObserver = true
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(6), metrics.TotalHosts)
 
@@ -193,19 +201,19 @@ func testTargetsCountHosts(t *testing.T, ds *Datastore) {
 	filter = fleet.TeamFilter{User: userTeam2}
 
 	// user can see team 2 which is associated with 3 hosts
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(3), metrics.TotalHosts)
 
 	// request team1, user cannot see it
 	filter.TeamID = &team1.ID
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(0), metrics.TotalHosts)
 
 	// request team2, ok
 	filter.TeamID = &team2.ID
-	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now())
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, mockClock.Now(), fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, uint(3), metrics.TotalHosts)
 }
@@ -215,7 +223,7 @@ func testTargetsHostStatus(t *testing.T, ds *Datastore) {
 
 	mockClock := clock.NewMockClock()
 
-	h, err := ds.EnrollHost(context.Background(), "1", "key1", nil, 0)
+	h, err := ds.EnrollHost(context.Background(), "1", "key1", nil, 0, "")
 	require.Nil(t, err)
 
 	user := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}
@@ -226,6 +234,7 @@ func testTargetsHostStatus(t *testing.T, ds *Datastore) {
 
 	expectOnline := fleet.TargetMetrics{TotalHosts: 1, OnlineHosts: 1}
 	expectOffline := fleet.TargetMetrics{TotalHosts: 1, OfflineHosts: 1}
+	expectMissing := fleet.TargetMetrics{TotalHosts: 1, MissingHosts: 1}
 	expectMIA := fleet.TargetMetrics{TotalHosts: 1, MissingInActionHosts: 1}
 
 	testCases := []struct {
@@ -250,6 +259,11 @@ func testTargetsHostStatus(t *testing.T, ds *Datastore) {
 		{mockClock.Now().Add(-1 * time.Second), 0, 0, expectOnline},
 		{mockClock.Now().Add(-2 * time.Minute), 0, 0, expectOffline},
 		{mockClock.Now().Add(-31 * 24 * time.Hour), 0, 0, expectMIA},
+
+		// The hosts are missing after the missing threshold, until they are MIA
+		{mockClock.Now().Add(-6 * 24 * time.Hour), 10, 10, expectOffline},
+		{mockClock.Now().Add(-8 * 24 * time.Hour), 10, 10, expectMissing},
+		{mockClock.Now().Add(-29 * 24 * time.Hour), 10, 10, expectMissing},
 	}
 
 	for _, tt := range testCases {
@@ -263,7 +277,7 @@ func testTargetsHostStatus(t *testing.T, ds *Datastore) {
 			require.NoError(t, ds.MarkHostsSeen(context.Background(), []uint{h.ID}, tt.seenTime))
 
 			// Verify status
-			metrics, err := ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h.ID}}, mockClock.Now())
+			metrics, err := ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h.ID}}, mockClock.Now(), fleet.HostSettings{})
 			require.NoError(t, err)
 			assert.Equal(t, tt.metrics, metrics)
 		})
@@ -319,45 +333,94 @@ func testTargetsHostIDsInTargets(t *testing.T, ds *Datastore) {
 		assert.Nil(t, err)
 	}
 
-	ids, err := ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}})
+	ids, err := ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID, l2.ID}}, fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, []uint{1, 2, 3, 4, 5, 6}, ids)
 
-	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID}})
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID}}, fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, []uint{1}, ids)
 
-	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID}, LabelIDs: []uint{l1.ID}})
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h1.ID}, LabelIDs: []uint{l1.ID}}, fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, []uint{1, 2, 3, 6}, ids)
 
-	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{4}, LabelIDs: []uint{l1.ID}})
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{4}, LabelIDs: []uint{l1.ID}}, fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, []uint{1, 2, 3, 4, 6}, ids)
 
-	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{4}, LabelIDs: []uint{l2.ID}})
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{4}, LabelIDs: []uint{l2.ID}}, fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, []uint{3, 4, 5}, ids)
 
-	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{}, LabelIDs: []uint{l2.ID}})
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{}, LabelIDs: []uint{l2.ID}}, fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, []uint{3, 4, 5}, ids)
 
-	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{1, 6}, LabelIDs: []uint{l2.ID}})
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{1, 6}, LabelIDs: []uint{l2.ID}}, fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Equal(t, []uint{1, 3, 4, 5, 6}, ids)
 
+	// label expressions
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{LabelExpression: `label:"label foo" AND label:"label bar"`}, fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Equal(t, []uint{3}, ids)
+
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{LabelExpression: `label:"label foo" AND NOT label:"label bar"`}, fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Equal(t, []uint{1, 2, 6}, ids)
+
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{LabelExpression: `NOT (label:"label foo" OR label:"label bar")`}, fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Empty(t, ids)
+
+	// the hosts matching the expression are added to the other targets
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{4}, LabelExpression: `label:"label foo" AND label:"label bar"`}, fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Equal(t, []uint{3, 4}, ids)
+
+	metrics, err := ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelExpression: `label:"label foo" AND NOT label:"label bar"`}, time.Now(), fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Equal(t, uint(3), metrics.TotalHosts)
+
+	// the excluded hosts and members of excluded labels are not targeted,
+	// even if they match the other targets
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{4}, LabelIDs: []uint{l1.ID}, ExcludedHostIDs: []uint{1, 4}}, fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Equal(t, []uint{2, 3, 6}, ids)
+
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID}, ExcludedHostIDs: []uint{1}, ExcludedLabelIDs: []uint{l2.ID}}, fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Equal(t, []uint{2, 6}, ids)
+
+	metrics, err = ds.CountHostsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID}, ExcludedLabelIDs: []uint{l2.ID}}, time.Now(), fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Equal(t, uint(3), metrics.TotalHosts)
+
+	// only the online hosts are targeted
+	require.NoError(t, ds.MarkHostsSeen(context.Background(), []uint{h2.ID}, ds.clock.Now().Add(-time.Hour)))
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{LabelIDs: []uint{l1.ID}, OnlineOnly: true}, fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Equal(t, []uint{1, 3, 6}, ids)
+
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{h2.ID}, OnlineOnly: true}, fleet.HostSettings{})
+	require.Nil(t, err)
+	assert.Empty(t, ids)
+
+	_, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{LabelExpression: `label:"label foo" AND`}, fleet.HostSettings{})
+	require.Error(t, err)
+
 	userObs := &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}
 	filter = fleet.TeamFilter{User: userObs}
 
 	// observer not included
-	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{1, 6}, LabelIDs: []uint{l2.ID}})
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{1, 6}, LabelIDs: []uint{l2.ID}}, fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Len(t, ids, 0)
 
 	// observer included
 	filter.IncludeObserver = true
-	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{1, 6}, LabelIDs: []uint{l2.ID}})
+	ids, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{HostIDs: []uint{1, 6}, LabelIDs: []uint{l2.ID}}, fleet.HostSettings{})
 	require.Nil(t, err)
 	assert.Len(t, ids, 5)
 }
@@ -395,7 +458,7 @@ func testTargetsHostIDsInTargetsTeam(t *testing.T, ds *Datastore) {
 	h1 := initHost(mockClock.Now().Add(-1*time.Second), 10, 60, &team1.ID)
 	initHost(mockClock.Now().Add(-1*time.Second), 10, 60, &team2.ID)
 
-	targets, err := ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{TeamIDs: []uint{team1.ID}})
+	targets, err := ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{TeamIDs: []uint{team1.ID}}, fleet.HostSettings{})
 	require.NoError(t, err)
 	assert.Equal(t, []uint{h1.ID}, targets)
 
@@ -403,7 +466,7 @@ func testTargetsHostIDsInTargetsTeam(t *testing.T, ds *Datastore) {
 	filter = fleet.TeamFilter{User: userTeam1}
 
 	// user can only see team1
-	targets, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{TeamIDs: []uint{team1.ID, team2.ID}})
+	targets, err = ds.HostIDsInTargets(context.Background(), filter, fleet.HostTargets{TeamIDs: []uint{team1.ID, team2.ID}}, fleet.HostSettings{})
 	require.NoError(t, err)
 	assert.Equal(t, []uint{h1.ID}, targets)
 }
@@ -75,12 +75,12 @@ func teamDB(ctx context.Context, q sqlx.QueryerContext, tid uint) (*fleet.Team,
 	return team, nil
 }
 
-func saveTeamSecretsDB(ctx context.Context, exec sqlx.ExecerContext, team *fleet.Team) error {
+func saveTeamSecretsDB(ctx context.Context, q sqlx.ExtContext, team *fleet.Team) error {
 	if team.Secrets == nil {
 		return nil
 	}
 
-	return applyEnrollSecretsDB(ctx, exec, &team.ID, team.Secrets)
+	return applyEnrollSecretsDB(ctx, q, &team.ID, team.Secrets)
 }
 
 func (ds *Datastore) DeleteTeam(ctx context.Context, tid uint) error {
@@ -300,3 +300,19 @@ func (ds *Datastore) TeamAgentOptions(ctx context.Context, tid uint) (*json.RawM
 	}
 	return agentOptions, nil
 }
+
+// TeamLogSettings loads the log settings of a team.
+func (ds *Datastore) TeamLogSettings(ctx context.Context, tid uint) (*fleet.TeamLogSettings, error) {
+	sql := `SELECT config->"$.log_settings" FROM teams WHERE id = ?`
+	var raw *json.RawMessage
+	if err := sqlx.GetContext(ctx, ds.reader, &raw, sql, tid); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select team")
+	}
+	var settings fleet.TeamLogSettings
+	if raw != nil && string(*raw) != "null" {
+		if err := json.Unmarshal(*raw, &settings); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "unmarshal team log settings")
+		}
+	}
+	return &settings, nil
+}
@@ -28,6 +28,7 @@ func TestTeams(t *testing.T) {
 		{"Search", testTeamsSearch},
 		{"EnrollSecrets", testTeamsEnrollSecrets},
 		{"TeamAgentOptions", testTeamsAgentOptions},
+		{"TeamLogSettings", testTeamsLogSettings},
 		{"TeamsDeleteRename", testTeamsDeleteRename},
 	}
 	for _, c := range cases {
@@ -318,3 +319,25 @@ func testTeamsAgentOptions(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 	require.JSONEq(t, string(agentOptions), string(*teamAgentOptions2))
 }
+
+func testTeamsLogSettings(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	settings, err := ds.TeamLogSettings(ctx, team1.ID)
+	require.NoError(t, err)
+	require.Equal(t, &fleet.TeamLogSettings{}, settings)
+
+	team1.Config.LogSettings = &fleet.TeamLogSettings{ResultLogPlugin: "splunk"}
+	_, err = ds.SaveTeam(ctx, team1)
+	require.NoError(t, err)
+
+	settings, err = ds.TeamLogSettings(ctx, team1.ID)
+	require.NoError(t, err)
+	require.Equal(t, &fleet.TeamLogSettings{ResultLogPlugin: "splunk"}, settings)
+
+	_, err = ds.TeamLogSettings(ctx, team1.ID+1)
+	require.Error(t, err)
+}
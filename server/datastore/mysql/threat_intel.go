@@ -0,0 +1,115 @@
+package mysql
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// hostIndicatorsSQL selects the indicators of the hosts from the listening
+// ports and the hashes of the startup items collected by their detail queries.
+const hostIndicatorsSQL = `
+	SELECT host_id, '` + fleet.IndicatorTypeListeningPort + `' AS type, CONCAT(protocol, '/', port) AS value
+	FROM host_listening_ports
+	UNION
+	SELECT host_id, '` + fleet.IndicatorTypeAutorunSHA256 + `' AS type, sha256 AS value
+	FROM host_startup_items
+	WHERE sha256 <> ''`
+
+func (ds *Datastore) ListIndicatorsToLookup(ctx context.Context, checkedBefore time.Time, limit int) ([]fleet.Indicator, error) {
+	sqlStatement := `
+		SELECT DISTINCT hi.type, hi.value
+		FROM (` + hostIndicatorsSQL + `) hi
+		LEFT JOIN threat_intel_verdicts v ON v.type = hi.type AND v.value = hi.value
+		WHERE v.checked_at IS NULL OR v.checked_at < ?
+		ORDER BY hi.type, hi.value
+		LIMIT ?
+	`
+	var indicators []fleet.Indicator
+	if err := sqlx.SelectContext(ctx, ds.reader, &indicators, sqlStatement, checkedBefore, limit); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list indicators to lookup")
+	}
+	return indicators, nil
+}
+
+func (ds *Datastore) SaveThreatIntelVerdicts(ctx context.Context, verdicts []fleet.ThreatIntelVerdict) error {
+	const (
+		insStmt = `INSERT INTO threat_intel_verdicts (type, value, malicious, description, checked_at) VALUES`
+		insPart = ` (?, ?, ?, ?, ?),`
+		updStmt = `
+			ON DUPLICATE KEY UPDATE
+				malicious = VALUES(malicious),
+				description = VALUES(description),
+				checked_at = VALUES(checked_at)`
+
+		// keep the number of placeholders well under the MySQL limit
+		batchSize = 1000
+	)
+
+	for len(verdicts) > 0 {
+		batch := verdicts
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		verdicts = verdicts[len(batch):]
+
+		args := make([]interface{}, 0, len(batch)*5)
+		for _, v := range batch {
+			args = append(args, v.Type, v.Value, v.Malicious, v.Description, v.CheckedAt)
+		}
+		stmt := insStmt + strings.TrimSuffix(strings.Repeat(insPart, len(batch)), ",") + updStmt
+		if _, err := ds.writer.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "save threat intel verdicts")
+		}
+	}
+	return nil
+}
+
+func (ds *Datastore) UpdateHostThreatFindings(ctx context.Context) error {
+	const (
+		insStmt = `
+			INSERT INTO host_threat_findings (host_id, type, value, description)
+			SELECT hi.host_id, hi.type, hi.value, v.description
+			FROM (` + hostIndicatorsSQL + `) hi
+			JOIN threat_intel_verdicts v ON v.type = hi.type AND v.value = hi.value
+			WHERE v.malicious
+			ON DUPLICATE KEY UPDATE description = VALUES(description)`
+
+		delStmt = `
+			DELETE f FROM host_threat_findings f
+			WHERE NOT EXISTS (
+				SELECT 1
+				FROM (` + hostIndicatorsSQL + `) hi
+				JOIN threat_intel_verdicts v ON v.type = hi.type AND v.value = hi.value
+				WHERE hi.host_id = f.host_id AND hi.type = f.type AND hi.value = f.value AND v.malicious
+			)`
+	)
+
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if _, err := tx.ExecContext(ctx, insStmt); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert host threat findings")
+		}
+		if _, err := tx.ExecContext(ctx, delStmt); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete stale host threat findings")
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ListHostThreatFindings(ctx context.Context, hostID uint) ([]*fleet.HostThreatFinding, error) {
+	sqlStatement := `
+		SELECT type, value, description, created_at
+		FROM host_threat_findings
+		WHERE host_id = ?
+		ORDER BY type, value
+	`
+	var findings []*fleet.HostThreatFinding
+	if err := sqlx.SelectContext(ctx, ds.reader, &findings, sqlStatement, hostID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host threat findings")
+	}
+	return findings, nil
+}
@@ -0,0 +1,137 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThreatIntel(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"ListIndicatorsToLookup", testThreatIntelListIndicatorsToLookup},
+		{"HostThreatFindings", testThreatIntelHostThreatFindings},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testThreatIntelListIndicatorsToLookup(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host1 := test.NewHost(t, ds, "host1", "", "key1", "uuid1", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "key2", "uuid2", time.Now())
+
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host1.ID, []fleet.HostListeningPort{
+		{Port: 22, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "sshd"},
+		{Port: 4444, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "nc"},
+		{Port: 22, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "sshd-session"},
+	}))
+	require.NoError(t, ds.ReplaceHostStartupItems(ctx, host1.ID, []fleet.HostStartupItem{
+		{Source: "launchd", Name: "com.example.agent", Path: "/usr/local/bin/agent", SHA256: "abc"},
+		{Source: "launchd", Name: "com.example.copy", Path: "/opt/agent", SHA256: "abc"},
+		{Source: "launchd", Name: "com.example.noop", Path: ""},
+	}))
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host2.ID, []fleet.HostListeningPort{
+		{Port: 22, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "sshd"},
+		{Port: 53, Protocol: fleet.ListeningPortProtocolUDP, ProcessName: "dnsmasq"},
+	}))
+
+	// indicators are distinct across hosts, processes and startup items, the
+	// startup items without a hash are ignored
+	indicators, err := ds.ListIndicatorsToLookup(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.Indicator{
+		{Type: fleet.IndicatorTypeAutorunSHA256, Value: "abc"},
+		{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/22"},
+		{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/4444"},
+		{Type: fleet.IndicatorTypeListeningPort, Value: "udp/53"},
+	}, indicators)
+
+	indicators, err = ds.ListIndicatorsToLookup(ctx, time.Now(), 2)
+	require.NoError(t, err)
+	assert.Len(t, indicators, 2)
+
+	// indicators follow the listening ports of the hosts
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host1.ID, []fleet.HostListeningPort{
+		{Port: 22, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "sshd"},
+	}))
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host2.ID, nil))
+	indicators, err = ds.ListIndicatorsToLookup(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.Indicator{
+		{Type: fleet.IndicatorTypeAutorunSHA256, Value: "abc"},
+		{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/22"},
+	}, indicators)
+
+	// indicators with a fresh verdict are not looked up again
+	checkedAt := time.Now().Add(-time.Hour)
+	require.NoError(t, ds.SaveThreatIntelVerdicts(ctx, []fleet.ThreatIntelVerdict{
+		{Indicator: fleet.Indicator{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/22"}, CheckedAt: checkedAt},
+	}))
+	indicators, err = ds.ListIndicatorsToLookup(ctx, checkedAt.Add(-time.Minute), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.Indicator{{Type: fleet.IndicatorTypeAutorunSHA256, Value: "abc"}}, indicators)
+
+	indicators, err = ds.ListIndicatorsToLookup(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	assert.Len(t, indicators, 2)
+}
+
+func testThreatIntelHostThreatFindings(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	host1 := test.NewHost(t, ds, "host1", "", "key1", "uuid1", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "key2", "uuid2", time.Now())
+
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host1.ID, []fleet.HostListeningPort{
+		{Port: 22, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "sshd"},
+		{Port: 4444, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "nc"},
+	}))
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host2.ID, []fleet.HostListeningPort{
+		{Port: 22, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "sshd"},
+	}))
+	require.NoError(t, ds.SaveThreatIntelVerdicts(ctx, []fleet.ThreatIntelVerdict{
+		{Indicator: fleet.Indicator{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/22"}, CheckedAt: time.Now()},
+		{Indicator: fleet.Indicator{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/4444"}, Malicious: true, Description: "backdoor", CheckedAt: time.Now()},
+	}))
+	require.NoError(t, ds.UpdateHostThreatFindings(ctx))
+
+	findings, err := ds.ListHostThreatFindings(ctx, host1.ID)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "tcp/4444", findings[0].Value)
+	assert.Equal(t, "backdoor", findings[0].Description)
+
+	findings, err = ds.ListHostThreatFindings(ctx, host2.ID)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+
+	// the verdict of an indicator changes
+	require.NoError(t, ds.SaveThreatIntelVerdicts(ctx, []fleet.ThreatIntelVerdict{
+		{Indicator: fleet.Indicator{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/22"}, Malicious: true, Description: "ssh", CheckedAt: time.Now()},
+	}))
+	require.NoError(t, ds.UpdateHostThreatFindings(ctx))
+	findings, err = ds.ListHostThreatFindings(ctx, host2.ID)
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "ssh", findings[0].Description)
+
+	// the indicator is not collected from the host anymore
+	require.NoError(t, ds.ReplaceHostListeningPorts(ctx, host1.ID, nil))
+	require.NoError(t, ds.UpdateHostThreatFindings(ctx))
+	findings, err = ds.ListHostThreatFindings(ctx, host1.ID)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
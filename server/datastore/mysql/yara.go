@@ -0,0 +1,97 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) NewYARASignatureGroup(ctx context.Context, group *fleet.YARASignatureGroup) (*fleet.YARASignatureGroup, error) {
+	sqlStatement := `
+		INSERT INTO yara_signature_groups (
+			team_id,
+			name,
+			signatures,
+			file_paths
+		) VALUES ( ?, ?, ?, ? )
+	`
+	result, err := ds.writer.ExecContext(ctx, sqlStatement, group.TeamID, group.Name, group.Signatures, group.FilePaths)
+	if err != nil && isDuplicate(err) {
+		return nil, ctxerr.Wrap(ctx, alreadyExists("YARASignatureGroup", group.Name))
+	} else if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "creating new yara signature group")
+	}
+
+	id, _ := result.LastInsertId()
+	return yaraSignatureGroupDB(ctx, ds.writer, uint(id))
+}
+
+func (ds *Datastore) YARASignatureGroup(ctx context.Context, id uint) (*fleet.YARASignatureGroup, error) {
+	return yaraSignatureGroupDB(ctx, ds.reader, id)
+}
+
+func yaraSignatureGroupDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.YARASignatureGroup, error) {
+	var group fleet.YARASignatureGroup
+	err := sqlx.GetContext(ctx, q, &group, `SELECT * FROM yara_signature_groups WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ctxerr.Wrap(ctx, notFound("YARASignatureGroup").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "selecting yara signature group")
+	}
+	return &group, nil
+}
+
+func (ds *Datastore) ListYARASignatureGroups(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+	sqlStatement := `SELECT * FROM yara_signature_groups WHERE team_id IS NULL ORDER BY name`
+	var args []interface{}
+	if teamID != nil {
+		sqlStatement = `SELECT * FROM yara_signature_groups WHERE team_id = ? ORDER BY name`
+		args = append(args, *teamID)
+	}
+
+	var groups []*fleet.YARASignatureGroup
+	if err := sqlx.SelectContext(ctx, ds.reader, &groups, sqlStatement, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing yara signature groups")
+	}
+	return groups, nil
+}
+
+func (ds *Datastore) ListYARASignatureGroupsForHost(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+	sqlStatement := `SELECT * FROM yara_signature_groups WHERE team_id IS NULL OR team_id = ? ORDER BY name`
+
+	var groups []*fleet.YARASignatureGroup
+	if err := sqlx.SelectContext(ctx, ds.reader, &groups, sqlStatement, teamID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "listing yara signature groups for host")
+	}
+	return groups, nil
+}
+
+func (ds *Datastore) SaveYARASignatureGroup(ctx context.Context, group *fleet.YARASignatureGroup) error {
+	sqlStatement := `
+		UPDATE yara_signature_groups
+			SET name = ?, signatures = ?, file_paths = ?
+			WHERE id = ?
+	`
+	result, err := ds.writer.ExecContext(ctx, sqlStatement, group.Name, group.Signatures, group.FilePaths, group.ID)
+	if err != nil && isDuplicate(err) {
+		return ctxerr.Wrap(ctx, alreadyExists("YARASignatureGroup", group.Name))
+	} else if err != nil {
+		return ctxerr.Wrap(ctx, err, "updating yara signature group")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "rows affected updating yara signature group")
+	}
+	if rows == 0 {
+		return ctxerr.Wrap(ctx, notFound("YARASignatureGroup").WithID(group.ID))
+	}
+	return nil
+}
+
+func (ds *Datastore) DeleteYARASignatureGroup(ctx context.Context, id uint) error {
+	return ds.deleteEntity(ctx, yaraSignatureGroupsTable, id)
+}
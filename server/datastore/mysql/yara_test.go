@@ -0,0 +1,85 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYARASignatureGroups(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	ctx := context.Background()
+
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	team2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+
+	malware, err := ds.NewYARASignatureGroup(ctx, &fleet.YARASignatureGroup{
+		Name:       "malware",
+		Signatures: fleet.YARAStrings{"/etc/osquery/yara/malware.sig"},
+		FilePaths:  fleet.YARAStrings{"homes"},
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, malware.ID)
+	assert.Nil(t, malware.TeamID)
+	assert.Equal(t, fleet.YARAStrings{"/etc/osquery/yara/malware.sig"}, malware.Signatures)
+	assert.Equal(t, fleet.YARAStrings{"homes"}, malware.FilePaths)
+
+	_, err = ds.NewYARASignatureGroup(ctx, &fleet.YARASignatureGroup{Name: "malware", TeamID: &team1.ID, Signatures: fleet.YARAStrings{"/other.sig"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exists")
+
+	crypto, err := ds.NewYARASignatureGroup(ctx, &fleet.YARASignatureGroup{Name: "crypto", TeamID: &team1.ID, Signatures: fleet.YARAStrings{"/crypto.sig"}})
+	require.NoError(t, err)
+	require.NotNil(t, crypto.TeamID)
+	assert.Equal(t, team1.ID, *crypto.TeamID)
+	assert.Equal(t, fleet.YARAStrings{}, crypto.FilePaths)
+
+	groups, err := ds.ListYARASignatureGroups(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "malware", groups[0].Name)
+
+	groups, err = ds.ListYARASignatureGroups(ctx, &team1.ID)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "crypto", groups[0].Name)
+
+	groups, err = ds.ListYARASignatureGroupsForHost(ctx, &team1.ID)
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "crypto", groups[0].Name)
+	assert.Equal(t, "malware", groups[1].Name)
+
+	groups, err = ds.ListYARASignatureGroupsForHost(ctx, &team2.ID)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, "malware", groups[0].Name)
+
+	groups, err = ds.ListYARASignatureGroupsForHost(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	malware.Signatures = fleet.YARAStrings{"/malware.sig", "/malware2.sig"}
+	malware.FilePaths = fleet.YARAStrings{"homes", "etc"}
+	require.NoError(t, ds.SaveYARASignatureGroup(ctx, malware))
+	got, err := ds.YARASignatureGroup(ctx, malware.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.YARAStrings{"/malware.sig", "/malware2.sig"}, got.Signatures)
+	assert.Equal(t, fleet.YARAStrings{"homes", "etc"}, got.FilePaths)
+
+	require.NoError(t, ds.DeleteYARASignatureGroup(ctx, malware.ID))
+	_, err = ds.YARASignatureGroup(ctx, malware.ID)
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+
+	// deleting the team deletes its groups
+	require.NoError(t, ds.DeleteTeam(ctx, team1.ID))
+	_, err = ds.YARASignatureGroup(ctx, crypto.ID)
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+}
@@ -3,6 +3,7 @@ package redis
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"time"
@@ -181,6 +182,45 @@ func BindConn(pool fleet.RedisPool, conn redis.Conn, keys ...string) error {
 	return nil
 }
 
+// RefreshCluster refreshes the mapping of the hash slots to the nodes of the
+// Redis Cluster, e.g. after a replica was promoted following the failure of
+// its primary. In a Redis Standalone setup, it is a no-op and never fails.
+func RefreshCluster(pool fleet.RedisPool) error {
+	if cluster, isCluster := pool.(*clusterPool); isCluster {
+		return cluster.Refresh()
+	}
+	return nil
+}
+
+// IsTransientError returns true if err is expected to be transient in a
+// Redis Cluster, so that the command can be retried (ideally on a new
+// connection): a redirection while a slot is migrated to another node, the
+// cluster being down or the connection being lost while a replica is promoted
+// after the failure of its primary, or the node still loading its data.
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if redisc.ParseRedir(err) != nil {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		for _, prefix := range []string{"TRYAGAIN", "CLUSTERDOWN", "LOADING", "MASTERDOWN", "READONLY"} {
+			if strings.HasPrefix(string(redisErr), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // PublishHasListeners is like the PUBLISH redis command, but it also returns a
 // boolean indicating if channel still has subscribed listeners. It is required
 // because the redis command only returns the count of subscribers active on
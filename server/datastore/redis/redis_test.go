@@ -2,6 +2,7 @@ package redis
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"testing"
 	"time"
@@ -111,3 +112,33 @@ func TestConnectRetry(t *testing.T) {
 		})
 	}
 }
+
+func TestIsTransientError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("boom"), false},
+		{redigo.Error("ERR unknown command"), false},
+		{redigo.Error("WRONGTYPE Operation against a key holding the wrong kind of value"), false},
+		{redigo.Error("MOVED 3999 127.0.0.1:7002"), true},
+		{redigo.Error("ASK 3999 127.0.0.1:7002"), true},
+		{redigo.Error("TRYAGAIN Multiple keys request during rehashing of slot"), true},
+		{redigo.Error("CLUSTERDOWN The cluster is down"), true},
+		{redigo.Error("LOADING Redis is loading the dataset in memory"), true},
+		{redigo.Error("READONLY You can't write against a read only replica."), true},
+		{io.EOF, true},
+		{fmt.Errorf("publish: %w", io.ErrUnexpectedEOF), true},
+		{&netError{error: errors.New("connection reset by peer")}, true},
+	}
+	for _, c := range cases {
+		name := "nil"
+		if c.err != nil {
+			name = c.err.Error()
+		}
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, c.want, IsTransientError(c.err))
+		})
+	}
+}
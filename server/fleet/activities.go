@@ -37,6 +37,22 @@ const (
 	ActivityTypeDeletedTeam = "deleted_team"
 	// ActivityTypeLiveQuery is the activity type for live queries
 	ActivityTypeLiveQuery = "live_query"
+	// ActivityTypeRolledBackAgentConfig is the activity type for agent config rollbacks
+	ActivityTypeRolledBackAgentConfig = "rolled_back_agent_config"
+	// ActivityTypeStartedAgentOptionsRollout is the activity type for starting an agent options rollout
+	ActivityTypeStartedAgentOptionsRollout = "started_agent_options_rollout"
+	// ActivityTypePromotedAgentOptionsRollout is the activity type for promoting an agent options rollout
+	ActivityTypePromotedAgentOptionsRollout = "promoted_agent_options_rollout"
+	// ActivityTypeAbortedAgentOptionsRollout is the activity type for aborting an agent options rollout
+	ActivityTypeAbortedAgentOptionsRollout = "aborted_agent_options_rollout"
+	// ActivityTypeEditedTeamPolicyExemptions is the activity type for edited exemptions of a team from global policies
+	ActivityTypeEditedTeamPolicyExemptions = "edited_team_policy_exemptions"
+	// ActivityTypeRequestedPolicyRemediation is the activity type for requested remediations of a policy on a host
+	ActivityTypeRequestedPolicyRemediation = "requested_policy_remediation"
+	// ActivityTypeTransferredHosts is the activity type for hosts transferred to a team
+	ActivityTypeTransferredHosts = "transferred_hosts"
+	// ActivityTypeMergedHosts is the activity type for duplicate hosts merged into a host
+	ActivityTypeMergedHosts = "merged_hosts"
 )
 
 type Activity struct {
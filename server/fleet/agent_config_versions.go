@@ -0,0 +1,143 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// AgentConfig is the versioned part of the configuration served to the
+// agents: the global agent options and the packs.
+type AgentConfig struct {
+	AgentOptions *json.RawMessage `json:"agent_options"`
+	Packs        []*PackSpec      `json:"packs"`
+}
+
+// AgentConfigVersion is a version of the agent configuration. A new version
+// is recorded every time the agent configuration changes.
+type AgentConfigVersion struct {
+	CreateTimestamp
+	ID         uint    `json:"id" db:"id"`
+	AuthorID   *uint   `json:"author_id" db:"author_id"`
+	AuthorName *string `json:"author_name" db:"author_name"`
+	// Changes are the changes made to the agent configuration of the previous
+	// version.
+	Changes []AgentConfigChange `json:"changes" db:"-"`
+	// Config is the agent configuration of this version. It is only loaded
+	// when getting a single version.
+	Config *AgentConfig `json:"config,omitempty" db:"-"`
+}
+
+// AgentConfigChange is a value of the agent configuration that was added,
+// modified or removed. Path is the dot-separated path of the value, where the
+// packs and their queries are identified by their names.
+type AgentConfigChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// DiffAgentConfigs returns the changes made to the old agent configuration to
+// get the new one, sorted by path. A nil old configuration is treated as an
+// empty one.
+func DiffAgentConfigs(old, new *AgentConfig) ([]AgentConfigChange, error) {
+	oldVal, err := old.diffable()
+	if err != nil {
+		return nil, fmt.Errorf("old agent config: %w", err)
+	}
+	newVal, err := new.diffable()
+	if err != nil {
+		return nil, fmt.Errorf("new agent config: %w", err)
+	}
+
+	var changes []AgentConfigChange
+	diffValues("", oldVal, newVal, &changes)
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes, nil
+}
+
+// diffable returns the agent configuration as generic JSON values, where the
+// packs and the pack queries are keyed by name rather than listed, so that
+// the changes are reported per pack and per query.
+func (c *AgentConfig) diffable() (map[string]interface{}, error) {
+	if c == nil {
+		c = &AgentConfig{}
+	}
+
+	val := make(map[string]interface{})
+
+	if c.AgentOptions != nil && len(*c.AgentOptions) > 0 {
+		var opts interface{}
+		if err := json.Unmarshal(*c.AgentOptions, &opts); err != nil {
+			return nil, fmt.Errorf("unmarshal agent options: %w", err)
+		}
+		val["agent_options"] = opts
+	}
+
+	packs := make(map[string]interface{}, len(c.Packs))
+	for _, spec := range c.Packs {
+		b, err := json.Marshal(spec)
+		if err != nil {
+			return nil, fmt.Errorf("marshal pack %s: %w", spec.Name, err)
+		}
+		var pack map[string]interface{}
+		if err := json.Unmarshal(b, &pack); err != nil {
+			return nil, fmt.Errorf("unmarshal pack %s: %w", spec.Name, err)
+		}
+		delete(pack, "id")
+		delete(pack, "name")
+
+		queries := make(map[string]interface{}, len(spec.Queries))
+		for _, q := range spec.Queries {
+			b, err := json.Marshal(q)
+			if err != nil {
+				return nil, fmt.Errorf("marshal pack %s query %s: %w", spec.Name, q.Name, err)
+			}
+			var query map[string]interface{}
+			if err := json.Unmarshal(b, &query); err != nil {
+				return nil, fmt.Errorf("unmarshal pack %s query %s: %w", spec.Name, q.Name, err)
+			}
+			delete(query, "name")
+			queries[q.Name] = query
+		}
+		pack["queries"] = queries
+		packs[spec.Name] = pack
+	}
+	val["packs"] = packs
+	return val, nil
+}
+
+func diffValues(path string, old, new interface{}, changes *[]AgentConfigChange) {
+	oldObj, oldIsObj := old.(map[string]interface{})
+	newObj, newIsObj := new.(map[string]interface{})
+	if !oldIsObj || !newIsObj {
+		if !reflect.DeepEqual(old, new) {
+			*changes = append(*changes, AgentConfigChange{Path: path, Old: old, New: new})
+		}
+		return
+	}
+
+	for k, oldV := range oldObj {
+		newV, ok := newObj[k]
+		if !ok {
+			*changes = append(*changes, AgentConfigChange{Path: joinDiffPath(path, k), Old: oldV})
+			continue
+		}
+		diffValues(joinDiffPath(path, k), oldV, newV, changes)
+	}
+	for k, newV := range newObj {
+		if _, ok := oldObj[k]; !ok {
+			*changes = append(*changes, AgentConfigChange{Path: joinDiffPath(path, k), New: newV})
+		}
+	}
+}
+
+func joinDiffPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
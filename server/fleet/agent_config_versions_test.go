@@ -0,0 +1,87 @@
+package fleet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffAgentConfigs(t *testing.T) {
+	rawOptions := func(s string) *json.RawMessage {
+		raw := json.RawMessage(s)
+		return &raw
+	}
+
+	old := &AgentConfig{
+		AgentOptions: rawOptions(`{"config":{"options":{"distributed_interval":10,"logger_plugin":"tls"}}}`),
+		Packs: []*PackSpec{
+			{
+				ID:       1,
+				Name:     "pack1",
+				Platform: "darwin",
+				Queries: []PackSpecQuery{
+					{Name: "q1", QueryName: "q1", Interval: 60},
+					{Name: "q2", QueryName: "q2", Interval: 60},
+				},
+			},
+			{ID: 2, Name: "pack2"},
+		},
+	}
+
+	// a nil old config is an empty one
+	changes, err := DiffAgentConfigs(nil, &AgentConfig{})
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	changes, err = DiffAgentConfigs(old, old)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// the pack IDs are not part of the config
+	updated := &AgentConfig{
+		AgentOptions: rawOptions(`{"config":{"options":{"distributed_interval":20}}}`),
+		Packs: []*PackSpec{
+			{
+				ID:       3,
+				Name:     "pack1",
+				Platform: "darwin",
+				Queries: []PackSpecQuery{
+					{Name: "q1", QueryName: "q1", Interval: 120},
+				},
+			},
+			{ID: 4, Name: "pack3"},
+		},
+	}
+	changes, err = DiffAgentConfigs(old, updated)
+	require.NoError(t, err)
+	assert.Equal(t, []AgentConfigChange{
+		{Path: "agent_options.config.options.distributed_interval", Old: float64(10), New: float64(20)},
+		{Path: "agent_options.config.options.logger_plugin", Old: "tls"},
+		{Path: "packs.pack1.queries.q1.interval", Old: float64(60), New: float64(120)},
+		{
+			Path: "packs.pack1.queries.q2",
+			Old:  map[string]interface{}{"query": "q2", "description": "", "interval": float64(60)},
+		},
+		{
+			Path: "packs.pack2",
+			Old: map[string]interface{}{
+				"disabled": false,
+				"targets":  map[string]interface{}{"labels": nil, "teams": nil},
+				"queries":  map[string]interface{}{},
+			},
+		},
+		{
+			Path: "packs.pack3",
+			New: map[string]interface{}{
+				"disabled": false,
+				"targets":  map[string]interface{}{"labels": nil, "teams": nil},
+				"queries":  map[string]interface{}{},
+			},
+		},
+	}, changes)
+
+	_, err = DiffAgentConfigs(&AgentConfig{AgentOptions: rawOptions(`{`)}, updated)
+	require.Error(t, err)
+}
@@ -1,19 +1,32 @@
 package fleet
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"sort"
 )
 
 type AgentOptions struct {
 	// Config is the base config options.
 	Config json.RawMessage `json:"config"`
-	// Overrides includes any platform-based overrides.
+	// Overrides includes any platform-based or label-based overrides.
 	Overrides AgentOptionsOverrides `json:"overrides,omitempty"`
+	// CommandLineStartUpFlags are the osquery command-line flags, served to the
+	// agents that manage osquery on the hosts (they cannot be set through the
+	// config).
+	CommandLineStartUpFlags json.RawMessage `json:"command_line_flags,omitempty"`
+	// Events is the config of the osquery events-based tables, served with
+	// the command-line flags.
+	Events *EventsConfig `json:"events,omitempty"`
 }
 
 type AgentOptionsOverrides struct {
 	// Platforms is a map from platform name to the config override.
 	Platforms map[string]json.RawMessage `json:"platforms,omitempty"`
+	// Labels is a map from label name to the config merged over the options
+	// of the hosts that are members of the label.
+	Labels map[string]json.RawMessage `json:"labels,omitempty"`
 }
 
 func (o *AgentOptions) ForPlatform(platform string) json.RawMessage {
@@ -25,3 +38,180 @@ func (o *AgentOptions) ForPlatform(platform string) json.RawMessage {
 	// Otherwise return base config for team.
 	return o.Config
 }
+
+// ForLabels returns the label overrides matching the provided label names, in
+// the order they must be merged: sorted by label name, so that the resulting
+// config is deterministic when a host is a member of multiple labels.
+func (o *AgentOptions) ForLabels(labelNames []string) []json.RawMessage {
+	if len(o.Overrides.Labels) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(labelNames))
+	for _, name := range labelNames {
+		if _, ok := o.Overrides.Labels[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var opts []json.RawMessage
+	for i, name := range names {
+		if i > 0 && names[i-1] == name {
+			continue
+		}
+		opts = append(opts, o.Overrides.Labels[name])
+	}
+	return opts
+}
+
+// MergeAgentOptions merges the override config over the base config, following
+// the JSON merge patch semantics (RFC 7386): objects are merged recursively,
+// any other value in override replaces the one in base, and null values in
+// override remove the corresponding key from base.
+func MergeAgentOptions(base, override json.RawMessage) (json.RawMessage, error) {
+	if len(override) == 0 {
+		return base, nil
+	}
+	if len(base) == 0 {
+		return override, nil
+	}
+
+	var baseVal, overrideVal interface{}
+	if err := json.Unmarshal(base, &baseVal); err != nil {
+		return nil, fmt.Errorf("unmarshal base agent options: %w", err)
+	}
+	if err := json.Unmarshal(override, &overrideVal); err != nil {
+		return nil, fmt.Errorf("unmarshal override agent options: %w", err)
+	}
+	merged, err := json.Marshal(mergePatch(baseVal, overrideVal))
+	if err != nil {
+		return nil, fmt.Errorf("marshal merged agent options: %w", err)
+	}
+	return merged, nil
+}
+
+func mergePatch(base, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	baseObj, ok := base.(map[string]interface{})
+	if !ok {
+		baseObj = make(map[string]interface{})
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(baseObj, k)
+			continue
+		}
+		baseObj[k] = mergePatch(baseObj[k], v)
+	}
+	return baseObj
+}
+
+// ValidateJSONAgentOptions validates the provided agent options before they
+// are saved: the "options" section of the base config and of each override,
+// as well as the command-line flags, must only contain known osquery options
+// with values of the expected type.
+func ValidateJSONAgentOptions(rawJSON json.RawMessage) error {
+	var opts AgentOptions
+	if err := json.Unmarshal(rawJSON, &opts); err != nil {
+		return fmt.Errorf("unmarshal agent options: %w", err)
+	}
+
+	if err := validateAgentOptionsConfig(opts.Config); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	for _, platform := range sortedKeys(opts.Overrides.Platforms) {
+		if err := validateAgentOptionsConfig(opts.Overrides.Platforms[platform]); err != nil {
+			return fmt.Errorf("overrides.platforms.%s: %w", platform, err)
+		}
+	}
+	for _, label := range sortedKeys(opts.Overrides.Labels) {
+		if err := validateAgentOptionsConfig(opts.Overrides.Labels[label]); err != nil {
+			return fmt.Errorf("overrides.labels.%s: %w", label, err)
+		}
+	}
+	if len(opts.CommandLineStartUpFlags) > 0 {
+		var flags map[string]json.RawMessage
+		if err := json.Unmarshal(opts.CommandLineStartUpFlags, &flags); err != nil {
+			return fmt.Errorf("command_line_flags: %w", err)
+		}
+		if err := validateOsqueryOptions(flags); err != nil {
+			return fmt.Errorf("command_line_flags: %w", err)
+		}
+		eventsFlags := opts.Events.osqueryFlags()
+		for _, name := range sortedKeys(flags) {
+			if _, ok := eventsFlags[name]; ok {
+				return fmt.Errorf("command_line_flags: %q is already set in events", name)
+			}
+		}
+	}
+	if err := opts.Events.validate(); err != nil {
+		return fmt.Errorf("events: %w", err)
+	}
+	return nil
+}
+
+func validateAgentOptionsConfig(config json.RawMessage) error {
+	if len(config) == 0 {
+		return nil
+	}
+
+	var cfg struct {
+		Options map[string]json.RawMessage `json:"options"`
+	}
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return err
+	}
+	return validateOsqueryOptions(cfg.Options)
+}
+
+func validateOsqueryOptions(options map[string]json.RawMessage) error {
+	for _, name := range sortedKeys(options) {
+		typ, ok := osqueryOptions[name]
+		if !ok {
+			return fmt.Errorf("unknown osquery option %q", name)
+		}
+		if !typ.accepts(options[name]) {
+			return fmt.Errorf("invalid value for osquery option %q: expected %s", name, typ)
+		}
+	}
+	return nil
+}
+
+// accepts returns true if the JSON value is of the type expected by the
+// option. A null value is always accepted, as it removes the option when
+// merged as an override.
+func (t osqueryOptionType) accepts(raw json.RawMessage) bool {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return false
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return t == osqueryOptionBool
+	case string:
+		return t == osqueryOptionString
+	case json.Number:
+		_, err := v.Int64()
+		return t == osqueryOptionInt && err == nil
+	default:
+		return false
+	}
+}
+
+func sortedKeys(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
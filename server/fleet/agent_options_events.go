@@ -0,0 +1,109 @@
+package fleet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EventsConfig is the "events" section of the agent options, managing the
+// osquery flags of the events-based tables (e.g. process_events). These flags
+// are only read by osquery at startup, so they are served with the
+// command-line flags.
+type EventsConfig struct {
+	// DisableEvents disables the events-based tables. osquery disables them by
+	// default.
+	DisableEvents *bool `json:"disable_events,omitempty"`
+	// AuditAllowConfig allows osquery to configure the Linux audit subsystem.
+	AuditAllowConfig *bool `json:"audit_allow_config,omitempty"`
+	// EventsExpiry is the number of seconds after which the buffered events
+	// are expired.
+	EventsExpiry *int `json:"events_expiry,omitempty"`
+	// EventsMax is the maximum number of buffered events per table.
+	EventsMax *int `json:"events_max,omitempty"`
+}
+
+// osqueryFlags returns the osquery flags of the events config that are set.
+func (c *EventsConfig) osqueryFlags() map[string]interface{} {
+	flags := make(map[string]interface{})
+	if c == nil {
+		return flags
+	}
+	if c.DisableEvents != nil {
+		flags["disable_events"] = *c.DisableEvents
+	}
+	if c.AuditAllowConfig != nil {
+		flags["audit_allow_config"] = *c.AuditAllowConfig
+	}
+	if c.EventsExpiry != nil {
+		flags["events_expiry"] = *c.EventsExpiry
+	}
+	if c.EventsMax != nil {
+		flags["events_max"] = *c.EventsMax
+	}
+	return flags
+}
+
+func (c *EventsConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.EventsExpiry != nil && *c.EventsExpiry < 0 {
+		return errors.New("events_expiry must not be negative")
+	}
+	if c.EventsMax != nil && *c.EventsMax < 0 {
+		return errors.New("events_max must not be negative")
+	}
+	return nil
+}
+
+// StartUpFlags returns the osquery command-line flags of the agent options,
+// including the flags of the events config.
+func (o *AgentOptions) StartUpFlags() (json.RawMessage, error) {
+	eventsFlags := o.Events.osqueryFlags()
+	if len(eventsFlags) == 0 {
+		return o.CommandLineStartUpFlags, nil
+	}
+	raw, err := json.Marshal(eventsFlags)
+	if err != nil {
+		return nil, fmt.Errorf("marshal events flags: %w", err)
+	}
+	return MergeAgentOptions(o.CommandLineStartUpFlags, raw)
+}
+
+// EventsDisabled returns whether the events-based tables are disabled, which
+// is the osquery default.
+func (c *EventsConfig) EventsDisabled() bool {
+	return c == nil || c.DisableEvents == nil || *c.DisableEvents
+}
+
+// EventsConfigStatus is the events config that applies to the hosts of a
+// team (or of no team), along with the warnings about the scheduled queries
+// that use events-based tables while the events are disabled.
+type EventsConfigStatus struct {
+	// Events is the resulting events config, from the global and team events
+	// sections and command-line flags.
+	Events         *EventsConfig `json:"events"`
+	EventsDisabled bool          `json:"events_disabled"`
+	Warnings       []string      `json:"warnings"`
+}
+
+var eventsTableRegexp = regexp.MustCompile(`(?i)\b[a-z0-9_]+_events\b`)
+
+// EventsTables returns the events-based tables used by the query, sorted by
+// name.
+func EventsTables(query string) []string {
+	seen := make(map[string]struct{})
+	for _, match := range eventsTableRegexp.FindAllString(query, -1) {
+		seen[strings.ToLower(match)] = struct{}{}
+	}
+	tables := make([]string, 0, len(seen))
+	for table := range seen {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return tables
+}
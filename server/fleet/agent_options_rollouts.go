@@ -0,0 +1,43 @@
+package fleet
+
+import "encoding/json"
+
+// The statuses of an agent options rollout. Only one rollout can be active at
+// a time.
+const (
+	AgentOptionsRolloutStatusActive   = "active"
+	AgentOptionsRolloutStatusPromoted = "promoted"
+	AgentOptionsRolloutStatusAborted  = "aborted"
+)
+
+// AgentOptionsRollout is a canary rollout of new global agent options. While
+// the rollout is active, the new options are only served to the hosts of the
+// canary: a percentage of the hosts and/or the members of a label. Promoting
+// the rollout replaces the global agent options for all the hosts, aborting
+// it serves the current global agent options to the canary again.
+type AgentOptionsRollout struct {
+	UpdateCreateTimestamps
+	ID           uint            `json:"id" db:"id"`
+	AuthorID     *uint           `json:"author_id" db:"author_id"`
+	AgentOptions json.RawMessage `json:"agent_options" db:"agent_options"`
+	// Percentage is the percentage of the hosts in the canary, selected by
+	// host ID.
+	Percentage uint `json:"percentage" db:"percentage"`
+	// LabelID is the label whose members are in the canary, if any.
+	LabelID *uint  `json:"label_id" db:"label_id"`
+	Status  string `json:"status" db:"status"`
+}
+
+// IncludesHostID returns whether the host is part of the percentage of hosts
+// of the canary. The membership of the label must be checked separately.
+func (r *AgentOptionsRollout) IncludesHostID(hostID uint) bool {
+	return hostID%100 < r.Percentage
+}
+
+// AgentOptionsRolloutPayload is the payload to start an agent options
+// rollout.
+type AgentOptionsRolloutPayload struct {
+	AgentOptions json.RawMessage `json:"agent_options"`
+	Percentage   uint            `json:"percentage"`
+	LabelID      *uint           `json:"label_id"`
+}
@@ -0,0 +1,232 @@
+package fleet
+
+// osqueryOptionType is the type of the value expected by an osquery option.
+type osqueryOptionType int
+
+const (
+	osqueryOptionBool osqueryOptionType = iota
+	osqueryOptionInt
+	osqueryOptionString
+)
+
+func (t osqueryOptionType) String() string {
+	switch t {
+	case osqueryOptionBool:
+		return "boolean"
+	case osqueryOptionInt:
+		return "integer"
+	default:
+		return "string"
+	}
+}
+
+// osqueryOptions is the schema of the osquery flags that can be set in the
+// "options" section of the agent options config, as documented by
+// `osqueryd --help` (osquery 5.2).
+var osqueryOptions = map[string]osqueryOptionType{
+	"alarm_timeout":                           osqueryOptionInt,
+	"allow_unsafe":                            osqueryOptionBool,
+	"audit_allow_accept_socket_events":        osqueryOptionBool,
+	"audit_allow_apparmor_events":             osqueryOptionBool,
+	"audit_allow_config":                      osqueryOptionBool,
+	"audit_allow_fim_events":                  osqueryOptionBool,
+	"audit_allow_fork_process_events":         osqueryOptionBool,
+	"audit_allow_kill_process_events":         osqueryOptionBool,
+	"audit_allow_null_accept_socket_events":   osqueryOptionBool,
+	"audit_allow_process_events":              osqueryOptionBool,
+	"audit_allow_seccomp_events":              osqueryOptionBool,
+	"audit_allow_selinux_events":              osqueryOptionBool,
+	"audit_allow_sockets":                     osqueryOptionBool,
+	"audit_allow_user_events":                 osqueryOptionBool,
+	"audit_backlog_limit":                     osqueryOptionInt,
+	"audit_backlog_wait_time":                 osqueryOptionInt,
+	"audit_debug":                             osqueryOptionBool,
+	"audit_fim_debug":                         osqueryOptionBool,
+	"audit_fim_show_accesses":                 osqueryOptionBool,
+	"audit_force_reconfigure":                 osqueryOptionBool,
+	"audit_force_unconfigure":                 osqueryOptionBool,
+	"audit_persist":                           osqueryOptionBool,
+	"audit_show_partial_fim_events":           osqueryOptionBool,
+	"audit_show_untracked_res_warnings":       osqueryOptionBool,
+	"augeas_lenses":                           osqueryOptionString,
+	"aws_access_key_id":                       osqueryOptionString,
+	"aws_debug":                               osqueryOptionBool,
+	"aws_enable_proxy":                        osqueryOptionBool,
+	"aws_firehose_endpoint":                   osqueryOptionString,
+	"aws_kinesis_endpoint":                    osqueryOptionString,
+	"aws_profile_name":                        osqueryOptionString,
+	"aws_proxy_host":                          osqueryOptionString,
+	"aws_proxy_password":                      osqueryOptionString,
+	"aws_proxy_port":                          osqueryOptionInt,
+	"aws_proxy_scheme":                        osqueryOptionString,
+	"aws_proxy_username":                      osqueryOptionString,
+	"aws_region":                              osqueryOptionString,
+	"aws_secret_access_key":                   osqueryOptionString,
+	"aws_session_token":                       osqueryOptionString,
+	"aws_sts_arn_role":                        osqueryOptionString,
+	"aws_sts_region":                          osqueryOptionString,
+	"aws_sts_session_name":                    osqueryOptionString,
+	"aws_sts_timeout":                         osqueryOptionInt,
+	"bpf_buffer_storage_size":                 osqueryOptionInt,
+	"bpf_perf_event_array_exp":                osqueryOptionInt,
+	"buffered_log_max":                        osqueryOptionInt,
+	"carver_block_size":                       osqueryOptionInt,
+	"carver_compression":                      osqueryOptionBool,
+	"carver_continue_endpoint":                osqueryOptionString,
+	"carver_disable_function":                 osqueryOptionBool,
+	"carver_expiry":                           osqueryOptionInt,
+	"carver_start_endpoint":                   osqueryOptionString,
+	"config_accelerated_refresh":              osqueryOptionInt,
+	"config_check":                            osqueryOptionBool,
+	"config_dump":                             osqueryOptionBool,
+	"config_enable_backup":                    osqueryOptionBool,
+	"config_path":                             osqueryOptionString,
+	"config_plugin":                           osqueryOptionString,
+	"config_refresh":                          osqueryOptionInt,
+	"config_tls_endpoint":                     osqueryOptionString,
+	"config_tls_max_attempts":                 osqueryOptionInt,
+	"config_tls_refresh":                      osqueryOptionInt,
+	"daemonize":                               osqueryOptionBool,
+	"database_dump":                           osqueryOptionBool,
+	"database_path":                           osqueryOptionString,
+	"decorations_top_level":                   osqueryOptionBool,
+	"disable_audit":                           osqueryOptionBool,
+	"disable_caching":                         osqueryOptionBool,
+	"disable_carver":                          osqueryOptionBool,
+	"disable_database":                        osqueryOptionBool,
+	"disable_decorators":                      osqueryOptionBool,
+	"disable_distributed":                     osqueryOptionBool,
+	"disable_endpointsecurity":                osqueryOptionBool,
+	"disable_endpointsecurity_fim":            osqueryOptionBool,
+	"disable_events":                          osqueryOptionBool,
+	"disable_extensions":                      osqueryOptionBool,
+	"disable_forensic":                        osqueryOptionBool,
+	"disable_hash_cache":                      osqueryOptionBool,
+	"disable_logging":                         osqueryOptionBool,
+	"disable_memory":                          osqueryOptionBool,
+	"disable_reenrollment":                    osqueryOptionBool,
+	"disable_tables":                          osqueryOptionString,
+	"disable_watchdog":                        osqueryOptionBool,
+	"distributed_denylist_duration":           osqueryOptionInt,
+	"distributed_interval":                    osqueryOptionInt,
+	"distributed_loginfo":                     osqueryOptionBool,
+	"distributed_plugin":                      osqueryOptionString,
+	"distributed_tls_max_attempts":            osqueryOptionInt,
+	"distributed_tls_read_endpoint":           osqueryOptionString,
+	"distributed_tls_write_endpoint":          osqueryOptionString,
+	"docker_socket":                           osqueryOptionString,
+	"enable_bpf_events":                       osqueryOptionBool,
+	"enable_extensions_watchdog":              osqueryOptionBool,
+	"enable_file_events":                      osqueryOptionBool,
+	"enable_foreign":                          osqueryOptionBool,
+	"enable_keyboard_events":                  osqueryOptionBool,
+	"enable_monitor":                          osqueryOptionBool,
+	"enable_mouse_events":                     osqueryOptionBool,
+	"enable_ntfs_event_publisher":             osqueryOptionBool,
+	"enable_numeric_monitoring":               osqueryOptionBool,
+	"enable_powershell_events_subscriber":     osqueryOptionBool,
+	"enable_syslog":                           osqueryOptionBool,
+	"enable_tables":                           osqueryOptionString,
+	"enable_windows_events_publisher":         osqueryOptionBool,
+	"enable_windows_events_subscriber":        osqueryOptionBool,
+	"enroll_always":                           osqueryOptionBool,
+	"enroll_secret_env":                       osqueryOptionString,
+	"enroll_secret_path":                      osqueryOptionString,
+	"enroll_tls_endpoint":                     osqueryOptionString,
+	"ephemeral":                               osqueryOptionBool,
+	"es_fim_mute_path_literal":                osqueryOptionString,
+	"es_fim_mute_path_prefix":                 osqueryOptionString,
+	"events_enforce_denylist":                 osqueryOptionBool,
+	"events_expiry":                           osqueryOptionInt,
+	"events_max":                              osqueryOptionInt,
+	"events_optimize":                         osqueryOptionBool,
+	"extensions_autoload":                     osqueryOptionString,
+	"extensions_default_index":                osqueryOptionBool,
+	"extensions_interval":                     osqueryOptionInt,
+	"extensions_require":                      osqueryOptionString,
+	"extensions_socket":                       osqueryOptionString,
+	"extensions_timeout":                      osqueryOptionInt,
+	"force":                                   osqueryOptionBool,
+	"hash_cache_max":                          osqueryOptionInt,
+	"hash_delay":                              osqueryOptionInt,
+	"host_identifier":                         osqueryOptionString,
+	"logger_event_type":                       osqueryOptionBool,
+	"logger_firehose_period":                  osqueryOptionInt,
+	"logger_firehose_stream":                  osqueryOptionString,
+	"logger_kafka_acks":                       osqueryOptionString,
+	"logger_kafka_brokers":                    osqueryOptionString,
+	"logger_kafka_compression":                osqueryOptionString,
+	"logger_kafka_topic":                      osqueryOptionString,
+	"logger_kinesis_period":                   osqueryOptionInt,
+	"logger_kinesis_random_partition_key":     osqueryOptionBool,
+	"logger_kinesis_stream":                   osqueryOptionString,
+	"logger_min_status":                       osqueryOptionInt,
+	"logger_min_stderr":                       osqueryOptionInt,
+	"logger_mode":                             osqueryOptionString,
+	"logger_numerics":                         osqueryOptionBool,
+	"logger_path":                             osqueryOptionString,
+	"logger_plugin":                           osqueryOptionString,
+	"logger_rotate":                           osqueryOptionBool,
+	"logger_rotate_max_files":                 osqueryOptionInt,
+	"logger_rotate_size":                      osqueryOptionInt,
+	"logger_secondary_status_only":            osqueryOptionBool,
+	"logger_snapshot_event_type":              osqueryOptionBool,
+	"logger_status_sync":                      osqueryOptionBool,
+	"logger_stderr":                           osqueryOptionBool,
+	"logger_syslog_facility":                  osqueryOptionInt,
+	"logger_syslog_prepend_cee":               osqueryOptionBool,
+	"logger_tls_compress":                     osqueryOptionBool,
+	"logger_tls_endpoint":                     osqueryOptionString,
+	"logger_tls_max_lines":                    osqueryOptionInt,
+	"logger_tls_max_linesize":                 osqueryOptionInt,
+	"logger_tls_period":                       osqueryOptionInt,
+	"malloc_trim_threshold":                   osqueryOptionInt,
+	"nullvalue":                               osqueryOptionString,
+	"numeric_monitoring_filesystem_path":      osqueryOptionString,
+	"numeric_monitoring_plugins":              osqueryOptionString,
+	"numeric_monitoring_pre_aggregation_time": osqueryOptionInt,
+	"pack_delimiter":                          osqueryOptionString,
+	"pack_refresh_interval":                   osqueryOptionInt,
+	"pidfile":                                 osqueryOptionString,
+	"read_max":                                osqueryOptionInt,
+	"schedule_default_interval":               osqueryOptionInt,
+	"schedule_epoch":                          osqueryOptionInt,
+	"schedule_lognames":                       osqueryOptionBool,
+	"schedule_max_drift":                      osqueryOptionInt,
+	"schedule_reload":                         osqueryOptionInt,
+	"schedule_splay_percent":                  osqueryOptionInt,
+	"schedule_timeout":                        osqueryOptionInt,
+	"specified_identifier":                    osqueryOptionString,
+	"syslog_events_expiry":                    osqueryOptionInt,
+	"syslog_events_max":                       osqueryOptionInt,
+	"syslog_pipe_path":                        osqueryOptionString,
+	"syslog_rate_limit":                       osqueryOptionInt,
+	"table_delay":                             osqueryOptionInt,
+	"thrift_string_size_limit":                osqueryOptionInt,
+	"thrift_timeout":                          osqueryOptionInt,
+	"thrift_verbose":                          osqueryOptionBool,
+	"tls_client_cert":                         osqueryOptionString,
+	"tls_client_key":                          osqueryOptionString,
+	"tls_disable_status_log":                  osqueryOptionBool,
+	"tls_dump":                                osqueryOptionBool,
+	"tls_enroll_max_attempts":                 osqueryOptionInt,
+	"tls_enroll_max_interval":                 osqueryOptionInt,
+	"tls_hostname":                            osqueryOptionString,
+	"tls_node_api":                            osqueryOptionBool,
+	"tls_server_certs":                        osqueryOptionString,
+	"tls_session_reuse":                       osqueryOptionBool,
+	"tls_session_timeout":                     osqueryOptionInt,
+	"utc":                                     osqueryOptionBool,
+	"value_max":                               osqueryOptionInt,
+	"verbose":                                 osqueryOptionBool,
+	"watchdog_delay":                          osqueryOptionInt,
+	"watchdog_forced_shutdown_delay":          osqueryOptionInt,
+	"watchdog_latency_limit":                  osqueryOptionInt,
+	"watchdog_level":                          osqueryOptionInt,
+	"watchdog_memory_limit":                   osqueryOptionInt,
+	"watchdog_utilization_limit":              osqueryOptionInt,
+	"windows_event_channels":                  osqueryOptionString,
+	"worker_threads":                          osqueryOptionInt,
+	"yara_delay":                              osqueryOptionInt,
+	"yara_malloc_trim":                        osqueryOptionBool,
+}
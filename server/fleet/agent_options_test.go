@@ -0,0 +1,130 @@
+package fleet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeAgentOptions(t *testing.T) {
+	cases := []struct {
+		name     string
+		base     string
+		override string
+		want     string
+	}{
+		{"empty override", `{"a":1}`, ``, `{"a":1}`},
+		{"empty base", ``, `{"a":1}`, `{"a":1}`},
+		{"replace value", `{"a":1,"b":2}`, `{"a":3}`, `{"a":3,"b":2}`},
+		{"nested objects", `{"options":{"a":1,"b":2},"c":3}`, `{"options":{"b":4,"d":5}}`, `{"options":{"a":1,"b":4,"d":5},"c":3}`},
+		{"remove with null", `{"options":{"a":1,"b":2}}`, `{"options":{"b":null}}`, `{"options":{"a":1}}`},
+		{"arrays are replaced", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+		{"object replaces scalar", `{"a":1}`, `{"a":{"b":1}}`, `{"a":{"b":1}}`},
+		{"null base", `null`, `{"a":1}`, `{"a":1}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := MergeAgentOptions(json.RawMessage(c.base), json.RawMessage(c.override))
+			require.NoError(t, err)
+			assert.JSONEq(t, c.want, string(got))
+		})
+	}
+
+	_, err := MergeAgentOptions(json.RawMessage(`{`), json.RawMessage(`{"a":1}`))
+	require.Error(t, err)
+}
+
+func TestAgentOptionsForLabels(t *testing.T) {
+	var opts AgentOptions
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"config":{"options":{"a":1}},
+		"overrides":{"labels":{"b-label":{"b":1},"a-label":{"a":2},"c-label":{"c":1}}}
+	}`), &opts))
+
+	assert.Nil(t, opts.ForLabels(nil))
+	assert.Nil(t, opts.ForLabels([]string{"unknown"}))
+
+	// overrides are returned sorted by label name, regardless of the order of the labels
+	got := opts.ForLabels([]string{"c-label", "unknown", "a-label", "a-label"})
+	require.Len(t, got, 2)
+	assert.JSONEq(t, `{"a":2}`, string(got[0]))
+	assert.JSONEq(t, `{"c":1}`, string(got[1]))
+
+	var noLabels AgentOptions
+	require.NoError(t, json.Unmarshal([]byte(`{"config":{"options":{"a":1}}}`), &noLabels))
+	assert.Nil(t, noLabels.ForLabels([]string{"a-label"}))
+}
+
+func TestValidateJSONAgentOptions(t *testing.T) {
+	cases := []struct {
+		name    string
+		opts    string
+		wantErr string
+	}{
+		{"empty config", `{}`, ""},
+		{"no options", `{"config":{"decorators":{"load":["SELECT 1"]}}}`, ""},
+		{"valid options", `{"config":{"options":{"distributed_interval":10,"logger_plugin":"tls","disable_distributed":false}}}`, ""},
+		{"null option", `{"config":{"options":{"distributed_interval":null}}}`, ""},
+		{"unknown option", `{"config":{"options":{"distributed_intervall":10}}}`, `config: unknown osquery option "distributed_intervall"`},
+		{"int as string", `{"config":{"options":{"distributed_interval":"10"}}}`, `config: invalid value for osquery option "distributed_interval": expected integer`},
+		{"float for int", `{"config":{"options":{"distributed_interval":1.5}}}`, `expected integer`},
+		{"string as bool", `{"config":{"options":{"disable_distributed":"false"}}}`, `expected boolean`},
+		{"int as string option", `{"config":{"options":{"logger_plugin":1}}}`, `expected string`},
+		{"object value", `{"config":{"options":{"logger_plugin":{}}}}`, `expected string`},
+		{"options not an object", `{"config":{"options":[]}}`, `config: json: cannot unmarshal`},
+		{"invalid platform override", `{"overrides":{"platforms":{"darwin":{"options":{"foo":1}}}}}`, `overrides.platforms.darwin: unknown osquery option "foo"`},
+		{"invalid label override", `{"overrides":{"labels":{"a":{"options":{"utc":1}}}}}`, `overrides.labels.a: invalid value for osquery option "utc": expected boolean`},
+		{"valid overrides", `{"overrides":{"platforms":{"darwin":{"options":{"utc":true}}},"labels":{"a":{"options":{"watchdog_memory_limit":100}}}}}`, ""},
+		{"valid command line flags", `{"command_line_flags":{"enable_file_events":true,"verbose":false}}`, ""},
+		{"unknown command line flag", `{"command_line_flags":{"enable_file_event":true}}`, `command_line_flags: unknown osquery option "enable_file_event"`},
+		{"invalid command line flag", `{"command_line_flags":{"enable_file_events":"true"}}`, `command_line_flags: invalid value for osquery option "enable_file_events": expected boolean`},
+		{"command line flags not an object", `{"command_line_flags":["--verbose"]}`, `command_line_flags: json: cannot unmarshal`},
+		{"valid events", `{"events":{"disable_events":false,"audit_allow_config":true,"events_expiry":3600,"events_max":50000}}`, ""},
+		{"invalid events type", `{"events":{"events_max":"10"}}`, "unmarshal agent options"},
+		{"negative events max", `{"events":{"events_max":-1}}`, "events: events_max must not be negative"},
+		{"events flag also in command line flags", `{"events":{"disable_events":false},"command_line_flags":{"disable_events":true}}`, `command_line_flags: "disable_events" is already set in events`},
+		{"invalid json", `{`, "unmarshal agent options"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateJSONAgentOptions(json.RawMessage(c.opts))
+			if c.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), c.wantErr)
+		})
+	}
+}
+
+func TestAgentOptionsStartUpFlags(t *testing.T) {
+	opts := AgentOptions{CommandLineStartUpFlags: json.RawMessage(`{"verbose":true}`)}
+	flags, err := opts.StartUpFlags()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"verbose":true}`, string(flags))
+
+	disabled, expiry := false, 3600
+	opts.Events = &EventsConfig{DisableEvents: &disabled, EventsExpiry: &expiry}
+	flags, err = opts.StartUpFlags()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"verbose":true,"disable_events":false,"events_expiry":3600}`, string(flags))
+
+	opts.CommandLineStartUpFlags = nil
+	flags, err = opts.StartUpFlags()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"disable_events":false,"events_expiry":3600}`, string(flags))
+	assert.False(t, opts.Events.EventsDisabled())
+
+	// osquery disables the events by default
+	assert.True(t, (&EventsConfig{}).EventsDisabled())
+}
+
+func TestEventsTables(t *testing.T) {
+	assert.Empty(t, EventsTables(`SELECT * FROM processes`))
+	assert.Equal(t, []string{"process_events", "socket_events"}, EventsTables(
+		`SELECT * FROM Process_Events p JOIN socket_events s USING (pid) JOIN process_events p2 USING (pid)`,
+	))
+}
@@ -2,6 +2,7 @@ package fleet
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -119,6 +120,10 @@ type AppConfig struct {
 
 	WebhookSettings WebhookSettings `json:"webhook_settings"`
 	Integrations    Integrations    `json:"integrations"`
+
+	// ScheduleSettings defines the guardrails applied to the intervals of the
+	// scheduled queries.
+	ScheduleSettings ScheduleSettings `json:"schedule_settings"`
 }
 
 // EnrichedAppConfig contains the AppConfig along with additional fleet
@@ -173,6 +178,9 @@ type WebhookSettings struct {
 	HostStatusWebhook      HostStatusWebhookSettings      `json:"host_status_webhook"`
 	FailingPoliciesWebhook FailingPoliciesWebhookSettings `json:"failing_policies_webhook"`
 	VulnerabilitiesWebhook VulnerabilitiesWebhookSettings `json:"vulnerabilities_webhook"`
+	HostOnlineWebhook      HostOnlineWebhookSettings      `json:"host_online_webhook"`
+	BatchQueryWebhook      BatchQueryWebhookSettings      `json:"batch_query_webhook"`
+	HostLifecycleWebhook   HostLifecycleWebhookSettings   `json:"host_lifecycle_webhook"`
 	// Interval is the interval for running the webhooks.
 	//
 	// This value currently configures both the host status and failing policies webhooks.
@@ -197,6 +205,9 @@ type FailingPoliciesWebhookSettings struct {
 	// HostBatchSize allows sending multiple requests in batches of hosts for each policy.
 	// A value of 0 means no batching.
 	HostBatchSize int `json:"host_batch_size"`
+	// MaxHosts is the maximum number of hosts sent for each policy every time the webhook runs,
+	// the other failing hosts are sent on the next runs. A value of 0 means no limit.
+	MaxHosts int `json:"max_hosts"`
 }
 
 // VulnerabilitiesWebhookSettings holds the settings for vulnerabilities webhooks.
@@ -210,6 +221,66 @@ type VulnerabilitiesWebhookSettings struct {
 	HostBatchSize int `json:"host_batch_size"`
 }
 
+// HostOnlineWebhookSettings holds the settings for the webhook triggered when
+// a host users subscribed to checks in. Unlike the other webhooks, it is not
+// run at the webhooks interval but as soon as the host is seen.
+type HostOnlineWebhookSettings struct {
+	// Enable indicates whether the webhook for hosts coming online is enabled.
+	Enable bool `json:"enable_host_online_webhook"`
+	// DestinationURL is the webhook's URL.
+	DestinationURL string `json:"destination_url"`
+}
+
+// BatchQueryWebhookSettings holds the settings for the webhook triggered when
+// a batch query reaches its completion threshold. Like the host online
+// webhook, it is not run at the webhooks interval but as soon as the threshold
+// is reached.
+type BatchQueryWebhookSettings struct {
+	// Enable indicates whether the webhook for batch queries is enabled.
+	Enable bool `json:"enable_batch_query_webhook"`
+	// DestinationURL is the webhook's URL.
+	DestinationURL string `json:"destination_url"`
+}
+
+// HostLifecycleWebhookSettings holds the settings for the webhook triggered
+// when a host enrolls, goes offline or is deleted. Unlike the other webhooks,
+// the events are sent every minute, in batches.
+type HostLifecycleWebhookSettings struct {
+	// Enable indicates whether the webhook for the host lifecycle events is enabled.
+	Enable bool `json:"enable_host_lifecycle_webhook"`
+	// DestinationURL is the webhook's URL.
+	DestinationURL string `json:"destination_url"`
+	// Secret signs the requests with an HMAC-SHA256 signature if not empty.
+	Secret string `json:"secret"`
+	// OfflineThreshold is how long a host must not check in to be reported
+	// offline. A value of 0 means HostLifecycleDefaultOfflineThreshold.
+	OfflineThreshold Duration `json:"offline_threshold"`
+}
+
+// ScheduleSettings configures the minimum intervals allowed for scheduled
+// queries, to prevent queries from accidentally running too frequently on
+// the hosts.
+type ScheduleSettings struct {
+	// MinInterval is the minimum interval, in seconds, of scheduled queries.
+	// A value of 0 disables the check.
+	MinInterval uint `json:"min_interval"`
+	// MinSnapshotInterval is the minimum interval, in seconds, of snapshot
+	// scheduled queries. A value of 0 disables the check.
+	MinSnapshotInterval uint `json:"min_snapshot_interval"`
+}
+
+// VerifyInterval returns an error if the interval of a scheduled query is
+// below the minimum configured for its type of query.
+func (s ScheduleSettings) VerifyInterval(interval uint, snapshot bool) error {
+	if interval < s.MinInterval {
+		return NewInvalidArgumentError("interval", fmt.Sprintf("must be at least %d seconds", s.MinInterval))
+	}
+	if snapshot && interval < s.MinSnapshotInterval {
+		return NewInvalidArgumentError("interval", fmt.Sprintf("must be at least %d seconds for snapshot queries", s.MinSnapshotInterval))
+	}
+	return nil
+}
+
 // JiraIntegration configures an instance of an integration with the Jira
 // system.
 type JiraIntegration struct {
@@ -218,6 +289,10 @@ type JiraIntegration struct {
 	Password                      string `json:"password"`
 	ProjectKey                    string `json:"project_key"`
 	EnableSoftwareVulnerabilities bool   `json:"enable_software_vulnerabilities"`
+	// IssueType is the type of the issues created for the failing policies,
+	// "Task" if empty.
+	IssueType             string `json:"issue_type"`
+	EnableFailingPolicies bool   `json:"enable_failing_policies"`
 }
 
 // Integrations configures the integrations with external systems.
@@ -225,6 +300,17 @@ type Integrations struct {
 	Jira []*JiraIntegration `json:"jira"`
 }
 
+// FailingPoliciesJira returns the Jira integration enabled for the failing
+// policies, or nil if there is none.
+func (i Integrations) FailingPoliciesJira() *JiraIntegration {
+	for _, jira := range i.Jira {
+		if jira.EnableFailingPolicies {
+			return jira
+		}
+	}
+	return nil
+}
+
 func (c *AppConfig) ApplyDefaultsForNewInstalls() {
 	c.ServerSettings.EnableAnalytics = true
 
@@ -245,7 +331,10 @@ func (c *AppConfig) ApplyDefaultsForNewInstalls() {
 
 func (c *AppConfig) ApplyDefaults() {
 	c.HostSettings.EnableHostUsers = true
+	c.HostSettings.DisplayNameSources = []string{HostDisplayNameSourceComputerName, HostDisplayNameSourceHostname}
 	c.WebhookSettings.Interval.Duration = 24 * time.Hour
+	c.ScheduleSettings.MinInterval = 10
+	c.ScheduleSettings.MinSnapshotInterval = 60
 }
 
 // OrgInfo contains general info about the organization using Fleet.
@@ -273,6 +362,26 @@ type HostSettings struct {
 	EnableHostUsers         bool             `json:"enable_host_users"`
 	EnableSoftwareInventory bool             `json:"enable_software_inventory"`
 	AdditionalQueries       *json.RawMessage `json:"additional_queries,omitempty"`
+	// DisplayNameSources are the host fields used as the display name of the
+	// hosts, in order of preference: the first non-empty one is used.
+	DisplayNameSources []string `json:"display_name_sources,omitempty"`
+	// OfflineThreshold is the minimum duration without checking in after
+	// which a host is offline. By default (0), the hosts are offline once they
+	// miss their check-in interval.
+	OfflineThreshold Duration `json:"offline_threshold"`
+	// MissingThresholdDays is the number of days without checking in after
+	// which a host is missing. By default (0), the hosts are never missing,
+	// they are offline until they are MIA.
+	MissingThresholdDays int `json:"missing_threshold_days"`
+}
+
+// MissingThreshold returns the duration without checking in after which a
+// host is missing, 0 if the hosts are never missing.
+func (s HostSettings) MissingThreshold() time.Duration {
+	if s.MissingThresholdDays <= 0 {
+		return 0
+	}
+	return time.Duration(s.MissingThresholdDays) * 24 * time.Hour
 }
 
 type OrderDirection int
@@ -317,6 +426,10 @@ type ListQueryOptions struct {
 	ListOptions
 
 	OnlyObserverCanRun bool
+	// TeamFilter, if set, restricts the queries to the global queries and the
+	// queries of the teams the user has access to. If its TeamID is set, only
+	// the queries of that team are returned.
+	TeamFilter *TeamFilter
 }
 
 // EnrollSecret contains information about an enroll secret, name, and active
@@ -329,12 +442,42 @@ type EnrollSecret struct {
 	// TeamID is the ID for the associated team. If no ID is set, then this is a
 	// global enroll secret.
 	TeamID *uint `json:"team_id,omitempty" db:"team_id"`
+	// ExpiresAt is the time after which the secret can no longer be used to
+	// enroll hosts. If not set, the secret never expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	// MaxUses is the maximum number of enrollments allowed with this secret.
+	// If not set, the secret can be used an unlimited number of times.
+	MaxUses *uint `json:"max_uses,omitempty" db:"max_uses"`
+	// Uses is the number of enrollments done with this secret. It is only
+	// tracked for secrets with MaxUses set.
+	Uses uint `json:"uses,omitempty" db:"uses"`
 }
 
 func (e *EnrollSecret) AuthzType() string {
 	return "enroll_secret"
 }
 
+// Validate checks that the enroll secret is well-formed.
+func (e *EnrollSecret) Validate() error {
+	if e.Secret == "" {
+		return NewInvalidArgumentError("secret", "enroll secret must not be empty")
+	}
+	if e.MaxUses != nil && *e.MaxUses == 0 {
+		return NewInvalidArgumentError("max_uses", "must be greater than zero")
+	}
+	return nil
+}
+
+var (
+	// ErrEnrollSecretNotFound is returned when an unknown enroll secret is used.
+	ErrEnrollSecretNotFound = errors.New("no matching secret found")
+	// ErrEnrollSecretExpired is returned when an expired enroll secret is used.
+	ErrEnrollSecretExpired = errors.New("enroll secret expired")
+	// ErrEnrollSecretExhausted is returned when an enroll secret that reached
+	// its maximum number of uses is used.
+	ErrEnrollSecretExhausted = errors.New("enroll secret reached its maximum number of uses")
+)
+
 const (
 	EnrollSecretKind          = "enroll_secret"
 	EnrollSecretDefaultLength = 24
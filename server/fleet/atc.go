@@ -0,0 +1,132 @@
+package fleet
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// ATCTable is an osquery Automatic Table Construction (ATC) table, which
+// exposes the result of a query on a local SQLite database as an osquery
+// table. See
+// https://osquery.readthedocs.io/en/stable/deployment/configuration/#automatic-table-construction
+type ATCTable struct {
+	UpdateCreateTimestamps
+	ID uint `json:"id" db:"id"`
+	// Name is the name of the osquery table.
+	Name string `json:"name" db:"name"`
+	// Query is the query run on the SQLite database to populate the table.
+	Query string `json:"query" db:"query"`
+	// Path is the path of the SQLite database on the host. It may contain
+	// wildcards.
+	Path string `json:"path" db:"path"`
+	// Columns are the names of the columns of the table, in the order of the
+	// columns returned by Query.
+	Columns ATCColumns `json:"columns" db:"columns"`
+	// Platform is the platform of the hosts that receive the table (darwin,
+	// linux or windows). Empty means all platforms.
+	Platform string `json:"platform" db:"platform"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (t ATCTable) AuthzType() string {
+	return "atc_table"
+}
+
+// ATCTableConfig is the configuration of an ATC table as expected by osquery
+// in the auto_table_construction section of the config.
+type ATCTableConfig struct {
+	Query    string   `json:"query"`
+	Path     string   `json:"path"`
+	Columns  []string `json:"columns"`
+	Platform string   `json:"platform,omitempty"`
+}
+
+// Config returns the osquery configuration of the table.
+func (t ATCTable) Config() ATCTableConfig {
+	return ATCTableConfig{
+		Query:    t.Query,
+		Path:     t.Path,
+		Columns:  t.Columns,
+		Platform: t.Platform,
+	}
+}
+
+// ATCColumns is the list of columns of an ATC table, stored as JSON.
+type ATCColumns []string
+
+// Scan implements the sql.Scanner interface
+func (c *ATCColumns) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil: // sql NULL
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements the sql.Valuer interface
+func (c ATCColumns) Value() (driver.Value, error) {
+	if c == nil {
+		c = ATCColumns{}
+	}
+	return json.Marshal(c)
+}
+
+// ATCTablePayload holds the data to create or modify an ATC table. Nil fields
+// are left unchanged when modifying a table.
+type ATCTablePayload struct {
+	Name     *string     `json:"name"`
+	Query    *string     `json:"query"`
+	Path     *string     `json:"path"`
+	Columns  *ATCColumns `json:"columns"`
+	Platform *string     `json:"platform"`
+}
+
+var (
+	atcTableNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+	errATCTableInvalidName     = errors.New("table name must only contain letters, digits and underscores, and cannot start with a digit")
+	errATCTableEmptyQuery      = errors.New("table query cannot be empty")
+	errATCTableEmptyPath       = errors.New("table path cannot be empty")
+	errATCTableEmptyColumns    = errors.New("table must have at least one column")
+	errATCTableInvalidColumn   = errors.New("table column names cannot be empty")
+	errATCTableInvalidPlatform = errors.New("table platform must be one of darwin, linux or windows")
+)
+
+// Verify verifies the fields set in the payload are valid.
+func (p ATCTablePayload) Verify() error {
+	if p.Name != nil && !atcTableNameRegexp.MatchString(*p.Name) {
+		return errATCTableInvalidName
+	}
+	if p.Query != nil && *p.Query == "" {
+		return errATCTableEmptyQuery
+	}
+	if p.Path != nil && *p.Path == "" {
+		return errATCTableEmptyPath
+	}
+	if p.Columns != nil {
+		if len(*p.Columns) == 0 {
+			return errATCTableEmptyColumns
+		}
+		for _, col := range *p.Columns {
+			if col == "" {
+				return errATCTableInvalidColumn
+			}
+		}
+	}
+	if p.Platform != nil {
+		switch *p.Platform {
+		case "", "darwin", "linux", "windows":
+		default:
+			return errATCTableInvalidPlatform
+		}
+	}
+	return nil
+}
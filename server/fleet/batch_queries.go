@@ -0,0 +1,55 @@
+package fleet
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BatchQuery is a distributed query campaign run as a batch query: the hosts
+// run the query as they check in, over hours or days, and their results are
+// stored instead of being streamed to the clients. A webhook is triggered once
+// the completion threshold is reached.
+type BatchQuery struct {
+	// CampaignID is the ID of the campaign of the batch query, which also
+	// identifies the batch query.
+	CampaignID uint      `json:"campaign_id" db:"distributed_query_campaign_id"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	QueryID    uint      `json:"query_id" db:"query_id"`
+	// Status is the status of the campaign of the batch query.
+	Status DistributedQueryStatus `json:"status" db:"status"`
+	// CompletionThreshold is the percentage of the targeted hosts that must
+	// respond for the batch query to reach its completion threshold.
+	CompletionThreshold uint `json:"completion_threshold" db:"completion_threshold"`
+	// TargetedHosts is the number of hosts targeted by the batch query when it
+	// was launched.
+	TargetedHosts uint `json:"targeted_hosts" db:"targeted_hosts"`
+	// RespondedHosts is the number of hosts that sent a result, including the
+	// failed ones.
+	RespondedHosts uint `json:"responded_hosts" db:"responded_hosts"`
+	// ExpiresAt is the time the hosts stop receiving the query.
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	// NotifiedAt is the time the completion threshold was reached, nil until
+	// then.
+	NotifiedAt *time.Time `json:"notified_at" db:"notified_at"`
+}
+
+// ThresholdReached returns true if enough targeted hosts responded for the
+// batch query to reach its completion threshold.
+func (bq *BatchQuery) ThresholdReached() bool {
+	return bq.RespondedHosts*100 >= bq.CompletionThreshold*bq.TargetedHosts
+}
+
+// BatchQueryResult is the result of a host for a batch query.
+type BatchQueryResult struct {
+	// HostID is the ID of the host, it is zero in the results of anonymized
+	// batch queries.
+	HostID uint `json:"host_id" db:"host_id"`
+	// Hostname is the hostname of the host when it responded, or its pseudonym
+	// for the anonymized batch queries.
+	Hostname string `json:"hostname" db:"hostname"`
+	// Rows are the rows of the result, encoded as JSON.
+	Rows      json.RawMessage `json:"rows" db:"result_rows"`
+	Error     *string         `json:"error" db:"error"`
+	Truncated bool            `json:"truncated" db:"truncated"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
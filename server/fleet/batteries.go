@@ -0,0 +1,14 @@
+package fleet
+
+// HostBattery is a battery of a host, with the health information used to
+// plan its replacement.
+type HostBattery struct {
+	SerialNumber string `json:"serial_number" db:"serial_number"`
+	CycleCount   int    `json:"cycle_count" db:"cycle_count"`
+	// Health is the health of the battery reported by the host, e.g. Good,
+	// Fair or Poor on macOS.
+	Health string `json:"health" db:"health"`
+	// Condition is the condition of the battery reported by the host, e.g.
+	// Normal or Service recommended on macOS.
+	Condition string `json:"condition" db:"condition"`
+}
@@ -19,6 +19,37 @@ type DistributedQueryCampaign struct {
 	QueryID uint                   `json:"query_id" db:"query_id"`
 	Status  DistributedQueryStatus `json:"status"`
 	UserID  uint                   `json:"user_id" db:"user_id"`
+	// Anonymize indicates whether the host identifiers are replaced by stable
+	// pseudonyms in the results of the campaign.
+	Anonymize bool `json:"anonymize" db:"anonymize"`
+	// PseudonymKey is the secret key used to derive the pseudonyms of an
+	// anonymized campaign. It is never sent to the clients.
+	PseudonymKey string `json:"-" db:"pseudonym_key"`
+	// LabelExpression is the label expression matching the hosts targeted by
+	// the campaign, if any.
+	LabelExpression string `json:"label_expression,omitempty" db:"label_expression"`
+	// OnlineOnly indicates whether the campaign only targets the hosts that
+	// were online when it was launched.
+	OnlineOnly bool `json:"online_only" db:"online_only"`
+	// Batch indicates whether the campaign is run as a batch query, its
+	// results are stored instead of being streamed to the clients.
+	Batch bool `json:"batch" db:"batch"`
+	// DuplicateResults overrides, for the campaign, whether its results are
+	// duplicated to the LQDuplicate Redis channel. If nil, the
+	// redis.duplicate_results configuration applies.
+	DuplicateResults *bool `json:"duplicate_results" db:"duplicate_results"`
+	DistributedQueryResultLimits
+}
+
+// DistributedQueryResultLimits are the limits of the result of each host of
+// a distributed query campaign. The rows exceeding the limits are dropped, and
+// the result is flagged as truncated. A zero limit means no limit.
+type DistributedQueryResultLimits struct {
+	// MaxRows is the maximum number of rows of a result.
+	MaxRows uint `json:"max_rows" db:"max_rows"`
+	// MaxResultBytes is the maximum size of the rows of a result, as the size
+	// of their column names and values.
+	MaxResultBytes uint `json:"max_result_bytes" db:"max_result_bytes"`
 }
 
 // DistributedQueryCampaignTarget stores a target (host or label) for a
@@ -29,6 +60,38 @@ type DistributedQueryCampaignTarget struct {
 	Type                       TargetType
 	DistributedQueryCampaignID uint `db:"distributed_query_campaign_id"`
 	TargetID                   uint `db:"target_id"`
+	// Excluded indicates whether the target (host or label) is excluded from
+	// the campaign, its hosts are not targeted even if they match the other
+	// targets.
+	Excluded bool `db:"excluded"`
+}
+
+// DistributedQueryExecutionStatus is the status of the execution of the query
+// of a distributed query campaign on a targeted host.
+type DistributedQueryExecutionStatus int
+
+const (
+	ExecutionPending DistributedQueryExecutionStatus = iota
+	ExecutionSucceeded
+	ExecutionFailed
+)
+
+// DistributedQueryCampaignProgress is the progress of a distributed query
+// campaign, computed from the executions of its query on the targeted hosts.
+type DistributedQueryCampaignProgress struct {
+	// TargetedHosts is the number of hosts targeted by the campaign when it
+	// was launched.
+	TargetedHosts uint `json:"targeted_hosts" db:"targeted_hosts"`
+	// OnlineHostsAtLaunch is the number of targeted hosts that were online
+	// when the campaign was launched.
+	OnlineHostsAtLaunch uint `json:"online_hosts_at_launch" db:"online_hosts_at_launch"`
+	// RespondedHosts is the number of hosts that sent a result, including the
+	// failed ones.
+	RespondedHosts uint `json:"responded_hosts" db:"responded_hosts"`
+	// FailedHosts is the number of hosts that failed to run the query.
+	FailedHosts uint `json:"failed_hosts" db:"failed_hosts"`
+	// PendingHosts is the number of hosts that did not send a result yet.
+	PendingHosts uint `json:"pending_hosts" db:"pending_hosts"`
 }
 
 // DistributedQueryResult is the result returned from the execution of a
@@ -42,6 +105,26 @@ type DistributedQueryResult struct {
 	// that we can't use the error interface here because something
 	// implementing that interface may not (un)marshal properly
 	Error *string `json:"error"`
+	// Truncated is true if rows were dropped because the result exceeded the
+	// limits of the campaign.
+	Truncated bool `json:"truncated"`
+	// DuplicateResults is the DuplicateResults override of the campaign, used
+	// by the result store when the result is written. It is not sent to the
+	// clients.
+	DuplicateResults *bool `json:"-"`
+}
+
+// CampaignResultsStream is a connection over which the results of a
+// distributed query campaign are streamed to a client, such as a websocket or
+// a Server-Sent Events response.
+type CampaignResultsStream interface {
+	// WriteJSONMessage writes a message of type typ with the provided data as
+	// JSON.
+	WriteJSONMessage(typ string, data interface{}) error
+	// WriteJSONError writes an error message with the provided data as JSON.
+	WriteJSONError(data interface{}) error
+	// Closed returns true if the client closed the connection.
+	Closed() bool
 }
 
 type QueryResult struct {
@@ -0,0 +1,17 @@
+package fleet
+
+import "time"
+
+// HostCertificate is a certificate installed on a host, as reported by the
+// osquery certificates table. The certificates are identified by their SHA1
+// fingerprint.
+type HostCertificate struct {
+	SHA1           string    `json:"sha1" db:"sha1"`
+	CommonName     string    `json:"common_name" db:"common_name"`
+	Subject        string    `json:"subject" db:"subject"`
+	Issuer         string    `json:"issuer" db:"issuer"`
+	CA             bool      `json:"ca" db:"ca"`
+	SelfSigned     bool      `json:"self_signed" db:"self_signed"`
+	NotValidBefore time.Time `json:"not_valid_before" db:"not_valid_before"`
+	NotValidAfter  time.Time `json:"not_valid_after" db:"not_valid_after"`
+}
@@ -0,0 +1,120 @@
+package fleet
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Types of widgets that can be added to a dashboard.
+const (
+	// DashboardWidgetPolicy displays the passing and failing hosts of a policy.
+	DashboardWidgetPolicy = "policy"
+	// DashboardWidgetLabel displays the hosts of a label.
+	DashboardWidgetLabel = "label"
+	// DashboardWidgetSoftware displays the results of a software search.
+	DashboardWidgetSoftware = "software"
+	// DashboardWidgetOSVersions displays the chart of operating system versions.
+	DashboardWidgetOSVersions = "os_versions"
+)
+
+// Dashboard is a user-defined, named collection of widgets.
+type Dashboard struct {
+	UpdateCreateTimestamps
+	ID          uint   `json:"id" db:"id"`
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+	// UserID is the ID of the user who owns the dashboard.
+	UserID uint `json:"user_id" db:"user_id"`
+	// TeamID is the ID of the team the dashboard belongs to, nil for global
+	// dashboards.
+	TeamID *uint `json:"team_id" db:"team_id"`
+	// Shared indicates whether the dashboard is visible to the other users of
+	// its team (or to all users for global dashboards). Dashboards that are not
+	// shared are only visible to their owner.
+	Shared  bool             `json:"shared" db:"shared"`
+	Widgets DashboardWidgets `json:"widgets" db:"widgets"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (d Dashboard) AuthzType() string {
+	return "dashboard"
+}
+
+// DashboardWidget is a widget displayed in a dashboard.
+type DashboardWidget struct {
+	// Type is the type of the widget, one of the DashboardWidget constants.
+	Type  string `json:"type"`
+	Title string `json:"title,omitempty"`
+	// ReferenceID is the ID of the policy or label displayed by policy and
+	// label widgets.
+	ReferenceID *uint `json:"reference_id,omitempty"`
+	// Query is the search displayed by software widgets.
+	Query string `json:"query,omitempty"`
+	// Options holds the display options of the widget (position, size, etc.),
+	// which are opaque to the server.
+	Options *json.RawMessage `json:"options,omitempty"`
+}
+
+// DashboardWidgets is the list of widgets of a dashboard, stored as JSON.
+type DashboardWidgets []DashboardWidget
+
+// Scan implements the sql.Scanner interface
+func (w *DashboardWidgets) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, w)
+	case string:
+		return json.Unmarshal([]byte(v), w)
+	case nil: // sql NULL
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements the sql.Valuer interface
+func (w DashboardWidgets) Value() (driver.Value, error) {
+	if w == nil {
+		w = DashboardWidgets{}
+	}
+	return json.Marshal(w)
+}
+
+// DashboardPayload holds the data to create or modify a dashboard. Nil fields
+// are left unchanged when modifying a dashboard.
+type DashboardPayload struct {
+	Name        *string           `json:"name"`
+	Description *string           `json:"description"`
+	TeamID      *uint             `json:"team_id"`
+	Shared      *bool             `json:"shared"`
+	Widgets     *DashboardWidgets `json:"widgets"`
+}
+
+var (
+	errDashboardEmptyName        = errors.New("dashboard name cannot be empty")
+	errDashboardInvalidWidget    = errors.New("invalid dashboard widget type")
+	errDashboardMissingReference = errors.New("dashboard widget reference_id is required for policy and label widgets")
+)
+
+// Verify verifies the dashboard payload is valid.
+func (p DashboardPayload) Verify() error {
+	if p.Name != nil && *p.Name == "" {
+		return errDashboardEmptyName
+	}
+	if p.Widgets != nil {
+		for _, w := range *p.Widgets {
+			switch w.Type {
+			case DashboardWidgetPolicy, DashboardWidgetLabel:
+				if w.ReferenceID == nil {
+					return errDashboardMissingReference
+				}
+			case DashboardWidgetSoftware, DashboardWidgetOSVersions:
+			default:
+				return errDashboardInvalidWidget
+			}
+		}
+	}
+	return nil
+}
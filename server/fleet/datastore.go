@@ -85,12 +85,73 @@ type Datastore interface {
 
 	// CleanupDistributedQueryCampaigns will clean and trim metadata for old distributed query campaigns. Any campaign
 	// in the QueryWaiting state will be moved to QueryComplete after one minute. Any campaign in the QueryRunning state
-	// will be moved to QueryComplete after one day. Times are from creation time. The now parameter makes this method
-	// easier to test. The return values indicate how many campaigns were expired and any error.
-	CleanupDistributedQueryCampaigns(ctx context.Context, now time.Time) (expired uint, err error)
+	// will be moved to QueryComplete after runningTTL. Times are from creation time. The campaigns of batch queries are
+	// instead moved to QueryComplete once their batch query expired. The now parameter makes this method easier to
+	// test. The return values are the IDs of the campaigns that were expired and any error.
+	CleanupDistributedQueryCampaigns(ctx context.Context, now time.Time, runningTTL time.Duration) (expired []uint, err error)
 
 	DistributedQueryCampaignsForQuery(ctx context.Context, queryID uint) ([]*DistributedQueryCampaign, error)
 
+	// NewDistributedQueryExecutions records the pending executions of the query of a campaign on the provided hosts,
+	// along with whether the hosts are online at the time now with the host settings.
+	NewDistributedQueryExecutions(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings HostSettings) error
+
+	// UpdateDistributedQueryExecutions records the status of the executions of the query of a campaign on the
+	// provided hosts.
+	UpdateDistributedQueryExecutions(ctx context.Context, campaignID uint, hostIDs []uint, status DistributedQueryExecutionStatus) error
+
+	// DeletePendingDistributedQueryExecutions deletes the pending executions of the query of a campaign on the provided
+	// hosts, returning the number of executions deleted.
+	DeletePendingDistributedQueryExecutions(ctx context.Context, campaignID uint, hostIDs []uint) (uint, error)
+
+	// DistributedQueryCampaignProgress returns the progress of the campaign, computed from its executions.
+	DistributedQueryCampaignProgress(ctx context.Context, campaignID uint) (*DistributedQueryCampaignProgress, error)
+
+	// CleanupDistributedQueryExecutions deletes the executions of the campaigns created more than a week before
+	// now.
+	CleanupDistributedQueryExecutions(ctx context.Context, now time.Time) error
+
+	// CountActiveDistributedQueryCampaigns returns the number of campaigns in the QueryWaiting or QueryRunning state,
+	// of all the users or of the user with ID userID if it is not nil.
+	CountActiveDistributedQueryCampaigns(ctx context.Context, userID *uint) (int, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// BatchQueryStore
+
+	// NewBatchQuery records the batch query of a campaign.
+	NewBatchQuery(ctx context.Context, bq *BatchQuery) error
+	// BatchQuery returns the batch query of the campaign with the provided ID.
+	BatchQuery(ctx context.Context, campaignID uint) (*BatchQuery, error)
+	// NewBatchQueryResults stores the results of the hosts for the batch query of the campaign, and returns the batch
+	// query with its responded hosts updated. Only the first result of a host is stored.
+	NewBatchQueryResults(ctx context.Context, campaignID uint, results []*BatchQueryResult) (*BatchQuery, error)
+	// MarkBatchQueryNotified records that the batch query of the campaign reached its completion threshold at now. It
+	// returns false if it was already recorded, so that the threshold is notified only once.
+	MarkBatchQueryNotified(ctx context.Context, campaignID uint, now time.Time) (bool, error)
+	// ListBatchQueryResults returns the results of the batch query of the campaign, in the order they were received
+	// unless otherwise ordered by the options.
+	ListBatchQueryResults(ctx context.Context, campaignID uint, opt ListOptions) ([]*BatchQueryResult, error)
+	// CleanupBatchQueries deletes the batch queries, and their results, that expired more than a month before now.
+	CleanupBatchQueries(ctx context.Context, now time.Time) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// QueryUsageStore
+
+	// RecordLiveQueryCampaignUsage records a live query campaign launched by the user on the day of now, along with
+	// the number of hosts it targets, per team of the hosts.
+	RecordLiveQueryCampaignUsage(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error
+	// RecordLiveQueryRowsUsage adds the provided rows returned to the live query campaigns to the usage of their
+	// user, team and day.
+	RecordLiveQueryRowsUsage(ctx context.Context, usage []QueryRowsUsage) error
+	// ListQueryUsage returns the live query usage per user and team over the days of the options.
+	ListQueryUsage(ctx context.Context, opt QueryUsageOptions) ([]QueryUsage, error)
+
+	// NewLiveQueryAuditLog records the audit log entry of a live query campaign.
+	NewLiveQueryAuditLog(ctx context.Context, entry *LiveQueryAuditLog) error
+	// ListLiveQueryAuditLog returns the audit log entries of the live query campaigns, most recent first unless
+	// otherwise ordered by the options.
+	ListLiveQueryAuditLog(ctx context.Context, opt LiveQueryAuditLogOptions) ([]*LiveQueryAuditLog, error)
+
 	///////////////////////////////////////////////////////////////////////////////
 	// PackStore is the datastore interface for managing query packs.
 
@@ -154,6 +215,12 @@ type Datastore interface {
 	// ListHostsInLabel returns a slice of hosts in the label with the given ID.
 	ListHostsInLabel(ctx context.Context, filter TeamFilter, lid uint, opt HostListOptions) ([]*Host, error)
 
+	// AddHostsToLabel adds the existing hosts among the given IDs to the members of the label, it is meant for
+	// manual labels.
+	AddHostsToLabel(ctx context.Context, lid uint, hostIDs []uint) error
+	// RemoveHostsFromLabel removes the hosts from the members of the label, it is meant for manual labels.
+	RemoveHostsFromLabel(ctx context.Context, lid uint, hostIDs []uint) error
+
 	// ListUniqueHostsInLabels returns a slice of all of the hosts in the given label IDs. A host will only appear once
 	// in the results even if it is in multiple of the provided labels.
 	ListUniqueHostsInLabels(ctx context.Context, filter TeamFilter, labels []uint) ([]*Host, error)
@@ -177,6 +244,77 @@ type Datastore interface {
 	DeleteHost(ctx context.Context, hid uint) error
 	Host(ctx context.Context, id uint, skipLoadingExtras bool) (*Host, error)
 	ListHosts(ctx context.Context, filter TeamFilter, opt HostListOptions) ([]*Host, error)
+	// ListHostChanges returns up to limit hosts created or updated and up to limit tombstones of the hosts deleted
+	// after the cursor, along with the cursor to provide to get the next changes.
+	ListHostChanges(ctx context.Context, filter TeamFilter, cursor HostChangesCursor, limit int) (*HostChanges, error)
+	// CleanupHostTombstones deletes the tombstones of the hosts deleted before now minus HostTombstoneRetention.
+	CleanupHostTombstones(ctx context.Context, now time.Time) error
+
+	// SetHostAgentOptionsOverride creates or replaces the agent options override of the host.
+	SetHostAgentOptionsOverride(ctx context.Context, override *HostAgentOptionsOverride) error
+	// HostAgentOptionsOverride returns the agent options override of the host, or a NotFoundError if the host has
+	// none or it expired at the provided time.
+	HostAgentOptionsOverride(ctx context.Context, hostID uint, now time.Time) (*HostAgentOptionsOverride, error)
+	// DeleteHostAgentOptionsOverride deletes the agent options override of the host, if any.
+	DeleteHostAgentOptionsOverride(ctx context.Context, hostID uint) error
+	// CleanupExpiredHostAgentOptionsOverrides deletes the agent options overrides expired at the provided time.
+	CleanupExpiredHostAgentOptionsOverrides(ctx context.Context, now time.Time) error
+	// ListHostStartupItems returns the startup items of the host.
+	ListHostStartupItems(ctx context.Context, hostID uint) ([]HostStartupItem, error)
+	// ListStartupItemChanges returns the changes of the startup items of the hosts visible to the filter.
+	ListStartupItemChanges(ctx context.Context, filter TeamFilter, opt StartupItemChangesListOptions) ([]StartupItemChange, error)
+	// CleanupStartupItemChanges deletes the startup item changes older than StartupItemChangesRetention.
+	CleanupStartupItemChanges(ctx context.Context, now time.Time) error
+	// ListHostListeningPorts returns the listening ports of the host.
+	ListHostListeningPorts(ctx context.Context, hostID uint) ([]HostListeningPort, error)
+	// ListHostDisks returns the disks of the host.
+	ListHostDisks(ctx context.Context, hostID uint) ([]HostDisk, error)
+	// ListHostBatteries returns the batteries of the host.
+	ListHostBatteries(ctx context.Context, hostID uint) ([]HostBattery, error)
+	// ListHostCertificates returns the certificates installed on the host.
+	ListHostCertificates(ctx context.Context, hostID uint) ([]HostCertificate, error)
+	// HostMetadata returns the notes and tags of the host, which are empty if they were never set.
+	HostMetadata(ctx context.Context, hostID uint) (*HostMetadata, error)
+	// SetHostMetadata creates or replaces the notes and tags of the host.
+	SetHostMetadata(ctx context.Context, metadata *HostMetadata) error
+	// NewHostActivities records the same activity for each of the hosts, performed by the user if not nil.
+	NewHostActivities(ctx context.Context, hostIDs []uint, user *User, activityType string, details *map[string]interface{}) error
+	// ListHostActivities returns the activities of the host, most recent first by default.
+	ListHostActivities(ctx context.Context, hostID uint, opt ListOptions) ([]*HostActivity, error)
+	// CleanupHostActivities deletes the host activities older than HostActivitiesRetention.
+	CleanupHostActivities(ctx context.Context, now time.Time) error
+	// ListDuplicateHosts returns the groups of hosts visible to the filter that have the same hardware serial
+	// number, or the same UUID if they have no serial number.
+	ListDuplicateHosts(ctx context.Context, filter TeamFilter) ([]*DuplicateHosts, error)
+	// MergeHosts merges the duplicate hosts into the host: the host keeps the oldest enrollment date, the history
+	// of the duplicates is transferred to it and the duplicates are deleted.
+	MergeHosts(ctx context.Context, hostID uint, duplicateIDs []uint) error
+	// NewHostOfflineEvents queues an offline lifecycle event for the hosts last seen before seenBefore, once until
+	// they are seen again.
+	NewHostOfflineEvents(ctx context.Context, seenBefore time.Time) error
+	// MarkHostsReportedOffline records the hosts last seen before seenBefore as reported offline without queuing
+	// events, so that the hosts already offline when the host lifecycle webhook is enabled are not reported.
+	MarkHostsReportedOffline(ctx context.Context, seenBefore time.Time) error
+	// ListHostLifecycleEvents returns at most limit queued host lifecycle events, oldest first.
+	ListHostLifecycleEvents(ctx context.Context, limit int) ([]*HostLifecycleEvent, error)
+	// DeleteHostLifecycleEvents deletes the delivered host lifecycle events.
+	DeleteHostLifecycleEvents(ctx context.Context, ids []uint) error
+	// CleanupHostLifecycleEvents deletes the host lifecycle events created before the time.
+	CleanupHostLifecycleEvents(ctx context.Context, before time.Time) error
+	// ListeningPortsReport returns the number of hosts where each process listens on each port, for the hosts
+	// visible to the filter.
+	ListeningPortsReport(ctx context.Context, filter TeamFilter, opt ListeningPortsReportOptions) ([]ListeningPortHostsCount, error)
+	// NewHostOnlineSubscription subscribes the user to be notified the next time the host checks in. Subscribing
+	// again to the same host returns the existing subscription.
+	NewHostOnlineSubscription(ctx context.Context, hostID, userID uint) (*HostOnlineSubscription, error)
+	// ListHostOnlineSubscriptions returns the pending host online subscriptions of the user.
+	ListHostOnlineSubscriptions(ctx context.Context, userID uint) ([]*HostOnlineSubscription, error)
+	// DeleteHostOnlineSubscription deletes the subscription of the user to the host, or returns a NotFoundError if
+	// the user is not subscribed to it.
+	DeleteHostOnlineSubscription(ctx context.Context, hostID, userID uint) error
+	// ConsumeHostOnlineSubscriptions deletes the subscriptions to the provided hosts and returns them, so that
+	// each subscription is notified only once.
+	ConsumeHostOnlineSubscriptions(ctx context.Context, hostIDs []uint) ([]*HostOnlineNotification, error)
 	MarkHostsSeen(ctx context.Context, hostIDs []uint, t time.Time) error
 	SearchHosts(ctx context.Context, filter TeamFilter, query string, omit ...uint) ([]*Host, error)
 	// CleanupIncomingHosts deletes hosts that have enrolled but never updated their status details. This clears dead
@@ -184,8 +322,9 @@ type Datastore interface {
 	// A host is considered incoming if both the hostname and osquery_version fields are empty. This means that multiple
 	// different osquery queries failed to populate details.
 	CleanupIncomingHosts(ctx context.Context, now time.Time) error
-	// GenerateHostStatusStatistics retrieves the count of online, offline, MIA and new hosts.
-	GenerateHostStatusStatistics(ctx context.Context, filter TeamFilter, now time.Time, platform *string) (*HostSummary, error)
+	// GenerateHostStatusStatistics retrieves the count of online, offline, missing, MIA and new hosts with the host
+	// settings, optionally restricted to the hosts of a platform and members of a label.
+	GenerateHostStatusStatistics(ctx context.Context, filter TeamFilter, now time.Time, settings HostSettings, platform *string, labelID *uint) (*HostSummary, error)
 	// HostIDsByName Retrieve the IDs associated with the given hostnames
 	HostIDsByName(ctx context.Context, filter TeamFilter, hostnames []string) ([]uint, error)
 	// HostByIdentifier returns one host matching the provided identifier. Possible matches can be on
@@ -198,19 +337,25 @@ type Datastore interface {
 
 	// DeleteHosts deletes associated tables for multiple hosts.
 	//
-	// It atomically deletes each host but if it returns an error, some of the hosts may be
-	// deleted and others not.
+	// It atomically deletes the hosts in batches but if it returns an error, some of the hosts
+	// may be deleted and others not.
 	DeleteHosts(ctx context.Context, ids []uint) error
 
 	CountHosts(ctx context.Context, filter TeamFilter, opt HostListOptions) (int, error)
 	CountHostsInLabel(ctx context.Context, filter TeamFilter, lid uint, opt HostListOptions) (int, error)
 	ListHostDeviceMapping(ctx context.Context, id uint) ([]*HostDeviceMapping, error)
+	// ListHostUsers returns the user accounts currently on the host, ordered by username.
+	ListHostUsers(ctx context.Context, hostID uint) ([]HostUser, error)
 
 	// LoadHostByDeviceAuthToken loads the host identified by the device auth token.
 	// If the token is invalid it returns a NotFoundError.
 	LoadHostByDeviceAuthToken(ctx context.Context, authToken string) (*Host, error)
 	// SetOrUpdateDeviceAuthToken inserts or updates the auth token for a host.
 	SetOrUpdateDeviceAuthToken(ctx context.Context, hostID uint, authToken string) error
+	// LoadHostByOrbitNodeKey loads the host identified by the orbit node key.
+	LoadHostByOrbitNodeKey(ctx context.Context, nodeKey string) (*Host, error)
+	// SetOrUpdateOrbitNodeKey inserts or updates the orbit node key for a host.
+	SetOrUpdateOrbitNodeKey(ctx context.Context, hostID uint, nodeKey string) error
 
 	// ListPoliciesForHost lists the policies that a host will check and whether they are passing
 	ListPoliciesForHost(ctx context.Context, host *Host) ([]*HostPolicy, error)
@@ -222,17 +367,28 @@ type Datastore interface {
 	AggregatedMDMStatus(ctx context.Context, teamID *uint) (AggregatedMDMStatus, time.Time, error)
 	GenerateAggregatedMunkiAndMDM(ctx context.Context) error
 
+	// ListHostAgentVersions returns the versions of the agent components reported by the host.
+	ListHostAgentVersions(ctx context.Context, hostID uint) ([]*HostAgentVersion, error)
+
+	// HostNetworkSettings returns the DNS servers and proxies configured on the host.
+	HostNetworkSettings(ctx context.Context, hostID uint) (*HostNetworkSettings, error)
+	// NetworkSettingsReport returns the number of hosts configured with each DNS server and proxy, counting only the
+	// hosts matching the team filter.
+	NetworkSettingsReport(ctx context.Context, filter TeamFilter) (*NetworkSettingsReport, error)
+
 	OSVersions(ctx context.Context, teamID *uint, platform *string) (*OSVersions, error)
 	UpdateOSVersions(ctx context.Context) error
 
 	///////////////////////////////////////////////////////////////////////////////
 	// TargetStore
 
-	// CountHostsInTargets returns the metrics of the hosts in the provided labels, teams, and explicit host IDs.
-	CountHostsInTargets(ctx context.Context, filter TeamFilter, targets HostTargets, now time.Time) (TargetMetrics, error)
+	// CountHostsInTargets returns the metrics of the hosts in the provided labels, teams, and explicit host IDs, with
+	// the status of the hosts computed with the host settings.
+	CountHostsInTargets(ctx context.Context, filter TeamFilter, targets HostTargets, now time.Time, settings HostSettings) (TargetMetrics, error)
 	// HostIDsInTargets returns the host IDs of the hosts in the provided labels, teams, and explicit host IDs. The
-	// returned host IDs should be sorted in ascending order.
-	HostIDsInTargets(ctx context.Context, filter TeamFilter, targets HostTargets) ([]uint, error)
+	// returned host IDs should be sorted in ascending order. The host settings are used if only the online hosts are
+	// targeted.
+	HostIDsInTargets(ctx context.Context, filter TeamFilter, targets HostTargets, settings HostSettings) ([]uint, error)
 
 	///////////////////////////////////////////////////////////////////////////////
 	// PasswordResetStore manages password resets in the Datastore
@@ -359,6 +515,31 @@ type Datastore interface {
 	NewActivity(ctx context.Context, user *User, activityType string, details *map[string]interface{}) error
 	ListActivities(ctx context.Context, opt ListOptions) ([]*Activity, error)
 
+	///////////////////////////////////////////////////////////////////////////////
+	// AgentConfigVersionStore
+
+	// RecordAgentConfigVersion records the current agent configuration (global agent options and packs) as a new
+	// version, unless it did not change since the latest version.
+	RecordAgentConfigVersion(ctx context.Context, user *User) error
+	// ListAgentConfigVersions lists the versions of the agent configuration, without their configuration.
+	ListAgentConfigVersions(ctx context.Context, opt ListOptions) ([]*AgentConfigVersion, error)
+	// AgentConfigVersion returns the version of the agent configuration, including its configuration.
+	AgentConfigVersion(ctx context.Context, id uint) (*AgentConfigVersion, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// AgentOptionsRolloutStore
+
+	// NewAgentOptionsRollout creates a rollout of new global agent options.
+	NewAgentOptionsRollout(ctx context.Context, rollout *AgentOptionsRollout) (*AgentOptionsRollout, error)
+	// AgentOptionsRollout returns the agent options rollout.
+	AgentOptionsRollout(ctx context.Context, id uint) (*AgentOptionsRollout, error)
+	// ActiveAgentOptionsRollout returns the active agent options rollout, or a not found error if there is none.
+	ActiveAgentOptionsRollout(ctx context.Context) (*AgentOptionsRollout, error)
+	// ListAgentOptionsRollouts lists the agent options rollouts.
+	ListAgentOptionsRollouts(ctx context.Context, opt ListOptions) ([]*AgentOptionsRollout, error)
+	// EndAgentOptionsRollout sets the final status (promoted or aborted) of the active agent options rollout.
+	EndAgentOptionsRollout(ctx context.Context, id uint, status string) error
+
 	///////////////////////////////////////////////////////////////////////////////
 	// StatisticsStore
 
@@ -379,11 +560,16 @@ type Datastore interface {
 	// It is also used to update team policies.
 	SavePolicy(ctx context.Context, p *Policy) error
 
-	ListGlobalPolicies(ctx context.Context) ([]*Policy, error)
+	ListGlobalPolicies(ctx context.Context, opts ListOptions) ([]*Policy, error)
 	PoliciesByID(ctx context.Context, ids []uint) (map[uint]*Policy, error)
 	DeleteGlobalPolicies(ctx context.Context, ids []uint) ([]uint, error)
 
+	// PolicyQueriesForHost returns the policy queries that are to be executed on the given host. The
+	// policies with an evaluation window are only returned within that window.
 	PolicyQueriesForHost(ctx context.Context, host *Host) (map[string]string, error)
+	// CriticalPolicyQueriesForHost returns the critical policy queries that are to be executed
+	// on the given host.
+	CriticalPolicyQueriesForHost(ctx context.Context, host *Host) (map[string]string, error)
 
 	// Methods used for async processing of host policy query results.
 	AsyncBatchInsertPolicyMembership(ctx context.Context, batch []PolicyMembershipResult) error
@@ -398,6 +584,8 @@ type Datastore interface {
 
 	ListSoftware(ctx context.Context, opt SoftwareListOptions) ([]Software, error)
 	CountSoftware(ctx context.Context, opt SoftwareListOptions) (int, error)
+	// ListHostSoftware lists the software installed on the host.
+	ListHostSoftware(ctx context.Context, hostID uint, opt SoftwareListOptions) ([]Software, error)
 	// ListVulnerableSoftwareBySource lists all the vulnerable software that matches the given source.
 	ListVulnerableSoftwareBySource(ctx context.Context, source string) ([]SoftwareWithCPE, error)
 	// DeleteVulnerabilities deletes the given list of vulnerabilities identified by CPE+CVE.
@@ -407,11 +595,116 @@ type Datastore interface {
 	// Team Policies
 
 	NewTeamPolicy(ctx context.Context, teamID uint, authorID *uint, args PolicyPayload) (*Policy, error)
-	ListTeamPolicies(ctx context.Context, teamID uint) ([]*Policy, error)
+	ListTeamPolicies(ctx context.Context, teamID uint, opts ListOptions) ([]*Policy, error)
 	DeleteTeamPolicies(ctx context.Context, teamID uint, ids []uint) ([]uint, error)
 	TeamPolicy(ctx context.Context, teamID uint, policyID uint) (*Policy, error)
+	// TeamPolicyExemptions returns the IDs of the global policies the team is exempted from.
+	TeamPolicyExemptions(ctx context.Context, teamID uint) ([]uint, error)
+	// SetTeamPolicyExemptions replaces the global policies the team is exempted from. The exempted policies are
+	// not run on the hosts of the team.
+	SetTeamPolicyExemptions(ctx context.Context, teamID uint, policyIDs []uint) error
 
 	CleanupPolicyMembership(ctx context.Context, now time.Time) error
+	// UpdatePolicyStats aggregates the number of hosts passing and failing each policy, returned as the host
+	// counts of the policies.
+	UpdatePolicyStats(ctx context.Context) error
+	// UpdateHostIssues recomputes the issues (the number of failing policies) of the hosts, returned with the
+	// hosts.
+	UpdateHostIssues(ctx context.Context) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// HostPolicyRemediationStore
+
+	// NewHostPolicyRemediation queues the remediation script of the policy to be run on the host, unless a
+	// remediation of the policy is already pending on the host, in which case that one is returned.
+	NewHostPolicyRemediation(ctx context.Context, hostID, policyID uint, authorID *uint) (*HostPolicyRemediation, error)
+	// QueueHostPolicyAutoRemediations queues the remediations of the automatically remediated policies among
+	// the provided ones that start failing on the host, i.e. that were not failing before the results are
+	// recorded.
+	QueueHostPolicyAutoRemediations(ctx context.Context, hostID uint, failingPolicyIDs []uint) error
+	// ListHostPolicyRemediations lists the remediations of the host, the most recent first.
+	ListHostPolicyRemediations(ctx context.Context, hostID uint, opts ListOptions) ([]*HostPolicyRemediation, error)
+	// PendingHostPolicyRemediations returns the remediations to be run on the host, the oldest first.
+	PendingHostPolicyRemediations(ctx context.Context, hostID uint) ([]*HostPolicyRemediation, error)
+	// SetHostPolicyRemediationResult records the result of a pending remediation of the host. It returns a not
+	// found error if the host has no such pending remediation.
+	SetHostPolicyRemediationResult(ctx context.Context, hostID, id uint, exitCode int, output string) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// PolicyJiraIssueStore
+
+	// NewPolicyJiraIssue records the Jira issue opened for the failing hosts of the policy.
+	NewPolicyJiraIssue(ctx context.Context, policyID uint, issueKey string) error
+	// PolicyJiraIssue returns the Jira issue of the policy, or a not found error if there is none.
+	PolicyJiraIssue(ctx context.Context, policyID uint) (*PolicyJiraIssue, error)
+	// ListPolicyJiraIssues returns the Jira issues of the policies, with the number of hosts each policy currently
+	// fails on.
+	ListPolicyJiraIssues(ctx context.Context) ([]*PolicyJiraIssue, error)
+	// DeletePolicyJiraIssue deletes the Jira issue of the policy, once it is closed.
+	DeletePolicyJiraIssue(ctx context.Context, policyID uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// ATC Tables
+
+	NewATCTable(ctx context.Context, table *ATCTable) (*ATCTable, error)
+	ATCTable(ctx context.Context, id uint) (*ATCTable, error)
+	// ListATCTables returns all the ATC tables, sorted by name.
+	ListATCTables(ctx context.Context) ([]*ATCTable, error)
+	SaveATCTable(ctx context.Context, table *ATCTable) error
+	DeleteATCTable(ctx context.Context, id uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// FIM Categories
+
+	NewFIMCategory(ctx context.Context, category *FIMCategory) (*FIMCategory, error)
+	FIMCategory(ctx context.Context, id uint) (*FIMCategory, error)
+	// ListFIMCategories returns the FIM categories of the team, or the global
+	// categories if teamID is nil, sorted by name.
+	ListFIMCategories(ctx context.Context, teamID *uint) ([]*FIMCategory, error)
+	// ListFIMCategoriesForHost returns the global FIM categories and the FIM
+	// categories of the team, if teamID is not nil, sorted by name.
+	ListFIMCategoriesForHost(ctx context.Context, teamID *uint) ([]*FIMCategory, error)
+	SaveFIMCategory(ctx context.Context, category *FIMCategory) error
+	DeleteFIMCategory(ctx context.Context, id uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// YARA Signature Groups
+
+	NewYARASignatureGroup(ctx context.Context, group *YARASignatureGroup) (*YARASignatureGroup, error)
+	YARASignatureGroup(ctx context.Context, id uint) (*YARASignatureGroup, error)
+	// ListYARASignatureGroups returns the YARA signature groups of the team,
+	// or the global groups if teamID is nil, sorted by name.
+	ListYARASignatureGroups(ctx context.Context, teamID *uint) ([]*YARASignatureGroup, error)
+	// ListYARASignatureGroupsForHost returns the global YARA signature groups
+	// and the groups of the team, if teamID is not nil, sorted by name.
+	ListYARASignatureGroupsForHost(ctx context.Context, teamID *uint) ([]*YARASignatureGroup, error)
+	SaveYARASignatureGroup(ctx context.Context, group *YARASignatureGroup) error
+	DeleteYARASignatureGroup(ctx context.Context, id uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// Dashboards
+
+	NewDashboard(ctx context.Context, dashboard *Dashboard) (*Dashboard, error)
+	// Dashboard returns the dashboard identified by id.
+	Dashboard(ctx context.Context, id uint) (*Dashboard, error)
+	// ListDashboards returns the dashboards owned by the user of the filter, along with the dashboards shared
+	// with the teams the user has access to. If filter.TeamID is set, only the dashboards of that team are returned.
+	ListDashboards(ctx context.Context, filter TeamFilter, opt ListOptions) ([]*Dashboard, error)
+	SaveDashboard(ctx context.Context, dashboard *Dashboard) error
+	DeleteDashboard(ctx context.Context, id uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// Threat Intel
+
+	// ListIndicatorsToLookup returns up to limit distinct indicators collected from hosts that have no cached verdict,
+	// or whose verdict was checked before checkedBefore.
+	ListIndicatorsToLookup(ctx context.Context, checkedBefore time.Time, limit int) ([]Indicator, error)
+	// SaveThreatIntelVerdicts caches the verdicts of indicator lookups.
+	SaveThreatIntelVerdicts(ctx context.Context, verdicts []ThreatIntelVerdict) error
+	// UpdateHostThreatFindings updates the threat findings of all hosts from their indicators and the cached verdicts.
+	UpdateHostThreatFindings(ctx context.Context) error
+	// ListHostThreatFindings returns the threat findings of the host.
+	ListHostThreatFindings(ctx context.Context, hostID uint) ([]*HostThreatFinding, error)
 
 	///////////////////////////////////////////////////////////////////////////////
 	// Locking
@@ -461,6 +754,10 @@ type Datastore interface {
 	// TeamAgentOptions loads the agents options of a team.
 	TeamAgentOptions(ctx context.Context, teamID uint) (*json.RawMessage, error)
 
+	// TeamLogSettings loads the log settings of a team. The settings are empty
+	// if the team does not override the global log settings.
+	TeamLogSettings(ctx context.Context, teamID uint) (*TeamLogSettings, error)
+
 	// SaveHostPackStats stores (and updates) the pack's scheduled queries stats of a host.
 	SaveHostPackStats(ctx context.Context, hostID uint, stats []PackStats) error
 
@@ -472,9 +769,21 @@ type Datastore interface {
 	// UpdateHost updates a host.
 	UpdateHost(ctx context.Context, host *Host) error
 
+	// UpdateHostsDetails updates the columns of multiple hosts set by the
+	// ingestion of their detail queries at once, the other columns of the hosts
+	// are left untouched.
+	UpdateHostsDetails(ctx context.Context, hosts []*Host) error
+
+	// UpdateHostDisplayNames recomputes the display name of all hosts from the provided display name sources.
+	UpdateHostDisplayNames(ctx context.Context, sources []string) error
+
 	// ListScheduledQueriesInPack lists all the scheduled queries of a pack.
 	ListScheduledQueriesInPack(ctx context.Context, packID uint) ([]*ScheduledQuery, error)
 
+	// ListDiscardDataScheduledQueryNames lists the pack and scheduled query names of the scheduled queries whose
+	// result logs must be discarded.
+	ListDiscardDataScheduledQueryNames(ctx context.Context) ([]PackScheduledQueryName, error)
+
 	// UpdateHostRefetchRequested updates a host's refetch requested field.
 	UpdateHostRefetchRequested(ctx context.Context, hostID uint, value bool) error
 
@@ -491,6 +800,10 @@ type Datastore interface {
 	// RecordPolicyQueryExecutions records the execution results of the policies for the given host.
 	RecordPolicyQueryExecutions(ctx context.Context, host *Host, results map[uint]*bool, updated time.Time, deferredSaveHost bool) error
 
+	// RecordCriticalPolicyQueryExecutions records the execution results of the critical policies
+	// for the given host, and updates the time the critical policies of the host were last run.
+	RecordCriticalPolicyQueryExecutions(ctx context.Context, host *Host, results map[uint]*bool, updated time.Time) error
+
 	// RecordLabelQueryExecutions saves the results of label queries. The results map is a map of label id -> whether or
 	// not the label matches. The time parameter is the timestamp to save with the query execution.
 	RecordLabelQueryExecutions(ctx context.Context, host *Host, results map[uint]*bool, t time.Time, deferredSaveHost bool) error
@@ -505,17 +818,51 @@ type Datastore interface {
 
 	SetOrUpdateMunkiVersion(ctx context.Context, hostID uint, version string) error
 	SetOrUpdateMDMData(ctx context.Context, hostID uint, enrolled bool, serverURL string, installedFromDep bool) error
+	// SetOrUpdateHostAgentVersion records the version of the agent component reported by the host. An empty
+	// version removes the component from the host.
+	SetOrUpdateHostAgentVersion(ctx context.Context, hostID uint, component, version string) error
 
+	// ReplaceHostDeviceMapping replaces the device mappings collected from the host. The custom device mapping
+	// of the host is left untouched.
 	ReplaceHostDeviceMapping(ctx context.Context, id uint, mappings []*HostDeviceMapping) error
+	// SetOrUpdateCustomHostDeviceMapping replaces the custom device mapping of the host with the email, or removes
+	// it if the email is empty, and returns the resulting device mappings of the host.
+	SetOrUpdateCustomHostDeviceMapping(ctx context.Context, hostID uint, email string) ([]*HostDeviceMapping, error)
+
+	// ReplaceHostDNSServers replaces the DNS servers configured on the host.
+	ReplaceHostDNSServers(ctx context.Context, hostID uint, addresses []string) error
+	// ReplaceHostNetworkAddresses replaces the addresses of the network interfaces of the host.
+	ReplaceHostNetworkAddresses(ctx context.Context, hostID uint, addresses []HostNetworkAddress) error
+	// ReplaceHostProxies replaces the proxies configured on the host.
+	ReplaceHostProxies(ctx context.Context, hostID uint, proxies []HostProxy) error
+	// ReplaceHostStartupItems replaces the startup items of the host and records the changes since the
+	// previous collection.
+	ReplaceHostStartupItems(ctx context.Context, hostID uint, items []HostStartupItem) error
+	// ReplaceHostListeningPorts replaces the listening ports of the host.
+	ReplaceHostListeningPorts(ctx context.Context, hostID uint, ports []HostListeningPort) error
+	// ReplaceHostDisks replaces the disks of the host.
+	ReplaceHostDisks(ctx context.Context, hostID uint, disks []HostDisk) error
+	// ReplaceHostBatteries replaces the batteries of the host.
+	ReplaceHostBatteries(ctx context.Context, hostID uint, batteries []HostBattery) error
+	// ReplaceHostCertificates replaces the certificates installed on the host.
+	ReplaceHostCertificates(ctx context.Context, hostID uint, certs []HostCertificate) error
 
 	// VerifyEnrollSecret checks that the provided secret matches an active enroll secret. If it is successfully
-	// matched, that secret is returned. Otherwise, an error is returned.
+	// matched, that secret is returned. Otherwise, an error is returned. The maximum number of uses of the secret is
+	// not checked, as it only limits the enrollment of new hosts and is enforced by EnrollHost.
 	VerifyEnrollSecret(ctx context.Context, secret string) (*EnrollSecret, error)
 
+	// RecordEnrollmentOutcomes adds the provided counts to the enrollment attempts counters of their day and
+	// outcome.
+	RecordEnrollmentOutcomes(ctx context.Context, counts []EnrollmentOutcomeCount) error
+	// EnrollmentStats returns the aggregated enrollment attempts by outcome since the provided time.
+	EnrollmentStats(ctx context.Context, since time.Time) (*EnrollmentStats, error)
+
 	// EnrollHost will enroll a new host with the given identifier, setting the node key, and team. Implementations of
 	// this method should respect the provided host enrollment cooldown, by returning an error if the host has enrolled
-	// within the cooldown period.
-	EnrollHost(ctx context.Context, osqueryHostId, nodeKey string, teamID *uint, cooldown time.Duration) (*Host, error)
+	// within the cooldown period. If consumeSecret is not empty, a use of that enroll secret is consumed when a new
+	// host is created, and an error is returned if the secret already reached its maximum number of uses.
+	EnrollHost(ctx context.Context, osqueryHostId, nodeKey string, teamID *uint, cooldown time.Duration, consumeSecret string) (*Host, error)
 
 	SerialUpdateHost(ctx context.Context, host *Host) error
 
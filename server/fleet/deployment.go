@@ -0,0 +1,20 @@
+package fleet
+
+// Kinds of agent deployment files that can be rendered by Fleet.
+const (
+	DeploymentFileFlagfile       = "flagfile"
+	DeploymentFileLaunchd        = "launchd"
+	DeploymentFileSystemd        = "systemd"
+	DeploymentFileWindowsService = "windows_service"
+)
+
+// AgentDeploymentFile is a rendered file that can be used to deploy osquery
+// and connect it to Fleet, e.g. a flagfile or a service definition.
+type AgentDeploymentFile struct {
+	// Filename is the suggested name of the file.
+	Filename string
+	// ContentType is the MIME type of the file.
+	ContentType string
+	// Content is the rendered file.
+	Content []byte
+}
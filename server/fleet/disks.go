@@ -0,0 +1,13 @@
+package fleet
+
+// HostDisk is a volume mounted on a host, with its total and available space
+// and whether it is encrypted.
+type HostDisk struct {
+	// Path is the mount point of the volume, or the drive letter on Windows.
+	Path           string `json:"path" db:"path"`
+	Device         string `json:"device" db:"device"`
+	Type           string `json:"type" db:"type"`
+	TotalBytes     uint64 `json:"total_bytes" db:"total_bytes"`
+	AvailableBytes uint64 `json:"available_bytes" db:"available_bytes"`
+	Encrypted      bool   `json:"encrypted" db:"encrypted"`
+}
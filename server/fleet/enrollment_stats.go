@@ -0,0 +1,81 @@
+package fleet
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrHostEnrollCooldown is returned when a host tries to enroll again before
+// the enroll cooldown period expired.
+var ErrHostEnrollCooldown = errors.New("host enrolling too often")
+
+// EnrollmentOutcome is the outcome of a host enrollment attempt.
+type EnrollmentOutcome string
+
+const (
+	// EnrollmentOutcomeSuccess is recorded when the host enrolled successfully.
+	EnrollmentOutcomeSuccess EnrollmentOutcome = "success"
+	// EnrollmentOutcomeInvalidSecret is recorded when the enroll secret is
+	// unknown, expired or exhausted.
+	EnrollmentOutcomeInvalidSecret EnrollmentOutcome = "invalid_secret"
+	// EnrollmentOutcomeCooldown is recorded when the host was rejected because
+	// it enrolled too recently (see osquery.enroll_cooldown).
+	EnrollmentOutcomeCooldown EnrollmentOutcome = "cooldown"
+	// EnrollmentOutcomeDatastoreError is recorded when the enrollment failed
+	// due to an unexpected datastore error.
+	EnrollmentOutcomeDatastoreError EnrollmentOutcome = "datastore_error"
+)
+
+// EnrollmentOutcomes lists all the possible enrollment outcomes.
+var EnrollmentOutcomes = []EnrollmentOutcome{
+	EnrollmentOutcomeSuccess,
+	EnrollmentOutcomeInvalidSecret,
+	EnrollmentOutcomeCooldown,
+	EnrollmentOutcomeDatastoreError,
+}
+
+// EnrollmentCounts holds the number of enrollment attempts by outcome.
+type EnrollmentCounts struct {
+	Attempts       uint `json:"attempts"`
+	Success        uint `json:"success"`
+	InvalidSecret  uint `json:"invalid_secret"`
+	Cooldown       uint `json:"cooldown"`
+	DatastoreError uint `json:"datastore_error"`
+}
+
+// Add adds n attempts with the provided outcome to the counts.
+func (c *EnrollmentCounts) Add(outcome EnrollmentOutcome, n uint) {
+	c.Attempts += n
+	switch outcome {
+	case EnrollmentOutcomeSuccess:
+		c.Success += n
+	case EnrollmentOutcomeInvalidSecret:
+		c.InvalidSecret += n
+	case EnrollmentOutcomeCooldown:
+		c.Cooldown += n
+	case EnrollmentOutcomeDatastoreError:
+		c.DatastoreError += n
+	}
+}
+
+// EnrollmentOutcomeCount is the number of enrollment attempts with a given
+// outcome on a given day (UTC).
+type EnrollmentOutcomeCount struct {
+	Date    time.Time
+	Outcome EnrollmentOutcome
+	Count   uint
+}
+
+// DailyEnrollmentCounts holds the enrollment counts for a given day (UTC).
+type DailyEnrollmentCounts struct {
+	Date time.Time `json:"date"`
+	EnrollmentCounts
+}
+
+// EnrollmentStats holds the aggregated enrollment attempts since a given
+// time, in total and by day.
+type EnrollmentStats struct {
+	Since time.Time `json:"since"`
+	EnrollmentCounts
+	Daily []DailyEnrollmentCounts `json:"daily"`
+}
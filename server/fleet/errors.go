@@ -220,6 +220,45 @@ func (e passwordResetRequiredError) StatusCode() int {
 	return http.StatusUnauthorized
 }
 
+// ConflictError is returned when a resource cannot be modified because it was
+// modified concurrently.
+type ConflictError struct {
+	Message string
+}
+
+// NewConflictError returns a conflict error with the provided message.
+func NewConflictError(message string) *ConflictError {
+	return &ConflictError{Message: message}
+}
+
+func (e ConflictError) Error() string {
+	return e.Message
+}
+
+func (e ConflictError) StatusCode() int {
+	return http.StatusConflict
+}
+
+// LiveQueryCampaignLimitError is returned when a live query campaign cannot be
+// created because too many campaigns are already running.
+type LiveQueryCampaignLimitError struct {
+	Message string
+}
+
+// NewLiveQueryCampaignLimitError returns a campaign limit error with the
+// provided message.
+func NewLiveQueryCampaignLimitError(message string) *LiveQueryCampaignLimitError {
+	return &LiveQueryCampaignLimitError{Message: message}
+}
+
+func (e LiveQueryCampaignLimitError) Error() string {
+	return e.Message
+}
+
+func (e LiveQueryCampaignLimitError) StatusCode() int {
+	return http.StatusTooManyRequests
+}
+
 // Error is a user facing error (API user). It's meant to be used for errors that are
 // related to fleet logic specifically. Other errors, such as mysql errors, shouldn't
 // be translated to this.
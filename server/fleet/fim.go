@@ -0,0 +1,117 @@
+package fleet
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FIMCategory is a category of file paths monitored by osquery's file
+// integrity monitoring (FIM). Each category is sent to the hosts in the
+// file_paths, exclude_paths and file_accesses sections of the config. See
+// https://osquery.readthedocs.io/en/stable/deployment/file-integrity-monitoring/
+type FIMCategory struct {
+	UpdateCreateTimestamps
+	ID uint `json:"id" db:"id"`
+	// TeamID is the ID of the team the category belongs to. If TeamID is nil,
+	// the category is global and is sent to all hosts.
+	TeamID *uint `json:"team_id" db:"team_id"`
+	// Name is the name of the category, used as key in the osquery config.
+	Name string `json:"name" db:"name"`
+	// Paths are the monitored paths. They may contain the osquery wildcards %
+	// and %%.
+	Paths FIMPaths `json:"paths" db:"paths"`
+	// ExcludePaths are the paths excluded from the monitored paths.
+	ExcludePaths FIMPaths `json:"exclude_paths" db:"exclude_paths"`
+	// Accesses indicates whether file accesses, and not only changes, are
+	// monitored for the category's paths. Only supported on Linux.
+	Accesses bool `json:"accesses" db:"accesses"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (c FIMCategory) AuthzType() string {
+	return "fim_category"
+}
+
+// FIMPaths is a list of file paths, stored as JSON.
+type FIMPaths []string
+
+// Scan implements the sql.Scanner interface
+func (p *FIMPaths) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case nil: // sql NULL
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements the sql.Valuer interface
+func (p FIMPaths) Value() (driver.Value, error) {
+	if p == nil {
+		p = FIMPaths{}
+	}
+	return json.Marshal(p)
+}
+
+// FIMCategoryPayload holds the data to create or modify a FIM category. Nil
+// fields are left unchanged when modifying a category.
+type FIMCategoryPayload struct {
+	Name         *string   `json:"name"`
+	Paths        *FIMPaths `json:"paths"`
+	ExcludePaths *FIMPaths `json:"exclude_paths"`
+	Accesses     *bool     `json:"accesses"`
+}
+
+var (
+	errFIMCategoryEmptyName          = errors.New("category name cannot be empty")
+	errFIMCategoryInvalidName        = errors.New("category name cannot contain leading or trailing spaces")
+	errFIMCategoryEmptyPaths         = errors.New("category must have at least one path")
+	errFIMCategoryInvalidPath        = errors.New("category paths must be absolute")
+	errFIMCategoryInvalidExcludePath = errors.New("category exclude paths must be absolute")
+)
+
+// Verify verifies the fields set in the payload are valid.
+func (p FIMCategoryPayload) Verify() error {
+	if p.Name != nil {
+		if *p.Name == "" {
+			return errFIMCategoryEmptyName
+		}
+		if strings.TrimSpace(*p.Name) != *p.Name {
+			return errFIMCategoryInvalidName
+		}
+	}
+	if p.Paths != nil {
+		if len(*p.Paths) == 0 {
+			return errFIMCategoryEmptyPaths
+		}
+		for _, path := range *p.Paths {
+			if !isFIMAbsolutePath(path) {
+				return errFIMCategoryInvalidPath
+			}
+		}
+	}
+	if p.ExcludePaths != nil {
+		for _, path := range *p.ExcludePaths {
+			if !isFIMAbsolutePath(path) {
+				return errFIMCategoryInvalidExcludePath
+			}
+		}
+	}
+	return nil
+}
+
+// isFIMAbsolutePath returns true if path is an absolute unix path or an
+// absolute windows path (e.g. C:\Users\%%).
+func isFIMAbsolutePath(path string) bool {
+	if strings.HasPrefix(path, "/") {
+		return true
+	}
+	return len(path) >= 3 && path[1] == ':' && (path[2] == '\\' || path[2] == '/')
+}
@@ -17,6 +17,8 @@ type GeoLocation struct {
 	Geometry   *Geometry `json:"geometry,omitempty"`
 }
 
+// Geometry is a GeoJSON geometry, its coordinates are in longitude, latitude
+// order.
 type Geometry struct {
 	Type        string    `json:"type"`
 	Coordinates []float64 `json:"coordinates"`
@@ -83,7 +85,7 @@ func parseCity(resp *geoip2.City) *GeoLocation {
 		CityName:   resp.City.Names["en"], // names is a map of language to city name names["us"] = "New York"
 		Geometry: &Geometry{
 			Type:        "Point",
-			Coordinates: []float64{resp.Location.Latitude, resp.Location.Longitude},
+			Coordinates: []float64{resp.Location.Longitude, resp.Location.Latitude},
 		},
 	}
 }
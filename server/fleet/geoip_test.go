@@ -0,0 +1,26 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/oschwald/geoip2-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCity(t *testing.T) {
+	assert.Nil(t, parseCity(nil))
+
+	var city geoip2.City
+	city.Country.IsoCode = "US"
+	city.City.Names = map[string]string{"en": "New York"}
+	city.Location.Latitude = 40.6799
+	city.Location.Longitude = -74.0028
+
+	loc := parseCity(&city)
+	require.NotNil(t, loc)
+	assert.Equal(t, "US", loc.CountryISO)
+	assert.Equal(t, "New York", loc.CityName)
+	// GeoJSON coordinates are longitude, latitude
+	assert.Equal(t, &Geometry{Type: "Point", Coordinates: []float64{-74.0028, 40.6799}}, loc.Geometry)
+}
@@ -0,0 +1,39 @@
+package fleet
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// HostActivitiesRetention is how long the activities of the hosts are kept.
+const HostActivitiesRetention = 90 * 24 * time.Hour
+
+// The types of the activities of a host.
+const (
+	// HostActivityTypeEnrolled is the activity type for the first enrollment of the host
+	HostActivityTypeEnrolled = "enrolled"
+	// HostActivityTypeReenrolled is the activity type for the enrollments of an already enrolled host
+	HostActivityTypeReenrolled = "re_enrolled"
+	// HostActivityTypeTransferred is the activity type for the transfers of the host to a team
+	HostActivityTypeTransferred = "transferred"
+	// HostActivityTypeLiveQuery is the activity type for the live queries executed on the host
+	HostActivityTypeLiveQuery = "live_query"
+	// HostActivityTypePolicyFailing is the activity type for the policies that start failing on the host
+	HostActivityTypePolicyFailing = "policy_failing"
+	// HostActivityTypePolicyPassing is the activity type for the policies that start passing on the host
+	HostActivityTypePolicyPassing = "policy_passing"
+	// HostActivityTypeMerged is the activity type for the duplicates of the host merged into it
+	HostActivityTypeMerged = "merged"
+)
+
+// HostActivity is an event in the timeline of a host. The activities
+// performed by the host itself (e.g. its enrollments) have no actor.
+type HostActivity struct {
+	ID            uint             `json:"id" db:"id"`
+	HostID        uint             `json:"host_id" db:"host_id"`
+	CreatedAt     time.Time        `json:"created_at" db:"created_at"`
+	ActorFullName *string          `json:"actor_full_name" db:"name"`
+	ActorID       *uint            `json:"actor_id" db:"user_id"`
+	Type          string           `json:"type" db:"activity_type"`
+	Details       *json.RawMessage `json:"details" db:"details"`
+}
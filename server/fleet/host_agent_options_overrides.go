@@ -0,0 +1,32 @@
+package fleet
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// DefaultHostAgentOptionsOverrideExpiry is how long a host agent options
+// override applies when no expiry is provided.
+const DefaultHostAgentOptionsOverrideExpiry = 24 * time.Hour
+
+// HostAgentOptionsOverride is a config fragment merged last over the osquery
+// config of a single host (e.g. to enable verbose logging on a host under
+// investigation), until it expires.
+type HostAgentOptionsOverride struct {
+	UpdateCreateTimestamps
+	HostID    uint            `json:"host_id" db:"host_id"`
+	Options   json.RawMessage `json:"options" db:"options"`
+	ExpiresAt time.Time       `json:"expires_at" db:"expires_at"`
+}
+
+// ValidateJSONHostAgentOptionsOverride validates the config fragment of a
+// host agent options override: it must be a JSON object and its "options"
+// section must only contain known osquery options.
+func ValidateJSONHostAgentOptionsOverride(rawJSON json.RawMessage) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(rawJSON, &obj); err != nil || obj == nil {
+		return errors.New("must be a JSON object")
+	}
+	return validateAgentOptionsConfig(rawJSON)
+}
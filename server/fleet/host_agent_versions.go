@@ -0,0 +1,34 @@
+package fleet
+
+import "time"
+
+// The components of the Fleet agent that report their version.
+const (
+	// AgentComponentOrbit is Orbit, reported with the orbit_info table.
+	AgentComponentOrbit = "orbit"
+	// AgentComponentFleetDesktop is Fleet Desktop, reported by Orbit with the
+	// orbit_info table.
+	AgentComponentFleetDesktop = "fleet_desktop"
+	// AgentComponentLauncher is Kolide's launcher, reported with the
+	// kolide_launcher_info table.
+	AgentComponentLauncher = "launcher"
+)
+
+// IsValidAgentComponent returns true if component is one of the agent
+// components that report their version.
+func IsValidAgentComponent(component string) bool {
+	switch component {
+	case AgentComponentOrbit, AgentComponentFleetDesktop, AgentComponentLauncher:
+		return true
+	default:
+		return false
+	}
+}
+
+// HostAgentVersion is the version of an agent component that runs on a host.
+type HostAgentVersion struct {
+	Component string `json:"component" db:"component"`
+	Version   string `json:"version" db:"version"`
+	// UpdatedAt is the time the host first reported the version.
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package fleet
+
+import "time"
+
+// DuplicateHosts is a group of hosts that report the same hardware serial
+// number, or the same UUID if they have no serial number, typically because
+// the machine was re-imaged and enrolled again as a new host.
+type DuplicateHosts struct {
+	// HardwareSerial is set if the hosts were matched by hardware serial
+	// number, UUID otherwise.
+	HardwareSerial string `json:"hardware_serial,omitempty"`
+	UUID           string `json:"uuid,omitempty"`
+	// Hosts are ordered from the most recently seen, which is usually the one
+	// to keep when merging them.
+	Hosts []*DuplicateHost `json:"hosts"`
+}
+
+// DuplicateHost is a host of a group of duplicate hosts.
+type DuplicateHost struct {
+	ID             uint      `json:"id" db:"id"`
+	Hostname       string    `json:"hostname" db:"hostname"`
+	DisplayName    string    `json:"display_name" db:"display_name"`
+	TeamID         *uint     `json:"team_id" db:"team_id"`
+	HardwareSerial string    `json:"-" db:"hardware_serial"`
+	UUID           string    `json:"-" db:"uuid"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	LastEnrolledAt time.Time `json:"last_enrolled_at" db:"last_enrolled_at"`
+	SeenTime       time.Time `json:"seen_time" db:"seen_time"`
+}
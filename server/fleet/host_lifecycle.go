@@ -0,0 +1,38 @@
+package fleet
+
+import "time"
+
+// HostLifecycleEventsRetention is how long the host lifecycle events are kept
+// when they cannot be delivered, e.g. because the destination of the host
+// lifecycle webhook is down.
+const HostLifecycleEventsRetention = 7 * 24 * time.Hour
+
+// HostLifecycleDefaultOfflineThreshold is how long a host must not check in to
+// be reported offline if the threshold of the host lifecycle webhook is not set.
+const HostLifecycleDefaultOfflineThreshold = time.Hour
+
+// The types of the host lifecycle events.
+const (
+	// HostLifecycleEventEnrolled is the event type for the first enrollment of a host
+	HostLifecycleEventEnrolled = "enrolled"
+	// HostLifecycleEventReenrolled is the event type for the enrollments of an already enrolled host
+	HostLifecycleEventReenrolled = "re_enrolled"
+	// HostLifecycleEventOffline is the event type for a host that did not check in for longer than the threshold
+	HostLifecycleEventOffline = "offline"
+	// HostLifecycleEventDeleted is the event type for a deleted host
+	HostLifecycleEventDeleted = "deleted"
+)
+
+// HostLifecycleEvent is an event of the lifecycle of a host, queued for
+// delivery by the host lifecycle webhook. The identifiers of the host are the
+// current ones, or the ones at the time of the event if the host was deleted.
+type HostLifecycleEvent struct {
+	ID             uint      `json:"id" db:"id"`
+	Type           string    `json:"type" db:"event_type"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	HostID         uint      `json:"host_id" db:"host_id"`
+	Hostname       string    `json:"hostname" db:"hostname"`
+	UUID           string    `json:"uuid" db:"uuid"`
+	HardwareSerial string    `json:"hardware_serial" db:"hardware_serial"`
+	TeamID         *uint     `json:"team_id" db:"team_id"`
+}
@@ -0,0 +1,79 @@
+package fleet
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits of the host metadata, the notes must fit in the TEXT column of the
+// host_metadata table.
+const (
+	HostNotesMaxLength    = 65535
+	HostTagKeyMaxLength   = 255
+	HostTagValueMaxLength = 255
+)
+
+// HostMetadata is the free-form metadata of a host set by the users, e.g. to
+// store its asset tag, owner or cost center.
+type HostMetadata struct {
+	HostID uint     `json:"-" db:"host_id"`
+	Notes  string   `json:"notes" db:"notes"`
+	Tags   HostTags `json:"tags" db:"tags"`
+}
+
+// HostTags are the key/value tags of a host, stored as JSON.
+type HostTags map[string]string
+
+// Scan implements the sql.Scanner interface
+func (t *HostTags) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, t)
+	case string:
+		return json.Unmarshal([]byte(v), t)
+	case nil: // sql NULL
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements the sql.Valuer interface
+func (t HostTags) Value() (driver.Value, error) {
+	if t == nil {
+		t = HostTags{}
+	}
+	return json.Marshal(t)
+}
+
+// HostMetadataPayload holds the data to modify the metadata of a host. Nil
+// fields are left unchanged, the tags replace all the existing tags.
+type HostMetadataPayload struct {
+	Notes *string  `json:"notes"`
+	Tags  HostTags `json:"tags"`
+}
+
+// Verify validates the payload, returning an InvalidArgumentError if any
+// field is invalid.
+func (p HostMetadataPayload) Verify() error {
+	invalid := &InvalidArgumentError{}
+	if p.Notes != nil && len(*p.Notes) > HostNotesMaxLength {
+		invalid.Appendf("notes", "must be at most %d bytes", HostNotesMaxLength)
+	}
+	for k, v := range p.Tags {
+		switch {
+		case k == "":
+			invalid.Append("tags", "keys must not be empty")
+		case utf8.RuneCountInString(k) > HostTagKeyMaxLength:
+			invalid.Appendf("tags", "key %q must be at most %d characters", k, HostTagKeyMaxLength)
+		case utf8.RuneCountInString(v) > HostTagValueMaxLength:
+			invalid.Appendf("tags", "value of %q must be at most %d characters", k, HostTagValueMaxLength)
+		}
+	}
+	if invalid.HasErrors() {
+		return invalid
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package fleet
+
+import "time"
+
+// HostOnlineSubscription is the request of a user to be notified, once, the
+// next time the host checks in with Fleet.
+type HostOnlineSubscription struct {
+	ID        uint      `json:"id" db:"id"`
+	HostID    uint      `json:"host_id" db:"host_id"`
+	UserID    uint      `json:"user_id" db:"user_id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// Hostname is the current hostname of the host.
+	Hostname string `json:"hostname" db:"hostname"`
+}
+
+// HostOnlineNotification is a host online subscription consumed when the
+// host checked in, along with what is needed to notify its user.
+type HostOnlineNotification struct {
+	HostID    uint   `db:"host_id"`
+	Hostname  string `db:"hostname"`
+	UserID    uint   `db:"user_id"`
+	UserName  string `db:"user_name"`
+	UserEmail string `db:"user_email"`
+}
@@ -1,8 +1,11 @@
 package fleet
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"time"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
 )
 
 type HostStatus string
@@ -14,6 +17,10 @@ const (
 	StatusOffline = HostStatus("offline")
 	// StatusMIA no communication with host for MIADuration.
 	StatusMIA = HostStatus("mia")
+	// StatusMissing no communication with host for the missing threshold of
+	// the host settings (see HostSettings.MissingThreshold), but for less
+	// than MIADuration. No host is missing if the threshold is not set.
+	StatusMissing = HostStatus("missing")
 	// StatusNew means the host has enrolled in the interval defined by
 	// NewDuration. It is independent of offline and online.
 	StatusNew = HostStatus("new")
@@ -26,6 +33,10 @@ const (
 	// is considered MIA.
 	MIADuration = 30 * 24 * time.Hour
 
+	// MaxHostMissingThresholdDays is the maximum missing threshold of the
+	// host settings, a host is MIA after MIADuration.
+	MaxHostMissingThresholdDays = 29
+
 	// OnlineIntervalBuffer is the additional time in seconds to add to the
 	// online interval to avoid flapping of hosts that check in a bit later
 	// than their expected checkin interval.
@@ -48,11 +59,41 @@ type HostListOptions struct {
 
 	SoftwareIDFilter *uint
 
+	// LabelIDsFilter selects the hosts that are members of all the specified
+	// labels.
+	LabelIDsFilter []uint
+
+	// AgentComponentFilter selects the hosts that report a version of the
+	// agent component, and AgentVersionFilter the hosts that report that
+	// version of the component.
+	AgentComponentFilter string
+	AgentVersionFilter   string
+
+	// LowDiskSpaceFilter selects the hosts with less than the specified
+	// gigabytes of disk space available, and LowDiskSpacePercentFilter the
+	// hosts with less than the specified percentage of disk space available.
+	LowDiskSpaceFilter        *int
+	LowDiskSpacePercentFilter *int
+
+	// DiskEncryptionEnabledFilter selects the hosts that reported their
+	// system disk as encrypted or not.
+	DiskEncryptionEnabledFilter *bool
+
+	// MDMEnrollmentStatusFilter selects the macOS hosts with the MDM
+	// enrollment status, and MunkiVersionFilter the hosts that report this
+	// version of Munki.
+	MDMEnrollmentStatusFilter string
+	MunkiVersionFilter        string
+
+	// CertificateSHA1Filter selects the hosts that have the certificate with
+	// this SHA1 fingerprint installed.
+	CertificateSHA1Filter string
+
 	DisableFailingPolicies bool
 }
 
 func (h HostListOptions) Empty() bool {
-	return h.ListOptions.Empty() && len(h.AdditionalFilters) == 0 && h.StatusFilter == "" && h.TeamFilter == nil && h.PolicyIDFilter == nil && h.PolicyResponseFilter == nil
+	return h.ListOptions.Empty() && len(h.AdditionalFilters) == 0 && h.StatusFilter == "" && h.TeamFilter == nil && h.PolicyIDFilter == nil && h.PolicyResponseFilter == nil && len(h.LabelIDsFilter) == 0 && h.AgentComponentFilter == "" && h.LowDiskSpaceFilter == nil && h.LowDiskSpacePercentFilter == nil && h.DiskEncryptionEnabledFilter == nil && h.MDMEnrollmentStatusFilter == "" && h.MunkiVersionFilter == "" && h.CertificateSHA1Filter == ""
 }
 
 type HostUser struct {
@@ -80,6 +121,13 @@ type Host struct {
 	NodeKey          string    `json:"-" db:"node_key" csv:"-"`
 	Hostname         string    `json:"hostname" db:"hostname" csv:"hostname"` // there is a fulltext index on this field
 	UUID             string    `json:"uuid" db:"uuid" csv:"uuid"`             // there is a fulltext index on this field
+	// DisplayName is the name under which the host is shown, computed from the
+	// ingested fields according to the host_settings.display_name_sources of
+	// the AppConfig.
+	DisplayName string `json:"display_name" db:"display_name" csv:"display_name"`
+	// CriticalPolicyUpdatedAt is the time the critical policies of the host
+	// were last updated, they are evaluated at their own interval.
+	CriticalPolicyUpdatedAt time.Time `json:"-" db:"critical_policy_updated_at" csv:"-"`
 	// Platform is the host's platform as defined by osquery's os_version.platform.
 	Platform       string        `json:"platform" csv:"platform"`
 	OsqueryVersion string        `json:"osquery_version" db:"osquery_version" csv:"osquery_version"`
@@ -125,10 +173,18 @@ type Host struct {
 
 	GigsDiskSpaceAvailable    float64 `json:"gigs_disk_space_available" db:"gigs_disk_space_available" csv:"gigs_disk_space_available"`
 	PercentDiskSpaceAvailable float64 `json:"percent_disk_space_available" db:"percent_disk_space_available" csv:"percent_disk_space_available"`
+	// DiskEncryptionEnabled is whether the system disk of the host is
+	// encrypted (FileVault on macOS, BitLocker on Windows and LUKS on Linux),
+	// nil if the host did not report it yet.
+	DiskEncryptionEnabled *bool `json:"disk_encryption_enabled" db:"disk_encryption_enabled" csv:"disk_encryption_enabled"`
 
 	HostIssues `json:"issues,omitempty" csv:"-"`
 
 	Modified bool `json:"-" csv:"-"`
+
+	// ComputedStatus is the status of the host with the host settings at the
+	// time it was loaded, set by the service for the hosts it returns.
+	ComputedStatus HostStatus `json:"-" db:"-" csv:"-"`
 }
 
 type HostIssues struct {
@@ -150,22 +206,114 @@ type HostDetail struct {
 	Packs []*Pack `json:"packs"`
 	// Policies is the list of policies and whether it passes for the host
 	Policies []*HostPolicy `json:"policies"`
+	// ThreatFindings is the list of indicators collected from the host that
+	// were reported as malicious by the threat intel API, if configured.
+	ThreatFindings []*HostThreatFinding `json:"threat_findings,omitempty"`
+	// AgentVersions is the list of versions of the agent components reported
+	// by the host.
+	AgentVersions []*HostAgentVersion `json:"agent_versions"`
+	// Batteries is the list of batteries of the host, with their health.
+	Batteries []HostBattery `json:"batteries"`
+	// Notes are the free-form notes of the host set by the users.
+	Notes string `json:"notes"`
+	// Tags are the key/value tags of the host set by the users.
+	Tags HostTags `json:"tags"`
 }
 
 const (
 	HostKind = "host"
 )
 
+// The host fields that can be used as the display name of the hosts, see
+// HostSettings.DisplayNameSources.
+const (
+	HostDisplayNameSourceComputerName   = "computer_name"
+	HostDisplayNameSourceHostname       = "hostname"
+	HostDisplayNameSourceHardwareSerial = "hardware_serial"
+)
+
+// IsValidHostDisplayNameSource returns true if source is a host field that
+// can be used as display name.
+func IsValidHostDisplayNameSource(source string) bool {
+	switch source {
+	case HostDisplayNameSourceComputerName, HostDisplayNameSourceHostname, HostDisplayNameSourceHardwareSerial:
+		return true
+	}
+	return false
+}
+
+// HostTombstoneRetention is how long the tombstones of deleted hosts are
+// kept, and thus how far back host changes can be listed.
+const HostTombstoneRetention = 30 * 24 * time.Hour
+
+// HostTombstone records the deletion of a host, so that the deletion can be
+// reported to the clients syncing the host changes.
+type HostTombstone struct {
+	HostID    uint      `json:"id" db:"host_id"`
+	TeamID    *uint     `json:"team_id" db:"team_id"`
+	Hostname  string    `json:"hostname" db:"hostname"`
+	UUID      string    `json:"uuid" db:"uuid"`
+	DeletedAt time.Time `json:"deleted_at" db:"deleted_at"`
+}
+
+// HostChangesCursor is the position of a client in the host changes. The
+// hosts are ordered by last update time and ID, and the tombstones by deletion
+// time and host ID, so the cursor holds a position in each of them.
+type HostChangesCursor struct {
+	UpdatedAt     time.Time `json:"updated_at"`
+	HostID        uint      `json:"host_id"`
+	DeletedAt     time.Time `json:"deleted_at"`
+	DeletedHostID uint      `json:"deleted_host_id"`
+}
+
+// NewHostChangesCursor returns the cursor to list the changes made since the
+// provided time, inclusive.
+func NewHostChangesCursor(since time.Time) HostChangesCursor {
+	return HostChangesCursor{UpdatedAt: since, DeletedAt: since}
+}
+
+// String returns the cursor encoded as an opaque string.
+func (c HostChangesCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseHostChangesCursor parses a cursor returned by HostChangesCursor.String.
+func ParseHostChangesCursor(s string) (HostChangesCursor, error) {
+	var c HostChangesCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// HostChanges are the hosts created, updated or deleted after a cursor.
+type HostChanges struct {
+	// Cursor is the cursor to provide to list the next changes.
+	Cursor HostChangesCursor
+	// More is true if the number of hosts or tombstones reached the limit, so
+	// the next changes can be listed right away.
+	More bool
+	// Hosts are the hosts created or updated.
+	Hosts []*Host
+	// Deleted are the tombstones of the hosts deleted.
+	Deleted []*HostTombstone
+}
+
 // HostSummary is a structure which represents a data summary about the total
 // set of hosts in the database. This structure is returned by the HostService
 // method GetHostSummary
 type HostSummary struct {
 	TeamID           *uint                  `json:"team_id,omitempty"`
+	LabelID          *uint                  `json:"label_id,omitempty"`
 	TotalsHostsCount uint                   `json:"totals_hosts_count" db:"total"`
 	Platforms        []*HostSummaryPlatform `json:"platforms"`
 	OnlineCount      uint                   `json:"online_count" db:"online"`
 	OfflineCount     uint                   `json:"offline_count" db:"offline"`
 	MIACount         uint                   `json:"mia_count" db:"mia"`
+	MissingCount     uint                   `json:"missing_count" db:"missing"`
 	NewCount         uint                   `json:"new_count" db:"new"`
 }
 
@@ -176,8 +324,31 @@ type HostSummaryPlatform struct {
 	HostsCount uint   `json:"hosts_count" db:"total"`
 }
 
-// Status calculates the online status of the host
-func (h *Host) Status(now time.Time) HostStatus {
+// ComputeDisplayName returns the first non-empty field of the host in the
+// sources order, or the hostname if they are all empty.
+func (h *Host) ComputeDisplayName(sources []string) string {
+	for _, source := range sources {
+		var name string
+		switch source {
+		case HostDisplayNameSourceComputerName:
+			name = h.ComputerName
+		case HostDisplayNameSourceHostname:
+			name = h.Hostname
+		case HostDisplayNameSourceHardwareSerial:
+			name = h.HardwareSerial
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return h.Hostname
+}
+
+// Status calculates the status of the host with the host settings. The host is
+// offline once it misses its check-in interval, or after the offline threshold
+// if it is longer, then missing after the missing threshold if set and MIA
+// after MIADuration.
+func (h *Host) Status(now time.Time, settings HostSettings) HostStatus {
 	// The logic in this function should remain synchronized with
 	// GenerateHostStatusStatistics and CountHostsInTargets
 
@@ -189,16 +360,72 @@ func (h *Host) Status(now time.Time) HostStatus {
 	// Add a small buffer to prevent flapping
 	onlineInterval += OnlineIntervalBuffer
 
+	onlineDuration := time.Duration(onlineInterval) * time.Second
+	if offlineThreshold := settings.OfflineThreshold.Duration; offlineThreshold > onlineDuration {
+		onlineDuration = offlineThreshold
+	}
+
+	missingThreshold := settings.MissingThreshold()
+
 	switch {
 	case h.SeenTime.Add(MIADuration).Before(now):
 		return StatusMIA
-	case h.SeenTime.Add(time.Duration(onlineInterval) * time.Second).Before(now):
+	case missingThreshold > 0 && h.SeenTime.Add(missingThreshold).Before(now):
+		return StatusMissing
+	case h.SeenTime.Add(onlineDuration).Before(now):
 		return StatusOffline
 	default:
 		return StatusOnline
 	}
 }
 
+// Copy returns a deep copy of the host, which can be modified without
+// affecting the host, e.g. to ingest its details asynchronously.
+func (h *Host) Copy() *Host {
+	if h == nil {
+		return nil
+	}
+
+	clone := *h
+	if h.PrimaryNetworkInterfaceID != nil {
+		clone.PrimaryNetworkInterfaceID = ptr.Uint(*h.PrimaryNetworkInterfaceID)
+	}
+	if h.TeamID != nil {
+		clone.TeamID = ptr.Uint(*h.TeamID)
+	}
+	if h.TeamName != nil {
+		clone.TeamName = ptr.String(*h.TeamName)
+	}
+	if h.Additional != nil {
+		additional := append(json.RawMessage(nil), *h.Additional...)
+		clone.Additional = &additional
+	}
+	if h.DiskEncryptionEnabled != nil {
+		clone.DiskEncryptionEnabled = ptr.Bool(*h.DiskEncryptionEnabled)
+	}
+
+	if h.NetworkInterfaces != nil {
+		clone.NetworkInterfaces = make([]*NetworkInterface, len(h.NetworkInterfaces))
+		for i, nic := range h.NetworkInterfaces {
+			if nic != nil {
+				nicCopy := *nic
+				clone.NetworkInterfaces[i] = &nicCopy
+			}
+		}
+	}
+	if h.PackStats != nil {
+		clone.PackStats = make([]PackStats, len(h.PackStats))
+		for i, stats := range h.PackStats {
+			stats.QueryStats = append([]ScheduledQueryStats(nil), stats.QueryStats...)
+			clone.PackStats[i] = stats
+		}
+	}
+	if h.Users != nil {
+		clone.Users = append([]HostUser(nil), h.Users...)
+	}
+	return &clone
+}
+
 func (h *Host) IsNew(now time.Time) bool {
 	withDuration := h.CreatedAt.Add(NewDuration)
 	if withDuration.After(now) ||
@@ -269,10 +496,46 @@ type HostDeviceMapping struct {
 	Source string `json:"source" db:"source"`
 }
 
+const (
+	// DeviceMappingGoogleChromeProfiles is the source of the device mappings
+	// collected from the Google Chrome profiles of the host.
+	DeviceMappingGoogleChromeProfiles = "google_chrome_profiles"
+	// DeviceMappingCustom is the source of the device mappings set via the
+	// API. A host has at most one custom device mapping.
+	DeviceMappingCustom = "custom"
+)
+
+// HostDeviceMappingPayload is the custom device mapping of a host, identified
+// by its hostname, osquery host id, node key or uuid, used to import the
+// device mappings in bulk.
+type HostDeviceMappingPayload struct {
+	Host  string `json:"host"`
+	Email string `json:"email"`
+}
+
 type HostMunkiInfo struct {
 	Version string `json:"version"`
 }
 
+// The MDM enrollment statuses of the hosts, see
+// HostListOptions.MDMEnrollmentStatusFilter.
+const (
+	MDMEnrollmentStatusManual     = "manual"
+	MDMEnrollmentStatusAutomatic  = "automatic"
+	MDMEnrollmentStatusUnenrolled = "unenrolled"
+)
+
+// IsValidMDMEnrollmentStatus returns true if status is one of the MDM
+// enrollment statuses of the hosts.
+func IsValidMDMEnrollmentStatus(status string) bool {
+	switch status {
+	case MDMEnrollmentStatusManual, MDMEnrollmentStatusAutomatic, MDMEnrollmentStatusUnenrolled:
+		return true
+	default:
+		return false
+	}
+}
+
 type HostMDM struct {
 	EnrollmentStatus string `json:"enrollment_status"`
 	ServerURL        string `json:"server_url"`
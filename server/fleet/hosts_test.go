@@ -1,10 +1,12 @@
 package fleet
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
 	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -16,24 +18,42 @@ func TestHostStatus(t *testing.T) {
 		seenTime            time.Time
 		distributedInterval uint
 		configTLSRefresh    uint
+		offlineThreshold    time.Duration
+		missingDays         int
 		status              HostStatus
 	}{
-		{mockClock.Now().Add(-30 * time.Second), 10, 3600, StatusOnline},
-		{mockClock.Now().Add(-75 * time.Second), 10, 3600, StatusOffline},
-		{mockClock.Now().Add(-30 * time.Second), 3600, 10, StatusOnline},
-		{mockClock.Now().Add(-75 * time.Second), 3600, 10, StatusOffline},
+		{mockClock.Now().Add(-30 * time.Second), 10, 3600, 0, 0, StatusOnline},
+		{mockClock.Now().Add(-75 * time.Second), 10, 3600, 0, 0, StatusOffline},
+		{mockClock.Now().Add(-30 * time.Second), 3600, 10, 0, 0, StatusOnline},
+		{mockClock.Now().Add(-75 * time.Second), 3600, 10, 0, 0, StatusOffline},
 
-		{mockClock.Now().Add(-60 * time.Second), 60, 60, StatusOnline},
-		{mockClock.Now().Add(-121 * time.Second), 60, 60, StatusOffline},
+		{mockClock.Now().Add(-60 * time.Second), 60, 60, 0, 0, StatusOnline},
+		{mockClock.Now().Add(-121 * time.Second), 60, 60, 0, 0, StatusOffline},
 
-		{mockClock.Now().Add(-1 * time.Second), 10, 10, StatusOnline},
-		{mockClock.Now().Add(-2 * time.Minute), 10, 10, StatusOffline},
-		{mockClock.Now().Add(-31 * 24 * time.Hour), 10, 10, StatusMIA},
+		{mockClock.Now().Add(-1 * time.Second), 10, 10, 0, 0, StatusOnline},
+		{mockClock.Now().Add(-2 * time.Minute), 10, 10, 0, 0, StatusOffline},
+		{mockClock.Now().Add(-31 * 24 * time.Hour), 10, 10, 0, 0, StatusMIA},
 
 		// Ensure behavior is reasonable if we don't have the values
-		{mockClock.Now().Add(-1 * time.Second), 0, 0, StatusOnline},
-		{mockClock.Now().Add(-2 * time.Minute), 0, 0, StatusOffline},
-		{mockClock.Now().Add(-31 * 24 * time.Hour), 0, 0, StatusMIA},
+		{mockClock.Now().Add(-1 * time.Second), 0, 0, 0, 0, StatusOnline},
+		{mockClock.Now().Add(-2 * time.Minute), 0, 0, 0, 0, StatusOffline},
+		{mockClock.Now().Add(-31 * 24 * time.Hour), 0, 0, 0, 0, StatusMIA},
+
+		// The offline threshold only applies if it is longer than the interval
+		{mockClock.Now().Add(-2 * time.Minute), 10, 10, 5 * time.Minute, 0, StatusOnline},
+		{mockClock.Now().Add(-6 * time.Minute), 10, 10, 5 * time.Minute, 0, StatusOffline},
+		{mockClock.Now().Add(-75 * time.Second), 60, 60, 30 * time.Second, 0, StatusOnline},
+		{mockClock.Now().Add(-31 * 24 * time.Hour), 10, 10, 5 * time.Minute, 0, StatusMIA},
+
+		// The hosts are missing after the missing threshold if set, until they
+		// are MIA
+		{mockClock.Now().Add(-8 * 24 * time.Hour), 10, 10, 0, 0, StatusOffline},
+		{mockClock.Now().Add(-29 * 24 * time.Hour), 10, 10, 0, 0, StatusOffline},
+		{mockClock.Now().Add(-6 * 24 * time.Hour), 10, 10, 0, 7, StatusOffline},
+		{mockClock.Now().Add(-8 * 24 * time.Hour), 10, 10, 0, 7, StatusMissing},
+		{mockClock.Now().Add(-8 * 24 * time.Hour), 10, 10, 0, 14, StatusOffline},
+		{mockClock.Now().Add(-15 * 24 * time.Hour), 10, 10, 0, 14, StatusMissing},
+		{mockClock.Now().Add(-31 * 24 * time.Hour), 10, 10, 0, 14, StatusMIA},
 	}
 
 	for _, tt := range testCases {
@@ -45,11 +65,51 @@ func TestHostStatus(t *testing.T) {
 				SeenTime:            tt.seenTime,
 			}
 
-			assert.Equal(t, tt.status, h.Status(mockClock.Now()))
+			settings := HostSettings{
+				OfflineThreshold:     Duration{Duration: tt.offlineThreshold},
+				MissingThresholdDays: tt.missingDays,
+			}
+			assert.Equal(t, tt.status, h.Status(mockClock.Now(), settings))
 		})
 	}
 }
 
+func TestHostCopy(t *testing.T) {
+	var nilHost *Host
+	assert.Nil(t, nilHost.Copy())
+
+	additional := json.RawMessage(`{"foo": "bar"}`)
+	h := &Host{
+		ID:                    1,
+		Hostname:              "foo",
+		TeamID:                ptr.Uint(2),
+		Additional:            &additional,
+		DiskEncryptionEnabled: ptr.Bool(true),
+		NetworkInterfaces:     []*NetworkInterface{{IPAddress: "10.0.0.1"}},
+		PackStats:             []PackStats{{PackName: "pack", QueryStats: []ScheduledQueryStats{{QueryName: "query"}}}},
+		Users:                 []HostUser{{Username: "root"}},
+	}
+	clone := h.Copy()
+	assert.Equal(t, h, clone)
+
+	// modifying the copy does not affect the host
+	clone.Hostname = "bar"
+	*clone.TeamID = 3
+	(*clone.Additional)[2] = 'x'
+	*clone.DiskEncryptionEnabled = false
+	clone.NetworkInterfaces[0].IPAddress = "10.0.0.2"
+	clone.PackStats[0].QueryStats[0].QueryName = "other"
+	clone.Users[0].Username = "admin"
+
+	assert.Equal(t, "foo", h.Hostname)
+	assert.Equal(t, uint(2), *h.TeamID)
+	assert.JSONEq(t, `{"foo": "bar"}`, string(*h.Additional))
+	assert.True(t, *h.DiskEncryptionEnabled)
+	assert.Equal(t, "10.0.0.1", h.NetworkInterfaces[0].IPAddress)
+	assert.Equal(t, "query", h.PackStats[0].QueryStats[0].QueryName)
+	assert.Equal(t, "root", h.Users[0].Username)
+}
+
 func TestHostIsNew(t *testing.T) {
 	mockClock := clock.NewMockClock()
 
@@ -121,3 +181,17 @@ func TestPlatformFromHost(t *testing.T) {
 
 	}
 }
+
+func TestHostComputeDisplayName(t *testing.T) {
+	host := &Host{Hostname: "foo.local", ComputerName: "foo", HardwareSerial: "ABC123"}
+
+	assert.Equal(t, "foo", host.ComputeDisplayName([]string{HostDisplayNameSourceComputerName, HostDisplayNameSourceHostname}))
+	assert.Equal(t, "ABC123", host.ComputeDisplayName([]string{HostDisplayNameSourceHardwareSerial}))
+	assert.Equal(t, "foo.local", host.ComputeDisplayName([]string{HostDisplayNameSourceHostname, HostDisplayNameSourceComputerName}))
+
+	// empty fields are skipped, and the hostname is the last fallback
+	host.ComputerName = ""
+	assert.Equal(t, "foo.local", host.ComputeDisplayName([]string{HostDisplayNameSourceComputerName}))
+	assert.Equal(t, "ABC123", host.ComputeDisplayName([]string{HostDisplayNameSourceComputerName, HostDisplayNameSourceHardwareSerial}))
+	assert.Equal(t, "foo.local", host.ComputeDisplayName(nil))
+}
@@ -0,0 +1,239 @@
+package fleet
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// LabelExpressionOp is the operator of a label expression.
+type LabelExpressionOp string
+
+const (
+	// LabelExpressionLabel is the operator of the expressions matching the
+	// members of a label.
+	LabelExpressionLabel LabelExpressionOp = "label"
+	LabelExpressionAnd   LabelExpressionOp = "AND"
+	LabelExpressionOr    LabelExpressionOp = "OR"
+	LabelExpressionNot   LabelExpressionOp = "NOT"
+)
+
+// LabelExpression is a boolean expression over the membership of the hosts in
+// labels, such as `(label:servers AND label:ubuntu) AND NOT label:staging`,
+// used to target the hosts of a campaign.
+//
+// Labels are referenced by name, with `label:<name>` or, for the names with
+// spaces or parentheses, `label:"<name>"`. The operators are, by order of
+// precedence, NOT, AND and OR, and can be grouped with parentheses.
+type LabelExpression struct {
+	Op LabelExpressionOp
+	// Label is the name of the label of a LabelExpressionLabel expression.
+	Label string
+	// Operands are the operands of the AND, OR and NOT expressions (a NOT
+	// expression has a single operand).
+	Operands []*LabelExpression
+}
+
+// ParseLabelExpression parses a label expression.
+func ParseLabelExpression(s string) (*LabelExpression, error) {
+	tokens, err := tokenizeLabelExpression(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &labelExpressionParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != "" {
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+	return expr, nil
+}
+
+// LabelNames returns the names of the labels referenced by the expression,
+// without duplicates.
+func (e *LabelExpression) LabelNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(e *LabelExpression)
+	walk = func(e *LabelExpression) {
+		if e.Op == LabelExpressionLabel {
+			if !seen[e.Label] {
+				seen[e.Label] = true
+				names = append(names, e.Label)
+			}
+			return
+		}
+		for _, operand := range e.Operands {
+			walk(operand)
+		}
+	}
+	walk(e)
+	return names
+}
+
+// String returns the expression with its operations explicitly grouped.
+func (e *LabelExpression) String() string {
+	switch e.Op {
+	case LabelExpressionLabel:
+		return "label:" + strconv.Quote(e.Label)
+	case LabelExpressionNot:
+		return "NOT " + e.Operands[0].String()
+	default:
+		operands := make([]string, 0, len(e.Operands))
+		for _, operand := range e.Operands {
+			operands = append(operands, operand.String())
+		}
+		return "(" + strings.Join(operands, " "+string(e.Op)+" ") + ")"
+	}
+}
+
+const labelExpressionPrefix = "label:"
+
+// tokenizeLabelExpression splits the expression into parentheses, operators
+// and label references. The label references are returned with their
+// (unquoted) name after the "label:" prefix.
+func tokenizeLabelExpression(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case strings.HasPrefix(s[i:], labelExpressionPrefix+`"`):
+			start := i + len(labelExpressionPrefix)
+			end := start + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, errors.New("unterminated label name")
+			}
+			name, err := strconv.Unquote(s[start : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid label name %s", s[start:end+1])
+			}
+			if name == "" {
+				return nil, errors.New("empty label name")
+			}
+			tokens = append(tokens, labelExpressionPrefix+name)
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) && !unicode.IsSpace(rune(s[end])) && s[end] != '(' && s[end] != ')' {
+				end++
+			}
+			word := s[i:end]
+			switch {
+			case strings.HasPrefix(word, labelExpressionPrefix):
+				if word == labelExpressionPrefix {
+					return nil, errors.New("empty label name")
+				}
+			case isLabelExpressionOperator(word):
+				word = strings.ToUpper(word)
+			default:
+				return nil, fmt.Errorf("unexpected %q, expected label:<name>, AND, OR, NOT or parentheses", word)
+			}
+			tokens = append(tokens, word)
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+func isLabelExpressionOperator(word string) bool {
+	switch LabelExpressionOp(strings.ToUpper(word)) {
+	case LabelExpressionAnd, LabelExpressionOr, LabelExpressionNot:
+		return true
+	}
+	return false
+}
+
+// labelExpressionParser is a recursive descent parser of label expressions.
+type labelExpressionParser struct {
+	tokens []string
+	pos    int
+}
+
+// peek returns the next token, or an empty string at the end of the
+// expression.
+func (p *labelExpressionParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *labelExpressionParser) parseOr() (*LabelExpression, error) {
+	return p.parseBinary(LabelExpressionOr, p.parseAnd)
+}
+
+func (p *labelExpressionParser) parseAnd() (*LabelExpression, error) {
+	return p.parseBinary(LabelExpressionAnd, p.parseNot)
+}
+
+// parseBinary parses the operands of the op operator, parsed with operand.
+func (p *labelExpressionParser) parseBinary(op LabelExpressionOp, operand func() (*LabelExpression, error)) (*LabelExpression, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	operands := []*LabelExpression{first}
+	for p.peek() == string(op) {
+		p.pos++
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, next)
+	}
+	if len(operands) == 1 {
+		return first, nil
+	}
+	return &LabelExpression{Op: op, Operands: operands}, nil
+}
+
+func (p *labelExpressionParser) parseNot() (*LabelExpression, error) {
+	if p.peek() != string(LabelExpressionNot) {
+		return p.parsePrimary()
+	}
+	p.pos++
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	return &LabelExpression{Op: LabelExpressionNot, Operands: []*LabelExpression{operand}}, nil
+}
+
+func (p *labelExpressionParser) parsePrimary() (*LabelExpression, error) {
+	tok := p.peek()
+	switch {
+	case tok == "":
+		return nil, errors.New("unexpected end of expression")
+	case tok == "(":
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		p.pos++
+		return expr, nil
+	case strings.HasPrefix(tok, labelExpressionPrefix):
+		p.pos++
+		return &LabelExpression{Op: LabelExpressionLabel, Label: strings.TrimPrefix(tok, labelExpressionPrefix)}, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+}
@@ -0,0 +1,63 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabelExpression(t *testing.T) {
+	testCases := []struct {
+		expr   string
+		parsed string
+		labels []string
+	}{
+		{"label:servers", `label:"servers"`, []string{"servers"}},
+		{
+			"(label:servers AND label:ubuntu) AND NOT label:staging",
+			`((label:"servers" AND label:"ubuntu") AND NOT label:"staging")`,
+			[]string{"servers", "ubuntu", "staging"},
+		},
+		{
+			"label:a or label:b and not label:c",
+			`(label:"a" OR (label:"b" AND NOT label:"c"))`,
+			[]string{"a", "b", "c"},
+		},
+		{
+			`label:"All Linux" AND NOT NOT (label:a OR label:"All Linux")`,
+			`(label:"All Linux" AND NOT NOT (label:"a" OR label:"All Linux"))`,
+			[]string{"All Linux", "a"},
+		},
+		{
+			`label:"quote \" (paren)" OR label:x`,
+			`(label:"quote \" (paren)" OR label:"x")`,
+			[]string{`quote " (paren)`, "x"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			expr, err := ParseLabelExpression(tc.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tc.parsed, expr.String())
+			assert.Equal(t, tc.labels, expr.LabelNames())
+		})
+	}
+
+	for _, invalid := range []string{
+		"",
+		"servers",
+		"label:",
+		`label:""`,
+		`label:"servers`,
+		"label:a AND",
+		"label:a label:b",
+		"(label:a OR label:b",
+		"label:a)",
+		"NOT",
+		"label:a XOR label:b",
+	} {
+		_, err := ParseLabelExpression(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -16,6 +16,9 @@ type LabelPayload struct {
 	Query       *string `json:"query"`
 	Platform    *string `json:"platform"`
 	Description *string `json:"description"`
+	// LabelMembershipType is dynamic by default, the query is optional for
+	// manual labels.
+	LabelMembershipType LabelMembershipType `json:"label_membership_type"`
 }
 
 // LabelType is used to catagorize the kind of label
@@ -0,0 +1,34 @@
+package fleet
+
+// The protocols of the ports listening on hosts.
+const (
+	ListeningPortProtocolTCP = "tcp"
+	ListeningPortProtocolUDP = "udp"
+)
+
+// HostListeningPort is a port listening for connections on a non-loopback
+// address of a host, with the name of the process listening on it.
+type HostListeningPort struct {
+	Port        uint16 `json:"port" db:"port"`
+	Protocol    string `json:"protocol" db:"protocol"`
+	ProcessName string `json:"process_name" db:"process_name"`
+}
+
+// ListeningPortHostsCount is the number of hosts where a process listens on
+// a port.
+type ListeningPortHostsCount struct {
+	HostListeningPort
+	HostsCount uint `json:"hosts_count" db:"hosts_count"`
+}
+
+type ListeningPortsReportOptions struct {
+	ListOptions
+
+	// Port filters the report to the port.
+	Port *uint
+	// Protocol filters the report to the protocol, one of
+	// ListeningPortProtocolTCP or ListeningPortProtocolUDP.
+	Protocol string
+	// ProcessName filters the report to the process name.
+	ProcessName string
+}
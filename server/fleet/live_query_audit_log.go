@@ -0,0 +1,42 @@
+package fleet
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LiveQueryAuditLog is the audit log entry of a live query campaign, recording
+// who ran which query against which targets and when.
+type LiveQueryAuditLog struct {
+	ID        uint      `json:"id" db:"id"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// UserID, UserName and UserEmail are those of the user that ran the
+	// query, at the time the query was run.
+	UserID     uint   `json:"user_id" db:"user_id"`
+	UserName   string `json:"user_name" db:"user_name"`
+	UserEmail  string `json:"user_email" db:"user_email"`
+	CampaignID uint   `json:"campaign_id" db:"distributed_query_campaign_id"`
+	// QueryID, QueryName and QuerySQL are those of the query run, at the time
+	// the query was run.
+	QueryID   uint   `json:"query_id" db:"query_id"`
+	QueryName string `json:"query_name" db:"query_name"`
+	QuerySQL  string `json:"query" db:"query_sql"`
+	// Targets are the targets of the campaign as requested, encoded as JSON
+	// (see HostTargets).
+	Targets json.RawMessage `json:"targets" db:"targets"`
+	// TargetedHosts is the number of hosts targeted by the campaign.
+	TargetedHosts uint `json:"targeted_hosts" db:"targeted_hosts"`
+}
+
+type LiveQueryAuditLogOptions struct {
+	// ListOptions.MatchQuery matches the SQL of the queries and the email of
+	// the users.
+	ListOptions
+
+	// StartDate and EndDate are the first and last days (inclusive, UTC) of
+	// the audit log.
+	StartDate time.Time
+	EndDate   time.Time
+	// UserID filters the audit log to the user.
+	UserID *uint
+}
@@ -16,4 +16,8 @@ type LiveQueryStore interface {
 	// given host. After calling QueryCompleted, that query will no longer be
 	// sent to the host.
 	QueryCompletedByHost(name string, hostID uint) error
+	// RemoveHostsFromQuery removes the given hosts from the targets of the
+	// running query with the given name, so that the query is no longer sent
+	// to those hosts.
+	RemoveHostsFromQuery(name string, hostIDs []uint) error
 }
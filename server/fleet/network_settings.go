@@ -0,0 +1,50 @@
+package fleet
+
+// The protocols of the proxies configured on hosts.
+const (
+	HostProxyProtocolHTTP  = "http"
+	HostProxyProtocolHTTPS = "https"
+	HostProxyProtocolSOCKS = "socks"
+	// HostProxyProtocolAutoConfig is the protocol of the proxy auto-config
+	// (PAC) URLs.
+	HostProxyProtocolAutoConfig = "auto_config"
+)
+
+// HostProxy is a proxy configured on a host. Address is the host:port of the
+// proxy, or the URL of the proxy auto-config file.
+type HostProxy struct {
+	Protocol string `json:"protocol" db:"protocol"`
+	Address  string `json:"address" db:"address"`
+}
+
+// HostNetworkAddress is an IP address of a network interface of a host, along
+// with the MAC address of the interface.
+type HostNetworkAddress struct {
+	Address string `json:"address" db:"address"`
+	MAC     string `json:"mac" db:"mac"`
+}
+
+// HostNetworkSettings are the DNS servers and proxies configured on a host.
+type HostNetworkSettings struct {
+	DNSServers []string    `json:"dns_servers"`
+	Proxies    []HostProxy `json:"proxies"`
+}
+
+// DNSServerHostsCount is the number of hosts configured with a DNS server.
+type DNSServerHostsCount struct {
+	Address    string `json:"address" db:"address"`
+	HostsCount uint   `json:"hosts_count" db:"hosts_count"`
+}
+
+// ProxyHostsCount is the number of hosts configured with a proxy.
+type ProxyHostsCount struct {
+	HostProxy
+	HostsCount uint `json:"hosts_count" db:"hosts_count"`
+}
+
+// NetworkSettingsReport is the fleet-wide report of the DNS servers and
+// proxies configured on the hosts, sorted by decreasing number of hosts.
+type NetworkSettingsReport struct {
+	DNSServers []DNSServerHostsCount `json:"dns_servers"`
+	Proxies    []ProxyHostsCount     `json:"proxies"`
+}
@@ -24,6 +24,9 @@ type QueryContent struct {
 	Removed     *bool   `json:"removed,omitempty"`
 	Shard       *uint   `json:"shard,omitempty"`
 	Denylist    *bool   `json:"denylist,omitempty"`
+	// LabelIDs are the labels the query is restricted to, they are not part of
+	// the osquery config and are used by Fleet to filter the queries of a host.
+	LabelIDs []uint `json:"-"`
 }
 
 type PermissiveQueryContent struct {
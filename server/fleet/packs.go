@@ -107,6 +107,9 @@ type PackSpecQuery struct {
 	Platform    *string `json:"platform,omitempty"`
 	Version     *string `json:"version,omitempty"`
 	Denylist    *bool   `json:"denylist,omitempty"`
+	DiscardData bool    `json:"discard_data,omitempty" db:"discard_data"`
+	// Labels are the names of the labels the query is restricted to.
+	Labels []string `json:"labels,omitempty" db:"-"`
 }
 
 // PackTarget targets a pack to a host, label, or team.
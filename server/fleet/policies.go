@@ -3,6 +3,7 @@ package fleet
 import (
 	"errors"
 	"strings"
+	"time"
 )
 
 // PolicyPayload holds data for policy creation.
@@ -27,6 +28,20 @@ type PolicyPayload struct {
 	//
 	// Empty string targets all platforms.
 	Platform string
+	// Critical marks the policy as critical, critical policies are listed
+	// first and evaluated more frequently on the hosts.
+	Critical bool
+	// EvaluationWindow is the cron-like expression of the times the policy
+	// is evaluated on the hosts, see PolicyEvaluationWindow.
+	//
+	// Empty string evaluates the policy at any time.
+	EvaluationWindow string
+	// RemediationScript is the script run on the hosts to remediate the
+	// policy.
+	RemediationScript string
+	// AutoRemediate indicates the remediation script is run automatically on
+	// the hosts the policy starts failing on.
+	AutoRemediate bool
 }
 
 var (
@@ -35,6 +50,9 @@ var (
 	errPolicyIDAndQuerySet   = errors.New("both fields \"queryID\" and \"query\" cannot be set")
 	errPolicyInvalidQuery    = errors.New("invalid policy query")
 	errPolicyInvalidPlatform = errors.New("invalid policy platform")
+
+	errPolicyRemediationScriptTooLong   = errors.New("policy remediation script is too long")
+	errPolicyAutoRemediateWithoutScript = errors.New("policy remediation script cannot be empty if auto remediate is set")
 )
 
 // Verify verifies the policy payload is valid.
@@ -54,6 +72,12 @@ func (p PolicyPayload) Verify() error {
 	if err := verifyPolicyPlatforms(p.Platform); err != nil {
 		return err
 	}
+	if err := verifyPolicyEvaluationWindow(p.EvaluationWindow); err != nil {
+		return err
+	}
+	if err := verifyPolicyRemediation(p.RemediationScript, p.AutoRemediate); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -93,6 +117,20 @@ func verifyPolicyPlatforms(platforms string) error {
 	return nil
 }
 
+// maxPolicyRemediationScriptSize is the maximum size in bytes of a remediation
+// script (the size of a TEXT column).
+const maxPolicyRemediationScriptSize = 65535
+
+func verifyPolicyRemediation(script string, autoRemediate bool) error {
+	if len(script) > maxPolicyRemediationScriptSize {
+		return errPolicyRemediationScriptTooLong
+	}
+	if autoRemediate && emptyString(script) {
+		return errPolicyAutoRemediateWithoutScript
+	}
+	return nil
+}
+
 // ModifyPolicyPayload holds data for policy modification.
 type ModifyPolicyPayload struct {
 	// Name is the name of the policy.
@@ -106,6 +144,17 @@ type ModifyPolicyPayload struct {
 	// Platform is a comma-separated string to indicate the target platforms.
 	// If non-nil, empty string targets all platforms.
 	Platform *string `json:"platform"`
+	// Critical marks the policy as critical.
+	Critical *bool `json:"critical"`
+	// EvaluationWindow is the cron-like expression of the times the policy is
+	// evaluated on the hosts. If non-nil, empty string evaluates the policy at
+	// any time.
+	EvaluationWindow *string `json:"evaluation_window"`
+	// RemediationScript is the script run on the hosts to remediate the
+	// policy. If non-nil, empty string removes the script.
+	RemediationScript *string `json:"remediation_script"`
+	// AutoRemediate indicates the remediation script is run automatically.
+	AutoRemediate *bool `json:"auto_remediate"`
 }
 
 // Verify verifies the policy payload is valid.
@@ -125,6 +174,16 @@ func (p ModifyPolicyPayload) Verify() error {
 			return err
 		}
 	}
+	if p.EvaluationWindow != nil {
+		if err := verifyPolicyEvaluationWindow(*p.EvaluationWindow); err != nil {
+			return err
+		}
+	}
+	if p.RemediationScript != nil {
+		if err := verifyPolicyRemediation(*p.RemediationScript, false); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -155,10 +214,33 @@ type PolicyData struct {
 	//
 	// Empty string targets all platforms.
 	Platform string `json:"platform" db:"platforms"`
+	// Critical indicates the policy is critical, critical policies are listed
+	// first and evaluated more frequently on the hosts.
+	Critical bool `json:"critical" db:"critical"`
+	// EvaluationWindow is the cron-like expression of the times the policy
+	// is evaluated on the hosts, see PolicyEvaluationWindow.
+	//
+	// Empty string evaluates the policy at any time.
+	EvaluationWindow string `json:"evaluation_window" db:"evaluation_window"`
+	// RemediationScript is the script run on the hosts to remediate the
+	// policy.
+	RemediationScript *string `json:"remediation_script,omitempty" db:"remediation_script"`
+	// AutoRemediate indicates the remediation script is run automatically on
+	// the hosts the policy starts failing on.
+	AutoRemediate bool `json:"auto_remediate" db:"auto_remediate"`
 
 	UpdateCreateTimestamps
 }
 
+// VerifyRemediation verifies the remediation settings of the policy are valid.
+func (p PolicyData) VerifyRemediation() error {
+	var script string
+	if p.RemediationScript != nil {
+		script = *p.RemediationScript
+	}
+	return verifyPolicyRemediation(script, p.AutoRemediate)
+}
+
 // Policy is a fleet's policy query.
 type Policy struct {
 	PolicyData
@@ -167,6 +249,9 @@ type Policy struct {
 	PassingHostCount uint `json:"passing_host_count" db:"passing_host_count"`
 	// FailingHostCount is the number of hosts this policy fails on.
 	FailingHostCount uint `json:"failing_host_count" db:"failing_host_count"`
+	// HostCountUpdatedAt is the time the host counts were last aggregated,
+	// nil if they were not aggregated yet.
+	HostCountUpdatedAt *time.Time `json:"host_count_updated_at" db:"host_count_updated_at"`
 }
 
 func (p Policy) AuthzType() string {
@@ -186,6 +271,9 @@ type HostPolicy struct {
 	//	- "fail": if the policy was executed and did not pass.
 	//	- "": if the policy did not run yet.
 	Response string `json:"response" db:"response"`
+	// LastEvaluatedAt is the time the host last reported the result of the
+	// policy, nil if the policy did not run yet.
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at" db:"last_evaluated_at"`
 }
 
 // PolicySpec is used to hold policy data to apply policy specs.
@@ -206,6 +294,16 @@ type PolicySpec struct {
 	//
 	// Empty string targets all platforms.
 	Platform string `json:"platform,omitempty"`
+	// Critical marks the policy as critical.
+	Critical bool `json:"critical,omitempty"`
+	// EvaluationWindow is the cron-like expression of the times the policy
+	// is evaluated on the hosts.
+	EvaluationWindow string `json:"evaluation_window,omitempty"`
+	// RemediationScript is the script run on the hosts to remediate the
+	// policy.
+	RemediationScript string `json:"remediation_script,omitempty"`
+	// AutoRemediate indicates the remediation script is run automatically.
+	AutoRemediate bool `json:"auto_remediate,omitempty"`
 }
 
 // Verify verifies the policy data is valid.
@@ -219,9 +317,47 @@ func (p PolicySpec) Verify() error {
 	if err := verifyPolicyPlatforms(p.Platform); err != nil {
 		return err
 	}
+	if err := verifyPolicyEvaluationWindow(p.EvaluationWindow); err != nil {
+		return err
+	}
+	if err := verifyPolicyRemediation(p.RemediationScript, p.AutoRemediate); err != nil {
+		return err
+	}
 	return nil
 }
 
+// PolicyBundle is a set of policies that can be imported at once, e.g. the
+// automated checks of a CIS benchmark.
+type PolicyBundle struct {
+	// ID is the unique identifier of the bundle, e.g. "cis-macos".
+	ID string `json:"id"`
+	// Name is the name of the bundle.
+	Name string `json:"name"`
+	// Description describes the bundle.
+	Description string `json:"description"`
+	// Platform is the target platform of the policies of the bundle.
+	Platform string `json:"platform"`
+	// PolicyCount is the number of policies of the bundle.
+	PolicyCount int `json:"policy_count"`
+	// Policies are the policies of the bundle, they are not set when the
+	// bundles are listed.
+	Policies []*PolicyBundlePolicy `json:"policies,omitempty"`
+}
+
+// PolicyBundlePolicy is a policy of a policy bundle.
+type PolicyBundlePolicy struct {
+	// CISControl is the number of the CIS control checked by the policy.
+	CISControl string `json:"cis_control"`
+	// Name is the name of the policy.
+	Name string `json:"name"`
+	// Query is the policy's SQL query.
+	Query string `json:"query"`
+	// Description describes the policy.
+	Description string `json:"description"`
+	// Resolution describes how to solve a failing policy.
+	Resolution string `json:"resolution"`
+}
+
 // FailingPolicySet holds sets of hosts that failed policy executions.
 type FailingPolicySet interface {
 	// ListSets lists all the policy sets.
@@ -244,6 +380,15 @@ type PolicySetHost struct {
 	Hostname string
 }
 
+// PolicyJiraIssue is the Jira issue opened for the hosts failing a policy.
+type PolicyJiraIssue struct {
+	PolicyID  uint      `db:"policy_id"`
+	IssueKey  string    `db:"issue_key"`
+	CreatedAt time.Time `db:"created_at"`
+	// FailingHostCount is the number of hosts the policy currently fails on.
+	FailingHostCount uint `db:"failing_host_count"`
+}
+
 type PolicyMembershipResult struct {
 	HostID   uint
 	PolicyID uint
@@ -0,0 +1,140 @@
+package fleet
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PolicyEvaluationWindow is a parsed policy evaluation window.
+//
+// An evaluation window is a cron-like expression of the times (in UTC) a
+// policy can be evaluated on the hosts, e.g. "* 1-4 * * 6,0" for between 01:00
+// and 04:59 on the weekends. The five fields are the minute (0-59), the hour
+// (0-23), the day of the month (1-31), the month (1-12) and the day of the week
+// (0-7, Sunday is 0 or 7). Each field is "*", a value, a range ("1-4") or a
+// comma-separated list of them ("6,0"), optionally followed by a step ("*/15",
+// "0-30/10"). As with cron, if both the day of the month and the day of the
+// week are restricted, a day matches if either field matches.
+type PolicyEvaluationWindow struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+type evaluationWindowField struct {
+	name     string
+	min, max int
+}
+
+var evaluationWindowFields = []evaluationWindowField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParsePolicyEvaluationWindow parses the evaluation window expression.
+func ParsePolicyEvaluationWindow(expr string) (*PolicyEvaluationWindow, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(evaluationWindowFields) {
+		return nil, fmt.Errorf("expected %d fields, got %d", len(evaluationWindowFields), len(parts))
+	}
+
+	var bits [5]uint64
+	for i, part := range parts {
+		b, err := parseEvaluationWindowField(part, evaluationWindowFields[i])
+		if err != nil {
+			return nil, err
+		}
+		bits[i] = b
+	}
+
+	// Sunday is both 0 and 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &PolicyEvaluationWindow{
+		minute:        bits[0],
+		hour:          bits[1],
+		dayOfMonth:    bits[2],
+		month:         bits[3],
+		dayOfWeek:     bits[4],
+		anyDayOfMonth: strings.HasPrefix(parts[2], "*"),
+		anyDayOfWeek:  strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+func parseEvaluationWindowField(s string, field evaluationWindowField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			rng = item[:i]
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %q", field.name, item)
+			}
+			step = n
+		}
+
+		start, end := field.min, field.max
+		if rng != "*" {
+			bounds := strings.SplitN(rng, "-", 2)
+			n, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field: %q", field.name, item)
+			}
+			start, end = n, n
+			if len(bounds) == 2 {
+				if end, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid value in %s field: %q", field.name, item)
+				}
+			} else if step > 1 {
+				// "n/step" means from n to the maximum
+				end = field.max
+			}
+		}
+		if start < field.min || end > field.max || start > end {
+			return 0, fmt.Errorf("%s field out of range (%d-%d): %q", field.name, field.min, field.max, item)
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Contains returns true if t is in the evaluation window.
+func (w *PolicyEvaluationWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	if w.minute&(1<<uint(t.Minute())) == 0 ||
+		w.hour&(1<<uint(t.Hour())) == 0 ||
+		w.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	dayOfMonth := w.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := w.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if w.anyDayOfMonth || w.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+var errPolicyInvalidEvaluationWindow = errors.New("invalid policy evaluation window")
+
+func verifyPolicyEvaluationWindow(expr string) error {
+	if expr == "" {
+		return nil
+	}
+	if _, err := ParsePolicyEvaluationWindow(expr); err != nil {
+		return fmt.Errorf("%w: %s", errPolicyInvalidEvaluationWindow, err)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package fleet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyEvaluationWindow(t *testing.T) {
+	// Saturday
+	sat := func(hour, min int) time.Time { return time.Date(2022, 3, 26, hour, min, 0, 0, time.UTC) }
+	// Sunday
+	sun := func(hour, min int) time.Time { return time.Date(2022, 3, 27, hour, min, 0, 0, time.UTC) }
+	// Monday
+	mon := func(hour, min int) time.Time { return time.Date(2022, 3, 28, hour, min, 0, 0, time.UTC) }
+
+	testCases := []struct {
+		expr string
+		in   []time.Time
+		out  []time.Time
+	}{
+		{"* * * * *", []time.Time{sat(0, 0), mon(23, 59)}, nil},
+		{"* 1-4 * * 6,0", []time.Time{sat(1, 0), sun(4, 59)}, []time.Time{sat(0, 59), sun(5, 0), mon(2, 0)}},
+		{"* 1-4 * * 6-7", []time.Time{sat(1, 0), sun(4, 59)}, []time.Time{mon(2, 0)}},
+		{"*/15 * * * *", []time.Time{sat(0, 0), sat(0, 15), sat(0, 45)}, []time.Time{sat(0, 1), sat(0, 50)}},
+		{"10/20 * * * *", []time.Time{sat(0, 10), sat(0, 30), sat(0, 50)}, []time.Time{sat(0, 0), sat(0, 20)}},
+		{"0-30/10 22 * * *", []time.Time{sat(22, 0), sat(22, 30)}, []time.Time{sat(22, 40), sat(21, 0)}},
+		{"0 0,12 * 3 *", []time.Time{sat(0, 0), mon(12, 0)}, []time.Time{sat(6, 0), time.Date(2022, 4, 2, 0, 0, 0, 0, time.UTC)}},
+		// both days restricted: either matches
+		{"* * 28 * 6", []time.Time{sat(3, 0), mon(3, 0)}, []time.Time{sun(3, 0)}},
+		// only one day restricted
+		{"* * */2 * *", []time.Time{sun(3, 0)}, []time.Time{sat(3, 0), mon(3, 0)}},
+		{"* * * * 1", []time.Time{mon(3, 0)}, []time.Time{sat(3, 0), sun(3, 0)}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			w, err := ParsePolicyEvaluationWindow(tc.expr)
+			require.NoError(t, err)
+			for _, tm := range tc.in {
+				assert.True(t, w.Contains(tm), tm)
+			}
+			for _, tm := range tc.out {
+				assert.False(t, w.Contains(tm), tm)
+			}
+		})
+	}
+
+	// the times are compared in UTC
+	w, err := ParsePolicyEvaluationWindow("* 1 * * *")
+	require.NoError(t, err)
+	assert.True(t, w.Contains(time.Date(2022, 3, 26, 3, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))))
+
+	for _, invalid := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1- * * * *",
+		"1,,2 * * * *",
+	} {
+		t.Run(invalid, func(t *testing.T) {
+			_, err := ParsePolicyEvaluationWindow(invalid)
+			require.Error(t, err)
+		})
+	}
+
+	require.NoError(t, verifyPolicyEvaluationWindow(""))
+	err = verifyPolicyEvaluationWindow("* * *")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errPolicyInvalidEvaluationWindow))
+}
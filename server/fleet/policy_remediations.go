@@ -0,0 +1,46 @@
+package fleet
+
+import "time"
+
+// PolicyRemediationStatus is the status of a policy remediation on a host.
+type PolicyRemediationStatus string
+
+const (
+	// PolicyRemediationPending is the status of the remediations that were not
+	// run on the host yet.
+	PolicyRemediationPending PolicyRemediationStatus = "pending"
+	// PolicyRemediationSucceeded is the status of the remediations whose script
+	// exited with a zero exit code.
+	PolicyRemediationSucceeded PolicyRemediationStatus = "succeeded"
+	// PolicyRemediationFailed is the status of the remediations whose script
+	// exited with a non-zero exit code or could not be run.
+	PolicyRemediationFailed PolicyRemediationStatus = "failed"
+)
+
+// MaxPolicyRemediationOutputSize is the maximum size in bytes of the output of
+// a remediation script that is stored, the rest is truncated.
+const MaxPolicyRemediationOutputSize = 10000
+
+// HostPolicyRemediation is a run of the remediation script of a policy on a
+// host.
+type HostPolicyRemediation struct {
+	ID       uint `json:"id" db:"id"`
+	HostID   uint `json:"host_id" db:"host_id"`
+	PolicyID uint `json:"policy_id" db:"policy_id"`
+	// PolicyName is retrieved with a join to the policies table.
+	PolicyName string `json:"policy_name" db:"policy_name"`
+	// AuthorID is the ID of the user that requested the remediation, nil for
+	// the automatic remediations (or if the user was deleted).
+	AuthorID *uint `json:"author_id" db:"author_id"`
+	// Script is the remediation script of the policy at the time the
+	// remediation was requested.
+	Script string                  `json:"script" db:"script"`
+	Status PolicyRemediationStatus `json:"status" db:"status"`
+	// ExitCode is the exit code of the script, nil if it was not run yet.
+	ExitCode *int `json:"exit_code" db:"exit_code"`
+	// Output is the (possibly truncated) combined output of the script, nil
+	// if it was not run yet.
+	Output    *string   `json:"output" db:"output"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -14,6 +14,12 @@ type QueryPayload struct {
 	Description    *string
 	Query          *string
 	ObserverCanRun *bool `json:"observer_can_run"`
+	// TeamID is the ID of the team the query belongs to. It can only be set
+	// when creating a query.
+	TeamID *uint `json:"team_id"`
+	// Version is the version of the query the modifications are based on.
+	// If set, the query is only modified if it is still at that version.
+	Version *uint `json:"version"`
 }
 
 type Query struct {
@@ -27,6 +33,13 @@ type Query struct {
 	// a live query.
 	ObserverCanRun bool  `json:"observer_can_run" db:"observer_can_run"`
 	AuthorID       *uint `json:"author_id" db:"author_id"`
+	// TeamID is the ID of the team the query belongs to. If TeamID is nil, the
+	// query is global and is visible to all users, otherwise it is only
+	// visible to the members of the team and to users with a global role.
+	TeamID *uint `json:"team_id" db:"team_id"`
+	// Version is incremented each time the query is modified, to detect
+	// concurrent modifications.
+	Version uint `json:"version" db:"version"`
 	// AuthorName is retrieved with a join to the users table in the MySQL
 	// backend (using AuthorID)
 	AuthorName string `json:"author_name" db:"author_name"`
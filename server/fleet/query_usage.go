@@ -0,0 +1,49 @@
+package fleet
+
+import "time"
+
+// QueryUsage is the live query usage of a user on the hosts of a team, summed
+// over a range of days.
+type QueryUsage struct {
+	UserID uint `json:"user_id" db:"user_id"`
+	// UserName and UserEmail are nil if the user was deleted.
+	UserName  *string `json:"user_name" db:"user_name"`
+	UserEmail *string `json:"user_email" db:"user_email"`
+	// TeamID is nil for the hosts without a team.
+	TeamID *uint `json:"team_id" db:"team_id"`
+	// TeamName is nil for the hosts without a team, or if the team was deleted.
+	TeamName *string `json:"team_name" db:"team_name"`
+	// CampaignsLaunched is the number of live query campaigns that targeted
+	// at least one host of the team.
+	CampaignsLaunched uint `json:"campaigns_launched" db:"campaigns_launched"`
+	// HostsTargeted is the number of hosts of the team targeted by the
+	// campaigns.
+	HostsTargeted uint `json:"hosts_targeted" db:"hosts_targeted"`
+	// RowsReturned is the number of rows returned by the hosts of the team.
+	RowsReturned uint64 `json:"rows_returned" db:"rows_returned"`
+}
+
+// QueryRowsUsage is the number of rows returned by the hosts of a team to the
+// live queries of a user on a day.
+type QueryRowsUsage struct {
+	UserID uint
+	// TeamID is 0 for the hosts without a team.
+	TeamID uint
+	// Date is the day (UTC) the rows were returned.
+	Date time.Time
+	Rows uint64
+}
+
+type QueryUsageOptions struct {
+	ListOptions
+
+	// StartDate and EndDate are the first and last days (inclusive, UTC) of
+	// the usage.
+	StartDate time.Time
+	EndDate   time.Time
+	// TeamID filters the usage to the hosts of the team, 0 for the hosts
+	// without a team.
+	TeamID *uint
+	// UserID filters the usage to the user.
+	UserID *uint
+}
@@ -46,6 +46,15 @@ type ScheduledQuery struct {
 	// (when stopped by the Watchdog for excessive resource consumption),
 	// default is true.
 	Denylist *bool `json:"denylist"`
+	// DiscardData is a boolean to determine if the result logs of this query
+	// are discarded by Fleet instead of being written to the result log
+	// destination. The query still runs on the hosts, e.g. so that its stats
+	// are reported.
+	DiscardData bool `json:"discard_data" db:"discard_data"`
+	// LabelIDs restricts this query to the hosts that are members of any of
+	// these labels. An empty list means the scheduled query will run on all
+	// the hosts targeted by the pack.
+	LabelIDs []uint `json:"label_ids,omitempty" db:"-"`
 
 	AggregatedStats `json:"stats,omitempty"`
 }
@@ -58,16 +67,26 @@ type AggregatedStats struct {
 	TotalExecutions *float64 `json:"total_executions" db:"total_executions"`
 }
 
+// PackScheduledQueryName identifies a scheduled query by the names of its
+// pack and of the scheduled query, as reported by osquery in the result logs
+// and stats of the query.
+type PackScheduledQueryName struct {
+	PackName           string `db:"pack_name"`
+	ScheduledQueryName string `db:"scheduled_query_name"`
+}
+
 type ScheduledQueryPayload struct {
-	PackID   *uint     `json:"pack_id"`
-	QueryID  *uint     `json:"query_id"`
-	Interval *uint     `json:"interval"`
-	Snapshot *bool     `json:"snapshot"`
-	Removed  *bool     `json:"removed"`
-	Platform *string   `json:"platform"`
-	Version  *string   `json:"version"`
-	Shard    *null.Int `json:"shard"`
-	Denylist *bool     `json:"denylist"`
+	PackID      *uint     `json:"pack_id"`
+	QueryID     *uint     `json:"query_id"`
+	Interval    *uint     `json:"interval"`
+	Snapshot    *bool     `json:"snapshot"`
+	Removed     *bool     `json:"removed"`
+	Platform    *string   `json:"platform"`
+	Version     *string   `json:"version"`
+	Shard       *null.Int `json:"shard"`
+	Denylist    *bool     `json:"denylist"`
+	DiscardData *bool     `json:"discard_data"`
+	LabelIDs    *[]uint   `json:"label_ids"`
 }
 
 type ScheduledQueryStats struct {
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"time"
 
-	"github.com/fleetdm/fleet/v4/server/websocket"
 	"github.com/kolide/kit/version"
 )
 
@@ -16,6 +15,9 @@ type OsqueryService interface {
 	// AuthenticateHost loads host identified by nodeKey. Returns an error if the nodeKey doesn't exist.
 	AuthenticateHost(ctx context.Context, nodeKey string) (host *Host, debug bool, err error)
 	GetClientConfig(ctx context.Context) (config map[string]interface{}, err error)
+	// GetClientFlags returns the osquery command-line flags of the host in the provided context, so that the agent
+	// managing osquery on the host can apply them (e.g. by rewriting the flagfile and restarting osquery).
+	GetClientFlags(ctx context.Context) (flags json.RawMessage, err error)
 	// GetDistributedQueries retrieves the distributed queries to run for the host in
 	// the provided context. These may be (depending on update intervals):
 	//	- detail queries (including additional queries, if any),
@@ -176,6 +178,10 @@ type Service interface {
 
 	// ListHostsInLabel returns a slice of hosts in the label with the given ID.
 	ListHostsInLabel(ctx context.Context, lid uint, opt HostListOptions) ([]*Host, error)
+	// AddHostsToLabel adds the hosts to the members of the manual label with the given ID.
+	AddHostsToLabel(ctx context.Context, lid uint, hostIDs []uint) error
+	// RemoveHostsFromLabel removes the hosts from the members of the manual label with the given ID.
+	RemoveHostsFromLabel(ctx context.Context, lid uint, hostIDs []uint) error
 
 	///////////////////////////////////////////////////////////////////////////////
 	// QueryService
@@ -188,8 +194,9 @@ type Service interface {
 	GetQuerySpec(ctx context.Context, name string) (*QuerySpec, error)
 
 	// ListQueries returns a list of saved queries. Note only saved queries should be returned (those that are created
-	// for distributed queries but not saved should not be returned).
-	ListQueries(ctx context.Context, opt ListOptions) ([]*Query, error)
+	// for distributed queries but not saved should not be returned). If teamID is not nil, only the queries of that
+	// team are returned.
+	ListQueries(ctx context.Context, opt ListOptions, teamID *uint) ([]*Query, error)
 	GetQuery(ctx context.Context, id uint) (*Query, error)
 	NewQuery(ctx context.Context, p QueryPayload) (*Query, error)
 	ModifyQuery(ctx context.Context, id uint, p QueryPayload) (*Query, error)
@@ -204,26 +211,72 @@ type Service interface {
 	// CampaignService defines the distributed query campaign related service methods
 
 	// NewDistributedQueryCampaignByNames creates a new distributed query campaign with the provided query (or the query
-	// referenced by ID) and host/label targets (specified by name).
+	// referenced by ID) and host/label targets (specified by name). If anonymize is true, the host identifiers are
+	// replaced by stable pseudonyms in the results of the campaign. If duplicateResults is not nil, it overrides the
+	// redis.duplicate_results configuration for the campaign. The results of the hosts are truncated to the provided
+	// limits.
 	NewDistributedQueryCampaignByNames(
-		ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string,
+		ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string, anonymize bool,
+		duplicateResults *bool, limits DistributedQueryResultLimits,
 	) (*DistributedQueryCampaign, error)
 
+	// RerunDistributedQueryCampaign creates a new distributed query campaign with the query and the host/label/team
+	// targets of the campaign with the provided ID, previously run by the same user.
+	RerunDistributedQueryCampaign(ctx context.Context, campaignID uint) (*DistributedQueryCampaign, error)
+
+	// DistributedQueryCampaignProgress returns the number of hosts targeted by the campaign with the provided ID,
+	// previously run by the same user, and how many of them responded, failed and are still pending.
+	DistributedQueryCampaignProgress(ctx context.Context, campaignID uint) (*DistributedQueryCampaignProgress, error)
+
+	// RemoveDistributedQueryCampaignTargets excludes the provided hosts and the members of the provided labels from
+	// the targets of the running campaign with the provided ID, previously run by the same user, so that the query is
+	// no longer sent to them. It returns the number of removed hosts that had not responded yet.
+	RemoveDistributedQueryCampaignTargets(ctx context.Context, campaignID uint, hostIDs, labelIDs []uint) (uint, error)
+
 	// NewDistributedQueryCampaign creates a new distributed query campaign with the provided query (or the query
-	// referenced by ID) and host/label targets
+	// referenced by ID) and host/label targets. If anonymize is true, the host identifiers are replaced by stable
+	// pseudonyms in the results of the campaign. If duplicateResults is not nil, it overrides the
+	// redis.duplicate_results configuration for the campaign. The results of the hosts are truncated to the provided
+	// limits.
 	NewDistributedQueryCampaign(
-		ctx context.Context, queryString string, queryID *uint, targets HostTargets,
+		ctx context.Context, queryString string, queryID *uint, targets HostTargets, anonymize bool,
+		duplicateResults *bool, limits DistributedQueryResultLimits,
 	) (*DistributedQueryCampaign, error)
 
-	// StreamCampaignResults streams updates with query results and expected host totals over the provided websocket.
+	// StreamCampaignResults streams updates with query results and expected host totals over the provided stream
+	// (a websocket or a Server-Sent Events response).
 	// Note that the type signature is somewhat inconsistent due to this being a streaming API and not the typical
 	// go-kit RPC style.
-	StreamCampaignResults(ctx context.Context, conn *websocket.Conn, campaignID uint)
+	StreamCampaignResults(ctx context.Context, conn CampaignResultsStream, campaignID uint)
 
 	GetCampaignReader(ctx context.Context, campaign *DistributedQueryCampaign) (<-chan interface{}, context.CancelFunc, error)
 	CompleteCampaign(ctx context.Context, campaign *DistributedQueryCampaign) error
 	RunLiveQueryDeadline(ctx context.Context, queryIDs []uint, hostIDs []uint, deadline time.Duration) ([]QueryCampaignResult, int)
 
+	// ListQueryUsage returns the live query usage (campaigns launched, hosts targeted and rows returned) per user and
+	// team.
+	ListQueryUsage(ctx context.Context, opt QueryUsageOptions) ([]QueryUsage, error)
+
+	// ListLiveQueryAuditLog returns the audit log of the live queries: who ran which query against which targets and
+	// when.
+	ListLiveQueryAuditLog(ctx context.Context, opt LiveQueryAuditLogOptions) ([]*LiveQueryAuditLog, error)
+
+	// NewBatchQuery creates a batch query with the provided query (or the query referenced by ID) and targets, like
+	// NewDistributedQueryCampaign. The hosts run the query as they check in during the provided duration, their
+	// results are stored and the batch query webhook is triggered once the percentage of the targeted hosts of the
+	// completion threshold responded. Zero values use the default completion threshold and duration.
+	NewBatchQuery(
+		ctx context.Context, queryString string, queryID *uint, targets HostTargets, anonymize bool,
+		limits DistributedQueryResultLimits, completionThreshold uint, duration time.Duration,
+	) (*BatchQuery, error)
+
+	// GetBatchQuery returns the batch query of the campaign with the provided ID, previously run by the same user.
+	GetBatchQuery(ctx context.Context, campaignID uint) (*BatchQuery, error)
+
+	// ListBatchQueryResults returns the results received for the batch query of the campaign with the provided ID,
+	// previously run by the same user.
+	ListBatchQueryResults(ctx context.Context, campaignID uint, opt ListOptions) ([]*BatchQueryResult, error)
+
 	///////////////////////////////////////////////////////////////////////////////
 	// AgentOptionsService
 
@@ -231,16 +284,51 @@ type Service interface {
 	// filtering based on team, platform, etc.
 	AgentOptionsForHost(ctx context.Context, hostTeamID *uint, hostPlatform string) (json.RawMessage, error)
 
+	// AgentDeploymentFile renders the file of the provided kind (flagfile, service definition, etc.) to deploy
+	// osquery on hosts of the provided team and platform.
+	AgentDeploymentFile(ctx context.Context, teamID *uint, platform, kind string) (*AgentDeploymentFile, error)
+
+	// ListAgentConfigVersions lists the recorded versions of the agent configuration.
+	ListAgentConfigVersions(ctx context.Context, opt ListOptions) ([]*AgentConfigVersion, error)
+	// GetAgentConfigVersion returns a version of the agent configuration, including the configuration itself.
+	GetAgentConfigVersion(ctx context.Context, id uint) (*AgentConfigVersion, error)
+	// RollbackAgentConfig restores the global agent options and packs of the provided version.
+	RollbackAgentConfig(ctx context.Context, id uint) error
+	// StartAgentOptionsRollout starts serving new global agent options to a canary of hosts only.
+	StartAgentOptionsRollout(ctx context.Context, payload AgentOptionsRolloutPayload) (*AgentOptionsRollout, error)
+	// ListAgentOptionsRollouts lists the agent options rollouts.
+	ListAgentOptionsRollouts(ctx context.Context, opt ListOptions) ([]*AgentOptionsRollout, error)
+	// GetAgentOptionsRollout returns the agent options rollout.
+	GetAgentOptionsRollout(ctx context.Context, id uint) (*AgentOptionsRollout, error)
+	// PromoteAgentOptionsRollout replaces the global agent options with the options of the active rollout.
+	PromoteAgentOptionsRollout(ctx context.Context, id uint) error
+	// AbortAgentOptionsRollout ends the active rollout, leaving the global agent options unchanged.
+	AbortAgentOptionsRollout(ctx context.Context, id uint) error
+	// EventsConfig returns the events config that applies to the hosts of the team (or of no team if teamID is nil),
+	// with a warning for each scheduled query using events-based tables while the events are disabled.
+	EventsConfig(ctx context.Context, teamID *uint) (*EventsConfigStatus, error)
+
 	///////////////////////////////////////////////////////////////////////////////
 	// HostService
 
 	// AuthenticateDevice loads host identified by the device's auth token.
 	// Returns an error if the auth token doesn't exist.
 	AuthenticateDevice(ctx context.Context, authToken string) (host *Host, debug bool, err error)
+	// EnrollOrbit enrolls the orbit agent of the host identified by the device's auth token, provided with a valid
+	// enroll secret, and returns its orbit node key.
+	EnrollOrbit(ctx context.Context, enrollSecret, deviceAuthToken string) (orbitNodeKey string, err error)
+	// AuthenticateOrbitHost loads host identified by the orbit node key.
+	// Returns an error if the orbit node key doesn't exist.
+	AuthenticateOrbitHost(ctx context.Context, nodeKey string) (host *Host, debug bool, err error)
 
 	ListHosts(ctx context.Context, opt HostListOptions) (hosts []*Host, err error)
+	// ListHostChanges returns up to limit hosts created or updated and up to limit hosts deleted after the cursor.
+	// If teamID is set, only the changes of the hosts of that team are returned.
+	ListHostChanges(ctx context.Context, teamID *uint, cursor HostChangesCursor, limit int) (*HostChanges, error)
 	GetHost(ctx context.Context, id uint) (host *HostDetail, err error)
-	GetHostSummary(ctx context.Context, teamID *uint, platform *string) (summary *HostSummary, err error)
+	GetHostSummary(ctx context.Context, teamID *uint, platform *string, labelID *uint) (summary *HostSummary, err error)
+	// EnrollmentStats returns the aggregated host enrollment attempts by outcome for the last days.
+	EnrollmentStats(ctx context.Context, days int) (*EnrollmentStats, error)
 	DeleteHost(ctx context.Context, id uint) (err error)
 	// HostByIdentifier returns one host matching the provided identifier. Possible matches can be on
 	// osquery_host_identifier, node_key, UUID, or hostname.
@@ -249,20 +337,92 @@ type Service interface {
 	RefetchHost(ctx context.Context, id uint) (err error)
 
 	FlushSeenHosts(ctx context.Context) error
+	// FlushEnrollmentStats records the enrollment attempts counted since the last flush in the datastore.
+	FlushEnrollmentStats(ctx context.Context) error
+	// FlushQueryUsage records the rows returned to the live queries since the last flush in the datastore.
+	FlushQueryUsage(ctx context.Context) error
+	// FlushLiveQueryResults records the executions, activities and batch query results of the live query results
+	// received since the last flush in the datastore.
+	FlushLiveQueryResults(ctx context.Context) error
 	// AddHostsToTeam adds hosts to an existing team, clearing their team settings if teamID is nil.
 	AddHostsToTeam(ctx context.Context, teamID *uint, hostIDs []uint) error
 	// AddHostsToTeamByFilter adds hosts to an existing team, clearing their team settings if teamID is nil. Hosts are
 	// selected by the label and HostListOptions provided.
 	AddHostsToTeamByFilter(ctx context.Context, teamID *uint, opt HostListOptions, lid *uint) error
-	DeleteHosts(ctx context.Context, ids []uint, opt HostListOptions, lid *uint) error
+	// DeleteHosts deletes the hosts with the ids, or the hosts matching the filters, and returns the number of
+	// deleted hosts.
+	DeleteHosts(ctx context.Context, ids []uint, opt HostListOptions, lid *uint) (int, error)
 	CountHosts(ctx context.Context, labelID *uint, opts HostListOptions) (int, error)
 	// ListHostDeviceMapping returns the list of device-mapping of user's email address
 	// for the host.
 	ListHostDeviceMapping(ctx context.Context, id uint) ([]*HostDeviceMapping, error)
+	// SetCustomHostDeviceMapping sets the custom device mapping of the host, or removes it if the email is empty,
+	// and returns the resulting device mappings of the host.
+	SetCustomHostDeviceMapping(ctx context.Context, id uint, email string) ([]*HostDeviceMapping, error)
+	// ApplyHostDeviceMappings sets the custom device mappings of the hosts in bulk. Nothing is applied if any of the
+	// hosts cannot be found.
+	ApplyHostDeviceMappings(ctx context.Context, mappings []HostDeviceMappingPayload) error
+	// ListHostUsers returns the user accounts on the host, as last reported by the host.
+	ListHostUsers(ctx context.Context, id uint) ([]HostUser, error)
+	// ListHostPolicies returns the policies that apply to the host, with the latest response of the host.
+	ListHostPolicies(ctx context.Context, id uint) ([]*HostPolicy, error)
+	// RemediateHostPolicy queues the remediation script of the policy to be run on the host.
+	RemediateHostPolicy(ctx context.Context, hostID, policyID uint) (*HostPolicyRemediation, error)
+	// ListHostPolicyRemediations returns the remediations of the policies run (or to be run) on the host.
+	ListHostPolicyRemediations(ctx context.Context, hostID uint, opts ListOptions) ([]*HostPolicyRemediation, error)
+	// PendingHostPolicyRemediations returns the remediations to be run on the host, for the device itself or its
+	// orbit agent.
+	PendingHostPolicyRemediations(ctx context.Context, hostID uint) ([]*HostPolicyRemediation, error)
+	// SetHostPolicyRemediationResult records the result of a remediation run on the host, for its orbit agent only.
+	SetHostPolicyRemediationResult(ctx context.Context, hostID, id uint, exitCode int, output string) error
+
+	// HostAgentOptionsOverride returns the unexpired agent options override of the host.
+	HostAgentOptionsOverride(ctx context.Context, hostID uint) (*HostAgentOptionsOverride, error)
+	// SetHostAgentOptionsOverride creates or replaces the agent options override of the host, which expires after
+	// expiresIn (or DefaultHostAgentOptionsOverrideExpiry if zero).
+	SetHostAgentOptionsOverride(ctx context.Context, hostID uint, options json.RawMessage, expiresIn time.Duration) (*HostAgentOptionsOverride, error)
+	DeleteHostAgentOptionsOverride(ctx context.Context, hostID uint) error
+	// HostEffectiveConfig returns the osquery client config (options, packs, decorators, etc.) served to the host.
+	HostEffectiveConfig(ctx context.Context, id uint) (map[string]interface{}, error)
+	// SubscribeHostOnline subscribes the current user to be notified the next time the host checks in.
+	SubscribeHostOnline(ctx context.Context, hostID uint) (*HostOnlineSubscription, error)
+	UnsubscribeHostOnline(ctx context.Context, hostID uint) error
+	// ListHostOnlineSubscriptions returns the pending host online subscriptions of the current user.
+	ListHostOnlineSubscriptions(ctx context.Context) ([]*HostOnlineSubscription, error)
 
 	MacadminsData(ctx context.Context, id uint) (*MacadminsData, error)
 	AggregatedMacadminsData(ctx context.Context, teamID *uint) (*AggregatedMacadminsData, error)
 
+	// HostNetworkSettings returns the DNS servers and proxies configured on the host.
+	HostNetworkSettings(ctx context.Context, id uint) (*HostNetworkSettings, error)
+	// NetworkSettingsReport returns the number of hosts configured with each DNS server and proxy, optionally
+	// restricted to the hosts of a team.
+	NetworkSettingsReport(ctx context.Context, teamID *uint) (*NetworkSettingsReport, error)
+	// ListHostStartupItems returns the startup items of the host.
+	ListHostStartupItems(ctx context.Context, id uint) ([]HostStartupItem, error)
+	// ListStartupItemChanges returns the changes of the startup items of the hosts, optionally filtered to a
+	// team.
+	ListStartupItemChanges(ctx context.Context, teamID *uint, opt StartupItemChangesListOptions) ([]StartupItemChange, error)
+	// ListHostListeningPorts returns the listening ports of the host.
+	ListHostListeningPorts(ctx context.Context, id uint) ([]HostListeningPort, error)
+	// ListHostDisks returns the disks of the host.
+	ListHostDisks(ctx context.Context, id uint) ([]HostDisk, error)
+	// ListHostCertificates returns the certificates installed on the host.
+	ListHostCertificates(ctx context.Context, id uint) ([]HostCertificate, error)
+	// ModifyHostMetadata modifies the notes and tags of the host, the provided tags replace all the existing ones.
+	ModifyHostMetadata(ctx context.Context, id uint, payload HostMetadataPayload) (*HostMetadata, error)
+	// ListHostActivities returns the activities of the host, e.g. its enrollments, team transfers, live queries
+	// and policy flips.
+	ListHostActivities(ctx context.Context, id uint, opt ListOptions) ([]*HostActivity, error)
+	// ListDuplicateHosts returns the groups of hosts that have the same hardware serial number, or the same UUID
+	// if they have no serial number, typically because they were re-imaged.
+	ListDuplicateHosts(ctx context.Context) ([]*DuplicateHosts, error)
+	// MergeHosts merges the duplicate hosts into the host, see Datastore.MergeHosts.
+	MergeHosts(ctx context.Context, id uint, duplicateIDs []uint) error
+	// ListeningPortsReport returns the number of hosts where each process listens on each port, optionally
+	// restricted to the hosts of a team.
+	ListeningPortsReport(ctx context.Context, teamID *uint, opt ListeningPortsReportOptions) ([]ListeningPortHostsCount, error)
+
 	OSVersions(ctx context.Context, teamID *uint, platform *string) (*OSVersions, error)
 
 	///////////////////////////////////////////////////////////////////////////////
@@ -430,27 +590,75 @@ type Service interface {
 	// GlobalPolicyService
 
 	NewGlobalPolicy(ctx context.Context, p PolicyPayload) (*Policy, error)
-	ListGlobalPolicies(ctx context.Context) ([]*Policy, error)
+	ListGlobalPolicies(ctx context.Context, opts ListOptions) ([]*Policy, error)
 	DeleteGlobalPolicies(ctx context.Context, ids []uint) ([]uint, error)
 	ModifyGlobalPolicy(ctx context.Context, id uint, p ModifyPolicyPayload) (*Policy, error)
 	GetPolicyByIDQueries(ctx context.Context, policyID uint) (*Policy, error)
 	ApplyPolicySpecs(ctx context.Context, policies []*PolicySpec) error
 
+	///////////////////////////////////////////////////////////////////////////////
+	// PolicyBundleService
+
+	ListPolicyBundles(ctx context.Context) ([]*PolicyBundle, error)
+	GetPolicyBundle(ctx context.Context, id string) (*PolicyBundle, error)
+	ImportPolicyBundle(ctx context.Context, id string, teamID *uint) error
+
 	///////////////////////////////////////////////////////////////////////////////
 	// Software
 
 	ListSoftware(ctx context.Context, opt SoftwareListOptions) ([]Software, error)
 	SoftwareByID(ctx context.Context, id uint) (*Software, error)
 	CountSoftware(ctx context.Context, opt SoftwareListOptions) (int, error)
+	// ListHostSoftware lists the software installed on the host.
+	ListHostSoftware(ctx context.Context, hostID uint, opt SoftwareListOptions) ([]Software, error)
 
 	///////////////////////////////////////////////////////////////////////////////
 	// Team Policies
 
 	NewTeamPolicy(ctx context.Context, teamID uint, p PolicyPayload) (*Policy, error)
-	ListTeamPolicies(ctx context.Context, teamID uint) ([]*Policy, error)
+	ListTeamPolicies(ctx context.Context, teamID uint, opts ListOptions) ([]*Policy, error)
 	DeleteTeamPolicies(ctx context.Context, teamID uint, ids []uint) ([]uint, error)
 	ModifyTeamPolicy(ctx context.Context, teamID uint, id uint, p ModifyPolicyPayload) (*Policy, error)
 	GetTeamPolicyByIDQueries(ctx context.Context, teamID uint, policyID uint) (*Policy, error)
+	// TeamPolicyExemptions returns the IDs of the global policies the team is exempted from.
+	TeamPolicyExemptions(ctx context.Context, teamID uint) ([]uint, error)
+	// SetTeamPolicyExemptions replaces the global policies the team is exempted from.
+	SetTeamPolicyExemptions(ctx context.Context, teamID uint, policyIDs []uint) ([]uint, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// ATC Tables
+
+	NewATCTable(ctx context.Context, p ATCTablePayload) (*ATCTable, error)
+	ListATCTables(ctx context.Context) ([]*ATCTable, error)
+	ModifyATCTable(ctx context.Context, id uint, p ATCTablePayload) (*ATCTable, error)
+	DeleteATCTable(ctx context.Context, id uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// FIM Categories
+
+	NewFIMCategory(ctx context.Context, teamID *uint, p FIMCategoryPayload) (*FIMCategory, error)
+	ListFIMCategories(ctx context.Context, teamID *uint) ([]*FIMCategory, error)
+	ModifyFIMCategory(ctx context.Context, id uint, p FIMCategoryPayload) (*FIMCategory, error)
+	DeleteFIMCategory(ctx context.Context, id uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// YARA Signature Groups
+
+	NewYARASignatureGroup(ctx context.Context, teamID *uint, p YARASignatureGroupPayload) (*YARASignatureGroup, error)
+	ListYARASignatureGroups(ctx context.Context, teamID *uint) ([]*YARASignatureGroup, error)
+	ModifyYARASignatureGroup(ctx context.Context, id uint, p YARASignatureGroupPayload) (*YARASignatureGroup, error)
+	DeleteYARASignatureGroup(ctx context.Context, id uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// Dashboards
+
+	NewDashboard(ctx context.Context, p DashboardPayload) (*Dashboard, error)
+	GetDashboard(ctx context.Context, id uint) (*Dashboard, error)
+	// ListDashboards lists the dashboards owned by the user, along with the dashboards shared with them. If teamID is
+	// set, only the dashboards of that team are returned.
+	ListDashboards(ctx context.Context, teamID *uint, opt ListOptions) ([]*Dashboard, error)
+	ModifyDashboard(ctx context.Context, id uint, p DashboardPayload) (*Dashboard, error)
+	DeleteDashboard(ctx context.Context, id uint) error
 
 	/// Geolocation
 	LookupGeoIP(ctx context.Context, ip string) *GeoLocation
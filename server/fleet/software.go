@@ -66,8 +66,9 @@ type SoftwareIterator interface {
 type SoftwareListOptions struct {
 	ListOptions
 
-	TeamID         *uint `query:"team_id,optional"`
-	VulnerableOnly bool  `query:"vulnerable,optional"`
+	TeamID         *uint  `query:"team_id,optional"`
+	VulnerableOnly bool   `query:"vulnerable,optional"`
+	Source         string `query:"source,optional"`
 
 	SkipLoadingCVEs bool
 
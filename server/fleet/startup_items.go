@@ -0,0 +1,52 @@
+package fleet
+
+import "time"
+
+// StartupItemChangesRetention is how long the changes of the startup items of
+// the hosts are kept.
+const StartupItemChangesRetention = 30 * 24 * time.Hour
+
+// The types of changes of the startup items of a host.
+const (
+	StartupItemChangeAdded    = "added"
+	StartupItemChangeRemoved  = "removed"
+	StartupItemChangeModified = "modified"
+)
+
+// HostStartupItem is an item started automatically on a host: a launchd job
+// or login item on macOS, a service or Run registry key on Windows, a systemd
+// unit on Linux. An item is identified by its source and name.
+type HostStartupItem struct {
+	// Source is where the item is configured (e.g. "launchd", "services" or
+	// the path of a Run registry key).
+	Source string `json:"source" db:"source"`
+	Name   string `json:"name" db:"name"`
+	Path   string `json:"path" db:"path"`
+	// SHA256 is the hash of the executable at Path, empty if it could not be
+	// hashed.
+	SHA256 string `json:"sha256,omitempty" db:"sha256"`
+}
+
+// StartupItemChange is a change of the startup items of a host between two
+// collections.
+type StartupItemChange struct {
+	ID       uint   `json:"id" db:"id"`
+	HostID   uint   `json:"host_id" db:"host_id"`
+	Hostname string `json:"hostname" db:"hostname"`
+	// ChangeType is one of StartupItemChangeAdded, StartupItemChangeRemoved
+	// or StartupItemChangeModified.
+	ChangeType string `json:"change_type" db:"change_type"`
+	HostStartupItem
+	// PreviousPath is the path of the item before a modification.
+	PreviousPath string    `json:"previous_path,omitempty" db:"previous_path"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+type StartupItemChangesListOptions struct {
+	ListOptions
+
+	// HostID filters the changes to the host.
+	HostID *uint
+	// ChangeType filters the changes to the type of change.
+	ChangeType string
+}
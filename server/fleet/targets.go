@@ -15,15 +15,18 @@ type TargetSearchResults struct {
 // hosts.
 type TargetMetrics struct {
 	// TotalHosts is the total hosts in any status. It should equal
-	// OnlineHosts + OfflineHosts + MissingInActionHosts.
+	// OnlineHosts + OfflineHosts + MissingHosts + MissingInActionHosts.
 	TotalHosts uint `db:"total"`
 	// OnlineHosts is the count of hosts that have checked in within their
 	// expected checkin interval (based on the configuration interval
 	// values, see Host.Status()).
 	OnlineHosts uint `db:"online"`
 	// OfflineHosts is the count of hosts that have not checked in within
-	// their expected interval.
+	// their expected interval, and are not missing.
 	OfflineHosts uint `db:"offline"`
+	// MissingHosts is the count of hosts that have not checked in within the
+	// missing threshold of the host settings, and are not MIA.
+	MissingHosts uint `db:"missing"`
 	// MissingInActionHosts is the count of hosts that have not checked in
 	// within the last 30 days.
 	MissingInActionHosts uint `db:"mia"`
@@ -34,7 +37,7 @@ type TargetMetrics struct {
 
 // HostTargets is the set of targets for a campaign (live query). These
 // targets are additive (include all hosts and all hosts in labels and all hosts
-// in teams).
+// in teams and all hosts matching the label expression).
 type HostTargets struct {
 	// HostIDs is the IDs of hosts to be targeted
 	HostIDs []uint `json:"hosts"`
@@ -42,6 +45,16 @@ type HostTargets struct {
 	LabelIDs []uint `json:"labels"`
 	// TeamIDs is the IDs of teams to be targeted
 	TeamIDs []uint `json:"teams"`
+	// LabelExpression is a label expression (see LabelExpression) matching
+	// the hosts to be targeted
+	LabelExpression string `json:"label_expression,omitempty"`
+	// OnlineOnly restricts the targeted hosts to those currently online
+	OnlineOnly bool `json:"online_only,omitempty"`
+	// ExcludedHostIDs is the IDs of hosts excluded from the targets
+	ExcludedHostIDs []uint `json:"excluded_hosts,omitempty"`
+	// ExcludedLabelIDs is the IDs of labels whose hosts are excluded from the
+	// targets
+	ExcludedLabelIDs []uint `json:"excluded_labels,omitempty"`
 }
 
 type TargetType int
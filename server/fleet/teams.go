@@ -18,6 +18,10 @@ type TeamPayload struct {
 	Description     *string              `json:"description"`
 	Secrets         []*EnrollSecret      `json:"secrets"`
 	WebhookSettings *TeamWebhookSettings `json:"webhook_settings"`
+	// ScheduleSettings overrides the global schedule settings for the team.
+	ScheduleSettings *ScheduleSettings `json:"schedule_settings"`
+	// LogSettings overrides the global log settings for the team.
+	LogSettings *TeamLogSettings `json:"log_settings"`
 	// Note AgentOptions must be set by a separate endpoint.
 }
 
@@ -120,6 +124,20 @@ type TeamConfig struct {
 	// AgentOptions is the options for osquery and Orbit.
 	AgentOptions    *json.RawMessage    `json:"agent_options,omitempty"`
 	WebhookSettings TeamWebhookSettings `json:"webhook_settings"`
+	// ScheduleSettings overrides the global schedule settings for the
+	// scheduled queries of the team. If nil, the global settings apply.
+	ScheduleSettings *ScheduleSettings `json:"schedule_settings,omitempty"`
+	// LogSettings overrides the global log settings for the logs of the
+	// hosts of the team. If nil, the global settings apply.
+	LogSettings *TeamLogSettings `json:"log_settings,omitempty"`
+}
+
+// TeamLogSettings are the log settings of a team.
+type TeamLogSettings struct {
+	// ResultLogPlugin is the log plugin of the result logs of the hosts of
+	// the team, it overrides the result log plugin and routes of the Fleet
+	// configuration. If empty, the Fleet configuration applies.
+	ResultLogPlugin string `json:"result_log_plugin"`
 }
 
 type TeamWebhookSettings struct {
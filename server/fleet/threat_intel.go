@@ -0,0 +1,35 @@
+package fleet
+
+import "time"
+
+const (
+	// IndicatorTypeListeningPort is the type of the indicators identifying a
+	// port a host listens on, formatted as "<protocol>/<port>" (e.g. tcp/4444).
+	IndicatorTypeListeningPort = "listening_port"
+	// IndicatorTypeAutorunSHA256 is the type of the indicators identifying the
+	// SHA-256 hash of an executable started automatically on a host.
+	IndicatorTypeAutorunSHA256 = "autorun_sha256"
+)
+
+// Indicator is an observable collected from hosts (from their listening ports
+// and startup items) that can be looked up with a threat intel provider.
+type Indicator struct {
+	Type  string `json:"type" db:"type"`
+	Value string `json:"value" db:"value"`
+}
+
+// ThreatIntelVerdict is the cached result of the lookup of an indicator.
+type ThreatIntelVerdict struct {
+	Indicator
+	Malicious   bool      `json:"malicious" db:"malicious"`
+	Description string    `json:"description" db:"description"`
+	CheckedAt   time.Time `json:"checked_at" db:"checked_at"`
+}
+
+// HostThreatFinding is an indicator collected from a host that the threat
+// intel provider reported as malicious.
+type HostThreatFinding struct {
+	Indicator
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,106 @@
+package fleet
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// YARASignatureGroup is a named group of YARA signature files sent to the
+// hosts in the yara section of the config. The files monitored by the FIM
+// categories listed in FilePaths are scanned with the signatures of the group
+// and the matches are reported in the yara_events table. See
+// https://osquery.readthedocs.io/en/stable/deployment/yara/
+type YARASignatureGroup struct {
+	UpdateCreateTimestamps
+	ID uint `json:"id" db:"id"`
+	// TeamID is the ID of the team the group belongs to. If TeamID is nil, the
+	// group is global and is sent to all hosts.
+	TeamID *uint `json:"team_id" db:"team_id"`
+	// Name is the name of the group, used as key in the osquery config.
+	Name string `json:"name" db:"name"`
+	// Signatures are the paths of the YARA signature files on the hosts.
+	Signatures YARAStrings `json:"signatures" db:"signatures"`
+	// FilePaths are the names of the FIM categories (the keys of the
+	// file_paths section of the config) scanned with the signatures of the
+	// group.
+	FilePaths YARAStrings `json:"file_paths" db:"file_paths"`
+}
+
+// AuthzType implements authz.AuthzTyper.
+func (g YARASignatureGroup) AuthzType() string {
+	return "yara_signature_group"
+}
+
+// YARAStrings is a list of strings, stored as JSON.
+type YARAStrings []string
+
+// Scan implements the sql.Scanner interface
+func (s *YARAStrings) Scan(val interface{}) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case nil: // sql NULL
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements the sql.Valuer interface
+func (s YARAStrings) Value() (driver.Value, error) {
+	if s == nil {
+		s = YARAStrings{}
+	}
+	return json.Marshal(s)
+}
+
+// YARASignatureGroupPayload holds the data to create or modify a YARA
+// signature group. Nil fields are left unchanged when modifying a group.
+type YARASignatureGroupPayload struct {
+	Name       *string      `json:"name"`
+	Signatures *YARAStrings `json:"signatures"`
+	FilePaths  *YARAStrings `json:"file_paths"`
+}
+
+var (
+	errYARAGroupEmptyName          = errors.New("signature group name cannot be empty")
+	errYARAGroupInvalidName        = errors.New("signature group name cannot contain leading or trailing spaces")
+	errYARAGroupEmptySignatures    = errors.New("signature group must have at least one signature")
+	errYARAGroupInvalidSignature   = errors.New("signature group signatures must be absolute paths")
+	errYARAGroupInvalidFilePathKey = errors.New("signature group file paths cannot be empty")
+)
+
+// Verify verifies the fields set in the payload are valid.
+func (p YARASignatureGroupPayload) Verify() error {
+	if p.Name != nil {
+		if *p.Name == "" {
+			return errYARAGroupEmptyName
+		}
+		if strings.TrimSpace(*p.Name) != *p.Name {
+			return errYARAGroupInvalidName
+		}
+	}
+	if p.Signatures != nil {
+		if len(*p.Signatures) == 0 {
+			return errYARAGroupEmptySignatures
+		}
+		for _, sig := range *p.Signatures {
+			if !isFIMAbsolutePath(sig) {
+				return errYARAGroupInvalidSignature
+			}
+		}
+	}
+	if p.FilePaths != nil {
+		for _, name := range *p.FilePaths {
+			if strings.TrimSpace(name) == "" {
+				return errYARAGroupInvalidFilePathKey
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package ingestsidecar implements an osquery_utils.Extension backed by an
+// external HTTP service, the sidecar, so that operators can collect custom
+// host data with additional detail queries without building a custom Fleet
+// binary.
+package ingestsidecar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service/osquery_utils"
+	kitlog "github.com/go-kit/kit/log"
+)
+
+// Sidecar is the extension backed by the sidecar service. It makes the
+// following requests to the sidecar URL:
+//
+//   - GET /detail_queries returns the detail queries to run, with a JSON body
+//     of the form {"queries": {"name": {"query": "...", "discovery": "...",
+//     "platforms": ["darwin"]}}}. The detail queries are refreshed periodically
+//     with Refresh.
+//   - POST /ingest receives the results of a detail query, with a JSON body of
+//     the form {"host": {...}, "query": "name", "rows": [...], "failed": false}.
+//   - POST /host_saved is notified when the details of a host were saved, with
+//     a JSON body of the form {"host": {...}}.
+//
+// If the API key is not empty, it is sent as bearer token.
+type Sidecar struct {
+	url    string
+	apiKey string
+	client *http.Client
+
+	mu      sync.RWMutex
+	queries map[string]osquery_utils.DetailQuery
+}
+
+var _ osquery_utils.Extension = (*Sidecar)(nil)
+
+// New returns the extension backed by the sidecar configured in cfg. It has no
+// detail queries until Refresh is called.
+func New(cfg config.IngestSidecarConfig) *Sidecar {
+	return &Sidecar{
+		url:     strings.TrimSuffix(cfg.URL, "/"),
+		apiKey:  cfg.APIKey,
+		client:  fleethttp.NewClient(fleethttp.WithTimeout(cfg.Timeout)),
+		queries: make(map[string]osquery_utils.DetailQuery),
+	}
+}
+
+type sidecarQuery struct {
+	Query     string   `json:"query"`
+	Discovery string   `json:"discovery"`
+	Platforms []string `json:"platforms"`
+}
+
+// Refresh fetches the detail queries of the sidecar. The detail queries
+// previously fetched are kept if it fails.
+func (s *Sidecar) Refresh(ctx context.Context) error {
+	var result struct {
+		Queries map[string]sidecarQuery `json:"queries"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/detail_queries", nil, &result); err != nil {
+		return err
+	}
+
+	queries := make(map[string]osquery_utils.DetailQuery, len(result.Queries))
+	for name, q := range result.Queries {
+		if q.Query == "" {
+			return fmt.Errorf("detail query %s of the sidecar has no query", name)
+		}
+		name := name
+		queries[name] = osquery_utils.DetailQuery{
+			Query:     q.Query,
+			Discovery: q.Discovery,
+			Platforms: q.Platforms,
+			DirectIngestFunc: func(ctx context.Context, logger kitlog.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+				return s.ingest(ctx, host, name, rows, failed)
+			},
+		}
+	}
+
+	s.mu.Lock()
+	s.queries = queries
+	s.mu.Unlock()
+	return nil
+}
+
+// DetailQueries implements osquery_utils.Extension.
+func (s *Sidecar) DetailQueries() map[string]osquery_utils.DetailQuery {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	queries := make(map[string]osquery_utils.DetailQuery, len(s.queries))
+	for name, q := range s.queries {
+		queries[name] = q
+	}
+	return queries
+}
+
+// HostSaved implements osquery_utils.Extension.
+func (s *Sidecar) HostSaved(ctx context.Context, logger kitlog.Logger, host *fleet.Host, ds fleet.Datastore) error {
+	body := struct {
+		Host *fleet.Host `json:"host"`
+	}{Host: host}
+	return s.do(ctx, http.MethodPost, "/host_saved", body, nil)
+}
+
+func (s *Sidecar) ingest(ctx context.Context, host *fleet.Host, name string, rows []map[string]string, failed bool) error {
+	if rows == nil {
+		rows = []map[string]string{}
+	}
+	body := struct {
+		Host   *fleet.Host         `json:"host"`
+		Query  string              `json:"query"`
+		Rows   []map[string]string `json:"rows"`
+		Failed bool                `json:"failed"`
+	}{Host: host, Query: name, Rows: rows, Failed: failed}
+	return s.do(ctx, http.MethodPost, "/ingest", body, nil)
+}
+
+// do makes a request to the sidecar, with body as JSON body if not nil, and
+// decodes the JSON response in result if not nil.
+func (s *Sidecar) do(ctx context.Context, method, path string, body, result interface{}) error {
+	url := s.url + path
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to %s %s: %w", method, url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("error %s %s: %d. %s", method, url, resp.StatusCode, string(b))
+	}
+
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("decoding response of %s: %w", url, err)
+		}
+	}
+	return nil
+}
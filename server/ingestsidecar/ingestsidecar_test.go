@@ -0,0 +1,94 @@
+package ingestsidecar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSidecar(t *testing.T) {
+	var queriesResponse string
+	var ingested []string
+	var saved []uint
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/detail_queries":
+			assert.Equal(t, http.MethodGet, r.Method)
+			if queriesResponse == "" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(queriesResponse))
+		case "/ingest":
+			var body struct {
+				Host   fleet.Host          `json:"host"`
+				Query  string              `json:"query"`
+				Rows   []map[string]string `json:"rows"`
+				Failed bool                `json:"failed"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, uint(1), body.Host.ID)
+			assert.False(t, body.Failed)
+			require.Len(t, body.Rows, 1)
+			ingested = append(ingested, body.Query+":"+body.Rows[0]["version"])
+		case "/host_saved":
+			var body struct {
+				Host fleet.Host `json:"host"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			saved = append(saved, body.Host.ID)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	sidecar := New(config.IngestSidecarConfig{URL: ts.URL + "/", APIKey: "secret", Timeout: 5 * time.Second})
+	ctx := context.Background()
+	assert.Empty(t, sidecar.DetailQueries())
+
+	// the detail queries are only available once fetched
+	require.Error(t, sidecar.Refresh(ctx))
+	assert.Empty(t, sidecar.DetailQueries())
+
+	queriesResponse = `{"queries": {"falcon": {"query": "select version from falcon_info", "discovery": "select 1 from osquery_registry where name = 'falcon_info'", "platforms": ["darwin", "windows"]}}}`
+	require.NoError(t, sidecar.Refresh(ctx))
+	queries := sidecar.DetailQueries()
+	require.Len(t, queries, 1)
+	require.Contains(t, queries, "falcon")
+	assert.Equal(t, "select version from falcon_info", queries["falcon"].Query)
+	assert.Equal(t, "select 1 from osquery_registry where name = 'falcon_info'", queries["falcon"].Discovery)
+	assert.Equal(t, []string{"darwin", "windows"}, queries["falcon"].Platforms)
+
+	host := &fleet.Host{ID: 1}
+	err := queries["falcon"].DirectIngestFunc(ctx, kitlog.NewNopLogger(), host, nil, []map[string]string{{"version": "6.35"}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"falcon:6.35"}, ingested)
+
+	require.NoError(t, sidecar.HostSaved(ctx, kitlog.NewNopLogger(), host, nil))
+	assert.Equal(t, []uint{1}, saved)
+
+	// the detail queries are kept when the refresh fails
+	queriesResponse = ""
+	require.Error(t, sidecar.Refresh(ctx))
+	assert.Len(t, sidecar.DetailQueries(), 1)
+
+	// a detail query without query is rejected
+	queriesResponse = `{"queries": {"falcon": {"platforms": ["darwin"]}}}`
+	require.Error(t, sidecar.Refresh(ctx))
+	assert.Len(t, sidecar.DetailQueries(), 1)
+
+	queriesResponse = `{"queries": {}}`
+	require.NoError(t, sidecar.Refresh(ctx))
+	assert.Empty(t, sidecar.DetailQueries())
+}
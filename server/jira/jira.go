@@ -0,0 +1,148 @@
+// Package jira is a client of the Jira REST API, used to create and update
+// the issues of the Jira integrations.
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// defaultIssueType is the type of the issues created when the integration does
+// not specify one.
+const defaultIssueType = "Task"
+
+// Client creates and updates the issues of a Jira project with the version 2
+// of the Jira REST API, authenticated with the username and password (or API
+// token) of the integration.
+type Client struct {
+	baseURL    string
+	username   string
+	password   string
+	projectKey string
+	issueType  string
+	client     *http.Client
+}
+
+// NewClient returns a client for the project of the Jira integration.
+func NewClient(integration *fleet.JiraIntegration) (*Client, error) {
+	u, err := url.Parse(integration.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse jira url %s: %w", integration.URL, err)
+	}
+	issueType := integration.IssueType
+	if issueType == "" {
+		issueType = defaultIssueType
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(u.String(), "/"),
+		username:   integration.Username,
+		password:   integration.Password,
+		projectKey: integration.ProjectKey,
+		issueType:  issueType,
+		client:     fleethttp.NewClient(fleethttp.WithTimeout(30 * time.Second)),
+	}, nil
+}
+
+// CreateIssue creates an issue in the project of the integration and returns
+// its key.
+func (c *Client) CreateIssue(ctx context.Context, summary, description string) (string, error) {
+	payload := map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]string{"key": c.projectKey},
+			"issuetype":   map[string]string{"name": c.issueType},
+			"summary":     summary,
+			"description": description,
+		},
+	}
+	var result struct {
+		Key string `json:"key"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/rest/api/2/issue", payload, &result); err != nil {
+		return "", err
+	}
+	return result.Key, nil
+}
+
+// AddComment adds a comment to the issue.
+func (c *Client) AddComment(ctx context.Context, issueKey, body string) error {
+	payload := map[string]string{"body": body}
+	return c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(issueKey)+"/comment", payload, nil)
+}
+
+// CloseIssue transitions the issue to a status of the "done" category, with
+// the first such transition available for the issue in its workflow.
+func (c *Client) CloseIssue(ctx context.Context, issueKey string) error {
+	path := "/rest/api/2/issue/" + url.PathEscape(issueKey) + "/transitions"
+
+	var result struct {
+		Transitions []struct {
+			ID string `json:"id"`
+			To struct {
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return err
+	}
+	for _, transition := range result.Transitions {
+		if transition.To.StatusCategory.Key == "done" {
+			payload := map[string]interface{}{
+				"transition": map[string]string{"id": transition.ID},
+			}
+			return c.do(ctx, http.MethodPost, path, payload, nil)
+		}
+	}
+	return fmt.Errorf("no transition to a done status for jira issue %s", issueKey)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	u := c.baseURL + path
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.password)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to %s %s: %w", method, u, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("error requesting %s %s: %d. %s", method, u, resp.StatusCode, string(b))
+	}
+	if result != nil {
+		if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+			return fmt.Errorf("decoding response of %s %s: %w", method, u, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	var requests []string
+	var bodies []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "fleet", user)
+		assert.Equal(t, "token", pass)
+
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.Method == http.MethodPost {
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			bodies = append(bodies, body)
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /rest/api/2/issue":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": "10000", "key": "FLEET-1"}`))
+		case "POST /rest/api/2/issue/FLEET-1/comment":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{}`))
+		case "GET /rest/api/2/issue/FLEET-1/transitions":
+			w.Write([]byte(`{"transitions": [
+				{"id": "11", "to": {"statusCategory": {"key": "indeterminate"}}},
+				{"id": "31", "to": {"statusCategory": {"key": "done"}}}
+			]}`))
+		case "GET /rest/api/2/issue/FLEET-2/transitions":
+			w.Write([]byte(`{"transitions": []}`))
+		case "POST /rest/api/2/issue/FLEET-1/transitions":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errorMessages": ["Issue does not exist"]}`))
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(&fleet.JiraIntegration{
+		URL:        ts.URL + "/",
+		Username:   "fleet",
+		Password:   "token",
+		ProjectKey: "FLEET",
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	key, err := client.CreateIssue(ctx, "summary", "description")
+	require.NoError(t, err)
+	assert.Equal(t, "FLEET-1", key)
+	assert.Equal(t, map[string]interface{}{
+		"fields": map[string]interface{}{
+			"project":     map[string]interface{}{"key": "FLEET"},
+			"issuetype":   map[string]interface{}{"name": "Task"},
+			"summary":     "summary",
+			"description": "description",
+		},
+	}, bodies[0])
+
+	require.NoError(t, client.AddComment(ctx, "FLEET-1", "comment"))
+	assert.Equal(t, map[string]interface{}{"body": "comment"}, bodies[1])
+
+	require.NoError(t, client.CloseIssue(ctx, "FLEET-1"))
+	assert.Equal(t, map[string]interface{}{"transition": map[string]interface{}{"id": "31"}}, bodies[2])
+
+	// the issue has no transition to a done status
+	require.Error(t, client.CloseIssue(ctx, "FLEET-2"))
+
+	require.Error(t, client.AddComment(ctx, "FLEET-3", "comment"))
+
+	assert.Equal(t, []string{
+		"POST /rest/api/2/issue",
+		"POST /rest/api/2/issue/FLEET-1/comment",
+		"GET /rest/api/2/issue/FLEET-1/transitions",
+		"POST /rest/api/2/issue/FLEET-1/transitions",
+		"GET /rest/api/2/issue/FLEET-2/transitions",
+		"POST /rest/api/2/issue/FLEET-3/comment",
+	}, requests)
+}
@@ -14,6 +14,7 @@ var testFunctions = [...]func(*testing.T, fleet.LiveQueryStore){
 	testLiveQuery,
 	testLiveQueryNoTargets,
 	testLiveQueryStopQuery,
+	testLiveQueryRemoveHosts,
 	testLiveQueryExpiredQuery,
 	testLiveQueryOnlyExpired,
 }
@@ -91,6 +92,26 @@ func testLiveQueryStopQuery(t *testing.T, store fleet.LiveQueryStore) {
 	assert.Len(t, queries, 1)
 }
 
+func testLiveQueryRemoveHosts(t *testing.T, store fleet.LiveQueryStore) {
+	require.NoError(t, store.RunQuery("test", "select 1", []uint{1, 2, 3}))
+	require.NoError(t, store.RunQuery("test2", "select 2", []uint{1, 3}))
+
+	// host 100 is past the end of the bitfield
+	require.NoError(t, store.RemoveHostsFromQuery("test", []uint{1, 3, 100}))
+	require.NoError(t, store.RemoveHostsFromQuery("test", nil))
+	require.NoError(t, store.RemoveHostsFromQuery("unknown", []uint{1}))
+
+	queries, err := store.QueriesForHost(1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"test2": "select 2"}, queries)
+	queries, err = store.QueriesForHost(2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"test": "select 1"}, queries)
+	queries, err = store.QueriesForHost(100)
+	require.NoError(t, err)
+	assert.Len(t, queries, 0)
+}
+
 func testLiveQueryExpiredQuery(t *testing.T, store fleet.LiveQueryStore) {
 	oldModulo := cleanupExpiredQueriesModulo
 	cleanupExpiredQueriesModulo = 1 // run the cleanup each time
@@ -30,3 +30,8 @@ func (m *MockLiveQuery) QueryCompletedByHost(name string, hostID uint) error {
 	args := m.Called(name, hostID)
 	return args.Error(0)
 }
+
+func (m *MockLiveQuery) RemoveHostsFromQuery(name string, hostIDs []uint) error {
+	args := m.Called(name, hostIDs)
+	return args.Error(0)
+}
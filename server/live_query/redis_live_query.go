@@ -257,6 +257,38 @@ func (r *redisLiveQuery) QueryCompletedByHost(name string, hostID uint) error {
 	return nil
 }
 
+func (r *redisLiveQuery) RemoveHostsFromQuery(name string, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	targetKey, _ := generateKeys(name)
+
+	// Setting a bit past the end of the bitfield would grow it, the hosts past
+	// the end are not targeted anyway.
+	size, err := redigo.Int(conn.Do("STRLEN", targetKey))
+	if err != nil {
+		return fmt.Errorf("strlen query key: %w", err)
+	}
+
+	// Update the bitfield for those hosts, pipelined in a single roundtrip.
+	for _, hostID := range hostIDs {
+		if hostID >= uint(size)*bitsInByte {
+			continue
+		}
+		if err := conn.Send("SETBIT", targetKey, hostID, 0); err != nil {
+			return fmt.Errorf("setbit query key: %w", err)
+		}
+	}
+	if _, err := conn.Do(""); err != nil {
+		return fmt.Errorf("setbit query key: %w", err)
+	}
+	return nil
+}
+
 func (r *redisLiveQuery) storeQueryInfo(name, sql string, hostIDs []uint) error {
 	conn := r.pool.Get()
 	defer conn.Close()
@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// asyncWriteTimeout is the timeout of the writes of a batch by the workers.
+const asyncWriteTimeout = 1 * time.Minute
+
+// errLogQueueFull is returned when the logs do not fit in the queue.
+var errLogQueueFull = errors.New("log queue is full")
+
+// asyncLogWriter queues the logs in memory and returns right away, the logs
+// are written in batches by background workers. This keeps the requests of
+// osquery fast when the destination is slow.
+//
+// The size of the queued logs is bounded: when the queue is full, the writes
+// fail so that osquery sends the logs again later. The logs the workers fail
+// to write are dropped (unless the writer spools them).
+type asyncLogWriter struct {
+	writer        fleet.JSONLogger
+	maxSize       int
+	maxBatchSize  int
+	flushInterval time.Duration
+	logger        log.Logger
+
+	mu    sync.Mutex
+	queue []json.RawMessage
+	// size is the size of the queued logs.
+	size int
+	// notify wakes up a worker once a batch is full.
+	notify chan struct{}
+	// writing is held for reading by the workers while they write a batch.
+	writing sync.RWMutex
+}
+
+// NewAsyncLogWriter returns a writer queuing the logs of type logType (status
+// or result), written with writer by the workers of cfg.
+func NewAsyncLogWriter(writer fleet.JSONLogger, cfg config.LogQueueConfig, logType string, logger log.Logger) (*asyncLogWriter, error) {
+	if cfg.MaxSize <= 0 || cfg.MaxBatchSize <= 0 || cfg.Workers <= 0 || cfg.FlushInterval <= 0 {
+		return nil, errors.New("create log queue: max size, max batch size, workers and flush interval must be positive")
+	}
+	w := newAsyncLogWriter(writer, cfg, logType, logger)
+	for i := 0; i < cfg.Workers; i++ {
+		go w.work()
+	}
+	return w, nil
+}
+
+func newAsyncLogWriter(writer fleet.JSONLogger, cfg config.LogQueueConfig, logType string, logger log.Logger) *asyncLogWriter {
+	return &asyncLogWriter{
+		writer:        writer,
+		maxSize:       cfg.MaxSize,
+		maxBatchSize:  cfg.MaxBatchSize,
+		flushInterval: cfg.FlushInterval,
+		logger:        log.With(logger, "component", "log-queue", "log_type", logType),
+		notify:        make(chan struct{}, 1),
+	}
+}
+
+func (w *asyncLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	size := 0
+	for _, log := range logs {
+		size += len(log)
+	}
+
+	w.mu.Lock()
+	if w.size+size > w.maxSize {
+		w.mu.Unlock()
+		return errLogQueueFull
+	}
+	w.queue = append(w.queue, logs...)
+	w.size += size
+	full := w.size >= w.maxBatchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.notify <- struct{}{}:
+		default:
+			// A worker is already notified.
+		}
+	}
+	return nil
+}
+
+// take removes from the queue and returns the oldest logs, up to the maximum
+// batch size. A log bigger than the maximum batch size is returned on its own.
+func (w *asyncLogWriter) take() []json.RawMessage {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n, size := 0, 0
+	for n < len(w.queue) && (n == 0 || size+len(w.queue[n]) <= w.maxBatchSize) {
+		size += len(w.queue[n])
+		n++
+	}
+	if n == 0 {
+		return nil
+	}
+	batch := make([]json.RawMessage, n)
+	copy(batch, w.queue)
+	// Clear the references to let the logs be garbage collected.
+	for i := 0; i < n; i++ {
+		w.queue[i] = nil
+	}
+	w.queue = w.queue[n:]
+	w.size -= size
+	return batch
+}
+
+// work writes the queued logs when a batch is full, and at every flush
+// interval.
+func (w *asyncLogWriter) work() {
+	ticker := time.NewTicker(w.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.notify:
+		case <-ticker.C:
+		}
+		for w.writeBatch() {
+		}
+	}
+}
+
+// writeBatch writes a batch of queued logs. It returns false if the queue was
+// empty.
+func (w *asyncLogWriter) writeBatch() bool {
+	w.writing.RLock()
+	defer w.writing.RUnlock()
+
+	batch := w.take()
+	if len(batch) == 0 {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), asyncWriteTimeout)
+	defer cancel()
+	if err := w.writer.Write(ctx, batch); err != nil {
+		level.Error(w.logger).Log("msg", "dropping queued logs that could not be written", "count", len(batch), "err", err)
+	}
+	return true
+}
+
+// Flush waits for the writes of the workers, writes the queued logs and
+// flushes the writer if it buffers the logs.
+func (w *asyncLogWriter) Flush(ctx context.Context) error {
+	w.writing.Lock()
+	defer w.writing.Unlock()
+
+	var errs []string
+	for batch := w.take(); len(batch) > 0; batch = w.take() {
+		if err := w.writer.Write(ctx, batch); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if f, ok := w.writer.(bufferedLogger); ok {
+		if err := f.Flush(ctx); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("flush queued logs: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
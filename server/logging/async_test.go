@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchRecordingLogWriter records the batches of logs it writes.
+type batchRecordingLogWriter struct {
+	mu      sync.Mutex
+	batches [][]json.RawMessage
+}
+
+func (w *batchRecordingLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches = append(w.batches, logs)
+	return nil
+}
+
+func (w *batchRecordingLogWriter) written() [][]json.RawMessage {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.batches
+}
+
+func TestAsyncLogWriter(t *testing.T) {
+	ctx := context.Background()
+	dest := &batchRecordingLogWriter{}
+	// two logs per batch
+	writer := newAsyncLogWriter(dest, config.LogQueueConfig{
+		MaxSize:      len(logs[0]) + len(logs[1]) + len(logs[2]) + len(logs[0]),
+		MaxBatchSize: len(logs[0]) + len(logs[1]),
+	}, "result", log.NewNopLogger())
+
+	// the logs are queued
+	require.NoError(t, writer.Write(ctx, logs))
+	require.NoError(t, writer.Write(ctx, logs[:1]))
+	assert.Empty(t, dest.written())
+
+	// the queue is full
+	require.ErrorIs(t, writer.Write(ctx, logs[1:2]), errLogQueueFull)
+
+	// the logs are written in batches, in order
+	require.True(t, writer.writeBatch())
+	require.NoError(t, writer.Flush(ctx))
+	assert.False(t, writer.writeBatch())
+	assert.Equal(t, [][]json.RawMessage{logs[:2], {logs[2], logs[0]}}, dest.written())
+	assert.Zero(t, writer.size)
+	assert.Empty(t, writer.queue)
+}
+
+func TestAsyncLogWriterWorkers(t *testing.T) {
+	ctx := context.Background()
+	dest := &batchRecordingLogWriter{}
+	writer, err := NewAsyncLogWriter(dest, config.LogQueueConfig{
+		MaxSize:       1024,
+		MaxBatchSize:  len(logs[0]) + len(logs[1]),
+		FlushInterval: time.Hour,
+		Workers:       2,
+	}, "result", log.NewNopLogger())
+	require.NoError(t, err)
+
+	// a worker is notified once a batch is full
+	require.NoError(t, writer.Write(ctx, logs[:2]))
+	require.Eventually(t, func() bool {
+		return len(dest.written()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, logs[:2], dest.written()[0])
+
+	// the rest is written on flush
+	require.NoError(t, writer.Write(ctx, logs[2:]))
+	require.NoError(t, writer.Flush(ctx))
+	assert.Equal(t, [][]json.RawMessage{logs[:2], logs[2:]}, dest.written())
+
+	_, err = NewAsyncLogWriter(dest, config.LogQueueConfig{MaxSize: 1024, MaxBatchSize: 1024}, "result", log.NewNopLogger())
+	require.Error(t, err)
+}
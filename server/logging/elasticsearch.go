@@ -0,0 +1,289 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cast"
+)
+
+type elasticsearchLogWriter struct {
+	client       *http.Client
+	url          string
+	username     string
+	password     string
+	apiKey       string
+	index        string
+	logType      string
+	maxBatchSize int
+	maxRetries   int
+	logger       log.Logger
+	now          func() time.Time
+	// backoff returns the time to wait before the given retry.
+	backoff func(try int) time.Duration
+}
+
+// NewElasticsearchLogWriter returns a writer indexing the logs of type logType
+// (status or result) with the bulk API of the Elasticsearch or OpenSearch
+// cluster of cfg.
+func NewElasticsearchLogWriter(cfg config.ElasticsearchConfig, logType string, logger log.Logger) (*elasticsearchLogWriter, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("create Elasticsearch writer: url is required")
+	}
+	return &elasticsearchLogWriter{
+		client:       fleethttp.NewClient(fleethttp.WithTimeout(cfg.Timeout)),
+		url:          strings.TrimSuffix(cfg.URL, "/") + "/_bulk",
+		username:     cfg.Username,
+		password:     cfg.Password,
+		apiKey:       cfg.APIKey,
+		index:        cfg.Index,
+		logType:      logType,
+		maxBatchSize: cfg.MaxBatchSize,
+		maxRetries:   cfg.MaxRetries,
+		logger:       logger,
+		now:          time.Now,
+		backoff:      exponentialBackoff,
+	}, nil
+}
+
+// elasticsearchAction is a document with the action line indexing it in a
+// bulk request.
+type elasticsearchAction []byte
+
+func (w *elasticsearchLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	var batch []elasticsearchAction
+	batchSize := 0
+	for _, log := range logs {
+		docs, t, err := elasticsearchDocuments(log)
+		if err != nil {
+			level.Info(w.logger).Log(
+				"msg", "dropping invalid log",
+				"err", err,
+			)
+			continue
+		}
+		if t.IsZero() {
+			t = w.now()
+		}
+		meta, err := json.Marshal(map[string]interface{}{
+			"index": map[string]string{"_index": expandLogTemplate(w.index, w.logType, t)},
+		})
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "elasticsearch marshal action")
+		}
+
+		for _, doc := range docs {
+			action := make(elasticsearchAction, 0, len(meta)+len(doc)+2)
+			action = append(action, meta...)
+			action = append(action, '\n')
+			action = append(action, doc...)
+			action = append(action, '\n')
+
+			// Push the batch before it exceeds the maximum size. A
+			// document bigger than the maximum size is sent on its
+			// own.
+			if len(batch) > 0 && batchSize+len(action) > w.maxBatchSize {
+				if err := w.bulk(ctx, batch); err != nil {
+					return ctxerr.Wrap(ctx, err, "elasticsearch bulk index")
+				}
+				batch, batchSize = nil, 0
+			}
+			batch = append(batch, action)
+			batchSize += len(action)
+		}
+	}
+
+	// Push the final batch
+	if len(batch) > 0 {
+		if err := w.bulk(ctx, batch); err != nil {
+			return ctxerr.Wrap(ctx, err, "elasticsearch bulk index")
+		}
+	}
+	return nil
+}
+
+// elasticsearchDocuments returns the documents to index for the log, with the
+// time of the log if it has one.
+//
+// Status logs and the results of differential queries logged as events are
+// indexed as is. The results of the queries in snapshot mode, and of the
+// differential queries when osquery does not log the results as events, are
+// indexed as one document per row, with the row in "columns" and the action
+// ("snapshot", "added" or "removed") in "action", so that all the results have
+// the same mapping. All the documents have an "@timestamp" field when the time
+// of the log is known.
+func elasticsearchDocuments(log json.RawMessage) ([]json.RawMessage, time.Time, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(log, &fields); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var t time.Time
+	if raw, ok := fields["unixTime"]; ok {
+		var unixTime interface{}
+		if err := json.Unmarshal(raw, &unixTime); err == nil {
+			if sec, err := cast.ToInt64E(unixTime); err == nil && sec > 0 {
+				t = time.Unix(sec, 0).UTC()
+				fields["@timestamp"] = json.RawMessage(`"` + t.Format(time.RFC3339) + `"`)
+			}
+		}
+	}
+
+	type actionRow struct {
+		action string
+		row    json.RawMessage
+	}
+	var rows []actionRow
+	if snapshot, ok := fields["snapshot"]; ok {
+		var snapshotRows []json.RawMessage
+		if err := json.Unmarshal(snapshot, &snapshotRows); err != nil {
+			return nil, time.Time{}, err
+		}
+		for _, row := range snapshotRows {
+			rows = append(rows, actionRow{action: "snapshot", row: row})
+		}
+		delete(fields, "snapshot")
+	} else if diff, ok := fields["diffResults"]; ok {
+		var diffRows diffResults
+		if err := json.Unmarshal(diff, &diffRows); err != nil {
+			return nil, time.Time{}, err
+		}
+		for _, row := range diffRows.Added {
+			rows = append(rows, actionRow{action: "added", row: row})
+		}
+		for _, row := range diffRows.Removed {
+			rows = append(rows, actionRow{action: "removed", row: row})
+		}
+		delete(fields, "diffResults")
+	} else {
+		doc, err := json.Marshal(fields)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		return []json.RawMessage{doc}, t, nil
+	}
+
+	docs := make([]json.RawMessage, 0, len(rows))
+	for _, r := range rows {
+		action, err := json.Marshal(r.action)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		fields["action"] = action
+		fields["columns"] = r.row
+		doc, err := json.Marshal(fields)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, t, nil
+}
+
+// elasticsearchBulkResponse is the response of the bulk API.
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// bulk indexes the documents of the batch, retrying with backoff the requests
+// rejected because the cluster is overloaded, and the documents rejected
+// because of the back pressure of the cluster.
+func (w *elasticsearchLogWriter) bulk(ctx context.Context, batch []elasticsearchAction) error {
+	for try := 0; ; try++ {
+		if try > 0 {
+			select {
+			case <-time.After(w.backoff(try)):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		var body bytes.Buffer
+		for _, action := range batch {
+			body.Write(action)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, &body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/x-ndjson")
+		switch {
+		case w.apiKey != "":
+			req.Header.Set("Authorization", "ApiKey "+w.apiKey)
+		case w.username != "":
+			req.SetBasicAuth(w.username, w.password)
+		}
+
+		resp, err := w.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to POST to %s: %w", w.url, err)
+		}
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			if try < w.maxRetries {
+				continue
+			}
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("error posting to %s: %d. %s", w.url, resp.StatusCode, string(respBody))
+		}
+
+		var result elasticsearchBulkResponse
+		if err := json.Unmarshal(respBody, &result); err != nil {
+			return fmt.Errorf("decoding response of %s: %w", w.url, err)
+		}
+		if !result.Errors {
+			return nil
+		}
+
+		// Collect the documents to retry, the other failures are not
+		// retryable.
+		var retry []elasticsearchAction
+		var failed int
+		var firstErr json.RawMessage
+		for i, item := range result.Items {
+			for _, res := range item {
+				if res.Status >= 200 && res.Status < 300 {
+					continue
+				}
+				if res.Status == http.StatusTooManyRequests && i < len(batch) {
+					retry = append(retry, batch[i])
+					continue
+				}
+				failed++
+				if firstErr == nil {
+					firstErr = res.Error
+				}
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to index %d documents. First error: %s", failed, string(firstErr))
+		}
+		if len(retry) == 0 {
+			return nil
+		}
+		if try >= w.maxRetries {
+			return fmt.Errorf("failed to index %d documents, retries exhausted", len(retry))
+		}
+		batch = retry
+	}
+}
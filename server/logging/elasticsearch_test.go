@@ -0,0 +1,175 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeElasticsearchWriter(t *testing.T, url string, cfg config.ElasticsearchConfig, logType string) *elasticsearchLogWriter {
+	cfg.URL = url
+	cfg.Index = "osquery-{log_type}-{year}.{month}.{day}"
+	if cfg.MaxBatchSize == 0 {
+		cfg.MaxBatchSize = 1024 * 1024
+	}
+	cfg.Timeout = 5 * time.Second
+	w, err := NewElasticsearchLogWriter(cfg, logType, log.NewNopLogger())
+	require.NoError(t, err)
+	w.now = func() time.Time { return time.Date(2022, 3, 26, 0, 0, 0, 0, time.UTC) }
+	w.backoff = func(int) time.Duration { return 0 }
+	return w
+}
+
+type elasticsearchBulkItem struct {
+	index string
+	doc   map[string]interface{}
+}
+
+func readElasticsearchBulk(t *testing.T, r *http.Request) []elasticsearchBulkItem {
+	var items []elasticsearchBulkItem
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var meta struct {
+			Index struct {
+				Index string `json:"_index"`
+			} `json:"index"`
+		}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &meta))
+		require.True(t, scanner.Scan())
+		var doc map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+		items = append(items, elasticsearchBulkItem{index: meta.Index.Index, doc: doc})
+	}
+	require.NoError(t, scanner.Err())
+	return items
+}
+
+func TestElasticsearchWrite(t *testing.T) {
+	var requests [][]elasticsearchBulkItem
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		username, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "fleet", username)
+		assert.Equal(t, "secret", password)
+		requests = append(requests, readElasticsearchBulk(t, r))
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	writer := makeElasticsearchWriter(t, server.URL+"/", config.ElasticsearchConfig{Username: "fleet", Password: "secret"}, "result")
+
+	resultLogs := []json.RawMessage{
+		// event format
+		json.RawMessage(`{"name":"pack/Global/users","hostIdentifier":"host1","unixTime":1648196000,"columns":{"uid":"0"},"action":"added"}`),
+		// snapshot
+		json.RawMessage(`{"name":"pack/Global/processes","hostIdentifier":"host2","unixTime":"1648252800","snapshot":[{"pid":"1"},{"pid":"2"}],"action":"snapshot"}`),
+		// batched differential results
+		json.RawMessage(`{"name":"pack/Global/users","hostIdentifier":"host3","unixTime":1648196000,"diffResults":{"added":[{"uid":"1"}],"removed":[{"uid":"2"}]},"action":"added"}`),
+		// no time
+		json.RawMessage(`{"foo":"bar"}`),
+		// invalid JSON is dropped
+		json.RawMessage(`{"foo":`),
+	}
+	require.NoError(t, writer.Write(context.Background(), resultLogs))
+	require.Len(t, requests, 1)
+	items := requests[0]
+	require.Len(t, items, 6)
+
+	assert.Equal(t, "osquery-result-2022.03.25", items[0].index)
+	assert.Equal(t, "2022-03-25T08:13:20Z", items[0].doc["@timestamp"])
+	assert.Equal(t, "added", items[0].doc["action"])
+	assert.Equal(t, map[string]interface{}{"uid": "0"}, items[0].doc["columns"])
+
+	for i, pid := range []string{"1", "2"} {
+		item := items[1+i]
+		assert.Equal(t, "osquery-result-2022.03.26", item.index)
+		assert.Equal(t, "host2", item.doc["hostIdentifier"])
+		assert.Equal(t, "snapshot", item.doc["action"])
+		assert.Equal(t, map[string]interface{}{"pid": pid}, item.doc["columns"])
+		assert.NotContains(t, item.doc, "snapshot")
+	}
+
+	assert.Equal(t, "added", items[3].doc["action"])
+	assert.Equal(t, map[string]interface{}{"uid": "1"}, items[3].doc["columns"])
+	assert.Equal(t, "removed", items[4].doc["action"])
+	assert.Equal(t, map[string]interface{}{"uid": "2"}, items[4].doc["columns"])
+	assert.NotContains(t, items[4].doc, "diffResults")
+
+	// the logs without time are indexed in the index of the current day
+	assert.Equal(t, "osquery-result-2022.03.26", items[5].index)
+	assert.Equal(t, map[string]interface{}{"foo": "bar"}, items[5].doc)
+}
+
+func TestElasticsearchWriteBatches(t *testing.T) {
+	var requests [][]elasticsearchBulkItem
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ApiKey key", r.Header.Get("Authorization"))
+		requests = append(requests, readElasticsearchBulk(t, r))
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	// two documents fit in a batch
+	action := `{"index":{"_index":"osquery-status-2022.03.26"}}` + "\n" + string(logs[0]) + "\n"
+	writer := makeElasticsearchWriter(t, server.URL, config.ElasticsearchConfig{APIKey: "key", MaxBatchSize: 2 * len(action)}, "status")
+
+	require.NoError(t, writer.Write(context.Background(), []json.RawMessage{logs[0], logs[0], logs[0]}))
+	require.Len(t, requests, 2)
+	assert.Len(t, requests[0], 2)
+	assert.Len(t, requests[1], 1)
+	assert.Equal(t, "osquery-status-2022.03.26", requests[1][0].index)
+}
+
+func TestElasticsearchWriteRetries(t *testing.T) {
+	var calls []int
+	responses := []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) { w.WriteHeader(http.StatusServiceUnavailable) },
+		// the second document is rejected by the back pressure
+		func(w http.ResponseWriter) {
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":429,"error":{"type":"es_rejected_execution_exception"}}},{"index":{"status":201}}]}`))
+		},
+		func(w http.ResponseWriter) {
+			w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, len(readElasticsearchBulk(t, r)))
+		responses[(len(calls)-1)%len(responses)](w)
+	}))
+	defer server.Close()
+
+	writer := makeElasticsearchWriter(t, server.URL, config.ElasticsearchConfig{MaxRetries: 2}, "status")
+	require.NoError(t, writer.Write(context.Background(), logs))
+	// only the rejected document is sent again
+	assert.Equal(t, []int{3, 3, 1}, calls)
+
+	// retries exhausted
+	calls = nil
+	writer.maxRetries = 1
+	err := writer.Write(context.Background(), logs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "retries exhausted")
+
+	// documents failing for other reasons are not retried
+	calls = nil
+	responses = []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) {
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}},{"index":{"status":201}},{"index":{"status":201}}]}`))
+		},
+	}
+	err = writer.Write(context.Background(), logs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "mapper_parsing_exception")
+	assert.Len(t, calls, 1)
+}
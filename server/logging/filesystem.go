@@ -27,8 +27,9 @@ type filesystemLogWriter struct {
 // The logFile can be rotated by sending a `SIGHUP` signal to Fleet if
 // enableRotation is true
 //
-// The enableCompression argument is only used when enableRotation is true.
-func NewFilesystemLogWriter(path string, appLogger log.Logger, enableRotation bool, enableCompression bool) (*filesystemLogWriter, error) {
+// The enableCompression, maxSize (in megabytes), maxAge (in days) and
+// maxBackups arguments are only used when enableRotation is true.
+func NewFilesystemLogWriter(path string, appLogger log.Logger, enableRotation bool, enableCompression bool, maxSize, maxAge, maxBackups int) (*filesystemLogWriter, error) {
 	// Fail early if the process does not have the necessary
 	// permissions to open the file at path.
 	file, err := openFile(path)
@@ -45,9 +46,9 @@ func NewFilesystemLogWriter(path string, appLogger log.Logger, enableRotation bo
 	file.Close()
 	osquerydLogger := &lumberjack.Logger{
 		Filename:   path,
-		MaxSize:    500, // megabytes
-		MaxBackups: 3,
-		MaxAge:     28, //days
+		MaxSize:    maxSize, // megabytes
+		MaxBackups: maxBackups,
+		MaxAge:     maxAge, //days
 		Compress:   enableCompression,
 	}
 	appLogger = log.With(appLogger, "component", "osqueryd-logger")
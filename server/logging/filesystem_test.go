@@ -9,7 +9,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/stretchr/testify/assert"
@@ -21,7 +24,7 @@ func TestFilesystemLogger(t *testing.T) {
 	tempPath := t.TempDir()
 	require.NoError(t, os.Chmod(tempPath, 0755))
 	fileName := path.Join(tempPath, "filesystemLogWriter")
-	lgr, err := NewFilesystemLogWriter(fileName, log.NewNopLogger(), false, false)
+	lgr, err := NewFilesystemLogWriter(fileName, log.NewNopLogger(), false, false, 500, 28, 3)
 	require.Nil(t, err)
 	defer os.Remove(fileName)
 
@@ -61,6 +64,44 @@ func TestFilesystemLogger(t *testing.T) {
 
 }
 
+func TestFilesystemLoggerRotation(t *testing.T) {
+	ctx := context.Background()
+	tempPath := t.TempDir()
+	fileName := path.Join(tempPath, "filesystemLogWriter")
+	// rotate every megabyte and keep 2 compressed backups
+	lgr, err := NewFilesystemLogWriter(fileName, log.NewNopLogger(), true, true, 1, 28, 2)
+	require.NoError(t, err)
+
+	var logs []json.RawMessage
+	for i := 0; i < 1024; i++ {
+		logs = append(logs, json.RawMessage(`"`+strings.Repeat("a", 1021)+`"`))
+	}
+	// each batch is 1 MB, with the newlines
+	for i := 0; i < 5; i++ {
+		require.NoError(t, lgr.Write(ctx, logs))
+	}
+	require.NoError(t, lgr.writer.Close())
+
+	// the rotated files are compressed and removed in the background
+	require.Eventually(t, func() bool {
+		matches, err := filepath.Glob(fileName + "-*")
+		require.NoError(t, err)
+		if len(matches) != 2 {
+			return false
+		}
+		for _, m := range matches {
+			if !strings.HasSuffix(m, ".gz") {
+				return false
+			}
+		}
+		return true
+	}, 5*time.Second, 50*time.Millisecond)
+
+	info, err := os.Stat(fileName)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024*1024), info.Size())
+}
+
 // TestFilesystemLoggerPermission tests that NewFilesystemLogWriter fails
 // if the process does not have permissions to write to the provided path.
 func TestFilesystemLoggerPermission(t *testing.T) {
@@ -75,7 +116,7 @@ func TestFilesystemLoggerPermission(t *testing.T) {
 		{name: "without-rotation", rotation: false},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			_, err := NewFilesystemLogWriter(fileName, log.NewNopLogger(), tc.rotation, false)
+			_, err := NewFilesystemLogWriter(fileName, log.NewNopLogger(), tc.rotation, false, 500, 28, 3)
 			require.Error(t, err)
 			require.True(t, errors.Is(err, fs.ErrPermission), err)
 		})
@@ -89,7 +130,7 @@ func BenchmarkFilesystemLogger(b *testing.B) {
 		b.Fatal("temp dir failed", err)
 	}
 	fileName := path.Join(tempPath, "filesystemLogWriter")
-	lgr, err := NewFilesystemLogWriter(fileName, log.NewNopLogger(), false, false)
+	lgr, err := NewFilesystemLogWriter(fileName, log.NewNopLogger(), false, false, 500, 28, 3)
 	if err != nil {
 		b.Fatal("new failed ", err)
 	}
@@ -130,7 +171,7 @@ func benchLumberjack(b *testing.B, compression bool) {
 		b.Fatal("temp dir failed", err)
 	}
 	fileName := path.Join(tempPath, "lumberjack")
-	lgr, err := NewFilesystemLogWriter(fileName, log.NewNopLogger(), true, compression)
+	lgr, err := NewFilesystemLogWriter(fileName, log.NewNopLogger(), true, compression, 500, 28, 3)
 	if err != nil {
 		b.Fatal("new failed ", err)
 	}
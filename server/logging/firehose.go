@@ -98,39 +98,50 @@ func (f *firehoseLogWriter) Write(ctx context.Context, logs []json.RawMessage) e
 	var records []*firehose.Record
 	totalBytes := 0
 	for _, log := range logs {
-		// Add newline because Firehose does not output each record on
-		// a separate line.
-		log = append(log, '\n')
-
-		// We don't really have a good option for what to do with logs
-		// that are too big for Firehose. This behavior is consistent
-		// with osquery's behavior in the Firehose logger plugin, and
-		// the beginning bytes of the log should help the Fleet admin
-		// diagnose the query generating huge results.
-		if len(log) > firehoseMaxSizeOfRecord {
-			level.Info(f.logger).Log(
-				"msg", "dropping log over 1MB Firehose limit",
-				"size", len(log),
-				"log", string(log[:100])+"...",
-			)
-			continue
+		// Account for the newline added to each record.
+		parts := []json.RawMessage{log}
+		if len(log)+1 > firehoseMaxSizeOfRecord {
+			// Logs that are too big for Firehose are split in
+			// multiple records when they contain multiple rows.
+			// Otherwise we don't really have a good option for
+			// what to do with them. This behavior is consistent
+			// with osquery's behavior in the Firehose logger
+			// plugin, and the beginning bytes of the log should
+			// help the Fleet admin diagnose the query generating
+			// huge results.
+			var ok bool
+			parts, ok = splitResultLog(log, firehoseMaxSizeOfRecord-1)
+			if !ok {
+				level.Info(f.logger).Log(
+					"msg", "dropping log over 1MB Firehose limit",
+					"size", len(log)+1,
+					"log", string(log[:100])+"...",
+				)
+				continue
+			}
 		}
 
-		// If adding this log will exceed the limit on number of
-		// records in the batch, or the limit on total size of the
-		// records in the batch, we need to push this batch before
-		// adding any more.
-		if len(records) >= firehoseMaxRecordsInBatch ||
-			totalBytes+len(log) > firehoseMaxSizeOfBatch {
-			if err := f.putRecordBatch(0, records); err != nil {
-				return ctxerr.Wrap(ctx, err, "put records")
+		for _, part := range parts {
+			// Add newline because Firehose does not output each
+			// record on a separate line.
+			part = append(part, '\n')
+
+			// If adding this log will exceed the limit on number
+			// of records in the batch, or the limit on total size
+			// of the records in the batch, we need to push this
+			// batch before adding any more.
+			if len(records) >= firehoseMaxRecordsInBatch ||
+				totalBytes+len(part) > firehoseMaxSizeOfBatch {
+				if err := f.putRecordBatch(0, records); err != nil {
+					return ctxerr.Wrap(ctx, err, "put records")
+				}
+				totalBytes = 0
+				records = nil
 			}
-			totalBytes = 0
-			records = nil
-		}
 
-		records = append(records, &firehose.Record{Data: []byte(log)})
-		totalBytes += len(log)
+			records = append(records, &firehose.Record{Data: []byte(part)})
+			totalBytes += len(part)
+		}
 	}
 
 	// Push the final batch
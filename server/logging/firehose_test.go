@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -13,6 +14,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/logging/mock"
 	"github.com/go-kit/kit/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -202,6 +204,43 @@ func TestFirehoseRecordTooBig(t *testing.T) {
 	assert.Equal(t, 1, callCount)
 }
 
+func TestFirehoseRecordTooBigSplit(t *testing.T) {
+	ctx := context.Background()
+	row := `{"data":"` + strings.Repeat("a", 400*1000) + `"}`
+	newLogs := []json.RawMessage{
+		logs[0],
+		json.RawMessage(`{"name":"pack/Global/big","action":"snapshot","snapshot":[` + row + `,` + row + `,` + row + `]}`),
+		logs[1],
+	}
+	callCount := 0
+	putFunc := func(input *firehose.PutRecordBatchInput) (*firehose.PutRecordBatchOutput, error) {
+		callCount += 1
+		records := getLogsFromInput(input)
+		require.Len(t, records, 4)
+		assert.Equal(t, logsWithNewlines[0], records[0])
+		assert.Equal(t, logsWithNewlines[1], records[3])
+		// the big log is split in two records under the size limit
+		for i, rows := range []int{2, 1} {
+			record := records[i+1]
+			assert.LessOrEqual(t, len(record), firehoseMaxSizeOfRecord)
+			assert.Equal(t, byte('\n'), record[len(record)-1])
+			var log struct {
+				Name     string            `json:"name"`
+				Snapshot []json.RawMessage `json:"snapshot"`
+			}
+			require.NoError(t, json.Unmarshal(record, &log))
+			assert.Equal(t, "pack/Global/big", log.Name)
+			assert.Len(t, log.Snapshot, rows)
+		}
+		return &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int64(0)}, nil
+	}
+	f := &mock.FirehoseMock{PutRecordBatchFunc: putFunc}
+	writer := makeFirehoseWriterWithMock(f, "foobar")
+	err := writer.Write(ctx, newLogs)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, callCount)
+}
+
 func TestFirehoseSplitBatchBySize(t *testing.T) {
 	ctx := context.Background()
 	// Make each record just under 1 MB so that it takes 3 total batches of
@@ -105,41 +105,53 @@ func (k *kinesisLogWriter) Write(ctx context.Context, logs []json.RawMessage) er
 	var records []*kinesis.PutRecordsRequestEntry
 	totalBytes := 0
 	for _, log := range logs {
-		// so we get nice NDJSON
-		log = append(log, '\n')
 		// Evenly distribute logs across shards by assigning each
 		// kinesis.PutRecordsRequestEntry a random partition key.
 		partitionKey := fmt.Sprint(k.rand.Intn(256))
 
-		// We don't really have a good option for what to do with logs
-		// that are too big for Kinesis. This behavior is consistent
-		// with osquery's behavior in the Kinesis logger plugin, and
-		// the beginning bytes of the log should help the Fleet admin
-		// diagnose the query generating huge results.
-		if len(log)+len(partitionKey) > kinesisMaxSizeOfRecord {
-			level.Info(k.logger).Log(
-				"msg", "dropping log over 1MB Kinesis limit",
-				"size", len(log),
-				"log", string(log[:100])+"...",
-			)
-			continue
+		// Account for the newline added to each record.
+		parts := []json.RawMessage{log}
+		if len(log)+1+len(partitionKey) > kinesisMaxSizeOfRecord {
+			// Logs that are too big for Kinesis are split in
+			// multiple records when they contain multiple rows.
+			// Otherwise we don't really have a good option for
+			// what to do with them. This behavior is consistent
+			// with osquery's behavior in the Kinesis logger
+			// plugin, and the beginning bytes of the log should
+			// help the Fleet admin diagnose the query generating
+			// huge results.
+			var ok bool
+			parts, ok = splitResultLog(log, kinesisMaxSizeOfRecord-1-len(partitionKey))
+			if !ok {
+				level.Info(k.logger).Log(
+					"msg", "dropping log over 1MB Kinesis limit",
+					"size", len(log)+1,
+					"log", string(log[:100])+"...",
+				)
+				continue
+			}
 		}
 
-		// If adding this log will exceed the limit on number of
-		// records in the batch, or the limit on total size of the
-		// records in the batch, we need to push this batch before
-		// adding any more.
-		if len(records) >= kinesisMaxRecordsInBatch ||
-			totalBytes+len(log)+len(partitionKey) > kinesisMaxSizeOfBatch {
-			if err := k.putRecords(0, records); err != nil {
-				return ctxerr.Wrap(ctx, err, "put records")
+		for _, part := range parts {
+			// so we get nice NDJSON
+			part = append(part, '\n')
+
+			// If adding this log will exceed the limit on number
+			// of records in the batch, or the limit on total size
+			// of the records in the batch, we need to push this
+			// batch before adding any more.
+			if len(records) >= kinesisMaxRecordsInBatch ||
+				totalBytes+len(part)+len(partitionKey) > kinesisMaxSizeOfBatch {
+				if err := k.putRecords(0, records); err != nil {
+					return ctxerr.Wrap(ctx, err, "put records")
+				}
+				totalBytes = 0
+				records = nil
 			}
-			totalBytes = 0
-			records = nil
-		}
 
-		records = append(records, &kinesis.PutRecordsRequestEntry{Data: []byte(log), PartitionKey: aws.String(partitionKey)})
-		totalBytes += len(log) + len(partitionKey)
+			records = append(records, &kinesis.PutRecordsRequestEntry{Data: []byte(part), PartitionKey: aws.String(partitionKey)})
+			totalBytes += len(part) + len(partitionKey)
+		}
 	}
 
 	// Push the final batch
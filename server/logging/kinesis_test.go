@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"math/rand"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/logging/mock"
 	"github.com/go-kit/kit/log"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func makeKinesisWriterWithMock(client kinesisiface.KinesisAPI, stream string) *kinesisLogWriter {
@@ -179,6 +181,44 @@ func TestKinesisRecordTooBig(t *testing.T) {
 	assert.Equal(t, 1, callCount)
 }
 
+func TestKinesisRecordTooBigSplit(t *testing.T) {
+	ctx := context.Background()
+	row := `{"data":"` + strings.Repeat("a", 400*1000) + `"}`
+	newLogs := []json.RawMessage{
+		logs[0],
+		json.RawMessage(`{"name":"pack/Global/big","action":"snapshot","snapshot":[` + row + `,` + row + `,` + row + `]}`),
+		logs[1],
+	}
+	callCount := 0
+	putFunc := func(input *kinesis.PutRecordsInput) (*kinesis.PutRecordsOutput, error) {
+		callCount += 1
+		require.Len(t, input.Records, 4)
+		records := getLogsFromPutRecordsInput(input)
+		assert.Equal(t, logs[0], records[0])
+		assert.Equal(t, logs[1], records[3])
+		// the big log is split in two records under the size limit, with
+		// the same partition key
+		assert.Equal(t, *input.Records[1].PartitionKey, *input.Records[2].PartitionKey)
+		for i, rows := range []int{2, 1} {
+			entry := input.Records[i+1]
+			assert.LessOrEqual(t, len(entry.Data)+len(*entry.PartitionKey), kinesisMaxSizeOfRecord)
+			var log struct {
+				Name     string            `json:"name"`
+				Snapshot []json.RawMessage `json:"snapshot"`
+			}
+			require.NoError(t, json.Unmarshal(records[i+1], &log))
+			assert.Equal(t, "pack/Global/big", log.Name)
+			assert.Len(t, log.Snapshot, rows)
+		}
+		return &kinesis.PutRecordsOutput{FailedRecordCount: aws.Int64(0)}, nil
+	}
+	k := &mock.KinesisMock{PutRecordsFunc: putFunc}
+	writer := makeKinesisWriterWithMock(k, "foobar")
+	err := writer.Write(ctx, newLogs)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, callCount)
+}
+
 func TestKinesisSplitBatchBySize(t *testing.T) {
 	ctx := context.Background()
 	// Make each record just under 1 MB (accounting for partitionkey) so that it
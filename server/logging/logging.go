@@ -3,7 +3,13 @@
 package logging
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -11,9 +17,44 @@ import (
 	"github.com/go-kit/kit/log/level"
 )
 
+// logPlugins are the names of the log plugins.
+var logPlugins = []string{
+	"filesystem", "firehose", "kinesis", "lambda", "pubsub", "stdout", "s3",
+	"splunk", "elasticsearch", "syslog", "webhook", "kafkarest",
+}
+
+// IsLogPlugin returns true if name is the name of a log plugin.
+func IsLogPlugin(name string) bool {
+	for _, plugin := range logPlugins {
+		if name == plugin {
+			return true
+		}
+	}
+	return false
+}
+
 type OsqueryLogger struct {
 	Status fleet.JSONLogger
 	Result fleet.JSONLogger
+	// Audit is the writer of the audit logs, the result logs of the queries
+	// of audit-based tables. It is nil if the audit logs are written as
+	// result logs.
+	Audit fleet.JSONLogger
+
+	// auditQueries are the patterns of the names of the queries of the
+	// audit logs.
+	auditQueries []string
+
+	// resultPlugin is the plugin of Result, and resultRouted is true if
+	// Result applies the result log routes.
+	resultPlugin string
+	resultRouted bool
+	// newResultWriter creates the writers of the result logs of the plugins
+	// selected by the teams.
+	newResultWriter func(plugin string) (fleet.JSONLogger, error)
+
+	mu            sync.Mutex
+	resultWriters map[string]fleet.JSONLogger
 }
 
 func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
@@ -31,6 +72,9 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 			logger,
 			config.Filesystem.EnableLogRotation,
 			config.Filesystem.EnableLogCompression,
+			config.Filesystem.MaxSize,
+			config.Filesystem.MaxAge,
+			config.Filesystem.MaxBackups,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("create filesystem status logger: %w", err)
@@ -88,6 +132,31 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 		if err != nil {
 			return nil, fmt.Errorf("create stdout status logger: %w", err)
 		}
+	case "s3":
+		status, err = NewS3LogWriter(config.S3Logging, "status", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create s3 status logger: %w", err)
+		}
+	case "splunk":
+		status, err = NewSplunkLogWriter(config.Splunk, "status", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create splunk status logger: %w", err)
+		}
+	case "elasticsearch":
+		status, err = NewElasticsearchLogWriter(config.Elasticsearch, "status", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create elasticsearch status logger: %w", err)
+		}
+	case "syslog":
+		status, err = NewSyslogLogWriter(config.Syslog, "status")
+		if err != nil {
+			return nil, fmt.Errorf("create syslog status logger: %w", err)
+		}
+	case "webhook":
+		status, err = NewWebhookLogWriter(config.WebhookLogging, "status", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create webhook status logger: %w", err)
+		}
 	case "kafkarest":
 		status, err = NewKafkaRESTWriter(&KafkaRESTParams{
 			KafkaProxyHost:        config.KafkaREST.ProxyHost,
@@ -104,7 +173,160 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 		)
 	}
 
-	switch config.Osquery.ResultLogPlugin {
+	resultPlugin := config.Osquery.ResultLogPlugin
+	if resultPlugin == "" {
+		resultPlugin = "filesystem"
+	}
+	baseResult, err := newResultLogWriter(config.Osquery.ResultLogPlugin, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	result = baseResult
+	if config.Osquery.ResultLogRoutes != "" {
+		result, err = newRoutedLogWriter(config.Osquery.ResultLogRoutes, config.Osquery.ResultLogPlugin, result, func(plugin string) (fleet.JSONLogger, error) {
+			return newResultLogWriter(plugin, config, logger)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create result log routes: %w", err)
+		}
+	}
+
+	status, err = wrapLogWriter(status, config, "status", logger)
+	if err != nil {
+		return nil, err
+	}
+	result, err = wrapLogWriter(result, config, "result", logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var audit fleet.JSONLogger
+	var auditQueries []string
+	if config.Osquery.AuditLogPlugin != "" {
+		auditQueries, err = parseLogNamePatterns(config.Osquery.AuditLogQueries)
+		if err != nil {
+			return nil, fmt.Errorf("parse audit log queries: %w", err)
+		}
+		audit, err = newAuditLogWriter(config.Osquery.AuditLogPlugin, config, logger)
+		if err != nil {
+			return nil, err
+		}
+		audit, err = wrapLogWriter(audit, config, "audit", logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &OsqueryLogger{
+		Status:       status,
+		Result:       result,
+		Audit:        audit,
+		auditQueries: auditQueries,
+		resultPlugin: resultPlugin,
+		resultRouted: config.Osquery.ResultLogRoutes != "",
+		newResultWriter: func(plugin string) (fleet.JSONLogger, error) {
+			// The writer of the result log plugin is shared, without the
+			// routes.
+			writer := baseResult
+			if plugin != resultPlugin {
+				var err error
+				writer, err = newResultLogWriter(plugin, config, logger)
+				if err != nil {
+					return nil, err
+				}
+			}
+			return wrapLogWriter(writer, config, "result-"+plugin, logger)
+		},
+	}, nil
+}
+
+// SetAuditWriter sets the writer of the audit logs, the result logs of the
+// queries with a name matching one of the patterns.
+func (l *OsqueryLogger) SetAuditWriter(writer fleet.JSONLogger, queries ...string) {
+	l.Audit = writer
+	l.auditQueries = queries
+}
+
+// SplitAuditLogs returns the result logs and the audit logs of logs. All the
+// logs are result logs if there is no audit writer.
+func (l *OsqueryLogger) SplitAuditLogs(logs []json.RawMessage) (results, audit []json.RawMessage) {
+	if l.Audit == nil {
+		return logs, nil
+	}
+	for _, log := range logs {
+		if matchLogName(log, l.auditQueries) {
+			audit = append(audit, log)
+		} else {
+			results = append(results, log)
+		}
+	}
+	return results, audit
+}
+
+// SetResultWriter sets the writer of the result logs for the plugin selected
+// by a team, instead of creating it when it is first requested.
+func (l *OsqueryLogger) SetResultWriter(plugin string, writer fleet.JSONLogger) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.resultWriters == nil {
+		l.resultWriters = make(map[string]fleet.JSONLogger)
+	}
+	l.resultWriters[plugin] = writer
+}
+
+// wrapLogWriter wraps the writer of the logs of type logType with the spool
+// and the queue, if they are enabled.
+func wrapLogWriter(writer fleet.JSONLogger, config config.FleetConfig, logType string, logger log.Logger) (fleet.JSONLogger, error) {
+	var err error
+	if config.LogSpool.Dir != "" {
+		writer, err = NewSpoolLogWriter(writer, config.LogSpool, logType, logger)
+		if err != nil {
+			return nil, fmt.Errorf("create %s log spool: %w", logType, err)
+		}
+	}
+	if config.LogQueue.Enabled {
+		writer, err = NewAsyncLogWriter(writer, config.LogQueue, logType, logger)
+		if err != nil {
+			return nil, fmt.Errorf("create %s log queue: %w", logType, err)
+		}
+	}
+	return writer, nil
+}
+
+// ResultWriter returns the writer of the result logs for the plugin selected
+// by a team. The writers are created the first time they are requested, an
+// empty plugin selects Result.
+func (l *OsqueryLogger) ResultWriter(plugin string) (fleet.JSONLogger, error) {
+	if plugin == "" || (plugin == l.resultPlugin && !l.resultRouted) {
+		return l.Result, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if writer, ok := l.resultWriters[plugin]; ok {
+		return writer, nil
+	}
+	if l.newResultWriter == nil {
+		return nil, fmt.Errorf("unknown result log plugin: %s", plugin)
+	}
+	writer, err := l.newResultWriter(plugin)
+	if err != nil {
+		return nil, fmt.Errorf("create %s result logger: %w", plugin, err)
+	}
+	if l.resultWriters == nil {
+		l.resultWriters = make(map[string]fleet.JSONLogger)
+	}
+	l.resultWriters[plugin] = writer
+	return writer, nil
+}
+
+// newResultLogWriter returns the writer of the result logs for the given
+// plugin.
+func newResultLogWriter(plugin string, config config.FleetConfig, logger log.Logger) (fleet.JSONLogger, error) {
+	var result fleet.JSONLogger
+	var err error
+
+	switch plugin {
 	case "":
 		// Allow "" to mean filesystem for backwards compatibility
 		level.Info(logger).Log("msg", "fleet_result_log_plugin not explicitly specified. Assuming 'filesystem'")
@@ -115,6 +337,9 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 			logger,
 			config.Filesystem.EnableLogRotation,
 			config.Filesystem.EnableLogCompression,
+			config.Filesystem.MaxSize,
+			config.Filesystem.MaxAge,
+			config.Filesystem.MaxBackups,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("create filesystem result logger: %w", err)
@@ -172,6 +397,31 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 		if err != nil {
 			return nil, fmt.Errorf("create stdout result logger: %w", err)
 		}
+	case "s3":
+		result, err = NewS3LogWriter(config.S3Logging, "result", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create s3 result logger: %w", err)
+		}
+	case "splunk":
+		result, err = NewSplunkLogWriter(config.Splunk, "result", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create splunk result logger: %w", err)
+		}
+	case "elasticsearch":
+		result, err = NewElasticsearchLogWriter(config.Elasticsearch, "result", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create elasticsearch result logger: %w", err)
+		}
+	case "syslog":
+		result, err = NewSyslogLogWriter(config.Syslog, "result")
+		if err != nil {
+			return nil, fmt.Errorf("create syslog result logger: %w", err)
+		}
+	case "webhook":
+		result, err = NewWebhookLogWriter(config.WebhookLogging, "result", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create webhook result logger: %w", err)
+		}
 	case "kafkarest":
 		result, err = NewKafkaRESTWriter(&KafkaRESTParams{
 			KafkaProxyHost:        config.KafkaREST.ProxyHost,
@@ -184,8 +434,172 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 		}
 	default:
 		return nil, fmt.Errorf(
-			"unknown result log plugin: %s", config.Osquery.StatusLogPlugin,
+			"unknown result log plugin: %s", plugin,
+		)
+	}
+	return result, nil
+}
+
+// newAuditLogWriter returns the writer of the audit logs for the given plugin.
+func newAuditLogWriter(plugin string, config config.FleetConfig, logger log.Logger) (fleet.JSONLogger, error) {
+	var audit fleet.JSONLogger
+	var err error
+
+	switch plugin {
+	case "filesystem":
+		audit, err = NewFilesystemLogWriter(
+			config.Filesystem.AuditLogFile,
+			logger,
+			config.Filesystem.EnableLogRotation,
+			config.Filesystem.EnableLogCompression,
+			config.Filesystem.MaxSize,
+			config.Filesystem.MaxAge,
+			config.Filesystem.MaxBackups,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create filesystem audit logger: %w", err)
+		}
+	case "firehose":
+		audit, err = NewFirehoseLogWriter(
+			config.Firehose.Region,
+			config.Firehose.EndpointURL,
+			config.Firehose.AccessKeyID,
+			config.Firehose.SecretAccessKey,
+			config.Firehose.StsAssumeRoleArn,
+			config.Firehose.AuditStream,
+			logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create firehose audit logger: %w", err)
+		}
+	case "kinesis":
+		audit, err = NewKinesisLogWriter(
+			config.Kinesis.Region,
+			config.Kinesis.EndpointURL,
+			config.Kinesis.AccessKeyID,
+			config.Kinesis.SecretAccessKey,
+			config.Kinesis.StsAssumeRoleArn,
+			config.Kinesis.AuditStream,
+			logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create kinesis audit logger: %w", err)
+		}
+	case "lambda":
+		audit, err = NewLambdaLogWriter(
+			config.Lambda.Region,
+			config.Lambda.AccessKeyID,
+			config.Lambda.SecretAccessKey,
+			config.Lambda.StsAssumeRoleArn,
+			config.Lambda.AuditFunction,
+			logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create lambda audit logger: %w", err)
+		}
+	case "pubsub":
+		audit, err = NewPubSubLogWriter(
+			config.PubSub.Project,
+			config.PubSub.AuditTopic,
+			config.PubSub.AddAttributes,
+			logger,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("create pubsub audit logger: %w", err)
+		}
+	case "stdout":
+		audit, err = NewStdoutLogWriter()
+		if err != nil {
+			return nil, fmt.Errorf("create stdout audit logger: %w", err)
+		}
+	case "s3":
+		audit, err = NewS3LogWriter(config.S3Logging, "audit", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create s3 audit logger: %w", err)
+		}
+	case "splunk":
+		audit, err = NewSplunkLogWriter(config.Splunk, "audit", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create splunk audit logger: %w", err)
+		}
+	case "elasticsearch":
+		audit, err = NewElasticsearchLogWriter(config.Elasticsearch, "audit", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create elasticsearch audit logger: %w", err)
+		}
+	case "syslog":
+		audit, err = NewSyslogLogWriter(config.Syslog, "audit")
+		if err != nil {
+			return nil, fmt.Errorf("create syslog audit logger: %w", err)
+		}
+	case "webhook":
+		audit, err = NewWebhookLogWriter(config.WebhookLogging, "audit", logger)
+		if err != nil {
+			return nil, fmt.Errorf("create webhook audit logger: %w", err)
+		}
+	case "kafkarest":
+		audit, err = NewKafkaRESTWriter(&KafkaRESTParams{
+			KafkaProxyHost:        config.KafkaREST.ProxyHost,
+			KafkaTopic:            config.KafkaREST.AuditTopic,
+			KafkaContentTypeValue: config.KafkaREST.ContentTypeValue,
+			KafkaTimeout:          config.KafkaREST.Timeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create kafka rest audit logger: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf(
+			"unknown audit log plugin: %s", plugin,
 		)
 	}
-	return &OsqueryLogger{Status: status, Result: result}, nil
+	return audit, nil
+}
+
+// expandLogTemplate replaces the placeholders of the template with the type
+// of the logs ({log_type}) and the UTC date t ({year}, {month}, {day} and
+// {hour}).
+func expandLogTemplate(template, logType string, t time.Time) string {
+	t = t.UTC()
+	return strings.NewReplacer(
+		"{log_type}", logType,
+		"{year}", t.Format("2006"),
+		"{month}", t.Format("01"),
+		"{day}", t.Format("02"),
+		"{hour}", t.Format("15"),
+	).Replace(template)
+}
+
+// exponentialBackoff returns the time to wait before the given retry of a
+// request to a log destination.
+func exponentialBackoff(try int) time.Duration {
+	return 100 * time.Millisecond * time.Duration(math.Pow(2.0, float64(try)))
+}
+
+// bufferedLogger is implemented by the loggers buffering the logs.
+type bufferedLogger interface {
+	Flush(ctx context.Context) error
+}
+
+// Flush writes the logs buffered by the status, result and audit loggers, it is
+// called when Fleet stops.
+func (l *OsqueryLogger) Flush(ctx context.Context) error {
+	loggers := []fleet.JSONLogger{l.Status, l.Result, l.Audit}
+	l.mu.Lock()
+	for _, writer := range l.resultWriters {
+		loggers = append(loggers, writer)
+	}
+	l.mu.Unlock()
+
+	var errs []string
+	for _, logger := range loggers {
+		if f, ok := logger.(bufferedLogger); ok {
+			if err := f.Flush(ctx); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("flush osquery logs: %s", strings.Join(errs, "; "))
+	}
+	return nil
 }
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOsqueryLoggerResultWriter(t *testing.T) {
+	result, status := &recordingLogWriter{}, &recordingLogWriter{}
+	created := make(map[string]int)
+	logger := &OsqueryLogger{
+		Status:       status,
+		Result:       result,
+		resultPlugin: "filesystem",
+		newResultWriter: func(plugin string) (fleet.JSONLogger, error) {
+			created[plugin]++
+			return &recordingLogWriter{}, nil
+		},
+	}
+
+	// the result log plugin uses Result
+	for _, plugin := range []string{"", "filesystem"} {
+		writer, err := logger.ResultWriter(plugin)
+		require.NoError(t, err)
+		assert.Same(t, result, writer)
+	}
+
+	// the writers of the other plugins are created once
+	splunk, err := logger.ResultWriter("splunk")
+	require.NoError(t, err)
+	assert.NotSame(t, result, splunk)
+	writer, err := logger.ResultWriter("splunk")
+	require.NoError(t, err)
+	assert.Same(t, splunk, writer)
+	assert.Equal(t, map[string]int{"splunk": 1}, created)
+
+	// the routes do not apply to the result log plugin selected by a team
+	logger.resultRouted = true
+	writer, err = logger.ResultWriter("filesystem")
+	require.NoError(t, err)
+	assert.NotSame(t, result, writer)
+	assert.Equal(t, map[string]int{"splunk": 1, "filesystem": 1}, created)
+
+	// the writers of the teams are flushed
+	require.NoError(t, logger.Flush(context.Background()))
+	assert.True(t, result.flushed)
+	assert.True(t, status.flushed)
+	assert.True(t, splunk.(*recordingLogWriter).flushed)
+
+	assert.True(t, IsLogPlugin("splunk"))
+	assert.False(t, IsLogPlugin("nope"))
+}
+
+func TestOsqueryLoggerSplitAuditLogs(t *testing.T) {
+	logs := []json.RawMessage{
+		json.RawMessage(`{"name":"pack/audit/process_events","action":"added"}`),
+		json.RawMessage(`{"name":"pack/it/apps","action":"snapshot"}`),
+		json.RawMessage(`{"name":"socket_events","action":"added"}`),
+		json.RawMessage(`{"action":"added"}`),
+	}
+
+	// all the logs are result logs without audit writer
+	logger := &OsqueryLogger{Result: &recordingLogWriter{}}
+	results, audit := logger.SplitAuditLogs(logs)
+	assert.Equal(t, logs, results)
+	assert.Empty(t, audit)
+
+	patterns, err := parseLogNamePatterns("pack/*/process_events, socket_events")
+	require.NoError(t, err)
+	logger.SetAuditWriter(&recordingLogWriter{}, patterns...)
+	results, audit = logger.SplitAuditLogs(logs)
+	assert.Equal(t, []json.RawMessage{logs[1], logs[3]}, results)
+	assert.Equal(t, []json.RawMessage{logs[0], logs[2]}, audit)
+
+	for _, invalid := range []string{"", "a,,b", "pack/[audit/*"} {
+		_, err := parseLogNamePatterns(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
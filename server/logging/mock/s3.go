@@ -0,0 +1,23 @@
+package mock
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+var _ s3iface.S3API = (*S3Mock)(nil)
+
+type PutObjectWithContextFunc func(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error)
+type S3Mock struct {
+	s3iface.S3API
+
+	PutObjectWithContextFunc        PutObjectWithContextFunc
+	PutObjectWithContextFuncInvoked bool
+}
+
+func (s *S3Mock) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	s.PutObjectWithContextFuncInvoked = true
+	return s.PutObjectWithContextFunc(ctx, input, opts...)
+}
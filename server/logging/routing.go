@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// logRoute sends the logs of the queries with a name matching pattern to
+// writer.
+type logRoute struct {
+	pattern string
+	writer  fleet.JSONLogger
+}
+
+// routedLogWriter sends the result logs to the writer of the first route
+// matching the name of the query of the log, or to the default writer when no
+// route matches.
+type routedLogWriter struct {
+	routes []logRoute
+	// writers are the distinct writers of the routes and the default writer.
+	writers       []fleet.JSONLogger
+	defaultWriter fleet.JSONLogger
+}
+
+// newRoutedLogWriter returns a writer routing the logs with the routes, in the
+// format "pattern1=plugin1,pattern2=plugin2". The patterns are matched against
+// the name of the query of the logs (for example pack/<pack name>/<query name>
+// for the queries of the packs) with the syntax of path.Match.
+//
+// The writer of defaultPlugin is defaultWriter, the writers of the other
+// plugins are created with newWriter. The routes to the same plugin share the
+// same writer.
+func newRoutedLogWriter(routes, defaultPlugin string, defaultWriter fleet.JSONLogger, newWriter func(plugin string) (fleet.JSONLogger, error)) (*routedLogWriter, error) {
+	if defaultPlugin == "" {
+		defaultPlugin = "filesystem"
+	}
+	w := &routedLogWriter{
+		writers:       []fleet.JSONLogger{defaultWriter},
+		defaultWriter: defaultWriter,
+	}
+	pluginWriters := map[string]fleet.JSONLogger{defaultPlugin: defaultWriter}
+	for _, route := range strings.Split(routes, ",") {
+		parts := strings.SplitN(route, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid route %q, expected <pattern>=<plugin>", route)
+		}
+		pattern, plugin := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if err := validateLogNamePattern(pattern); err != nil {
+			return nil, fmt.Errorf("invalid route: %w", err)
+		}
+
+		writer, ok := pluginWriters[plugin]
+		if !ok {
+			var err error
+			writer, err = newWriter(plugin)
+			if err != nil {
+				return nil, fmt.Errorf("create %s logger for route %q: %w", plugin, pattern, err)
+			}
+			pluginWriters[plugin] = writer
+			w.writers = append(w.writers, writer)
+		}
+		w.routes = append(w.routes, logRoute{pattern: pattern, writer: writer})
+	}
+	return w, nil
+}
+
+func (w *routedLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	// Group the logs by writer, keeping the order of the logs of each
+	// writer.
+	writerLogs := make(map[fleet.JSONLogger][]json.RawMessage)
+	for _, log := range logs {
+		writer := w.route(log)
+		writerLogs[writer] = append(writerLogs[writer], log)
+	}
+
+	var errs []string
+	for _, writer := range w.writers {
+		if len(writerLogs[writer]) == 0 {
+			continue
+		}
+		if err := writer.Write(ctx, writerLogs[writer]); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("write routed logs: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// route returns the writer of the log.
+func (w *routedLogWriter) route(log json.RawMessage) fleet.JSONLogger {
+	name := logName(log)
+	if name == "" {
+		return w.defaultWriter
+	}
+	for _, route := range w.routes {
+		if ok, _ := path.Match(route.pattern, name); ok {
+			return route.writer
+		}
+	}
+	return w.defaultWriter
+}
+
+// logName returns the name of the query of the log, or an empty string if
+// the log has no name.
+func logName(log json.RawMessage) string {
+	var fields struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(log, &fields); err != nil {
+		return ""
+	}
+	return fields.Name
+}
+
+// validateLogNamePattern returns an error if the pattern of query names is
+// not a valid path.Match pattern.
+func validateLogNamePattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// parseLogNamePatterns parses the patterns of query names, in the format
+// "pattern1,pattern2".
+func parseLogNamePatterns(s string) ([]string, error) {
+	var patterns []string
+	for _, pattern := range strings.Split(s, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			return nil, fmt.Errorf("invalid patterns %q, expected <pattern>,<pattern>", s)
+		}
+		if err := validateLogNamePattern(pattern); err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// matchLogName returns true if the name of the query of the log matches one
+// of the patterns.
+func matchLogName(log json.RawMessage, patterns []string) bool {
+	name := logName(log)
+	if name == "" {
+		return false
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush writes the logs buffered by the writers of the routes.
+func (w *routedLogWriter) Flush(ctx context.Context) error {
+	var errs []string
+	for _, writer := range w.writers {
+		if f, ok := writer.(bufferedLogger); ok {
+			if err := f.Flush(ctx); err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("flush routed logs: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
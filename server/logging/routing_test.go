@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingLogWriter struct {
+	logs    []json.RawMessage
+	flushed bool
+}
+
+func (w *recordingLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	w.logs = append(w.logs, logs...)
+	return nil
+}
+
+func (w *recordingLogWriter) Flush(ctx context.Context) error {
+	w.flushed = true
+	return nil
+}
+
+func TestRoutedLogWriter(t *testing.T) {
+	defaultWriter := &recordingLogWriter{}
+	writers := make(map[string]*recordingLogWriter)
+	writer, err := newRoutedLogWriter(
+		"pack/FIM/*=splunk, pack/*/inventory_*=s3, usb_devices=splunk, pack/Global/*=filesystem",
+		"filesystem",
+		defaultWriter,
+		func(plugin string) (fleet.JSONLogger, error) {
+			w := &recordingLogWriter{}
+			writers[plugin] = w
+			return w, nil
+		},
+	)
+	require.NoError(t, err)
+	// one writer per plugin
+	require.Len(t, writers, 2)
+	assert.Len(t, writer.writers, 3)
+
+	input := []json.RawMessage{
+		json.RawMessage(`{"name":"pack/FIM/file_events","action":"added"}`),
+		json.RawMessage(`{"name":"pack/IT/inventory_apps","action":"snapshot"}`),
+		json.RawMessage(`{"name":"pack/Global/inventory_apps","action":"snapshot"}`),
+		json.RawMessage(`{"name":"usb_devices","action":"added"}`),
+		json.RawMessage(`{"name":"pack/FIM/process_events","action":"added"}`),
+		json.RawMessage(`{"name":"pack/IT/processes","action":"snapshot"}`),
+		json.RawMessage(`{"action":"snapshot"}`),
+		json.RawMessage(`{"name":`),
+	}
+	require.NoError(t, writer.Write(context.Background(), input))
+
+	assert.Equal(t, []json.RawMessage{input[0], input[3], input[4]}, writers["splunk"].logs)
+	// the first matching route wins
+	assert.Equal(t, []json.RawMessage{input[1], input[2]}, writers["s3"].logs)
+	assert.Equal(t, []json.RawMessage{input[5], input[6], input[7]}, defaultWriter.logs)
+
+	require.NoError(t, writer.Flush(context.Background()))
+	assert.True(t, defaultWriter.flushed)
+	assert.True(t, writers["splunk"].flushed)
+	assert.True(t, writers["s3"].flushed)
+}
+
+func TestRoutedLogWriterErrors(t *testing.T) {
+	newWriter := func(plugin string) (fleet.JSONLogger, error) {
+		if plugin == "nope" {
+			return nil, errors.New("unknown result log plugin: nope")
+		}
+		return &recordingLogWriter{}, nil
+	}
+	for _, routes := range []string{
+		"pack/FIM/*",
+		"=splunk",
+		"pack/FIM/*=",
+		"pack/FIM/*=splunk,",
+		"pack/[FIM/*=splunk",
+		"pack/FIM/*=nope",
+	} {
+		_, err := newRoutedLogWriter(routes, "filesystem", &recordingLogWriter{}, newWriter)
+		assert.Error(t, err, routes)
+	}
+}
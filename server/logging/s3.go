@@ -0,0 +1,225 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	s3AwsRegionHint = "us-east-1"
+	// s3UploadTimeout is the timeout of the uploads of the objects rotated
+	// because of their age.
+	s3UploadTimeout = 1 * time.Minute
+	// s3AgeCheckInterval is how often the age of the buffered object is
+	// checked.
+	s3AgeCheckInterval = 1 * time.Second
+)
+
+// s3LogWriter buffers the logs in a gzipped NDJSON object, one log per line,
+// and uploads the object to S3 once it reaches the maximum size or age.
+type s3LogWriter struct {
+	client  s3iface.S3API
+	bucket  string
+	prefix  string
+	logType string
+	maxSize int
+	maxAge  time.Duration
+	logger  log.Logger
+	now     func() time.Time
+
+	mu sync.Mutex
+	// buf and gz are nil while there are no buffered logs.
+	buf *bytes.Buffer
+	gz  *gzip.Writer
+	// size is the uncompressed size of the buffered logs.
+	size int
+	// openedAt is the time the first buffered log was written.
+	openedAt time.Time
+}
+
+// s3Object is an object ready to be uploaded.
+type s3Object struct {
+	key  string
+	body []byte
+}
+
+// NewS3LogWriter returns a writer uploading the logs of type logType (status
+// or result) to the S3 bucket of cfg. The objects are rotated in the
+// background once they reach the configured maximum age.
+func NewS3LogWriter(cfg config.S3LoggingConfig, logType string, logger log.Logger) (*s3LogWriter, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("create S3 writer: bucket is required")
+	}
+
+	conf := &aws.Config{}
+
+	// Only provide static credentials if we have them
+	// otherwise use the default credentials provider chain
+	if cfg.AccessKeyID != "" && cfg.SecretAccessKey != "" {
+		conf.Credentials = credentials.NewStaticCredentials(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	}
+	if cfg.EndpointURL != "" {
+		conf.Endpoint = &cfg.EndpointURL
+	}
+	conf.DisableSSL = &cfg.DisableSSL
+	conf.S3ForcePathStyle = &cfg.ForceS3PathStyle
+
+	sess, err := session.NewSession(conf)
+	if err != nil {
+		return nil, fmt.Errorf("create S3 client: %w", err)
+	}
+
+	if cfg.StsAssumeRoleArn != "" {
+		conf.Credentials = stscreds.NewCredentials(sess, cfg.StsAssumeRoleArn)
+		sess, err = session.NewSession(conf)
+		if err != nil {
+			return nil, fmt.Errorf("create S3 client: %w", err)
+		}
+	}
+
+	region := cfg.Region
+	if region == "" {
+		region, err = s3manager.GetBucketRegion(context.TODO(), sess, cfg.Bucket, s3AwsRegionHint)
+		if err != nil {
+			return nil, fmt.Errorf("create S3 client: %w", err)
+		}
+	}
+
+	w := newS3LogWriter(s3.New(sess, &aws.Config{Region: &region}), cfg, logType, logger)
+	go w.rotateOldObjects()
+	return w, nil
+}
+
+func newS3LogWriter(client s3iface.S3API, cfg config.S3LoggingConfig, logType string, logger log.Logger) *s3LogWriter {
+	return &s3LogWriter{
+		client:  client,
+		bucket:  cfg.Bucket,
+		prefix:  cfg.Prefix,
+		logType: logType,
+		maxSize: cfg.MaxObjectSize,
+		maxAge:  cfg.MaxObjectAge,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+func (w *s3LogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	w.mu.Lock()
+	for _, log := range logs {
+		if w.gz == nil {
+			w.buf = new(bytes.Buffer)
+			w.gz = gzip.NewWriter(w.buf)
+			w.openedAt = w.now()
+		}
+		// Writes to a bytes.Buffer cannot fail.
+		_, _ = w.gz.Write(bytes.TrimRight(log, "\n"))
+		_, _ = w.gz.Write([]byte{'\n'})
+		w.size += len(log) + 1
+	}
+	var obj *s3Object
+	if w.size >= w.maxSize {
+		obj = w.rotate()
+	}
+	w.mu.Unlock()
+
+	if obj != nil {
+		return w.upload(ctx, obj)
+	}
+	return nil
+}
+
+// Flush uploads the buffered logs, if any.
+func (w *s3LogWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	obj := w.rotate()
+	w.mu.Unlock()
+
+	if obj != nil {
+		return w.upload(ctx, obj)
+	}
+	return nil
+}
+
+// rotate returns the object of the buffered logs, and resets the buffer. It
+// returns nil if there are no buffered logs. w.mu must be held.
+func (w *s3LogWriter) rotate() *s3Object {
+	if w.gz == nil {
+		return nil
+	}
+	// Closing a gzip.Writer on a bytes.Buffer cannot fail.
+	_ = w.gz.Close()
+	obj := &s3Object{key: w.objectKey(w.openedAt), body: w.buf.Bytes()}
+	w.buf, w.gz, w.size = nil, nil, 0
+	return obj
+}
+
+// rotateOldObjects uploads the buffered logs once they reach the maximum age.
+func (w *s3LogWriter) rotateOldObjects() {
+	ticker := time.NewTicker(s3AgeCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		w.mu.Lock()
+		var obj *s3Object
+		if w.gz != nil && w.now().Sub(w.openedAt) >= w.maxAge {
+			obj = w.rotate()
+		}
+		w.mu.Unlock()
+
+		if obj != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), s3UploadTimeout)
+			if err := w.upload(ctx, obj); err != nil {
+				level.Error(w.logger).Log("msg", "upload S3 log object", "key", obj.key, "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// objectKey returns the key of an object opened at t. The placeholders of the
+// prefix are replaced by the log type and the UTC date of t, and the object
+// name is made unique by a random suffix.
+func (w *s3LogWriter) objectKey(t time.Time) string {
+	t = t.UTC()
+	prefix := expandLogTemplate(w.prefix, w.logType, t)
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		// Fall back on the nanoseconds, the key is still very unlikely to
+		// collide.
+		return fmt.Sprintf("%s%s-%09d.ndjson.gz", prefix, t.Format("20060102T150405Z"), t.Nanosecond())
+	}
+	return fmt.Sprintf("%s%s-%s.ndjson.gz", prefix, t.Format("20060102T150405Z"), hex.EncodeToString(suffix))
+}
+
+func (w *s3LogWriter) upload(ctx context.Context, obj *s3Object) error {
+	_, err := w.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      &w.bucket,
+		Key:         &obj.key,
+		Body:        bytes.NewReader(obj.body),
+		ContentType: aws.String("application/gzip"),
+	})
+	if err != nil {
+		return ctxerr.Wrapf(ctx, err, "put S3 log object %s", obj.key)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/logging/mock"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type s3PutObjects struct {
+	mu      sync.Mutex
+	keys    []string
+	objects []string
+}
+
+func (p *s3PutObjects) put(t *testing.T) mock.PutObjectWithContextFunc {
+	return func(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+		assert.Equal(t, "logs-bucket", *input.Bucket)
+		gz, err := gzip.NewReader(input.Body)
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(gz)
+		require.NoError(t, err)
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.keys = append(p.keys, *input.Key)
+		p.objects = append(p.objects, string(b))
+		return &s3.PutObjectOutput{}, nil
+	}
+}
+
+func TestS3RotateBySize(t *testing.T) {
+	ctx := context.Background()
+	var puts s3PutObjects
+	client := &mock.S3Mock{PutObjectWithContextFunc: puts.put(t)}
+	writer := newS3LogWriter(client, config.S3LoggingConfig{
+		Bucket:        "logs-bucket",
+		Prefix:        "osquery/{log_type}/{year}/{month}/{day}/{hour}/",
+		MaxObjectSize: 32,
+		MaxObjectAge:  time.Hour,
+	}, "result", log.NewNopLogger())
+	writer.now = func() time.Time { return time.Date(2022, 3, 25, 8, 30, 0, 0, time.UTC) }
+
+	// the logs are buffered until the object reaches the maximum size
+	require.NoError(t, writer.Write(ctx, logs[:1]))
+	assert.False(t, client.PutObjectWithContextFuncInvoked)
+	require.NoError(t, writer.Write(ctx, logs[1:]))
+	require.True(t, client.PutObjectWithContextFuncInvoked)
+	require.Len(t, puts.objects, 1)
+	assert.Equal(t, "{\"foo\":\"bar\"}\n{\"flim\":\"flam\"}\n{\"jim\":\"jom\"}\n", puts.objects[0])
+	assert.Regexp(t, regexp.MustCompile(`^osquery/result/2022/03/25/08/20220325T083000Z-[0-9a-f]{16}\.ndjson\.gz$`), puts.keys[0])
+
+	// the newlines of the logs are not duplicated
+	require.NoError(t, writer.Write(ctx, logsWithNewlines))
+	require.Len(t, puts.objects, 2)
+	assert.Equal(t, puts.objects[0], puts.objects[1])
+	assert.NotEqual(t, puts.keys[0], puts.keys[1])
+
+	// flush uploads the buffered logs
+	require.NoError(t, writer.Write(ctx, logs[:1]))
+	require.Len(t, puts.objects, 2)
+	require.NoError(t, writer.Flush(ctx))
+	require.Len(t, puts.objects, 3)
+	assert.Equal(t, "{\"foo\":\"bar\"}\n", puts.objects[2])
+
+	// nothing to flush
+	require.NoError(t, writer.Flush(ctx))
+	require.Len(t, puts.objects, 3)
+}
+
+func TestS3RotateByAge(t *testing.T) {
+	var puts s3PutObjects
+	client := &mock.S3Mock{PutObjectWithContextFunc: puts.put(t)}
+	writer := newS3LogWriter(client, config.S3LoggingConfig{
+		Bucket:        "logs-bucket",
+		Prefix:        "{log_type}/",
+		MaxObjectSize: 1024,
+		MaxObjectAge:  100 * time.Millisecond,
+	}, "status", log.NewNopLogger())
+	go writer.rotateOldObjects()
+
+	require.NoError(t, writer.Write(context.Background(), logs))
+	require.Eventually(t, func() bool {
+		puts.mu.Lock()
+		defer puts.mu.Unlock()
+		return len(puts.objects) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Regexp(t, regexp.MustCompile(`^status/\d{8}T\d{6}Z-[0-9a-f]{16}\.ndjson\.gz$`), puts.keys[0])
+	assert.Equal(t, "{\"foo\":\"bar\"}\n{\"flim\":\"flam\"}\n{\"jim\":\"jom\"}\n", puts.objects[0])
+}
+
+func TestS3UploadError(t *testing.T) {
+	ctx := context.Background()
+	client := &mock.S3Mock{PutObjectWithContextFunc: func(aws.Context, *s3.PutObjectInput, ...request.Option) (*s3.PutObjectOutput, error) {
+		return nil, errors.New("access denied")
+	}}
+	writer := newS3LogWriter(client, config.S3LoggingConfig{Bucket: "logs-bucket", MaxObjectSize: 1, MaxObjectAge: time.Hour}, "result", log.NewNopLogger())
+	err := writer.Write(ctx, logs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "access denied")
+
+	// the logs of the failed upload are not uploaded again
+	client.PutObjectWithContextFuncInvoked = false
+	require.NoError(t, writer.Flush(ctx))
+	assert.False(t, client.PutObjectWithContextFuncInvoked)
+}
@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// resultRow is a row of an osquery result log, with the diff it belongs to for
+// the logs of differential queries.
+type resultRow struct {
+	removed bool
+	row     json.RawMessage
+}
+
+// diffResults is the format of the rows of the differential queries when
+// osquery is configured to not log the results as events.
+type diffResults struct {
+	Added   []json.RawMessage `json:"added"`
+	Removed []json.RawMessage `json:"removed"`
+}
+
+// splitResultLog splits an osquery result log into multiple result logs of at
+// most maxSize bytes each, so that it fits in the records of the destinations
+// limiting their size. Only the logs with multiple rows can be split, that is
+// the logs of the queries in snapshot mode and the logs of the differential
+// queries when osquery does not log the results as events. The split logs keep
+// all the fields of the original log (name, hostIdentifier, decorations...)
+// with a subset of the rows.
+//
+// It returns false if the log cannot be split, or if one of its rows does not
+// fit in maxSize bytes on its own.
+func splitResultLog(log json.RawMessage, maxSize int) ([]json.RawMessage, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(log, &fields); err != nil {
+		return nil, false
+	}
+
+	var rows []resultRow
+	rowsField := "snapshot"
+	if snapshot, ok := fields["snapshot"]; ok {
+		var snapshotRows []json.RawMessage
+		if err := json.Unmarshal(snapshot, &snapshotRows); err != nil {
+			return nil, false
+		}
+		for _, row := range snapshotRows {
+			rows = append(rows, resultRow{row: row})
+		}
+	} else if diff, ok := fields["diffResults"]; ok {
+		rowsField = "diffResults"
+		var diffRows diffResults
+		if err := json.Unmarshal(diff, &diffRows); err != nil {
+			return nil, false
+		}
+		for _, row := range diffRows.Added {
+			rows = append(rows, resultRow{row: row})
+		}
+		for _, row := range diffRows.Removed {
+			rows = append(rows, resultRow{removed: true, row: row})
+		}
+	} else {
+		return nil, false
+	}
+	if len(rows) < 2 {
+		return nil, false
+	}
+
+	// Compact the rows so that their size is the one they have in the split
+	// logs.
+	for i, r := range rows {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, r.row); err != nil {
+			return nil, false
+		}
+		rows[i].row = buf.Bytes()
+	}
+
+	build := func(rows []resultRow) (json.RawMessage, error) {
+		var value interface{}
+		if rowsField == "snapshot" {
+			snapshotRows := make([]json.RawMessage, 0, len(rows))
+			for _, r := range rows {
+				snapshotRows = append(snapshotRows, r.row)
+			}
+			value = snapshotRows
+		} else {
+			diffRows := diffResults{Added: []json.RawMessage{}, Removed: []json.RawMessage{}}
+			for _, r := range rows {
+				if r.removed {
+					diffRows.Removed = append(diffRows.Removed, r.row)
+				} else {
+					diffRows.Added = append(diffRows.Added, r.row)
+				}
+			}
+			value = diffRows
+		}
+		rowsJSON, err := marshalUnescaped(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[rowsField] = rowsJSON
+		return marshalUnescaped(fields)
+	}
+
+	empty, err := build(nil)
+	if err != nil {
+		return nil, false
+	}
+
+	var logs []json.RawMessage
+	flush := func(part []resultRow) bool {
+		splitLog, err := build(part)
+		if err != nil || len(splitLog) > maxSize {
+			return false
+		}
+		logs = append(logs, splitLog)
+		return true
+	}
+
+	// Each row adds its size, and a comma after the first row, to the size of
+	// the log without rows.
+	var part []resultRow
+	size := len(empty)
+	for _, r := range rows {
+		if len(part) > 0 && size+1+len(r.row) > maxSize {
+			if !flush(part) {
+				return nil, false
+			}
+			part = nil
+			size = len(empty)
+		}
+		if len(part) > 0 {
+			size++
+		}
+		part = append(part, r)
+		size += len(r.row)
+	}
+	if !flush(part) {
+		return nil, false
+	}
+
+	return logs, true
+}
+
+// marshalUnescaped is like json.Marshal, without escaping the HTML characters
+// so that the rows don't grow when they are split.
+func marshalUnescaped(v interface{}) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitResultLogSnapshot(t *testing.T) {
+	log := json.RawMessage(`{
+		"name": "pack/Global/processes",
+		"hostIdentifier": "host1",
+		"action": "snapshot",
+		"snapshot": [
+			{"pid": "1", "name": "<init>"},
+			{"pid": "2", "name": "kthreadd"},
+			{"pid": "3", "name": "rcu_gp"}
+		],
+		"decorations": {"host_uuid": "uuid1"}
+	}`)
+
+	// the split logs have the fields of the log, in the order of the keys
+	twoRows := `{"action":"snapshot","decorations":{"host_uuid":"uuid1"},"hostIdentifier":"host1","name":"pack/Global/processes","snapshot":[{"pid":"1","name":"<init>"},{"pid":"2","name":"kthreadd"}]}`
+	oneRow := `{"action":"snapshot","decorations":{"host_uuid":"uuid1"},"hostIdentifier":"host1","name":"pack/Global/processes","snapshot":[{"pid":"3","name":"rcu_gp"}]}`
+
+	logs, ok := splitResultLog(log, len(twoRows))
+	require.True(t, ok)
+	require.Len(t, logs, 2)
+	// the HTML characters are not escaped, so the logs are exactly the expected
+	// ones
+	assert.Equal(t, twoRows, string(logs[0]))
+	assert.Equal(t, oneRow, string(logs[1]))
+
+	logs, ok = splitResultLog(log, len(twoRows)-1)
+	require.True(t, ok)
+	require.Len(t, logs, 3)
+	for _, l := range logs {
+		assert.LessOrEqual(t, len(l), len(twoRows)-1)
+	}
+
+	// the row of pid 2 does not fit on its own
+	_, ok = splitResultLog(log, len(oneRow))
+	assert.False(t, ok)
+}
+
+func TestSplitResultLogDiffResults(t *testing.T) {
+	log := json.RawMessage(`{"name":"pack/Global/users","hostIdentifier":"host1","diffResults":{"added":[{"uid":"1"},{"uid":"2"}],"removed":[{"uid":"3"}]},"action":"added"}`)
+
+	logs, ok := splitResultLog(log, len(log)-1)
+	require.True(t, ok)
+	require.Len(t, logs, 2)
+	assert.JSONEq(t,
+		`{"name":"pack/Global/users","hostIdentifier":"host1","diffResults":{"added":[{"uid":"1"},{"uid":"2"}],"removed":[]},"action":"added"}`,
+		string(logs[0]),
+	)
+	assert.JSONEq(t,
+		`{"name":"pack/Global/users","hostIdentifier":"host1","diffResults":{"added":[],"removed":[{"uid":"3"}]},"action":"added"}`,
+		string(logs[1]),
+	)
+}
+
+func TestSplitResultLogNotSplittable(t *testing.T) {
+	cases := []json.RawMessage{
+		// not JSON
+		make(json.RawMessage, 100),
+		// status log
+		json.RawMessage(`{"severity":"0","filename":"scheduler.cpp","message":"` + strings.Repeat("a", 100) + `"}`),
+		// event format result log
+		json.RawMessage(`{"name":"pack/Global/users","columns":{"uid":"` + strings.Repeat("1", 100) + `"},"action":"added"}`),
+		// snapshot with a single row
+		json.RawMessage(`{"name":"pack/Global/users","snapshot":[{"uid":"` + strings.Repeat("1", 100) + `"}],"action":"snapshot"}`),
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			_, ok := splitResultLog(c, 50)
+			assert.False(t, ok)
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cast"
+)
+
+const splunkEventPath = "/services/collector/event"
+
+type splunkLogWriter struct {
+	client *http.Client
+	url    string
+	token  string
+	index  string
+	source string
+	// sourcetype is the sourcetype of the logs, unless the logs are the
+	// results of a query of a pack of packSourcetypes.
+	sourcetype      string
+	packSourcetypes map[string]string
+	maxBatchSize    int
+	maxRetries      int
+	logger          log.Logger
+	// backoff returns the time to wait before the given retry.
+	backoff func(try int) time.Duration
+}
+
+// splunkEvent is the format of the events sent to the HTTP Event Collector.
+type splunkEvent struct {
+	Time       int64           `json:"time,omitempty"`
+	Host       string          `json:"host,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	Sourcetype string          `json:"sourcetype,omitempty"`
+	Index      string          `json:"index,omitempty"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// NewSplunkLogWriter returns a writer sending the logs of type logType
+// (status, result or audit) to the Splunk HTTP Event Collector of cfg.
+func NewSplunkLogWriter(cfg config.SplunkConfig, logType string, logger log.Logger) (*splunkLogWriter, error) {
+	if cfg.URL == "" || cfg.Token == "" {
+		return nil, errors.New("create Splunk writer: url and token are required")
+	}
+
+	sourcetype := cfg.StatusSourcetype
+	var packSourcetypes map[string]string
+	switch logType {
+	case "result":
+		sourcetype = cfg.ResultSourcetype
+		var err error
+		packSourcetypes, err = parsePackSourcetypes(cfg.PackSourcetypes)
+		if err != nil {
+			return nil, fmt.Errorf("create Splunk writer: %w", err)
+		}
+	case "audit":
+		sourcetype = cfg.AuditSourcetype
+	}
+
+	return &splunkLogWriter{
+		client:          fleethttp.NewClient(fleethttp.WithTimeout(cfg.Timeout)),
+		url:             strings.TrimSuffix(cfg.URL, "/") + splunkEventPath,
+		token:           cfg.Token,
+		index:           cfg.Index,
+		source:          cfg.Source,
+		sourcetype:      sourcetype,
+		packSourcetypes: packSourcetypes,
+		maxBatchSize:    cfg.MaxBatchSize,
+		maxRetries:      cfg.MaxRetries,
+		logger:          logger,
+		backoff:         exponentialBackoff,
+	}, nil
+}
+
+// parsePackSourcetypes parses the sourcetypes of the packs, in the format
+// "pack1=sourcetype1,pack2=sourcetype2".
+func parsePackSourcetypes(s string) (map[string]string, error) {
+	sourcetypes := make(map[string]string)
+	if strings.TrimSpace(s) == "" {
+		return sourcetypes, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("invalid pack sourcetype %q, expected <pack>=<sourcetype>", pair)
+		}
+		sourcetypes[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return sourcetypes, nil
+}
+
+func (w *splunkLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	var batch bytes.Buffer
+	for _, log := range logs {
+		event, err := json.Marshal(w.event(log))
+		if err != nil {
+			level.Info(w.logger).Log(
+				"msg", "dropping invalid log",
+				"err", err,
+			)
+			continue
+		}
+
+		// Push the batch before it exceeds the maximum size. An event
+		// bigger than the maximum size is sent on its own.
+		if batch.Len() > 0 && batch.Len()+len(event)+1 > w.maxBatchSize {
+			if err := w.post(ctx, batch.Bytes()); err != nil {
+				return ctxerr.Wrap(ctx, err, "splunk post events")
+			}
+			batch.Reset()
+		}
+		batch.Write(event)
+		batch.WriteByte('\n')
+	}
+
+	// Push the final batch
+	if batch.Len() > 0 {
+		if err := w.post(ctx, batch.Bytes()); err != nil {
+			return ctxerr.Wrap(ctx, err, "splunk post events")
+		}
+	}
+	return nil
+}
+
+// event returns the event of the log. The host, time and pack of the log are
+// used if they can be found in the log.
+func (w *splunkLogWriter) event(log json.RawMessage) splunkEvent {
+	event := splunkEvent{
+		Source:     w.source,
+		Sourcetype: w.sourcetype,
+		Index:      w.index,
+		Event:      log,
+	}
+
+	var fields struct {
+		Name           string      `json:"name"`
+		HostIdentifier string      `json:"hostIdentifier"`
+		UnixTime       interface{} `json:"unixTime"`
+	}
+	if err := json.Unmarshal(log, &fields); err != nil {
+		return event
+	}
+	event.Host = fields.HostIdentifier
+	if unixTime, err := cast.ToInt64E(fields.UnixTime); err == nil {
+		event.Time = unixTime
+	}
+	// The results of the queries of the packs are named
+	// pack/<pack name>/<query name>.
+	if parts := strings.SplitN(fields.Name, "/", 3); len(parts) == 3 && parts[0] == "pack" {
+		if sourcetype, ok := w.packSourcetypes[parts[1]]; ok {
+			event.Sourcetype = sourcetype
+		}
+	}
+	return event
+}
+
+// post sends the events to the HTTP Event Collector, retrying with backoff
+// when it is overloaded.
+func (w *splunkLogWriter) post(ctx context.Context, events []byte) error {
+	for try := 0; ; try++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(events))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Splunk "+w.token)
+
+		resp, err := w.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to POST to %s: %w", w.url, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode >= 200 && resp.StatusCode < 300:
+			return nil
+		case (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) && try < w.maxRetries:
+			// Retry with backoff, or after the time requested by the
+			// collector.
+			wait := w.backoff(try + 1)
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+				wait = time.Duration(seconds) * time.Second
+			}
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		default:
+			return fmt.Errorf("error posting to %s: %d. %s", w.url, resp.StatusCode, string(body))
+		}
+	}
+}
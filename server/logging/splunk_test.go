@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeSplunkWriter(t *testing.T, url string, cfg config.SplunkConfig, logType string) *splunkLogWriter {
+	cfg.URL = url
+	cfg.Token = "hec-token"
+	cfg.Source = "fleet"
+	cfg.StatusSourcetype = "osquery:status"
+	cfg.ResultSourcetype = "osquery:results"
+	if cfg.MaxBatchSize == 0 {
+		cfg.MaxBatchSize = 1024 * 1024
+	}
+	cfg.Timeout = 5 * time.Second
+	w, err := NewSplunkLogWriter(cfg, logType, log.NewNopLogger())
+	require.NoError(t, err)
+	w.backoff = func(int) time.Duration { return 0 }
+	return w
+}
+
+func readSplunkEvents(t *testing.T, r *http.Request) []splunkEvent {
+	var events []splunkEvent
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var event splunkEvent
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.NoError(t, scanner.Err())
+	return events
+}
+
+func TestSplunkWrite(t *testing.T) {
+	var requests [][]splunkEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/services/collector/event", r.URL.Path)
+		assert.Equal(t, "Splunk hec-token", r.Header.Get("Authorization"))
+		requests = append(requests, readSplunkEvents(t, r))
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer server.Close()
+
+	writer := makeSplunkWriter(t, server.URL+"/", config.SplunkConfig{
+		Index:           "osquery",
+		PackSourcetypes: "Security=osquery:security, team-2=osquery:team2",
+	}, "result")
+
+	resultLogs := []json.RawMessage{
+		json.RawMessage(`{"name":"pack/Security/processes","hostIdentifier":"host1","unixTime":1648196000,"columns":{"pid":"1"},"action":"added"}`),
+		json.RawMessage(`{"name":"pack/Global/users","hostIdentifier":"host2","unixTime":1648196001,"columns":{"uid":"0"},"action":"added"}`),
+		json.RawMessage(`{"name":"pack/team-2/users","hostIdentifier":"host3","unixTime":"1648196002","columns":{"uid":"0"},"action":"added"}`),
+		json.RawMessage(`{"foo":"bar"}`),
+		// invalid JSON is dropped
+		json.RawMessage(`{"foo":`),
+	}
+	require.NoError(t, writer.Write(context.Background(), resultLogs))
+	require.Len(t, requests, 1)
+	events := requests[0]
+	require.Len(t, events, 4)
+
+	assert.Equal(t, "host1", events[0].Host)
+	assert.Equal(t, int64(1648196000), events[0].Time)
+	assert.Equal(t, "osquery:security", events[0].Sourcetype)
+	assert.Equal(t, "osquery", events[0].Index)
+	assert.Equal(t, "fleet", events[0].Source)
+	assert.JSONEq(t, string(resultLogs[0]), string(events[0].Event))
+
+	assert.Equal(t, "host2", events[1].Host)
+	assert.Equal(t, "osquery:results", events[1].Sourcetype)
+
+	assert.Equal(t, int64(1648196002), events[2].Time)
+	assert.Equal(t, "osquery:team2", events[2].Sourcetype)
+
+	assert.Empty(t, events[3].Host)
+	assert.Zero(t, events[3].Time)
+	assert.Equal(t, "osquery:results", events[3].Sourcetype)
+}
+
+func TestSplunkWriteBatches(t *testing.T) {
+	var requests [][]splunkEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, readSplunkEvents(t, r))
+	}))
+	defer server.Close()
+
+	event, err := json.Marshal(splunkEvent{Source: "fleet", Sourcetype: "osquery:status", Event: logs[0]})
+	require.NoError(t, err)
+	// two events fit in a batch
+	writer := makeSplunkWriter(t, server.URL, config.SplunkConfig{MaxBatchSize: 2*len(event) + 2}, "status")
+
+	require.NoError(t, writer.Write(context.Background(), []json.RawMessage{logs[0], logs[0], logs[0]}))
+	require.Len(t, requests, 2)
+	assert.Len(t, requests[0], 2)
+	assert.Len(t, requests[1], 1)
+	assert.Equal(t, "osquery:status", requests[1][0].Sourcetype)
+}
+
+func TestSplunkWriteRetries(t *testing.T) {
+	var calls int
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[calls%len(statuses)])
+		calls++
+	}))
+	defer server.Close()
+
+	writer := makeSplunkWriter(t, server.URL, config.SplunkConfig{MaxRetries: 2}, "status")
+	require.NoError(t, writer.Write(context.Background(), logs))
+	assert.Equal(t, 3, calls)
+
+	// retries exhausted
+	calls = 0
+	writer.maxRetries = 1
+	err := writer.Write(context.Background(), logs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+	assert.Equal(t, 2, calls)
+
+	// other errors are not retried
+	calls = 0
+	statuses = []int{http.StatusForbidden}
+	err = writer.Write(context.Background(), logs)
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestParsePackSourcetypes(t *testing.T) {
+	sourcetypes, err := parsePackSourcetypes("")
+	require.NoError(t, err)
+	assert.Empty(t, sourcetypes)
+
+	sourcetypes, err = parsePackSourcetypes("a=osquery:a,b c = osquery:bc")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "osquery:a", "b c": "osquery:bc"}, sourcetypes)
+
+	for _, invalid := range []string{"a", "a=", "=b", "a=b,"} {
+		_, err = parsePackSourcetypes(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	_, err = NewSplunkLogWriter(config.SplunkConfig{URL: "http://localhost", Token: "t", PackSourcetypes: "a"}, "result", log.NewNopLogger())
+	assert.Error(t, err)
+	_, err = NewSplunkLogWriter(config.SplunkConfig{URL: "http://localhost"}, "result", log.NewNopLogger())
+	assert.True(t, strings.Contains(err.Error(), "token"))
+}
@@ -0,0 +1,210 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	spoolFileExt = ".json"
+	// spoolReplayTimeout is the timeout of a replay of the spooled logs.
+	spoolReplayTimeout = 5 * time.Minute
+)
+
+// spoolLogWriter writes the logs with the writer of a logging plugin. When
+// the writer fails, the logs are spooled to disk instead of failing the
+// request of osquery, and they are replayed in the background once the
+// destination recovers.
+//
+// Each failed batch is spooled as a JSON array in its own file, the files are
+// replayed in the order they were written. The logs received while there are
+// spooled logs are written right away, so the logs may reach the destination
+// out of order.
+type spoolLogWriter struct {
+	writer  fleet.JSONLogger
+	dir     string
+	maxSize int64
+	logger  log.Logger
+	now     func() time.Time
+
+	// replayMu serializes the replays.
+	replayMu sync.Mutex
+	mu       sync.Mutex
+	// size is the size of the spooled files.
+	size int64
+}
+
+// NewSpoolLogWriter returns a writer spooling the logs of type logType
+// (status or result) that writer failed to write in the directory of cfg. The
+// spooled logs are replayed at the interval of cfg.
+func NewSpoolLogWriter(writer fleet.JSONLogger, cfg config.LogSpoolConfig, logType string, logger log.Logger) (*spoolLogWriter, error) {
+	if cfg.ReplayInterval <= 0 {
+		return nil, errors.New("create log spool: replay interval must be positive")
+	}
+	w, err := newSpoolLogWriter(writer, cfg, logType, logger)
+	if err != nil {
+		return nil, err
+	}
+	go w.replaySpooledLogs(cfg.ReplayInterval)
+	return w, nil
+}
+
+func newSpoolLogWriter(writer fleet.JSONLogger, cfg config.LogSpoolConfig, logType string, logger log.Logger) (*spoolLogWriter, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("create log spool: dir is required")
+	}
+	dir := filepath.Join(cfg.Dir, logType)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create log spool: %w", err)
+	}
+	w := &spoolLogWriter{
+		writer:  writer,
+		dir:     dir,
+		maxSize: int64(cfg.MaxSize),
+		logger:  log.With(logger, "component", "log-spool", "log_type", logType),
+		now:     time.Now,
+	}
+
+	// Account for the logs spooled before a restart.
+	files, err := w.spooledFiles()
+	if err != nil {
+		return nil, fmt.Errorf("create log spool: %w", err)
+	}
+	for _, file := range files {
+		w.size += file.Size()
+	}
+	return w, nil
+}
+
+func (w *spoolLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	err := w.writer.Write(ctx, logs)
+	if err == nil {
+		return nil
+	}
+
+	if spoolErr := w.spool(logs); spoolErr != nil {
+		level.Error(w.logger).Log("msg", "spool logs", "err", spoolErr)
+		return err
+	}
+	level.Info(w.logger).Log("msg", "spooled logs that could not be written", "count", len(logs), "err", err)
+	return nil
+}
+
+// spool writes the logs in a new spool file. The file is written under a
+// temporary name and then renamed, so that replays never read partial files.
+func (w *spoolLogWriter) spool(logs []json.RawMessage) error {
+	b, err := json.Marshal(logs)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size+int64(len(b)) > w.maxSize {
+		return fmt.Errorf("spool is full (%d bytes)", w.size)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	// The time prefix keeps the files sorted in the order they were
+	// written.
+	name := filepath.Join(w.dir, fmt.Sprintf("%020d-%s", w.now().UnixNano(), hex.EncodeToString(suffix)))
+	if err := ioutil.WriteFile(name+".tmp", b, 0o600); err != nil {
+		os.Remove(name + ".tmp")
+		return err
+	}
+	if err := os.Rename(name+".tmp", name+spoolFileExt); err != nil {
+		os.Remove(name + ".tmp")
+		return err
+	}
+	w.size += int64(len(b))
+	return nil
+}
+
+// spooledFiles returns the spooled files, in the order they were written.
+func (w *spoolLogWriter) spooledFiles() ([]os.FileInfo, error) {
+	entries, err := ioutil.ReadDir(w.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []os.FileInfo
+	for _, entry := range entries {
+		if entry.Mode().IsRegular() && strings.HasSuffix(entry.Name(), spoolFileExt) {
+			files = append(files, entry)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	return files, nil
+}
+
+// Replay writes the spooled logs, in the order they were spooled, and removes
+// them from the spool. It stops at the first batch that cannot be written.
+func (w *spoolLogWriter) Replay(ctx context.Context) error {
+	w.replayMu.Lock()
+	defer w.replayMu.Unlock()
+
+	files, err := w.spooledFiles()
+	if err != nil {
+		return fmt.Errorf("list spooled logs: %w", err)
+	}
+	for _, file := range files {
+		name := filepath.Join(w.dir, file.Name())
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("read spooled logs: %w", err)
+		}
+		var logs []json.RawMessage
+		if err := json.Unmarshal(b, &logs); err != nil {
+			level.Error(w.logger).Log("msg", "dropping invalid spooled logs", "file", name, "err", err)
+		} else if err := w.writer.Write(ctx, logs); err != nil {
+			return fmt.Errorf("replay spooled logs: %w", err)
+		}
+
+		if err := os.Remove(name); err != nil {
+			return fmt.Errorf("remove spooled logs: %w", err)
+		}
+		w.mu.Lock()
+		w.size -= file.Size()
+		w.mu.Unlock()
+	}
+	return nil
+}
+
+// replaySpooledLogs replays the spooled logs at every interval.
+func (w *spoolLogWriter) replaySpooledLogs(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), spoolReplayTimeout)
+		if err := w.Replay(ctx); err != nil {
+			level.Debug(w.logger).Log("msg", "replay spooled logs", "err", err)
+		}
+		cancel()
+	}
+}
+
+// Flush writes the logs buffered by the writer, if it buffers the logs.
+func (w *spoolLogWriter) Flush(ctx context.Context) error {
+	if f, ok := w.writer.(bufferedLogger); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unavailableLogWriter fails to write the logs while it is down.
+type unavailableLogWriter struct {
+	recordingLogWriter
+	down bool
+}
+
+func (w *unavailableLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	if w.down {
+		return errors.New("destination is down")
+	}
+	return w.recordingLogWriter.Write(ctx, logs)
+}
+
+func spooledFileCount(t *testing.T, w *spoolLogWriter) int {
+	files, err := w.spooledFiles()
+	require.NoError(t, err)
+	return len(files)
+}
+
+func TestSpoolLogWriter(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.LogSpoolConfig{Dir: t.TempDir(), MaxSize: 1024 * 1024}
+	dest := &unavailableLogWriter{}
+	writer, err := newSpoolLogWriter(dest, cfg, "result", log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cfg.Dir, "result"), writer.dir)
+
+	// the logs are written while the destination is up
+	require.NoError(t, writer.Write(ctx, logs[:1]))
+	assert.Equal(t, logs[:1], dest.logs)
+	assert.Equal(t, 0, spooledFileCount(t, writer))
+
+	// the logs are spooled while the destination is down
+	dest.down = true
+	require.NoError(t, writer.Write(ctx, logsWithNewlines[1:2]))
+	require.NoError(t, writer.Write(ctx, logs[2:]))
+	assert.Equal(t, 2, spooledFileCount(t, writer))
+	assert.NotZero(t, writer.size)
+	require.Error(t, writer.Replay(ctx))
+	assert.Equal(t, 2, spooledFileCount(t, writer))
+
+	// the spooled logs are accounted for after a restart
+	restarted, err := newSpoolLogWriter(dest, cfg, "result", log.NewNopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, writer.size, restarted.size)
+
+	// the spooled logs are replayed in order once the destination is up
+	dest.down = false
+	require.NoError(t, restarted.Replay(ctx))
+	assert.Equal(t, logs, dest.logs)
+	assert.Equal(t, 0, spooledFileCount(t, restarted))
+	assert.Zero(t, restarted.size)
+
+	// the write fails when the spool is full
+	dest.down = true
+	restarted.maxSize = 10
+	require.Error(t, restarted.Write(ctx, logs))
+	assert.Equal(t, 0, spooledFileCount(t, restarted))
+
+	// flushes are passed to the destination
+	require.NoError(t, restarted.Flush(ctx))
+	assert.True(t, dest.flushed)
+}
@@ -0,0 +1,191 @@
+package logging
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+)
+
+const (
+	syslogAppName = "fleet"
+	// syslogSDID is the ID of the structured data element of the messages,
+	// using the private enterprise number reserved for documentation
+	// (RFC 5612).
+	syslogSDID        = "fleet@32473"
+	syslogDialTimeout = 10 * time.Second
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var syslogSeverities = map[string]int{
+	"emerg": 0, "alert": 1, "crit": 2, "err": 3,
+	"warning": 4, "notice": 5, "info": 6, "debug": 7,
+}
+
+// syslogLogWriter sends the logs as RFC 5424 syslog messages, over UDP, TCP
+// or TLS. Over TCP and TLS the messages are framed with octet counting
+// (RFC 6587, RFC 5425).
+type syslogLogWriter struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	priority  int
+	hostname  string
+	procID    string
+	logType   string
+	now       func() time.Time
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogLogWriter returns a writer sending the logs of type logType
+// (status or result) to the syslog collector of cfg.
+func NewSyslogLogWriter(cfg config.SyslogConfig, logType string) (*syslogLogWriter, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("create syslog writer: address is required")
+	}
+
+	facility, ok := syslogFacilities[cfg.Facility]
+	if !ok {
+		return nil, fmt.Errorf("create syslog writer: unknown facility %q", cfg.Facility)
+	}
+	severity, ok := syslogSeverities[cfg.Severity]
+	if !ok {
+		return nil, fmt.Errorf("create syslog writer: unknown severity %q", cfg.Severity)
+	}
+
+	var tlsConfig *tls.Config
+	switch cfg.Network {
+	case "udp", "tcp":
+	case "tls":
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		if cfg.TLSCAFile != "" {
+			pem, err := ioutil.ReadFile(cfg.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("create syslog writer: read CA file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, errors.New("create syslog writer: no certificate found in CA file")
+			}
+			tlsConfig.RootCAs = pool
+		}
+	default:
+		return nil, fmt.Errorf("create syslog writer: unknown network %q", cfg.Network)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	w := &syslogLogWriter{
+		network:   cfg.Network,
+		address:   cfg.Address,
+		tlsConfig: tlsConfig,
+		priority:  facility*8 + severity,
+		hostname:  hostname,
+		procID:    fmt.Sprint(os.Getpid()),
+		logType:   logType,
+		now:       time.Now,
+	}
+	// Check that the collector can be reached.
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.dial(); err != nil {
+		return nil, fmt.Errorf("create syslog writer: %w", err)
+	}
+	return w, nil
+}
+
+// dial connects to the collector. w.mu must be held.
+func (w *syslogLogWriter) dial() error {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+	var conn net.Conn
+	var err error
+	if w.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", w.address, w.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(w.network, w.address)
+	}
+	if err != nil {
+		return fmt.Errorf("dial %s %s: %w", w.network, w.address, err)
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *syslogLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, log := range logs {
+		msg := w.message(log)
+		if err := w.send(msg); err != nil {
+			// The collector may have closed the connection, retry once
+			// with a new connection.
+			if w.conn != nil {
+				w.conn.Close()
+				w.conn = nil
+			}
+			if err := w.send(msg); err != nil {
+				return ctxerr.Wrap(ctx, err, "send syslog message")
+			}
+		}
+	}
+	return nil
+}
+
+// send sends the message, connecting to the collector if needed. w.mu must be
+// held.
+func (w *syslogLogWriter) send(msg []byte) error {
+	if w.conn == nil {
+		if err := w.dial(); err != nil {
+			return err
+		}
+	}
+	if w.network != "udp" {
+		// Octet counting framing
+		msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+	}
+	_, err := w.conn.Write(msg)
+	return err
+}
+
+// message returns the RFC 5424 message of the log.
+func (w *syslogLogWriter) message(log json.RawMessage) []byte {
+	msg := fmt.Sprintf(
+		"<%d>1 %s %s %s %s %s [%s log_type=\"%s\"] ",
+		w.priority,
+		w.now().UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		w.hostname,
+		syslogAppName,
+		w.procID,
+		w.logType,
+		syslogSDID,
+		syslogParamValueEscaper.Replace(w.logType),
+	)
+	return append([]byte(msg), strings.TrimRight(string(log), "\n")...)
+}
+
+// syslogParamValueEscaper escapes the characters that must be escaped in the
+// values of the parameters of the structured data.
+var syslogParamValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyslogWriteUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	writer, err := NewSyslogLogWriter(config.SyslogConfig{
+		Network:  "udp",
+		Address:  pc.LocalAddr().String(),
+		Facility: "local3",
+		Severity: "notice",
+	}, "result")
+	require.NoError(t, err)
+	writer.now = func() time.Time { return time.Date(2022, 3, 25, 8, 30, 0, 123456000, time.UTC) }
+	writer.hostname = "fleet-1"
+
+	require.NoError(t, writer.Write(context.Background(), logsWithNewlines))
+
+	buf := make([]byte, 1024)
+	for _, log := range logs {
+		require.NoError(t, pc.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := pc.ReadFrom(buf)
+		require.NoError(t, err)
+		// local3 (19) * 8 + notice (5)
+		assert.Equal(t,
+			fmt.Sprintf(`<157>1 2022-03-25T08:30:00.123456Z fleet-1 fleet %d result [fleet@32473 log_type="result"] %s`, os.Getpid(), log),
+			string(buf[:n]),
+		)
+	}
+}
+
+func TestSyslogWriteTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	messages := make(chan string)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					// octet counting framing
+					length, err := r.ReadString(' ')
+					if err != nil {
+						return
+					}
+					n, err := strconv.Atoi(strings.TrimSpace(length))
+					if err != nil {
+						return
+					}
+					msg := make([]byte, n)
+					if _, err := io.ReadFull(r, msg); err != nil {
+						return
+					}
+					messages <- string(msg)
+				}
+			}()
+		}
+	}()
+
+	writer, err := NewSyslogLogWriter(config.SyslogConfig{
+		Network:  "tcp",
+		Address:  ln.Addr().String(),
+		Facility: "local0",
+		Severity: "info",
+	}, "status")
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(context.Background(), logs))
+	for _, log := range logs {
+		select {
+		case msg := <-messages:
+			// local0 (16) * 8 + info (6)
+			assert.True(t, strings.HasPrefix(msg, "<134>1 "), msg)
+			assert.True(t, strings.HasSuffix(msg, ` status [fleet@32473 log_type="status"] `+string(log)), msg)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for syslog message")
+		}
+	}
+
+	// the writer reconnects when the connection was closed
+	writer.conn.Close()
+	require.NoError(t, writer.Write(context.Background(), logs[:1]))
+	select {
+	case msg := <-messages:
+		assert.True(t, strings.HasSuffix(msg, string(logs[0])), msg)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for syslog message")
+	}
+}
+
+func TestSyslogConfigErrors(t *testing.T) {
+	cases := []config.SyslogConfig{
+		{Network: "udp", Facility: "local0", Severity: "info"},
+		{Network: "udp", Address: "127.0.0.1:514", Facility: "nope", Severity: "info"},
+		{Network: "udp", Address: "127.0.0.1:514", Facility: "local0", Severity: "nope"},
+		{Network: "unix", Address: "127.0.0.1:514", Facility: "local0", Severity: "info"},
+		{Network: "tls", Address: "127.0.0.1:514", Facility: "local0", Severity: "info", TLSCAFile: "/does/not/exist"},
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			_, err := NewSyslogLogWriter(c, "result")
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const webhookLogTypeHeader = "X-Fleet-Log-Type"
+
+// webhookLogWriter posts the logs, in batches of JSON arrays, to an HTTP
+// endpoint.
+//
+// If a secret is configured, the requests are signed with the
+// X-Fleet-Signature header, of the form "sha256=<hex>", where <hex> is the
+// HMAC-SHA256 with the secret of the X-Fleet-Timestamp header (the Unix time
+// of the request), a dot and the body of the request.
+type webhookLogWriter struct {
+	client       *http.Client
+	url          string
+	headers      http.Header
+	secret       string
+	logType      string
+	maxBatchSize int
+	maxRetries   int
+	logger       log.Logger
+	now          func() time.Time
+	// backoff returns the time to wait before the given retry.
+	backoff func(try int) time.Duration
+}
+
+// NewWebhookLogWriter returns a writer posting the logs of type logType
+// (status or result) to the endpoint of cfg.
+func NewWebhookLogWriter(cfg config.WebhookLoggingConfig, logType string, logger log.Logger) (*webhookLogWriter, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("create webhook writer: url is required")
+	}
+	headers, err := parseWebhookHeaders(cfg.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook writer: %w", err)
+	}
+
+	return &webhookLogWriter{
+		client:       fleethttp.NewClient(fleethttp.WithTimeout(cfg.Timeout)),
+		url:          cfg.URL,
+		headers:      headers,
+		secret:       cfg.Secret,
+		logType:      logType,
+		maxBatchSize: cfg.MaxBatchSize,
+		maxRetries:   cfg.MaxRetries,
+		logger:       logger,
+		now:          time.Now,
+		backoff:      exponentialBackoff,
+	}, nil
+}
+
+// parseWebhookHeaders parses the headers, in the format
+// "Name1: value1, Name2: value2".
+func parseWebhookHeaders(s string) (http.Header, error) {
+	headers := make(http.Header)
+	if strings.TrimSpace(s) == "" {
+		return headers, nil
+	}
+	for _, header := range strings.Split(s, ",") {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected <name>: <value>", header)
+		}
+		headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	return headers, nil
+}
+
+func (w *webhookLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	var batch []json.RawMessage
+	// Size of the JSON array of the batch.
+	batchSize := 2
+	for _, log := range logs {
+		if !json.Valid(log) {
+			level.Info(w.logger).Log(
+				"msg", "dropping invalid log",
+				"size", len(log),
+			)
+			continue
+		}
+
+		// Push the batch before it exceeds the maximum size. A log bigger
+		// than the maximum size is sent on its own.
+		if len(batch) > 0 && batchSize+1+len(log) > w.maxBatchSize {
+			if err := w.post(ctx, batch); err != nil {
+				return ctxerr.Wrap(ctx, err, "webhook post logs")
+			}
+			batch, batchSize = nil, 2
+		}
+		if len(batch) > 0 {
+			batchSize++
+		}
+		batch = append(batch, log)
+		batchSize += len(log)
+	}
+
+	// Push the final batch
+	if len(batch) > 0 {
+		if err := w.post(ctx, batch); err != nil {
+			return ctxerr.Wrap(ctx, err, "webhook post logs")
+		}
+	}
+	return nil
+}
+
+// post posts the logs, retrying with backoff when the request fails or the
+// endpoint is unavailable.
+func (w *webhookLogWriter) post(ctx context.Context, logs []json.RawMessage) error {
+	body, err := json.Marshal(logs)
+	if err != nil {
+		return err
+	}
+
+	for try := 0; ; try++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		for name, values := range w.headers {
+			req.Header[name] = values
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(webhookLogTypeHeader, w.logType)
+		if w.secret != "" {
+			timestamp := strconv.FormatInt(w.now().Unix(), 10)
+			req.Header.Set(server.WebhookTimestampHeader, timestamp)
+			req.Header.Set(server.WebhookSignatureHeader, "sha256="+server.WebhookSignature(w.secret, timestamp, body))
+		}
+
+		var retryErr error
+		var retryAfter time.Duration
+		resp, err := w.client.Do(req)
+		if err != nil {
+			retryErr = fmt.Errorf("failed to POST to %s: %w", w.url, err)
+		} else {
+			respBody, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			switch {
+			case resp.StatusCode >= 200 && resp.StatusCode < 300:
+				return nil
+			case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+				retryErr = fmt.Errorf("error posting to %s: %d. %s", w.url, resp.StatusCode, string(respBody))
+				if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+					retryAfter = time.Duration(seconds) * time.Second
+				}
+			default:
+				return fmt.Errorf("error posting to %s: %d. %s", w.url, resp.StatusCode, string(respBody))
+			}
+		}
+
+		if try >= w.maxRetries {
+			return retryErr
+		}
+		// Retry with backoff, or after the time requested by the endpoint.
+		wait := w.backoff(try + 1)
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeWebhookWriter(t *testing.T, url string, cfg config.WebhookLoggingConfig) *webhookLogWriter {
+	cfg.URL = url
+	if cfg.MaxBatchSize == 0 {
+		cfg.MaxBatchSize = 1024 * 1024
+	}
+	cfg.Timeout = 5 * time.Second
+	w, err := NewWebhookLogWriter(cfg, "result", log.NewNopLogger())
+	require.NoError(t, err)
+	w.now = func() time.Time { return time.Unix(1648196000, 0) }
+	w.backoff = func(int) time.Duration { return 0 }
+	return w
+}
+
+func TestWebhookWrite(t *testing.T) {
+	var requests [][]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "result", r.Header.Get("X-Fleet-Log-Type"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		assert.Equal(t, "fleet", r.Header.Get("X-Source"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "1648196000", r.Header.Get("X-Fleet-Timestamp"))
+		assert.Equal(t, "sha256="+server.WebhookSignature("secret", "1648196000", body), r.Header.Get("X-Fleet-Signature"))
+
+		var logs []json.RawMessage
+		require.NoError(t, json.Unmarshal(body, &logs))
+		requests = append(requests, logs)
+	}))
+	defer server.Close()
+
+	// two logs fit in a batch
+	batchSize := len(`[,]`) + len(logsWithNewlines[0]) + len(logsWithNewlines[1])
+	writer := makeWebhookWriter(t, server.URL, config.WebhookLoggingConfig{
+		Headers:      "Authorization: Bearer token, X-Source: fleet",
+		Secret:       "secret",
+		MaxBatchSize: batchSize,
+	})
+
+	// invalid JSON is dropped
+	require.NoError(t, writer.Write(context.Background(), append(logsWithNewlines, json.RawMessage(`{"foo":`))))
+	require.Len(t, requests, 2)
+	assert.Equal(t, logs[:2], requests[0])
+	assert.Equal(t, logs[2:], requests[1])
+}
+
+func TestWebhookSignature(t *testing.T) {
+	// echo -n '1648196000.[{"foo":"bar"}]' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t,
+		"7b908fba01d5ae8c597d0985100510097ef96bab50a9e74633f8e30d409ccb77",
+		server.WebhookSignature("secret", "1648196000", []byte(`[{"foo":"bar"}]`)),
+	)
+}
+
+func TestWebhookWriteRetries(t *testing.T) {
+	var calls int
+	statuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[calls%len(statuses)])
+		calls++
+	}))
+	defer server.Close()
+
+	writer := makeWebhookWriter(t, server.URL, config.WebhookLoggingConfig{MaxRetries: 2})
+	require.NoError(t, writer.Write(context.Background(), logs))
+	assert.Equal(t, 3, calls)
+
+	// retries exhausted
+	calls = 0
+	writer.maxRetries = 1
+	err := writer.Write(context.Background(), logs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+	assert.Equal(t, 2, calls)
+
+	// client errors are not retried
+	calls = 0
+	statuses = []int{http.StatusUnauthorized}
+	err = writer.Write(context.Background(), logs)
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+
+	// connection errors are retried
+	server.Close()
+	err = writer.Write(context.Background(), logs)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to POST")
+}
+
+func TestParseWebhookHeaders(t *testing.T) {
+	headers, err := parseWebhookHeaders("")
+	require.NoError(t, err)
+	assert.Empty(t, headers)
+
+	headers, err = parseWebhookHeaders("Authorization: Basic Zm9vOmJhcg==,x-api-key:abc")
+	require.NoError(t, err)
+	assert.Equal(t, http.Header{"Authorization": {"Basic Zm9vOmJhcg=="}, "X-Api-Key": {"abc"}}, headers)
+
+	for _, invalid := range []string{"Authorization", ": value", "A: b,"} {
+		_, err = parseWebhookHeaders(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
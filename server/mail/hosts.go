@@ -0,0 +1,28 @@
+package mail
+
+import (
+	"bytes"
+	"html/template"
+)
+
+// HostOnlineMailer is used to build the email notifying a user that a host
+// they subscribed to checked in with Fleet.
+type HostOnlineMailer struct {
+	BaseURL  template.URL
+	AssetURL template.URL
+	HostID   uint
+	Hostname string
+}
+
+func (m *HostOnlineMailer) Message() ([]byte, error) {
+	t, err := getTemplate("server/mail/templates/host_online.html")
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	if err = t.Execute(&msg, m); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
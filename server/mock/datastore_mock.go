@@ -74,10 +74,44 @@ type DistributedQueryCampaignTargetIDsFunc func(ctx context.Context, id uint) (t
 
 type NewDistributedQueryCampaignTargetFunc func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error)
 
-type CleanupDistributedQueryCampaignsFunc func(ctx context.Context, now time.Time) (expired uint, err error)
+type CleanupDistributedQueryCampaignsFunc func(ctx context.Context, now time.Time, runningTTL time.Duration) (expired []uint, err error)
 
 type DistributedQueryCampaignsForQueryFunc func(ctx context.Context, queryID uint) ([]*fleet.DistributedQueryCampaign, error)
 
+type CountActiveDistributedQueryCampaignsFunc func(ctx context.Context, userID *uint) (int, error)
+
+type NewBatchQueryFunc func(ctx context.Context, bq *fleet.BatchQuery) error
+
+type BatchQueryFunc func(ctx context.Context, campaignID uint) (*fleet.BatchQuery, error)
+
+type NewBatchQueryResultsFunc func(ctx context.Context, campaignID uint, results []*fleet.BatchQueryResult) (*fleet.BatchQuery, error)
+
+type MarkBatchQueryNotifiedFunc func(ctx context.Context, campaignID uint, now time.Time) (bool, error)
+
+type ListBatchQueryResultsFunc func(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.BatchQueryResult, error)
+
+type CleanupBatchQueriesFunc func(ctx context.Context, now time.Time) error
+
+type NewDistributedQueryExecutionsFunc func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error
+
+type UpdateDistributedQueryExecutionsFunc func(ctx context.Context, campaignID uint, hostIDs []uint, status fleet.DistributedQueryExecutionStatus) error
+
+type DeletePendingDistributedQueryExecutionsFunc func(ctx context.Context, campaignID uint, hostIDs []uint) (uint, error)
+
+type DistributedQueryCampaignProgressFunc func(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaignProgress, error)
+
+type CleanupDistributedQueryExecutionsFunc func(ctx context.Context, now time.Time) error
+
+type RecordLiveQueryCampaignUsageFunc func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error
+
+type RecordLiveQueryRowsUsageFunc func(ctx context.Context, usage []fleet.QueryRowsUsage) error
+
+type ListQueryUsageFunc func(ctx context.Context, opt fleet.QueryUsageOptions) ([]fleet.QueryUsage, error)
+
+type NewLiveQueryAuditLogFunc func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error
+
+type ListLiveQueryAuditLogFunc func(ctx context.Context, opt fleet.LiveQueryAuditLogOptions) ([]*fleet.LiveQueryAuditLog, error)
+
 type ApplyPackSpecsFunc func(ctx context.Context, specs []*fleet.PackSpec) error
 
 type GetPackSpecsFunc func(ctx context.Context) ([]*fleet.PackSpec, error)
@@ -124,6 +158,10 @@ type ListLabelsForHostFunc func(ctx context.Context, hid uint) ([]*fleet.Label,
 
 type ListHostsInLabelFunc func(ctx context.Context, filter fleet.TeamFilter, lid uint, opt fleet.HostListOptions) ([]*fleet.Host, error)
 
+type AddHostsToLabelFunc func(ctx context.Context, lid uint, hostIDs []uint) error
+
+type RemoveHostsFromLabelFunc func(ctx context.Context, lid uint, hostIDs []uint) error
+
 type ListUniqueHostsInLabelsFunc func(ctx context.Context, filter fleet.TeamFilter, labels []uint) ([]*fleet.Host, error)
 
 type SearchLabelsFunc func(ctx context.Context, filter fleet.TeamFilter, query string, omit ...uint) ([]*fleet.Label, error)
@@ -146,13 +184,73 @@ type HostFunc func(ctx context.Context, id uint, skipLoadingExtras bool) (*fleet
 
 type ListHostsFunc func(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions) ([]*fleet.Host, error)
 
+type ListHostChangesFunc func(ctx context.Context, filter fleet.TeamFilter, cursor fleet.HostChangesCursor, limit int) (*fleet.HostChanges, error)
+
+type CleanupHostTombstonesFunc func(ctx context.Context, now time.Time) error
+
+type SetHostAgentOptionsOverrideFunc func(ctx context.Context, override *fleet.HostAgentOptionsOverride) error
+
+type HostAgentOptionsOverrideFunc func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error)
+
+type DeleteHostAgentOptionsOverrideFunc func(ctx context.Context, hostID uint) error
+
+type CleanupExpiredHostAgentOptionsOverridesFunc func(ctx context.Context, now time.Time) error
+
+type ListHostStartupItemsFunc func(ctx context.Context, hostID uint) ([]fleet.HostStartupItem, error)
+
+type ListStartupItemChangesFunc func(ctx context.Context, filter fleet.TeamFilter, opt fleet.StartupItemChangesListOptions) ([]fleet.StartupItemChange, error)
+
+type CleanupStartupItemChangesFunc func(ctx context.Context, now time.Time) error
+
+type ListHostListeningPortsFunc func(ctx context.Context, hostID uint) ([]fleet.HostListeningPort, error)
+
+type ListHostDisksFunc func(ctx context.Context, hostID uint) ([]fleet.HostDisk, error)
+
+type ListHostBatteriesFunc func(ctx context.Context, hostID uint) ([]fleet.HostBattery, error)
+
+type ListHostCertificatesFunc func(ctx context.Context, hostID uint) ([]fleet.HostCertificate, error)
+
+type HostMetadataFunc func(ctx context.Context, hostID uint) (*fleet.HostMetadata, error)
+
+type SetHostMetadataFunc func(ctx context.Context, metadata *fleet.HostMetadata) error
+
+type NewHostActivitiesFunc func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error
+
+type ListHostActivitiesFunc func(ctx context.Context, hostID uint, opt fleet.ListOptions) ([]*fleet.HostActivity, error)
+
+type CleanupHostActivitiesFunc func(ctx context.Context, now time.Time) error
+
+type ListDuplicateHostsFunc func(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.DuplicateHosts, error)
+
+type MergeHostsFunc func(ctx context.Context, hostID uint, duplicateIDs []uint) error
+
+type NewHostOfflineEventsFunc func(ctx context.Context, seenBefore time.Time) error
+
+type MarkHostsReportedOfflineFunc func(ctx context.Context, seenBefore time.Time) error
+
+type ListHostLifecycleEventsFunc func(ctx context.Context, limit int) ([]*fleet.HostLifecycleEvent, error)
+
+type DeleteHostLifecycleEventsFunc func(ctx context.Context, ids []uint) error
+
+type CleanupHostLifecycleEventsFunc func(ctx context.Context, before time.Time) error
+
+type ListeningPortsReportFunc func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListeningPortsReportOptions) ([]fleet.ListeningPortHostsCount, error)
+
+type NewHostOnlineSubscriptionFunc func(ctx context.Context, hostID uint, userID uint) (*fleet.HostOnlineSubscription, error)
+
+type ListHostOnlineSubscriptionsFunc func(ctx context.Context, userID uint) ([]*fleet.HostOnlineSubscription, error)
+
+type DeleteHostOnlineSubscriptionFunc func(ctx context.Context, hostID uint, userID uint) error
+
+type ConsumeHostOnlineSubscriptionsFunc func(ctx context.Context, hostIDs []uint) ([]*fleet.HostOnlineNotification, error)
+
 type MarkHostsSeenFunc func(ctx context.Context, hostIDs []uint, t time.Time) error
 
 type SearchHostsFunc func(ctx context.Context, filter fleet.TeamFilter, query string, omit ...uint) ([]*fleet.Host, error)
 
 type CleanupIncomingHostsFunc func(ctx context.Context, now time.Time) error
 
-type GenerateHostStatusStatisticsFunc func(ctx context.Context, filter fleet.TeamFilter, now time.Time, platform *string) (*fleet.HostSummary, error)
+type GenerateHostStatusStatisticsFunc func(ctx context.Context, filter fleet.TeamFilter, now time.Time, settings fleet.HostSettings, platform *string, labelID *uint) (*fleet.HostSummary, error)
 
 type HostIDsByNameFunc func(ctx context.Context, filter fleet.TeamFilter, hostnames []string) ([]uint, error)
 
@@ -170,10 +268,16 @@ type CountHostsInLabelFunc func(ctx context.Context, filter fleet.TeamFilter, li
 
 type ListHostDeviceMappingFunc func(ctx context.Context, id uint) ([]*fleet.HostDeviceMapping, error)
 
+type ListHostUsersFunc func(ctx context.Context, hostID uint) ([]fleet.HostUser, error)
+
 type LoadHostByDeviceAuthTokenFunc func(ctx context.Context, authToken string) (*fleet.Host, error)
 
 type SetOrUpdateDeviceAuthTokenFunc func(ctx context.Context, hostID uint, authToken string) error
 
+type LoadHostByOrbitNodeKeyFunc func(ctx context.Context, nodeKey string) (*fleet.Host, error)
+
+type SetOrUpdateOrbitNodeKeyFunc func(ctx context.Context, hostID uint, nodeKey string) error
+
 type ListPoliciesForHostFunc func(ctx context.Context, host *fleet.Host) ([]*fleet.HostPolicy, error)
 
 type GetMunkiVersionFunc func(ctx context.Context, hostID uint) (string, error)
@@ -186,13 +290,19 @@ type AggregatedMDMStatusFunc func(ctx context.Context, teamID *uint) (fleet.Aggr
 
 type GenerateAggregatedMunkiAndMDMFunc func(ctx context.Context) error
 
+type ListHostAgentVersionsFunc func(ctx context.Context, hostID uint) ([]*fleet.HostAgentVersion, error)
+
+type HostNetworkSettingsFunc func(ctx context.Context, hostID uint) (*fleet.HostNetworkSettings, error)
+
+type NetworkSettingsReportFunc func(ctx context.Context, filter fleet.TeamFilter) (*fleet.NetworkSettingsReport, error)
+
 type OSVersionsFunc func(ctx context.Context, teamID *uint, platform *string) (*fleet.OSVersions, error)
 
 type UpdateOSVersionsFunc func(ctx context.Context) error
 
-type CountHostsInTargetsFunc func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error)
+type CountHostsInTargetsFunc func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error)
 
-type HostIDsInTargetsFunc func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error)
+type HostIDsInTargetsFunc func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error)
 
 type NewPasswordResetRequestFunc func(ctx context.Context, req *fleet.PasswordResetRequest) (*fleet.PasswordResetRequest, error)
 
@@ -288,6 +398,22 @@ type NewActivityFunc func(ctx context.Context, user *fleet.User, activityType st
 
 type ListActivitiesFunc func(ctx context.Context, opt fleet.ListOptions) ([]*fleet.Activity, error)
 
+type RecordAgentConfigVersionFunc func(ctx context.Context, user *fleet.User) error
+
+type ListAgentConfigVersionsFunc func(ctx context.Context, opt fleet.ListOptions) ([]*fleet.AgentConfigVersion, error)
+
+type AgentConfigVersionFunc func(ctx context.Context, id uint) (*fleet.AgentConfigVersion, error)
+
+type NewAgentOptionsRolloutFunc func(ctx context.Context, rollout *fleet.AgentOptionsRollout) (*fleet.AgentOptionsRollout, error)
+
+type AgentOptionsRolloutFunc func(ctx context.Context, id uint) (*fleet.AgentOptionsRollout, error)
+
+type ActiveAgentOptionsRolloutFunc func(ctx context.Context) (*fleet.AgentOptionsRollout, error)
+
+type ListAgentOptionsRolloutsFunc func(ctx context.Context, opt fleet.ListOptions) ([]*fleet.AgentOptionsRollout, error)
+
+type EndAgentOptionsRolloutFunc func(ctx context.Context, id uint, status string) error
+
 type ShouldSendStatisticsFunc func(ctx context.Context, frequency time.Duration, license *fleet.LicenseInfo) (fleet.StatisticsPayload, bool, error)
 
 type RecordStatisticsSentFunc func(ctx context.Context) error
@@ -300,7 +426,7 @@ type PolicyFunc func(ctx context.Context, id uint) (*fleet.Policy, error)
 
 type SavePolicyFunc func(ctx context.Context, p *fleet.Policy) error
 
-type ListGlobalPoliciesFunc func(ctx context.Context) ([]*fleet.Policy, error)
+type ListGlobalPoliciesFunc func(ctx context.Context, opts fleet.ListOptions) ([]*fleet.Policy, error)
 
 type PoliciesByIDFunc func(ctx context.Context, ids []uint) (map[uint]*fleet.Policy, error)
 
@@ -308,6 +434,8 @@ type DeleteGlobalPoliciesFunc func(ctx context.Context, ids []uint) ([]uint, err
 
 type PolicyQueriesForHostFunc func(ctx context.Context, host *fleet.Host) (map[string]string, error)
 
+type CriticalPolicyQueriesForHostFunc func(ctx context.Context, host *fleet.Host) (map[string]string, error)
+
 type AsyncBatchInsertPolicyMembershipFunc func(ctx context.Context, batch []fleet.PolicyMembershipResult) error
 
 type AsyncBatchUpdatePolicyTimestampFunc func(ctx context.Context, ids []uint, ts time.Time) error
@@ -322,20 +450,100 @@ type ListSoftwareFunc func(ctx context.Context, opt fleet.SoftwareListOptions) (
 
 type CountSoftwareFunc func(ctx context.Context, opt fleet.SoftwareListOptions) (int, error)
 
+type ListHostSoftwareFunc func(ctx context.Context, hostID uint, opt fleet.SoftwareListOptions) ([]fleet.Software, error)
+
 type ListVulnerableSoftwareBySourceFunc func(ctx context.Context, source string) ([]fleet.SoftwareWithCPE, error)
 
 type DeleteVulnerabilitiesByCPECVEFunc func(ctx context.Context, vulnerabilities []fleet.SoftwareVulnerability) error
 
 type NewTeamPolicyFunc func(ctx context.Context, teamID uint, authorID *uint, args fleet.PolicyPayload) (*fleet.Policy, error)
 
-type ListTeamPoliciesFunc func(ctx context.Context, teamID uint) ([]*fleet.Policy, error)
+type ListTeamPoliciesFunc func(ctx context.Context, teamID uint, opts fleet.ListOptions) ([]*fleet.Policy, error)
 
 type DeleteTeamPoliciesFunc func(ctx context.Context, teamID uint, ids []uint) ([]uint, error)
 
 type TeamPolicyFunc func(ctx context.Context, teamID uint, policyID uint) (*fleet.Policy, error)
 
+type TeamPolicyExemptionsFunc func(ctx context.Context, teamID uint) ([]uint, error)
+
+type SetTeamPolicyExemptionsFunc func(ctx context.Context, teamID uint, policyIDs []uint) error
+
 type CleanupPolicyMembershipFunc func(ctx context.Context, now time.Time) error
 
+type UpdatePolicyStatsFunc func(ctx context.Context) error
+
+type UpdateHostIssuesFunc func(ctx context.Context) error
+
+type NewPolicyJiraIssueFunc func(ctx context.Context, policyID uint, issueKey string) error
+
+type NewHostPolicyRemediationFunc func(ctx context.Context, hostID, policyID uint, authorID *uint) (*fleet.HostPolicyRemediation, error)
+
+type QueueHostPolicyAutoRemediationsFunc func(ctx context.Context, hostID uint, failingPolicyIDs []uint) error
+
+type ListHostPolicyRemediationsFunc func(ctx context.Context, hostID uint, opts fleet.ListOptions) ([]*fleet.HostPolicyRemediation, error)
+
+type PendingHostPolicyRemediationsFunc func(ctx context.Context, hostID uint) ([]*fleet.HostPolicyRemediation, error)
+
+type SetHostPolicyRemediationResultFunc func(ctx context.Context, hostID, id uint, exitCode int, output string) error
+
+type PolicyJiraIssueFunc func(ctx context.Context, policyID uint) (*fleet.PolicyJiraIssue, error)
+
+type ListPolicyJiraIssuesFunc func(ctx context.Context) ([]*fleet.PolicyJiraIssue, error)
+
+type DeletePolicyJiraIssueFunc func(ctx context.Context, policyID uint) error
+
+type NewATCTableFunc func(ctx context.Context, table *fleet.ATCTable) (*fleet.ATCTable, error)
+
+type ATCTableFunc func(ctx context.Context, id uint) (*fleet.ATCTable, error)
+
+type ListATCTablesFunc func(ctx context.Context) ([]*fleet.ATCTable, error)
+
+type SaveATCTableFunc func(ctx context.Context, table *fleet.ATCTable) error
+
+type DeleteATCTableFunc func(ctx context.Context, id uint) error
+
+type NewFIMCategoryFunc func(ctx context.Context, category *fleet.FIMCategory) (*fleet.FIMCategory, error)
+
+type FIMCategoryFunc func(ctx context.Context, id uint) (*fleet.FIMCategory, error)
+
+type ListFIMCategoriesFunc func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error)
+
+type ListFIMCategoriesForHostFunc func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error)
+
+type SaveFIMCategoryFunc func(ctx context.Context, category *fleet.FIMCategory) error
+
+type DeleteFIMCategoryFunc func(ctx context.Context, id uint) error
+
+type NewYARASignatureGroupFunc func(ctx context.Context, group *fleet.YARASignatureGroup) (*fleet.YARASignatureGroup, error)
+
+type YARASignatureGroupFunc func(ctx context.Context, id uint) (*fleet.YARASignatureGroup, error)
+
+type ListYARASignatureGroupsFunc func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error)
+
+type ListYARASignatureGroupsForHostFunc func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error)
+
+type SaveYARASignatureGroupFunc func(ctx context.Context, group *fleet.YARASignatureGroup) error
+
+type DeleteYARASignatureGroupFunc func(ctx context.Context, id uint) error
+
+type NewDashboardFunc func(ctx context.Context, dashboard *fleet.Dashboard) (*fleet.Dashboard, error)
+
+type DashboardFunc func(ctx context.Context, id uint) (*fleet.Dashboard, error)
+
+type ListDashboardsFunc func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.Dashboard, error)
+
+type SaveDashboardFunc func(ctx context.Context, dashboard *fleet.Dashboard) error
+
+type DeleteDashboardFunc func(ctx context.Context, id uint) error
+
+type ListIndicatorsToLookupFunc func(ctx context.Context, checkedBefore time.Time, limit int) ([]fleet.Indicator, error)
+
+type SaveThreatIntelVerdictsFunc func(ctx context.Context, verdicts []fleet.ThreatIntelVerdict) error
+
+type UpdateHostThreatFindingsFunc func(ctx context.Context) error
+
+type ListHostThreatFindingsFunc func(ctx context.Context, hostID uint) ([]*fleet.HostThreatFinding, error)
+
 type LockFunc func(ctx context.Context, name string, owner string, expiration time.Duration) (bool, error)
 
 type UnlockFunc func(ctx context.Context, name string, owner string) error
@@ -354,20 +562,30 @@ type UpdateHostOsqueryIntervalsFunc func(ctx context.Context, hostID uint, inter
 
 type TeamAgentOptionsFunc func(ctx context.Context, teamID uint) (*json.RawMessage, error)
 
+type TeamLogSettingsFunc func(ctx context.Context, teamID uint) (*fleet.TeamLogSettings, error)
+
 type SaveHostPackStatsFunc func(ctx context.Context, hostID uint, stats []fleet.PackStats) error
 
 type UpdateHostSoftwareFunc func(ctx context.Context, hostID uint, software []fleet.Software) error
 
 type UpdateHostFunc func(ctx context.Context, host *fleet.Host) error
 
+type UpdateHostsDetailsFunc func(ctx context.Context, hosts []*fleet.Host) error
+
+type UpdateHostDisplayNamesFunc func(ctx context.Context, sources []string) error
+
 type ListScheduledQueriesInPackFunc func(ctx context.Context, packID uint) ([]*fleet.ScheduledQuery, error)
 
+type ListDiscardDataScheduledQueryNamesFunc func(ctx context.Context) ([]fleet.PackScheduledQueryName, error)
+
 type UpdateHostRefetchRequestedFunc func(ctx context.Context, hostID uint, value bool) error
 
 type FlippingPoliciesForHostFunc func(ctx context.Context, hostID uint, incomingResults map[uint]*bool) (newFailing []uint, newPassing []uint, err error)
 
 type RecordPolicyQueryExecutionsFunc func(ctx context.Context, host *fleet.Host, results map[uint]*bool, updated time.Time, deferredSaveHost bool) error
 
+type RecordCriticalPolicyQueryExecutionsFunc func(ctx context.Context, host *fleet.Host, results map[uint]*bool, updated time.Time) error
+
 type RecordLabelQueryExecutionsFunc func(ctx context.Context, host *fleet.Host, results map[uint]*bool, t time.Time, deferredSaveHost bool) error
 
 type SaveHostUsersFunc func(ctx context.Context, hostID uint, users []fleet.HostUser) error
@@ -378,11 +596,35 @@ type SetOrUpdateMunkiVersionFunc func(ctx context.Context, hostID uint, version
 
 type SetOrUpdateMDMDataFunc func(ctx context.Context, hostID uint, enrolled bool, serverURL string, installedFromDep bool) error
 
+type SetOrUpdateHostAgentVersionFunc func(ctx context.Context, hostID uint, component string, version string) error
+
 type ReplaceHostDeviceMappingFunc func(ctx context.Context, id uint, mappings []*fleet.HostDeviceMapping) error
 
+type SetOrUpdateCustomHostDeviceMappingFunc func(ctx context.Context, hostID uint, email string) ([]*fleet.HostDeviceMapping, error)
+
+type ReplaceHostDNSServersFunc func(ctx context.Context, hostID uint, addresses []string) error
+
+type ReplaceHostNetworkAddressesFunc func(ctx context.Context, hostID uint, addresses []fleet.HostNetworkAddress) error
+
+type ReplaceHostProxiesFunc func(ctx context.Context, hostID uint, proxies []fleet.HostProxy) error
+
+type ReplaceHostStartupItemsFunc func(ctx context.Context, hostID uint, items []fleet.HostStartupItem) error
+
+type ReplaceHostListeningPortsFunc func(ctx context.Context, hostID uint, ports []fleet.HostListeningPort) error
+
+type ReplaceHostDisksFunc func(ctx context.Context, hostID uint, disks []fleet.HostDisk) error
+
+type ReplaceHostBatteriesFunc func(ctx context.Context, hostID uint, batteries []fleet.HostBattery) error
+
+type ReplaceHostCertificatesFunc func(ctx context.Context, hostID uint, certs []fleet.HostCertificate) error
+
 type VerifyEnrollSecretFunc func(ctx context.Context, secret string) (*fleet.EnrollSecret, error)
 
-type EnrollHostFunc func(ctx context.Context, osqueryHostId string, nodeKey string, teamID *uint, cooldown time.Duration) (*fleet.Host, error)
+type RecordEnrollmentOutcomesFunc func(ctx context.Context, counts []fleet.EnrollmentOutcomeCount) error
+
+type EnrollmentStatsFunc func(ctx context.Context, since time.Time) (*fleet.EnrollmentStats, error)
+
+type EnrollHostFunc func(ctx context.Context, osqueryHostId string, nodeKey string, teamID *uint, cooldown time.Duration, consumeSecret string) (*fleet.Host, error)
 
 type SerialUpdateHostFunc func(ctx context.Context, host *fleet.Host) error
 
@@ -490,6 +732,57 @@ type DataStore struct {
 	DistributedQueryCampaignsForQueryFunc        DistributedQueryCampaignsForQueryFunc
 	DistributedQueryCampaignsForQueryFuncInvoked bool
 
+	CountActiveDistributedQueryCampaignsFunc        CountActiveDistributedQueryCampaignsFunc
+	CountActiveDistributedQueryCampaignsFuncInvoked bool
+
+	NewBatchQueryFunc        NewBatchQueryFunc
+	NewBatchQueryFuncInvoked bool
+
+	BatchQueryFunc        BatchQueryFunc
+	BatchQueryFuncInvoked bool
+
+	NewBatchQueryResultsFunc        NewBatchQueryResultsFunc
+	NewBatchQueryResultsFuncInvoked bool
+
+	MarkBatchQueryNotifiedFunc        MarkBatchQueryNotifiedFunc
+	MarkBatchQueryNotifiedFuncInvoked bool
+
+	ListBatchQueryResultsFunc        ListBatchQueryResultsFunc
+	ListBatchQueryResultsFuncInvoked bool
+
+	CleanupBatchQueriesFunc        CleanupBatchQueriesFunc
+	CleanupBatchQueriesFuncInvoked bool
+
+	NewDistributedQueryExecutionsFunc        NewDistributedQueryExecutionsFunc
+	NewDistributedQueryExecutionsFuncInvoked bool
+
+	UpdateDistributedQueryExecutionsFunc        UpdateDistributedQueryExecutionsFunc
+	UpdateDistributedQueryExecutionsFuncInvoked bool
+
+	DeletePendingDistributedQueryExecutionsFunc        DeletePendingDistributedQueryExecutionsFunc
+	DeletePendingDistributedQueryExecutionsFuncInvoked bool
+
+	DistributedQueryCampaignProgressFunc        DistributedQueryCampaignProgressFunc
+	DistributedQueryCampaignProgressFuncInvoked bool
+
+	CleanupDistributedQueryExecutionsFunc        CleanupDistributedQueryExecutionsFunc
+	CleanupDistributedQueryExecutionsFuncInvoked bool
+
+	RecordLiveQueryCampaignUsageFunc        RecordLiveQueryCampaignUsageFunc
+	RecordLiveQueryCampaignUsageFuncInvoked bool
+
+	RecordLiveQueryRowsUsageFunc        RecordLiveQueryRowsUsageFunc
+	RecordLiveQueryRowsUsageFuncInvoked bool
+
+	ListQueryUsageFunc        ListQueryUsageFunc
+	ListQueryUsageFuncInvoked bool
+
+	NewLiveQueryAuditLogFunc        NewLiveQueryAuditLogFunc
+	NewLiveQueryAuditLogFuncInvoked bool
+
+	ListLiveQueryAuditLogFunc        ListLiveQueryAuditLogFunc
+	ListLiveQueryAuditLogFuncInvoked bool
+
 	ApplyPackSpecsFunc        ApplyPackSpecsFunc
 	ApplyPackSpecsFuncInvoked bool
 
@@ -559,6 +852,12 @@ type DataStore struct {
 	ListHostsInLabelFunc        ListHostsInLabelFunc
 	ListHostsInLabelFuncInvoked bool
 
+	AddHostsToLabelFunc        AddHostsToLabelFunc
+	AddHostsToLabelFuncInvoked bool
+
+	RemoveHostsFromLabelFunc        RemoveHostsFromLabelFunc
+	RemoveHostsFromLabelFuncInvoked bool
+
 	ListUniqueHostsInLabelsFunc        ListUniqueHostsInLabelsFunc
 	ListUniqueHostsInLabelsFuncInvoked bool
 
@@ -592,6 +891,96 @@ type DataStore struct {
 	ListHostsFunc        ListHostsFunc
 	ListHostsFuncInvoked bool
 
+	ListHostChangesFunc        ListHostChangesFunc
+	ListHostChangesFuncInvoked bool
+
+	CleanupHostTombstonesFunc        CleanupHostTombstonesFunc
+	CleanupHostTombstonesFuncInvoked bool
+
+	SetHostAgentOptionsOverrideFunc        SetHostAgentOptionsOverrideFunc
+	SetHostAgentOptionsOverrideFuncInvoked bool
+
+	HostAgentOptionsOverrideFunc        HostAgentOptionsOverrideFunc
+	HostAgentOptionsOverrideFuncInvoked bool
+
+	DeleteHostAgentOptionsOverrideFunc        DeleteHostAgentOptionsOverrideFunc
+	DeleteHostAgentOptionsOverrideFuncInvoked bool
+
+	CleanupExpiredHostAgentOptionsOverridesFunc        CleanupExpiredHostAgentOptionsOverridesFunc
+	CleanupExpiredHostAgentOptionsOverridesFuncInvoked bool
+
+	ListHostStartupItemsFunc        ListHostStartupItemsFunc
+	ListHostStartupItemsFuncInvoked bool
+
+	ListStartupItemChangesFunc        ListStartupItemChangesFunc
+	ListStartupItemChangesFuncInvoked bool
+
+	CleanupStartupItemChangesFunc        CleanupStartupItemChangesFunc
+	CleanupStartupItemChangesFuncInvoked bool
+
+	ListHostListeningPortsFunc        ListHostListeningPortsFunc
+	ListHostListeningPortsFuncInvoked bool
+
+	ListHostDisksFunc        ListHostDisksFunc
+	ListHostDisksFuncInvoked bool
+
+	ListHostBatteriesFunc        ListHostBatteriesFunc
+	ListHostBatteriesFuncInvoked bool
+
+	ListHostCertificatesFunc        ListHostCertificatesFunc
+	ListHostCertificatesFuncInvoked bool
+
+	HostMetadataFunc        HostMetadataFunc
+	HostMetadataFuncInvoked bool
+
+	SetHostMetadataFunc        SetHostMetadataFunc
+	SetHostMetadataFuncInvoked bool
+
+	NewHostActivitiesFunc        NewHostActivitiesFunc
+	NewHostActivitiesFuncInvoked bool
+
+	ListHostActivitiesFunc        ListHostActivitiesFunc
+	ListHostActivitiesFuncInvoked bool
+
+	CleanupHostActivitiesFunc        CleanupHostActivitiesFunc
+	CleanupHostActivitiesFuncInvoked bool
+
+	ListDuplicateHostsFunc        ListDuplicateHostsFunc
+	ListDuplicateHostsFuncInvoked bool
+
+	MergeHostsFunc        MergeHostsFunc
+	MergeHostsFuncInvoked bool
+
+	NewHostOfflineEventsFunc        NewHostOfflineEventsFunc
+	NewHostOfflineEventsFuncInvoked bool
+
+	MarkHostsReportedOfflineFunc        MarkHostsReportedOfflineFunc
+	MarkHostsReportedOfflineFuncInvoked bool
+
+	ListHostLifecycleEventsFunc        ListHostLifecycleEventsFunc
+	ListHostLifecycleEventsFuncInvoked bool
+
+	DeleteHostLifecycleEventsFunc        DeleteHostLifecycleEventsFunc
+	DeleteHostLifecycleEventsFuncInvoked bool
+
+	CleanupHostLifecycleEventsFunc        CleanupHostLifecycleEventsFunc
+	CleanupHostLifecycleEventsFuncInvoked bool
+
+	ListeningPortsReportFunc        ListeningPortsReportFunc
+	ListeningPortsReportFuncInvoked bool
+
+	NewHostOnlineSubscriptionFunc        NewHostOnlineSubscriptionFunc
+	NewHostOnlineSubscriptionFuncInvoked bool
+
+	ListHostOnlineSubscriptionsFunc        ListHostOnlineSubscriptionsFunc
+	ListHostOnlineSubscriptionsFuncInvoked bool
+
+	DeleteHostOnlineSubscriptionFunc        DeleteHostOnlineSubscriptionFunc
+	DeleteHostOnlineSubscriptionFuncInvoked bool
+
+	ConsumeHostOnlineSubscriptionsFunc        ConsumeHostOnlineSubscriptionsFunc
+	ConsumeHostOnlineSubscriptionsFuncInvoked bool
+
 	MarkHostsSeenFunc        MarkHostsSeenFunc
 	MarkHostsSeenFuncInvoked bool
 
@@ -628,12 +1017,21 @@ type DataStore struct {
 	ListHostDeviceMappingFunc        ListHostDeviceMappingFunc
 	ListHostDeviceMappingFuncInvoked bool
 
+	ListHostUsersFunc        ListHostUsersFunc
+	ListHostUsersFuncInvoked bool
+
 	LoadHostByDeviceAuthTokenFunc        LoadHostByDeviceAuthTokenFunc
 	LoadHostByDeviceAuthTokenFuncInvoked bool
 
 	SetOrUpdateDeviceAuthTokenFunc        SetOrUpdateDeviceAuthTokenFunc
 	SetOrUpdateDeviceAuthTokenFuncInvoked bool
 
+	LoadHostByOrbitNodeKeyFunc        LoadHostByOrbitNodeKeyFunc
+	LoadHostByOrbitNodeKeyFuncInvoked bool
+
+	SetOrUpdateOrbitNodeKeyFunc        SetOrUpdateOrbitNodeKeyFunc
+	SetOrUpdateOrbitNodeKeyFuncInvoked bool
+
 	ListPoliciesForHostFunc        ListPoliciesForHostFunc
 	ListPoliciesForHostFuncInvoked bool
 
@@ -652,6 +1050,15 @@ type DataStore struct {
 	GenerateAggregatedMunkiAndMDMFunc        GenerateAggregatedMunkiAndMDMFunc
 	GenerateAggregatedMunkiAndMDMFuncInvoked bool
 
+	ListHostAgentVersionsFunc        ListHostAgentVersionsFunc
+	ListHostAgentVersionsFuncInvoked bool
+
+	HostNetworkSettingsFunc        HostNetworkSettingsFunc
+	HostNetworkSettingsFuncInvoked bool
+
+	NetworkSettingsReportFunc        NetworkSettingsReportFunc
+	NetworkSettingsReportFuncInvoked bool
+
 	OSVersionsFunc        OSVersionsFunc
 	OSVersionsFuncInvoked bool
 
@@ -805,6 +1212,30 @@ type DataStore struct {
 	ListActivitiesFunc        ListActivitiesFunc
 	ListActivitiesFuncInvoked bool
 
+	RecordAgentConfigVersionFunc        RecordAgentConfigVersionFunc
+	RecordAgentConfigVersionFuncInvoked bool
+
+	ListAgentConfigVersionsFunc        ListAgentConfigVersionsFunc
+	ListAgentConfigVersionsFuncInvoked bool
+
+	AgentConfigVersionFunc        AgentConfigVersionFunc
+	AgentConfigVersionFuncInvoked bool
+
+	NewAgentOptionsRolloutFunc        NewAgentOptionsRolloutFunc
+	NewAgentOptionsRolloutFuncInvoked bool
+
+	AgentOptionsRolloutFunc        AgentOptionsRolloutFunc
+	AgentOptionsRolloutFuncInvoked bool
+
+	ActiveAgentOptionsRolloutFunc        ActiveAgentOptionsRolloutFunc
+	ActiveAgentOptionsRolloutFuncInvoked bool
+
+	ListAgentOptionsRolloutsFunc        ListAgentOptionsRolloutsFunc
+	ListAgentOptionsRolloutsFuncInvoked bool
+
+	EndAgentOptionsRolloutFunc        EndAgentOptionsRolloutFunc
+	EndAgentOptionsRolloutFuncInvoked bool
+
 	ShouldSendStatisticsFunc        ShouldSendStatisticsFunc
 	ShouldSendStatisticsFuncInvoked bool
 
@@ -835,6 +1266,9 @@ type DataStore struct {
 	PolicyQueriesForHostFunc        PolicyQueriesForHostFunc
 	PolicyQueriesForHostFuncInvoked bool
 
+	CriticalPolicyQueriesForHostFunc        CriticalPolicyQueriesForHostFunc
+	CriticalPolicyQueriesForHostFuncInvoked bool
+
 	AsyncBatchInsertPolicyMembershipFunc        AsyncBatchInsertPolicyMembershipFunc
 	AsyncBatchInsertPolicyMembershipFuncInvoked bool
 
@@ -856,6 +1290,9 @@ type DataStore struct {
 	CountSoftwareFunc        CountSoftwareFunc
 	CountSoftwareFuncInvoked bool
 
+	ListHostSoftwareFunc        ListHostSoftwareFunc
+	ListHostSoftwareFuncInvoked bool
+
 	ListVulnerableSoftwareBySourceFunc        ListVulnerableSoftwareBySourceFunc
 	ListVulnerableSoftwareBySourceFuncInvoked bool
 
@@ -874,9 +1311,126 @@ type DataStore struct {
 	TeamPolicyFunc        TeamPolicyFunc
 	TeamPolicyFuncInvoked bool
 
+	TeamPolicyExemptionsFunc        TeamPolicyExemptionsFunc
+	TeamPolicyExemptionsFuncInvoked bool
+
+	SetTeamPolicyExemptionsFunc        SetTeamPolicyExemptionsFunc
+	SetTeamPolicyExemptionsFuncInvoked bool
+
 	CleanupPolicyMembershipFunc        CleanupPolicyMembershipFunc
 	CleanupPolicyMembershipFuncInvoked bool
 
+	UpdatePolicyStatsFunc        UpdatePolicyStatsFunc
+	UpdatePolicyStatsFuncInvoked bool
+
+	UpdateHostIssuesFunc        UpdateHostIssuesFunc
+	UpdateHostIssuesFuncInvoked bool
+
+	NewPolicyJiraIssueFunc        NewPolicyJiraIssueFunc
+	NewPolicyJiraIssueFuncInvoked bool
+
+	NewHostPolicyRemediationFunc        NewHostPolicyRemediationFunc
+	NewHostPolicyRemediationFuncInvoked bool
+
+	QueueHostPolicyAutoRemediationsFunc        QueueHostPolicyAutoRemediationsFunc
+	QueueHostPolicyAutoRemediationsFuncInvoked bool
+
+	ListHostPolicyRemediationsFunc        ListHostPolicyRemediationsFunc
+	ListHostPolicyRemediationsFuncInvoked bool
+
+	PendingHostPolicyRemediationsFunc        PendingHostPolicyRemediationsFunc
+	PendingHostPolicyRemediationsFuncInvoked bool
+
+	SetHostPolicyRemediationResultFunc        SetHostPolicyRemediationResultFunc
+	SetHostPolicyRemediationResultFuncInvoked bool
+
+	PolicyJiraIssueFunc        PolicyJiraIssueFunc
+	PolicyJiraIssueFuncInvoked bool
+
+	ListPolicyJiraIssuesFunc        ListPolicyJiraIssuesFunc
+	ListPolicyJiraIssuesFuncInvoked bool
+
+	DeletePolicyJiraIssueFunc        DeletePolicyJiraIssueFunc
+	DeletePolicyJiraIssueFuncInvoked bool
+
+	NewATCTableFunc        NewATCTableFunc
+	NewATCTableFuncInvoked bool
+
+	ATCTableFunc        ATCTableFunc
+	ATCTableFuncInvoked bool
+
+	ListATCTablesFunc        ListATCTablesFunc
+	ListATCTablesFuncInvoked bool
+
+	SaveATCTableFunc        SaveATCTableFunc
+	SaveATCTableFuncInvoked bool
+
+	DeleteATCTableFunc        DeleteATCTableFunc
+	DeleteATCTableFuncInvoked bool
+
+	NewFIMCategoryFunc        NewFIMCategoryFunc
+	NewFIMCategoryFuncInvoked bool
+
+	FIMCategoryFunc        FIMCategoryFunc
+	FIMCategoryFuncInvoked bool
+
+	ListFIMCategoriesFunc        ListFIMCategoriesFunc
+	ListFIMCategoriesFuncInvoked bool
+
+	ListFIMCategoriesForHostFunc        ListFIMCategoriesForHostFunc
+	ListFIMCategoriesForHostFuncInvoked bool
+
+	SaveFIMCategoryFunc        SaveFIMCategoryFunc
+	SaveFIMCategoryFuncInvoked bool
+
+	DeleteFIMCategoryFunc        DeleteFIMCategoryFunc
+	DeleteFIMCategoryFuncInvoked bool
+
+	NewYARASignatureGroupFunc        NewYARASignatureGroupFunc
+	NewYARASignatureGroupFuncInvoked bool
+
+	YARASignatureGroupFunc        YARASignatureGroupFunc
+	YARASignatureGroupFuncInvoked bool
+
+	ListYARASignatureGroupsFunc        ListYARASignatureGroupsFunc
+	ListYARASignatureGroupsFuncInvoked bool
+
+	ListYARASignatureGroupsForHostFunc        ListYARASignatureGroupsForHostFunc
+	ListYARASignatureGroupsForHostFuncInvoked bool
+
+	SaveYARASignatureGroupFunc        SaveYARASignatureGroupFunc
+	SaveYARASignatureGroupFuncInvoked bool
+
+	DeleteYARASignatureGroupFunc        DeleteYARASignatureGroupFunc
+	DeleteYARASignatureGroupFuncInvoked bool
+
+	NewDashboardFunc        NewDashboardFunc
+	NewDashboardFuncInvoked bool
+
+	DashboardFunc        DashboardFunc
+	DashboardFuncInvoked bool
+
+	ListDashboardsFunc        ListDashboardsFunc
+	ListDashboardsFuncInvoked bool
+
+	SaveDashboardFunc        SaveDashboardFunc
+	SaveDashboardFuncInvoked bool
+
+	DeleteDashboardFunc        DeleteDashboardFunc
+	DeleteDashboardFuncInvoked bool
+
+	ListIndicatorsToLookupFunc        ListIndicatorsToLookupFunc
+	ListIndicatorsToLookupFuncInvoked bool
+
+	SaveThreatIntelVerdictsFunc        SaveThreatIntelVerdictsFunc
+	SaveThreatIntelVerdictsFuncInvoked bool
+
+	UpdateHostThreatFindingsFunc        UpdateHostThreatFindingsFunc
+	UpdateHostThreatFindingsFuncInvoked bool
+
+	ListHostThreatFindingsFunc        ListHostThreatFindingsFunc
+	ListHostThreatFindingsFuncInvoked bool
+
 	LockFunc        LockFunc
 	LockFuncInvoked bool
 
@@ -904,6 +1458,9 @@ type DataStore struct {
 	TeamAgentOptionsFunc        TeamAgentOptionsFunc
 	TeamAgentOptionsFuncInvoked bool
 
+	TeamLogSettingsFunc        TeamLogSettingsFunc
+	TeamLogSettingsFuncInvoked bool
+
 	SaveHostPackStatsFunc        SaveHostPackStatsFunc
 	SaveHostPackStatsFuncInvoked bool
 
@@ -913,9 +1470,18 @@ type DataStore struct {
 	UpdateHostFunc        UpdateHostFunc
 	UpdateHostFuncInvoked bool
 
+	UpdateHostsDetailsFunc        UpdateHostsDetailsFunc
+	UpdateHostsDetailsFuncInvoked bool
+
+	UpdateHostDisplayNamesFunc        UpdateHostDisplayNamesFunc
+	UpdateHostDisplayNamesFuncInvoked bool
+
 	ListScheduledQueriesInPackFunc        ListScheduledQueriesInPackFunc
 	ListScheduledQueriesInPackFuncInvoked bool
 
+	ListDiscardDataScheduledQueryNamesFunc        ListDiscardDataScheduledQueryNamesFunc
+	ListDiscardDataScheduledQueryNamesFuncInvoked bool
+
 	UpdateHostRefetchRequestedFunc        UpdateHostRefetchRequestedFunc
 	UpdateHostRefetchRequestedFuncInvoked bool
 
@@ -925,6 +1491,9 @@ type DataStore struct {
 	RecordPolicyQueryExecutionsFunc        RecordPolicyQueryExecutionsFunc
 	RecordPolicyQueryExecutionsFuncInvoked bool
 
+	RecordCriticalPolicyQueryExecutionsFunc        RecordCriticalPolicyQueryExecutionsFunc
+	RecordCriticalPolicyQueryExecutionsFuncInvoked bool
+
 	RecordLabelQueryExecutionsFunc        RecordLabelQueryExecutionsFunc
 	RecordLabelQueryExecutionsFuncInvoked bool
 
@@ -940,12 +1509,48 @@ type DataStore struct {
 	SetOrUpdateMDMDataFunc        SetOrUpdateMDMDataFunc
 	SetOrUpdateMDMDataFuncInvoked bool
 
+	SetOrUpdateHostAgentVersionFunc        SetOrUpdateHostAgentVersionFunc
+	SetOrUpdateHostAgentVersionFuncInvoked bool
+
 	ReplaceHostDeviceMappingFunc        ReplaceHostDeviceMappingFunc
 	ReplaceHostDeviceMappingFuncInvoked bool
 
+	SetOrUpdateCustomHostDeviceMappingFunc        SetOrUpdateCustomHostDeviceMappingFunc
+	SetOrUpdateCustomHostDeviceMappingFuncInvoked bool
+
+	ReplaceHostDNSServersFunc        ReplaceHostDNSServersFunc
+	ReplaceHostDNSServersFuncInvoked bool
+
+	ReplaceHostNetworkAddressesFunc        ReplaceHostNetworkAddressesFunc
+	ReplaceHostNetworkAddressesFuncInvoked bool
+
+	ReplaceHostProxiesFunc        ReplaceHostProxiesFunc
+	ReplaceHostProxiesFuncInvoked bool
+
+	ReplaceHostStartupItemsFunc        ReplaceHostStartupItemsFunc
+	ReplaceHostStartupItemsFuncInvoked bool
+
+	ReplaceHostListeningPortsFunc        ReplaceHostListeningPortsFunc
+	ReplaceHostListeningPortsFuncInvoked bool
+
+	ReplaceHostDisksFunc        ReplaceHostDisksFunc
+	ReplaceHostDisksFuncInvoked bool
+
+	ReplaceHostBatteriesFunc        ReplaceHostBatteriesFunc
+	ReplaceHostBatteriesFuncInvoked bool
+
+	ReplaceHostCertificatesFunc        ReplaceHostCertificatesFunc
+	ReplaceHostCertificatesFuncInvoked bool
+
 	VerifyEnrollSecretFunc        VerifyEnrollSecretFunc
 	VerifyEnrollSecretFuncInvoked bool
 
+	RecordEnrollmentOutcomesFunc        RecordEnrollmentOutcomesFunc
+	RecordEnrollmentOutcomesFuncInvoked bool
+
+	EnrollmentStatsFunc        EnrollmentStatsFunc
+	EnrollmentStatsFuncInvoked bool
+
 	EnrollHostFunc        EnrollHostFunc
 	EnrollHostFuncInvoked bool
 
@@ -1114,9 +1719,9 @@ func (s *DataStore) NewDistributedQueryCampaignTarget(ctx context.Context, targe
 	return s.NewDistributedQueryCampaignTargetFunc(ctx, target)
 }
 
-func (s *DataStore) CleanupDistributedQueryCampaigns(ctx context.Context, now time.Time) (expired uint, err error) {
+func (s *DataStore) CleanupDistributedQueryCampaigns(ctx context.Context, now time.Time, runningTTL time.Duration) (expired []uint, err error) {
 	s.CleanupDistributedQueryCampaignsFuncInvoked = true
-	return s.CleanupDistributedQueryCampaignsFunc(ctx, now)
+	return s.CleanupDistributedQueryCampaignsFunc(ctx, now, runningTTL)
 }
 
 func (s *DataStore) DistributedQueryCampaignsForQuery(ctx context.Context, queryID uint) ([]*fleet.DistributedQueryCampaign, error) {
@@ -1124,6 +1729,91 @@ func (s *DataStore) DistributedQueryCampaignsForQuery(ctx context.Context, query
 	return s.DistributedQueryCampaignsForQueryFunc(ctx, queryID)
 }
 
+func (s *DataStore) CountActiveDistributedQueryCampaigns(ctx context.Context, userID *uint) (int, error) {
+	s.CountActiveDistributedQueryCampaignsFuncInvoked = true
+	return s.CountActiveDistributedQueryCampaignsFunc(ctx, userID)
+}
+
+func (s *DataStore) NewBatchQuery(ctx context.Context, bq *fleet.BatchQuery) error {
+	s.NewBatchQueryFuncInvoked = true
+	return s.NewBatchQueryFunc(ctx, bq)
+}
+
+func (s *DataStore) BatchQuery(ctx context.Context, campaignID uint) (*fleet.BatchQuery, error) {
+	s.BatchQueryFuncInvoked = true
+	return s.BatchQueryFunc(ctx, campaignID)
+}
+
+func (s *DataStore) NewBatchQueryResults(ctx context.Context, campaignID uint, results []*fleet.BatchQueryResult) (*fleet.BatchQuery, error) {
+	s.NewBatchQueryResultsFuncInvoked = true
+	return s.NewBatchQueryResultsFunc(ctx, campaignID, results)
+}
+
+func (s *DataStore) MarkBatchQueryNotified(ctx context.Context, campaignID uint, now time.Time) (bool, error) {
+	s.MarkBatchQueryNotifiedFuncInvoked = true
+	return s.MarkBatchQueryNotifiedFunc(ctx, campaignID, now)
+}
+
+func (s *DataStore) ListBatchQueryResults(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.BatchQueryResult, error) {
+	s.ListBatchQueryResultsFuncInvoked = true
+	return s.ListBatchQueryResultsFunc(ctx, campaignID, opt)
+}
+
+func (s *DataStore) CleanupBatchQueries(ctx context.Context, now time.Time) error {
+	s.CleanupBatchQueriesFuncInvoked = true
+	return s.CleanupBatchQueriesFunc(ctx, now)
+}
+
+func (s *DataStore) NewDistributedQueryExecutions(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+	s.NewDistributedQueryExecutionsFuncInvoked = true
+	return s.NewDistributedQueryExecutionsFunc(ctx, campaignID, hostIDs, now, settings)
+}
+
+func (s *DataStore) UpdateDistributedQueryExecutions(ctx context.Context, campaignID uint, hostIDs []uint, status fleet.DistributedQueryExecutionStatus) error {
+	s.UpdateDistributedQueryExecutionsFuncInvoked = true
+	return s.UpdateDistributedQueryExecutionsFunc(ctx, campaignID, hostIDs, status)
+}
+
+func (s *DataStore) DeletePendingDistributedQueryExecutions(ctx context.Context, campaignID uint, hostIDs []uint) (uint, error) {
+	s.DeletePendingDistributedQueryExecutionsFuncInvoked = true
+	return s.DeletePendingDistributedQueryExecutionsFunc(ctx, campaignID, hostIDs)
+}
+
+func (s *DataStore) DistributedQueryCampaignProgress(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaignProgress, error) {
+	s.DistributedQueryCampaignProgressFuncInvoked = true
+	return s.DistributedQueryCampaignProgressFunc(ctx, campaignID)
+}
+
+func (s *DataStore) CleanupDistributedQueryExecutions(ctx context.Context, now time.Time) error {
+	s.CleanupDistributedQueryExecutionsFuncInvoked = true
+	return s.CleanupDistributedQueryExecutionsFunc(ctx, now)
+}
+
+func (s *DataStore) RecordLiveQueryCampaignUsage(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+	s.RecordLiveQueryCampaignUsageFuncInvoked = true
+	return s.RecordLiveQueryCampaignUsageFunc(ctx, userID, hostIDs, now)
+}
+
+func (s *DataStore) RecordLiveQueryRowsUsage(ctx context.Context, usage []fleet.QueryRowsUsage) error {
+	s.RecordLiveQueryRowsUsageFuncInvoked = true
+	return s.RecordLiveQueryRowsUsageFunc(ctx, usage)
+}
+
+func (s *DataStore) ListQueryUsage(ctx context.Context, opt fleet.QueryUsageOptions) ([]fleet.QueryUsage, error) {
+	s.ListQueryUsageFuncInvoked = true
+	return s.ListQueryUsageFunc(ctx, opt)
+}
+
+func (s *DataStore) NewLiveQueryAuditLog(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+	s.NewLiveQueryAuditLogFuncInvoked = true
+	return s.NewLiveQueryAuditLogFunc(ctx, entry)
+}
+
+func (s *DataStore) ListLiveQueryAuditLog(ctx context.Context, opt fleet.LiveQueryAuditLogOptions) ([]*fleet.LiveQueryAuditLog, error) {
+	s.ListLiveQueryAuditLogFuncInvoked = true
+	return s.ListLiveQueryAuditLogFunc(ctx, opt)
+}
+
 func (s *DataStore) ApplyPackSpecs(ctx context.Context, specs []*fleet.PackSpec) error {
 	s.ApplyPackSpecsFuncInvoked = true
 	return s.ApplyPackSpecsFunc(ctx, specs)
@@ -1239,6 +1929,16 @@ func (s *DataStore) ListHostsInLabel(ctx context.Context, filter fleet.TeamFilte
 	return s.ListHostsInLabelFunc(ctx, filter, lid, opt)
 }
 
+func (s *DataStore) AddHostsToLabel(ctx context.Context, lid uint, hostIDs []uint) error {
+	s.AddHostsToLabelFuncInvoked = true
+	return s.AddHostsToLabelFunc(ctx, lid, hostIDs)
+}
+
+func (s *DataStore) RemoveHostsFromLabel(ctx context.Context, lid uint, hostIDs []uint) error {
+	s.RemoveHostsFromLabelFuncInvoked = true
+	return s.RemoveHostsFromLabelFunc(ctx, lid, hostIDs)
+}
+
 func (s *DataStore) ListUniqueHostsInLabels(ctx context.Context, filter fleet.TeamFilter, labels []uint) ([]*fleet.Host, error) {
 	s.ListUniqueHostsInLabelsFuncInvoked = true
 	return s.ListUniqueHostsInLabelsFunc(ctx, filter, labels)
@@ -1294,6 +1994,156 @@ func (s *DataStore) ListHosts(ctx context.Context, filter fleet.TeamFilter, opt
 	return s.ListHostsFunc(ctx, filter, opt)
 }
 
+func (s *DataStore) ListHostChanges(ctx context.Context, filter fleet.TeamFilter, cursor fleet.HostChangesCursor, limit int) (*fleet.HostChanges, error) {
+	s.ListHostChangesFuncInvoked = true
+	return s.ListHostChangesFunc(ctx, filter, cursor, limit)
+}
+
+func (s *DataStore) CleanupHostTombstones(ctx context.Context, now time.Time) error {
+	s.CleanupHostTombstonesFuncInvoked = true
+	return s.CleanupHostTombstonesFunc(ctx, now)
+}
+
+func (s *DataStore) SetHostAgentOptionsOverride(ctx context.Context, override *fleet.HostAgentOptionsOverride) error {
+	s.SetHostAgentOptionsOverrideFuncInvoked = true
+	return s.SetHostAgentOptionsOverrideFunc(ctx, override)
+}
+
+func (s *DataStore) HostAgentOptionsOverride(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+	s.HostAgentOptionsOverrideFuncInvoked = true
+	return s.HostAgentOptionsOverrideFunc(ctx, hostID, now)
+}
+
+func (s *DataStore) DeleteHostAgentOptionsOverride(ctx context.Context, hostID uint) error {
+	s.DeleteHostAgentOptionsOverrideFuncInvoked = true
+	return s.DeleteHostAgentOptionsOverrideFunc(ctx, hostID)
+}
+
+func (s *DataStore) CleanupExpiredHostAgentOptionsOverrides(ctx context.Context, now time.Time) error {
+	s.CleanupExpiredHostAgentOptionsOverridesFuncInvoked = true
+	return s.CleanupExpiredHostAgentOptionsOverridesFunc(ctx, now)
+}
+
+func (s *DataStore) ListHostStartupItems(ctx context.Context, hostID uint) ([]fleet.HostStartupItem, error) {
+	s.ListHostStartupItemsFuncInvoked = true
+	return s.ListHostStartupItemsFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListStartupItemChanges(ctx context.Context, filter fleet.TeamFilter, opt fleet.StartupItemChangesListOptions) ([]fleet.StartupItemChange, error) {
+	s.ListStartupItemChangesFuncInvoked = true
+	return s.ListStartupItemChangesFunc(ctx, filter, opt)
+}
+
+func (s *DataStore) CleanupStartupItemChanges(ctx context.Context, now time.Time) error {
+	s.CleanupStartupItemChangesFuncInvoked = true
+	return s.CleanupStartupItemChangesFunc(ctx, now)
+}
+
+func (s *DataStore) ListHostListeningPorts(ctx context.Context, hostID uint) ([]fleet.HostListeningPort, error) {
+	s.ListHostListeningPortsFuncInvoked = true
+	return s.ListHostListeningPortsFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListHostDisks(ctx context.Context, hostID uint) ([]fleet.HostDisk, error) {
+	s.ListHostDisksFuncInvoked = true
+	return s.ListHostDisksFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListHostBatteries(ctx context.Context, hostID uint) ([]fleet.HostBattery, error) {
+	s.ListHostBatteriesFuncInvoked = true
+	return s.ListHostBatteriesFunc(ctx, hostID)
+}
+
+func (s *DataStore) ListHostCertificates(ctx context.Context, hostID uint) ([]fleet.HostCertificate, error) {
+	s.ListHostCertificatesFuncInvoked = true
+	return s.ListHostCertificatesFunc(ctx, hostID)
+}
+
+func (s *DataStore) HostMetadata(ctx context.Context, hostID uint) (*fleet.HostMetadata, error) {
+	s.HostMetadataFuncInvoked = true
+	return s.HostMetadataFunc(ctx, hostID)
+}
+
+func (s *DataStore) SetHostMetadata(ctx context.Context, metadata *fleet.HostMetadata) error {
+	s.SetHostMetadataFuncInvoked = true
+	return s.SetHostMetadataFunc(ctx, metadata)
+}
+
+func (s *DataStore) NewHostActivities(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+	s.NewHostActivitiesFuncInvoked = true
+	return s.NewHostActivitiesFunc(ctx, hostIDs, user, activityType, details)
+}
+
+func (s *DataStore) ListHostActivities(ctx context.Context, hostID uint, opt fleet.ListOptions) ([]*fleet.HostActivity, error) {
+	s.ListHostActivitiesFuncInvoked = true
+	return s.ListHostActivitiesFunc(ctx, hostID, opt)
+}
+
+func (s *DataStore) CleanupHostActivities(ctx context.Context, now time.Time) error {
+	s.CleanupHostActivitiesFuncInvoked = true
+	return s.CleanupHostActivitiesFunc(ctx, now)
+}
+
+func (s *DataStore) ListDuplicateHosts(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.DuplicateHosts, error) {
+	s.ListDuplicateHostsFuncInvoked = true
+	return s.ListDuplicateHostsFunc(ctx, filter)
+}
+
+func (s *DataStore) MergeHosts(ctx context.Context, hostID uint, duplicateIDs []uint) error {
+	s.MergeHostsFuncInvoked = true
+	return s.MergeHostsFunc(ctx, hostID, duplicateIDs)
+}
+
+func (s *DataStore) NewHostOfflineEvents(ctx context.Context, seenBefore time.Time) error {
+	s.NewHostOfflineEventsFuncInvoked = true
+	return s.NewHostOfflineEventsFunc(ctx, seenBefore)
+}
+
+func (s *DataStore) MarkHostsReportedOffline(ctx context.Context, seenBefore time.Time) error {
+	s.MarkHostsReportedOfflineFuncInvoked = true
+	return s.MarkHostsReportedOfflineFunc(ctx, seenBefore)
+}
+
+func (s *DataStore) ListHostLifecycleEvents(ctx context.Context, limit int) ([]*fleet.HostLifecycleEvent, error) {
+	s.ListHostLifecycleEventsFuncInvoked = true
+	return s.ListHostLifecycleEventsFunc(ctx, limit)
+}
+
+func (s *DataStore) DeleteHostLifecycleEvents(ctx context.Context, ids []uint) error {
+	s.DeleteHostLifecycleEventsFuncInvoked = true
+	return s.DeleteHostLifecycleEventsFunc(ctx, ids)
+}
+
+func (s *DataStore) CleanupHostLifecycleEvents(ctx context.Context, before time.Time) error {
+	s.CleanupHostLifecycleEventsFuncInvoked = true
+	return s.CleanupHostLifecycleEventsFunc(ctx, before)
+}
+
+func (s *DataStore) ListeningPortsReport(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListeningPortsReportOptions) ([]fleet.ListeningPortHostsCount, error) {
+	s.ListeningPortsReportFuncInvoked = true
+	return s.ListeningPortsReportFunc(ctx, filter, opt)
+}
+
+func (s *DataStore) NewHostOnlineSubscription(ctx context.Context, hostID uint, userID uint) (*fleet.HostOnlineSubscription, error) {
+	s.NewHostOnlineSubscriptionFuncInvoked = true
+	return s.NewHostOnlineSubscriptionFunc(ctx, hostID, userID)
+}
+
+func (s *DataStore) ListHostOnlineSubscriptions(ctx context.Context, userID uint) ([]*fleet.HostOnlineSubscription, error) {
+	s.ListHostOnlineSubscriptionsFuncInvoked = true
+	return s.ListHostOnlineSubscriptionsFunc(ctx, userID)
+}
+
+func (s *DataStore) DeleteHostOnlineSubscription(ctx context.Context, hostID uint, userID uint) error {
+	s.DeleteHostOnlineSubscriptionFuncInvoked = true
+	return s.DeleteHostOnlineSubscriptionFunc(ctx, hostID, userID)
+}
+
+func (s *DataStore) ConsumeHostOnlineSubscriptions(ctx context.Context, hostIDs []uint) ([]*fleet.HostOnlineNotification, error) {
+	s.ConsumeHostOnlineSubscriptionsFuncInvoked = true
+	return s.ConsumeHostOnlineSubscriptionsFunc(ctx, hostIDs)
+}
+
 func (s *DataStore) MarkHostsSeen(ctx context.Context, hostIDs []uint, t time.Time) error {
 	s.MarkHostsSeenFuncInvoked = true
 	return s.MarkHostsSeenFunc(ctx, hostIDs, t)
@@ -1309,9 +2159,9 @@ func (s *DataStore) CleanupIncomingHosts(ctx context.Context, now time.Time) err
 	return s.CleanupIncomingHostsFunc(ctx, now)
 }
 
-func (s *DataStore) GenerateHostStatusStatistics(ctx context.Context, filter fleet.TeamFilter, now time.Time, platform *string) (*fleet.HostSummary, error) {
+func (s *DataStore) GenerateHostStatusStatistics(ctx context.Context, filter fleet.TeamFilter, now time.Time, settings fleet.HostSettings, platform *string, labelID *uint) (*fleet.HostSummary, error) {
 	s.GenerateHostStatusStatisticsFuncInvoked = true
-	return s.GenerateHostStatusStatisticsFunc(ctx, filter, now, platform)
+	return s.GenerateHostStatusStatisticsFunc(ctx, filter, now, settings, platform, labelID)
 }
 
 func (s *DataStore) HostIDsByName(ctx context.Context, filter fleet.TeamFilter, hostnames []string) ([]uint, error) {
@@ -1354,6 +2204,11 @@ func (s *DataStore) ListHostDeviceMapping(ctx context.Context, id uint) ([]*flee
 	return s.ListHostDeviceMappingFunc(ctx, id)
 }
 
+func (s *DataStore) ListHostUsers(ctx context.Context, hostID uint) ([]fleet.HostUser, error) {
+	s.ListHostUsersFuncInvoked = true
+	return s.ListHostUsersFunc(ctx, hostID)
+}
+
 func (s *DataStore) LoadHostByDeviceAuthToken(ctx context.Context, authToken string) (*fleet.Host, error) {
 	s.LoadHostByDeviceAuthTokenFuncInvoked = true
 	return s.LoadHostByDeviceAuthTokenFunc(ctx, authToken)
@@ -1364,6 +2219,16 @@ func (s *DataStore) SetOrUpdateDeviceAuthToken(ctx context.Context, hostID uint,
 	return s.SetOrUpdateDeviceAuthTokenFunc(ctx, hostID, authToken)
 }
 
+func (s *DataStore) LoadHostByOrbitNodeKey(ctx context.Context, nodeKey string) (*fleet.Host, error) {
+	s.LoadHostByOrbitNodeKeyFuncInvoked = true
+	return s.LoadHostByOrbitNodeKeyFunc(ctx, nodeKey)
+}
+
+func (s *DataStore) SetOrUpdateOrbitNodeKey(ctx context.Context, hostID uint, nodeKey string) error {
+	s.SetOrUpdateOrbitNodeKeyFuncInvoked = true
+	return s.SetOrUpdateOrbitNodeKeyFunc(ctx, hostID, nodeKey)
+}
+
 func (s *DataStore) ListPoliciesForHost(ctx context.Context, host *fleet.Host) ([]*fleet.HostPolicy, error) {
 	s.ListPoliciesForHostFuncInvoked = true
 	return s.ListPoliciesForHostFunc(ctx, host)
@@ -1394,6 +2259,21 @@ func (s *DataStore) GenerateAggregatedMunkiAndMDM(ctx context.Context) error {
 	return s.GenerateAggregatedMunkiAndMDMFunc(ctx)
 }
 
+func (s *DataStore) ListHostAgentVersions(ctx context.Context, hostID uint) ([]*fleet.HostAgentVersion, error) {
+	s.ListHostAgentVersionsFuncInvoked = true
+	return s.ListHostAgentVersionsFunc(ctx, hostID)
+}
+
+func (s *DataStore) HostNetworkSettings(ctx context.Context, hostID uint) (*fleet.HostNetworkSettings, error) {
+	s.HostNetworkSettingsFuncInvoked = true
+	return s.HostNetworkSettingsFunc(ctx, hostID)
+}
+
+func (s *DataStore) NetworkSettingsReport(ctx context.Context, filter fleet.TeamFilter) (*fleet.NetworkSettingsReport, error) {
+	s.NetworkSettingsReportFuncInvoked = true
+	return s.NetworkSettingsReportFunc(ctx, filter)
+}
+
 func (s *DataStore) OSVersions(ctx context.Context, teamID *uint, platform *string) (*fleet.OSVersions, error) {
 	s.OSVersionsFuncInvoked = true
 	return s.OSVersionsFunc(ctx, teamID, platform)
@@ -1404,14 +2284,14 @@ func (s *DataStore) UpdateOSVersions(ctx context.Context) error {
 	return s.UpdateOSVersionsFunc(ctx)
 }
 
-func (s *DataStore) CountHostsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+func (s *DataStore) CountHostsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
 	s.CountHostsInTargetsFuncInvoked = true
-	return s.CountHostsInTargetsFunc(ctx, filter, targets, now)
+	return s.CountHostsInTargetsFunc(ctx, filter, targets, now, settings)
 }
 
-func (s *DataStore) HostIDsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+func (s *DataStore) HostIDsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
 	s.HostIDsInTargetsFuncInvoked = true
-	return s.HostIDsInTargetsFunc(ctx, filter, targets)
+	return s.HostIDsInTargetsFunc(ctx, filter, targets, settings)
 }
 
 func (s *DataStore) NewPasswordResetRequest(ctx context.Context, req *fleet.PasswordResetRequest) (*fleet.PasswordResetRequest, error) {
@@ -1649,6 +2529,46 @@ func (s *DataStore) ListActivities(ctx context.Context, opt fleet.ListOptions) (
 	return s.ListActivitiesFunc(ctx, opt)
 }
 
+func (s *DataStore) RecordAgentConfigVersion(ctx context.Context, user *fleet.User) error {
+	s.RecordAgentConfigVersionFuncInvoked = true
+	return s.RecordAgentConfigVersionFunc(ctx, user)
+}
+
+func (s *DataStore) ListAgentConfigVersions(ctx context.Context, opt fleet.ListOptions) ([]*fleet.AgentConfigVersion, error) {
+	s.ListAgentConfigVersionsFuncInvoked = true
+	return s.ListAgentConfigVersionsFunc(ctx, opt)
+}
+
+func (s *DataStore) AgentConfigVersion(ctx context.Context, id uint) (*fleet.AgentConfigVersion, error) {
+	s.AgentConfigVersionFuncInvoked = true
+	return s.AgentConfigVersionFunc(ctx, id)
+}
+
+func (s *DataStore) NewAgentOptionsRollout(ctx context.Context, rollout *fleet.AgentOptionsRollout) (*fleet.AgentOptionsRollout, error) {
+	s.NewAgentOptionsRolloutFuncInvoked = true
+	return s.NewAgentOptionsRolloutFunc(ctx, rollout)
+}
+
+func (s *DataStore) AgentOptionsRollout(ctx context.Context, id uint) (*fleet.AgentOptionsRollout, error) {
+	s.AgentOptionsRolloutFuncInvoked = true
+	return s.AgentOptionsRolloutFunc(ctx, id)
+}
+
+func (s *DataStore) ActiveAgentOptionsRollout(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+	s.ActiveAgentOptionsRolloutFuncInvoked = true
+	return s.ActiveAgentOptionsRolloutFunc(ctx)
+}
+
+func (s *DataStore) ListAgentOptionsRollouts(ctx context.Context, opt fleet.ListOptions) ([]*fleet.AgentOptionsRollout, error) {
+	s.ListAgentOptionsRolloutsFuncInvoked = true
+	return s.ListAgentOptionsRolloutsFunc(ctx, opt)
+}
+
+func (s *DataStore) EndAgentOptionsRollout(ctx context.Context, id uint, status string) error {
+	s.EndAgentOptionsRolloutFuncInvoked = true
+	return s.EndAgentOptionsRolloutFunc(ctx, id, status)
+}
+
 func (s *DataStore) ShouldSendStatistics(ctx context.Context, frequency time.Duration, license *fleet.LicenseInfo) (fleet.StatisticsPayload, bool, error) {
 	s.ShouldSendStatisticsFuncInvoked = true
 	return s.ShouldSendStatisticsFunc(ctx, frequency, license)
@@ -1679,9 +2599,9 @@ func (s *DataStore) SavePolicy(ctx context.Context, p *fleet.Policy) error {
 	return s.SavePolicyFunc(ctx, p)
 }
 
-func (s *DataStore) ListGlobalPolicies(ctx context.Context) ([]*fleet.Policy, error) {
+func (s *DataStore) ListGlobalPolicies(ctx context.Context, opts fleet.ListOptions) ([]*fleet.Policy, error) {
 	s.ListGlobalPoliciesFuncInvoked = true
-	return s.ListGlobalPoliciesFunc(ctx)
+	return s.ListGlobalPoliciesFunc(ctx, opts)
 }
 
 func (s *DataStore) PoliciesByID(ctx context.Context, ids []uint) (map[uint]*fleet.Policy, error) {
@@ -1699,6 +2619,11 @@ func (s *DataStore) PolicyQueriesForHost(ctx context.Context, host *fleet.Host)
 	return s.PolicyQueriesForHostFunc(ctx, host)
 }
 
+func (s *DataStore) CriticalPolicyQueriesForHost(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+	s.CriticalPolicyQueriesForHostFuncInvoked = true
+	return s.CriticalPolicyQueriesForHostFunc(ctx, host)
+}
+
 func (s *DataStore) AsyncBatchInsertPolicyMembership(ctx context.Context, batch []fleet.PolicyMembershipResult) error {
 	s.AsyncBatchInsertPolicyMembershipFuncInvoked = true
 	return s.AsyncBatchInsertPolicyMembershipFunc(ctx, batch)
@@ -1734,6 +2659,11 @@ func (s *DataStore) CountSoftware(ctx context.Context, opt fleet.SoftwareListOpt
 	return s.CountSoftwareFunc(ctx, opt)
 }
 
+func (s *DataStore) ListHostSoftware(ctx context.Context, hostID uint, opt fleet.SoftwareListOptions) ([]fleet.Software, error) {
+	s.ListHostSoftwareFuncInvoked = true
+	return s.ListHostSoftwareFunc(ctx, hostID, opt)
+}
+
 func (s *DataStore) ListVulnerableSoftwareBySource(ctx context.Context, source string) ([]fleet.SoftwareWithCPE, error) {
 	s.ListVulnerableSoftwareBySourceFuncInvoked = true
 	return s.ListVulnerableSoftwareBySourceFunc(ctx, source)
@@ -1749,9 +2679,9 @@ func (s *DataStore) NewTeamPolicy(ctx context.Context, teamID uint, authorID *ui
 	return s.NewTeamPolicyFunc(ctx, teamID, authorID, args)
 }
 
-func (s *DataStore) ListTeamPolicies(ctx context.Context, teamID uint) ([]*fleet.Policy, error) {
+func (s *DataStore) ListTeamPolicies(ctx context.Context, teamID uint, opts fleet.ListOptions) ([]*fleet.Policy, error) {
 	s.ListTeamPoliciesFuncInvoked = true
-	return s.ListTeamPoliciesFunc(ctx, teamID)
+	return s.ListTeamPoliciesFunc(ctx, teamID, opts)
 }
 
 func (s *DataStore) DeleteTeamPolicies(ctx context.Context, teamID uint, ids []uint) ([]uint, error) {
@@ -1764,11 +2694,206 @@ func (s *DataStore) TeamPolicy(ctx context.Context, teamID uint, policyID uint)
 	return s.TeamPolicyFunc(ctx, teamID, policyID)
 }
 
+func (s *DataStore) TeamPolicyExemptions(ctx context.Context, teamID uint) ([]uint, error) {
+	s.TeamPolicyExemptionsFuncInvoked = true
+	return s.TeamPolicyExemptionsFunc(ctx, teamID)
+}
+
+func (s *DataStore) SetTeamPolicyExemptions(ctx context.Context, teamID uint, policyIDs []uint) error {
+	s.SetTeamPolicyExemptionsFuncInvoked = true
+	return s.SetTeamPolicyExemptionsFunc(ctx, teamID, policyIDs)
+}
+
 func (s *DataStore) CleanupPolicyMembership(ctx context.Context, now time.Time) error {
 	s.CleanupPolicyMembershipFuncInvoked = true
 	return s.CleanupPolicyMembershipFunc(ctx, now)
 }
 
+func (s *DataStore) UpdatePolicyStats(ctx context.Context) error {
+	s.UpdatePolicyStatsFuncInvoked = true
+	return s.UpdatePolicyStatsFunc(ctx)
+}
+
+func (s *DataStore) UpdateHostIssues(ctx context.Context) error {
+	s.UpdateHostIssuesFuncInvoked = true
+	return s.UpdateHostIssuesFunc(ctx)
+}
+
+func (s *DataStore) NewPolicyJiraIssue(ctx context.Context, policyID uint, issueKey string) error {
+	s.NewPolicyJiraIssueFuncInvoked = true
+	return s.NewPolicyJiraIssueFunc(ctx, policyID, issueKey)
+}
+
+func (s *DataStore) NewHostPolicyRemediation(ctx context.Context, hostID, policyID uint, authorID *uint) (*fleet.HostPolicyRemediation, error) {
+	s.NewHostPolicyRemediationFuncInvoked = true
+	return s.NewHostPolicyRemediationFunc(ctx, hostID, policyID, authorID)
+}
+
+func (s *DataStore) QueueHostPolicyAutoRemediations(ctx context.Context, hostID uint, failingPolicyIDs []uint) error {
+	s.QueueHostPolicyAutoRemediationsFuncInvoked = true
+	return s.QueueHostPolicyAutoRemediationsFunc(ctx, hostID, failingPolicyIDs)
+}
+
+func (s *DataStore) ListHostPolicyRemediations(ctx context.Context, hostID uint, opts fleet.ListOptions) ([]*fleet.HostPolicyRemediation, error) {
+	s.ListHostPolicyRemediationsFuncInvoked = true
+	return s.ListHostPolicyRemediationsFunc(ctx, hostID, opts)
+}
+
+func (s *DataStore) PendingHostPolicyRemediations(ctx context.Context, hostID uint) ([]*fleet.HostPolicyRemediation, error) {
+	s.PendingHostPolicyRemediationsFuncInvoked = true
+	return s.PendingHostPolicyRemediationsFunc(ctx, hostID)
+}
+
+func (s *DataStore) SetHostPolicyRemediationResult(ctx context.Context, hostID, id uint, exitCode int, output string) error {
+	s.SetHostPolicyRemediationResultFuncInvoked = true
+	return s.SetHostPolicyRemediationResultFunc(ctx, hostID, id, exitCode, output)
+}
+
+func (s *DataStore) PolicyJiraIssue(ctx context.Context, policyID uint) (*fleet.PolicyJiraIssue, error) {
+	s.PolicyJiraIssueFuncInvoked = true
+	return s.PolicyJiraIssueFunc(ctx, policyID)
+}
+
+func (s *DataStore) ListPolicyJiraIssues(ctx context.Context) ([]*fleet.PolicyJiraIssue, error) {
+	s.ListPolicyJiraIssuesFuncInvoked = true
+	return s.ListPolicyJiraIssuesFunc(ctx)
+}
+
+func (s *DataStore) DeletePolicyJiraIssue(ctx context.Context, policyID uint) error {
+	s.DeletePolicyJiraIssueFuncInvoked = true
+	return s.DeletePolicyJiraIssueFunc(ctx, policyID)
+}
+
+func (s *DataStore) NewATCTable(ctx context.Context, table *fleet.ATCTable) (*fleet.ATCTable, error) {
+	s.NewATCTableFuncInvoked = true
+	return s.NewATCTableFunc(ctx, table)
+}
+
+func (s *DataStore) ATCTable(ctx context.Context, id uint) (*fleet.ATCTable, error) {
+	s.ATCTableFuncInvoked = true
+	return s.ATCTableFunc(ctx, id)
+}
+
+func (s *DataStore) ListATCTables(ctx context.Context) ([]*fleet.ATCTable, error) {
+	s.ListATCTablesFuncInvoked = true
+	return s.ListATCTablesFunc(ctx)
+}
+
+func (s *DataStore) SaveATCTable(ctx context.Context, table *fleet.ATCTable) error {
+	s.SaveATCTableFuncInvoked = true
+	return s.SaveATCTableFunc(ctx, table)
+}
+
+func (s *DataStore) DeleteATCTable(ctx context.Context, id uint) error {
+	s.DeleteATCTableFuncInvoked = true
+	return s.DeleteATCTableFunc(ctx, id)
+}
+
+func (s *DataStore) NewFIMCategory(ctx context.Context, category *fleet.FIMCategory) (*fleet.FIMCategory, error) {
+	s.NewFIMCategoryFuncInvoked = true
+	return s.NewFIMCategoryFunc(ctx, category)
+}
+
+func (s *DataStore) FIMCategory(ctx context.Context, id uint) (*fleet.FIMCategory, error) {
+	s.FIMCategoryFuncInvoked = true
+	return s.FIMCategoryFunc(ctx, id)
+}
+
+func (s *DataStore) ListFIMCategories(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+	s.ListFIMCategoriesFuncInvoked = true
+	return s.ListFIMCategoriesFunc(ctx, teamID)
+}
+
+func (s *DataStore) ListFIMCategoriesForHost(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+	s.ListFIMCategoriesForHostFuncInvoked = true
+	return s.ListFIMCategoriesForHostFunc(ctx, teamID)
+}
+
+func (s *DataStore) SaveFIMCategory(ctx context.Context, category *fleet.FIMCategory) error {
+	s.SaveFIMCategoryFuncInvoked = true
+	return s.SaveFIMCategoryFunc(ctx, category)
+}
+
+func (s *DataStore) DeleteFIMCategory(ctx context.Context, id uint) error {
+	s.DeleteFIMCategoryFuncInvoked = true
+	return s.DeleteFIMCategoryFunc(ctx, id)
+}
+
+func (s *DataStore) NewYARASignatureGroup(ctx context.Context, group *fleet.YARASignatureGroup) (*fleet.YARASignatureGroup, error) {
+	s.NewYARASignatureGroupFuncInvoked = true
+	return s.NewYARASignatureGroupFunc(ctx, group)
+}
+
+func (s *DataStore) YARASignatureGroup(ctx context.Context, id uint) (*fleet.YARASignatureGroup, error) {
+	s.YARASignatureGroupFuncInvoked = true
+	return s.YARASignatureGroupFunc(ctx, id)
+}
+
+func (s *DataStore) ListYARASignatureGroups(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+	s.ListYARASignatureGroupsFuncInvoked = true
+	return s.ListYARASignatureGroupsFunc(ctx, teamID)
+}
+
+func (s *DataStore) ListYARASignatureGroupsForHost(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+	s.ListYARASignatureGroupsForHostFuncInvoked = true
+	return s.ListYARASignatureGroupsForHostFunc(ctx, teamID)
+}
+
+func (s *DataStore) SaveYARASignatureGroup(ctx context.Context, group *fleet.YARASignatureGroup) error {
+	s.SaveYARASignatureGroupFuncInvoked = true
+	return s.SaveYARASignatureGroupFunc(ctx, group)
+}
+
+func (s *DataStore) DeleteYARASignatureGroup(ctx context.Context, id uint) error {
+	s.DeleteYARASignatureGroupFuncInvoked = true
+	return s.DeleteYARASignatureGroupFunc(ctx, id)
+}
+
+func (s *DataStore) NewDashboard(ctx context.Context, dashboard *fleet.Dashboard) (*fleet.Dashboard, error) {
+	s.NewDashboardFuncInvoked = true
+	return s.NewDashboardFunc(ctx, dashboard)
+}
+
+func (s *DataStore) Dashboard(ctx context.Context, id uint) (*fleet.Dashboard, error) {
+	s.DashboardFuncInvoked = true
+	return s.DashboardFunc(ctx, id)
+}
+
+func (s *DataStore) ListDashboards(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.Dashboard, error) {
+	s.ListDashboardsFuncInvoked = true
+	return s.ListDashboardsFunc(ctx, filter, opt)
+}
+
+func (s *DataStore) SaveDashboard(ctx context.Context, dashboard *fleet.Dashboard) error {
+	s.SaveDashboardFuncInvoked = true
+	return s.SaveDashboardFunc(ctx, dashboard)
+}
+
+func (s *DataStore) DeleteDashboard(ctx context.Context, id uint) error {
+	s.DeleteDashboardFuncInvoked = true
+	return s.DeleteDashboardFunc(ctx, id)
+}
+
+func (s *DataStore) ListIndicatorsToLookup(ctx context.Context, checkedBefore time.Time, limit int) ([]fleet.Indicator, error) {
+	s.ListIndicatorsToLookupFuncInvoked = true
+	return s.ListIndicatorsToLookupFunc(ctx, checkedBefore, limit)
+}
+
+func (s *DataStore) SaveThreatIntelVerdicts(ctx context.Context, verdicts []fleet.ThreatIntelVerdict) error {
+	s.SaveThreatIntelVerdictsFuncInvoked = true
+	return s.SaveThreatIntelVerdictsFunc(ctx, verdicts)
+}
+
+func (s *DataStore) UpdateHostThreatFindings(ctx context.Context) error {
+	s.UpdateHostThreatFindingsFuncInvoked = true
+	return s.UpdateHostThreatFindingsFunc(ctx)
+}
+
+func (s *DataStore) ListHostThreatFindings(ctx context.Context, hostID uint) ([]*fleet.HostThreatFinding, error) {
+	s.ListHostThreatFindingsFuncInvoked = true
+	return s.ListHostThreatFindingsFunc(ctx, hostID)
+}
+
 func (s *DataStore) Lock(ctx context.Context, name string, owner string, expiration time.Duration) (bool, error) {
 	s.LockFuncInvoked = true
 	return s.LockFunc(ctx, name, owner, expiration)
@@ -1814,6 +2939,11 @@ func (s *DataStore) TeamAgentOptions(ctx context.Context, teamID uint) (*json.Ra
 	return s.TeamAgentOptionsFunc(ctx, teamID)
 }
 
+func (s *DataStore) TeamLogSettings(ctx context.Context, teamID uint) (*fleet.TeamLogSettings, error) {
+	s.TeamLogSettingsFuncInvoked = true
+	return s.TeamLogSettingsFunc(ctx, teamID)
+}
+
 func (s *DataStore) SaveHostPackStats(ctx context.Context, hostID uint, stats []fleet.PackStats) error {
 	s.SaveHostPackStatsFuncInvoked = true
 	return s.SaveHostPackStatsFunc(ctx, hostID, stats)
@@ -1829,11 +2959,26 @@ func (s *DataStore) UpdateHost(ctx context.Context, host *fleet.Host) error {
 	return s.UpdateHostFunc(ctx, host)
 }
 
+func (s *DataStore) UpdateHostsDetails(ctx context.Context, hosts []*fleet.Host) error {
+	s.UpdateHostsDetailsFuncInvoked = true
+	return s.UpdateHostsDetailsFunc(ctx, hosts)
+}
+
+func (s *DataStore) UpdateHostDisplayNames(ctx context.Context, sources []string) error {
+	s.UpdateHostDisplayNamesFuncInvoked = true
+	return s.UpdateHostDisplayNamesFunc(ctx, sources)
+}
+
 func (s *DataStore) ListScheduledQueriesInPack(ctx context.Context, packID uint) ([]*fleet.ScheduledQuery, error) {
 	s.ListScheduledQueriesInPackFuncInvoked = true
 	return s.ListScheduledQueriesInPackFunc(ctx, packID)
 }
 
+func (s *DataStore) ListDiscardDataScheduledQueryNames(ctx context.Context) ([]fleet.PackScheduledQueryName, error) {
+	s.ListDiscardDataScheduledQueryNamesFuncInvoked = true
+	return s.ListDiscardDataScheduledQueryNamesFunc(ctx)
+}
+
 func (s *DataStore) UpdateHostRefetchRequested(ctx context.Context, hostID uint, value bool) error {
 	s.UpdateHostRefetchRequestedFuncInvoked = true
 	return s.UpdateHostRefetchRequestedFunc(ctx, hostID, value)
@@ -1849,6 +2994,11 @@ func (s *DataStore) RecordPolicyQueryExecutions(ctx context.Context, host *fleet
 	return s.RecordPolicyQueryExecutionsFunc(ctx, host, results, updated, deferredSaveHost)
 }
 
+func (s *DataStore) RecordCriticalPolicyQueryExecutions(ctx context.Context, host *fleet.Host, results map[uint]*bool, updated time.Time) error {
+	s.RecordCriticalPolicyQueryExecutionsFuncInvoked = true
+	return s.RecordCriticalPolicyQueryExecutionsFunc(ctx, host, results, updated)
+}
+
 func (s *DataStore) RecordLabelQueryExecutions(ctx context.Context, host *fleet.Host, results map[uint]*bool, t time.Time, deferredSaveHost bool) error {
 	s.RecordLabelQueryExecutionsFuncInvoked = true
 	return s.RecordLabelQueryExecutionsFunc(ctx, host, results, t, deferredSaveHost)
@@ -1874,19 +3024,79 @@ func (s *DataStore) SetOrUpdateMDMData(ctx context.Context, hostID uint, enrolle
 	return s.SetOrUpdateMDMDataFunc(ctx, hostID, enrolled, serverURL, installedFromDep)
 }
 
+func (s *DataStore) SetOrUpdateHostAgentVersion(ctx context.Context, hostID uint, component string, version string) error {
+	s.SetOrUpdateHostAgentVersionFuncInvoked = true
+	return s.SetOrUpdateHostAgentVersionFunc(ctx, hostID, component, version)
+}
+
 func (s *DataStore) ReplaceHostDeviceMapping(ctx context.Context, id uint, mappings []*fleet.HostDeviceMapping) error {
 	s.ReplaceHostDeviceMappingFuncInvoked = true
 	return s.ReplaceHostDeviceMappingFunc(ctx, id, mappings)
 }
 
+func (s *DataStore) SetOrUpdateCustomHostDeviceMapping(ctx context.Context, hostID uint, email string) ([]*fleet.HostDeviceMapping, error) {
+	s.SetOrUpdateCustomHostDeviceMappingFuncInvoked = true
+	return s.SetOrUpdateCustomHostDeviceMappingFunc(ctx, hostID, email)
+}
+
+func (s *DataStore) ReplaceHostDNSServers(ctx context.Context, hostID uint, addresses []string) error {
+	s.ReplaceHostDNSServersFuncInvoked = true
+	return s.ReplaceHostDNSServersFunc(ctx, hostID, addresses)
+}
+
+func (s *DataStore) ReplaceHostNetworkAddresses(ctx context.Context, hostID uint, addresses []fleet.HostNetworkAddress) error {
+	s.ReplaceHostNetworkAddressesFuncInvoked = true
+	return s.ReplaceHostNetworkAddressesFunc(ctx, hostID, addresses)
+}
+
+func (s *DataStore) ReplaceHostProxies(ctx context.Context, hostID uint, proxies []fleet.HostProxy) error {
+	s.ReplaceHostProxiesFuncInvoked = true
+	return s.ReplaceHostProxiesFunc(ctx, hostID, proxies)
+}
+
+func (s *DataStore) ReplaceHostStartupItems(ctx context.Context, hostID uint, items []fleet.HostStartupItem) error {
+	s.ReplaceHostStartupItemsFuncInvoked = true
+	return s.ReplaceHostStartupItemsFunc(ctx, hostID, items)
+}
+
+func (s *DataStore) ReplaceHostListeningPorts(ctx context.Context, hostID uint, ports []fleet.HostListeningPort) error {
+	s.ReplaceHostListeningPortsFuncInvoked = true
+	return s.ReplaceHostListeningPortsFunc(ctx, hostID, ports)
+}
+
+func (s *DataStore) ReplaceHostDisks(ctx context.Context, hostID uint, disks []fleet.HostDisk) error {
+	s.ReplaceHostDisksFuncInvoked = true
+	return s.ReplaceHostDisksFunc(ctx, hostID, disks)
+}
+
+func (s *DataStore) ReplaceHostBatteries(ctx context.Context, hostID uint, batteries []fleet.HostBattery) error {
+	s.ReplaceHostBatteriesFuncInvoked = true
+	return s.ReplaceHostBatteriesFunc(ctx, hostID, batteries)
+}
+
+func (s *DataStore) ReplaceHostCertificates(ctx context.Context, hostID uint, certs []fleet.HostCertificate) error {
+	s.ReplaceHostCertificatesFuncInvoked = true
+	return s.ReplaceHostCertificatesFunc(ctx, hostID, certs)
+}
+
 func (s *DataStore) VerifyEnrollSecret(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
 	s.VerifyEnrollSecretFuncInvoked = true
 	return s.VerifyEnrollSecretFunc(ctx, secret)
 }
 
-func (s *DataStore) EnrollHost(ctx context.Context, osqueryHostId string, nodeKey string, teamID *uint, cooldown time.Duration) (*fleet.Host, error) {
+func (s *DataStore) RecordEnrollmentOutcomes(ctx context.Context, counts []fleet.EnrollmentOutcomeCount) error {
+	s.RecordEnrollmentOutcomesFuncInvoked = true
+	return s.RecordEnrollmentOutcomesFunc(ctx, counts)
+}
+
+func (s *DataStore) EnrollmentStats(ctx context.Context, since time.Time) (*fleet.EnrollmentStats, error) {
+	s.EnrollmentStatsFuncInvoked = true
+	return s.EnrollmentStatsFunc(ctx, since)
+}
+
+func (s *DataStore) EnrollHost(ctx context.Context, osqueryHostId string, nodeKey string, teamID *uint, cooldown time.Duration, consumeSecret string) (*fleet.Host, error) {
 	s.EnrollHostFuncInvoked = true
-	return s.EnrollHostFunc(ctx, osqueryHostId, nodeKey, teamID, cooldown)
+	return s.EnrollHostFunc(ctx, osqueryHostId, nodeKey, teamID, cooldown, consumeSecret)
 }
 
 func (s *DataStore) SerialUpdateHost(ctx context.Context, host *fleet.Host) error {
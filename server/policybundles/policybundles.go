@@ -0,0 +1,105 @@
+// Package policybundles provides the policy bundles shipped with Fleet, sets
+// of policies that can be imported at once such as the automated checks of the
+// CIS benchmarks.
+//
+// Each bundle is defined in a YAML file of this directory, the ID of the bundle
+// is the name of its file without the extension.
+package policybundles
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/ghodss/yaml"
+)
+
+//go:embed *.yml
+var files embed.FS
+
+var (
+	loadOnce sync.Once
+	bundles  map[string]*fleet.PolicyBundle
+	loadErr  error
+)
+
+// load parses the bundles of the embedded YAML files.
+func load() (map[string]*fleet.PolicyBundle, error) {
+	loadOnce.Do(func() {
+		entries, err := files.ReadDir(".")
+		if err != nil {
+			loadErr = fmt.Errorf("read bundles: %w", err)
+			return
+		}
+		loaded := make(map[string]*fleet.PolicyBundle, len(entries))
+		for _, entry := range entries {
+			b, err := files.ReadFile(entry.Name())
+			if err != nil {
+				loadErr = fmt.Errorf("read bundle %s: %w", entry.Name(), err)
+				return
+			}
+			var bundle fleet.PolicyBundle
+			if err := yaml.Unmarshal(b, &bundle); err != nil {
+				loadErr = fmt.Errorf("parse bundle %s: %w", entry.Name(), err)
+				return
+			}
+			bundle.ID = strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+			bundle.PolicyCount = len(bundle.Policies)
+			loaded[bundle.ID] = &bundle
+		}
+		bundles = loaded
+	})
+	return bundles, loadErr
+}
+
+// List returns the policy bundles sorted by ID, without their policies.
+func List() ([]*fleet.PolicyBundle, error) {
+	loaded, err := load()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*fleet.PolicyBundle, 0, len(loaded))
+	for _, bundle := range loaded {
+		summary := *bundle
+		summary.Policies = nil
+		list = append(list, &summary)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// Get returns the policy bundle with the given ID, along with its policies.
+func Get(id string) (*fleet.PolicyBundle, error) {
+	loaded, err := load()
+	if err != nil {
+		return nil, err
+	}
+	bundle, ok := loaded[id]
+	if !ok {
+		return nil, &notFoundError{id: id}
+	}
+	cp := *bundle
+	cp.Policies = make([]*fleet.PolicyBundlePolicy, 0, len(bundle.Policies))
+	for _, p := range bundle.Policies {
+		policy := *p
+		cp.Policies = append(cp.Policies, &policy)
+	}
+	return &cp, nil
+}
+
+type notFoundError struct {
+	id string
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("policy bundle %s was not found", e.id)
+}
+
+// IsNotFound implements the fleet.NotFoundError interface.
+func (e *notFoundError) IsNotFound() bool {
+	return true
+}
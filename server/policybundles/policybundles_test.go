@@ -0,0 +1,52 @@
+package policybundles
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundles(t *testing.T) {
+	list, err := List()
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	var ids []string
+	names := make(map[string]bool)
+	for _, summary := range list {
+		ids = append(ids, summary.ID)
+		assert.NotEmpty(t, summary.Name)
+		assert.NotEmpty(t, summary.Description)
+		assert.Nil(t, summary.Policies)
+
+		bundle, err := Get(summary.ID)
+		require.NoError(t, err)
+		require.Len(t, bundle.Policies, summary.PolicyCount)
+		require.NotZero(t, summary.PolicyCount)
+		for _, p := range bundle.Policies {
+			assert.NotEmpty(t, p.CISControl, p.Name)
+			assert.NotEmpty(t, p.Description, p.Name)
+			assert.NotEmpty(t, p.Resolution, p.Name)
+			// the bundles are imported as policy specs, identified by name
+			assert.False(t, names[p.Name], p.Name)
+			names[p.Name] = true
+			spec := fleet.PolicySpec{Name: p.Name, Query: p.Query, Platform: bundle.Platform}
+			require.NoError(t, spec.Verify(), p.Name)
+		}
+	}
+	assert.Equal(t, []string{"cis-macos", "cis-ubuntu", "cis-windows"}, ids)
+
+	// the returned bundles are copies
+	bundle, err := Get("cis-macos")
+	require.NoError(t, err)
+	bundle.Policies[0].Name = "changed"
+	bundle, err = Get("cis-macos")
+	require.NoError(t, err)
+	assert.NotEqual(t, "changed", bundle.Policies[0].Name)
+
+	_, err = Get("no-such-bundle")
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+}
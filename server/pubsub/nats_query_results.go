@@ -0,0 +1,230 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/nats-io/nats.go"
+)
+
+// natsListenerTimeout is how long WriteResult waits for a listener of the
+// campaign to respond before failing.
+const natsListenerTimeout = 5 * time.Second
+
+// natsQueryResults is a QueryResultStore backed by NATS JetStream. The results
+// are published to a JetStream stream with a subject per campaign, and each
+// ReadChannel reads them with an ordered consumer of the campaign's subject,
+// which is recreated by the client if it loses messages or the connection to
+// the server, so that the results are not lost during a NATS failover.
+//
+// A stream does not know if a subject has consumers, so the listeners of a
+// campaign also respond to requests on a core NATS subject of the campaign,
+// and WriteResult checks that one of them responds before publishing.
+type natsQueryResults struct {
+	conn             *nats.Conn
+	js               nats.JetStreamContext
+	stream           string
+	subjectPrefix    string
+	duplicateResults bool
+}
+
+var _ fleet.QueryResultStore = &natsQueryResults{}
+
+func init() {
+	RegisterResultStore("nats", func(config config.FleetConfig, _ fleet.RedisPool) (fleet.QueryResultStore, error) {
+		return NewNATSQueryResults(config.NATS)
+	})
+}
+
+// NewNATSQueryResults creates a new NATS JetStream implementation of the
+// QueryResultStore interface, connected to the NATS servers of the provided
+// configuration. The stream of the results is created if it does not exist.
+func NewNATSQueryResults(cfg config.NATSConfig) (*natsQueryResults, error) {
+	opts := []nats.Option{
+		nats.Name("fleet"),
+		nats.Timeout(cfg.ConnectTimeout),
+		// keep reconnecting, the subscriptions are restored on reconnection
+		nats.MaxReconnects(-1),
+	}
+	if cfg.CredentialsFile != "" {
+		opts = append(opts, nats.UserCredentials(cfg.CredentialsFile))
+	}
+	if cfg.TLSCert != "" || cfg.TLSKey != "" {
+		opts = append(opts, nats.ClientCert(cfg.TLSCert, cfg.TLSKey))
+	}
+	if cfg.TLSCA != "" {
+		opts = append(opts, nats.RootCAs(cfg.TLSCA))
+	}
+
+	conn, err := nats.Connect(cfg.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("connect to NATS: %w", err)
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("get JetStream context: %w", err)
+	}
+
+	r := &natsQueryResults{
+		conn:             conn,
+		js:               js,
+		stream:           cfg.Stream,
+		subjectPrefix:    cfg.SubjectPrefix,
+		duplicateResults: cfg.DuplicateResults,
+	}
+	if err := r.ensureStream(cfg.Replicas, cfg.MaxAge); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// ensureStream creates the stream of the results if it does not exist. The
+// results are only kept while a listener is interested in them, and at most
+// for maxAge.
+func (r *natsQueryResults) ensureStream(replicas int, maxAge time.Duration) error {
+	_, err := r.js.StreamInfo(r.stream)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return fmt.Errorf("get stream %s: %w", r.stream, err)
+	}
+
+	_, err = r.js.AddStream(&nats.StreamConfig{
+		Name:      r.stream,
+		Subjects:  []string{r.resultsSubject("*")},
+		Retention: nats.InterestPolicy,
+		Storage:   nats.MemoryStorage,
+		Replicas:  replicas,
+		MaxAge:    maxAge,
+	})
+	if err != nil {
+		return fmt.Errorf("create stream %s: %w", r.stream, err)
+	}
+	return nil
+}
+
+func (r *natsQueryResults) resultsSubject(id string) string {
+	return r.subjectPrefix + ".results." + id
+}
+
+func (r *natsQueryResults) listenersSubject(id uint) string {
+	return fmt.Sprintf("%s.listeners.%d", r.subjectPrefix, id)
+}
+
+func (r *natsQueryResults) duplicateSubject() string {
+	return r.subjectPrefix + ".duplicate"
+}
+
+// Close closes the connection to the NATS servers.
+func (r *natsQueryResults) Close() {
+	r.conn.Close()
+}
+
+func (r *natsQueryResults) WriteResult(result fleet.DistributedQueryResult) error {
+	subject := r.resultsSubject(fmt.Sprint(result.DistributedQueryCampaignID))
+
+	jsonVal, err := json.Marshal(&result)
+	if err != nil {
+		return fmt.Errorf("marshalling JSON for result: %w", err)
+	}
+
+	// the results are not kept for campaigns without listener
+	if _, err := r.conn.Request(r.listenersSubject(result.DistributedQueryCampaignID), nil, natsListenerTimeout); err != nil {
+		if errors.Is(err, nats.ErrNoResponders) {
+			return noSubscriberError{subject}
+		}
+		return fmt.Errorf("request listeners of subject %s: %w", subject, err)
+	}
+
+	if _, err := r.js.Publish(subject, jsonVal); err != nil {
+		return fmt.Errorf("publish to subject %s: %w", subject, err)
+	}
+
+	duplicate := r.duplicateResults
+	if result.DuplicateResults != nil {
+		duplicate = *result.DuplicateResults
+	}
+	if duplicate {
+		// Ignore errors, duplicate result publishing is on a "best-effort" basis.
+		_ = r.conn.Publish(r.duplicateSubject(), jsonVal)
+	}
+	return nil
+}
+
+func (r *natsQueryResults) ReadChannel(ctx context.Context, query fleet.DistributedQueryCampaign) (<-chan interface{}, error) {
+	subject := r.resultsSubject(fmt.Sprint(query.ID))
+
+	// The messages are only forwarded to outChannel by the goroutine below,
+	// which closes it, so that the subscription handler cannot send on the
+	// closed channel.
+	msgChannel := make(chan *nats.Msg)
+	sub, err := r.js.Subscribe(subject, func(msg *nats.Msg) {
+		select {
+		case msgChannel <- msg:
+		case <-ctx.Done():
+		}
+	}, nats.OrderedConsumer(), nats.DeliverNew())
+	if err != nil {
+		return nil, ctxerr.Wrapf(ctx, err, "subscribe to subject %s", subject)
+	}
+
+	// The consumer is created before responding to the listener requests, so
+	// that the results published once a listener responded are received.
+	listenerSub, err := r.conn.Subscribe(r.listenersSubject(query.ID), func(msg *nats.Msg) {
+		_ = msg.Respond(nil)
+	})
+	if err != nil {
+		_ = sub.Unsubscribe()
+		return nil, ctxerr.Wrapf(ctx, err, "subscribe to listeners of subject %s", subject)
+	}
+
+	outChannel := make(chan interface{})
+	go func() {
+		defer close(outChannel)
+		defer func() {
+			_ = listenerSub.Unsubscribe()
+			_ = sub.Unsubscribe()
+		}()
+
+		for {
+			select {
+			case msg := <-msgChannel:
+				var res fleet.DistributedQueryResult
+				if err := json.Unmarshal(msg.Data, &res); err != nil {
+					if writeOrDone(ctx, outChannel, err) {
+						return
+					}
+					continue
+				}
+				if writeOrDone(ctx, outChannel, res) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return outChannel, nil
+}
+
+// HealthCheck verifies that the store is connected to NATS and that the
+// stream of the results is available, returning an error otherwise.
+func (r *natsQueryResults) HealthCheck() error {
+	if !r.conn.IsConnected() {
+		return errors.New("not connected to NATS")
+	}
+	if _, err := r.js.StreamInfo(r.stream); err != nil {
+		return fmt.Errorf("get stream %s: %w", r.stream, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupNATSForTest(t *testing.T) *natsQueryResults {
+	if _, ok := os.LookupEnv("NATS_TEST"); !ok {
+		t.Skip("set NATS_TEST environment variable to run NATS-based tests")
+	}
+
+	// each test uses its own stream so that the tests can run concurrently
+	name := fmt.Sprintf("fleet_test_%d", time.Now().UnixNano())
+	store, err := NewNATSQueryResults(config.NATSConfig{
+		URL:            "nats://127.0.0.1:4222",
+		ConnectTimeout: 5 * time.Second,
+		Stream:         name,
+		SubjectPrefix:  name,
+		Replicas:       1,
+		MaxAge:         time.Minute,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = store.js.DeleteStream(name)
+		store.Close()
+	})
+	return store
+}
+
+func TestNATSQueryResultsStore(t *testing.T) {
+	store := setupNATSForTest(t)
+	require.NoError(t, store.HealthCheck())
+
+	result := fleet.DistributedQueryResult{
+		DistributedQueryCampaignID: 1,
+		Rows:                       []map[string]string{{"foo": "bar"}},
+		Host: fleet.Host{
+			ID:       1,
+			Hostname: "foo",
+			// the times are set to avoid issues with roundtrip serializing the
+			// zero time value.
+			UpdateCreateTimestamps: fleet.UpdateCreateTimestamps{
+				UpdateTimestamp: fleet.UpdateTimestamp{UpdatedAt: time.Now().UTC()},
+			},
+			DetailUpdatedAt: time.Now().UTC(),
+		},
+	}
+
+	// write with no listener
+	err := store.WriteResult(result)
+	require.Error(t, err)
+	var pse Error
+	require.True(t, errors.As(err, &pse))
+	assert.True(t, pse.NoSubscriber())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	channel, err := store.ReadChannel(ctx, fleet.DistributedQueryCampaign{ID: 1})
+	require.NoError(t, err)
+
+	// the duplicated results are published to their own subject
+	dups, err := store.conn.SubscribeSync(store.duplicateSubject())
+	require.NoError(t, err)
+	defer dups.Unsubscribe() //nolint:errcheck
+
+	for i := 0; i < 3; i++ {
+		result.Rows[0]["i"] = fmt.Sprint(i)
+		result.DuplicateResults = ptr.Bool(i == 1)
+		require.NoError(t, store.WriteResult(result))
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-channel:
+			res, ok := msg.(fleet.DistributedQueryResult)
+			require.True(t, ok, "unexpected message %v", msg)
+			assert.Equal(t, uint(1), res.DistributedQueryCampaignID)
+			assert.Equal(t, "foo", res.Host.Hostname)
+			assert.Equal(t, fmt.Sprint(i), res.Rows[0]["i"])
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for result")
+		}
+	}
+	dup, err := dups.NextMsg(time.Second)
+	require.NoError(t, err)
+	assert.Contains(t, string(dup.Data), `"i":"1"`)
+	_, err = dups.NextMsg(100 * time.Millisecond)
+	assert.ErrorIs(t, err, nats.ErrTimeout)
+
+	// the results of the other campaigns are not received
+	_, err = store.ReadChannel(ctx, fleet.DistributedQueryCampaign{ID: 2})
+	require.NoError(t, err)
+	result.DistributedQueryCampaignID = 2
+	require.NoError(t, store.WriteResult(result))
+	select {
+	case msg := <-channel:
+		t.Fatalf("unexpected message %v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// the channel is closed once the context is cancelled, and the campaign
+	// has no listener anymore
+	cancel()
+	select {
+	case _, ok := <-channel:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the channel to close")
+	}
+	result.DistributedQueryCampaignID = 1
+	require.Eventually(t, func() bool {
+		err := store.WriteResult(result)
+		return errors.As(err, &pse) && pse.NoSubscriber()
+	}, 5*time.Second, 100*time.Millisecond)
+}
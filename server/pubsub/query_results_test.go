@@ -2,12 +2,16 @@ package pubsub
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/datastore/redis"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	redigo "github.com/gomodule/redigo/redis"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -280,3 +284,122 @@ func TestQueryResultsStore(t *testing.T) {
 		runTest(t, store)
 	})
 }
+
+func TestQueryResultsStoreClusterFaults(t *testing.T) {
+	newResult := func(hostID uint) fleet.DistributedQueryResult {
+		return fleet.DistributedQueryResult{
+			DistributedQueryCampaignID: 1,
+			Rows:                       []map[string]string{{"host": fmt.Sprint(hostID)}},
+			Host: fleet.Host{
+				ID: hostID,
+				UpdateCreateTimestamps: fleet.UpdateCreateTimestamps{
+					UpdateTimestamp: fleet.UpdateTimestamp{
+						UpdatedAt: time.Now().UTC(),
+					},
+					CreateTimestamp: fleet.CreateTimestamp{
+						CreatedAt: time.Now().UTC(),
+					},
+				},
+				DetailUpdatedAt: time.Now().UTC(),
+				SeenTime:        time.Now().UTC(),
+			},
+		}
+	}
+
+	runTest := func(t *testing.T, store *redisQueryResults, faults *clusterFaults) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		channel, err := store.ReadChannel(ctx, fleet.DistributedQueryCampaign{ID: 1})
+		require.NoError(t, err)
+
+		var results []fleet.DistributedQueryResult
+		var errs []error
+		var readerWg sync.WaitGroup
+		readerWg.Add(1)
+		go func() {
+			defer readerWg.Done()
+			for res := range channel {
+				switch res := res.(type) {
+				case fleet.DistributedQueryResult:
+					results = append(results, res)
+				case error:
+					errs = append(errs, res)
+				}
+			}
+		}()
+
+		// Wait to ensure subscriptions are activated before writing
+		time.Sleep(100 * time.Millisecond)
+
+		expected := []fleet.DistributedQueryResult{newResult(1)}
+		require.NoError(t, store.WriteResult(expected[0]))
+
+		// the subscription is dropped by the failover, results are published
+		// again once the channel was subscribed again.
+		faults.failover(t)
+		expected = append(expected, newResult(2))
+		require.Eventually(t, func() bool {
+			return store.WriteResult(expected[1]) == nil
+		}, 5*time.Second, 50*time.Millisecond)
+
+		// publishing is retried while the cluster is down or the slot migrated
+		faults.failover(t)
+		expected = append(expected, newResult(3))
+		require.Eventually(t, func() bool {
+			return store.WriteResult(expected[2]) == nil
+		}, 5*time.Second, 50*time.Millisecond)
+
+		faults.migrateSlot()
+		expected = append(expected, newResult(4))
+		require.NoError(t, store.WriteResult(expected[3]))
+
+		// publishing fails if the errors persist
+		faults.failPublish(io.EOF, io.EOF, io.EOF, io.EOF, io.EOF)
+		err = store.WriteResult(newResult(5))
+		require.Error(t, err)
+		var pubsubErr Error
+		require.False(t, errors.As(err, &pubsubErr) && pubsubErr.NoSubscriber())
+
+		// non-transient errors are not retried
+		faults.failPublish(redigo.Error("ERR unknown command"))
+		require.Error(t, store.WriteResult(newResult(6)))
+		expected = append(expected, newResult(7))
+		require.NoError(t, store.WriteResult(expected[4]))
+
+		time.Sleep(300 * time.Millisecond)
+		cancel()
+		if waitTimeout(&readerWg, 5*time.Second) {
+			t.Error("Timed out waiting for reader to join")
+		}
+
+		assert.EqualValues(t, expected, results)
+		assert.Empty(t, errs)
+	}
+
+	t.Run("standalone", func(t *testing.T) {
+		store, faults := setupRedisWithFaultsForTest(t, false, false)
+		runTest(t, store, faults)
+	})
+
+	t.Run("cluster", func(t *testing.T) {
+		store, faults := setupRedisWithFaultsForTest(t, true, true)
+		runTest(t, store, faults)
+	})
+}
+
+func TestQueryResultsStoreDuplicate(t *testing.T) {
+	result := fleet.DistributedQueryResult{DistributedQueryCampaignID: 1}
+
+	// the configuration of the store applies by default
+	store := NewRedisQueryResults(nil, true)
+	assert.True(t, store.duplicate(result))
+	store = NewRedisQueryResults(nil, false)
+	assert.False(t, store.duplicate(result))
+
+	// unless overridden by the campaign
+	result.DuplicateResults = ptr.Bool(true)
+	assert.True(t, store.duplicate(result))
+	store = NewRedisQueryResults(nil, true)
+	result.DuplicateResults = ptr.Bool(false)
+	assert.False(t, store.duplicate(result))
+}
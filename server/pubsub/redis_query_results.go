@@ -4,23 +4,40 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/datastore/redis"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	redigo "github.com/gomodule/redigo/redis"
 )
 
+// retryMaxAttempts is the maximum number of attempts to publish a result or
+// to subscribe again to a campaign's channel after the subscription was lost,
+// when the errors are transient (e.g. during a Redis Cluster failover or slot
+// migration).
+const retryMaxAttempts = 5
+
 type redisQueryResults struct {
 	// connection pool
 	pool             fleet.RedisPool
 	duplicateResults bool
+
+	// testWrapConn, if set, wraps the connections used by the store. It is
+	// used in tests to simulate the failures of a Redis Cluster.
+	testWrapConn func(redigo.Conn) redigo.Conn
 }
 
 var _ fleet.QueryResultStore = &redisQueryResults{}
 
+func init() {
+	RegisterResultStore("redis", func(config config.FleetConfig, pool fleet.RedisPool) (fleet.QueryResultStore, error) {
+		return NewRedisQueryResults(pool, config.Redis.DuplicateResults), nil
+	})
+}
+
 // NewRedisQueryResults creats a new Redis implementation of the
 // QueryResultStore interface using the provided Redis connection pool.
 func NewRedisQueryResults(pool fleet.RedisPool, duplicateResults bool) *redisQueryResults {
@@ -36,11 +53,38 @@ func (r *redisQueryResults) Pool() fleet.RedisPool {
 	return r.pool
 }
 
-func (r *redisQueryResults) WriteResult(result fleet.DistributedQueryResult) error {
+func (r *redisQueryResults) conn() redigo.Conn {
 	// pub-sub can publish and listen on any node in the cluster
 	conn := redis.ReadOnlyConn(r.pool, r.pool.Get())
-	defer conn.Close()
+	if r.testWrapConn != nil {
+		conn = r.testWrapConn(conn)
+	}
+	return conn
+}
 
+// retryTransient runs op, retrying it with an exponential backoff while it
+// fails with a transient error. The cluster's mapping is refreshed before each
+// retry, as the error may be caused by a change of the cluster's topology.
+func (r *redisQueryResults) retryTransient(ctx context.Context, op func() error) error {
+	bo := backoff.NewExponentialBackOff()
+	bo.InitialInterval = 100 * time.Millisecond
+
+	return backoff.Retry(func() error {
+		err := op()
+		if err == nil {
+			return nil
+		}
+		if !redis.IsTransientError(err) {
+			return backoff.Permanent(err)
+		}
+		// ignore the refresh errors, the next attempt fails if the cluster is
+		// still unavailable.
+		_ = redis.RefreshCluster(r.pool)
+		return err
+	}, backoff.WithContext(backoff.WithMaxRetries(bo, retryMaxAttempts-1), ctx))
+}
+
+func (r *redisQueryResults) WriteResult(result fleet.DistributedQueryResult) error {
 	channelName := pubSubForID(result.DistributedQueryCampaignID)
 
 	jsonVal, err := json.Marshal(&result)
@@ -48,13 +92,15 @@ func (r *redisQueryResults) WriteResult(result fleet.DistributedQueryResult) err
 		return fmt.Errorf("marshalling JSON for result: %w", err)
 	}
 
-	hasSubs, err := redis.PublishHasListeners(r.pool, conn, channelName, string(jsonVal))
-
-	if hasSubs && r.duplicateResults {
-		// Ignore errors, duplicate result publishing is on a "best-effort" basis.
-		_, _ = redigo.Int(conn.Do("PUBLISH", "LQDuplicate", string(jsonVal)))
-	}
-
+	// Publishing is retried on a new connection if it failed with a transient
+	// error, which may rarely publish the same result twice if the connection
+	// was lost after the result was published.
+	var hasSubs bool
+	err = r.retryTransient(context.Background(), func() error {
+		var err error
+		hasSubs, err = r.publish(channelName, string(jsonVal), r.duplicate(result))
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("PUBLISH failed to channel "+channelName+": %w", err)
 	}
@@ -65,6 +111,29 @@ func (r *redisQueryResults) WriteResult(result fleet.DistributedQueryResult) err
 	return nil
 }
 
+// duplicate returns whether the result is duplicated to the LQDuplicate
+// channel, as configured for the store unless overridden by the campaign of
+// the result.
+func (r *redisQueryResults) duplicate(result fleet.DistributedQueryResult) bool {
+	if result.DuplicateResults != nil {
+		return *result.DuplicateResults
+	}
+	return r.duplicateResults
+}
+
+func (r *redisQueryResults) publish(channelName, val string, duplicate bool) (bool, error) {
+	conn := r.conn()
+	defer conn.Close()
+
+	hasSubs, err := redis.PublishHasListeners(r.pool, conn, channelName, val)
+
+	if hasSubs && duplicate {
+		// Ignore errors, duplicate result publishing is on a "best-effort" basis.
+		_, _ = redigo.Int(conn.Do("PUBLISH", "LQDuplicate", val))
+	}
+	return hasSubs, err
+}
+
 // writeOrDone tries to write the item into the channel taking into account context.Done(). If context is done, returns
 // true, otherwise false
 func writeOrDone(ctx context.Context, ch chan<- interface{}, item interface{}) bool {
@@ -106,72 +175,123 @@ func receiveMessages(ctx context.Context, conn *redigo.PubSubConn, outChan chan<
 	}
 }
 
-func (r *redisQueryResults) ReadChannel(ctx context.Context, query fleet.DistributedQueryCampaign) (<-chan interface{}, error) {
-	outChannel := make(chan interface{})
-	msgChannel := make(chan interface{})
-
-	// pub-sub can publish and listen on any node in the cluster
-	conn := redis.ReadOnlyConn(r.pool, r.pool.Get())
+func (r *redisQueryResults) subscribe(pubSubName string) (*redigo.PubSubConn, error) {
+	conn := r.conn()
 	psc := &redigo.PubSubConn{Conn: conn}
-	pubSubName := pubSubForID(query.ID)
 	if err := psc.Subscribe(pubSubName); err != nil {
 		// Explicit conn.Close() here because we can't defer it until in the goroutine
 		_ = conn.Close()
-		return nil, ctxerr.Wrapf(ctx, err, "subscribe to channel %s", pubSubName)
+		return nil, err
+	}
+	return psc, nil
+}
+
+// resubscribe subscribes again to the channel after the subscription was
+// lost, e.g. because the node it was connected to failed. Unlike the initial
+// subscription, it waits for the confirmation of the subscription so that a
+// subscription to a node that is still unavailable is retried.
+func (r *redisQueryResults) resubscribe(ctx context.Context, pubSubName string) (*redigo.PubSubConn, error) {
+	var psc *redigo.PubSubConn
+	err := r.retryTransient(ctx, func() error {
+		var err error
+		psc, err = r.subscribe(pubSubName)
+		if err != nil {
+			return err
+		}
+		switch msg := psc.ReceiveWithTimeout(5 * time.Second).(type) {
+		case redigo.Subscription:
+			return nil
+		case error:
+			err = msg
+		default:
+			err = fmt.Errorf("unexpected message %T while subscribing", msg)
+		}
+		_ = psc.Conn.Close()
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
+	return psc, nil
+}
 
-	var wg sync.WaitGroup
+// forwardMessages forwards the results received on the subscription to
+// outChannel until the context is cancelled or the subscription is lost, in
+// which case it returns true. The subscription's connection is closed on
+// return.
+func forwardMessages(ctx context.Context, psc *redigo.PubSubConn, pubSubName string, outChannel chan<- interface{}) (lost bool) {
+	msgChannel := make(chan interface{})
 
 	// Run a separate goroutine feeding redis messages into msgChannel.
-	wg.Add(+1)
+	receiveDone := make(chan struct{})
 	go func() {
-		defer wg.Done()
+		defer close(receiveDone)
 
 		receiveMessages(ctx, psc, msgChannel)
 	}()
 
-	wg.Add(+1)
-	go func() {
-		defer wg.Done()
-		defer close(outChannel)
+	defer func() {
+		// unsubscribing also unblocks receiveMessages if the context was
+		// cancelled while it was waiting for a message.
+		_ = psc.Unsubscribe(pubSubName)
+		<-receiveDone
+		psc.Conn.Close()
+	}()
 
-		for {
-			// Loop reading messages from conn.Receive() (via msgChannel) until the context is cancelled.
-			select {
-			case msg, ok := <-msgChannel:
-				if !ok {
-					writeOrDone(ctx, outChannel, ctxerr.New(ctx, "unexpected exit in receiveMessages"))
-					return
-				}
+	for {
+		// Loop reading messages from conn.Receive() (via msgChannel) until the context is cancelled.
+		select {
+		case msg, ok := <-msgChannel:
+			if !ok {
+				return ctx.Err() == nil
+			}
 
-				switch msg := msg.(type) {
-				case redigo.Message:
-					var res fleet.DistributedQueryResult
-					err := json.Unmarshal(msg.Data, &res)
-					if err != nil {
-						if writeOrDone(ctx, outChannel, err) {
-							return
-						}
-					}
-					if writeOrDone(ctx, outChannel, res) {
-						return
-					}
-				case error:
-					if writeOrDone(ctx, outChannel, ctxerr.Wrap(ctx, msg, "read from redis")) {
-						return
+			switch msg := msg.(type) {
+			case redigo.Message:
+				var res fleet.DistributedQueryResult
+				err := json.Unmarshal(msg.Data, &res)
+				if err != nil {
+					if writeOrDone(ctx, outChannel, err) {
+						return false
 					}
 				}
-
-			case <-ctx.Done():
-				return
+				if writeOrDone(ctx, outChannel, res) {
+					return false
+				}
 			}
+			// errors are not forwarded, receiveMessages exits on error and the
+			// subscription is then considered lost.
+
+		case <-ctx.Done():
+			return false
 		}
-	}()
+	}
+}
+
+func (r *redisQueryResults) ReadChannel(ctx context.Context, query fleet.DistributedQueryCampaign) (<-chan interface{}, error) {
+	outChannel := make(chan interface{})
+
+	pubSubName := pubSubForID(query.ID)
+	psc, err := r.subscribe(pubSubName)
+	if err != nil {
+		return nil, ctxerr.Wrapf(ctx, err, "subscribe to channel %s", pubSubName)
+	}
 
 	go func() {
-		wg.Wait()
-		psc.Unsubscribe(pubSubName)
-		conn.Close()
+		defer close(outChannel)
+
+		for forwardMessages(ctx, psc, pubSubName, outChannel) {
+			// The subscription was lost, e.g. during a Redis Cluster failover:
+			// subscribe again so that the campaign keeps receiving results. The
+			// results published until then are lost.
+			psc, err = r.resubscribe(ctx, pubSubName)
+			if err != nil {
+				if ctx.Err() == nil {
+					writeOrDone(ctx, outChannel, ctxerr.Wrapf(ctx, err, "subscribe again to channel %s", pubSubName))
+				}
+				return
+			}
+		}
 	}()
 
 	return outChannel, nil
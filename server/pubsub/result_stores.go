@@ -0,0 +1,71 @@
+package pubsub
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// ResultStoreFactory creates a QueryResultStore from the Fleet configuration.
+// The Redis pool is the one of the Fleet server, it is only used by the stores
+// relying on Redis.
+type ResultStoreFactory func(config config.FleetConfig, pool fleet.RedisPool) (fleet.QueryResultStore, error)
+
+var (
+	resultStoresMu sync.RWMutex
+	resultStores   = make(map[string]ResultStoreFactory)
+)
+
+// RegisterResultStore registers the factory of a QueryResultStore under the
+// given name, so that it can be selected with the
+// osquery.live_query_result_store configuration. It panics if the name is
+// empty, the factory is nil or a store is already registered under that name,
+// and is meant to be called at startup.
+func RegisterResultStore(name string, factory ResultStoreFactory) {
+	resultStoresMu.Lock()
+	defer resultStoresMu.Unlock()
+
+	if name == "" {
+		panic("pubsub: RegisterResultStore name is empty")
+	}
+	if factory == nil {
+		panic("pubsub: RegisterResultStore factory is nil")
+	}
+	if _, dup := resultStores[name]; dup {
+		panic("pubsub: RegisterResultStore called twice for store " + name)
+	}
+	resultStores[name] = factory
+}
+
+// ResultStores returns the sorted names of the registered stores.
+func ResultStores() []string {
+	resultStoresMu.RLock()
+	defer resultStoresMu.RUnlock()
+
+	names := make([]string, 0, len(resultStores))
+	for name := range resultStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewQueryResultStore creates the QueryResultStore registered under the given
+// name.
+func NewQueryResultStore(name string, config config.FleetConfig, pool fleet.RedisPool) (fleet.QueryResultStore, error) {
+	resultStoresMu.RLock()
+	factory, ok := resultStores[name]
+	resultStoresMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown live query result store %q, must be one of %v", name, ResultStores())
+	}
+	store, err := factory(config, pool)
+	if err != nil {
+		return nil, fmt.Errorf("create %s live query result store: %w", name, err)
+	}
+	return store, nil
+}
@@ -0,0 +1,32 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/datastore/redis/redistest"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultStores(t *testing.T) {
+	assert.Equal(t, []string{"nats", "redis"}, ResultStores())
+
+	cfg := config.TestConfig()
+	cfg.Redis.DuplicateResults = true
+	store, err := NewQueryResultStore("redis", cfg, redistest.NopRedis())
+	require.NoError(t, err)
+	require.IsType(t, &redisQueryResults{}, store)
+	assert.True(t, store.(*redisQueryResults).duplicateResults)
+
+	_, err = NewQueryResultStore("kafka", cfg, redistest.NopRedis())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown live query result store "kafka"`)
+
+	assert.Panics(t, func() {
+		RegisterResultStore("redis", func(config.FleetConfig, fleet.RedisPool) (fleet.QueryResultStore, error) {
+			return NewInmemQueryResults(), nil
+		})
+	})
+}
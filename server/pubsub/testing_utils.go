@@ -1,9 +1,17 @@
 package pubsub
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/fleetdm/fleet/v4/server/datastore/redis"
 	"github.com/fleetdm/fleet/v4/server/datastore/redis/redistest"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/stretchr/testify/require"
 )
 
 func SetupRedisForTest(t *testing.T, cluster, readReplica bool) *redisQueryResults {
@@ -11,3 +19,156 @@ func SetupRedisForTest(t *testing.T, cluster, readReplica bool) *redisQueryResul
 	pool := redistest.SetupRedis(t, "zz", cluster, false, readReplica)
 	return NewRedisQueryResults(pool, dupResults)
 }
+
+// setupRedisWithFaultsForTest is like SetupRedisForTest, but the connections
+// of the returned store can be made to fail as they would during a Redis
+// Cluster slot migration or primary failover, using the returned
+// clusterFaults.
+func setupRedisWithFaultsForTest(t *testing.T, cluster, readReplica bool) (*redisQueryResults, *clusterFaults) {
+	store := SetupRedisForTest(t, cluster, readReplica)
+	faults := &clusterFaults{
+		pool:   store.pool,
+		prefix: fmt.Sprintf("fleet_pubsub_test_%d", time.Now().UnixNano()),
+	}
+	store.testWrapConn = faults.wrapConn
+	return store, faults
+}
+
+// clusterFaults simulates the failures of a Redis Cluster on the connections
+// of a store:
+//
+//   - while a slot is migrated to another node, the commands are redirected
+//     with ASK then MOVED errors;
+//   - while a replica is promoted after its primary failed, the commands fail
+//     with CLUSTERDOWN errors or because the connection was lost, and the
+//     subscriptions of the clients connected to the primary are dropped.
+type clusterFaults struct {
+	pool fleet.RedisPool
+	// prefix is the prefix of the client names of the connections of the
+	// store, used to find the connections to drop on the Redis nodes.
+	prefix string
+
+	mu sync.Mutex
+	// publishErrs are returned, in order, by the next PUBLISH commands
+	// instead of running them.
+	publishErrs []error
+	conns       int
+}
+
+func (f *clusterFaults) wrapConn(conn redigo.Conn) redigo.Conn {
+	f.mu.Lock()
+	f.conns++
+	name := fmt.Sprintf("%s_%d", f.prefix, f.conns)
+	f.mu.Unlock()
+
+	// name the connection so that its subscriptions can be found and dropped
+	// on the node it is connected to.
+	_, _ = conn.Do("CLIENT", "SETNAME", name)
+	return &faultyConn{Conn: conn, faults: f}
+}
+
+func (f *clusterFaults) nextPublishErr() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.publishErrs) == 0 {
+		return nil
+	}
+	err := f.publishErrs[0]
+	f.publishErrs = f.publishErrs[1:]
+	return err
+}
+
+// failPublish makes the next PUBLISH commands fail with errs, in order.
+func (f *clusterFaults) failPublish(errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.publishErrs = append(f.publishErrs, errs...)
+}
+
+// migrateSlot simulates the migration of a slot to another node: the next
+// PUBLISH commands are redirected to the node importing the slot, then to the
+// node that owns it once the migration completed.
+func (f *clusterFaults) migrateSlot() {
+	f.failPublish(
+		redigo.Error("ASK 3999 127.0.0.1:7002"),
+		redigo.Error("MOVED 3999 127.0.0.1:7002"),
+	)
+}
+
+// failover simulates the failure of a primary until its replica is promoted:
+// the next PUBLISH commands fail while the cluster is down, and the
+// subscriptions of the store are dropped.
+func (f *clusterFaults) failover(t *testing.T) {
+	f.failPublish(
+		redigo.Error("CLUSTERDOWN The cluster is down"),
+		redigo.Error("CLUSTERDOWN The cluster is down"),
+	)
+	f.dropSubscriptions(t)
+}
+
+// dropSubscriptions kills, on all the nodes, the subscribed connections of
+// the store, as happens when the node they are connected to fails.
+func (f *clusterFaults) dropSubscriptions(t *testing.T) {
+	var killed int
+	for _, replicas := range []bool{false, true} {
+		err := redis.EachNode(f.pool, replicas, func(conn redigo.Conn) error {
+			clients, err := redigo.String(conn.Do("CLIENT", "LIST", "TYPE", "pubsub"))
+			if err != nil {
+				return err
+			}
+			for _, client := range strings.Split(clients, "\n") {
+				var id, name string
+				for _, field := range strings.Fields(client) {
+					if v := strings.TrimPrefix(field, "id="); v != field {
+						id = v
+					}
+					if v := strings.TrimPrefix(field, "name="); v != field {
+						name = v
+					}
+				}
+				if id == "" || !strings.HasPrefix(name, f.prefix) {
+					continue
+				}
+				if _, err := conn.Do("CLIENT", "KILL", "ID", id); err != nil {
+					return err
+				}
+				killed++
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	require.NotZero(t, killed, "no subscription to drop")
+}
+
+// faultyConn is a connection of a store with simulated cluster faults.
+type faultyConn struct {
+	redigo.Conn
+	faults *clusterFaults
+}
+
+var _ redigo.ConnWithTimeout = (*faultyConn)(nil)
+
+func (c *faultyConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	if strings.EqualFold(cmd, "PUBLISH") {
+		if err := c.faults.nextPublishErr(); err != nil {
+			return nil, err
+		}
+	}
+	return c.Conn.Do(cmd, args...)
+}
+
+func (c *faultyConn) DoWithTimeout(timeout time.Duration, cmd string, args ...interface{}) (interface{}, error) {
+	if strings.EqualFold(cmd, "PUBLISH") {
+		if err := c.faults.nextPublishErr(); err != nil {
+			return nil, err
+		}
+	}
+	return redigo.DoWithTimeout(c.Conn, timeout, cmd, args...)
+}
+
+func (c *faultyConn) ReceiveWithTimeout(timeout time.Duration) (interface{}, error) {
+	return redigo.ReceiveWithTimeout(c.Conn, timeout)
+}
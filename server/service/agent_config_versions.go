@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List Agent Config Versions
+////////////////////////////////////////////////////////////////////////////////
+
+type listAgentConfigVersionsRequest struct {
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listAgentConfigVersionsResponse struct {
+	Versions []*fleet.AgentConfigVersion `json:"versions"`
+	Err      error                       `json:"error,omitempty"`
+}
+
+func (r listAgentConfigVersionsResponse) error() error { return r.Err }
+
+func listAgentConfigVersionsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listAgentConfigVersionsRequest)
+	versions, err := svc.ListAgentConfigVersions(ctx, req.ListOptions)
+	if err != nil {
+		return listAgentConfigVersionsResponse{Err: err}, nil
+	}
+	return listAgentConfigVersionsResponse{Versions: versions}, nil
+}
+
+func (svc *Service) ListAgentConfigVersions(ctx context.Context, opt fleet.ListOptions) ([]*fleet.AgentConfigVersion, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	// the latest versions are listed first by default
+	if opt.OrderKey == "" {
+		opt.OrderKey = "id"
+		opt.OrderDirection = fleet.OrderDescending
+	}
+	return svc.ds.ListAgentConfigVersions(ctx, opt)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Agent Config Version
+////////////////////////////////////////////////////////////////////////////////
+
+type getAgentConfigVersionRequest struct {
+	ID uint `url:"id"`
+}
+
+type getAgentConfigVersionResponse struct {
+	Version *fleet.AgentConfigVersion `json:"version,omitempty"`
+	Err     error                     `json:"error,omitempty"`
+}
+
+func (r getAgentConfigVersionResponse) error() error { return r.Err }
+
+func getAgentConfigVersionEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getAgentConfigVersionRequest)
+	version, err := svc.GetAgentConfigVersion(ctx, req.ID)
+	if err != nil {
+		return getAgentConfigVersionResponse{Err: err}, nil
+	}
+	return getAgentConfigVersionResponse{Version: version}, nil
+}
+
+func (svc *Service) GetAgentConfigVersion(ctx context.Context, id uint) (*fleet.AgentConfigVersion, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	return svc.ds.AgentConfigVersion(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Rollback Agent Config
+////////////////////////////////////////////////////////////////////////////////
+
+type rollbackAgentConfigRequest struct {
+	ID uint `url:"id"`
+}
+
+type rollbackAgentConfigResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r rollbackAgentConfigResponse) error() error { return r.Err }
+
+func rollbackAgentConfigEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*rollbackAgentConfigRequest)
+	if err := svc.RollbackAgentConfig(ctx, req.ID); err != nil {
+		return rollbackAgentConfigResponse{Err: err}, nil
+	}
+	return rollbackAgentConfigResponse{}, nil
+}
+
+// RollbackAgentConfig restores the global agent options and the packs of the
+// version. The packs that did not exist in the version are deleted. The
+// rollback is itself recorded as a new version.
+func (svc *Service) RollbackAgentConfig(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	version, err := svc.ds.AgentConfigVersion(ctx, id)
+	if err != nil {
+		return err
+	}
+	config := version.Config
+	if config == nil {
+		config = &fleet.AgentConfig{}
+	}
+
+	if len(config.Packs) > 0 {
+		if err := svc.ds.ApplyPackSpecs(ctx, config.Packs); err != nil {
+			return ctxerr.Wrap(ctx, err, "apply pack specs")
+		}
+	}
+
+	versionPacks := make(map[string]bool, len(config.Packs))
+	for _, spec := range config.Packs {
+		versionPacks[spec.Name] = true
+	}
+	currentPacks, err := svc.ds.GetPackSpecs(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get pack specs")
+	}
+	for _, spec := range currentPacks {
+		if versionPacks[spec.Name] {
+			continue
+		}
+		if err := svc.ds.DeletePack(ctx, spec.Name); err != nil {
+			return ctxerr.Wrapf(ctx, err, "delete pack %s", spec.Name)
+		}
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return err
+	}
+	appConfig.AgentOptions = config.AgentOptions
+	if err := svc.ds.SaveAppConfig(ctx, appConfig); err != nil {
+		return err
+	}
+	svc.clientConfigCache.invalidate()
+
+	if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+		return err
+	}
+
+	return svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeRolledBackAgentConfig,
+		&map[string]interface{}{"version_id": id},
+	)
+}
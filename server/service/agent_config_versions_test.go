@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackAgentConfig(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AgentConfigVersionFunc = func(ctx context.Context, id uint) (*fleet.AgentConfigVersion, error) {
+		return &fleet.AgentConfigVersion{
+			ID: id,
+			Config: &fleet.AgentConfig{
+				AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":10}}}`)),
+				Packs:        []*fleet.PackSpec{{Name: "pack1"}},
+			},
+		}, nil
+	}
+	var appliedPacks []*fleet.PackSpec
+	ds.ApplyPackSpecsFunc = func(ctx context.Context, specs []*fleet.PackSpec) error {
+		appliedPacks = specs
+		return nil
+	}
+	ds.GetPackSpecsFunc = func(ctx context.Context) ([]*fleet.PackSpec, error) {
+		return []*fleet.PackSpec{{Name: "pack1"}, {Name: "pack2"}}, nil
+	}
+	var deletedPacks []string
+	ds.DeletePackFunc = func(ctx context.Context, name string) error {
+		deletedPacks = append(deletedPacks, name)
+		return nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	var savedAppConfig *fleet.AppConfig
+	ds.SaveAppConfigFunc = func(ctx context.Context, info *fleet.AppConfig) error {
+		savedAppConfig = info
+		return nil
+	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
+	var activityDetails map[string]interface{}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, fleet.ActivityTypeRolledBackAgentConfig, activityType)
+		activityDetails = *details
+		return nil
+	}
+
+	err := svc.RollbackAgentConfig(test.UserContext(test.UserMaintainer), 1)
+	require.Error(t, err)
+	assert.False(t, ds.AgentConfigVersionFuncInvoked)
+
+	require.NoError(t, svc.RollbackAgentConfig(test.UserContext(test.UserAdmin), 1))
+	require.Len(t, appliedPacks, 1)
+	assert.Equal(t, "pack1", appliedPacks[0].Name)
+	assert.Equal(t, []string{"pack2"}, deletedPacks)
+	require.NotNil(t, savedAppConfig)
+	assert.JSONEq(t, `{"config":{"options":{"distributed_interval":10}}}`, string(*savedAppConfig.AgentOptions))
+	assert.True(t, ds.RecordAgentConfigVersionFuncInvoked)
+	assert.Equal(t, map[string]interface{}{"version_id": uint(1)}, activityDetails)
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Start Agent Options Rollout
+////////////////////////////////////////////////////////////////////////////////
+
+type startAgentOptionsRolloutRequest struct {
+	fleet.AgentOptionsRolloutPayload
+}
+
+type startAgentOptionsRolloutResponse struct {
+	Rollout *fleet.AgentOptionsRollout `json:"rollout,omitempty"`
+	Err     error                      `json:"error,omitempty"`
+}
+
+func (r startAgentOptionsRolloutResponse) error() error { return r.Err }
+
+func startAgentOptionsRolloutEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*startAgentOptionsRolloutRequest)
+	rollout, err := svc.StartAgentOptionsRollout(ctx, req.AgentOptionsRolloutPayload)
+	if err != nil {
+		return startAgentOptionsRolloutResponse{Err: err}, nil
+	}
+	return startAgentOptionsRolloutResponse{Rollout: rollout}, nil
+}
+
+func (svc *Service) StartAgentOptionsRollout(ctx context.Context, payload fleet.AgentOptionsRolloutPayload) (*fleet.AgentOptionsRollout, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	invalid := &fleet.InvalidArgumentError{}
+	if len(payload.AgentOptions) == 0 {
+		invalid.Append("agent_options", "agent options are required")
+	} else if err := fleet.ValidateJSONAgentOptions(payload.AgentOptions); err != nil {
+		invalid.Append("agent_options", err.Error())
+	}
+	if payload.Percentage > 100 {
+		invalid.Append("percentage", "must be between 0 and 100")
+	}
+	if payload.Percentage == 0 && payload.LabelID == nil {
+		invalid.Append("percentage", "a percentage or a label_id is required")
+	}
+	if invalid.HasErrors() {
+		return nil, ctxerr.Wrap(ctx, invalid)
+	}
+
+	if payload.LabelID != nil {
+		if _, err := svc.ds.Label(ctx, *payload.LabelID); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get rollout label")
+		}
+	}
+
+	switch _, err := svc.ds.ActiveAgentOptionsRollout(ctx); {
+	case err == nil:
+		return nil, fleet.NewConflictError("an agent options rollout is already active, promote or abort it first")
+	case !fleet.IsNotFound(err):
+		return nil, ctxerr.Wrap(ctx, err, "get active agent options rollout")
+	}
+
+	rollout := &fleet.AgentOptionsRollout{
+		AgentOptions: payload.AgentOptions,
+		Percentage:   payload.Percentage,
+		LabelID:      payload.LabelID,
+		Status:       fleet.AgentOptionsRolloutStatusActive,
+	}
+	if user := authz.UserFromContext(ctx); user != nil {
+		rollout.AuthorID = &user.ID
+	}
+	rollout, err := svc.ds.NewAgentOptionsRollout(ctx, rollout)
+	if err != nil {
+		return nil, err
+	}
+	svc.clientConfigCache.invalidate()
+
+	if err := svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeStartedAgentOptionsRollout,
+		&map[string]interface{}{"rollout_id": rollout.ID, "percentage": rollout.Percentage, "label_id": rollout.LabelID},
+	); err != nil {
+		return nil, err
+	}
+	return rollout, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Agent Options Rollouts
+////////////////////////////////////////////////////////////////////////////////
+
+type listAgentOptionsRolloutsRequest struct {
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listAgentOptionsRolloutsResponse struct {
+	Rollouts []*fleet.AgentOptionsRollout `json:"rollouts"`
+	Err      error                        `json:"error,omitempty"`
+}
+
+func (r listAgentOptionsRolloutsResponse) error() error { return r.Err }
+
+func listAgentOptionsRolloutsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listAgentOptionsRolloutsRequest)
+	rollouts, err := svc.ListAgentOptionsRollouts(ctx, req.ListOptions)
+	if err != nil {
+		return listAgentOptionsRolloutsResponse{Err: err}, nil
+	}
+	return listAgentOptionsRolloutsResponse{Rollouts: rollouts}, nil
+}
+
+func (svc *Service) ListAgentOptionsRollouts(ctx context.Context, opt fleet.ListOptions) ([]*fleet.AgentOptionsRollout, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	// the latest rollouts are listed first by default
+	if opt.OrderKey == "" {
+		opt.OrderKey = "id"
+		opt.OrderDirection = fleet.OrderDescending
+	}
+	return svc.ds.ListAgentOptionsRollouts(ctx, opt)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Agent Options Rollout
+////////////////////////////////////////////////////////////////////////////////
+
+type getAgentOptionsRolloutRequest struct {
+	ID uint `url:"id"`
+}
+
+type getAgentOptionsRolloutResponse struct {
+	Rollout *fleet.AgentOptionsRollout `json:"rollout,omitempty"`
+	Err     error                      `json:"error,omitempty"`
+}
+
+func (r getAgentOptionsRolloutResponse) error() error { return r.Err }
+
+func getAgentOptionsRolloutEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getAgentOptionsRolloutRequest)
+	rollout, err := svc.GetAgentOptionsRollout(ctx, req.ID)
+	if err != nil {
+		return getAgentOptionsRolloutResponse{Err: err}, nil
+	}
+	return getAgentOptionsRolloutResponse{Rollout: rollout}, nil
+}
+
+func (svc *Service) GetAgentOptionsRollout(ctx context.Context, id uint) (*fleet.AgentOptionsRollout, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	return svc.ds.AgentOptionsRollout(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Promote / Abort Agent Options Rollout
+////////////////////////////////////////////////////////////////////////////////
+
+type endAgentOptionsRolloutRequest struct {
+	ID uint `url:"id"`
+}
+
+type endAgentOptionsRolloutResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r endAgentOptionsRolloutResponse) error() error { return r.Err }
+
+func promoteAgentOptionsRolloutEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*endAgentOptionsRolloutRequest)
+	if err := svc.PromoteAgentOptionsRollout(ctx, req.ID); err != nil {
+		return endAgentOptionsRolloutResponse{Err: err}, nil
+	}
+	return endAgentOptionsRolloutResponse{}, nil
+}
+
+func abortAgentOptionsRolloutEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*endAgentOptionsRolloutRequest)
+	if err := svc.AbortAgentOptionsRollout(ctx, req.ID); err != nil {
+		return endAgentOptionsRolloutResponse{Err: err}, nil
+	}
+	return endAgentOptionsRolloutResponse{}, nil
+}
+
+// PromoteAgentOptionsRollout replaces the global agent options with the
+// options of the rollout, for all the hosts. The new global agent options are
+// recorded as a new version of the agent configuration.
+func (svc *Service) PromoteAgentOptionsRollout(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	rollout, err := svc.activeAgentOptionsRollout(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// ending the rollout first guarantees that it is promoted only once
+	if err := svc.ds.EndAgentOptionsRollout(ctx, id, fleet.AgentOptionsRolloutStatusPromoted); err != nil {
+		return err
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return err
+	}
+	agentOptions := rollout.AgentOptions
+	appConfig.AgentOptions = &agentOptions
+	if err := svc.ds.SaveAppConfig(ctx, appConfig); err != nil {
+		return err
+	}
+	svc.clientConfigCache.invalidate()
+
+	if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+		return err
+	}
+
+	return svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypePromotedAgentOptionsRollout,
+		&map[string]interface{}{"rollout_id": id},
+	)
+}
+
+// AbortAgentOptionsRollout ends the rollout: the canary hosts get the current
+// global agent options again.
+func (svc *Service) AbortAgentOptionsRollout(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	if _, err := svc.activeAgentOptionsRollout(ctx, id); err != nil {
+		return err
+	}
+	if err := svc.ds.EndAgentOptionsRollout(ctx, id, fleet.AgentOptionsRolloutStatusAborted); err != nil {
+		return err
+	}
+	svc.clientConfigCache.invalidate()
+
+	return svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeAbortedAgentOptionsRollout,
+		&map[string]interface{}{"rollout_id": id},
+	)
+}
+
+// activeAgentOptionsRollout returns the rollout, or an error if it is not
+// active.
+func (svc *Service) activeAgentOptionsRollout(ctx context.Context, id uint) (*fleet.AgentOptionsRollout, error) {
+	rollout, err := svc.ds.AgentOptionsRollout(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if rollout.Status != fleet.AgentOptionsRolloutStatusActive {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("id", "the agent options rollout is "+rollout.Status))
+	}
+	return rollout, nil
+}
+
+// globalAgentOptionsForHost returns the global agent options that apply to
+// the host: the options of the active rollout if the host is part of its
+// canary (in which case canary is true), the global agent options otherwise.
+func (svc *Service) globalAgentOptionsForHost(ctx context.Context, host *fleet.Host) (options *json.RawMessage, canary bool, err error) {
+	rollout, ok := svc.clientConfigCache.getRollout()
+	if !ok {
+		rollout, err = svc.ds.ActiveAgentOptionsRollout(ctx)
+		switch {
+		case fleet.IsNotFound(err):
+			rollout = nil
+		case err != nil:
+			return nil, false, ctxerr.Wrap(ctx, err, "get active agent options rollout")
+		}
+		svc.clientConfigCache.setRollout(rollout)
+	}
+
+	if rollout != nil {
+		canary = rollout.IncludesHostID(host.ID)
+		if !canary && rollout.LabelID != nil {
+			labels, err := svc.ds.ListLabelsForHost(ctx, host.ID)
+			if err != nil {
+				return nil, false, ctxerr.Wrap(ctx, err, "list labels for host")
+			}
+			for _, label := range labels {
+				if label.ID == *rollout.LabelID {
+					canary = true
+					break
+				}
+			}
+		}
+		if canary {
+			options := rollout.AgentOptions
+			return &options, true, nil
+		}
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, false, ctxerr.Wrap(ctx, err, "load global agent options")
+	}
+	return appConfig.AgentOptions, false, nil
+}
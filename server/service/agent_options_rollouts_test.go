@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartAgentOptionsRollout(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.LabelFunc = func(ctx context.Context, lid uint) (*fleet.Label, error) {
+		if lid != 1 {
+			return nil, notFoundError{}
+		}
+		return &fleet.Label{ID: lid, Name: "Canary"}, nil
+	}
+	var active *fleet.AgentOptionsRollout
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		if active == nil {
+			return nil, notFoundError{}
+		}
+		return active, nil
+	}
+	ds.NewAgentOptionsRolloutFunc = func(ctx context.Context, rollout *fleet.AgentOptionsRollout) (*fleet.AgentOptionsRollout, error) {
+		rollout.ID = 42
+		return rollout, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, fleet.ActivityTypeStartedAgentOptionsRollout, activityType)
+		return nil
+	}
+
+	validOptions := json.RawMessage(`{"config":{"options":{"distributed_interval":5}}}`)
+
+	for _, user := range []*fleet.User{test.UserMaintainer, test.UserObserver, test.UserTeamAdminTeam1} {
+		_, err := svc.StartAgentOptionsRollout(test.UserContext(user), fleet.AgentOptionsRolloutPayload{AgentOptions: validOptions, Percentage: 10})
+		require.Error(t, err)
+	}
+	assert.False(t, ds.NewAgentOptionsRolloutFuncInvoked)
+
+	ctx := test.UserContext(test.UserAdmin)
+	cases := []struct {
+		name    string
+		payload fleet.AgentOptionsRolloutPayload
+		errMsg  string
+	}{
+		{"no options", fleet.AgentOptionsRolloutPayload{Percentage: 10}, "agent options are required"},
+		{"invalid options", fleet.AgentOptionsRolloutPayload{AgentOptions: json.RawMessage(`{"config":{"options":{"no_such_option":1}}}`), Percentage: 10}, "no_such_option"},
+		{"percentage too high", fleet.AgentOptionsRolloutPayload{AgentOptions: validOptions, Percentage: 101}, "must be between 0 and 100"},
+		{"no canary", fleet.AgentOptionsRolloutPayload{AgentOptions: validOptions}, "a percentage or a label_id is required"},
+		{"unknown label", fleet.AgentOptionsRolloutPayload{AgentOptions: validOptions, LabelID: ptr.Uint(2)}, "not found"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := svc.StartAgentOptionsRollout(ctx, c.payload)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), c.errMsg)
+		})
+	}
+	assert.False(t, ds.NewAgentOptionsRolloutFuncInvoked)
+
+	rollout, err := svc.StartAgentOptionsRollout(ctx, fleet.AgentOptionsRolloutPayload{AgentOptions: validOptions, Percentage: 10, LabelID: ptr.Uint(1)})
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), rollout.ID)
+	assert.Equal(t, fleet.AgentOptionsRolloutStatusActive, rollout.Status)
+	assert.Equal(t, test.UserAdmin.ID, *rollout.AuthorID)
+	assert.True(t, ds.NewActivityFuncInvoked)
+
+	// only one rollout can be active at a time
+	active = rollout
+	ds.NewAgentOptionsRolloutFuncInvoked = false
+	_, err = svc.StartAgentOptionsRollout(ctx, fleet.AgentOptionsRolloutPayload{AgentOptions: validOptions, Percentage: 10})
+	require.Error(t, err)
+	var conflict *fleet.ConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.False(t, ds.NewAgentOptionsRolloutFuncInvoked)
+}
+
+func TestEndAgentOptionsRollout(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	rollouts := map[uint]*fleet.AgentOptionsRollout{
+		1: {ID: 1, AgentOptions: json.RawMessage(`{"config":{"options":{"distributed_interval":5}}}`), Percentage: 10, Status: fleet.AgentOptionsRolloutStatusActive},
+		2: {ID: 2, AgentOptions: json.RawMessage(`{"config":{}}`), Percentage: 10, Status: fleet.AgentOptionsRolloutStatusActive},
+		3: {ID: 3, AgentOptions: json.RawMessage(`{"config":{}}`), Percentage: 10, Status: fleet.AgentOptionsRolloutStatusAborted},
+	}
+	ds.AgentOptionsRolloutFunc = func(ctx context.Context, id uint) (*fleet.AgentOptionsRollout, error) {
+		r, ok := rollouts[id]
+		if !ok {
+			return nil, notFoundError{}
+		}
+		return r, nil
+	}
+	ds.EndAgentOptionsRolloutFunc = func(ctx context.Context, id uint, status string) error {
+		rollouts[id].Status = status
+		return nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{}}`))}, nil
+	}
+	var savedAppConfig *fleet.AppConfig
+	ds.SaveAppConfigFunc = func(ctx context.Context, info *fleet.AppConfig) error {
+		savedAppConfig = info
+		return nil
+	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
+	var activities []string
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		activities = append(activities, activityType)
+		return nil
+	}
+
+	require.Error(t, svc.PromoteAgentOptionsRollout(test.UserContext(test.UserMaintainer), 1))
+	require.Error(t, svc.AbortAgentOptionsRollout(test.UserContext(test.UserObserver), 1))
+	assert.False(t, ds.EndAgentOptionsRolloutFuncInvoked)
+
+	ctx := test.UserContext(test.UserAdmin)
+
+	require.NoError(t, svc.PromoteAgentOptionsRollout(ctx, 1))
+	assert.Equal(t, fleet.AgentOptionsRolloutStatusPromoted, rollouts[1].Status)
+	require.NotNil(t, savedAppConfig)
+	assert.JSONEq(t, `{"config":{"options":{"distributed_interval":5}}}`, string(*savedAppConfig.AgentOptions))
+	assert.True(t, ds.RecordAgentConfigVersionFuncInvoked)
+
+	// a rollout can only be ended once
+	require.Error(t, svc.PromoteAgentOptionsRollout(ctx, 1))
+	require.Error(t, svc.AbortAgentOptionsRollout(ctx, 3))
+	require.Error(t, svc.AbortAgentOptionsRollout(ctx, 4))
+
+	savedAppConfig = nil
+	require.NoError(t, svc.AbortAgentOptionsRollout(ctx, 2))
+	assert.Equal(t, fleet.AgentOptionsRolloutStatusAborted, rollouts[2].Status)
+	assert.Nil(t, savedAppConfig)
+
+	assert.Equal(t, []string{fleet.ActivityTypePromotedAgentOptionsRollout, fleet.ActivityTypeAbortedAgentOptionsRollout}, activities)
+}
+
+func TestGetClientConfigAgentOptionsRollout(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{}, nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":10}}}`)),
+		}, nil
+	}
+	ds.UpdateHostOsqueryIntervalsFunc = func(ctx context.Context, hostID uint, intervals fleet.HostOsqueryIntervals) error {
+		return nil
+	}
+	var hostLabels []*fleet.Label
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		return hostLabels, nil
+	}
+	var rollout *fleet.AgentOptionsRollout
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		if rollout == nil {
+			return nil, notFoundError{}
+		}
+		return rollout, nil
+	}
+
+	getOptions := func(hostID uint) map[string]interface{} {
+		conf, err := svc.GetClientConfig(hostctx.NewContext(context.Background(), &fleet.Host{ID: hostID}))
+		require.NoError(t, err)
+		return conf["options"].(map[string]interface{})
+	}
+	global := map[string]interface{}{"distributed_interval": float64(10)}
+	candidate := map[string]interface{}{"distributed_interval": float64(5)}
+
+	// no active rollout
+	assert.Equal(t, global, getOptions(5))
+
+	// the first 10% of the hosts get the options of the rollout
+	rollout = &fleet.AgentOptionsRollout{
+		ID:           1,
+		AgentOptions: json.RawMessage(`{"config":{"options":{"distributed_interval":5}}}`),
+		Percentage:   10,
+		LabelID:      ptr.Uint(3),
+		Status:       fleet.AgentOptionsRolloutStatusActive,
+	}
+	assert.Equal(t, candidate, getOptions(5))
+	assert.Equal(t, candidate, getOptions(105))
+	assert.Equal(t, global, getOptions(50))
+
+	// as do the members of the label
+	hostLabels = []*fleet.Label{{ID: 3, Name: "Canary"}}
+	assert.Equal(t, candidate, getOptions(50))
+	hostLabels = []*fleet.Label{{ID: 4, Name: "Other"}}
+	assert.Equal(t, global, getOptions(50))
+}
@@ -8,10 +8,15 @@ import (
 	"encoding/json"
 	"encoding/pem"
 	"errors"
+	"fmt"
 	"io"
 	"net"
 	"net/url"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/fleetdm/fleet/v4/server/authz"
 	authz_ctx "github.com/fleetdm/fleet/v4/server/contexts/authz"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
@@ -79,6 +84,10 @@ func getAppConfigEndpoint(ctx context.Context, request interface{}, svc fleet.Se
 		agentOptions = config.AgentOptions
 	}
 	hostSettings := config.HostSettings
+	webhookSettings := config.WebhookSettings
+	if webhookSettings.HostLifecycleWebhook.Secret != "" {
+		webhookSettings.HostLifecycleWebhook.Secret = "********"
+	}
 	response := appConfigResponse{
 		AppConfig: fleet.AppConfig{
 			OrgInfo:               config.OrgInfo,
@@ -91,7 +100,7 @@ func getAppConfigEndpoint(ctx context.Context, request interface{}, svc fleet.Se
 			HostExpirySettings: hostExpirySettings,
 			AgentOptions:       agentOptions,
 
-			WebhookSettings: config.WebhookSettings,
+			WebhookSettings: webhookSettings,
 			Integrations:    config.Integrations,
 		},
 		UpdateInterval:  updateIntervalConfig,
@@ -143,6 +152,9 @@ func modifyAppConfigEndpoint(ctx context.Context, request interface{}, svc fleet
 	if response.SMTPSettings.SMTPPassword != "" {
 		response.SMTPSettings.SMTPPassword = "********"
 	}
+	if response.WebhookSettings.HostLifecycleWebhook.Secret != "" {
+		response.WebhookSettings.HostLifecycleWebhook.Secret = "********"
+	}
 	return response, nil
 }
 
@@ -157,6 +169,10 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte) (*fleet.AppCo
 	}
 
 	oldSmtpSettings := appConfig.SMTPSettings
+	oldHostLifecycleSecret := appConfig.WebhookSettings.HostLifecycleWebhook.Secret
+	oldHostLifecycleEnabled := appConfig.WebhookSettings.HostLifecycleWebhook.Enable
+	// copy the sources, the slice is reused when the payload is decoded
+	oldDisplayNameSources := append([]string(nil), appConfig.HostSettings.DisplayNameSources...)
 
 	// TODO(mna): this ports the validations from the old validationMiddleware
 	// correctly, but this could be optimized so that we don't unmarshal the
@@ -168,6 +184,13 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte) (*fleet.AppCo
 	}
 
 	validateSSOSettings(newAppConfig, appConfig, invalid)
+	if newAppConfig.AgentOptions != nil {
+		if err := fleet.ValidateJSONAgentOptions(*newAppConfig.AgentOptions); err != nil {
+			invalid.Append("agent_options", err.Error())
+		}
+	}
+	validateHostDisplayNameSources(newAppConfig.HostSettings.DisplayNameSources, invalid)
+	validateHostAdditionalQueries(newAppConfig.HostSettings.AdditionalQueries, invalid)
 	if invalid.HasErrors() {
 		return nil, ctxerr.Wrap(ctx, invalid)
 	}
@@ -179,7 +202,17 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte) (*fleet.AppCo
 		return nil, &badRequestError{message: err.Error()}
 	}
 
+	// the masked secret returned by the API is applied back as is, e.g. by
+	// fleetctl get config and apply.
+	if appConfig.WebhookSettings.HostLifecycleWebhook.Secret == "********" {
+		appConfig.WebhookSettings.HostLifecycleWebhook.Secret = oldHostLifecycleSecret
+	}
+
+	validateHostExpirySettings(appConfig, invalid)
+	validateHostStatusThresholds(appConfig, invalid)
 	validateVulnerabilitiesAutomation(appConfig, invalid)
+	validateFailingPoliciesAutomation(appConfig, invalid)
+	validateHostLifecycleWebhook(appConfig, invalid)
 	if invalid.HasErrors() {
 		return nil, ctxerr.Wrap(ctx, invalid)
 	}
@@ -200,9 +233,32 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte) (*fleet.AppCo
 		appConfig.SMTPSettings.SMTPConfigured = false
 	}
 
+	// the hosts that are already offline when the host lifecycle webhook is
+	// enabled are not reported, only the ones that go offline after.
+	if hostLifecycle := appConfig.WebhookSettings.HostLifecycleWebhook; hostLifecycle.Enable && !oldHostLifecycleEnabled {
+		threshold := hostLifecycle.OfflineThreshold.ValueOr(fleet.HostLifecycleDefaultOfflineThreshold)
+		if err := svc.ds.MarkHostsReportedOffline(ctx, svc.clock.Now().Add(-threshold)); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := svc.ds.SaveAppConfig(ctx, appConfig); err != nil {
 		return nil, err
 	}
+	svc.clientConfigCache.invalidate()
+
+	if newAppConfig.HostSettings.DisplayNameSources != nil && !reflect.DeepEqual(oldDisplayNameSources, appConfig.HostSettings.DisplayNameSources) {
+		if err := svc.ds.UpdateHostDisplayNames(ctx, appConfig.HostSettings.DisplayNameSources); err != nil {
+			return nil, err
+		}
+	}
+
+	// only the agent options of the app config are versioned
+	if newAppConfig.AgentOptions != nil {
+		if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+			return nil, err
+		}
+	}
 	return appConfig, nil
 }
 
@@ -233,6 +289,79 @@ func validateSSOSettings(p fleet.AppConfig, existing *fleet.AppConfig, invalid *
 	}
 }
 
+// validateHostDisplayNameSources validates the display name sources, if they
+// are provided (nil sources are left unchanged).
+func validateHostDisplayNameSources(sources []string, invalid *fleet.InvalidArgumentError) {
+	if sources == nil {
+		return
+	}
+	if len(sources) == 0 {
+		invalid.Append("display_name_sources", "at least one source is required")
+		return
+	}
+	seen := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		if !fleet.IsValidHostDisplayNameSource(source) {
+			invalid.Append("display_name_sources", fmt.Sprintf("unsupported source %q", source))
+			continue
+		}
+		if seen[source] {
+			invalid.Append("display_name_sources", fmt.Sprintf("duplicate source %q", source))
+		}
+		seen[source] = true
+	}
+}
+
+// validateHostAdditionalQueries validates the additional queries, if they are
+// provided. They are run with the detail queries of every host, an invalid
+// value would prevent the hosts from refreshing their details.
+func validateHostAdditionalQueries(raw *json.RawMessage, invalid *fleet.InvalidArgumentError) {
+	if raw == nil {
+		return
+	}
+	var queries map[string]string
+	if err := json.Unmarshal(*raw, &queries); err != nil {
+		invalid.Append("additional_queries", "must be an object of query names to queries")
+		return
+	}
+	for name, query := range queries {
+		if strings.TrimSpace(name) == "" {
+			invalid.Append("additional_queries", "query name cannot be empty")
+		}
+		if strings.TrimSpace(query) == "" {
+			invalid.Append("additional_queries", fmt.Sprintf("query %q cannot be empty", name))
+		}
+	}
+}
+
+// validateHostExpirySettings validates the expiry window if the host expiry
+// is enabled, otherwise all the hosts would be expired by the cleanup job.
+func validateHostExpirySettings(merged *fleet.AppConfig, invalid *fleet.InvalidArgumentError) {
+	if merged.HostExpirySettings.HostExpiryEnabled && merged.HostExpirySettings.HostExpiryWindow <= 0 {
+		invalid.Append("host_expiry_window", "must be a positive number of days when host expiry is enabled")
+	}
+}
+
+// validateHostStatusThresholds validates the thresholds used to compute the
+// status of the hosts, 0 means the default offline threshold and no missing
+// status.
+func validateHostStatusThresholds(merged *fleet.AppConfig, invalid *fleet.InvalidArgumentError) {
+	if merged.HostSettings.OfflineThreshold.Duration < 0 {
+		invalid.Append("offline_threshold", "must not be negative")
+	}
+	if merged.HostSettings.MissingThresholdDays < 0 {
+		invalid.Append("missing_threshold_days", "must not be negative")
+	}
+	if merged.HostSettings.MissingThresholdDays > fleet.MaxHostMissingThresholdDays {
+		invalid.Append("missing_threshold_days", fmt.Sprintf("must be at most %d, the hosts are MIA after 30 days", fleet.MaxHostMissingThresholdDays))
+	}
+	// the statuses are mutually exclusive, a host must be offline before it
+	// is missing.
+	if missingThreshold := merged.HostSettings.MissingThreshold(); missingThreshold > 0 && merged.HostSettings.OfflineThreshold.Duration >= missingThreshold {
+		invalid.Append("offline_threshold", "must be less than the missing threshold")
+	}
+}
+
 func validateVulnerabilitiesAutomation(merged *fleet.AppConfig, invalid *fleet.InvalidArgumentError) {
 	webhookEnabled := merged.WebhookSettings.VulnerabilitiesWebhook.Enable
 	var jiraEnabledCount int
@@ -249,6 +378,36 @@ func validateVulnerabilitiesAutomation(merged *fleet.AppConfig, invalid *fleet.I
 	}
 }
 
+func validateFailingPoliciesAutomation(merged *fleet.AppConfig, invalid *fleet.InvalidArgumentError) {
+	webhookEnabled := merged.WebhookSettings.FailingPoliciesWebhook.Enable
+	var jiraEnabledCount int
+	for _, jira := range merged.Integrations.Jira {
+		if !jira.EnableFailingPolicies {
+			continue
+		}
+		jiraEnabledCount++
+		if jira.URL == "" || jira.ProjectKey == "" {
+			invalid.Append("failing_policies", "jira integration requires url and project_key")
+		}
+	}
+	if webhookEnabled && jiraEnabledCount > 0 {
+		invalid.Append("failing_policies", "cannot enable both webhook failing policies and jira integration automations")
+	}
+	if jiraEnabledCount > 1 {
+		invalid.Append("failing_policies", "cannot enable more than one jira integration")
+	}
+}
+
+func validateHostLifecycleWebhook(merged *fleet.AppConfig, invalid *fleet.InvalidArgumentError) {
+	webhook := merged.WebhookSettings.HostLifecycleWebhook
+	if webhook.Enable && webhook.DestinationURL == "" {
+		invalid.Append("destination_url", "required when the host lifecycle webhook is enabled")
+	}
+	if threshold := webhook.OfflineThreshold.Duration; threshold != 0 && threshold < time.Minute {
+		invalid.Append("offline_threshold", "must be at least 1m")
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Apply enroll secret spec
 ////////////////////////////////////////////////////////////////////////////////
@@ -278,8 +437,8 @@ func (svc *Service) ApplyEnrollSecretSpec(ctx context.Context, spec *fleet.Enrol
 	}
 
 	for _, s := range spec.Secrets {
-		if s.Secret == "" {
-			return ctxerr.New(ctx, "enroll secret must not be empty")
+		if err := s.Validate(); err != nil {
+			return ctxerr.Wrap(ctx, err, "validate enroll secret")
 		}
 	}
 
@@ -9,7 +9,9 @@ import (
 	"net/http/httputil"
 	"net/url"
 	"testing"
+	"time"
 
+	"github.com/WatchBeam/clock"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mock"
@@ -275,3 +277,234 @@ func TestMissingMetadata(t *testing.T) {
 	assert.Contains(t, invalid.Error(), "metadata")
 	assert.Contains(t, invalid.Error(), "either metadata or metadata_url must be defined")
 }
+
+func TestModifyAppConfigAgentOptions(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
+		return nil
+	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	_, err := svc.ModifyAppConfig(ctx, []byte(`{"agent_options": {"config": {"options": {"distributed_intervall": 10}}}}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown osquery option "distributed_intervall"`)
+	assert.False(t, ds.SaveAppConfigFuncInvoked)
+
+	_, err = svc.ModifyAppConfig(ctx, []byte(`{"agent_options": {"config": {"options": {"distributed_interval": "10"}}}}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `expected integer`)
+	assert.False(t, ds.SaveAppConfigFuncInvoked)
+
+	conf, err := svc.ModifyAppConfig(ctx, []byte(`{"agent_options": {"config": {"options": {"distributed_interval": 10}}}}`))
+	require.NoError(t, err)
+	assert.True(t, ds.SaveAppConfigFuncInvoked)
+	require.NotNil(t, conf.AgentOptions)
+	assert.JSONEq(t, `{"config": {"options": {"distributed_interval": 10}}}`, string(*conf.AgentOptions))
+}
+
+func TestModifyAppConfigDisplayNameSources(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		conf := &fleet.AppConfig{}
+		conf.ApplyDefaults()
+		return conf, nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
+		return nil
+	}
+	var updatedSources []string
+	ds.UpdateHostDisplayNamesFunc = func(ctx context.Context, sources []string) error {
+		updatedSources = sources
+		return nil
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	for _, payload := range []string{
+		`{"host_settings": {"display_name_sources": []}}`,
+		`{"host_settings": {"display_name_sources": ["uuid"]}}`,
+		`{"host_settings": {"display_name_sources": ["hostname", "hostname"]}}`,
+	} {
+		_, err := svc.ModifyAppConfig(ctx, []byte(payload))
+		require.Error(t, err, payload)
+		assert.Contains(t, err.Error(), "display_name_sources", payload)
+	}
+	assert.False(t, ds.SaveAppConfigFuncInvoked)
+
+	// unchanged sources do not update the hosts
+	_, err := svc.ModifyAppConfig(ctx, []byte(`{"host_settings": {"display_name_sources": ["computer_name", "hostname"]}}`))
+	require.NoError(t, err)
+	assert.False(t, ds.UpdateHostDisplayNamesFuncInvoked)
+
+	conf, err := svc.ModifyAppConfig(ctx, []byte(`{"host_settings": {"display_name_sources": ["hardware_serial", "hostname"]}}`))
+	require.NoError(t, err)
+	assert.True(t, ds.UpdateHostDisplayNamesFuncInvoked)
+	assert.Equal(t, []string{"hardware_serial", "hostname"}, updatedSources)
+	assert.Equal(t, []string{"hardware_serial", "hostname"}, conf.HostSettings.DisplayNameSources)
+}
+
+func TestModifyAppConfigAdditionalQueries(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		conf := &fleet.AppConfig{}
+		conf.ApplyDefaults()
+		return conf, nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
+		return nil
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	for _, payload := range []string{
+		`{"host_settings": {"additional_queries": true}}`,
+		`{"host_settings": {"additional_queries": ["select 1"]}}`,
+		`{"host_settings": {"additional_queries": {"time": 1}}}`,
+		`{"host_settings": {"additional_queries": {"time": " "}}}`,
+		`{"host_settings": {"additional_queries": {"": "select * from time"}}}`,
+	} {
+		_, err := svc.ModifyAppConfig(ctx, []byte(payload))
+		require.Error(t, err, payload)
+		assert.Contains(t, err.Error(), "additional_queries", payload)
+	}
+	assert.False(t, ds.SaveAppConfigFuncInvoked)
+
+	conf, err := svc.ModifyAppConfig(ctx, []byte(`{"host_settings": {"additional_queries": {"time": "select * from time"}}}`))
+	require.NoError(t, err)
+	require.NotNil(t, conf.HostSettings.AdditionalQueries)
+	assert.JSONEq(t, `{"time": "select * from time"}`, string(*conf.HostSettings.AdditionalQueries))
+
+	_, err = svc.ModifyAppConfig(ctx, []byte(`{"host_settings": {"additional_queries": null}}`))
+	require.NoError(t, err)
+}
+
+func TestModifyAppConfigHostExpirySettings(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		conf := &fleet.AppConfig{}
+		conf.ApplyDefaults()
+		return conf, nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
+		return nil
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	for _, payload := range []string{
+		`{"host_expiry_settings": {"host_expiry_enabled": true}}`,
+		`{"host_expiry_settings": {"host_expiry_enabled": true, "host_expiry_window": 0}}`,
+		`{"host_expiry_settings": {"host_expiry_enabled": true, "host_expiry_window": -1}}`,
+	} {
+		_, err := svc.ModifyAppConfig(ctx, []byte(payload))
+		require.Error(t, err, payload)
+		assert.Contains(t, err.Error(), "host_expiry_window", payload)
+	}
+	assert.False(t, ds.SaveAppConfigFuncInvoked)
+
+	conf, err := svc.ModifyAppConfig(ctx, []byte(`{"host_expiry_settings": {"host_expiry_enabled": true, "host_expiry_window": 30}}`))
+	require.NoError(t, err)
+	assert.True(t, conf.HostExpirySettings.HostExpiryEnabled)
+	assert.Equal(t, 30, conf.HostExpirySettings.HostExpiryWindow)
+
+	// the window is not validated if the expiry is disabled
+	_, err = svc.ModifyAppConfig(ctx, []byte(`{"host_expiry_settings": {"host_expiry_enabled": false, "host_expiry_window": 0}}`))
+	require.NoError(t, err)
+}
+
+func TestModifyAppConfigHostStatusThresholds(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		conf := &fleet.AppConfig{}
+		conf.ApplyDefaults()
+		return conf, nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
+		return nil
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	for payload, field := range map[string]string{
+		`{"host_settings": {"offline_threshold": "-5m"}}`:                              "offline_threshold",
+		`{"host_settings": {"missing_threshold_days": -1}}`:                            "missing_threshold_days",
+		`{"host_settings": {"missing_threshold_days": 30}}`:                            "missing_threshold_days",
+		`{"host_settings": {"offline_threshold": "48h", "missing_threshold_days": 2}}`: "offline_threshold",
+	} {
+		_, err := svc.ModifyAppConfig(ctx, []byte(payload))
+		require.Error(t, err, payload)
+		assert.Contains(t, err.Error(), field, payload)
+	}
+	assert.False(t, ds.SaveAppConfigFuncInvoked)
+
+	conf, err := svc.ModifyAppConfig(ctx, []byte(`{"host_settings": {"offline_threshold": "15m", "missing_threshold_days": 14}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, conf.HostSettings.OfflineThreshold.Duration)
+	assert.Equal(t, 14*24*time.Hour, conf.HostSettings.MissingThreshold())
+
+	conf, err = svc.ModifyAppConfig(ctx, []byte(`{"host_settings": {"offline_threshold": "0s", "missing_threshold_days": 0}}`))
+	require.NoError(t, err)
+	assert.Zero(t, conf.HostSettings.MissingThreshold())
+
+	// the offline threshold is not limited without missing threshold
+	conf, err = svc.ModifyAppConfig(ctx, []byte(`{"host_settings": {"offline_threshold": "240h", "missing_threshold_days": 0}}`))
+	require.NoError(t, err)
+	assert.Equal(t, 240*time.Hour, conf.HostSettings.OfflineThreshold.Duration)
+}
+
+func TestModifyAppConfigHostLifecycleWebhook(t *testing.T) {
+	ds := new(mock.Store)
+	mockClock := clock.NewMockClock()
+	svc := newTestServiceWithClock(t, ds, nil, nil, mockClock)
+
+	appConfig := &fleet.AppConfig{}
+	appConfig.ApplyDefaults()
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		conf := *appConfig
+		return &conf, nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, conf *fleet.AppConfig) error {
+		appConfig = conf
+		return nil
+	}
+	var reportedBefore time.Time
+	ds.MarkHostsReportedOfflineFunc = func(ctx context.Context, seenBefore time.Time) error {
+		reportedBefore = seenBefore
+		return nil
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	// the hosts already offline are marked as reported when the webhook is enabled
+	_, err := svc.ModifyAppConfig(ctx, []byte(`{"webhook_settings": {"host_lifecycle_webhook": {"enable_host_lifecycle_webhook": true, "destination_url": "http://example.com", "offline_threshold": "2h"}}}`))
+	require.NoError(t, err)
+	assert.True(t, ds.MarkHostsReportedOfflineFuncInvoked)
+	assert.Equal(t, mockClock.Now().Add(-2*time.Hour), reportedBefore)
+
+	// but not when it stays enabled
+	ds.MarkHostsReportedOfflineFuncInvoked = false
+	_, err = svc.ModifyAppConfig(ctx, []byte(`{"webhook_settings": {"host_lifecycle_webhook": {"offline_threshold": "3h"}}}`))
+	require.NoError(t, err)
+	assert.False(t, ds.MarkHostsReportedOfflineFuncInvoked)
+
+	_, err = svc.ModifyAppConfig(ctx, []byte(`{"webhook_settings": {"host_lifecycle_webhook": {"enable_host_lifecycle_webhook": false}}}`))
+	require.NoError(t, err)
+	assert.False(t, ds.MarkHostsReportedOfflineFuncInvoked)
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create ATC Table
+////////////////////////////////////////////////////////////////////////////////
+
+type createATCTableRequest struct {
+	fleet.ATCTablePayload
+}
+
+type atcTableResponse struct {
+	Table *fleet.ATCTable `json:"atc_table,omitempty"`
+	Err   error           `json:"error,omitempty"`
+}
+
+func (r atcTableResponse) error() error { return r.Err }
+
+func createATCTableEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*createATCTableRequest)
+	table, err := svc.NewATCTable(ctx, req.ATCTablePayload)
+	if err != nil {
+		return atcTableResponse{Err: err}, nil
+	}
+	return atcTableResponse{Table: table}, nil
+}
+
+func (svc *Service) NewATCTable(ctx context.Context, p fleet.ATCTablePayload) (*fleet.ATCTable, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.ATCTable{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	invalid := &fleet.InvalidArgumentError{}
+	if p.Name == nil {
+		invalid.Append("name", "missing required argument")
+	}
+	if p.Query == nil {
+		invalid.Append("query", "missing required argument")
+	}
+	if p.Path == nil {
+		invalid.Append("path", "missing required argument")
+	}
+	if p.Columns == nil {
+		invalid.Append("columns", "missing required argument")
+	}
+	if invalid.HasErrors() {
+		return nil, ctxerr.Wrap(ctx, invalid)
+	}
+	if err := p.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &badRequestError{
+			message: fmt.Sprintf("atc table payload verification: %s", err),
+		})
+	}
+
+	table := &fleet.ATCTable{}
+	applyATCTablePayload(table, p)
+	return svc.ds.NewATCTable(ctx, table)
+}
+
+func applyATCTablePayload(table *fleet.ATCTable, p fleet.ATCTablePayload) {
+	if p.Name != nil {
+		table.Name = *p.Name
+	}
+	if p.Query != nil {
+		table.Query = *p.Query
+	}
+	if p.Path != nil {
+		table.Path = *p.Path
+	}
+	if p.Columns != nil {
+		table.Columns = *p.Columns
+	}
+	if p.Platform != nil {
+		table.Platform = *p.Platform
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List ATC Tables
+////////////////////////////////////////////////////////////////////////////////
+
+type listATCTablesResponse struct {
+	Tables []*fleet.ATCTable `json:"atc_tables"`
+	Err    error             `json:"error,omitempty"`
+}
+
+func (r listATCTablesResponse) error() error { return r.Err }
+
+func listATCTablesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	tables, err := svc.ListATCTables(ctx)
+	if err != nil {
+		return listATCTablesResponse{Err: err}, nil
+	}
+	if tables == nil {
+		tables = []*fleet.ATCTable{}
+	}
+	return listATCTablesResponse{Tables: tables}, nil
+}
+
+func (svc *Service) ListATCTables(ctx context.Context) ([]*fleet.ATCTable, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.ATCTable{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.ListATCTables(ctx)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Modify ATC Table
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyATCTableRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.ATCTablePayload
+}
+
+func modifyATCTableEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*modifyATCTableRequest)
+	table, err := svc.ModifyATCTable(ctx, req.ID, req.ATCTablePayload)
+	if err != nil {
+		return atcTableResponse{Err: err}, nil
+	}
+	return atcTableResponse{Table: table}, nil
+}
+
+func (svc *Service) ModifyATCTable(ctx context.Context, id uint, p fleet.ATCTablePayload) (*fleet.ATCTable, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.ATCTable{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := p.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &badRequestError{
+			message: fmt.Sprintf("atc table payload verification: %s", err),
+		})
+	}
+
+	table, err := svc.ds.ATCTable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	applyATCTablePayload(table, p)
+	if err := svc.ds.SaveATCTable(ctx, table); err != nil {
+		return nil, err
+	}
+	return svc.ds.ATCTable(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete ATC Table
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteATCTableRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteATCTableResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteATCTableResponse) error() error { return r.Err }
+
+func deleteATCTableEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*deleteATCTableRequest)
+	if err := svc.DeleteATCTable(ctx, req.ID); err != nil {
+		return deleteATCTableResponse{Err: err}, nil
+	}
+	return deleteATCTableResponse{}, nil
+}
+
+func (svc *Service) DeleteATCTable(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.ATCTable{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+	return svc.ds.DeleteATCTable(ctx, id)
+}
+
+// atcConfigForHost returns the auto_table_construction config section for the
+// host, made of the ATC tables defined in the agent options (which take
+// precedence) and the ATC tables defined in Fleet for the host's platform.
+func (svc *Service) atcConfigForHost(ctx context.Context, host *fleet.Host, agentATC interface{}) (map[string]interface{}, error) {
+	tables, err := svc.ds.ListATCTables(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list atc tables")
+	}
+
+	atc := make(map[string]interface{})
+	platform := host.FleetPlatform()
+	for _, table := range tables {
+		if table.Platform != "" && table.Platform != platform {
+			continue
+		}
+		atc[table.Name] = table.Config()
+	}
+	if agentTables, ok := agentATC.(map[string]interface{}); ok {
+		for name, table := range agentTables {
+			atc[name] = table
+		}
+	}
+	return atc, nil
+}
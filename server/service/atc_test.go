@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestATCTablesAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.NewATCTableFunc = func(ctx context.Context, table *fleet.ATCTable) (*fleet.ATCTable, error) {
+		return table, nil
+	}
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ATCTableFunc = func(ctx context.Context, id uint) (*fleet.ATCTable, error) {
+		return &fleet.ATCTable{ID: id}, nil
+	}
+	ds.SaveATCTableFunc = func(ctx context.Context, table *fleet.ATCTable) error {
+		return nil
+	}
+	ds.DeleteATCTableFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name            string
+		user            *fleet.User
+		shouldFailWrite bool
+		shouldFailRead  bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, false, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, true, false},
+		{"team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			_, err := svc.NewATCTable(ctx, fleet.ATCTablePayload{
+				Name:    ptr.String("tcc_entries"),
+				Query:   ptr.String("SELECT service, client FROM access"),
+				Path:    ptr.String("/Library/Application Support/com.apple.TCC/TCC.db"),
+				Columns: &fleet.ATCColumns{"service", "client"},
+			})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			_, err = svc.ListATCTables(ctx)
+			checkAuthErr(t, tt.shouldFailRead, err)
+
+			_, err = svc.ModifyATCTable(ctx, 1, fleet.ATCTablePayload{Platform: ptr.String("darwin")})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			err = svc.DeleteATCTable(ctx, 1)
+			checkAuthErr(t, tt.shouldFailWrite, err)
+		})
+	}
+}
+
+func TestNewATCTableValidation(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	_, err := svc.NewATCTable(ctx, fleet.ATCTablePayload{Name: ptr.String("foo")})
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+
+	valid := func() fleet.ATCTablePayload {
+		return fleet.ATCTablePayload{
+			Name:    ptr.String("foo"),
+			Query:   ptr.String("SELECT a FROM b"),
+			Path:    ptr.String("/tmp/foo.db"),
+			Columns: &fleet.ATCColumns{"a"},
+		}
+	}
+	cases := []struct {
+		modify func(p *fleet.ATCTablePayload)
+		errMsg string
+	}{
+		{func(p *fleet.ATCTablePayload) { p.Name = ptr.String("1foo") }, "table name"},
+		{func(p *fleet.ATCTablePayload) { p.Name = ptr.String("foo-bar") }, "table name"},
+		{func(p *fleet.ATCTablePayload) { p.Query = ptr.String("") }, "query cannot be empty"},
+		{func(p *fleet.ATCTablePayload) { p.Columns = &fleet.ATCColumns{} }, "at least one column"},
+		{func(p *fleet.ATCTablePayload) { p.Platform = ptr.String("ubuntu") }, "platform must be one of"},
+	}
+	for _, c := range cases {
+		p := valid()
+		c.modify(&p)
+		_, err := svc.NewATCTable(ctx, p)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), c.errMsg)
+	}
+}
+
+func TestGetClientConfigATCTables(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{}, nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{
+			"auto_table_construction":{"custom":{"query":"SELECT 1","path":"/custom.db","columns":["a"]}}
+		}}`))}, nil
+	}
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return []*fleet.ATCTable{
+			{Name: "all_platforms", Query: "SELECT a FROM t", Path: "/all.db", Columns: fleet.ATCColumns{"a"}},
+			{Name: "darwin_only", Query: "SELECT b FROM t", Path: "/darwin.db", Columns: fleet.ATCColumns{"b"}, Platform: "darwin"},
+			{Name: "custom", Query: "SELECT c FROM t", Path: "/overridden.db", Columns: fleet.ATCColumns{"c"}},
+		}, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+
+	getATC := func(platform string) string {
+		ctx := hostctx.NewContext(context.Background(), &fleet.Host{ID: 1, Platform: platform})
+		conf, err := svc.GetClientConfig(ctx)
+		require.NoError(t, err)
+		b, err := json.Marshal(conf["auto_table_construction"])
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	assert.JSONEq(t, `{
+		"all_platforms":{"query":"SELECT a FROM t","path":"/all.db","columns":["a"]},
+		"darwin_only":{"query":"SELECT b FROM t","path":"/darwin.db","columns":["b"],"platform":"darwin"},
+		"custom":{"query":"SELECT 1","path":"/custom.db","columns":["a"]}
+	}`, getATC("darwin"))
+
+	assert.JSONEq(t, `{
+		"all_platforms":{"query":"SELECT a FROM t","path":"/all.db","columns":["a"]},
+		"custom":{"query":"SELECT 1","path":"/custom.db","columns":["a"]}
+	}`, getATC("ubuntu"))
+}
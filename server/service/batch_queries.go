@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+const (
+	// defaultBatchQueryCompletionThreshold is the percentage of the targeted
+	// hosts that must respond for a batch query to reach its completion
+	// threshold, if not specified.
+	defaultBatchQueryCompletionThreshold = 100
+	// defaultBatchQueryDuration is how long the hosts receive the query of a
+	// batch query, if not specified.
+	defaultBatchQueryDuration = 24 * time.Hour
+	// maxBatchQueryDuration is the maximum duration of a batch query, the
+	// queries expire from Redis after a week.
+	maxBatchQueryDuration = 7 * 24 * time.Hour
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create Batch Query
+////////////////////////////////////////////////////////////////////////////////
+
+type createBatchQueryRequest struct {
+	QuerySQL            string            `json:"query"`
+	QueryID             *uint             `json:"query_id"`
+	Selected            fleet.HostTargets `json:"selected"`
+	Anonymize           bool              `json:"anonymize"`
+	CompletionThreshold uint              `json:"completion_threshold"`
+	DurationHours       uint              `json:"duration_hours"`
+	fleet.DistributedQueryResultLimits
+}
+
+type batchQueryResponse struct {
+	BatchQuery *fleet.BatchQuery `json:"batch_query,omitempty"`
+	Err        error             `json:"error,omitempty"`
+}
+
+func (r batchQueryResponse) error() error { return r.Err }
+
+func createBatchQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*createBatchQueryRequest)
+	bq, err := svc.NewBatchQuery(
+		ctx, req.QuerySQL, req.QueryID, req.Selected, req.Anonymize, req.DistributedQueryResultLimits,
+		req.CompletionThreshold, time.Duration(req.DurationHours)*time.Hour,
+	)
+	if err != nil {
+		return batchQueryResponse{Err: err}, nil
+	}
+	return batchQueryResponse{BatchQuery: bq}, nil
+}
+
+func (svc *Service) NewBatchQuery(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets, anonymize bool, limits fleet.DistributedQueryResultLimits, completionThreshold uint, duration time.Duration) (*fleet.BatchQuery, error) {
+	if completionThreshold == 0 {
+		completionThreshold = defaultBatchQueryCompletionThreshold
+	}
+	if duration == 0 {
+		duration = defaultBatchQueryDuration
+	}
+	if completionThreshold > 100 {
+		return nil, fleet.NewInvalidArgumentError("completion_threshold", "must be a percentage between 1 and 100")
+	}
+	if duration > maxBatchQueryDuration {
+		return nil, fleet.NewInvalidArgumentError("duration_hours", fmt.Sprintf("must be at most %d hours", int(maxBatchQueryDuration.Hours())))
+	}
+
+	batch := &fleet.BatchQuery{
+		CompletionThreshold: completionThreshold,
+		ExpiresAt:           svc.clock.Now().Add(duration),
+	}
+	campaign, err := svc.newDistributedQueryCampaign(ctx, queryString, queryID, targets, anonymize, nil, limits, batch)
+	if err != nil {
+		return nil, err
+	}
+	bq, err := svc.ds.BatchQuery(ctx, campaign.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get new batch query")
+	}
+	return bq, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Batch Query
+////////////////////////////////////////////////////////////////////////////////
+
+type getBatchQueryRequest struct {
+	ID uint `url:"id"`
+}
+
+func getBatchQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getBatchQueryRequest)
+	bq, err := svc.GetBatchQuery(ctx, req.ID)
+	if err != nil {
+		return batchQueryResponse{Err: err}, nil
+	}
+	return batchQueryResponse{BatchQuery: bq}, nil
+}
+
+func (svc *Service) GetBatchQuery(ctx context.Context, campaignID uint) (*fleet.BatchQuery, error) {
+	campaign, err := svc.viewerDistributedQueryCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	bq, err := svc.ds.BatchQuery(ctx, campaign.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get batch query")
+	}
+	return bq, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Batch Query Results
+////////////////////////////////////////////////////////////////////////////////
+
+type listBatchQueryResultsRequest struct {
+	ID          uint              `url:"id"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listBatchQueryResultsResponse struct {
+	Results []*fleet.BatchQueryResult `json:"results"`
+	Err     error                     `json:"error,omitempty"`
+}
+
+func (r listBatchQueryResultsResponse) error() error { return r.Err }
+
+func listBatchQueryResultsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listBatchQueryResultsRequest)
+	results, err := svc.ListBatchQueryResults(ctx, req.ID, req.ListOptions)
+	if err != nil {
+		return listBatchQueryResultsResponse{Err: err}, nil
+	}
+	return listBatchQueryResultsResponse{Results: results}, nil
+}
+
+func (svc *Service) ListBatchQueryResults(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.BatchQueryResult, error) {
+	campaign, err := svc.viewerDistributedQueryCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	// the batch query may have been cleaned up since it expired
+	if _, err := svc.ds.BatchQuery(ctx, campaign.ID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get batch query")
+	}
+
+	results, err := svc.ds.ListBatchQueryResults(ctx, campaign.ID, opt)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list batch query results")
+	}
+	if campaign.Anonymize {
+		// the host IDs are only stored to count the hosts once
+		for _, res := range results {
+			res.HostID = 0
+		}
+	}
+	return results, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Store Batch Query Results
+////////////////////////////////////////////////////////////////////////////////
+
+// newBatchQueryResult returns the result of a host to store for a batch query.
+func newBatchQueryResult(res fleet.DistributedQueryResult) *fleet.BatchQueryResult {
+	rows := res.Rows
+	if rows == nil {
+		rows = []map[string]string{}
+	}
+	// the rows are strings, encoding them cannot fail
+	b, _ := json.Marshal(rows)
+	return &fleet.BatchQueryResult{
+		Hostname:  res.Host.Hostname,
+		Rows:      b,
+		Error:     res.Error,
+		Truncated: res.Truncated,
+	}
+}
+
+// checkBatchQueryCompletion triggers the batch query webhook once the batch
+// query of the campaign reaches its completion threshold, and completes the
+// campaign once all the targeted hosts responded.
+func (svc *Service) checkBatchQueryCompletion(ctx context.Context, campaign *fleet.DistributedQueryCampaign, bq *fleet.BatchQuery) error {
+	if bq.NotifiedAt == nil && bq.ThresholdReached() {
+		// the threshold is recorded before notifying, so that it is notified
+		// only once by the Fleet instances receiving the results.
+		notify, err := svc.ds.MarkBatchQueryNotified(ctx, bq.CampaignID, svc.clock.Now())
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "mark batch query notified")
+		}
+		if notify {
+			if err := svc.notifyBatchQueryThreshold(ctx, bq); err != nil {
+				return err
+			}
+		}
+	}
+
+	if bq.RespondedHosts >= bq.TargetedHosts && bq.Status != fleet.QueryComplete {
+		campaign.Status = fleet.QueryComplete
+		if err := svc.ds.SaveDistributedQueryCampaign(ctx, campaign); err != nil {
+			return ctxerr.Wrap(ctx, err, "complete batch query campaign")
+		}
+		if err := svc.liveQueryStore.StopQuery(strconv.Itoa(int(campaign.ID))); err != nil {
+			return ctxerr.Wrap(ctx, err, "stop batch query")
+		}
+	}
+	return nil
+}
+
+// notifyBatchQueryThreshold triggers the batch query webhook, if enabled, for
+// the batch query that reached its completion threshold.
+func (svc *Service) notifyBatchQueryThreshold(ctx context.Context, bq *fleet.BatchQuery) error {
+	config, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config for batch query notification")
+	}
+	webhook := config.WebhookSettings.BatchQueryWebhook
+	if !webhook.Enable {
+		return nil
+	}
+
+	payload := map[string]interface{}{
+		"text": fmt.Sprintf(
+			"Batch query %d reached its completion threshold: %d of the %d targeted hosts responded. You've been sent this message because the Batch query webhook is enabled in your Fleet instance.",
+			bq.CampaignID, bq.RespondedHosts, bq.TargetedHosts,
+		),
+		"data": map[string]interface{}{
+			"campaign_id":          bq.CampaignID,
+			"query_id":             bq.QueryID,
+			"completion_threshold": bq.CompletionThreshold,
+			"targeted_hosts":       bq.TargetedHosts,
+			"responded_hosts":      bq.RespondedHosts,
+			"url":                  fmt.Sprintf("%s%s/api/v1/fleet/queries/batch/%d/results", config.ServerSettings.ServerURL, svc.config.Server.URLPrefix, bq.CampaignID),
+		},
+	}
+	if err := server.PostJSONWithTimeout(ctx, webhook.DestinationURL, &payload); err != nil {
+		return ctxerr.Wrap(ctx, err, "post batch query webhook")
+	}
+	return nil
+}
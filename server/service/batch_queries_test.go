@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/live_query"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/pubsub"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBatchQuery(t *testing.T) {
+	ds := new(mock.Store)
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	rs := &mock.QueryResultStore{
+		HealthCheckFunc: func() error {
+			return nil
+		},
+	}
+	lq := &live_query.MockLiveQuery{}
+	mockClock := clock.NewMockClock()
+	svc := newTestServiceWithClock(t, ds, rs, lq, mockClock)
+
+	ds.NewQueryFunc = func(ctx context.Context, query *fleet.Query, opts ...fleet.OptionalArg) (*fleet.Query, error) {
+		query.ID = 42
+		return query, nil
+	}
+	var gotCampaign *fleet.DistributedQueryCampaign
+	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
+		gotCampaign = camp
+		camp.ID = 21
+		return camp, nil
+	}
+	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
+		return target, nil
+	}
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
+		return fleet.TargetMetrics{}, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
+		return []uint{1, 3, 5}, nil
+	}
+	ds.RecordLiveQueryCampaignUsageFunc = func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+		return nil
+	}
+	ds.NewDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+		return nil
+	}
+	ds.NewLiveQueryAuditLogFunc = func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+	var gotBatchQuery *fleet.BatchQuery
+	ds.NewBatchQueryFunc = func(ctx context.Context, bq *fleet.BatchQuery) error {
+		// the batch query is recorded before the query is run
+		lq.AssertNotCalled(t, "RunQuery", "21", "select 1", []uint{1, 3, 5})
+		gotBatchQuery = bq
+		return nil
+	}
+	ds.BatchQueryFunc = func(ctx context.Context, campaignID uint) (*fleet.BatchQuery, error) {
+		return gotBatchQuery, nil
+	}
+	lq.On("RunQuery", "21", "select 1", []uint{1, 3, 5}).Return(nil)
+
+	viewerCtx := viewer.NewContext(context.Background(), viewer.Viewer{
+		User: &fleet.User{
+			ID:         0,
+			GlobalRole: ptr.String(fleet.RoleAdmin),
+		},
+	})
+	targets := fleet.HostTargets{LabelIDs: []uint{1}}
+	limits := fleet.DistributedQueryResultLimits{}
+
+	_, err := svc.NewBatchQuery(viewerCtx, "select 1", nil, targets, false, limits, 101, 0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "completion_threshold")
+	_, err = svc.NewBatchQuery(viewerCtx, "select 1", nil, targets, false, limits, 0, 8*24*time.Hour)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "duration_hours")
+	assert.False(t, ds.NewDistributedQueryCampaignFuncInvoked)
+
+	bq, err := svc.NewBatchQuery(viewerCtx, "select 1", nil, targets, false, limits, 0, 0)
+	require.NoError(t, err)
+	assert.True(t, gotCampaign.Batch)
+	assert.Equal(t, fleet.QueryRunning, gotCampaign.Status)
+	assert.Equal(t, &fleet.BatchQuery{
+		CampaignID:          21,
+		CompletionThreshold: defaultBatchQueryCompletionThreshold,
+		TargetedHosts:       3,
+		ExpiresAt:           mockClock.Now().Add(defaultBatchQueryDuration),
+	}, bq)
+	lq.AssertExpectations(t)
+}
+
+func TestIngestBatchQueryResult(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	ds := new(mock.Store)
+	lq := new(live_query.MockLiveQuery)
+	svc := &Service{
+		ds:              ds,
+		resultStore:     pubsub.NewInmemQueryResults(),
+		liveQueryStore:  lq,
+		config:          config.TestConfig(),
+		logger:          kitlog.NewNopLogger(),
+		clock:           mockClock,
+		queryRowsUsage:  newQueryRowsUsageCounts(),
+		liveQueryWrites: newLiveQueryResultWrites(),
+	}
+
+	var webhookPayloads []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &payload))
+		webhookPayloads = append(webhookPayloads, payload)
+	}))
+	defer ts.Close()
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com"},
+			WebhookSettings: fleet.WebhookSettings{
+				BatchQueryWebhook: fleet.BatchQueryWebhookSettings{Enable: true, DestinationURL: ts.URL},
+			},
+		}, nil
+	}
+
+	campaign := &fleet.DistributedQueryCampaign{ID: 42, QueryID: 7, Status: fleet.QueryRunning, Batch: true}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return campaign, nil
+	}
+	ds.UpdateDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, status fleet.DistributedQueryExecutionStatus) error {
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
+	bq := &fleet.BatchQuery{CampaignID: 42, QueryID: 7, Status: fleet.QueryRunning, CompletionThreshold: 50, TargetedHosts: 3}
+	var gotResults []*fleet.BatchQueryResult
+	ds.NewBatchQueryResultsFunc = func(ctx context.Context, campaignID uint, results []*fleet.BatchQueryResult) (*fleet.BatchQuery, error) {
+		assert.Equal(t, uint(42), campaignID)
+		gotResults = append(gotResults, results...)
+		bq.RespondedHosts += uint(len(results))
+		copied := *bq
+		return &copied, nil
+	}
+	ds.MarkBatchQueryNotifiedFunc = func(ctx context.Context, campaignID uint, now time.Time) (bool, error) {
+		if bq.NotifiedAt != nil {
+			return false, nil
+		}
+		bq.NotifiedAt = &now
+		return true, nil
+	}
+	ds.SaveDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) error {
+		bq.Status = camp.Status
+		return nil
+	}
+
+	ingest := func(hostID uint, rows []map[string]string) {
+		lq.On("QueryCompletedByHost", strconv.Itoa(int(campaign.ID)), hostID).Return(nil).Once()
+		host := fleet.Host{ID: hostID, Hostname: "host" + strconv.Itoa(int(hostID))}
+		err := svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", rows, false, "")
+		require.NoError(t, err)
+	}
+
+	// the results are stored by the next flush, there is no subscriber
+	ingest(1, []map[string]string{{"name": "ssh"}})
+	assert.Empty(t, gotResults)
+	require.NoError(t, svc.FlushLiveQueryResults(context.Background()))
+	require.Len(t, gotResults, 1)
+	assert.Equal(t, uint(1), gotResults[0].HostID)
+	assert.Equal(t, "host1", gotResults[0].Hostname)
+	assert.JSONEq(t, `[{"name":"ssh"}]`, string(gotResults[0].Rows))
+	assert.Empty(t, webhookPayloads)
+
+	// the webhook is triggered once the threshold is reached
+	ingest(2, nil)
+	require.NoError(t, svc.FlushLiveQueryResults(context.Background()))
+	assert.JSONEq(t, `[]`, string(gotResults[1].Rows))
+	require.Len(t, webhookPayloads, 1)
+	assert.Equal(t, map[string]interface{}{
+		"campaign_id":          float64(42),
+		"query_id":             float64(7),
+		"completion_threshold": float64(50),
+		"targeted_hosts":       float64(3),
+		"responded_hosts":      float64(2),
+		"url":                  "https://fleet.example.com/api/v1/fleet/queries/batch/42/results",
+	}, webhookPayloads[0]["data"])
+	assert.False(t, ds.SaveDistributedQueryCampaignFuncInvoked)
+
+	// and only once, the campaign is completed once all the hosts responded
+	lq.On("StopQuery", "42").Return(nil)
+	ingest(3, nil)
+	require.NoError(t, svc.FlushLiveQueryResults(context.Background()))
+	assert.Len(t, webhookPayloads, 1)
+	assert.True(t, ds.SaveDistributedQueryCampaignFuncInvoked)
+	assert.Equal(t, fleet.QueryComplete, campaign.Status)
+	lq.AssertExpectations(t)
+}
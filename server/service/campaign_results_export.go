@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	authz_ctx "github.com/fleetdm/fleet/v4/server/contexts/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/sse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Export Distributed Query Campaign Results
+////////////////////////////////////////////////////////////////////////////////
+
+const (
+	campaignExportCSV    = "csv"
+	campaignExportNDJSON = "ndjson"
+)
+
+var campaignExportContentTypes = map[string]string{
+	campaignExportCSV:    "text/csv",
+	campaignExportNDJSON: "application/x-ndjson",
+}
+
+// errCampaignExportFinished is returned when writing to an export once the
+// campaign is finished.
+var errCampaignExportFinished = errors.New("campaign export finished")
+
+// campaignResultsExport is a fleet.CampaignResultsStream writing the rows of
+// the results of a campaign as CSV or NDJSON, one row per line. The messages
+// other than the results are not exported, and the export ends once all the
+// hosts expected to respond did.
+type campaignResultsExport struct {
+	w        io.Writer
+	closed   func() bool
+	format   string
+	finished bool
+
+	csv *csv.Writer
+	// columns are the columns of the CSV export, set from the first row.
+	columns []string
+}
+
+func newCampaignResultsExport(w io.Writer, closed func() bool, format string) *campaignResultsExport {
+	e := &campaignResultsExport{w: w, closed: closed, format: format}
+	if format == campaignExportCSV {
+		e.csv = csv.NewWriter(w)
+	}
+	return e
+}
+
+func (e *campaignResultsExport) WriteJSONMessage(typ string, data interface{}) error {
+	if e.finished {
+		return errCampaignExportFinished
+	}
+	switch data := data.(type) {
+	case fleet.DistributedQueryResult:
+		return e.writeResult(data)
+	case campaignStatus:
+		if data.Status == campaignStatusFinished {
+			e.finished = true
+		}
+	}
+	return nil
+}
+
+// WriteJSONError writes the error as the last line of the export, as an
+// object with an "error" key in NDJSON, or prefixed with "error: " in CSV.
+func (e *campaignResultsExport) WriteJSONError(data interface{}) error {
+	if e.finished {
+		return errCampaignExportFinished
+	}
+	e.finished = true
+	msg := fmt.Sprint(data)
+	if e.format == campaignExportNDJSON {
+		return e.writeJSONLine(map[string]string{"error": msg})
+	}
+	return e.writeCSVRecord([]string{"error: " + msg})
+}
+
+func (e *campaignResultsExport) Closed() bool {
+	return e.finished || e.closed()
+}
+
+func (e *campaignResultsExport) writeResult(res fleet.DistributedQueryResult) error {
+	// The errors of the hosts are not exported, only their rows.
+	if res.Error != nil {
+		return nil
+	}
+	for _, row := range res.Rows {
+		if row == nil {
+			continue
+		}
+		if e.format == campaignExportNDJSON {
+			if err := e.writeJSONLine(row); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if e.columns == nil {
+			e.columns = campaignExportColumns(row)
+			e.csv.Write(e.columns)
+		}
+		// The values of the columns not in the first row are dropped.
+		record := make([]string, len(e.columns))
+		for i, col := range e.columns {
+			record[i] = row[col]
+		}
+		e.csv.Write(record)
+	}
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
+
+func (e *campaignResultsExport) writeJSONLine(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(b, '\n'))
+	return err
+}
+
+func (e *campaignResultsExport) writeCSVRecord(record []string) error {
+	e.csv.Write(record)
+	e.csv.Flush()
+	return e.csv.Error()
+}
+
+// campaignExportColumns returns the columns of row, with the hostname of the
+// host first and the others sorted by name.
+func campaignExportColumns(row map[string]string) []string {
+	columns := make([]string, 0, len(row))
+	for col := range row {
+		if col != "host_hostname" {
+			columns = append(columns, col)
+		}
+	}
+	sort.Strings(columns)
+	if _, ok := row["host_hostname"]; ok {
+		columns = append([]string{"host_hostname"}, columns...)
+	}
+	return columns
+}
+
+type exportDistributedQueryCampaignResultsRequest struct {
+	ID     uint   `url:"id"`
+	Format string `query:"format"`
+}
+
+type exportDistributedQueryCampaignResultsResponse struct {
+	svc        fleet.Service
+	campaignID uint
+	format     string
+	Err        error `json:"error,omitempty"`
+}
+
+func (r exportDistributedQueryCampaignResultsResponse) error() error { return r.Err }
+
+// hijackRender streams the rows of the results of the campaign as they are
+// received, until all the hosts expected to respond did.
+func (r exportDistributedQueryCampaignResultsResponse) hijackRender(ctx context.Context, w http.ResponseWriter) {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		logging.WithErr(ctx, fleet.ErrNoContext)
+		return
+	}
+
+	stream, err := sse.NewRawStream(ctx, w, campaignExportContentTypes[r.format], map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="Live query %d %s.%s"`, r.campaignID, time.Now().Format("2006-01-02"), r.format),
+	})
+	if err != nil {
+		logging.WithErr(ctx, ctxerr.Wrap(ctx, err, "start results export"))
+		return
+	}
+	defer stream.Close()
+
+	// As with the Server-Sent Events stream, the export outlives the request
+	// when the connection is hijacked.
+	export := newCampaignResultsExport(stream, stream.Closed, r.format)
+	r.svc.StreamCampaignResults(viewer.NewContext(context.Background(), vc), export, r.campaignID)
+}
+
+func exportDistributedQueryCampaignResultsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*exportDistributedQueryCampaignResultsRequest)
+
+	// The authorization is checked by StreamCampaignResults once the export
+	// is started, and its errors are written at the end of the export.
+	if az, ok := authz_ctx.FromContext(ctx); ok {
+		az.SetChecked()
+	}
+	if _, ok := campaignExportContentTypes[req.Format]; !ok {
+		err := ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("format", "unsupported or unspecified export format, must be csv or ndjson").
+			WithStatus(http.StatusUnsupportedMediaType))
+		return exportDistributedQueryCampaignResultsResponse{Err: err}, nil
+	}
+	return exportDistributedQueryCampaignResultsResponse{svc: svc, campaignID: req.ID, format: req.Format}, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaignResultsExport(t *testing.T) {
+	results := []fleet.DistributedQueryResult{
+		{
+			Host: fleet.Host{Hostname: "host1"},
+			Rows: []map[string]string{
+				{"host_hostname": "host1", "name": "a", "version": "1,0"},
+				nil,
+				{"host_hostname": "host1", "name": "b", "version": "2"},
+			},
+		},
+		{
+			Host:  fleet.Host{Hostname: "host2"},
+			Error: ptr.String("no such table"),
+		},
+		{
+			Host: fleet.Host{Hostname: "host3"},
+			Rows: []map[string]string{
+				{"host_hostname": "host3", "name": "c", "extra": "dropped"},
+			},
+		},
+	}
+
+	cases := []struct {
+		format string
+		want   string
+	}{
+		{campaignExportCSV, "host_hostname,name,version\nhost1,a,\"1,0\"\nhost1,b,2\nhost3,c,\n"},
+		{campaignExportNDJSON, `{"host_hostname":"host1","name":"a","version":"1,0"}
+{"host_hostname":"host1","name":"b","version":"2"}
+{"extra":"dropped","host_hostname":"host3","name":"c"}
+`},
+	}
+	for _, c := range cases {
+		t.Run(c.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			closed := false
+			export := newCampaignResultsExport(&buf, func() bool { return closed }, c.format)
+
+			// the messages other than the results are not exported
+			require.NoError(t, export.WriteJSONMessage("totals", targetTotals{Total: 3, Online: 3}))
+			require.NoError(t, export.WriteJSONMessage("status", campaignStatus{ExpectedResults: 3, Status: campaignStatusPending}))
+			for _, res := range results {
+				require.NoError(t, export.WriteJSONMessage("result", res))
+			}
+			assert.Equal(t, c.want, buf.String())
+			assert.False(t, export.Closed())
+
+			// the export ends once the campaign is finished
+			require.NoError(t, export.WriteJSONMessage("status", campaignStatus{ExpectedResults: 3, ActualResults: 3, Status: campaignStatusFinished}))
+			assert.True(t, export.Closed())
+			require.ErrorIs(t, export.WriteJSONMessage("result", results[0]), errCampaignExportFinished)
+			assert.Equal(t, c.want, buf.String())
+		})
+	}
+
+	// the export ends when the client closes it
+	closed := false
+	export := newCampaignResultsExport(&bytes.Buffer{}, func() bool { return closed }, campaignExportCSV)
+	assert.False(t, export.Closed())
+	closed = true
+	assert.True(t, export.Closed())
+}
+
+func TestCampaignResultsExportError(t *testing.T) {
+	var buf bytes.Buffer
+	export := newCampaignResultsExport(&buf, func() bool { return false }, campaignExportCSV)
+	require.NoError(t, export.WriteJSONError("forbidden"))
+	assert.Equal(t, "error: forbidden\n", buf.String())
+	assert.True(t, export.Closed())
+
+	buf.Reset()
+	export = newCampaignResultsExport(&buf, func() bool { return false }, campaignExportNDJSON)
+	require.NoError(t, export.WriteJSONError("cannot find campaign for ID 1"))
+	assert.Equal(t, `{"error":"cannot find campaign for ID 1"}`+"\n", buf.String())
+}
@@ -2,16 +2,23 @@ package service
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/fleetdm/fleet/v4/server"
 	"github.com/fleetdm/fleet/v4/server/authz"
+	authz_ctx "github.com/fleetdm/fleet/v4/server/contexts/authz"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/contexts/logging"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/sse"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -19,9 +26,14 @@ import (
 ////////////////////////////////////////////////////////////////////////////////
 
 type createDistributedQueryCampaignRequest struct {
-	QuerySQL string            `json:"query"`
-	QueryID  *uint             `json:"query_id"`
-	Selected fleet.HostTargets `json:"selected"`
+	QuerySQL  string            `json:"query"`
+	QueryID   *uint             `json:"query_id"`
+	Selected  fleet.HostTargets `json:"selected"`
+	Anonymize bool              `json:"anonymize"`
+	// DuplicateResults overrides the redis.duplicate_results configuration
+	// for the campaign, if set.
+	DuplicateResults *bool `json:"duplicate_results"`
+	fleet.DistributedQueryResultLimits
 }
 
 type createDistributedQueryCampaignResponse struct {
@@ -33,14 +45,21 @@ func (r createDistributedQueryCampaignResponse) error() error { return r.Err }
 
 func createDistributedQueryCampaignEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*createDistributedQueryCampaignRequest)
-	campaign, err := svc.NewDistributedQueryCampaign(ctx, req.QuerySQL, req.QueryID, req.Selected)
+	campaign, err := svc.NewDistributedQueryCampaign(ctx, req.QuerySQL, req.QueryID, req.Selected, req.Anonymize, req.DuplicateResults, req.DistributedQueryResultLimits)
 	if err != nil {
 		return createDistributedQueryCampaignResponse{Err: err}, nil
 	}
 	return createDistributedQueryCampaignResponse{Campaign: campaign}, nil
 }
 
-func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets) (*fleet.DistributedQueryCampaign, error) {
+func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets, anonymize bool, duplicateResults *bool, limits fleet.DistributedQueryResultLimits) (*fleet.DistributedQueryCampaign, error) {
+	return svc.newDistributedQueryCampaign(ctx, queryString, queryID, targets, anonymize, duplicateResults, limits, nil)
+}
+
+// newDistributedQueryCampaign creates the campaign of a live query or, if
+// batch is not nil, of a batch query, recorded with the campaign ID and the
+// number of targeted hosts set.
+func (svc *Service) newDistributedQueryCampaign(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets, anonymize bool, duplicateResults *bool, limits fleet.DistributedQueryResultLimits, batch *fleet.BatchQuery) (*fleet.DistributedQueryCampaign, error) {
 	if err := svc.StatusLiveQuery(ctx); err != nil {
 		return nil, err
 	}
@@ -53,6 +72,9 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 	if queryID == nil && queryString == "" {
 		return nil, fleet.NewInvalidArgumentError("query", "one of query or query_id must be specified")
 	}
+	if err := svc.validateLabelExpression(ctx, targets.LabelExpression); err != nil {
+		return nil, err
+	}
 
 	var query *fleet.Query
 	var err error
@@ -85,14 +107,42 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 	if err := svc.authz.Authorize(ctx, tq, fleet.ActionRun); err != nil {
 		return nil, err
 	}
+	if batch == nil {
+		if err := svc.checkLiveQueryCampaignLimits(ctx, vc.UserID()); err != nil {
+			return nil, err
+		}
+	}
 
 	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: query.ObserverCanRun}
 
-	campaign, err := svc.ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
-		QueryID: query.ID,
-		Status:  fleet.QueryWaiting,
-		UserID:  vc.UserID(),
-	})
+	newCampaign := &fleet.DistributedQueryCampaign{
+		QueryID:          query.ID,
+		Status:           fleet.QueryWaiting,
+		UserID:           vc.UserID(),
+		Anonymize:        anonymize,
+		LabelExpression:  targets.LabelExpression,
+		OnlineOnly:       targets.OnlineOnly,
+		DuplicateResults: duplicateResults,
+
+		DistributedQueryResultLimits: limits,
+	}
+	if batch != nil {
+		// nobody subscribes to the results of a batch query, it is running
+		// as soon as it is launched
+		newCampaign.Status = fleet.QueryRunning
+		newCampaign.Batch = true
+	}
+	if anonymize {
+		// the pseudonyms are derived from a key specific to the campaign, so
+		// that the same host cannot be correlated across campaigns.
+		key, err := server.GenerateRandomText(pseudonymKeySize)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "generate pseudonym key")
+		}
+		newCampaign.PseudonymKey = key
+	}
+
+	campaign, err := svc.ds.NewDistributedQueryCampaign(ctx, newCampaign)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "new campaign")
 	}
@@ -141,17 +191,52 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 		}
 	}
 
-	hostIDs, err := svc.ds.HostIDsInTargets(ctx, filter, targets)
+	if err := svc.addExcludedDistributedQueryCampaignTargets(ctx, campaign.ID, targets.ExcludedHostIDs, targets.ExcludedLabelIDs); err != nil {
+		return nil, err
+	}
+
+	// the status of the hosts is computed with the same host settings for all
+	// the targets.
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config for host status")
+	}
+
+	hostIDs, err := svc.ds.HostIDsInTargets(ctx, filter, targets, appConfig.HostSettings)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "get target IDs")
 	}
 
+	// the live query is not run if it cannot be audited
+	if err := svc.newLiveQueryAuditLog(ctx, vc.User, campaign.ID, query, targets, len(hostIDs)); err != nil {
+		return nil, err
+	}
+
+	if err := svc.ds.NewDistributedQueryExecutions(ctx, campaign.ID, hostIDs, time.Now(), appConfig.HostSettings); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "record executions")
+	}
+
+	// the batch query is recorded before the hosts can send their results
+	if batch != nil {
+		batch.CampaignID = campaign.ID
+		batch.TargetedHosts = uint(len(hostIDs))
+		if err := svc.ds.NewBatchQuery(ctx, batch); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "new batch query")
+		}
+	}
+
 	err = svc.liveQueryStore.RunQuery(strconv.Itoa(int(campaign.ID)), queryString, hostIDs)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "run query")
 	}
 
-	campaign.Metrics, err = svc.ds.CountHostsInTargets(ctx, filter, targets, time.Now())
+	// the usage is only accounted for, a failure must not prevent the query
+	// from running
+	if err := svc.ds.RecordLiveQueryCampaignUsage(ctx, vc.UserID(), hostIDs, svc.clock.Now()); err != nil {
+		logging.WithErr(ctx, err)
+	}
+
+	campaign.Metrics, err = svc.ds.CountHostsInTargets(ctx, filter, targets, time.Now(), appConfig.HostSettings)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "counting hosts")
 	}
@@ -167,14 +252,72 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 	return campaign, nil
 }
 
+// maxLabelExpressionLength is the maximum length of the label expression of
+// the targets of a campaign.
+const maxLabelExpressionLength = 1024
+
+// checkLiveQueryCampaignLimits returns an error if the user, or all the users,
+// have as many live query campaigns running as allowed by the configuration.
+func (svc *Service) checkLiveQueryCampaignLimits(ctx context.Context, userID uint) error {
+	if max := svc.config.Osquery.MaxLiveQueryCampaignsPerUser; max > 0 {
+		count, err := svc.ds.CountActiveDistributedQueryCampaigns(ctx, &userID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "count user campaigns")
+		}
+		if count >= max {
+			return fleet.NewLiveQueryCampaignLimitError(fmt.Sprintf(
+				"live query limit reached: you already have %d live queries running, stop one or wait for it to complete", count))
+		}
+	}
+	if max := svc.config.Osquery.MaxLiveQueryCampaigns; max > 0 {
+		count, err := svc.ds.CountActiveDistributedQueryCampaigns(ctx, nil)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "count campaigns")
+		}
+		if count >= max {
+			return fleet.NewLiveQueryCampaignLimitError(fmt.Sprintf(
+				"live query limit reached: %d live queries are already running in Fleet, wait for one to complete", count))
+		}
+	}
+	return nil
+}
+
+// validateLabelExpression returns an invalid argument error if the label
+// expression of the targets of a campaign is invalid or references unknown
+// labels.
+func (svc *Service) validateLabelExpression(ctx context.Context, expr string) error {
+	if expr == "" {
+		return nil
+	}
+	if len(expr) > maxLabelExpressionLength {
+		return fleet.NewInvalidArgumentError("selected.label_expression", fmt.Sprintf("must be at most %d characters", maxLabelExpressionLength))
+	}
+	parsed, err := fleet.ParseLabelExpression(expr)
+	if err != nil {
+		return fleet.NewInvalidArgumentError("selected.label_expression", err.Error())
+	}
+	names := parsed.LabelNames()
+	labelIDs, err := svc.ds.LabelIDsByName(ctx, names)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "finding label IDs")
+	}
+	if len(labelIDs) != len(names) {
+		return fleet.NewInvalidArgumentError("selected.label_expression", "references unknown labels")
+	}
+	return nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Create Distributed Query Campaign By Names
 ////////////////////////////////////////////////////////////////////////////////
 
 type createDistributedQueryCampaignByNamesRequest struct {
-	QuerySQL string                                 `json:"query"`
-	QueryID  *uint                                  `json:"query_id"`
-	Selected distributedQueryCampaignTargetsByNames `json:"selected"`
+	QuerySQL         string                                 `json:"query"`
+	QueryID          *uint                                  `json:"query_id"`
+	Selected         distributedQueryCampaignTargetsByNames `json:"selected"`
+	Anonymize        bool                                   `json:"anonymize"`
+	DuplicateResults *bool                                  `json:"duplicate_results"`
+	fleet.DistributedQueryResultLimits
 }
 
 type distributedQueryCampaignTargetsByNames struct {
@@ -184,14 +327,14 @@ type distributedQueryCampaignTargetsByNames struct {
 
 func createDistributedQueryCampaignByNamesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*createDistributedQueryCampaignByNamesRequest)
-	campaign, err := svc.NewDistributedQueryCampaignByNames(ctx, req.QuerySQL, req.QueryID, req.Selected.Hosts, req.Selected.Labels)
+	campaign, err := svc.NewDistributedQueryCampaignByNames(ctx, req.QuerySQL, req.QueryID, req.Selected.Hosts, req.Selected.Labels, req.Anonymize, req.DuplicateResults, req.DistributedQueryResultLimits)
 	if err != nil {
 		return createDistributedQueryCampaignResponse{Err: err}, nil
 	}
 	return createDistributedQueryCampaignResponse{Campaign: campaign}, nil
 }
 
-func (svc *Service) NewDistributedQueryCampaignByNames(ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string) (*fleet.DistributedQueryCampaign, error) {
+func (svc *Service) NewDistributedQueryCampaignByNames(ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string, anonymize bool, duplicateResults *bool, limits fleet.DistributedQueryResultLimits) (*fleet.DistributedQueryCampaign, error) {
 	vc, ok := viewer.FromContext(ctx)
 	if !ok {
 		return nil, fleet.ErrNoContext
@@ -209,5 +352,324 @@ func (svc *Service) NewDistributedQueryCampaignByNames(ctx context.Context, quer
 	}
 
 	targets := fleet.HostTargets{HostIDs: hostIDs, LabelIDs: labelIDs}
-	return svc.NewDistributedQueryCampaign(ctx, queryString, queryID, targets)
+	return svc.NewDistributedQueryCampaign(ctx, queryString, queryID, targets, anonymize, duplicateResults, limits)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Rerun Distributed Query Campaign
+////////////////////////////////////////////////////////////////////////////////
+
+type rerunDistributedQueryCampaignRequest struct {
+	ID uint `url:"id"`
+}
+
+func rerunDistributedQueryCampaignEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*rerunDistributedQueryCampaignRequest)
+	campaign, err := svc.RerunDistributedQueryCampaign(ctx, req.ID)
+	if err != nil {
+		return createDistributedQueryCampaignResponse{Err: err}, nil
+	}
+	return createDistributedQueryCampaignResponse{Campaign: campaign}, nil
+}
+
+func (svc *Service) RerunDistributedQueryCampaign(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaign, error) {
+	// The query and the targets of the new campaign are authorized when it is
+	// created, this only checks that the user can run queries.
+	campaign, err := svc.viewerDistributedQueryCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := svc.ds.DistributedQueryCampaignTargetIDs(ctx, campaign.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get campaign targets")
+	}
+	query, err := svc.ds.Query(ctx, campaign.QueryID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get campaign query")
+	}
+
+	if query.Saved {
+		return svc.NewDistributedQueryCampaign(ctx, "", &query.ID, *targets, campaign.Anonymize, campaign.DuplicateResults, campaign.DistributedQueryResultLimits)
+	}
+	// The query of the campaign was not saved, it is run as a new query so
+	// that the user must still be allowed to run new queries.
+	return svc.NewDistributedQueryCampaign(ctx, query.Query, nil, *targets, campaign.Anonymize, campaign.DuplicateResults, campaign.DistributedQueryResultLimits)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Remove Distributed Query Campaign Targets
+////////////////////////////////////////////////////////////////////////////////
+
+type removeDistributedQueryCampaignTargetsRequest struct {
+	ID       uint   `url:"id"`
+	HostIDs  []uint `json:"hosts"`
+	LabelIDs []uint `json:"labels"`
+}
+
+type removeDistributedQueryCampaignTargetsResponse struct {
+	RemovedHosts uint  `json:"removed_hosts"`
+	Err          error `json:"error,omitempty"`
+}
+
+func (r removeDistributedQueryCampaignTargetsResponse) error() error { return r.Err }
+
+func removeDistributedQueryCampaignTargetsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*removeDistributedQueryCampaignTargetsRequest)
+	removed, err := svc.RemoveDistributedQueryCampaignTargets(ctx, req.ID, req.HostIDs, req.LabelIDs)
+	if err != nil {
+		return removeDistributedQueryCampaignTargetsResponse{Err: err}, nil
+	}
+	return removeDistributedQueryCampaignTargetsResponse{RemovedHosts: removed}, nil
+}
+
+func (svc *Service) RemoveDistributedQueryCampaignTargets(ctx context.Context, campaignID uint, hostIDs, labelIDs []uint) (uint, error) {
+	campaign, err := svc.viewerDistributedQueryCampaign(ctx, campaignID)
+	if err != nil {
+		return 0, err
+	}
+	if len(hostIDs) == 0 && len(labelIDs) == 0 {
+		return 0, fleet.NewInvalidArgumentError("hosts", "at least one host or label must be specified")
+	}
+	if campaign.Status == fleet.QueryComplete {
+		return 0, fleet.NewInvalidArgumentError("id", "the live query campaign is not running")
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return 0, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
+
+	// The exclusions take precedence over the other targets, so the hosts
+	// remain excluded even if they are also targeted by a label or team.
+	// the host settings are not used, the targets are not restricted to the
+	// online hosts.
+	removedHostIDs, err := svc.ds.HostIDsInTargets(ctx, filter, fleet.HostTargets{HostIDs: hostIDs, LabelIDs: labelIDs}, fleet.HostSettings{})
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "get removed target IDs")
+	}
+	if err := svc.addExcludedDistributedQueryCampaignTargets(ctx, campaign.ID, hostIDs, labelIDs); err != nil {
+		return 0, err
+	}
+
+	if err := svc.liveQueryStore.RemoveHostsFromQuery(strconv.Itoa(int(campaign.ID)), removedHostIDs); err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "remove hosts from query")
+	}
+
+	// Only the hosts that did not respond yet are removed from the progress
+	// of the campaign, the results already received are kept.
+	removed, err := svc.ds.DeletePendingDistributedQueryExecutions(ctx, campaign.ID, removedHostIDs)
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "delete pending executions")
+	}
+	return removed, nil
+}
+
+// addExcludedDistributedQueryCampaignTargets records the hosts and labels
+// excluded from the targets of the campaign.
+func (svc *Service) addExcludedDistributedQueryCampaignTargets(ctx context.Context, campaignID uint, hostIDs, labelIDs []uint) error {
+	for _, hid := range hostIDs {
+		_, err := svc.ds.NewDistributedQueryCampaignTarget(ctx, &fleet.DistributedQueryCampaignTarget{
+			Type:                       fleet.TargetHost,
+			DistributedQueryCampaignID: campaignID,
+			TargetID:                   hid,
+			Excluded:                   true,
+		})
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "adding excluded host target")
+		}
+	}
+	for _, lid := range labelIDs {
+		_, err := svc.ds.NewDistributedQueryCampaignTarget(ctx, &fleet.DistributedQueryCampaignTarget{
+			Type:                       fleet.TargetLabel,
+			DistributedQueryCampaignID: campaignID,
+			TargetID:                   lid,
+			Excluded:                   true,
+		})
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "adding excluded label target")
+		}
+	}
+	return nil
+}
+
+// viewerDistributedQueryCampaign returns the campaign with the provided ID if
+// it was run by the user of the viewer context, who must be allowed to run
+// queries.
+func (svc *Service) viewerDistributedQueryCampaign(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaign, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.TargetedQuery{Query: &fleet.Query{ObserverCanRun: true}}, fleet.ActionRun); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+
+	campaign, err := svc.ds.DistributedQueryCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get campaign")
+	}
+	// Only the user that ran the campaign can see its query and targets.
+	if campaign.UserID != vc.UserID() {
+		return nil, authz.ForbiddenWithInternal("campaign run by another user", vc.User, campaign, fleet.ActionRun)
+	}
+	return campaign, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Distributed Query Campaign Progress
+////////////////////////////////////////////////////////////////////////////////
+
+type getDistributedQueryCampaignProgressRequest struct {
+	ID uint `url:"id"`
+}
+
+type getDistributedQueryCampaignProgressResponse struct {
+	Progress *fleet.DistributedQueryCampaignProgress `json:"progress,omitempty"`
+	Err      error                                   `json:"error,omitempty"`
+}
+
+func (r getDistributedQueryCampaignProgressResponse) error() error { return r.Err }
+
+func getDistributedQueryCampaignProgressEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getDistributedQueryCampaignProgressRequest)
+	progress, err := svc.DistributedQueryCampaignProgress(ctx, req.ID)
+	if err != nil {
+		return getDistributedQueryCampaignProgressResponse{Err: err}, nil
+	}
+	return getDistributedQueryCampaignProgressResponse{Progress: progress}, nil
+}
+
+func (svc *Service) DistributedQueryCampaignProgress(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaignProgress, error) {
+	campaign, err := svc.viewerDistributedQueryCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.ds.DistributedQueryCampaignProgress(ctx, campaign.ID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Stream Distributed Query Campaign Results with Server-Sent Events
+////////////////////////////////////////////////////////////////////////////////
+
+type streamDistributedQueryCampaignResultsRequest struct {
+	ID uint `url:"id"`
+}
+
+type streamDistributedQueryCampaignResultsResponse struct {
+	svc        fleet.Service
+	campaignID uint
+	Err        error `json:"error,omitempty"`
+}
+
+func (r streamDistributedQueryCampaignResultsResponse) error() error { return r.Err }
+
+// hijackRender streams the results of the campaign as Server-Sent Events,
+// with the same messages as the websocket stream of the results.
+func (r streamDistributedQueryCampaignResultsResponse) hijackRender(ctx context.Context, w http.ResponseWriter) {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		logging.WithErr(ctx, fleet.ErrNoContext)
+		return
+	}
+
+	stream, err := sse.NewStream(ctx, w)
+	if err != nil {
+		logging.WithErr(ctx, ctxerr.Wrap(ctx, err, "start results stream"))
+		return
+	}
+	defer stream.Close()
+
+	// The stream outlives the request when the connection is hijacked, and
+	// the campaign is completed once the stream ends, so the context of the
+	// request is not used.
+	r.svc.StreamCampaignResults(viewer.NewContext(context.Background(), vc), stream, r.campaignID)
+}
+
+func streamDistributedQueryCampaignResultsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*streamDistributedQueryCampaignResultsRequest)
+
+	// The authorization is checked by StreamCampaignResults once the stream
+	// is started, and its errors are sent as events of the stream, like with
+	// the websocket stream.
+	if az, ok := authz_ctx.FromContext(ctx); ok {
+		az.SetChecked()
+	}
+	return streamDistributedQueryCampaignResultsResponse{svc: svc, campaignID: req.ID}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Truncate Distributed Query Results
+////////////////////////////////////////////////////////////////////////////////
+
+// truncateDistributedQueryResult drops the rows of the result exceeding the
+// limits, and flags the result as truncated if rows were dropped.
+func truncateDistributedQueryResult(limits fleet.DistributedQueryResultLimits, res *fleet.DistributedQueryResult) {
+	if limits.MaxRows > 0 && uint(len(res.Rows)) > limits.MaxRows {
+		res.Rows = res.Rows[:limits.MaxRows]
+		res.Truncated = true
+	}
+	if limits.MaxResultBytes > 0 {
+		var size uint
+		for i, row := range res.Rows {
+			for col, val := range row {
+				size += uint(len(col) + len(val))
+			}
+			if size > limits.MaxResultBytes {
+				res.Rows = res.Rows[:i]
+				res.Truncated = true
+				break
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Anonymize Distributed Query Results
+////////////////////////////////////////////////////////////////////////////////
+
+// pseudonymKeySize is the size of the random key used to derive the
+// pseudonyms of an anonymized campaign.
+const pseudonymKeySize = 24
+
+// anonymizedColumns are the result columns that identify a host or a user.
+// In the results of anonymized campaigns, their values are replaced by
+// pseudonyms.
+var anonymizedColumns = map[string]bool{
+	"board_serial":    true,
+	"computer_name":   true,
+	"email":           true,
+	"hardware_serial": true,
+	"host_hostname":   true,
+	"hostname":        true,
+	"local_hostname":  true,
+	"mac":             true,
+	"user":            true,
+	"username":        true,
+	"uuid":            true,
+}
+
+// anonymizeDistributedQueryResult replaces the host of the result by a host
+// identified only by its pseudonym, and the values of the identifying columns
+// by their pseudonyms. The pseudonyms are stable for the campaign, so that the
+// prevalence of the results can still be computed.
+func anonymizeDistributedQueryResult(campaign *fleet.DistributedQueryCampaign, res *fleet.DistributedQueryResult) {
+	res.Host = fleet.Host{
+		Hostname: pseudonym(campaign.PseudonymKey, "host:"+strconv.FormatUint(uint64(res.Host.ID), 10)),
+	}
+	for _, row := range res.Rows {
+		for col, val := range row {
+			if anonymizedColumns[col] && val != "" {
+				row[col] = pseudonym(campaign.PseudonymKey, val)
+			}
+		}
+	}
+}
+
+func pseudonym(key, value string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
 }
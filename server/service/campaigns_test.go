@@ -30,6 +30,10 @@ func (nopLiveQuery) QueryCompletedByHost(name string, hostID uint) error {
 	return nil
 }
 
+func (nopLiveQuery) RemoveHostsFromQuery(name string, hostIDs []uint) error {
+	return nil
+}
+
 func TestLiveQueryAuth(t *testing.T) {
 	ds := new(mock.Store)
 	qr := pubsub.NewInmemQueryResults()
@@ -68,10 +72,19 @@ func TestLiveQueryAuth(t *testing.T) {
 	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
 		return camp, nil
 	}
+	ds.RecordLiveQueryCampaignUsageFunc = func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+		return nil
+	}
+	ds.NewDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+		return nil
+	}
+	ds.NewLiveQueryAuditLogFunc = func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+		return nil
+	}
 	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
 		return target, nil
 	}
-	ds.HostIDsInTargetsFunc = func(ctx context.Context, filters fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filters fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
 		return nil, nil
 	}
 	ds.HostIDsByNameFunc = func(ctx context.Context, filter fleet.TeamFilter, names []string) ([]uint, error) {
@@ -80,7 +93,7 @@ func TestLiveQueryAuth(t *testing.T) {
 	ds.LabelIDsByNameFunc = func(ctx context.Context, names []string) ([]uint, error) {
 		return nil, nil
 	}
-	ds.CountHostsInTargetsFunc = func(ctx context.Context, filters fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filters fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
 		return fleet.TargetMetrics{}, nil
 	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
@@ -205,28 +218,28 @@ func TestLiveQueryAuth(t *testing.T) {
 			if len(tt.user.Teams) > 0 {
 				tms = []uint{tt.user.Teams[0].ID}
 			}
-			_, err := svc.NewDistributedQueryCampaign(ctx, query1ObsCanRun.Query, nil, fleet.HostTargets{TeamIDs: tms})
+			_, err := svc.NewDistributedQueryCampaign(ctx, query1ObsCanRun.Query, nil, fleet.HostTargets{TeamIDs: tms}, false, nil, fleet.DistributedQueryResultLimits{})
 			checkAuthErr(t, tt.shouldFailRunNew, err)
 
 			if tt.teamID != nil {
 				tms = []uint{*tt.teamID}
 			}
-			_, err = svc.NewDistributedQueryCampaign(ctx, query1ObsCanRun.Query, ptr.Uint(query1ObsCanRun.ID), fleet.HostTargets{TeamIDs: tms})
+			_, err = svc.NewDistributedQueryCampaign(ctx, query1ObsCanRun.Query, ptr.Uint(query1ObsCanRun.ID), fleet.HostTargets{TeamIDs: tms}, false, nil, fleet.DistributedQueryResultLimits{})
 			checkAuthErr(t, tt.shouldFailRunObsCan, err)
 
-			_, err = svc.NewDistributedQueryCampaign(ctx, query2ObsCannotRun.Query, ptr.Uint(query2ObsCannotRun.ID), fleet.HostTargets{TeamIDs: tms})
+			_, err = svc.NewDistributedQueryCampaign(ctx, query2ObsCannotRun.Query, ptr.Uint(query2ObsCannotRun.ID), fleet.HostTargets{TeamIDs: tms}, false, nil, fleet.DistributedQueryResultLimits{})
 			checkAuthErr(t, tt.shouldFailRunObsCannot, err)
 
 			// tests with a team target cannot run the "ByNames" calls, as there's no way
 			// to pass a team target with this call.
 			if tt.teamID == nil {
-				_, err = svc.NewDistributedQueryCampaignByNames(ctx, query1ObsCanRun.Query, nil, nil, nil)
+				_, err = svc.NewDistributedQueryCampaignByNames(ctx, query1ObsCanRun.Query, nil, nil, nil, false, nil, fleet.DistributedQueryResultLimits{})
 				checkAuthErr(t, tt.shouldFailRunNew, err)
 
-				_, err = svc.NewDistributedQueryCampaignByNames(ctx, query1ObsCanRun.Query, ptr.Uint(query1ObsCanRun.ID), nil, nil)
+				_, err = svc.NewDistributedQueryCampaignByNames(ctx, query1ObsCanRun.Query, ptr.Uint(query1ObsCanRun.ID), nil, nil, false, nil, fleet.DistributedQueryResultLimits{})
 				checkAuthErr(t, tt.shouldFailRunObsCan, err)
 
-				_, err = svc.NewDistributedQueryCampaignByNames(ctx, query2ObsCannotRun.Query, ptr.Uint(query2ObsCannotRun.ID), nil, nil)
+				_, err = svc.NewDistributedQueryCampaignByNames(ctx, query2ObsCannotRun.Query, ptr.Uint(query2ObsCannotRun.ID), nil, nil, false, nil, fleet.DistributedQueryResultLimits{})
 				checkAuthErr(t, tt.shouldFailRunObsCannot, err)
 			}
 		})
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List Host Certificates
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostCertificatesRequest struct {
+	ID uint `url:"id"`
+}
+
+type listHostCertificatesResponse struct {
+	Err          error                   `json:"error,omitempty"`
+	Certificates []fleet.HostCertificate `json:"certificates"`
+}
+
+func (r listHostCertificatesResponse) error() error { return r.Err }
+
+func listHostCertificatesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostCertificatesRequest)
+	certs, err := svc.ListHostCertificates(ctx, req.ID)
+	if err != nil {
+		return listHostCertificatesResponse{Err: err}, nil
+	}
+	return listHostCertificatesResponse{Certificates: certs}, nil
+}
+
+func (svc *Service) ListHostCertificates(ctx context.Context, id uint) ([]fleet.HostCertificate, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "find host for certificates")
+	}
+
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.ListHostCertificates(ctx, id)
+}
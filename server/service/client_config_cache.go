@@ -0,0 +1,183 @@
+package service
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// clientConfigCache caches the parts of the osquery client config that are
+// rendered on every host check-in but do not depend on the host itself: the
+// agent options keyed by team and platform, the rendered queries of each pack
+// (the packs that apply to a host are still listed per host, as they depend
+// on the host's labels and targets) and the active agent options rollout.
+//
+// Entries expire after the configured TTL so that changes made through other
+// Fleet instances are eventually picked up, and the whole cache is
+// invalidated when this instance modifies agent options, packs or queries. A
+// zero TTL disables the cache.
+type clientConfigCache struct {
+	ttl   time.Duration
+	clock clock.Clock
+
+	mu           sync.Mutex
+	agentOptions map[agentOptionsCacheKey]agentOptionsCacheEntry
+	packs        map[uint]packCacheEntry
+	// rollout is the active agent options rollout, nil if there is none.
+	rollout        *fleet.AgentOptionsRollout
+	rolloutExpires time.Time
+}
+
+type agentOptionsCacheKey struct {
+	teamID   uint // 0 for hosts that do not belong to a team
+	platform string
+}
+
+type agentOptionsCacheEntry struct {
+	options json.RawMessage
+	expires time.Time
+}
+
+type packCacheEntry struct {
+	content fleet.PackContent
+	expires time.Time
+}
+
+func newClientConfigCache(ttl time.Duration, c clock.Clock) *clientConfigCache {
+	return &clientConfigCache{
+		ttl:          ttl,
+		clock:        c,
+		agentOptions: make(map[agentOptionsCacheKey]agentOptionsCacheEntry),
+		packs:        make(map[uint]packCacheEntry),
+	}
+}
+
+func (c *clientConfigCache) enabled() bool {
+	return c != nil && c.ttl > 0
+}
+
+func newAgentOptionsCacheKey(teamID *uint, platform string) agentOptionsCacheKey {
+	key := agentOptionsCacheKey{platform: platform}
+	if teamID != nil {
+		key.teamID = *teamID
+	}
+	return key
+}
+
+// getAgentOptions returns the cached agent options of the team and platform.
+// The returned options must not be modified.
+func (c *clientConfigCache) getAgentOptions(teamID *uint, platform string) (json.RawMessage, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := newAgentOptionsCacheKey(teamID, platform)
+	entry, ok := c.agentOptions[key]
+	if !ok {
+		return nil, false
+	}
+	if !c.clock.Now().Before(entry.expires) {
+		delete(c.agentOptions, key)
+		return nil, false
+	}
+	return entry.options, true
+}
+
+func (c *clientConfigCache) setAgentOptions(teamID *uint, platform string, options json.RawMessage) {
+	if !c.enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.agentOptions[newAgentOptionsCacheKey(teamID, platform)] = agentOptionsCacheEntry{
+		options: options,
+		expires: c.clock.Now().Add(c.ttl),
+	}
+}
+
+// getPack returns the cached rendered content of the pack. The returned
+// content must not be modified.
+func (c *clientConfigCache) getPack(packID uint) (fleet.PackContent, bool) {
+	if !c.enabled() {
+		return fleet.PackContent{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.packs[packID]
+	if !ok {
+		return fleet.PackContent{}, false
+	}
+	if !c.clock.Now().Before(entry.expires) {
+		delete(c.packs, packID)
+		return fleet.PackContent{}, false
+	}
+	return entry.content, true
+}
+
+func (c *clientConfigCache) setPack(packID uint, content fleet.PackContent) {
+	if !c.enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.packs[packID] = packCacheEntry{
+		content: content,
+		expires: c.clock.Now().Add(c.ttl),
+	}
+}
+
+// getRollout returns the cached active agent options rollout, nil if there
+// is none. The returned rollout must not be modified.
+func (c *clientConfigCache) getRollout() (*fleet.AgentOptionsRollout, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.clock.Now().Before(c.rolloutExpires) {
+		return nil, false
+	}
+	return c.rollout, true
+}
+
+func (c *clientConfigCache) setRollout(rollout *fleet.AgentOptionsRollout) {
+	if !c.enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rollout = rollout
+	c.rolloutExpires = c.clock.Now().Add(c.ttl)
+}
+
+// invalidate removes all the cached entries. It must be called after agent
+// options, agent options rollouts, packs or queries are modified.
+func (c *clientConfigCache) invalidate() {
+	if !c.enabled() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.agentOptions = make(map[agentOptionsCacheKey]agentOptionsCacheEntry)
+	c.packs = make(map[uint]packCacheEntry)
+	c.rollout = nil
+	c.rolloutExpires = time.Time{}
+}
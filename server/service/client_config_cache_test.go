@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/config"
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientConfigCache(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	c := newClientConfigCache(time.Minute, mockClock)
+
+	_, ok := c.getAgentOptions(nil, "darwin")
+	require.False(t, ok)
+
+	c.setAgentOptions(nil, "darwin", json.RawMessage(`{"a":1}`))
+	c.setAgentOptions(ptr.Uint(1), "darwin", json.RawMessage(`{"a":2}`))
+	c.setPack(1, fleet.PackContent{Platform: "darwin"})
+
+	opts, ok := c.getAgentOptions(nil, "darwin")
+	require.True(t, ok)
+	assert.JSONEq(t, `{"a":1}`, string(opts))
+	opts, ok = c.getAgentOptions(ptr.Uint(1), "darwin")
+	require.True(t, ok)
+	assert.JSONEq(t, `{"a":2}`, string(opts))
+	_, ok = c.getAgentOptions(nil, "windows")
+	require.False(t, ok)
+	pack, ok := c.getPack(1)
+	require.True(t, ok)
+	assert.Equal(t, "darwin", pack.Platform)
+
+	// the absence of an active rollout is cached too
+	_, ok = c.getRollout()
+	require.False(t, ok)
+	c.setRollout(nil)
+	rollout, ok := c.getRollout()
+	require.True(t, ok)
+	assert.Nil(t, rollout)
+
+	// entries expire after the TTL
+	mockClock.AddTime(time.Minute)
+	_, ok = c.getAgentOptions(nil, "darwin")
+	require.False(t, ok)
+	_, ok = c.getPack(1)
+	require.False(t, ok)
+
+	_, ok = c.getRollout()
+	require.False(t, ok)
+
+	// invalidate removes all entries
+	c.setAgentOptions(nil, "darwin", json.RawMessage(`{"a":1}`))
+	c.setPack(1, fleet.PackContent{Platform: "darwin"})
+	c.setRollout(&fleet.AgentOptionsRollout{ID: 1})
+	c.invalidate()
+	_, ok = c.getAgentOptions(nil, "darwin")
+	require.False(t, ok)
+	_, ok = c.getPack(1)
+	require.False(t, ok)
+	_, ok = c.getRollout()
+	require.False(t, ok)
+
+	// a zero TTL disables the cache
+	disabled := newClientConfigCache(0, mockClock)
+	disabled.setAgentOptions(nil, "darwin", json.RawMessage(`{"a":1}`))
+	_, ok = disabled.getAgentOptions(nil, "darwin")
+	require.False(t, ok)
+}
+
+func TestGetClientConfigCached(t *testing.T) {
+	ds := new(mock.Store)
+	mockClock := clock.NewMockClock()
+	cfg := config.TestConfig()
+	cfg.Osquery.ClientConfigCacheTTL = time.Minute
+	svc := newTestServiceWithConfig(t, ds, cfg, nil, nil, TestServerOpts{Clock: mockClock})
+
+	agentOptions := `{"config":{"options":{"distributed_interval":10}}}`
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{AgentOptions: ptr.RawMessage(json.RawMessage(agentOptions))}, nil
+	}
+	ds.SaveAppConfigFunc = func(ctx context.Context, info *fleet.AppConfig) error {
+		return nil
+	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{{ID: 1, Name: "pack"}}, nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
+	interval := uint(60)
+	scheduledQueriesCalls := 0
+	ds.ListScheduledQueriesInPackFunc = func(ctx context.Context, packID uint) ([]*fleet.ScheduledQuery, error) {
+		scheduledQueriesCalls++
+		return []*fleet.ScheduledQuery{{Name: "time", Query: "select * from time", Interval: interval}}, nil
+	}
+	ds.ScheduledQueryFunc = func(ctx context.Context, id uint) (*fleet.ScheduledQuery, error) {
+		return &fleet.ScheduledQuery{ID: id, PackID: 1}, nil
+	}
+	ds.SaveScheduledQueryFunc = func(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+		return sq, nil
+	}
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+	ds.UpdateHostOsqueryIntervalsFunc = func(ctx context.Context, hostID uint, intervals fleet.HostOsqueryIntervals) error {
+		return nil
+	}
+
+	hostCtx := hostctx.NewContext(context.Background(), &fleet.Host{ID: 1, Platform: "darwin"})
+	getConfig := func() (interface{}, string) {
+		conf, err := svc.GetClientConfig(hostCtx)
+		require.NoError(t, err)
+		packs, err := json.Marshal(conf["packs"])
+		require.NoError(t, err)
+		return conf["options"], string(packs)
+	}
+
+	options, packs := getConfig()
+	assert.Equal(t, map[string]interface{}{"distributed_interval": float64(10)}, options)
+	assert.JSONEq(t, `{"pack":{"queries":{"time":{"query":"select * from time","interval":60}}}}`, packs)
+	assert.Equal(t, 1, scheduledQueriesCalls)
+
+	// the rendered config is served from the cache
+	agentOptions = `{"config":{"options":{"distributed_interval":20}}}`
+	interval = 120
+	options, packs = getConfig()
+	assert.Equal(t, map[string]interface{}{"distributed_interval": float64(10)}, options)
+	assert.JSONEq(t, `{"pack":{"queries":{"time":{"query":"select * from time","interval":60}}}}`, packs)
+	assert.Equal(t, 1, scheduledQueriesCalls)
+
+	// modifying a scheduled query invalidates the cache
+	adminCtx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+	_, err := svc.ModifyScheduledQuery(adminCtx, 1, fleet.ScheduledQueryPayload{Interval: ptr.Uint(120)})
+	require.NoError(t, err)
+	options, packs = getConfig()
+	assert.Equal(t, map[string]interface{}{"distributed_interval": float64(20)}, options)
+	assert.JSONEq(t, `{"pack":{"queries":{"time":{"query":"select * from time","interval":120}}}}`, packs)
+	assert.Equal(t, 2, scheduledQueriesCalls)
+
+	// modifying the agent options invalidates the cache
+	_, err = svc.ModifyAppConfig(adminCtx, []byte(`{"agent_options":{"config":{"options":{"distributed_interval":30}}}}`))
+	require.NoError(t, err)
+	agentOptions = `{"config":{"options":{"distributed_interval":30}}}`
+	options, _ = getConfig()
+	assert.Equal(t, map[string]interface{}{"distributed_interval": float64(30)}, options)
+	assert.Equal(t, 3, scheduledQueriesCalls)
+
+	// entries expire after the TTL
+	agentOptions = `{"config":{"options":{"distributed_interval":40}}}`
+	mockClock.AddTime(time.Minute)
+	options, _ = getConfig()
+	assert.Equal(t, map[string]interface{}{"distributed_interval": float64(40)}, options)
+	assert.Equal(t, 4, scheduledQueriesCalls)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create Dashboard
+////////////////////////////////////////////////////////////////////////////////
+
+type createDashboardRequest struct {
+	fleet.DashboardPayload
+}
+
+type dashboardResponse struct {
+	Dashboard *fleet.Dashboard `json:"dashboard,omitempty"`
+	Err       error            `json:"error,omitempty"`
+}
+
+func (r dashboardResponse) error() error { return r.Err }
+
+func createDashboardEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*createDashboardRequest)
+	dashboard, err := svc.NewDashboard(ctx, req.DashboardPayload)
+	if err != nil {
+		return dashboardResponse{Err: err}, nil
+	}
+	return dashboardResponse{Dashboard: dashboard}, nil
+}
+
+func (svc *Service) NewDashboard(ctx context.Context, p fleet.DashboardPayload) (*fleet.Dashboard, error) {
+	dashboard := &fleet.Dashboard{Widgets: fleet.DashboardWidgets{}}
+	if user := authz.UserFromContext(ctx); user != nil {
+		dashboard.UserID = user.ID
+	}
+	dashboard.TeamID = p.TeamID
+	if err := svc.authz.Authorize(ctx, dashboard, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if p.Name == nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("name", "missing required argument"))
+	}
+	if err := p.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &badRequestError{
+			message: fmt.Sprintf("dashboard payload verification: %s", err),
+		})
+	}
+	if p.TeamID != nil {
+		if _, err := svc.ds.Team(ctx, *p.TeamID); err != nil {
+			return nil, err
+		}
+	}
+
+	applyDashboardPayload(dashboard, p)
+	return svc.ds.NewDashboard(ctx, dashboard)
+}
+
+func applyDashboardPayload(dashboard *fleet.Dashboard, p fleet.DashboardPayload) {
+	if p.Name != nil {
+		dashboard.Name = *p.Name
+	}
+	if p.Description != nil {
+		dashboard.Description = *p.Description
+	}
+	if p.TeamID != nil {
+		dashboard.TeamID = p.TeamID
+	}
+	if p.Shared != nil {
+		dashboard.Shared = *p.Shared
+	}
+	if p.Widgets != nil {
+		dashboard.Widgets = *p.Widgets
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Dashboard
+////////////////////////////////////////////////////////////////////////////////
+
+type getDashboardRequest struct {
+	ID uint `url:"id"`
+}
+
+func getDashboardEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getDashboardRequest)
+	dashboard, err := svc.GetDashboard(ctx, req.ID)
+	if err != nil {
+		return dashboardResponse{Err: err}, nil
+	}
+	return dashboardResponse{Dashboard: dashboard}, nil
+}
+
+func (svc *Service) GetDashboard(ctx context.Context, id uint) (*fleet.Dashboard, error) {
+	// First make sure the user can list dashboards, the ownership and sharing
+	// of the dashboard can only be verified once loaded.
+	if err := svc.authz.Authorize(ctx, &fleet.Dashboard{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	dashboard, err := svc.ds.Dashboard(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authz.Authorize(ctx, dashboard, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return dashboard, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Dashboards
+////////////////////////////////////////////////////////////////////////////////
+
+type listDashboardsRequest struct {
+	TeamID      *uint             `query:"team_id,optional"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listDashboardsResponse struct {
+	Dashboards []*fleet.Dashboard `json:"dashboards"`
+	Err        error              `json:"error,omitempty"`
+}
+
+func (r listDashboardsResponse) error() error { return r.Err }
+
+func listDashboardsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listDashboardsRequest)
+	dashboards, err := svc.ListDashboards(ctx, req.TeamID, req.ListOptions)
+	if err != nil {
+		return listDashboardsResponse{Err: err}, nil
+	}
+	if dashboards == nil {
+		dashboards = []*fleet.Dashboard{}
+	}
+	return listDashboardsResponse{Dashboards: dashboards}, nil
+}
+
+func (svc *Service) ListDashboards(ctx context.Context, teamID *uint, opt fleet.ListOptions) ([]*fleet.Dashboard, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Dashboard{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	user := authz.UserFromContext(ctx)
+	if user == nil {
+		return nil, ctxerr.New(ctx, "user must be authenticated to list dashboards")
+	}
+	filter := fleet.TeamFilter{User: user, IncludeObserver: true, TeamID: teamID}
+	return svc.ds.ListDashboards(ctx, filter, opt)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Modify Dashboard
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyDashboardRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.DashboardPayload
+}
+
+func modifyDashboardEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*modifyDashboardRequest)
+	dashboard, err := svc.ModifyDashboard(ctx, req.ID, req.DashboardPayload)
+	if err != nil {
+		return dashboardResponse{Err: err}, nil
+	}
+	return dashboardResponse{Dashboard: dashboard}, nil
+}
+
+func (svc *Service) ModifyDashboard(ctx context.Context, id uint, p fleet.DashboardPayload) (*fleet.Dashboard, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Dashboard{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	dashboard, err := svc.ds.Dashboard(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authz.Authorize(ctx, dashboard, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := p.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &badRequestError{
+			message: fmt.Sprintf("dashboard payload verification: %s", err),
+		})
+	}
+	if p.TeamID != nil && (dashboard.TeamID == nil || *dashboard.TeamID != *p.TeamID) {
+		if _, err := svc.ds.Team(ctx, *p.TeamID); err != nil {
+			return nil, err
+		}
+	}
+
+	applyDashboardPayload(dashboard, p)
+
+	// The user must also be allowed to write the modified dashboard, e.g. when
+	// moving it to another team or changing its sharing.
+	if err := svc.authz.Authorize(ctx, dashboard, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := svc.ds.SaveDashboard(ctx, dashboard); err != nil {
+		return nil, err
+	}
+	return svc.ds.Dashboard(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete Dashboard
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteDashboardRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteDashboardResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteDashboardResponse) error() error { return r.Err }
+
+func deleteDashboardEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*deleteDashboardRequest)
+	if err := svc.DeleteDashboard(ctx, req.ID); err != nil {
+		return deleteDashboardResponse{Err: err}, nil
+	}
+	return deleteDashboardResponse{}, nil
+}
+
+func (svc *Service) DeleteDashboard(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Dashboard{}, fleet.ActionList); err != nil {
+		return err
+	}
+
+	dashboard, err := svc.ds.Dashboard(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := svc.authz.Authorize(ctx, dashboard, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	return svc.ds.DeleteDashboard(ctx, id)
+}
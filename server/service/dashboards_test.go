@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	owner := &fleet.User{ID: 1, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}
+	teamObserver := &fleet.User{ID: 2, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}
+	otherTeamAdmin := &fleet.User{ID: 3, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}}
+	globalAdmin := &fleet.User{ID: 4, GlobalRole: ptr.String(fleet.RoleAdmin)}
+
+	dashboards := map[uint]*fleet.Dashboard{
+		1: {ID: 1, Name: "private", UserID: owner.ID, TeamID: ptr.Uint(1)},
+		2: {ID: 2, Name: "shared", UserID: owner.ID, TeamID: ptr.Uint(1), Shared: true},
+	}
+	ds.DashboardFunc = func(ctx context.Context, id uint) (*fleet.Dashboard, error) {
+		d := *dashboards[id]
+		return &d, nil
+	}
+	ds.NewDashboardFunc = func(ctx context.Context, dashboard *fleet.Dashboard) (*fleet.Dashboard, error) {
+		return dashboard, nil
+	}
+	ds.SaveDashboardFunc = func(ctx context.Context, dashboard *fleet.Dashboard) error {
+		return nil
+	}
+	ds.DeleteDashboardFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	ds.ListDashboardsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.Dashboard, error) {
+		return nil, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+
+	testCases := []struct {
+		name                 string
+		user                 *fleet.User
+		shouldFailPrivate    bool
+		shouldFailShared     bool
+		shouldFailEditShared bool
+		shouldFailNewTeam1   bool
+	}{
+		{"owner", owner, false, false, false, false},
+		{"team observer", teamObserver, true, false, true, false},
+		{"other team admin", otherTeamAdmin, true, true, true, true},
+		{"global admin", globalAdmin, true, false, false, false},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			_, err := svc.ListDashboards(ctx, nil, fleet.ListOptions{})
+			checkAuthErr(t, false, err)
+
+			_, err = svc.GetDashboard(ctx, 1)
+			checkAuthErr(t, tt.shouldFailPrivate, err)
+
+			_, err = svc.GetDashboard(ctx, 2)
+			checkAuthErr(t, tt.shouldFailShared, err)
+
+			_, err = svc.ModifyDashboard(ctx, 2, fleet.DashboardPayload{Name: ptr.String("renamed")})
+			checkAuthErr(t, tt.shouldFailEditShared, err)
+
+			err = svc.DeleteDashboard(ctx, 1)
+			checkAuthErr(t, tt.shouldFailPrivate, err)
+
+			_, err = svc.NewDashboard(ctx, fleet.DashboardPayload{Name: ptr.String("new"), TeamID: ptr.Uint(1)})
+			checkAuthErr(t, tt.shouldFailNewTeam1, err)
+		})
+	}
+}
+
+func TestNewDashboard(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.NewDashboardFunc = func(ctx context.Context, dashboard *fleet.Dashboard) (*fleet.Dashboard, error) {
+		dashboard.ID = 1
+		return dashboard, nil
+	}
+
+	user := &fleet.User{ID: 42, GlobalRole: ptr.String(fleet.RoleObserver)}
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: user})
+
+	dashboard, err := svc.NewDashboard(ctx, fleet.DashboardPayload{
+		Name:    ptr.String("Fleet health"),
+		Shared:  ptr.Bool(true),
+		Widgets: &fleet.DashboardWidgets{{Type: fleet.DashboardWidgetOSVersions}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), dashboard.UserID)
+	assert.Equal(t, "Fleet health", dashboard.Name)
+	assert.True(t, dashboard.Shared)
+	assert.Len(t, dashboard.Widgets, 1)
+
+	// name is required
+	_, err = svc.NewDashboard(ctx, fleet.DashboardPayload{})
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+
+	// invalid widgets
+	_, err = svc.NewDashboard(ctx, fleet.DashboardPayload{
+		Name:    ptr.String("Fleet health"),
+		Widgets: &fleet.DashboardWidgets{{Type: fleet.DashboardWidgetPolicy}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "reference_id is required")
+
+	_, err = svc.NewDashboard(ctx, fleet.DashboardPayload{
+		Name:    ptr.String("Fleet health"),
+		Widgets: &fleet.DashboardWidgets{{Type: "pie"}},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid dashboard widget type")
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"text/template"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// deploymentPaths holds the platform-specific paths of the osquery files.
+type deploymentPaths struct {
+	Osqueryd     string
+	Flagfile     string
+	EnrollSecret string
+	ServerCerts  string
+	Database     string
+	Pidfile      string
+}
+
+var deploymentPathsByPlatform = map[string]deploymentPaths{
+	"linux": {
+		Osqueryd:     "/opt/osquery/bin/osqueryd",
+		Flagfile:     "/etc/osquery/osquery.flags",
+		EnrollSecret: "/etc/osquery/enroll_secret",
+		ServerCerts:  "/etc/osquery/fleet.pem",
+		Database:     "/var/osquery/osquery.db",
+		Pidfile:      "/var/osquery/osqueryd.pidfile",
+	},
+	"darwin": {
+		Osqueryd:     "/opt/osquery/lib/osquery.app/Contents/MacOS/osqueryd",
+		Flagfile:     "/var/osquery/osquery.flags",
+		EnrollSecret: "/var/osquery/enroll_secret",
+		ServerCerts:  "/var/osquery/fleet.pem",
+		Database:     "/var/osquery/osquery.db",
+		Pidfile:      "/var/osquery/osqueryd.pidfile",
+	},
+	"windows": {
+		Osqueryd:     `C:\Program Files\osquery\osqueryd\osqueryd.exe`,
+		Flagfile:     `C:\Program Files\osquery\osquery.flags`,
+		EnrollSecret: `C:\Program Files\osquery\enroll_secret`,
+		ServerCerts:  `C:\Program Files\osquery\fleet.pem`,
+		Database:     `C:\Program Files\osquery\osquery.db`,
+		Pidfile:      `C:\Program Files\osquery\osqueryd.pidfile`,
+	},
+}
+
+// deploymentTemplateData is the data available to the deployment templates.
+type deploymentTemplateData struct {
+	Paths               deploymentPaths
+	TLSHostname         string
+	URLPrefix           string
+	ConfigRefresh       uint
+	DistributedInterval uint
+	LoggerTLSPeriod     uint
+}
+
+// Endpoint returns the path of the osquery endpoint, taking into account the
+// server URL prefix.
+func (d deploymentTemplateData) Endpoint(p string) string {
+	return path.Join("/", d.URLPrefix, p)
+}
+
+var deploymentTemplates = map[string]struct {
+	filename    string
+	contentType string
+	platforms   []string
+	tmpl        *template.Template
+}{
+	fleet.DeploymentFileFlagfile: {
+		filename:    "osquery.flags",
+		contentType: "text/plain",
+		platforms:   []string{"linux", "darwin", "windows"},
+		tmpl: template.Must(template.New("flagfile").Parse(`# Server
+--tls_hostname={{ .TLSHostname }}
+--tls_server_certs={{ .Paths.ServerCerts }}
+# Enrollment
+--host_identifier=instance
+--enroll_secret_path={{ .Paths.EnrollSecret }}
+--enroll_tls_endpoint={{ .Endpoint "/api/v1/osquery/enroll" }}
+# Configuration
+--config_plugin=tls
+--config_tls_endpoint={{ .Endpoint "/api/v1/osquery/config" }}
+--config_refresh={{ .ConfigRefresh }}
+# Live query
+--disable_distributed=false
+--distributed_plugin=tls
+--distributed_interval={{ .DistributedInterval }}
+--distributed_tls_max_attempts=3
+--distributed_tls_read_endpoint={{ .Endpoint "/api/v1/osquery/distributed/read" }}
+--distributed_tls_write_endpoint={{ .Endpoint "/api/v1/osquery/distributed/write" }}
+# Logging
+--logger_plugin=tls
+--logger_tls_endpoint={{ .Endpoint "/api/v1/osquery/log" }}
+--logger_tls_period={{ .LoggerTLSPeriod }}
+# File carving
+--disable_carver=false
+--carver_start_endpoint={{ .Endpoint "/api/v1/osquery/carve/begin" }}
+--carver_continue_endpoint={{ .Endpoint "/api/v1/osquery/carve/block" }}
+--carver_block_size=2000000
+`)),
+	},
+	fleet.DeploymentFileLaunchd: {
+		filename:    "com.facebook.osqueryd.plist",
+		contentType: "application/xml",
+		platforms:   []string{"darwin"},
+		tmpl: template.Must(template.New("launchd").Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+  <key>Label</key>
+  <string>com.facebook.osqueryd</string>
+  <key>ProgramArguments</key>
+  <array>
+    <string>{{ .Paths.Osqueryd }}</string>
+    <string>--flagfile={{ .Paths.Flagfile }}</string>
+    <string>--database_path={{ .Paths.Database }}</string>
+    <string>--pidfile={{ .Paths.Pidfile }}</string>
+  </array>
+  <key>RunAtLoad</key>
+  <true/>
+  <key>KeepAlive</key>
+  <true/>
+  <key>ThrottleInterval</key>
+  <integer>60</integer>
+</dict>
+</plist>
+`)),
+	},
+	fleet.DeploymentFileSystemd: {
+		filename:    "osqueryd.service",
+		contentType: "text/plain",
+		platforms:   []string{"linux"},
+		tmpl: template.Must(template.New("systemd").Parse(`[Unit]
+Description=osquery daemon connected to Fleet
+After=network.service syslog.service
+
+[Service]
+ExecStart={{ .Paths.Osqueryd }} --flagfile={{ .Paths.Flagfile }} --database_path={{ .Paths.Database }} --pidfile={{ .Paths.Pidfile }}
+Restart=on-failure
+KillMode=control-group
+KillSignal=SIGTERM
+TimeoutStopSec=15
+
+[Install]
+WantedBy=multi-user.target
+`)),
+	},
+	fleet.DeploymentFileWindowsService: {
+		filename:    "install-osqueryd-service.ps1",
+		contentType: "text/plain",
+		platforms:   []string{"windows"},
+		tmpl: template.Must(template.New("windows_service").Parse(`# Installs the osqueryd service connected to Fleet. Run as Administrator.
+$binaryPath = '"{{ .Paths.Osqueryd }}" --flagfile="{{ .Paths.Flagfile }}" --database_path="{{ .Paths.Database }}" --pidfile="{{ .Paths.Pidfile }}"'
+New-Service -Name "osqueryd" -DisplayName "osquery daemon service" -BinaryPathName $binaryPath -StartupType Automatic
+Start-Service -Name "osqueryd"
+`)),
+	},
+}
+
+// deploymentIntervals extracts the intervals used in the flagfile from the
+// agent options, so that the flagfile is consistent with the options served
+// by Fleet.
+func deploymentIntervals(agentOptions json.RawMessage) (configRefresh, distributedInterval, loggerTLSPeriod uint, err error) {
+	configRefresh, distributedInterval, loggerTLSPeriod = 10, 10, 10
+	if len(agentOptions) == 0 {
+		return configRefresh, distributedInterval, loggerTLSPeriod, nil
+	}
+
+	var opts struct {
+		Options struct {
+			ConfigRefresh       *uint `json:"config_refresh"`
+			ConfigTLSRefresh    *uint `json:"config_tls_refresh"`
+			DistributedInterval *uint `json:"distributed_interval"`
+			LoggerTLSPeriod     *uint `json:"logger_tls_period"`
+		} `json:"options"`
+	}
+	if err := json.Unmarshal(agentOptions, &opts); err != nil {
+		return 0, 0, 0, err
+	}
+	if opts.Options.ConfigTLSRefresh != nil {
+		configRefresh = *opts.Options.ConfigTLSRefresh
+	}
+	if opts.Options.ConfigRefresh != nil {
+		configRefresh = *opts.Options.ConfigRefresh
+	}
+	if opts.Options.DistributedInterval != nil {
+		distributedInterval = *opts.Options.DistributedInterval
+	}
+	if opts.Options.LoggerTLSPeriod != nil {
+		loggerTLSPeriod = *opts.Options.LoggerTLSPeriod
+	}
+	return configRefresh, distributedInterval, loggerTLSPeriod, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Agent Deployment File
+////////////////////////////////////////////////////////////////////////////////
+
+type getAgentDeploymentFileRequest struct {
+	Kind     string `url:"kind"`
+	TeamID   *uint  `query:"team_id,optional"`
+	Platform string `query:"platform,optional"`
+}
+
+type getAgentDeploymentFileResponse struct {
+	File *fleet.AgentDeploymentFile `json:"-"`
+	Err  error                      `json:"error,omitempty"`
+}
+
+func (r getAgentDeploymentFileResponse) error() error { return r.Err }
+
+func (r getAgentDeploymentFileResponse) hijackRender(ctx context.Context, w http.ResponseWriter) {
+	w.Header().Add("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, r.File.Filename))
+	w.Header().Set("Content-Type", r.File.ContentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(r.File.Content); err != nil {
+		logging.WithErr(ctx, err)
+	}
+}
+
+func getAgentDeploymentFileEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getAgentDeploymentFileRequest)
+	file, err := svc.AgentDeploymentFile(ctx, req.TeamID, req.Platform, req.Kind)
+	if err != nil {
+		return getAgentDeploymentFileResponse{Err: err}, nil
+	}
+	return getAgentDeploymentFileResponse{File: file}, nil
+}
+
+func (svc *Service) AgentDeploymentFile(ctx context.Context, teamID *uint, platform, kind string) (*fleet.AgentDeploymentFile, error) {
+	// the rendered files are meant to be used along with the enroll secret, so
+	// they require the same permissions.
+	if err := svc.authz.Authorize(ctx, &fleet.EnrollSecret{TeamID: teamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	tpl, ok := deploymentTemplates[kind]
+	if !ok {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("kind", fmt.Sprintf("unsupported deployment file kind %q", kind)))
+	}
+	if platform == "" {
+		platform = tpl.platforms[0]
+	}
+	supported := false
+	for _, p := range tpl.platforms {
+		if p == platform {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("platform", fmt.Sprintf("unsupported platform %q for %s", platform, kind)))
+	}
+
+	if teamID != nil {
+		if _, err := svc.ds.Team(ctx, *teamID); err != nil {
+			return nil, err
+		}
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	serverURL, err := url.Parse(appConfig.ServerSettings.ServerURL)
+	if err != nil || serverURL.Host == "" {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("server_url", "the Fleet server URL must be configured"))
+	}
+
+	agentOptions, err := svc.AgentOptionsForHost(ctx, teamID, platform)
+	if err != nil {
+		return nil, err
+	}
+	data := deploymentTemplateData{
+		Paths:       deploymentPathsByPlatform[platform],
+		TLSHostname: serverURL.Host,
+		URLPrefix:   serverURL.Path,
+	}
+	data.ConfigRefresh, data.DistributedInterval, data.LoggerTLSPeriod, err = deploymentIntervals(agentOptions)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "parse agent options")
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.tmpl.Execute(&buf, data); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "render deployment file")
+	}
+	return &fleet.AgentDeploymentFile{
+		Filename:    tpl.filename,
+		ContentType: tpl.contentType,
+		Content:     buf.Bytes(),
+	}, nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentDeploymentFile(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com:8080/prefix"},
+			AgentOptions:   ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":5,"config_refresh":60}}}`)),
+		}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, tid uint) (*json.RawMessage, error) {
+		return ptr.RawMessage(json.RawMessage(`{"config":{"options":{"logger_tls_period":30}}}`)), nil
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	file, err := svc.AgentDeploymentFile(ctx, nil, "", fleet.DeploymentFileFlagfile)
+	require.NoError(t, err)
+	assert.Equal(t, "osquery.flags", file.Filename)
+	content := string(file.Content)
+	assert.Contains(t, content, "--tls_hostname=fleet.example.com:8080\n")
+	assert.Contains(t, content, "--tls_server_certs=/etc/osquery/fleet.pem\n")
+	assert.Contains(t, content, "--enroll_tls_endpoint=/prefix/api/v1/osquery/enroll\n")
+	assert.Contains(t, content, "--distributed_interval=5\n")
+	assert.Contains(t, content, "--config_refresh=60\n")
+	assert.Contains(t, content, "--logger_tls_period=10\n")
+
+	file, err = svc.AgentDeploymentFile(ctx, ptr.Uint(1), "windows", fleet.DeploymentFileFlagfile)
+	require.NoError(t, err)
+	content = string(file.Content)
+	assert.Contains(t, content, `--enroll_secret_path=C:\Program Files\osquery\enroll_secret`)
+	assert.Contains(t, content, "--logger_tls_period=30\n")
+	assert.Contains(t, content, "--distributed_interval=5\n")
+
+	file, err = svc.AgentDeploymentFile(ctx, nil, "", fleet.DeploymentFileLaunchd)
+	require.NoError(t, err)
+	assert.Equal(t, "com.facebook.osqueryd.plist", file.Filename)
+	assert.Contains(t, string(file.Content), "<string>--flagfile=/var/osquery/osquery.flags</string>")
+
+	_, err = svc.AgentDeploymentFile(ctx, nil, "windows", fleet.DeploymentFileSystemd)
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+
+	_, err = svc.AgentDeploymentFile(ctx, nil, "", "rpm")
+	require.ErrorAs(t, err, &iae)
+}
+
+func TestAgentDeploymentFileAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com"}}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		teamID     *uint
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, nil, false},
+		{"global maintainer", &fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)}, nil, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, nil, true},
+		{"team maintainer, own team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}, ptr.Uint(1), false},
+		{"team maintainer, other team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}, ptr.Uint(2), true},
+		{"team maintainer, no team", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}, nil, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+			_, err := svc.AgentDeploymentFile(ctx, tt.teamID, "", fleet.DeploymentFileFlagfile)
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+}
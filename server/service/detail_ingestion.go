@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service/osquery_utils"
+	"github.com/go-kit/kit/log/level"
+)
+
+// detailIngestionJob holds the detail query results of a host, queued for
+// asynchronous ingestion.
+type detailIngestionJob struct {
+	host *fleet.Host
+	// results and failed are keyed by detail query name.
+	results fleet.OsqueryDistributedQueryResults
+	failed  map[string]bool
+	// refetched is true if the host was refetched, in which case it must be
+	// saved even if the results did not update it.
+	refetched bool
+}
+
+// detailIngester ingests the detail query results of the hosts outside of the
+// distributed results requests. The results are queued in memory and
+// processed by a pool of workers, each saving the updated hosts in batches.
+// The results of a host are always processed by the same worker, so that they
+// are ingested and saved in the order they were received.
+//
+// Because this is done asynchronously, the queued results are lost if the
+// server shuts down before they are processed. This is an acceptable tradeoff
+// as the detail queries are sent again to the hosts whose details are not
+// updated.
+type detailIngester struct {
+	svc *Service
+	// queues are the queues of the workers, the jobs of a host are queued
+	// for the worker host.ID % len(queues). The queue size is split between
+	// them.
+	queues      []chan detailIngestionJob
+	updateBatch int
+	interval    time.Duration
+}
+
+func newDetailIngester(svc *Service, workers, queueSize, updateBatch int, interval time.Duration) *detailIngester {
+	if workers < 1 {
+		workers = 1
+	}
+	workerQueueSize := queueSize / workers
+	if workerQueueSize < 1 {
+		workerQueueSize = 1
+	}
+	queues := make([]chan detailIngestionJob, workers)
+	for i := range queues {
+		queues[i] = make(chan detailIngestionJob, workerQueueSize)
+	}
+	return &detailIngester{
+		svc:         svc,
+		queues:      queues,
+		updateBatch: updateBatch,
+		interval:    interval,
+	}
+}
+
+// start starts the workers, they stop when ctx is done.
+func (d *detailIngester) start(ctx context.Context) {
+	for _, queue := range d.queues {
+		go d.run(ctx, queue)
+	}
+}
+
+// enqueue queues the job for ingestion by the worker of its host. It returns
+// false if the queue of the worker is full, in which case the caller should
+// ingest the results itself.
+func (d *detailIngester) enqueue(job detailIngestionJob) bool {
+	select {
+	case d.queues[job.host.ID%uint(len(d.queues))] <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+func (d *detailIngester) run(ctx context.Context, queue <-chan detailIngestionJob) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	// the pending hosts are indexed by id so that a host ingested multiple
+	// times before the flush is only saved once, with its latest details.
+	pending := make(map[uint]*fleet.Host)
+	for {
+		select {
+		case job := <-queue:
+			if d.ingest(ctx, job) {
+				pending[job.host.ID] = job.host
+			}
+			if len(pending) >= d.updateBatch {
+				d.flush(ctx, pending)
+			}
+		case <-ticker.C:
+			d.flush(ctx, pending)
+		case <-ctx.Done():
+			// save what was already ingested before stopping
+			flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			d.flush(flushCtx, pending)
+			cancel()
+			return
+		}
+	}
+}
+
+// ingest ingests the results of the job in its host and returns true if the
+// host must be saved.
+func (d *detailIngester) ingest(ctx context.Context, job detailIngestionJob) bool {
+	detailUpdated := false
+	for name, rows := range job.results {
+		updated, err := d.svc.ingestDetailResult(ctx, job.host, name, rows, job.failed[name])
+		if err != nil {
+			level.Info(d.svc.logger).Log("err", "ingest detail query", "query", name, "host_id", job.host.ID, "details", err)
+		}
+		detailUpdated = detailUpdated || updated
+	}
+	if !detailUpdated && !job.refetched {
+		return false
+	}
+
+	if detailUpdated {
+		job.host.DetailUpdatedAt = d.svc.clock.Now()
+	}
+	appConfig, err := d.svc.ds.AppConfig(ctx)
+	if err != nil {
+		level.Error(d.svc.logger).Log("err", "get app config", "details", err)
+	} else {
+		job.host.DisplayName = job.host.ComputeDisplayName(appConfig.HostSettings.DisplayNameSources)
+	}
+	return true
+}
+
+func (d *detailIngester) flush(ctx context.Context, pending map[uint]*fleet.Host) {
+	if len(pending) == 0 {
+		return
+	}
+
+	hosts := make([]*fleet.Host, 0, len(pending))
+	for id, host := range pending {
+		hosts = append(hosts, host)
+		delete(pending, id)
+	}
+	if err := d.svc.ds.UpdateHostsDetails(ctx, hosts); err != nil {
+		level.Error(d.svc.logger).Log("err", "update ingested hosts", "count", len(hosts), "details", err)
+		return
+	}
+	for _, host := range hosts {
+		osquery_utils.RunHostSavedHooks(ctx, d.svc.logger, host, d.svc.ds)
+	}
+}
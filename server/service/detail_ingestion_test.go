@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetailIngesterEnqueue(t *testing.T) {
+	d := newDetailIngester(nil, 3, 6, 10, time.Second)
+	require.Len(t, d.queues, 3)
+
+	// the jobs of a host are queued for the same worker
+	for _, id := range []uint{1, 4} {
+		require.True(t, d.enqueue(detailIngestionJob{host: &fleet.Host{ID: id}}))
+	}
+	assert.Len(t, d.queues[0], 0)
+	assert.Len(t, d.queues[1], 2)
+	assert.Len(t, d.queues[2], 0)
+
+	// the queue of the worker is full, the other workers still accept jobs
+	assert.False(t, d.enqueue(detailIngestionJob{host: &fleet.Host{ID: 7}}))
+	assert.True(t, d.enqueue(detailIngestionJob{host: &fleet.Host{ID: 2}}))
+	job := <-d.queues[1]
+	assert.Equal(t, uint(1), job.host.ID)
+	job = <-d.queues[1]
+	assert.Equal(t, uint(4), job.host.ID)
+}
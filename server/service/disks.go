@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List Host Disks
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostDisksRequest struct {
+	ID uint `url:"id"`
+}
+
+type listHostDisksResponse struct {
+	Err   error            `json:"error,omitempty"`
+	Disks []fleet.HostDisk `json:"disks"`
+}
+
+func (r listHostDisksResponse) error() error { return r.Err }
+
+func listHostDisksEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostDisksRequest)
+	disks, err := svc.ListHostDisks(ctx, req.ID)
+	if err != nil {
+		return listHostDisksResponse{Err: err}, nil
+	}
+	return listHostDisksResponse{Disks: disks}, nil
+}
+
+func (svc *Service) ListHostDisks(ctx context.Context, id uint) ([]fleet.HostDisk, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "find host for disks")
+	}
+
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.ListHostDisks(ctx, id)
+}
@@ -81,6 +81,53 @@ func getDeviceAuthToken(r interface{}) (string, error) {
 	return "", fleet.NewAuthRequiredError("request type does not implement deviceAuthToken method. This is likely a Fleet programmer error.")
 }
 
+// authenticatedOrbitHost wraps an endpoint, checks the validity of the orbit
+// node key provided in the request, and attaches the corresponding host to the
+// context for the request.
+func authenticatedOrbitHost(svc fleet.Service, logger log.Logger, next endpoint.Endpoint) endpoint.Endpoint {
+	authOrbitHostFunc := func(ctx context.Context, request interface{}) (interface{}, error) {
+		nodeKey, err := getOrbitNodeKey(request)
+		if err != nil {
+			return nil, err
+		}
+
+		host, debug, err := svc.AuthenticateOrbitHost(ctx, nodeKey)
+		if err != nil {
+			logging.WithErr(ctx, err)
+			return nil, err
+		}
+
+		hlogger := log.With(logger, "host-id", host.ID)
+		if debug {
+			logJSON(hlogger, request, "request")
+		}
+
+		ctx = hostctx.NewContext(ctx, host)
+		instrumentHostLogger(ctx)
+		if ac, ok := authz_ctx.FromContext(ctx); ok {
+			ac.SetAuthnMethod(authz_ctx.AuthnOrbitNodeKey)
+		}
+
+		resp, err := next(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+
+		if debug {
+			logJSON(hlogger, resp, "response")
+		}
+		return resp, nil
+	}
+	return logged(authOrbitHostFunc)
+}
+
+func getOrbitNodeKey(r interface{}) (string, error) {
+	if onk, ok := r.(interface{ orbitNodeKey() string }); ok {
+		return onk.orbitNodeKey(), nil
+	}
+	return "", fleet.NewAuthRequiredError("request type does not implement orbitNodeKey method. This is likely a Fleet programmer error.")
+}
+
 // authenticatedHost wraps an endpoint, checks the validity of the node_key
 // provided in the request, and attaches the corresponding osquery host to the
 // context for the request
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// defaultEnrollmentStatsDays is the number of days of enrollment stats
+// returned when none is specified.
+const defaultEnrollmentStatsDays = 7
+
+var enrollmentAttempts = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "fleet",
+		Subsystem: "osquery",
+		Name:      "enrollment_attempts_total",
+		Help:      "Total number of osquery enrollment attempts by outcome.",
+	},
+	[]string{"outcome"},
+)
+
+func init() {
+	prometheus.MustRegister(enrollmentAttempts)
+}
+
+// enrollmentOutcomeForErr returns the enrollment outcome corresponding to the
+// error returned while enrolling a host.
+func enrollmentOutcomeForErr(err error) fleet.EnrollmentOutcome {
+	switch {
+	case err == nil:
+		return fleet.EnrollmentOutcomeSuccess
+	case errors.Is(err, fleet.ErrEnrollSecretNotFound),
+		errors.Is(err, fleet.ErrEnrollSecretExpired),
+		errors.Is(err, fleet.ErrEnrollSecretExhausted):
+		return fleet.EnrollmentOutcomeInvalidSecret
+	case errors.Is(err, fleet.ErrHostEnrollCooldown):
+		return fleet.EnrollmentOutcomeCooldown
+	default:
+		return fleet.EnrollmentOutcomeDatastoreError
+	}
+}
+
+// enrollmentOutcomeCounts implements synchronized storage for the
+// enrollment attempts not yet recorded in the datastore, by day and outcome.
+type enrollmentOutcomeCounts struct {
+	mutex  sync.Mutex
+	counts map[fleet.EnrollmentOutcomeCount]uint
+}
+
+func newEnrollmentOutcomeCounts() *enrollmentOutcomeCounts {
+	return &enrollmentOutcomeCounts{
+		counts: make(map[fleet.EnrollmentOutcomeCount]uint),
+	}
+}
+
+// add counts an enrollment attempt with the provided outcome on the day (UTC)
+// of now.
+func (c *enrollmentOutcomeCounts) add(now time.Time, outcome fleet.EnrollmentOutcome) {
+	now = now.UTC()
+	key := fleet.EnrollmentOutcomeCount{
+		Date:    time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		Outcome: outcome,
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.counts[key]++
+}
+
+// getAndClear returns the counted enrollment attempts and resets the counts.
+func (c *enrollmentOutcomeCounts) getAndClear() []fleet.EnrollmentOutcomeCount {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var counts []fleet.EnrollmentOutcomeCount
+	for key, n := range c.counts {
+		key.Count = n
+		counts = append(counts, key)
+	}
+	c.counts = make(map[fleet.EnrollmentOutcomeCount]uint)
+	return counts
+}
+
+// recordEnrollmentOutcome records the outcome of an enrollment attempt in the
+// prometheus metrics. It is recorded in the datastore by the next
+// FlushEnrollmentStats, so that enrollments do not write to the datastore.
+func (svc *Service) recordEnrollmentOutcome(ctx context.Context, err error) {
+	outcome := enrollmentOutcomeForErr(err)
+	enrollmentAttempts.WithLabelValues(string(outcome)).Inc()
+	svc.enrollmentCounts.add(svc.clock.Now(), outcome)
+}
+
+func (svc *Service) FlushEnrollmentStats(ctx context.Context) error {
+	// No authorization check because this is used only internally.
+	counts := svc.enrollmentCounts.getAndClear()
+	return svc.ds.RecordEnrollmentOutcomes(ctx, counts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Enrollment Stats
+////////////////////////////////////////////////////////////////////////////////
+
+type getEnrollmentStatsRequest struct {
+	Days int `query:"days,optional"`
+}
+
+type getEnrollmentStatsResponse struct {
+	Stats *fleet.EnrollmentStats `json:"enrollment_stats,omitempty"`
+	Err   error                  `json:"error,omitempty"`
+}
+
+func (r getEnrollmentStatsResponse) error() error { return r.Err }
+
+func getEnrollmentStatsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getEnrollmentStatsRequest)
+	stats, err := svc.EnrollmentStats(ctx, req.Days)
+	if err != nil {
+		return getEnrollmentStatsResponse{Err: err}, nil
+	}
+	return getEnrollmentStatsResponse{Stats: stats}, nil
+}
+
+func (svc *Service) EnrollmentStats(ctx context.Context, days int) (*fleet.EnrollmentStats, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if days < 0 {
+		return nil, fleet.NewInvalidArgumentError("days", "must be a positive number")
+	}
+	if days == 0 {
+		days = defaultEnrollmentStatsDays
+	}
+	// include the current day
+	now := svc.clock.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(days - 1))
+	return svc.ds.EnrollmentStats(ctx, since)
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrollmentOutcomeForErr(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		err  error
+		want fleet.EnrollmentOutcome
+	}{
+		{nil, fleet.EnrollmentOutcomeSuccess},
+		{ctxerr.Wrap(ctx, fleet.ErrEnrollSecretNotFound), fleet.EnrollmentOutcomeInvalidSecret},
+		{ctxerr.Wrap(ctx, fleet.ErrEnrollSecretExpired, "verify"), fleet.EnrollmentOutcomeInvalidSecret},
+		{ctxerr.Wrap(ctx, fleet.ErrEnrollSecretExhausted, "consume"), fleet.EnrollmentOutcomeInvalidSecret},
+		{ctxerr.Wrapf(ctx, fleet.ErrHostEnrollCooldown, "host identified by %s", "abc"), fleet.EnrollmentOutcomeCooldown},
+		{errors.New("connection refused"), fleet.EnrollmentOutcomeDatastoreError},
+	}
+	for _, c := range cases {
+		require.Equal(t, c.want, enrollmentOutcomeForErr(c.err), c.err)
+	}
+}
+
+func TestFlushEnrollmentStats(t *testing.T) {
+	ds := new(mock.Store)
+	ds.VerifyEnrollSecretFunc = func(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
+		return nil, ctxerr.Wrap(ctx, fleet.ErrEnrollSecretNotFound)
+	}
+	var recorded []fleet.EnrollmentOutcomeCount
+	ds.RecordEnrollmentOutcomesFunc = func(ctx context.Context, counts []fleet.EnrollmentOutcomeCount) error {
+		recorded = counts
+		return nil
+	}
+	svc := newTestService(t, ds, nil, nil)
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.EnrollAgent(context.Background(), "wrong", "host123", nil)
+		require.Error(t, err)
+	}
+	// enrollments do not write the stats to the datastore
+	require.False(t, ds.RecordEnrollmentOutcomesFuncInvoked)
+
+	require.NoError(t, svc.FlushEnrollmentStats(context.Background()))
+	now := time.Now().UTC()
+	require.Equal(t, []fleet.EnrollmentOutcomeCount{{
+		Date:    time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		Outcome: fleet.EnrollmentOutcomeInvalidSecret,
+		Count:   2,
+	}}, recorded)
+
+	// the counts are reset once flushed
+	require.NoError(t, svc.FlushEnrollmentStats(context.Background()))
+	require.Empty(t, recorded)
+}
+
+func TestEnrollmentStatsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	var gotSince time.Time
+	ds.EnrollmentStatsFunc = func(ctx context.Context, since time.Time) (*fleet.EnrollmentStats, error) {
+		gotSince = since
+		return &fleet.EnrollmentStats{Since: since}, nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		shouldFail bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}, false},
+		{"global observer", &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}, false},
+		{"team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+			_, err := svc.EnrollmentStats(ctx, 0)
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+	_, err := svc.EnrollmentStats(ctx, 3)
+	require.NoError(t, err)
+	now := time.Now().UTC()
+	require.Equal(t, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -2), gotSince)
+
+	_, err = svc.EnrollmentStats(ctx, -1)
+	require.Error(t, err)
+}
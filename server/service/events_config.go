@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+type getEventsConfigRequest struct {
+	TeamID *uint `query:"team_id,optional"`
+}
+
+type getEventsConfigResponse struct {
+	EventsConfig *fleet.EventsConfigStatus `json:"events_config,omitempty"`
+	Err          error                     `json:"error,omitempty"`
+}
+
+func (r getEventsConfigResponse) error() error { return r.Err }
+
+func getEventsConfigEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getEventsConfigRequest)
+	status, err := svc.EventsConfig(ctx, req.TeamID)
+	if err != nil {
+		return getEventsConfigResponse{Err: err}, nil
+	}
+	return getEventsConfigResponse{EventsConfig: status}, nil
+}
+
+func (svc *Service) EventsConfig(ctx context.Context, teamID *uint) (*fleet.EventsConfigStatus, error) {
+	if teamID != nil {
+		if err := svc.authz.Authorize(ctx, &fleet.Team{ID: *teamID}, fleet.ActionRead); err != nil {
+			return nil, err
+		}
+		if _, err := svc.ds.Team(ctx, *teamID); err != nil {
+			return nil, err
+		}
+	} else if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	// the events sections are served as command-line flags, along with the
+	// events flags that may be set directly in command_line_flags.
+	flags, err := svc.commandLineFlagsForHost(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+	events := &fleet.EventsConfig{}
+	if len(flags) > 0 {
+		if err := json.Unmarshal(flags, events); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "unmarshal events flags")
+		}
+	}
+
+	status := &fleet.EventsConfigStatus{
+		Events:         events,
+		EventsDisabled: events.EventsDisabled(),
+		Warnings:       []string{},
+	}
+	if !status.EventsDisabled {
+		return status, nil
+	}
+
+	packs, err := svc.ds.ListPacks(ctx, fleet.PackListOptions{IncludeSystemPacks: true})
+	if err != nil {
+		return nil, err
+	}
+	for _, pack := range packs {
+		if pack.Disabled || !packMayTargetTeam(pack, teamID) {
+			continue
+		}
+		queries, err := svc.ds.ListScheduledQueriesInPack(ctx, pack.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, query := range queries {
+			if tables := fleet.EventsTables(query.Query); len(tables) > 0 {
+				status.Warnings = append(status.Warnings, fmt.Sprintf(
+					"scheduled query %q of pack %q uses %s but events are disabled",
+					query.Name, pack.Name, strings.Join(tables, ", "),
+				))
+			}
+		}
+	}
+	return status, nil
+}
+
+// packMayTargetTeam returns whether the pack may run on hosts of the team (or
+// of no team if teamID is nil). Packs targeting labels or specific hosts are
+// assumed to run on any team.
+func packMayTargetTeam(pack *fleet.Pack, teamID *uint) bool {
+	if pack.Type != nil && *pack.Type != "" {
+		if *pack.Type == "global" {
+			return true
+		}
+		return teamID != nil && *pack.Type == fmt.Sprintf("team-%d", *teamID)
+	}
+	if len(pack.LabelIDs) > 0 || len(pack.HostIDs) > 0 {
+		return true
+	}
+	if teamID == nil {
+		return false
+	}
+	for _, id := range pack.TeamIDs {
+		if id == *teamID {
+			return true
+		}
+	}
+	return false
+}
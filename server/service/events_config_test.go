@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventsConfig(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			AgentOptions: ptr.RawMessage(json.RawMessage(`{"events":{"events_max":1000},"command_line_flags":{"audit_allow_config":true}}`)),
+		}, nil
+	}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, id uint) (*json.RawMessage, error) {
+		return ptr.RawMessage(json.RawMessage(`{"events":{"disable_events":false,"events_expiry":60}}`)), nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.ListPacksFunc = func(ctx context.Context, opt fleet.PackListOptions) ([]*fleet.Pack, error) {
+		require.True(t, opt.IncludeSystemPacks)
+		return []*fleet.Pack{
+			{ID: 1, Name: "Global", Type: ptr.String("global")},
+			{ID: 2, Name: "Team: team1", Type: ptr.String("team-1")},
+			{ID: 3, Name: "team2 pack", TeamIDs: []uint{2}},
+			{ID: 4, Name: "disabled pack", Disabled: true, LabelIDs: []uint{1}},
+		}, nil
+	}
+	ds.ListScheduledQueriesInPackFunc = func(ctx context.Context, packID uint) ([]*fleet.ScheduledQuery, error) {
+		switch packID {
+		case 1:
+			return []*fleet.ScheduledQuery{
+				{Name: "processes", Query: "SELECT * FROM processes"},
+				{Name: "process_events", Query: "SELECT * FROM process_events"},
+			}, nil
+		case 4:
+			t.Fatal("disabled packs are not checked")
+		default:
+			t.Fatalf("pack %d does not target the hosts without team", packID)
+		}
+		return nil, nil
+	}
+
+	// the global events section is served with the command-line flags
+	flags, err := svc.GetClientFlags(hostctx.NewContext(context.Background(), &fleet.Host{ID: 1}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"audit_allow_config":true,"events_max":1000}`, string(flags))
+
+	// the team events section is merged over the global one
+	flags, err = svc.GetClientFlags(hostctx.NewContext(context.Background(), &fleet.Host{ID: 2, TeamID: ptr.Uint(1)}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"audit_allow_config":true,"events_max":1000,"disable_events":false,"events_expiry":60}`, string(flags))
+
+	ctx := test.UserContext(test.UserAdmin)
+
+	// events are disabled by default for the hosts without team
+	status, err := svc.EventsConfig(ctx, nil)
+	require.NoError(t, err)
+	assert.True(t, status.EventsDisabled)
+	assert.Equal(t, &fleet.EventsConfig{AuditAllowConfig: ptr.Bool(true), EventsMax: ptr.Int(1000)}, status.Events)
+	assert.Equal(t, []string{`scheduled query "process_events" of pack "Global" uses process_events but events are disabled`}, status.Warnings)
+
+	// events are enabled for team1
+	ds.ListPacksFuncInvoked = false
+	status, err = svc.EventsConfig(ctx, ptr.Uint(1))
+	require.NoError(t, err)
+	assert.False(t, status.EventsDisabled)
+	assert.Equal(t, ptr.Int(60), status.Events.EventsExpiry)
+	assert.Empty(t, status.Warnings)
+	assert.False(t, ds.ListPacksFuncInvoked)
+
+	_, err = svc.EventsConfig(test.UserContext(test.UserTeamMaintainerTeam2), ptr.Uint(1))
+	checkAuthErr(t, true, err)
+}
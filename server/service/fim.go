@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create FIM Category
+////////////////////////////////////////////////////////////////////////////////
+
+type createFIMCategoryRequest struct {
+	TeamID *uint `json:"team_id"`
+	fleet.FIMCategoryPayload
+}
+
+type fimCategoryResponse struct {
+	Category *fleet.FIMCategory `json:"fim_category,omitempty"`
+	Err      error              `json:"error,omitempty"`
+}
+
+func (r fimCategoryResponse) error() error { return r.Err }
+
+func createFIMCategoryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*createFIMCategoryRequest)
+	category, err := svc.NewFIMCategory(ctx, req.TeamID, req.FIMCategoryPayload)
+	if err != nil {
+		return fimCategoryResponse{Err: err}, nil
+	}
+	return fimCategoryResponse{Category: category}, nil
+}
+
+func (svc *Service) NewFIMCategory(ctx context.Context, teamID *uint, p fleet.FIMCategoryPayload) (*fleet.FIMCategory, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.FIMCategory{TeamID: teamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	invalid := &fleet.InvalidArgumentError{}
+	if p.Name == nil {
+		invalid.Append("name", "missing required argument")
+	}
+	if p.Paths == nil {
+		invalid.Append("paths", "missing required argument")
+	}
+	if invalid.HasErrors() {
+		return nil, ctxerr.Wrap(ctx, invalid)
+	}
+	if err := p.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &badRequestError{
+			message: fmt.Sprintf("fim category payload verification: %s", err),
+		})
+	}
+
+	if teamID != nil {
+		if _, err := svc.ds.Team(ctx, *teamID); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get team")
+		}
+	}
+
+	category := &fleet.FIMCategory{TeamID: teamID, ExcludePaths: fleet.FIMPaths{}}
+	applyFIMCategoryPayload(category, p)
+	return svc.ds.NewFIMCategory(ctx, category)
+}
+
+func applyFIMCategoryPayload(category *fleet.FIMCategory, p fleet.FIMCategoryPayload) {
+	if p.Name != nil {
+		category.Name = *p.Name
+	}
+	if p.Paths != nil {
+		category.Paths = *p.Paths
+	}
+	if p.ExcludePaths != nil {
+		category.ExcludePaths = *p.ExcludePaths
+	}
+	if p.Accesses != nil {
+		category.Accesses = *p.Accesses
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List FIM Categories
+////////////////////////////////////////////////////////////////////////////////
+
+type listFIMCategoriesRequest struct {
+	TeamID *uint `query:"team_id,optional"`
+}
+
+type listFIMCategoriesResponse struct {
+	Categories []*fleet.FIMCategory `json:"fim_categories"`
+	Err        error                `json:"error,omitempty"`
+}
+
+func (r listFIMCategoriesResponse) error() error { return r.Err }
+
+func listFIMCategoriesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listFIMCategoriesRequest)
+	categories, err := svc.ListFIMCategories(ctx, req.TeamID)
+	if err != nil {
+		return listFIMCategoriesResponse{Err: err}, nil
+	}
+	if categories == nil {
+		categories = []*fleet.FIMCategory{}
+	}
+	return listFIMCategoriesResponse{Categories: categories}, nil
+}
+
+func (svc *Service) ListFIMCategories(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.FIMCategory{TeamID: teamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.ListFIMCategories(ctx, teamID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Modify FIM Category
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyFIMCategoryRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.FIMCategoryPayload
+}
+
+func modifyFIMCategoryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*modifyFIMCategoryRequest)
+	category, err := svc.ModifyFIMCategory(ctx, req.ID, req.FIMCategoryPayload)
+	if err != nil {
+		return fimCategoryResponse{Err: err}, nil
+	}
+	return fimCategoryResponse{Category: category}, nil
+}
+
+func (svc *Service) ModifyFIMCategory(ctx context.Context, id uint, p fleet.FIMCategoryPayload) (*fleet.FIMCategory, error) {
+	// First make sure the user can read FIM categories, the write access is
+	// checked once the team of the category is known.
+	if err := svc.authz.Authorize(ctx, &fleet.FIMCategory{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	category, err := svc.ds.FIMCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authz.Authorize(ctx, category, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := p.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &badRequestError{
+			message: fmt.Sprintf("fim category payload verification: %s", err),
+		})
+	}
+
+	applyFIMCategoryPayload(category, p)
+	if err := svc.ds.SaveFIMCategory(ctx, category); err != nil {
+		return nil, err
+	}
+	return svc.ds.FIMCategory(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete FIM Category
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteFIMCategoryRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteFIMCategoryResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteFIMCategoryResponse) error() error { return r.Err }
+
+func deleteFIMCategoryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*deleteFIMCategoryRequest)
+	if err := svc.DeleteFIMCategory(ctx, req.ID); err != nil {
+		return deleteFIMCategoryResponse{Err: err}, nil
+	}
+	return deleteFIMCategoryResponse{}, nil
+}
+
+func (svc *Service) DeleteFIMCategory(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.FIMCategory{}, fleet.ActionRead); err != nil {
+		return err
+	}
+
+	category, err := svc.ds.FIMCategory(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := svc.authz.Authorize(ctx, category, fleet.ActionWrite); err != nil {
+		return err
+	}
+	return svc.ds.DeleteFIMCategory(ctx, id)
+}
+
+// fimConfigForHost adds the FIM categories that apply to the host (the global
+// categories and the categories of the host's team) to the file_paths,
+// exclude_paths and file_accesses sections of the config. Categories defined
+// in the agent options take precedence over FIM categories with the same
+// name.
+func (svc *Service) fimConfigForHost(ctx context.Context, host *fleet.Host, config map[string]interface{}) error {
+	categories, err := svc.ds.ListFIMCategoriesForHost(ctx, host.TeamID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list fim categories")
+	}
+	if len(categories) == 0 {
+		return nil
+	}
+
+	agentPaths, _ := config["file_paths"].(map[string]interface{})
+	agentExcludePaths, _ := config["exclude_paths"].(map[string]interface{})
+	agentAccesses, _ := config["file_accesses"].([]interface{})
+
+	filePaths := make(map[string]interface{})
+	excludePaths := make(map[string]interface{})
+	var accesses []interface{}
+	for _, category := range categories {
+		if _, ok := agentPaths[category.Name]; ok {
+			continue
+		}
+		filePaths[category.Name] = category.Paths
+		if len(category.ExcludePaths) > 0 {
+			excludePaths[category.Name] = category.ExcludePaths
+		}
+		if category.Accesses {
+			accesses = append(accesses, category.Name)
+		}
+	}
+
+	for name, paths := range agentPaths {
+		filePaths[name] = paths
+	}
+	for name, paths := range agentExcludePaths {
+		excludePaths[name] = paths
+	}
+	accesses = append(accesses, agentAccesses...)
+
+	config["file_paths"] = filePaths
+	if len(excludePaths) > 0 {
+		config["exclude_paths"] = excludePaths
+	}
+	if len(accesses) > 0 {
+		config["file_accesses"] = accesses
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIMCategoriesAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.NewFIMCategoryFunc = func(ctx context.Context, category *fleet.FIMCategory) (*fleet.FIMCategory, error) {
+		return category, nil
+	}
+	ds.ListFIMCategoriesFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.FIMCategoryFunc = func(ctx context.Context, id uint) (*fleet.FIMCategory, error) {
+		if id == 1 {
+			return &fleet.FIMCategory{ID: id}, nil
+		}
+		return &fleet.FIMCategory{ID: id, TeamID: ptr.Uint(1)}, nil
+	}
+	ds.SaveFIMCategoryFunc = func(ctx context.Context, category *fleet.FIMCategory) error {
+		return nil
+	}
+	ds.DeleteFIMCategoryFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailGlobalWrite bool
+		shouldFailGlobalRead  bool
+		shouldFailTeamWrite   bool
+		shouldFailTeamRead    bool
+	}{
+		{
+			"global admin",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			false, false, false, false,
+		},
+		{
+			"global maintainer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			false, false, false, false,
+		},
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			true, false, true, false,
+		},
+		{
+			"team maintainer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}},
+			true, false, false, false,
+		},
+		{
+			"team observer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			true, false, true, false,
+		},
+		{
+			"team admin, DOES NOT belong to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			true, false, true, true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			payload := fleet.FIMCategoryPayload{
+				Name:  ptr.String("homes"),
+				Paths: &fleet.FIMPaths{"/home/%/%%"},
+			}
+
+			_, err := svc.NewFIMCategory(ctx, nil, payload)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			_, err = svc.NewFIMCategory(ctx, ptr.Uint(1), payload)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			_, err = svc.ListFIMCategories(ctx, nil)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+			_, err = svc.ListFIMCategories(ctx, ptr.Uint(1))
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			// category 1 is global, category 2 belongs to team 1
+			_, err = svc.ModifyFIMCategory(ctx, 1, fleet.FIMCategoryPayload{Accesses: ptr.Bool(true)})
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			_, err = svc.ModifyFIMCategory(ctx, 2, fleet.FIMCategoryPayload{Accesses: ptr.Bool(true)})
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			err = svc.DeleteFIMCategory(ctx, 1)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			err = svc.DeleteFIMCategory(ctx, 2)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+		})
+	}
+}
+
+func TestNewFIMCategoryValidation(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	_, err := svc.NewFIMCategory(ctx, nil, fleet.FIMCategoryPayload{Name: ptr.String("homes")})
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+
+	valid := func() fleet.FIMCategoryPayload {
+		return fleet.FIMCategoryPayload{
+			Name:  ptr.String("homes"),
+			Paths: &fleet.FIMPaths{"/home/%/%%", `C:\Users\%%`},
+		}
+	}
+	cases := []struct {
+		modify func(p *fleet.FIMCategoryPayload)
+		errMsg string
+	}{
+		{func(p *fleet.FIMCategoryPayload) { p.Name = ptr.String("") }, "name cannot be empty"},
+		{func(p *fleet.FIMCategoryPayload) { p.Name = ptr.String(" homes") }, "leading or trailing spaces"},
+		{func(p *fleet.FIMCategoryPayload) { p.Paths = &fleet.FIMPaths{} }, "at least one path"},
+		{func(p *fleet.FIMCategoryPayload) { p.Paths = &fleet.FIMPaths{"home/%%"} }, "paths must be absolute"},
+		{func(p *fleet.FIMCategoryPayload) { p.ExcludePaths = &fleet.FIMPaths{"%%"} }, "exclude paths must be absolute"},
+	}
+	for _, c := range cases {
+		p := valid()
+		c.modify(&p)
+		_, err := svc.NewFIMCategory(ctx, nil, p)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), c.errMsg)
+	}
+}
+
+func TestGetClientConfigFIMCategories(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{}, nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{
+			"file_paths":{"etc":["/etc/%%"]},
+			"file_accesses":["etc"]
+		}}`))}, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		categories := []*fleet.FIMCategory{
+			{Name: "etc", Paths: fleet.FIMPaths{"/overridden/%%"}},
+			{Name: "homes", Paths: fleet.FIMPaths{"/home/%/%%"}, ExcludePaths: fleet.FIMPaths{"/home/%/.cache/%%"}},
+		}
+		if teamID != nil {
+			categories = append(categories, &fleet.FIMCategory{Name: "tmp", TeamID: teamID, Paths: fleet.FIMPaths{"/tmp/%%"}, Accesses: true})
+		}
+		return categories, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, teamID uint) (*json.RawMessage, error) {
+		return nil, nil
+	}
+
+	ctx := hostctx.NewContext(context.Background(), &fleet.Host{ID: 1, TeamID: ptr.Uint(1), Platform: "ubuntu"})
+	conf, err := svc.GetClientConfig(ctx)
+	require.NoError(t, err)
+
+	b, err := json.Marshal(map[string]interface{}{
+		"file_paths":    conf["file_paths"],
+		"exclude_paths": conf["exclude_paths"],
+		"file_accesses": conf["file_accesses"],
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"file_paths":{"etc":["/etc/%%"],"homes":["/home/%/%%"],"tmp":["/tmp/%%"]},
+		"exclude_paths":{"homes":["/home/%/.cache/%%"]},
+		"file_accesses":["tmp","etc"]
+	}`, string(b))
+}
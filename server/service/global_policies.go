@@ -17,12 +17,16 @@ import (
 /////////////////////////////////////////////////////////////////////////////////
 
 type globalPolicyRequest struct {
-	QueryID     *uint  `json:"query_id"`
-	Query       string `json:"query"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Resolution  string `json:"resolution"`
-	Platform    string `json:"platform"`
+	QueryID           *uint  `json:"query_id"`
+	Query             string `json:"query"`
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	Resolution        string `json:"resolution"`
+	Platform          string `json:"platform"`
+	Critical          bool   `json:"critical"`
+	EvaluationWindow  string `json:"evaluation_window"`
+	RemediationScript string `json:"remediation_script"`
+	AutoRemediate     bool   `json:"auto_remediate"`
 }
 
 type globalPolicyResponse struct {
@@ -35,12 +39,16 @@ func (r globalPolicyResponse) error() error { return r.Err }
 func globalPolicyEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*globalPolicyRequest)
 	resp, err := svc.NewGlobalPolicy(ctx, fleet.PolicyPayload{
-		QueryID:     req.QueryID,
-		Query:       req.Query,
-		Name:        req.Name,
-		Description: req.Description,
-		Resolution:  req.Resolution,
-		Platform:    req.Platform,
+		QueryID:           req.QueryID,
+		Query:             req.Query,
+		Name:              req.Name,
+		Description:       req.Description,
+		Resolution:        req.Resolution,
+		Platform:          req.Platform,
+		Critical:          req.Critical,
+		EvaluationWindow:  req.EvaluationWindow,
+		RemediationScript: req.RemediationScript,
+		AutoRemediate:     req.AutoRemediate,
 	})
 	if err != nil {
 		return globalPolicyResponse{Err: err}, nil
@@ -82,6 +90,10 @@ func (svc Service) NewGlobalPolicy(ctx context.Context, p fleet.PolicyPayload) (
 // List
 /////////////////////////////////////////////////////////////////////////////////
 
+type listGlobalPoliciesRequest struct {
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
 type listGlobalPoliciesResponse struct {
 	Policies []*fleet.Policy `json:"policies,omitempty"`
 	Err      error           `json:"error,omitempty"`
@@ -89,20 +101,21 @@ type listGlobalPoliciesResponse struct {
 
 func (r listGlobalPoliciesResponse) error() error { return r.Err }
 
-func listGlobalPoliciesEndpoint(ctx context.Context, _ interface{}, svc fleet.Service) (interface{}, error) {
-	resp, err := svc.ListGlobalPolicies(ctx)
+func listGlobalPoliciesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listGlobalPoliciesRequest)
+	resp, err := svc.ListGlobalPolicies(ctx, req.ListOptions)
 	if err != nil {
 		return listGlobalPoliciesResponse{Err: err}, nil
 	}
 	return listGlobalPoliciesResponse{Policies: resp}, nil
 }
 
-func (svc Service) ListGlobalPolicies(ctx context.Context) ([]*fleet.Policy, error) {
+func (svc Service) ListGlobalPolicies(ctx context.Context, opts fleet.ListOptions) ([]*fleet.Policy, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionRead); err != nil {
 		return nil, err
 	}
 
-	return svc.ds.ListGlobalPolicies(ctx)
+	return svc.ds.ListGlobalPolicies(ctx, opts)
 }
 
 /////////////////////////////////////////////////////////////////////////////////
@@ -53,13 +53,15 @@ func (svc *Service) GetGlobalScheduledQueries(ctx context.Context, opts fleet.Li
 ////////////////////////////////////////////////////////////////////////////////
 
 type globalScheduleQueryRequest struct {
-	QueryID  uint    `json:"query_id"`
-	Interval uint    `json:"interval"`
-	Snapshot *bool   `json:"snapshot"`
-	Removed  *bool   `json:"removed"`
-	Platform *string `json:"platform"`
-	Version  *string `json:"version"`
-	Shard    *uint   `json:"shard"`
+	QueryID     uint    `json:"query_id"`
+	Interval    uint    `json:"interval"`
+	Snapshot    *bool   `json:"snapshot"`
+	Removed     *bool   `json:"removed"`
+	Platform    *string `json:"platform"`
+	Version     *string `json:"version"`
+	Shard       *uint   `json:"shard"`
+	DiscardData bool    `json:"discard_data"`
+	LabelIDs    []uint  `json:"label_ids"`
 }
 
 type globalScheduleQueryResponse struct {
@@ -73,13 +75,15 @@ func globalScheduleQueryEndpoint(ctx context.Context, request interface{}, svc f
 	req := request.(*globalScheduleQueryRequest)
 
 	scheduled, err := svc.GlobalScheduleQuery(ctx, &fleet.ScheduledQuery{
-		QueryID:  req.QueryID,
-		Interval: req.Interval,
-		Snapshot: req.Snapshot,
-		Removed:  req.Removed,
-		Platform: req.Platform,
-		Version:  req.Version,
-		Shard:    req.Shard,
+		QueryID:     req.QueryID,
+		Interval:    req.Interval,
+		Snapshot:    req.Snapshot,
+		Removed:     req.Removed,
+		Platform:    req.Platform,
+		Version:     req.Version,
+		Shard:       req.Shard,
+		DiscardData: req.DiscardData,
+		LabelIDs:    req.LabelIDs,
 	})
 	if err != nil {
 		return globalScheduleQueryResponse{Err: err}, nil
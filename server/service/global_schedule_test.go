@@ -14,6 +14,10 @@ func TestGlobalScheduleAuth(t *testing.T) {
 	ds := new(mock.Store)
 	svc := newTestService(t, ds, nil, nil)
 
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
 	ds.ListScheduledQueriesInPackWithStatsFunc = func(ctx context.Context, id uint, opts fleet.ListOptions) ([]*fleet.ScheduledQuery, error) {
 		return nil, nil
 	}
@@ -32,6 +36,9 @@ func TestGlobalScheduleAuth(t *testing.T) {
 	ds.DeleteScheduledQueryFunc = func(ctx context.Context, id uint) error {
 		return nil
 	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
 
 	testCases := []struct {
 		name            string
@@ -220,6 +220,7 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.DELETE("/api/_version_/fleet/sessions/{id:[0-9]+}", deleteSessionEndpoint, deleteSessionRequest{})
 
 	ue.GET("/api/_version_/fleet/config/certificate", getCertificateEndpoint, nil)
+	ue.GET("/api/_version_/fleet/deployment/{kind}", getAgentDeploymentFileEndpoint, getAgentDeploymentFileRequest{})
 	ue.GET("/api/_version_/fleet/config", getAppConfigEndpoint, nil)
 	ue.PATCH("/api/_version_/fleet/config", modifyAppConfigEndpoint, modifyAppConfigRequest{})
 	ue.POST("/api/_version_/fleet/spec/enroll_secret", applyEnrollSecretSpecEndpoint, applyEnrollSecretSpecRequest{})
@@ -266,7 +267,7 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.PATCH("/api/_version_/fleet/invites/{id:[0-9]+}", updateInviteEndpoint, updateInviteRequest{})
 
 	ue.POST("/api/_version_/fleet/global/policies", globalPolicyEndpoint, globalPolicyRequest{})
-	ue.GET("/api/_version_/fleet/global/policies", listGlobalPoliciesEndpoint, nil)
+	ue.GET("/api/_version_/fleet/global/policies", listGlobalPoliciesEndpoint, listGlobalPoliciesRequest{})
 	ue.GET("/api/_version_/fleet/global/policies/{policy_id}", getPolicyByIDEndpoint, getPolicyByIDRequest{})
 	ue.POST("/api/_version_/fleet/global/policies/delete", deleteGlobalPoliciesEndpoint, deleteGlobalPoliciesRequest{})
 	ue.PATCH("/api/_version_/fleet/global/policies/{policy_id}", modifyGlobalPolicyEndpoint, modifyGlobalPolicyRequest{})
@@ -277,8 +278,14 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.WithAltPaths("/api/_version_/fleet/team/{team_id}/policies/{policy_id}").GET("/api/_version_/fleet/teams/{team_id}/policies/{policy_id}", getTeamPolicyByIDEndpoint, getTeamPolicyByIDRequest{})
 	ue.WithAltPaths("/api/_version_/fleet/team/{team_id}/policies/delete").POST("/api/_version_/fleet/teams/{team_id}/policies/delete", deleteTeamPoliciesEndpoint, deleteTeamPoliciesRequest{})
 	ue.PATCH("/api/_version_/fleet/teams/{team_id}/policies/{policy_id}", modifyTeamPolicyEndpoint, modifyTeamPolicyRequest{})
+	ue.GET("/api/_version_/fleet/teams/{team_id}/policy_exemptions", teamPolicyExemptionsEndpoint, teamPolicyExemptionsRequest{})
+	ue.PATCH("/api/_version_/fleet/teams/{team_id}/policy_exemptions", setTeamPolicyExemptionsEndpoint, setTeamPolicyExemptionsRequest{})
 	ue.POST("/api/_version_/fleet/spec/policies", applyPolicySpecsEndpoint, applyPolicySpecsRequest{})
 
+	ue.GET("/api/_version_/fleet/policy_bundles", listPolicyBundlesEndpoint, nil)
+	ue.GET("/api/_version_/fleet/policy_bundles/{id}", getPolicyBundleEndpoint, getPolicyBundleRequest{})
+	ue.POST("/api/_version_/fleet/policy_bundles/{id}/import", importPolicyBundleEndpoint, importPolicyBundleRequest{})
+
 	ue.GET("/api/_version_/fleet/queries/{id:[0-9]+}", getQueryEndpoint, getQueryRequest{})
 	ue.GET("/api/_version_/fleet/queries", listQueriesEndpoint, listQueriesRequest{})
 	ue.POST("/api/_version_/fleet/queries", createQueryEndpoint, createQueryRequest{})
@@ -309,17 +316,58 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.GET("/api/_version_/fleet/software", listSoftwareEndpoint, listSoftwareRequest{})
 	ue.GET("/api/_version_/fleet/software/count", countSoftwareEndpoint, countSoftwareRequest{})
 
+	ue.POST("/api/_version_/fleet/atc_tables", createATCTableEndpoint, createATCTableRequest{})
+	ue.GET("/api/_version_/fleet/atc_tables", listATCTablesEndpoint, nil)
+	ue.PATCH("/api/_version_/fleet/atc_tables/{id:[0-9]+}", modifyATCTableEndpoint, modifyATCTableRequest{})
+	ue.DELETE("/api/_version_/fleet/atc_tables/{id:[0-9]+}", deleteATCTableEndpoint, deleteATCTableRequest{})
+
+	ue.POST("/api/_version_/fleet/fim_categories", createFIMCategoryEndpoint, createFIMCategoryRequest{})
+	ue.GET("/api/_version_/fleet/fim_categories", listFIMCategoriesEndpoint, listFIMCategoriesRequest{})
+	ue.PATCH("/api/_version_/fleet/fim_categories/{id:[0-9]+}", modifyFIMCategoryEndpoint, modifyFIMCategoryRequest{})
+	ue.DELETE("/api/_version_/fleet/fim_categories/{id:[0-9]+}", deleteFIMCategoryEndpoint, deleteFIMCategoryRequest{})
+
+	ue.POST("/api/_version_/fleet/yara_signature_groups", createYARASignatureGroupEndpoint, createYARASignatureGroupRequest{})
+	ue.GET("/api/_version_/fleet/yara_signature_groups", listYARASignatureGroupsEndpoint, listYARASignatureGroupsRequest{})
+	ue.PATCH("/api/_version_/fleet/yara_signature_groups/{id:[0-9]+}", modifyYARASignatureGroupEndpoint, modifyYARASignatureGroupRequest{})
+	ue.DELETE("/api/_version_/fleet/yara_signature_groups/{id:[0-9]+}", deleteYARASignatureGroupEndpoint, deleteYARASignatureGroupRequest{})
+
+	ue.POST("/api/_version_/fleet/dashboards", createDashboardEndpoint, createDashboardRequest{})
+	ue.GET("/api/_version_/fleet/dashboards", listDashboardsEndpoint, listDashboardsRequest{})
+	ue.GET("/api/_version_/fleet/dashboards/{id:[0-9]+}", getDashboardEndpoint, getDashboardRequest{})
+	ue.PATCH("/api/_version_/fleet/dashboards/{id:[0-9]+}", modifyDashboardEndpoint, modifyDashboardRequest{})
+	ue.DELETE("/api/_version_/fleet/dashboards/{id:[0-9]+}", deleteDashboardEndpoint, deleteDashboardRequest{})
+
 	ue.GET("/api/_version_/fleet/host_summary", getHostSummaryEndpoint, getHostSummaryRequest{})
+	ue.GET("/api/_version_/fleet/enrollment_stats", getEnrollmentStatsEndpoint, getEnrollmentStatsRequest{})
 	ue.GET("/api/_version_/fleet/hosts", listHostsEndpoint, listHostsRequest{})
 	ue.POST("/api/_version_/fleet/hosts/delete", deleteHostsEndpoint, deleteHostsRequest{})
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}", getHostEndpoint, getHostRequest{})
 	ue.GET("/api/_version_/fleet/hosts/count", countHostsEndpoint, countHostsRequest{})
+	ue.GET("/api/_version_/fleet/hosts/changes", listHostChangesEndpoint, listHostChangesRequest{})
 	ue.GET("/api/_version_/fleet/hosts/identifier/{identifier}", hostByIdentifierEndpoint, hostByIdentifierRequest{})
 	ue.DELETE("/api/_version_/fleet/hosts/{id:[0-9]+}", deleteHostEndpoint, deleteHostRequest{})
+	ue.GET("/api/_version_/fleet/hosts/duplicates", listDuplicateHostsEndpoint, listDuplicateHostsRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/merge", mergeHostsEndpoint, mergeHostsRequest{})
+	ue.PATCH("/api/_version_/fleet/hosts/{id:[0-9]+}/metadata", modifyHostMetadataEndpoint, modifyHostMetadataRequest{})
 	ue.POST("/api/_version_/fleet/hosts/transfer", addHostsToTeamEndpoint, addHostsToTeamRequest{})
 	ue.POST("/api/_version_/fleet/hosts/transfer/filter", addHostsToTeamByFilterEndpoint, addHostsToTeamByFilterRequest{})
 	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/refetch", refetchHostEndpoint, refetchHostRequest{})
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/device_mapping", listHostDeviceMappingEndpoint, listHostDeviceMappingRequest{})
+	ue.PUT("/api/_version_/fleet/hosts/{id:[0-9]+}/device_mapping", putHostDeviceMappingEndpoint, putHostDeviceMappingRequest{})
+	ue.POST("/api/_version_/fleet/hosts/device_mapping", applyHostDeviceMappingsEndpoint, applyHostDeviceMappingsRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/policies", listHostPoliciesEndpoint, listHostPoliciesRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/software", listHostSoftwareEndpoint, listHostSoftwareRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/users", listHostUsersEndpoint, listHostUsersRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/policies/{policy_id:[0-9]+}/remediate", remediateHostPolicyEndpoint, remediateHostPolicyRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/policy_remediations", listHostPolicyRemediationsEndpoint, listHostPolicyRemediationsRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/activities", listHostActivitiesEndpoint, listHostActivitiesRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/agent_options_override", getHostAgentOptionsOverrideEndpoint, getHostAgentOptionsOverrideRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/agent_options_override", setHostAgentOptionsOverrideEndpoint, setHostAgentOptionsOverrideRequest{})
+	ue.DELETE("/api/_version_/fleet/hosts/{id:[0-9]+}/agent_options_override", deleteHostAgentOptionsOverrideEndpoint, deleteHostAgentOptionsOverrideRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/effective_config", getHostEffectiveConfigEndpoint, getHostEffectiveConfigRequest{})
+	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/online_subscription", subscribeHostOnlineEndpoint, subscribeHostOnlineRequest{})
+	ue.DELETE("/api/_version_/fleet/hosts/{id:[0-9]+}/online_subscription", unsubscribeHostOnlineEndpoint, unsubscribeHostOnlineRequest{})
+	ue.GET("/api/_version_/fleet/hosts/online_subscriptions", listHostOnlineSubscriptionsEndpoint, listHostOnlineSubscriptionsRequest{})
 	ue.GET("/api/_version_/fleet/hosts/report", hostsReportEndpoint, hostsReportRequest{})
 	ue.GET("/api/_version_/fleet/os_versions", osVersionsEndpoint, osVersionsRequest{})
 
@@ -328,6 +376,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.GET("/api/_version_/fleet/labels/{id:[0-9]+}", getLabelEndpoint, getLabelRequest{})
 	ue.GET("/api/_version_/fleet/labels", listLabelsEndpoint, listLabelsRequest{})
 	ue.GET("/api/_version_/fleet/labels/{id:[0-9]+}/hosts", listHostsInLabelEndpoint, listHostsInLabelRequest{})
+	ue.POST("/api/_version_/fleet/labels/{id:[0-9]+}/hosts", addHostsToLabelEndpoint, addHostsToLabelRequest{})
+	ue.POST("/api/_version_/fleet/labels/{id:[0-9]+}/hosts/delete", removeHostsFromLabelEndpoint, removeHostsFromLabelRequest{})
 	ue.DELETE("/api/_version_/fleet/labels/{name}", deleteLabelEndpoint, deleteLabelRequest{})
 	ue.DELETE("/api/_version_/fleet/labels/id/{id:[0-9]+}", deleteLabelByIDEndpoint, deleteLabelByIDRequest{})
 	ue.POST("/api/_version_/fleet/spec/labels", applyLabelSpecsEndpoint, applyLabelSpecsRequest{})
@@ -337,9 +387,29 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.GET("/api/_version_/fleet/queries/run", runLiveQueryEndpoint, runLiveQueryRequest{})
 	ue.POST("/api/_version_/fleet/queries/run", createDistributedQueryCampaignEndpoint, createDistributedQueryCampaignRequest{})
 	ue.POST("/api/_version_/fleet/queries/run_by_names", createDistributedQueryCampaignByNamesEndpoint, createDistributedQueryCampaignByNamesRequest{})
+	ue.POST("/api/_version_/fleet/queries/run/{id:[0-9]+}/rerun", rerunDistributedQueryCampaignEndpoint, rerunDistributedQueryCampaignRequest{})
+	ue.POST("/api/_version_/fleet/queries/run/{id:[0-9]+}/targets/delete", removeDistributedQueryCampaignTargetsEndpoint, removeDistributedQueryCampaignTargetsRequest{})
+	ue.GET("/api/_version_/fleet/queries/run/{id:[0-9]+}/progress", getDistributedQueryCampaignProgressEndpoint, getDistributedQueryCampaignProgressRequest{})
+	ue.GET("/api/_version_/fleet/queries/run/{id:[0-9]+}/events", streamDistributedQueryCampaignResultsEndpoint, streamDistributedQueryCampaignResultsRequest{})
+	ue.GET("/api/_version_/fleet/queries/run/{id:[0-9]+}/results", exportDistributedQueryCampaignResultsEndpoint, exportDistributedQueryCampaignResultsRequest{})
+	ue.GET("/api/_version_/fleet/queries/usage", listQueryUsageEndpoint, listQueryUsageRequest{})
+	ue.GET("/api/_version_/fleet/queries/audit", listLiveQueryAuditLogEndpoint, listLiveQueryAuditLogRequest{})
+	ue.POST("/api/_version_/fleet/queries/batch", createBatchQueryEndpoint, createBatchQueryRequest{})
+	ue.GET("/api/_version_/fleet/queries/batch/{id:[0-9]+}", getBatchQueryEndpoint, getBatchQueryRequest{})
+	ue.GET("/api/_version_/fleet/queries/batch/{id:[0-9]+}/results", listBatchQueryResultsEndpoint, listBatchQueryResultsRequest{})
 
 	ue.GET("/api/_version_/fleet/activities", listActivitiesEndpoint, listActivitiesRequest{})
 
+	ue.GET("/api/_version_/fleet/agent_config/versions", listAgentConfigVersionsEndpoint, listAgentConfigVersionsRequest{})
+	ue.GET("/api/_version_/fleet/agent_config/versions/{id:[0-9]+}", getAgentConfigVersionEndpoint, getAgentConfigVersionRequest{})
+	ue.POST("/api/_version_/fleet/agent_config/versions/{id:[0-9]+}/rollback", rollbackAgentConfigEndpoint, rollbackAgentConfigRequest{})
+	ue.POST("/api/_version_/fleet/agent_options/rollouts", startAgentOptionsRolloutEndpoint, startAgentOptionsRolloutRequest{})
+	ue.GET("/api/_version_/fleet/agent_options/rollouts", listAgentOptionsRolloutsEndpoint, listAgentOptionsRolloutsRequest{})
+	ue.GET("/api/_version_/fleet/agent_options/rollouts/{id:[0-9]+}", getAgentOptionsRolloutEndpoint, getAgentOptionsRolloutRequest{})
+	ue.POST("/api/_version_/fleet/agent_options/rollouts/{id:[0-9]+}/promote", promoteAgentOptionsRolloutEndpoint, endAgentOptionsRolloutRequest{})
+	ue.POST("/api/_version_/fleet/agent_options/rollouts/{id:[0-9]+}/abort", abortAgentOptionsRolloutEndpoint, endAgentOptionsRolloutRequest{})
+	ue.GET("/api/_version_/fleet/events_config", getEventsConfigEndpoint, getEventsConfigRequest{})
+
 	ue.GET("/api/_version_/fleet/global/schedule", getGlobalScheduleEndpoint, getGlobalScheduleRequest{})
 	ue.POST("/api/_version_/fleet/global/schedule", globalScheduleQueryEndpoint, globalScheduleQueryRequest{})
 	ue.PATCH("/api/_version_/fleet/global/schedule/{id:[0-9]+}", modifyGlobalScheduleEndpoint, modifyGlobalScheduleRequest{})
@@ -351,6 +421,14 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/macadmins", getMacadminsDataEndpoint, getMacadminsDataRequest{})
 	ue.GET("/api/_version_/fleet/macadmins", getAggregatedMacadminsDataEndpoint, getAggregatedMacadminsDataRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/network_settings", getHostNetworkSettingsEndpoint, getHostNetworkSettingsRequest{})
+	ue.GET("/api/_version_/fleet/network_settings", getNetworkSettingsReportEndpoint, getNetworkSettingsReportRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/startup_items", listHostStartupItemsEndpoint, listHostStartupItemsRequest{})
+	ue.GET("/api/_version_/fleet/startup_items/changes", listStartupItemChangesEndpoint, listStartupItemChangesRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/listening_ports", listHostListeningPortsEndpoint, listHostListeningPortsRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/disks", listHostDisksEndpoint, listHostDisksRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/certificates", listHostCertificatesEndpoint, listHostCertificatesRequest{})
+	ue.GET("/api/_version_/fleet/listening_ports", getListeningPortsReportEndpoint, getListeningPortsReportRequest{})
 
 	ue.GET("/api/_version_/fleet/status/result_store", statusResultStoreEndpoint, nil)
 	ue.GET("/api/_version_/fleet/status/live_query", statusLiveQueryEndpoint, nil)
@@ -361,10 +439,17 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	de.POST("/api/_version_/fleet/device/{token}/refetch", refetchDeviceHostEndpoint, refetchDeviceHostRequest{})
 	de.GET("/api/_version_/fleet/device/{token}/device_mapping", listDeviceHostDeviceMappingEndpoint, listDeviceHostDeviceMappingRequest{})
 	de.GET("/api/_version_/fleet/device/{token}/macadmins", getDeviceMacadminsDataEndpoint, getDeviceMacadminsDataRequest{})
+	de.GET("/api/_version_/fleet/device/{token}/policy_remediations", listDevicePolicyRemediationsEndpoint, listDevicePolicyRemediationsRequest{})
+
+	// orbit-authenticated endpoints
+	oe := newOrbitAuthenticatedEndpointer(svc, logger, opts, r, "v1")
+	oe.POST("/api/_version_/fleet/orbit/policy_remediations", listOrbitPolicyRemediationsEndpoint, listOrbitPolicyRemediationsRequest{})
+	oe.POST("/api/_version_/fleet/orbit/policy_remediations/{remediation_id:[0-9]+}/result", setOrbitPolicyRemediationResultEndpoint, setOrbitPolicyRemediationResultRequest{})
 
 	// host-authenticated endpoints
 	he := newHostAuthenticatedEndpointer(svc, logger, opts, r, "v1")
 	he.POST("/api/_version_/osquery/config", getClientConfigEndpoint, getClientConfigRequest{})
+	he.POST("/api/_version_/osquery/flags", getClientFlagsEndpoint, getClientFlagsRequest{})
 	he.POST("/api/_version_/osquery/distributed/read", getDistributedQueriesEndpoint, getDistributedQueriesRequest{})
 	he.POST("/api/_version_/osquery/distributed/write", submitDistributedQueryResultsEndpoint, submitDistributedQueryResultsRequestShim{})
 	he.POST("/api/_version_/osquery/carve/begin", carveBeginEndpoint, carveBeginRequest{})
@@ -376,6 +461,7 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	// with the request.
 	ne := newNoAuthEndpointer(svc, opts, r, "v1")
 	ne.POST("/api/_version_/osquery/enroll", enrollAgentEndpoint, enrollAgentRequest{})
+	ne.POST("/api/_version_/fleet/orbit/enroll", enrollOrbitEndpoint, enrollOrbitRequest{})
 
 	// For some reason osquery does not provide a node key with the block data.
 	// Instead the carve session ID should be verified in the service method.
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List Host Activities
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostActivitiesRequest struct {
+	ID          uint              `url:"id"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listHostActivitiesResponse struct {
+	HostID     uint                  `json:"host_id"`
+	Activities []*fleet.HostActivity `json:"activities"`
+	Err        error                 `json:"error,omitempty"`
+}
+
+func (r listHostActivitiesResponse) error() error { return r.Err }
+
+func listHostActivitiesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostActivitiesRequest)
+	activities, err := svc.ListHostActivities(ctx, req.ID, req.ListOptions)
+	if err != nil {
+		return listHostActivitiesResponse{Err: err}, nil
+	}
+	return listHostActivitiesResponse{HostID: req.ID, Activities: activities}, nil
+}
+
+func (svc *Service) ListHostActivities(ctx context.Context, id uint, opt fleet.ListOptions) ([]*fleet.HostActivity, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	activities, err := svc.ds.ListHostActivities(ctx, host.ID, opt)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host activities")
+	}
+	return activities, nil
+}
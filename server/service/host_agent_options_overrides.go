@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Host Agent Options Override
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostAgentOptionsOverrideRequest struct {
+	ID uint `url:"id"`
+}
+
+type hostAgentOptionsOverrideResponse struct {
+	Override *fleet.HostAgentOptionsOverride `json:"agent_options_override,omitempty"`
+	Err      error                           `json:"error,omitempty"`
+}
+
+func (r hostAgentOptionsOverrideResponse) error() error { return r.Err }
+
+func getHostAgentOptionsOverrideEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getHostAgentOptionsOverrideRequest)
+	override, err := svc.HostAgentOptionsOverride(ctx, req.ID)
+	if err != nil {
+		return hostAgentOptionsOverrideResponse{Err: err}, nil
+	}
+	return hostAgentOptionsOverrideResponse{Override: override}, nil
+}
+
+func (svc *Service) HostAgentOptionsOverride(ctx context.Context, hostID uint) (*fleet.HostAgentOptionsOverride, error) {
+	if err := svc.authorizeHostAgentOptionsOverride(ctx, hostID, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.HostAgentOptionsOverride(ctx, hostID, svc.clock.Now())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Set Host Agent Options Override
+////////////////////////////////////////////////////////////////////////////////
+
+type setHostAgentOptionsOverrideRequest struct {
+	ID        uint            `json:"-" url:"id"`
+	Options   json.RawMessage `json:"options"`
+	ExpiresIn fleet.Duration  `json:"expires_in"`
+}
+
+func setHostAgentOptionsOverrideEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*setHostAgentOptionsOverrideRequest)
+	override, err := svc.SetHostAgentOptionsOverride(ctx, req.ID, req.Options, req.ExpiresIn.Duration)
+	if err != nil {
+		return hostAgentOptionsOverrideResponse{Err: err}, nil
+	}
+	return hostAgentOptionsOverrideResponse{Override: override}, nil
+}
+
+func (svc *Service) SetHostAgentOptionsOverride(ctx context.Context, hostID uint, options json.RawMessage, expiresIn time.Duration) (*fleet.HostAgentOptionsOverride, error) {
+	if err := svc.authorizeHostAgentOptionsOverride(ctx, hostID, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	invalid := &fleet.InvalidArgumentError{}
+	if err := fleet.ValidateJSONHostAgentOptionsOverride(options); err != nil {
+		invalid.Append("options", err.Error())
+	}
+	if expiresIn < 0 {
+		invalid.Append("expires_in", "must not be negative")
+	}
+	if invalid.HasErrors() {
+		return nil, ctxerr.Wrap(ctx, invalid)
+	}
+	if expiresIn == 0 {
+		expiresIn = fleet.DefaultHostAgentOptionsOverrideExpiry
+	}
+
+	override := &fleet.HostAgentOptionsOverride{
+		HostID:    hostID,
+		Options:   options,
+		ExpiresAt: svc.clock.Now().Add(expiresIn).UTC().Truncate(time.Second),
+	}
+	if err := svc.ds.SetHostAgentOptionsOverride(ctx, override); err != nil {
+		return nil, err
+	}
+	return svc.ds.HostAgentOptionsOverride(ctx, hostID, svc.clock.Now())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete Host Agent Options Override
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteHostAgentOptionsOverrideRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteHostAgentOptionsOverrideResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteHostAgentOptionsOverrideResponse) error() error { return r.Err }
+
+func deleteHostAgentOptionsOverrideEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*deleteHostAgentOptionsOverrideRequest)
+	if err := svc.DeleteHostAgentOptionsOverride(ctx, req.ID); err != nil {
+		return deleteHostAgentOptionsOverrideResponse{Err: err}, nil
+	}
+	return deleteHostAgentOptionsOverrideResponse{}, nil
+}
+
+func (svc *Service) DeleteHostAgentOptionsOverride(ctx context.Context, hostID uint) error {
+	if err := svc.authorizeHostAgentOptionsOverride(ctx, hostID, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	return svc.ds.DeleteHostAgentOptionsOverride(ctx, hostID)
+}
+
+// authorizeHostAgentOptionsOverride authorizes the action on the agent options
+// override of the host: reading it requires reading the host, modifying it
+// requires modifying the agent options of the host's team (or the global
+// agent options for hosts without a team).
+func (svc *Service) authorizeHostAgentOptionsOverride(ctx context.Context, hostID uint, action string) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return err
+	}
+
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "find host for agent options override")
+	}
+
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return err
+	}
+	if action == fleet.ActionRead {
+		return nil
+	}
+
+	if host.TeamID != nil {
+		return svc.authz.Authorize(ctx, &fleet.Team{ID: *host.TeamID}, action)
+	}
+	return svc.authz.Authorize(ctx, &fleet.AppConfig{}, action)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetHostAgentOptionsOverride(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		if id == 1 {
+			return &fleet.Host{ID: 1, TeamID: ptr.Uint(1)}, nil
+		}
+		return &fleet.Host{ID: id}, nil
+	}
+	var saved *fleet.HostAgentOptionsOverride
+	ds.SetHostAgentOptionsOverrideFunc = func(ctx context.Context, override *fleet.HostAgentOptionsOverride) error {
+		saved = override
+		return nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return saved, nil
+	}
+	ds.DeleteHostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint) error {
+		return nil
+	}
+
+	teamAdmin := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}},
+	}})
+	globalObserver := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{
+		GlobalRole: ptr.String(fleet.RoleObserver),
+	}})
+	verbose := json.RawMessage(`{"options":{"verbose":true}}`)
+
+	// observers can read the override but not modify it
+	_, err := svc.HostAgentOptionsOverride(globalObserver, 1)
+	require.NoError(t, err)
+	_, err = svc.SetHostAgentOptionsOverride(globalObserver, 1, verbose, time.Hour)
+	checkAuthErr(t, true, err)
+	checkAuthErr(t, true, svc.DeleteHostAgentOptionsOverride(globalObserver, 1))
+
+	// team admins can only modify the overrides of the hosts of their team
+	_, err = svc.SetHostAgentOptionsOverride(teamAdmin, 2, verbose, time.Hour)
+	checkAuthErr(t, true, err)
+	require.NoError(t, svc.DeleteHostAgentOptionsOverride(teamAdmin, 1))
+
+	_, err = svc.SetHostAgentOptionsOverride(teamAdmin, 1, json.RawMessage(`{"options":{"no_such_option":1}}`), time.Hour)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown osquery option")
+	_, err = svc.SetHostAgentOptionsOverride(teamAdmin, 1, json.RawMessage(`[]`), time.Hour)
+	require.Error(t, err)
+	_, err = svc.SetHostAgentOptionsOverride(teamAdmin, 1, verbose, -time.Hour)
+	require.Error(t, err)
+	assert.Nil(t, saved)
+
+	// the override expires after the default duration if none is provided
+	before := time.Now()
+	override, err := svc.SetHostAgentOptionsOverride(teamAdmin, 1, verbose, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), override.HostID)
+	assert.WithinDuration(t, before.Add(fleet.DefaultHostAgentOptionsOverrideExpiry), override.ExpiresAt, 2*time.Second)
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List Duplicate Hosts
+////////////////////////////////////////////////////////////////////////////////
+
+type listDuplicateHostsRequest struct{}
+
+type listDuplicateHostsResponse struct {
+	Duplicates []*fleet.DuplicateHosts `json:"duplicates"`
+	Err        error                   `json:"error,omitempty"`
+}
+
+func (r listDuplicateHostsResponse) error() error { return r.Err }
+
+func listDuplicateHostsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	duplicates, err := svc.ListDuplicateHosts(ctx)
+	if err != nil {
+		return listDuplicateHostsResponse{Err: err}, nil
+	}
+	return listDuplicateHostsResponse{Duplicates: duplicates}, nil
+}
+
+func (svc *Service) ListDuplicateHosts(ctx context.Context) ([]*fleet.DuplicateHosts, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
+
+	duplicates, err := svc.ds.ListDuplicateHosts(ctx, filter)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list duplicate hosts")
+	}
+	return duplicates, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Merge Hosts
+////////////////////////////////////////////////////////////////////////////////
+
+type mergeHostsRequest struct {
+	ID      uint   `url:"id" json:"-"`
+	HostIDs []uint `json:"host_ids"`
+}
+
+type mergeHostsResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r mergeHostsResponse) error() error { return r.Err }
+
+func mergeHostsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*mergeHostsRequest)
+	if err := svc.MergeHosts(ctx, req.ID, req.HostIDs); err != nil {
+		return mergeHostsResponse{Err: err}, nil
+	}
+	return mergeHostsResponse{}, nil
+}
+
+func (svc *Service) MergeHosts(ctx context.Context, id uint, duplicateIDs []uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return err
+	}
+
+	host, err := svc.ds.Host(ctx, id, true)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	if len(duplicateIDs) == 0 {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_ids", "at least one host must be specified"))
+	}
+	seen := make(map[uint]bool, len(duplicateIDs))
+	for _, dupID := range duplicateIDs {
+		if dupID == host.ID {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_ids", "a host cannot be merged into itself"))
+		}
+		if seen[dupID] {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_ids", fmt.Sprintf("host %d is specified more than once", dupID)))
+		}
+		seen[dupID] = true
+
+		dup, err := svc.ds.Host(ctx, dupID, true)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get duplicate host")
+		}
+		// the duplicate is deleted by the merge
+		if err := svc.authz.Authorize(ctx, dup, fleet.ActionWrite); err != nil {
+			return err
+		}
+		if !isDuplicateHost(host, dup) {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("host_ids",
+				fmt.Sprintf("host %d does not have the same hardware serial number or UUID as host %d", dup.ID, host.ID)))
+		}
+	}
+
+	if err := svc.ds.MergeHosts(ctx, host.ID, duplicateIDs); err != nil {
+		return ctxerr.Wrap(ctx, err, "merge hosts")
+	}
+
+	if err := svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeMergedHosts,
+		&map[string]interface{}{"host_id": host.ID, "host_display_name": host.DisplayName, "merged_host_ids": duplicateIDs},
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for merged hosts")
+	}
+	if err := svc.ds.NewHostActivities(
+		ctx,
+		[]uint{host.ID},
+		authz.UserFromContext(ctx),
+		fleet.HostActivityTypeMerged,
+		&map[string]interface{}{"merged_host_ids": duplicateIDs},
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "create host activity for merged hosts")
+	}
+	return nil
+}
+
+// isDuplicateHost returns true if the hosts have the same hardware serial
+// number, or the same UUID if either has no serial number.
+func isDuplicateHost(host, other *fleet.Host) bool {
+	if host.HardwareSerial != "" && other.HardwareSerial != "" {
+		return host.HardwareSerial == other.HardwareSerial
+	}
+	return host.UUID != "" && host.UUID == other.UUID
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeHosts(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	hosts := map[uint]*fleet.Host{
+		1: {ID: 1, HardwareSerial: "S1", UUID: "U1"},
+		2: {ID: 2, HardwareSerial: "S1", UUID: "U2"},
+		3: {ID: 3, HardwareSerial: "", UUID: "U1"},
+		4: {ID: 4, HardwareSerial: "S2", UUID: "U1"},
+		5: {ID: 5, HardwareSerial: "S1", UUID: "U5", TeamID: ptr.Uint(1)},
+	}
+	ds.HostFunc = func(ctx context.Context, id uint, skipLoadingExtras bool) (*fleet.Host, error) {
+		h, ok := hosts[id]
+		if !ok {
+			return nil, notFoundError{}
+		}
+		return h, nil
+	}
+	var mergedID uint
+	var mergedDups []uint
+	ds.MergeHostsFunc = func(ctx context.Context, hostID uint, duplicateIDs []uint) error {
+		mergedID, mergedDups = hostID, duplicateIDs
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, fleet.ActivityTypeMergedHosts, activityType)
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, []uint{mergedID}, hostIDs)
+		assert.Equal(t, fleet.HostActivityTypeMerged, activityType)
+		return nil
+	}
+	ctx := test.UserContext(test.UserAdmin)
+
+	require.NoError(t, svc.MergeHosts(ctx, 1, []uint{2, 3}))
+	assert.Equal(t, uint(1), mergedID)
+	assert.Equal(t, []uint{2, 3}, mergedDups)
+	assert.True(t, ds.NewActivityFuncInvoked)
+	assert.True(t, ds.NewHostActivitiesFuncInvoked)
+
+	ds.MergeHostsFuncInvoked = false
+	var argErr *fleet.InvalidArgumentError
+	for _, dups := range [][]uint{nil, {1}, {2, 2}, {4}} {
+		err := svc.MergeHosts(ctx, 1, dups)
+		require.ErrorAs(t, err, &argErr, dups)
+	}
+	err := svc.MergeHosts(ctx, 1, []uint{99})
+	require.True(t, fleet.IsNotFound(err))
+	assert.False(t, ds.MergeHostsFuncInvoked)
+
+	// the user must be able to write both hosts
+	maintainer := &fleet.User{ID: 42, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: maintainer})
+	checkAuthErr(t, true, svc.MergeHosts(ctx, 5, []uint{2}))
+	checkAuthErr(t, true, svc.MergeHosts(ctx, 2, []uint{5}))
+	observer := &fleet.User{ID: 43, GlobalRole: ptr.String(fleet.RoleObserver)}
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: observer})
+	checkAuthErr(t, true, svc.MergeHosts(ctx, 1, []uint{2}))
+	assert.False(t, ds.MergeHostsFuncInvoked)
+}
+
+func TestListDuplicateHosts(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.ListDuplicateHostsFunc = func(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.DuplicateHosts, error) {
+		assert.True(t, filter.IncludeObserver)
+		return []*fleet.DuplicateHosts{{HardwareSerial: "S1", Hosts: []*fleet.DuplicateHost{{ID: 2}, {ID: 1}}}}, nil
+	}
+
+	observer := &fleet.User{ID: 43, GlobalRole: ptr.String(fleet.RoleObserver)}
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: observer})
+	groups, err := svc.ListDuplicateHosts(ctx)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Len(t, groups[0].Hosts, 2)
+}
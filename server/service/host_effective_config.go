@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Host Effective Config
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostEffectiveConfigRequest struct {
+	ID uint `url:"id"`
+}
+
+type getHostEffectiveConfigResponse struct {
+	Config map[string]interface{} `json:"config"`
+	Err    error                  `json:"error,omitempty"`
+}
+
+func (r getHostEffectiveConfigResponse) error() error { return r.Err }
+
+func getHostEffectiveConfigEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getHostEffectiveConfigRequest)
+	config, err := svc.HostEffectiveConfig(ctx, req.ID)
+	if err != nil {
+		return getHostEffectiveConfigResponse{Err: err}, nil
+	}
+	return getHostEffectiveConfigResponse{Config: config}, nil
+}
+
+// HostEffectiveConfig returns the osquery client config that is served to the
+// host when it next requests its config. As the config includes the agent
+// options, it requires modifying the agent options of the host's team (or the
+// global agent options for hosts without a team).
+func (svc *Service) HostEffectiveConfig(ctx context.Context, id uint) (map[string]interface{}, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "find host for effective config")
+	}
+
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	if host.TeamID != nil {
+		if err := svc.authz.Authorize(ctx, &fleet.Team{ID: *host.TeamID}, fleet.ActionWrite); err != nil {
+			return nil, err
+		}
+	} else if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	return svc.renderClientConfig(ctx, host)
+}
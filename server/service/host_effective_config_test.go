@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostEffectiveConfig(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	hosts := map[uint]*fleet.Host{
+		1: {ID: 1, TeamID: ptr.Uint(1), Platform: "darwin", DistributedInterval: 60},
+		2: {ID: 2, Platform: "ubuntu"},
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		return nil, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":10},"decorators":{"load":["SELECT uuid FROM osquery_info"]}}}`)),
+		}, nil
+	}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, id uint) (*json.RawMessage, error) {
+		return ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":30}}}`)), nil
+	}
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{{ID: 1, Name: "monitoring"}}, nil
+	}
+	ds.ListScheduledQueriesInPackFunc = func(ctx context.Context, id uint) ([]*fleet.ScheduledQuery, error) {
+		return []*fleet.ScheduledQuery{{Name: "time", Query: "SELECT * FROM time", Interval: 60}}, nil
+	}
+	ds.UpdateHostOsqueryIntervalsFunc = func(ctx context.Context, hostID uint, intervals fleet.HostOsqueryIntervals) error {
+		return nil
+	}
+
+	teamAdmin := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}},
+	}})
+
+	// observers and maintainers cannot read the effective config
+	_, err := svc.HostEffectiveConfig(test.UserContext(test.UserObserver), 1)
+	checkAuthErr(t, true, err)
+	_, err = svc.HostEffectiveConfig(test.UserContext(test.UserMaintainer), 1)
+	checkAuthErr(t, true, err)
+	// team admins can only read the config of the hosts of their team
+	_, err = svc.HostEffectiveConfig(teamAdmin, 2)
+	checkAuthErr(t, true, err)
+
+	config, err := svc.HostEffectiveConfig(teamAdmin, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"distributed_interval": float64(30)}, config["options"])
+	assert.Equal(t, map[string]interface{}{"load": []interface{}{"SELECT uuid FROM osquery_info"}}, config["decorators"])
+	require.Contains(t, config, "packs")
+
+	// the config is the one served to the host, without updating the host
+	served, err := svc.GetClientConfig(hostctx.NewContext(context.Background(), hosts[1]))
+	require.NoError(t, err)
+	assert.True(t, ds.UpdateHostOsqueryIntervalsFuncInvoked)
+	servedJSON, err := json.Marshal(served)
+	require.NoError(t, err)
+	configJSON, err := json.Marshal(config)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(servedJSON), string(configJSON))
+
+	ds.UpdateHostOsqueryIntervalsFuncInvoked = false
+	config, err = svc.HostEffectiveConfig(test.UserContext(test.UserAdmin), 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"distributed_interval": float64(10)}, config["options"])
+	assert.False(t, ds.UpdateHostOsqueryIntervalsFuncInvoked)
+}
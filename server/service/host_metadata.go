@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Modify Host Metadata
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyHostMetadataRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.HostMetadataPayload
+}
+
+type modifyHostMetadataResponse struct {
+	Metadata *fleet.HostMetadata `json:"metadata,omitempty"`
+	Err      error               `json:"error,omitempty"`
+}
+
+func (r modifyHostMetadataResponse) error() error { return r.Err }
+
+func modifyHostMetadataEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*modifyHostMetadataRequest)
+	metadata, err := svc.ModifyHostMetadata(ctx, req.ID, req.HostMetadataPayload)
+	if err != nil {
+		return modifyHostMetadataResponse{Err: err}, nil
+	}
+	return modifyHostMetadataResponse{Metadata: metadata}, nil
+}
+
+func (svc *Service) ModifyHostMetadata(ctx context.Context, id uint, payload fleet.HostMetadataPayload) (*fleet.HostMetadata, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := payload.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+
+	metadata, err := svc.ds.HostMetadata(ctx, host.ID)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Notes != nil {
+		metadata.Notes = *payload.Notes
+	}
+	if payload.Tags != nil {
+		metadata.Tags = payload.Tags
+	}
+	if err := svc.ds.SetHostMetadata(ctx, metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModifyHostMetadata(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id}, nil
+	}
+	saved := &fleet.HostMetadata{HostID: 1, Notes: "old notes", Tags: fleet.HostTags{"owner": "alice"}}
+	ds.HostMetadataFunc = func(ctx context.Context, hostID uint) (*fleet.HostMetadata, error) {
+		m := *saved
+		return &m, nil
+	}
+	ds.SetHostMetadataFunc = func(ctx context.Context, metadata *fleet.HostMetadata) error {
+		saved = metadata
+		return nil
+	}
+	ctx := test.UserContext(test.UserAdmin)
+
+	// the tags are left unchanged if not provided
+	metadata, err := svc.ModifyHostMetadata(ctx, 1, fleet.HostMetadataPayload{Notes: ptr.String("new notes")})
+	require.NoError(t, err)
+	assert.Equal(t, "new notes", metadata.Notes)
+	assert.Equal(t, fleet.HostTags{"owner": "alice"}, metadata.Tags)
+
+	// the tags replace the existing ones
+	metadata, err = svc.ModifyHostMetadata(ctx, 1, fleet.HostMetadataPayload{Tags: fleet.HostTags{"cost_center": "42"}})
+	require.NoError(t, err)
+	assert.Equal(t, "new notes", metadata.Notes)
+	assert.Equal(t, fleet.HostTags{"cost_center": "42"}, metadata.Tags)
+
+	ds.SetHostMetadataFuncInvoked = false
+	_, err = svc.ModifyHostMetadata(ctx, 1, fleet.HostMetadataPayload{Tags: fleet.HostTags{"": "x"}})
+	var argErr *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &argErr)
+	_, err = svc.ModifyHostMetadata(ctx, 1, fleet.HostMetadataPayload{Tags: fleet.HostTags{"owner": strings.Repeat("a", fleet.HostTagValueMaxLength+1)}})
+	require.ErrorAs(t, err, &argErr)
+	_, err = svc.ModifyHostMetadata(ctx, 1, fleet.HostMetadataPayload{Notes: ptr.String(strings.Repeat("a", fleet.HostNotesMaxLength+1))})
+	require.ErrorAs(t, err, &argErr)
+	assert.False(t, ds.SetHostMetadataFuncInvoked)
+}
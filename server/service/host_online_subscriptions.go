@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mail"
+	"github.com/go-kit/kit/log/level"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Subscribe Host Online
+////////////////////////////////////////////////////////////////////////////////
+
+type subscribeHostOnlineRequest struct {
+	ID uint `url:"id"`
+}
+
+type subscribeHostOnlineResponse struct {
+	Subscription *fleet.HostOnlineSubscription `json:"subscription,omitempty"`
+	Err          error                         `json:"error,omitempty"`
+}
+
+func (r subscribeHostOnlineResponse) error() error { return r.Err }
+
+func subscribeHostOnlineEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*subscribeHostOnlineRequest)
+	sub, err := svc.SubscribeHostOnline(ctx, req.ID)
+	if err != nil {
+		return subscribeHostOnlineResponse{Err: err}, nil
+	}
+	return subscribeHostOnlineResponse{Subscription: sub}, nil
+}
+
+func (svc *Service) SubscribeHostOnline(ctx context.Context, hostID uint) (*fleet.HostOnlineSubscription, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "find host for online subscription")
+	}
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.NewHostOnlineSubscription(ctx, host.ID, vc.UserID())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Unsubscribe Host Online
+////////////////////////////////////////////////////////////////////////////////
+
+type unsubscribeHostOnlineRequest struct {
+	ID uint `url:"id"`
+}
+
+type unsubscribeHostOnlineResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r unsubscribeHostOnlineResponse) error() error { return r.Err }
+
+func unsubscribeHostOnlineEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*unsubscribeHostOnlineRequest)
+	if err := svc.UnsubscribeHostOnline(ctx, req.ID); err != nil {
+		return unsubscribeHostOnlineResponse{Err: err}, nil
+	}
+	return unsubscribeHostOnlineResponse{}, nil
+}
+
+func (svc *Service) UnsubscribeHostOnline(ctx context.Context, hostID uint) error {
+	// Users can only delete their own subscriptions, there is no need to read
+	// the host.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return fleet.ErrNoContext
+	}
+
+	return svc.ds.DeleteHostOnlineSubscription(ctx, hostID, vc.UserID())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Host Online Subscriptions
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostOnlineSubscriptionsRequest struct{}
+
+type listHostOnlineSubscriptionsResponse struct {
+	Subscriptions []*fleet.HostOnlineSubscription `json:"subscriptions"`
+	Err           error                           `json:"error,omitempty"`
+}
+
+func (r listHostOnlineSubscriptionsResponse) error() error { return r.Err }
+
+func listHostOnlineSubscriptionsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	subs, err := svc.ListHostOnlineSubscriptions(ctx)
+	if err != nil {
+		return listHostOnlineSubscriptionsResponse{Err: err}, nil
+	}
+	return listHostOnlineSubscriptionsResponse{Subscriptions: subs}, nil
+}
+
+func (svc *Service) ListHostOnlineSubscriptions(ctx context.Context) ([]*fleet.HostOnlineSubscription, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+
+	return svc.ds.ListHostOnlineSubscriptions(ctx, vc.UserID())
+}
+
+// notifyHostsOnline notifies, by email and with the host online webhook, the
+// users subscribed to the hosts that were just seen. The subscriptions are
+// consumed before notifying, so a failure to notify is logged and not retried.
+func (svc *Service) notifyHostsOnline(ctx context.Context, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	notifications, err := svc.ds.ConsumeHostOnlineSubscriptions(ctx, hostIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "consume host online subscriptions")
+	}
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	config, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config for host online notifications")
+	}
+
+	if config.SMTPSettings.SMTPConfigured {
+		for _, n := range notifications {
+			err := svc.mailService.SendEmail(fleet.Email{
+				Subject: fmt.Sprintf("%s is online", n.Hostname),
+				To:      []string{n.UserEmail},
+				Config:  config,
+				Mailer: &mail.HostOnlineMailer{
+					BaseURL:  template.URL(config.ServerSettings.ServerURL + svc.config.Server.URLPrefix),
+					AssetURL: getAssetURL(),
+					HostID:   n.HostID,
+					Hostname: n.Hostname,
+				},
+			})
+			if err != nil {
+				level.Error(svc.logger).Log("op", "notifyHostsOnline", "host_id", n.HostID, "user_id", n.UserID, "err", err)
+			}
+		}
+	}
+
+	if webhook := config.WebhookSettings.HostOnlineWebhook; webhook.Enable {
+		// one request per host, with all the users that subscribed to it
+		var hostIDsOrder []uint
+		subscribers := make(map[uint][]string)
+		hostnames := make(map[uint]string)
+		for _, n := range notifications {
+			if _, ok := subscribers[n.HostID]; !ok {
+				hostIDsOrder = append(hostIDsOrder, n.HostID)
+			}
+			subscribers[n.HostID] = append(subscribers[n.HostID], n.UserEmail)
+			hostnames[n.HostID] = n.Hostname
+		}
+		for _, hostID := range hostIDsOrder {
+			payload := map[string]interface{}{
+				"text": fmt.Sprintf(
+					"Host %s is online. You've been sent this message because the Host online webhook is enabled in your Fleet instance.",
+					hostnames[hostID],
+				),
+				"data": map[string]interface{}{
+					"host_id":     hostID,
+					"hostname":    hostnames[hostID],
+					"url":         fmt.Sprintf("%s%s/hosts/%d", config.ServerSettings.ServerURL, svc.config.Server.URLPrefix, hostID),
+					"subscribers": subscribers[hostID],
+				},
+			}
+			if err := server.PostJSONWithTimeout(ctx, webhook.DestinationURL, &payload); err != nil {
+				level.Error(svc.logger).Log("op", "notifyHostsOnline", "host_id", hostID, "err", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mail"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscribeHostOnline(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	hosts := map[uint]*fleet.Host{
+		1: {ID: 1, TeamID: ptr.Uint(1), Hostname: "foo"},
+		2: {ID: 2, Hostname: "bar"},
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return hosts[id], nil
+	}
+	var gotUserID uint
+	ds.NewHostOnlineSubscriptionFunc = func(ctx context.Context, hostID, userID uint) (*fleet.HostOnlineSubscription, error) {
+		gotUserID = userID
+		return &fleet.HostOnlineSubscription{HostID: hostID, UserID: userID, Hostname: hosts[hostID].Hostname}, nil
+	}
+	ds.DeleteHostOnlineSubscriptionFunc = func(ctx context.Context, hostID, userID uint) error {
+		gotUserID = userID
+		return nil
+	}
+	ds.ListHostOnlineSubscriptionsFunc = func(ctx context.Context, userID uint) ([]*fleet.HostOnlineSubscription, error) {
+		gotUserID = userID
+		return []*fleet.HostOnlineSubscription{{HostID: 1, UserID: userID}}, nil
+	}
+
+	teamObserver := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{
+		ID:    42,
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}},
+	}})
+
+	// users can only subscribe to the hosts they can read
+	_, err := svc.SubscribeHostOnline(teamObserver, 2)
+	checkAuthErr(t, true, err)
+	assert.False(t, ds.NewHostOnlineSubscriptionFuncInvoked)
+
+	sub, err := svc.SubscribeHostOnline(teamObserver, 1)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), gotUserID)
+	assert.Equal(t, "foo", sub.Hostname)
+
+	sub, err = svc.SubscribeHostOnline(test.UserContext(test.UserObserver), 2)
+	require.NoError(t, err)
+	assert.Equal(t, test.UserObserver.ID, gotUserID)
+	assert.Equal(t, "bar", sub.Hostname)
+
+	subs, err := svc.ListHostOnlineSubscriptions(teamObserver)
+	require.NoError(t, err)
+	require.Len(t, subs, 1)
+	assert.Equal(t, uint(42), gotUserID)
+
+	require.NoError(t, svc.UnsubscribeHostOnline(teamObserver, 1))
+	assert.Equal(t, uint(42), gotUserID)
+}
+
+func TestFlushSeenHostsNotifiesOnline(t *testing.T) {
+	ds := new(mock.Store)
+
+	var webhookPayloads []map[string]interface{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		var payload map[string]interface{}
+		require.NoError(t, json.Unmarshal(body, &payload))
+		webhookPayloads = append(webhookPayloads, payload)
+	}))
+	defer ts.Close()
+
+	var emails []fleet.Email
+	mailer := &mockMailService{SendEmailFn: func(e fleet.Email) error {
+		emails = append(emails, e)
+		return nil
+	}}
+	svc := &Service{
+		ds:          ds,
+		config:      config.TestConfig(),
+		mailService: mailer,
+		clock:       clock.NewMockClock(time.Now()),
+		authz:       authz.Must(),
+		logger:      kitlog.NewNopLogger(),
+		seenHostSet: newSeenHostSet(),
+	}
+
+	appConfig := &fleet.AppConfig{
+		ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com"},
+		SMTPSettings:   fleet.SMTPSettings{SMTPConfigured: true},
+		WebhookSettings: fleet.WebhookSettings{
+			HostOnlineWebhook: fleet.HostOnlineWebhookSettings{Enable: true, DestinationURL: ts.URL},
+		},
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appConfig, nil
+	}
+	ds.MarkHostsSeenFunc = func(ctx context.Context, hostIDs []uint, t time.Time) error {
+		return nil
+	}
+	var gotHostIDs []uint
+	ds.ConsumeHostOnlineSubscriptionsFunc = func(ctx context.Context, hostIDs []uint) ([]*fleet.HostOnlineNotification, error) {
+		gotHostIDs = hostIDs
+		return []*fleet.HostOnlineNotification{
+			{HostID: 1, Hostname: "foo", UserID: 1, UserName: "Alice", UserEmail: "alice@example.com"},
+			{HostID: 1, Hostname: "foo", UserID: 2, UserName: "Bob", UserEmail: "bob@example.com"},
+		}, nil
+	}
+
+	// nothing is consumed when no host was seen
+	require.NoError(t, svc.FlushSeenHosts(context.Background()))
+	assert.False(t, ds.ConsumeHostOnlineSubscriptionsFuncInvoked)
+
+	svc.seenHostSet.addHostID(1)
+	svc.seenHostSet.addHostID(3)
+	require.NoError(t, svc.FlushSeenHosts(context.Background()))
+	assert.ElementsMatch(t, []uint{1, 3}, gotHostIDs)
+
+	require.Len(t, emails, 2)
+	assert.Equal(t, []string{"alice@example.com"}, emails[0].To)
+	assert.Equal(t, []string{"bob@example.com"}, emails[1].To)
+	assert.Equal(t, "foo is online", emails[0].Subject)
+	hostMailer, ok := emails[0].Mailer.(*mail.HostOnlineMailer)
+	require.True(t, ok)
+	assert.Equal(t, uint(1), hostMailer.HostID)
+
+	require.Len(t, webhookPayloads, 1)
+	assert.Equal(t, map[string]interface{}{
+		"host_id":     float64(1),
+		"hostname":    "foo",
+		"url":         "https://fleet.example.com/hosts/1",
+		"subscribers": []interface{}{"alice@example.com", "bob@example.com"},
+	}, webhookPayloads[0]["data"])
+
+	// no email without SMTP, no request with the webhook disabled
+	emails, webhookPayloads = nil, nil
+	appConfig.SMTPSettings.SMTPConfigured = false
+	appConfig.WebhookSettings.HostOnlineWebhook.Enable = false
+	svc.seenHostSet.addHostID(1)
+	require.NoError(t, svc.FlushSeenHosts(context.Background()))
+	assert.Empty(t, emails)
+	assert.Empty(t, webhookPayloads)
+}
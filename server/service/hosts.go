@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/mail"
 	"time"
 
-	"github.com/fleetdm/fleet/v4/server/contexts/authz"
+	"github.com/fleetdm/fleet/v4/server/authz"
 	authz_ctx "github.com/fleetdm/fleet/v4/server/contexts/authz"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/contexts/logging"
@@ -29,7 +31,7 @@ type HostResponse struct {
 func hostResponseForHost(ctx context.Context, svc fleet.Service, host *fleet.Host) (*HostResponse, error) {
 	return &HostResponse{
 		Host:        host,
-		Status:      host.Status(time.Now()),
+		Status:      host.ComputedStatus,
 		DisplayText: host.Hostname,
 		Geolocation: svc.LookupGeoIP(ctx, host.PublicIP),
 	}, nil
@@ -47,16 +49,36 @@ type HostDetailResponse struct {
 func hostDetailResponseForHost(ctx context.Context, svc fleet.Service, host *fleet.HostDetail) (*HostDetailResponse, error) {
 	return &HostDetailResponse{
 		HostDetail:  *host,
-		Status:      host.Status(time.Now()),
+		Status:      host.ComputedStatus,
 		DisplayText: host.Hostname,
 		Geolocation: svc.LookupGeoIP(ctx, host.PublicIP),
 	}, nil
 }
 
+// setHostsStatus sets the computed status of the hosts returned by the
+// service, with the host settings loaded once for all the hosts.
+func (svc *Service) setHostsStatus(ctx context.Context, hosts ...*fleet.Host) error {
+	if len(hosts) == 0 {
+		return nil
+	}
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config for host status")
+	}
+	now := svc.clock.Now()
+	for _, host := range hosts {
+		host.ComputedStatus = host.Status(now, appConfig.HostSettings)
+	}
+	return nil
+}
+
 func (svc *Service) FlushSeenHosts(ctx context.Context) error {
 	// No authorization check because this is used only internally.
 	hostIDs := svc.seenHostSet.getAndClearHostIDs()
-	return svc.ds.MarkHostsSeen(ctx, hostIDs, svc.clock.Now())
+	if err := svc.ds.MarkHostsSeen(ctx, hostIDs, svc.clock.Now()); err != nil {
+		return err
+	}
+	return svc.notifyHostsOnline(ctx, hostIDs)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -112,7 +134,14 @@ func (svc *Service) ListHosts(ctx context.Context, opt fleet.HostListOptions) ([
 	}
 	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
 
-	return svc.ds.ListHosts(ctx, filter, opt)
+	hosts, err := svc.ds.ListHosts(ctx, filter, opt)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.setHostsStatus(ctx, hosts...); err != nil {
+		return nil, err
+	}
+	return hosts, nil
 }
 
 func (svc *Service) SoftwareByID(ctx context.Context, id uint) (*fleet.Software, error) {
@@ -123,6 +152,114 @@ func (svc *Service) SoftwareByID(ctx context.Context, id uint) (*fleet.Software,
 	return svc.ds.SoftwareByID(ctx, id)
 }
 
+/////////////////////////////////////////////////////////////////////////////////
+// List Host Changes
+/////////////////////////////////////////////////////////////////////////////////
+
+const (
+	defaultHostChangesPerPage = 100
+	maxHostChangesPerPage     = 1000
+)
+
+type listHostChangesRequest struct {
+	Since   string `query:"since,optional"`
+	Cursor  string `query:"cursor,optional"`
+	PerPage int    `query:"per_page,optional"`
+	TeamID  *uint  `query:"team_id,optional"`
+}
+
+type listHostChangesResponse struct {
+	Cursor  string                 `json:"cursor"`
+	More    bool                   `json:"more"`
+	Hosts   []HostResponse         `json:"hosts"`
+	Deleted []*fleet.HostTombstone `json:"deleted"`
+	Err     error                  `json:"error,omitempty"`
+}
+
+func (r listHostChangesResponse) error() error { return r.Err }
+
+func listHostChangesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostChangesRequest)
+
+	var cursor fleet.HostChangesCursor
+	switch {
+	case req.Cursor != "":
+		c, err := fleet.ParseHostChangesCursor(req.Cursor)
+		if err != nil {
+			return listHostChangesResponse{Err: ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("cursor", "must be the cursor of a previous response"))}, nil
+		}
+		cursor = c
+	case req.Since != "":
+		since, err := time.Parse(time.RFC3339, req.Since)
+		if err != nil {
+			return listHostChangesResponse{Err: ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("since", "must be a RFC3339 timestamp"))}, nil
+		}
+		cursor = fleet.NewHostChangesCursor(since)
+	default:
+		return listHostChangesResponse{Err: ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("since", "since or cursor is required"))}, nil
+	}
+
+	perPage := req.PerPage
+	switch {
+	case perPage <= 0:
+		perPage = defaultHostChangesPerPage
+	case perPage > maxHostChangesPerPage:
+		perPage = maxHostChangesPerPage
+	}
+
+	changes, err := svc.ListHostChanges(ctx, req.TeamID, cursor, perPage)
+	if err != nil {
+		return listHostChangesResponse{Err: err}, nil
+	}
+
+	hostResponses := make([]HostResponse, len(changes.Hosts))
+	for i, host := range changes.Hosts {
+		h, err := hostResponseForHost(ctx, svc, host)
+		if err != nil {
+			return listHostChangesResponse{Err: err}, nil
+		}
+		hostResponses[i] = *h
+	}
+	deleted := changes.Deleted
+	if deleted == nil {
+		deleted = []*fleet.HostTombstone{}
+	}
+	return listHostChangesResponse{
+		Cursor:  changes.Cursor.String(),
+		More:    changes.More,
+		Hosts:   hostResponses,
+		Deleted: deleted,
+	}, nil
+}
+
+func (svc *Service) ListHostChanges(ctx context.Context, teamID *uint, cursor fleet.HostChangesCursor, limit int) (*fleet.HostChanges, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true, TeamID: teamID}
+
+	// the deletions older than the retention of tombstones cannot be listed,
+	// so the client must sync all the hosts again.
+	if cursor.DeletedAt.Before(svc.clock.Now().Add(-fleet.HostTombstoneRetention)) {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("since",
+			fmt.Sprintf("changes are only available for the last %d days, list all hosts instead", int(fleet.HostTombstoneRetention.Hours()/24))))
+	}
+
+	changes, err := svc.ds.ListHostChanges(ctx, filter, cursor, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.setHostsStatus(ctx, changes.Hosts...); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
 /////////////////////////////////////////////////////////////////////////////////
 // Delete Hosts
 /////////////////////////////////////////////////////////////////////////////////
@@ -138,7 +275,8 @@ type deleteHostsRequest struct {
 }
 
 type deleteHostsResponse struct {
-	Err error `json:"error,omitempty"`
+	Deleted int   `json:"deleted"`
+	Err     error `json:"error,omitempty"`
 }
 
 func (r deleteHostsResponse) error() error { return r.Err }
@@ -152,44 +290,55 @@ func deleteHostsEndpoint(ctx context.Context, request interface{}, svc fleet.Ser
 		StatusFilter: req.Filters.Status,
 		TeamFilter:   req.Filters.TeamID,
 	}
-	err := svc.DeleteHosts(ctx, req.IDs, listOpt, req.Filters.LabelID)
+	deleted, err := svc.DeleteHosts(ctx, req.IDs, listOpt, req.Filters.LabelID)
 	if err != nil {
 		return deleteHostsResponse{Err: err}, nil
 	}
-	return deleteHostsResponse{}, nil
+	return deleteHostsResponse{Deleted: deleted}, nil
 }
 
-func (svc *Service) DeleteHosts(ctx context.Context, ids []uint, opts fleet.HostListOptions, lid *uint) error {
+func (svc *Service) DeleteHosts(ctx context.Context, ids []uint, opts fleet.HostListOptions, lid *uint) (int, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
-		return err
+		return 0, err
 	}
 
 	if len(ids) > 0 && (lid != nil || !opts.Empty()) {
-		return &badRequestError{"Cannot specify a list of ids and filters at the same time"}
+		return 0, &badRequestError{"Cannot specify a list of ids and filters at the same time"}
 	}
 
 	if len(ids) > 0 {
 		err := svc.checkWriteForHostIDs(ctx, ids)
 		if err != nil {
-			return err
+			return 0, err
+		}
+		if err := svc.ds.DeleteHosts(ctx, ids); err != nil {
+			return 0, err
 		}
-		return svc.ds.DeleteHosts(ctx, ids)
+		return len(ids), nil
 	}
 
-	hostIDs, err := svc.hostIDsFromFilters(ctx, opts, lid)
+	hosts, err := svc.hostsFromFilters(ctx, opts, lid)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	if len(hostIDs) == 0 {
-		return nil
+	if len(hosts) == 0 {
+		return 0, nil
 	}
 
-	err = svc.checkWriteForHostIDs(ctx, hostIDs)
-	if err != nil {
-		return err
+	// the hosts are already loaded with their team, no need to load them again
+	// to check the write permission as for the ids.
+	hostIDs := make([]uint, 0, len(hosts))
+	for _, host := range hosts {
+		if err := svc.authz.Authorize(ctx, host, fleet.ActionWrite); err != nil {
+			return 0, err
+		}
+		hostIDs = append(hostIDs, host.ID)
 	}
-	return svc.ds.DeleteHosts(ctx, hostIDs)
+	if err := svc.ds.DeleteHosts(ctx, hostIDs); err != nil {
+		return 0, err
+	}
+	return len(hostIDs), nil
 }
 
 /////////////////////////////////////////////////////////////////////////////////
@@ -321,6 +470,7 @@ func (svc *Service) checkWriteForHostIDs(ctx context.Context, ids []uint) error
 type getHostSummaryRequest struct {
 	TeamID   *uint   `query:"team_id,optional"`
 	Platform *string `query:"platform,optional"`
+	LabelID  *uint   `query:"label_id,optional"`
 }
 
 type getHostSummaryResponse struct {
@@ -332,7 +482,7 @@ func (r getHostSummaryResponse) error() error { return r.Err }
 
 func getHostSummaryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*getHostSummaryRequest)
-	summary, err := svc.GetHostSummary(ctx, req.TeamID, req.Platform)
+	summary, err := svc.GetHostSummary(ctx, req.TeamID, req.Platform, req.LabelID)
 	if err != nil {
 		return getHostSummaryResponse{Err: err}, nil
 	}
@@ -343,7 +493,7 @@ func getHostSummaryEndpoint(ctx context.Context, request interface{}, svc fleet.
 	return resp, nil
 }
 
-func (svc *Service) GetHostSummary(ctx context.Context, teamID *uint, platform *string) (*fleet.HostSummary, error) {
+func (svc *Service) GetHostSummary(ctx context.Context, teamID *uint, platform *string, labelID *uint) (*fleet.HostSummary, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.Host{TeamID: teamID}, fleet.ActionList); err != nil {
 		return nil, err
 	}
@@ -353,7 +503,12 @@ func (svc *Service) GetHostSummary(ctx context.Context, teamID *uint, platform *
 	}
 	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true, TeamID: teamID}
 
-	summary, err := svc.ds.GenerateHostStatusStatistics(ctx, filter, svc.clock.Now(), platform)
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config for host status")
+	}
+
+	summary, err := svc.ds.GenerateHostStatusStatistics(ctx, filter, svc.clock.Now(), appConfig.HostSettings, platform, labelID)
 	if err != nil {
 		return nil, err
 	}
@@ -478,7 +633,10 @@ func (svc *Service) AddHostsToTeam(ctx context.Context, teamID *uint, hostIDs []
 		return err
 	}
 
-	return svc.ds.AddHostsToTeam(ctx, teamID, hostIDs)
+	if err := svc.ds.AddHostsToTeam(ctx, teamID, hostIDs); err != nil {
+		return err
+	}
+	return svc.newTransferredHostsActivity(ctx, teamID, hostIDs)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -533,7 +691,47 @@ func (svc *Service) AddHostsToTeamByFilter(ctx context.Context, teamID *uint, op
 	}
 
 	// Apply the team to the selected hosts.
-	return svc.ds.AddHostsToTeam(ctx, teamID, hostIDs)
+	if err := svc.ds.AddHostsToTeam(ctx, teamID, hostIDs); err != nil {
+		return err
+	}
+	return svc.newTransferredHostsActivity(ctx, teamID, hostIDs)
+}
+
+// newTransferredHostsActivity records the transfer of the hosts to the team,
+// or to no team if teamID is nil, as an activity and in the activities of each
+// host. The team policies and packs of the hosts are updated on their next
+// check-in.
+func (svc *Service) newTransferredHostsActivity(ctx context.Context, teamID *uint, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	var teamName *string
+	if teamID != nil {
+		team, err := svc.ds.Team(ctx, *teamID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get team for transferred hosts activity")
+		}
+		teamName = &team.Name
+	}
+	if err := svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeTransferredHosts,
+		&map[string]interface{}{"team_id": teamID, "team_name": teamName, "host_ids": hostIDs},
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for transferred hosts")
+	}
+	if err := svc.ds.NewHostActivities(
+		ctx,
+		hostIDs,
+		authz.UserFromContext(ctx),
+		fleet.HostActivityTypeTransferred,
+		&map[string]interface{}{"team_id": teamID, "team_name": teamName},
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "create host activities for transferred hosts")
+	}
+	return nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -585,6 +783,10 @@ func (svc *Service) RefetchHost(ctx context.Context, id uint) error {
 }
 
 func (svc *Service) getHostDetails(ctx context.Context, host *fleet.Host) (*fleet.HostDetail, error) {
+	if err := svc.setHostsStatus(ctx, host); err != nil {
+		return nil, err
+	}
+
 	if err := svc.ds.LoadHostSoftware(ctx, host); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "load host software")
 	}
@@ -604,22 +806,44 @@ func (svc *Service) getHostDetails(ctx context.Context, host *fleet.Host) (*flee
 		return nil, ctxerr.Wrap(ctx, err, "get policies for host")
 	}
 
-	return &fleet.HostDetail{Host: *host, Labels: labels, Packs: packs, Policies: policies}, nil
-}
+	var findings []*fleet.HostThreatFinding
+	if svc.config.ThreatIntel.URL != "" {
+		findings, err = svc.ds.ListHostThreatFindings(ctx, host.ID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get threat findings for host")
+		}
+	}
 
-func (svc *Service) hostIDsFromFilters(ctx context.Context, opt fleet.HostListOptions, lid *uint) ([]uint, error) {
-	filter, err := processHostFilters(ctx, opt, lid)
+	agentVersions, err := svc.ds.ListHostAgentVersions(ctx, host.ID)
 	if err != nil {
-		return nil, err
+		return nil, ctxerr.Wrap(ctx, err, "get agent versions for host")
 	}
 
-	// Load hosts, either from label if provided or from all hosts.
-	var hosts []*fleet.Host
-	if lid != nil {
-		hosts, err = svc.ds.ListHostsInLabel(ctx, filter, *lid, opt)
-	} else {
-		hosts, err = svc.ds.ListHosts(ctx, filter, opt)
+	batteries, err := svc.ds.ListHostBatteries(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get batteries for host")
 	}
+
+	metadata, err := svc.ds.HostMetadata(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get metadata for host")
+	}
+
+	return &fleet.HostDetail{
+		Host:           *host,
+		Labels:         labels,
+		Packs:          packs,
+		Policies:       policies,
+		ThreatFindings: findings,
+		AgentVersions:  agentVersions,
+		Batteries:      batteries,
+		Notes:          metadata.Notes,
+		Tags:           metadata.Tags,
+	}, nil
+}
+
+func (svc *Service) hostIDsFromFilters(ctx context.Context, opt fleet.HostListOptions, lid *uint) ([]uint, error) {
+	hosts, err := svc.hostsFromFilters(ctx, opt, lid)
 	if err != nil {
 		return nil, err
 	}
@@ -635,6 +859,25 @@ func (svc *Service) hostIDsFromFilters(ctx context.Context, opt fleet.HostListOp
 	return hostIDs, nil
 }
 
+func (svc *Service) hostsFromFilters(ctx context.Context, opt fleet.HostListOptions, lid *uint) ([]*fleet.Host, error) {
+	filter, err := processHostFilters(ctx, opt, lid)
+	if err != nil {
+		return nil, err
+	}
+
+	// Load hosts, either from label if provided or from all hosts.
+	var hosts []*fleet.Host
+	if lid != nil {
+		hosts, err = svc.ds.ListHostsInLabel(ctx, filter, *lid, opt)
+	} else {
+		hosts, err = svc.ds.ListHosts(ctx, filter, opt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
 func processHostFilters(ctx context.Context, opt fleet.HostListOptions, lid *uint) (fleet.TeamFilter, error) {
 	vc, ok := viewer.FromContext(ctx)
 	if !ok {
@@ -695,6 +938,217 @@ func (svc *Service) ListHostDeviceMapping(ctx context.Context, id uint) ([]*flee
 	return svc.ds.ListHostDeviceMapping(ctx, id)
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Set Custom Host Device Mapping
+////////////////////////////////////////////////////////////////////////////////
+
+type putHostDeviceMappingRequest struct {
+	ID    uint   `url:"id"`
+	Email string `json:"email"`
+}
+
+func putHostDeviceMappingEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*putHostDeviceMappingRequest)
+	dms, err := svc.SetCustomHostDeviceMapping(ctx, req.ID, req.Email)
+	if err != nil {
+		return listHostDeviceMappingResponse{Err: err}, nil
+	}
+	return listHostDeviceMappingResponse{HostID: req.ID, DeviceMapping: dms}, nil
+}
+
+func (svc *Service) SetCustomHostDeviceMapping(ctx context.Context, id uint, email string) ([]*fleet.HostDeviceMapping, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := validateDeviceMappingEmail(email); err != nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("email", err.Error()))
+	}
+
+	return svc.ds.SetOrUpdateCustomHostDeviceMapping(ctx, host.ID, email)
+}
+
+// validateDeviceMappingEmail checks that the email of a custom device mapping
+// is a plain email address. An empty email is valid, it removes the mapping.
+func validateDeviceMappingEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return errors.New("must be a valid email address")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Apply Host Device Mappings
+////////////////////////////////////////////////////////////////////////////////
+
+type applyHostDeviceMappingsRequest struct {
+	DeviceMapping []fleet.HostDeviceMappingPayload `json:"device_mapping"`
+}
+
+type applyHostDeviceMappingsResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r applyHostDeviceMappingsResponse) error() error { return r.Err }
+
+func applyHostDeviceMappingsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*applyHostDeviceMappingsRequest)
+	if err := svc.ApplyHostDeviceMappings(ctx, req.DeviceMapping); err != nil {
+		return applyHostDeviceMappingsResponse{Err: err}, nil
+	}
+	return applyHostDeviceMappingsResponse{}, nil
+}
+
+func (svc *Service) ApplyHostDeviceMappings(ctx context.Context, mappings []fleet.HostDeviceMappingPayload) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return err
+	}
+
+	// resolve and validate all the mappings first so that nothing is applied
+	// if any of them is invalid
+	invalid := &fleet.InvalidArgumentError{}
+	hosts := make([]*fleet.Host, len(mappings))
+	for i, m := range mappings {
+		if err := validateDeviceMappingEmail(m.Email); err != nil {
+			invalid.Append(fmt.Sprintf("device_mapping[%d].email", i), err.Error())
+			continue
+		}
+		host, err := svc.ds.HostByIdentifier(ctx, m.Host)
+		if err != nil {
+			if fleet.IsNotFound(err) {
+				invalid.Appendf(fmt.Sprintf("device_mapping[%d].host", i), "host %q not found", m.Host)
+				continue
+			}
+			return ctxerr.Wrap(ctx, err, "get host by identifier")
+		}
+		if err := svc.authz.Authorize(ctx, host, fleet.ActionWrite); err != nil {
+			return err
+		}
+		hosts[i] = host
+	}
+	if invalid.HasErrors() {
+		return ctxerr.Wrap(ctx, invalid)
+	}
+
+	for i, m := range mappings {
+		if _, err := svc.ds.SetOrUpdateCustomHostDeviceMapping(ctx, hosts[i].ID, m.Email); err != nil {
+			return ctxerr.Wrap(ctx, err, "set custom host device mapping")
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Host Users
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostUsersRequest struct {
+	ID uint `url:"id"`
+}
+
+type listHostUsersResponse struct {
+	HostID uint             `json:"host_id"`
+	Users  []fleet.HostUser `json:"users"`
+	Err    error            `json:"error,omitempty"`
+}
+
+func (r listHostUsersResponse) error() error { return r.Err }
+
+func listHostUsersEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostUsersRequest)
+	users, err := svc.ListHostUsers(ctx, req.ID)
+	if err != nil {
+		return listHostUsersResponse{Err: err}, nil
+	}
+	if users == nil {
+		users = []fleet.HostUser{}
+	}
+	return listHostUsersResponse{HostID: req.ID, Users: users}, nil
+}
+
+func (svc *Service) ListHostUsers(ctx context.Context, id uint) ([]fleet.HostUser, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	users, err := svc.ds.ListHostUsers(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host users")
+	}
+	return users, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Host Policies
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostPoliciesRequest struct {
+	ID uint `url:"id"`
+}
+
+type listHostPoliciesResponse struct {
+	HostID   uint                `json:"host_id"`
+	Policies []*fleet.HostPolicy `json:"policies"`
+	Err      error               `json:"error,omitempty"`
+}
+
+func (r listHostPoliciesResponse) error() error { return r.Err }
+
+func listHostPoliciesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostPoliciesRequest)
+	policies, err := svc.ListHostPolicies(ctx, req.ID)
+	if err != nil {
+		return listHostPoliciesResponse{Err: err}, nil
+	}
+	return listHostPoliciesResponse{HostID: req.ID, Policies: policies}, nil
+}
+
+func (svc *Service) ListHostPolicies(ctx context.Context, id uint) ([]*fleet.HostPolicy, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	policies, err := svc.ds.ListPoliciesForHost(ctx, host)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list policies for host")
+	}
+	return policies, nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Macadmins
 ////////////////////////////////////////////////////////////////////////////////
@@ -862,7 +1316,7 @@ func hostsReportEndpoint(ctx context.Context, request interface{}, svc fleet.Ser
 	// for now, only csv format is allowed
 	if req.Format != "csv" {
 		// prevent returning an "unauthorized" error, we want that specific error
-		if az, ok := authz.FromContext(ctx); ok {
+		if az, ok := authz_ctx.FromContext(ctx); ok {
 			az.SetChecked()
 		}
 		err := ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("format", "unsupported or unspecified report format").
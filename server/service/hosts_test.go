@@ -19,9 +19,12 @@ import (
 
 func TestHostDetails(t *testing.T) {
 	ds := new(mock.Store)
-	svc := &Service{ds: ds}
+	svc := &Service{ds: ds, clock: clock.NewMockClock()}
 
 	host := &fleet.Host{ID: 3}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
 	expectedLabels := []*fleet.Label{
 		{
 			Name:        "foobar",
@@ -48,11 +51,30 @@ func TestHostDetails(t *testing.T) {
 	ds.ListPoliciesForHostFunc = func(ctx context.Context, host *fleet.Host) ([]*fleet.HostPolicy, error) {
 		return nil, nil
 	}
+	expectedAgentVersions := []*fleet.HostAgentVersion{
+		{Component: fleet.AgentComponentOrbit, Version: "0.0.11"},
+	}
+	ds.ListHostAgentVersionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostAgentVersion, error) {
+		return expectedAgentVersions, nil
+	}
+	expectedBatteries := []fleet.HostBattery{
+		{SerialNumber: "abc", CycleCount: 250, Health: "Good", Condition: "Normal"},
+	}
+	ds.ListHostBatteriesFunc = func(ctx context.Context, hostID uint) ([]fleet.HostBattery, error) {
+		return expectedBatteries, nil
+	}
+	ds.HostMetadataFunc = func(ctx context.Context, hostID uint) (*fleet.HostMetadata, error) {
+		return &fleet.HostMetadata{HostID: hostID, Notes: "some notes", Tags: fleet.HostTags{"owner": "alice"}}, nil
+	}
 
 	hostDetail, err := svc.getHostDetails(test.UserContext(test.UserAdmin), host)
 	require.NoError(t, err)
 	assert.Equal(t, expectedLabels, hostDetail.Labels)
 	assert.Equal(t, expectedPacks, hostDetail.Packs)
+	assert.Equal(t, expectedAgentVersions, hostDetail.AgentVersions)
+	assert.Equal(t, expectedBatteries, hostDetail.Batteries)
+	assert.Equal(t, "some notes", hostDetail.Notes)
+	assert.Equal(t, fleet.HostTags{"owner": "alice"}, hostDetail.Tags)
 }
 
 func TestHostAuth(t *testing.T) {
@@ -62,6 +84,9 @@ func TestHostAuth(t *testing.T) {
 	teamHost := &fleet.Host{TeamID: ptr.Uint(1)}
 	globalHost := &fleet.Host{}
 
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
 	ds.DeleteHostFunc = func(ctx context.Context, hid uint) error {
 		return nil
 	}
@@ -98,15 +123,45 @@ func TestHostAuth(t *testing.T) {
 	ds.AddHostsToTeamFunc = func(ctx context.Context, teamID *uint, hostIDs []uint) error {
 		return nil
 	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
 	ds.SaveHostFunc = func(ctx context.Context, host *fleet.Host) error {
 		return nil
 	}
 	ds.ListPoliciesForHostFunc = func(ctx context.Context, host *fleet.Host) ([]*fleet.HostPolicy, error) {
 		return nil, nil
 	}
+	ds.ListHostUsersFunc = func(ctx context.Context, hostID uint) ([]fleet.HostUser, error) {
+		return nil, nil
+	}
+	ds.ListHostAgentVersionsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostAgentVersion, error) {
+		return nil, nil
+	}
+	ds.ListHostBatteriesFunc = func(ctx context.Context, hostID uint) ([]fleet.HostBattery, error) {
+		return nil, nil
+	}
+	ds.HostMetadataFunc = func(ctx context.Context, hostID uint) (*fleet.HostMetadata, error) {
+		return &fleet.HostMetadata{HostID: hostID, Tags: fleet.HostTags{}}, nil
+	}
+	ds.SetHostMetadataFunc = func(ctx context.Context, metadata *fleet.HostMetadata) error {
+		return nil
+	}
+	ds.ListHostActivitiesFunc = func(ctx context.Context, hostID uint, opt fleet.ListOptions) ([]*fleet.HostActivity, error) {
+		return nil, nil
+	}
 	ds.DeleteHostsFunc = func(ctx context.Context, ids []uint) error {
 		return nil
 	}
+	ds.SetOrUpdateCustomHostDeviceMappingFunc = func(ctx context.Context, hostID uint, email string) ([]*fleet.HostDeviceMapping, error) {
+		return nil, nil
+	}
 	ds.UpdateHostRefetchRequestedFunc = func(ctx context.Context, id uint, value bool) error {
 		if id == 1 {
 			teamHost.RefetchRequested = true
@@ -203,10 +258,10 @@ func TestHostAuth(t *testing.T) {
 			err = svc.DeleteHost(ctx, 2)
 			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
 
-			err = svc.DeleteHosts(ctx, []uint{1}, fleet.HostListOptions{}, nil)
+			_, err = svc.DeleteHosts(ctx, []uint{1}, fleet.HostListOptions{}, nil)
 			checkAuthErr(t, tt.shouldFailTeamWrite, err)
 
-			err = svc.DeleteHosts(ctx, []uint{2}, fleet.HostListOptions{}, nil)
+			_, err = svc.DeleteHosts(ctx, []uint{2}, fleet.HostListOptions{}, nil)
 			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
 
 			err = svc.AddHostsToTeam(ctx, ptr.Uint(1), []uint{1})
@@ -217,25 +272,136 @@ func TestHostAuth(t *testing.T) {
 
 			err = svc.RefetchHost(ctx, 1)
 			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, err = svc.ListHostPolicies(ctx, 1)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, err = svc.ListHostPolicies(ctx, 2)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+
+			_, err = svc.ListHostUsers(ctx, 1)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, err = svc.ListHostUsers(ctx, 2)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+
+			_, err = svc.SetCustomHostDeviceMapping(ctx, 1, "a@example.com")
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			_, err = svc.SetCustomHostDeviceMapping(ctx, 2, "a@example.com")
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+
+			err = svc.ApplyHostDeviceMappings(ctx, []fleet.HostDeviceMappingPayload{{Host: "1", Email: "a@example.com"}})
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			err = svc.ApplyHostDeviceMappings(ctx, []fleet.HostDeviceMappingPayload{{Host: "2", Email: "a@example.com"}})
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+
+			_, err = svc.ModifyHostMetadata(ctx, 1, fleet.HostMetadataPayload{Notes: ptr.String("notes")})
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			_, err = svc.ModifyHostMetadata(ctx, 2, fleet.HostMetadataPayload{Notes: ptr.String("notes")})
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+
+			_, err = svc.ListHostActivities(ctx, 1, fleet.ListOptions{})
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, err = svc.ListHostActivities(ctx, 2, fleet.ListOptions{})
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
 		})
 	}
 
 	// List, GetHostSummary, FlushSeenHost work for all
 }
 
+func TestDeleteHostsByFilter(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.ListHostsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions) ([]*fleet.Host, error) {
+		assert.Equal(t, fleet.StatusMIA, opt.StatusFilter)
+		return []*fleet.Host{{ID: 1, TeamID: ptr.Uint(1)}, {ID: 2, TeamID: ptr.Uint(1)}, {ID: 3, TeamID: ptr.Uint(2)}}, nil
+	}
+	var deletedIDs []uint
+	ds.DeleteHostsFunc = func(ctx context.Context, ids []uint) error {
+		deletedIDs = ids
+		return nil
+	}
+
+	opts := fleet.HostListOptions{StatusFilter: fleet.StatusMIA}
+	deleted, err := svc.DeleteHosts(test.UserContext(test.UserAdmin), nil, opts, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+	assert.Equal(t, []uint{1, 2, 3}, deletedIDs)
+	assert.False(t, ds.HostLiteFuncInvoked)
+
+	// nothing is deleted if any of the hosts cannot be written
+	ds.DeleteHostsFuncInvoked = false
+	maintainer := &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}
+	_, err = svc.DeleteHosts(test.UserContext(maintainer), nil, opts, nil)
+	checkAuthErr(t, true, err)
+	assert.False(t, ds.DeleteHostsFuncInvoked)
+}
+
+func TestApplyHostDeviceMappings(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.HostByIdentifierFunc = func(ctx context.Context, identifier string) (*fleet.Host, error) {
+		switch identifier {
+		case "host1":
+			return &fleet.Host{ID: 1}, nil
+		case "host2":
+			return &fleet.Host{ID: 2}, nil
+		}
+		return nil, notFoundError{}
+	}
+	applied := make(map[uint]string)
+	ds.SetOrUpdateCustomHostDeviceMappingFunc = func(ctx context.Context, hostID uint, email string) ([]*fleet.HostDeviceMapping, error) {
+		applied[hostID] = email
+		return nil, nil
+	}
+
+	ctx := test.UserContext(test.UserAdmin)
+
+	// nothing is applied if any of the mappings is invalid
+	var argErr *fleet.InvalidArgumentError
+	err := svc.ApplyHostDeviceMappings(ctx, []fleet.HostDeviceMappingPayload{
+		{Host: "host1", Email: "a@example.com"},
+		{Host: "host2", Email: "Bob <b@example.com>"},
+		{Host: "host3", Email: "c@example.com"},
+	})
+	require.ErrorAs(t, err, &argErr)
+	assert.Len(t, *argErr, 2)
+	assert.False(t, ds.SetOrUpdateCustomHostDeviceMappingFuncInvoked)
+
+	// an empty email removes the mapping
+	err = svc.ApplyHostDeviceMappings(ctx, []fleet.HostDeviceMappingPayload{
+		{Host: "host1", Email: "a@example.com"},
+		{Host: "host2", Email: ""},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]string{1: "a@example.com", 2: ""}, applied)
+}
+
 func TestListHosts(t *testing.T) {
 	ds := new(mock.Store)
 	svc := newTestService(t, ds, nil, nil)
 
 	ds.ListHostsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions) ([]*fleet.Host, error) {
 		return []*fleet.Host{
-			{ID: 1},
+			{ID: 1, SeenTime: time.Now().Add(-10 * 24 * time.Hour)},
 		}, nil
 	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{HostSettings: fleet.HostSettings{MissingThresholdDays: 7}}, nil
+	}
 
 	hosts, err := svc.ListHosts(test.UserContext(test.UserAdmin), fleet.HostListOptions{})
 	require.NoError(t, err)
 	require.Len(t, hosts, 1)
+	// the status is computed with the configured thresholds
+	assert.Equal(t, fleet.StatusMissing, hosts[0].ComputedStatus)
 
 	// anyone can list hosts
 	hosts, err = svc.ListHosts(test.UserContext(test.UserNoRoles), fleet.HostListOptions{})
@@ -248,11 +414,61 @@ func TestListHosts(t *testing.T) {
 	require.Contains(t, err.Error(), authz.ForbiddenErrorMessage)
 }
 
+func TestListHostChanges(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.ListHostChangesFunc = func(ctx context.Context, filter fleet.TeamFilter, cursor fleet.HostChangesCursor, limit int) (*fleet.HostChanges, error) {
+		require.NotNil(t, filter.TeamID)
+		assert.Equal(t, uint(1), *filter.TeamID)
+		assert.True(t, filter.IncludeObserver)
+		assert.Equal(t, 10, limit)
+		return &fleet.HostChanges{
+			Cursor:  cursor,
+			Hosts:   []*fleet.Host{{ID: 1}},
+			Deleted: []*fleet.HostTombstone{{HostID: 2}},
+		}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
+	changes, err := svc.ListHostChanges(test.UserContext(test.UserAdmin), ptr.Uint(1), fleet.NewHostChangesCursor(time.Now().Add(-time.Hour)), 10)
+	require.NoError(t, err)
+	require.Len(t, changes.Hosts, 1)
+	require.Len(t, changes.Deleted, 1)
+
+	// changes older than the tombstones retention cannot be listed
+	_, err = svc.ListHostChanges(test.UserContext(test.UserAdmin), ptr.Uint(1), fleet.NewHostChangesCursor(time.Now().Add(-fleet.HostTombstoneRetention-time.Hour)), 10)
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+
+	// a user is required
+	_, err = svc.ListHostChanges(context.Background(), ptr.Uint(1), fleet.NewHostChangesCursor(time.Now().Add(-time.Hour)), 10)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), authz.ForbiddenErrorMessage)
+}
+
+func TestHostChangesCursor(t *testing.T) {
+	cursor := fleet.HostChangesCursor{
+		UpdatedAt:     time.Date(2022, 3, 24, 16, 0, 0, 0, time.UTC),
+		HostID:        1,
+		DeletedAt:     time.Date(2022, 3, 24, 15, 0, 0, 0, time.UTC),
+		DeletedHostID: 2,
+	}
+	parsed, err := fleet.ParseHostChangesCursor(cursor.String())
+	require.NoError(t, err)
+	assert.Equal(t, cursor, parsed)
+
+	_, err = fleet.ParseHostChangesCursor("2022-03-24T16:00:00Z")
+	require.Error(t, err)
+}
+
 func TestGetHostSummary(t *testing.T) {
 	ds := new(mock.Store)
 	svc := newTestService(t, ds, nil, nil)
 
-	ds.GenerateHostStatusStatisticsFunc = func(ctx context.Context, filter fleet.TeamFilter, now time.Time, platform *string) (*fleet.HostSummary, error) {
+	ds.GenerateHostStatusStatisticsFunc = func(ctx context.Context, filter fleet.TeamFilter, now time.Time, settings fleet.HostSettings, platform *string, labelID *uint) (*fleet.HostSummary, error) {
 		return &fleet.HostSummary{
 			OnlineCount:      1,
 			OfflineCount:     2,
@@ -261,8 +477,11 @@ func TestGetHostSummary(t *testing.T) {
 			TotalsHostsCount: 5,
 		}, nil
 	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
 
-	summary, err := svc.GetHostSummary(test.UserContext(test.UserAdmin), nil, nil)
+	summary, err := svc.GetHostSummary(test.UserContext(test.UserAdmin), nil, nil, nil)
 	require.NoError(t, err)
 	require.Nil(t, summary.TeamID)
 	require.Equal(t, uint(1), summary.OnlineCount)
@@ -271,11 +490,11 @@ func TestGetHostSummary(t *testing.T) {
 	require.Equal(t, uint(4), summary.NewCount)
 	require.Equal(t, uint(5), summary.TotalsHostsCount)
 
-	_, err = svc.GetHostSummary(test.UserContext(test.UserNoRoles), nil, nil)
+	_, err = svc.GetHostSummary(test.UserContext(test.UserNoRoles), nil, nil, nil)
 	require.NoError(t, err)
 
 	// a user is required
-	_, err = svc.GetHostSummary(context.Background(), nil, nil)
+	_, err = svc.GetHostSummary(context.Background(), nil, nil, nil)
 	require.Error(t, err)
 	require.Contains(t, err.Error(), authz.ForbiddenErrorMessage)
 }
@@ -318,10 +537,24 @@ func TestAddHostsToTeamByFilter(t *testing.T) {
 		assert.Equal(t, expectedHostIDs, hostIDs)
 		return nil
 	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, fleet.ActivityTypeTransferredHosts, activityType)
+		assert.Equal(t, expectedHostIDs, (*details)["host_ids"])
+		assert.Nil(t, (*details)["team_name"])
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, fleet.HostActivityTypeTransferred, activityType)
+		assert.Equal(t, expectedHostIDs, hostIDs)
+		return nil
+	}
 
 	require.NoError(t, svc.AddHostsToTeamByFilter(test.UserContext(test.UserAdmin), expectedTeam, fleet.HostListOptions{}, nil))
 	assert.True(t, ds.ListHostsFuncInvoked)
 	assert.True(t, ds.AddHostsToTeamFuncInvoked)
+	assert.True(t, ds.NewActivityFuncInvoked)
+	assert.True(t, ds.NewHostActivitiesFuncInvoked)
+	assert.False(t, ds.TeamFuncInvoked)
 }
 
 func TestAddHostsToTeamByFilterLabel(t *testing.T) {
@@ -344,10 +577,26 @@ func TestAddHostsToTeamByFilterLabel(t *testing.T) {
 		assert.Equal(t, expectedHostIDs, hostIDs)
 		return nil
 	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		assert.Equal(t, *expectedTeam, tid)
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, fleet.ActivityTypeTransferredHosts, activityType)
+		assert.Equal(t, expectedTeam, (*details)["team_id"])
+		assert.Equal(t, "team1", *(*details)["team_name"].(*string))
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, fleet.HostActivityTypeTransferred, activityType)
+		assert.Equal(t, expectedHostIDs, hostIDs)
+		return nil
+	}
 
 	require.NoError(t, svc.AddHostsToTeamByFilter(test.UserContext(test.UserAdmin), expectedTeam, fleet.HostListOptions{}, expectedLabel))
 	assert.True(t, ds.ListHostsInLabelFuncInvoked)
 	assert.True(t, ds.AddHostsToTeamFuncInvoked)
+	assert.True(t, ds.NewActivityFuncInvoked)
 }
 
 func TestAddHostsToTeamByFilterEmptyHosts(t *testing.T) {
@@ -364,6 +613,7 @@ func TestAddHostsToTeamByFilterEmptyHosts(t *testing.T) {
 	require.NoError(t, svc.AddHostsToTeamByFilter(test.UserContext(test.UserAdmin), nil, fleet.HostListOptions{}, nil))
 	assert.True(t, ds.ListHostsFuncInvoked)
 	assert.False(t, ds.AddHostsToTeamFuncInvoked)
+	assert.False(t, ds.NewActivityFuncInvoked)
 }
 
 func TestRefetchHost(t *testing.T) {
@@ -80,7 +80,7 @@ func (s *integrationTestSuite) TearDownTest() {
 		require.NoError(t, err)
 	}
 
-	globalPolicies, err := s.ds.ListGlobalPolicies(ctx)
+	globalPolicies, err := s.ds.ListGlobalPolicies(ctx, fleet.ListOptions{})
 	require.NoError(t, err)
 	if len(globalPolicies) > 0 {
 		var globalPolicyIDs []uint
@@ -287,7 +287,7 @@ func (s *integrationTestSuite) TestAppConfigAdditionalQueriesCanBeRemoved() {
 	spec := []byte(`
   host_expiry_settings:
     host_expiry_enabled: true
-    host_expiry_window: 0
+    host_expiry_window: 10
   host_settings:
     additional_queries:
       time: SELECT * FROM time
@@ -682,6 +682,7 @@ func (s *integrationTestSuite) TestBulkDeleteHostsFromTeam() {
 	}
 	resp := deleteHostsResponse{}
 	s.DoJSON("POST", "/api/v1/fleet/hosts/delete", req, http.StatusOK, &resp)
+	assert.Equal(t, 1, resp.Deleted)
 
 	_, err = s.ds.Host(context.Background(), hosts[0].ID, false)
 	require.Error(t, err)
@@ -724,6 +725,7 @@ func (s *integrationTestSuite) TestBulkDeleteHostsInLabel() {
 	}
 	resp := deleteHostsResponse{}
 	s.DoJSON("POST", "/api/v1/fleet/hosts/delete", req, http.StatusOK, &resp)
+	assert.Equal(t, 2, resp.Deleted)
 
 	_, err = s.ds.Host(context.Background(), hosts[0].ID, false)
 	require.NoError(t, err)
@@ -746,6 +748,7 @@ func (s *integrationTestSuite) TestBulkDeleteHostByIDs() {
 	}
 	resp := deleteHostsResponse{}
 	s.DoJSON("POST", "/api/v1/fleet/hosts/delete", req, http.StatusOK, &resp)
+	assert.Equal(t, 2, resp.Deleted)
 
 	_, err := s.ds.Host(context.Background(), hosts[0].ID, false)
 	require.Error(t, err)
@@ -1283,6 +1286,22 @@ func (s *integrationTestSuite) TestGetHostSummary() {
 	s.DoJSON("GET", "/api/v1/fleet/host_summary", nil, http.StatusOK, &resp, "platform", "darwin")
 	require.Equal(t, resp.TotalsHostsCount, uint(0))
 	require.Len(t, resp.Platforms, 0)
+
+	// label filter
+	label, err := s.ds.NewLabel(context.Background(), &fleet.Label{Name: t.Name() + "label1", Query: "select 1"})
+	require.NoError(t, err)
+	require.NoError(t, s.ds.RecordLabelQueryExecutions(context.Background(), hosts[1], map[uint]*bool{label.ID: ptr.Bool(true)}, time.Now(), false))
+
+	resp = getHostSummaryResponse{}
+	s.DoJSON("GET", "/api/v1/fleet/host_summary", nil, http.StatusOK, &resp, "label_id", fmt.Sprint(label.ID))
+	require.Equal(t, resp.TotalsHostsCount, uint(1))
+	require.Len(t, resp.Platforms, 1)
+	require.Equal(t, hosts[1].Platform, resp.Platforms[0].Platform)
+	require.Equal(t, label.ID, *resp.LabelID)
+
+	resp = getHostSummaryResponse{}
+	s.DoJSON("GET", "/api/v1/fleet/host_summary", nil, http.StatusOK, &resp, "label_id", fmt.Sprint(label.ID), "team_id", fmt.Sprint(team1.ID))
+	require.Equal(t, resp.TotalsHostsCount, uint(0))
 }
 
 func (s *integrationTestSuite) TestGlobalPoliciesProprietary() {
@@ -1887,6 +1906,16 @@ func (s *integrationTestSuite) TestHostsAddToTeam() {
 	require.NotNil(t, getResp.Host.TeamID)
 	require.Equal(t, tm2.ID, *getResp.Host.TeamID)
 
+	// the transfers are recorded as activities
+	var actResp listActivitiesResponse
+	s.DoJSON("GET", "/api/v1/fleet/activities", nil, http.StatusOK, &actResp, "per_page", "2", "order_key", "id", "order_direction", "desc")
+	require.Len(t, actResp.Activities, 2)
+	for _, act := range actResp.Activities {
+		assert.Equal(t, fleet.ActivityTypeTransferredHosts, act.Type)
+	}
+	require.NotNil(t, actResp.Activities[0].Details)
+	assert.JSONEq(t, fmt.Sprintf(`{"team_id": %d, "team_name": %q, "host_ids": [%d]}`, tm2.ID, tm2.Name, hosts[2].ID), string(*actResp.Activities[0].Details))
+
 	// delete host 0
 	var delResp deleteHostResponse
 	s.DoJSON("DELETE", fmt.Sprintf("/api/v1/fleet/hosts/%d", hosts[0].ID), nil, http.StatusOK, &delResp)
@@ -1959,18 +1988,18 @@ func (s *integrationTestSuite) TestScheduledQueries() {
 	reqSQ := &scheduleQueryRequest{
 		PackID:   pack.ID,
 		QueryID:  query.ID,
-		Interval: 1,
+		Interval: 10,
 	}
 	s.DoJSON("POST", "/api/v1/fleet/schedule", reqSQ, http.StatusOK, &createResp)
 	sq1 := createResp.Scheduled.ScheduledQuery
 	assert.NotZero(t, sq1.ID)
-	assert.Equal(t, uint(1), sq1.Interval)
+	assert.Equal(t, uint(10), sq1.Interval)
 
 	// create scheduled query with invalid pack
 	reqSQ = &scheduleQueryRequest{
 		PackID:   pack.ID + 1,
 		QueryID:  query.ID,
-		Interval: 2,
+		Interval: 20,
 	}
 	s.DoJSON("POST", "/api/v1/fleet/schedule", reqSQ, http.StatusUnprocessableEntity, &createResp)
 
@@ -1978,10 +2007,18 @@ func (s *integrationTestSuite) TestScheduledQueries() {
 	reqSQ = &scheduleQueryRequest{
 		PackID:   pack.ID,
 		QueryID:  query.ID + 1,
-		Interval: 3,
+		Interval: 30,
 	}
 	s.DoJSON("POST", "/api/v1/fleet/schedule", reqSQ, http.StatusNotFound, &createResp)
 
+	// create scheduled query with an interval below the minimum
+	reqSQ = &scheduleQueryRequest{
+		PackID:   pack.ID,
+		QueryID:  query.ID,
+		Interval: 1,
+	}
+	s.DoJSON("POST", "/api/v1/fleet/schedule", reqSQ, http.StatusUnprocessableEntity, &createResp)
+
 	// list scheduled queries in pack
 	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/packs/%d/scheduled", pack.ID), nil, http.StatusOK, &getInPackResp)
 	require.Len(t, getInPackResp.Scheduled, 1)
@@ -2003,15 +2040,21 @@ func (s *integrationTestSuite) TestScheduledQueries() {
 	// modify scheduled query
 	var modResp modifyScheduledQueryResponse
 	reqMod := fleet.ScheduledQueryPayload{
-		Interval: ptr.Uint(4),
+		Interval: ptr.Uint(40),
 	}
 	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/schedule/%d", sq1.ID), reqMod, http.StatusOK, &modResp)
 	assert.Equal(t, sq1.ID, modResp.Scheduled.ID)
-	assert.Equal(t, uint(4), modResp.Scheduled.Interval)
+	assert.Equal(t, uint(40), modResp.Scheduled.Interval)
+
+	// modify scheduled query with an interval below the minimum
+	reqMod = fleet.ScheduledQueryPayload{
+		Interval: ptr.Uint(1),
+	}
+	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/schedule/%d", sq1.ID), reqMod, http.StatusUnprocessableEntity, &modResp)
 
 	// modify non-existing scheduled query
 	reqMod = fleet.ScheduledQueryPayload{
-		Interval: ptr.Uint(5),
+		Interval: ptr.Uint(50),
 	}
 	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/schedule/%d", sq1.ID+1), reqMod, http.StatusNotFound, &modResp)
 
@@ -2078,6 +2121,119 @@ func (s *integrationTestSuite) TestScheduledQueries() {
 	assert.Equal(t, uint(0), delBatchResp.Deleted)
 }
 
+func (s *integrationTestSuite) TestHostActivities() {
+	t := s.T()
+	ctx := context.Background()
+
+	host, err := s.ds.EnrollHost(ctx, t.Name(), t.Name()+"key", nil, 0, "")
+	require.NoError(t, err)
+	tm, err := s.ds.NewTeam(ctx, &fleet.Team{Name: t.Name()})
+	require.NoError(t, err)
+
+	var addResp addHostsToTeamResponse
+	s.DoJSON("POST", "/api/v1/fleet/hosts/transfer", addHostsToTeamRequest{
+		TeamID:  &tm.ID,
+		HostIDs: []uint{host.ID},
+	}, http.StatusOK, &addResp)
+
+	var listResp listHostActivitiesResponse
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/hosts/%d/activities", host.ID), nil, http.StatusOK, &listResp)
+	assert.Equal(t, host.ID, listResp.HostID)
+	require.Len(t, listResp.Activities, 2)
+	assert.Equal(t, fleet.HostActivityTypeTransferred, listResp.Activities[0].Type)
+	require.NotNil(t, listResp.Activities[0].ActorFullName)
+	assert.Equal(t, fleet.HostActivityTypeEnrolled, listResp.Activities[1].Type)
+	assert.Nil(t, listResp.Activities[1].ActorID)
+
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/hosts/%d/activities", host.ID), nil, http.StatusOK, &listResp, "order_key", "id", "order_direction", "asc", "per_page", "1")
+	require.Len(t, listResp.Activities, 1)
+	assert.Equal(t, fleet.HostActivityTypeEnrolled, listResp.Activities[0].Type)
+
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/hosts/%d/activities", host.ID+1000), nil, http.StatusNotFound, &listResp)
+}
+
+func (s *integrationTestSuite) TestDuplicateHosts() {
+	t := s.T()
+	ctx := context.Background()
+
+	uuid := t.Name() + "uuid"
+	var hostIDs []uint
+	for i := 0; i < 3; i++ {
+		host, err := s.ds.NewHost(ctx, &fleet.Host{
+			DetailUpdatedAt: time.Now(),
+			LabelUpdatedAt:  time.Now(),
+			PolicyUpdatedAt: time.Now(),
+			SeenTime:        time.Now().Add(time.Duration(i) * time.Minute),
+			NodeKey:         fmt.Sprintf("%s%dkey", t.Name(), i),
+			OsqueryHostID:   fmt.Sprintf("%s%d", t.Name(), i),
+			UUID:            uuid,
+			Hostname:        fmt.Sprintf("%s%d", t.Name(), i),
+		})
+		require.NoError(t, err)
+		hostIDs = append(hostIDs, host.ID)
+	}
+
+	var listResp listDuplicateHostsResponse
+	s.DoJSON("GET", "/api/v1/fleet/hosts/duplicates", nil, http.StatusOK, &listResp)
+	var group *fleet.DuplicateHosts
+	for _, g := range listResp.Duplicates {
+		if g.UUID == uuid {
+			group = g
+		}
+	}
+	require.NotNil(t, group)
+	require.Len(t, group.Hosts, 3)
+	// the most recently seen host first
+	assert.Equal(t, hostIDs[2], group.Hosts[0].ID)
+
+	var mergeResp mergeHostsResponse
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/hosts/%d/merge", hostIDs[2]), mergeHostsRequest{HostIDs: []uint{hostIDs[2]}}, http.StatusUnprocessableEntity, &mergeResp)
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/hosts/%d/merge", hostIDs[2]), mergeHostsRequest{HostIDs: []uint{hostIDs[0] + 1000}}, http.StatusNotFound, &mergeResp)
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/hosts/%d/merge", hostIDs[2]), mergeHostsRequest{HostIDs: hostIDs[:2]}, http.StatusOK, &mergeResp)
+
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/hosts/%d", hostIDs[0]), nil, http.StatusNotFound, &getHostResponse{})
+	var activitiesResp listHostActivitiesResponse
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/hosts/%d/activities", hostIDs[2]), nil, http.StatusOK, &activitiesResp)
+	require.NotEmpty(t, activitiesResp.Activities)
+	assert.Equal(t, fleet.HostActivityTypeMerged, activitiesResp.Activities[0].Type)
+
+	s.DoJSON("GET", "/api/v1/fleet/hosts/duplicates", nil, http.StatusOK, &listResp)
+	for _, g := range listResp.Duplicates {
+		assert.NotEqual(t, uuid, g.UUID)
+	}
+}
+
+func (s *integrationTestSuite) TestHostMetadata() {
+	t := s.T()
+
+	hosts := s.createHosts(t)
+
+	var modResp modifyHostMetadataResponse
+	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/hosts/%d/metadata", hosts[2].ID+1), fleet.HostMetadataPayload{Notes: ptr.String("notes")}, http.StatusNotFound, &modResp)
+	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/hosts/%d/metadata", hosts[0].ID), fleet.HostMetadataPayload{Tags: fleet.HostTags{"": "x"}}, http.StatusUnprocessableEntity, &modResp)
+
+	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/hosts/%d/metadata", hosts[0].ID), fleet.HostMetadataPayload{
+		Notes: ptr.String("in the server room"),
+		Tags:  fleet.HostTags{"owner": "alice", "cost_center": "42"},
+	}, http.StatusOK, &modResp)
+	require.NotNil(t, modResp.Metadata)
+	assert.Equal(t, "in the server room", modResp.Metadata.Notes)
+
+	// the notes are left unchanged when only the tags are provided
+	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/hosts/%d/metadata", hosts[0].ID), fleet.HostMetadataPayload{
+		Tags: fleet.HostTags{"owner": "bob"},
+	}, http.StatusOK, &modResp)
+
+	var getResp getHostResponse
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/hosts/%d", hosts[0].ID), nil, http.StatusOK, &getResp)
+	assert.Equal(t, "in the server room", getResp.Host.Notes)
+	assert.Equal(t, fleet.HostTags{"owner": "bob"}, getResp.Host.Tags)
+
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/hosts/%d", hosts[1].ID), nil, http.StatusOK, &getResp)
+	assert.Empty(t, getResp.Host.Notes)
+	assert.Empty(t, getResp.Host.Tags)
+}
+
 func (s *integrationTestSuite) TestHostDeviceMapping() {
 	t := s.T()
 	ctx := context.Background()
@@ -2368,6 +2524,43 @@ func (s *integrationTestSuite) TestLabels() {
 	}
 }
 
+func (s *integrationTestSuite) TestManualLabelHosts() {
+	t := s.T()
+
+	hosts := s.createHosts(t)
+
+	// a manual label does not require a query
+	var createResp createLabelResponse
+	s.DoJSON("POST", "/api/v1/fleet/labels", &fleet.LabelPayload{Name: ptr.String(t.Name()), LabelMembershipType: fleet.LabelMembershipTypeManual}, http.StatusOK, &createResp)
+	manual := createResp.Label.Label
+	assert.Equal(t, fleet.LabelMembershipTypeManual, manual.LabelMembershipType)
+	s.DoJSON("POST", "/api/v1/fleet/labels", &fleet.LabelPayload{Name: ptr.String(t.Name() + "dynamic"), Query: ptr.String("select 1")}, http.StatusOK, &createResp)
+	dynamic := createResp.Label.Label
+
+	listIDs := func() []uint {
+		var listResp listHostsResponse
+		s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/labels/%d/hosts", manual.ID), nil, http.StatusOK, &listResp)
+		var ids []uint
+		for _, h := range listResp.Hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	var addResp addHostsToLabelResponse
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/labels/%d/hosts", manual.ID), addHostsToLabelRequest{HostIDs: []uint{hosts[0].ID, hosts[1].ID}}, http.StatusOK, &addResp)
+	assert.ElementsMatch(t, []uint{hosts[0].ID, hosts[1].ID}, listIDs())
+
+	var removeResp removeHostsFromLabelResponse
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/labels/%d/hosts/delete", manual.ID), removeHostsFromLabelRequest{HostIDs: []uint{hosts[0].ID}}, http.StatusOK, &removeResp)
+	assert.Equal(t, []uint{hosts[1].ID}, listIDs())
+
+	// the hosts of a dynamic label cannot be set
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/labels/%d/hosts", dynamic.ID), addHostsToLabelRequest{HostIDs: []uint{hosts[0].ID}}, http.StatusUnprocessableEntity, &addResp)
+	// unknown label
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/labels/%d/hosts", dynamic.ID+1000), addHostsToLabelRequest{HostIDs: []uint{hosts[0].ID}}, http.StatusNotFound, &addResp)
+}
+
 func (s *integrationTestSuite) TestLabelSpecs() {
 	t := s.T()
 
@@ -2617,7 +2810,8 @@ func (s *integrationTestSuite) TestGlobalPoliciesAutomationConfig() {
      	 		"enable_failing_policies_webhook": true,
      	 		"destination_url": "http://some/url",
      			"policy_ids": [%d],
-				"host_batch_size": 1000
+				"host_batch_size": 1000,
+				"max_hosts": 5000
     		},
     		"interval": "1h"
   		}
@@ -2629,6 +2823,7 @@ func (s *integrationTestSuite) TestGlobalPoliciesAutomationConfig() {
 	require.Equal(t, []uint{gpResp.Policy.ID}, config.WebhookSettings.FailingPoliciesWebhook.PolicyIDs)
 	require.Equal(t, 1*time.Hour, config.WebhookSettings.Interval.Duration)
 	require.Equal(t, 1000, config.WebhookSettings.FailingPoliciesWebhook.HostBatchSize)
+	require.Equal(t, 5000, config.WebhookSettings.FailingPoliciesWebhook.MaxHosts)
 
 	deletePolicyParams := deleteGlobalPoliciesRequest{IDs: []uint{gpResp.Policy.ID}}
 	deletePolicyResp := deleteGlobalPoliciesResponse{}
@@ -2758,6 +2953,66 @@ func (s *integrationTestSuite) TestIntegrationsConfig() {
       }]
     }
   }`), http.StatusUnprocessableEntity)
+
+	// enable jira for the failing policies
+	s.DoRaw("PATCH", "/api/v1/fleet/config", []byte(`{
+    "integrations": {
+      "jira": [{
+        "url": "http://some/url",
+        "username": "foo",
+        "password": "bar",
+        "project_key": "qux",
+        "issue_type": "Bug",
+        "enable_failing_policies": true
+      }]
+    },
+    "webhook_settings": {
+      "failing_policies_webhook": {
+        "enable_failing_policies_webhook": false
+      }
+    }
+  }`), http.StatusOK)
+
+	config = s.getConfig()
+	require.Len(t, config.Integrations.Jira, 1)
+	require.Equal(t, "Bug", config.Integrations.Jira[0].IssueType)
+	require.True(t, config.Integrations.Jira[0].EnableFailingPolicies)
+	require.NotNil(t, config.Integrations.FailingPoliciesJira())
+
+	// cannot enable the failing policies webhook with jira enabled
+	s.DoRaw("PATCH", "/api/v1/fleet/config", []byte(`{
+    "webhook_settings": {
+      "failing_policies_webhook": {
+        "enable_failing_policies_webhook": true,
+        "destination_url": "http://some/url"
+      }
+    }
+  }`), http.StatusUnprocessableEntity)
+
+	// the project key is required
+	s.DoRaw("PATCH", "/api/v1/fleet/config", []byte(`{
+    "integrations": {
+      "jira": [{
+        "url": "http://some/url",
+        "username": "foo",
+        "password": "bar",
+        "enable_failing_policies": true
+      }]
+    }
+  }`), http.StatusUnprocessableEntity)
+
+	// disable jira for the failing policies
+	s.DoRaw("PATCH", "/api/v1/fleet/config", []byte(`{
+    "integrations": {
+      "jira": [{
+        "url": "http://some/url",
+        "username": "foo",
+        "password": "bar",
+        "project_key": "qux",
+        "enable_failing_policies": false
+      }]
+    }
+  }`), http.StatusOK)
 }
 
 func (s *integrationTestSuite) TestQueriesBadRequests() {
@@ -3371,6 +3626,39 @@ func (s *integrationTestSuite) TestOsqueryConfig() {
 	assert.Contains(t, errRes["error"], "invalid node key")
 }
 
+func (s *integrationTestSuite) TestOsqueryFlags() {
+	t := s.T()
+
+	var acResp appConfigResponse
+	s.DoJSON("GET", "/api/v1/fleet/config", nil, http.StatusOK, &acResp)
+	require.NotNil(t, acResp.AgentOptions)
+	origAgentOptions := *acResp.AgentOptions
+	t.Cleanup(func() {
+		s.DoRaw("PATCH", "/api/v1/fleet/config", []byte(fmt.Sprintf(`{"agent_options":%s}`, origAgentOptions)), http.StatusOK)
+	})
+
+	s.DoRaw("PATCH", "/api/v1/fleet/config", []byte(`{
+		"agent_options": {"config": {"options": {"distributed_interval": 10}}, "command_line_flags": {"enable_file_events": true}}
+	}`), http.StatusOK)
+
+	hosts := s.createHosts(t)
+	req := getClientFlagsRequest{NodeKey: hosts[0].NodeKey}
+	var resp getClientFlagsResponse
+	s.DoJSON("POST", "/api/v1/osquery/flags", req, http.StatusOK, &resp)
+	assert.JSONEq(t, `{"enable_file_events":true}`, string(resp.CommandLineFlags))
+
+	// unknown flags are rejected
+	s.DoRaw("PATCH", "/api/v1/fleet/config", []byte(`{
+		"agent_options": {"command_line_flags": {"enable_file_event": true}}
+	}`), http.StatusUnprocessableEntity)
+
+	// test with invalid node key
+	var errRes map[string]interface{}
+	req.NodeKey += "zzzz"
+	s.DoJSON("POST", "/api/v1/osquery/flags", req, http.StatusUnauthorized, &errRes)
+	assert.Contains(t, errRes["error"], "invalid node key")
+}
+
 func (s *integrationTestSuite) TestEnrollHost() {
 	t := s.T()
 
@@ -3709,6 +3997,73 @@ func (s *integrationTestSuite) TestDeviceAuthenticatedEndpoints() {
 	res.Body.Close()
 }
 
+func (s *integrationTestSuite) TestOrbitPolicyRemediations() {
+	t := s.T()
+	ctx := context.Background()
+
+	hosts := s.createHosts(t)
+	token := "orbit_device_token"
+	require.NoError(t, s.ds.SetOrUpdateDeviceAuthToken(ctx, hosts[0].ID, token))
+	require.NoError(t, s.ds.ApplyEnrollSecrets(ctx, nil, []*fleet.EnrollSecret{{Secret: "orbit_secret"}}))
+	t.Cleanup(func() {
+		require.NoError(t, s.ds.ApplyEnrollSecrets(ctx, nil, nil))
+	})
+
+	policy, err := s.ds.NewGlobalPolicy(ctx, nil, fleet.PolicyPayload{
+		Name:              "orbit remediation",
+		Query:             "SELECT 1",
+		RemediationScript: "echo fix",
+	})
+	require.NoError(t, err)
+	remediation, err := s.ds.NewHostPolicyRemediation(ctx, hosts[0].ID, policy.ID, nil)
+	require.NoError(t, err)
+
+	// orbit cannot enroll without a valid enroll secret, nor for an unknown device
+	res := s.DoRawNoAuth("POST", "/api/v1/fleet/orbit/enroll", jsonMustMarshal(t, enrollOrbitRequest{EnrollSecret: "invalid", DeviceAuthToken: token}), http.StatusUnauthorized)
+	res.Body.Close()
+	res = s.DoRawNoAuth("POST", "/api/v1/fleet/orbit/enroll", jsonMustMarshal(t, enrollOrbitRequest{EnrollSecret: "orbit_secret", DeviceAuthToken: "no_such_token"}), http.StatusUnauthorized)
+	res.Body.Close()
+
+	var enrollResp enrollOrbitResponse
+	res = s.DoRawNoAuth("POST", "/api/v1/fleet/orbit/enroll", jsonMustMarshal(t, enrollOrbitRequest{EnrollSecret: "orbit_secret", DeviceAuthToken: token}), http.StatusOK)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&enrollResp))
+	res.Body.Close()
+	require.NotEmpty(t, enrollResp.OrbitNodeKey)
+
+	// the device token lists the pending remediations
+	var listResp listDevicePolicyRemediationsResponse
+	res = s.DoRawNoAuth("GET", "/api/v1/fleet/device/"+token+"/policy_remediations", nil, http.StatusOK)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&listResp))
+	res.Body.Close()
+	require.Len(t, listResp.Remediations, 1)
+
+	// but cannot set their results
+	res = s.DoRawNoAuth("POST", fmt.Sprintf("/api/v1/fleet/device/%s/policy_remediations/%d/result", token, remediation.ID), []byte(`{"exit_code": 0}`), http.StatusNotFound)
+	res.Body.Close()
+
+	// orbit lists them and sets their results with its node key
+	res = s.DoRawNoAuth("POST", "/api/v1/fleet/orbit/policy_remediations", jsonMustMarshal(t, listOrbitPolicyRemediationsRequest{OrbitNodeKey: "invalid"}), http.StatusUnauthorized)
+	res.Body.Close()
+	listResp = listDevicePolicyRemediationsResponse{}
+	res = s.DoRawNoAuth("POST", "/api/v1/fleet/orbit/policy_remediations", jsonMustMarshal(t, listOrbitPolicyRemediationsRequest{OrbitNodeKey: enrollResp.OrbitNodeKey}), http.StatusOK)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&listResp))
+	res.Body.Close()
+	require.Len(t, listResp.Remediations, 1)
+	require.Equal(t, remediation.ID, listResp.Remediations[0].ID)
+
+	resultPath := fmt.Sprintf("/api/v1/fleet/orbit/policy_remediations/%d/result", remediation.ID)
+	res = s.DoRawNoAuth("POST", resultPath, jsonMustMarshal(t, setOrbitPolicyRemediationResultRequest{OrbitNodeKey: "invalid"}), http.StatusUnauthorized)
+	res.Body.Close()
+	res = s.DoRawNoAuth("POST", resultPath, jsonMustMarshal(t, setOrbitPolicyRemediationResultRequest{OrbitNodeKey: enrollResp.OrbitNodeKey, Output: "fixed"}), http.StatusOK)
+	res.Body.Close()
+
+	listResp = listDevicePolicyRemediationsResponse{}
+	res = s.DoRawNoAuth("POST", "/api/v1/fleet/orbit/policy_remediations", jsonMustMarshal(t, listOrbitPolicyRemediationsRequest{OrbitNodeKey: enrollResp.OrbitNodeKey}), http.StatusOK)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&listResp))
+	res.Body.Close()
+	require.Empty(t, listResp.Remediations)
+}
+
 func (s *integrationTestSuite) TestModifyUser() {
 	t := s.T()
 
@@ -373,6 +373,19 @@ func (s *integrationEnterpriseTestSuite) TestTeamEndpoints() {
 	tmResp.Team = nil
 	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/teams/%d", tm1ID+1), team, http.StatusNotFound, &tmResp)
 
+	// modify team log settings
+	tmResp.Team = nil
+	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/teams/%d", tm1ID), fleet.TeamPayload{LogSettings: &fleet.TeamLogSettings{ResultLogPlugin: "splunk"}}, http.StatusOK, &tmResp)
+	require.NotNil(t, tmResp.Team.Config.LogSettings)
+	assert.Equal(t, "splunk", tmResp.Team.Config.LogSettings.ResultLogPlugin)
+	logSettings, err := s.ds.TeamLogSettings(context.Background(), tm1ID)
+	require.NoError(t, err)
+	assert.Equal(t, "splunk", logSettings.ResultLogPlugin)
+
+	// modify team log settings - unknown plugin
+	tmResp.Team = nil
+	s.DoJSON("PATCH", fmt.Sprintf("/api/v1/fleet/teams/%d", tm1ID), fleet.TeamPayload{LogSettings: &fleet.TeamLogSettings{ResultLogPlugin: "nope"}}, http.StatusUnprocessableEntity, &tmResp)
+
 	// list team users
 	var usersResp listUsersResponse
 	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/teams/%d/users", tm1ID), nil, http.StatusOK, &usersResp)
@@ -389,7 +402,7 @@ func (s *integrationEnterpriseTestSuite) TestTeamEndpoints() {
 		GlobalRole: ptr.String("observer"),
 	}
 	require.NoError(t, user.SetPassword("foobar123#", 10, 10))
-	user, err := s.ds.NewUser(context.Background(), user)
+	user, err = s.ds.NewUser(context.Background(), user)
 	require.NoError(t, err)
 
 	// add a team user
@@ -439,6 +452,11 @@ func (s *integrationEnterpriseTestSuite) TestTeamEndpoints() {
 	require.NoError(t, json.Unmarshal(*tmResp.Team.Config.AgentOptions, &m))
 	assert.Equal(t, opts, m)
 
+	// modify team agent options - unknown osquery option
+	tmResp.Team = nil
+	badOpts := json.RawMessage(`{"config": {"options": {"distributed_intervall": 10}}}`)
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/teams/%d/agent_options", tm1ID), badOpts, http.StatusUnprocessableEntity, &tmResp)
+
 	// modify team agent options - unknown team
 	tmResp.Team = nil
 	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/teams/%d/agent_options", tm1ID+1), opts, http.StatusNotFound, &tmResp)
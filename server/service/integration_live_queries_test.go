@@ -425,6 +425,10 @@ func (s *liveQueriesTestSuite) TestCreateDistributedQueryCampaign() {
 	// create with unknown query
 	s.DoJSON("POST", "/api/v1/fleet/queries/run", createDistributedQueryCampaignRequest{QueryID: ptr.Uint(9999)}, http.StatusNotFound, &createResp)
 
+	// create with invalid label expressions
+	s.DoJSON("POST", "/api/v1/fleet/queries/run", createDistributedQueryCampaignRequest{QuerySQL: "SELECT 1", Selected: fleet.HostTargets{LabelExpression: `label:"All Hosts" AND`}}, http.StatusUnprocessableEntity, &createResp)
+	s.DoJSON("POST", "/api/v1/fleet/queries/run", createDistributedQueryCampaignRequest{QuerySQL: "SELECT 1", Selected: fleet.HostTargets{LabelExpression: `label:"All Hosts" AND NOT label:nosuchlabel`}}, http.StatusUnprocessableEntity, &createResp)
+
 	// create with new query
 	s.DoJSON("POST", "/api/v1/fleet/queries/run", createDistributedQueryCampaignRequest{QuerySQL: "SELECT 1"}, http.StatusOK, &createResp)
 	assert.NotZero(t, createResp.Campaign.ID)
@@ -439,6 +443,50 @@ func (s *liveQueriesTestSuite) TestCreateDistributedQueryCampaign() {
 	s.DoJSON("POST", "/api/v1/fleet/queries/run", createDistributedQueryCampaignRequest{QuerySQL: "SELECT 2", Selected: fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}}}, http.StatusOK, &createResp)
 	assert.NotEqual(t, camp1.ID, createResp.Campaign.ID)
 	assert.Equal(t, uint(2), createResp.Campaign.Metrics.TotalHosts)
+	camp2 := *createResp.Campaign
+
+	// get the progress of the campaign, no host responded yet
+	var progressResp getDistributedQueryCampaignProgressResponse
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/queries/run/%d/progress", camp2.ID), nil, http.StatusOK, &progressResp)
+	require.NotNil(t, progressResp.Progress)
+	assert.Equal(t, uint(2), progressResp.Progress.TargetedHosts)
+	assert.Equal(t, uint(2), progressResp.Progress.PendingHosts)
+	assert.Zero(t, progressResp.Progress.RespondedHosts)
+
+	// get the progress of an unknown campaign
+	s.DoJSON("GET", "/api/v1/fleet/queries/run/9999/progress", nil, http.StatusNotFound, &progressResp)
+
+	// wait a second to prevent duplicate name for new query
+	time.Sleep(time.Second)
+
+	// rerun the campaign, with the same query and targets
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/queries/run/%d/rerun", camp2.ID), nil, http.StatusOK, &createResp)
+	assert.NotEqual(t, camp2.ID, createResp.Campaign.ID)
+	assert.Equal(t, uint(2), createResp.Campaign.Metrics.TotalHosts)
+	targets, err := s.ds.DistributedQueryCampaignTargetIDs(context.Background(), createResp.Campaign.ID)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{h1.ID, h2.ID}, targets.HostIDs)
+	query, err := s.ds.Query(context.Background(), createResp.Campaign.QueryID)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT 2", query.Query)
+
+	// rerun an unknown campaign
+	s.DoJSON("POST", "/api/v1/fleet/queries/run/9999/rerun", nil, http.StatusNotFound, &createResp)
+
+	// remove a host from the targets of the campaign
+	s.lq.On("RemoveHostsFromQuery", fmt.Sprint(camp2.ID), []uint{h2.ID}).Return(nil)
+	var removeResp removeDistributedQueryCampaignTargetsResponse
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/queries/run/%d/targets/delete", camp2.ID), map[string]interface{}{"hosts": []uint{h2.ID}}, http.StatusOK, &removeResp)
+	assert.Equal(t, uint(1), removeResp.RemovedHosts)
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/queries/run/%d/progress", camp2.ID), nil, http.StatusOK, &progressResp)
+	assert.Equal(t, uint(1), progressResp.Progress.TargetedHosts)
+	targets, err = s.ds.DistributedQueryCampaignTargetIDs(context.Background(), camp2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{h2.ID}, targets.ExcludedHostIDs)
+
+	// remove no targets, or from an unknown campaign
+	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/queries/run/%d/targets/delete", camp2.ID), map[string]interface{}{}, http.StatusUnprocessableEntity, &removeResp)
+	s.DoJSON("POST", "/api/v1/fleet/queries/run/9999/targets/delete", map[string]interface{}{"hosts": []uint{h2.ID}}, http.StatusNotFound, &removeResp)
 
 	// wait a second to prevent duplicate name for new query
 	time.Sleep(time.Second)
@@ -457,6 +505,16 @@ func (s *liveQueriesTestSuite) TestCreateDistributedQueryCampaign() {
 	s.DoJSON("POST", "/api/v1/fleet/queries/run_by_names", createDistributedQueryCampaignByNamesRequest{
 		QuerySQL: "SELECT 3", Selected: distributedQueryCampaignTargetsByNames{Hosts: []string{h1.Hostname + "ZZZZZ"}}},
 		http.StatusOK, &createResp)
+
+	// the campaigns are in the audit log, most recent first
+	var auditResp listLiveQueryAuditLogResponse
+	s.DoJSON("GET", "/api/v1/fleet/queries/audit", nil, http.StatusOK, &auditResp)
+	require.NotEmpty(t, auditResp.AuditLog)
+	assert.Equal(t, createResp.Campaign.ID, auditResp.AuditLog[0].CampaignID)
+	assert.Equal(t, "SELECT 3", auditResp.AuditLog[0].QuerySQL)
+	assert.Zero(t, auditResp.AuditLog[0].TargetedHosts)
+
+	s.DoJSON("GET", "/api/v1/fleet/queries/audit", nil, http.StatusUnprocessableEntity, &auditResp, "start_date", "2022-13-01")
 }
 
 func (s *liveQueriesTestSuite) TestOsqueryDistributedRead() {
@@ -476,3 +534,61 @@ func (s *liveQueriesTestSuite) TestOsqueryDistributedRead() {
 	s.DoJSON("POST", "/api/v1/osquery/distributed/read", req, http.StatusUnauthorized, &errRes)
 	assert.Contains(t, errRes["error"], "invalid node key")
 }
+
+func (s *liveQueriesTestSuite) TestBatchQuery() {
+	t := s.T()
+
+	h1 := s.hosts[0]
+	h2 := s.hosts[1]
+	s.lq.On("RunQuery", mock.Anything, "SELECT 1 FROM batch", []uint{h1.ID, h2.ID}).Return(nil)
+	s.lq.On("QueryCompletedByHost", mock.Anything, h1.ID).Return(nil)
+
+	var createResp batchQueryResponse
+	s.DoJSON("POST", "/api/v1/fleet/queries/batch", createBatchQueryRequest{QuerySQL: "SELECT 1 FROM batch", CompletionThreshold: 200}, http.StatusUnprocessableEntity, &createResp)
+
+	s.DoJSON("POST", "/api/v1/fleet/queries/batch", createBatchQueryRequest{
+		QuerySQL:      "SELECT 1 FROM batch",
+		Selected:      fleet.HostTargets{HostIDs: []uint{h1.ID, h2.ID}},
+		DurationHours: 48,
+	}, http.StatusOK, &createResp)
+	require.NotNil(t, createResp.BatchQuery)
+	bq := createResp.BatchQuery
+	assert.Equal(t, fleet.QueryRunning, bq.Status)
+	assert.Equal(t, uint(100), bq.CompletionThreshold)
+	assert.Equal(t, uint(2), bq.TargetedHosts)
+	assert.Zero(t, bq.RespondedHosts)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), bq.ExpiresAt, time.Minute)
+
+	cid := fmt.Sprint(bq.CampaignID)
+	distributedReq := submitDistributedQueryResultsRequestShim{
+		NodeKey: h1.NodeKey,
+		Results: map[string]json.RawMessage{
+			hostDistributedQueryPrefix + cid: json.RawMessage(`[{"col1": "a"}]`),
+		},
+		Statuses: map[string]interface{}{
+			hostDistributedQueryPrefix + cid: 0,
+		},
+	}
+	distributedResp := submitDistributedQueryResultsResponse{}
+	s.DoJSON("POST", "/api/v1/osquery/distributed/write", distributedReq, http.StatusOK, &distributedResp)
+
+	var getResp batchQueryResponse
+	s.DoJSON("GET", "/api/v1/fleet/queries/batch/"+cid, nil, http.StatusOK, &getResp)
+	assert.Equal(t, uint(1), getResp.BatchQuery.RespondedHosts)
+	assert.Equal(t, fleet.QueryRunning, getResp.BatchQuery.Status)
+	assert.Nil(t, getResp.BatchQuery.NotifiedAt)
+
+	var resultsResp listBatchQueryResultsResponse
+	s.DoJSON("GET", "/api/v1/fleet/queries/batch/"+cid+"/results", nil, http.StatusOK, &resultsResp)
+	require.Len(t, resultsResp.Results, 1)
+	assert.Equal(t, h1.ID, resultsResp.Results[0].HostID)
+	assert.Equal(t, h1.Hostname, resultsResp.Results[0].Hostname)
+	assert.JSONEq(t, `[{"col1":"a"}]`, string(resultsResp.Results[0].Rows))
+
+	// the batch queries are not live queries
+	s.DoJSON("GET", "/api/v1/fleet/queries/batch/99999", nil, http.StatusNotFound, &getResp)
+	s.lq.On("RunQuery", mock.Anything, "SELECT 2 FROM batch", mock.Anything).Return(nil)
+	var campResp createDistributedQueryCampaignResponse
+	s.DoJSON("POST", "/api/v1/fleet/queries/run", createDistributedQueryCampaignRequest{QuerySQL: "SELECT 2 FROM batch"}, http.StatusOK, &campResp)
+	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/queries/batch/%d", campResp.Campaign.ID), nil, http.StatusNotFound, &getResp)
+}
@@ -82,3 +82,26 @@ func (jh *jitterHashTable) jitterForHost(hostID uint) time.Duration {
 	jh.mu.Unlock()
 	return jh.jitterForHost(hostID)
 }
+
+// jitterInterval returns the interval (in seconds) with a jitter of up to maxPercent percent added or
+// removed. The jitter only depends on the host ID, so a host gets the same interval from every fleet
+// instance and across config refreshes, while hosts with consecutive IDs are spread evenly across the
+// jitter range.
+func jitterInterval(hostID uint, interval uint, maxPercent int) uint {
+	if maxPercent <= 0 || interval == 0 {
+		return interval
+	}
+
+	spread := interval * uint(maxPercent) / 100
+	if spread == 0 {
+		return interval
+	}
+
+	// offset is in [-spread, spread]
+	offset := int(hostID%(2*spread+1)) - int(spread)
+	jittered := int(interval) + offset
+	if jittered < 1 {
+		jittered = 1
+	}
+	return uint(jittered)
+}
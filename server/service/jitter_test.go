@@ -50,3 +50,31 @@ func TestNoJitter(t *testing.T) {
 		require.Equal(t, int64(0), jitterMinutes)
 	}
 }
+
+func TestJitterInterval(t *testing.T) {
+	// no jitter configured
+	require.Equal(t, uint(10), jitterInterval(1, 10, 0))
+	require.Equal(t, uint(0), jitterInterval(1, 0, 10))
+	// interval too short for the jitter to be at least a second
+	require.Equal(t, uint(5), jitterInterval(1, 5, 10))
+
+	// the jitter is deterministic and within bounds
+	require.Equal(t, jitterInterval(42, 60, 10), jitterInterval(42, 60, 10))
+	histogram := make(map[uint]int)
+	for hostID := uint(1); hostID <= 1300; hostID++ {
+		interval := jitterInterval(hostID, 60, 10)
+		require.GreaterOrEqual(t, interval, uint(54))
+		require.LessOrEqual(t, interval, uint(66))
+		histogram[interval]++
+	}
+	// consecutive host IDs are spread evenly across the range
+	require.Len(t, histogram, 13)
+	for _, count := range histogram {
+		require.Equal(t, 100, count)
+	}
+
+	// never below a second
+	for hostID := uint(1); hostID <= 10; hostID++ {
+		require.GreaterOrEqual(t, jitterInterval(hostID, 2, 100), uint(1))
+	}
+}
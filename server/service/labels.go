@@ -51,10 +51,13 @@ func (svc *Service) NewLabel(ctx context.Context, p fleet.LabelPayload) (*fleet.
 	}
 	label.Name = *p.Name
 
-	if p.Query == nil {
+	label.LabelMembershipType = p.LabelMembershipType
+	if p.Query == nil && p.LabelMembershipType != fleet.LabelMembershipTypeManual {
 		return nil, fleet.NewInvalidArgumentError("query", "missing required argument")
 	}
-	label.Query = *p.Query
+	if p.Query != nil {
+		label.Query = *p.Query
+	}
 
 	if p.Platform != nil {
 		label.Platform = *p.Platform
@@ -255,7 +258,94 @@ func (svc *Service) ListHostsInLabel(ctx context.Context, lid uint, opt fleet.Ho
 	}
 	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
 
-	return svc.ds.ListHostsInLabel(ctx, filter, lid, opt)
+	hosts, err := svc.ds.ListHostsInLabel(ctx, filter, lid, opt)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.setHostsStatus(ctx, hosts...); err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Add Hosts to Label
+////////////////////////////////////////////////////////////////////////////////
+
+type addHostsToLabelRequest struct {
+	ID      uint   `json:"-" url:"id"`
+	HostIDs []uint `json:"host_ids"`
+}
+
+type addHostsToLabelResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r addHostsToLabelResponse) error() error { return r.Err }
+
+func addHostsToLabelEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*addHostsToLabelRequest)
+	if err := svc.AddHostsToLabel(ctx, req.ID, req.HostIDs); err != nil {
+		return addHostsToLabelResponse{Err: err}, nil
+	}
+	return addHostsToLabelResponse{}, nil
+}
+
+func (svc *Service) AddHostsToLabel(ctx context.Context, lid uint, hostIDs []uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Label{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+	if err := svc.checkManualLabel(ctx, lid); err != nil {
+		return err
+	}
+	return svc.ds.AddHostsToLabel(ctx, lid, hostIDs)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Remove Hosts from Label
+////////////////////////////////////////////////////////////////////////////////
+
+type removeHostsFromLabelRequest struct {
+	ID      uint   `json:"-" url:"id"`
+	HostIDs []uint `json:"host_ids"`
+}
+
+type removeHostsFromLabelResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r removeHostsFromLabelResponse) error() error { return r.Err }
+
+func removeHostsFromLabelEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*removeHostsFromLabelRequest)
+	if err := svc.RemoveHostsFromLabel(ctx, req.ID, req.HostIDs); err != nil {
+		return removeHostsFromLabelResponse{Err: err}, nil
+	}
+	return removeHostsFromLabelResponse{}, nil
+}
+
+func (svc *Service) RemoveHostsFromLabel(ctx context.Context, lid uint, hostIDs []uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Label{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+	if err := svc.checkManualLabel(ctx, lid); err != nil {
+		return err
+	}
+	return svc.ds.RemoveHostsFromLabel(ctx, lid, hostIDs)
+}
+
+// checkManualLabel returns an error if the label does not exist or if its
+// members are not set manually, as the members of the other labels are
+// determined by their query.
+func (svc *Service) checkManualLabel(ctx context.Context, lid uint) error {
+	label, err := svc.ds.Label(ctx, lid)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get label")
+	}
+	if label.LabelType == fleet.LabelTypeBuiltIn || label.LabelMembershipType != fleet.LabelMembershipTypeManual {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("id", "the hosts can only be added to or removed from a manual label"))
+	}
+	return nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -31,7 +31,13 @@ func TestLabelsAuth(t *testing.T) {
 		return nil
 	}
 	ds.LabelFunc = func(ctx context.Context, id uint) (*fleet.Label, error) {
-		return &fleet.Label{}, nil
+		return &fleet.Label{LabelMembershipType: fleet.LabelMembershipTypeManual}, nil
+	}
+	ds.AddHostsToLabelFunc = func(ctx context.Context, lid uint, hostIDs []uint) error {
+		return nil
+	}
+	ds.RemoveHostsFromLabelFunc = func(ctx context.Context, lid uint, hostIDs []uint) error {
+		return nil
 	}
 	ds.ListLabelsFunc = func(ctx context.Context, filter fleet.TeamFilter, opts fleet.ListOptions) ([]*fleet.Label, error) {
 		return nil, nil
@@ -111,6 +117,12 @@ func TestLabelsAuth(t *testing.T) {
 			_, err = svc.ListHostsInLabel(ctx, 1, fleet.HostListOptions{})
 			checkAuthErr(t, tt.shouldFailRead, err)
 
+			err = svc.AddHostsToLabel(ctx, 1, []uint{1})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			err = svc.RemoveHostsFromLabel(ctx, 1, []uint{1})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
 			err = svc.DeleteLabel(ctx, "abc")
 			checkAuthErr(t, tt.shouldFailWrite, err)
 
@@ -120,6 +132,60 @@ func TestLabelsAuth(t *testing.T) {
 	}
 }
 
+func TestLabelsManualHosts(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	labels := map[uint]*fleet.Label{
+		1: {ID: 1, LabelType: fleet.LabelTypeRegular, LabelMembershipType: fleet.LabelMembershipTypeManual},
+		2: {ID: 2, LabelType: fleet.LabelTypeRegular, LabelMembershipType: fleet.LabelMembershipTypeDynamic},
+		3: {ID: 3, LabelType: fleet.LabelTypeBuiltIn, LabelMembershipType: fleet.LabelMembershipTypeManual},
+	}
+	ds.LabelFunc = func(ctx context.Context, id uint) (*fleet.Label, error) {
+		if l, ok := labels[id]; ok {
+			return l, nil
+		}
+		return nil, notFoundError{}
+	}
+	ds.AddHostsToLabelFunc = func(ctx context.Context, lid uint, hostIDs []uint) error {
+		assert.Equal(t, uint(1), lid)
+		assert.Equal(t, []uint{1, 2}, hostIDs)
+		return nil
+	}
+	ds.RemoveHostsFromLabelFunc = func(ctx context.Context, lid uint, hostIDs []uint) error {
+		assert.Equal(t, uint(1), lid)
+		assert.Equal(t, []uint{2}, hostIDs)
+		return nil
+	}
+
+	ctx := test.UserContext(test.UserAdmin)
+	require.NoError(t, svc.AddHostsToLabel(ctx, 1, []uint{1, 2}))
+	require.NoError(t, svc.RemoveHostsFromLabel(ctx, 1, []uint{2}))
+	assert.True(t, ds.AddHostsToLabelFuncInvoked)
+	assert.True(t, ds.RemoveHostsFromLabelFuncInvoked)
+	ds.AddHostsToLabelFuncInvoked = false
+
+	// the hosts of dynamic and builtin labels cannot be set
+	for _, id := range []uint{2, 3} {
+		err := svc.AddHostsToLabel(ctx, id, []uint{1})
+		var argErr *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &argErr)
+	}
+	err := svc.AddHostsToLabel(ctx, 4, []uint{1})
+	require.True(t, fleet.IsNotFound(err))
+	assert.False(t, ds.AddHostsToLabelFuncInvoked)
+
+	// the query is optional for manual labels only
+	ds.NewLabelFunc = func(ctx context.Context, lbl *fleet.Label, opts ...fleet.OptionalArg) (*fleet.Label, error) {
+		return lbl, nil
+	}
+	label, err := svc.NewLabel(ctx, fleet.LabelPayload{Name: ptr.String("manual"), LabelMembershipType: fleet.LabelMembershipTypeManual})
+	require.NoError(t, err)
+	assert.Equal(t, fleet.LabelMembershipTypeManual, label.LabelMembershipType)
+	_, err = svc.NewLabel(ctx, fleet.LabelPayload{Name: ptr.String("dynamic")})
+	require.Error(t, err)
+}
+
 func TestLabelsWithDS(t *testing.T) {
 	ds := mysql.CreateMySQLDS(t)
 
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List Host Listening Ports
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostListeningPortsRequest struct {
+	ID uint `url:"id"`
+}
+
+type listHostListeningPortsResponse struct {
+	Err            error                     `json:"error,omitempty"`
+	ListeningPorts []fleet.HostListeningPort `json:"listening_ports"`
+}
+
+func (r listHostListeningPortsResponse) error() error { return r.Err }
+
+func listHostListeningPortsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostListeningPortsRequest)
+	ports, err := svc.ListHostListeningPorts(ctx, req.ID)
+	if err != nil {
+		return listHostListeningPortsResponse{Err: err}, nil
+	}
+	return listHostListeningPortsResponse{ListeningPorts: ports}, nil
+}
+
+func (svc *Service) ListHostListeningPorts(ctx context.Context, id uint) ([]fleet.HostListeningPort, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "find host for listening ports")
+	}
+
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.ListHostListeningPorts(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Listening Ports Report
+////////////////////////////////////////////////////////////////////////////////
+
+type getListeningPortsReportRequest struct {
+	TeamID      *uint             `query:"team_id,optional"`
+	Port        *uint             `query:"port,optional"`
+	Protocol    string            `query:"protocol,optional"`
+	ProcessName string            `query:"process_name,optional"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type getListeningPortsReportResponse struct {
+	Err            error                           `json:"error,omitempty"`
+	ListeningPorts []fleet.ListeningPortHostsCount `json:"listening_ports"`
+}
+
+func (r getListeningPortsReportResponse) error() error { return r.Err }
+
+func getListeningPortsReportEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getListeningPortsReportRequest)
+	report, err := svc.ListeningPortsReport(ctx, req.TeamID, fleet.ListeningPortsReportOptions{
+		ListOptions: req.ListOptions,
+		Port:        req.Port,
+		Protocol:    req.Protocol,
+		ProcessName: req.ProcessName,
+	})
+	if err != nil {
+		return getListeningPortsReportResponse{Err: err}, nil
+	}
+	return getListeningPortsReportResponse{ListeningPorts: report}, nil
+}
+
+func (svc *Service) ListeningPortsReport(ctx context.Context, teamID *uint, opt fleet.ListeningPortsReportOptions) ([]fleet.ListeningPortHostsCount, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{TeamID: teamID}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	switch opt.Protocol {
+	case "", fleet.ListeningPortProtocolTCP, fleet.ListeningPortProtocolUDP:
+	default:
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("protocol", "must be one of tcp or udp"))
+	}
+
+	if teamID != nil {
+		if _, err := svc.ds.Team(ctx, *teamID); err != nil {
+			return nil, err
+		}
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true, TeamID: teamID}
+
+	return svc.ds.ListeningPortsReport(ctx, filter, opt)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// defaultLiveQueryAuditLogDays is the number of days of the audit log
+// returned when no start date is provided.
+const defaultLiveQueryAuditLogDays = 30
+
+// newLiveQueryAuditLog records who runs the query against which targets, for
+// the campaign with the provided ID.
+func (svc *Service) newLiveQueryAuditLog(ctx context.Context, user *fleet.User, campaignID uint, query *fleet.Query, targets fleet.HostTargets, targetedHosts int) error {
+	encodedTargets, err := json.Marshal(targets)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal audited targets")
+	}
+	entry := &fleet.LiveQueryAuditLog{
+		UserID:        user.ID,
+		UserName:      user.Name,
+		UserEmail:     user.Email,
+		CampaignID:    campaignID,
+		QueryID:       query.ID,
+		QueryName:     query.Name,
+		QuerySQL:      query.Query,
+		Targets:       encodedTargets,
+		TargetedHosts: uint(targetedHosts),
+	}
+	if err := svc.ds.NewLiveQueryAuditLog(ctx, entry); err != nil {
+		return ctxerr.Wrap(ctx, err, "record live query audit log")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Live Query Audit Log
+////////////////////////////////////////////////////////////////////////////////
+
+type listLiveQueryAuditLogRequest struct {
+	ListOptions fleet.ListOptions `url:"list_options"`
+	StartDate   string            `query:"start_date,optional"`
+	EndDate     string            `query:"end_date,optional"`
+	UserID      *uint             `query:"user_id,optional"`
+}
+
+type listLiveQueryAuditLogResponse struct {
+	AuditLog []*fleet.LiveQueryAuditLog `json:"audit_log"`
+	Err      error                      `json:"error,omitempty"`
+}
+
+func (r listLiveQueryAuditLogResponse) error() error { return r.Err }
+
+func listLiveQueryAuditLogEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listLiveQueryAuditLogRequest)
+
+	opt := fleet.LiveQueryAuditLogOptions{
+		ListOptions: req.ListOptions,
+		UserID:      req.UserID,
+	}
+	invalid := &fleet.InvalidArgumentError{}
+	if req.StartDate != "" {
+		d, err := time.Parse(queryUsageDateLayout, req.StartDate)
+		if err != nil {
+			invalid.Append("start_date", "must be a date in the YYYY-MM-DD format")
+		}
+		opt.StartDate = d
+	}
+	if req.EndDate != "" {
+		d, err := time.Parse(queryUsageDateLayout, req.EndDate)
+		if err != nil {
+			invalid.Append("end_date", "must be a date in the YYYY-MM-DD format")
+		}
+		opt.EndDate = d
+	}
+	if invalid.HasErrors() {
+		return listLiveQueryAuditLogResponse{Err: ctxerr.Wrap(ctx, invalid)}, nil
+	}
+
+	entries, err := svc.ListLiveQueryAuditLog(ctx, opt)
+	if err != nil {
+		return listLiveQueryAuditLogResponse{Err: err}, nil
+	}
+	return listLiveQueryAuditLogResponse{AuditLog: entries}, nil
+}
+
+func (svc *Service) ListLiveQueryAuditLog(ctx context.Context, opt fleet.LiveQueryAuditLogOptions) ([]*fleet.LiveQueryAuditLog, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if opt.EndDate.IsZero() {
+		opt.EndDate = svc.clock.Now().UTC()
+	}
+	if opt.StartDate.IsZero() {
+		opt.StartDate = opt.EndDate.AddDate(0, 0, -(defaultLiveQueryAuditLogDays - 1))
+	}
+	if opt.StartDate.After(opt.EndDate) {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("start_date", "must not be after end_date"))
+	}
+	return svc.ds.ListLiveQueryAuditLog(ctx, opt)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/hashicorp/go-multierror"
+)
+
+// campaignCacheTTL is how long a campaign is cached once loaded for the
+// ingestion of its results.
+const campaignCacheTTL = time.Minute
+
+// campaignCache caches the live query campaigns by ID while their results are
+// ingested, so that they are not loaded from the datastore for the result of
+// each host. The settings of a campaign used for its results do not change
+// once it is created, only its status does, which is eventually picked up
+// when the entry expires. A nil cache is disabled.
+type campaignCache struct {
+	clock clock.Clock
+
+	mu        sync.Mutex
+	campaigns map[uint]campaignCacheEntry
+}
+
+type campaignCacheEntry struct {
+	campaign fleet.DistributedQueryCampaign
+	expires  time.Time
+}
+
+func newCampaignCache(c clock.Clock) *campaignCache {
+	return &campaignCache{
+		clock:     c,
+		campaigns: make(map[uint]campaignCacheEntry),
+	}
+}
+
+// get returns a copy of the cached campaign with the provided ID.
+func (c *campaignCache) get(id uint) (*fleet.DistributedQueryCampaign, bool) {
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.campaigns[id]
+	if !ok || !c.clock.Now().Before(entry.expires) {
+		return nil, false
+	}
+	campaign := entry.campaign
+	return &campaign, true
+}
+
+func (c *campaignCache) set(campaign *fleet.DistributedQueryCampaign) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// the campaigns are short lived, the expired ones are dropped so that the
+	// cache does not grow with every campaign
+	now := c.clock.Now()
+	for id, entry := range c.campaigns {
+		if !now.Before(entry.expires) {
+			delete(c.campaigns, id)
+		}
+	}
+	c.campaigns[campaign.ID] = campaignCacheEntry{
+		campaign: *campaign,
+		expires:  now.Add(campaignCacheTTL),
+	}
+}
+
+// campaignResultWrites are the writes of the results of the hosts to a
+// campaign not yet done in the datastore.
+type campaignResultWrites struct {
+	campaign *fleet.DistributedQueryCampaign
+	// succeededHosts and failedHosts are the IDs of the hosts that responded.
+	succeededHosts []uint
+	failedHosts    []uint
+	// batchResults are the results to store for a batch query.
+	batchResults []*fleet.BatchQueryResult
+}
+
+// liveQueryResultWrites implements synchronized storage for the writes of the
+// live query results not yet done in the datastore, by campaign, so that the
+// results received from many hosts are written at once by the next
+// FlushLiveQueryResults instead of one by one.
+type liveQueryResultWrites struct {
+	mutex     sync.Mutex
+	campaigns map[uint]*campaignResultWrites
+}
+
+func newLiveQueryResultWrites() *liveQueryResultWrites {
+	return &liveQueryResultWrites{
+		campaigns: make(map[uint]*campaignResultWrites),
+	}
+}
+
+// add records the result of the host to the campaign. batchResult is the
+// result to store if the campaign is a batch query, nil otherwise.
+func (w *liveQueryResultWrites) add(campaign *fleet.DistributedQueryCampaign, hostID uint, failed bool, batchResult *fleet.BatchQueryResult) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	cw, ok := w.campaigns[campaign.ID]
+	if !ok {
+		cw = &campaignResultWrites{campaign: campaign}
+		w.campaigns[campaign.ID] = cw
+	}
+	if failed {
+		cw.failedHosts = append(cw.failedHosts, hostID)
+	} else {
+		cw.succeededHosts = append(cw.succeededHosts, hostID)
+	}
+	if batchResult != nil {
+		cw.batchResults = append(cw.batchResults, batchResult)
+	}
+}
+
+// getAndClear returns the pending writes and resets them.
+func (w *liveQueryResultWrites) getAndClear() map[uint]*campaignResultWrites {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	campaigns := w.campaigns
+	w.campaigns = make(map[uint]*campaignResultWrites)
+	return campaigns
+}
+
+func (svc *Service) FlushLiveQueryResults(ctx context.Context) error {
+	// No authorization check because this is used only internally.
+	var errs *multierror.Error
+	for _, cw := range svc.liveQueryWrites.getAndClear() {
+		if err := svc.writeCampaignResults(ctx, cw); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// writeCampaignResults stores the results of a batch query, and records the
+// executions of the hosts that responded to the campaign and their activities.
+func (svc *Service) writeCampaignResults(ctx context.Context, cw *campaignResultWrites) error {
+	campaign := cw.campaign
+	// the results are stored first, they cannot be received again
+	var bq *fleet.BatchQuery
+	if len(cw.batchResults) > 0 {
+		var err error
+		bq, err = svc.ds.NewBatchQueryResults(ctx, campaign.ID, cw.batchResults)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "store batch query results")
+		}
+	}
+
+	for _, hosts := range []struct {
+		ids    []uint
+		status fleet.DistributedQueryExecutionStatus
+	}{
+		{cw.succeededHosts, fleet.ExecutionSucceeded},
+		{cw.failedHosts, fleet.ExecutionFailed},
+	} {
+		if len(hosts.ids) == 0 {
+			continue
+		}
+		if err := svc.ds.UpdateDistributedQueryExecutions(ctx, campaign.ID, hosts.ids, hosts.status); err != nil {
+			return ctxerr.Wrap(ctx, err, "update distributed query executions")
+		}
+		if err := svc.ds.NewHostActivities(
+			ctx,
+			hosts.ids,
+			&fleet.User{ID: campaign.UserID},
+			fleet.HostActivityTypeLiveQuery,
+			&map[string]interface{}{"campaign_id": campaign.ID, "query_id": campaign.QueryID, "failed": hosts.status == fleet.ExecutionFailed},
+		); err != nil {
+			return ctxerr.Wrap(ctx, err, "new live query host activities")
+		}
+	}
+
+	if bq != nil {
+		return svc.checkBatchQueryCompletion(ctx, campaign, bq)
+	}
+	return nil
+}
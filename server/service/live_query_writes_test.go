@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/live_query"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCampaignCache(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	c := newCampaignCache(mockClock)
+
+	_, ok := c.get(1)
+	require.False(t, ok)
+
+	c.set(&fleet.DistributedQueryCampaign{ID: 1, Anonymize: true})
+	campaign, ok := c.get(1)
+	require.True(t, ok)
+	assert.True(t, campaign.Anonymize)
+
+	// a copy is returned
+	campaign.Status = fleet.QueryComplete
+	campaign, ok = c.get(1)
+	require.True(t, ok)
+	assert.Equal(t, fleet.QueryWaiting, campaign.Status)
+
+	// the entries expire, and are dropped once expired
+	mockClock.AddTime(campaignCacheTTL)
+	_, ok = c.get(1)
+	require.False(t, ok)
+	c.set(&fleet.DistributedQueryCampaign{ID: 2})
+	assert.Len(t, c.campaigns, 1)
+
+	// a nil cache is disabled
+	var nilCache *campaignCache
+	nilCache.set(&fleet.DistributedQueryCampaign{ID: 1})
+	_, ok = nilCache.get(1)
+	require.False(t, ok)
+}
+
+func TestFlushLiveQueryResults(t *testing.T) {
+	ds := new(mock.Store)
+	svc := &Service{ds: ds, clock: clock.NewMockClock(), liveQueryWrites: newLiveQueryResultWrites()}
+
+	executions := make(map[fleet.DistributedQueryExecutionStatus][]uint)
+	ds.UpdateDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, status fleet.DistributedQueryExecutionStatus) error {
+		assert.Equal(t, uint(42), campaignID)
+		executions[status] = hostIDs
+		return nil
+	}
+	activities := make(map[bool][]uint)
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, uint(7), user.ID)
+		assert.Equal(t, fleet.HostActivityTypeLiveQuery, activityType)
+		activities[(*details)["failed"].(bool)] = hostIDs
+		return nil
+	}
+
+	// the results of the hosts are written at once
+	campaign := &fleet.DistributedQueryCampaign{ID: 42, UserID: 7}
+	svc.liveQueryWrites.add(campaign, 1, false, nil)
+	svc.liveQueryWrites.add(campaign, 2, true, nil)
+	svc.liveQueryWrites.add(campaign, 3, false, nil)
+	require.NoError(t, svc.FlushLiveQueryResults(context.Background()))
+	assert.Equal(t, map[fleet.DistributedQueryExecutionStatus][]uint{
+		fleet.ExecutionSucceeded: {1, 3},
+		fleet.ExecutionFailed:    {2},
+	}, executions)
+	assert.Equal(t, map[bool][]uint{false: {1, 3}, true: {2}}, activities)
+	assert.False(t, ds.NewBatchQueryResultsFuncInvoked)
+
+	// nothing is written once flushed
+	ds.UpdateDistributedQueryExecutionsFuncInvoked = false
+	require.NoError(t, svc.FlushLiveQueryResults(context.Background()))
+	assert.False(t, ds.UpdateDistributedQueryExecutionsFuncInvoked)
+}
+
+func TestIngestDistributedQueryCachesCampaign(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	ds := new(mock.Store)
+	svc := &Service{
+		ds:              ds,
+		clock:           mockClock,
+		queryRowsUsage:  newQueryRowsUsageCounts(),
+		campaignCache:   newCampaignCache(mockClock),
+		liveQueryWrites: newLiveQueryResultWrites(),
+	}
+
+	campaign := &fleet.DistributedQueryCampaign{ID: 42, Batch: true}
+	var loads int
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		loads++
+		return campaign, nil
+	}
+	lq := new(live_query.MockLiveQuery)
+	svc.liveQueryStore = lq
+
+	// the campaign is loaded once for the results of all the hosts
+	for _, hostID := range []uint{1, 2, 3} {
+		lq.On("QueryCompletedByHost", "42", hostID).Return(nil)
+		err := svc.ingestDistributedQuery(context.Background(), fleet.Host{ID: hostID}, "fleet_distributed_query_42", nil, false, "")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, loads)
+	lq.AssertExpectations(t)
+	assert.Len(t, svc.liveQueryWrites.getAndClear()[42].batchResults, 3)
+}
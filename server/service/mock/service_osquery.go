@@ -17,6 +17,8 @@ type AuthenticateHostFunc func(ctx context.Context, nodeKey string) (host *fleet
 
 type GetClientConfigFunc func(ctx context.Context) (config map[string]interface{}, err error)
 
+type GetClientFlagsFunc func(ctx context.Context) (flags json.RawMessage, err error)
+
 type GetDistributedQueriesFunc func(ctx context.Context) (queries map[string]string, discovery map[string]string, accelerate uint, err error)
 
 type SubmitDistributedQueryResultsFunc func(ctx context.Context, results fleet.OsqueryDistributedQueryResults, statuses map[string]fleet.OsqueryStatus, messages map[string]string) (err error)
@@ -35,6 +37,9 @@ type TLSService struct {
 	GetClientConfigFunc        GetClientConfigFunc
 	GetClientConfigFuncInvoked bool
 
+	GetClientFlagsFunc        GetClientFlagsFunc
+	GetClientFlagsFuncInvoked bool
+
 	GetDistributedQueriesFunc        GetDistributedQueriesFunc
 	GetDistributedQueriesFuncInvoked bool
 
@@ -63,6 +68,11 @@ func (s *TLSService) GetClientConfig(ctx context.Context) (config map[string]int
 	return s.GetClientConfigFunc(ctx)
 }
 
+func (s *TLSService) GetClientFlags(ctx context.Context) (flags json.RawMessage, err error) {
+	s.GetClientFlagsFuncInvoked = true
+	return s.GetClientFlagsFunc(ctx)
+}
+
 func (s *TLSService) GetDistributedQueries(ctx context.Context) (queries map[string]string, discovery map[string]string, accelerate uint, err error) {
 	s.GetDistributedQueriesFuncInvoked = true
 	return s.GetDistributedQueriesFunc(ctx)
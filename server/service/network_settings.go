@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Host Network Settings
+////////////////////////////////////////////////////////////////////////////////
+
+type getHostNetworkSettingsRequest struct {
+	ID uint `url:"id"`
+}
+
+type getHostNetworkSettingsResponse struct {
+	Err             error                      `json:"error,omitempty"`
+	NetworkSettings *fleet.HostNetworkSettings `json:"network_settings"`
+}
+
+func (r getHostNetworkSettingsResponse) error() error { return r.Err }
+
+func getHostNetworkSettingsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getHostNetworkSettingsRequest)
+	settings, err := svc.HostNetworkSettings(ctx, req.ID)
+	if err != nil {
+		return getHostNetworkSettingsResponse{Err: err}, nil
+	}
+	return getHostNetworkSettingsResponse{NetworkSettings: settings}, nil
+}
+
+func (svc *Service) HostNetworkSettings(ctx context.Context, id uint) (*fleet.HostNetworkSettings, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "find host for network settings")
+	}
+
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.HostNetworkSettings(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Network Settings Report
+////////////////////////////////////////////////////////////////////////////////
+
+type getNetworkSettingsReportRequest struct {
+	TeamID *uint `query:"team_id,optional"`
+}
+
+type getNetworkSettingsReportResponse struct {
+	Err             error                        `json:"error,omitempty"`
+	NetworkSettings *fleet.NetworkSettingsReport `json:"network_settings"`
+}
+
+func (r getNetworkSettingsReportResponse) error() error { return r.Err }
+
+func getNetworkSettingsReportEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getNetworkSettingsReportRequest)
+	report, err := svc.NetworkSettingsReport(ctx, req.TeamID)
+	if err != nil {
+		return getNetworkSettingsReportResponse{Err: err}, nil
+	}
+	return getNetworkSettingsReportResponse{NetworkSettings: report}, nil
+}
+
+func (svc *Service) NetworkSettingsReport(ctx context.Context, teamID *uint) (*fleet.NetworkSettingsReport, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{TeamID: teamID}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	if teamID != nil {
+		if _, err := svc.ds.Team(ctx, *teamID); err != nil {
+			return nil, err
+		}
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true, TeamID: teamID}
+
+	return svc.ds.NetworkSettingsReport(ctx, filter)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkSettingsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	teamHost := &fleet.Host{ID: 1, TeamID: ptr.Uint(1)}
+	globalHost := &fleet.Host{ID: 2}
+
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		if id == 1 {
+			return teamHost, nil
+		}
+		return globalHost, nil
+	}
+	ds.HostNetworkSettingsFunc = func(ctx context.Context, hostID uint) (*fleet.HostNetworkSettings, error) {
+		return &fleet.HostNetworkSettings{}, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.NetworkSettingsReportFunc = func(ctx context.Context, filter fleet.TeamFilter) (*fleet.NetworkSettingsReport, error) {
+		return &fleet.NetworkSettingsReport{}, nil
+	}
+
+	testCases := []struct {
+		name                 string
+		user                 *fleet.User
+		shouldFailGlobalRead bool
+		shouldFailTeamRead   bool
+	}{
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			false,
+			false,
+		},
+		{
+			"team observer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			true,
+			false,
+		},
+		{
+			"team maintainer, DOES NOT belong to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer}}},
+			true,
+			true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			_, err := svc.HostNetworkSettings(ctx, 1)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, err = svc.HostNetworkSettings(ctx, 2)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+
+			_, err = svc.NetworkSettingsReport(ctx, ptr.Uint(1))
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+		})
+	}
+
+	// the report is filtered by the user's teams
+	var gotFilter fleet.TeamFilter
+	ds.NetworkSettingsReportFunc = func(ctx context.Context, filter fleet.TeamFilter) (*fleet.NetworkSettingsReport, error) {
+		gotFilter = filter
+		return &fleet.NetworkSettingsReport{}, nil
+	}
+	user := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}
+	_, err := svc.NetworkSettingsReport(viewer.NewContext(context.Background(), viewer.Viewer{User: user}), ptr.Uint(1))
+	require.NoError(t, err)
+	require.Equal(t, user, gotFilter.User)
+	require.Equal(t, ptr.Uint(1), gotFilter.TeamID)
+	require.True(t, gotFilter.IncludeObserver)
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Enroll Orbit
+////////////////////////////////////////////////////////////////////////////////
+
+type enrollOrbitRequest struct {
+	EnrollSecret    string `json:"enroll_secret"`
+	DeviceAuthToken string `json:"device_auth_token"`
+}
+
+type enrollOrbitResponse struct {
+	OrbitNodeKey string `json:"orbit_node_key,omitempty"`
+	Err          error  `json:"error,omitempty"`
+}
+
+func (r enrollOrbitResponse) error() error { return r.Err }
+
+func enrollOrbitEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*enrollOrbitRequest)
+	nodeKey, err := svc.EnrollOrbit(ctx, req.EnrollSecret, req.DeviceAuthToken)
+	if err != nil {
+		return enrollOrbitResponse{Err: err}, nil
+	}
+	return enrollOrbitResponse{OrbitNodeKey: nodeKey}, nil
+}
+
+// EnrollOrbit enrolls the orbit agent of the host identified by the device
+// auth token, and returns its new orbit node key. The device auth token is
+// known to the unprivileged users of the host, so the enroll secret is
+// required too.
+func (svc *Service) EnrollOrbit(ctx context.Context, enrollSecret, deviceAuthToken string) (string, error) {
+	// skipauth: Authorization is currently for user endpoints only.
+	svc.authz.SkipAuthorization(ctx)
+
+	if _, err := svc.ds.VerifyEnrollSecret(ctx, enrollSecret); err != nil {
+		return "", ctxerr.Wrap(ctx, fleet.NewAuthFailedError("orbit enroll failed: "+err.Error()))
+	}
+
+	host, err := svc.ds.LoadHostByDeviceAuthToken(ctx, deviceAuthToken)
+	switch {
+	case err == nil:
+		// OK
+	case fleet.IsNotFound(err):
+		// the device auth token is reported by osquery, orbit retries until
+		// the host is enrolled and has reported it
+		return "", ctxerr.Wrap(ctx, fleet.NewAuthFailedError("orbit enroll failed: unknown device"))
+	default:
+		return "", ctxerr.Wrap(ctx, err, "orbit enroll load host")
+	}
+	logging.WithExtras(ctx, "host_id", host.ID)
+
+	nodeKey, err := server.GenerateRandomText(svc.config.Osquery.NodeKeySize)
+	if err != nil {
+		return "", ctxerr.Wrap(ctx, err, "generate orbit node key")
+	}
+	if err := svc.ds.SetOrUpdateOrbitNodeKey(ctx, host.ID, nodeKey); err != nil {
+		return "", ctxerr.Wrap(ctx, err, "save orbit node key")
+	}
+	return nodeKey, nil
+}
+
+// AuthenticateOrbitHost loads the host identified by the orbit node key.
+func (svc *Service) AuthenticateOrbitHost(ctx context.Context, nodeKey string) (*fleet.Host, bool, error) {
+	// skipauth: Authorization is currently for user endpoints only.
+	svc.authz.SkipAuthorization(ctx)
+
+	if nodeKey == "" {
+		return nil, false, ctxerr.Wrap(ctx, fleet.NewAuthRequiredError("authentication error: missing orbit node key"))
+	}
+
+	host, err := svc.ds.LoadHostByOrbitNodeKey(ctx, nodeKey)
+	switch {
+	case err == nil:
+		// OK
+	case fleet.IsNotFound(err):
+		return nil, false, ctxerr.Wrap(ctx, fleet.NewAuthRequiredError("authentication error: invalid orbit node key"))
+	default:
+		return nil, false, ctxerr.Wrap(ctx, err, "authenticate orbit")
+	}
+
+	return host, svc.debugEnabledForHost(ctx, host.ID), nil
+}
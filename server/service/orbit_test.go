@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrollOrbit(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.VerifyEnrollSecretFunc = func(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
+		if secret != "secret" {
+			return nil, fleet.ErrEnrollSecretNotFound
+		}
+		return &fleet.EnrollSecret{Secret: secret}, nil
+	}
+	ds.LoadHostByDeviceAuthTokenFunc = func(ctx context.Context, authToken string) (*fleet.Host, error) {
+		if authToken != "token" {
+			return nil, notFoundError{}
+		}
+		return &fleet.Host{ID: 1}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	nodeKeys := make(map[string]uint)
+	ds.SetOrUpdateOrbitNodeKeyFunc = func(ctx context.Context, hostID uint, nodeKey string) error {
+		nodeKeys[nodeKey] = hostID
+		return nil
+	}
+	ds.LoadHostByOrbitNodeKeyFunc = func(ctx context.Context, nodeKey string) (*fleet.Host, error) {
+		if hostID, ok := nodeKeys[nodeKey]; ok {
+			return &fleet.Host{ID: hostID}, nil
+		}
+		return nil, notFoundError{}
+	}
+
+	// the enroll secret and the device auth token are both required
+	var authErr *fleet.AuthFailedError
+	_, err := svc.EnrollOrbit(context.Background(), "invalid", "token")
+	require.True(t, errors.As(err, &authErr))
+	_, err = svc.EnrollOrbit(context.Background(), "secret", "invalid")
+	require.True(t, errors.As(err, &authErr))
+	assert.False(t, ds.SetOrUpdateOrbitNodeKeyFuncInvoked)
+
+	nodeKey, err := svc.EnrollOrbit(context.Background(), "secret", "token")
+	require.NoError(t, err)
+	require.NotEmpty(t, nodeKey)
+
+	host, _, err := svc.AuthenticateOrbitHost(context.Background(), nodeKey)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), host.ID)
+
+	// the device auth token is not an orbit node key
+	var authReqErr *fleet.AuthRequiredError
+	_, _, err = svc.AuthenticateOrbitHost(context.Background(), "token")
+	require.True(t, errors.As(err, &authReqErr))
+	_, _, err = svc.AuthenticateOrbitHost(context.Background(), "")
+	require.True(t, errors.As(err, &authReqErr))
+}
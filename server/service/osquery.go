@@ -109,11 +109,17 @@ func (svc *Service) EnrollAgent(ctx context.Context, enrollSecret, hostIdentifie
 
 	secret, err := svc.ds.VerifyEnrollSecret(ctx, enrollSecret)
 	if err != nil {
+		svc.recordEnrollmentOutcome(ctx, err)
 		return "", osqueryError{
 			message:     "enroll failed: " + err.Error(),
 			nodeInvalid: true,
 		}
 	}
+	// a use of a limited secret is consumed only if a new host is enrolled
+	consumeSecret := ""
+	if secret.MaxUses != nil {
+		consumeSecret = enrollSecret
+	}
 
 	nodeKey, err := server.GenerateRandomText(svc.config.Osquery.NodeKeySize)
 	if err != nil {
@@ -125,7 +131,8 @@ func (svc *Service) EnrollAgent(ctx context.Context, enrollSecret, hostIdentifie
 
 	hostIdentifier = getHostIdentifier(svc.logger, svc.config.Osquery.HostIdentifier, hostIdentifier, hostDetails)
 
-	host, err := svc.ds.EnrollHost(ctx, hostIdentifier, nodeKey, secret.TeamID, svc.config.Osquery.EnrollCooldown)
+	host, err := svc.ds.EnrollHost(ctx, hostIdentifier, nodeKey, secret.TeamID, svc.config.Osquery.EnrollCooldown, consumeSecret)
+	svc.recordEnrollmentOutcome(ctx, err)
 	if err != nil {
 		return "", osqueryError{message: "save enroll failed: " + err.Error(), nodeInvalid: true}
 	}
@@ -161,6 +168,7 @@ func (svc *Service) EnrollAgent(ctx context.Context, enrollSecret, hostIdentifie
 	}
 
 	if save {
+		host.DisplayName = host.ComputeDisplayName(appConfig.HostSettings.DisplayNameSources)
 		if appConfig.ServerSettings.DeferredSaveHost {
 			go svc.serialUpdateHost(host)
 		} else {
@@ -187,7 +195,9 @@ func (svc *Service) serialUpdateHost(host *fleet.Host) {
 	err := svc.ds.SerialUpdateHost(ctx, host)
 	if err != nil {
 		level.Error(svc.logger).Log("background-err", err)
+		return
 	}
+	osquery_utils.RunHostSavedHooks(ctx, svc.logger, host, svc.ds)
 }
 
 func getHostIdentifier(logger log.Logger, identifierOption, providedIdentifier string, details map[string](map[string]string)) string {
@@ -312,71 +322,9 @@ func (svc *Service) GetClientConfig(ctx context.Context) (map[string]interface{}
 		return nil, osqueryError{message: "internal error: missing host from request context"}
 	}
 
-	baseConfig, err := svc.AgentOptionsForHost(ctx, host.TeamID, host.Platform)
+	config, err := svc.renderClientConfig(ctx, host)
 	if err != nil {
-		return nil, osqueryError{message: "internal error: fetch base config: " + err.Error()}
-	}
-
-	config := make(map[string]interface{})
-	if baseConfig != nil {
-		err = json.Unmarshal(baseConfig, &config)
-		if err != nil {
-			return nil, osqueryError{message: "internal error: parse base configuration: " + err.Error()}
-		}
-	}
-
-	packs, err := svc.ds.ListPacksForHost(ctx, host.ID)
-	if err != nil {
-		return nil, osqueryError{message: "database error: " + err.Error()}
-	}
-
-	packConfig := fleet.Packs{}
-	for _, pack := range packs {
-		// first, we must figure out what queries are in this pack
-		queries, err := svc.ds.ListScheduledQueriesInPack(ctx, pack.ID)
-		if err != nil {
-			return nil, osqueryError{message: "database error: " + err.Error()}
-		}
-
-		// the serializable osquery config struct expects content in a
-		// particular format, so we do the conversion here
-		configQueries := fleet.Queries{}
-		for _, query := range queries {
-			queryContent := fleet.QueryContent{
-				Query:    query.Query,
-				Interval: query.Interval,
-				Platform: query.Platform,
-				Version:  query.Version,
-				Removed:  query.Removed,
-				Shard:    query.Shard,
-				Denylist: query.Denylist,
-			}
-
-			if query.Removed != nil {
-				queryContent.Removed = query.Removed
-			}
-
-			if query.Snapshot != nil && *query.Snapshot {
-				queryContent.Snapshot = query.Snapshot
-			}
-
-			configQueries[query.Name] = queryContent
-		}
-
-		// finally, we add the pack to the client config struct with all of
-		// the pack's queries
-		packConfig[pack.Name] = fleet.PackContent{
-			Platform: pack.Platform,
-			Queries:  configQueries,
-		}
-	}
-
-	if len(packConfig) > 0 {
-		packJSON, err := json.Marshal(packConfig)
-		if err != nil {
-			return nil, osqueryError{message: "internal error: marshal pack JSON: " + err.Error()}
-		}
-		config["packs"] = json.RawMessage(packJSON)
+		return nil, osqueryError{message: "internal error: " + err.Error()}
 	}
 
 	// Save interval values if they have been updated.
@@ -423,10 +371,264 @@ func (svc *Service) GetClientConfig(ctx context.Context) (map[string]interface{}
 	return config, nil
 }
 
+// renderClientConfig renders the osquery client config served to the host:
+// the agent options, with the label and host overrides merged in, the packs,
+// and the ATC, FIM and YARA configs.
+func (svc *Service) renderClientConfig(ctx context.Context, host *fleet.Host) (map[string]interface{}, error) {
+	globalAgentOptions, canary, err := svc.globalAgentOptionsForHost(ctx, host)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "fetch global agent options")
+	}
+
+	// the options of the canary hosts of a rollout are not cached, as they
+	// differ from the ones of the other hosts of the same team and platform
+	var baseConfig json.RawMessage
+	cached := false
+	if !canary {
+		baseConfig, cached = svc.clientConfigCache.getAgentOptions(host.TeamID, host.Platform)
+	}
+	if !cached {
+		baseConfig, err = svc.agentOptionsForHost(ctx, globalAgentOptions, host.TeamID, host.Platform)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "fetch base config")
+		}
+		if !canary {
+			svc.clientConfigCache.setAgentOptions(host.TeamID, host.Platform, baseConfig)
+		}
+	}
+
+	labelConfigs, err := svc.labelAgentOptionsForHost(ctx, host, globalAgentOptions)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "fetch label config")
+	}
+	for _, labelConfig := range labelConfigs {
+		baseConfig, err = fleet.MergeAgentOptions(baseConfig, labelConfig)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "merge label config")
+		}
+	}
+
+	config := make(map[string]interface{})
+	if baseConfig != nil {
+		err = json.Unmarshal(baseConfig, &config)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "parse base configuration")
+		}
+	}
+
+	packs, err := svc.ds.ListPacksForHost(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list packs for host")
+	}
+
+	packConfig := fleet.Packs{}
+	var hostLabelIDs map[uint]bool
+	for _, pack := range packs {
+		content, ok := svc.clientConfigCache.getPack(pack.ID)
+		if !ok {
+			content, err = svc.packContentForConfig(ctx, pack)
+			if err != nil {
+				return nil, ctxerr.Wrap(ctx, err, "render pack")
+			}
+			svc.clientConfigCache.setPack(pack.ID, content)
+		}
+		if packHasLabelQueries(content) {
+			// avoid loading the labels of the host when not needed
+			if hostLabelIDs == nil {
+				labels, err := svc.ds.ListLabelsForHost(ctx, host.ID)
+				if err != nil {
+					return nil, ctxerr.Wrap(ctx, err, "list labels for host")
+				}
+				hostLabelIDs = make(map[uint]bool, len(labels))
+				for _, label := range labels {
+					hostLabelIDs[label.ID] = true
+				}
+			}
+			content = filterPackQueriesByLabels(content, hostLabelIDs)
+		}
+		packConfig[pack.Name] = content
+	}
+
+	if len(packConfig) > 0 {
+		packJSON, err := json.Marshal(packConfig)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "marshal pack JSON")
+		}
+		config["packs"] = json.RawMessage(packJSON)
+	}
+
+	atcConfig, err := svc.atcConfigForHost(ctx, host, config["auto_table_construction"])
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "fetch atc config")
+	}
+	if len(atcConfig) > 0 {
+		config["auto_table_construction"] = atcConfig
+	}
+
+	if err := svc.fimConfigForHost(ctx, host, config); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "fetch fim config")
+	}
+
+	if err := svc.yaraConfigForHost(ctx, host, config); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "fetch yara config")
+	}
+
+	// The host override is merged last, over the complete config.
+	switch override, err := svc.ds.HostAgentOptionsOverride(ctx, host.ID, svc.clock.Now()); {
+	case err != nil && !fleet.IsNotFound(err):
+		return nil, ctxerr.Wrap(ctx, err, "fetch host override")
+	case err == nil:
+		config, err = mergeHostAgentOptionsOverride(config, override.Options)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "merge host override")
+		}
+	}
+
+	svc.jitterIntervals(host, config)
+
+	return config, nil
+}
+
+// jitterIntervals spreads the check-ins of the hosts sharing the same
+// distributed_interval and logger_tls_period by adding a per-host jitter to
+// these options, if configured. Options that are not valid intervals are left
+// untouched.
+func (svc *Service) jitterIntervals(host *fleet.Host, config map[string]interface{}) {
+	maxPercent := svc.config.Osquery.IntervalJitterPercent
+	if maxPercent <= 0 {
+		return
+	}
+	options, ok := config["options"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, name := range []string{"distributed_interval", "logger_tls_period"} {
+		interval, err := cast.ToUintE(options[name])
+		if err != nil || interval == 0 {
+			continue
+		}
+		options[name] = jitterInterval(host.ID, interval, maxPercent)
+	}
+}
+
+// mergeHostAgentOptionsOverride merges the host override over the rendered
+// client config.
+func mergeHostAgentOptionsOverride(config map[string]interface{}, override json.RawMessage) (map[string]interface{}, error) {
+	base, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := fleet.MergeAgentOptions(base, override)
+	if err != nil {
+		return nil, err
+	}
+	config = make(map[string]interface{})
+	if err := json.Unmarshal(merged, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// packContentForConfig renders the pack and its scheduled queries in the
+// format expected by the osquery client config.
+func (svc *Service) packContentForConfig(ctx context.Context, pack *fleet.Pack) (fleet.PackContent, error) {
+	// first, we must figure out what queries are in this pack
+	queries, err := svc.ds.ListScheduledQueriesInPack(ctx, pack.ID)
+	if err != nil {
+		return fleet.PackContent{}, err
+	}
+
+	// the serializable osquery config struct expects content in a
+	// particular format, so we do the conversion here
+	configQueries := fleet.Queries{}
+	for _, query := range queries {
+		queryContent := fleet.QueryContent{
+			Query:    query.Query,
+			Interval: query.Interval,
+			Platform: query.Platform,
+			Version:  query.Version,
+			Removed:  query.Removed,
+			Shard:    query.Shard,
+			Denylist: query.Denylist,
+			LabelIDs: query.LabelIDs,
+		}
+
+		if query.Removed != nil {
+			queryContent.Removed = query.Removed
+		}
+
+		if query.Snapshot != nil && *query.Snapshot {
+			queryContent.Snapshot = query.Snapshot
+		}
+
+		configQueries[query.Name] = queryContent
+	}
+
+	// finally, we add the pack to the client config struct with all of
+	// the pack's queries
+	return fleet.PackContent{
+		Platform: pack.Platform,
+		Queries:  configQueries,
+	}, nil
+}
+
+// packHasLabelQueries returns true if any query of the pack is restricted to
+// labels.
+func packHasLabelQueries(content fleet.PackContent) bool {
+	for _, query := range content.Queries {
+		if len(query.LabelIDs) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// filterPackQueriesByLabels returns the pack without the queries restricted
+// to labels the host is not a member of. The queries of the provided pack are
+// not modified, as it may be cached.
+func filterPackQueriesByLabels(content fleet.PackContent, hostLabelIDs map[uint]bool) fleet.PackContent {
+	queries := make(fleet.Queries, len(content.Queries))
+	for name, query := range content.Queries {
+		if len(query.LabelIDs) > 0 {
+			member := false
+			for _, labelID := range query.LabelIDs {
+				if hostLabelIDs[labelID] {
+					member = true
+					break
+				}
+			}
+			if !member {
+				continue
+			}
+		}
+		queries[name] = query
+	}
+	content.Queries = queries
+	return content
+}
+
 // AgentOptionsForHost gets the agent options for the provided host.
 // The host information should be used for filtering based on team, platform, etc.
 func (svc *Service) AgentOptionsForHost(ctx context.Context, hostTeamID *uint, hostPlatform string) (json.RawMessage, error) {
-	// Team agent options have priority over global options.
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "load global agent options")
+	}
+	return svc.agentOptionsForHost(ctx, appConfig.AgentOptions, hostTeamID, hostPlatform)
+}
+
+// agentOptionsForHost is AgentOptionsForHost with the provided global agent
+// options.
+func (svc *Service) agentOptionsForHost(ctx context.Context, globalAgentOptions *json.RawMessage, hostTeamID *uint, hostPlatform string) (json.RawMessage, error) {
+	var options fleet.AgentOptions
+	if globalAgentOptions != nil {
+		if err := json.Unmarshal(*globalAgentOptions, &options); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "unmarshal global agent options")
+		}
+	}
+	globalOptions := options.ForPlatform(hostPlatform)
+
+	// Team agent options are merged over the global options.
 	if hostTeamID != nil {
 		teamAgentOptions, err := svc.ds.TeamAgentOptions(ctx, *hostTeamID)
 		if err != nil {
@@ -438,10 +640,121 @@ func (svc *Service) AgentOptionsForHost(ctx context.Context, hostTeamID *uint, h
 			if err := json.Unmarshal(*teamAgentOptions, &options); err != nil {
 				return nil, ctxerr.Wrap(ctx, err, "unmarshal team agent options")
 			}
-			return options.ForPlatform(hostPlatform), nil
+			merged, err := fleet.MergeAgentOptions(globalOptions, options.ForPlatform(hostPlatform))
+			if err != nil {
+				return nil, ctxerr.Wrap(ctx, err, "merge team agent options")
+			}
+			return merged, nil
+		}
+	}
+	return globalOptions, nil
+}
+
+// labelAgentOptionsForHost returns the label overrides of the agent options
+// that apply to the host, in the order they must be merged over the options
+// returned by AgentOptionsForHost: the global label overrides first, then the
+// team label overrides, each sorted by label name.
+func (svc *Service) labelAgentOptionsForHost(ctx context.Context, host *fleet.Host, globalAgentOptions *json.RawMessage) ([]json.RawMessage, error) {
+	var sources []fleet.AgentOptions
+	if globalAgentOptions != nil {
+		var options fleet.AgentOptions
+		if err := json.Unmarshal(*globalAgentOptions, &options); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "unmarshal global agent options")
 		}
+		sources = append(sources, options)
 	}
-	// Otherwise return the appropriate override for global options.
+	if host.TeamID != nil {
+		teamAgentOptions, err := svc.ds.TeamAgentOptions(ctx, *host.TeamID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "load team agent options for host")
+		}
+		if teamAgentOptions != nil && len(*teamAgentOptions) > 0 {
+			var options fleet.AgentOptions
+			if err := json.Unmarshal(*teamAgentOptions, &options); err != nil {
+				return nil, ctxerr.Wrap(ctx, err, "unmarshal team agent options")
+			}
+			sources = append(sources, options)
+		}
+	}
+
+	hasLabelOverrides := false
+	for _, options := range sources {
+		if len(options.Overrides.Labels) > 0 {
+			hasLabelOverrides = true
+			break
+		}
+	}
+	if !hasLabelOverrides {
+		// avoid loading the labels of the host when not needed
+		return nil, nil
+	}
+
+	labels, err := svc.ds.ListLabelsForHost(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list labels for host")
+	}
+	labelNames := make([]string, 0, len(labels))
+	for _, label := range labels {
+		labelNames = append(labelNames, label.Name)
+	}
+
+	var configs []json.RawMessage
+	for _, options := range sources {
+		configs = append(configs, options.ForLabels(labelNames)...)
+	}
+	return configs, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Client Flags
+////////////////////////////////////////////////////////////////////////////////
+
+type getClientFlagsRequest struct {
+	NodeKey string `json:"node_key"`
+}
+
+func (r *getClientFlagsRequest) hostNodeKey() string {
+	return r.NodeKey
+}
+
+type getClientFlagsResponse struct {
+	CommandLineFlags json.RawMessage `json:"command_line_flags"`
+	Err              error           `json:"error,omitempty"`
+}
+
+func (r getClientFlagsResponse) error() error { return r.Err }
+
+func getClientFlagsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	flags, err := svc.GetClientFlags(ctx)
+	if err != nil {
+		return getClientFlagsResponse{Err: err}, nil
+	}
+	return getClientFlagsResponse{CommandLineFlags: flags}, nil
+}
+
+func (svc *Service) GetClientFlags(ctx context.Context) (json.RawMessage, error) {
+	// skipauth: Authorization is currently for user endpoints only.
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return nil, osqueryError{message: "internal error: missing host from request context"}
+	}
+
+	flags, err := svc.commandLineFlagsForHost(ctx, host.TeamID)
+	if err != nil {
+		return nil, osqueryError{message: "internal error: fetch command line flags: " + err.Error()}
+	}
+	if len(flags) == 0 {
+		flags = json.RawMessage(`{}`)
+	}
+	return flags, nil
+}
+
+// commandLineFlagsForHost returns the osquery command-line flags of the
+// agent options that apply to hosts of the provided team: the team flags are
+// merged over the global flags.
+func (svc *Service) commandLineFlagsForHost(ctx context.Context, hostTeamID *uint) (json.RawMessage, error) {
 	appConfig, err := svc.ds.AppConfig(ctx)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "load global agent options")
@@ -452,7 +765,32 @@ func (svc *Service) AgentOptionsForHost(ctx context.Context, hostTeamID *uint, h
 			return nil, ctxerr.Wrap(ctx, err, "unmarshal global agent options")
 		}
 	}
-	return options.ForPlatform(hostPlatform), nil
+	flags, err := options.StartUpFlags()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "global command line flags")
+	}
+
+	if hostTeamID != nil {
+		teamAgentOptions, err := svc.ds.TeamAgentOptions(ctx, *hostTeamID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "load team agent options for host")
+		}
+		if teamAgentOptions != nil && len(*teamAgentOptions) > 0 {
+			var options fleet.AgentOptions
+			if err := json.Unmarshal(*teamAgentOptions, &options); err != nil {
+				return nil, ctxerr.Wrap(ctx, err, "unmarshal team agent options")
+			}
+			teamFlags, err := options.StartUpFlags()
+			if err != nil {
+				return nil, ctxerr.Wrap(ctx, err, "team command line flags")
+			}
+			flags, err = fleet.MergeAgentOptions(flags, teamFlags)
+			if err != nil {
+				return nil, ctxerr.Wrap(ctx, err, "merge team command line flags")
+			}
+		}
+	}
+	return flags, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -538,6 +876,18 @@ func (svc *Service) GetDistributedQueries(ctx context.Context) (queries map[stri
 		queries[hostPolicyQueryPrefix+name] = query
 	}
 
+	if len(policyQueries) == 0 {
+		// the critical policies are evaluated more frequently, in between the
+		// runs of all the policies.
+		criticalPolicyQueries, err := svc.criticalPolicyQueriesForHost(ctx, host)
+		if err != nil {
+			return nil, nil, 0, osqueryError{message: err.Error()}
+		}
+		for name, query := range criticalPolicyQueries {
+			queries[hostCriticalPolicyQueryPrefix+name] = query
+		}
+	}
+
 	accelerate = uint(0)
 	if host.Hostname == "" || host.Platform == "" {
 		// Assume this host is just enrolling, and accelerate checkins
@@ -650,6 +1000,36 @@ func (svc *Service) policyQueriesForHost(ctx context.Context, host *fleet.Host)
 	return policyQueries, nil
 }
 
+// criticalPolicyQueriesForHost returns the critical policy queries of the host if the
+// critical policy update interval has elapsed since the last run of the critical
+// policies (or of all the policies). If the host has no critical policies, their
+// last run time is updated so that they are not fetched at each check-in.
+func (svc *Service) criticalPolicyQueriesForHost(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+	interval := svc.config.Osquery.CriticalPolicyUpdateInterval
+	if interval <= 0 {
+		return nil, nil
+	}
+	reportedAt := svc.task.GetHostPolicyReportedAt(ctx, host)
+	if host.CriticalPolicyUpdatedAt.After(reportedAt) {
+		reportedAt = host.CriticalPolicyUpdatedAt
+	}
+	if !svc.shouldUpdate(reportedAt, interval, host.ID) {
+		return nil, nil
+	}
+	criticalPolicyQueries, err := svc.ds.CriticalPolicyQueriesForHost(ctx, host)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "retrieve critical policy queries")
+	}
+	if len(criticalPolicyQueries) == 0 {
+		now := svc.clock.Now()
+		if err := svc.ds.RecordCriticalPolicyQueryExecutions(ctx, host, nil, now); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "record critical policy update")
+		}
+		host.CriticalPolicyUpdatedAt = now
+	}
+	return criticalPolicyQueries, nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Write Distributed Query Results
 ////////////////////////////////////////////////////////////////////////////////
@@ -759,6 +1139,11 @@ const (
 	// osqueryd writes the distributed query results.
 	hostPolicyQueryPrefix = "fleet_policy_query_"
 
+	// hostCriticalPolicyQueryPrefix is appended before the query name when a
+	// critical policy query is provided on its own, in between the runs of all
+	// the policy queries.
+	hostCriticalPolicyQueryPrefix = "fleet_critical_policy_query_"
+
 	// hostDistributedQueryPrefix is appended before the query name when a query is
 	// run from a distributed query campaign
 	hostDistributedQueryPrefix = "fleet_distributed_query_"
@@ -779,10 +1164,19 @@ func (svc *Service) SubmitDistributedQueryResults(
 	}
 
 	detailUpdated := false
+	// the detail results queued for asynchronous ingestion, if enabled
+	var detailJob *detailIngestionJob
+	if svc.detailIngester != nil {
+		detailJob = &detailIngestionJob{
+			results: make(fleet.OsqueryDistributedQueryResults),
+			failed:  make(map[string]bool),
+		}
+	}
 	additionalResults := make(fleet.OsqueryDistributedQueryResults)
 	additionalUpdated := false
 	labelResults := map[uint]*bool{}
 	policyResults := map[uint]*bool{}
+	criticalPolicyResults := map[uint]*bool{}
 
 	svc.maybeDebugHost(ctx, host, results, statuses, messages)
 
@@ -797,14 +1191,14 @@ func (svc *Service) SubmitDistributedQueryResults(
 		switch {
 		case strings.HasPrefix(query, hostDetailQueryPrefix):
 			trimmedQuery := strings.TrimPrefix(query, hostDetailQueryPrefix)
-			var ingested bool
-			ingested, err = svc.directIngestDetailQuery(ctx, host, trimmedQuery, rows, failed)
-			if !ingested && err == nil {
-				err = svc.ingestDetailQuery(ctx, host, trimmedQuery, rows)
-				// No err != nil check here because ingestDetailQuery could have updated
-				// successfully some values of host.
-				detailUpdated = true
+			if detailJob != nil {
+				detailJob.results[trimmedQuery] = rows
+				detailJob.failed[trimmedQuery] = failed
+				break
 			}
+			var updated bool
+			updated, err = svc.ingestDetailResult(ctx, host, trimmedQuery, rows, failed)
+			detailUpdated = detailUpdated || updated
 		case strings.HasPrefix(query, hostAdditionalQueryPrefix):
 			name := strings.TrimPrefix(query, hostAdditionalQueryPrefix)
 			additionalResults[name] = rows
@@ -813,6 +1207,8 @@ func (svc *Service) SubmitDistributedQueryResults(
 			err = ingestMembershipQuery(hostLabelQueryPrefix, query, rows, labelResults, failed)
 		case strings.HasPrefix(query, hostPolicyQueryPrefix):
 			err = ingestMembershipQuery(hostPolicyQueryPrefix, query, rows, policyResults, failed)
+		case strings.HasPrefix(query, hostCriticalPolicyQueryPrefix):
+			err = ingestMembershipQuery(hostCriticalPolicyQueryPrefix, query, rows, criticalPolicyResults, failed)
 		case strings.HasPrefix(query, hostDistributedQueryPrefix):
 			err = svc.ingestDistributedQuery(ctx, *host, query, rows, failed, messages[query])
 		default:
@@ -837,46 +1233,22 @@ func (svc *Service) SubmitDistributedQueryResults(
 	}
 
 	if len(policyResults) > 0 {
-
-		// filter policy results for webhooks
-		var policyIDs []uint
-		if ac.WebhookSettings.FailingPoliciesWebhook.Enable {
-			policyIDs = append(policyIDs, ac.WebhookSettings.FailingPoliciesWebhook.PolicyIDs...)
+		svc.processFlippedPolicies(ctx, host, ac, policyResults)
+		if err := svc.task.RecordPolicyQueryExecutions(ctx, host, policyResults, svc.clock.Now(), ac.ServerSettings.DeferredSaveHost); err != nil {
+			logging.WithErr(ctx, err)
 		}
+	}
 
-		if host.TeamID != nil {
-			team, err := svc.ds.Team(ctx, *host.TeamID)
-			if err != nil {
-				logging.WithErr(ctx, err)
-			} else {
-				if team.Config.WebhookSettings.FailingPoliciesWebhook.Enable {
-					policyIDs = append(policyIDs, team.Config.WebhookSettings.FailingPoliciesWebhook.PolicyIDs...)
-				}
-			}
-		}
+	if len(criticalPolicyResults) > 0 {
+		svc.processFlippedPolicies(ctx, host, ac, criticalPolicyResults)
 
-		filteredResults := filterPolicyResults(policyResults, policyIDs)
-		if len(filteredResults) > 0 {
-			if failingPolicies, passingPolicies, err := svc.ds.FlippingPoliciesForHost(ctx, host.ID, filteredResults); err != nil {
-				logging.WithErr(ctx, err)
-			} else {
-				// Register the flipped policies on a goroutine to not block the hosts on redis requests.
-				go func() {
-					if err := svc.registerFlippedPolicies(ctx, host.ID, host.Hostname, failingPolicies, passingPolicies); err != nil {
-						logging.WithErr(ctx, err)
-					}
-				}()
-			}
-		}
-		// NOTE(mna): currently, failing policies webhook wouldn't see the new
-		// flipped policies on the next run if async processing is enabled and the
-		// collection has not been done yet (not persisted in mysql). Should
-		// FlippingPoliciesForHost take pending redis data into consideration, or
-		// maybe we should impose restrictions between async collection interval
-		// and policy update interval?
-
-		if err := svc.task.RecordPolicyQueryExecutions(ctx, host, policyResults, svc.clock.Now(), ac.ServerSettings.DeferredSaveHost); err != nil {
+		// the critical policies are recorded synchronously, as they only update
+		// the critical policy timestamp of the host.
+		now := svc.clock.Now()
+		if err := svc.ds.RecordCriticalPolicyQueryExecutions(ctx, host, criticalPolicyResults, now); err != nil {
 			logging.WithErr(ctx, err)
+		} else {
+			host.CriticalPolicyUpdatedAt = now
 		}
 	}
 
@@ -892,25 +1264,49 @@ func (svc *Service) SubmitDistributedQueryResults(
 		}
 	}
 
-	if detailUpdated {
-		host.DetailUpdatedAt = svc.clock.Now()
-	}
-
 	refetchRequested := host.RefetchRequested
 	if refetchRequested {
 		host.RefetchRequested = false
 	}
 
+	if detailJob != nil && len(detailJob.results) > 0 {
+		// the worker saves the details of the host once they are ingested, in
+		// a copy of the host as it is after the other results were recorded.
+		detailJob.host = host.Copy()
+		detailJob.refetched = refetchRequested
+		if svc.detailIngester.enqueue(*detailJob) {
+			return nil
+		}
+
+		// the queue is full, fall back to the synchronous ingestion
+		level.Info(svc.logger).Log("msg", "detail ingestion queue is full, ingesting synchronously", "host_id", host.ID)
+		for name, rows := range detailJob.results {
+			updated, err := svc.ingestDetailResult(ctx, host, name, rows, detailJob.failed[name])
+			if err != nil {
+				logging.WithErr(ctx, ctxerr.New(ctx, "error in query ingestion"))
+				logging.WithExtras(ctx, "ingestion-err", err)
+			}
+			detailUpdated = detailUpdated || updated
+		}
+	}
+
+	if detailUpdated {
+		host.DetailUpdatedAt = svc.clock.Now()
+	}
+
 	if refetchRequested || detailUpdated {
 		appConfig, err := svc.ds.AppConfig(ctx)
 		if err != nil {
 			logging.WithErr(ctx, err)
 		} else {
+			host.DisplayName = host.ComputeDisplayName(appConfig.HostSettings.DisplayNameSources)
 			if appConfig.ServerSettings.DeferredSaveHost {
 				go svc.serialUpdateHost(host)
 			} else {
 				if err := svc.ds.UpdateHost(ctx, host); err != nil {
 					logging.WithErr(ctx, err)
+				} else {
+					osquery_utils.RunHostSavedHooks(ctx, svc.logger, host, svc.ds)
 				}
 			}
 		}
@@ -921,6 +1317,19 @@ func (svc *Service) SubmitDistributedQueryResults(
 
 var noSuchTableRegexp = regexp.MustCompile(`^no such table: \S+$`)
 
+// ingestDetailResult ingests the result of the detail query in the host. It
+// returns true if the fields of the host were updated and the host must be
+// saved, which can be the case even if it returns an error.
+func (svc *Service) ingestDetailResult(ctx context.Context, host *fleet.Host, name string, rows []map[string]string, failed bool) (bool, error) {
+	ingested, err := svc.directIngestDetailQuery(ctx, host, name, rows, failed)
+	if ingested || err != nil {
+		return false, err
+	}
+	// ingestDetailQuery could have updated successfully some values of host
+	// even if it returns an error.
+	return true, svc.ingestDetailQuery(ctx, host, name, rows)
+}
+
 func (svc *Service) directIngestDetailQuery(ctx context.Context, host *fleet.Host, name string, rows []map[string]string, failed bool) (ingested bool, err error) {
 	config, err := svc.ds.AppConfig(ctx)
 	if err != nil {
@@ -954,18 +1363,43 @@ func (svc *Service) ingestDistributedQuery(ctx context.Context, host fleet.Host,
 		return osqueryError{message: "unable to parse campaign ID: " + trimmedQuery}
 	}
 
+	// The campaign is loaded before writing the results, as its results may
+	// need to be anonymized. It is cached, not loaded for each result.
+	campaign, ok := svc.campaignCache.get(uint(campaignID))
+	if !ok {
+		campaign, err = svc.ds.DistributedQueryCampaign(ctx, uint(campaignID))
+		if err != nil {
+			if err := svc.liveQueryStore.StopQuery(strconv.Itoa(campaignID)); err != nil {
+				return osqueryError{message: "stop campaign after load failure: " + err.Error()}
+			}
+			return osqueryError{message: "loading campaign: " + err.Error()}
+		}
+		svc.campaignCache.set(campaign)
+	}
+
 	// Write the results to the pubsub store
 	res := fleet.DistributedQueryResult{
 		DistributedQueryCampaignID: uint(campaignID),
 		Host:                       host,
 		Rows:                       rows,
+		DuplicateResults:           campaign.DuplicateResults,
 	}
 	if failed {
 		res.Error = &errMsg
 	}
+	truncateDistributedQueryResult(campaign.DistributedQueryResultLimits, &res)
+	if campaign.Anonymize {
+		anonymizeDistributedQueryResult(campaign, &res)
+	}
 
-	err = svc.resultStore.WriteResult(res)
-	if err != nil {
+	var batchResult *fleet.BatchQueryResult
+	if campaign.Batch {
+		// The results of the batch queries are stored by the next
+		// FlushLiveQueryResults, nobody subscribes to them. The ID of the
+		// host is stored to count it once, even if anonymized.
+		batchResult = newBatchQueryResult(res)
+		batchResult.HostID = host.ID
+	} else if err = svc.resultStore.WriteResult(res); err != nil {
 		var pse pubsub.Error
 		ok := errors.As(err, &pse)
 		if !ok || !pse.NoSubscriber() {
@@ -975,14 +1409,6 @@ func (svc *Service) ingestDistributedQuery(ctx context.Context, host fleet.Host,
 		// If there are no subscribers, the campaign is "orphaned"
 		// and should be closed so that we don't continue trying to
 		// execute that query when we can't write to any subscriber
-		campaign, err := svc.ds.DistributedQueryCampaign(ctx, uint(campaignID))
-		if err != nil {
-			if err := svc.liveQueryStore.StopQuery(strconv.Itoa(campaignID)); err != nil {
-				return osqueryError{message: "stop orphaned campaign after load failure: " + err.Error()}
-			}
-			return osqueryError{message: "loading orphaned campaign: " + err.Error()}
-		}
-
 		if campaign.CreatedAt.After(svc.clock.Now().Add(-1 * time.Minute)) {
 			// Give the client a minute to connect before considering the
 			// campaign orphaned
@@ -1009,9 +1435,75 @@ func (svc *Service) ingestDistributedQuery(ctx context.Context, host fleet.Host,
 		return osqueryError{message: "record query completion: " + err.Error()}
 	}
 
+	// the execution, activity and usage are written in batches, by the next
+	// FlushLiveQueryResults and FlushQueryUsage
+	svc.liveQueryWrites.add(campaign, host.ID, failed, batchResult)
+	svc.queryRowsUsage.add(svc.clock.Now(), campaign.UserID, host.TeamID, len(res.Rows))
+
 	return nil
 }
 
+// processFlippedPolicies records the policies of the results that flipped on
+// the host in its activities, registers them for the policies of the failing
+// policies automations, and queues the automatic remediations of the policies
+// that start failing.
+func (svc *Service) processFlippedPolicies(ctx context.Context, host *fleet.Host, ac *fleet.AppConfig, results map[uint]*bool) {
+	failingPolicies, passingPolicies, err := svc.ds.FlippingPoliciesForHost(ctx, host.ID, results)
+	if err != nil {
+		logging.WithErr(ctx, err)
+	}
+	svc.newFlippedPoliciesHostActivities(ctx, host.ID, failingPolicies, passingPolicies)
+
+	// filter policy results for webhooks and the jira integration
+	var policyIDs []uint
+	if ac.WebhookSettings.FailingPoliciesWebhook.Enable || ac.Integrations.FailingPoliciesJira() != nil {
+		policyIDs = append(policyIDs, ac.WebhookSettings.FailingPoliciesWebhook.PolicyIDs...)
+	}
+
+	if host.TeamID != nil {
+		team, err := svc.ds.Team(ctx, *host.TeamID)
+		if err != nil {
+			logging.WithErr(ctx, err)
+		} else {
+			if team.Config.WebhookSettings.FailingPoliciesWebhook.Enable {
+				policyIDs = append(policyIDs, team.Config.WebhookSettings.FailingPoliciesWebhook.PolicyIDs...)
+			}
+		}
+	}
+
+	webhookFailing := filterPolicyIDs(failingPolicies, policyIDs)
+	webhookPassing := filterPolicyIDs(passingPolicies, policyIDs)
+	if len(webhookFailing) > 0 || len(webhookPassing) > 0 {
+		// Register the flipped policies on a goroutine to not block the hosts on redis requests.
+		go func() {
+			if err := svc.registerFlippedPolicies(ctx, host.ID, host.Hostname, webhookFailing, webhookPassing); err != nil {
+				logging.WithErr(ctx, err)
+			}
+		}()
+	}
+
+	// queue the remediations of the automatically remediated policies that
+	// start failing on the host
+	var failingPolicyIDs []uint
+	for policyID, passes := range results {
+		if passes != nil && !*passes {
+			failingPolicyIDs = append(failingPolicyIDs, policyID)
+		}
+	}
+	if len(failingPolicyIDs) > 0 {
+		if err := svc.ds.QueueHostPolicyAutoRemediations(ctx, host.ID, failingPolicyIDs); err != nil {
+			logging.WithErr(ctx, err)
+		}
+	}
+
+	// NOTE(mna): currently, failing policies webhook wouldn't see the new
+	// flipped policies on the next run if async processing is enabled and the
+	// collection has not been done yet (not persisted in mysql). Should
+	// FlippingPoliciesForHost take pending redis data into consideration, or
+	// maybe we should impose restrictions between async collection interval
+	// and policy update interval?
+}
+
 // ingestMembershipQuery records the results of label queries run by a host
 func ingestMembershipQuery(
 	prefix string,
@@ -1062,22 +1554,37 @@ func (svc *Service) ingestDetailQuery(ctx context.Context, host *fleet.Host, nam
 	return nil
 }
 
-// filterPolicyResults filters out policies that aren't configured for webhook automation.
-func filterPolicyResults(incoming map[uint]*bool, webhookPolicies []uint) map[uint]*bool {
+// filterPolicyIDs filters out policies that aren't configured for webhook automation.
+func filterPolicyIDs(policyIDs []uint, webhookPolicies []uint) []uint {
 	wp := make(map[uint]struct{})
 	for _, policyID := range webhookPolicies {
 		wp[policyID] = struct{}{}
 	}
-	filtered := make(map[uint]*bool)
-	for policyID, passes := range incoming {
+	var filtered []uint
+	for _, policyID := range policyIDs {
 		if _, ok := wp[policyID]; !ok {
 			continue
 		}
-		filtered[policyID] = passes
+		filtered = append(filtered, policyID)
 	}
 	return filtered
 }
 
+// newFlippedPoliciesHostActivities records the policies that start failing
+// and passing on the host in its activities.
+func (svc *Service) newFlippedPoliciesHostActivities(ctx context.Context, hostID uint, newFailing, newPassing []uint) {
+	for _, policyID := range newFailing {
+		if err := svc.ds.NewHostActivities(ctx, []uint{hostID}, nil, fleet.HostActivityTypePolicyFailing, &map[string]interface{}{"policy_id": policyID}); err != nil {
+			logging.WithErr(ctx, err)
+		}
+	}
+	for _, policyID := range newPassing {
+		if err := svc.ds.NewHostActivities(ctx, []uint{hostID}, nil, fleet.HostActivityTypePolicyPassing, &map[string]interface{}{"policy_id": policyID}); err != nil {
+			logging.WithErr(ctx, err)
+		}
+	}
+}
+
 func (svc *Service) registerFlippedPolicies(ctx context.Context, hostID uint, hostname string, newFailing, newPassing []uint) error {
 	host := fleet.PolicySetHost{
 		ID:       hostID,
@@ -1182,8 +1689,164 @@ func (svc *Service) SubmitResultLogs(ctx context.Context, logs []json.RawMessage
 	// skipauth: Authorization is currently for user endpoints only.
 	svc.authz.SkipAuthorization(ctx)
 
-	if err := svc.osqueryLogWriter.Result.Write(ctx, logs); err != nil {
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return osqueryError{message: "internal error: missing host from request context"}
+	}
+
+	logs, err := svc.filterDiscardedResultLogs(ctx, logs)
+	if err != nil {
+		return osqueryError{message: "internal error: filter result logs: " + err.Error()}
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+
+	if svc.config.Osquery.EnrichResultLogs {
+		logs, err = svc.enrichResultLogs(ctx, host, logs)
+		if err != nil {
+			return osqueryError{message: "internal error: enrich result logs: " + err.Error()}
+		}
+	}
+
+	logs, auditLogs := svc.osqueryLogWriter.SplitAuditLogs(logs)
+	if len(auditLogs) > 0 {
+		if err := svc.osqueryLogWriter.Audit.Write(ctx, auditLogs); err != nil {
+			return osqueryError{message: "error writing audit logs: " + err.Error()}
+		}
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+
+	writer, err := svc.resultLogWriter(ctx, host)
+	if err != nil {
+		return osqueryError{message: "internal error: get result log writer: " + err.Error()}
+	}
+	if err := writer.Write(ctx, logs); err != nil {
 		return osqueryError{message: "error writing result logs: " + err.Error()}
 	}
 	return nil
 }
+
+// resultLogWriter returns the writer of the result logs of the host, which is
+// the writer of the log plugin of its team if the team overrides the result
+// log plugin.
+func (svc *Service) resultLogWriter(ctx context.Context, host *fleet.Host) (fleet.JSONLogger, error) {
+	if host.TeamID == nil {
+		return svc.osqueryLogWriter.Result, nil
+	}
+	settings, err := svc.ds.TeamLogSettings(ctx, *host.TeamID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get team log settings")
+	}
+	writer, err := svc.osqueryLogWriter.ResultWriter(settings.ResultLogPlugin)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get team result log writer")
+	}
+	return writer, nil
+}
+
+// resultLogHostMetadata is the metadata of the host added to the result logs
+// when osquery.enrich_result_logs is set.
+type resultLogHostMetadata struct {
+	HostID   uint     `json:"host_id"`
+	Hostname string   `json:"hostname"`
+	TeamID   *uint    `json:"team_id"`
+	TeamName *string  `json:"team_name"`
+	Labels   []string `json:"labels"`
+}
+
+// enrichResultLogs returns the result logs with the metadata of the host in
+// the "fleet" field. The logs that are not JSON objects are kept as is.
+func (svc *Service) enrichResultLogs(ctx context.Context, host *fleet.Host, logs []json.RawMessage) ([]json.RawMessage, error) {
+	metadata := resultLogHostMetadata{
+		HostID:   host.ID,
+		Hostname: host.Hostname,
+		TeamID:   host.TeamID,
+		Labels:   []string{},
+	}
+	if host.TeamID != nil {
+		team, err := svc.ds.Team(ctx, *host.TeamID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get host team")
+		}
+		metadata.TeamName = &team.Name
+	}
+	labels, err := svc.ds.ListLabelsForHost(ctx, host.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host labels")
+	}
+	for _, label := range labels {
+		metadata.Labels = append(metadata.Labels, label.Name)
+	}
+	rawMetadata, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "marshal host metadata")
+	}
+
+	enriched := make([]json.RawMessage, 0, len(logs))
+	for _, log := range logs {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(log, &fields); err != nil || fields == nil {
+			enriched = append(enriched, log)
+			continue
+		}
+		fields["fleet"] = rawMetadata
+		enrichedLog, err := json.Marshal(fields)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "marshal enriched result log")
+		}
+		enriched = append(enriched, enrichedLog)
+	}
+	return enriched, nil
+}
+
+// filterDiscardedResultLogs returns the result logs without those of the
+// scheduled queries that have discard_data set.
+func (svc *Service) filterDiscardedResultLogs(ctx context.Context, logs []json.RawMessage) ([]json.RawMessage, error) {
+	discarded, err := svc.ds.ListDiscardDataScheduledQueryNames(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list discard data scheduled queries")
+	}
+	if len(discarded) == 0 {
+		return logs, nil
+	}
+
+	filtered := make([]json.RawMessage, 0, len(logs))
+	for _, log := range logs {
+		var result struct {
+			Name string `json:"name"`
+		}
+		// logs that cannot be parsed are kept, as fleet accepts anything in
+		// the result logs.
+		if err := json.Unmarshal(log, &result); err == nil && isDiscardedResultLog(result.Name, discarded) {
+			continue
+		}
+		filtered = append(filtered, log)
+	}
+	return filtered, nil
+}
+
+// isDiscardedResultLog returns true if the name of the result log is the name
+// of one of the discarded scheduled queries. Osquery names the result logs of
+// the queries in packs "pack" + delimiter + pack name + delimiter + query
+// name, where the delimiter is the pack_delimiter option of the host.
+func isDiscardedResultLog(logName string, discarded []fleet.PackScheduledQueryName) bool {
+	const prefix = "pack"
+	if !strings.HasPrefix(logName, prefix) {
+		return false
+	}
+	rest := logName[len(prefix):]
+	for _, name := range discarded {
+		delimLen := len(rest) - len(name.PackName) - len(name.ScheduledQueryName)
+		if delimLen <= 0 || delimLen%2 != 0 {
+			continue
+		}
+		delim := rest[:delimLen/2]
+		if rest == delim+name.PackName+delim+name.ScheduledQueryName {
+			return true
+		}
+	}
+	return false
+}
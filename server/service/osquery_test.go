@@ -38,9 +38,24 @@ import (
 
 func TestGetClientConfig(t *testing.T) {
 	ds := new(mock.Store)
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
 	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
 		return []*fleet.Pack{}, nil
 	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
 	ds.ListScheduledQueriesInPackFunc = func(ctx context.Context, pid uint) ([]*fleet.ScheduledQuery, error) {
 		tru := true
 		fals := false
@@ -167,10 +182,11 @@ func TestAgentOptionsForHost(t *testing.T) {
 	require.NoError(t, err)
 	assert.JSONEq(t, `{"foo":"override"}`, string(opt))
 
+	// Team options are merged over the global options
 	host.Platform = "windows"
 	opt, err = svc.AgentOptionsForHost(context.Background(), host.TeamID, host.Platform)
 	require.NoError(t, err)
-	assert.JSONEq(t, `{"foo":"bar"}`, string(opt))
+	assert.JSONEq(t, `{"foo":"bar","baz":"bar"}`, string(opt))
 
 	// Should take gobal option with no team
 	host.TeamID = nil
@@ -182,10 +198,304 @@ func TestAgentOptionsForHost(t *testing.T) {
 	opt, err = svc.AgentOptionsForHost(context.Background(), host.TeamID, host.Platform)
 	require.NoError(t, err)
 	assert.JSONEq(t, `{"foo":"override2"}`, string(opt))
+
+	// Should take global options when team has no options
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, id uint) (*json.RawMessage, error) {
+		return nil, nil
+	}
+	host.TeamID = &teamID
+	opt, err = svc.AgentOptionsForHost(context.Background(), host.TeamID, host.Platform)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"foo":"override2"}`, string(opt))
+}
+
+func TestAgentOptionsForHostMergesTeamOptions(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":60,"logger_tls_period":10,"pack_delimiter":"/"},"decorators":{"load":["SELECT uuid FROM system_info"]}}}`)),
+		}, nil
+	}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, id uint) (*json.RawMessage, error) {
+		return ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":5,"pack_delimiter":null}}}`)), nil
+	}
+
+	opt, err := svc.AgentOptionsForHost(context.Background(), ptr.Uint(1), "linux")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"options":{"distributed_interval":5,"logger_tls_period":10},
+		"decorators":{"load":["SELECT uuid FROM system_info"]}
+	}`, string(opt))
+}
+
+func TestGetClientFlags(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":60}},"command_line_flags":{"enable_file_events":true,"verbose":true}}`)),
+		}, nil
+	}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, id uint) (*json.RawMessage, error) {
+		switch id {
+		case 1:
+			return ptr.RawMessage(json.RawMessage(`{"command_line_flags":{"verbose":null,"disable_events":false}}`)), nil
+		default:
+			return ptr.RawMessage(json.RawMessage(`{"config":{"options":{"distributed_interval":10}}}`)), nil
+		}
+	}
+
+	flags, err := svc.GetClientFlags(hostctx.NewContext(context.Background(), &fleet.Host{ID: 1}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"enable_file_events":true,"verbose":true}`, string(flags))
+
+	// team flags are merged over the global flags
+	flags, err = svc.GetClientFlags(hostctx.NewContext(context.Background(), &fleet.Host{ID: 2, TeamID: ptr.Uint(1)}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"enable_file_events":true,"disable_events":false}`, string(flags))
+
+	// teams without flags use the global flags
+	flags, err = svc.GetClientFlags(hostctx.NewContext(context.Background(), &fleet.Host{ID: 3, TeamID: ptr.Uint(2)}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"enable_file_events":true,"verbose":true}`, string(flags))
+
+	// no flags set
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	flags, err = svc.GetClientFlags(hostctx.NewContext(context.Background(), &fleet.Host{ID: 1}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(flags))
+
+	_, err = svc.GetClientFlags(context.Background())
+	require.Error(t, err)
+}
+
+func TestGetClientConfigLabelAgentOptions(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{}, nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			AgentOptions: ptr.RawMessage(json.RawMessage(`{
+				"config":{"options":{"watchdog_memory_limit":350,"distributed_interval":10}},
+				"overrides":{"labels":{
+					"Low memory":{"options":{"watchdog_memory_limit":100}},
+					"Canary":{"options":{"watchdog_memory_limit":200,"distributed_interval":5}}
+				}}
+			}`)),
+		}, nil
+	}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, id uint) (*json.RawMessage, error) {
+		return ptr.RawMessage(json.RawMessage(`{
+			"config":{"options":{"distributed_interval":30}},
+			"overrides":{"labels":{"Canary":{"schedule":{"canary":{"query":"SELECT 1","interval":60}}}}}
+		}`)), nil
+	}
+	ds.UpdateHostOsqueryIntervalsFunc = func(ctx context.Context, hostID uint, intervals fleet.HostOsqueryIntervals) error {
+		return nil
+	}
+	var hostLabels []*fleet.Label
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		return hostLabels, nil
+	}
+
+	host := &fleet.Host{ID: 1}
+	getConfig := func() map[string]interface{} {
+		conf, err := svc.GetClientConfig(hostctx.NewContext(context.Background(), host))
+		require.NoError(t, err)
+		delete(conf, "packs")
+		return conf
+	}
+
+	// host not in any label
+	assert.Equal(t, map[string]interface{}{
+		"options": map[string]interface{}{"watchdog_memory_limit": float64(350), "distributed_interval": float64(10)},
+	}, getConfig())
+
+	// global label overrides are merged in label name order
+	hostLabels = []*fleet.Label{{Name: "Low memory"}, {Name: "Canary"}}
+	assert.Equal(t, map[string]interface{}{
+		"options": map[string]interface{}{"watchdog_memory_limit": float64(100), "distributed_interval": float64(5)},
+	}, getConfig())
+
+	// team label overrides are merged last
+	host.TeamID = ptr.Uint(1)
+	assert.Equal(t, map[string]interface{}{
+		"options":  map[string]interface{}{"watchdog_memory_limit": float64(100), "distributed_interval": float64(5)},
+		"schedule": map[string]interface{}{"canary": map[string]interface{}{"query": "SELECT 1", "interval": float64(60)}},
+	}, getConfig())
+
+	hostLabels = nil
+	assert.Equal(t, map[string]interface{}{
+		"options": map[string]interface{}{"watchdog_memory_limit": float64(350), "distributed_interval": float64(30)},
+	}, getConfig())
 }
 
-// One of these queries is the disk space, only one of the two works in a platform
-var expectedDetailQueries = len(osquery_utils.GetDetailQueries(&fleet.AppConfig{HostSettings: fleet.HostSettings{EnableHostUsers: true}}, config.FleetConfig{})) - 1
+func TestGetClientConfigLabelScheduledQueries(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{"options":{}}}`))}, nil
+	}
+	ds.UpdateHostOsqueryIntervalsFunc = func(ctx context.Context, hostID uint, intervals fleet.HostOsqueryIntervals) error {
+		return nil
+	}
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{{ID: 1, Name: "pack_labels"}, {ID: 2, Name: "pack_no_labels"}}, nil
+	}
+	ds.ListScheduledQueriesInPackFunc = func(ctx context.Context, packID uint) ([]*fleet.ScheduledQuery, error) {
+		if packID == 1 {
+			return []*fleet.ScheduledQuery{
+				{Name: "all", Query: "SELECT 1", Interval: 60},
+				{Name: "low_memory", Query: "SELECT 2", Interval: 60, LabelIDs: []uint{1}},
+				{Name: "low_memory_or_canary", Query: "SELECT 3", Interval: 60, LabelIDs: []uint{1, 2}},
+			}, nil
+		}
+		return []*fleet.ScheduledQuery{{Name: "other", Query: "SELECT 4", Interval: 60}}, nil
+	}
+	var hostLabels []*fleet.Label
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		return hostLabels, nil
+	}
+
+	host := &fleet.Host{ID: 1}
+	getQueryNames := func() map[string][]string {
+		conf, err := svc.GetClientConfig(hostctx.NewContext(context.Background(), host))
+		require.NoError(t, err)
+		var packs fleet.Packs
+		require.NoError(t, json.Unmarshal(conf["packs"].(json.RawMessage), &packs))
+		names := make(map[string][]string)
+		for packName, pack := range packs {
+			names[packName] = []string{}
+			for queryName := range pack.Queries {
+				names[packName] = append(names[packName], queryName)
+			}
+			sort.Strings(names[packName])
+		}
+		return names
+	}
+
+	// host not in any label
+	assert.Equal(t, map[string][]string{
+		"pack_labels":    {"all"},
+		"pack_no_labels": {"other"},
+	}, getQueryNames())
+
+	// host in one of the labels of a query
+	hostLabels = []*fleet.Label{{ID: 2, Name: "Canary"}}
+	assert.Equal(t, map[string][]string{
+		"pack_labels":    {"all", "low_memory_or_canary"},
+		"pack_no_labels": {"other"},
+	}, getQueryNames())
+
+	hostLabels = []*fleet.Label{{ID: 1, Name: "Low memory"}, {ID: 3, Name: "Other"}}
+	assert.Equal(t, map[string][]string{
+		"pack_labels":    {"all", "low_memory", "low_memory_or_canary"},
+		"pack_no_labels": {"other"},
+	}, getQueryNames())
+
+	// the cached packs are not modified by the filtering
+	hostLabels = nil
+	assert.Equal(t, map[string][]string{
+		"pack_labels":    {"all"},
+		"pack_no_labels": {"other"},
+	}, getQueryNames())
+}
+
+func TestGetClientConfigHostAgentOptionsOverride(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			AgentOptions: ptr.RawMessage(json.RawMessage(`{
+				"config":{"options":{"verbose":false,"distributed_interval":10}},
+				"overrides":{"labels":{"Canary":{"options":{"distributed_interval":5}}}}
+			}`)),
+		}, nil
+	}
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		return []*fleet.Label{{Name: "Canary"}}, nil
+	}
+	var gotIntervals fleet.HostOsqueryIntervals
+	ds.UpdateHostOsqueryIntervalsFunc = func(ctx context.Context, hostID uint, intervals fleet.HostOsqueryIntervals) error {
+		gotIntervals = intervals
+		return nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		require.Equal(t, uint(1), hostID)
+		return &fleet.HostAgentOptionsOverride{
+			HostID:  hostID,
+			Options: json.RawMessage(`{"options":{"verbose":true,"distributed_interval":2}}`),
+		}, nil
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
+
+	// the host override is merged over the label overrides
+	host := &fleet.Host{ID: 1}
+	conf, err := svc.GetClientConfig(hostctx.NewContext(context.Background(), host))
+	require.NoError(t, err)
+	delete(conf, "packs")
+	assert.Equal(t, map[string]interface{}{
+		"options": map[string]interface{}{"verbose": true, "distributed_interval": float64(2)},
+	}, conf)
+	assert.Equal(t, uint(2), gotIntervals.DistributedInterval)
+}
+
+// Some of these queries are platform-specific (disk space, disks, disk
+// encryption, DNS servers, proxies and startup items), only one of each kind
+// works in a platform
+var expectedDetailQueries = len(osquery_utils.GetDetailQueries(&fleet.AppConfig{HostSettings: fleet.HostSettings{EnableHostUsers: true}}, config.FleetConfig{})) - 9
 
 func TestEnrollAgent(t *testing.T) {
 	ds := new(mock.Store)
@@ -197,7 +507,7 @@ func TestEnrollAgent(t *testing.T) {
 			return nil, errors.New("not found")
 		}
 	}
-	ds.EnrollHostFunc = func(ctx context.Context, osqueryHostId, nodeKey string, teamID *uint, cooldown time.Duration) (*fleet.Host, error) {
+	ds.EnrollHostFunc = func(ctx context.Context, osqueryHostId, nodeKey string, teamID *uint, cooldown time.Duration, consumeSecret string) (*fleet.Host, error) {
 		assert.Equal(t, ptr.Uint(3), teamID)
 		return &fleet.Host{
 			OsqueryHostID: osqueryHostId, NodeKey: nodeKey,
@@ -232,12 +542,65 @@ func TestEnrollAgentIncorrectEnrollSecret(t *testing.T) {
 	assert.Empty(t, nodeKey)
 }
 
+func TestEnrollAgentEnrollSecretMaxUses(t *testing.T) {
+	ds := new(mock.Store)
+	ds.VerifyEnrollSecretFunc = func(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
+		if secret == "unlimited_secret" {
+			return &fleet.EnrollSecret{Secret: secret}, nil
+		}
+		return &fleet.EnrollSecret{Secret: secret, MaxUses: ptr.Uint(1)}, nil
+	}
+	var consumedSecrets []string
+	enrolledHosts := make(map[string]bool)
+	ds.EnrollHostFunc = func(ctx context.Context, osqueryHostId, nodeKey string, teamID *uint, cooldown time.Duration, consumeSecret string) (*fleet.Host, error) {
+		if !enrolledHosts[osqueryHostId] {
+			if consumeSecret != "" && len(consumedSecrets) > 0 {
+				return nil, fleet.ErrEnrollSecretExhausted
+			}
+			consumedSecrets = append(consumedSecrets, consumeSecret)
+			enrolledHosts[osqueryHostId] = true
+		}
+		return &fleet.Host{
+			OsqueryHostID: osqueryHostId, NodeKey: nodeKey,
+		}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
+	svc := newTestService(t, ds, nil, nil)
+
+	// the limited secret is consumed by the datastore when the host is enrolled
+	nodeKey, err := svc.EnrollAgent(context.Background(), "one_use_secret", "host123", nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, nodeKey)
+	assert.Equal(t, []string{"one_use_secret"}, consumedSecrets)
+
+	nodeKey, err = svc.EnrollAgent(context.Background(), "one_use_secret", "host456", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), fleet.ErrEnrollSecretExhausted.Error())
+	assert.Empty(t, nodeKey)
+
+	// the exhausted secret still re-enrolls the host enrolled with it
+	nodeKey, err = svc.EnrollAgent(context.Background(), "one_use_secret", "host123", nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, nodeKey)
+	assert.Equal(t, []string{"one_use_secret"}, consumedSecrets)
+
+	// unlimited secrets are not consumed
+	consumedSecrets = nil
+	nodeKey, err = svc.EnrollAgent(context.Background(), "unlimited_secret", "host789", nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, nodeKey)
+	assert.Equal(t, []string{""}, consumedSecrets)
+}
+
 func TestEnrollAgentDetails(t *testing.T) {
 	ds := new(mock.Store)
 	ds.VerifyEnrollSecretFunc = func(ctx context.Context, secret string) (*fleet.EnrollSecret, error) {
 		return &fleet.EnrollSecret{}, nil
 	}
-	ds.EnrollHostFunc = func(ctx context.Context, osqueryHostId, nodeKey string, teamID *uint, cooldown time.Duration) (*fleet.Host, error) {
+	ds.EnrollHostFunc = func(ctx context.Context, osqueryHostId, nodeKey string, teamID *uint, cooldown time.Duration, consumeSecret string) (*fleet.Host, error) {
 		return &fleet.Host{
 			OsqueryHostID: osqueryHostId, NodeKey: nodeKey,
 		}, nil
@@ -291,6 +654,9 @@ func TestAuthenticateHost(t *testing.T) {
 		gotHostIDs = hostIDs
 		return nil
 	}
+	ds.ConsumeHostOnlineSubscriptionsFunc = func(ctx context.Context, hostIDs []uint) ([]*fleet.HostOnlineNotification, error) {
+		return nil, nil
+	}
 	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
 		return &fleet.AppConfig{}, nil
 	}
@@ -382,6 +748,10 @@ func TestSubmitResultLogs(t *testing.T) {
 	testLogger := &testJSONLogger{}
 	serv.osqueryLogWriter = &logging.OsqueryLogger{Result: testLogger}
 
+	ds.ListDiscardDataScheduledQueryNamesFunc = func(ctx context.Context) ([]fleet.PackScheduledQueryName, error) {
+		return nil, nil
+	}
+
 	logs := []string{
 		`{"name":"system_info","hostIdentifier":"some_uuid","calendarTime":"Fri Sep 30 17:55:15 2016 UTC","unixTime":"1475258115","decorations":{"host_uuid":"some_uuid","username":"zwass"},"columns":{"cpu_brand":"Intel(R) Core(TM) i7-4770HQ CPU @ 2.20GHz","hostname":"hostimus","physical_memory":"17179869184"},"action":"added"}`,
 		`{"name":"encrypted","hostIdentifier":"some_uuid","calendarTime":"Fri Sep 30 21:19:15 2016 UTC","unixTime":"1475270355","decorations":{"host_uuid":"4740D59F-699E-5B29-960B-979AAF9BBEEB","username":"zwass"},"columns":{"encrypted":"1","name":"\/dev\/disk1","type":"AES-XTS","uid":"","user_uuid":"","uuid":"some_uuid"},"action":"added"}`,
@@ -404,12 +774,192 @@ func TestSubmitResultLogs(t *testing.T) {
 	assert.Equal(t, results, testLogger.logs)
 }
 
+func TestSubmitResultLogsDiscardData(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	// Hack to get at the service internals and modify the writer
+	serv := ((svc.(validationMiddleware)).Service).(*Service)
+
+	testLogger := &testJSONLogger{}
+	serv.osqueryLogWriter = &logging.OsqueryLogger{Result: testLogger}
+
+	ds.ListDiscardDataScheduledQueryNamesFunc = func(ctx context.Context) ([]fleet.PackScheduledQueryName, error) {
+		return []fleet.PackScheduledQueryName{
+			{PackName: "test", ScheduledQueryName: "hosts"},
+			{PackName: "Global Schedule", ScheduledQueryName: "time"},
+		}, nil
+	}
+
+	logs := []string{
+		`{"name":"pack/test/hosts","action":"added","columns":{"address":"127.0.0.1"}}`,
+		`{"name":"pack_Global Schedule_time","action":"snapshot","snapshot":[{"hour":"20"}]}`,
+		`{"name":"pack/test/hosts2","action":"added","columns":{"address":"127.0.0.1"}}`,
+		`{"name":"pack/other/hosts","action":"added","columns":{"address":"127.0.0.1"}}`,
+		`{"name":"time","action":"snapshot","snapshot":[{"hour":"20"}]}`,
+		`{"unknown":{"foo": [] }}`,
+	}
+	var results []json.RawMessage
+	err := json.Unmarshal([]byte(fmt.Sprintf("[%s]", strings.Join(logs, ","))), &results)
+	require.NoError(t, err)
+
+	ctx := hostctx.NewContext(context.Background(), &fleet.Host{})
+	err = serv.SubmitResultLogs(ctx, results)
+	require.NoError(t, err)
+	assert.Equal(t, results[2:], testLogger.logs)
+
+	// nothing is written if all the logs are discarded
+	testLogger.logs = nil
+	err = serv.SubmitResultLogs(ctx, results[:2])
+	require.NoError(t, err)
+	assert.Nil(t, testLogger.logs)
+}
+
+func TestSubmitResultLogsEnrichResultLogs(t *testing.T) {
+	ds := new(mock.Store)
+	cfg := config.TestConfig()
+	cfg.Osquery.EnrichResultLogs = true
+	svc := newTestServiceWithConfig(t, ds, cfg, nil, nil)
+
+	// Hack to get at the service internals and modify the writer
+	serv := ((svc.(validationMiddleware)).Service).(*Service)
+
+	testLogger := &testJSONLogger{}
+	serv.osqueryLogWriter = &logging.OsqueryLogger{Result: testLogger}
+
+	ds.ListDiscardDataScheduledQueryNamesFunc = func(ctx context.Context) ([]fleet.PackScheduledQueryName, error) {
+		return nil, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+	ds.TeamLogSettingsFunc = func(ctx context.Context, teamID uint) (*fleet.TeamLogSettings, error) {
+		return &fleet.TeamLogSettings{}, nil
+	}
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		require.Equal(t, uint(42), hid)
+		return []*fleet.Label{{Name: "All Hosts"}, {Name: "macOS"}}, nil
+	}
+
+	logs := []json.RawMessage{
+		json.RawMessage(`{"name":"pack/test/hosts","action":"added","columns":{"address":"127.0.0.1"}}`),
+		json.RawMessage(`{"unknown":{"foo": [] }}`),
+		json.RawMessage(`["not", "an", "object"]`),
+	}
+
+	host := &fleet.Host{ID: 42, Hostname: "foo.local", TeamID: ptr.Uint(3)}
+	ctx := hostctx.NewContext(context.Background(), host)
+	require.NoError(t, serv.SubmitResultLogs(ctx, logs))
+	require.Len(t, testLogger.logs, 3)
+	metadata := `{"host_id":42,"hostname":"foo.local","team_id":3,"team_name":"team1","labels":["All Hosts","macOS"]}`
+	assert.JSONEq(t, `{"name":"pack/test/hosts","action":"added","columns":{"address":"127.0.0.1"},"fleet":`+metadata+`}`, string(testLogger.logs[0]))
+	assert.JSONEq(t, `{"unknown":{"foo":[]},"fleet":`+metadata+`}`, string(testLogger.logs[1]))
+	assert.Equal(t, logs[2], testLogger.logs[2])
+
+	// hosts without team
+	ds.TeamFuncInvoked = false
+	testLogger.logs = nil
+	host.TeamID = nil
+	require.NoError(t, serv.SubmitResultLogs(ctx, logs[:1]))
+	require.Len(t, testLogger.logs, 1)
+	assert.False(t, ds.TeamFuncInvoked)
+	assert.JSONEq(t,
+		`{"name":"pack/test/hosts","action":"added","columns":{"address":"127.0.0.1"},"fleet":{"host_id":42,"hostname":"foo.local","team_id":null,"team_name":null,"labels":["All Hosts","macOS"]}}`,
+		string(testLogger.logs[0]),
+	)
+}
+
+func TestSubmitResultLogsTeamLogSettings(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	// Hack to get at the service internals and modify the writer
+	serv := ((svc.(validationMiddleware)).Service).(*Service)
+
+	globalLogger, splunkLogger := &testJSONLogger{}, &testJSONLogger{}
+	osqueryLogger := &logging.OsqueryLogger{Result: globalLogger}
+	osqueryLogger.SetResultWriter("splunk", splunkLogger)
+	serv.osqueryLogWriter = osqueryLogger
+
+	ds.ListDiscardDataScheduledQueryNamesFunc = func(ctx context.Context) ([]fleet.PackScheduledQueryName, error) {
+		return nil, nil
+	}
+	ds.TeamLogSettingsFunc = func(ctx context.Context, teamID uint) (*fleet.TeamLogSettings, error) {
+		switch teamID {
+		case 1:
+			return &fleet.TeamLogSettings{ResultLogPlugin: "splunk"}, nil
+		case 2:
+			return &fleet.TeamLogSettings{ResultLogPlugin: "nope"}, nil
+		default:
+			return &fleet.TeamLogSettings{}, nil
+		}
+	}
+
+	logs := []json.RawMessage{json.RawMessage(`{"name":"pack/test/hosts","action":"added"}`)}
+
+	// hosts without team use the global plugin
+	ctx := hostctx.NewContext(context.Background(), &fleet.Host{})
+	require.NoError(t, serv.SubmitResultLogs(ctx, logs))
+	assert.Equal(t, logs, globalLogger.logs)
+	assert.False(t, ds.TeamLogSettingsFuncInvoked)
+
+	// teams without log settings use the global plugin
+	globalLogger.logs = nil
+	ctx = hostctx.NewContext(context.Background(), &fleet.Host{TeamID: ptr.Uint(3)})
+	require.NoError(t, serv.SubmitResultLogs(ctx, logs))
+	assert.Equal(t, logs, globalLogger.logs)
+	assert.Empty(t, splunkLogger.logs)
+
+	// teams with log settings use their plugin
+	globalLogger.logs = nil
+	ctx = hostctx.NewContext(context.Background(), &fleet.Host{TeamID: ptr.Uint(1)})
+	require.NoError(t, serv.SubmitResultLogs(ctx, logs))
+	assert.Empty(t, globalLogger.logs)
+	assert.Equal(t, logs, splunkLogger.logs)
+
+	// the plugin of the team cannot be created
+	ctx = hostctx.NewContext(context.Background(), &fleet.Host{TeamID: ptr.Uint(2)})
+	require.Error(t, serv.SubmitResultLogs(ctx, logs))
+}
+
+func TestSubmitResultLogsAuditLogs(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	// Hack to get at the service internals and modify the writer
+	serv := ((svc.(validationMiddleware)).Service).(*Service)
+
+	resultLogger, auditLogger := &testJSONLogger{}, &testJSONLogger{}
+	osqueryLogger := &logging.OsqueryLogger{Result: resultLogger}
+	osqueryLogger.SetAuditWriter(auditLogger, "pack/*/process_events")
+	serv.osqueryLogWriter = osqueryLogger
+
+	ds.ListDiscardDataScheduledQueryNamesFunc = func(ctx context.Context) ([]fleet.PackScheduledQueryName, error) {
+		return nil, nil
+	}
+
+	logs := []json.RawMessage{
+		json.RawMessage(`{"name":"pack/audit/process_events","action":"added"}`),
+		json.RawMessage(`{"name":"pack/it/apps","action":"snapshot"}`),
+	}
+	ctx := hostctx.NewContext(context.Background(), &fleet.Host{})
+	require.NoError(t, serv.SubmitResultLogs(ctx, logs))
+	assert.Equal(t, logs[1:], resultLogger.logs)
+	assert.Equal(t, logs[:1], auditLogger.logs)
+
+	// the result logger is not called without result logs
+	resultLogger.logs = nil
+	require.NoError(t, serv.SubmitResultLogs(ctx, logs[:1]))
+	assert.Nil(t, resultLogger.logs)
+}
+
 func verifyDiscovery(t *testing.T, queries, discovery map[string]string) {
 	assert.Equal(t, len(queries), len(discovery))
 	// discoveryUsed holds the queries where we know use the distributed discovery feature.
 	discoveryUsed := map[string]struct{}{
 		hostDetailQueryPrefix + "google_chrome_profiles": {},
 		hostDetailQueryPrefix + "orbit_info":             {},
+		hostDetailQueryPrefix + "launcher_info":          {},
 	}
 	for name := range queries {
 		require.NotEmpty(t, discovery[name])
@@ -686,7 +1236,7 @@ func TestDetailQueriesWithEmptyStrings(t *testing.T) {
 	// queries)
 	queries, discovery, acc, err := svc.GetDistributedQueries(ctx)
 	require.NoError(t, err)
-	require.Len(t, queries, expectedDetailQueries-2)
+	require.Len(t, queries, expectedDetailQueries-3)
 	verifyDiscovery(t, queries, discovery)
 	assert.NotZero(t, acc)
 
@@ -881,6 +1431,12 @@ func TestDetailQueries(t *testing.T) {
 		require.Equal(t, "foo", authToken)
 		return nil
 	}
+	gotAgentVersions := make(map[string]string)
+	ds.SetOrUpdateHostAgentVersionFunc = func(ctx context.Context, hostID uint, component string, version string) error {
+		require.Equal(t, uint(1), hostID)
+		gotAgentVersions[component] = version
+		return nil
+	}
 	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
 		if id != 1 {
 			return nil, errors.New("not found")
@@ -892,7 +1448,7 @@ func TestDetailQueries(t *testing.T) {
 	// queries)
 	queries, discovery, acc, err := svc.GetDistributedQueries(ctx)
 	require.NoError(t, err)
-	require.Len(t, queries, expectedDetailQueries-1)
+	require.Len(t, queries, expectedDetailQueries-2)
 	verifyDiscovery(t, queries, discovery)
 	assert.NotZero(t, acc)
 
@@ -1073,6 +1629,7 @@ func TestDetailQueries(t *testing.T) {
 	require.True(t, ds.SetOrUpdateMDMDataFuncInvoked)
 	require.True(t, ds.SetOrUpdateMunkiVersionFuncInvoked)
 	require.True(t, ds.SetOrUpdateDeviceAuthTokenFuncInvoked)
+	assert.Equal(t, map[string]string{fleet.AgentComponentOrbit: "42", fleet.AgentComponentFleetDesktop: ""}, gotAgentVersions)
 
 	// osquery_info
 	assert.Equal(t, "darwin", gotHost.Platform)
@@ -1191,12 +1748,28 @@ func TestNewDistributedQueryCampaign(t *testing.T) {
 		return target, nil
 	}
 
-	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
 		return fleet.TargetMetrics{}, nil
 	}
-	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
 		return []uint{1, 3, 5}, nil
 	}
+	var usageHostIDs []uint
+	ds.RecordLiveQueryCampaignUsageFunc = func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+		usageHostIDs = hostIDs
+		return nil
+	}
+	var executionHostIDs []uint
+	ds.NewDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+		assert.Equal(t, uint(21), campaignID)
+		executionHostIDs = hostIDs
+		return nil
+	}
+	var gotAuditLog *fleet.LiveQueryAuditLog
+	ds.NewLiveQueryAuditLogFunc = func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+		gotAuditLog = entry
+		return nil
+	}
 	lq.On("RunQuery", "21", "select year, month, day, hour, minutes, seconds from time", []uint{1, 3, 5}).Return(nil)
 	viewerCtx := viewer.NewContext(context.Background(), viewer.Viewer{
 		User: &fleet.User{
@@ -1208,23 +1781,169 @@ func TestNewDistributedQueryCampaign(t *testing.T) {
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
 		return nil
 	}
-	campaign, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	campaign, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, false, nil, fleet.DistributedQueryResultLimits{})
+	require.NoError(t, err)
+	assert.Equal(t, gotQuery.ID, gotCampaign.QueryID)
+	assert.True(t, ds.NewActivityFuncInvoked)
+	assert.Equal(t, []uint{1, 3, 5}, usageHostIDs)
+	assert.Equal(t, []uint{1, 3, 5}, executionHostIDs)
+	require.NotNil(t, gotAuditLog)
+	assert.Equal(t, uint(21), gotAuditLog.CampaignID)
+	assert.Equal(t, uint(42), gotAuditLog.QueryID)
+	assert.Equal(t, q, gotAuditLog.QuerySQL)
+	assert.Equal(t, uint(3), gotAuditLog.TargetedHosts)
+	assert.JSONEq(t, `{"hosts":[2],"labels":[1],"teams":null}`, string(gotAuditLog.Targets))
+	assert.Equal(t, []*fleet.DistributedQueryCampaignTarget{
+		{
+			Type:                       fleet.TargetHost,
+			DistributedQueryCampaignID: campaign.ID,
+			TargetID:                   2,
+		},
+		{
+			Type:                       fleet.TargetLabel,
+			DistributedQueryCampaignID: campaign.ID,
+			TargetID:                   1,
+		},
+	}, gotTargets,
+	)
+}
+
+func TestNewDistributedQueryCampaignLimits(t *testing.T) {
+	ds := new(mock.Store)
+	rs := &mock.QueryResultStore{
+		HealthCheckFunc: func() error {
+			return nil
+		},
+	}
+	lq := &live_query.MockLiveQuery{}
+	cfg := config.TestConfig()
+	cfg.Osquery.MaxLiveQueryCampaigns = 10
+	cfg.Osquery.MaxLiveQueryCampaignsPerUser = 2
+	svc := newTestServiceWithConfig(t, ds, cfg, rs, lq)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return &fleet.Query{ID: 42, Name: "query", Query: "select 1;"}, nil
+	}
+	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
+		camp.ID = 21
+		return camp, nil
+	}
+	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
+		return target, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
+		return []uint{1}, nil
+	}
+	ds.RecordLiveQueryCampaignUsageFunc = func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+		return nil
+	}
+	ds.NewDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+		return nil
+	}
+	ds.NewLiveQueryAuditLogFunc = func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+		return nil
+	}
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
+		return fleet.TargetMetrics{}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+	lq.On("RunQuery", "21", "select 1;", []uint{1}).Return(nil)
+
+	userCount, totalCount := 1, 9
+	ds.CountActiveDistributedQueryCampaignsFunc = func(ctx context.Context, userID *uint) (int, error) {
+		if userID != nil {
+			assert.Equal(t, uint(7), *userID)
+			return userCount, nil
+		}
+		return totalCount, nil
+	}
+
+	viewerCtx := viewer.NewContext(context.Background(), viewer.Viewer{
+		User: &fleet.User{ID: 7, GlobalRole: ptr.String(fleet.RoleAdmin)},
+	})
+	run := func() error {
+		ds.NewDistributedQueryCampaignFuncInvoked = false
+		_, err := svc.NewDistributedQueryCampaign(viewerCtx, "", ptr.Uint(42), fleet.HostTargets{HostIDs: []uint{1}}, false, nil, fleet.DistributedQueryResultLimits{})
+		return err
+	}
+
+	// below both limits
+	require.NoError(t, run())
+	assert.True(t, ds.NewDistributedQueryCampaignFuncInvoked)
+
+	// the user reached their limit
+	userCount = 2
+	err := run()
+	var limitErr *fleet.LiveQueryCampaignLimitError
+	require.ErrorAs(t, err, &limitErr)
+	assert.Contains(t, err.Error(), "you already have 2 live queries running")
+	assert.False(t, ds.NewDistributedQueryCampaignFuncInvoked)
+
+	// the organization reached its limit
+	userCount, totalCount = 0, 10
+	err = run()
+	require.ErrorAs(t, err, &limitErr)
+	assert.Contains(t, err.Error(), "10 live queries are already running")
+	assert.False(t, ds.NewDistributedQueryCampaignFuncInvoked)
+}
+
+func TestRemoveDistributedQueryCampaignTargets(t *testing.T) {
+	ds := new(mock.Store)
+	lq := &live_query.MockLiveQuery{}
+	svc := newTestService(t, ds, nil, lq)
+
+	campaign := &fleet.DistributedQueryCampaign{ID: 21, UserID: 7, Status: fleet.QueryRunning}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return campaign, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
+		assert.Equal(t, fleet.HostTargets{HostIDs: []uint{1}, LabelIDs: []uint{2}}, targets)
+		return []uint{1, 3}, nil
+	}
+	var excluded []fleet.DistributedQueryCampaignTarget
+	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
+		excluded = append(excluded, *target)
+		return target, nil
+	}
+	ds.DeletePendingDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint) (uint, error) {
+		assert.Equal(t, uint(21), campaignID)
+		assert.Equal(t, []uint{1, 3}, hostIDs)
+		return 1, nil
+	}
+	lq.On("RemoveHostsFromQuery", "21", []uint{1, 3}).Return(nil)
+
+	viewerCtx := viewer.NewContext(context.Background(), viewer.Viewer{
+		User: &fleet.User{ID: 7, GlobalRole: ptr.String(fleet.RoleAdmin)},
+	})
+	removed, err := svc.RemoveDistributedQueryCampaignTargets(viewerCtx, 21, []uint{1}, []uint{2})
 	require.NoError(t, err)
-	assert.Equal(t, gotQuery.ID, gotCampaign.QueryID)
-	assert.True(t, ds.NewActivityFuncInvoked)
-	assert.Equal(t, []*fleet.DistributedQueryCampaignTarget{
-		{
-			Type:                       fleet.TargetHost,
-			DistributedQueryCampaignID: campaign.ID,
-			TargetID:                   2,
-		},
-		{
-			Type:                       fleet.TargetLabel,
-			DistributedQueryCampaignID: campaign.ID,
-			TargetID:                   1,
-		},
-	}, gotTargets,
-	)
+	assert.Equal(t, uint(1), removed)
+	assert.Equal(t, []fleet.DistributedQueryCampaignTarget{
+		{Type: fleet.TargetHost, DistributedQueryCampaignID: 21, TargetID: 1, Excluded: true},
+		{Type: fleet.TargetLabel, DistributedQueryCampaignID: 21, TargetID: 2, Excluded: true},
+	}, excluded)
+	lq.AssertExpectations(t)
+
+	// at least one target must be removed
+	_, err = svc.RemoveDistributedQueryCampaignTargets(viewerCtx, 21, nil, nil)
+	var invalidErr *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &invalidErr)
+
+	// the campaign must be running
+	campaign.Status = fleet.QueryComplete
+	_, err = svc.RemoveDistributedQueryCampaignTargets(viewerCtx, 21, []uint{1}, nil)
+	require.ErrorAs(t, err, &invalidErr)
+
+	// the campaign must have been run by the user
+	campaign.Status, campaign.UserID = fleet.QueryRunning, 8
+	_, err = svc.RemoveDistributedQueryCampaignTargets(viewerCtx, 21, []uint{1}, nil)
+	var forbiddenErr *authz.Forbidden
+	require.ErrorAs(t, err, &forbiddenErr)
 }
 
 func TestDistributedQueryResults(t *testing.T) {
@@ -1235,6 +1954,22 @@ func TestDistributedQueryResults(t *testing.T) {
 	svc := newTestServiceWithClock(t, ds, rs, lq, mockClock)
 
 	campaign := &fleet.DistributedQueryCampaign{ID: 42}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return campaign, nil
+	}
+	ds.UpdateDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, status fleet.DistributedQueryExecutionStatus) error {
+		assert.Equal(t, campaign.ID, campaignID)
+		assert.Equal(t, []uint{1}, hostIDs)
+		assert.Equal(t, fleet.ExecutionSucceeded, status)
+		return nil
+	}
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, []uint{1}, hostIDs)
+		assert.Equal(t, campaign.UserID, user.ID)
+		assert.Equal(t, fleet.HostActivityTypeLiveQuery, activityType)
+		assert.Equal(t, campaign.ID, (*details)["campaign_id"])
+		return nil
+	}
 
 	ds.LabelQueriesForHostFunc = func(ctx context.Context, host *fleet.Host) (map[string]string, error) {
 		return map[string]string{}, nil
@@ -1275,7 +2010,7 @@ func TestDistributedQueryResults(t *testing.T) {
 	// Now we should get the active distributed query
 	queries, discovery, acc, err := svc.GetDistributedQueries(hostCtx)
 	require.NoError(t, err)
-	require.Len(t, queries, expectedDetailQueries-1)
+	require.Len(t, queries, expectedDetailQueries-2)
 	verifyDiscovery(t, queries, discovery)
 	queryKey := fmt.Sprintf("%s%d", hostDistributedQueryPrefix, campaign.ID)
 	assert.Equal(t, "select * from time", queries[queryKey])
@@ -1333,6 +2068,12 @@ func TestDistributedQueryResults(t *testing.T) {
 
 	err = svc.SubmitDistributedQueryResults(hostCtx, results, map[string]fleet.OsqueryStatus{}, map[string]string{})
 	require.NoError(t, err)
+	// the execution and activity are written by the next flush
+	assert.False(t, ds.UpdateDistributedQueryExecutionsFuncInvoked)
+	assert.False(t, ds.NewHostActivitiesFuncInvoked)
+	require.NoError(t, svc.FlushLiveQueryResults(context.Background()))
+	assert.True(t, ds.UpdateDistributedQueryExecutionsFuncInvoked)
+	assert.True(t, ds.NewHostActivitiesFuncInvoked)
 }
 
 func TestIngestDistributedQueryParseIdError(t *testing.T) {
@@ -1377,7 +2118,7 @@ func TestIngestDistributedQueryOrphanedCampaignLoadError(t *testing.T) {
 
 	err := svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", []map[string]string{}, false, "")
 	require.Error(t, err)
-	assert.Contains(t, err.Error(), "loading orphaned campaign")
+	assert.Contains(t, err.Error(), "loading campaign")
 }
 
 func TestIngestDistributedQueryOrphanedCampaignWaitListener(t *testing.T) {
@@ -1540,6 +2281,10 @@ func TestIngestDistributedQueryRecordCompletionError(t *testing.T) {
 	campaign := &fleet.DistributedQueryCampaign{ID: 42}
 	host := fleet.Host{ID: 1}
 
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return campaign, nil
+	}
+
 	lq.On("QueryCompletedByHost", strconv.Itoa(int(campaign.ID)), host.ID).Return(errors.New("fail"))
 
 	go func() {
@@ -1561,15 +2306,21 @@ func TestIngestDistributedQuery(t *testing.T) {
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	svc := &Service{
-		ds:             ds,
-		resultStore:    rs,
-		liveQueryStore: lq,
-		logger:         log.NewNopLogger(),
-		clock:          mockClock,
+		ds:              ds,
+		resultStore:     rs,
+		liveQueryStore:  lq,
+		logger:          log.NewNopLogger(),
+		clock:           mockClock,
+		queryRowsUsage:  newQueryRowsUsageCounts(),
+		liveQueryWrites: newLiveQueryResultWrites(),
 	}
 
-	campaign := &fleet.DistributedQueryCampaign{ID: 42}
-	host := fleet.Host{ID: 1}
+	campaign := &fleet.DistributedQueryCampaign{ID: 42, UserID: 7}
+	host := fleet.Host{ID: 1, TeamID: ptr.Uint(3)}
+
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return campaign, nil
+	}
 
 	lq.On("QueryCompletedByHost", strconv.Itoa(int(campaign.ID)), host.ID).Return(nil)
 
@@ -1580,19 +2331,216 @@ func TestIngestDistributedQuery(t *testing.T) {
 	}()
 	time.Sleep(10 * time.Millisecond)
 
-	err := svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", []map[string]string{}, false, "")
+	rows := []map[string]string{{"name": "ssh"}, {"name": "cron"}}
+	err := svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", rows, false, "")
+	require.NoError(t, err)
+	lq.AssertExpectations(t)
+	// the rows are counted in memory, not recorded in the datastore
+	assert.False(t, ds.RecordLiveQueryRowsUsageFuncInvoked)
+	now := mockClock.Now().UTC()
+	assert.Equal(t, []fleet.QueryRowsUsage{{
+		UserID: 7,
+		TeamID: 3,
+		Date:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		Rows:   2,
+	}}, svc.queryRowsUsage.getAndClear())
+	// so are the execution and activity of the host
+	assert.False(t, ds.UpdateDistributedQueryExecutionsFuncInvoked)
+	writes := svc.liveQueryWrites.getAndClear()
+	require.Contains(t, writes, campaign.ID)
+	assert.Equal(t, []uint{host.ID}, writes[campaign.ID].succeededHosts)
+	assert.Empty(t, writes[campaign.ID].failedHosts)
+}
+
+func TestIngestDistributedQueryAnonymized(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	ds := new(mock.Store)
+	rs := pubsub.NewInmemQueryResults()
+	lq := new(live_query.MockLiveQuery)
+	svc := &Service{
+		ds:              ds,
+		resultStore:     rs,
+		liveQueryStore:  lq,
+		logger:          log.NewNopLogger(),
+		clock:           mockClock,
+		queryRowsUsage:  newQueryRowsUsageCounts(),
+		liveQueryWrites: newLiveQueryResultWrites(),
+	}
+
+	campaign := &fleet.DistributedQueryCampaign{ID: 42, Anonymize: true, PseudonymKey: "key"}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return campaign, nil
+	}
+
+	hosts := []fleet.Host{
+		{ID: 1, Hostname: "alice-laptop", UUID: "uuid-1", HardwareSerial: "serial-1"},
+		{ID: 2, Hostname: "bob-laptop", UUID: "uuid-2", HardwareSerial: "serial-2"},
+	}
+	for _, host := range hosts {
+		lq.On("QueryCompletedByHost", strconv.Itoa(int(campaign.ID)), host.ID).Return(nil)
+	}
+
+	ch, err := rs.ReadChannel(context.Background(), *campaign)
 	require.NoError(t, err)
+
+	var results []fleet.DistributedQueryResult
+	for _, host := range hosts {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			select {
+			case val := <-ch:
+				res, ok := val.(fleet.DistributedQueryResult)
+				require.True(t, ok)
+				results = append(results, res)
+			case <-time.After(1 * time.Second):
+				t.Error("No result received")
+			}
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		rows := []map[string]string{
+			{"username": "alice", "hostname": host.Hostname, "name": "ssh"},
+			{"username": "", "name": "cron"},
+		}
+		err := svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", rows, false, "")
+		require.NoError(t, err)
+		<-done
+	}
+	require.Len(t, results, 2)
+
+	for i, res := range results {
+		// only the pseudonym of the host is sent
+		assert.Equal(t, fleet.Host{Hostname: res.Host.Hostname}, res.Host)
+		assert.NotEmpty(t, res.Host.Hostname)
+		assert.NotContains(t, res.Host.Hostname, "laptop")
+
+		assert.NotEqual(t, hosts[i].Hostname, res.Rows[0]["hostname"])
+		assert.NotEqual(t, "alice", res.Rows[0]["username"])
+		assert.Equal(t, "ssh", res.Rows[0]["name"])
+		assert.Equal(t, "", res.Rows[1]["username"])
+		assert.Equal(t, "cron", res.Rows[1]["name"])
+	}
+
+	// pseudonyms are stable within the campaign
+	assert.NotEqual(t, results[0].Host.Hostname, results[1].Host.Hostname)
+	assert.NotEqual(t, results[0].Rows[0]["hostname"], results[1].Rows[0]["hostname"])
+	assert.Equal(t, results[0].Rows[0]["username"], results[1].Rows[0]["username"])
+
+	// and differ across campaigns
+	res := fleet.DistributedQueryResult{Host: hosts[0], Rows: []map[string]string{{"username": "alice"}}}
+	anonymizeDistributedQueryResult(&fleet.DistributedQueryCampaign{ID: 43, Anonymize: true, PseudonymKey: "other"}, &res)
+	assert.NotEqual(t, results[0].Host.Hostname, res.Host.Hostname)
+	assert.NotEqual(t, results[0].Rows[0]["username"], res.Rows[0]["username"])
+
 	lq.AssertExpectations(t)
 }
 
+func TestIngestDistributedQueryTruncated(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	ds := new(mock.Store)
+	rs := pubsub.NewInmemQueryResults()
+	lq := new(live_query.MockLiveQuery)
+	svc := &Service{
+		ds:              ds,
+		resultStore:     rs,
+		liveQueryStore:  lq,
+		logger:          log.NewNopLogger(),
+		clock:           mockClock,
+		queryRowsUsage:  newQueryRowsUsageCounts(),
+		liveQueryWrites: newLiveQueryResultWrites(),
+	}
+
+	campaign := &fleet.DistributedQueryCampaign{ID: 42}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return campaign, nil
+	}
+	host := fleet.Host{ID: 1}
+	lq.On("QueryCompletedByHost", strconv.Itoa(int(campaign.ID)), host.ID).Return(nil)
+
+	ch, err := rs.ReadChannel(context.Background(), *campaign)
+	require.NoError(t, err)
+
+	rows := []map[string]string{{"name": "ssh"}, {"name": "cron"}, {"name": "launchd"}}
+	ingest := func(limits fleet.DistributedQueryResultLimits) fleet.DistributedQueryResult {
+		campaign.DistributedQueryResultLimits = limits
+		var res fleet.DistributedQueryResult
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			select {
+			case val := <-ch:
+				var ok bool
+				res, ok = val.(fleet.DistributedQueryResult)
+				require.True(t, ok)
+			case <-time.After(1 * time.Second):
+				t.Error("No result received")
+			}
+		}()
+		time.Sleep(10 * time.Millisecond)
+
+		err := svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", rows, false, "")
+		require.NoError(t, err)
+		<-done
+		return res
+	}
+
+	// no limits
+	res := ingest(fleet.DistributedQueryResultLimits{})
+	assert.Equal(t, rows, res.Rows)
+	assert.False(t, res.Truncated)
+
+	// limits not exceeded
+	res = ingest(fleet.DistributedQueryResultLimits{MaxRows: 3, MaxResultBytes: 100})
+	assert.Equal(t, rows, res.Rows)
+	assert.False(t, res.Truncated)
+
+	// the rows returned are counted in the usage
+	usage := svc.queryRowsUsage.getAndClear()
+	require.Len(t, usage, 1)
+	assert.Equal(t, uint64(6), usage[0].Rows)
+
+	// max rows exceeded
+	res = ingest(fleet.DistributedQueryResultLimits{MaxRows: 2})
+	assert.Equal(t, rows[:2], res.Rows)
+	assert.True(t, res.Truncated)
+	usage = svc.queryRowsUsage.getAndClear()
+	require.Len(t, usage, 1)
+	assert.Equal(t, uint64(2), usage[0].Rows)
+
+	// max bytes exceeded, the second row does not fit
+	res = ingest(fleet.DistributedQueryResultLimits{MaxResultBytes: uint(len("namessh") + len("namecron") - 1)})
+	assert.Equal(t, rows[:1], res.Rows)
+	assert.True(t, res.Truncated)
+	usage = svc.queryRowsUsage.getAndClear()
+	require.Len(t, usage, 1)
+	assert.Equal(t, uint64(1), usage[0].Rows)
+}
+
 func TestUpdateHostIntervals(t *testing.T) {
 	ds := new(mock.Store)
 
 	svc := newTestService(t, ds, nil, nil)
 
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+
 	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
 		return []*fleet.Pack{}, nil
 	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
 
 	testCases := []struct {
 		name                  string
@@ -1738,6 +2686,72 @@ func TestUpdateHostIntervals(t *testing.T) {
 	}
 }
 
+func TestGetClientConfigIntervalJitter(t *testing.T) {
+	ds := new(mock.Store)
+
+	cfg := config.TestConfig()
+	cfg.Osquery.IntervalJitterPercent = 10
+	svc := newTestServiceWithConfig(t, ds, cfg, nil, nil)
+
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{}, nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{"options": {
+			"distributed_interval": 100,
+			"logger_tls_period":    600,
+			"logger_plugin":        "tls"
+		}}}`))}, nil
+	}
+	var gotIntervals fleet.HostOsqueryIntervals
+	ds.UpdateHostOsqueryIntervalsFunc = func(ctx context.Context, hostID uint, intervals fleet.HostOsqueryIntervals) error {
+		gotIntervals = intervals
+		return nil
+	}
+
+	getIntervals := func(hostID uint) (uint, uint) {
+		ctx := hostctx.NewContext(context.Background(), &fleet.Host{ID: hostID, NodeKey: "123456"})
+		conf, err := svc.GetClientConfig(ctx)
+		require.NoError(t, err)
+		options := conf["options"].(map[string]interface{})
+		require.Equal(t, "tls", options["logger_plugin"])
+		// the jittered intervals are the ones recorded for the host
+		require.Equal(t, options["distributed_interval"], gotIntervals.DistributedInterval)
+		require.Equal(t, options["logger_tls_period"], gotIntervals.LoggerTLSPeriod)
+		return gotIntervals.DistributedInterval, gotIntervals.LoggerTLSPeriod
+	}
+
+	seen := make(map[uint]bool)
+	for hostID := uint(1); hostID <= 30; hostID++ {
+		distributedInterval, loggerTLSPeriod := getIntervals(hostID)
+		require.GreaterOrEqual(t, distributedInterval, uint(90))
+		require.LessOrEqual(t, distributedInterval, uint(110))
+		require.GreaterOrEqual(t, loggerTLSPeriod, uint(540))
+		require.LessOrEqual(t, loggerTLSPeriod, uint(660))
+		seen[distributedInterval] = true
+
+		// the jitter of a host is stable
+		again, _ := getIntervals(hostID)
+		require.Equal(t, distributedInterval, again)
+	}
+	require.Greater(t, len(seen), 1)
+}
+
 type notFoundError struct{}
 
 func (e notFoundError) Error() string {
@@ -1945,6 +2959,61 @@ func TestDistributedQueriesReloadsHostIfDetailsAreIn(t *testing.T) {
 	assert.True(t, ds.UpdateHostFuncInvoked)
 }
 
+func TestDistributedQueriesAsyncDetailIngestion(t *testing.T) {
+	ds := new(mock.Store)
+	cfg := config.TestConfig()
+	cfg.Osquery.EnableAsyncDetailIngestion = true
+	cfg.Osquery.AsyncDetailIngestionQueueSize = 10
+	cfg.Osquery.AsyncDetailIngestionWorkers = 1
+	cfg.Osquery.AsyncDetailIngestionUpdateBatch = 10
+	cfg.Osquery.AsyncDetailIngestionInterval = 10 * time.Millisecond
+	svc := newTestServiceWithConfig(t, ds, cfg, nil, nil)
+
+	ds.UpdateHostFunc = func(ctx context.Context, host *fleet.Host) error {
+		return nil
+	}
+	updatedCh := make(chan []*fleet.Host, 1)
+	ds.UpdateHostsDetailsFunc = func(ctx context.Context, hosts []*fleet.Host) error {
+		updatedCh <- hosts
+		return nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
+	host := &fleet.Host{
+		ID:               42,
+		Platform:         "darwin",
+		RefetchRequested: true,
+	}
+	ctx := hostctx.NewContext(context.Background(), host)
+
+	err := svc.SubmitDistributedQueryResults(
+		ctx,
+		map[string][]map[string]string{
+			hostDetailQueryPrefix + "os_version": {{"name": "macOS", "major": "12", "minor": "3", "patch": "0", "build": "21E230", "platform": "darwin"}},
+		},
+		map[string]fleet.OsqueryStatus{},
+		map[string]string{},
+	)
+	require.NoError(t, err)
+	// the host is not saved in the request
+	assert.False(t, ds.UpdateHostFuncInvoked)
+
+	select {
+	case hosts := <-updatedCh:
+		require.Len(t, hosts, 1)
+		assert.Equal(t, uint(42), hosts[0].ID)
+		assert.Equal(t, "macOS 12.3.0", hosts[0].OSVersion)
+		// the details are ingested in a copy of the host of the request
+		assert.Empty(t, host.OSVersion)
+		assert.False(t, hosts[0].RefetchRequested)
+		assert.False(t, hosts[0].DetailUpdatedAt.IsZero())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the host to be saved")
+	}
+}
+
 func TestObserversCanOnlyRunDistributedCampaigns(t *testing.T) {
 	ds := new(mock.Store)
 	rs := &mock.QueryResultStore{
@@ -1963,6 +3032,15 @@ func TestObserversCanOnlyRunDistributedCampaigns(t *testing.T) {
 	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
 		return camp, nil
 	}
+	ds.RecordLiveQueryCampaignUsageFunc = func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+		return nil
+	}
+	ds.NewDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+		return nil
+	}
+	ds.NewLiveQueryAuditLogFunc = func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+		return nil
+	}
 	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
 		return &fleet.Query{
 			ID:             42,
@@ -1979,10 +3057,10 @@ func TestObserversCanOnlyRunDistributedCampaigns(t *testing.T) {
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
 		return nil
 	}
-	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, false, nil, fleet.DistributedQueryResultLimits{})
 	require.Error(t, err)
 
-	_, err = svc.NewDistributedQueryCampaign(viewerCtx, "", ptr.Uint(42), fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	_, err = svc.NewDistributedQueryCampaign(viewerCtx, "", ptr.Uint(42), fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, false, nil, fleet.DistributedQueryResultLimits{})
 	require.Error(t, err)
 
 	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
@@ -2002,20 +3080,29 @@ func TestObserversCanOnlyRunDistributedCampaigns(t *testing.T) {
 		camp.ID = 21
 		return camp, nil
 	}
+	ds.RecordLiveQueryCampaignUsageFunc = func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+		return nil
+	}
+	ds.NewDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+		return nil
+	}
+	ds.NewLiveQueryAuditLogFunc = func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+		return nil
+	}
 	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
 		return target, nil
 	}
-	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
 		return fleet.TargetMetrics{}, nil
 	}
-	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
 		return []uint{1, 3, 5}, nil
 	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
 		return nil
 	}
 	lq.On("RunQuery", "21", "select 1;", []uint{1, 3, 5}).Return(nil)
-	_, err = svc.NewDistributedQueryCampaign(viewerCtx, "", ptr.Uint(42), fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	_, err = svc.NewDistributedQueryCampaign(viewerCtx, "", ptr.Uint(42), fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, false, nil, fleet.DistributedQueryResultLimits{})
 	require.NoError(t, err)
 }
 
@@ -2037,6 +3124,15 @@ func TestTeamMaintainerCanRunNewDistributedCampaigns(t *testing.T) {
 	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
 		return camp, nil
 	}
+	ds.RecordLiveQueryCampaignUsageFunc = func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+		return nil
+	}
+	ds.NewDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+		return nil
+	}
+	ds.NewLiveQueryAuditLogFunc = func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+		return nil
+	}
 	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
 		return &fleet.Query{
 			ID:             42,
@@ -2063,17 +3159,17 @@ func TestTeamMaintainerCanRunNewDistributedCampaigns(t *testing.T) {
 	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
 		return target, nil
 	}
-	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
 		return fleet.TargetMetrics{}, nil
 	}
-	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
 		return []uint{1, 3, 5}, nil
 	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
 		return nil
 	}
 	lq.On("RunQuery", "0", "select year, month, day, hour, minutes, seconds from time", []uint{1, 3, 5}).Return(nil)
-	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}, TeamIDs: []uint{123}})
+	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}, TeamIDs: []uint{123}}, false, nil, fleet.DistributedQueryResultLimits{})
 	require.NoError(t, err)
 }
 
@@ -2115,6 +3211,9 @@ func TestPolicyQueries(t *testing.T) {
 	ds.FlippingPoliciesForHostFunc = func(ctx context.Context, hostID uint, incomingResults map[uint]*bool) (newFailing []uint, newPassing []uint, err error) {
 		return nil, nil, nil
 	}
+	ds.QueueHostPolicyAutoRemediationsFunc = func(ctx context.Context, hostID uint, failingPolicyIDs []uint) error {
+		return nil
+	}
 
 	ctx := hostctx.NewContext(context.Background(), host)
 
@@ -2257,6 +3356,153 @@ func TestPolicyQueries(t *testing.T) {
 	noPolicyResults(queries)
 }
 
+func TestCriticalPolicyQueries(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	ds := new(mock.Store)
+	lq := new(live_query.MockLiveQuery)
+	cfg := config.TestConfig()
+	cfg.Osquery.PolicyUpdateInterval = 1 * time.Hour
+	cfg.Osquery.CriticalPolicyUpdateInterval = 10 * time.Minute
+	svc := newTestServiceWithConfig(t, ds, cfg, nil, lq, TestServerOpts{Clock: mockClock})
+
+	host := &fleet.Host{
+		Platform: "darwin",
+	}
+
+	ds.LabelQueriesForHostFunc = func(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+		return map[string]string{}, nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.UpdateHostFunc = func(ctx context.Context, gotHost *fleet.Host) error {
+		host = gotHost
+		return nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{HostSettings: fleet.HostSettings{EnableHostUsers: true}}, nil
+	}
+
+	lq.On("QueriesForHost", uint(0)).Return(map[string]string{}, nil)
+
+	ds.PolicyQueriesForHostFunc = func(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+		return map[string]string{"1": "select 1", "2": "select 42;"}, nil
+	}
+	criticalQueries := map[string]string{"2": "select 42;"}
+	ds.CriticalPolicyQueriesForHostFunc = func(ctx context.Context, host *fleet.Host) (map[string]string, error) {
+		return criticalQueries, nil
+	}
+	ds.RecordPolicyQueryExecutionsFunc = func(ctx context.Context, gotHost *fleet.Host, results map[uint]*bool, updated time.Time, deferred bool) error {
+		return nil
+	}
+	var recordedCriticalResults map[uint]*bool
+	ds.RecordCriticalPolicyQueryExecutionsFunc = func(ctx context.Context, gotHost *fleet.Host, results map[uint]*bool, updated time.Time) error {
+		recordedCriticalResults = results
+		return nil
+	}
+	ds.FlippingPoliciesForHostFunc = func(ctx context.Context, hostID uint, incomingResults map[uint]*bool) (newFailing []uint, newPassing []uint, err error) {
+		return nil, nil, nil
+	}
+	ds.QueueHostPolicyAutoRemediationsFunc = func(ctx context.Context, hostID uint, failingPolicyIDs []uint) error {
+		return nil
+	}
+
+	ctx := hostctx.NewContext(context.Background(), host)
+
+	countPolicyQueries := func(queries map[string]string) (policies, critical int) {
+		for name := range queries {
+			switch {
+			case strings.HasPrefix(name, hostPolicyQueryPrefix):
+				policies++
+			case strings.HasPrefix(name, hostCriticalPolicyQueryPrefix):
+				critical++
+			}
+		}
+		return policies, critical
+	}
+
+	// all the policies are run first
+	queries, discovery, _, err := svc.GetDistributedQueries(ctx)
+	require.NoError(t, err)
+	verifyDiscovery(t, queries, discovery)
+	policies, critical := countPolicyQueries(queries)
+	assert.Equal(t, 2, policies)
+	assert.Equal(t, 0, critical)
+	assert.False(t, ds.CriticalPolicyQueriesForHostFuncInvoked)
+
+	err = svc.SubmitDistributedQueryResults(
+		ctx,
+		map[string][]map[string]string{
+			hostPolicyQueryPrefix + "1": {{"col1": "val1"}},
+			hostPolicyQueryPrefix + "2": {},
+		},
+		map[string]fleet.OsqueryStatus{},
+		map[string]string{},
+	)
+	require.NoError(t, err)
+	assert.False(t, ds.RecordCriticalPolicyQueryExecutionsFuncInvoked)
+
+	queries, _, _, err = svc.GetDistributedQueries(ctx)
+	require.NoError(t, err)
+	policies, critical = countPolicyQueries(queries)
+	assert.Equal(t, 0, policies)
+	assert.Equal(t, 0, critical)
+
+	// the critical policies are run once their interval elapsed
+	mockClock.AddTime(15 * time.Minute)
+	queries, discovery, _, err = svc.GetDistributedQueries(ctx)
+	require.NoError(t, err)
+	verifyDiscovery(t, queries, discovery)
+	policies, critical = countPolicyQueries(queries)
+	assert.Equal(t, 0, policies)
+	assert.Equal(t, 1, critical)
+	assert.Equal(t, "select 42;", queries[hostCriticalPolicyQueryPrefix+"2"])
+
+	err = svc.SubmitDistributedQueryResults(
+		ctx,
+		map[string][]map[string]string{
+			hostCriticalPolicyQueryPrefix + "2": {{"col1": "val1"}},
+		},
+		map[string]fleet.OsqueryStatus{},
+		map[string]string{},
+	)
+	require.NoError(t, err)
+	require.True(t, ds.RecordCriticalPolicyQueryExecutionsFuncInvoked)
+	require.Len(t, recordedCriticalResults, 1)
+	require.NotNil(t, recordedCriticalResults[2])
+	assert.True(t, *recordedCriticalResults[2])
+	assert.Equal(t, mockClock.Now(), host.CriticalPolicyUpdatedAt)
+	ds.RecordCriticalPolicyQueryExecutionsFuncInvoked = false
+
+	queries, _, _, err = svc.GetDistributedQueries(ctx)
+	require.NoError(t, err)
+	policies, critical = countPolicyQueries(queries)
+	assert.Equal(t, 0, policies)
+	assert.Equal(t, 0, critical)
+
+	// without critical policies, their last run time is updated
+	criticalQueries = map[string]string{}
+	mockClock.AddTime(15 * time.Minute)
+	queries, _, _, err = svc.GetDistributedQueries(ctx)
+	require.NoError(t, err)
+	policies, critical = countPolicyQueries(queries)
+	assert.Equal(t, 0, policies)
+	assert.Equal(t, 0, critical)
+	require.True(t, ds.RecordCriticalPolicyQueryExecutionsFuncInvoked)
+	assert.Empty(t, recordedCriticalResults)
+	assert.Equal(t, mockClock.Now(), host.CriticalPolicyUpdatedAt)
+
+	// all the policies are run once their interval elapsed
+	mockClock.AddTime(time.Hour)
+	ds.CriticalPolicyQueriesForHostFuncInvoked = false
+	queries, _, _, err = svc.GetDistributedQueries(ctx)
+	require.NoError(t, err)
+	policies, critical = countPolicyQueries(queries)
+	assert.Equal(t, 2, policies)
+	assert.Equal(t, 0, critical)
+	assert.False(t, ds.CriticalPolicyQueriesForHostFuncInvoked)
+}
+
 func TestPolicyWebhooks(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
@@ -2313,6 +3559,18 @@ func TestPolicyWebhooks(t *testing.T) {
 		host = gotHost
 		return nil
 	}
+	var queuedRemediations []uint
+	ds.QueueHostPolicyAutoRemediationsFunc = func(ctx context.Context, hostID uint, failingPolicyIDs []uint) error {
+		queuedRemediations = failingPolicyIDs
+		return nil
+	}
+	flippedActivities := make(map[uint]string)
+	ds.NewHostActivitiesFunc = func(ctx context.Context, hostIDs []uint, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		assert.Equal(t, []uint{host.ID}, hostIDs)
+		assert.Nil(t, user)
+		flippedActivities[(*details)["policy_id"].(uint)] = activityType
+		return nil
+	}
 	ctx := hostctx.NewContext(context.Background(), host)
 
 	queries, discovery, _, err := svc.GetDistributedQueries(ctx)
@@ -2367,6 +3625,10 @@ func TestPolicyWebhooks(t *testing.T) {
 	require.Nil(t, result)
 	require.NotNil(t, recordedResults[3])
 	require.False(t, *recordedResults[3])
+	// the failing policies are checked for automatic remediations
+	require.Equal(t, []uint{3}, queuedRemediations)
+	// the flipped policies are recorded in the activities of the host
+	assert.Equal(t, map[uint]string{3: fleet.HostActivityTypePolicyFailing}, flippedActivities)
 
 	cmpSets := func(expSets map[uint][]fleet.PolicySetHost) error {
 		actualSets, err := failingPolicySet.ListSets()
@@ -0,0 +1,103 @@
+package osquery_utils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// extensionQueryPrefix is the prefix of the names of the detail queries
+// contributed by extensions, so that they cannot override the built-in ones.
+const extensionQueryPrefix = "ext_"
+
+// Extension contributes additional detail queries, with the logic to ingest
+// their results, and is notified when the details of a host are saved. It
+// allows to collect custom host data without modifying the built-in detail
+// queries.
+type Extension interface {
+	// DetailQueries returns the detail queries of the extension, by name. It is
+	// called every time the detail queries are sent to or ingested from a host,
+	// so the queries of an extension may change at runtime.
+	DetailQueries() map[string]DetailQuery
+	// HostSaved is called after the details of a host were ingested and saved.
+	HostSaved(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore) error
+}
+
+var (
+	extensionsMu sync.RWMutex
+	extensions   = make(map[string]Extension)
+)
+
+// RegisterExtension registers an extension under the given name. The detail
+// queries of the extension are run as ext_<name>_<query>. It panics if the
+// name is empty, the extension is nil or an extension is already registered
+// under that name, and is meant to be called at startup.
+func RegisterExtension(name string, ext Extension) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+
+	if name == "" {
+		panic("osquery_utils: RegisterExtension name is empty")
+	}
+	if ext == nil {
+		panic("osquery_utils: RegisterExtension extension is nil")
+	}
+	if _, dup := extensions[name]; dup {
+		panic("osquery_utils: RegisterExtension called twice for extension " + name)
+	}
+	extensions[name] = ext
+}
+
+// unregisterExtension removes the extension registered under the given name,
+// it is only used by tests.
+func unregisterExtension(name string) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+
+	delete(extensions, name)
+}
+
+// registeredExtensions returns the registered extensions sorted by name.
+func registeredExtensions() ([]string, []Extension) {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	exts := make([]Extension, 0, len(names))
+	for _, name := range names {
+		exts = append(exts, extensions[name])
+	}
+	return names, exts
+}
+
+// addExtensionDetailQueries adds the detail queries of the registered
+// extensions to queries.
+func addExtensionDetailQueries(queries map[string]DetailQuery) {
+	names, exts := registeredExtensions()
+	for i, ext := range exts {
+		for name, query := range ext.DetailQueries() {
+			queries[fmt.Sprintf("%s%s_%s", extensionQueryPrefix, names[i], name)] = query
+		}
+	}
+}
+
+// RunHostSavedHooks notifies the registered extensions that the details of the
+// host were saved. The errors of the extensions are logged, so that an
+// extension cannot prevent the others from being notified.
+func RunHostSavedHooks(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore) {
+	names, exts := registeredExtensions()
+	for i, ext := range exts {
+		if err := ext.HostSaved(ctx, logger, host, ds); err != nil {
+			level.Error(logger).Log("op", "hostSaved", "extension", names[i], "host_id", host.ID, "err", err)
+		}
+	}
+}
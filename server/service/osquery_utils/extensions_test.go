@@ -0,0 +1,81 @@
+package osquery_utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testExtension struct {
+	queries    map[string]DetailQuery
+	savedErr   error
+	savedHosts []uint
+}
+
+func (e *testExtension) DetailQueries() map[string]DetailQuery {
+	return e.queries
+}
+
+func (e *testExtension) HostSaved(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore) error {
+	e.savedHosts = append(e.savedHosts, host.ID)
+	return e.savedErr
+}
+
+func TestExtensionDetailQueries(t *testing.T) {
+	ext := &testExtension{queries: map[string]DetailQuery{
+		"crowdstrike": {Query: "select * from crowdstrike_falcon", Platforms: []string{"darwin"}},
+		// does not override the built-in query of the same name
+		"os_version": {Query: "select 1"},
+	}}
+	RegisterExtension("acme", ext)
+	defer unregisterExtension("acme")
+
+	baseQueries := GetDetailQueries(nil, config.FleetConfig{})
+	assert.Equal(t, detailQueries["os_version"].Query, baseQueries["os_version"].Query)
+	require.Contains(t, baseQueries, "ext_acme_crowdstrike")
+	assert.Equal(t, "select * from crowdstrike_falcon", baseQueries["ext_acme_crowdstrike"].Query)
+	assert.Equal(t, []string{"darwin"}, baseQueries["ext_acme_crowdstrike"].Platforms)
+	require.Contains(t, baseQueries, "ext_acme_os_version")
+	assert.Equal(t, "select 1", baseQueries["ext_acme_os_version"].Query)
+
+	// the queries of the extension are read every time
+	ext.queries = map[string]DetailQuery{"other": {Query: "select 2"}}
+	queries := GetDetailQueries(nil, config.FleetConfig{})
+	assert.NotContains(t, queries, "ext_acme_crowdstrike")
+	assert.Contains(t, queries, "ext_acme_other")
+
+	unregisterExtension("acme")
+	queries = GetDetailQueries(nil, config.FleetConfig{})
+	assert.Len(t, queries, len(baseQueries)-2)
+}
+
+func TestRegisterExtensionPanics(t *testing.T) {
+	ext := &testExtension{}
+	assert.Panics(t, func() { RegisterExtension("", ext) })
+	assert.Panics(t, func() { RegisterExtension("nil", nil) })
+
+	RegisterExtension("dup", ext)
+	defer unregisterExtension("dup")
+	assert.Panics(t, func() { RegisterExtension("dup", ext) })
+}
+
+func TestRunHostSavedHooks(t *testing.T) {
+	failing := &testExtension{savedErr: errors.New("fail")}
+	ok := &testExtension{}
+	RegisterExtension("a_failing", failing)
+	defer unregisterExtension("a_failing")
+	RegisterExtension("b_ok", ok)
+	defer unregisterExtension("b_ok")
+
+	// an extension failing does not prevent the others from being notified
+	RunHostSavedHooks(context.Background(), log.NewNopLogger(), &fleet.Host{ID: 1}, nil)
+	RunHostSavedHooks(context.Background(), log.NewNopLogger(), &fleet.Host{ID: 2}, nil)
+	assert.Equal(t, []uint{1, 2}, failing.savedHosts)
+	assert.Equal(t, []uint{1, 2}, ok.savedHosts)
+}
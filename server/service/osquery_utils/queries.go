@@ -298,6 +298,61 @@ FROM logical_drives WHERE file_system = 'NTFS' LIMIT 1;`,
 		Platforms:  []string{"windows"},
 		IngestFunc: ingestDiskSpace,
 	},
+	"disks_unix": {
+		// only the volumes of actual devices are reported, the disk_encryption
+		// table reports the encryption of the volumes by device name.
+		Query: `
+SELECT m.path, m.device, m.type,
+       m.blocks * m.blocks_size AS total_bytes,
+       m.blocks_available * m.blocks_size AS available_bytes,
+       COALESCE(de.encrypted, 0) AS encrypted
+FROM mounts m LEFT JOIN disk_encryption de ON de.name = m.device
+WHERE m.device LIKE '/dev/%' AND m.blocks > 0 AND m.type NOT IN ('squashfs', 'iso9660')`,
+		Platforms:        append(fleet.HostLinuxOSs, "darwin"),
+		DirectIngestFunc: directIngestDisks,
+	},
+	"disks_windows": {
+		Query: `
+SELECT ld.device_id AS path, ld.device_id AS device, ld.file_system AS type,
+       ld.size AS total_bytes,
+       ld.free_space AS available_bytes,
+       COALESCE(bi.protection_status = 1, 0) AS encrypted
+FROM logical_drives ld LEFT JOIN bitlocker_info bi ON bi.drive_letter = ld.device_id
+WHERE ld.size > 0`,
+		Platforms:        []string{"windows"},
+		DirectIngestFunc: directIngestDisks,
+	},
+	// the disk encryption queries report a single row, whether the system
+	// disk of the host is encrypted.
+	"disk_encryption_darwin": {
+		Query: `
+SELECT COUNT(*) > 0 AS enabled FROM disk_encryption
+WHERE user_uuid <> '' AND filevault_status = 'on'`,
+		Platforms:  []string{"darwin"},
+		IngestFunc: ingestDiskEncryption,
+	},
+	"disk_encryption_windows": {
+		Query: `
+SELECT COUNT(*) > 0 AS enabled FROM bitlocker_info
+WHERE drive_letter = 'C:' AND protection_status = 1`,
+		Platforms:  []string{"windows"},
+		IngestFunc: ingestDiskEncryption,
+	},
+	"disk_encryption_linux": {
+		// LUKS volumes are reported by the device mapper name, which is the
+		// device alias of the mount.
+		Query: `
+SELECT COUNT(*) > 0 AS enabled FROM disk_encryption de
+JOIN mounts m ON m.device_alias = de.name
+WHERE m.path = '/' AND de.encrypted = 1`,
+		Platforms:  fleet.HostLinuxOSs,
+		IngestFunc: ingestDiskEncryption,
+	},
+	"battery": {
+		Query:            `SELECT serial_number, cycle_count, health, condition FROM battery`,
+		Platforms:        []string{"darwin"},
+		DirectIngestFunc: directIngestBatteries,
+	},
 	"mdm": {
 		Query:            `select enrolled, server_url, installed_from_dep from mdm;`,
 		DirectIngestFunc: directIngestMDM,
@@ -318,6 +373,108 @@ FROM logical_drives WHERE file_system = 'NTFS' LIMIT 1;`,
 		DirectIngestFunc: directIngestOrbitInfo,
 		Discovery:        discoveryTable("orbit_info"),
 	},
+	"launcher_info": {
+		Query:            `SELECT version FROM kolide_launcher_info`,
+		DirectIngestFunc: directIngestLauncherInfo,
+		Discovery:        discoveryTable("kolide_launcher_info"),
+	},
+	"dns_servers_unix": {
+		Query:            `SELECT DISTINCT address FROM dns_resolvers WHERE type = 'nameserver'`,
+		Platforms:        append(fleet.HostLinuxOSs, "darwin"),
+		DirectIngestFunc: directIngestDNSServers,
+	},
+	"dns_servers_windows": {
+		Query:            `SELECT DISTINCT dns_server_search_order AS address FROM interface_details WHERE dns_server_search_order <> ''`,
+		Platforms:        []string{"windows"},
+		DirectIngestFunc: directIngestDNSServers,
+	},
+	"network_addresses": {
+		// unlike network_interface that only keeps the primary address, this
+		// collects the addresses of all the interfaces to search the hosts by
+		// any of them.
+		Query: `
+SELECT DISTINCT ia.address, id.mac
+FROM interface_addresses ia JOIN interface_details id ON id.interface = ia.interface`,
+		DirectIngestFunc: directIngestNetworkAddresses,
+	},
+	"proxies_macos": {
+		// the proxies of each network service are stored under
+		// NetworkServices/<service id>/Proxies.
+		Query: `
+SELECT subkey, value FROM plist
+WHERE path = '/Library/Preferences/SystemConfiguration/preferences.plist'
+AND key = 'NetworkServices' AND subkey LIKE '%/Proxies/%'`,
+		Platforms:        []string{"darwin"},
+		DirectIngestFunc: directIngestProxiesMacOS,
+	},
+	"proxies_windows": {
+		Query: `
+SELECT key, name, data FROM registry
+WHERE key LIKE 'HKEY_USERS\%\Software\Microsoft\Windows\CurrentVersion\Internet Settings'
+AND name IN ('ProxyEnable', 'ProxyServer', 'AutoConfigURL')`,
+		Platforms:        []string{"windows"},
+		DirectIngestFunc: directIngestProxiesWindows,
+	},
+	"proxies_linux": {
+		// Linux has no system-wide proxy settings, use the environment of
+		// osqueryd instead.
+		Query: `
+SELECT lower(key) AS key, value FROM process_envs
+WHERE pid = (SELECT pid FROM osquery_info)
+AND lower(key) IN ('http_proxy', 'https_proxy', 'all_proxy') AND value <> ''`,
+		Platforms:        fleet.HostLinuxOSs,
+		DirectIngestFunc: directIngestProxiesLinux,
+	},
+	"startup_items_macos": {
+		// startup_items only reports the login items on macOS, the launchd
+		// jobs are collected from the launchd table.
+		Query: `
+SELECT s.source, s.name, s.path, COALESCE(h.sha256, '') AS sha256 FROM (
+	SELECT source, name, path FROM startup_items
+	UNION
+	SELECT 'launchd' AS source, label AS name, COALESCE(NULLIF(program, ''), program_arguments) AS path FROM launchd
+	WHERE run_at_load = '1'
+) s LEFT JOIN hash h ON h.path = s.path`,
+		Platforms:        []string{"darwin"},
+		DirectIngestFunc: directIngestStartupItems,
+	},
+	"startup_items_windows": {
+		// startup_items reports the Run registry keys and startup folders,
+		// the services started automatically are collected from the services
+		// table.
+		Query: `
+SELECT s.source, s.name, s.path, COALESCE(h.sha256, '') AS sha256 FROM (
+	SELECT source, name, path FROM startup_items
+	UNION
+	SELECT 'services' AS source, name, path FROM services
+	WHERE start_type = 'AUTO_START'
+) s LEFT JOIN hash h ON h.path = s.path`,
+		Platforms:        []string{"windows"},
+		DirectIngestFunc: directIngestStartupItems,
+	},
+	"startup_items_linux": {
+		Query: `
+SELECT s.source, s.name, s.path, COALESCE(h.sha256, '') AS sha256
+FROM startup_items s LEFT JOIN hash h ON h.path = s.path`,
+		Platforms:        fleet.HostLinuxOSs,
+		DirectIngestFunc: directIngestStartupItems,
+	},
+	"listening_ports": {
+		Query: `
+SELECT DISTINCT lp.port, lp.protocol, COALESCE(p.name, '') AS process_name
+FROM listening_ports lp LEFT JOIN processes p ON p.pid = lp.pid
+WHERE lp.port <> 0 AND lp.protocol IN (6, 17) AND lp.address NOT IN ('127.0.0.1', '::1')`,
+		DirectIngestFunc: directIngestHostListeningPorts,
+	},
+	"certificates": {
+		// the certificates table is not available on every platform and
+		// version of osquery.
+		Query: `
+SELECT DISTINCT sha1, common_name, subject, issuer, ca, self_signed, not_valid_before, not_valid_after
+FROM certificates WHERE sha1 <> ''`,
+		DirectIngestFunc: directIngestCertificates,
+		Discovery:        discoveryTable("certificates"),
+	},
 }
 
 // discoveryTable returns a query to determine whether a table exists or not.
@@ -575,7 +732,7 @@ func directIngestChromeProfiles(ctx context.Context, logger log.Logger, host *fl
 		mapping = append(mapping, &fleet.HostDeviceMapping{
 			HostID: host.ID,
 			Email:  row["email"],
-			Source: "google_chrome_profiles",
+			Source: fleet.DeviceMappingGoogleChromeProfiles,
 		})
 	}
 	return ds.ReplaceHostDeviceMapping(ctx, host.ID, mapping)
@@ -585,6 +742,16 @@ func directIngestOrbitInfo(ctx context.Context, logger log.Logger, host *fleet.H
 	if len(rows) != 1 {
 		return ctxerr.Errorf(ctx, "invalid number of orbit_info rows: %d", len(rows))
 	}
+
+	if err := ds.SetOrUpdateHostAgentVersion(ctx, host.ID, fleet.AgentComponentOrbit, rows[0]["version"]); err != nil {
+		return ctxerr.Wrap(ctx, err, "set or update orbit version")
+	}
+	// desktop_version is empty when Fleet Desktop is not enabled, and missing
+	// with the Orbit versions that don't report it.
+	if err := ds.SetOrUpdateHostAgentVersion(ctx, host.ID, fleet.AgentComponentFleetDesktop, rows[0]["desktop_version"]); err != nil {
+		return ctxerr.Wrap(ctx, err, "set or update fleet desktop version")
+	}
+
 	deviceAuthToken := rows[0]["device_auth_token"]
 	if deviceAuthToken == "" {
 		return ctxerr.New(ctx, "empty orbit_info.device_auth_token")
@@ -595,6 +762,22 @@ func directIngestOrbitInfo(ctx context.Context, logger log.Logger, host *fleet.H
 	return nil
 }
 
+func directIngestLauncherInfo(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if len(rows) == 0 || failed {
+		// assume the launcher is not there
+		return nil
+	}
+	if len(rows) > 1 {
+		logger.Log("component", "service", "method", "directIngestLauncherInfo", "warn",
+			fmt.Sprintf("kolide_launcher_info expected single result got %d", len(rows)))
+	}
+
+	if err := ds.SetOrUpdateHostAgentVersion(ctx, host.ID, fleet.AgentComponentLauncher, rows[0]["version"]); err != nil {
+		return ctxerr.Wrap(ctx, err, "set or update launcher version")
+	}
+	return nil
+}
+
 func directIngestScheduledQueryStats(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
 	if failed {
 		level.Error(logger).Log("op", "directIngestScheduledQueryStats", "err", "failed")
@@ -747,6 +930,368 @@ func directIngestUsers(ctx context.Context, logger log.Logger, host *fleet.Host,
 	return nil
 }
 
+func directIngestDNSServers(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestDNSServers", "err", "failed")
+		return nil
+	}
+
+	addresses := make([]string, 0, len(rows))
+	for _, row := range rows {
+		// Windows reports the servers of an interface as a comma-separated list
+		for _, address := range strings.Split(row["address"], ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	if err := ds.ReplaceHostDNSServers(ctx, host.ID, addresses); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host dns servers")
+	}
+	return nil
+}
+
+func directIngestNetworkAddresses(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestNetworkAddresses", "err", "failed")
+		return nil
+	}
+
+	addresses := make([]fleet.HostNetworkAddress, 0, len(rows))
+	for _, row := range rows {
+		// the IPv6 link-local addresses are reported with their zone, e.g.
+		// fe80::1%en0
+		address := strings.SplitN(row["address"], "%", 2)[0]
+		ip := net.ParseIP(address)
+		// the loopback addresses are the same on every host
+		if ip == nil || ip.IsLoopback() {
+			continue
+		}
+		addresses = append(addresses, fleet.HostNetworkAddress{
+			Address: address,
+			MAC:     strings.ToLower(row["mac"]),
+		})
+	}
+	if err := ds.ReplaceHostNetworkAddresses(ctx, host.ID, addresses); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host network addresses")
+	}
+	return nil
+}
+
+func directIngestProxiesMacOS(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestProxiesMacOS", "err", "failed")
+		return nil
+	}
+
+	// group the settings by network service
+	services := make(map[string]map[string]string)
+	for _, row := range rows {
+		parts := strings.SplitN(row["subkey"], "/Proxies/", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if services[parts[0]] == nil {
+			services[parts[0]] = make(map[string]string)
+		}
+		services[parts[0]][parts[1]] = row["value"]
+	}
+
+	var proxies []fleet.HostProxy
+	for _, settings := range services {
+		for prefix, protocol := range map[string]string{
+			"HTTP":  fleet.HostProxyProtocolHTTP,
+			"HTTPS": fleet.HostProxyProtocolHTTPS,
+			"SOCKS": fleet.HostProxyProtocolSOCKS,
+		} {
+			if settings[prefix+"Enable"] != "1" || settings[prefix+"Proxy"] == "" {
+				continue
+			}
+			address := settings[prefix+"Proxy"]
+			if port := settings[prefix+"Port"]; port != "" {
+				address = net.JoinHostPort(address, port)
+			}
+			proxies = append(proxies, fleet.HostProxy{Protocol: protocol, Address: address})
+		}
+		if settings["ProxyAutoConfigEnable"] == "1" && settings["ProxyAutoConfigURLString"] != "" {
+			proxies = append(proxies, fleet.HostProxy{
+				Protocol: fleet.HostProxyProtocolAutoConfig,
+				Address:  settings["ProxyAutoConfigURLString"],
+			})
+		}
+	}
+	if err := ds.ReplaceHostProxies(ctx, host.ID, proxies); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host proxies")
+	}
+	return nil
+}
+
+func directIngestProxiesWindows(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestProxiesWindows", "err", "failed")
+		return nil
+	}
+
+	// group the settings by user
+	users := make(map[string]map[string]string)
+	for _, row := range rows {
+		if users[row["key"]] == nil {
+			users[row["key"]] = make(map[string]string)
+		}
+		users[row["key"]][row["name"]] = row["data"]
+	}
+
+	var proxies []fleet.HostProxy
+	for _, settings := range users {
+		if settings["ProxyEnable"] == "1" && settings["ProxyServer"] != "" {
+			proxies = append(proxies, parseWindowsProxyServer(settings["ProxyServer"])...)
+		}
+		if settings["AutoConfigURL"] != "" {
+			proxies = append(proxies, fleet.HostProxy{
+				Protocol: fleet.HostProxyProtocolAutoConfig,
+				Address:  settings["AutoConfigURL"],
+			})
+		}
+	}
+	if err := ds.ReplaceHostProxies(ctx, host.ID, proxies); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host proxies")
+	}
+	return nil
+}
+
+// parseWindowsProxyServer parses the ProxyServer registry value, which is
+// either a single proxy used for all protocols ("host:port") or a list of
+// proxies per protocol ("http=host:port;https=host:port;socks=host:port").
+func parseWindowsProxyServer(value string) []fleet.HostProxy {
+	if !strings.Contains(value, "=") {
+		address := trimProxyScheme(value)
+		return []fleet.HostProxy{
+			{Protocol: fleet.HostProxyProtocolHTTP, Address: address},
+			{Protocol: fleet.HostProxyProtocolHTTPS, Address: address},
+		}
+	}
+
+	var proxies []fleet.HostProxy
+	for _, entry := range strings.Split(value, ";") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			continue
+		}
+		var protocol string
+		switch strings.ToLower(strings.TrimSpace(parts[0])) {
+		case "http":
+			protocol = fleet.HostProxyProtocolHTTP
+		case "https":
+			protocol = fleet.HostProxyProtocolHTTPS
+		case "socks":
+			protocol = fleet.HostProxyProtocolSOCKS
+		default:
+			continue
+		}
+		proxies = append(proxies, fleet.HostProxy{Protocol: protocol, Address: trimProxyScheme(parts[1])})
+	}
+	return proxies
+}
+
+func directIngestProxiesLinux(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestProxiesLinux", "err", "failed")
+		return nil
+	}
+
+	proxies := make([]fleet.HostProxy, 0, len(rows))
+	for _, row := range rows {
+		var protocol string
+		switch row["key"] {
+		case "http_proxy":
+			protocol = fleet.HostProxyProtocolHTTP
+		case "https_proxy":
+			protocol = fleet.HostProxyProtocolHTTPS
+		case "all_proxy":
+			protocol = fleet.HostProxyProtocolSOCKS
+		default:
+			continue
+		}
+		proxies = append(proxies, fleet.HostProxy{Protocol: protocol, Address: trimProxyScheme(row["value"])})
+	}
+	if err := ds.ReplaceHostProxies(ctx, host.ID, proxies); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host proxies")
+	}
+	return nil
+}
+
+func directIngestStartupItems(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestStartupItems", "err", "failed")
+		return nil
+	}
+
+	items := make([]fleet.HostStartupItem, 0, len(rows))
+	for _, row := range rows {
+		if row["name"] == "" {
+			continue
+		}
+		items = append(items, fleet.HostStartupItem{
+			Source: row["source"],
+			Name:   row["name"],
+			Path:   row["path"],
+			SHA256: strings.ToLower(row["sha256"]),
+		})
+	}
+	if err := ds.ReplaceHostStartupItems(ctx, host.ID, items); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host startup items")
+	}
+	return nil
+}
+
+func directIngestHostListeningPorts(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestHostListeningPorts", "err", "failed")
+		return nil
+	}
+
+	ports := make([]fleet.HostListeningPort, 0, len(rows))
+	for _, row := range rows {
+		port, err := strconv.ParseUint(row["port"], 10, 16)
+		if err != nil {
+			level.Debug(logger).Log("op", "directIngestHostListeningPorts", "err", err)
+			continue
+		}
+		protocol := fleet.ListeningPortProtocolTCP
+		if row["protocol"] == "17" {
+			protocol = fleet.ListeningPortProtocolUDP
+		}
+		ports = append(ports, fleet.HostListeningPort{
+			Port:        uint16(port),
+			Protocol:    protocol,
+			ProcessName: row["process_name"],
+		})
+	}
+	if err := ds.ReplaceHostListeningPorts(ctx, host.ID, ports); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host listening ports")
+	}
+	return nil
+}
+
+func directIngestDisks(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestDisks", "err", "failed")
+		return nil
+	}
+
+	disks := make([]fleet.HostDisk, 0, len(rows))
+	for _, row := range rows {
+		if row["path"] == "" {
+			continue
+		}
+		total, err := strconv.ParseUint(EmptyToZero(row["total_bytes"]), 10, 64)
+		if err != nil {
+			level.Debug(logger).Log("op", "directIngestDisks", "err", err)
+			continue
+		}
+		available, err := strconv.ParseUint(EmptyToZero(row["available_bytes"]), 10, 64)
+		if err != nil {
+			level.Debug(logger).Log("op", "directIngestDisks", "err", err)
+			continue
+		}
+		disks = append(disks, fleet.HostDisk{
+			Path:           row["path"],
+			Device:         row["device"],
+			Type:           row["type"],
+			TotalBytes:     total,
+			AvailableBytes: available,
+			Encrypted:      row["encrypted"] == "1",
+		})
+	}
+	if err := ds.ReplaceHostDisks(ctx, host.ID, disks); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host disks")
+	}
+	return nil
+}
+
+func directIngestBatteries(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestBatteries", "err", "failed")
+		return nil
+	}
+
+	batteries := make([]fleet.HostBattery, 0, len(rows))
+	for _, row := range rows {
+		if row["serial_number"] == "" {
+			continue
+		}
+		cycleCount, err := strconv.Atoi(EmptyToZero(row["cycle_count"]))
+		if err != nil {
+			level.Debug(logger).Log("op", "directIngestBatteries", "err", err)
+			continue
+		}
+		batteries = append(batteries, fleet.HostBattery{
+			SerialNumber: row["serial_number"],
+			CycleCount:   cycleCount,
+			Health:       row["health"],
+			Condition:    row["condition"],
+		})
+	}
+	if err := ds.ReplaceHostBatteries(ctx, host.ID, batteries); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host batteries")
+	}
+	return nil
+}
+
+func directIngestCertificates(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
+	if failed {
+		level.Error(logger).Log("op", "directIngestCertificates", "err", "failed")
+		return nil
+	}
+
+	certs := make([]fleet.HostCertificate, 0, len(rows))
+	for _, row := range rows {
+		notValidBefore, err := parseUnixTime(row["not_valid_before"])
+		if err != nil {
+			level.Debug(logger).Log("op", "directIngestCertificates", "err", err)
+			continue
+		}
+		notValidAfter, err := parseUnixTime(row["not_valid_after"])
+		if err != nil {
+			level.Debug(logger).Log("op", "directIngestCertificates", "err", err)
+			continue
+		}
+		certs = append(certs, fleet.HostCertificate{
+			SHA1:           strings.ToLower(row["sha1"]),
+			CommonName:     row["common_name"],
+			Subject:        row["subject"],
+			Issuer:         row["issuer"],
+			CA:             row["ca"] == "1",
+			SelfSigned:     row["self_signed"] == "1",
+			NotValidBefore: notValidBefore,
+			NotValidAfter:  notValidAfter,
+		})
+	}
+	if err := ds.ReplaceHostCertificates(ctx, host.ID, certs); err != nil {
+		return ctxerr.Wrap(ctx, err, "replace host certificates")
+	}
+	return nil
+}
+
+// parseUnixTime parses the unix timestamps reported by osquery, that may have
+// a fractional part.
+func parseUnixTime(s string) (time.Time, error) {
+	secs, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(secs), 0).UTC(), nil
+}
+
+// trimProxyScheme returns the host:port of a proxy configured as a URL.
+func trimProxyScheme(address string) string {
+	address = strings.TrimSpace(address)
+	if i := strings.Index(address, "://"); i >= 0 {
+		address = address[i+len("://"):]
+	}
+	return strings.TrimSuffix(address, "/")
+}
+
 func ingestDiskSpace(ctx context.Context, logger log.Logger, host *fleet.Host, rows []map[string]string) error {
 	if len(rows) != 1 {
 		logger.Log("component", "service", "method", "ingestDiskSpace", "err",
@@ -766,6 +1311,20 @@ func ingestDiskSpace(ctx context.Context, logger log.Logger, host *fleet.Host, r
 	return nil
 }
 
+func ingestDiskEncryption(ctx context.Context, logger log.Logger, host *fleet.Host, rows []map[string]string) error {
+	// the query failed (e.g. the table is not available), keep the previous
+	// status rather than reporting the disk as not encrypted
+	if len(rows) != 1 {
+		logger.Log("component", "service", "method", "ingestDiskEncryption", "err",
+			fmt.Sprintf("detail_query_disk_encryption expected single result got %d", len(rows)))
+		return nil
+	}
+
+	enabled := rows[0]["enabled"] == "1"
+	host.DiskEncryptionEnabled = &enabled
+	return nil
+}
+
 func directIngestMDM(ctx context.Context, logger log.Logger, host *fleet.Host, ds fleet.Datastore, rows []map[string]string, failed bool) error {
 	if len(rows) == 0 || failed {
 		// assume the extension is not there
@@ -828,5 +1387,7 @@ func GetDetailQueries(ac *fleet.AppConfig, fleetConfig config.FleetConfig) map[s
 		generatedMap["scheduled_query_stats"] = scheduledQueryStats
 	}
 
+	addExtensionDetailQueries(generatedMap)
+
 	return generatedMap
 }
@@ -290,7 +290,7 @@ func sortedKeysCompare(t *testing.T, m map[string]DetailQuery, expectedKeys []st
 
 func TestGetDetailQueries(t *testing.T) {
 	queriesNoConfig := GetDetailQueries(nil, config.FleetConfig{})
-	require.Len(t, queriesNoConfig, 12)
+	require.Len(t, queriesNoConfig, 30)
 	baseQueries := []string{
 		"network_interface",
 		"os_version",
@@ -300,19 +300,37 @@ func TestGetDetailQueries(t *testing.T) {
 		"uptime",
 		"disk_space_unix",
 		"disk_space_windows",
+		"disks_unix",
+		"disks_windows",
+		"disk_encryption_darwin",
+		"disk_encryption_windows",
+		"disk_encryption_linux",
+		"battery",
 		"mdm",
 		"munki_info",
 		"google_chrome_profiles",
 		"orbit_info",
+		"launcher_info",
+		"dns_servers_unix",
+		"dns_servers_windows",
+		"network_addresses",
+		"proxies_macos",
+		"proxies_windows",
+		"proxies_linux",
+		"startup_items_macos",
+		"startup_items_windows",
+		"startup_items_linux",
+		"listening_ports",
+		"certificates",
 	}
 	sortedKeysCompare(t, queriesNoConfig, baseQueries)
 
 	queriesWithUsers := GetDetailQueries(&fleet.AppConfig{HostSettings: fleet.HostSettings{EnableHostUsers: true}}, config.FleetConfig{App: config.AppConfig{EnableScheduledQueryStats: true}})
-	require.Len(t, queriesWithUsers, 14)
+	require.Len(t, queriesWithUsers, 32)
 	sortedKeysCompare(t, queriesWithUsers, append(baseQueries, "users", "scheduled_query_stats"))
 
 	queriesWithUsersAndSoftware := GetDetailQueries(&fleet.AppConfig{HostSettings: fleet.HostSettings{EnableHostUsers: true, EnableSoftwareInventory: true}}, config.FleetConfig{App: config.AppConfig{EnableScheduledQueryStats: true}})
-	require.Len(t, queriesWithUsersAndSoftware, 17)
+	require.Len(t, queriesWithUsersAndSoftware, 35)
 	sortedKeysCompare(t, queriesWithUsersAndSoftware,
 		append(baseQueries, "users", "software_macos", "software_linux", "software_windows", "scheduled_query_stats"))
 }
@@ -403,6 +421,12 @@ func TestDirectIngestOrbitInfo(t *testing.T) {
 		require.Equal(t, authToken, "foo")
 		return nil
 	}
+	versions := make(map[string]string)
+	ds.SetOrUpdateHostAgentVersionFunc = func(ctx context.Context, hostID uint, component string, version string) error {
+		require.Equal(t, hostID, uint(1))
+		versions[component] = version
+		return nil
+	}
 
 	host := fleet.Host{
 		ID: 1,
@@ -414,4 +438,315 @@ func TestDirectIngestOrbitInfo(t *testing.T) {
 	}}, true)
 	require.NoError(t, err)
 	require.True(t, ds.SetOrUpdateDeviceAuthTokenFuncInvoked)
+	// Orbit did not report the version of Fleet Desktop
+	assert.Equal(t, map[string]string{fleet.AgentComponentOrbit: "42", fleet.AgentComponentFleetDesktop: ""}, versions)
+
+	err = directIngestOrbitInfo(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{{
+		"version":           "43",
+		"desktop_version":   "0.0.3",
+		"device_auth_token": "foo",
+	}}, true)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{fleet.AgentComponentOrbit: "43", fleet.AgentComponentFleetDesktop: "0.0.3"}, versions)
+}
+
+func TestDirectIngestLauncherInfo(t *testing.T) {
+	ds := new(mock.Store)
+	ds.SetOrUpdateHostAgentVersionFunc = func(ctx context.Context, hostID uint, component string, version string) error {
+		require.Equal(t, uint(1), hostID)
+		require.Equal(t, fleet.AgentComponentLauncher, component)
+		require.Equal(t, "0.11.25", version)
+		return nil
+	}
+
+	host := fleet.Host{
+		ID: 1,
+	}
+
+	// the launcher is not running
+	err := directIngestLauncherInfo(context.Background(), log.NewNopLogger(), &host, ds, nil, false)
+	require.NoError(t, err)
+	require.False(t, ds.SetOrUpdateHostAgentVersionFuncInvoked)
+
+	err = directIngestLauncherInfo(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{{
+		"version": "0.11.25",
+	}}, false)
+	require.NoError(t, err)
+	require.True(t, ds.SetOrUpdateHostAgentVersionFuncInvoked)
+}
+
+func TestDirectIngestNetworkSettings(t *testing.T) {
+	ds := new(mock.Store)
+	var gotDNSServers []string
+	ds.ReplaceHostDNSServersFunc = func(ctx context.Context, hostID uint, addresses []string) error {
+		require.Equal(t, uint(1), hostID)
+		gotDNSServers = addresses
+		return nil
+	}
+	var gotProxies []fleet.HostProxy
+	ds.ReplaceHostProxiesFunc = func(ctx context.Context, hostID uint, proxies []fleet.HostProxy) error {
+		require.Equal(t, uint(1), hostID)
+		gotProxies = proxies
+		return nil
+	}
+
+	host := fleet.Host{ID: 1}
+
+	err := directIngestDNSServers(context.Background(), log.NewNopLogger(), &host, ds, nil, true)
+	require.NoError(t, err)
+	require.False(t, ds.ReplaceHostDNSServersFuncInvoked)
+
+	err = directIngestDNSServers(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"address": "10.0.0.1"},
+		{"address": "8.8.8.8, 8.8.4.4"},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.1", "8.8.8.8", "8.8.4.4"}, gotDNSServers)
+
+	err = directIngestProxiesMacOS(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"subkey": "A1/Proxies/HTTPEnable", "value": "1"},
+		{"subkey": "A1/Proxies/HTTPProxy", "value": "proxy.example.com"},
+		{"subkey": "A1/Proxies/HTTPPort", "value": "3128"},
+		{"subkey": "A1/Proxies/HTTPSEnable", "value": "0"},
+		{"subkey": "A1/Proxies/HTTPSProxy", "value": "disabled.example.com"},
+		{"subkey": "B2/Proxies/ProxyAutoConfigEnable", "value": "1"},
+		{"subkey": "B2/Proxies/ProxyAutoConfigURLString", "value": "http://example.com/proxy.pac"},
+	}, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []fleet.HostProxy{
+		{Protocol: fleet.HostProxyProtocolHTTP, Address: "proxy.example.com:3128"},
+		{Protocol: fleet.HostProxyProtocolAutoConfig, Address: "http://example.com/proxy.pac"},
+	}, gotProxies)
+
+	err = directIngestProxiesWindows(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"key": `HKEY_USERS\S-1\Internet Settings`, "name": "ProxyEnable", "data": "1"},
+		{"key": `HKEY_USERS\S-1\Internet Settings`, "name": "ProxyServer", "data": "http=proxy:80;https=proxy:443;ftp=proxy:21"},
+		{"key": `HKEY_USERS\S-2\Internet Settings`, "name": "ProxyEnable", "data": "0"},
+		{"key": `HKEY_USERS\S-2\Internet Settings`, "name": "ProxyServer", "data": "disabled:80"},
+	}, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []fleet.HostProxy{
+		{Protocol: fleet.HostProxyProtocolHTTP, Address: "proxy:80"},
+		{Protocol: fleet.HostProxyProtocolHTTPS, Address: "proxy:443"},
+	}, gotProxies)
+
+	err = directIngestProxiesWindows(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"key": `HKEY_USERS\S-1\Internet Settings`, "name": "ProxyEnable", "data": "1"},
+		{"key": `HKEY_USERS\S-1\Internet Settings`, "name": "ProxyServer", "data": "proxy:8080"},
+	}, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []fleet.HostProxy{
+		{Protocol: fleet.HostProxyProtocolHTTP, Address: "proxy:8080"},
+		{Protocol: fleet.HostProxyProtocolHTTPS, Address: "proxy:8080"},
+	}, gotProxies)
+
+	err = directIngestProxiesLinux(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"key": "http_proxy", "value": "http://proxy.example.com:3128/"},
+		{"key": "all_proxy", "value": "socks5://proxy.example.com:1080"},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostProxy{
+		{Protocol: fleet.HostProxyProtocolHTTP, Address: "proxy.example.com:3128"},
+		{Protocol: fleet.HostProxyProtocolSOCKS, Address: "proxy.example.com:1080"},
+	}, gotProxies)
+}
+
+func TestDirectIngestNetworkAddresses(t *testing.T) {
+	ds := new(mock.Store)
+	var got []fleet.HostNetworkAddress
+	ds.ReplaceHostNetworkAddressesFunc = func(ctx context.Context, hostID uint, addresses []fleet.HostNetworkAddress) error {
+		require.Equal(t, uint(1), hostID)
+		got = addresses
+		return nil
+	}
+
+	host := fleet.Host{ID: 1}
+
+	err := directIngestNetworkAddresses(context.Background(), log.NewNopLogger(), &host, ds, nil, true)
+	require.NoError(t, err)
+	require.False(t, ds.ReplaceHostNetworkAddressesFuncInvoked)
+
+	err = directIngestNetworkAddresses(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"address": "127.0.0.1", "mac": "00:00:00:00:00:00"},
+		{"address": "::1", "mac": ""},
+		{"address": "10.0.0.5", "mac": "AA:BB:CC:DD:EE:FF"},
+		{"address": "fe80::1%en0", "mac": "aa:bb:cc:dd:ee:ff"},
+		{"address": "192.168.1.10", "mac": ""},
+		{"address": "not an address", "mac": "aa:bb:cc:dd:ee:00"},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostNetworkAddress{
+		{Address: "10.0.0.5", MAC: "aa:bb:cc:dd:ee:ff"},
+		{Address: "fe80::1", MAC: "aa:bb:cc:dd:ee:ff"},
+		{Address: "192.168.1.10", MAC: ""},
+	}, got)
+}
+
+func TestDirectIngestStartupItems(t *testing.T) {
+	ds := new(mock.Store)
+	var gotItems []fleet.HostStartupItem
+	ds.ReplaceHostStartupItemsFunc = func(ctx context.Context, hostID uint, items []fleet.HostStartupItem) error {
+		require.Equal(t, uint(1), hostID)
+		gotItems = items
+		return nil
+	}
+
+	host := fleet.Host{ID: 1}
+
+	err := directIngestStartupItems(context.Background(), log.NewNopLogger(), &host, ds, nil, true)
+	require.NoError(t, err)
+	require.False(t, ds.ReplaceHostStartupItemsFuncInvoked)
+
+	err = directIngestStartupItems(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"source": "launchd", "name": "com.example.agent", "path": "/usr/local/bin/agent", "sha256": "ABC"},
+		{"source": `HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows\CurrentVersion\Run`, "name": "updater", "path": `C:\updater.exe`},
+		{"source": "services", "name": "", "path": `C:\ignored.exe`},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostStartupItem{
+		{Source: "launchd", Name: "com.example.agent", Path: "/usr/local/bin/agent", SHA256: "abc"},
+		{Source: `HKEY_LOCAL_MACHINE\SOFTWARE\Microsoft\Windows\CurrentVersion\Run`, Name: "updater", Path: `C:\updater.exe`},
+	}, gotItems)
+}
+
+func TestDirectIngestHostListeningPorts(t *testing.T) {
+	ds := new(mock.Store)
+	var gotPorts []fleet.HostListeningPort
+	ds.ReplaceHostListeningPortsFunc = func(ctx context.Context, hostID uint, ports []fleet.HostListeningPort) error {
+		require.Equal(t, uint(1), hostID)
+		gotPorts = ports
+		return nil
+	}
+
+	host := fleet.Host{ID: 1}
+
+	err := directIngestHostListeningPorts(context.Background(), log.NewNopLogger(), &host, ds, nil, true)
+	require.NoError(t, err)
+	require.False(t, ds.ReplaceHostListeningPortsFuncInvoked)
+
+	err = directIngestHostListeningPorts(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"port": "3389", "protocol": "6", "process_name": "svchost.exe"},
+		{"port": "53", "protocol": "17", "process_name": ""},
+		{"port": "not a port", "protocol": "6", "process_name": "invalid"},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostListeningPort{
+		{Port: 3389, Protocol: fleet.ListeningPortProtocolTCP, ProcessName: "svchost.exe"},
+		{Port: 53, Protocol: fleet.ListeningPortProtocolUDP},
+	}, gotPorts)
+}
+
+func TestDirectIngestDisks(t *testing.T) {
+	ds := new(mock.Store)
+	var gotDisks []fleet.HostDisk
+	ds.ReplaceHostDisksFunc = func(ctx context.Context, hostID uint, disks []fleet.HostDisk) error {
+		require.Equal(t, uint(1), hostID)
+		gotDisks = disks
+		return nil
+	}
+
+	host := fleet.Host{ID: 1}
+
+	err := directIngestDisks(context.Background(), log.NewNopLogger(), &host, ds, nil, true)
+	require.NoError(t, err)
+	require.False(t, ds.ReplaceHostDisksFuncInvoked)
+
+	err = directIngestDisks(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"path": "/", "device": "/dev/disk1s1", "type": "apfs", "total_bytes": "499963174912", "available_bytes": "123456789", "encrypted": "1"},
+		{"path": "C:", "device": "C:", "type": "NTFS", "total_bytes": "1000", "available_bytes": "", "encrypted": "0"},
+		{"path": "/data", "device": "/dev/sdb1", "type": "ext4", "total_bytes": "-1", "available_bytes": "0", "encrypted": "0"},
+		{"path": "", "device": "/dev/sdc1", "type": "ext4", "total_bytes": "10", "available_bytes": "0", "encrypted": "0"},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostDisk{
+		{Path: "/", Device: "/dev/disk1s1", Type: "apfs", TotalBytes: 499963174912, AvailableBytes: 123456789, Encrypted: true},
+		{Path: "C:", Device: "C:", Type: "NTFS", TotalBytes: 1000},
+	}, gotDisks)
+}
+
+func TestDirectIngestBatteries(t *testing.T) {
+	ds := new(mock.Store)
+	var gotBatteries []fleet.HostBattery
+	ds.ReplaceHostBatteriesFunc = func(ctx context.Context, hostID uint, batteries []fleet.HostBattery) error {
+		require.Equal(t, uint(1), hostID)
+		gotBatteries = batteries
+		return nil
+	}
+
+	host := fleet.Host{ID: 1}
+
+	err := directIngestBatteries(context.Background(), log.NewNopLogger(), &host, ds, nil, true)
+	require.NoError(t, err)
+	require.False(t, ds.ReplaceHostBatteriesFuncInvoked)
+
+	err = directIngestBatteries(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{"serial_number": "D8641234", "cycle_count": "742", "health": "Fair", "condition": "Service recommended"},
+		{"serial_number": "D8645678", "cycle_count": "", "health": "Good", "condition": "Normal"},
+		{"serial_number": "D864abcd", "cycle_count": "n/a", "health": "Good", "condition": "Normal"},
+		{"serial_number": "", "cycle_count": "10", "health": "Good", "condition": "Normal"},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostBattery{
+		{SerialNumber: "D8641234", CycleCount: 742, Health: "Fair", Condition: "Service recommended"},
+		{SerialNumber: "D8645678", Health: "Good", Condition: "Normal"},
+	}, gotBatteries)
+}
+
+func TestIngestDiskEncryption(t *testing.T) {
+	host := fleet.Host{ID: 1}
+
+	// the status is unknown until reported
+	require.NoError(t, ingestDiskEncryption(context.Background(), log.NewNopLogger(), &host, nil))
+	assert.Nil(t, host.DiskEncryptionEnabled)
+
+	require.NoError(t, ingestDiskEncryption(context.Background(), log.NewNopLogger(), &host, []map[string]string{{"enabled": "1"}}))
+	require.NotNil(t, host.DiskEncryptionEnabled)
+	assert.True(t, *host.DiskEncryptionEnabled)
+
+	require.NoError(t, ingestDiskEncryption(context.Background(), log.NewNopLogger(), &host, []map[string]string{{"enabled": "0"}}))
+	require.NotNil(t, host.DiskEncryptionEnabled)
+	assert.False(t, *host.DiskEncryptionEnabled)
+
+	// a failed query keeps the previous status
+	require.NoError(t, ingestDiskEncryption(context.Background(), log.NewNopLogger(), &host, []map[string]string{}))
+	require.NotNil(t, host.DiskEncryptionEnabled)
+	assert.False(t, *host.DiskEncryptionEnabled)
+}
+
+func TestDirectIngestCertificates(t *testing.T) {
+	ds := new(mock.Store)
+	var gotCerts []fleet.HostCertificate
+	ds.ReplaceHostCertificatesFunc = func(ctx context.Context, hostID uint, certs []fleet.HostCertificate) error {
+		require.Equal(t, uint(1), hostID)
+		gotCerts = certs
+		return nil
+	}
+
+	host := fleet.Host{ID: 1}
+
+	err := directIngestCertificates(context.Background(), log.NewNopLogger(), &host, ds, nil, true)
+	require.NoError(t, err)
+	require.False(t, ds.ReplaceHostCertificatesFuncInvoked)
+
+	err = directIngestCertificates(context.Background(), log.NewNopLogger(), &host, ds, []map[string]string{
+		{
+			"sha1": "D1EB23A46D17D68FD92564C2F1F1601764D8E349", "common_name": "AAA Certificate Services",
+			"subject": "/C=GB/O=Comodo CA Limited/CN=AAA Certificate Services", "issuer": "/C=GB/O=Comodo CA Limited/CN=AAA Certificate Services",
+			"ca": "1", "self_signed": "1", "not_valid_before": "1072915200", "not_valid_after": "1924991999.0",
+		},
+		{
+			"sha1": "0123456789abcdef0123456789abcdef01234567", "common_name": "foo.example.com",
+			"subject": "/CN=foo.example.com", "issuer": "/CN=Example CA",
+			"ca": "0", "self_signed": "0", "not_valid_before": "", "not_valid_after": "1924991999",
+		},
+	}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.HostCertificate{
+		{
+			SHA1: "d1eb23a46d17d68fd92564c2f1f1601764d8e349", CommonName: "AAA Certificate Services",
+			Subject: "/C=GB/O=Comodo CA Limited/CN=AAA Certificate Services", Issuer: "/C=GB/O=Comodo CA Limited/CN=AAA Certificate Services",
+			CA: true, SelfSigned: true,
+			NotValidBefore: time.Date(2004, 1, 1, 0, 0, 0, 0, time.UTC), NotValidAfter: time.Date(2028, 12, 31, 23, 59, 59, 0, time.UTC),
+		},
+	}, gotCerts)
 }
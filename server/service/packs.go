@@ -166,6 +166,9 @@ func (svc *Service) NewPack(ctx context.Context, p fleet.PackPayload) (*fleet.Pa
 	if err != nil {
 		return nil, err
 	}
+	if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+		return nil, err
+	}
 
 	if err := svc.ds.NewActivity(
 		ctx,
@@ -260,6 +263,10 @@ func (svc *Service) ModifyPack(ctx context.Context, id uint, p fleet.PackPayload
 	if err != nil {
 		return nil, err
 	}
+	svc.clientConfigCache.invalidate()
+	if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+		return nil, err
+	}
 
 	if err := svc.ds.NewActivity(
 		ctx,
@@ -354,6 +361,9 @@ func (svc *Service) DeletePack(ctx context.Context, name string) error {
 	if err := svc.ds.DeletePack(ctx, name); err != nil {
 		return err
 	}
+	if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+		return err
+	}
 
 	return svc.ds.NewActivity(
 		ctx,
@@ -401,6 +411,9 @@ func (svc *Service) DeletePackByID(ctx context.Context, id uint) error {
 	if err := svc.ds.DeletePack(ctx, pack.Name); err != nil {
 		return err
 	}
+	if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+		return err
+	}
 
 	return svc.ds.NewActivity(
 		ctx,
@@ -471,11 +484,20 @@ func (svc *Service) ApplyPackSpecs(ctx context.Context, specs []*fleet.PackSpec)
 				message: fmt.Sprintf("pack payload verification: %s", err),
 			})
 		}
+		for _, q := range packSpec.Queries {
+			if err := svc.verifyScheduleInterval(ctx, nil, q.Interval, q.Snapshot); err != nil {
+				return nil, ctxerr.Wrapf(ctx, err, "pack %s query %s", packSpec.Name, q.Name)
+			}
+		}
 	}
 
 	if err := svc.ds.ApplyPackSpecs(ctx, result); err != nil {
 		return nil, err
 	}
+	svc.clientConfigCache.invalidate()
+	if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+		return nil, err
+	}
 
 	return result, svc.ds.NewActivity(
 		ctx,
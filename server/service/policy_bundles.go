@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/policybundles"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List Policy Bundles
+////////////////////////////////////////////////////////////////////////////////
+
+type listPolicyBundlesResponse struct {
+	Bundles []*fleet.PolicyBundle `json:"policy_bundles"`
+	Err     error                 `json:"error,omitempty"`
+}
+
+func (r listPolicyBundlesResponse) error() error { return r.Err }
+
+func listPolicyBundlesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	bundles, err := svc.ListPolicyBundles(ctx)
+	if err != nil {
+		return listPolicyBundlesResponse{Err: err}, nil
+	}
+	return listPolicyBundlesResponse{Bundles: bundles}, nil
+}
+
+func (svc *Service) ListPolicyBundles(ctx context.Context) ([]*fleet.PolicyBundle, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	bundles, err := policybundles.List()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list policy bundles")
+	}
+	return bundles, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Policy Bundle
+////////////////////////////////////////////////////////////////////////////////
+
+type getPolicyBundleRequest struct {
+	ID string `url:"id"`
+}
+
+type getPolicyBundleResponse struct {
+	Bundle *fleet.PolicyBundle `json:"policy_bundle,omitempty"`
+	Err    error               `json:"error,omitempty"`
+}
+
+func (r getPolicyBundleResponse) error() error { return r.Err }
+
+func getPolicyBundleEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getPolicyBundleRequest)
+	bundle, err := svc.GetPolicyBundle(ctx, req.ID)
+	if err != nil {
+		return getPolicyBundleResponse{Err: err}, nil
+	}
+	return getPolicyBundleResponse{Bundle: bundle}, nil
+}
+
+func (svc *Service) GetPolicyBundle(ctx context.Context, id string) (*fleet.PolicyBundle, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	bundle, err := policybundles.Get(id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get policy bundle")
+	}
+	return bundle, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Import Policy Bundle
+////////////////////////////////////////////////////////////////////////////////
+
+type importPolicyBundleRequest struct {
+	ID     string `url:"id"`
+	TeamID *uint  `json:"team_id"`
+}
+
+type importPolicyBundleResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r importPolicyBundleResponse) error() error { return r.Err }
+
+func importPolicyBundleEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*importPolicyBundleRequest)
+	if err := svc.ImportPolicyBundle(ctx, req.ID, req.TeamID); err != nil {
+		return importPolicyBundleResponse{Err: err}, nil
+	}
+	return importPolicyBundleResponse{}, nil
+}
+
+// ImportPolicyBundle applies the policies of the bundle as policy specs, to the
+// team if teamID is not nil or as global policies otherwise. As with the specs,
+// the policies are identified by name, so importing a bundle again updates its
+// policies.
+func (svc *Service) ImportPolicyBundle(ctx context.Context, id string, teamID *uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{PolicyData: fleet.PolicyData{TeamID: teamID}}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	bundle, err := policybundles.Get(id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get policy bundle")
+	}
+
+	var teamName string
+	if teamID != nil {
+		team, err := svc.ds.Team(ctx, *teamID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get team")
+		}
+		teamName = team.Name
+	}
+
+	specs := make([]*fleet.PolicySpec, 0, len(bundle.Policies))
+	for _, p := range bundle.Policies {
+		specs = append(specs, &fleet.PolicySpec{
+			Name:        p.Name,
+			Query:       p.Query,
+			Description: p.Description,
+			Resolution:  p.Resolution,
+			Team:        teamName,
+			Platform:    bundle.Platform,
+		})
+	}
+	return svc.ApplyPolicySpecs(ctx, specs)
+}
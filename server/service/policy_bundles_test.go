@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyBundlesAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+	ds.TeamByNameFunc = func(ctx context.Context, name string) (*fleet.Team, error) {
+		return &fleet.Team{ID: 1, Name: name}, nil
+	}
+	ds.ApplyPolicySpecsFunc = func(ctx context.Context, authorID uint, specs []*fleet.PolicySpec) error {
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                string
+		user                *fleet.User
+		shouldFailRead      bool
+		shouldFailGlobal    bool
+		shouldFailTeamWrite bool
+	}{
+		{
+			"global admin",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			false,
+			false,
+			false,
+		},
+		{
+			"global maintainer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			false,
+			false,
+			false,
+		},
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			false,
+			true,
+			true,
+		},
+		{
+			"team maintainer",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}},
+			false,
+			true,
+			false,
+		},
+		{
+			"team observer",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			false,
+			true,
+			true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			_, err := svc.ListPolicyBundles(ctx)
+			checkAuthErr(t, tt.shouldFailRead, err)
+
+			_, err = svc.GetPolicyBundle(ctx, "cis-macos")
+			checkAuthErr(t, tt.shouldFailRead, err)
+
+			err = svc.ImportPolicyBundle(ctx, "cis-macos", nil)
+			checkAuthErr(t, tt.shouldFailGlobal, err)
+
+			err = svc.ImportPolicyBundle(ctx, "cis-macos", ptr.Uint(1))
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+		})
+	}
+}
+
+func TestImportPolicyBundle(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+	ds.TeamByNameFunc = func(ctx context.Context, name string) (*fleet.Team, error) {
+		return &fleet.Team{ID: 1, Name: name}, nil
+	}
+	var appliedSpecs []*fleet.PolicySpec
+	ds.ApplyPolicySpecsFunc = func(ctx context.Context, authorID uint, specs []*fleet.PolicySpec) error {
+		appliedSpecs = specs
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	bundle, err := svc.GetPolicyBundle(ctx, "cis-ubuntu")
+	require.NoError(t, err)
+
+	require.NoError(t, svc.ImportPolicyBundle(ctx, "cis-ubuntu", ptr.Uint(1)))
+	require.Len(t, appliedSpecs, len(bundle.Policies))
+	for i, spec := range appliedSpecs {
+		assert.Equal(t, bundle.Policies[i].Name, spec.Name)
+		assert.Equal(t, bundle.Policies[i].Query, spec.Query)
+		assert.Equal(t, "linux", spec.Platform)
+		assert.Equal(t, "team1", spec.Team)
+	}
+
+	require.NoError(t, svc.ImportPolicyBundle(ctx, "cis-windows", nil))
+	require.NotEmpty(t, appliedSpecs)
+	for _, spec := range appliedSpecs {
+		assert.Equal(t, "windows", spec.Platform)
+		assert.Empty(t, spec.Team)
+	}
+
+	err = svc.ImportPolicyBundle(ctx, "no-such-bundle", nil)
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+}
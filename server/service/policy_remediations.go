@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	authz_ctx "github.com/fleetdm/fleet/v4/server/contexts/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Remediate Host Policy
+////////////////////////////////////////////////////////////////////////////////
+
+type remediateHostPolicyRequest struct {
+	ID       uint `url:"id"`
+	PolicyID uint `url:"policy_id"`
+}
+
+type remediateHostPolicyResponse struct {
+	Remediation *fleet.HostPolicyRemediation `json:"remediation,omitempty"`
+	Err         error                        `json:"error,omitempty"`
+}
+
+func (r remediateHostPolicyResponse) error() error { return r.Err }
+
+func remediateHostPolicyEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*remediateHostPolicyRequest)
+	remediation, err := svc.RemediateHostPolicy(ctx, req.ID, req.PolicyID)
+	if err != nil {
+		return remediateHostPolicyResponse{Err: err}, nil
+	}
+	return remediateHostPolicyResponse{Remediation: remediation}, nil
+}
+
+func (svc *Service) RemediateHostPolicy(ctx context.Context, hostID, policyID uint) (*fleet.HostPolicyRemediation, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// running a script on the host requires the permission to write the host
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	policy, err := svc.ds.Policy(ctx, policyID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get policy")
+	}
+	if policy.TeamID != nil && (host.TeamID == nil || *host.TeamID != *policy.TeamID) {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("policy_id", "the policy does not apply to the host"))
+	}
+
+	var authorID *uint
+	if vc, ok := viewer.FromContext(ctx); ok {
+		authorID = &vc.User.ID
+	}
+	remediation, err := svc.ds.NewHostPolicyRemediation(ctx, host.ID, policy.ID, authorID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "new host policy remediation")
+	}
+
+	if err := svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeRequestedPolicyRemediation,
+		&map[string]interface{}{"host_id": host.ID, "host_hostname": host.Hostname, "policy_id": policy.ID, "policy_name": policy.Name},
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for policy remediation")
+	}
+	return remediation, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Host Policy Remediations
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostPolicyRemediationsRequest struct {
+	ID          uint              `url:"id"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listHostPolicyRemediationsResponse struct {
+	HostID       uint                           `json:"host_id"`
+	Remediations []*fleet.HostPolicyRemediation `json:"remediations"`
+	Err          error                          `json:"error,omitempty"`
+}
+
+func (r listHostPolicyRemediationsResponse) error() error { return r.Err }
+
+func listHostPolicyRemediationsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostPolicyRemediationsRequest)
+	remediations, err := svc.ListHostPolicyRemediations(ctx, req.ID, req.ListOptions)
+	if err != nil {
+		return listHostPolicyRemediationsResponse{Err: err}, nil
+	}
+	return listHostPolicyRemediationsResponse{HostID: req.ID, Remediations: remediations}, nil
+}
+
+func (svc *Service) ListHostPolicyRemediations(ctx context.Context, hostID uint, opts fleet.ListOptions) ([]*fleet.HostPolicyRemediation, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host")
+	}
+
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	remediations, err := svc.ds.ListHostPolicyRemediations(ctx, host.ID, opts)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host policy remediations")
+	}
+	return remediations, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Current Device's Pending Policy Remediations
+////////////////////////////////////////////////////////////////////////////////
+
+type listDevicePolicyRemediationsRequest struct {
+	Token string `url:"token"`
+}
+
+func (r *listDevicePolicyRemediationsRequest) deviceAuthToken() string {
+	return r.Token
+}
+
+type listDevicePolicyRemediationsResponse struct {
+	Remediations []*fleet.HostPolicyRemediation `json:"remediations"`
+	Err          error                          `json:"error,omitempty"`
+}
+
+func (r listDevicePolicyRemediationsResponse) error() error { return r.Err }
+
+func listDevicePolicyRemediationsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		err := ctxerr.Wrap(ctx, fleet.NewAuthRequiredError("internal error: missing host from request context"))
+		return listDevicePolicyRemediationsResponse{Err: err}, nil
+	}
+
+	remediations, err := svc.PendingHostPolicyRemediations(ctx, host.ID)
+	if err != nil {
+		return listDevicePolicyRemediationsResponse{Err: err}, nil
+	}
+	return listDevicePolicyRemediationsResponse{Remediations: remediations}, nil
+}
+
+// PendingHostPolicyRemediations returns the remediations to be run on the
+// host. It is only available to the device itself and its orbit agent, the
+// device auth token only gives read access to them.
+func (svc *Service) PendingHostPolicyRemediations(ctx context.Context, hostID uint) ([]*fleet.HostPolicyRemediation, error) {
+	if !svc.authz.IsAuthenticatedWith(ctx, authz_ctx.AuthnDeviceToken) && !svc.authz.IsAuthenticatedWith(ctx, authz_ctx.AuthnOrbitNodeKey) {
+		return nil, ctxerr.Wrap(ctx, fleet.NewPermissionError("pending remediations are only available to the device"))
+	}
+
+	remediations, err := svc.ds.PendingHostPolicyRemediations(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "pending host policy remediations")
+	}
+	return remediations, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Orbit's Pending Policy Remediations
+////////////////////////////////////////////////////////////////////////////////
+
+type listOrbitPolicyRemediationsRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+}
+
+func (r *listOrbitPolicyRemediationsRequest) orbitNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+func listOrbitPolicyRemediationsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		err := ctxerr.Wrap(ctx, fleet.NewAuthRequiredError("internal error: missing host from request context"))
+		return listDevicePolicyRemediationsResponse{Err: err}, nil
+	}
+
+	remediations, err := svc.PendingHostPolicyRemediations(ctx, host.ID)
+	if err != nil {
+		return listDevicePolicyRemediationsResponse{Err: err}, nil
+	}
+	return listDevicePolicyRemediationsResponse{Remediations: remediations}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Set Orbit's Policy Remediation Result
+////////////////////////////////////////////////////////////////////////////////
+
+type setOrbitPolicyRemediationResultRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	ID           uint   `url:"remediation_id"`
+	ExitCode     int    `json:"exit_code"`
+	Output       string `json:"output"`
+}
+
+func (r *setOrbitPolicyRemediationResultRequest) orbitNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type setOrbitPolicyRemediationResultResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r setOrbitPolicyRemediationResultResponse) error() error { return r.Err }
+
+func setOrbitPolicyRemediationResultEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*setOrbitPolicyRemediationResultRequest)
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		err := ctxerr.Wrap(ctx, fleet.NewAuthRequiredError("internal error: missing host from request context"))
+		return setOrbitPolicyRemediationResultResponse{Err: err}, nil
+	}
+
+	if err := svc.SetHostPolicyRemediationResult(ctx, host.ID, req.ID, req.ExitCode, req.Output); err != nil {
+		return setOrbitPolicyRemediationResultResponse{Err: err}, nil
+	}
+	return setOrbitPolicyRemediationResultResponse{}, nil
+}
+
+// SetHostPolicyRemediationResult records the result of a pending remediation
+// of the host. It is only available to the orbit agent of the host, as the
+// scripts run as root: the device auth token is also known to the
+// unprivileged users of the host, who must not be able to report results.
+func (svc *Service) SetHostPolicyRemediationResult(ctx context.Context, hostID, id uint, exitCode int, output string) error {
+	if !svc.authz.IsAuthenticatedWith(ctx, authz_ctx.AuthnOrbitNodeKey) {
+		return ctxerr.Wrap(ctx, fleet.NewPermissionError("remediation results can only be set by orbit"))
+	}
+
+	if len(output) > fleet.MaxPolicyRemediationOutputSize {
+		output = output[:fleet.MaxPolicyRemediationOutputSize]
+	}
+	if err := svc.ds.SetHostPolicyRemediationResult(ctx, hostID, id, exitCode, output); err != nil {
+		return ctxerr.Wrap(ctx, err, "set host policy remediation result")
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	authz_ctx "github.com/fleetdm/fleet/v4/server/contexts/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyRemediationsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id, TeamID: ptr.Uint(1)}, nil
+	}
+	ds.PolicyFunc = func(ctx context.Context, id uint) (*fleet.Policy, error) {
+		return &fleet.Policy{PolicyData: fleet.PolicyData{ID: id, RemediationScript: ptr.String("echo fix")}}, nil
+	}
+	ds.NewHostPolicyRemediationFunc = func(ctx context.Context, hostID, policyID uint, authorID *uint) (*fleet.HostPolicyRemediation, error) {
+		return &fleet.HostPolicyRemediation{ID: 1, HostID: hostID, PolicyID: policyID}, nil
+	}
+	ds.ListHostPolicyRemediationsFunc = func(ctx context.Context, hostID uint, opts fleet.ListOptions) ([]*fleet.HostPolicyRemediation, error) {
+		return nil, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name            string
+		user            *fleet.User
+		shouldFailWrite bool
+		shouldFailRead  bool
+	}{
+		{
+			"global admin",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			false,
+			false,
+		},
+		{
+			"global maintainer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			false,
+			false,
+		},
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			true,
+			false,
+		},
+		{
+			"team maintainer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}},
+			false,
+			false,
+		},
+		{
+			"team observer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			true,
+			false,
+		},
+		{
+			"team maintainer, DOES NOT belong to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer}}},
+			true,
+			true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			_, err := svc.RemediateHostPolicy(ctx, 1, 2)
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			_, err = svc.ListHostPolicyRemediations(ctx, 1, fleet.ListOptions{})
+			checkAuthErr(t, tt.shouldFailRead, err)
+
+			// the pending remediations are only available to the device
+			var permErr *fleet.PermissionError
+			_, err = svc.PendingHostPolicyRemediations(ctx, 1)
+			require.True(t, errors.As(err, &permErr))
+			err = svc.SetHostPolicyRemediationResult(ctx, 1, 1, 0, "")
+			require.True(t, errors.As(err, &permErr))
+		})
+	}
+}
+
+func TestRemediateHostPolicy(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id, Hostname: "foo.local", TeamID: ptr.Uint(1)}, nil
+	}
+	ds.PolicyFunc = func(ctx context.Context, id uint) (*fleet.Policy, error) {
+		// policy 3 belongs to another team
+		p := &fleet.Policy{PolicyData: fleet.PolicyData{ID: id, Name: "p", RemediationScript: ptr.String("echo fix")}}
+		if id == 3 {
+			p.TeamID = ptr.Uint(2)
+		}
+		return p, nil
+	}
+	var authorID *uint
+	ds.NewHostPolicyRemediationFunc = func(ctx context.Context, hostID, policyID uint, author *uint) (*fleet.HostPolicyRemediation, error) {
+		authorID = author
+		return &fleet.HostPolicyRemediation{ID: 1, HostID: hostID, PolicyID: policyID, Status: fleet.PolicyRemediationPending}, nil
+	}
+	var activity string
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		activity = activityType
+		return nil
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{ID: 42, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	remediation, err := svc.RemediateHostPolicy(ctx, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), remediation.PolicyID)
+	assert.Equal(t, fleet.PolicyRemediationPending, remediation.Status)
+	require.NotNil(t, authorID)
+	assert.Equal(t, uint(42), *authorID)
+	assert.Equal(t, fleet.ActivityTypeRequestedPolicyRemediation, activity)
+
+	ds.NewHostPolicyRemediationFuncInvoked = false
+	_, err = svc.RemediateHostPolicy(ctx, 1, 3)
+	var argErr *fleet.InvalidArgumentError
+	require.True(t, errors.As(err, &argErr))
+	assert.False(t, ds.NewHostPolicyRemediationFuncInvoked)
+
+	// orbit reports the results of the remediations
+	var gotOutput string
+	ds.SetHostPolicyRemediationResultFunc = func(ctx context.Context, hostID, id uint, exitCode int, output string) error {
+		gotOutput = output
+		return nil
+	}
+	ds.PendingHostPolicyRemediationsFunc = func(ctx context.Context, hostID uint) ([]*fleet.HostPolicyRemediation, error) {
+		return []*fleet.HostPolicyRemediation{{ID: 1, HostID: hostID}}, nil
+	}
+	authnCtx := func(method authz_ctx.AuthenticationMethod) context.Context {
+		ctx := authz_ctx.NewContext(context.Background(), &authz_ctx.AuthorizationContext{})
+		ac, _ := authz_ctx.FromContext(ctx)
+		ac.SetAuthnMethod(method)
+		return ctx
+	}
+	orbitCtx := authnCtx(authz_ctx.AuthnOrbitNodeKey)
+
+	remediations, err := svc.PendingHostPolicyRemediations(orbitCtx, 1)
+	require.NoError(t, err)
+	assert.Len(t, remediations, 1)
+	output := strings.Repeat("a", fleet.MaxPolicyRemediationOutputSize+10)
+	require.NoError(t, svc.SetHostPolicyRemediationResult(orbitCtx, 1, 1, 0, output))
+	assert.Len(t, gotOutput, fleet.MaxPolicyRemediationOutputSize)
+
+	// the device auth token only lists them
+	devCtx := authnCtx(authz_ctx.AuthnDeviceToken)
+	remediations, err = svc.PendingHostPolicyRemediations(devCtx, 1)
+	require.NoError(t, err)
+	assert.Len(t, remediations, 1)
+
+	ds.SetHostPolicyRemediationResultFuncInvoked = false
+	var permErr *fleet.PermissionError
+	err = svc.SetHostPolicyRemediationResult(devCtx, 1, 1, 0, "")
+	require.True(t, errors.As(err, &permErr))
+	assert.False(t, ds.SetHostPolicyRemediationResultFuncInvoked)
+}
@@ -39,11 +39,22 @@ func getQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Servic
 }
 
 func (svc *Service) GetQuery(ctx context.Context, id uint) (*fleet.Query, error) {
+	// First make sure the user can read queries
 	if err := svc.authz.Authorize(ctx, &fleet.Query{}, fleet.ActionRead); err != nil {
 		return nil, err
 	}
 
-	return svc.ds.Query(ctx, id)
+	query, err := svc.ds.Query(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Then we make sure they can read this one, as team queries are only
+	// visible to the members of the team
+	if err := svc.authz.Authorize(ctx, query, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return query, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -52,6 +63,7 @@ func (svc *Service) GetQuery(ctx context.Context, id uint) (*fleet.Query, error)
 
 type listQueriesRequest struct {
 	ListOptions fleet.ListOptions `url:"list_options"`
+	TeamID      *uint             `query:"team_id,optional"`
 }
 
 type listQueriesResponse struct {
@@ -63,7 +75,7 @@ func (r listQueriesResponse) error() error { return r.Err }
 
 func listQueriesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*listQueriesRequest)
-	queries, err := svc.ListQueries(ctx, req.ListOptions)
+	queries, err := svc.ListQueries(ctx, req.ListOptions, req.TeamID)
 	if err != nil {
 		return listQueriesResponse{Err: err}, nil
 	}
@@ -75,8 +87,8 @@ func listQueriesEndpoint(ctx context.Context, request interface{}, svc fleet.Ser
 	return resp, nil
 }
 
-func (svc *Service) ListQueries(ctx context.Context, opt fleet.ListOptions) ([]*fleet.Query, error) {
-	if err := svc.authz.Authorize(ctx, &fleet.Query{}, fleet.ActionRead); err != nil {
+func (svc *Service) ListQueries(ctx context.Context, opt fleet.ListOptions, teamID *uint) ([]*fleet.Query, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Query{TeamID: teamID}, fleet.ActionRead); err != nil {
 		return nil, err
 	}
 
@@ -86,6 +98,7 @@ func (svc *Service) ListQueries(ctx context.Context, opt fleet.ListOptions) ([]*
 	queries, err := svc.ds.ListQueries(ctx, fleet.ListQueryOptions{
 		ListOptions:        opt,
 		OnlyObserverCanRun: onlyShowObserverCanRun,
+		TeamFilter:         &fleet.TeamFilter{User: user, TeamID: teamID},
 	})
 	if err != nil {
 		return nil, err
@@ -136,7 +149,7 @@ func createQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Ser
 
 func (svc *Service) NewQuery(ctx context.Context, p fleet.QueryPayload) (*fleet.Query, error) {
 	user := authz.UserFromContext(ctx)
-	q := &fleet.Query{}
+	q := &fleet.Query{TeamID: p.TeamID}
 	if user != nil {
 		q.AuthorID = ptr.Uint(user.ID)
 	}
@@ -150,7 +163,13 @@ func (svc *Service) NewQuery(ctx context.Context, p fleet.QueryPayload) (*fleet.
 		})
 	}
 
-	query := &fleet.Query{Saved: true}
+	if p.TeamID != nil {
+		if _, err := svc.ds.Team(ctx, *p.TeamID); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get team")
+		}
+	}
+
+	query := &fleet.Query{Saved: true, TeamID: p.TeamID}
 
 	if p.Name != nil {
 		query.Name = *p.Name
@@ -241,6 +260,15 @@ func (svc *Service) ModifyQuery(ctx context.Context, id uint, p fleet.QueryPaylo
 		return nil, err
 	}
 
+	if p.TeamID != nil && (query.TeamID == nil || *p.TeamID != *query.TeamID) {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("team_id", "the team of a query cannot be modified"))
+	}
+	if p.Version != nil && *p.Version != query.Version {
+		return nil, ctxerr.Wrap(ctx, fleet.NewConflictError(
+			fmt.Sprintf("query %d was modified since version %d, reload it and retry", query.ID, *p.Version),
+		))
+	}
+
 	if p.Name != nil {
 		query.Name = *p.Name
 	}
@@ -262,6 +290,7 @@ func (svc *Service) ModifyQuery(ctx context.Context, id uint, p fleet.QueryPaylo
 	if err := svc.ds.SaveQuery(ctx, query); err != nil {
 		return nil, err
 	}
+	svc.clientConfigCache.invalidate()
 
 	if err := svc.ds.NewActivity(
 		ctx,
@@ -317,6 +346,7 @@ func (svc *Service) DeleteQuery(ctx context.Context, name string) error {
 	if err := svc.ds.DeleteQuery(ctx, name); err != nil {
 		return err
 	}
+	svc.clientConfigCache.invalidate()
 
 	return svc.ds.NewActivity(
 		ctx,
@@ -368,6 +398,7 @@ func (svc *Service) DeleteQueryByID(ctx context.Context, id uint) error {
 	if err := svc.ds.DeleteQuery(ctx, query.Name); err != nil {
 		return ctxerr.Wrap(ctx, err, "delete query")
 	}
+	svc.clientConfigCache.invalidate()
 
 	return svc.ds.NewActivity(
 		ctx,
@@ -423,6 +454,7 @@ func (svc *Service) DeleteQueries(ctx context.Context, ids []uint) (uint, error)
 	if err != nil {
 		return n, err
 	}
+	svc.clientConfigCache.invalidate()
 
 	err = svc.ds.NewActivity(
 		ctx,
@@ -498,6 +530,7 @@ func (svc *Service) ApplyQuerySpecs(ctx context.Context, specs []*fleet.QuerySpe
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "applying queries")
 	}
+	svc.clientConfigCache.invalidate()
 
 	return svc.ds.NewActivity(
 		ctx,
@@ -539,7 +572,9 @@ func (svc *Service) GetQuerySpecs(ctx context.Context) ([]*fleet.QuerySpec, erro
 		return nil, err
 	}
 
-	queries, err := svc.ds.ListQueries(ctx, fleet.ListQueryOptions{})
+	queries, err := svc.ds.ListQueries(ctx, fleet.ListQueryOptions{
+		TeamFilter: &fleet.TeamFilter{User: authz.UserFromContext(ctx)},
+	})
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "getting queries")
 	}
@@ -588,5 +623,8 @@ func (svc *Service) GetQuerySpec(ctx context.Context, name string) (*fleet.Query
 	if err != nil {
 		return nil, err
 	}
+	if err := svc.authz.Authorize(ctx, query, fleet.ActionRead); err != nil {
+		return nil, err
+	}
 	return specFromQuery(query), nil
 }
@@ -76,9 +76,11 @@ func TestListQueries(t *testing.T) {
 	for _, tt := range cases {
 		t.Run(tt.title, func(t *testing.T) {
 			viewerCtx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
-			_, err := svc.ListQueries(viewerCtx, fleet.ListOptions{})
+			_, err := svc.ListQueries(viewerCtx, fleet.ListOptions{}, nil)
 			require.NoError(t, err)
-			assert.Equal(t, tt.expectedOpts, calledWithOpts)
+			expectedOpts := tt.expectedOpts
+			expectedOpts.TeamFilter = &fleet.TeamFilter{User: tt.user}
+			assert.Equal(t, expectedOpts, calledWithOpts)
 		})
 	}
 }
@@ -208,7 +210,7 @@ func TestQueryAuth(t *testing.T) {
 			_, err = svc.GetQuery(ctx, tt.qid)
 			checkAuthErr(t, tt.shouldFailRead, err)
 
-			_, err = svc.ListQueries(ctx, fleet.ListOptions{})
+			_, err = svc.ListQueries(ctx, fleet.ListOptions{}, nil)
 			checkAuthErr(t, tt.shouldFailRead, err)
 
 			err = svc.ApplyQuerySpecs(ctx, []*fleet.QuerySpec{{Name: queryName[tt.qid], Query: "SELECT 1"}})
@@ -222,3 +224,123 @@ func TestQueryAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestTeamQueryAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.NewQueryFunc = func(ctx context.Context, query *fleet.Query, opts ...fleet.OptionalArg) (*fleet.Query, error) {
+		return query, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+	// the query belongs to team 1 and was authored by another user
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return &fleet.Query{ID: id, Name: "team1", AuthorID: ptr.Uint(6666), TeamID: ptr.Uint(1)}, nil
+	}
+	ds.SaveQueryFunc = func(ctx context.Context, query *fleet.Query) error {
+		return nil
+	}
+	ds.ListQueriesFunc = func(ctx context.Context, opts fleet.ListQueryOptions) ([]*fleet.Query, error) {
+		return nil, nil
+	}
+
+	testCases := []struct {
+		name            string
+		user            *fleet.User
+		shouldFailWrite bool
+		shouldFailRead  bool
+	}{
+		{
+			"global admin",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			false,
+			false,
+		},
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			true,
+			false,
+		},
+		{
+			"team maintainer, belongs to team",
+			&fleet.User{ID: 42, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}},
+			false,
+			false,
+		},
+		{
+			"team observer, belongs to team",
+			&fleet.User{ID: 43, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			true,
+			false,
+		},
+		{
+			"team admin, DOES NOT belong to team",
+			&fleet.User{ID: 44, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			true,
+			true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			_, err := svc.NewQuery(ctx, fleet.QueryPayload{Name: ptr.String("name"), Query: ptr.String("select 1"), TeamID: ptr.Uint(1)})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			_, err = svc.ModifyQuery(ctx, 1, fleet.QueryPayload{Description: ptr.String("desc")})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+
+			_, err = svc.GetQuery(ctx, 1)
+			checkAuthErr(t, tt.shouldFailRead, err)
+
+			_, err = svc.ListQueries(ctx, fleet.ListOptions{}, ptr.Uint(1))
+			checkAuthErr(t, tt.shouldFailRead, err)
+		})
+	}
+}
+
+func TestModifyQueryVersion(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return &fleet.Query{ID: id, Name: "foo", Query: "SELECT 1", Version: 3}, nil
+	}
+	ds.SaveQueryFunc = func(ctx context.Context, query *fleet.Query) error {
+		query.Version++
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
+	// modifying an outdated version is rejected with a conflict
+	_, err := svc.ModifyQuery(ctx, 1, fleet.QueryPayload{Query: ptr.String("SELECT 2"), Version: ptr.Uint(2)})
+	require.Error(t, err)
+	var conflictErr *fleet.ConflictError
+	require.ErrorAs(t, err, &conflictErr)
+	assert.False(t, ds.SaveQueryFuncInvoked)
+
+	// the team of a query cannot be modified
+	_, err = svc.ModifyQuery(ctx, 1, fleet.QueryPayload{TeamID: ptr.Uint(1)})
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+	assert.False(t, ds.SaveQueryFuncInvoked)
+
+	query, err := svc.ModifyQuery(ctx, 1, fleet.QueryPayload{Query: ptr.String("SELECT 2"), Version: ptr.Uint(3)})
+	require.NoError(t, err)
+	assert.True(t, ds.SaveQueryFuncInvoked)
+	assert.Equal(t, "SELECT 2", query.Query)
+	assert.Equal(t, uint(4), query.Version)
+
+	// the version is optional
+	_, err = svc.ModifyQuery(ctx, 1, fleet.QueryPayload{Query: ptr.String("SELECT 3")})
+	require.NoError(t, err)
+}
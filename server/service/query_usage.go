@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// defaultQueryUsageDays is the number of days of the query usage returned
+// when no start date is provided.
+const defaultQueryUsageDays = 30
+
+const queryUsageDateLayout = "2006-01-02"
+
+// queryRowsUsageCounts implements synchronized storage for the rows returned
+// to the live queries not yet recorded in the datastore, by user, team and day.
+type queryRowsUsageCounts struct {
+	mutex  sync.Mutex
+	counts map[fleet.QueryRowsUsage]uint64
+}
+
+func newQueryRowsUsageCounts() *queryRowsUsageCounts {
+	return &queryRowsUsageCounts{
+		counts: make(map[fleet.QueryRowsUsage]uint64),
+	}
+}
+
+// add counts the rows returned by a host of the team (nil for no team) to a
+// live query of the user, on the day (UTC) of now.
+func (c *queryRowsUsageCounts) add(now time.Time, userID uint, teamID *uint, rows int) {
+	if rows <= 0 {
+		return
+	}
+	now = now.UTC()
+	key := fleet.QueryRowsUsage{
+		UserID: userID,
+		Date:   time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+	}
+	if teamID != nil {
+		key.TeamID = *teamID
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.counts[key] += uint64(rows)
+}
+
+// getAndClear returns the counted rows and resets the counts.
+func (c *queryRowsUsageCounts) getAndClear() []fleet.QueryRowsUsage {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var usage []fleet.QueryRowsUsage
+	for key, n := range c.counts {
+		key.Rows = n
+		usage = append(usage, key)
+	}
+	c.counts = make(map[fleet.QueryRowsUsage]uint64)
+	return usage
+}
+
+func (svc *Service) FlushQueryUsage(ctx context.Context) error {
+	// No authorization check because this is used only internally.
+	usage := svc.queryRowsUsage.getAndClear()
+	return svc.ds.RecordLiveQueryRowsUsage(ctx, usage)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Query Usage
+////////////////////////////////////////////////////////////////////////////////
+
+type listQueryUsageRequest struct {
+	ListOptions fleet.ListOptions `url:"list_options"`
+	StartDate   string            `query:"start_date,optional"`
+	EndDate     string            `query:"end_date,optional"`
+	TeamID      *uint             `query:"team_id,optional"`
+	UserID      *uint             `query:"user_id,optional"`
+}
+
+type listQueryUsageResponse struct {
+	Usage []fleet.QueryUsage `json:"usage"`
+	Err   error              `json:"error,omitempty"`
+}
+
+func (r listQueryUsageResponse) error() error { return r.Err }
+
+func listQueryUsageEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listQueryUsageRequest)
+
+	opt := fleet.QueryUsageOptions{
+		ListOptions: req.ListOptions,
+		TeamID:      req.TeamID,
+		UserID:      req.UserID,
+	}
+	invalid := &fleet.InvalidArgumentError{}
+	if req.StartDate != "" {
+		d, err := time.Parse(queryUsageDateLayout, req.StartDate)
+		if err != nil {
+			invalid.Append("start_date", "must be a date in the YYYY-MM-DD format")
+		}
+		opt.StartDate = d
+	}
+	if req.EndDate != "" {
+		d, err := time.Parse(queryUsageDateLayout, req.EndDate)
+		if err != nil {
+			invalid.Append("end_date", "must be a date in the YYYY-MM-DD format")
+		}
+		opt.EndDate = d
+	}
+	if invalid.HasErrors() {
+		return listQueryUsageResponse{Err: ctxerr.Wrap(ctx, invalid)}, nil
+	}
+
+	usage, err := svc.ListQueryUsage(ctx, opt)
+	if err != nil {
+		return listQueryUsageResponse{Err: err}, nil
+	}
+	return listQueryUsageResponse{Usage: usage}, nil
+}
+
+func (svc *Service) ListQueryUsage(ctx context.Context, opt fleet.QueryUsageOptions) ([]fleet.QueryUsage, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if opt.EndDate.IsZero() {
+		opt.EndDate = svc.clock.Now().UTC()
+	}
+	if opt.StartDate.IsZero() {
+		opt.StartDate = opt.EndDate.AddDate(0, 0, -(defaultQueryUsageDays - 1))
+	}
+	if opt.StartDate.After(opt.EndDate) {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("start_date", "must not be after end_date"))
+	}
+	return svc.ds.ListQueryUsage(ctx, opt)
+}
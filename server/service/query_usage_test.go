@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListQueryUsage(t *testing.T) {
+	ds := new(mock.Store)
+	mockClock := clock.NewMockClock(time.Date(2022, 3, 25, 12, 0, 0, 0, time.UTC))
+	svc := newTestServiceWithClock(t, ds, nil, nil, mockClock)
+
+	var gotOpt fleet.QueryUsageOptions
+	ds.ListQueryUsageFunc = func(ctx context.Context, opt fleet.QueryUsageOptions) ([]fleet.QueryUsage, error) {
+		gotOpt = opt
+		return []fleet.QueryUsage{{UserID: 1, RowsReturned: 42}}, nil
+	}
+
+	for _, user := range []*fleet.User{test.UserMaintainer, test.UserObserver, test.UserTeamAdminTeam1} {
+		_, err := svc.ListQueryUsage(test.UserContext(user), fleet.QueryUsageOptions{})
+		require.Error(t, err)
+	}
+	assert.False(t, ds.ListQueryUsageFuncInvoked)
+
+	ctx := test.UserContext(test.UserAdmin)
+
+	// the last 30 days by default
+	usage, err := svc.ListQueryUsage(ctx, fleet.QueryUsageOptions{})
+	require.NoError(t, err)
+	require.Len(t, usage, 1)
+	assert.Equal(t, "2022-03-25", gotOpt.EndDate.Format("2006-01-02"))
+	assert.Equal(t, "2022-02-24", gotOpt.StartDate.Format("2006-01-02"))
+
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 3, 10, 0, 0, 0, 0, time.UTC)
+	_, err = svc.ListQueryUsage(ctx, fleet.QueryUsageOptions{StartDate: start, EndDate: end})
+	require.NoError(t, err)
+	assert.Equal(t, start, gotOpt.StartDate)
+	assert.Equal(t, end, gotOpt.EndDate)
+
+	ds.ListQueryUsageFuncInvoked = false
+	_, err = svc.ListQueryUsage(ctx, fleet.QueryUsageOptions{StartDate: end, EndDate: start})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must not be after end_date")
+	assert.False(t, ds.ListQueryUsageFuncInvoked)
+}
+
+func TestFlushQueryUsage(t *testing.T) {
+	ds := new(mock.Store)
+	mockClock := clock.NewMockClock(time.Date(2022, 3, 25, 12, 0, 0, 0, time.UTC))
+	svc := ((newTestServiceWithClock(t, ds, nil, nil, mockClock).(validationMiddleware)).Service).(*Service)
+
+	var recorded []fleet.QueryRowsUsage
+	ds.RecordLiveQueryRowsUsageFunc = func(ctx context.Context, usage []fleet.QueryRowsUsage) error {
+		recorded = usage
+		return nil
+	}
+
+	// the rows of the same user, team and day are added up
+	teamID := uint(3)
+	svc.queryRowsUsage.add(mockClock.Now(), 1, &teamID, 2)
+	svc.queryRowsUsage.add(mockClock.Now(), 1, &teamID, 5)
+	svc.queryRowsUsage.add(mockClock.Now(), 1, &teamID, 0)
+	require.NoError(t, svc.FlushQueryUsage(context.Background()))
+	require.Equal(t, []fleet.QueryRowsUsage{{
+		UserID: 1,
+		TeamID: 3,
+		Date:   time.Date(2022, 3, 25, 0, 0, 0, 0, time.UTC),
+		Rows:   7,
+	}}, recorded)
+
+	// the counts are reset once flushed
+	require.NoError(t, svc.FlushQueryUsage(context.Background()))
+	require.Empty(t, recorded)
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 
+	"github.com/fleetdm/fleet/v4/server/authz"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 )
@@ -59,14 +60,16 @@ func (svc *Service) GetScheduledQueriesInPack(ctx context.Context, id uint, opts
 ////////////////////////////////////////////////////////////////////////////////
 
 type scheduleQueryRequest struct {
-	PackID   uint    `json:"pack_id"`
-	QueryID  uint    `json:"query_id"`
-	Interval uint    `json:"interval"`
-	Snapshot *bool   `json:"snapshot"`
-	Removed  *bool   `json:"removed"`
-	Platform *string `json:"platform"`
-	Version  *string `json:"version"`
-	Shard    *uint   `json:"shard"`
+	PackID      uint    `json:"pack_id"`
+	QueryID     uint    `json:"query_id"`
+	Interval    uint    `json:"interval"`
+	Snapshot    *bool   `json:"snapshot"`
+	Removed     *bool   `json:"removed"`
+	Platform    *string `json:"platform"`
+	Version     *string `json:"version"`
+	Shard       *uint   `json:"shard"`
+	DiscardData bool    `json:"discard_data"`
+	LabelIDs    []uint  `json:"label_ids"`
 }
 
 type scheduleQueryResponse struct {
@@ -80,14 +83,16 @@ func scheduleQueryEndpoint(ctx context.Context, request interface{}, svc fleet.S
 	req := request.(*scheduleQueryRequest)
 
 	scheduled, err := svc.ScheduleQuery(ctx, &fleet.ScheduledQuery{
-		PackID:   req.PackID,
-		QueryID:  req.QueryID,
-		Interval: req.Interval,
-		Snapshot: req.Snapshot,
-		Removed:  req.Removed,
-		Platform: req.Platform,
-		Version:  req.Version,
-		Shard:    req.Shard,
+		PackID:      req.PackID,
+		QueryID:     req.QueryID,
+		Interval:    req.Interval,
+		Snapshot:    req.Snapshot,
+		Removed:     req.Removed,
+		Platform:    req.Platform,
+		Version:     req.Version,
+		Shard:       req.Shard,
+		DiscardData: req.DiscardData,
+		LabelIDs:    req.LabelIDs,
 	})
 	if err != nil {
 		return scheduleQueryResponse{Err: err}, nil
@@ -103,10 +108,16 @@ func (svc *Service) ScheduleQuery(ctx context.Context, sq *fleet.ScheduledQuery)
 		return nil, err
 	}
 
-	return svc.unauthorizedScheduleQuery(ctx, sq)
+	return svc.unauthorizedScheduleQuery(ctx, nil, sq)
 }
 
-func (svc *Service) unauthorizedScheduleQuery(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+// unauthorizedScheduleQuery creates the scheduled query, teamID is the ID of
+// the team if the query is scheduled in the team's pack, nil otherwise.
+func (svc *Service) unauthorizedScheduleQuery(ctx context.Context, teamID *uint, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+	if err := svc.verifyScheduleInterval(ctx, teamID, sq.Interval, sq.Snapshot); err != nil {
+		return nil, err
+	}
+
 	// Fill in the name with query name if it is unset (because the UI
 	// doesn't provide a way to set it)
 	if sq.Name == "" {
@@ -129,7 +140,42 @@ func (svc *Service) unauthorizedScheduleQuery(ctx context.Context, sq *fleet.Sch
 		}
 		sq.QueryName = query.Name
 	}
-	return svc.ds.NewScheduledQuery(ctx, sq)
+
+	sq, err := svc.ds.NewScheduledQuery(ctx, sq)
+	if err != nil {
+		return nil, err
+	}
+	svc.clientConfigCache.invalidate()
+	if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+		return nil, err
+	}
+	return sq, nil
+}
+
+// verifyScheduleInterval returns an error if the interval is below the
+// minimum allowed by the schedule settings of the team (if teamID is not nil
+// and the team overrides them) or by the global schedule settings.
+func (svc *Service) verifyScheduleInterval(ctx context.Context, teamID *uint, interval uint, snapshot *bool) error {
+	var settings *fleet.ScheduleSettings
+	if teamID != nil {
+		team, err := svc.ds.Team(ctx, *teamID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "load team schedule settings")
+		}
+		settings = team.Config.ScheduleSettings
+	}
+	if settings == nil {
+		appConfig, err := svc.ds.AppConfig(ctx)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "load schedule settings")
+		}
+		settings = &appConfig.ScheduleSettings
+	}
+
+	if err := settings.VerifyInterval(interval, snapshot != nil && *snapshot); err != nil {
+		return ctxerr.Wrap(ctx, err)
+	}
+	return nil
 }
 
 // Add "-1" suffixes to the query name until it is unique
@@ -218,10 +264,13 @@ func (svc *Service) ModifyScheduledQuery(ctx context.Context, id uint, p fleet.S
 		return nil, err
 	}
 
-	return svc.unauthorizedModifyScheduledQuery(ctx, id, p)
+	return svc.unauthorizedModifyScheduledQuery(ctx, nil, id, p)
 }
 
-func (svc *Service) unauthorizedModifyScheduledQuery(ctx context.Context, id uint, p fleet.ScheduledQueryPayload) (*fleet.ScheduledQuery, error) {
+// unauthorizedModifyScheduledQuery modifies the scheduled query, teamID is
+// the ID of the team if the query is scheduled in the team's pack, nil
+// otherwise.
+func (svc *Service) unauthorizedModifyScheduledQuery(ctx context.Context, teamID *uint, id uint, p fleet.ScheduledQueryPayload) (*fleet.ScheduledQuery, error) {
 	sq, err := svc.ds.ScheduledQuery(ctx, id)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "getting scheduled query to modify")
@@ -264,7 +313,31 @@ func (svc *Service) unauthorizedModifyScheduledQuery(ctx context.Context, id uin
 		}
 	}
 
-	return svc.ds.SaveScheduledQuery(ctx, sq)
+	if p.DiscardData != nil {
+		sq.DiscardData = *p.DiscardData
+	}
+
+	if p.LabelIDs != nil {
+		sq.LabelIDs = *p.LabelIDs
+	}
+
+	// only verify the interval if it is modified, so that existing scheduled
+	// queries can still be edited after the minimum intervals are raised.
+	if p.Interval != nil || p.Snapshot != nil {
+		if err := svc.verifyScheduleInterval(ctx, teamID, sq.Interval, sq.Snapshot); err != nil {
+			return nil, err
+		}
+	}
+
+	sq, err = svc.ds.SaveScheduledQuery(ctx, sq)
+	if err != nil {
+		return nil, err
+	}
+	svc.clientConfigCache.invalidate()
+	if err := svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx)); err != nil {
+		return nil, err
+	}
+	return sq, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -298,5 +371,9 @@ func (svc *Service) DeleteScheduledQuery(ctx context.Context, id uint) error {
 		return err
 	}
 
-	return svc.ds.DeleteScheduledQuery(ctx, id)
+	if err := svc.ds.DeleteScheduledQuery(ctx, id); err != nil {
+		return err
+	}
+	svc.clientConfigCache.invalidate()
+	return svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx))
 }
@@ -17,6 +17,10 @@ func TestScheduledQueriesAuth(t *testing.T) {
 	ds := new(mock.Store)
 	svc := newTestService(t, ds, nil, nil)
 
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
 	ds.ListScheduledQueriesInPackWithStatsFunc = func(ctx context.Context, id uint, opts fleet.ListOptions) ([]*fleet.ScheduledQuery, error) {
 		return nil, nil
 	}
@@ -35,6 +39,9 @@ func TestScheduledQueriesAuth(t *testing.T) {
 	ds.DeleteScheduledQueryFunc = func(ctx context.Context, id uint) error {
 		return nil
 	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
 
 	testCases := []struct {
 		name            string
@@ -105,6 +112,10 @@ func TestScheduleQuery(t *testing.T) {
 	ds := new(mock.Store)
 	svc := newTestService(t, ds, nil, nil)
 
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
 	expectedQuery := &fleet.ScheduledQuery{
 		Name:      "foobar",
 		QueryName: "foobar",
@@ -115,6 +126,9 @@ func TestScheduleQuery(t *testing.T) {
 		assert.Equal(t, expectedQuery, q)
 		return expectedQuery, nil
 	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
 
 	_, err := svc.ScheduleQuery(test.UserContext(test.UserAdmin), expectedQuery)
 	assert.NoError(t, err)
@@ -125,6 +139,10 @@ func TestScheduleQueryNoName(t *testing.T) {
 	ds := new(mock.Store)
 	svc := newTestService(t, ds, nil, nil)
 
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
 	expectedQuery := &fleet.ScheduledQuery{
 		Name:      "foobar",
 		QueryName: "foobar",
@@ -147,6 +165,9 @@ func TestScheduleQueryNoName(t *testing.T) {
 		assert.Equal(t, expectedQuery, q)
 		return expectedQuery, nil
 	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
 
 	_, err := svc.ScheduleQuery(
 		test.UserContext(test.UserAdmin),
@@ -160,6 +181,10 @@ func TestScheduleQueryNoNameMultiple(t *testing.T) {
 	ds := new(mock.Store)
 	svc := newTestService(t, ds, nil, nil)
 
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
 	expectedQuery := &fleet.ScheduledQuery{
 		Name:      "foobar-1",
 		QueryName: "foobar",
@@ -182,6 +207,9 @@ func TestScheduleQueryNoNameMultiple(t *testing.T) {
 		assert.Equal(t, expectedQuery, q)
 		return expectedQuery, nil
 	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
 
 	_, err := svc.ScheduleQuery(
 		test.UserContext(test.UserAdmin),
@@ -231,3 +259,72 @@ func TestFindNextNameForQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestScheduleQueryMinInterval(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{ScheduleSettings: fleet.ScheduleSettings{MinInterval: 10, MinSnapshotInterval: 60}}, nil
+	}
+	teamSettings := map[uint]*fleet.ScheduleSettings{
+		1: {MinInterval: 1, MinSnapshotInterval: 5},
+		2: nil, // inherits the global settings
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Config: fleet.TeamConfig{ScheduleSettings: teamSettings[tid]}}, nil
+	}
+	ds.EnsureTeamPackFunc = func(ctx context.Context, teamID uint) (*fleet.Pack, error) {
+		return &fleet.Pack{ID: 100 + teamID}, nil
+	}
+	ds.NewScheduledQueryFunc = func(ctx context.Context, sq *fleet.ScheduledQuery, opts ...fleet.OptionalArg) (*fleet.ScheduledQuery, error) {
+		return sq, nil
+	}
+	ds.ScheduledQueryFunc = func(ctx context.Context, id uint) (*fleet.ScheduledQuery, error) {
+		return &fleet.ScheduledQuery{ID: id, Interval: 1}, nil
+	}
+	ds.SaveScheduledQueryFunc = func(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+		return sq, nil
+	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
+
+	ctx := test.UserContext(test.UserAdmin)
+	newQuery := func(interval uint, snapshot bool) *fleet.ScheduledQuery {
+		return &fleet.ScheduledQuery{Name: "foo", QueryName: "foo", QueryID: 1, Interval: interval, Snapshot: ptr.Bool(snapshot)}
+	}
+
+	_, err := svc.ScheduleQuery(ctx, newQuery(1, false))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be at least 10 seconds")
+	_, err = svc.ScheduleQuery(ctx, newQuery(10, false))
+	require.NoError(t, err)
+	_, err = svc.ScheduleQuery(ctx, newQuery(30, true))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be at least 60 seconds for snapshot queries")
+	_, err = svc.ScheduleQuery(ctx, newQuery(60, true))
+	require.NoError(t, err)
+
+	// team 1 overrides the global settings
+	_, err = svc.TeamScheduleQuery(ctx, 1, newQuery(1, false))
+	require.NoError(t, err)
+	_, err = svc.TeamScheduleQuery(ctx, 1, newQuery(1, true))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be at least 5 seconds for snapshot queries")
+
+	// team 2 inherits the global settings
+	_, err = svc.TeamScheduleQuery(ctx, 2, newQuery(1, false))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be at least 10 seconds")
+
+	// modifying a scheduled query without changing its interval is allowed
+	_, err = svc.ModifyScheduledQuery(ctx, 1, fleet.ScheduledQueryPayload{Removed: ptr.Bool(true)})
+	require.NoError(t, err)
+	_, err = svc.ModifyScheduledQuery(ctx, 1, fleet.ScheduledQueryPayload{Interval: ptr.Uint(5)})
+	require.Error(t, err)
+	_, err = svc.ModifyScheduledQuery(ctx, 1, fleet.ScheduledQueryPayload{Interval: ptr.Uint(20), Snapshot: ptr.Bool(true)})
+	require.Error(t, err)
+	_, err = svc.ModifyTeamScheduledQueries(ctx, 1, 1, fleet.ScheduledQueryPayload{Interval: ptr.Uint(5)})
+	require.NoError(t, err)
+}
@@ -40,6 +40,18 @@ type Service struct {
 
 	seenHostSet *seenHostSet
 
+	enrollmentCounts *enrollmentOutcomeCounts
+
+	queryRowsUsage *queryRowsUsageCounts
+
+	campaignCache   *campaignCache
+	liveQueryWrites *liveQueryResultWrites
+
+	// detailIngester is nil if the asynchronous detail ingestion is disabled.
+	detailIngester *detailIngester
+
+	clientConfigCache *clientConfigCache
+
 	failingPolicySet fleet.FailingPolicySet
 
 	authz *authz.Authorizer
@@ -78,24 +90,34 @@ func NewService(
 	}
 
 	svc := &Service{
-		ds:               ds,
-		task:             task,
-		carveStore:       carveStore,
-		resultStore:      resultStore,
-		liveQueryStore:   lq,
-		logger:           logger,
-		config:           config,
-		clock:            c,
-		osqueryLogWriter: osqueryLogger,
-		mailService:      mailService,
-		ssoSessionStore:  sso,
-		seenHostSet:      newSeenHostSet(),
-		license:          license,
-		failingPolicySet: failingPolicySet,
-		authz:            authorizer,
-		jitterH:          make(map[time.Duration]*jitterHashTable),
-		jitterMu:         new(sync.Mutex),
-		geoIP:            geoIP,
+		ds:                ds,
+		task:              task,
+		carveStore:        carveStore,
+		resultStore:       resultStore,
+		liveQueryStore:    lq,
+		logger:            logger,
+		config:            config,
+		clock:             c,
+		osqueryLogWriter:  osqueryLogger,
+		mailService:       mailService,
+		ssoSessionStore:   sso,
+		seenHostSet:       newSeenHostSet(),
+		enrollmentCounts:  newEnrollmentOutcomeCounts(),
+		queryRowsUsage:    newQueryRowsUsageCounts(),
+		campaignCache:     newCampaignCache(c),
+		liveQueryWrites:   newLiveQueryResultWrites(),
+		clientConfigCache: newClientConfigCache(config.Osquery.ClientConfigCacheTTL, c),
+		license:           license,
+		failingPolicySet:  failingPolicySet,
+		authz:             authorizer,
+		jitterH:           make(map[time.Duration]*jitterHashTable),
+		jitterMu:          new(sync.Mutex),
+		geoIP:             geoIP,
+	}
+	if config.Osquery.EnableAsyncDetailIngestion {
+		svc.detailIngester = newDetailIngester(svc, config.Osquery.AsyncDetailIngestionWorkers, config.Osquery.AsyncDetailIngestionQueueSize,
+			config.Osquery.AsyncDetailIngestionUpdateBatch, config.Osquery.AsyncDetailIngestionInterval)
+		svc.detailIngester.start(ctx)
 	}
 	return validationMiddleware{svc, ds, sso}, nil
 }
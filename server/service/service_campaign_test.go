@@ -50,13 +50,22 @@ func TestStreamCampaignResultsClosesReditOnWSClose(t *testing.T) {
 	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
 		return camp, nil
 	}
+	ds.RecordLiveQueryCampaignUsageFunc = func(ctx context.Context, userID uint, hostIDs []uint, now time.Time) error {
+		return nil
+	}
+	ds.NewDistributedQueryExecutionsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint, now time.Time, settings fleet.HostSettings) error {
+		return nil
+	}
+	ds.NewLiveQueryAuditLogFunc = func(ctx context.Context, entry *fleet.LiveQueryAuditLog) error {
+		return nil
+	}
 	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
 		return target, nil
 	}
-	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, settings fleet.HostSettings) ([]uint, error) {
 		return []uint{1}, nil
 	}
-	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time, settings fleet.HostSettings) (fleet.TargetMetrics, error) {
 		return fleet.TargetMetrics{TotalHosts: 1}, nil
 	}
 	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
@@ -89,7 +98,7 @@ func TestStreamCampaignResultsClosesReditOnWSClose(t *testing.T) {
 		},
 	})
 	q := "select year, month, day, hour, minutes, seconds from time"
-	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}})
+	_, err := svc.NewDistributedQueryCampaign(viewerCtx, q, nil, fleet.HostTargets{HostIDs: []uint{2}, LabelIDs: []uint{1}}, false, nil, fleet.DistributedQueryResultLimits{})
 	require.NoError(t, err)
 
 	s := httptest.NewServer(makeStreamDistributedQueryCampaignResultsHandler(svc, kitlog.NewNopLogger()))
@@ -137,6 +146,9 @@ func TestStreamCampaignResultsClosesReditOnWSClose(t *testing.T) {
 	ds.DistributedQueryCampaignTargetIDsFunc = func(ctx context.Context, id uint) (targets *fleet.HostTargets, err error) {
 		return &fleet.HostTargets{HostIDs: []uint{1}}, nil
 	}
+	ds.DistributedQueryCampaignProgressFunc = func(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaignProgress, error) {
+		return &fleet.DistributedQueryCampaignProgress{}, nil
+	}
 	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
 		return &fleet.Query{}, nil
 	}
@@ -10,15 +10,14 @@ import (
 	"github.com/fleetdm/fleet/v4/server/contexts/logging"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
-	"github.com/fleetdm/fleet/v4/server/websocket"
 	"github.com/go-kit/kit/log/level"
-	"github.com/igm/sockjs-go/v3/sockjs"
 )
 
 type targetTotals struct {
 	Total           uint `json:"count"`
 	Online          uint `json:"online"`
 	Offline         uint `json:"offline"`
+	Missing         uint `json:"missing"`
 	MissingInAction uint `json:"missing_in_action"`
 }
 
@@ -33,7 +32,7 @@ type campaignStatus struct {
 	Status          string `json:"status"`
 }
 
-func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Conn, campaignID uint) {
+func (svc Service) StreamCampaignResults(ctx context.Context, conn fleet.CampaignResultsStream, campaignID uint) {
 	logging.WithExtras(ctx, "campaign_id", campaignID)
 
 	// Explicitly set ObserverCanRun: true in this check because we check that the user trying to
@@ -70,6 +69,13 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 		return
 	}
 
+	// The results of the batch queries are stored instead of being streamed,
+	// and the campaign would be completed once the stream ends.
+	if campaign.Batch {
+		conn.WriteJSONError(fmt.Sprintf("campaign %d is a batch query, its results are not streamed", campaignID))
+		return
+	}
+
 	// Open the channel from which we will receive incoming query results
 	// (probably from the redis pubsub implementation)
 	readChan, cancelFunc, err := svc.GetCampaignReader(ctx, campaign)
@@ -89,6 +95,7 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 	}
 	lastStatus := status
 	lastTotals := targetTotals{}
+	var lastProgress fleet.DistributedQueryCampaignProgress
 
 	// to improve performance of the frontend rendering the results table, we
 	// add the "host_hostname" field to every row and clean null rows.
@@ -105,13 +112,17 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 		res.Rows = filteredRows
 	}
 
-	targets, err := svc.ds.DistributedQueryCampaignTargetIDs(ctx, campaign.ID)
-	if err != nil {
-		conn.WriteJSONError("error retrieving campaign targets: " + err.Error())
-		return
-	}
-
 	updateStatus := func() error {
+		// the targets are retrieved on each update, as hosts and labels can be
+		// removed from the targets of a running campaign
+		targets, err := svc.ds.DistributedQueryCampaignTargetIDs(ctx, campaign.ID)
+		if err != nil {
+			if werr := conn.WriteJSONError("error retrieving campaign targets: " + err.Error()); werr != nil {
+				return ctxerr.Wrap(ctx, werr, "retrieve campaign targets, write failed")
+			}
+			return ctxerr.Wrap(ctx, err, "retrieve campaign targets")
+		}
+
 		metrics, err := svc.CountHostsInTargets(ctx, &campaign.QueryID, *targets)
 		if err != nil {
 			if err = conn.WriteJSONError("error retrieving target counts"); err != nil {
@@ -124,6 +135,7 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 			Total:           metrics.TotalHosts,
 			Online:          metrics.OnlineHosts,
 			Offline:         metrics.OfflineHosts,
+			Missing:         metrics.MissingHosts,
 			MissingInAction: metrics.MissingInActionHosts,
 		}
 		if lastTotals != totals {
@@ -145,6 +157,17 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 			}
 		}
 
+		// the progress is informational, the stream goes on without it
+		progress, err := svc.ds.DistributedQueryCampaignProgress(ctx, campaign.ID)
+		if err != nil {
+			level.Info(svc.logger).Log("msg", "error retrieving campaign progress", "err", err)
+		} else if lastProgress != *progress {
+			lastProgress = *progress
+			if err = conn.WriteJSONMessage("progress", progress); err != nil {
+				return ctxerr.Wrap(ctx, err, "write progress")
+			}
+		}
+
 		return nil
 	}
 
@@ -163,13 +186,13 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 		// 0 Hosts Returning y Records")
 		select {
 		case res := <-readChan:
-			// Receive a result and push it over the websocket
+			// Receive a result and push it over the stream
 			switch res := res.(type) {
 			case fleet.DistributedQueryResult:
 				mapHostnameRows(&res)
 				err = conn.WriteJSONMessage("result", res)
-				if ctxerr.Cause(err) == sockjs.ErrSessionNotOpen {
-					// return and stop sending the query if the session was closed
+				if err != nil && conn.Closed() {
+					// return and stop sending the query if the connection was closed
 					// by the client
 					return
 				}
@@ -180,7 +203,7 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 			}
 
 		case <-ticker.C:
-			if conn.GetSessionState() == sockjs.SessionClosed {
+			if conn.Closed() {
 				// return and stop sending the query if the session was closed
 				// by the client
 				return
@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 )
 
@@ -88,3 +89,58 @@ func (svc Service) CountSoftware(ctx context.Context, opt fleet.SoftwareListOpti
 
 	return svc.ds.CountSoftware(ctx, opt)
 }
+
+/////////////////////////////////////////////////////////////////////////////////
+// List Host Software
+/////////////////////////////////////////////////////////////////////////////////
+
+type listHostSoftwareRequest struct {
+	ID uint `url:"id"`
+	fleet.SoftwareListOptions
+}
+
+type listHostSoftwareResponse struct {
+	HostID   uint             `json:"host_id"`
+	Software []fleet.Software `json:"software"`
+	Err      error            `json:"error,omitempty"`
+}
+
+func (r listHostSoftwareResponse) error() error { return r.Err }
+
+func listHostSoftwareEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostSoftwareRequest)
+	software, err := svc.ListHostSoftware(ctx, req.ID, req.SoftwareListOptions)
+	if err != nil {
+		return listHostSoftwareResponse{Err: err}, nil
+	}
+	if software == nil {
+		software = []fleet.Software{}
+	}
+	return listHostSoftwareResponse{HostID: req.ID, Software: software}, nil
+}
+
+func (svc Service) ListHostSoftware(ctx context.Context, hostID uint, opt fleet.SoftwareListOptions) ([]fleet.Software, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "find host for software")
+	}
+
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	// the team and host counts do not apply to the software of a single host
+	opt.TeamID = nil
+	opt.WithHostCounts = false
+
+	// default sort order to name ascending
+	if opt.OrderKey == "" {
+		opt.OrderKey = "name"
+		opt.OrderDirection = fleet.OrderAscending
+	}
+	return svc.ds.ListHostSoftware(ctx, host.ID, opt)
+}
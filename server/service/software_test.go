@@ -48,3 +48,52 @@ func TestService_ListSoftware(t *testing.T) {
 	assert.Equal(t, fleet.ListOptions{PerPage: 11, Page: 2, OrderKey: "id", OrderDirection: fleet.OrderAscending}, calledWithOpt.ListOptions)
 	assert.True(t, calledWithOpt.WithHostCounts)
 }
+
+func TestListHostSoftware(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id, TeamID: ptr.Uint(1)}, nil
+	}
+	var calledWithOpt fleet.SoftwareListOptions
+	ds.ListHostSoftwareFunc = func(ctx context.Context, hostID uint, opt fleet.SoftwareListOptions) ([]fleet.Software, error) {
+		calledWithOpt = opt
+		return []fleet.Software{}, nil
+	}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		shouldFail bool
+	}{
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			false,
+		},
+		{
+			"team observer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			false,
+		},
+		{
+			"team maintainer, DOES NOT belong to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer}}},
+			true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+			_, err := svc.ListHostSoftware(ctx, 1, fleet.SoftwareListOptions{})
+			checkAuthErr(t, tt.shouldFail, err)
+		})
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)}})
+	_, err := svc.ListHostSoftware(ctx, 1, fleet.SoftwareListOptions{TeamID: ptr.Uint(2), WithHostCounts: true, Source: "apps"})
+	require.NoError(t, err)
+	// sort order defaults to name ascending and the team is ignored
+	assert.Equal(t, fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{OrderKey: "name", OrderDirection: fleet.OrderAscending}, Source: "apps"}, calledWithOpt)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// List Host Startup Items
+////////////////////////////////////////////////////////////////////////////////
+
+type listHostStartupItemsRequest struct {
+	ID uint `url:"id"`
+}
+
+type listHostStartupItemsResponse struct {
+	Err          error                   `json:"error,omitempty"`
+	StartupItems []fleet.HostStartupItem `json:"startup_items"`
+}
+
+func (r listHostStartupItemsResponse) error() error { return r.Err }
+
+func listHostStartupItemsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostStartupItemsRequest)
+	items, err := svc.ListHostStartupItems(ctx, req.ID)
+	if err != nil {
+		return listHostStartupItemsResponse{Err: err}, nil
+	}
+	return listHostStartupItemsResponse{StartupItems: items}, nil
+}
+
+func (svc *Service) ListHostStartupItems(ctx context.Context, id uint) ([]fleet.HostStartupItem, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	host, err := svc.ds.HostLite(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "find host for startup items")
+	}
+
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.ListHostStartupItems(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Startup Item Changes
+////////////////////////////////////////////////////////////////////////////////
+
+type listStartupItemChangesRequest struct {
+	TeamID      *uint             `query:"team_id,optional"`
+	HostID      *uint             `query:"host_id,optional"`
+	ChangeType  string            `query:"change_type,optional"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listStartupItemChangesResponse struct {
+	Err     error                     `json:"error,omitempty"`
+	Changes []fleet.StartupItemChange `json:"changes"`
+}
+
+func (r listStartupItemChangesResponse) error() error { return r.Err }
+
+func listStartupItemChangesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listStartupItemChangesRequest)
+	changes, err := svc.ListStartupItemChanges(ctx, req.TeamID, fleet.StartupItemChangesListOptions{
+		ListOptions: req.ListOptions,
+		HostID:      req.HostID,
+		ChangeType:  req.ChangeType,
+	})
+	if err != nil {
+		return listStartupItemChangesResponse{Err: err}, nil
+	}
+	return listStartupItemChangesResponse{Changes: changes}, nil
+}
+
+func (svc *Service) ListStartupItemChanges(ctx context.Context, teamID *uint, opt fleet.StartupItemChangesListOptions) ([]fleet.StartupItemChange, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{TeamID: teamID}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	switch opt.ChangeType {
+	case "", fleet.StartupItemChangeAdded, fleet.StartupItemChangeRemoved, fleet.StartupItemChangeModified:
+	default:
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("change_type", "must be one of added, removed or modified"))
+	}
+
+	if teamID != nil {
+		if _, err := svc.ds.Team(ctx, *teamID); err != nil {
+			return nil, err
+		}
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true, TeamID: teamID}
+
+	return svc.ds.ListStartupItemChanges(ctx, filter, opt)
+}
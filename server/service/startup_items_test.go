@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupItemsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	teamHost := &fleet.Host{ID: 1, TeamID: ptr.Uint(1)}
+	globalHost := &fleet.Host{ID: 2}
+
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		if id == 1 {
+			return teamHost, nil
+		}
+		return globalHost, nil
+	}
+	ds.ListHostStartupItemsFunc = func(ctx context.Context, hostID uint) ([]fleet.HostStartupItem, error) {
+		return nil, nil
+	}
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.ListStartupItemChangesFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.StartupItemChangesListOptions) ([]fleet.StartupItemChange, error) {
+		return nil, nil
+	}
+
+	testCases := []struct {
+		name                 string
+		user                 *fleet.User
+		shouldFailGlobalRead bool
+		shouldFailTeamRead   bool
+	}{
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			false,
+			false,
+		},
+		{
+			"team observer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			true,
+			false,
+		},
+		{
+			"team maintainer, DOES NOT belong to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer}}},
+			true,
+			true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			_, err := svc.ListHostStartupItems(ctx, 1)
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			_, err = svc.ListHostStartupItems(ctx, 2)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+
+			_, err = svc.ListStartupItemChanges(ctx, ptr.Uint(1), fleet.StartupItemChangesListOptions{})
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+		})
+	}
+
+	// the changes are filtered by the user's teams
+	var gotFilter fleet.TeamFilter
+	ds.ListStartupItemChangesFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.StartupItemChangesListOptions) ([]fleet.StartupItemChange, error) {
+		gotFilter = filter
+		return nil, nil
+	}
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+	_, err := svc.ListStartupItemChanges(ctx, ptr.Uint(1), fleet.StartupItemChangesListOptions{ChangeType: fleet.StartupItemChangeAdded})
+	require.NoError(t, err)
+	require.Equal(t, ptr.Uint(1), gotFilter.TeamID)
+	require.True(t, gotFilter.IncludeObserver)
+
+	_, err = svc.ListStartupItemChanges(ctx, nil, fleet.StartupItemChangesListOptions{ChangeType: "renamed"})
+	var invalid *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &invalid)
+}
@@ -125,6 +125,7 @@ type searchTargetsResponse struct {
 	TargetsCount           uint         `json:"targets_count"`
 	TargetsOnline          uint         `json:"targets_online"`
 	TargetsOffline         uint         `json:"targets_offline"`
+	TargetsMissing         uint         `json:"targets_missing"`
 	TargetsMissingInAction uint         `json:"targets_missing_in_action"`
 	Err                    error        `json:"error,omitempty"`
 }
@@ -150,7 +151,7 @@ func searchTargetsEndpoint(ctx context.Context, request interface{}, svc fleet.S
 			hostSearchResult{
 				HostResponse{
 					Host:   host,
-					Status: host.Status(time.Now()),
+					Status: host.ComputedStatus,
 				},
 				host.Hostname,
 			},
@@ -187,6 +188,7 @@ func searchTargetsEndpoint(ctx context.Context, request interface{}, svc fleet.S
 		TargetsCount:           metrics.TotalHosts,
 		TargetsOnline:          metrics.OnlineHosts,
 		TargetsOffline:         metrics.OfflineHosts,
+		TargetsMissing:         metrics.MissingHosts,
 		TargetsMissingInAction: metrics.MissingInActionHosts,
 	}, nil
 }
@@ -218,6 +220,9 @@ func (svc *Service) SearchTargets(ctx context.Context, matchQuery string, queryI
 	if err != nil {
 		return nil, err
 	}
+	if err := svc.setHostsStatus(ctx, hosts...); err != nil {
+		return nil, err
+	}
 
 	results.Hosts = append(results.Hosts, hosts...)
 
@@ -257,7 +262,12 @@ func (svc *Service) CountHostsInTargets(ctx context.Context, queryID *uint, targ
 
 	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: includeObserver}
 
-	metrics, err := svc.ds.CountHostsInTargets(ctx, filter, targets, svc.clock.Now())
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := svc.ds.CountHostsInTargets(ctx, filter, targets, svc.clock.Now(), appConfig.HostSettings)
 	if err != nil {
 		return nil, err
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 
 	"github.com/fleetdm/fleet/v4/server/authz"
@@ -19,13 +20,17 @@ import (
 /////////////////////////////////////////////////////////////////////////////////
 
 type teamPolicyRequest struct {
-	TeamID      uint   `url:"team_id"`
-	QueryID     *uint  `json:"query_id"`
-	Query       string `json:"query"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Resolution  string `json:"resolution"`
-	Platform    string `json:"platform"`
+	TeamID            uint   `url:"team_id"`
+	QueryID           *uint  `json:"query_id"`
+	Query             string `json:"query"`
+	Name              string `json:"name"`
+	Description       string `json:"description"`
+	Resolution        string `json:"resolution"`
+	Platform          string `json:"platform"`
+	Critical          bool   `json:"critical"`
+	EvaluationWindow  string `json:"evaluation_window"`
+	RemediationScript string `json:"remediation_script"`
+	AutoRemediate     bool   `json:"auto_remediate"`
 }
 
 type teamPolicyResponse struct {
@@ -38,12 +43,16 @@ func (r teamPolicyResponse) error() error { return r.Err }
 func teamPolicyEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*teamPolicyRequest)
 	resp, err := svc.NewTeamPolicy(ctx, req.TeamID, fleet.PolicyPayload{
-		QueryID:     req.QueryID,
-		Name:        req.Name,
-		Query:       req.Query,
-		Description: req.Description,
-		Resolution:  req.Resolution,
-		Platform:    req.Platform,
+		QueryID:           req.QueryID,
+		Name:              req.Name,
+		Query:             req.Query,
+		Description:       req.Description,
+		Resolution:        req.Resolution,
+		Platform:          req.Platform,
+		Critical:          req.Critical,
+		EvaluationWindow:  req.EvaluationWindow,
+		RemediationScript: req.RemediationScript,
+		AutoRemediate:     req.AutoRemediate,
 	})
 	if err != nil {
 		return teamPolicyResponse{Err: err}, nil
@@ -92,7 +101,8 @@ func (svc Service) NewTeamPolicy(ctx context.Context, teamID uint, p fleet.Polic
 /////////////////////////////////////////////////////////////////////////////////
 
 type listTeamPoliciesRequest struct {
-	TeamID uint `url:"team_id"`
+	TeamID      uint              `url:"team_id"`
+	ListOptions fleet.ListOptions `url:"list_options"`
 }
 
 type listTeamPoliciesResponse struct {
@@ -104,14 +114,14 @@ func (r listTeamPoliciesResponse) error() error { return r.Err }
 
 func listTeamPoliciesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*listTeamPoliciesRequest)
-	resp, err := svc.ListTeamPolicies(ctx, req.TeamID)
+	resp, err := svc.ListTeamPolicies(ctx, req.TeamID, req.ListOptions)
 	if err != nil {
 		return listTeamPoliciesResponse{Err: err}, nil
 	}
 	return listTeamPoliciesResponse{Policies: resp}, nil
 }
 
-func (svc Service) ListTeamPolicies(ctx context.Context, teamID uint) ([]*fleet.Policy, error) {
+func (svc Service) ListTeamPolicies(ctx context.Context, teamID uint, opts fleet.ListOptions) ([]*fleet.Policy, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.Policy{
 		PolicyData: fleet.PolicyData{
 			TeamID: ptr.Uint(teamID),
@@ -124,7 +134,7 @@ func (svc Service) ListTeamPolicies(ctx context.Context, teamID uint) ([]*fleet.
 		return nil, ctxerr.Wrapf(ctx, err, "loading team %d", teamID)
 	}
 
-	return svc.ds.ListTeamPolicies(ctx, teamID)
+	return svc.ds.ListTeamPolicies(ctx, teamID, opts)
 }
 
 /////////////////////////////////////////////////////////////////////////////////
@@ -320,6 +330,23 @@ func (svc *Service) modifyPolicy(ctx context.Context, teamID *uint, id uint, p f
 	if p.Platform != nil {
 		policy.Platform = *p.Platform
 	}
+	if p.Critical != nil {
+		policy.Critical = *p.Critical
+	}
+	if p.EvaluationWindow != nil {
+		policy.EvaluationWindow = *p.EvaluationWindow
+	}
+	if p.RemediationScript != nil {
+		policy.RemediationScript = p.RemediationScript
+	}
+	if p.AutoRemediate != nil {
+		policy.AutoRemediate = *p.AutoRemediate
+	}
+	if err := policy.VerifyRemediation(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &badRequestError{
+			message: fmt.Sprintf("policy payload verification: %s", err),
+		})
+	}
 	logging.WithExtras(ctx, "name", policy.Name, "sql", policy.Query)
 
 	err = svc.ds.SavePolicy(ctx, policy)
@@ -339,3 +366,113 @@ func (svc *Service) modifyPolicy(ctx context.Context, teamID *uint, id uint, p f
 
 	return policy, nil
 }
+
+/////////////////////////////////////////////////////////////////////////////////
+// Exemptions
+/////////////////////////////////////////////////////////////////////////////////
+
+type teamPolicyExemptionsRequest struct {
+	TeamID uint `url:"team_id"`
+}
+
+type teamPolicyExemptionsResponse struct {
+	PolicyIDs []uint `json:"policy_ids"`
+	Err       error  `json:"error,omitempty"`
+}
+
+func (r teamPolicyExemptionsResponse) error() error { return r.Err }
+
+func teamPolicyExemptionsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*teamPolicyExemptionsRequest)
+	policyIDs, err := svc.TeamPolicyExemptions(ctx, req.TeamID)
+	if err != nil {
+		return teamPolicyExemptionsResponse{Err: err}, nil
+	}
+	if policyIDs == nil {
+		policyIDs = []uint{}
+	}
+	return teamPolicyExemptionsResponse{PolicyIDs: policyIDs}, nil
+}
+
+func (svc Service) TeamPolicyExemptions(ctx context.Context, teamID uint) ([]uint, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{
+		PolicyData: fleet.PolicyData{
+			TeamID: ptr.Uint(teamID),
+		},
+	}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if _, err := svc.ds.Team(ctx, teamID); err != nil {
+		return nil, ctxerr.Wrapf(ctx, err, "loading team %d", teamID)
+	}
+
+	return svc.ds.TeamPolicyExemptions(ctx, teamID)
+}
+
+type setTeamPolicyExemptionsRequest struct {
+	TeamID    uint   `url:"team_id"`
+	PolicyIDs []uint `json:"policy_ids"`
+}
+
+func setTeamPolicyExemptionsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*setTeamPolicyExemptionsRequest)
+	policyIDs, err := svc.SetTeamPolicyExemptions(ctx, req.TeamID, req.PolicyIDs)
+	if err != nil {
+		return teamPolicyExemptionsResponse{Err: err}, nil
+	}
+	return teamPolicyExemptionsResponse{PolicyIDs: policyIDs}, nil
+}
+
+func (svc Service) SetTeamPolicyExemptions(ctx context.Context, teamID uint, policyIDs []uint) ([]uint, error) {
+	// The global policies apply to all the hosts, exempting a team from them
+	// requires to be allowed to write the global policies.
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	team, err := svc.ds.Team(ctx, teamID)
+	if err != nil {
+		return nil, ctxerr.Wrapf(ctx, err, "loading team %d", teamID)
+	}
+
+	seen := make(map[uint]bool, len(policyIDs))
+	ids := make([]uint, 0, len(policyIDs))
+	for _, id := range policyIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	if len(ids) > 0 {
+		policiesByID, err := svc.ds.PoliciesByID(ctx, ids)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "getting policies by ID")
+		}
+		for _, id := range ids {
+			policy, ok := policiesByID[id]
+			if !ok {
+				return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("policy_ids", fmt.Sprintf("policy %d does not exist", id)))
+			}
+			if policy.TeamID != nil {
+				return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("policy_ids", fmt.Sprintf("policy %d is not a global policy", id)))
+			}
+		}
+	}
+
+	if err := svc.ds.SetTeamPolicyExemptions(ctx, teamID, ids); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "set team policy exemptions")
+	}
+
+	if err := svc.ds.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeEditedTeamPolicyExemptions,
+		&map[string]interface{}{"team_id": team.ID, "team_name": team.Name, "policy_ids": ids},
+	); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
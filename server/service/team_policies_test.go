@@ -24,7 +24,7 @@ func TestTeamPoliciesAuth(t *testing.T) {
 			},
 		}, nil
 	}
-	ds.ListTeamPoliciesFunc = func(ctx context.Context, teamID uint) ([]*fleet.Policy, error) {
+	ds.ListTeamPoliciesFunc = func(ctx context.Context, teamID uint, opts fleet.ListOptions) ([]*fleet.Policy, error) {
 		return nil, nil
 	}
 	ds.PoliciesByIDFunc = func(ctx context.Context, ids []uint) (map[uint]*fleet.Policy, error) {
@@ -149,7 +149,7 @@ func TestTeamPoliciesAuth(t *testing.T) {
 			})
 			checkAuthErr(t, tt.shouldFailWrite, err)
 
-			_, err = svc.ListTeamPolicies(ctx, 1)
+			_, err = svc.ListTeamPolicies(ctx, 1, fleet.ListOptions{})
 			checkAuthErr(t, tt.shouldFailRead, err)
 
 			_, err = svc.GetTeamPolicyByIDQueries(ctx, 1, 1)
@@ -173,6 +173,107 @@ func TestTeamPoliciesAuth(t *testing.T) {
 	}
 }
 
+func TestTeamPolicyExemptions(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team1"}, nil
+	}
+	ds.TeamPolicyExemptionsFunc = func(ctx context.Context, teamID uint) ([]uint, error) {
+		return []uint{1}, nil
+	}
+	ds.PoliciesByIDFunc = func(ctx context.Context, ids []uint) (map[uint]*fleet.Policy, error) {
+		policies := map[uint]*fleet.Policy{
+			1: {PolicyData: fleet.PolicyData{ID: 1}},
+			2: {PolicyData: fleet.PolicyData{ID: 2}},
+			3: {PolicyData: fleet.PolicyData{ID: 3, TeamID: ptr.Uint(1)}},
+		}
+		res := make(map[uint]*fleet.Policy)
+		for _, id := range ids {
+			if p, ok := policies[id]; ok {
+				res[id] = p
+			}
+		}
+		return res, nil
+	}
+	var exempted []uint
+	ds.SetTeamPolicyExemptionsFunc = func(ctx context.Context, teamID uint, policyIDs []uint) error {
+		exempted = policyIDs
+		return nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name            string
+		user            *fleet.User
+		shouldFailWrite bool
+		shouldFailRead  bool
+	}{
+		{
+			"global admin",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			false,
+			false,
+		},
+		{
+			"global maintainer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			false,
+			false,
+		},
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			true,
+			false,
+		},
+		{
+			"team maintainer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}},
+			true,
+			false,
+		},
+		{
+			"team maintainer, DOES NOT belong to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer}}},
+			true,
+			true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			_, err := svc.TeamPolicyExemptions(ctx, 1)
+			checkAuthErr(t, tt.shouldFailRead, err)
+
+			_, err = svc.SetTeamPolicyExemptions(ctx, 1, []uint{1})
+			checkAuthErr(t, tt.shouldFailWrite, err)
+		})
+	}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	ids, err := svc.SetTeamPolicyExemptions(ctx, 1, []uint{2, 1, 2})
+	require.NoError(t, err)
+	require.Equal(t, []uint{1, 2}, ids)
+	require.Equal(t, []uint{1, 2}, exempted)
+
+	// only the existing global policies can be exempted
+	var iae *fleet.InvalidArgumentError
+	_, err = svc.SetTeamPolicyExemptions(ctx, 1, []uint{3})
+	require.ErrorAs(t, err, &iae)
+	_, err = svc.SetTeamPolicyExemptions(ctx, 1, []uint{4})
+	require.ErrorAs(t, err, &iae)
+
+	_, err = svc.SetTeamPolicyExemptions(ctx, 1, nil)
+	require.NoError(t, err)
+	require.Empty(t, exempted)
+}
+
 func checkAuthErr(t *testing.T, shouldFail bool, err error) {
 	if shouldFail {
 		require.Error(t, err)
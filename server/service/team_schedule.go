@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 
+	"github.com/fleetdm/fleet/v4/server/authz"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"gopkg.in/guregu/null.v3"
@@ -71,6 +72,13 @@ func uintValueOrZero(v *uint) uint {
 	return *v
 }
 
+func uintSliceValueOrNil(v *[]uint) []uint {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
 func nullIntToPtrUint(v *null.Int) *uint {
 	if v == nil {
 		return nil
@@ -81,13 +89,15 @@ func nullIntToPtrUint(v *null.Int) *uint {
 func teamScheduleQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*teamScheduleQueryRequest)
 	resp, err := svc.TeamScheduleQuery(ctx, req.TeamID, &fleet.ScheduledQuery{
-		QueryID:  uintValueOrZero(req.QueryID),
-		Interval: uintValueOrZero(req.Interval),
-		Snapshot: req.Snapshot,
-		Removed:  req.Removed,
-		Platform: req.Platform,
-		Version:  req.Version,
-		Shard:    nullIntToPtrUint(req.Shard),
+		QueryID:     uintValueOrZero(req.QueryID),
+		Interval:    uintValueOrZero(req.Interval),
+		Snapshot:    req.Snapshot,
+		Removed:     req.Removed,
+		Platform:    req.Platform,
+		Version:     req.Version,
+		Shard:       nullIntToPtrUint(req.Shard),
+		DiscardData: req.DiscardData != nil && *req.DiscardData,
+		LabelIDs:    uintSliceValueOrNil(req.LabelIDs),
 	})
 	if err != nil {
 		return teamScheduleQueryResponse{Err: err}, nil
@@ -109,7 +119,7 @@ func (svc Service) TeamScheduleQuery(ctx context.Context, teamID uint, q *fleet.
 	}
 	q.PackID = gp.ID
 
-	return svc.unauthorizedScheduleQuery(ctx, q)
+	return svc.unauthorizedScheduleQuery(ctx, &teamID, q)
 }
 
 /////////////////////////////////////////////////////////////////////////////////
@@ -151,7 +161,7 @@ func (svc Service) ModifyTeamScheduledQueries(ctx context.Context, teamID uint,
 
 	query.PackID = ptr.Uint(gp.ID)
 
-	return svc.unauthorizedModifyScheduledQuery(ctx, scheduledQueryID, query)
+	return svc.unauthorizedModifyScheduledQuery(ctx, &teamID, scheduledQueryID, query)
 }
 
 /////////////////////////////////////////////////////////////////////////////////
@@ -183,5 +193,9 @@ func (svc Service) DeleteTeamScheduledQueries(ctx context.Context, teamID uint,
 	if err := svc.authz.Authorize(ctx, &fleet.Pack{TeamIDs: []uint{teamID}}, fleet.ActionWrite); err != nil {
 		return err
 	}
-	return svc.ds.DeleteScheduledQuery(ctx, scheduledQueryID)
+	if err := svc.ds.DeleteScheduledQuery(ctx, scheduledQueryID); err != nil {
+		return err
+	}
+	svc.clientConfigCache.invalidate()
+	return svc.ds.RecordAgentConfigVersion(ctx, authz.UserFromContext(ctx))
 }
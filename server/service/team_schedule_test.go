@@ -14,6 +14,13 @@ func TestTeamScheduleAuth(t *testing.T) {
 	ds := new(mock.Store)
 	svc := newTestService(t, ds, nil, nil)
 
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
 	ds.EnsureTeamPackFunc = func(ctx context.Context, teamID uint) (*fleet.Pack, error) {
 		return &fleet.Pack{ID: 999}, nil
 	}
@@ -35,6 +42,9 @@ func TestTeamScheduleAuth(t *testing.T) {
 	ds.DeleteScheduledQueryFunc = func(ctx context.Context, id uint) error {
 		return nil
 	}
+	ds.RecordAgentConfigVersionFunc = func(ctx context.Context, user *fleet.User) error {
+		return nil
+	}
 
 	testCases := []struct {
 		name            string
@@ -37,6 +37,9 @@ func newTestServiceWithConfig(t *testing.T, ds fleet.Datastore, fleetConfig conf
 		kitlog.NewNopLogger(),
 		fleetConfig.Filesystem.EnableLogRotation,
 		fleetConfig.Filesystem.EnableLogCompression,
+		fleetConfig.Filesystem.MaxSize,
+		fleetConfig.Filesystem.MaxAge,
+		fleetConfig.Filesystem.MaxBackups,
 	)
 
 	require.NoError(t, err)
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io"
@@ -192,7 +193,7 @@ func hostListOptionsFromRequest(r *http.Request) (fleet.HostListOptions, error)
 
 	status := r.URL.Query().Get("status")
 	switch fleet.HostStatus(status) {
-	case fleet.StatusNew, fleet.StatusOnline, fleet.StatusOffline, fleet.StatusMIA:
+	case fleet.StatusNew, fleet.StatusOnline, fleet.StatusOffline, fleet.StatusMIA, fleet.StatusMissing:
 		hopt.StatusFilter = fleet.HostStatus(status)
 	case "":
 		// No error when unset
@@ -251,6 +252,70 @@ func hostListOptionsFromRequest(r *http.Request) (fleet.HostListOptions, error)
 		hopt.SoftwareIDFilter = &sid
 	}
 
+	labelIDs := r.URL.Query().Get("label_ids")
+	if labelIDs != "" {
+		for _, v := range strings.Split(labelIDs, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(v), 10, 32)
+			if err != nil {
+				return hopt, ctxerr.New(r.Context(), "non-int label_ids value")
+			}
+			hopt.LabelIDsFilter = append(hopt.LabelIDsFilter, uint(id))
+		}
+	}
+
+	agentComponent := r.URL.Query().Get("agent_component")
+	if agentComponent != "" && !fleet.IsValidAgentComponent(agentComponent) {
+		return hopt, ctxerr.Errorf(r.Context(), "invalid agent_component %s", agentComponent)
+	}
+	agentVersion := r.URL.Query().Get("agent_version")
+	if agentVersion != "" && agentComponent == "" {
+		return hopt, ctxerr.New(r.Context(), "agent_version requires agent_component")
+	}
+	hopt.AgentComponentFilter = agentComponent
+	hopt.AgentVersionFilter = agentVersion
+
+	lowDiskSpace := r.URL.Query().Get("low_disk_space")
+	if lowDiskSpace != "" {
+		v, err := strconv.Atoi(lowDiskSpace)
+		if err != nil || v <= 0 {
+			return hopt, ctxerr.New(r.Context(), "invalid low_disk_space value, must be a positive integer")
+		}
+		hopt.LowDiskSpaceFilter = &v
+	}
+
+	lowDiskSpacePercent := r.URL.Query().Get("low_disk_space_percent")
+	if lowDiskSpacePercent != "" {
+		v, err := strconv.Atoi(lowDiskSpacePercent)
+		if err != nil || v <= 0 || v > 100 {
+			return hopt, ctxerr.New(r.Context(), "invalid low_disk_space_percent value, must be an integer between 1 and 100")
+		}
+		hopt.LowDiskSpacePercentFilter = &v
+	}
+
+	diskEncryptionEnabled := r.URL.Query().Get("disk_encryption_enabled")
+	if diskEncryptionEnabled != "" {
+		v, err := strconv.ParseBool(diskEncryptionEnabled)
+		if err != nil {
+			return hopt, ctxerr.New(r.Context(), "invalid disk_encryption_enabled value, must be true or false")
+		}
+		hopt.DiskEncryptionEnabledFilter = &v
+	}
+
+	mdmEnrollmentStatus := r.URL.Query().Get("mdm_enrollment_status")
+	if mdmEnrollmentStatus != "" && !fleet.IsValidMDMEnrollmentStatus(mdmEnrollmentStatus) {
+		return hopt, ctxerr.Errorf(r.Context(), "invalid mdm_enrollment_status %s", mdmEnrollmentStatus)
+	}
+	hopt.MDMEnrollmentStatusFilter = mdmEnrollmentStatus
+	hopt.MunkiVersionFilter = r.URL.Query().Get("munki_version")
+
+	certificateSHA1 := strings.ToLower(r.URL.Query().Get("certificate_sha1"))
+	if certificateSHA1 != "" {
+		if _, err := hex.DecodeString(certificateSHA1); err != nil || len(certificateSHA1) != 40 {
+			return hopt, ctxerr.New(r.Context(), "invalid certificate_sha1 value, must be a hex-encoded SHA1 fingerprint")
+		}
+		hopt.CertificateSHA1Filter = certificateSHA1
+	}
+
 	disableFailingPolicies := r.URL.Query().Get("disable_failing_policies")
 	if disableFailingPolicies != "" {
 		boolVal, err := strconv.ParseBool(disableFailingPolicies)
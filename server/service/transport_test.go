@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -111,3 +112,193 @@ func TestListOptionsFromRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestHostListOptionsFromRequestLabelIDs(t *testing.T) {
+	var hostListOptionsTests = []struct {
+		url       string
+		labelIDs  []uint
+		shouldErr bool
+	}{
+		{url: "/foo", labelIDs: nil},
+		{url: "/foo?label_ids=1", labelIDs: []uint{1}},
+		{url: "/foo?label_ids=1,2,%203", labelIDs: []uint{1, 2, 3}},
+		{url: "/foo?label_ids=1,a", shouldErr: true},
+		{url: "/foo?label_ids=-1", shouldErr: true},
+		{url: "/foo?label_ids=1,,2", shouldErr: true},
+	}
+
+	for _, tt := range hostListOptionsTests {
+		t.Run(tt.url, func(t *testing.T) {
+			url, _ := url.Parse(tt.url)
+			req := &http.Request{URL: url}
+			opt, err := hostListOptionsFromRequest(req)
+
+			if tt.shouldErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.labelIDs, opt.LabelIDsFilter)
+		})
+	}
+}
+
+func TestHostListOptionsFromRequestAgentVersion(t *testing.T) {
+	var hostListOptionsTests = []struct {
+		url       string
+		component string
+		version   string
+		shouldErr bool
+	}{
+		{url: "/foo"},
+		{url: "/foo?agent_component=orbit", component: "orbit"},
+		{url: "/foo?agent_component=fleet_desktop&agent_version=0.0.3", component: "fleet_desktop", version: "0.0.3"},
+		{url: "/foo?agent_component=launcher&agent_version=", component: "launcher"},
+		{url: "/foo?agent_component=osquery", shouldErr: true},
+		{url: "/foo?agent_version=0.0.3", shouldErr: true},
+	}
+
+	for _, tt := range hostListOptionsTests {
+		t.Run(tt.url, func(t *testing.T) {
+			url, _ := url.Parse(tt.url)
+			req := &http.Request{URL: url}
+			opt, err := hostListOptionsFromRequest(req)
+
+			if tt.shouldErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.component, opt.AgentComponentFilter)
+			assert.Equal(t, tt.version, opt.AgentVersionFilter)
+		})
+	}
+}
+
+func TestHostListOptionsFromRequestDiskSpace(t *testing.T) {
+	var hostListOptionsTests = []struct {
+		url       string
+		gigs      *int
+		percent   *int
+		shouldErr bool
+	}{
+		{url: "/foo"},
+		{url: "/foo?low_disk_space=32", gigs: ptr.Int(32)},
+		{url: "/foo?low_disk_space_percent=10", percent: ptr.Int(10)},
+		{url: "/foo?low_disk_space=32&low_disk_space_percent=10", gigs: ptr.Int(32), percent: ptr.Int(10)},
+		{url: "/foo?low_disk_space=0", shouldErr: true},
+		{url: "/foo?low_disk_space=abc", shouldErr: true},
+		{url: "/foo?low_disk_space_percent=101", shouldErr: true},
+		{url: "/foo?low_disk_space_percent=-1", shouldErr: true},
+	}
+
+	for _, tt := range hostListOptionsTests {
+		t.Run(tt.url, func(t *testing.T) {
+			url, _ := url.Parse(tt.url)
+			req := &http.Request{URL: url}
+			opt, err := hostListOptionsFromRequest(req)
+
+			if tt.shouldErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.gigs, opt.LowDiskSpaceFilter)
+			assert.Equal(t, tt.percent, opt.LowDiskSpacePercentFilter)
+		})
+	}
+}
+
+func TestHostListOptionsFromRequestDiskEncryption(t *testing.T) {
+	var hostListOptionsTests = []struct {
+		url       string
+		enabled   *bool
+		shouldErr bool
+	}{
+		{url: "/foo"},
+		{url: "/foo?disk_encryption_enabled=true", enabled: ptr.Bool(true)},
+		{url: "/foo?disk_encryption_enabled=false", enabled: ptr.Bool(false)},
+		{url: "/foo?disk_encryption_enabled=maybe", shouldErr: true},
+	}
+
+	for _, tt := range hostListOptionsTests {
+		t.Run(tt.url, func(t *testing.T) {
+			url, _ := url.Parse(tt.url)
+			req := &http.Request{URL: url}
+			opt, err := hostListOptionsFromRequest(req)
+
+			if tt.shouldErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.enabled, opt.DiskEncryptionEnabledFilter)
+		})
+	}
+}
+
+func TestHostListOptionsFromRequestMacadmins(t *testing.T) {
+	var hostListOptionsTests = []struct {
+		url       string
+		status    string
+		munki     string
+		shouldErr bool
+	}{
+		{url: "/foo"},
+		{url: "/foo?mdm_enrollment_status=manual", status: "manual"},
+		{url: "/foo?mdm_enrollment_status=unenrolled&munki_version=5.6.3", status: "unenrolled", munki: "5.6.3"},
+		{url: "/foo?munki_version=5.6.3", munki: "5.6.3"},
+		{url: "/foo?mdm_enrollment_status=pending", shouldErr: true},
+	}
+
+	for _, tt := range hostListOptionsTests {
+		t.Run(tt.url, func(t *testing.T) {
+			url, _ := url.Parse(tt.url)
+			req := &http.Request{URL: url}
+			opt, err := hostListOptionsFromRequest(req)
+
+			if tt.shouldErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.status, opt.MDMEnrollmentStatusFilter)
+			assert.Equal(t, tt.munki, opt.MunkiVersionFilter)
+		})
+	}
+}
+
+func TestHostListOptionsFromRequestCertificate(t *testing.T) {
+	var hostListOptionsTests = []struct {
+		url       string
+		sha1      string
+		shouldErr bool
+	}{
+		{url: "/foo"},
+		{url: "/foo?certificate_sha1=d1eb23a46d17d68fd92564c2f1f1601764d8e349", sha1: "d1eb23a46d17d68fd92564c2f1f1601764d8e349"},
+		{url: "/foo?certificate_sha1=D1EB23A46D17D68FD92564C2F1F1601764D8E349", sha1: "d1eb23a46d17d68fd92564c2f1f1601764d8e349"},
+		{url: "/foo?certificate_sha1=d1eb23a46d17d68f", shouldErr: true},
+		{url: "/foo?certificate_sha1=z1eb23a46d17d68fd92564c2f1f1601764d8e349", shouldErr: true},
+	}
+
+	for _, tt := range hostListOptionsTests {
+		t.Run(tt.url, func(t *testing.T) {
+			url, _ := url.Parse(tt.url)
+			req := &http.Request{URL: url}
+			opt, err := hostListOptionsFromRequest(req)
+
+			if tt.shouldErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.sha1, opt.CertificateSHA1Filter)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Create YARA Signature Group
+////////////////////////////////////////////////////////////////////////////////
+
+type createYARASignatureGroupRequest struct {
+	TeamID *uint `json:"team_id"`
+	fleet.YARASignatureGroupPayload
+}
+
+type yaraSignatureGroupResponse struct {
+	Group *fleet.YARASignatureGroup `json:"yara_signature_group,omitempty"`
+	Err   error                     `json:"error,omitempty"`
+}
+
+func (r yaraSignatureGroupResponse) error() error { return r.Err }
+
+func createYARASignatureGroupEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*createYARASignatureGroupRequest)
+	group, err := svc.NewYARASignatureGroup(ctx, req.TeamID, req.YARASignatureGroupPayload)
+	if err != nil {
+		return yaraSignatureGroupResponse{Err: err}, nil
+	}
+	return yaraSignatureGroupResponse{Group: group}, nil
+}
+
+func (svc *Service) NewYARASignatureGroup(ctx context.Context, teamID *uint, p fleet.YARASignatureGroupPayload) (*fleet.YARASignatureGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.YARASignatureGroup{TeamID: teamID}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	invalid := &fleet.InvalidArgumentError{}
+	if p.Name == nil {
+		invalid.Append("name", "missing required argument")
+	}
+	if p.Signatures == nil {
+		invalid.Append("signatures", "missing required argument")
+	}
+	if invalid.HasErrors() {
+		return nil, ctxerr.Wrap(ctx, invalid)
+	}
+	if err := p.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &badRequestError{
+			message: fmt.Sprintf("yara signature group payload verification: %s", err),
+		})
+	}
+
+	if teamID != nil {
+		if _, err := svc.ds.Team(ctx, *teamID); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get team")
+		}
+	}
+
+	group := &fleet.YARASignatureGroup{TeamID: teamID, FilePaths: fleet.YARAStrings{}}
+	applyYARASignatureGroupPayload(group, p)
+	return svc.ds.NewYARASignatureGroup(ctx, group)
+}
+
+func applyYARASignatureGroupPayload(group *fleet.YARASignatureGroup, p fleet.YARASignatureGroupPayload) {
+	if p.Name != nil {
+		group.Name = *p.Name
+	}
+	if p.Signatures != nil {
+		group.Signatures = *p.Signatures
+	}
+	if p.FilePaths != nil {
+		group.FilePaths = *p.FilePaths
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List YARA Signature Groups
+////////////////////////////////////////////////////////////////////////////////
+
+type listYARASignatureGroupsRequest struct {
+	TeamID *uint `query:"team_id,optional"`
+}
+
+type listYARASignatureGroupsResponse struct {
+	Groups []*fleet.YARASignatureGroup `json:"yara_signature_groups"`
+	Err    error                       `json:"error,omitempty"`
+}
+
+func (r listYARASignatureGroupsResponse) error() error { return r.Err }
+
+func listYARASignatureGroupsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listYARASignatureGroupsRequest)
+	groups, err := svc.ListYARASignatureGroups(ctx, req.TeamID)
+	if err != nil {
+		return listYARASignatureGroupsResponse{Err: err}, nil
+	}
+	if groups == nil {
+		groups = []*fleet.YARASignatureGroup{}
+	}
+	return listYARASignatureGroupsResponse{Groups: groups}, nil
+}
+
+func (svc *Service) ListYARASignatureGroups(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.YARASignatureGroup{TeamID: teamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.ListYARASignatureGroups(ctx, teamID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Modify YARA Signature Group
+////////////////////////////////////////////////////////////////////////////////
+
+type modifyYARASignatureGroupRequest struct {
+	ID uint `json:"-" url:"id"`
+	fleet.YARASignatureGroupPayload
+}
+
+func modifyYARASignatureGroupEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*modifyYARASignatureGroupRequest)
+	group, err := svc.ModifyYARASignatureGroup(ctx, req.ID, req.YARASignatureGroupPayload)
+	if err != nil {
+		return yaraSignatureGroupResponse{Err: err}, nil
+	}
+	return yaraSignatureGroupResponse{Group: group}, nil
+}
+
+func (svc *Service) ModifyYARASignatureGroup(ctx context.Context, id uint, p fleet.YARASignatureGroupPayload) (*fleet.YARASignatureGroup, error) {
+	// First make sure the user can read YARA signature groups, the write access is
+	// checked once the team of the group is known.
+	if err := svc.authz.Authorize(ctx, &fleet.YARASignatureGroup{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	group, err := svc.ds.YARASignatureGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.authz.Authorize(ctx, group, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := p.Verify(); err != nil {
+		return nil, ctxerr.Wrap(ctx, &badRequestError{
+			message: fmt.Sprintf("yara signature group payload verification: %s", err),
+		})
+	}
+
+	applyYARASignatureGroupPayload(group, p)
+	if err := svc.ds.SaveYARASignatureGroup(ctx, group); err != nil {
+		return nil, err
+	}
+	return svc.ds.YARASignatureGroup(ctx, id)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Delete YARA Signature Group
+////////////////////////////////////////////////////////////////////////////////
+
+type deleteYARASignatureGroupRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteYARASignatureGroupResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteYARASignatureGroupResponse) error() error { return r.Err }
+
+func deleteYARASignatureGroupEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*deleteYARASignatureGroupRequest)
+	if err := svc.DeleteYARASignatureGroup(ctx, req.ID); err != nil {
+		return deleteYARASignatureGroupResponse{Err: err}, nil
+	}
+	return deleteYARASignatureGroupResponse{}, nil
+}
+
+func (svc *Service) DeleteYARASignatureGroup(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.YARASignatureGroup{}, fleet.ActionRead); err != nil {
+		return err
+	}
+
+	group, err := svc.ds.YARASignatureGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := svc.authz.Authorize(ctx, group, fleet.ActionWrite); err != nil {
+		return err
+	}
+	return svc.ds.DeleteYARASignatureGroup(ctx, id)
+}
+
+// yaraConfigForHost adds the YARA signature groups that apply to the host
+// (the global groups and the groups of the host's team) to the yara section
+// of the config: the signatures of each group are added to yara.signatures
+// and the group is added to the yara.file_paths entries of the FIM categories
+// it scans. Signature groups and file paths defined in the agent options take
+// precedence over the groups with the same name and the same category.
+func (svc *Service) yaraConfigForHost(ctx context.Context, host *fleet.Host, config map[string]interface{}) error {
+	groups, err := svc.ds.ListYARASignatureGroupsForHost(ctx, host.TeamID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list yara signature groups")
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	agentYARA, _ := config["yara"].(map[string]interface{})
+	agentSignatures, _ := agentYARA["signatures"].(map[string]interface{})
+	agentFilePaths, _ := agentYARA["file_paths"].(map[string]interface{})
+
+	signatures := make(map[string]interface{})
+	filePaths := make(map[string]interface{})
+	for _, group := range groups {
+		if _, ok := agentSignatures[group.Name]; ok {
+			continue
+		}
+		signatures[group.Name] = group.Signatures
+		for _, category := range group.FilePaths {
+			names, _ := filePaths[category].([]string)
+			filePaths[category] = append(names, group.Name)
+		}
+	}
+
+	for name, sigs := range agentSignatures {
+		signatures[name] = sigs
+	}
+	for category, names := range agentFilePaths {
+		filePaths[category] = names
+	}
+
+	yara := make(map[string]interface{}, len(agentYARA)+2)
+	for k, v := range agentYARA {
+		yara[k] = v
+	}
+	yara["signatures"] = signatures
+	if len(filePaths) > 0 {
+		yara["file_paths"] = filePaths
+	}
+	config["yara"] = yara
+	return nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYARASignatureGroupsAuth(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid}, nil
+	}
+	ds.NewYARASignatureGroupFunc = func(ctx context.Context, group *fleet.YARASignatureGroup) (*fleet.YARASignatureGroup, error) {
+		return group, nil
+	}
+	ds.ListYARASignatureGroupsFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		return nil, nil
+	}
+	ds.YARASignatureGroupFunc = func(ctx context.Context, id uint) (*fleet.YARASignatureGroup, error) {
+		if id == 1 {
+			return &fleet.YARASignatureGroup{ID: id}, nil
+		}
+		return &fleet.YARASignatureGroup{ID: id, TeamID: ptr.Uint(1)}, nil
+	}
+	ds.SaveYARASignatureGroupFunc = func(ctx context.Context, group *fleet.YARASignatureGroup) error {
+		return nil
+	}
+	ds.DeleteYARASignatureGroupFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+
+	testCases := []struct {
+		name                  string
+		user                  *fleet.User
+		shouldFailGlobalWrite bool
+		shouldFailGlobalRead  bool
+		shouldFailTeamWrite   bool
+		shouldFailTeamRead    bool
+	}{
+		{
+			"global admin",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+			false, false, false, false,
+		},
+		{
+			"global maintainer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleMaintainer)},
+			false, false, false, false,
+		},
+		{
+			"global observer",
+			&fleet.User{GlobalRole: ptr.String(fleet.RoleObserver)},
+			true, false, true, false,
+		},
+		{
+			"team maintainer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}},
+			true, false, false, false,
+		},
+		{
+			"team observer, belongs to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}},
+			true, false, true, false,
+		},
+		{
+			"team admin, DOES NOT belong to team",
+			&fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}},
+			true, false, true, true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			payload := fleet.YARASignatureGroupPayload{
+				Name:       ptr.String("malware"),
+				Signatures: &fleet.YARAStrings{"/etc/osquery/yara/malware.sig"},
+			}
+
+			_, err := svc.NewYARASignatureGroup(ctx, nil, payload)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			_, err = svc.NewYARASignatureGroup(ctx, ptr.Uint(1), payload)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			_, err = svc.ListYARASignatureGroups(ctx, nil)
+			checkAuthErr(t, tt.shouldFailGlobalRead, err)
+			_, err = svc.ListYARASignatureGroups(ctx, ptr.Uint(1))
+			checkAuthErr(t, tt.shouldFailTeamRead, err)
+
+			// group 1 is global, group 2 belongs to team 1
+			_, err = svc.ModifyYARASignatureGroup(ctx, 1, fleet.YARASignatureGroupPayload{FilePaths: &fleet.YARAStrings{"homes"}})
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			_, err = svc.ModifyYARASignatureGroup(ctx, 2, fleet.YARASignatureGroupPayload{FilePaths: &fleet.YARAStrings{"homes"}})
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+
+			err = svc.DeleteYARASignatureGroup(ctx, 1)
+			checkAuthErr(t, tt.shouldFailGlobalWrite, err)
+			err = svc.DeleteYARASignatureGroup(ctx, 2)
+			checkAuthErr(t, tt.shouldFailTeamWrite, err)
+		})
+	}
+}
+
+func TestNewYARASignatureGroupValidation(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	_, err := svc.NewYARASignatureGroup(ctx, nil, fleet.YARASignatureGroupPayload{Name: ptr.String("malware")})
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+
+	valid := func() fleet.YARASignatureGroupPayload {
+		return fleet.YARASignatureGroupPayload{
+			Name:       ptr.String("malware"),
+			Signatures: &fleet.YARAStrings{"/etc/osquery/yara/malware.sig", `C:\yara\malware.sig`},
+			FilePaths:  &fleet.YARAStrings{"homes"},
+		}
+	}
+	cases := []struct {
+		modify func(p *fleet.YARASignatureGroupPayload)
+		errMsg string
+	}{
+		{func(p *fleet.YARASignatureGroupPayload) { p.Name = ptr.String("") }, "name cannot be empty"},
+		{func(p *fleet.YARASignatureGroupPayload) { p.Name = ptr.String("malware ") }, "leading or trailing spaces"},
+		{func(p *fleet.YARASignatureGroupPayload) { p.Signatures = &fleet.YARAStrings{} }, "at least one signature"},
+		{func(p *fleet.YARASignatureGroupPayload) { p.Signatures = &fleet.YARAStrings{"malware.sig"} }, "must be absolute paths"},
+		{func(p *fleet.YARASignatureGroupPayload) { p.FilePaths = &fleet.YARAStrings{" "} }, "file paths cannot be empty"},
+	}
+	for _, c := range cases {
+		p := valid()
+		c.modify(&p)
+		_, err := svc.NewYARASignatureGroup(ctx, nil, p)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), c.errMsg)
+	}
+}
+
+func TestGetClientConfigYARASignatureGroups(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(t, ds, nil, nil)
+
+	ds.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+		return []*fleet.Pack{}, nil
+	}
+	ds.HostAgentOptionsOverrideFunc = func(ctx context.Context, hostID uint, now time.Time) (*fleet.HostAgentOptionsOverride, error) {
+		return nil, notFoundError{}
+	}
+	ds.ActiveAgentOptionsRolloutFunc = func(ctx context.Context) (*fleet.AgentOptionsRollout, error) {
+		return nil, notFoundError{}
+	}
+	ds.ListATCTablesFunc = func(ctx context.Context) ([]*fleet.ATCTable, error) {
+		return nil, nil
+	}
+	ds.ListFIMCategoriesForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.FIMCategory, error) {
+		return nil, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{AgentOptions: ptr.RawMessage(json.RawMessage(`{"config":{
+			"yara":{
+				"signatures":{"agent":["/agent.sig"],"overridden":["/agent-overridden.sig"]},
+				"file_paths":{"etc":["agent"]},
+				"signature_urls":["https://example.com/.*\\.sig"]
+			}
+		}}`))}, nil
+	}
+	ds.ListYARASignatureGroupsForHostFunc = func(ctx context.Context, teamID *uint) ([]*fleet.YARASignatureGroup, error) {
+		groups := []*fleet.YARASignatureGroup{
+			{Name: "malware", Signatures: fleet.YARAStrings{"/malware.sig"}, FilePaths: fleet.YARAStrings{"homes", "etc"}},
+			{Name: "overridden", Signatures: fleet.YARAStrings{"/overridden.sig"}, FilePaths: fleet.YARAStrings{"homes"}},
+		}
+		if teamID != nil {
+			groups = append(groups, &fleet.YARASignatureGroup{Name: "team", TeamID: teamID, Signatures: fleet.YARAStrings{"/team.sig"}, FilePaths: fleet.YARAStrings{"homes"}})
+		}
+		return groups, nil
+	}
+	ds.TeamAgentOptionsFunc = func(ctx context.Context, teamID uint) (*json.RawMessage, error) {
+		return nil, nil
+	}
+
+	getYARA := func(teamID *uint) string {
+		ctx := hostctx.NewContext(context.Background(), &fleet.Host{ID: 1, TeamID: teamID, Platform: "ubuntu"})
+		conf, err := svc.GetClientConfig(ctx)
+		require.NoError(t, err)
+		b, err := json.Marshal(conf["yara"])
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	assert.JSONEq(t, `{
+		"signatures":{"agent":["/agent.sig"],"overridden":["/agent-overridden.sig"],"malware":["/malware.sig"],"team":["/team.sig"]},
+		"file_paths":{"etc":["agent"],"homes":["malware","team"]},
+		"signature_urls":["https://example.com/.*\\.sig"]
+	}`, getYARA(ptr.Uint(1)))
+
+	assert.JSONEq(t, `{
+		"signatures":{"agent":["/agent.sig"],"overridden":["/agent-overridden.sig"],"malware":["/malware.sig"]},
+		"file_paths":{"etc":["agent"],"homes":["malware"]},
+		"signature_urls":["https://example.com/.*\\.sig"]
+	}`, getYARA(nil))
+}
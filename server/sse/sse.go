@@ -0,0 +1,190 @@
+// Package sse contains helpers to stream events to HTTP clients with
+// Server-Sent Events, an alternative to websockets that works with plain HTTP
+// clients and proxies.
+package sse
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// errType is the event type used for error messages.
+const errType = "error"
+
+// ErrStreamClosed is returned when writing to a stream closed by the client.
+var ErrStreamClosed = errors.New("stream closed")
+
+// Stream writes Server-Sent Events to a client. Each message is written as an
+// event of its type, with its data encoded as JSON:
+//
+//	event: result
+//	data: {"host":{...},"rows":[...]}
+type Stream struct {
+	mu    sync.Mutex
+	w     io.Writer
+	flush func() error
+	done  <-chan struct{}
+	conn  net.Conn
+}
+
+// NewStream starts a Server-Sent Events response on w. ctx is the context of
+// the request, it is used to detect that the client closed the stream.
+//
+// The connection is taken over from the HTTP server when possible, so that
+// the stream is not interrupted by the write timeout of the server. The stream
+// must be closed with Close.
+func NewStream(ctx context.Context, w http.ResponseWriter) (*Stream, error) {
+	return newStream(ctx, w, map[string]string{"Content-Type": "text/event-stream"})
+}
+
+// NewRawStream starts a streamed response of the provided content type on w,
+// for the long-lived responses that are not made of events, such as exports.
+// The response is written with Write instead of WriteJSONMessage, and header
+// holds the additional headers of the response.
+func NewRawStream(ctx context.Context, w http.ResponseWriter, contentType string, header map[string]string) (*Stream, error) {
+	h := map[string]string{"Content-Type": contentType}
+	for k, v := range header {
+		h[k] = v
+	}
+	return newStream(ctx, w, h)
+}
+
+func newStream(ctx context.Context, w http.ResponseWriter, header map[string]string) (*Stream, error) {
+	for k, v := range streamHeaders {
+		header[k] = v
+	}
+	if hj, ok := w.(http.Hijacker); ok {
+		return hijackStream(hj, header)
+	}
+
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming is not supported by the response writer")
+	}
+	for k, v := range header {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+	return &Stream{
+		w:     w,
+		flush: func() error { f.Flush(); return nil },
+		done:  ctx.Done(),
+	}, nil
+}
+
+var streamHeaders = map[string]string{
+	"Cache-Control": "no-cache",
+	// Disable the buffering of the responses by nginx.
+	"X-Accel-Buffering": "no",
+}
+
+func hijackStream(hj http.Hijacker, header map[string]string) (*Stream, error) {
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("hijack connection: %w", err)
+	}
+	// Clear the deadlines set by the HTTP server.
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("clear connection deadline: %w", err)
+	}
+
+	fmt.Fprint(rw, "HTTP/1.1 200 OK\r\n")
+	for k, v := range header {
+		fmt.Fprintf(rw, "%s: %s\r\n", k, v)
+	}
+	fmt.Fprint(rw, "Connection: close\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("write headers: %w", err)
+	}
+
+	// The client does not send anything else, the read fails once the
+	// connection is closed.
+	done := make(chan struct{})
+	go func(r *bufio.Reader) {
+		_, _ = io.Copy(ioutil.Discard, r)
+		close(done)
+	}(rw.Reader)
+
+	return &Stream{
+		w:     rw,
+		flush: rw.Flush,
+		done:  done,
+		conn:  conn,
+	}, nil
+}
+
+// WriteJSONMessage writes an event of type typ with data encoded as JSON,
+// returning ErrStreamClosed if the client closed the stream.
+func (s *Stream) WriteJSONMessage(typ string, data interface{}) error {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshalling JSON: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Closed() {
+		return ErrStreamClosed
+	}
+	// The JSON encoding escapes the newlines, so the data fits on a line.
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", typ, buf); err != nil {
+		return fmt.Errorf("sending: %w", err)
+	}
+	if err := s.flush(); err != nil {
+		return fmt.Errorf("sending: %w", err)
+	}
+	return nil
+}
+
+// Write writes p as is to a stream started with NewRawStream, returning
+// ErrStreamClosed if the client closed the stream.
+func (s *Stream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Closed() {
+		return 0, ErrStreamClosed
+	}
+	n, err := s.w.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("sending: %w", err)
+	}
+	if err := s.flush(); err != nil {
+		return n, fmt.Errorf("sending: %w", err)
+	}
+	return n, nil
+}
+
+// WriteJSONError writes an event of type "error" with data encoded as JSON.
+func (s *Stream) WriteJSONError(data interface{}) error {
+	return s.WriteJSONMessage(errType, data)
+}
+
+// Closed returns true if the client closed the stream.
+func (s *Stream) Closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close ends the stream.
+func (s *Stream) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
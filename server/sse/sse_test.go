@@ -0,0 +1,85 @@
+package sse
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvent reads an event from the stream.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	var event string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\n" {
+			return event
+		}
+		event += line
+	}
+}
+
+func TestStream(t *testing.T) {
+	streams := make(chan *Stream, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream, err := NewStream(r.Context(), w)
+		require.NoError(t, err)
+		require.NoError(t, stream.WriteJSONMessage("status", map[string]interface{}{"status": "pending"}))
+		require.NoError(t, stream.WriteJSONError("multi\nline"))
+		streams <- stream
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+	r := bufio.NewReader(resp.Body)
+	assert.Equal(t, "event: status\ndata: {\"status\":\"pending\"}\n", readEvent(t, r))
+	assert.Equal(t, "event: error\ndata: \"multi\\nline\"\n", readEvent(t, r))
+
+	// the stream outlives the request
+	stream := <-streams
+	require.NoError(t, stream.WriteJSONMessage("result", []int{1, 2}))
+	assert.Equal(t, "event: result\ndata: [1,2]\n", readEvent(t, r))
+	assert.False(t, stream.Closed())
+
+	// the stream is closed by the client
+	require.NoError(t, resp.Body.Close())
+	require.Eventually(t, stream.Closed, 5*time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, stream.WriteJSONMessage("result", nil), ErrStreamClosed)
+	require.NoError(t, stream.Close())
+}
+
+func TestRawStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stream, err := NewRawStream(r.Context(), w, "text/csv", map[string]string{"Content-Disposition": `attachment; filename="export.csv"`})
+		require.NoError(t, err)
+		defer stream.Close()
+		_, err = stream.Write([]byte("a,b\n"))
+		require.NoError(t, err)
+		_, err = stream.Write([]byte("1,2\n"))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="export.csv"`, resp.Header.Get("Content-Disposition"))
+	assert.Equal(t, "no-cache", resp.Header.Get("Cache-Control"))
+
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "a,b\n1,2\n", string(body))
+}
@@ -0,0 +1,156 @@
+// Package threatintel looks up the indicators collected from hosts (listening
+// ports, hashes of the executables started automatically) with an external
+// threat intel API and records the malicious ones as host threat findings.
+package threatintel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// defaultRateLimit is the number of lookups per minute used when the
+// configured rate limit is not positive.
+const defaultRateLimit = 60
+
+// ErrQuotaExceeded is returned when the threat intel API rejects a lookup
+// because the quota of the provider is exhausted.
+var ErrQuotaExceeded = errors.New("threat intel quota exceeded")
+
+// Client looks up indicators with a threat intel API.
+//
+// Each indicator is looked up with a POST request to the API URL with a JSON
+// body of the form {"type": "listening_port", "value": "tcp/4444"}. The API
+// must respond with a JSON body of the form {"malicious": true,
+// "description": "..."}.
+type Client struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewClient returns a client for the threat intel API at url. If apiKey is
+// not empty, it is sent as bearer token.
+func NewClient(url, apiKey string) *Client {
+	return &Client{
+		url:    url,
+		apiKey: apiKey,
+		client: fleethttp.NewClient(fleethttp.WithTimeout(30 * time.Second)),
+	}
+}
+
+// Lookup returns the verdict of the threat intel API for the indicator.
+func (c *Client) Lookup(ctx context.Context, indicator fleet.Indicator) (*fleet.ThreatIntelVerdict, error) {
+	body, err := json.Marshal(indicator)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to POST to %s: %w", c.url, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, ErrQuotaExceeded
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error posting to %s: %d. %s", c.url, resp.StatusCode, string(b))
+	}
+
+	var result struct {
+		Malicious   bool   `json:"malicious"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decoding response of %s: %w", c.url, err)
+	}
+	return &fleet.ThreatIntelVerdict{
+		Indicator:   indicator,
+		Malicious:   result.Malicious,
+		Description: result.Description,
+	}, nil
+}
+
+// Enrich looks up the host indicators that have no cached verdict or whose
+// verdict is older than the cache TTL, then updates the threat findings of
+// the hosts.
+//
+// Lookups are spaced to respect the configured rate limit, and at most the
+// number of lookups allowed by the rate limit during the configured
+// periodicity are made, so that a run completes before the next one starts.
+// The remaining indicators are looked up by the next runs.
+func Enrich(ctx context.Context, ds fleet.Datastore, client *Client, logger kitlog.Logger, cfg config.ThreatIntelConfig, now time.Time) error {
+	rateLimit := cfg.RateLimit
+	if rateLimit <= 0 {
+		rateLimit = defaultRateLimit
+	}
+	interval := time.Minute / time.Duration(rateLimit)
+	limit := int(cfg.Periodicity / interval)
+	if limit < 1 {
+		limit = 1
+	}
+
+	indicators, err := ds.ListIndicatorsToLookup(ctx, now.Add(-cfg.CacheTTL), limit)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list indicators to lookup")
+	}
+	level.Debug(logger).Log("indicators", len(indicators))
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	verdicts := make([]fleet.ThreatIntelVerdict, 0, len(indicators))
+lookups:
+	for i, indicator := range indicators {
+		if i > 0 {
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				break lookups
+			}
+		}
+
+		verdict, err := client.Lookup(ctx, indicator)
+		if err != nil {
+			if errors.Is(err, ErrQuotaExceeded) {
+				level.Info(logger).Log("msg", "threat intel quota exceeded, remaining indicators are looked up by the next run")
+				break
+			}
+			level.Error(logger).Log("msg", "looking up indicator", "type", indicator.Type, "value", indicator.Value, "err", err)
+			continue
+		}
+		verdict.CheckedAt = now
+		verdicts = append(verdicts, *verdict)
+	}
+
+	if err := ds.SaveThreatIntelVerdicts(ctx, verdicts); err != nil {
+		return ctxerr.Wrap(ctx, err, "save threat intel verdicts")
+	}
+	if err := ds.UpdateHostThreatFindings(ctx); err != nil {
+		return ctxerr.Wrap(ctx, err, "update host threat findings")
+	}
+	return nil
+}
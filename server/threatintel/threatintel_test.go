@@ -0,0 +1,120 @@
+package threatintel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var indicator fleet.Indicator
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&indicator))
+		switch indicator.Value {
+		case "tcp/4444":
+			w.Write([]byte(`{"malicious": true, "description": "metasploit default port"}`))
+		case "quota":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"malicious": false}`))
+		}
+	}))
+	defer ts.Close()
+
+	client := NewClient(ts.URL, "secret")
+	ctx := context.Background()
+
+	verdict, err := client.Lookup(ctx, fleet.Indicator{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/4444"})
+	require.NoError(t, err)
+	assert.True(t, verdict.Malicious)
+	assert.Equal(t, "metasploit default port", verdict.Description)
+	assert.Equal(t, "tcp/4444", verdict.Value)
+
+	verdict, err = client.Lookup(ctx, fleet.Indicator{Type: fleet.IndicatorTypeListeningPort, Value: "tcp/22"})
+	require.NoError(t, err)
+	assert.False(t, verdict.Malicious)
+
+	_, err = client.Lookup(ctx, fleet.Indicator{Type: fleet.IndicatorTypeListeningPort, Value: "quota"})
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	_, err = client.Lookup(ctx, fleet.Indicator{Type: fleet.IndicatorTypeListeningPort, Value: "error"})
+	require.Error(t, err)
+}
+
+func TestEnrich(t *testing.T) {
+	var lookups []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var indicator fleet.Indicator
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&indicator))
+		lookups = append(lookups, indicator.Value)
+		switch indicator.Value {
+		case "bad":
+			w.Write([]byte(`{"malicious": true, "description": "known malware"}`))
+		case "error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "quota":
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.Write([]byte(`{"malicious": false}`))
+		}
+	}))
+	defer ts.Close()
+
+	now := time.Now()
+	cfg := config.ThreatIntelConfig{
+		URL:         ts.URL,
+		Periodicity: time.Second,
+		RateLimit:   600,
+		CacheTTL:    24 * time.Hour,
+	}
+
+	ds := new(mock.Store)
+	ds.ListIndicatorsToLookupFunc = func(ctx context.Context, checkedBefore time.Time, limit int) ([]fleet.Indicator, error) {
+		assert.Equal(t, now.Add(-24*time.Hour), checkedBefore)
+		// 600 lookups per minute during a second
+		assert.Equal(t, 10, limit)
+		return []fleet.Indicator{
+			{Type: fleet.IndicatorTypeAutorunSHA256, Value: "bad"},
+			{Type: fleet.IndicatorTypeAutorunSHA256, Value: "error"},
+			{Type: fleet.IndicatorTypeAutorunSHA256, Value: "good"},
+			{Type: fleet.IndicatorTypeAutorunSHA256, Value: "quota"},
+			{Type: fleet.IndicatorTypeAutorunSHA256, Value: "never"},
+		}, nil
+	}
+	var saved []fleet.ThreatIntelVerdict
+	ds.SaveThreatIntelVerdictsFunc = func(ctx context.Context, verdicts []fleet.ThreatIntelVerdict) error {
+		saved = verdicts
+		return nil
+	}
+	ds.UpdateHostThreatFindingsFunc = func(ctx context.Context) error {
+		return nil
+	}
+
+	start := time.Now()
+	require.NoError(t, Enrich(context.Background(), ds, NewClient(ts.URL, ""), kitlog.NewNopLogger(), cfg, now))
+	// lookups are spaced by 100ms
+	assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+
+	// lookups stop when the quota is exceeded
+	assert.Equal(t, []string{"bad", "error", "good", "quota"}, lookups)
+	require.Len(t, saved, 2)
+	assert.Equal(t, "bad", saved[0].Value)
+	assert.True(t, saved[0].Malicious)
+	assert.Equal(t, now, saved[0].CheckedAt)
+	assert.Equal(t, "good", saved[1].Value)
+	assert.False(t, saved[1].Malicious)
+	assert.True(t, ds.UpdateHostThreatFindingsFuncInvoked)
+}
@@ -3,12 +3,16 @@ package server
 import (
 	"bytes"
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
@@ -30,6 +34,21 @@ func httpSuccessStatus(statusCode int) bool {
 }
 
 func PostJSONWithTimeout(ctx context.Context, url string, v interface{}) error {
+	return PostSignedJSONWithTimeout(ctx, url, "", v)
+}
+
+// Headers of the signed webhook requests.
+const (
+	WebhookTimestampHeader = "X-Fleet-Timestamp"
+	WebhookSignatureHeader = "X-Fleet-Signature"
+)
+
+// PostSignedJSONWithTimeout is like PostJSONWithTimeout, but the request is
+// signed if the secret is not empty, with the X-Fleet-Signature header of the
+// form "sha256=<hex>", where <hex> is the HMAC-SHA256 with the secret of the
+// X-Fleet-Timestamp header (the Unix time of the request), a dot and the body
+// of the request.
+func PostSignedJSONWithTimeout(ctx context.Context, url, secret string, v interface{}) error {
 	jsonBytes, err := json.Marshal(v)
 	if err != nil {
 		return err
@@ -42,6 +61,11 @@ func PostJSONWithTimeout(ctx context.Context, url string, v interface{}) error {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+WebhookSignature(secret, timestamp, jsonBytes))
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -56,3 +80,13 @@ func PostJSONWithTimeout(ctx context.Context, url string, v interface{}) error {
 
 	return nil
 }
+
+// WebhookSignature returns the hex encoded HMAC-SHA256 of the timestamp and
+// body of a signed webhook request.
+func WebhookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -134,6 +134,7 @@ func TriggerFailingPoliciesWebhook(
 				policy,
 				failingPoliciesSet,
 				settings.HostBatchSize,
+				settings.MaxHosts,
 				serverURL,
 				webhookURL,
 				now,
@@ -147,6 +148,10 @@ func TriggerFailingPoliciesWebhook(
 		}
 
 		// global policy
+		if !globalSettings.Enable && appConfig.Integrations.FailingPoliciesJira() != nil {
+			// the set is processed by the jira integration
+			continue
+		}
 		_, ok := globalPolicyIDs[policy.ID]
 		if !ok {
 			level.Debug(logger).Log("msg", "skipping failing policy, not found in global policy IDs", "policyID", policyID)
@@ -161,6 +166,7 @@ func TriggerFailingPoliciesWebhook(
 			policy,
 			failingPoliciesSet,
 			globalSettings.HostBatchSize,
+			globalSettings.MaxHosts,
 			serverURL,
 			globalWebhookURL,
 			now,
@@ -179,6 +185,7 @@ func sendFailingPoliciesBatchedPOSTs(
 	policy *fleet.Policy,
 	failingPoliciesSet fleet.FailingPolicySet,
 	hostBatchSize int,
+	maxHosts int,
 	serverURL *url.URL,
 	webhookURL *url.URL,
 	now time.Time,
@@ -195,6 +202,11 @@ func sendFailingPoliciesBatchedPOSTs(
 	sort.Slice(hosts, func(i, j int) bool {
 		return hosts[i].ID < hosts[j].ID
 	})
+	if maxHosts > 0 && len(hosts) > maxHosts {
+		// the other hosts stay in the set, they are sent on the next runs
+		level.Debug(logger).Log("msg", "limiting failing hosts", "policyID", policy.ID, "hosts", len(hosts), "maxHosts", maxHosts)
+		hosts = hosts[:maxHosts]
+	}
 
 	if hostBatchSize == 0 {
 		hostBatchSize = len(hosts)
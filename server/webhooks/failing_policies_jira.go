@@ -0,0 +1,178 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// maxJiraIssueHosts is the maximum number of hosts listed in the description
+// or a comment of a Jira issue.
+const maxJiraIssueHosts = 50
+
+// JiraClient creates and updates the Jira issues of the failing policies.
+type JiraClient interface {
+	// CreateIssue creates an issue and returns its key.
+	CreateIssue(ctx context.Context, summary, description string) (string, error)
+	// AddComment adds a comment to the issue.
+	AddComment(ctx context.Context, issueKey, body string) error
+	// CloseIssue transitions the issue to a done status.
+	CloseIssue(ctx context.Context, issueKey string) error
+}
+
+// TriggerFailingPoliciesJira opens a Jira issue for each global policy of the
+// failing policies settings that starts failing on hosts, comments the issue
+// with the hosts that start failing while it is open, and closes it once the
+// policy passes on all the hosts.
+func TriggerFailingPoliciesJira(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	appConfig *fleet.AppConfig,
+	failingPoliciesSet fleet.FailingPolicySet,
+	client JiraClient,
+) error {
+	serverURL, err := url.Parse(appConfig.ServerSettings.ServerURL)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "invalid server url")
+	}
+
+	policyIDs := make(map[uint]struct{})
+	for _, policyID := range appConfig.WebhookSettings.FailingPoliciesWebhook.PolicyIDs {
+		policyIDs[policyID] = struct{}{}
+	}
+
+	policySets, err := failingPoliciesSet.ListSets()
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list policies set")
+	}
+
+	for _, policyID := range policySets {
+		policy, err := ds.Policy(ctx, policyID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			level.Debug(logger).Log("msg", "skipping failing policy, deleted", "policyID", policyID)
+			if err := failingPoliciesSet.RemoveSet(policyID); err != nil {
+				level.Error(logger).Log("msg", "failed to remove policy from set", "policyID", policyID, "err", err)
+			}
+			continue
+		case err != nil:
+			return ctxerr.Wrapf(ctx, err, "get policy: %d", policyID)
+		}
+
+		if policy.TeamID != nil {
+			// the team policies are left to the team webhooks
+			continue
+		}
+		if _, ok := policyIDs[policy.ID]; !ok {
+			level.Debug(logger).Log("msg", "skipping failing policy, not found in global policy IDs", "policyID", policyID)
+			if err := failingPoliciesSet.RemoveSet(policy.ID); err != nil {
+				level.Error(logger).Log("msg", "failed to remove policy from set", "policyID", policyID, "err", err)
+			}
+			continue
+		}
+
+		if err := reportFailingPolicyJira(ctx, ds, policy, failingPoliciesSet, client, serverURL, logger); err != nil {
+			level.Error(logger).Log("msg", "failed to report failing policy to jira", "policyID", policy.ID, "err", err)
+		}
+	}
+
+	issues, err := ds.ListPolicyJiraIssues(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list policy jira issues")
+	}
+	for _, issue := range issues {
+		if issue.FailingHostCount > 0 {
+			continue
+		}
+		if err := client.CloseIssue(ctx, issue.IssueKey); err != nil {
+			level.Error(logger).Log("msg", "failed to close jira issue", "policyID", issue.PolicyID, "issue", issue.IssueKey, "err", err)
+			continue
+		}
+		if err := ds.DeletePolicyJiraIssue(ctx, issue.PolicyID); err != nil {
+			return ctxerr.Wrapf(ctx, err, "delete policy jira issue: %d", issue.PolicyID)
+		}
+	}
+
+	return nil
+}
+
+// reportFailingPolicyJira creates the Jira issue of the policy with the hosts
+// of its failing policy set, or comments the open issue with them, then
+// removes them from the set.
+func reportFailingPolicyJira(
+	ctx context.Context,
+	ds fleet.Datastore,
+	policy *fleet.Policy,
+	failingPoliciesSet fleet.FailingPolicySet,
+	client JiraClient,
+	serverURL *url.URL,
+	logger kitlog.Logger,
+) error {
+	hosts, err := failingPoliciesSet.ListHosts(policy.ID)
+	if err != nil {
+		return ctxerr.Wrapf(ctx, err, "listing hosts for failing policies set %d", policy.ID)
+	}
+	if len(hosts) == 0 {
+		level.Debug(logger).Log("msg", "no hosts", "policyID", policy.ID)
+		return nil
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].ID < hosts[j].ID
+	})
+
+	issue, err := ds.PolicyJiraIssue(ctx, policy.ID)
+	switch {
+	case fleet.IsNotFound(err):
+		var description strings.Builder
+		description.WriteString(policy.Description)
+		if policy.Resolution != nil && *policy.Resolution != "" {
+			fmt.Fprintf(&description, "\n\nh3. Resolution\n%s", *policy.Resolution)
+		}
+		fmt.Fprintf(&description, "\n\nh3. Failing hosts\n%s", jiraHostList(hosts, serverURL))
+
+		key, err := client.CreateIssue(ctx, fmt.Sprintf("Policy %q is failing", policy.Name), description.String())
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "create jira issue")
+		}
+		if err := ds.NewPolicyJiraIssue(ctx, policy.ID, key); err != nil {
+			return ctxerr.Wrap(ctx, err, "record jira issue")
+		}
+	case err != nil:
+		return ctxerr.Wrap(ctx, err, "get policy jira issue")
+	default:
+		body := fmt.Sprintf("The policy started failing on %d more hosts:\n%s", len(hosts), jiraHostList(hosts, serverURL))
+		if err := client.AddComment(ctx, issue.IssueKey, body); err != nil {
+			return ctxerr.Wrapf(ctx, err, "comment jira issue %s", issue.IssueKey)
+		}
+	}
+
+	if err := failingPoliciesSet.RemoveHosts(policy.ID, hosts); err != nil {
+		return ctxerr.Wrapf(ctx, err, "removing hosts from failing policies set %d", policy.ID)
+	}
+	return nil
+}
+
+// jiraHostList returns the list of the hosts with links to their page, in the
+// Jira text formatting notation.
+func jiraHostList(hosts []fleet.PolicySetHost, serverURL *url.URL) string {
+	var list strings.Builder
+	for i, host := range hosts {
+		if i == maxJiraIssueHosts {
+			fmt.Fprintf(&list, "* and %d more hosts\n", len(hosts)-maxJiraIssueHosts)
+			break
+		}
+		failingHost := makeFailingHost(host, serverURL)
+		fmt.Fprintf(&list, "* [%s|%s]\n", failingHost.Hostname, failingHost.URL)
+	}
+	return list.String()
+}
@@ -0,0 +1,171 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/service"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockJiraClient struct {
+	created  map[string]string
+	comments map[string][]string
+	closed   []string
+}
+
+func (c *mockJiraClient) CreateIssue(ctx context.Context, summary, description string) (string, error) {
+	key := fmt.Sprintf("FLEET-%d", len(c.created)+1)
+	c.created[key] = summary + "\n" + description
+	return key, nil
+}
+
+func (c *mockJiraClient) AddComment(ctx context.Context, issueKey, body string) error {
+	c.comments[issueKey] = append(c.comments[issueKey], body)
+	return nil
+}
+
+func (c *mockJiraClient) CloseIssue(ctx context.Context, issueKey string) error {
+	c.closed = append(c.closed, issueKey)
+	return nil
+}
+
+type notFoundError struct{}
+
+func (e notFoundError) Error() string {
+	return "not found"
+}
+
+func (e notFoundError) IsNotFound() bool {
+	return true
+}
+
+func TestTriggerFailingPoliciesJira(t *testing.T) {
+	ds := new(mock.Store)
+
+	policies := map[uint]*fleet.Policy{
+		1: {PolicyData: fleet.PolicyData{ID: 1, Name: "policy1", Description: "policy1 description", Resolution: ptr.String("policy1 resolution")}},
+		2: {PolicyData: fleet.PolicyData{ID: 2, Name: "policy2", Description: "policy2 description"}},
+		3: {PolicyData: fleet.PolicyData{ID: 3, Name: "policy3", TeamID: ptr.Uint(1)}},
+	}
+	ds.PolicyFunc = func(ctx context.Context, id uint) (*fleet.Policy, error) {
+		if p, ok := policies[id]; ok {
+			return p, nil
+		}
+		return nil, ctxerr.Wrap(ctx, sql.ErrNoRows)
+	}
+
+	issues := make(map[uint]*fleet.PolicyJiraIssue)
+	failingHostCounts := make(map[uint]uint)
+	ds.PolicyJiraIssueFunc = func(ctx context.Context, policyID uint) (*fleet.PolicyJiraIssue, error) {
+		if issue, ok := issues[policyID]; ok {
+			return issue, nil
+		}
+		return nil, notFoundError{}
+	}
+	ds.NewPolicyJiraIssueFunc = func(ctx context.Context, policyID uint, issueKey string) error {
+		issues[policyID] = &fleet.PolicyJiraIssue{PolicyID: policyID, IssueKey: issueKey}
+		return nil
+	}
+	ds.ListPolicyJiraIssuesFunc = func(ctx context.Context) ([]*fleet.PolicyJiraIssue, error) {
+		var list []*fleet.PolicyJiraIssue
+		for policyID, issue := range issues {
+			issue.FailingHostCount = failingHostCounts[policyID]
+			list = append(list, issue)
+		}
+		return list, nil
+	}
+	ds.DeletePolicyJiraIssueFunc = func(ctx context.Context, policyID uint) error {
+		delete(issues, policyID)
+		return nil
+	}
+
+	ac := &fleet.AppConfig{
+		WebhookSettings: fleet.WebhookSettings{
+			FailingPoliciesWebhook: fleet.FailingPoliciesWebhookSettings{
+				PolicyIDs: []uint{1, 3, 4},
+			},
+		},
+		ServerSettings: fleet.ServerSettings{
+			ServerURL: "https://fleet.example.com",
+		},
+		Integrations: fleet.Integrations{
+			Jira: []*fleet.JiraIntegration{{EnableFailingPolicies: true}},
+		},
+	}
+	client := &mockJiraClient{created: make(map[string]string), comments: make(map[string][]string)}
+
+	failingPolicySet := service.NewMemFailingPolicySet()
+	for policyID := uint(1); policyID <= 4; policyID++ {
+		require.NoError(t, failingPolicySet.AddHost(policyID, fleet.PolicySetHost{ID: 1, Hostname: "host1.example"}))
+	}
+	require.NoError(t, failingPolicySet.AddHost(1, fleet.PolicySetHost{ID: 2, Hostname: "host2.example"}))
+	failingHostCounts[1] = 2
+
+	err := TriggerFailingPoliciesJira(context.Background(), ds, kitlog.NewNopLogger(), ac, failingPolicySet, client)
+	require.NoError(t, err)
+
+	require.Len(t, client.created, 1)
+	assert.Equal(t, `Policy "policy1" is failing
+policy1 description
+
+h3. Resolution
+policy1 resolution
+
+h3. Failing hosts
+* [host1.example|https://fleet.example.com/hosts/1]
+* [host2.example|https://fleet.example.com/hosts/2]
+`, client.created["FLEET-1"])
+	assert.Equal(t, "FLEET-1", issues[1].IssueKey)
+	assert.Empty(t, client.closed)
+
+	// the sets of the reported, deleted and not configured policies are
+	// emptied, the team policy is left to the team webhook
+	hosts, err := failingPolicySet.ListHosts(1)
+	require.NoError(t, err)
+	assert.Empty(t, hosts)
+	sets, err := failingPolicySet.ListSets()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{1, 3}, sets)
+
+	// a new host fails the policy
+	require.NoError(t, failingPolicySet.AddHost(1, fleet.PolicySetHost{ID: 3, Hostname: "host3.example"}))
+	failingHostCounts[1] = 3
+	err = TriggerFailingPoliciesJira(context.Background(), ds, kitlog.NewNopLogger(), ac, failingPolicySet, client)
+	require.NoError(t, err)
+	assert.Len(t, client.created, 1)
+	assert.Equal(t, []string{"The policy started failing on 1 more hosts:\n* [host3.example|https://fleet.example.com/hosts/3]\n"}, client.comments["FLEET-1"])
+	assert.Empty(t, client.closed)
+
+	// the policy passes on all the hosts
+	failingHostCounts[1] = 0
+	err = TriggerFailingPoliciesJira(context.Background(), ds, kitlog.NewNopLogger(), ac, failingPolicySet, client)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"FLEET-1"}, client.closed)
+	assert.Empty(t, issues)
+}
+
+func TestJiraHostList(t *testing.T) {
+	serverURL, err := url.Parse("https://fleet.example.com")
+	require.NoError(t, err)
+
+	var hosts []fleet.PolicySetHost
+	for i := 1; i <= maxJiraIssueHosts+2; i++ {
+		hosts = append(hosts, fleet.PolicySetHost{ID: uint(i), Hostname: fmt.Sprintf("host%d.example", i)})
+	}
+	list := jiraHostList(hosts, serverURL)
+	lines := strings.Split(strings.TrimSuffix(list, "\n"), "\n")
+	require.Len(t, lines, maxJiraIssueHosts+1)
+	assert.Equal(t, "* [host1.example|https://fleet.example.com/hosts/1]", lines[0])
+	assert.Equal(t, "* and 2 more hosts", lines[maxJiraIssueHosts])
+}
@@ -104,10 +104,14 @@ func TestTriggerFailingPoliciesWebhookBasic(t *testing.T) {
         "team_id": null,
         "resolution": "policy1 resolution",
         "platform": "darwin",
+        "critical": false,
+        "evaluation_window": "",
+        "auto_remediate": false,
         "created_at": "0001-01-01T00:00:00Z",
         "updated_at": "0001-01-01T00:00:00Z",
         "passing_host_count": 0,
-        "failing_host_count": 0
+        "failing_host_count": 0,
+        "host_count_updated_at": null
     },
     "hosts": [
         {
@@ -265,10 +269,14 @@ func TestTriggerFailingPoliciesWebhookTeam(t *testing.T) {
         "team_id": 1,
         "resolution": "policy1 resolution",
         "platform": "darwin",
+        "critical": false,
+        "evaluation_window": "",
+        "auto_remediate": false,
         "created_at": "0001-01-01T00:00:00Z",
         "updated_at": "0001-01-01T00:00:00Z",
         "passing_host_count": 0,
-        "failing_host_count": 0
+        "failing_host_count": 0,
+        "host_count_updated_at": null
     },
     "hosts": [
         {
@@ -345,6 +353,7 @@ func TestSendBatchedPOSTs(t *testing.T) {
 		name            string
 		hostCount       int
 		batchSize       int
+		maxHosts        int
 		expRequestCount int
 	}{
 		{
@@ -389,6 +398,27 @@ func TestSendBatchedPOSTs(t *testing.T) {
 			batchSize:       0,
 			expRequestCount: 1,
 		},
+		{
+			name:            "max-hosts-no-batching",
+			hostCount:       10,
+			batchSize:       0,
+			maxHosts:        4,
+			expRequestCount: 1,
+		},
+		{
+			name:            "max-hosts-batching",
+			hostCount:       10,
+			batchSize:       3,
+			maxHosts:        4,
+			expRequestCount: 2,
+		},
+		{
+			name:            "max-hosts-bigger-than-host-count",
+			hostCount:       10,
+			batchSize:       0,
+			maxHosts:        11,
+			expRequestCount: 1,
+		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			allHosts = []uint{}
@@ -408,20 +438,26 @@ func TestSendBatchedPOSTs(t *testing.T) {
 				p,
 				failingPolicySet,
 				tc.batchSize,
+				tc.maxHosts,
 				serverURL,
 				webhookURL,
 				now,
 				kitlog.NewNopLogger(),
 			)
 			require.NoError(t, err)
-			require.Len(t, allHosts, tc.hostCount)
+			expHostCount := tc.hostCount
+			if tc.maxHosts > 0 && tc.maxHosts < expHostCount {
+				expHostCount = tc.maxHosts
+			}
+			require.Len(t, allHosts, expHostCount)
 			for i := range allHosts {
 				require.Equal(t, allHosts[i], hosts[i].ID)
 			}
 			require.Equal(t, tc.expRequestCount, requestCount)
+			// the hosts over the limit are sent on the next runs
 			setHosts, err := failingPolicySet.ListHosts(p.ID)
 			require.NoError(t, err)
-			assert.Empty(t, setHosts)
+			assert.Len(t, setHosts, tc.hostCount-expHostCount)
 		})
 	}
 }
@@ -0,0 +1,83 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+// hostLifecycleBatchSize is the maximum number of events sent in a request of
+// the host lifecycle webhook.
+var hostLifecycleBatchSize = 100
+
+// TriggerHostLifecycleWebhook queues the offline events of the hosts that did
+// not check in for longer than the threshold and sends the queued host
+// lifecycle events in batches, oldest first. The events are deleted once
+// delivered, if a request fails the remaining events are sent on the next run
+// until they expire.
+func TriggerHostLifecycleWebhook(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	appConfig *fleet.AppConfig,
+	now time.Time,
+) error {
+	settings := appConfig.WebhookSettings.HostLifecycleWebhook
+	if !settings.Enable {
+		// only the events that happen while the webhook is enabled are sent
+		if err := ds.CleanupHostLifecycleEvents(ctx, now); err != nil {
+			return ctxerr.Wrap(ctx, err, "cleanup host lifecycle events")
+		}
+		return nil
+	}
+
+	level.Debug(logger).Log("enabled", "true")
+
+	if err := ds.CleanupHostLifecycleEvents(ctx, now.Add(-fleet.HostLifecycleEventsRetention)); err != nil {
+		return ctxerr.Wrap(ctx, err, "cleanup expired host lifecycle events")
+	}
+	threshold := settings.OfflineThreshold.ValueOr(fleet.HostLifecycleDefaultOfflineThreshold)
+	if err := ds.NewHostOfflineEvents(ctx, now.Add(-threshold)); err != nil {
+		return ctxerr.Wrap(ctx, err, "queue host offline events")
+	}
+
+	for {
+		events, err := ds.ListHostLifecycleEvents(ctx, hostLifecycleBatchSize)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "list host lifecycle events")
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		payload := map[string]interface{}{
+			"text": fmt.Sprintf(
+				"%d host lifecycle events. You've been sent this message because the Host lifecycle webhook is enabled in your Fleet instance.",
+				len(events),
+			),
+			"data": map[string]interface{}{
+				"events": events,
+			},
+		}
+		if err := server.PostSignedJSONWithTimeout(ctx, settings.DestinationURL, settings.Secret, &payload); err != nil {
+			return ctxerr.Wrapf(ctx, err, "posting to %s", settings.DestinationURL)
+		}
+
+		ids := make([]uint, 0, len(events))
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		if err := ds.DeleteHostLifecycleEvents(ctx, ids); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete delivered host lifecycle events")
+		}
+		if len(events) < hostLifecycleBatchSize {
+			return nil
+		}
+	}
+}
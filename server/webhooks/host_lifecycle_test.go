@@ -0,0 +1,118 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerHostLifecycleWebhook(t *testing.T) {
+	ds := new(mock.Store)
+	now := time.Date(2022, 4, 2, 12, 0, 0, 0, time.UTC)
+
+	defer func(size int) { hostLifecycleBatchSize = size }(hostLifecycleBatchSize)
+	hostLifecycleBatchSize = 2
+
+	var received [][]uint
+	fail := false
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		timestamp := r.Header.Get(server.WebhookTimestampHeader)
+		require.NotEmpty(t, timestamp)
+		assert.Equal(t, "sha256="+server.WebhookSignature("secret", timestamp, body), r.Header.Get(server.WebhookSignatureHeader))
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var payload struct {
+			Data struct {
+				Events []fleet.HostLifecycleEvent `json:"events"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &payload))
+		var ids []uint
+		for _, e := range payload.Data.Events {
+			ids = append(ids, e.ID)
+		}
+		received = append(received, ids)
+	}))
+	defer ts.Close()
+
+	ac := &fleet.AppConfig{
+		WebhookSettings: fleet.WebhookSettings{
+			HostLifecycleWebhook: fleet.HostLifecycleWebhookSettings{
+				Enable:         true,
+				DestinationURL: ts.URL,
+				Secret:         "secret",
+			},
+		},
+	}
+
+	queued := []*fleet.HostLifecycleEvent{
+		{ID: 1, Type: fleet.HostLifecycleEventEnrolled, HostID: 1},
+		{ID: 2, Type: fleet.HostLifecycleEventOffline, HostID: 2},
+		{ID: 3, Type: fleet.HostLifecycleEventDeleted, HostID: 3},
+	}
+	ds.ListHostLifecycleEventsFunc = func(ctx context.Context, limit int) ([]*fleet.HostLifecycleEvent, error) {
+		if len(queued) < limit {
+			return queued, nil
+		}
+		return queued[:limit], nil
+	}
+	ds.DeleteHostLifecycleEventsFunc = func(ctx context.Context, ids []uint) error {
+		queued = queued[len(ids):]
+		return nil
+	}
+	var cleanedBefore time.Time
+	ds.CleanupHostLifecycleEventsFunc = func(ctx context.Context, before time.Time) error {
+		cleanedBefore = before
+		return nil
+	}
+	var seenBefore time.Time
+	ds.NewHostOfflineEventsFunc = func(ctx context.Context, before time.Time) error {
+		seenBefore = before
+		return nil
+	}
+
+	// the delivery stops at the first failure, the events are sent again on the
+	// next run
+	fail = true
+	require.Error(t, TriggerHostLifecycleWebhook(context.Background(), ds, kitlog.NewNopLogger(), ac, now))
+	assert.Len(t, queued, 3)
+	assert.Equal(t, now.Add(-fleet.HostLifecycleEventsRetention), cleanedBefore)
+	assert.Equal(t, now.Add(-fleet.HostLifecycleDefaultOfflineThreshold), seenBefore)
+
+	fail = false
+	ac.WebhookSettings.HostLifecycleWebhook.OfflineThreshold = fleet.Duration{Duration: 10 * time.Minute}
+	require.NoError(t, TriggerHostLifecycleWebhook(context.Background(), ds, kitlog.NewNopLogger(), ac, now))
+	assert.Equal(t, [][]uint{{1, 2}, {3}}, received)
+	assert.Empty(t, queued)
+	assert.Equal(t, now.Add(-10*time.Minute), seenBefore)
+
+	// nothing to send
+	received = nil
+	require.NoError(t, TriggerHostLifecycleWebhook(context.Background(), ds, kitlog.NewNopLogger(), ac, now))
+	assert.Empty(t, received)
+
+	// the events are discarded while the webhook is disabled
+	ds.NewHostOfflineEventsFuncInvoked = false
+	ds.ListHostLifecycleEventsFuncInvoked = false
+	ac.WebhookSettings.HostLifecycleWebhook.Enable = false
+	require.NoError(t, TriggerHostLifecycleWebhook(context.Background(), ds, kitlog.NewNopLogger(), ac, now))
+	assert.Equal(t, now, cleanedBefore)
+	assert.False(t, ds.NewHostOfflineEventsFuncInvoked)
+	assert.False(t, ds.ListHostLifecycleEventsFuncInvoked)
+}
@@ -58,6 +58,12 @@ func (c *Conn) WriteJSONError(data interface{}) error {
 	return c.WriteJSONMessage(errType, data)
 }
 
+// Closed returns true if the session is no longer active, usually because it
+// was closed by the client.
+func (c *Conn) Closed() bool {
+	return c.GetSessionState() != sockjs.SessionActive
+}
+
 // ReadJSONMessage reads an incoming Message from JSON. Note that the
 // Message.Data field is guaranteed to be *json.RawMessage, and so unchecked
 // type assertions may be performed as in: